@@ -2,17 +2,44 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/auditexport"
+	"github.com/n1rocket/go-auth-jwt/internal/authz"
 	"github.com/n1rocket/go-auth-jwt/internal/config"
 	"github.com/n1rocket/go-auth-jwt/internal/db"
+	"github.com/n1rocket/go-auth-jwt/internal/email"
+	"github.com/n1rocket/go-auth-jwt/internal/emailnorm"
+	"github.com/n1rocket/go-auth-jwt/internal/emailpolicy"
+	"github.com/n1rocket/go-auth-jwt/internal/emailtrack"
+	"github.com/n1rocket/go-auth-jwt/internal/errreport"
 	httpserver "github.com/n1rocket/go-auth-jwt/internal/http"
+	"github.com/n1rocket/go-auth-jwt/internal/http/handlers"
+	"github.com/n1rocket/go-auth-jwt/internal/http/middleware"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+	"github.com/n1rocket/go-auth-jwt/internal/i18n"
+	"github.com/n1rocket/go-auth-jwt/internal/ids"
+	"github.com/n1rocket/go-auth-jwt/internal/lock"
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
+	"github.com/n1rocket/go-auth-jwt/internal/replication"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
 	"github.com/n1rocket/go-auth-jwt/internal/repository/postgres"
+	"github.com/n1rocket/go-auth-jwt/internal/risk"
+	"github.com/n1rocket/go-auth-jwt/internal/rollout"
 	"github.com/n1rocket/go-auth-jwt/internal/security"
 	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/sms"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
+	"github.com/n1rocket/go-auth-jwt/internal/worker"
 )
 
 // App represents the application with all its dependencies
@@ -22,12 +49,26 @@ type App struct {
 	Server       *http.Server
 	AuthService  *service.AuthService
 	TokenManager *token.Manager
+
+	// Metrics and MetricsServer serve Prometheus metrics; the server is nil
+	// when metrics are disabled
+	Metrics       *metrics.Metrics
+	MetricsServer *http.Server
+
+	// stopBackground cancels background jobs such as dataset refreshers
+	stopBackground context.CancelFunc
+	scheduler      *worker.Scheduler
+	auditStreamer  *auditexport.Streamer
+	replicator     *replication.Dispatcher
+	errorReports   *errreport.Client
+	emails         *worker.EmailDispatcher
+	hashPool       *security.HashPool
 }
 
 // NewApp creates a new application instance
 func NewApp(cfg *config.Config) (*App, error) {
 	// Connect to database
-	dbPool, err := db.Connect(cfg.Database.ConnectionString())
+	dbPool, dsn, err := connectDatabase(cfg.Database)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -39,13 +80,69 @@ func NewApp(cfg *config.Config) (*App, error) {
 		dbPool.Close()
 		return nil, fmt.Errorf("failed to test database connection: %w", err)
 	}
+	if cfg.App.DevMode {
+		if err := migrateDevDatabase(dbPool.Pool()); err != nil {
+			dbPool.Close()
+			return nil, err
+		}
+	}
+
+	readOnly := middleware.NewReadOnlyMode()
+	if cfg.App.ReadOnly {
+		readOnly.Enable(cfg.App.ReadOnlyReason)
+	}
+	if err := checkSchema(ctx, cfg.Database, dbPool.Pool(), readOnly); err != nil {
+		dbPool.Close()
+		return nil, err
+	}
 
 	// Initialize repositories
 	userRepo := postgres.NewUserRepository(dbPool)
 	refreshTokenRepo := postgres.NewRefreshTokenRepository(dbPool)
+	var auditLogRepo repository.AuditLogRepository = postgres.NewAuditLogRepository(dbPool)
+	var auditChain *postgres.ChainedAuditLogRepository
+	if cfg.AuditChain.Enabled {
+		auditChain = postgres.NewChainedAuditLogRepository(dbPool)
+		auditLogRepo = auditChain
+	}
+	activityRepo := postgres.NewAccountActivityRepository(dbPool)
+
+	auditStreamer, err := newAuditStreamer(cfg.AuditExport, cfg.App.Name)
+	if err != nil {
+		dbPool.Close()
+		return nil, err
+	}
+	if auditStreamer != nil {
+		auditLogRepo = auditexport.NewRepository(auditLogRepo, auditStreamer)
+	}
+
+	errorReports, err := newErrorReporter(cfg.ErrorReporting, cfg.App.Environment, version)
+	if err != nil {
+		dbPool.Close()
+		return nil, err
+	}
+
+	if cfg.PII.EncryptionKeys != "" {
+		fieldCipher, err := newFieldCipher(cfg.PII)
+		if err != nil {
+			dbPool.Close()
+			return nil, err
+		}
+		refreshTokenRepo.SetFieldCipher(fieldCipher)
+	}
+
+	var tokenRepo repository.RefreshTokenRepository = refreshTokenRepo
+	replicator, err := newReplicationDispatcher(cfg.Replication)
+	if err != nil {
+		dbPool.Close()
+		return nil, err
+	}
+	if replicator != nil {
+		tokenRepo = replication.NewRepository(tokenRepo, replicator)
+	}
 
 	// Initialize security components
-	passwordHasher := security.NewDefaultPasswordHasher()
+	passwordHasher := newPasswordHasher(cfg.Crypto)
 
 	tokenManager, err := token.NewManager(
 		cfg.JWT.Algorithm,
@@ -59,23 +156,334 @@ func NewApp(cfg *config.Config) (*App, error) {
 		dbPool.Close()
 		return nil, fmt.Errorf("failed to create token manager: %w", err)
 	}
+	tokenManager.SetClockSkew(cfg.JWT.ClockSkew)
+	tokenManager.SetKeyRolloverWindow(cfg.JWT.KeyRolloverWindow)
+	if cfg.JWT.MaxTokenBytes > 0 {
+		tokenManager.SetClaimsBudget(cfg.JWT.MaxTokenBytes, token.OversizeMode(cfg.JWT.OversizeMode))
+	}
+	if cfg.JWT.DPoPEnabled {
+		tokenManager.EnableDPoP(cfg.JWT.DPoPProofMaxAge)
+	}
+	if cfg.JWT.EncryptionAlgorithm != "" {
+		if err := tokenManager.EnableEncryption(cfg.JWT.EncryptionAlgorithm, cfg.JWT.EncryptionKeyPath); err != nil {
+			dbPool.Close()
+			return nil, fmt.Errorf("failed to enable token encryption: %w", err)
+		}
+	}
+	if cfg.Crypto.FIPSMode {
+		if err := tokenManager.ValidateFIPS(); err != nil {
+			dbPool.Close()
+			return nil, fmt.Errorf("invalid FIPS configuration: %w", err)
+		}
+	}
 
 	// Initialize services
 	authService := service.NewAuthService(
 		userRepo,
-		refreshTokenRepo,
+		tokenRepo,
 		passwordHasher,
 		tokenManager,
 		cfg.JWT.RefreshTokenTTL,
 	)
+	authService.EnableIDTokens(cfg.JWT.IDTokenAudience)
+	authService.SetMinResponseTime(cfg.Auth.MinResponseTime)
+	authService.SetEnumerationSafe(cfg.Auth.EnumerationSafe)
+	authService.SetRefreshGracePeriod(cfg.JWT.RefreshGracePeriod)
+	authService.SetActivityRecorder(activityRepo)
+	authService.SetAuditLog(auditLogRepo)
+	authService.SetSecurityEvents(postgres.NewAuditLogRepository(dbPool))
+	idGenerator, err := ids.New(ids.Strategy(cfg.Signup.UserIDStrategy))
+	if err != nil {
+		dbPool.Close()
+		return nil, err
+	}
+	authService.SetIDGenerator(idGenerator)
+	if cfg.Signup.TermsVersion != "" {
+		authService.SetConsents(postgres.NewConsentRepository(dbPool), cfg.Signup.TermsVersion)
+	}
+	if cfg.Email.TrackingEnabled {
+		emailTracker := emailtrack.New(cfg.Email.TrackingSecret, cfg.Email.TrackingBaseURL)
+		emailTracker.SetPixel(cfg.Email.TrackingPixel)
+		authService.SetEmailTracking(emailTracker, postgres.NewEmailTrackingOptOutRepository(dbPool))
+	}
+	var tokenVersions repository.TokenVersionRepository
+	if cfg.JWT.TokenVersionCheck {
+		tokenVersions = postgres.NewTokenVersionRepository(dbPool)
+		authService.SetTokenVersions(tokenVersions)
+	}
+	var accessTokenDenylist repository.AccessTokenDenylistRepository
+	if cfg.JWT.AccessTokenDenylist {
+		accessTokenDenylist = postgres.NewAccessTokenDenylistRepository(dbPool)
+		authService.SetAccessTokenDenylist(accessTokenDenylist)
+	}
+	if cfg.Email.CodesEnabled {
+		authService.SetEmailCodes(postgres.NewEmailCodeRepository(dbPool), service.CodePolicy{
+			TTL:         cfg.Email.CodeTTL,
+			MaxAttempts: cfg.Email.CodeMaxAttempts,
+		})
+	}
+	if cfg.SMS.Provider != "" {
+		smsSender, err := newSMSSender(cfg.SMS)
+		if err != nil {
+			dbPool.Close()
+			return nil, err
+		}
+		authService.SetSMS(smsSender, postgres.NewEmailCodeRepository(dbPool), cfg.App.Name, service.CodePolicy{
+			TTL:         cfg.SMS.CodeTTL,
+			MaxAttempts: cfg.SMS.CodeMaxAttempts,
+		})
+	}
+	if cfg.Auth.LoginApprovalsEnabled {
+		authService.SetLoginApprovals(postgres.NewTrustedDeviceRepository(dbPool), postgres.NewLoginApprovalRepository(dbPool), cfg.Auth.LoginApprovalTTL)
+	}
+	if cfg.Auth.SessionHandoffEnabled {
+		authService.SetSessionHandoff(postgres.NewSessionHandoffRepository(dbPool), cfg.Auth.SessionHandoffTTL)
+	}
+	if cfg.Auth.UserMetadataEnabled {
+		authService.SetUserMetadata(userRepo, cfg.Auth.UserMetadataMaxBytes, cfg.Auth.UserMetadataClaims)
+	}
+	authService.SetSessionStats(refreshTokenRepo)
+	if cfg.Auth.AccessWindowsEnabled {
+		authService.SetAccessWindows(postgres.NewAccessWindowRepository(dbPool))
+	}
+	if cfg.Auth.ClientPasswordHashingEnabled {
+		authService.SetClientPasswordHashing(cfg.Auth.ClientPasswordHashingIterations, []byte(cfg.Auth.ClientPasswordHashingSaltKey))
+	}
+	if cfg.Email.ResendCooldown > 0 || cfg.Email.ResendDailyLimit > 0 {
+		authService.SetVerificationResendLimit(postgres.NewVerificationResendRepository(dbPool), cfg.Email.ResendCooldown, cfg.Email.ResendDailyLimit)
+	}
+	if cfg.Auth.DeviceFlowEnabled {
+		authService.SetDeviceAuthorization(postgres.NewDeviceAuthorizationRepository(dbPool), cfg.Auth.DeviceCodeTTL, cfg.Auth.DeviceVerificationURI)
+	}
+	handlers.BuildVersion = version
+	handlers.FIPSMode = cfg.Crypto.FIPSMode
+
+	if cfg.Risk.Enabled {
+		riskEngine, err := newRiskEngine(cfg.Risk)
+		if err != nil {
+			dbPool.Close()
+			return nil, err
+		}
+		authService.EnableRiskEngine(riskEngine, auditLogRepo)
+
+		captchaVerifier, err := newCaptchaVerifier(cfg.Risk)
+		if err != nil {
+			dbPool.Close()
+			return nil, err
+		}
+		if captchaVerifier != nil {
+			authService.SetCaptchaVerifier(captchaVerifier)
+		}
+	}
+
+	if cfg.Avatar.UploadBaseURL != "" {
+		signer, err := security.NewURLSigner(cfg.Avatar.UploadSigningKey)
+		if err != nil {
+			dbPool.Close()
+			return nil, fmt.Errorf("failed to create avatar upload signer: %w", err)
+		}
+		authService.SetAvatarUploads(signer, cfg.Avatar.UploadBaseURL, cfg.Avatar.UploadURLTTL)
+	}
+	if cfg.Auth.RememberMeEnabled {
+		authService.SetRememberMe(service.RememberMePolicy{TTL: cfg.Auth.RememberMeTTL, Sliding: cfg.Auth.RememberMeSliding})
+	}
+	if err := configureUserEvents(authService, cfg.UserEvents, postgres.NewUserEventRepository(dbPool)); err != nil {
+		dbPool.Close()
+		return nil, err
+	}
+
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	if err := configureEmailPolicy(bgCtx, authService, cfg.Signup); err != nil {
+		stopBackground()
+		dbPool.Close()
+		return nil, err
+	}
+
+	var dormancyService *service.DormancyService
+	scheduler := worker.NewScheduler(slog.Default())
+	if cfg.Lock.Backend != "" {
+		locker, err := lock.New(cfg.Lock.Backend, cfg.Lock.RedisURL, dbPool)
+		if err != nil {
+			stopBackground()
+			dbPool.Close()
+			return nil, err
+		}
+		scheduler.SetLocker(locker, cfg.Lock.SchedulerTTL)
+	}
+	if cfg.Dormancy.Enabled {
+		dormancyService, err = newDormancyService(cfg, activityRepo, userRepo, tokenRepo, auditLogRepo)
+		if err != nil {
+			stopBackground()
+			dbPool.Close()
+			return nil, err
+		}
+		if err := scheduleDormancy(scheduler, dormancyService, cfg.Dormancy); err != nil {
+			stopBackground()
+			dbPool.Close()
+			return nil, err
+		}
+	}
+	if auditChain != nil {
+		if err := scheduleAuditAnchoring(scheduler, auditChain, cfg.AuditChain); err != nil {
+			stopBackground()
+			dbPool.Close()
+			return nil, err
+		}
+	}
+	if cfg.Integrity.Enabled {
+		if err := scheduleIntegrityCheck(scheduler, postgres.NewIntegrityRepository(dbPool), cfg.Integrity); err != nil {
+			stopBackground()
+			dbPool.Close()
+			return nil, err
+		}
+	}
+
+	var quotaService *service.QuotaService
+	if cfg.Quota.Enabled {
+		quotaService, err = newQuotaService(cfg.Quota, postgres.NewQuotaRepository(dbPool))
+		if err != nil {
+			stopBackground()
+			scheduler.Stop()
+			dbPool.Close()
+			return nil, err
+		}
+	}
+
+	appMetrics, err := newMetrics(cfg.Metrics)
+	if err != nil {
+		stopBackground()
+		scheduler.Stop()
+		dbPool.Close()
+		return nil, err
+	}
+	authService.SetResendRecorder(appMetrics)
+	authService.SetAuthRecorder(appMetrics)
+	if emailTracker := authService.EmailTracker(); emailTracker != nil {
+		emailTracker.SetRecorder(appMetrics)
+	}
+	if err := configureRollout(authService, cfg.Rollout, appMetrics); err != nil {
+		stopBackground()
+		appMetrics.Stop()
+		dbPool.Close()
+		return nil, err
+	}
+	if cfg.JWT.NextPrivateKeyPath != "" {
+		if err := scheduleKeyRollover(bgCtx, scheduler, tokenManager, cfg.JWT, appMetrics.SigningKeys); err != nil {
+			stopBackground()
+			appMetrics.Stop()
+			dbPool.Close()
+			return nil, err
+		}
+	}
+	if cfg.Database.CredentialsFile != "" {
+		if err := scheduleCredentialRotation(scheduler, dbPool, cfg.Database, dsn, appMetrics.Database); err != nil {
+			stopBackground()
+			appMetrics.Stop()
+			dbPool.Close()
+			return nil, err
+		}
+	}
+	scheduler.Start(bgCtx)
+	if auditStreamer != nil {
+		auditStreamer.SetMetrics(appMetrics.AuditExport)
+		auditStreamer.Start()
+	}
+	if replicator != nil {
+		replicator.Start()
+	}
+	if errorReports != nil {
+		errorReports.Start()
+	}
+	var hashPool *security.HashPool
+	if cfg.Crypto.HashWorkers > 0 {
+		hashPool = newHashPool(cfg.Crypto, appMetrics.Hashing)
+		passwordHasher.SetPool(hashPool)
+	}
+	readiness := handlers.NewReadiness()
+	var emailDispatcher *worker.EmailDispatcher
+	var emailService *service.AuthServiceWithEmail
+
+	// cleanup releases what was started so far when the app cannot be built
+	cleanup := func() {
+		stopBackground()
+		scheduler.Stop()
+		if auditStreamer != nil {
+			auditStreamer.Stop(cfg.App.ShutdownTimeout)
+		}
+		if replicator != nil {
+			replicator.Stop(cfg.App.ShutdownTimeout)
+		}
+		if errorReports != nil {
+			errorReports.Stop(cfg.App.ShutdownTimeout)
+		}
+		if emailDispatcher != nil {
+			emailDispatcher.Stop(cfg.App.ShutdownTimeout)
+		}
+		if hashPool != nil {
+			hashPool.Stop()
+		}
+		appMetrics.Stop()
+		dbPool.Close()
+	}
+
+	if cfg.Email.DeliveryEnabled {
+		emailDispatcher, err = newEmailDispatcher(cfg.Email, readiness, appMetrics)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to create email dispatcher: %w", err)
+		}
+		emailDispatcher.Start()
+		emailService = service.NewAuthServiceWithEmail(authService, emailDispatcher, cfg, slog.Default())
+	}
+	if cfg.App.DevMode {
+		seedCtx, cancelSeed := context.WithTimeout(context.Background(), 10*time.Second)
+		err := seedDevData(seedCtx, authService)
+		cancelSeed()
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	routes, err := newRoutes(cfg, authService, tokenManager, routeServices{
+		dormancy:  dormancyService,
+		adminKeys: newAdminKeyService(cfg.Admin, postgres.NewAdminSigningKeyRepository(dbPool), auditLogRepo),
+		quota:     quotaService,
+		metrics:   appMetrics,
+		readOnly:  readOnly,
+
+		serviceAccounts: newServiceAccountService(cfg, postgres.NewServiceAccountRepository(dbPool), tokenManager, auditLogRepo),
+
+		tokenVersions:       tokenVersions,
+		accessTokenDenylist: accessTokenDenylist,
+		errorReports:        errorReports,
+		emails:              emailService,
+		readiness:           readiness,
+
+		database:   dbPool,
+		emailQueue: emailDispatcher,
+		scheduler:  scheduler,
+	})
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
 
 	// Create HTTP server
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.App.Port),
-		Handler:      httpserver.Routes(authService, tokenManager),
-		ReadTimeout:  cfg.App.ReadTimeout,
-		WriteTimeout: cfg.App.WriteTimeout,
-		IdleTimeout:  cfg.App.IdleTimeout,
+		Addr:              fmt.Sprintf(":%d", cfg.App.Port),
+		Handler:           routes,
+		ReadTimeout:       cfg.App.ReadTimeout,
+		ReadHeaderTimeout: cfg.App.ReadHeaderTimeout,
+		WriteTimeout:      cfg.App.WriteTimeout,
+		IdleTimeout:       cfg.App.IdleTimeout,
+		MaxHeaderBytes:    cfg.App.MaxHeaderBytes,
+	}
+
+	srv.TLSConfig, err = newTLSConfig(cfg.App)
+	if err != nil {
+		cleanup()
+		return nil, err
 	}
 
 	return &App{
@@ -84,13 +492,962 @@ func NewApp(cfg *config.Config) (*App, error) {
 		Server:       srv,
 		AuthService:  authService,
 		TokenManager: tokenManager,
+
+		Metrics:       appMetrics,
+		MetricsServer: newMetricsServer(cfg.Metrics, appMetrics),
+
+		stopBackground: stopBackground,
+		scheduler:      scheduler,
+		auditStreamer:  auditStreamer,
+		replicator:     replicator,
+		errorReports:   errorReports,
+		emails:         emailDispatcher,
+		hashPool:       hashPool,
 	}, nil
 }
 
 // Close closes all resources
 func (a *App) Close() error {
+	if a.stopBackground != nil {
+		a.stopBackground()
+	}
+	if a.scheduler != nil {
+		a.scheduler.Stop()
+	}
+	if a.auditStreamer != nil {
+		if err := a.auditStreamer.Stop(a.Config.App.ShutdownTimeout); err != nil {
+			slog.Error("failed to flush audit exporters", "error", err)
+		}
+	}
+	if a.replicator != nil {
+		if err := a.replicator.Stop(a.Config.App.ShutdownTimeout); err != nil {
+			slog.Error("failed to flush refresh token replicators", "error", err)
+		}
+	}
+	if a.errorReports != nil {
+		if err := a.errorReports.Stop(a.Config.App.ShutdownTimeout); err != nil {
+			slog.Error("failed to flush error reports", "error", err)
+		}
+	}
+	if a.emails != nil {
+		if err := a.emails.Stop(a.Config.App.ShutdownTimeout); err != nil {
+			slog.Error("failed to stop email dispatcher", "error", err)
+		}
+	}
+	if a.hashPool != nil {
+		a.hashPool.Stop()
+	}
+	if a.Metrics != nil {
+		a.Metrics.Stop()
+	}
 	if a.DB != nil {
 		a.DB.Close()
 	}
 	return nil
 }
+
+// checkSchema compares the database migration version with the range this
+// release supports and applies DB_SCHEMA_CHECK when it is outside
+func checkSchema(ctx context.Context, cfg config.DatabaseConfig, sqlDB *sql.DB, readOnly *middleware.ReadOnlyMode) error {
+	if cfg.SchemaCheck == "off" {
+		return nil
+	}
+
+	expected, err := db.ExpectedSchema(uint(cfg.SchemaMaxAhead))
+	if err != nil {
+		return err
+	}
+
+	status, err := db.CheckSchema(ctx, sqlDB, expected)
+	if err == nil {
+		slog.Info("database schema is compatible", "version", status.Version, "min", expected.Min, "max", expected.Max)
+		return nil
+	}
+
+	switch cfg.SchemaCheck {
+	case "read_only":
+		slog.Warn("starting in read-only mode", "error", err)
+		readOnly.Enable("database schema upgrade in progress")
+		return nil
+	case "warn":
+		slog.Warn("database schema check failed", "error", err)
+		return nil
+	}
+	return err
+}
+
+// newTLSConfig requests client certificates when a client CA is configured,
+// so tokens issued over mTLS are bound to the certificate (RFC 8705).
+// Clients without a certificate can still connect.
+func newTLSConfig(cfg config.AppConfig) (*tls.Config, error) {
+	if cfg.TLSClientCAFile == "" {
+		return nil, nil
+	}
+
+	pool, err := readCertPool(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  pool,
+	}, nil
+}
+
+// readCertPool reads the PEM certificates in path
+func readCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// serve accepts connections for srv, with TLS when a certificate is
+// configured. It listens itself, instead of leaving it to ListenAndServe, to
+// set the TCP keep-alive interval.
+func serve(srv *http.Server, cfg config.AppConfig) error {
+	listenConfig := net.ListenConfig{KeepAlive: cfg.TCPKeepAlive}
+	listener, err := listenConfig.Listen(context.Background(), "tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	if cfg.TLSCertFile != "" {
+		return srv.ServeTLS(listener, cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return srv.Serve(listener)
+}
+
+// newPasswordHasher uses PBKDF2 in FIPS mode and bcrypt otherwise. With a
+// hash target the work factor is calibrated on this host instead.
+func newPasswordHasher(cfg config.CryptoConfig) *security.PasswordHasher {
+	if cfg.HashTarget <= 0 {
+		if cfg.FIPSMode {
+			return security.NewPBKDF2PasswordHasher(cfg.PBKDF2Iterations)
+		}
+		return security.NewDefaultPasswordHasher()
+	}
+
+	var c security.Calibration
+	if cfg.FIPSMode {
+		c = security.CalibratePBKDF2(cfg.HashTarget)
+	} else {
+		c = security.CalibrateBcrypt(cfg.HashTarget)
+	}
+
+	attrs := []any{
+		"algorithm", c.Algorithm,
+		"cost", c.Cost,
+		"duration", c.Duration,
+		"target", cfg.HashTarget,
+	}
+	if c.Clamped {
+		slog.Warn("Password hash target is outside the allowed work factor bounds", attrs...)
+	} else {
+		slog.Info("Calibrated password hash work factor", attrs...)
+	}
+
+	if cfg.FIPSMode {
+		return security.NewPBKDF2PasswordHasher(c.Cost)
+	}
+	return security.NewPasswordHasher(c.Cost)
+}
+
+// newHashPool starts the workers password hashes and compares run on
+func newHashPool(cfg config.CryptoConfig, recorder security.HashPoolRecorder) *security.HashPool {
+	pool := security.NewHashPool(cfg.HashWorkers, cfg.HashQueueSize, cfg.HashMaxWait)
+	pool.SetMetrics(recorder)
+	pool.Start()
+	return pool
+}
+
+// newFieldCipher creates the cipher used to encrypt personal data at rest
+func newFieldCipher(cfg config.PIIConfig) (*security.FieldCipher, error) {
+	keys, err := security.ParseFieldKeys(cfg.EncryptionKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PII_ENCRYPTION_KEYS: %w", err)
+	}
+	fieldCipher, err := security.NewFieldCipher(keys, cfg.EncryptionKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PII cipher: %w", err)
+	}
+	return fieldCipher, nil
+}
+
+// newRiskEngine creates the login risk engine from configuration
+func newRiskEngine(cfg config.RiskConfig) (*risk.Engine, error) {
+	riskConfig := risk.DefaultConfig()
+	riskConfig.CaptchaThreshold = cfg.CaptchaThreshold
+	riskConfig.EmailConfirmationThreshold = cfg.EmailConfirmationThreshold
+	riskConfig.BlockThreshold = cfg.BlockThreshold
+
+	engine := risk.NewEngine(riskConfig, slog.Default())
+	if cfg.TorExitListPath != "" {
+		if err := engine.LoadTorExitListFile(cfg.TorExitListPath); err != nil {
+			return nil, fmt.Errorf("failed to load TOR exit list: %w", err)
+		}
+	}
+
+	return engine, nil
+}
+
+// newCaptchaVerifier checks captcha solutions with the configured provider,
+// or returns nil when none is configured. Solutions are single-use, so a
+// retried request would be rejected as a duplicate.
+func newCaptchaVerifier(cfg config.RiskConfig) (*risk.SiteVerifier, error) {
+	if cfg.CaptchaVerifyURL == "" {
+		return nil, nil
+	}
+
+	clientConfig := httpclient.DefaultConfig()
+	clientConfig.MaxRetries = 0
+	client, err := httpclient.New(clientConfig, slog.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create captcha client: %w", err)
+	}
+	return risk.NewSiteVerifier(client, cfg.CaptchaVerifyURL, cfg.CaptchaSecret), nil
+}
+
+// newBotDetector creates the bot detector for the auth routes, reporting
+// signals to appMetrics when set
+func newBotDetector(cfg config.RiskConfig, appMetrics *metrics.Metrics) *risk.BotDetector {
+	botConfig := risk.DefaultBotConfig()
+	botConfig.BurstWindow = cfg.BotBurstWindow
+	botConfig.IPBurstLimit = cfg.BotIPBurstLimit
+	botConfig.HighRiskScore = cfg.BotHighRiskScore
+
+	detector := risk.NewBotDetector(botConfig, slog.Default())
+	if appMetrics != nil {
+		detector.SetRecorder(appMetrics)
+	}
+	return detector
+}
+
+// configureEmailPolicy applies email normalization, the signup email domain
+// policy and MX validation, starting the disposable domain refresher when a
+// dataset URL is configured
+func configureEmailPolicy(ctx context.Context, authService *service.AuthService, cfg config.SignupConfig) error {
+	authService.SetEmailNormalizer(emailnorm.Normalizer{CanonicalizeGmail: cfg.CanonicalizeGmail})
+
+	if cfg.MXValidation != "off" {
+		checker := emailpolicy.NewMXChecker(nil, cfg.MXLookupTimeout, cfg.MXCacheTTL)
+		authService.SetDeliverabilityChecker(checker, cfg.MXValidation == "reject")
+	}
+
+	if len(cfg.AllowedEmailDomains) == 0 && len(cfg.DeniedEmailDomains) == 0 && !cfg.BlockDisposableEmails {
+		return nil
+	}
+
+	var disposable *emailpolicy.DisposableList
+	if cfg.BlockDisposableEmails {
+		disposable = emailpolicy.NewDisposableList()
+		if cfg.DisposableDomainsURL != "" {
+			client, err := httpclient.New(httpclient.DefaultConfig(), slog.Default())
+			if err != nil {
+				return fmt.Errorf("failed to create HTTP client: %w", err)
+			}
+			disposable.StartRefresher(ctx, client, cfg.DisposableDomainsURL, cfg.DisposableDomainsRefresh, slog.Default())
+		}
+	}
+
+	authService.SetEmailPolicy(emailpolicy.NewPolicy(cfg.AllowedEmailDomains, cfg.DeniedEmailDomains, disposable))
+	return nil
+}
+
+// configureRollout enables the auth behavior changes rolled out to a share
+// of users
+func configureRollout(authService *service.AuthService, cfg config.RolloutConfig, recorder service.RolloutRecorder) error {
+	cohorts, err := cfg.FlagCohorts()
+	if err != nil || len(cohorts) == 0 {
+		return err
+	}
+	flags, err := rollout.New(cohorts)
+	if err != nil {
+		return fmt.Errorf("ROLLOUT_FLAGS: %w", err)
+	}
+	authService.SetRollout(flags, recorder)
+	for flag, list := range cohorts {
+		slog.Info("rollout flag enabled", "flag", flag, "percent", len(list)*100/rollout.Cohorts)
+	}
+	return nil
+}
+
+// configureUserEvents stores user lifecycle events and delivers them to the
+// configured webhook
+func configureUserEvents(authService *service.AuthService, cfg config.UserEventsConfig, repo repository.UserEventRepository) error {
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+	client, err := httpclient.New(httpclient.DefaultConfig(), slog.Default())
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	notifier := service.NewWebhookUserEventNotifier(client, cfg.WebhookURL, cfg.WebhookSecret)
+	authService.SetUserEvents(repo, notifier)
+	return nil
+}
+
+// newStatusSources collects the components the admin status endpoint
+// reports on, leaving out the ones that are not running
+func newStatusSources(svc routeServices, tokenManager *token.Manager, rateLimits *middleware.RateLimitStats) handlers.StatusSources {
+	sources := handlers.StatusSources{RateLimits: rateLimits}
+	if tokenManager != nil {
+		sources.SigningKeys = tokenManager
+	}
+	if svc.database != nil {
+		sources.Database = svc.database
+	}
+	if svc.emailQueue != nil {
+		sources.EmailQueue = svc.emailQueue
+	}
+	if svc.scheduler != nil {
+		sources.Scheduler = svc.scheduler
+	}
+	return sources
+}
+
+// routeServices are the optional services served by newRoutes; nil
+// services are not served
+type routeServices struct {
+	dormancy  *service.DormancyService
+	adminKeys *service.AdminKeyService
+	quota     *service.QuotaService
+	metrics   *metrics.Metrics
+	readOnly  *middleware.ReadOnlyMode
+
+	serviceAccounts     *service.ServiceAccountService
+	tokenVersions       repository.TokenVersionRepository
+	accessTokenDenylist repository.AccessTokenDenylistRepository
+	errorReports        *errreport.Client
+	emails              *service.AuthServiceWithEmail
+	readiness           *handlers.Readiness
+
+	// reported by the admin status endpoint
+	database   *db.DB
+	emailQueue *worker.EmailDispatcher
+	scheduler  *worker.Scheduler
+}
+
+// newRoutes serves the admin API when an admin token is configured,
+// enforces quotas when the quota service is set, and enforces the route
+// policy file and OPA decisions when they are configured
+func newRoutes(cfg *config.Config, authService *service.AuthService, tokenManager *token.Manager, svc routeServices) (http.Handler, error) {
+	opts := httpserver.RouteOptions{
+		Metrics:           svc.metrics,
+		MaxRouteLabels:    cfg.Metrics.MaxRouteLabels,
+		Quota:             svc.quota,
+		QuotaTenantHeader: cfg.Quota.TenantHeader,
+		ReadOnly:          svc.readOnly,
+		ServiceAccounts:   svc.serviceAccounts,
+
+		TokenResponseFormat: handlers.TokenResponseFormat(cfg.JWT.ResponseFormat),
+	}
+	if svc.tokenVersions != nil {
+		opts.TokenVersions = svc.tokenVersions
+	}
+	if svc.accessTokenDenylist != nil {
+		opts.AccessTokenDenylist = svc.accessTokenDenylist
+	}
+	if cfg.Admin.APIToken != "" {
+		opts.Admin = handlers.NewAdminHandler(svc.dormancy)
+		opts.AdminToken = cfg.Admin.APIToken
+		opts.RateLimitStats = middleware.NewRateLimitStats()
+		opts.Admin.SetAccessWindows(authService)
+		opts.Admin.SetAnalytics(authService)
+		opts.Admin.SetSessions(authService)
+		opts.Admin.SetUserEvents(authService)
+		opts.Admin.SetUsers(authService)
+		opts.Admin.SetStatus(newStatusSources(svc, tokenManager, opts.RateLimitStats))
+		if svc.emails != nil {
+			opts.Admin.SetEmails(svc.emails)
+		}
+		if svc.readOnly != nil {
+			opts.Admin.SetReadOnly(svc.readOnly)
+		}
+		if svc.adminKeys != nil {
+			opts.Admin.SetSigningKeys(svc.adminKeys)
+			opts.AdminSignatures = middleware.NewSignatureVerifier(svc.adminKeys, cfg.Admin.SignatureMaxSkew)
+		}
+	}
+	if cfg.Auth.PolicyFile != "" {
+		policy, err := authz.LoadPolicy(cfg.Auth.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.Policy = policy
+	}
+	if cfg.OPA.URL != "" {
+		decider, err := newOPAClient(cfg.OPA)
+		if err != nil {
+			return nil, err
+		}
+		opts.Decider = decider
+		opts.DecisionTenantHeader = cfg.OPA.TenantHeader
+		opts.DecisionFailOpen = cfg.OPA.FailOpen
+	}
+	if cfg.Auth.RememberMeEnabled {
+		signer, err := security.NewCookieSigner(cfg.Auth.RememberMeCookieSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remember-me cookie signer: %w", err)
+		}
+		opts.RememberMeCookie = &handlers.RememberMeCookie{
+			Name:   cfg.Auth.RememberMeCookieName,
+			Path:   cfg.Auth.RememberMeCookiePath,
+			Domain: cfg.Auth.RememberMeCookieDomain,
+			Secure: cfg.Auth.RememberMeCookieSecure,
+			Signer: signer,
+		}
+	}
+	opts.JWKSMaxAge = cfg.JWT.JWKSMaxAge
+	if len(cfg.Mobile.AppleAppIDs) > 0 || cfg.Mobile.AndroidPackage != "" {
+		opts.AppLinks = &handlers.AppLinks{
+			AppleAppIDs:             cfg.Mobile.AppleAppIDs,
+			Paths:                   cfg.Mobile.LinkPaths,
+			AndroidPackage:          cfg.Mobile.AndroidPackage,
+			AndroidCertFingerprints: cfg.Mobile.AndroidCertFingerprints,
+		}
+	}
+	opts.AllowAnyOrigin = cfg.App.DevMode
+	opts.CSPReportOnly = cfg.App.CSPReportOnly
+	opts.EarlyHints = cfg.App.EarlyHints
+	opts.ErrorReports = svc.errorReports
+	var catalogs []i18n.Loader
+	if cfg.App.MessageCatalogDir != "" {
+		catalogs = append(catalogs, i18n.DirLoader{Dir: cfg.App.MessageCatalogDir})
+	}
+	messages, err := i18n.NewBundle(catalogs...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Messages = messages
+	opts.Email = svc.emails
+	opts.Readiness = svc.readiness
+	if cfg.Risk.BotDetectionEnabled {
+		opts.BotDetector = newBotDetector(cfg.Risk, svc.metrics)
+	}
+	if len(cfg.Risk.ExemptCIDRs) > 0 || len(cfg.Risk.ExemptAPIKeys) > 0 {
+		exemptions, err := risk.NewExemptions(cfg.Risk.ExemptCIDRs, cfg.Risk.ExemptAPIKeys)
+		if err != nil {
+			return nil, err
+		}
+		opts.RiskExemptions = exemptions
+	}
+	opts.ShadowRateLimits = cfg.RateLimit.ShadowPolicies
+	opts.RateLimitKeys = cfg.RateLimit.Keys
+	opts.APIv2 = cfg.API.V2Enabled
+	deprecations, err := cfg.API.RouteDeprecations()
+	if err != nil {
+		return nil, fmt.Errorf("invalid API deprecations: %w", err)
+	}
+	if len(deprecations) > 0 {
+		opts.Deprecations = make(map[string]middleware.Deprecation, len(deprecations))
+		for route, d := range deprecations {
+			opts.Deprecations[route] = middleware.Deprecation{Since: d.Since, Sunset: d.Sunset, Successor: d.Successor}
+		}
+	}
+	if cfg.App.OpenAPIFile != "" {
+		spec, err := os.ReadFile(cfg.App.OpenAPIFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenAPI document: %w", err)
+		}
+		opts.OpenAPISpec = spec
+	}
+	return httpserver.NewRouter(authService, tokenManager, opts)
+}
+
+// newOPAClient queries the OPA server for decisions. Requests are not
+// retried, since every API request waits for its decision; the circuit
+// breaker fails fast while OPA is down.
+func newOPAClient(cfg config.OPAConfig) (*authz.OPAClient, error) {
+	clientConfig := httpclient.DefaultConfig()
+	clientConfig.Timeout = cfg.Timeout
+	clientConfig.MaxRetries = 0
+
+	client, err := httpclient.New(clientConfig, slog.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OPA client: %w", err)
+	}
+	return authz.NewOPAClient(client, cfg.URL), nil
+}
+
+// newSMSSender creates the configured SMS provider, or returns nil when SMS
+// is disabled
+func newSMSSender(cfg config.SMSConfig) (sms.Sender, error) {
+	if cfg.Provider == "" {
+		return nil, nil
+	}
+
+	// A retried send could text the same code twice
+	clientConfig := httpclient.DefaultConfig()
+	clientConfig.Timeout = cfg.Timeout
+	clientConfig.MaxRetries = 0
+
+	client, err := httpclient.New(clientConfig, slog.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SMS client: %w", err)
+	}
+
+	switch cfg.Provider {
+	case "twilio":
+		return sms.NewTwilioSender(client, cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.From), nil
+	default:
+		return sms.NewHTTPSender(client, cfg.HTTPURL, cfg.HTTPToken), nil
+	}
+}
+
+// newErrorReporter reports panics and 5xx responses to the configured
+// Sentry DSN, or returns nil when none is configured
+func newErrorReporter(cfg config.ErrorReportingConfig, environment, release string) (*errreport.Client, error) {
+	if cfg.DSN == "" {
+		return nil, nil
+	}
+
+	// Sentry drops events it has already seen by event ID
+	clientConfig := httpclient.DefaultConfig()
+	clientConfig.Timeout = cfg.Timeout
+	clientConfig.RetryNonIdempotent = true
+	client, err := httpclient.New(clientConfig, slog.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create error reporting client: %w", err)
+	}
+
+	reporter, err := errreport.NewSentryReporter(client, cfg.DSN, environment, release)
+	if err != nil {
+		return nil, err
+	}
+
+	reportConfig := errreport.DefaultConfig()
+	reportConfig.SampleRate = cfg.SampleRate
+	reportConfig.ReportTimeout = cfg.Timeout
+	return errreport.NewClient(reporter, reportConfig, slog.Default()), nil
+}
+
+// newAuditStreamer streams audit events to the configured SIEM sinks, or
+// returns nil when no sink is configured
+func newAuditStreamer(cfg config.AuditExportConfig, appName string) (*auditexport.Streamer, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	// Sinks receive events at least once, so posts are retried too
+	clientConfig := httpclient.DefaultConfig()
+	clientConfig.RetryNonIdempotent = true
+	client, err := httpclient.New(clientConfig, slog.Default())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	var exporters []auditexport.Exporter
+	if cfg.SyslogAddress != "" {
+		exporters = append(exporters, auditexport.NewSyslogExporter(cfg.SyslogNetwork, cfg.SyslogAddress, appName))
+	}
+	if cfg.S3Bucket != "" {
+		exporters = append(exporters, auditexport.NewS3Exporter(client, auditexport.S3Config{
+			Bucket:          cfg.S3Bucket,
+			Region:          cfg.S3Region,
+			Prefix:          cfg.S3Prefix,
+			Endpoint:        cfg.S3Endpoint,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			SessionToken:    cfg.S3SessionToken,
+		}))
+	}
+	if cfg.HTTPURL != "" {
+		exporters = append(exporters, auditexport.NewHTTPExporter(client, cfg.HTTPURL, cfg.HTTPToken))
+	}
+
+	streamerConfig := auditexport.DefaultConfig()
+	streamerConfig.QueueSize = cfg.QueueSize
+	streamerConfig.BatchSize = cfg.BatchSize
+	streamerConfig.FlushInterval = cfg.FlushInterval
+	streamerConfig.EnqueueTimeout = cfg.EnqueueTimeout
+
+	return auditexport.NewStreamer(exporters, streamerConfig, slog.Default()), nil
+}
+
+// newReplicationDispatcher replicates refresh token changes to the secondary
+// region, or returns nil when no target is configured
+func newReplicationDispatcher(cfg config.ReplicationConfig) (*replication.Dispatcher, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	var replicators []replication.Replicator
+	if cfg.SecondaryDSN != "" {
+		// Not pinged, so an unavailable secondary region does not block
+		// startup; the dispatcher retries until it is reachable
+		secondary, err := sql.Open("pgx", cfg.SecondaryDSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open secondary database: %w", err)
+		}
+		secondary.SetMaxOpenConns(2)
+		replicators = append(replicators, replication.NewSQLReplicator(secondary))
+	}
+	if cfg.KafkaRESTURL != "" {
+		// Replication is at least once, so posts are retried too
+		clientConfig := httpclient.DefaultConfig()
+		clientConfig.RetryNonIdempotent = true
+		client, err := httpclient.New(clientConfig, slog.Default())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+		}
+		replicators = append(replicators, replication.NewKafkaReplicator(client, cfg.KafkaRESTURL, cfg.KafkaTopic))
+	}
+
+	dispatcherConfig := replication.DefaultConfig()
+	dispatcherConfig.Region = cfg.Region
+	dispatcherConfig.QueueSize = cfg.QueueSize
+	dispatcherConfig.BatchSize = cfg.BatchSize
+	dispatcherConfig.FlushInterval = cfg.FlushInterval
+	dispatcherConfig.EnqueueTimeout = cfg.EnqueueTimeout
+
+	return replication.NewDispatcher(replicators, dispatcherConfig, slog.Default()), nil
+}
+
+// newAdminKeyService manages admin request signing keys, or returns nil when
+// request signing is disabled
+func newAdminKeyService(cfg config.AdminConfig, repo repository.AdminSigningKeyRepository, auditLogRepo repository.AuditLogRepository) *service.AdminKeyService {
+	if cfg.RequestSigningSecret == "" {
+		return nil
+	}
+	keys := service.NewAdminKeyService(repo, cfg.RequestSigningSecret)
+	keys.SetAuditLog(auditLogRepo)
+	return keys
+}
+
+// newServiceAccountService manages service accounts, or returns nil when
+// they are disabled. Assertions may name the token endpoint or the token
+// issuer as their audience.
+func newServiceAccountService(cfg *config.Config, repo repository.ServiceAccountRepository, tokenManager *token.Manager, auditLogRepo repository.AuditLogRepository) *service.ServiceAccountService {
+	if !cfg.Auth.ServiceAccountsEnabled {
+		return nil
+	}
+	audiences := []string{
+		strings.TrimRight(cfg.App.BaseURL, "/") + "/api/v1/auth/service-accounts/token",
+		cfg.JWT.Issuer,
+	}
+	accounts := service.NewServiceAccountService(repo, tokenManager, audiences, cfg.Auth.ServiceAccountAssertionMaxAge)
+	accounts.SetAuditLog(auditLogRepo)
+	return accounts
+}
+
+// newMetrics creates the application metrics with the configured latency
+// buckets and route SLOs
+func newMetrics(cfg config.MetricsConfig) (*metrics.Metrics, error) {
+	buckets, err := cfg.Buckets()
+	if err != nil {
+		return nil, fmt.Errorf("invalid latency buckets: %w", err)
+	}
+	slos, err := cfg.SLOs()
+	if err != nil {
+		return nil, fmt.Errorf("invalid route SLOs: %w", err)
+	}
+
+	metricsConfig := metrics.Config{LatencyBuckets: buckets}
+	if len(slos) > 0 {
+		metricsConfig.SLOs = make(map[string]metrics.SLO, len(slos))
+		for route, slo := range slos {
+			metricsConfig.SLOs[route] = metrics.SLO{Latency: slo.Latency, Objective: slo.Objective}
+		}
+	}
+	return metrics.NewMetricsWithConfig(metricsConfig), nil
+}
+
+// newMetricsServer serves Prometheus metrics on the metrics port, or returns
+// nil when metrics are disabled
+func newMetricsServer(cfg config.MetricsConfig, m *metrics.Metrics) *http.Server {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.PrometheusHandler())
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// newDormancyService creates the inactive account policy. Dormancy notices
+// are sent directly over SMTP so a failed send leaves the account unmarked.
+func newDormancyService(
+	cfg *config.Config,
+	activityRepo repository.AccountActivityRepository,
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	auditLogRepo repository.AuditLogRepository,
+) (*service.DormancyService, error) {
+	policy := service.DormancyPolicy{
+		NotifyAfter:  cfg.Dormancy.NotifyAfter,
+		DisableAfter: cfg.Dormancy.DisableAfter,
+		PurgeAfter:   cfg.Dormancy.PurgeAfter,
+		BatchSize:    cfg.Dormancy.BatchSize,
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid dormancy policy: %w", err)
+	}
+
+	relay, err := smtpConfig(cfg.Email)
+	if err != nil {
+		return nil, err
+	}
+	sender := email.NewSMTPService(relay, slog.Default())
+
+	dormancy := service.NewDormancyService(activityRepo, userRepo, refreshTokenRepo,
+		service.NewEmailDormancyNotifier(sender, cfg), policy)
+	dormancy.SetAuditLog(auditLogRepo)
+	return dormancy, nil
+}
+
+// smtpConfig is the SMTP server every email sender uses
+func smtpConfig(cfg config.EmailConfig) (email.SMTPConfig, error) {
+	relay := email.SMTPConfig{
+		Host:               cfg.SMTPHost,
+		Port:               cfg.SMTPPort,
+		Username:           cfg.SMTPUser,
+		Password:           cfg.SMTPPassword,
+		FromAddress:        cfg.FromAddress,
+		FromName:           cfg.FromName,
+		TLSEnabled:         cfg.TLSEnabled,
+		TLSPolicy:          email.TLSPolicy(cfg.TLSPolicy),
+		InsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+	}
+	if cfg.TLSCAFile != "" {
+		pool, err := readCertPool(cfg.TLSCAFile)
+		if err != nil {
+			return email.SMTPConfig{}, fmt.Errorf("failed to read SMTP CA file: %w", err)
+		}
+		relay.RootCAs = pool
+	}
+	return relay, nil
+}
+
+// secondarySMTPConfig is smtpConfig for the secondary relay, sending from
+// the same address with the same TLS settings
+func secondarySMTPConfig(cfg config.EmailConfig) (email.SMTPConfig, error) {
+	relay, err := smtpConfig(cfg)
+	if err != nil {
+		return email.SMTPConfig{}, err
+	}
+	relay.Host = cfg.SecondarySMTPHost
+	relay.Port = cfg.SecondarySMTPPort
+	relay.Username = cfg.SecondarySMTPUser
+	relay.Password = cfg.SecondarySMTPPassword
+	return relay, nil
+}
+
+// newEmailDispatcher creates the queue that sends verification, password
+// reset and login emails in the background. It has to be started, and
+// stopped once the server no longer accepts requests. A backed-up queue
+// marks readiness degraded, and workers abandoned on stop are counted. With
+// a secondary relay, sends fail over to it while the primary is failing.
+// Sends to each recipient domain are rate limited.
+func newEmailDispatcher(cfg config.EmailConfig, readiness *handlers.Readiness, appMetrics *metrics.Metrics) (*worker.EmailDispatcher, error) {
+	dispatcherConfig := worker.DefaultConfig()
+	dispatcherConfig.Workers = cfg.WorkerCount
+	dispatcherConfig.QueueSize = cfg.QueueSize
+	dispatcherConfig.SnapshotPath = cfg.QueueSnapshotPath
+	dispatcherConfig.Backpressure = worker.BackpressurePolicy(cfg.QueueBackpressure)
+	dispatcherConfig.BlockTimeout = cfg.QueueBlockTimeout
+	dispatcherConfig.HighWatermark = cfg.QueueHighWatermark
+	domainLimits, err := cfg.DomainLimits()
+	if err != nil {
+		return nil, err
+	}
+	dispatcherConfig.DomainRateLimits = worker.DomainRateLimits{
+		PerMinute: cfg.DomainRateLimit,
+		Burst:     cfg.DomainRateBurst,
+		Domains:   domainLimits,
+	}
+	primary, err := smtpConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dispatcher := worker.NewEmailDispatcher(email.NewSMTPService(primary, slog.Default()), dispatcherConfig, slog.Default())
+	if cfg.SecondarySMTPHost != "" {
+		secondary, err := secondarySMTPConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.SetProviders([]worker.EmailProvider{
+			{Name: "primary", Service: email.NewSMTPService(primary, slog.Default())},
+			{Name: "secondary", Service: email.NewSMTPService(secondary, slog.Default())},
+		}, worker.FailoverPolicy{Threshold: cfg.FailoverThreshold, Cooldown: cfg.FailoverCooldown})
+		dispatcher.SetProviderRecorder(appMetrics)
+	}
+
+	if cfg.OutboxDir != "" {
+		outbox, err := worker.NewFileOutbox(cfg.OutboxDir)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.SetOutbox(outbox)
+	}
+	dispatcher.SetWatermarkHandler(func(high bool) {
+		readiness.SetDegraded("email_queue", high)
+	})
+	dispatcher.SetStuckWorkerRecorder(appMetrics)
+	return dispatcher, nil
+}
+
+// newQuotaService creates the monthly quota accounting. Threshold events are
+// posted to the billing webhook when one is configured.
+func newQuotaService(cfg config.QuotaConfig, repo repository.QuotaRepository) (*service.QuotaService, error) {
+	limits, err := cfg.SubjectLimits()
+	if err != nil {
+		return nil, fmt.Errorf("invalid quota limits: %w", err)
+	}
+
+	quotas := service.NewQuotaService(repo, int64(cfg.MonthlyLimit), limits)
+	if cfg.WebhookURL != "" {
+		client, err := httpclient.New(httpclient.DefaultConfig(), slog.Default())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+		}
+		quotas.SetNotifier(service.NewWebhookQuotaNotifier(client, cfg.WebhookURL, cfg.WebhookSecret))
+	}
+	return quotas, nil
+}
+
+// scheduleAuditAnchoring publishes the audit chain head on the configured
+// interval, to the log and the anchor webhook when one is set
+func scheduleAuditAnchoring(scheduler *worker.Scheduler, chain repository.AuditChainRepository, cfg config.AuditChainConfig) error {
+	anchorer := service.NewAuditAnchorer(chain, slog.Default())
+	if cfg.AnchorWebhookURL != "" {
+		client, err := httpclient.New(httpclient.DefaultConfig(), slog.Default())
+		if err != nil {
+			return fmt.Errorf("failed to create HTTP client: %w", err)
+		}
+		anchorer.SetWebhook(client, cfg.AnchorWebhookURL, cfg.AnchorWebhookSecret)
+	}
+	return scheduler.Add(worker.Job{
+		Name:     "audit-anchor",
+		Interval: cfg.AnchorInterval,
+		Run: func(ctx context.Context) error {
+			_, err := anchorer.Anchor(ctx)
+			return err
+		},
+	})
+}
+
+// scheduleIntegrityCheck checks the database for integrity issues on the
+// configured interval, fixing those of the checks in cfg.AutoFix, and logs
+// what it found
+func scheduleIntegrityCheck(scheduler *worker.Scheduler, repo repository.IntegrityRepository, cfg config.IntegrityConfig) error {
+	fix, err := service.ParseIntegrityFixes(cfg.AutoFix)
+	if err != nil {
+		return fmt.Errorf("invalid INTEGRITY_CHECK_AUTO_FIX: %w", err)
+	}
+	checker := service.NewIntegrityChecker(repo)
+	return scheduler.Add(worker.Job{
+		Name:     "integrity-check",
+		Interval: cfg.Interval,
+		Run: func(ctx context.Context) error {
+			report := checker.Check(ctx, fix)
+			for _, finding := range report.Findings {
+				switch {
+				case finding.Error != "":
+					slog.ErrorContext(ctx, "integrity check failed", "check", finding.Check, "error", finding.Error)
+				case finding.Issues > finding.Fixed:
+					slog.WarnContext(ctx, "integrity issues found",
+						"check", finding.Check,
+						"issues", finding.Issues,
+						"fixed", finding.Fixed,
+						"detail", finding.Detail,
+					)
+				case finding.Fixed > 0:
+					slog.InfoContext(ctx, "integrity issues fixed", "check", finding.Check, "fixed", finding.Fixed)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// connectDatabase connects with the DSN in the credentials file when one is
+// configured, and returns the DSN it connected with
+func connectDatabase(cfg config.DatabaseConfig) (*db.DB, string, error) {
+	dsn := cfg.ConnectionString()
+	if cfg.CredentialsFile != "" {
+		var err error
+		if dsn, err = db.ReadCredentials(cfg.CredentialsFile); err != nil {
+			return nil, "", err
+		}
+	}
+	dbPool, err := db.Connect(dsn)
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.CredentialsFile != "" {
+		dbPool.SetDrainTimeout(cfg.CredentialsDrainTimeout)
+	}
+	return dbPool, dsn, nil
+}
+
+// scheduleCredentialRotation swaps in a new connection pool whenever the
+// credentials file holds a new DSN
+func scheduleCredentialRotation(scheduler *worker.Scheduler, dbPool *db.DB, cfg config.DatabaseConfig, dsn string, recorder db.CredentialRotationRecorder) error {
+	rotator := db.NewCredentialRotator(dbPool, cfg.CredentialsFile, dsn, slog.Default())
+	rotator.SetMetrics(recorder)
+	return scheduler.Add(worker.Job{
+		Name:     "db-credential-rotation",
+		Local:    true,
+		Interval: cfg.CredentialsCheckInterval,
+		Run:      rotator.Run,
+	})
+}
+
+// scheduleKeyRollover stages the next signing key right away, so a restart
+// resumes a rollover before serving, and then checks it on the configured
+// interval
+func scheduleKeyRollover(ctx context.Context, scheduler *worker.Scheduler, tokenManager *token.Manager, cfg config.JWTConfig, recorder token.KeyRolloverRecorder) error {
+	rollover := token.NewKeyRollover(tokenManager, cfg.NextPrivateKeyPath, slog.Default())
+	rollover.SetMetrics(recorder)
+	if err := rollover.Run(ctx); err != nil {
+		return fmt.Errorf("failed to stage next signing key: %w", err)
+	}
+	return scheduler.Add(worker.Job{
+		Name:     "jwks-rollover",
+		Local:    true,
+		Interval: cfg.KeyRolloverCheckInterval,
+		Run:      rollover.Run,
+	})
+}
+
+// reloadTokenManager loads the configuration again and swaps the JWT
+// algorithm, secret or key files, issuer and access token lifetime into
+// tokenManager. Other settings still need a restart.
+func reloadTokenManager(tokenManager *token.Manager) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return tokenManager.Reload(token.Config{
+		Algorithm:      cfg.JWT.Algorithm,
+		Secret:         cfg.JWT.Secret,
+		PrivateKeyPath: cfg.JWT.PrivateKeyPath,
+		PublicKeyPath:  cfg.JWT.PublicKeyPath,
+		Issuer:         cfg.JWT.Issuer,
+		AccessTokenTTL: cfg.JWT.AccessTokenTTL,
+		FIPS:           cfg.Crypto.FIPSMode,
+	})
+}
+
+// scheduleDormancy runs the dormancy policy on the configured interval
+func scheduleDormancy(scheduler *worker.Scheduler, dormancy *service.DormancyService, cfg config.DormancyConfig) error {
+	return scheduler.Add(worker.Job{
+		Name:     "dormancy",
+		Interval: cfg.Interval,
+		Run: func(ctx context.Context) error {
+			_, err := dormancy.Run(ctx, cfg.DryRun)
+			return err
+		},
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/config"
+	"github.com/n1rocket/go-auth-jwt/internal/db"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// The verified user DEV_MODE seeds
+const (
+	devUserEmail    = "dev@example.com"
+	devUserPassword = "dev-password"
+)
+
+// migrateDevDatabase applies pending migrations, so DEV_MODE starts against
+// an empty database
+func migrateDevDatabase(sqlDB *sql.DB) error {
+	if err := db.NewMigrator(sqlDB, db.MigrationConfig{}).Up(); err != nil {
+		return err
+	}
+	slog.Info("dev mode: database migrated")
+	return nil
+}
+
+// seedDevData creates the verified dev user through the normal signup and
+// verification path. It is left alone when it already exists.
+func seedDevData(ctx context.Context, authService *service.AuthService) error {
+	output, err := authService.Signup(ctx, service.SignupInput{Email: devUserEmail, Password: devUserPassword})
+	if errors.Is(err, domain.ErrDuplicateEmail) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to seed dev user: %w", err)
+	}
+	if err := authService.VerifyEmail(ctx, service.VerifyEmailInput{Email: devUserEmail, Token: output.EmailVerificationToken}); err != nil {
+		return fmt.Errorf("failed to verify dev user: %w", err)
+	}
+	slog.Info("dev mode: seeded verified user", "email", devUserEmail)
+	return nil
+}
+
+// printDevBanner prints how to reach the dev instance and curl commands to
+// try it with the seeded user and admin token
+func printDevBanner(w io.Writer, cfg *config.Config) {
+	baseURL := strings.TrimRight(cfg.App.BaseURL, "/")
+	fmt.Fprintf(w, `
+Dev mode is on. Do not use it in production.
+
+  API       %[1]s
+  User      %[2]s / %[3]s
+  Admin     Authorization: Bearer %[4]s
+  Emails    http://localhost:8025 (MailHog, when SMTP_HOST is not set)
+
+Log in:
+  curl -s -X POST %[1]s/api/v1/auth/login \
+    -H 'Content-Type: application/json' \
+    -d '{"email":"%[2]s","password":"%[3]s"}'
+
+Fetch the profile with the access_token from the login response:
+  curl -s %[1]s/api/v1/auth/me -H 'Authorization: Bearer <access_token>'
+
+Sign up another user; the verification email shows up in MailHog:
+  curl -s -X POST %[1]s/api/v1/auth/signup \
+    -H 'Content-Type: application/json' \
+    -d '{"email":"jane@example.com","password":"jane-password"}'
+
+Check read-only mode with the admin token:
+  curl -s %[1]s/api/v1/admin/read-only -H 'Authorization: Bearer %[4]s'
+
+`, baseURL, devUserEmail, devUserPassword, cfg.Admin.APIToken)
+}
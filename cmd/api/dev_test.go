@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/config"
+)
+
+func TestPrintDevBanner(t *testing.T) {
+	cfg := &config.Config{
+		App:   config.AppConfig{BaseURL: "http://localhost:8080/"},
+		Admin: config.AdminConfig{APIToken: config.DevAdminToken},
+	}
+
+	var out bytes.Buffer
+	printDevBanner(&out, cfg)
+
+	for _, want := range []string{
+		"curl -s -X POST http://localhost:8080/api/v1/auth/login",
+		`"email":"dev@example.com","password":"dev-password"`,
+		"Authorization: Bearer " + config.DevAdminToken,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("banner is missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -11,15 +11,25 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/auditexport"
 	"github.com/n1rocket/go-auth-jwt/internal/config"
-	"github.com/n1rocket/go-auth-jwt/internal/db"
-	httpserver "github.com/n1rocket/go-auth-jwt/internal/http"
+	"github.com/n1rocket/go-auth-jwt/internal/emailtrack"
+	"github.com/n1rocket/go-auth-jwt/internal/http/handlers"
+	"github.com/n1rocket/go-auth-jwt/internal/http/middleware"
+	"github.com/n1rocket/go-auth-jwt/internal/ids"
+	"github.com/n1rocket/go-auth-jwt/internal/lock"
+	"github.com/n1rocket/go-auth-jwt/internal/replication"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
 	"github.com/n1rocket/go-auth-jwt/internal/repository/postgres"
 	"github.com/n1rocket/go-auth-jwt/internal/security"
 	"github.com/n1rocket/go-auth-jwt/internal/service"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
+	"github.com/n1rocket/go-auth-jwt/internal/worker"
 )
 
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
 	// Initialize structured logger
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
@@ -35,7 +45,7 @@ func main() {
 	}
 
 	// Connect to database
-	dbPool, err := db.Connect(cfg.Database.ConnectionString())
+	dbPool, dsn, err := connectDatabase(cfg.Database)
 	if err != nil {
 		slog.Error("failed to connect to database", "error", err)
 		os.Exit(1)
@@ -50,11 +60,69 @@ func main() {
 		os.Exit(1)
 	}
 	cancel()
+	if cfg.App.DevMode {
+		slog.Warn("dev mode enabled; never use it in production")
+		if err := migrateDevDatabase(dbPool.Pool()); err != nil {
+			slog.Error("failed to migrate database", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	readOnly := middleware.NewReadOnlyMode()
+	if cfg.App.ReadOnly {
+		readOnly.Enable(cfg.App.ReadOnlyReason)
+		slog.Warn("starting in read-only mode", "reason", cfg.App.ReadOnlyReason)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	if err := checkSchema(ctx, cfg.Database, dbPool.Pool(), readOnly); err != nil {
+		cancel()
+		slog.Error("database schema is incompatible with this release", "error", err)
+		os.Exit(1)
+	}
+	cancel()
 
 	// Initialize dependencies
 	userRepo := postgres.NewUserRepository(dbPool)
 	refreshTokenRepo := postgres.NewRefreshTokenRepository(dbPool)
-	passwordHasher := security.NewDefaultPasswordHasher()
+	var auditLogRepo repository.AuditLogRepository = postgres.NewAuditLogRepository(dbPool)
+	var auditChain *postgres.ChainedAuditLogRepository
+	if cfg.AuditChain.Enabled {
+		auditChain = postgres.NewChainedAuditLogRepository(dbPool)
+		auditLogRepo = auditChain
+	}
+	activityRepo := postgres.NewAccountActivityRepository(dbPool)
+	auditStreamer, err := newAuditStreamer(cfg.AuditExport, cfg.App.Name)
+	if err != nil {
+		slog.Error("failed to configure audit export", "error", err)
+		os.Exit(1)
+	}
+	if auditStreamer != nil {
+		auditLogRepo = auditexport.NewRepository(auditLogRepo, auditStreamer)
+	}
+	errorReports, err := newErrorReporter(cfg.ErrorReporting, cfg.App.Environment, version)
+	if err != nil {
+		slog.Error("failed to configure error reporting", "error", err)
+		os.Exit(1)
+	}
+	if cfg.PII.EncryptionKeys != "" {
+		fieldCipher, err := newFieldCipher(cfg.PII)
+		if err != nil {
+			slog.Error("failed to configure PII encryption", "error", err)
+			os.Exit(1)
+		}
+		refreshTokenRepo.SetFieldCipher(fieldCipher)
+	}
+
+	var tokenRepo repository.RefreshTokenRepository = refreshTokenRepo
+	replicator, err := newReplicationDispatcher(cfg.Replication)
+	if err != nil {
+		slog.Error("failed to configure refresh token replication", "error", err)
+		os.Exit(1)
+	}
+	if replicator != nil {
+		tokenRepo = replication.NewRepository(tokenRepo, replicator)
+	}
+	passwordHasher := newPasswordHasher(cfg.Crypto)
 
 	tokenManager, err := token.NewManager(
 		cfg.JWT.Algorithm,
@@ -68,22 +136,340 @@ func main() {
 		slog.Error("failed to create token manager", "error", err)
 		os.Exit(1)
 	}
+	tokenManager.SetClockSkew(cfg.JWT.ClockSkew)
+	tokenManager.SetKeyRolloverWindow(cfg.JWT.KeyRolloverWindow)
+	if cfg.JWT.MaxTokenBytes > 0 {
+		tokenManager.SetClaimsBudget(cfg.JWT.MaxTokenBytes, token.OversizeMode(cfg.JWT.OversizeMode))
+	}
+	if cfg.JWT.DPoPEnabled {
+		tokenManager.EnableDPoP(cfg.JWT.DPoPProofMaxAge)
+	}
+	if cfg.JWT.EncryptionAlgorithm != "" {
+		if err := tokenManager.EnableEncryption(cfg.JWT.EncryptionAlgorithm, cfg.JWT.EncryptionKeyPath); err != nil {
+			slog.Error("failed to enable token encryption", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("access token encryption enabled", "algorithm", cfg.JWT.EncryptionAlgorithm)
+	}
+	if cfg.Crypto.FIPSMode {
+		if err := tokenManager.ValidateFIPS(); err != nil {
+			slog.Error("invalid FIPS configuration", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("FIPS mode enabled")
+	}
 
 	authService := service.NewAuthService(
 		userRepo,
-		refreshTokenRepo,
+		tokenRepo,
 		passwordHasher,
 		tokenManager,
 		cfg.JWT.RefreshTokenTTL,
 	)
+	authService.EnableIDTokens(cfg.JWT.IDTokenAudience)
+	authService.SetMinResponseTime(cfg.Auth.MinResponseTime)
+	authService.SetEnumerationSafe(cfg.Auth.EnumerationSafe)
+	authService.SetRefreshGracePeriod(cfg.JWT.RefreshGracePeriod)
+	authService.SetActivityRecorder(activityRepo)
+	authService.SetAuditLog(auditLogRepo)
+	authService.SetSecurityEvents(postgres.NewAuditLogRepository(dbPool))
+	idGenerator, err := ids.New(ids.Strategy(cfg.Signup.UserIDStrategy))
+	if err != nil {
+		slog.Error("failed to configure user ids", "error", err)
+		os.Exit(1)
+	}
+	authService.SetIDGenerator(idGenerator)
+	if cfg.Signup.TermsVersion != "" {
+		authService.SetConsents(postgres.NewConsentRepository(dbPool), cfg.Signup.TermsVersion)
+	}
+	if cfg.Email.TrackingEnabled {
+		emailTracker := emailtrack.New(cfg.Email.TrackingSecret, cfg.Email.TrackingBaseURL)
+		emailTracker.SetPixel(cfg.Email.TrackingPixel)
+		authService.SetEmailTracking(emailTracker, postgres.NewEmailTrackingOptOutRepository(dbPool))
+	}
+	var tokenVersions repository.TokenVersionRepository
+	if cfg.JWT.TokenVersionCheck {
+		tokenVersions = postgres.NewTokenVersionRepository(dbPool)
+		authService.SetTokenVersions(tokenVersions)
+	}
+	var accessTokenDenylist repository.AccessTokenDenylistRepository
+	if cfg.JWT.AccessTokenDenylist {
+		accessTokenDenylist = postgres.NewAccessTokenDenylistRepository(dbPool)
+		authService.SetAccessTokenDenylist(accessTokenDenylist)
+	}
+	if cfg.Email.CodesEnabled {
+		authService.SetEmailCodes(postgres.NewEmailCodeRepository(dbPool), service.CodePolicy{
+			TTL:         cfg.Email.CodeTTL,
+			MaxAttempts: cfg.Email.CodeMaxAttempts,
+		})
+	}
+	if cfg.SMS.Provider != "" {
+		smsSender, err := newSMSSender(cfg.SMS)
+		if err != nil {
+			slog.Error("failed to create SMS sender", "error", err)
+			os.Exit(1)
+		}
+		authService.SetSMS(smsSender, postgres.NewEmailCodeRepository(dbPool), cfg.App.Name, service.CodePolicy{
+			TTL:         cfg.SMS.CodeTTL,
+			MaxAttempts: cfg.SMS.CodeMaxAttempts,
+		})
+	}
+	if cfg.Auth.LoginApprovalsEnabled {
+		authService.SetLoginApprovals(postgres.NewTrustedDeviceRepository(dbPool), postgres.NewLoginApprovalRepository(dbPool), cfg.Auth.LoginApprovalTTL)
+	}
+	if cfg.Auth.SessionHandoffEnabled {
+		authService.SetSessionHandoff(postgres.NewSessionHandoffRepository(dbPool), cfg.Auth.SessionHandoffTTL)
+	}
+	if cfg.Auth.UserMetadataEnabled {
+		authService.SetUserMetadata(userRepo, cfg.Auth.UserMetadataMaxBytes, cfg.Auth.UserMetadataClaims)
+	}
+	authService.SetSessionStats(refreshTokenRepo)
+	if cfg.Auth.AccessWindowsEnabled {
+		authService.SetAccessWindows(postgres.NewAccessWindowRepository(dbPool))
+	}
+	if cfg.Auth.ClientPasswordHashingEnabled {
+		authService.SetClientPasswordHashing(cfg.Auth.ClientPasswordHashingIterations, []byte(cfg.Auth.ClientPasswordHashingSaltKey))
+	}
+	if cfg.Email.ResendCooldown > 0 || cfg.Email.ResendDailyLimit > 0 {
+		authService.SetVerificationResendLimit(postgres.NewVerificationResendRepository(dbPool), cfg.Email.ResendCooldown, cfg.Email.ResendDailyLimit)
+	}
+	if cfg.Auth.DeviceFlowEnabled {
+		authService.SetDeviceAuthorization(postgres.NewDeviceAuthorizationRepository(dbPool), cfg.Auth.DeviceCodeTTL, cfg.Auth.DeviceVerificationURI)
+	}
+	handlers.BuildVersion = version
+	handlers.FIPSMode = cfg.Crypto.FIPSMode
+
+	if cfg.Risk.Enabled {
+		riskEngine, err := newRiskEngine(cfg.Risk)
+		if err != nil {
+			slog.Error("failed to create risk engine", "error", err)
+			os.Exit(1)
+		}
+		authService.EnableRiskEngine(riskEngine, auditLogRepo)
+
+		captchaVerifier, err := newCaptchaVerifier(cfg.Risk)
+		if err != nil {
+			slog.Error("failed to create captcha verifier", "error", err)
+			os.Exit(1)
+		}
+		if captchaVerifier != nil {
+			authService.SetCaptchaVerifier(captchaVerifier)
+		}
+	}
+
+	if cfg.Avatar.UploadBaseURL != "" {
+		signer, err := security.NewURLSigner(cfg.Avatar.UploadSigningKey)
+		if err != nil {
+			slog.Error("failed to create avatar upload signer", "error", err)
+			os.Exit(1)
+		}
+		authService.SetAvatarUploads(signer, cfg.Avatar.UploadBaseURL, cfg.Avatar.UploadURLTTL)
+	}
+	if cfg.Auth.RememberMeEnabled {
+		authService.SetRememberMe(service.RememberMePolicy{TTL: cfg.Auth.RememberMeTTL, Sliding: cfg.Auth.RememberMeSliding})
+	}
+	if err := configureUserEvents(authService, cfg.UserEvents, postgres.NewUserEventRepository(dbPool)); err != nil {
+		slog.Error("failed to configure user events", "error", err)
+		os.Exit(1)
+	}
+
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	if err := configureEmailPolicy(bgCtx, authService, cfg.Signup); err != nil {
+		slog.Error("failed to configure signup email policy", "error", err)
+		os.Exit(1)
+	}
+
+	var dormancyService *service.DormancyService
+	scheduler := worker.NewScheduler(logger)
+	if cfg.Lock.Backend != "" {
+		locker, err := lock.New(cfg.Lock.Backend, cfg.Lock.RedisURL, dbPool)
+		if err != nil {
+			slog.Error("failed to configure scheduler lock", "error", err)
+			os.Exit(1)
+		}
+		scheduler.SetLocker(locker, cfg.Lock.SchedulerTTL)
+		slog.Info("scheduler leader election enabled", "backend", cfg.Lock.Backend, "ttl", cfg.Lock.SchedulerTTL)
+	}
+	if cfg.Dormancy.Enabled {
+		dormancyService, err = newDormancyService(cfg, activityRepo, userRepo, tokenRepo, auditLogRepo)
+		if err != nil {
+			slog.Error("failed to configure dormancy policy", "error", err)
+			os.Exit(1)
+		}
+		if err := scheduleDormancy(scheduler, dormancyService, cfg.Dormancy); err != nil {
+			slog.Error("failed to schedule dormancy job", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("dormancy policy enabled", "dry_run", cfg.Dormancy.DryRun)
+	}
+	if auditChain != nil {
+		if err := scheduleAuditAnchoring(scheduler, auditChain, cfg.AuditChain); err != nil {
+			slog.Error("failed to schedule audit anchoring", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("audit hash chain enabled", "anchor_interval", cfg.AuditChain.AnchorInterval)
+	}
+	if cfg.Integrity.Enabled {
+		if err := scheduleIntegrityCheck(scheduler, postgres.NewIntegrityRepository(dbPool), cfg.Integrity); err != nil {
+			slog.Error("failed to schedule integrity check", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("integrity check enabled", "interval", cfg.Integrity.Interval, "auto_fix", cfg.Integrity.AutoFix)
+	}
+
+	var quotaService *service.QuotaService
+	if cfg.Quota.Enabled {
+		quotaService, err = newQuotaService(cfg.Quota, postgres.NewQuotaRepository(dbPool))
+		if err != nil {
+			slog.Error("failed to configure quotas", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("request quotas enabled", "monthly_limit", cfg.Quota.MonthlyLimit, "webhook", cfg.Quota.WebhookURL != "")
+	}
+
+	appMetrics, err := newMetrics(cfg.Metrics)
+	if err != nil {
+		slog.Error("failed to configure metrics", "error", err)
+		os.Exit(1)
+	}
+	authService.SetResendRecorder(appMetrics)
+	authService.SetAuthRecorder(appMetrics)
+	if emailTracker := authService.EmailTracker(); emailTracker != nil {
+		emailTracker.SetRecorder(appMetrics)
+	}
+	if err := configureRollout(authService, cfg.Rollout, appMetrics); err != nil {
+		slog.Error("failed to configure rollout flags", "error", err)
+		os.Exit(1)
+	}
+	defer appMetrics.Stop()
+
+	if cfg.JWT.NextPrivateKeyPath != "" {
+		if err := scheduleKeyRollover(bgCtx, scheduler, tokenManager, cfg.JWT, appMetrics.SigningKeys); err != nil {
+			slog.Error("failed to configure signing key rollover", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("signing key rollover enabled", "window", cfg.JWT.KeyRolloverWindow)
+	}
+	if cfg.Database.CredentialsFile != "" {
+		if err := scheduleCredentialRotation(scheduler, dbPool, cfg.Database, dsn, appMetrics.Database); err != nil {
+			slog.Error("failed to configure database credential rotation", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("database credential rotation enabled", "file", cfg.Database.CredentialsFile, "interval", cfg.Database.CredentialsCheckInterval)
+	}
+	scheduler.Start(bgCtx)
+	defer scheduler.Stop()
+
+	if cfg.Crypto.HashWorkers > 0 {
+		hashPool := newHashPool(cfg.Crypto, appMetrics.Hashing)
+		defer hashPool.Stop()
+		passwordHasher.SetPool(hashPool)
+		slog.Info("password hashing pool enabled", "workers", cfg.Crypto.HashWorkers, "queue_size", cfg.Crypto.HashQueueSize, "max_wait", cfg.Crypto.HashMaxWait)
+	}
+
+	if auditStreamer != nil {
+		auditStreamer.SetMetrics(appMetrics.AuditExport)
+		auditStreamer.Start()
+		defer func() {
+			if err := auditStreamer.Stop(cfg.App.ShutdownTimeout); err != nil {
+				slog.Error("failed to flush audit exporters", "error", err)
+			}
+		}()
+	}
+	if replicator != nil {
+		replicator.Start()
+		defer func() {
+			if err := replicator.Stop(cfg.App.ShutdownTimeout); err != nil {
+				slog.Error("failed to flush refresh token replicators", "error", err)
+			}
+		}()
+		slog.Info("refresh token replication enabled", "region", cfg.Replication.Region)
+	}
+	if errorReports != nil {
+		errorReports.Start()
+		defer func() {
+			if err := errorReports.Stop(cfg.App.ShutdownTimeout); err != nil {
+				slog.Error("failed to flush error reports", "error", err)
+			}
+		}()
+		slog.Info("error reporting enabled", "sample_rate", cfg.ErrorReporting.SampleRate)
+	}
+
+	// Deferred after the server so queued emails are sent or snapshotted
+	// once no request can add to the queue
+	readiness := handlers.NewReadiness()
+	var emailDispatcher *worker.EmailDispatcher
+	var emailService *service.AuthServiceWithEmail
+	if cfg.Email.DeliveryEnabled {
+		emailDispatcher, err = newEmailDispatcher(cfg.Email, readiness, appMetrics)
+		if err != nil {
+			slog.Error("failed to create email dispatcher", "error", err)
+			os.Exit(1)
+		}
+		emailDispatcher.Start()
+		defer func() {
+			if err := emailDispatcher.Stop(cfg.App.ShutdownTimeout); err != nil {
+				slog.Error("failed to stop email dispatcher", "error", err)
+			}
+		}()
+		emailService = service.NewAuthServiceWithEmail(authService, emailDispatcher, cfg, slog.Default())
+	} else {
+		slog.Warn("email delivery disabled; verification and reset emails are not sent")
+	}
+	if cfg.App.DevMode {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := seedDevData(ctx, authService)
+		cancel()
+		if err != nil {
+			slog.Error("failed to seed dev data", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	routes, err := newRoutes(cfg, authService, tokenManager, routeServices{
+		dormancy:  dormancyService,
+		adminKeys: newAdminKeyService(cfg.Admin, postgres.NewAdminSigningKeyRepository(dbPool), auditLogRepo),
+		quota:     quotaService,
+		metrics:   appMetrics,
+		readOnly:  readOnly,
+
+		serviceAccounts: newServiceAccountService(cfg, postgres.NewServiceAccountRepository(dbPool), tokenManager, auditLogRepo),
+
+		tokenVersions:       tokenVersions,
+		accessTokenDenylist: accessTokenDenylist,
+		errorReports:        errorReports,
+		emails:              emailService,
+		readiness:           readiness,
+
+		database:   dbPool,
+		emailQueue: emailDispatcher,
+		scheduler:  scheduler,
+	})
+	if err != nil {
+		slog.Error("failed to configure routes", "error", err)
+		os.Exit(1)
+	}
 
 	// Create HTTP server
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.App.Port),
-		Handler:      httpserver.Routes(authService, tokenManager),
-		ReadTimeout:  cfg.App.ReadTimeout,
-		WriteTimeout: cfg.App.WriteTimeout,
-		IdleTimeout:  cfg.App.IdleTimeout,
+		Addr:              fmt.Sprintf(":%d", cfg.App.Port),
+		Handler:           routes,
+		ReadTimeout:       cfg.App.ReadTimeout,
+		ReadHeaderTimeout: cfg.App.ReadHeaderTimeout,
+		WriteTimeout:      cfg.App.WriteTimeout,
+		IdleTimeout:       cfg.App.IdleTimeout,
+		MaxHeaderBytes:    cfg.App.MaxHeaderBytes,
+	}
+
+	srv.TLSConfig, err = newTLSConfig(cfg.App)
+	if err != nil {
+		slog.Error("failed to configure TLS", "error", err)
+		os.Exit(1)
+	}
+
+	if cfg.App.DevMode {
+		printDevBanner(os.Stdout, cfg)
 	}
 
 	// Start server in a goroutine
@@ -92,8 +478,35 @@ func main() {
 		slog.Info("starting HTTP server",
 			"port", cfg.App.Port,
 			"environment", cfg.App.Environment,
+			"tls", cfg.App.TLSCertFile != "",
+			"mtls", cfg.App.TLSClientCAFile != "",
 		)
-		serverErrors <- srv.ListenAndServe()
+		serverErrors <- serve(srv, cfg.App)
+	}()
+
+	// Metrics are served on their own port so they are not exposed publicly
+	metricsSrv := newMetricsServer(cfg.Metrics, appMetrics)
+	if metricsSrv != nil {
+		go func() {
+			slog.Info("starting metrics server", "port", cfg.Metrics.Port)
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("metrics server error", "error", err)
+			}
+		}()
+		defer metricsSrv.Close()
+	}
+
+	// Reload the JWT settings on SIGHUP, e.g. after replacing the key files
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := reloadTokenManager(tokenManager); err != nil {
+				slog.Error("failed to reload JWT settings", "error", err)
+				continue
+			}
+			slog.Info("JWT settings reloaded")
+		}
 	}()
 
 	// Wait for interrupt signal or server error
@@ -0,0 +1,85 @@
+// Command authctl is the operator CLI of the auth service.
+//
+//	authctl config validate --file config.yaml
+//	authctl config schema > config.schema.json
+//
+// A config file is a YAML mapping of the environment variables the service
+// reads to their values. Lists may be given as comma-separated strings or
+// YAML sequences.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/n1rocket/go-auth-jwt/internal/config"
+)
+
+const usage = `Usage:
+  authctl config validate --file <path>  Check a config file against the schema and the service's own validation
+  authctl config schema                  Print the JSON Schema of config files
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command in args and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) < 2 || args[0] != "config" {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[1] {
+	case "validate":
+		flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+		flags.SetOutput(stderr)
+		path := flags.String("file", "", "YAML config file to validate")
+		if err := flags.Parse(args[2:]); err != nil {
+			return 2
+		}
+		if *path == "" {
+			fmt.Fprintln(stderr, "config validate: --file is required")
+			return 2
+		}
+		return validateFile(*path, stdout, stderr)
+
+	case "schema":
+		if _, err := stdout.Write(config.JSONSchema); err != nil {
+			fmt.Fprintf(stderr, "failed to write schema: %v\n", err)
+			return 1
+		}
+		return 0
+
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+}
+
+// validateFile reports the problems of the config file at path
+func validateFile(path string, stdout, stderr io.Writer) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read config file: %v\n", err)
+		return 1
+	}
+
+	problems, err := Validate(data)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to validate config file: %v\n", err)
+		return 1
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintln(stderr, p.Format(path))
+		}
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%s: OK\n", path)
+	return 0
+}
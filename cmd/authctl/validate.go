@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/n1rocket/go-auth-jwt/internal/config"
+)
+
+// Problem is something wrong with a config file
+type Problem struct {
+	Line    int    // 1-based; 0 when the problem is not tied to a line
+	Setting string // variable name; empty for file-level problems
+	Message string
+}
+
+// Format returns the problem as path:line: SETTING: message
+func (p Problem) Format(path string) string {
+	location := path
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s:%d", path, p.Line)
+	}
+	if p.Setting == "" {
+		return fmt.Sprintf("%s: %s", location, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, p.Setting, p.Message)
+}
+
+// setting is a variable in the config JSON Schema
+type setting struct {
+	Types   []string
+	Pattern *regexp.Regexp
+}
+
+// accepts reports whether the setting's schema allows typ
+func (s setting) accepts(typ string) bool {
+	for _, t := range s.Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSchema returns the settings of the embedded config JSON Schema
+func loadSchema() (map[string]setting, error) {
+	var schema struct {
+		Properties map[string]struct {
+			Type    json.RawMessage `json:"type"`
+			Pattern string          `json:"pattern"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(config.JSONSchema, &schema); err != nil {
+		return nil, fmt.Errorf("invalid config schema: %w", err)
+	}
+
+	settings := make(map[string]setting, len(schema.Properties))
+	for name, property := range schema.Properties {
+		var s setting
+		if err := json.Unmarshal(property.Type, &s.Types); err != nil {
+			var typ string
+			if err := json.Unmarshal(property.Type, &typ); err != nil {
+				return nil, fmt.Errorf("invalid type of %s in config schema: %w", name, err)
+			}
+			s.Types = []string{typ}
+		}
+		if property.Pattern != "" {
+			pattern, err := regexp.Compile(property.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern of %s in config schema: %w", name, err)
+			}
+			s.Pattern = pattern
+		}
+		settings[name] = s
+	}
+	return settings, nil
+}
+
+// Validate checks a YAML config file: every key must be a known setting
+// with a value of its type, and the resulting environment must pass the
+// service's own config validation. It returns an error only when the
+// embedded schema is unusable.
+func Validate(data []byte) ([]Problem, error) {
+	settings, err := loadSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []Problem{{Message: fmt.Sprintf("invalid YAML: %v", err)}}, nil
+	}
+
+	env := make(map[string]string)
+	lines := make(map[string]int)
+	var problems []Problem
+
+	if len(doc.Content) > 0 {
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return []Problem{{Line: root.Line, Message: "must be a mapping of setting names to values"}}, nil
+		}
+
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				problems = append(problems, Problem{Line: key.Line, Message: "setting names must be strings"})
+				continue
+			}
+
+			name := key.Value
+			if line, ok := lines[name]; ok {
+				problems = append(problems, Problem{Line: key.Line, Setting: name, Message: fmt.Sprintf("already set on line %d", line)})
+				continue
+			}
+			lines[name] = key.Line
+
+			s, ok := settings[name]
+			if !ok {
+				problems = append(problems, Problem{Line: key.Line, Setting: name, Message: "unknown setting"})
+				continue
+			}
+
+			envValue, err := settingValue(value, s)
+			if err != nil {
+				problems = append(problems, Problem{Line: value.Line, Setting: name, Message: err.Error()})
+				continue
+			}
+			if envValue != "" {
+				env[name] = envValue
+			}
+		}
+	}
+
+	// Values the service would silently replace with defaults fail above,
+	// so only check the combined configuration once each value is valid
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	if err := loadConfig(settings, env); err != nil {
+		name := mentionedSetting(err.Error(), lines)
+		return []Problem{{Line: lines[name], Message: err.Error()}}, nil
+	}
+	return nil, nil
+}
+
+// settingValue returns the environment value of a YAML value, checked
+// against the setting's schema
+func settingValue(node *yaml.Node, s setting) (string, error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return "", nil
+		}
+		value := node.Value
+		switch {
+		case s.accepts("integer"):
+			if _, err := strconv.Atoi(value); err != nil {
+				return "", fmt.Errorf("must be an integer, got %q", value)
+			}
+		case s.accepts("number"):
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return "", fmt.Errorf("must be a number, got %q", value)
+			}
+		case s.accepts("boolean"):
+			if _, err := strconv.ParseBool(value); err != nil {
+				return "", fmt.Errorf("must be true or false, got %q", value)
+			}
+		case s.Pattern != nil:
+			if !s.Pattern.MatchString(value) {
+				return "", fmt.Errorf("%q does not match %s", value, s.Pattern)
+			}
+		}
+		return value, nil
+
+	case yaml.SequenceNode:
+		if !s.accepts("array") {
+			return "", fmt.Errorf("must be a %s, not a list", strings.Join(s.Types, " or "))
+		}
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("list items must be strings")
+			}
+			if strings.Contains(item.Value, ",") {
+				return "", fmt.Errorf("list item %q must not contain a comma", item.Value)
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, ","), nil
+
+	default:
+		return "", fmt.Errorf("must be a %s, not a mapping", strings.Join(s.Types, " or "))
+	}
+}
+
+// loadConfig runs config.Load with exactly the settings in env, restoring
+// the process environment afterwards
+func loadConfig(settings map[string]setting, env map[string]string) error {
+	for name := range settings {
+		previous, wasSet := os.LookupEnv(name)
+		if value, ok := env[name]; ok {
+			os.Setenv(name, value)
+		} else {
+			os.Unsetenv(name)
+		}
+		defer func(name string) {
+			if wasSet {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+		}(name)
+	}
+
+	_, err := config.Load()
+	return err
+}
+
+// mentionedSetting returns the setting of the file named first in msg, so a
+// config error can point at its line; empty when none is named
+func mentionedSetting(msg string, lines map[string]int) string {
+	found, at := "", len(msg)
+	for name := range lines {
+		i := strings.Index(msg, name)
+		if i < 0 {
+			continue
+		}
+		// JWT_SECRET is also found inside a longer name starting at the same place
+		if i < at || (i == at && len(name) > len(found)) {
+			found, at = name, i
+		}
+	}
+	return found
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variable types in the schema. Durations and lists are strings in the
+// environment; lists may also be YAML sequences in a config file.
+const (
+	TypeString   = "string"
+	TypeInteger  = "integer"
+	TypeNumber   = "number"
+	TypeBoolean  = "boolean"
+	TypeDuration = "duration"
+	TypeList     = "list"
+)
+
+// DurationPattern matches the values time.ParseDuration accepts
+const DurationPattern = `^[-+]?(0|([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+
+// helperTypes are the types read by the config loading helpers
+var helperTypes = map[string]string{
+	"getEnvOrDefault":        TypeString,
+	"getEnvOrError":          TypeString,
+	"parseIntOrDefault":      TypeInteger,
+	"parseFloatOrDefault":    TypeNumber,
+	"parseBoolOrDefault":     TypeBoolean,
+	"parseDurationOrDefault": TypeDuration,
+	"parseListOrDefault":     TypeList,
+}
+
+// Variable is an environment variable the configuration reads
+type Variable struct {
+	Name    string
+	Type    string
+	Default interface{} // nil when the default is empty or not a constant
+}
+
+// Property is a JSON Schema property
+type Property struct {
+	Type        interface{}     `json:"type"`
+	Description string          `json:"description,omitempty"`
+	Default     json.RawMessage `json:"default,omitempty"`
+	Pattern     string          `json:"pattern,omitempty"`
+	Items       *Property       `json:"items,omitempty"`
+}
+
+// Schema is the JSON Schema of a config file
+type Schema struct {
+	Schema               string               `json:"$schema"`
+	Comment              string               `json:"$comment"`
+	Title                string               `json:"title"`
+	Description          string               `json:"description"`
+	Type                 string               `json:"type"`
+	Properties           map[string]*Property `json:"properties"`
+	AdditionalProperties bool                 `json:"additionalProperties"`
+}
+
+// Generate returns the JSON Schema of the variables read by the config
+// source, described by the readme
+func Generate(source, readme []byte) ([]byte, error) {
+	variables, err := Variables(source)
+	if err != nil {
+		return nil, err
+	}
+	descriptions := Descriptions(readme)
+
+	schema := Schema{
+		Schema:      "https://json-schema.org/draft/2020-12/schema",
+		Comment:     "Generated by cmd/configschemagen from internal/config/config.go and README.md; do not edit",
+		Title:       "go-auth-jwt configuration",
+		Description: "Environment variables configuring the service, as a mapping of variable names to values",
+		Type:        "object",
+		Properties:  make(map[string]*Property, len(variables)),
+	}
+	for _, v := range variables {
+		description, ok := descriptions[v.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not documented in the README configuration tables", v.Name)
+		}
+		property, err := newProperty(v, description)
+		if err != nil {
+			return nil, err
+		}
+		schema.Properties[v.Name] = property
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// newProperty describes v in JSON Schema
+func newProperty(v Variable, description string) (*Property, error) {
+	p := &Property{Type: v.Type, Description: description}
+	defaultValue := v.Default
+	switch v.Type {
+	case TypeDuration:
+		p.Type = TypeString
+		p.Pattern = DurationPattern
+		p.Description += " (a duration such as 30s, 15m or 24h)"
+		if n, ok := defaultValue.(int64); ok {
+			defaultValue = time.Duration(n)
+		}
+		if d, ok := defaultValue.(time.Duration); ok {
+			defaultValue = d.String()
+		}
+	case TypeList:
+		p.Type = []string{TypeString, "array"}
+		p.Items = &Property{Type: TypeString}
+	case TypeNumber:
+		if n, ok := defaultValue.(int64); ok {
+			defaultValue = float64(n)
+		}
+	}
+
+	if defaultValue != nil {
+		raw, err := json.Marshal(defaultValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default of %s: %w", v.Name, err)
+		}
+		p.Default = raw
+	}
+	return p, nil
+}
+
+// Variables returns the variables read by the config source, in the order
+// they are first read
+func Variables(source []byte) ([]Variable, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "config.go", source, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config source: %w", err)
+	}
+	policies := stringSlice(file, "RateLimitPolicies")
+
+	var variables []Variable
+	seen := make(map[string]int)
+	add := func(v Variable, pos token.Pos) error {
+		if i, ok := seen[v.Name]; ok {
+			if variables[i].Type != v.Type {
+				return fmt.Errorf("%s: %s is read as both %s and %s", fset.Position(pos), v.Name, variables[i].Type, v.Type)
+			}
+			return nil
+		}
+		seen[v.Name] = len(variables)
+		variables = append(variables, v)
+		return nil
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 || err != nil {
+			return err == nil
+		}
+
+		varType, isHelper := "", false
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			varType, isHelper = helperTypes[fun.Name]
+		case *ast.SelectorExpr:
+			if pkg, ok := fun.X.(*ast.Ident); ok && pkg.Name == "os" && fun.Sel.Name == "Getenv" {
+				varType, isHelper = TypeString, true
+			}
+		}
+		if !isHelper {
+			return true
+		}
+
+		// os.Getenv(rateLimitKeyEnv(policy)) reads one variable per policy
+		if inner, ok := call.Args[0].(*ast.CallExpr); ok {
+			if fun, ok := inner.Fun.(*ast.Ident); ok && fun.Name == "rateLimitKeyEnv" {
+				for _, policy := range policies {
+					name := "RATE_LIMIT_" + strings.ToUpper(policy) + "_KEY"
+					if err = add(Variable{Name: name, Type: varType}, call.Pos()); err != nil {
+						return false
+					}
+				}
+			}
+			return true
+		}
+
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		name, unquoteErr := strconv.Unquote(lit.Value)
+		if unquoteErr != nil {
+			return true
+		}
+		v := Variable{Name: name, Type: varType}
+		if len(call.Args) > 1 {
+			v.Default = constantValue(call.Args[1])
+		}
+		if s, ok := v.Default.(string); ok && s == "" {
+			v.Default = nil
+		}
+		err = add(v, call.Pos())
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// stringSlice returns the elements of the package-level []string literal
+// assigned to name
+func stringSlice(file *ast.File, name string) []string {
+	var values []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != name || len(spec.Values) != 1 {
+			return true
+		}
+		if list, ok := constantValue(spec.Values[0]).([]string); ok {
+			values = list
+		}
+		return false
+	})
+	return values
+}
+
+// timeUnits are the time package durations defaults are written with
+var timeUnits = map[string]time.Duration{
+	"Nanosecond":  time.Nanosecond,
+	"Microsecond": time.Microsecond,
+	"Millisecond": time.Millisecond,
+	"Second":      time.Second,
+	"Minute":      time.Minute,
+	"Hour":        time.Hour,
+}
+
+// constantValue evaluates the constant expressions defaults are written
+// with: literals, booleans, time units, products of those and string slice
+// literals. It returns nil for anything else.
+func constantValue(expr ast.Expr) interface{} {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return constantValue(e.X)
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			n, err := strconv.ParseInt(e.Value, 0, 64)
+			if err == nil {
+				return n
+			}
+		case token.FLOAT:
+			f, err := strconv.ParseFloat(e.Value, 64)
+			if err == nil {
+				return f
+			}
+		case token.STRING:
+			s, err := strconv.Unquote(e.Value)
+			if err == nil {
+				return s
+			}
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == "time" {
+			if unit, ok := timeUnits[e.Sel.Name]; ok {
+				return unit
+			}
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.SUB {
+			switch v := constantValue(e.X).(type) {
+			case int64:
+				return -v
+			case float64:
+				return -v
+			case time.Duration:
+				return -v
+			}
+		}
+	case *ast.BinaryExpr:
+		if e.Op != token.MUL {
+			return nil
+		}
+		x, y := constantValue(e.X), constantValue(e.Y)
+		switch a := x.(type) {
+		case int64:
+			switch b := y.(type) {
+			case int64:
+				return a * b
+			case time.Duration:
+				return time.Duration(a) * b
+			}
+		case time.Duration:
+			if b, ok := y.(int64); ok {
+				return a * time.Duration(b)
+			}
+		}
+	case *ast.CompositeLit:
+		if arr, ok := e.Type.(*ast.ArrayType); ok {
+			if elt, ok := arr.Elt.(*ast.Ident); ok && elt.Name == "string" {
+				values := []string{}
+				for _, item := range e.Elts {
+					s, ok := constantValue(item).(string)
+					if !ok {
+						return nil
+					}
+					values = append(values, s)
+				}
+				return values
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	tableRow     = regexp.MustCompile("^\\|\\s*`([A-Z][A-Z0-9_]*)`\\s*\\|")
+	markdownLink = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+)
+
+// Descriptions returns the description of each variable in the readme's
+// configuration tables, as plain text
+func Descriptions(readme []byte) map[string]string {
+	descriptions := make(map[string]string)
+	for _, line := range strings.Split(string(readme), "\n") {
+		match := tableRow.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if _, ok := descriptions[match[1]]; ok {
+			continue
+		}
+		cells := splitRow(line)
+		if len(cells) < 2 {
+			continue
+		}
+		descriptions[match[1]] = plainText(cells[1])
+	}
+	return descriptions
+}
+
+// splitRow splits a Markdown table row into trimmed cells, keeping escaped
+// pipes
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// plainText drops Markdown links, code spans and footnote markers
+func plainText(s string) string {
+	s = markdownLink.ReplaceAllString(s, "$1")
+	s = strings.ReplaceAll(s, `\*`, "")
+	s = strings.ReplaceAll(s, "`", "")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSource = `package config
+
+var RateLimitPolicies = []string{"auth", "api"}
+
+func Load() (*Config, error) {
+	cfg := &Config{
+		Port:     parseIntOrDefault("APP_PORT", 8080),
+		Name:     getEnvOrDefault("APP_NAME", "Auth Service"),
+		DSN:      getEnvOrError("DB_DSN"),
+		Timeout:  parseDurationOrDefault("APP_READ_TIMEOUT", 15*time.Second),
+		Lifetime: parseDurationOrDefault("DB_CONN_MAX_LIFETIME", time.Minute*5),
+		Grace:    parseDurationOrDefault("JWT_REFRESH_GRACE_PERIOD", 0),
+		Metrics:  parseBoolOrDefault("METRICS_ENABLED", true),
+		Ratio:    parseFloatOrDefault("EMAIL_QUEUE_HIGH_WATERMARK", 0.8),
+		Rate:     parseFloatOrDefault("ERROR_REPORTING_SAMPLE_RATE", 1),
+		Domains:  parseListOrDefault("SIGNUP_ALLOWED_EMAIL_DOMAINS", nil),
+		Hints:    parseListOrDefault("APP_EARLY_HINTS", []string{"a", "b"}),
+		Secret:   os.Getenv("JWT_SECRET"),
+	}
+	for _, policy := range RateLimitPolicies {
+		_ = os.Getenv(rateLimitKeyEnv(policy))
+	}
+	_ = getEnvOrDefault("APP_NAME", "ignored")
+	return cfg, nil
+}
+
+func parseIntOrDefault(key string, defaultValue int) int {
+	_ = os.Getenv(key)
+	return defaultValue
+}
+`
+
+const testReadme = "| Variable | Description | Default | Required |\n" +
+	"| -------- | ----------- | ------- | -------- |\n" +
+	"| `APP_PORT` | API server port | `8080` | No |\n" +
+	"| `APP_NAME` | Service name | `Auth Service` | No |\n" +
+	"| `DB_DSN` | PostgreSQL connection string | - | Yes |\n" +
+	"| `APP_READ_TIMEOUT` | HTTP read timeout | `15s` | No |\n" +
+	"| `DB_CONN_MAX_LIFETIME` | Connection maximum lifetime | `5m` | No |\n" +
+	"| `JWT_REFRESH_GRACE_PERIOD` | Refresh grace, see [rotation](#rotation) | `0` | No |\n" +
+	"| `METRICS_ENABLED` | Enable `/metrics` | `true` | No |\n" +
+	"| `EMAIL_QUEUE_HIGH_WATERMARK` | Queue fill (fraction) | `0.8` | No |\n" +
+	"| `ERROR_REPORTING_SAMPLE_RATE` | Share of errors reported | `1.0` | No |\n" +
+	"| `SIGNUP_ALLOWED_EMAIL_DOMAINS` | Allowed domains a \\| b | - | No |\n" +
+	"| `APP_EARLY_HINTS` | Link headers | - | No |\n" +
+	"| `JWT_SECRET` | HS256 secret key | - | Conditional\\* |\n" +
+	"| `RATE_LIMIT_AUTH_KEY` | Auth rate limit key | `ip` | No |\n" +
+	"| `RATE_LIMIT_API_KEY` | API rate limit key | `ip` | No |\n" +
+	"| `APP_PORT` | Duplicate row | - | No |\n"
+
+func TestVariables(t *testing.T) {
+	variables, err := Variables([]byte(testSource))
+	if err != nil {
+		t.Fatalf("Variables() error = %v", err)
+	}
+
+	want := []Variable{
+		{Name: "APP_PORT", Type: TypeInteger, Default: int64(8080)},
+		{Name: "APP_NAME", Type: TypeString, Default: "Auth Service"},
+		{Name: "DB_DSN", Type: TypeString},
+		{Name: "APP_READ_TIMEOUT", Type: TypeDuration, Default: 15 * time.Second},
+		{Name: "DB_CONN_MAX_LIFETIME", Type: TypeDuration, Default: 5 * time.Minute},
+		{Name: "JWT_REFRESH_GRACE_PERIOD", Type: TypeDuration, Default: int64(0)},
+		{Name: "METRICS_ENABLED", Type: TypeBoolean, Default: true},
+		{Name: "EMAIL_QUEUE_HIGH_WATERMARK", Type: TypeNumber, Default: 0.8},
+		{Name: "ERROR_REPORTING_SAMPLE_RATE", Type: TypeNumber, Default: int64(1)},
+		{Name: "SIGNUP_ALLOWED_EMAIL_DOMAINS", Type: TypeList},
+		{Name: "APP_EARLY_HINTS", Type: TypeList, Default: []string{"a", "b"}},
+		{Name: "JWT_SECRET", Type: TypeString},
+		{Name: "RATE_LIMIT_AUTH_KEY", Type: TypeString},
+		{Name: "RATE_LIMIT_API_KEY", Type: TypeString},
+	}
+	if len(variables) != len(want) {
+		t.Fatalf("Variables() = %d variables, want %d: %+v", len(variables), len(want), variables)
+	}
+	for i, v := range variables {
+		if v.Name != want[i].Name || v.Type != want[i].Type {
+			t.Errorf("variable %d = %s (%s), want %s (%s)", i, v.Name, v.Type, want[i].Name, want[i].Type)
+		}
+		if !reflect.DeepEqual(v.Default, want[i].Default) {
+			t.Errorf("%s default = %#v, want %#v", v.Name, v.Default, want[i].Default)
+		}
+	}
+}
+
+func TestVariables_ConflictingTypes(t *testing.T) {
+	source := `package config
+
+func Load() {
+	_ = parseIntOrDefault("APP_PORT", 8080)
+	_ = getEnvOrDefault("APP_PORT", "8080")
+}
+`
+	_, err := Variables([]byte(source))
+	if err == nil || !strings.Contains(err.Error(), "APP_PORT is read as both integer and string") {
+		t.Errorf("Variables() error = %v, want conflicting types", err)
+	}
+}
+
+func TestDescriptions(t *testing.T) {
+	descriptions := Descriptions([]byte(testReadme))
+
+	tests := map[string]string{
+		"APP_PORT":                     "API server port",
+		"JWT_REFRESH_GRACE_PERIOD":     "Refresh grace, see rotation",
+		"METRICS_ENABLED":              "Enable /metrics",
+		"SIGNUP_ALLOWED_EMAIL_DOMAINS": "Allowed domains a | b",
+	}
+	for name, want := range tests {
+		if got := descriptions[name]; got != want {
+			t.Errorf("description of %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	out, err := Generate([]byte(testSource), []byte(testReadme))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	var schema struct {
+		Type                 string                            `json:"type"`
+		AdditionalProperties bool                              `json:"additionalProperties"`
+		Properties           map[string]map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(out, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if schema.Type != "object" || schema.AdditionalProperties {
+		t.Errorf("schema type = %q, additionalProperties = %v", schema.Type, schema.AdditionalProperties)
+	}
+
+	tests := []struct {
+		name        string
+		wantType    interface{}
+		wantDefault interface{}
+	}{
+		{"APP_PORT", "integer", float64(8080)},
+		{"DB_DSN", "string", nil},
+		{"APP_READ_TIMEOUT", "string", "15s"},
+		{"DB_CONN_MAX_LIFETIME", "string", "5m0s"},
+		{"JWT_REFRESH_GRACE_PERIOD", "string", "0s"},
+		{"METRICS_ENABLED", "boolean", true},
+		{"ERROR_REPORTING_SAMPLE_RATE", "number", float64(1)},
+		{"SIGNUP_ALLOWED_EMAIL_DOMAINS", []interface{}{"string", "array"}, nil},
+		{"APP_EARLY_HINTS", []interface{}{"string", "array"}, []interface{}{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			property, ok := schema.Properties[tt.name]
+			if !ok {
+				t.Fatalf("%s is missing from the schema", tt.name)
+			}
+			if !reflect.DeepEqual(property["type"], tt.wantType) {
+				t.Errorf("type = %v, want %v", property["type"], tt.wantType)
+			}
+			if !reflect.DeepEqual(property["default"], tt.wantDefault) {
+				t.Errorf("default = %#v, want %#v", property["default"], tt.wantDefault)
+			}
+		})
+	}
+
+	if pattern := schema.Properties["APP_READ_TIMEOUT"]["pattern"]; pattern != DurationPattern {
+		t.Errorf("duration pattern = %v, want %s", pattern, DurationPattern)
+	}
+}
+
+func TestGenerate_Undocumented(t *testing.T) {
+	readme := strings.Replace(testReadme, "| `DB_DSN` |", "| DB_DSN |", 1)
+
+	_, err := Generate([]byte(testSource), []byte(readme))
+	if err == nil || !strings.Contains(err.Error(), "DB_DSN is not documented") {
+		t.Errorf("Generate() error = %v, want undocumented variable", err)
+	}
+}
+
+// TestGenerate_CommittedSchema fails when the committed schema is stale
+func TestGenerate_CommittedSchema(t *testing.T) {
+	source, err := os.ReadFile("../../internal/config/config.go")
+	if err != nil {
+		t.Skipf("config source not available: %v", err)
+	}
+	readme, err := os.ReadFile("../../README.md")
+	if err != nil {
+		t.Skipf("readme not available: %v", err)
+	}
+	want, err := os.ReadFile("../../internal/config/schema.json")
+	if err != nil {
+		t.Skipf("schema not available: %v", err)
+	}
+
+	got, err := Generate(source, readme)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if string(got) != string(want) {
+		t.Error("internal/config/schema.json is out of date; run make generate-config-schema")
+	}
+}
@@ -0,0 +1,41 @@
+// Command configschemagen generates the JSON Schema of the configuration in
+// internal/config/schema.json.
+//
+// Variables and their types and defaults are read from the calls in
+// internal/config/config.go, such as parseIntOrDefault("APP_PORT", 8080);
+// descriptions come from the environment variable tables in README.md.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	var configPath, readmePath, outPath string
+	flag.StringVar(&configPath, "config", "internal/config/config.go", "Go file that loads the configuration")
+	flag.StringVar(&readmePath, "readme", "README.md", "Markdown file documenting the variables")
+	flag.StringVar(&outPath, "out", "internal/config/schema.json", "JSON Schema file to write")
+	flag.Parse()
+
+	source, err := os.ReadFile(configPath)
+	if err != nil {
+		log.Fatalf("failed to read config source: %v", err)
+	}
+	readme, err := os.ReadFile(readmePath)
+	if err != nil {
+		log.Fatalf("failed to read readme: %v", err)
+	}
+
+	out, err := Generate(source, readme)
+	if err != nil {
+		log.Fatalf("failed to generate schema: %v", err)
+	}
+
+	if err := os.WriteFile(outPath, out, 0o644); err != nil {
+		log.Fatalf("failed to write schema: %v", err)
+	}
+	fmt.Printf("wrote %s\n", outPath)
+}
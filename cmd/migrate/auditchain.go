@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository/postgres"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// verifyAuditChain checks the audit hash chain against the comma-separated
+// <seq>:<hash> anchors and writes the report to out. It reports whether the
+// chain verified.
+func verifyAuditChain(ctx context.Context, db *sql.DB, anchorList string, out io.Writer) (bool, error) {
+	var anchors []domain.AuditAnchor
+	for _, s := range strings.Split(anchorList, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		anchor, err := domain.ParseAuditAnchor(s)
+		if err != nil {
+			return false, err
+		}
+		anchors = append(anchors, anchor)
+	}
+
+	report, err := service.VerifyAuditChain(ctx, postgres.NewChainedAuditLogRepository(db), anchors)
+	if err != nil {
+		return false, err
+	}
+
+	for _, b := range report.Breaks {
+		fmt.Fprintf(out, "Entry %d: %s\n", b.Seq, b.Reason)
+	}
+	fmt.Fprintf(out, "Checked %d entries against %d anchors, head %s, %d breaks\n",
+		report.Checked, len(anchors), report.Head, len(report.Breaks))
+	return report.OK(), nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/n1rocket/go-auth-jwt/internal/repository/postgres"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// doctor checks the database for integrity issues, fixing those of the
+// comma-separated checks in fixList, and writes the report to out. It
+// reports whether no issue is left.
+func doctor(ctx context.Context, db *sql.DB, fixList string, out io.Writer) (bool, error) {
+	fix, err := service.ParseIntegrityFixes(fixList)
+	if err != nil {
+		return false, err
+	}
+
+	report := service.NewIntegrityChecker(postgres.NewIntegrityRepository(db)).Check(ctx, fix)
+	for _, finding := range report.Findings {
+		switch {
+		case finding.Error != "":
+			fmt.Fprintf(out, "%s: check failed: %s\n", finding.Check, finding.Error)
+		case finding.Issues == 0:
+			fmt.Fprintf(out, "%s: ok\n", finding.Check)
+		default:
+			fmt.Fprintf(out, "%s: %d issues, %d fixed (%s)\n",
+				finding.Check, finding.Issues, finding.Fixed, finding.Detail)
+		}
+	}
+	return report.OK(), nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -9,6 +10,9 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/n1rocket/go-auth-jwt/internal/config"
 	"github.com/n1rocket/go-auth-jwt/internal/db"
+	"github.com/n1rocket/go-auth-jwt/internal/emailnorm"
+	"github.com/n1rocket/go-auth-jwt/internal/lock"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
 )
 
 func main() {
@@ -19,14 +23,20 @@ func main() {
 		migrationsPath string
 		databaseDSN    string
 		useEmbedded    bool
+		canonicalGmail bool
+		anchors        string
+		fix            string
 	)
 
-	flag.StringVar(&command, "command", "up", "Migration command: up, down, steps, version, force")
+	flag.StringVar(&command, "command", "up", "Migration command: up, down, steps, version, force, normalize-emails, reencrypt-pii, verify-audit-chain, doctor")
 	flag.IntVar(&steps, "steps", 0, "Number of migration steps (positive for up, negative for down)")
 	flag.IntVar(&version, "version", 0, "Force migration to specific version")
 	flag.StringVar(&migrationsPath, "path", "./migrations", "Path to migrations directory")
 	flag.StringVar(&databaseDSN, "database", "", "Database connection string (overrides environment)")
 	flag.BoolVar(&useEmbedded, "embedded", false, "Use embedded migrations")
+	flag.BoolVar(&canonicalGmail, "canonicalize-gmail", false, "Strip dots and +suffixes from Gmail addresses (normalize-emails)")
+	flag.StringVar(&anchors, "anchors", "", "Comma-separated <seq>:<hash> audit chain anchors to check (verify-audit-chain)")
+	flag.StringVar(&fix, "fix", "", "Comma-separated integrity checks to fix, or all (doctor)")
 	flag.Parse()
 
 	// Get database DSN
@@ -47,17 +57,29 @@ func main() {
 	}
 	defer database.Close()
 
+	// Deploys migrating together wait for each other with a lock backend
+	var locker lock.Locker
+	if backend := os.Getenv("LOCK_BACKEND"); backend != "" {
+		if locker, err = lock.New(backend, os.Getenv("LOCK_REDIS_URL"), database); err != nil {
+			log.Fatalf("Failed to configure migration lock: %v", err)
+		}
+	}
+
 	// Run migrations based on command
 	switch command {
 	case "up":
 		fmt.Println("Running all pending migrations...")
 		if useEmbedded {
-			migrator := db.NewMigrator(database.DB, db.MigrationConfig{})
+			migrator := db.NewMigrator(database.Pool(), db.MigrationConfig{})
+			migrator.SetLocker(locker)
 			if err := migrator.Up(); err != nil {
 				log.Fatalf("Failed to run migrations: %v", err)
 			}
 		} else {
-			if err := db.RunMigrationsFromPath(database.DB, migrationsPath, db.MigrationConfig{}); err != nil {
+			err := db.WithMigrationLock(locker, func() error {
+				return db.RunMigrationsFromPath(database.Pool(), migrationsPath, db.MigrationConfig{})
+			})
+			if err != nil {
 				log.Fatalf("Failed to run migrations: %v", err)
 			}
 		}
@@ -65,7 +87,8 @@ func main() {
 
 	case "down":
 		fmt.Println("Rolling back last migration...")
-		migrator := db.NewMigrator(database.DB, db.MigrationConfig{})
+		migrator := db.NewMigrator(database.Pool(), db.MigrationConfig{})
+		migrator.SetLocker(locker)
 		if err := migrator.Down(); err != nil {
 			log.Fatalf("Failed to rollback migration: %v", err)
 		}
@@ -76,14 +99,15 @@ func main() {
 			log.Fatal("Steps count is required for steps command")
 		}
 		fmt.Printf("Running %d migration steps...\n", steps)
-		migrator := db.NewMigrator(database.DB, db.MigrationConfig{})
+		migrator := db.NewMigrator(database.Pool(), db.MigrationConfig{})
+		migrator.SetLocker(locker)
 		if err := migrator.Steps(steps); err != nil {
 			log.Fatalf("Failed to run migration steps: %v", err)
 		}
 		fmt.Println("Migration steps completed successfully!")
 
 	case "version":
-		migrator := db.NewMigrator(database.DB, db.MigrationConfig{})
+		migrator := db.NewMigrator(database.Pool(), db.MigrationConfig{})
 		v, dirty, err := migrator.Version()
 		if err != nil {
 			log.Fatalf("Failed to get version: %v", err)
@@ -106,12 +130,64 @@ func main() {
 			return
 		}
 
-		migrator := db.NewMigrator(database.DB, db.MigrationConfig{})
+		migrator := db.NewMigrator(database.Pool(), db.MigrationConfig{})
+		migrator.SetLocker(locker)
 		if err := migrator.Force(version); err != nil {
 			log.Fatalf("Failed to force version: %v", err)
 		}
 		fmt.Printf("Forced to version %d successfully!\n", version)
 
+	case "normalize-emails":
+		fmt.Println("Recomputing normalized emails...")
+		normalizer := emailnorm.Normalizer{CanonicalizeGmail: canonicalGmail}
+		updated, conflicts, err := normalizeEmails(context.Background(), database.Pool(), normalizer)
+		if err != nil {
+			log.Fatalf("Failed to normalize emails: %v", err)
+		}
+		for _, conflict := range conflicts {
+			fmt.Printf("Skipped %s\n", conflict)
+		}
+		fmt.Printf("Updated %d users, skipped %d\n", updated, len(conflicts))
+
+	case "reencrypt-pii":
+		keys, err := security.ParseFieldKeys(os.Getenv("PII_ENCRYPTION_KEYS"))
+		if err != nil {
+			log.Fatalf("Invalid PII_ENCRYPTION_KEYS: %v", err)
+		}
+		cipher, err := security.NewFieldCipher(keys, os.Getenv("PII_ENCRYPTION_KEY_ID"))
+		if err != nil {
+			log.Fatalf("Failed to create PII cipher: %v", err)
+		}
+		fmt.Printf("Re-encrypting personal data with key %s...\n", cipher.ActiveKeyID())
+		updated, failures, err := reencryptPII(context.Background(), database.Pool(), cipher)
+		if err != nil {
+			log.Fatalf("Failed to re-encrypt personal data: %v", err)
+		}
+		for _, failure := range failures {
+			fmt.Printf("Skipped %s\n", failure)
+		}
+		fmt.Printf("Updated %d refresh tokens, skipped %d\n", updated, len(failures))
+
+	case "verify-audit-chain":
+		fmt.Println("Verifying audit log hash chain...")
+		ok, err := verifyAuditChain(context.Background(), database.Pool(), anchors, os.Stdout)
+		if err != nil {
+			log.Fatalf("Failed to verify audit chain: %v", err)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+
+	case "doctor":
+		fmt.Println("Checking database integrity...")
+		ok, err := doctor(context.Background(), database.Pool(), fix, os.Stdout)
+		if err != nil {
+			log.Fatalf("Failed to check database integrity: %v", err)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+
 	default:
 		log.Fatalf("Unknown command: %s", command)
 	}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/n1rocket/go-auth-jwt/internal/emailnorm"
+)
+
+// normalizeEmails recomputes normalized_email for every user. Users whose new
+// value would collide with another account are left unchanged and returned
+// so they can be resolved by hand.
+func normalizeEmails(ctx context.Context, db *sql.DB, normalizer emailnorm.Normalizer) (updated int, conflicts []string, err error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, email, normalized_email FROM users ORDER BY created_at`)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	type row struct{ id, email, current string }
+	var users []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.email, &r.current); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	for _, u := range users {
+		normalized, err := normalizer.Normalize(u.email)
+		if err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s): %v", u.id, u.email, err))
+			continue
+		}
+		if normalized == u.current {
+			continue
+		}
+
+		var taken bool
+		err = db.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM users WHERE normalized_email = $1 AND id <> $2)`,
+			normalized, u.id,
+		).Scan(&taken)
+		if err != nil {
+			return updated, conflicts, fmt.Errorf("failed to check normalized email: %w", err)
+		}
+		if taken {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s): %s already in use", u.id, u.email, normalized))
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx,
+			`UPDATE users SET normalized_email = $2 WHERE id = $1`,
+			u.id, normalized,
+		); err != nil {
+			return updated, conflicts, fmt.Errorf("failed to update user %s: %w", u.id, err)
+		}
+		updated++
+	}
+
+	return updated, conflicts, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// reencryptPII rewrites refresh token user_agent and ip_address values that
+// are plaintext or sealed with a retired key so they use the active key.
+// Values sealed with a key that is no longer configured are left unchanged
+// and returned so they can be resolved by hand.
+func reencryptPII(ctx context.Context, db *sql.DB, cipher *security.FieldCipher) (updated int, failures []string, err error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT token, user_agent, ip_address FROM refresh_tokens
+		WHERE user_agent IS NOT NULL OR ip_address IS NOT NULL`)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+
+	type row struct {
+		token                string
+		userAgent, ipAddress sql.NullString
+	}
+	var tokens []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.token, &r.userAgent, &r.ipAddress); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("failed to scan refresh token: %w", err)
+		}
+		tokens = append(tokens, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+
+	reseal := func(value sql.NullString) (sql.NullString, bool, error) {
+		if !value.Valid || !cipher.NeedsReencryption(value.String) {
+			return value, false, nil
+		}
+		plaintext, err := cipher.Decrypt(value.String)
+		if err != nil {
+			return value, false, err
+		}
+		sealed, err := cipher.Encrypt(plaintext)
+		if err != nil {
+			return value, false, err
+		}
+		return sql.NullString{String: sealed, Valid: true}, true, nil
+	}
+
+	for _, t := range tokens {
+		userAgent, uaChanged, err := reseal(t.userAgent)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s user_agent: %v", t.token, err))
+			continue
+		}
+		ipAddress, ipChanged, err := reseal(t.ipAddress)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s ip_address: %v", t.token, err))
+			continue
+		}
+		if !uaChanged && !ipChanged {
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx,
+			`UPDATE refresh_tokens SET user_agent = $2, ip_address = $3 WHERE token = $1`,
+			t.token, userAgent, ipAddress,
+		); err != nil {
+			return updated, failures, fmt.Errorf("failed to update refresh token %s: %w", t.token, err)
+		}
+		updated++
+	}
+
+	return updated, failures, nil
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Spec is the part of an OpenAPI 3.0 document the generator reads
+type Spec struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Operation is an OpenAPI operation
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Parameters  []Parameter           `json:"parameters"`
+	RequestBody *RequestBody          `json:"requestBody"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is an operation's request body
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is an operation's response for one status
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema as used by OpenAPI 3.0
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []string           `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+}
+
+// methodOrder orders the operations of a path in the generated client
+var methodOrder = []string{"get", "post", "put", "patch", "delete"}
+
+// Generate returns the TypeScript client for spec. source names the
+// document in the generated header.
+func Generate(spec *Spec, source string) ([]byte, error) {
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", spec.OpenAPI)
+	}
+	// ApiError carries the body of error responses
+	if _, ok := spec.Components.Schemas["ErrorResponse"]; !ok {
+		return nil, fmt.Errorf("components.schemas.ErrorResponse is required")
+	}
+	basePath := ""
+	if len(spec.Servers) > 0 {
+		basePath = strings.TrimRight(spec.Servers[0].URL, "/")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by tsclientgen from %s. DO NOT EDIT.\n", source)
+	fmt.Fprintf(&b, "// %s %s\n\n", spec.Info.Title, spec.Info.Version)
+
+	// Schemas, in name order so the output is stable
+	names := sortedKeys(spec.Components.Schemas)
+	for _, name := range names {
+		if err := writeInterface(&b, name, spec.Components.Schemas[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	b.WriteString(runtimeSource)
+
+	fmt.Fprintf(&b, "\n/** Client for the %s */\n", spec.Info.Title)
+	b.WriteString("export class AuthApiClient {\n")
+	b.WriteString("  private readonly baseUrl: string;\n")
+	b.WriteString("  private readonly accessToken?: ClientOptions[\"accessToken\"];\n")
+	b.WriteString("  private readonly fetchImpl: typeof fetch;\n\n")
+	b.WriteString("  constructor(options: ClientOptions) {\n")
+	fmt.Fprintf(&b, "    this.baseUrl = options.baseUrl.replace(/\\/+$/, \"\") + %s;\n", strconv.Quote(basePath))
+	b.WriteString("    this.accessToken = options.accessToken;\n")
+	b.WriteString("    this.fetchImpl = options.fetch ?? globalThis.fetch.bind(globalThis);\n")
+	b.WriteString("  }\n")
+
+	seen := make(map[string]bool)
+	for _, path := range sortedKeys(spec.Paths) {
+		for _, method := range methodOrder {
+			op, ok := spec.Paths[path][method]
+			if !ok {
+				continue
+			}
+			if op.OperationID == "" {
+				return nil, fmt.Errorf("%s %s has no operationId", strings.ToUpper(method), path)
+			}
+			if seen[op.OperationID] {
+				return nil, fmt.Errorf("duplicate operationId %q", op.OperationID)
+			}
+			seen[op.OperationID] = true
+			if err := writeMethod(&b, method, path, op); err != nil {
+				return nil, fmt.Errorf("%s: %w", op.OperationID, err)
+			}
+		}
+	}
+
+	b.WriteString(requestSource)
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+// writeInterface writes a component schema as a TypeScript interface, or as
+// a type alias when it is not an object
+func writeInterface(b *bytes.Buffer, name string, schema *Schema) error {
+	writeDoc(b, "", schema.Description)
+	if schema.Type != "object" || schema.Properties == nil {
+		typ, err := tsType(schema)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", name, err)
+		}
+		fmt.Fprintf(b, "export type %s = %s;\n\n", name, typ)
+		return nil
+	}
+
+	fmt.Fprintf(b, "export interface %s {\n", name)
+	if err := writeProperties(b, "  ", schema); err != nil {
+		return fmt.Errorf("schema %s: %w", name, err)
+	}
+	b.WriteString("}\n\n")
+	return nil
+}
+
+// writeProperties writes an object schema's properties in name order
+func writeProperties(b *bytes.Buffer, indent string, schema *Schema) error {
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	for _, name := range sortedKeys(schema.Properties) {
+		prop := schema.Properties[name]
+		typ, err := tsType(prop)
+		if err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+		optional := "?"
+		if required[name] {
+			optional = ""
+		}
+		writeDoc(b, indent, prop.Description)
+		fmt.Fprintf(b, "%s%s%s: %s;\n", indent, propertyName(name), optional, typ)
+	}
+	return nil
+}
+
+// tsType returns the TypeScript type of a schema
+func tsType(schema *Schema) (string, error) {
+	if schema == nil {
+		return "unknown", nil
+	}
+	var typ string
+	switch {
+	case schema.Ref != "":
+		const prefix = "#/components/schemas/"
+		if !strings.HasPrefix(schema.Ref, prefix) {
+			return "", fmt.Errorf("unsupported $ref %q", schema.Ref)
+		}
+		typ = strings.TrimPrefix(schema.Ref, prefix)
+	case len(schema.Enum) > 0:
+		literals := make([]string, len(schema.Enum))
+		for i, value := range schema.Enum {
+			literals[i] = strconv.Quote(value)
+		}
+		typ = strings.Join(literals, " | ")
+	case schema.Type == "string":
+		typ = "string"
+	case schema.Type == "integer", schema.Type == "number":
+		typ = "number"
+	case schema.Type == "boolean":
+		typ = "boolean"
+	case schema.Type == "array":
+		item, err := tsType(schema.Items)
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(item, " ") {
+			item = "(" + item + ")"
+		}
+		typ = item + "[]"
+	case schema.Type == "object" && schema.Properties != nil:
+		var b bytes.Buffer
+		b.WriteString("{ ")
+		inline := &bytes.Buffer{}
+		if err := writeProperties(inline, "", schema); err != nil {
+			return "", err
+		}
+		b.WriteString(strings.ReplaceAll(strings.TrimSpace(inline.String()), "\n", " "))
+		b.WriteString(" }")
+		typ = b.String()
+	case schema.Type == "object":
+		value, err := tsType(schema.AdditionalProperties)
+		if err != nil {
+			return "", err
+		}
+		typ = "Record<string, " + value + ">"
+	default:
+		return "", fmt.Errorf("unsupported schema type %q", schema.Type)
+	}
+	if schema.Nullable {
+		typ += " | null"
+	}
+	return typ, nil
+}
+
+// writeMethod writes the client method of an operation. Path parameters
+// come first, then the body, then an object of query parameters.
+func writeMethod(b *bytes.Buffer, method, path string, op *Operation) error {
+	var args, pathParams, queryFields []string
+	queryRequired := false
+	for _, param := range op.Parameters {
+		typ, err := tsType(param.Schema)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		switch param.In {
+		case "path":
+			args = append(args, param.Name+": "+typ)
+			pathParams = append(pathParams, param.Name)
+		case "query":
+			optional := "?"
+			if param.Required {
+				optional = ""
+				queryRequired = true
+			}
+			queryFields = append(queryFields, propertyName(param.Name)+optional+": "+typ)
+		default:
+			return fmt.Errorf("unsupported parameter location %q", param.In)
+		}
+	}
+
+	var options []string
+	if op.RequestBody != nil {
+		schema, err := jsonSchema(op.RequestBody.Content)
+		if err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+		typ, err := tsType(schema)
+		if err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+		optional := "?"
+		if op.RequestBody.Required {
+			optional = ""
+		}
+		args = append(args, "body"+optional+": "+typ)
+		options = append(options, "body")
+	}
+	if len(queryFields) > 0 {
+		optional := "?"
+		if queryRequired {
+			optional = ""
+		}
+		args = append(args, "query"+optional+": { "+strings.Join(queryFields, "; ")+" }")
+		options = append(options, "query")
+	}
+	if len(op.Security) > 0 {
+		options = append(options, "auth: true")
+	}
+
+	result, err := resultType(op)
+	if err != nil {
+		return err
+	}
+
+	tsPath := strconv.Quote(path)
+	if len(pathParams) > 0 {
+		tsPath = "`" + path + "`"
+		for _, name := range pathParams {
+			tsPath = strings.ReplaceAll(tsPath, "{"+name+"}", "${encodeURIComponent(String("+name+"))}")
+		}
+	}
+
+	b.WriteString("\n")
+	writeDoc(b, "  ", op.Summary)
+	fmt.Fprintf(b, "  %s(%s): Promise<%s> {\n", op.OperationID, strings.Join(args, ", "), result)
+	fmt.Fprintf(b, "    return this.request<%s>(%s, %s, { %s });\n", result, strconv.Quote(strings.ToUpper(method)), tsPath, strings.Join(options, ", "))
+	b.WriteString("  }\n")
+	return nil
+}
+
+// resultType returns the type of the lowest 2xx response body, or void
+func resultType(op *Operation) (string, error) {
+	var codes []string
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return "", fmt.Errorf("no 2xx response")
+	}
+	sort.Strings(codes)
+
+	resp := op.Responses[codes[0]]
+	if len(resp.Content) == 0 {
+		return "void", nil
+	}
+	schema, err := jsonSchema(resp.Content)
+	if err != nil {
+		return "", fmt.Errorf("response %s: %w", codes[0], err)
+	}
+	return tsType(schema)
+}
+
+// jsonSchema returns the schema of the application/json content
+func jsonSchema(content map[string]*MediaType) (*Schema, error) {
+	media, ok := content["application/json"]
+	if !ok {
+		return nil, fmt.Errorf("only application/json bodies are supported")
+	}
+	return media.Schema, nil
+}
+
+// writeDoc writes a JSDoc comment when text is set
+func writeDoc(b *bytes.Buffer, indent, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(b, "%s/** %s */\n", indent, strings.ReplaceAll(text, "*/", "*\\/"))
+}
+
+// propertyName quotes names that are not valid identifiers
+func propertyName(name string) string {
+	for i, r := range name {
+		if r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runtimeSource is the hand-written part of the client shared by all
+// operations
+const runtimeSource = `/** Options of AuthApiClient */
+export interface ClientOptions {
+  /** Origin of the auth service, e.g. https://auth.example.com */
+  baseUrl: string;
+  /** Returns the access token sent to operations that require authentication */
+  accessToken?: () => string | undefined | Promise<string | undefined>;
+  /** fetch implementation; the global fetch by default */
+  fetch?: typeof fetch;
+}
+
+/** Thrown for responses with an error status */
+export class ApiError extends Error {
+  /** HTTP status */
+  readonly status: number;
+  /** Error code from the service's error catalog, e.g. DUPLICATE_EMAIL */
+  readonly code?: string;
+  /** Error response body, when the service sent one */
+  readonly body?: ErrorResponse;
+  /** Seconds to wait before retrying, from Retry-After */
+  readonly retryAfter?: number;
+
+  constructor(status: number, body: ErrorResponse | undefined, retryAfter: number | undefined) {
+    super(body?.message ?? ` + "`request failed with status ${status}`" + `);
+    this.name = "ApiError";
+    this.status = status;
+    this.code = body?.code;
+    this.body = body;
+    this.retryAfter = retryAfter;
+  }
+}
+
+type Query = Record<string, string | number | boolean | undefined>;
+
+interface RequestOptions {
+  body?: unknown;
+  query?: Query;
+  auth?: boolean;
+}
+
+function parseRetryAfter(value: string | null): number | undefined {
+  if (value === null || value.trim() === "") {
+    return undefined;
+  }
+  const seconds = Number(value);
+  if (Number.isFinite(seconds)) {
+    return Math.max(0, seconds);
+  }
+  const at = Date.parse(value);
+  return Number.isNaN(at) ? undefined : Math.max(0, Math.ceil((at - Date.now()) / 1000));
+}
+`
+
+// requestSource is the client's request method
+const requestSource = `
+  private async request<T>(method: string, path: string, options: RequestOptions): Promise<T> {
+    let url = this.baseUrl + path;
+    if (options.query) {
+      const params = new URLSearchParams();
+      for (const [name, value] of Object.entries(options.query)) {
+        if (value !== undefined) {
+          params.set(name, String(value));
+        }
+      }
+      const query = params.toString();
+      if (query) {
+        url += "?" + query;
+      }
+    }
+
+    const headers: Record<string, string> = { Accept: "application/json" };
+    if (options.body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+    if (options.auth) {
+      const token = await this.accessToken?.();
+      if (token) {
+        headers["Authorization"] = "Bearer " + token;
+      }
+    }
+
+    const response = await this.fetchImpl(url, {
+      method,
+      headers,
+      body: options.body === undefined ? undefined : JSON.stringify(options.body),
+    });
+    if (!response.ok) {
+      let body: ErrorResponse | undefined;
+      try {
+        body = (await response.json()) as ErrorResponse;
+      } catch {
+        body = undefined;
+      }
+      throw new ApiError(response.status, body, parseRetryAfter(response.headers.get("Retry-After")));
+    }
+    if (response.status === 204) {
+      return undefined as T;
+    }
+    return (await response.json()) as T;
+  }
+`
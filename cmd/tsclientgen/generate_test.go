@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "Test API", "version": "1.0.0"},
+  "servers": [{"url": "/api/v1"}],
+  "paths": {
+    "/items/{id}": {
+      "get": {
+        "operationId": "getItem",
+        "summary": "Get an item",
+        "security": [{"bearerAuth": []}],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "expand", "in": "query", "schema": {"type": "boolean"}}
+        ],
+        "responses": {
+          "200": {"description": "ok", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}}
+        }
+      },
+      "delete": {
+        "operationId": "deleteItem",
+        "security": [{"bearerAuth": []}],
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"204": {"description": "deleted"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "required": ["id"],
+        "properties": {
+          "id": {"type": "string"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "note": {"type": "string", "nullable": true}
+        }
+      },
+      "ErrorResponse": {
+        "type": "object",
+        "required": ["error"],
+        "properties": {"error": {"type": "string"}, "code": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+func parseSpec(t *testing.T, data string) *Spec {
+	t.Helper()
+	var spec Spec
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		t.Fatalf("failed to parse spec: %v", err)
+	}
+	return &spec
+}
+
+func TestGenerate(t *testing.T) {
+	out, err := Generate(parseSpec(t, testSpec), "spec.json")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	got := string(out)
+
+	for _, want := range []string{
+		"// Code generated by tsclientgen from spec.json. DO NOT EDIT.",
+		"export interface Item {",
+		"  id: string;",
+		"  tags?: string[];",
+		"  note?: string | null;",
+		"  /** Get an item */",
+		"  getItem(id: string, query?: { expand?: boolean }): Promise<Item> {",
+		"return this.request<Item>(\"GET\", `/items/${encodeURIComponent(String(id))}`, { query, auth: true });",
+		"  deleteItem(id: string): Promise<void> {",
+		"this.baseUrl = options.baseUrl.replace(/\\/+$/, \"\") + \"/api/v1\";",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+
+	// GET is generated before DELETE for the same path
+	if strings.Index(got, "getItem(") > strings.Index(got, "deleteItem(") {
+		t.Error("expected getItem before deleteItem")
+	}
+}
+
+func TestGenerate_Deterministic(t *testing.T) {
+	first, err := Generate(parseSpec(t, testSpec), "spec.json")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		next, err := Generate(parseSpec(t, testSpec), "spec.json")
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if string(next) != string(first) {
+			t.Fatal("Generate() output is not stable")
+		}
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Spec)
+		wantErr string
+	}{
+		{
+			name:    "missing error schema",
+			mutate:  func(s *Spec) { delete(s.Components.Schemas, "ErrorResponse") },
+			wantErr: "ErrorResponse is required",
+		},
+		{
+			name:    "unsupported version",
+			mutate:  func(s *Spec) { s.OpenAPI = "2.0" },
+			wantErr: "unsupported OpenAPI version",
+		},
+		{
+			name:    "missing operationId",
+			mutate:  func(s *Spec) { s.Paths["/items/{id}"]["get"].OperationID = "" },
+			wantErr: "has no operationId",
+		},
+		{
+			name:    "duplicate operationId",
+			mutate:  func(s *Spec) { s.Paths["/items/{id}"]["delete"].OperationID = "getItem" },
+			wantErr: "duplicate operationId",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := parseSpec(t, testSpec)
+			tt.mutate(spec)
+			_, err := Generate(spec, "spec.json")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Generate() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestGenerate_CommittedClient fails when the committed client is stale
+func TestGenerate_CommittedClient(t *testing.T) {
+	data, err := os.ReadFile("../../docs/openapi.json")
+	if err != nil {
+		t.Skipf("spec not available: %v", err)
+	}
+	want, err := os.ReadFile("../../examples/clients/typescript/client.ts")
+	if err != nil {
+		t.Skipf("client not available: %v", err)
+	}
+
+	got, err := Generate(parseSpec(t, string(data)), "docs/openapi.json")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if string(got) != string(want) {
+		t.Error("examples/clients/typescript/client.ts is out of date; run make generate-ts-client")
+	}
+}
@@ -0,0 +1,138 @@
+// Command verifier validates access tokens for API gateways that cannot
+// validate JWTs themselves. It runs next to the gateway, fetches the auth
+// service's JWKS and needs no database:
+//
+//	verifier -jwks-url https://auth.example.com/.well-known/jwks.json
+//
+// Flags default to the environment variables named in their usage.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+	slog.SetDefault(logger)
+
+	if err := run(os.Args[1:], logger); err != nil {
+		logger.Error("verifier failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the flags and serves until SIGINT or SIGTERM
+func run(args []string, logger *slog.Logger) error {
+	clockSkew, err := durationEnv("JWT_CLOCK_SKEW", 30*time.Second)
+	if err != nil {
+		return err
+	}
+	refreshInterval, err := durationEnv("VERIFIER_JWKS_REFRESH_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("verifier", flag.ContinueOnError)
+	addr := flags.String("addr", envOrDefault("VERIFIER_ADDR", ":8081"), "Listen address (VERIFIER_ADDR)")
+	jwksURL := flags.String("jwks-url", os.Getenv("VERIFIER_JWKS_URL"), "JWKS URL of the auth service (VERIFIER_JWKS_URL)")
+	issuer := flags.String("issuer", envOrDefault("JWT_ISSUER", "go-auth-jwt"), "Required iss claim; empty accepts any (JWT_ISSUER)")
+	flags.DurationVar(&clockSkew, "clock-skew", clockSkew, "Leeway for exp/nbf/iat validation (JWT_CLOCK_SKEW)")
+	flags.DurationVar(&refreshInterval, "refresh-interval", refreshInterval, "How often the JWKS is refetched (VERIFIER_JWKS_REFRESH_INTERVAL)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *jwksURL == "" {
+		return errors.New("VERIFIER_JWKS_URL is required")
+	}
+	if clockSkew < 0 {
+		return errors.New("JWT_CLOCK_SKEW must not be negative")
+	}
+	if refreshInterval <= 0 {
+		return errors.New("VERIFIER_JWKS_REFRESH_INTERVAL must be positive")
+	}
+
+	client, err := httpclient.New(httpclient.DefaultConfig(), logger)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	keys := token.NewKeySet(*jwksURL, client)
+	verifier := token.NewRemoteVerifier(keys, *issuer)
+	verifier.SetClockSkew(clockSkew)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start serving before the first fetch succeeds; readiness reports it
+	if err := keys.Refresh(ctx); err != nil {
+		logger.Warn("failed to fetch JWKS", "url", *jwksURL, "error", err)
+	}
+	go keys.Run(ctx, refreshInterval, logger)
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           NewServer(verifier, keys),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		logger.Info("starting verifier", "addr", *addr, "jwks_url", *jwksURL, "issuer", *issuer)
+		serverErrors <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErrors:
+		return err
+	case <-ctx.Done():
+		logger.Info("shutdown signal received")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+	logger.Info("verifier stopped")
+	return nil
+}
+
+// envOrDefault returns the environment variable or def when it is unset
+func envOrDefault(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// durationEnv parses the environment variable as a duration, returning def
+// when it is unset
+func durationEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a valid duration: %w", name, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// Headers /verify sets on success, for the gateway to forward upstream
+const (
+	HeaderUserID        = "X-Auth-User-ID"
+	HeaderEmail         = "X-Auth-Email"
+	HeaderEmailVerified = "X-Auth-Email-Verified"
+	HeaderScope         = "X-Auth-Scope"
+	HeaderRoles         = "X-Auth-Roles"
+	HeaderClientID      = "X-Auth-Client-ID"
+)
+
+// maxIntrospectionBytes bounds introspection request bodies
+const maxIntrospectionBytes = 16 << 10
+
+// errBoundToken is returned by /verify for tokens bound to a client
+// certificate or DPoP key, whose binding only the gateway can check
+var errBoundToken = errors.New("bound tokens must be introspected")
+
+// IntrospectionResponse is an RFC 7662 token introspection response.
+// Inactive tokens only carry active.
+type IntrospectionResponse struct {
+	Active        bool                `json:"active"`
+	Subject       string              `json:"sub,omitempty"`
+	ClientID      string              `json:"client_id,omitempty"`
+	Scope         string              `json:"scope,omitempty"`
+	TokenType     string              `json:"token_type,omitempty"`
+	ExpiresAt     int64               `json:"exp,omitempty"`
+	IssuedAt      int64               `json:"iat,omitempty"`
+	NotBefore     int64               `json:"nbf,omitempty"`
+	Issuer        string              `json:"iss,omitempty"`
+	TokenID       string              `json:"jti,omitempty"`
+	Email         string              `json:"email,omitempty"`
+	EmailVerified bool                `json:"email_verified,omitempty"`
+	Roles         []string            `json:"roles,omitempty"`
+	Confirmation  *token.Confirmation `json:"cnf,omitempty"` // the gateway checks the binding
+}
+
+// Server serves token validation for a gateway
+type Server struct {
+	verifier *token.RemoteVerifier
+	keys     *token.KeySet
+	mux      *http.ServeMux
+}
+
+// NewServer creates the verifier's HTTP handler:
+//
+//	GET  /verify       forward authentication, e.g. nginx auth_request
+//	POST /introspect   RFC 7662 token introspection
+//	GET  /health       liveness
+//	GET  /ready        ready once the JWKS has been fetched
+func NewServer(verifier *token.RemoteVerifier, keys *token.KeySet) *Server {
+	s := &Server{verifier: verifier, keys: keys, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /verify", s.verify)
+	s.mux.HandleFunc("POST /introspect", s.introspect)
+	s.mux.HandleFunc("GET /health", s.health)
+	s.mux.HandleFunc("GET /ready", s.ready)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// verify answers 200 with the token's identity in X-Auth-* headers when the
+// request carries a valid bearer token, and 401 otherwise
+func (s *Server) verify(w http.ResponseWriter, r *http.Request) {
+	_, tokenString, err := request.ExtractAccessToken(r)
+	if err != nil {
+		s.unauthorized(w, token.ErrInvalidToken)
+		return
+	}
+
+	claims, err := s.verifier.ValidateAccessToken(r.Context(), tokenString)
+	if errors.Is(err, token.ErrKeySetNotLoaded) {
+		s.unavailable(w)
+		return
+	}
+	if err != nil {
+		s.unauthorized(w, err)
+		return
+	}
+	if claims.Confirmation != nil {
+		s.unauthorized(w, errBoundToken)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set(HeaderUserID, claims.UserID)
+	header.Set(HeaderEmail, claims.Email)
+	header.Set(HeaderEmailVerified, strconv.FormatBool(claims.EmailVerified))
+	if claims.Scope != "" {
+		header.Set(HeaderScope, claims.Scope)
+	}
+	if len(claims.Roles) > 0 {
+		header.Set(HeaderRoles, strings.Join(claims.Roles, ","))
+	}
+	if claims.ClientID != "" {
+		header.Set(HeaderClientID, claims.ClientID)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// introspect describes the token in the form parameter token. Invalid and
+// expired tokens are reported as inactive, not as errors.
+func (s *Server) introspect(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxIntrospectionBytes)
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("token") == "" {
+		response.WriteValidationError(w, []response.ValidationError{{Field: "token", Message: "token is required", Code: "REQUIRED_FIELD"}})
+		return
+	}
+
+	claims, err := s.verifier.ValidateAccessToken(r.Context(), r.PostForm.Get("token"))
+	if errors.Is(err, token.ErrKeySetNotLoaded) {
+		s.unavailable(w)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err != nil {
+		response.WriteJSON(w, http.StatusOK, IntrospectionResponse{Active: false})
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, newIntrospectionResponse(claims))
+}
+
+// newIntrospectionResponse describes an active token
+func newIntrospectionResponse(claims *token.Claims) IntrospectionResponse {
+	resp := IntrospectionResponse{
+		Active:        true,
+		Subject:       claims.Subject,
+		ClientID:      claims.ClientID,
+		Scope:         claims.Scope,
+		TokenType:     "Bearer",
+		Issuer:        claims.Issuer,
+		TokenID:       claims.ID,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Roles:         claims.Roles,
+		Confirmation:  claims.Confirmation,
+	}
+	if resp.Subject == "" {
+		resp.Subject = claims.UserID
+	}
+	if claims.DPoPThumbprint() != "" {
+		resp.TokenType = token.DPoPTokenType
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		resp.NotBefore = claims.NotBefore.Unix()
+	}
+	return resp
+}
+
+// health reports that the process is serving
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	response.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ready reports whether tokens can be validated, i.e. the JWKS has been
+// fetched at least once
+func (s *Server) ready(w http.ResponseWriter, r *http.Request) {
+	fetchedAt := s.keys.FetchedAt()
+	if fetchedAt.IsZero() {
+		response.WriteJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready", "jwks": "not fetched"})
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, map[string]string{"status": "ready", "jwks_fetched_at": fetchedAt.UTC().Format(time.RFC3339)})
+}
+
+// unauthorized rejects a request whose token is missing or invalid
+func (s *Server) unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	if !errors.Is(err, token.ErrInvalidToken) && !errors.Is(err, token.ErrExpiredToken) {
+		err = token.ErrInvalidToken
+	}
+	response.WriteError(w, err)
+}
+
+// unavailable fails a request that cannot be answered until the JWKS has
+// been fetched
+func (s *Server) unavailable(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "5")
+	response.WriteJSON(w, http.StatusServiceUnavailable, response.ErrorResponse{
+		Error:   "service_unavailable",
+		Message: "Signing keys have not been fetched yet",
+		Code:    "JWKS_UNAVAILABLE",
+	})
+}
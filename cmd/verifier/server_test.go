@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+const testKeyID = "test-key"
+
+// newTestServer returns a verifier server trusting a key the test signs
+// with. The JWKS is fetched up front when fetch is set.
+func newTestServer(t *testing.T, fetch bool) (*Server, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]interface{}{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": testKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(jwks.Close)
+
+	keys := token.NewKeySet(jwks.URL, jwks.Client())
+	if fetch {
+		if err := keys.Refresh(context.Background()); err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+	}
+	return NewServer(token.NewRemoteVerifier(keys, "go-auth-jwt"), keys), key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims token.Claims) string {
+	t.Helper()
+
+	now := time.Now()
+	claims.Issuer = "go-auth-jwt"
+	claims.Subject = claims.UserID
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(15 * time.Minute))
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = testKeyID
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+// signIDToken signs an ID token for user-123 as the auth server issues them
+func signIDToken(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	now := time.Now()
+	claims := token.IDTokenClaims{
+		Email: "test@example.com",
+		Use:   token.IDTokenUse,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "go-auth-jwt",
+			Subject:   "user-123",
+			Audience:  jwt.ClaimStrings{"client"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(15 * time.Minute)),
+		},
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = testKeyID
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func TestServer_Verify(t *testing.T) {
+	server, key := newTestServer(t, true)
+	valid := signToken(t, key, token.Claims{UserID: "user-123", Email: "test@example.com", EmailVerified: true, Scope: "read write", Roles: []string{"admin"}})
+	bound := signToken(t, key, token.Claims{UserID: "user-123", Confirmation: &token.Confirmation{JWKThumbprint: "thumbprint"}})
+	idToken := signIDToken(t, key)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantUserID    string
+	}{
+		{"valid token", "Bearer " + valid, http.StatusOK, "user-123"},
+		{"missing token", "", http.StatusUnauthorized, ""},
+		{"invalid token", "Bearer " + valid[:len(valid)-4] + "AAAA", http.StatusUnauthorized, ""},
+		{"bound token", "DPoP " + bound, http.StatusUnauthorized, ""},
+		{"ID token", "Bearer " + idToken, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/verify", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := rec.Header().Get(HeaderUserID); got != tt.wantUserID {
+				t.Errorf("%s = %q, want %q", HeaderUserID, got, tt.wantUserID)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate header is not set")
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/verify", nil)
+	req.Header.Set("Authorization", "Bearer "+valid)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if got := rec.Header().Get(HeaderScope); got != "read write" {
+		t.Errorf("%s = %q", HeaderScope, got)
+	}
+	if got := rec.Header().Get(HeaderRoles); got != "admin" {
+		t.Errorf("%s = %q", HeaderRoles, got)
+	}
+	if got := rec.Header().Get(HeaderEmailVerified); got != "true" {
+		t.Errorf("%s = %q", HeaderEmailVerified, got)
+	}
+}
+
+func TestServer_Introspect(t *testing.T) {
+	server, key := newTestServer(t, true)
+	bound := signToken(t, key, token.Claims{UserID: "user-123", Email: "test@example.com", Confirmation: &token.Confirmation{JWKThumbprint: "thumbprint"}})
+
+	introspect := func(form url.Values) (*httptest.ResponseRecorder, IntrospectionResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		var resp IntrospectionResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response %s: %v", rec.Body, err)
+			}
+		}
+		return rec, resp
+	}
+
+	rec, resp := introspect(url.Values{"token": {bound}})
+	if rec.Code != http.StatusOK || !resp.Active {
+		t.Fatalf("introspect() = %d %s, want an active token", rec.Code, rec.Body)
+	}
+	if resp.Subject != "user-123" || resp.TokenType != token.DPoPTokenType || resp.Confirmation == nil || resp.Confirmation.JWKThumbprint != "thumbprint" {
+		t.Errorf("introspect() = %+v", resp)
+	}
+
+	rec, resp = introspect(url.Values{"token": {signIDToken(t, key)}})
+	if rec.Code != http.StatusOK || resp.Active || resp.Subject != "" {
+		t.Errorf("introspect() of an ID token = %d %s, want inactive", rec.Code, rec.Body)
+	}
+
+	rec, resp = introspect(url.Values{"token": {"not-a-token"}})
+	if rec.Code != http.StatusOK || resp.Active || resp.Subject != "" {
+		t.Errorf("introspect() of an invalid token = %d %s, want inactive", rec.Code, rec.Body)
+	}
+
+	if rec, _ := introspect(url.Values{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("introspect() without a token = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServer_Ready(t *testing.T) {
+	server, key := newTestServer(t, false)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ready before the JWKS is fetched = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	// Validating a token fetches the JWKS
+	req := httptest.NewRequest(http.MethodGet, "/verify", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, key, token.Claims{UserID: "user-123"}))
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("verify before the JWKS is fetched = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("ready after the JWKS is fetched = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
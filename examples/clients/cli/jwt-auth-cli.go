@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -149,7 +150,7 @@ func handleSignup(ctx context.Context, authClient *client.Client, config *Config
 	if err := authClient.Signup(ctx, email, password); err != nil {
 		if apiErr, ok := err.(*client.APIError); ok {
 			fmt.Printf("Signup failed: %s\n", apiErr.Message)
-			if apiErr.Code == "DUPLICATE_EMAIL" {
+			if errors.Is(err, client.ErrDuplicateEmail) {
 				fmt.Println("This email is already registered. Please login instead.")
 			}
 		} else {
@@ -232,7 +233,7 @@ func handleProfile(ctx context.Context, authClient *client.Client) {
 	fmt.Println("Fetching profile...")
 	profile, err := authClient.GetProfile(ctx)
 	if err != nil {
-		if apiErr, ok := err.(*client.APIError); ok && apiErr.StatusCode == 401 {
+		if errors.Is(err, client.ErrInvalidToken) {
 			fmt.Println("Error: Session expired. Please login again.")
 		} else {
 			fmt.Printf("Error: %v\n", err)
@@ -275,7 +276,7 @@ func handleRefresh(ctx context.Context, authClient *client.Client, config *Confi
 	if err != nil {
 		if apiErr, ok := err.(*client.APIError); ok {
 			fmt.Printf("Refresh failed: %s\n", apiErr.Message)
-			if apiErr.StatusCode == 401 {
+			if errors.Is(err, client.ErrInvalidToken) {
 				fmt.Println("Your session has expired. Please login again.")
 			}
 		} else {
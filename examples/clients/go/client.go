@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"sync"
 	"time"
@@ -23,6 +25,10 @@ type Client struct {
 	autoRefresh  bool
 	refreshTimer *time.Timer
 	mu           sync.RWMutex
+
+	maxRetries     int
+	retryBaseDelay time.Duration
+	maxRetryDelay  time.Duration
 }
 
 // Config holds client configuration
@@ -31,6 +37,16 @@ type Config struct {
 	APIPath     string
 	Timeout     time.Duration
 	AutoRefresh bool
+
+	// MaxRetries is how often rate limited (429) and unavailable (503)
+	// requests are retried; 0 means 3 and a negative value disables retries
+	MaxRetries int
+	// RetryBaseDelay is the backoff before the first retry when the service
+	// sends no Retry-After; it doubles on each retry
+	RetryBaseDelay time.Duration
+	// MaxRetryDelay caps the wait before a retry. A longer Retry-After is
+	// returned to the caller instead of waited for.
+	MaxRetryDelay time.Duration
 }
 
 // DefaultConfig returns default client configuration
@@ -40,6 +56,10 @@ func DefaultConfig() Config {
 		APIPath:     "/api/v1",
 		Timeout:     30 * time.Second,
 		AutoRefresh: true,
+
+		MaxRetries:     3,
+		RetryBaseDelay: 500 * time.Millisecond,
+		MaxRetryDelay:  30 * time.Second,
 	}
 }
 
@@ -54,6 +74,15 @@ func NewClient(config Config) *Client {
 	if config.Timeout == 0 {
 		config.Timeout = 30 * time.Second
 	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.RetryBaseDelay == 0 {
+		config.RetryBaseDelay = 500 * time.Millisecond
+	}
+	if config.MaxRetryDelay == 0 {
+		config.MaxRetryDelay = 30 * time.Second
+	}
 
 	return &Client{
 		baseURL:     config.BaseURL,
@@ -62,6 +91,9 @@ func NewClient(config Config) *Client {
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
+		maxRetries:     config.MaxRetries,
+		retryBaseDelay: config.RetryBaseDelay,
+		maxRetryDelay:  config.MaxRetryDelay,
 	}
 }
 
@@ -239,17 +271,73 @@ func (c *Client) SetTokens(accessToken, refreshToken string, expiresIn int) {
 	c.setTokens(accessToken, refreshToken, expiresIn)
 }
 
-// request performs an HTTP request
+// request performs an HTTP request, retrying rate limited and unavailable
+// responses with jittered backoff
 func (c *Client) request(ctx context.Context, method, endpoint string, payload interface{}, authenticated bool) ([]byte, error) {
-	url := c.baseURL + c.apiPath + endpoint
-
-	var body io.Reader
+	var jsonData []byte
 	if payload != nil {
-		jsonData, err := json.Marshal(payload)
+		var err error
+		jsonData, err = json.Marshal(payload)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal payload: %w", err)
 		}
-		body = bytes.NewBuffer(jsonData)
+	}
+
+	for attempt := 0; ; attempt++ {
+		respBody, err := c.do(ctx, method, endpoint, jsonData, authenticated)
+		var apiErr *APIError
+		if err == nil || !errors.As(err, &apiErr) || !apiErr.retryable() || attempt >= c.maxRetries {
+			return respBody, err
+		}
+
+		delay, ok := c.retryDelay(apiErr, attempt)
+		if !ok {
+			return nil, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retry attempt+1. Retry-After is
+// honored with up to 10% added jitter, so clients told to wait the same time
+// do not return at once; without it the backoff doubles from retryBaseDelay
+// with full jitter. It reports false when the wait exceeds maxRetryDelay.
+func (c *Client) retryDelay(apiErr *APIError, attempt int) (time.Duration, bool) {
+	if apiErr.RetryAfter > 0 {
+		if apiErr.RetryAfter > c.maxRetryDelay {
+			return 0, false
+		}
+		return apiErr.RetryAfter + jitter(apiErr.RetryAfter/10), true
+	}
+
+	backoff := c.retryBaseDelay << attempt
+	if backoff <= 0 || backoff > c.maxRetryDelay {
+		backoff = c.maxRetryDelay
+	}
+	return jitter(backoff), true
+}
+
+// jitter returns a random duration in [0, limit]
+func jitter(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit + 1)
+}
+
+// do sends a request once
+func (c *Client) do(ctx context.Context, method, endpoint string, jsonData []byte, authenticated bool) ([]byte, error) {
+	url := c.baseURL + c.apiPath + endpoint
+
+	var body io.Reader
+	if jsonData != nil {
+		body = bytes.NewReader(jsonData)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
@@ -282,17 +370,20 @@ func (c *Client) request(ctx context.Context, method, endpoint string, payload i
 	}
 
 	if resp.StatusCode >= 400 {
-		// Try to parse error response
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		// Try to parse error response; proxies may answer with plain text
 		var errResp ErrorResponse
 		if err := json.Unmarshal(respBody, &errResp); err == nil {
-			return nil, &APIError{
-				StatusCode: resp.StatusCode,
-				Message:    errResp.Message,
-				Code:       errResp.Code,
-				Details:    errResp.Details,
-			}
+			apiErr.Message = errResp.Message
+			apiErr.Code = errResp.Code
+			apiErr.Details = errResp.Details
+		} else {
+			apiErr.Message = fmt.Sprintf("request failed with status %d: %s", resp.StatusCode, string(respBody))
 		}
-		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(respBody))
+		return nil, apiErr
 	}
 
 	return respBody, nil
@@ -346,21 +437,6 @@ func (c *Client) Close() {
 	c.clearTokens()
 }
 
-// APIError represents an API error
-type APIError struct {
-	StatusCode int
-	Message    string
-	Code       string
-	Details    map[string]interface{}
-}
-
-func (e *APIError) Error() string {
-	if e.Code != "" {
-		return fmt.Sprintf("%s (code: %s)", e.Message, e.Code)
-	}
-	return e.Message
-}
-
 // WithRetry creates a client with retry capabilities
 func WithRetry(client *Client, maxRetries int, backoff time.Duration) *Client {
 	// Wrap the HTTP client with retry logic
@@ -388,8 +464,9 @@ func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 			time.Sleep(t.backoff * time.Duration(i))
 		}
 
+		// 503s are left to the client, which honors Retry-After
 		resp, err = t.base.RoundTrip(req)
-		if err == nil && resp.StatusCode < 500 {
+		if err == nil && (resp.StatusCode < 500 || resp.StatusCode == http.StatusServiceUnavailable) {
 			return resp, nil
 		}
 
@@ -0,0 +1,111 @@
+package jwtauthclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Errors from the service's error catalog. Check them with errors.Is; use
+// errors.As with *APIError for the status code, message and RetryAfter.
+var (
+	ErrValidation         = errors.New("validation failed")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrWeakPassword       = errors.New("weak password")
+	ErrDuplicateEmail     = errors.New("email already registered")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrEmailNotVerified   = errors.New("email not verified")
+	ErrAccountDisabled    = errors.New("account disabled")
+	ErrCaptchaRequired    = errors.New("captcha required")
+	ErrSMSCodeRequired    = errors.New("SMS code required")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrQuotaExceeded      = errors.New("quota exceeded")
+	// ErrRateLimited is returned for 429 responses once retries are spent
+	ErrRateLimited = errors.New("rate limited")
+	// ErrServiceUnavailable is returned for 503 responses, e.g. while the
+	// service is read-only, once retries are spent
+	ErrServiceUnavailable = errors.New("service unavailable")
+)
+
+// errorCatalog maps the service's error codes to the errors above
+var errorCatalog = map[string]error{
+	"VALIDATION_FAILED":   ErrValidation,
+	"INVALID_EMAIL":       ErrInvalidEmail,
+	"WEAK_PASSWORD":       ErrWeakPassword,
+	"DUPLICATE_EMAIL":     ErrDuplicateEmail,
+	"INVALID_CREDENTIALS": ErrInvalidCredentials,
+	"INVALID_TOKEN":       ErrInvalidToken,
+	"EMAIL_NOT_VERIFIED":  ErrEmailNotVerified,
+	"ACCOUNT_DISABLED":    ErrAccountDisabled,
+	"CAPTCHA_REQUIRED":    ErrCaptchaRequired,
+	"SMS_CODE_REQUIRED":   ErrSMSCodeRequired,
+	"PERMISSION_DENIED":   ErrPermissionDenied,
+	"QUOTA_EXCEEDED":      ErrQuotaExceeded,
+	"READ_ONLY_MODE":      ErrServiceUnavailable,
+}
+
+// APIError represents an API error
+type APIError struct {
+	StatusCode int
+	Message    string
+	Code       string
+	Details    map[string]interface{}
+	// RetryAfter is how long the service asked clients to wait, from the
+	// Retry-After header; zero when it sent none
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s (code: %s)", e.Message, e.Code)
+	}
+	return e.Message
+}
+
+// Unwrap returns the catalog error for the error code, or for the status
+// when the code is unknown, so errors.Is matches it
+func (e *APIError) Unwrap() error {
+	if err, ok := errorCatalog[e.Code]; ok {
+		return err
+	}
+	switch e.StatusCode {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusServiceUnavailable:
+		return ErrServiceUnavailable
+	case http.StatusUnauthorized:
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// retryable reports whether the request may be sent again. The service
+// rejects rate limited and read-only requests before acting on them, so
+// even non-idempotent requests are safe to retry.
+func (e *APIError) retryable() bool {
+	if e.Code == "QUOTA_EXCEEDED" {
+		// Quotas reset monthly; retrying cannot help
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
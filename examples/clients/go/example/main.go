@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -32,13 +33,8 @@ func main() {
 	// 1. Signup
 	fmt.Println("1. Signing up new user...")
 	if err := authClient.Signup(ctx, email, password); err != nil {
-		// Check if it's an API error
-		if apiErr, ok := err.(*client.APIError); ok {
-			if apiErr.Code == "DUPLICATE_EMAIL" {
-				fmt.Println("   User already exists, proceeding to login")
-			} else {
-				log.Fatalf("Signup failed: %v", apiErr)
-			}
+		if errors.Is(err, client.ErrDuplicateEmail) {
+			fmt.Println("   User already exists, proceeding to login")
 		} else {
 			log.Fatalf("Signup failed: %v", err)
 		}
@@ -0,0 +1,73 @@
+// Package auditexport streams audit events to external SIEM sinks. Events
+// are queued per sink and exported in batches so a slow or unavailable sink
+// never blocks audit writes for longer than the configured enqueue timeout.
+package auditexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// Exporter delivers batches of audit events to one sink
+type Exporter interface {
+	// Name identifies the sink in logs and metrics
+	Name() string
+	// Export delivers a batch. A failed batch is retried as a whole, so
+	// sinks may see an event more than once.
+	Export(ctx context.Context, events []*domain.AuditLog) error
+}
+
+// Event is the JSON representation of an audit log entry sent to sinks
+type Event struct {
+	ID           string                 `json:"id"`
+	Timestamp    time.Time              `json:"timestamp"`
+	Action       string                 `json:"action"`
+	Status       string                 `json:"status"`
+	UserID       *string                `json:"user_id,omitempty"`
+	ResourceType *string                `json:"resource_type,omitempty"`
+	ResourceID   *string                `json:"resource_id,omitempty"`
+	IPAddress    *string                `json:"ip_address,omitempty"`
+	UserAgent    *string                `json:"user_agent,omitempty"`
+	RequestID    *string                `json:"request_id,omitempty"`
+	ErrorMessage *string                `json:"error_message,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	ChainSeq     int64                  `json:"chain_seq,omitempty"`
+	Hash         string                 `json:"hash,omitempty"`
+}
+
+// NewEvent converts an audit log entry to its exported form
+func NewEvent(log *domain.AuditLog) Event {
+	return Event{
+		ID:           log.ID,
+		Timestamp:    log.CreatedAt.UTC(),
+		Action:       log.Action,
+		Status:       log.Status,
+		UserID:       log.UserID,
+		ResourceType: log.ResourceType,
+		ResourceID:   log.ResourceID,
+		IPAddress:    log.IPAddress,
+		UserAgent:    log.UserAgent,
+		RequestID:    log.RequestID,
+		ErrorMessage: log.ErrorMessage,
+		Metadata:     log.Metadata,
+		ChainSeq:     log.ChainSeq,
+		Hash:         log.Hash,
+	}
+}
+
+// EncodeJSONL encodes events as newline-delimited JSON
+func EncodeJSONL(events []*domain.AuditLog) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, log := range events {
+		if err := enc.Encode(NewEvent(log)); err != nil {
+			return nil, fmt.Errorf("failed to encode audit event: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
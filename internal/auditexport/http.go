@@ -0,0 +1,59 @@
+package auditexport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// HTTPExporter posts each batch as newline-delimited JSON to a collector
+type HTTPExporter struct {
+	client *httpclient.Client
+	url    string
+	token  string
+}
+
+// NewHTTPExporter creates an HTTP collector exporter. The token is sent as a
+// bearer token when set.
+func NewHTTPExporter(client *httpclient.Client, url, token string) *HTTPExporter {
+	return &HTTPExporter{client: client, url: url, token: token}
+}
+
+// Name identifies the exporter
+func (e *HTTPExporter) Name() string {
+	return "http"
+}
+
+// Export posts the batch to the collector
+func (e *HTTPExporter) Export(ctx context.Context, events []*domain.AuditLog) error {
+	body, err := EncodeJSONL(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create collector request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send audit batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to send audit batch: unexpected status %s: %s", resp.Status, detail)
+	}
+	return nil
+}
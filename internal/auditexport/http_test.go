@@ -0,0 +1,54 @@
+package auditexport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestHTTPExporter_Export(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "rejected", status: http.StatusUnauthorized, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var auth, contentType, body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				contentType = r.Header.Get("Content-Type")
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			e := NewHTTPExporter(newTestHTTPClient(t), server.URL, "collector-token")
+			events := []*domain.AuditLog{
+				domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusSuccess),
+				domain.NewAuditLog(domain.AuditActionDormancyDisable, domain.AuditStatusSuccess),
+			}
+
+			err := e.Export(context.Background(), events)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Export() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if auth != "Bearer collector-token" || contentType != "application/x-ndjson" {
+				t.Errorf("unexpected headers: Authorization=%q Content-Type=%q", auth, contentType)
+			}
+			if lines := strings.Split(strings.TrimSpace(body), "\n"); len(lines) != 2 {
+				t.Errorf("collector received %d lines, want 2", len(lines))
+			}
+		})
+	}
+}
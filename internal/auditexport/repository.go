@@ -0,0 +1,31 @@
+package auditexport
+
+import (
+	"context"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// Repository publishes every audit log entry to the streamer after it has
+// been stored, so the database stays the system of record
+type Repository struct {
+	repository.AuditLogRepository
+	streamer *Streamer
+}
+
+// NewRepository wraps an audit log repository
+func NewRepository(repo repository.AuditLogRepository, streamer *Streamer) *Repository {
+	return &Repository{AuditLogRepository: repo, streamer: streamer}
+}
+
+// Create stores the entry and queues it for export
+func (r *Repository) Create(ctx context.Context, log *domain.AuditLog) error {
+	if err := r.AuditLogRepository.Create(ctx, log); err != nil {
+		return err
+	}
+	r.streamer.Publish(log)
+	return nil
+}
+
+var _ repository.AuditLogRepository = (*Repository)(nil)
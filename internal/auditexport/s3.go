@@ -0,0 +1,212 @@
+package auditexport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// S3Config configures the S3 exporter
+type S3Config struct {
+	Bucket          string
+	Region          string
+	Prefix          string // object key prefix, without a trailing slash
+	Endpoint        string // S3-compatible endpoint using path-style URLs; AWS when empty
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // only for temporary credentials
+}
+
+// S3Exporter writes each batch as a gzipped JSONL object under
+// <prefix>/YYYY/MM/DD/. Requests are signed with AWS Signature Version 4.
+type S3Exporter struct {
+	client *httpclient.Client
+	config S3Config
+	now    func() time.Time
+}
+
+// NewS3Exporter creates an S3 exporter
+func NewS3Exporter(client *httpclient.Client, config S3Config) *S3Exporter {
+	config.Prefix = strings.Trim(config.Prefix, "/")
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	return &S3Exporter{client: client, config: config, now: time.Now}
+}
+
+// Name identifies the exporter
+func (e *S3Exporter) Name() string {
+	return "s3"
+}
+
+// Export uploads the batch as one object
+func (e *S3Exporter) Export(ctx context.Context, events []*domain.AuditLog) error {
+	body, err := gzipJSONL(events)
+	if err != nil {
+		return err
+	}
+
+	key, err := e.objectKey()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, e.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	e.sign(req, body, e.now())
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload audit batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to upload audit batch: unexpected status %s: %s", resp.Status, detail)
+	}
+	return nil
+}
+
+// objectKey names a new object after the upload time with a random suffix
+// so concurrent instances never overwrite each other
+func (e *S3Exporter) objectKey() (string, error) {
+	suffix, err := security.GenerateToken(8)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate object key: %w", err)
+	}
+
+	now := e.now().UTC()
+	key := fmt.Sprintf("%s/%s-%s.jsonl.gz", now.Format("2006/01/02"), now.Format("20060102T150405Z"), suffix)
+	if e.config.Prefix != "" {
+		key = e.config.Prefix + "/" + key
+	}
+	return key, nil
+}
+
+func (e *S3Exporter) objectURL(key string) string {
+	if e.config.Endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", e.config.Endpoint, e.config.Bucket, uriEncode(key))
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", e.config.Bucket, e.config.Region, uriEncode(key))
+}
+
+// sign adds a Signature Version 4 Authorization header covering the host,
+// the payload hash and the request time
+func (e *S3Exporter) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if e.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", e.config.SessionToken)
+	}
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	if e.config.SessionToken != "" {
+		headers["x-amz-security-token"] = e.config.SessionToken
+		signedHeaders += ";x-amz-security-token"
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + e.config.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := deriveSigningKey(e.config.SecretAccessKey, date, e.config.Region, "s3")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		e.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// deriveSigningKey derives the Signature Version 4 key for a day, region
+// and service
+func deriveSigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), []byte(date))
+	key = hmacSHA256(key, []byte(region))
+	key = hmacSHA256(key, []byte(service))
+	return hmacSHA256(key, []byte("aws4_request"))
+}
+
+// uriEncode percent-encodes an object path the way Signature Version 4
+// expects: everything but unreserved characters and slashes
+func uriEncode(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func gzipJSONL(events []*domain.AuditLog) ([]byte, error) {
+	data, err := EncodeJSONL(events)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress audit batch: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress audit batch: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package auditexport
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+func newTestHTTPClient(t *testing.T) *httpclient.Client {
+	t.Helper()
+
+	config := httpclient.DefaultConfig()
+	config.MaxRetries = 0
+	client, err := httpclient.New(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("httpclient.New() error = %v", err)
+	}
+	return client
+}
+
+func TestS3Exporter_Export(t *testing.T) {
+	var (
+		path  string
+		auth  string
+		lines []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		scanner := bufio.NewScanner(zr)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	}))
+	defer server.Close()
+
+	e := NewS3Exporter(newTestHTTPClient(t), S3Config{
+		Bucket:          "audit-archive",
+		Region:          "eu-west-1",
+		Prefix:          "/auth/",
+		Endpoint:        server.URL + "/",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	})
+	e.now = func() time.Time { return time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC) }
+
+	events := []*domain.AuditLog{
+		domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusSuccess),
+		domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusBlocked),
+	}
+	if err := e.Export(context.Background(), events); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if !strings.HasPrefix(path, "/audit-archive/auth/2026/03/01/20260301T123000Z-") || !strings.HasSuffix(path, ".jsonl.gz") {
+		t.Errorf("object path = %q", path)
+	}
+	wantCredential := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260301/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(auth, wantCredential) {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if len(lines) != 2 || !strings.Contains(lines[1], `"status":"blocked"`) {
+		t.Errorf("uploaded lines = %v", lines)
+	}
+}
+
+func TestS3Exporter_ObjectURL(t *testing.T) {
+	e := NewS3Exporter(nil, S3Config{Bucket: "audit-archive", Region: "eu-west-1"})
+
+	want := "https://audit-archive.s3.eu-west-1.amazonaws.com/audit/a%20b.jsonl.gz"
+	if got := e.objectURL("audit/a b.jsonl.gz"); got != want {
+		t.Errorf("objectURL() = %q, want %q", got, want)
+	}
+}
+
+func TestDeriveSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := deriveSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("deriveSigningKey() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,276 @@
+package auditexport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
+)
+
+// Config holds configuration for the streamer
+type Config struct {
+	QueueSize      int           // events buffered per exporter
+	BatchSize      int           // events per Export call
+	FlushInterval  time.Duration // longest time an event waits for its batch to fill
+	EnqueueTimeout time.Duration // how long Publish waits for a full queue; 0 drops immediately
+	MaxRetries     int
+	RetryDelay     time.Duration // doubled after every failed attempt
+	ExportTimeout  time.Duration // per Export attempt
+}
+
+// DefaultConfig returns default configuration
+func DefaultConfig() Config {
+	return Config{
+		QueueSize:      10000,
+		BatchSize:      500,
+		FlushInterval:  5 * time.Second,
+		EnqueueTimeout: 50 * time.Millisecond,
+		MaxRetries:     3,
+		RetryDelay:     time.Second,
+		ExportTimeout:  30 * time.Second,
+	}
+}
+
+// sink is the queue and worker state of one exporter
+type sink struct {
+	exporter Exporter
+	queue    chan *domain.AuditLog
+}
+
+// Streamer fans audit events out to exporters. Each exporter has its own
+// bounded queue and worker, so one slow sink does not hold back the others.
+type Streamer struct {
+	sinks   []*sink
+	config  Config
+	logger  *slog.Logger
+	metrics *metrics.AuditExportMetrics
+
+	// mu guards closed so Publish never sends on a closed queue
+	mu     sync.RWMutex
+	closed bool
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewStreamer creates a streamer for the given exporters
+func NewStreamer(exporters []Exporter, config Config, logger *slog.Logger) *Streamer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Streamer{
+		config: config,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, exporter := range exporters {
+		s.sinks = append(s.sinks, &sink{
+			exporter: exporter,
+			queue:    make(chan *domain.AuditLog, config.QueueSize),
+		})
+	}
+	return s
+}
+
+// SetMetrics records export lag, queue depth and event outcomes
+func (s *Streamer) SetMetrics(m *metrics.AuditExportMetrics) {
+	s.metrics = m
+}
+
+// Start starts one worker per exporter
+func (s *Streamer) Start() {
+	for _, sk := range s.sinks {
+		s.logger.Info("starting audit exporter",
+			"exporter", sk.exporter.Name(),
+			"queue_size", cap(sk.queue),
+			"batch_size", s.config.BatchSize,
+		)
+		s.wg.Add(1)
+		go s.run(sk)
+	}
+}
+
+// Stop stops accepting events, flushes the queues and waits for the workers
+// to finish. Exports still running after the timeout are canceled.
+func (s *Streamer) Stop(timeout time.Duration) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for _, sk := range s.sinks {
+		close(sk.queue)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+	case <-time.After(timeout):
+		s.cancel()
+		return fmt.Errorf("timeout waiting for audit exporters to flush")
+	}
+
+	// Release connections held by exporters such as syslog
+	for _, sk := range s.sinks {
+		if closer, ok := sk.exporter.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				s.logger.Warn("failed to close audit exporter", "exporter", sk.exporter.Name(), "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Publish queues an event for every exporter. When a queue is full Publish
+// waits up to the enqueue timeout for the exporter to catch up and then drops
+// the event for that exporter; the audit_logs table remains complete.
+func (s *Streamer) Publish(log *domain.AuditLog) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+
+	for _, sk := range s.sinks {
+		if !s.enqueue(sk, log) {
+			s.logger.Warn("audit export queue full, dropping event",
+				"exporter", sk.exporter.Name(),
+				"action", log.Action,
+				"audit_id", log.ID,
+			)
+			if s.metrics != nil {
+				s.metrics.RecordDropped(sk.exporter.Name())
+			}
+		}
+	}
+}
+
+func (s *Streamer) enqueue(sk *sink, log *domain.AuditLog) bool {
+	select {
+	case sk.queue <- log:
+		return true
+	default:
+	}
+
+	if s.config.EnqueueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(s.config.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case sk.queue <- log:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// run batches queued events until the queue is closed and drained
+func (s *Streamer) run(sk *sink) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*domain.AuditLog, 0, s.config.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			s.export(sk, batch)
+			batch = make([]*domain.AuditLog, 0, s.config.BatchSize)
+		}
+		if s.metrics != nil {
+			s.metrics.SetQueueDepth(sk.exporter.Name(), len(sk.queue))
+		}
+	}
+
+	for {
+		select {
+		case log, ok := <-sk.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, log)
+			if len(batch) >= s.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// export delivers a batch, retrying with exponential backoff
+func (s *Streamer) export(sk *sink, batch []*domain.AuditLog) {
+	name := sk.exporter.Name()
+	delay := s.config.RetryDelay
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(s.ctx, s.config.ExportTimeout)
+		err = sk.exporter.Export(ctx, batch)
+		cancel()
+		if err == nil {
+			if s.metrics != nil {
+				now := time.Now()
+				lags := make([]time.Duration, len(batch))
+				for i, log := range batch {
+					lags[i] = now.Sub(log.CreatedAt)
+				}
+				s.metrics.RecordExported(name, lags)
+			}
+			return
+		}
+
+		s.logger.Warn("audit export failed",
+			"exporter", name,
+			"events", len(batch),
+			"attempt", attempt+1,
+			"error", err,
+		)
+
+		if attempt == s.config.MaxRetries || !s.wait(delay) {
+			break
+		}
+		delay *= 2
+	}
+
+	s.logger.Error("audit export failed after retries",
+		"exporter", name,
+		"events", len(batch),
+		"error", err,
+	)
+	if s.metrics != nil {
+		s.metrics.RecordFailed(name, len(batch))
+	}
+}
+
+// wait sleeps before a retry and reports false when the streamer was stopped
+func (s *Streamer) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
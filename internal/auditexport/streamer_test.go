@@ -0,0 +1,181 @@
+package auditexport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
+)
+
+// recordingExporter keeps exported batches and fails the first failures calls
+type recordingExporter struct {
+	mu       sync.Mutex
+	batches  [][]*domain.AuditLog
+	failures int
+	block    chan struct{}
+}
+
+func (e *recordingExporter) Name() string {
+	return "test"
+}
+
+func (e *recordingExporter) Export(ctx context.Context, events []*domain.AuditLog) error {
+	if e.block != nil {
+		<-e.block
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.failures > 0 {
+		e.failures--
+		return errors.New("sink unavailable")
+	}
+	e.batches = append(e.batches, events)
+	return nil
+}
+
+func (e *recordingExporter) exported() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sizes := make([]int, len(e.batches))
+	for i, batch := range e.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func testStreamerConfig() Config {
+	config := DefaultConfig()
+	config.QueueSize = 10
+	config.BatchSize = 3
+	config.FlushInterval = time.Hour
+	config.EnqueueTimeout = 0
+	config.RetryDelay = time.Millisecond
+	return config
+}
+
+func newTestStreamer(exporter Exporter, config Config) (*Streamer, *metrics.AuditExportMetrics) {
+	m := metrics.NewAuditExportMetrics()
+	s := NewStreamer([]Exporter{exporter}, config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.SetMetrics(m)
+	return s, m
+}
+
+func exportedCount(m *metrics.AuditExportMetrics, result string) int64 {
+	return m.AuditExportEvents.WithLabels(map[string]string{"exporter": "test", "result": result}).Value()
+}
+
+func TestStreamer_Batching(t *testing.T) {
+	exporter := &recordingExporter{}
+	s, m := newTestStreamer(exporter, testStreamerConfig())
+	s.Start()
+
+	for i := 0; i < 7; i++ {
+		s.Publish(domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusSuccess))
+	}
+	if err := s.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	// Full batches are exported as they fill, the rest on Stop
+	got := exporter.exported()
+	if len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 1 {
+		t.Errorf("exported batch sizes = %v, want [3 3 1]", got)
+	}
+	if n := exportedCount(m, "exported"); n != 7 {
+		t.Errorf("exported events = %d, want 7", n)
+	}
+	if m.AuditExportLag.Count() != 7 {
+		t.Errorf("lag observations = %d, want 7", m.AuditExportLag.Count())
+	}
+
+	// Events published after Stop are ignored
+	s.Publish(domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusSuccess))
+}
+
+func TestStreamer_FlushInterval(t *testing.T) {
+	exporter := &recordingExporter{}
+	config := testStreamerConfig()
+	config.FlushInterval = 10 * time.Millisecond
+	s, _ := newTestStreamer(exporter, config)
+	s.Start()
+	defer s.Stop(time.Second)
+
+	s.Publish(domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusSuccess))
+
+	deadline := time.Now().Add(time.Second)
+	for len(exporter.exported()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("partial batch was not flushed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamer_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantExported int64
+		wantFailed   int64
+	}{
+		{name: "recovers after retry", failures: 2, wantExported: 1},
+		{name: "gives up after max retries", failures: 10, wantFailed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &recordingExporter{failures: tt.failures}
+			s, m := newTestStreamer(exporter, testStreamerConfig())
+			s.Start()
+
+			s.Publish(domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusFailure))
+			if err := s.Stop(time.Second); err != nil {
+				t.Fatalf("Stop() error = %v", err)
+			}
+
+			if n := exportedCount(m, "exported"); n != tt.wantExported {
+				t.Errorf("exported events = %d, want %d", n, tt.wantExported)
+			}
+			if n := exportedCount(m, "failed"); n != tt.wantFailed {
+				t.Errorf("failed events = %d, want %d", n, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestStreamer_Backpressure(t *testing.T) {
+	exporter := &recordingExporter{block: make(chan struct{})}
+	config := testStreamerConfig()
+	config.QueueSize = 3
+	config.BatchSize = 1
+	config.EnqueueTimeout = 10 * time.Millisecond
+	s, m := newTestStreamer(exporter, config)
+	s.Start()
+
+	// The worker holds one event in a blocked export and the queue holds
+	// three more; the rest are dropped once the enqueue timeout passes
+	for i := 0; i < 6; i++ {
+		s.Publish(domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusSuccess))
+	}
+
+	if n := exportedCount(m, "dropped"); n < 2 {
+		t.Errorf("dropped events = %d, want at least 2", n)
+	}
+
+	close(exporter.block)
+	if err := s.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if n := exportedCount(m, "exported") + exportedCount(m, "dropped"); n != 6 {
+		t.Errorf("exported and dropped events = %d, want 6", n)
+	}
+}
@@ -0,0 +1,168 @@
+package auditexport
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// Syslog severities used for audit events (RFC 5424 section 6.2.1)
+const (
+	syslogSeverityWarning = 4
+	syslogSeverityInfo    = 6
+)
+
+// syslogFacilityAuthPriv is the security/authorization facility reserved for
+// private messages
+const syslogFacilityAuthPriv = 10
+
+// SyslogExporter sends audit events as RFC 5424 messages with the event JSON
+// as the message body. TCP and TLS streams use octet-counting framing
+// (RFC 6587, RFC 5425); UDP sends one message per datagram.
+type SyslogExporter struct {
+	network  string // udp, tcp or tls
+	address  string
+	hostname string
+	appName  string
+	procID   string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogExporter creates a syslog exporter. The connection is opened on
+// the first export and reopened after write errors.
+func NewSyslogExporter(network, address, appName string) *SyslogExporter {
+	// An unknown hostname is sent as the NILVALUE
+	hostname, _ := os.Hostname()
+
+	return &SyslogExporter{
+		network:  network,
+		address:  address,
+		hostname: syslogHeaderField(hostname, 255),
+		appName:  syslogHeaderField(appName, 48),
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+}
+
+// Name identifies the exporter
+func (e *SyslogExporter) Name() string {
+	return "syslog"
+}
+
+// Export writes one syslog message per event
+func (e *SyslogExporter) Export(ctx context.Context, events []*domain.AuditLog) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		conn, err := e.dial(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog receiver: %w", err)
+		}
+		e.conn = conn
+	}
+
+	// Without a deadline the zero time clears any previous one
+	deadline, _ := ctx.Deadline()
+	if err := e.conn.SetWriteDeadline(deadline); err != nil {
+		return e.reset(err)
+	}
+
+	for _, log := range events {
+		msg, err := e.format(log)
+		if err != nil {
+			return err
+		}
+		if e.network != "udp" {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+		if _, err := e.conn.Write(msg); err != nil {
+			return e.reset(err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the connection to the receiver
+func (e *SyslogExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+func (e *SyslogExporter) dial(ctx context.Context) (net.Conn, error) {
+	if e.network == "tls" {
+		dialer := &tls.Dialer{Config: &tls.Config{MinVersion: tls.VersionTLS12}}
+		return dialer.DialContext(ctx, "tcp", e.address)
+	}
+
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, e.network, e.address)
+}
+
+// reset drops a broken connection so the next export reconnects
+func (e *SyslogExporter) reset(err error) error {
+	e.conn.Close()
+	e.conn = nil
+	return fmt.Errorf("failed to write to syslog receiver: %w", err)
+}
+
+// format renders an event as
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG
+func (e *SyslogExporter) format(log *domain.AuditLog) ([]byte, error) {
+	body, err := json.Marshal(NewEvent(log))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	severity := syslogSeverityInfo
+	if log.Status != domain.AuditStatusSuccess {
+		severity = syslogSeverityWarning
+	}
+
+	header := fmt.Sprintf("<%d>1 %s %s %s %s %s - ",
+		syslogFacilityAuthPriv*8+severity,
+		log.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		e.hostname,
+		e.appName,
+		e.procID,
+		syslogHeaderField(log.Action, 32),
+	)
+
+	return append([]byte(header), body...), nil
+}
+
+// syslogHeaderField makes a value safe for a header field, which must be
+// printable US-ASCII without spaces. Empty values become the NILVALUE.
+func syslogHeaderField(value string, maxLen int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+
+	if len(field) > maxLen {
+		field = field[:maxLen]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
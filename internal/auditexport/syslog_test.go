@@ -0,0 +1,107 @@
+package auditexport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestSyslogExporter_Format(t *testing.T) {
+	e := NewSyslogExporter("tcp", "127.0.0.1:514", "auth service")
+	e.hostname = "auth-1"
+	e.procID = "42"
+
+	log := domain.NewAuditLog(domain.AuditActionSigningKeyRevoke, domain.AuditStatusSuccess)
+	log.ID = "17"
+	log.CreatedAt = time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC)
+
+	msg, err := e.format(log)
+	if err != nil {
+		t.Fatalf("format() error = %v", err)
+	}
+
+	// authpriv (10) * 8 + informational (6)
+	wantHeader := "<86>1 2026-03-01T12:30:00.123456Z auth-1 auth_service 42 admin_signing_key_revoked - "
+	if !strings.HasPrefix(string(msg), wantHeader) {
+		t.Fatalf("message = %q, want header %q", msg, wantHeader)
+	}
+
+	var event Event
+	if err := json.Unmarshal(msg[len(wantHeader):], &event); err != nil {
+		t.Fatalf("message body is not JSON: %v", err)
+	}
+	if event.ID != "17" || event.Action != domain.AuditActionSigningKeyRevoke {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	// Failures are logged as warnings
+	log.Status = domain.AuditStatusBlocked
+	if msg, _ := e.format(log); !strings.HasPrefix(string(msg), "<84>1 ") {
+		t.Errorf("blocked event priority = %q, want <84>", msg[:6])
+	}
+}
+
+func TestSyslogExporter_ExportTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Octet-counting framing: MSG-LEN SP SYSLOG-MSG
+		r := bufio.NewReader(conn)
+		for {
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(size))
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	e := NewSyslogExporter("tcp", ln.Addr().String(), "auth")
+	defer e.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	events := []*domain.AuditLog{
+		domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusSuccess),
+		domain.NewAuditLog(domain.AuditActionDormancyPurge, domain.AuditStatusSuccess),
+	}
+	if err := e.Export(ctx, events); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	for _, want := range []string{domain.AuditActionLoginRisk, domain.AuditActionDormancyPurge} {
+		select {
+		case msg := <-received:
+			if !strings.Contains(msg, " "+want+" - {") {
+				t.Errorf("message %q does not carry MSGID %s", msg, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("syslog message not received")
+		}
+	}
+}
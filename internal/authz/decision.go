@@ -0,0 +1,27 @@
+package authz
+
+import "context"
+
+// Subject is the authenticated caller of a request
+type Subject struct {
+	ID            string   `json:"id"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+	Scopes        []string `json:"scopes"`
+}
+
+// Input is the request context a policy engine decides on
+type Input struct {
+	Method string   `json:"method"`
+	Path   string   `json:"path"`
+	Route  string   `json:"route"`            // pattern the request matched, e.g. "GET /api/v1/admin/dormancy/runs/{id}"
+	User   *Subject `json:"user"`             // nil when the request carries no valid access token
+	Tenant string   `json:"tenant,omitempty"` // from a trusted proxy header
+}
+
+// Decider decides whether a request is allowed, e.g. by evaluating a Rego
+// policy. A non-nil error means no decision could be made.
+type Decider interface {
+	Decide(ctx context.Context, input Input) (bool, error)
+}
@@ -0,0 +1,81 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// maxDecisionSize bounds the size of a decision response
+const maxDecisionSize = 1 << 20
+
+// OPAClient asks an OPA server, typically a sidecar, for decisions through
+// its Data API
+type OPAClient struct {
+	client *httpclient.Client
+	url    string
+}
+
+// NewOPAClient creates a client for the decision document at url, e.g.
+// http://localhost:8181/v1/data/authz/allow
+func NewOPAClient(client *httpclient.Client, url string) *OPAClient {
+	return &OPAClient{client: client, url: url}
+}
+
+// opaResponse is the Data API response. Result is absent when the document
+// is undefined, for example when no rule matched.
+type opaResponse struct {
+	Result *json.RawMessage `json:"result"`
+}
+
+// Decide posts the input to OPA. The document must be a boolean, or an
+// object with a boolean allow field; an undefined document denies.
+func (c *OPAClient) Decide(ctx context.Context, input Input) (bool, error) {
+	body, err := json.Marshal(map[string]Input{"input": input})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode OPA input: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create OPA request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to query OPA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("failed to query OPA: unexpected status %s: %s", resp.Status, detail)
+	}
+
+	var decision opaResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDecisionSize)).Decode(&decision); err != nil {
+		return false, fmt.Errorf("failed to decode OPA response: %w", err)
+	}
+	if decision.Result == nil {
+		return false, nil
+	}
+
+	var allowed bool
+	if err := json.Unmarshal(*decision.Result, &allowed); err == nil {
+		return allowed, nil
+	}
+	var document struct {
+		Allow *bool `json:"allow"`
+	}
+	if err := json.Unmarshal(*decision.Result, &document); err != nil || document.Allow == nil {
+		return false, errors.New("OPA decision is neither a boolean nor an object with a boolean allow field")
+	}
+	return *document.Allow, nil
+}
@@ -0,0 +1,75 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+func TestOPAClient_Decide(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "boolean allow", status: http.StatusOK, body: `{"result":true}`, want: true},
+		{name: "boolean deny", status: http.StatusOK, body: `{"result":false}`},
+		{name: "object allow", status: http.StatusOK, body: `{"result":{"allow":true,"reason":"owner"}}`, want: true},
+		{name: "undefined document", status: http.StatusOK, body: `{}`},
+		{name: "object without allow", status: http.StatusOK, body: `{"result":{"reason":"owner"}}`, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, body: `{"code":"internal_error"}`, wantErr: true},
+		{name: "malformed response", status: http.StatusOK, body: `not json`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Input Input `json:"input"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v1/data/authz/allow" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode input: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			config := httpclient.DefaultConfig()
+			config.MaxRetries = 0
+			client, err := httpclient.New(config, slog.Default())
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			opa := NewOPAClient(client, server.URL+"/v1/data/authz/allow")
+
+			input := Input{
+				Method: http.MethodGet,
+				Path:   "/api/v1/admin/dormancy/runs/42",
+				Route:  "GET /api/v1/admin/dormancy/runs/{id}",
+				User:   &Subject{ID: "user-123", Roles: []string{"ops"}, Scopes: []string{}},
+				Tenant: "acme",
+			}
+			allowed, err := opa.Decide(context.Background(), input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decide() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if allowed != tt.want {
+				t.Errorf("Decide() = %v, want %v", allowed, tt.want)
+			}
+			if got.Input.Route != input.Route || got.Input.Tenant != "acme" || got.Input.User == nil || got.Input.User.Roles[0] != "ops" {
+				t.Errorf("OPA received input %+v", got.Input)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Package authz maps every route of the API to the access it requires,
+// loaded from an operator-supplied policy file
+package authz
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// adminPrefix is the path prefix of the admin API, which authenticates
+// callers with the operator token or a signing key instead of access tokens
+const adminPrefix = "/api/v1/admin/"
+
+// Access is the kind of caller a route admits
+type Access string
+
+const (
+	// AccessPublic admits anyone
+	AccessPublic Access = "public"
+	// AccessUser admits callers with a valid access token carrying the
+	// rule's roles and scopes
+	AccessUser Access = "user"
+	// AccessAdmin admits callers authenticated by the admin API
+	AccessAdmin Access = "admin"
+)
+
+// Rule is the access required by one route
+type Rule struct {
+	Route  string   `yaml:"route"`            // method and path as registered, e.g. "GET /api/v1/auth/me"
+	Access Access   `yaml:"access"`           // public, user or admin
+	Roles  []string `yaml:"roles,omitempty"`  // the token needs at least one of them
+	Scopes []string `yaml:"scopes,omitempty"` // the token needs all of them
+}
+
+// Allows reports whether a token with the given roles and scopes satisfies
+// the rule
+func (r Rule) Allows(roles, scopes []string) bool {
+	if len(r.Roles) > 0 && !slices.ContainsFunc(r.Roles, func(role string) bool {
+		return slices.Contains(roles, role)
+	}) {
+		return false
+	}
+	for _, scope := range r.Scopes {
+		if !slices.Contains(scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Policy is a set of rules keyed by route
+type Policy struct {
+	rules map[string]Rule
+}
+
+// policyFile is the YAML layout of a policy file
+type policyFile struct {
+	Routes []Rule `yaml:"routes"`
+}
+
+// LoadPolicy reads a policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses a YAML policy. Unknown fields, duplicate routes and
+// roles or scopes on routes not admitting users are rejected.
+func ParsePolicy(data []byte) (*Policy, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var file policyFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	policy := &Policy{rules: make(map[string]Rule, len(file.Routes))}
+	for _, rule := range file.Routes {
+		route, err := normalizeRoute(rule.Route)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := policy.rules[route]; ok {
+			return nil, fmt.Errorf("duplicate route %q", route)
+		}
+
+		switch rule.Access {
+		case AccessPublic, AccessAdmin:
+			if len(rule.Roles) > 0 || len(rule.Scopes) > 0 {
+				return nil, fmt.Errorf("route %q: roles and scopes require access %q", route, AccessUser)
+			}
+		case AccessUser:
+		default:
+			return nil, fmt.Errorf("route %q: access must be %s, %s or %s", route, AccessPublic, AccessUser, AccessAdmin)
+		}
+
+		rule.Route = route
+		policy.rules[route] = rule
+	}
+
+	return policy, nil
+}
+
+// normalizeRoute checks a route is a method and an absolute path and
+// collapses the whitespace between them
+func normalizeRoute(route string) (string, error) {
+	fields := strings.Fields(route)
+	if len(fields) != 2 || fields[0] != strings.ToUpper(fields[0]) || !strings.HasPrefix(fields[1], "/") {
+		return "", fmt.Errorf("route %q must be a method and a path, e.g. \"GET /api/v1/auth/me\"", route)
+	}
+	return fields[0] + " " + fields[1], nil
+}
+
+// Rule returns the rule of a registered route pattern
+func (p *Policy) Rule(route string) (Rule, bool) {
+	rule, ok := p.rules[route]
+	return rule, ok
+}
+
+// Validate checks that every registered route has a rule, and that admin
+// access is used for exactly the admin API. Rules for routes that are not
+// registered, such as those of disabled features, are ignored.
+func (p *Policy) Validate(routes []string) error {
+	var missing, mismatched []string
+	for _, route := range routes {
+		rule, ok := p.rules[route]
+		if !ok {
+			missing = append(missing, route)
+			continue
+		}
+		if isAdminRoute(route) != (rule.Access == AccessAdmin) {
+			mismatched = append(mismatched, route)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("routes without a policy rule: %s", strings.Join(missing, ", "))
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("routes must use access %q if and only if they are under %s: %s",
+			AccessAdmin, adminPrefix, strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// isAdminRoute reports whether a route pattern belongs to the admin API
+func isAdminRoute(route string) bool {
+	_, path, _ := strings.Cut(route, " ")
+	return strings.HasPrefix(path, adminPrefix)
+}
@@ -0,0 +1,141 @@
+package authz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{
+			name: "valid policy",
+			policy: `
+routes:
+  - route: POST   /api/v1/auth/login
+    access: public
+  - route: GET /api/v1/auth/me
+    access: user
+    scopes: [profile]
+  - route: GET /api/v1/admin/read-only
+    access: admin
+`,
+		},
+		{name: "unknown field", policy: "routes:\n  - route: GET /health\n    acess: public\n", wantErr: "acess"},
+		{name: "unknown access", policy: "routes:\n  - route: GET /health\n    access: everyone\n", wantErr: "access must be"},
+		{name: "missing access", policy: "routes:\n  - route: GET /health\n", wantErr: "access must be"},
+		{name: "route without method", policy: "routes:\n  - route: /health\n    access: public\n", wantErr: "method and a path"},
+		{name: "lowercase method", policy: "routes:\n  - route: get /health\n    access: public\n", wantErr: "method and a path"},
+		{
+			name:    "duplicate route",
+			policy:  "routes:\n  - route: GET /health\n    access: public\n  - route: GET  /health\n    access: user\n",
+			wantErr: "duplicate route",
+		},
+		{
+			name:    "scopes on public route",
+			policy:  "routes:\n  - route: GET /health\n    access: public\n    scopes: [ops]\n",
+			wantErr: "roles and scopes require",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePolicy([]byte(tt.policy))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParsePolicy() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePolicy() error = %v", err)
+			}
+			if rule, ok := policy.Rule("POST /api/v1/auth/login"); !ok || rule.Access != AccessPublic {
+				t.Errorf("Rule() = %+v, %v; want normalized public rule", rule, ok)
+			}
+		})
+	}
+}
+
+func TestRule_Allows(t *testing.T) {
+	rule := Rule{Access: AccessUser, Roles: []string{"support", "ops"}, Scopes: []string{"users:read", "users:write"}}
+
+	tests := []struct {
+		name   string
+		roles  []string
+		scopes []string
+		want   bool
+	}{
+		{name: "one role and all scopes", roles: []string{"ops"}, scopes: []string{"users:write", "users:read"}, want: true},
+		{name: "missing scope", roles: []string{"ops"}, scopes: []string{"users:read"}},
+		{name: "missing role", roles: []string{"billing"}, scopes: []string{"users:read", "users:write"}},
+		{name: "nothing granted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Allows(tt.roles, tt.scopes); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !(Rule{Access: AccessUser}).Allows(nil, nil) {
+		t.Error("rule without roles or scopes should allow any token")
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+routes:
+  - route: GET /health
+    access: public
+  - route: GET /api/v1/auth/me
+    access: user
+  - route: GET /api/v1/admin/read-only
+    access: admin
+  - route: GET /api/v1/quota
+    access: admin
+`))
+	if err != nil {
+		t.Fatalf("ParsePolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		routes  []string
+		wantErr string
+	}{
+		{name: "all routes covered", routes: []string{"GET /health", "GET /api/v1/auth/me", "GET /api/v1/admin/read-only"}},
+		{name: "missing route", routes: []string{"GET /health", "PATCH /api/v1/auth/me"}, wantErr: "PATCH /api/v1/auth/me"},
+		{name: "admin access outside admin API", routes: []string{"GET /api/v1/quota"}, wantErr: "GET /api/v1/quota"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.routes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error naming %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy_Example(t *testing.T) {
+	policy, err := LoadPolicy("../../examples/route-policy.yaml")
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+	if rule, ok := policy.Rule("DELETE /api/v1/admin/signing-keys/{id}"); !ok || rule.Access != AccessAdmin {
+		t.Errorf("Rule() = %+v, %v; want admin rule", rule, ok)
+	}
+}
@@ -0,0 +1,52 @@
+// Package clock abstracts the passing of time, so expiry, TTL and
+// throttling can be tested by advancing a fake clock instead of sleeping.
+package clock
+
+import "time"
+
+// Clock tells the time and waits for it to pass
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the returned channel once d has passed
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a timer that fires once d has passed
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a stoppable single-shot timer, like time.Timer
+type Timer interface {
+	// C receives the time when the timer fires
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, returning false if it already
+	// fired or was stopped
+	Stop() bool
+	// Reset makes the timer fire once d has passed from now, returning
+	// whether it was still pending
+	Reset(d time.Duration) bool
+}
+
+// Real is the system clock
+type Real struct{}
+
+// Now returns time.Now()
+func (Real) Now() time.Time { return time.Now() }
+
+// After waits with time.After
+func (Real) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// NewTimer wraps time.NewTimer
+func (Real) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// OrReal returns c, or the system clock when c is nil
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real{}
+	}
+	return c
+}
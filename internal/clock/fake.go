@@ -0,0 +1,131 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a clock that only moves when advanced. Timers fire, in deadline
+// order, when Advance moves the time past them.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond // signaled whenever timers are added or removed
+	now     time.Time
+	timers  []*fakeTimer // pending, in no particular order
+}
+
+// NewFake creates a fake clock set to now
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After fires once the clock is advanced by d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer creates a timer that fires once the clock is advanced by d. A
+// timer for zero or less fires at once.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule(t, d)
+	return t
+}
+
+// Advance moves the time forward by d, firing the timers that come due
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	end := f.now.Add(d)
+	sort.Slice(f.timers, func(i, j int) bool { return f.timers[i].deadline.Before(f.timers[j].deadline) })
+	due := 0
+	for due < len(f.timers) && !f.timers[due].deadline.After(end) {
+		t := f.timers[due]
+		f.now = t.deadline
+		t.fire(f.now)
+		due++
+	}
+	f.timers = f.timers[due:]
+	f.now = end
+	if due > 0 {
+		f.changed.Broadcast()
+	}
+}
+
+// BlockUntil waits until n timers are pending, such as those of goroutines
+// that have to start waiting before the clock is advanced
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+// schedule arms t to fire d from now. The caller holds mu.
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	if d <= 0 {
+		t.fire(f.now)
+		return
+	}
+	t.deadline = f.now.Add(d)
+	f.timers = append(f.timers, t)
+	f.changed.Broadcast()
+}
+
+// unschedule disarms t, reporting whether it was pending. The caller holds
+// mu.
+func (f *Fake) unschedule(t *fakeTimer) bool {
+	for i, pending := range f.timers {
+		if pending == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	pending := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+	return pending
+}
+
+// fire sends now unless an earlier firing is still unread, like a
+// time.Timer whose value was never received
+func (t *fakeTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,83 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func fired(t Timer) bool {
+	select {
+	case <-t.C():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFake(start)
+
+	short := clock.NewTimer(time.Second)
+	long := clock.NewTimer(time.Minute)
+	stopped := clock.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Error("Stop() of a pending timer = false")
+	}
+
+	clock.Advance(999 * time.Millisecond)
+	if fired(short) {
+		t.Error("timer fired before its deadline")
+	}
+	clock.Advance(time.Millisecond)
+	if !fired(short) || fired(long) || fired(stopped) {
+		t.Error("only the timer that came due should fire")
+	}
+	if got := clock.Now(); !got.Equal(start.Add(time.Second)) {
+		t.Errorf("Now() = %v, want %v", got, start.Add(time.Second))
+	}
+
+	// Reset rearms from the current time
+	if short.Reset(time.Second) {
+		t.Error("Reset() of a fired timer = true")
+	}
+	clock.Advance(time.Minute)
+	if !fired(short) || !fired(long) {
+		t.Error("timers passed by a long Advance should fire")
+	}
+
+	select {
+	case <-clock.After(0):
+	default:
+		t.Error("After(0) should fire at once")
+	}
+}
+
+func TestFake_TimersFireInOrder(t *testing.T) {
+	clock := NewFake(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	later := clock.NewTimer(2 * time.Second)
+	sooner := clock.NewTimer(time.Second)
+
+	clock.Advance(time.Hour)
+	first, second := <-sooner.C(), <-later.C()
+	if !first.Before(second) {
+		t.Errorf("timers fired at %v and %v, want each at its deadline", first, second)
+	}
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	clock := NewFake(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	done := make(chan struct{})
+	go func() {
+		<-clock.After(time.Minute)
+		close(done)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("goroutine waiting on the clock was not woken")
+	}
+}
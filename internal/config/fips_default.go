@@ -0,0 +1,6 @@
+//go:build !fips
+
+package config
+
+// fipsBuild is false unless the binary is built with -tags fips
+const fipsBuild = false
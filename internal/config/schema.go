@@ -0,0 +1,11 @@
+package config
+
+import _ "embed"
+
+// JSONSchema is the JSON Schema of a config file: a mapping of the
+// environment variables Load reads to their values. It is generated by
+// cmd/configschemagen; run make generate-config-schema after adding a
+// variable.
+//
+//go:embed schema.json
+var JSONSchema []byte
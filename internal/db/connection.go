@@ -4,68 +4,194 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/n1rocket/go-auth-jwt/internal/config"
 )
 
-// DB wraps the SQL database connection
+// DefaultDrainTimeout is how long a pool replaced by Rotate may keep serving
+// the queries and transactions already running on it
+const DefaultDrainTimeout = 30 * time.Second
+
+// poolSettings configures every pool a DB opens, so a rotated pool behaves
+// like the one it replaces
+type poolSettings struct {
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+}
+
+// DB wraps the SQL database connection pool. The pool can be replaced while
+// serving, by Rotate, so callers go through DB's methods rather than holding
+// on to the *sql.DB.
 type DB struct {
-	*sql.DB
+	pool         atomic.Pointer[sql.DB]
+	driver       string
+	settings     poolSettings
+	drainTimeout time.Duration
+
+	rotateMu sync.Mutex
+	draining sync.WaitGroup
 }
 
 // Connect creates a new database connection pool using a DSN string
 func Connect(dsn string) (*DB, error) {
-	db, err := sql.Open("pgx", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
 	// Default connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-	db.SetConnMaxIdleTime(1 * time.Minute)
+	return open("pgx", dsn, poolSettings{
+		maxOpenConns:    25,
+		maxIdleConns:    5,
+		connMaxLifetime: 5 * time.Minute,
+		connMaxIdleTime: 1 * time.Minute,
+	})
+}
 
-	// Verify connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// New creates a new database connection pool
+func New(cfg *config.DatabaseConfig) (*DB, error) {
+	return open("pgx", cfg.DSN, poolSettings{
+		maxOpenConns:    cfg.MaxOpenConns,
+		maxIdleConns:    cfg.MaxIdleConns,
+		connMaxLifetime: cfg.ConnMaxLifetime,
+		connMaxIdleTime: cfg.ConnMaxIdleTime,
+	})
+}
 
-	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+func open(driver, dsn string, settings poolSettings) (*DB, error) {
+	db := &DB{driver: driver, settings: settings, drainTimeout: DefaultDrainTimeout}
+	pool, err := db.openPool(context.Background(), dsn)
+	if err != nil {
+		return nil, err
 	}
+	db.pool.Store(pool)
+	return db, nil
+}
 
-	return &DB{db}, nil
+// wrap returns a DB serving pool, for tests
+func wrap(pool *sql.DB) *DB {
+	db := &DB{drainTimeout: DefaultDrainTimeout}
+	db.pool.Store(pool)
+	return db
 }
 
-// New creates a new database connection pool
-func New(cfg *config.DatabaseConfig) (*DB, error) {
-	db, err := sql.Open("pgx", cfg.DSN)
+// openPool opens a pool with db's settings and verifies it can connect
+func (db *DB) openPool(ctx context.Context, dsn string) (*sql.DB, error) {
+	pool, err := sql.Open(db.driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Configure connection pool
-	db.SetMaxOpenConns(cfg.MaxOpenConns)
-	db.SetMaxIdleConns(cfg.MaxIdleConns)
-	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
-	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	pool.SetMaxOpenConns(db.settings.maxOpenConns)
+	pool.SetMaxIdleConns(db.settings.maxIdleConns)
+	pool.SetConnMaxLifetime(db.settings.connMaxLifetime)
+	pool.SetConnMaxIdleTime(db.settings.connMaxIdleTime)
 
 	// Verify connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if err := db.PingContext(ctx); err != nil {
+	if err := pool.PingContext(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{db}, nil
+	return pool, nil
+}
+
+// SetDrainTimeout sets how long a pool replaced by Rotate may keep serving
+// the queries and transactions already running on it
+func (db *DB) SetDrainTimeout(timeout time.Duration) {
+	db.drainTimeout = timeout
+}
+
+// Rotate connects with dsn, typically new credentials, and swaps the new
+// pool in for the current one. Queries and transactions already running on
+// the old pool finish there; it is closed once idle or after the drain
+// timeout. When the new pool cannot connect the current one keeps serving.
+func (db *DB) Rotate(ctx context.Context, dsn string) error {
+	db.rotateMu.Lock()
+	defer db.rotateMu.Unlock()
+
+	pool, err := db.openPool(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	old := db.pool.Swap(pool)
+
+	db.draining.Add(1)
+	go func() {
+		defer db.draining.Done()
+		drain(old, db.drainTimeout)
+	}()
+	return nil
+}
+
+// drain closes pool once none of its connections are in use, or after
+// timeout. Close itself waits for queries already running on the server.
+func drain(pool *sql.DB, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for range ticker.C {
+		if pool.Stats().InUse == 0 || !time.Now().Before(deadline) {
+			break
+		}
+	}
+	pool.Close()
+}
+
+// Pool returns the current connection pool. It is replaced by Rotate, so
+// hold on to it only for work that ends before the next rotation, such as
+// migrations at startup.
+func (db *DB) Pool() *sql.DB {
+	return db.pool.Load()
+}
+
+// ExecContext executes a query without returning rows on the current pool
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.Pool().ExecContext(ctx, query, args...)
+}
+
+// PrepareContext creates a prepared statement on the current pool
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.Pool().PrepareContext(ctx, query)
+}
+
+// QueryContext executes a query returning rows on the current pool
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.Pool().QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query returning at most one row on the
+// current pool
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.Pool().QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction on the current pool. The transaction stays
+// on that pool until it ends, even if the pool is rotated out meanwhile.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.Pool().BeginTx(ctx, opts)
+}
+
+// Conn reserves a connection of the current pool for session state, such as
+// advisory locks. Like a transaction, it stays on that pool until closed.
+func (db *DB) Conn(ctx context.Context) (*sql.Conn, error) {
+	return db.Pool().Conn(ctx)
+}
+
+// PingContext verifies a connection to the database is still alive
+func (db *DB) PingContext(ctx context.Context) error {
+	return db.Pool().PingContext(ctx)
 }
 
-// Close closes the database connection
+// Close closes the database connection, after the pools being drained
 func (db *DB) Close() error {
-	return db.DB.Close()
+	db.draining.Wait()
+	return db.Pool().Close()
 }
 
 // Health checks the database connection health
@@ -89,7 +215,7 @@ func (db *DB) Health(ctx context.Context) error {
 
 // Stats returns database statistics
 func (db *DB) Stats() sql.DBStats {
-	return db.DB.Stats()
+	return db.Pool().Stats()
 }
 
 // TestConnection tests the database connection
@@ -89,7 +89,7 @@ func TestDB_Health(t *testing.T) {
 			}
 			defer mockDB.Close()
 
-			db := &DB{mockDB}
+			db := wrap(mockDB)
 			tt.setupMock(mock)
 
 			ctx := context.Background()
@@ -117,7 +117,7 @@ func TestDB_Close(t *testing.T) {
 		t.Fatalf("Failed to create mock: %v", err)
 	}
 
-	db := &DB{mockDB}
+	db := wrap(mockDB)
 
 	// Expect close to be called
 	mock.ExpectClose()
@@ -142,7 +142,7 @@ func TestDB_Stats(t *testing.T) {
 	}
 	defer mockDB.Close()
 
-	db := &DB{mockDB}
+	db := wrap(mockDB)
 
 	// Call Stats
 	stats := db.Stats()
@@ -184,7 +184,7 @@ func TestDB_TestConnection(t *testing.T) {
 			}
 			defer mockDB.Close()
 
-			db := &DB{mockDB}
+			db := wrap(mockDB)
 			tt.setupMock(mock)
 
 			ctx := context.Background()
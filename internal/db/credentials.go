@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Credential rotation results, as recorded in metrics
+const (
+	CredentialRotationSuccess = "success"
+	CredentialRotationFailure = "failure"
+)
+
+// CredentialRotationRecorder records the outcome of credential rotations
+type CredentialRotationRecorder interface {
+	RecordCredentialRotation(result string)
+}
+
+// ReadCredentials returns the DSN in the credentials file at path, such as a
+// mounted secret
+func ReadCredentials(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read database credentials: %w", err)
+	}
+	dsn := strings.TrimSpace(string(data))
+	if dsn == "" {
+		return "", fmt.Errorf("database credentials file %s is empty", path)
+	}
+	return dsn, nil
+}
+
+// CredentialRotator rotates a DB to the DSN in a credentials file whenever
+// the file changes, so credentials issued by a secrets manager can be
+// replaced without a restart. The old credentials must stay valid until the
+// old pool has drained.
+type CredentialRotator struct {
+	db       *DB
+	path     string
+	logger   *slog.Logger
+	recorder CredentialRotationRecorder
+
+	modTime time.Time
+	dsn     string
+}
+
+// NewCredentialRotator creates a rotator of db, which is connected with
+// dsn, to the credentials file at path
+func NewCredentialRotator(db *DB, path, dsn string, logger *slog.Logger) *CredentialRotator {
+	return &CredentialRotator{
+		db:     db,
+		path:   path,
+		logger: logger,
+		dsn:    dsn,
+	}
+}
+
+// SetMetrics records the outcome of every rotation
+func (r *CredentialRotator) SetMetrics(recorder CredentialRotationRecorder) {
+	r.recorder = recorder
+}
+
+// Run rotates the pool when the credentials file holds a new DSN. A failed
+// rotation leaves the current pool serving and is retried on the next run.
+// It is meant to run periodically.
+func (r *CredentialRotator) Run(ctx context.Context) error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		r.record(CredentialRotationFailure)
+		return fmt.Errorf("failed to read database credentials: %w", err)
+	}
+	if info.ModTime().Equal(r.modTime) {
+		return nil
+	}
+
+	dsn, err := ReadCredentials(r.path)
+	if err != nil {
+		r.record(CredentialRotationFailure)
+		return err
+	}
+	if dsn != r.dsn {
+		if err := r.db.Rotate(ctx, dsn); err != nil {
+			r.record(CredentialRotationFailure)
+			return fmt.Errorf("failed to rotate database credentials: %w", err)
+		}
+		r.record(CredentialRotationSuccess)
+		r.logger.InfoContext(ctx, "database credentials rotated", "drain_timeout", r.db.drainTimeout)
+		r.dsn = dsn
+	}
+	r.modTime = info.ModTime()
+	return nil
+}
+
+func (r *CredentialRotator) record(result string) {
+	if r.recorder != nil {
+		r.recorder.RecordCredentialRotation(result)
+	}
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type rotationRecorder struct {
+	results []string
+}
+
+func (r *rotationRecorder) RecordCredentialRotation(result string) {
+	r.results = append(r.results, result)
+}
+
+// writeCredentials writes dsn to path with a modification time the
+// rotator has not seen yet
+func writeCredentials(t *testing.T, path, dsn string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(dsn+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write credentials: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set credentials mod time: %v", err)
+	}
+}
+
+func TestCredentialRotator_Run(t *testing.T) {
+	oldMockDB, oldMock, err := sqlmock.NewWithDSN("rotate-old")
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer oldMockDB.Close()
+	newMockDB, newMock, err := sqlmock.NewWithDSN("rotate-new")
+	if err != nil {
+		t.Fatalf("Failed to create mock: %v", err)
+	}
+	defer newMockDB.Close()
+
+	path := filepath.Join(t.TempDir(), "dsn")
+	modTime := time.Now().Add(-time.Hour)
+	writeCredentials(t, path, "rotate-old", modTime)
+
+	db, err := open("sqlmock", "rotate-old", poolSettings{maxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	db.SetDrainTimeout(time.Second)
+	recorder := &rotationRecorder{}
+	rotator := NewCredentialRotator(db, path, "rotate-old", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	rotator.SetMetrics(recorder)
+	ctx := context.Background()
+
+	// The credentials already in use are not rotated
+	if err := rotator.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(recorder.results) != 0 {
+		t.Fatalf("results after first run = %v, want none", recorder.results)
+	}
+
+	// A transaction started before the rotation finishes on the old pool
+	oldMock.ExpectBegin()
+	oldMock.ExpectCommit()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	oldPool := db.Pool()
+
+	modTime = modTime.Add(time.Minute)
+	writeCredentials(t, path, "rotate-new", modTime)
+	if err := rotator.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if db.Pool() == oldPool {
+		t.Fatal("pool was not swapped")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Errorf("Commit() on the old pool error = %v", err)
+	}
+
+	newMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	var one int
+	if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		t.Errorf("query on the new pool error = %v", err)
+	}
+
+	// Credentials that cannot connect leave the current pool serving, and
+	// are retried on the next run
+	newPool := db.Pool()
+	modTime = modTime.Add(time.Minute)
+	writeCredentials(t, path, "rotate-unknown", modTime)
+	for i := 0; i < 2; i++ {
+		if err := rotator.Run(ctx); err == nil {
+			t.Fatal("Run() with unusable credentials error = nil")
+		}
+	}
+	if db.Pool() != newPool {
+		t.Error("pool was swapped for unusable credentials")
+	}
+
+	want := []string{CredentialRotationSuccess, CredentialRotationFailure, CredentialRotationFailure}
+	if len(recorder.results) != len(want) {
+		t.Fatalf("results = %v, want %v", recorder.results, want)
+	}
+	for i := range want {
+		if recorder.results[i] != want[i] {
+			t.Errorf("results = %v, want %v", recorder.results, want)
+		}
+	}
+
+	db.Close()
+	if err := oldMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations on the old pool: %v", err)
+	}
+	if err := newMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations on the new pool: %v", err)
+	}
+}
+
+func TestReadCredentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dsn")
+	writeCredentials(t, path, "  postgres://app@db/auth  ", time.Now())
+
+	dsn, err := ReadCredentials(path)
+	if err != nil || dsn != "postgres://app@db/auth" {
+		t.Errorf("ReadCredentials() = %q, %v", dsn, err)
+	}
+
+	writeCredentials(t, path, "", time.Now())
+	if _, err := ReadCredentials(path); err == nil {
+		t.Error("ReadCredentials() of an empty file error = nil")
+	}
+	if _, err := ReadCredentials(filepath.Join(dir, "missing")); err == nil {
+		t.Error("ReadCredentials() of a missing file error = nil")
+	}
+}
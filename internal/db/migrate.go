@@ -1,13 +1,24 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"fmt"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/n1rocket/go-auth-jwt/internal/lock"
+)
+
+const (
+	// migrationLockKey is the lock runners take before migrating
+	migrationLockKey = "migrations"
+	// migrationLockTTL is how long a crashed runner keeps other runners
+	// waiting on a lock that expires
+	migrationLockTTL = 30 * time.Second
 )
 
 //go:embed migrations/*.sql
@@ -23,6 +34,7 @@ type MigrationConfig struct {
 type Migrator struct {
 	db     *sql.DB
 	config MigrationConfig
+	locker lock.Locker // waits for the migrations lock when set
 }
 
 // NewMigrator creates a new Migrator instance
@@ -39,8 +51,30 @@ func NewMigrator(db *sql.DB, config MigrationConfig) *Migrator {
 	}
 }
 
+// SetLocker makes Up, Down, Steps and Force wait for the migrations lock,
+// so runners started together by several deploys migrate one at a time
+func (m *Migrator) SetLocker(locker lock.Locker) {
+	m.locker = locker
+}
+
+// WithMigrationLock runs fn while holding the migrations lock, waiting for
+// it as long as another runner holds it. fn runs without the lock when
+// locker is nil.
+func WithMigrationLock(locker lock.Locker, fn func() error) error {
+	if locker == nil {
+		return fn()
+	}
+	return lock.Do(context.Background(), locker, migrationLockKey, migrationLockTTL, time.Second, func(context.Context) error {
+		return fn()
+	})
+}
+
 // Up runs all pending migrations
 func (m *Migrator) Up() error {
+	return WithMigrationLock(m.locker, m.up)
+}
+
+func (m *Migrator) up() error {
 	migration, err := m.getMigration()
 	if err != nil {
 		return fmt.Errorf("failed to create migration instance: %w", err)
@@ -56,6 +90,10 @@ func (m *Migrator) Up() error {
 
 // Down rolls back the last migration
 func (m *Migrator) Down() error {
+	return WithMigrationLock(m.locker, m.down)
+}
+
+func (m *Migrator) down() error {
 	migration, err := m.getMigration()
 	if err != nil {
 		return fmt.Errorf("failed to create migration instance: %w", err)
@@ -71,6 +109,10 @@ func (m *Migrator) Down() error {
 
 // Steps runs N migrations (positive for up, negative for down)
 func (m *Migrator) Steps(n int) error {
+	return WithMigrationLock(m.locker, func() error { return m.steps(n) })
+}
+
+func (m *Migrator) steps(n int) error {
 	migration, err := m.getMigration()
 	if err != nil {
 		return fmt.Errorf("failed to create migration instance: %w", err)
@@ -97,6 +139,10 @@ func (m *Migrator) Version() (uint, bool, error) {
 
 // Force forces the migration to a specific version
 func (m *Migrator) Force(version int) error {
+	return WithMigrationLock(m.locker, func() error { return m.force(version) })
+}
+
+func (m *Migrator) force(version int) error {
 	migration, err := m.getMigration()
 	if err != nil {
 		return fmt.Errorf("failed to create migration instance: %w", err)
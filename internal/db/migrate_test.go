@@ -1,10 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/lock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -187,3 +191,38 @@ func TestRunMigrationsFromPath_WithValidDB(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to create database driver")
 }
+
+func TestWithMigrationLock(t *testing.T) {
+	locker := lock.NewMemoryLocker()
+
+	// Runs while no other runner migrates, and releases the lock after
+	ran := false
+	err := WithMigrationLock(locker, func() error {
+		ran = true
+		_, err := locker.TryLock(context.Background(), migrationLockKey, time.Minute)
+		assert.ErrorIs(t, err, lock.ErrNotAcquired)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ran)
+
+	// Waits for another runner to finish
+	held, err := locker.TryLock(context.Background(), migrationLockKey, time.Minute)
+	assert.NoError(t, err)
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(released)
+		_ = locker.Unlock(context.Background(), held)
+	}()
+	err = WithMigrationLock(locker, func() error {
+		select {
+		case <-released:
+			return errors.New("migration failed")
+		default:
+			t.Error("ran while another runner held the lock")
+			return nil
+		}
+	})
+	assert.EqualError(t, err, "migration failed")
+}
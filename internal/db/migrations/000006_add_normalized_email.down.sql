@@ -0,0 +1,8 @@
+-- Remove normalized email
+BEGIN;
+
+DROP INDEX IF EXISTS idx_users_normalized_email;
+
+ALTER TABLE users DROP COLUMN IF EXISTS normalized_email;
+
+COMMIT;
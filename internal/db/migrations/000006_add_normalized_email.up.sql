@@ -0,0 +1,17 @@
+-- Add normalized email used for uniqueness checks and lookups
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS normalized_email VARCHAR(255);
+
+-- Backfill with the default normalization (trimmed, lowercased). Run
+-- `migrate -command normalize-emails` afterwards to apply Gmail
+-- canonicalization or IDNA conversion to existing rows.
+UPDATE users SET normalized_email = lower(trim(email)) WHERE normalized_email IS NULL;
+
+ALTER TABLE users ALTER COLUMN normalized_email SET NOT NULL;
+
+-- Fails if existing emails differ only by case; resolve those accounts first:
+--   SELECT lower(trim(email)), count(*) FROM users GROUP BY 1 HAVING count(*) > 1;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_normalized_email ON users(normalized_email);
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Remove username
+BEGIN;
+
+DROP INDEX IF EXISTS idx_users_username;
+
+ALTER TABLE users DROP COLUMN IF EXISTS username;
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Remove display name and localization preferences; avatar_url belongs to 000003
+BEGIN;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS display_name,
+DROP COLUMN IF EXISTS locale,
+DROP COLUMN IF EXISTS timezone;
+
+COMMIT;
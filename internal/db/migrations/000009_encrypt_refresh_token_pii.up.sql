@@ -0,0 +1,7 @@
+-- Store refresh token ip_address as text so it can hold encrypted values
+BEGIN;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN ip_address TYPE TEXT USING host(ip_address);
+
+COMMIT;
@@ -0,0 +1,6 @@
+-- Bind refresh tokens to the DPoP key they were issued to
+BEGIN;
+
+ALTER TABLE refresh_tokens ADD COLUMN dpop_jkt VARCHAR(64);
+
+COMMIT;
@@ -0,0 +1,21 @@
+-- Track account activity for the dormancy policy
+BEGIN;
+
+-- Kept out of users so recording activity does not bump users.updated_at
+CREATE TABLE IF NOT EXISTS account_activity (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    last_active_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    dormancy_notified_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Existing accounts were last active at their last login, or when created
+INSERT INTO account_activity (user_id, last_active_at)
+SELECT id, COALESCE(last_login_at, created_at) FROM users
+ON CONFLICT (user_id) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_account_activity_last_active_at ON account_activity(last_active_at);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_users_disabled_at ON users(disabled_at) WHERE disabled_at IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Per-user counter of verification email resends, for the resend cooldown
+-- and daily limit
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS verification_resends (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    last_sent_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_audit_logs_user_created;
+
+COMMIT;
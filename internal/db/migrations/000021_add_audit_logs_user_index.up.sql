@@ -0,0 +1,8 @@
+-- Lets users page through their own audit entries newest first, as the
+-- security events feed does
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_created
+    ON audit_logs(user_id, created_at DESC, id DESC);
+
+COMMIT;
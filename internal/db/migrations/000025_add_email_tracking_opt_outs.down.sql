@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS email_tracking_opt_outs;
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- Events about users delivered to downstream systems by webhook, kept so
+-- missed deliveries can be replayed. user_id has no foreign key so events
+-- of deleted users can still be replayed.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    user_id UUID NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_events_type_occurred_at ON user_events (type, occurred_at);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Daily windows of local time outside which a user's access tokens are
+-- rejected, set by admins while AUTH_ACCESS_WINDOWS_ENABLED is on
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS access_windows (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    start_time TEXT NOT NULL,
+    end_time TEXT NOT NULL,
+    timezone TEXT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// MinSchemaVersion is the oldest schema this release runs against. Raise it
+// when code starts using a table or column added by a newer migration.
+const MinSchemaVersion uint = 31
+
+// ErrSchemaIncompatible is returned when the database schema is outside the
+// range this release supports
+var ErrSchemaIncompatible = errors.New("incompatible database schema")
+
+// SchemaRange is the range of migration versions a release runs against
+type SchemaRange struct {
+	Min uint
+	Max uint
+}
+
+// Contains reports whether version is within the range
+func (r SchemaRange) Contains(version uint) bool {
+	return version >= r.Min && version <= r.Max
+}
+
+// SchemaStatus is the migration state of the database
+type SchemaStatus struct {
+	Version  uint
+	Dirty    bool // a migration failed halfway and must be fixed by hand
+	Expected SchemaRange
+}
+
+// LatestSchemaVersion returns the newest embedded migration version
+func LatestSchemaVersion() (uint, error) {
+	files, err := fs.Glob(migrationsFS, "migrations/*.up.sql")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var latest uint
+	for _, file := range files {
+		name := strings.TrimPrefix(file, "migrations/")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %s", name)
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
+
+// ExpectedSchema returns the schema range this release supports. A database
+// up to maxAhead migrations newer than the release is accepted, so the
+// previous release keeps running while the next one migrates during a
+// blue/green rollout; this is only safe while migrations are additive.
+func ExpectedSchema(maxAhead uint) (SchemaRange, error) {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return SchemaRange{}, err
+	}
+	return SchemaRange{Min: MinSchemaVersion, Max: latest + maxAhead}, nil
+}
+
+// CheckSchema reads the migration version from schema_migrations and
+// returns ErrSchemaIncompatible when it is outside expected or dirty
+func CheckSchema(ctx context.Context, db *sql.DB, expected SchemaRange) (SchemaStatus, error) {
+	status := SchemaStatus{Expected: expected}
+
+	var version int64
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &status.Dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return status, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > 0 {
+		status.Version = uint(version)
+	}
+
+	if status.Dirty {
+		return status, fmt.Errorf("%w: migration %d is dirty", ErrSchemaIncompatible, status.Version)
+	}
+	if !expected.Contains(status.Version) {
+		return status, fmt.Errorf("%w: database is at version %d, this release supports %d to %d",
+			ErrSchemaIncompatible, status.Version, expected.Min, expected.Max)
+	}
+	return status, nil
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLatestSchemaVersion(t *testing.T) {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		t.Fatalf("LatestSchemaVersion() error = %v", err)
+	}
+	if latest < MinSchemaVersion {
+		t.Errorf("latest migration %d is older than MinSchemaVersion %d", latest, MinSchemaVersion)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	expected := SchemaRange{Min: 12, Max: 14}
+	query := regexp.QuoteMeta(`SELECT version, dirty FROM schema_migrations LIMIT 1`)
+
+	tests := []struct {
+		name        string
+		setupMock   func(sqlmock.Sqlmock)
+		wantVersion uint
+		wantErr     error
+	}{
+		{
+			name: "within range",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(13, false))
+			},
+			wantVersion: 13,
+		},
+		{
+			name: "ahead of the release",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(15, false))
+			},
+			wantVersion: 15,
+			wantErr:     ErrSchemaIncompatible,
+		},
+		{
+			name: "behind the release",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(11, false))
+			},
+			wantVersion: 11,
+			wantErr:     ErrSchemaIncompatible,
+		},
+		{
+			name: "dirty migration",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(13, true))
+			},
+			wantVersion: 13,
+			wantErr:     ErrSchemaIncompatible,
+		},
+		{
+			name: "never migrated",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+			},
+			wantErr: ErrSchemaIncompatible,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			status, err := CheckSchema(context.Background(), db, expected)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckSchema() error = %v, want %v", err, tt.wantErr)
+			}
+			if status.Version != tt.wantVersion {
+				t.Errorf("CheckSchema() version = %d, want %d", status.Version, tt.wantVersion)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -78,7 +78,7 @@ func TestDB_WithTransaction(t *testing.T) {
 			}
 			defer mockDB.Close()
 
-			db := &DB{mockDB}
+			db := wrap(mockDB)
 			tt.setupMock(mock)
 
 			err = db.WithTransaction(context.Background(), tt.fn)
@@ -106,7 +106,7 @@ func TestDB_WithTransaction_Panic(t *testing.T) {
 	}
 	defer mockDB.Close()
 
-	db := &DB{mockDB}
+	db := wrap(mockDB)
 
 	// Expect begin and rollback
 	mock.ExpectBegin()
@@ -198,7 +198,7 @@ func TestDB_WithTransactionIsolation(t *testing.T) {
 			}
 			defer mockDB.Close()
 
-			db := &DB{mockDB}
+			db := wrap(mockDB)
 			tt.setupMock(mock)
 
 			err = db.WithTransactionIsolation(context.Background(), tt.level, tt.fn)
@@ -226,7 +226,7 @@ func TestDB_WithTransactionIsolation_Panic(t *testing.T) {
 	}
 	defer mockDB.Close()
 
-	db := &DB{mockDB}
+	db := wrap(mockDB)
 
 	// Expect begin and rollback
 	mock.ExpectBegin()
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrInvalidAccessWindow is returned when an access window fails validation
+	ErrInvalidAccessWindow = errors.New("invalid access window")
+	// ErrAccessWindowNotFound is returned when a user has no access window
+	ErrAccessWindowNotFound = errors.New("access window not found")
+	// ErrOutsideAccessWindow is returned for requests made outside the
+	// caller's access window
+	ErrOutsideAccessWindow = errors.New("outside the access window")
+)
+
+// accessWindowLayout is the format of an access window's start and end
+const accessWindowLayout = "15:04"
+
+// AccessWindow limits a user's access tokens to a daily window of local
+// time, such as 08:00 to 18:00 for contractor accounts
+type AccessWindow struct {
+	Start     string // HH:MM, inclusive
+	End       string // HH:MM, exclusive; before Start for a window spanning midnight
+	Timezone  string // IANA time zone; the user's profile timezone, or UTC, when empty
+	UpdatedAt time.Time
+}
+
+// Validate checks the window's times and time zone
+func (w AccessWindow) Validate() error {
+	start, err := time.Parse(accessWindowLayout, w.Start)
+	if err != nil {
+		return fmt.Errorf("%w: start must be a time as HH:MM", ErrInvalidAccessWindow)
+	}
+	end, err := time.Parse(accessWindowLayout, w.End)
+	if err != nil {
+		return fmt.Errorf("%w: end must be a time as HH:MM", ErrInvalidAccessWindow)
+	}
+	if start.Equal(end) {
+		return fmt.Errorf("%w: start and end must differ", ErrInvalidAccessWindow)
+	}
+	if w.Timezone != "" {
+		if _, err := NormalizeTimezone(w.Timezone); err != nil {
+			return fmt.Errorf("%w: timezone must be an IANA time zone name", ErrInvalidAccessWindow)
+		}
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the window, in the window's time
+// zone or UTC. An invalid window contains no time at all.
+func (w AccessWindow) Contains(t time.Time) bool {
+	start, err := time.Parse(accessWindowLayout, w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(accessWindowLayout, w.End)
+	if err != nil {
+		return false
+	}
+	loc := time.UTC
+	if w.Timezone != "" {
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return false
+		}
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from < to {
+		return minute >= from && minute < to
+	}
+	// The window spans midnight
+	return minute >= from || minute < to
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAccessWindow_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  AccessWindow
+		wantErr bool
+	}{
+		{name: "office hours", window: AccessWindow{Start: "08:00", End: "18:00", Timezone: "Europe/Madrid"}},
+		{name: "overnight without timezone", window: AccessWindow{Start: "22:00", End: "06:00"}},
+		{name: "bad start", window: AccessWindow{Start: "8am", End: "18:00"}, wantErr: true},
+		{name: "bad end", window: AccessWindow{Start: "08:00", End: "24:00"}, wantErr: true},
+		{name: "empty window", window: AccessWindow{Start: "08:00", End: "08:00"}, wantErr: true},
+		{name: "unknown timezone", window: AccessWindow{Start: "08:00", End: "18:00", Timezone: "Mars/Olympus"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.window.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAccessWindow) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrInvalidAccessWindow)
+			}
+		})
+	}
+}
+
+func TestAccessWindow_Contains(t *testing.T) {
+	office := AccessWindow{Start: "08:00", End: "18:00", Timezone: "America/New_York"}
+	overnight := AccessWindow{Start: "22:00", End: "06:00"}
+
+	tests := []struct {
+		name   string
+		window AccessWindow
+		at     time.Time
+		want   bool
+	}{
+		{name: "inside, local time", window: office, at: time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC), want: true},
+		{name: "at the start", window: office, at: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), want: true},
+		{name: "at the end", window: office, at: time.Date(2026, 10, 19, 22, 0, 0, 0, time.UTC), want: false},
+		{name: "before, local time", window: office, at: time.Date(2026, 10, 19, 11, 59, 0, 0, time.UTC), want: false},
+		{name: "overnight, late", window: overnight, at: time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC), want: true},
+		{name: "overnight, early", window: overnight, at: time.Date(2026, 10, 19, 5, 59, 0, 0, time.UTC), want: true},
+		{name: "overnight, daytime", window: overnight, at: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), want: false},
+		{name: "invalid window", window: AccessWindow{Start: "8am", End: "18:00"}, at: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.at); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
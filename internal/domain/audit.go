@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+// Audit log actions
+const (
+	AuditActionLoginRisk               = "login_risk_assessed"
+	AuditActionDormancyDisable         = "account_dormancy_disabled"
+	AuditActionDormancyPurge           = "account_dormancy_purged"
+	AuditActionSigningKeyCreate        = "admin_signing_key_created"
+	AuditActionSigningKeyRevoke        = "admin_signing_key_revoked"
+	AuditActionSessionsRevoked         = "user_sessions_revoked"
+	AuditActionLogin                   = "user_login"
+	AuditActionServiceAccountCreate    = "service_account_created"
+	AuditActionServiceAccountUpdate    = "service_account_updated"
+	AuditActionServiceAccountDelete    = "service_account_deleted"
+	AuditActionServiceAccountKeyAdd    = "service_account_key_added"
+	AuditActionServiceAccountKeyRevoke = "service_account_key_revoked"
+	AuditActionServiceAccountToken     = "service_account_token_issued"
+	AuditActionSessionHandoffCreate    = "session_handoff_created"
+	AuditActionSessionHandoffExchange  = "session_handoff_exchanged"
+	AuditActionAppMetadataUpdate       = "user_app_metadata_updated"
+	AuditActionAccessWindowUpdate      = "user_access_window_updated"
+	AuditActionAccessWindowDenied      = "access_window_denied"
+)
+
+// Audit log statuses
+const (
+	AuditStatusSuccess = "success"
+	AuditStatusFailure = "failure"
+	AuditStatusBlocked = "blocked"
+)
+
+// AuditLog represents an entry in the audit trail
+type AuditLog struct {
+	ID           string
+	UserID       *string
+	Action       string
+	ResourceType *string
+	ResourceID   *string
+	IPAddress    *string
+	UserAgent    *string
+	RequestID    *string
+	Status       string
+	ErrorMessage *string
+	Metadata     map[string]interface{}
+	CreatedAt    time.Time
+
+	// Hash chain fields, set when the entry is written with chaining enabled
+	ChainSeq   int64  // position in the chain, starting at 1; 0 when unchained
+	PrevHash   string // Hash of the entry at ChainSeq-1; empty for the first
+	Hash       string // hex SHA-256 over PrevHash and the entry's content
+	UserDigest string // hex SHA-256 of UserID, which is nulled when the user is deleted
+}
+
+// AuditCursor is the position of an entry when listing the audit trail
+// newest first. Entries written in the same instant are ordered by ID.
+type AuditCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// NewAuditLog creates a new audit log entry
+func NewAuditLog(action, status string) *AuditLog {
+	return &AuditLog{
+		Action:    action,
+		Status:    status,
+		Metadata:  make(map[string]interface{}),
+		CreatedAt: time.Now(),
+	}
+}
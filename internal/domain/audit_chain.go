@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditAnchor identifies a point in the audit hash chain. Anchors published
+// outside the database let a verifier detect a chain rewritten from scratch.
+type AuditAnchor struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// String formats the anchor as <seq>:<hash>
+func (a AuditAnchor) String() string {
+	return fmt.Sprintf("%d:%s", a.Seq, a.Hash)
+}
+
+// ParseAuditAnchor parses an anchor formatted as <seq>:<hash>
+func ParseAuditAnchor(s string) (AuditAnchor, error) {
+	seq, hash, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return AuditAnchor{}, fmt.Errorf("invalid audit anchor %q: want <seq>:<hash>", s)
+	}
+	n, err := strconv.ParseInt(seq, 10, 64)
+	if err != nil || n <= 0 {
+		return AuditAnchor{}, fmt.Errorf("invalid audit anchor sequence %q", seq)
+	}
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+		return AuditAnchor{}, fmt.Errorf("invalid audit anchor hash %q", hash)
+	}
+	return AuditAnchor{Seq: n, Hash: hash}, nil
+}
+
+// AuditGap is a range of chain positions with no audit entry, From to To
+// inclusive. Chained entries are never deleted, so a gap means entries were
+// removed from the database.
+type AuditGap struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// AuditUserDigest returns the hex SHA-256 of a user id, or "" for none
+func AuditUserDigest(userID *string) string {
+	if userID == nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(*userID))
+	return hex.EncodeToString(sum[:])
+}
+
+// auditHashInput fixes the field order hashed for a chained entry. The user
+// is represented by its digest so deleting the user does not break the chain.
+type auditHashInput struct {
+	Seq          int64           `json:"seq"`
+	PrevHash     string          `json:"prev_hash"`
+	UserDigest   string          `json:"user_digest"`
+	Action       string          `json:"action"`
+	ResourceType *string         `json:"resource_type"`
+	ResourceID   *string         `json:"resource_id"`
+	IPAddress    *string         `json:"ip_address"`
+	UserAgent    *string         `json:"user_agent"`
+	RequestID    *string         `json:"request_id"`
+	Status       string          `json:"status"`
+	ErrorMessage *string         `json:"error_message"`
+	Metadata     json.RawMessage `json:"metadata"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+// ComputeHash returns the chain hash of the entry from its chain position,
+// PrevHash, UserDigest and content. CreatedAt is hashed at microsecond
+// precision, as stored by PostgreSQL.
+func (l *AuditLog) ComputeHash() (string, error) {
+	metadata, err := canonicalMetadata(l.Metadata)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(auditHashInput{
+		Seq:          l.ChainSeq,
+		PrevHash:     l.PrevHash,
+		UserDigest:   l.UserDigest,
+		Action:       l.Action,
+		ResourceType: l.ResourceType,
+		ResourceID:   l.ResourceID,
+		IPAddress:    l.IPAddress,
+		UserAgent:    l.UserAgent,
+		RequestID:    l.RequestID,
+		Status:       l.Status,
+		ErrorMessage: l.ErrorMessage,
+		Metadata:     metadata,
+		CreatedAt:    l.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalMetadata encodes metadata the way it reads back from a JSONB
+// column, so the hash does not depend on the Go types it was built from
+func canonicalMetadata(metadata map[string]interface{}) ([]byte, error) {
+	if metadata == nil {
+		return []byte("{}"), nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit metadata: %w", err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode audit metadata: %w", err)
+	}
+	return json.Marshal(decoded)
+}
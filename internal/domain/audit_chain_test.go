@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAuditAnchor(t *testing.T) {
+	hash := strings.Repeat("0f", 32)
+	tests := []struct {
+		input   string
+		want    AuditAnchor
+		wantErr bool
+	}{
+		{input: "42:" + hash, want: AuditAnchor{Seq: 42, Hash: hash}},
+		{input: " 1:" + hash + "\n", want: AuditAnchor{Seq: 1, Hash: hash}},
+		{input: hash, wantErr: true},
+		{input: "0:" + hash, wantErr: true},
+		{input: "x:" + hash, wantErr: true},
+		{input: "42:abc", wantErr: true},
+		{input: "42:" + strings.Repeat("zz", 32), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAuditAnchor(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAuditAnchor(%q) = %+v, %v", tt.input, got, err)
+		}
+		if err == nil {
+			if again, _ := ParseAuditAnchor(got.String()); again != got {
+				t.Errorf("String() does not round-trip: %q", got.String())
+			}
+		}
+	}
+}
+
+func TestAuditLog_ComputeHash(t *testing.T) {
+	userID := "user-123"
+	newLog := func(metadata map[string]interface{}, createdAt time.Time) *AuditLog {
+		return &AuditLog{
+			UserID:     &userID,
+			Action:     AuditActionLoginRisk,
+			Status:     AuditStatusBlocked,
+			Metadata:   metadata,
+			CreatedAt:  createdAt,
+			ChainSeq:   7,
+			PrevHash:   strings.Repeat("ab", 32),
+			UserDigest: AuditUserDigest(&userID),
+		}
+	}
+	createdAt := time.Date(2026, 3, 4, 11, 0, 0, 123456789, time.FixedZone("CET", 3600))
+
+	hash, err := newLog(map[string]interface{}{"score": 90, "signals": []string{"tor"}}, createdAt).ComputeHash()
+	if err != nil || len(hash) != 64 {
+		t.Fatalf("ComputeHash() = %q, %v", hash, err)
+	}
+
+	// The entry as read back from PostgreSQL hashes the same
+	stored := newLog(map[string]interface{}{"signals": []interface{}{"tor"}, "score": float64(90)},
+		time.Date(2026, 3, 4, 10, 0, 0, 123456000, time.UTC))
+	stored.UserID = nil
+	if got, _ := stored.ComputeHash(); got != hash {
+		t.Errorf("hash after storage = %q, want %q", got, hash)
+	}
+
+	// Empty and missing metadata are stored alike
+	empty, _ := newLog(map[string]interface{}{}, createdAt).ComputeHash()
+	missing, _ := newLog(nil, createdAt).ComputeHash()
+	if empty != missing {
+		t.Error("nil metadata should hash like empty metadata")
+	}
+
+	changed := newLog(map[string]interface{}{"score": 91, "signals": []string{"tor"}}, createdAt)
+	if got, _ := changed.ComputeHash(); got == hash {
+		t.Error("changed metadata should change the hash")
+	}
+	relinked := newLog(map[string]interface{}{"score": 90, "signals": []string{"tor"}}, createdAt)
+	relinked.PrevHash = strings.Repeat("cd", 32)
+	if got, _ := relinked.ComputeHash(); got == hash {
+		t.Error("changed previous hash should change the hash")
+	}
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrInvalidResetGrant is returned for a wrong, expired or used grant to
+	// set a client-hashed password
+	ErrInvalidResetGrant = errors.New("invalid or expired reset grant")
+	// ErrInvalidPasswordVerifier is returned for a password verifier that
+	// is not the lowercase hex of a derived key, such as a plain password
+	ErrInvalidPasswordVerifier = errors.New("password verifier must be 64 lowercase hex characters")
+	// ErrPasswordResetRequired is returned for a password change on an
+	// account with a client-hashed password, whose new verifier needs the
+	// fresh params only a reset grant provides
+	ErrPasswordResetRequired = errors.New("client-hashed passwords can only be changed by password reset")
+)
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTermsNotAccepted is returned when a signup or consent names a terms
+	// of service version other than the current one
+	ErrTermsNotAccepted = errors.New("the current terms of service must be accepted")
+	// ErrConsentRequired is returned for requests of users who have not
+	// accepted the current terms of service
+	ErrConsentRequired = errors.New("consent to the current terms of service required")
+)
+
+// ConsentKind is what a consent answer is about
+type ConsentKind string
+
+const (
+	// ConsentTerms is acceptance of a terms of service version
+	ConsentTerms ConsentKind = "terms"
+	// ConsentMarketing is agreement to marketing email, or its withdrawal
+	ConsentMarketing ConsentKind = "marketing"
+)
+
+// Consent is one answer of a user, kept for compliance. Answers are never
+// changed; a later answer of the same kind replaces it.
+type Consent struct {
+	ID        string
+	UserID    string
+	Kind      ConsentKind
+	Version   string // terms of service version; empty for marketing
+	Granted   bool
+	IPAddress *string // of the client that answered
+	CreatedAt time.Time
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidUserCode is returned when a user code is unknown, expired or
+	// already decided
+	ErrInvalidUserCode = errors.New("invalid or expired user code")
+	// ErrInvalidDeviceCode is returned when a device code is unknown or was
+	// already exchanged for tokens
+	ErrInvalidDeviceCode = errors.New("invalid device code")
+	// ErrDeviceCodeExpired is returned when a device code expired before the
+	// user approved it
+	ErrDeviceCodeExpired = errors.New("device code expired")
+	// ErrAuthorizationPending is returned while the user has not approved or
+	// denied the device
+	ErrAuthorizationPending = errors.New("authorization pending")
+	// ErrSlowDown is returned when a device polls faster than its interval
+	ErrSlowDown = errors.New("polling too frequently")
+	// ErrDeviceAccessDenied is returned when the user denied the device
+	ErrDeviceAccessDenied = errors.New("device authorization denied")
+	// ErrInvalidClientID is returned for a malformed client_id
+	ErrInvalidClientID = errors.New("invalid client ID")
+)
+
+// DeviceAuthorizationStatus is the state of a device authorization
+type DeviceAuthorizationStatus string
+
+const (
+	DeviceAuthorizationPending  DeviceAuthorizationStatus = "pending"
+	DeviceAuthorizationApproved DeviceAuthorizationStatus = "approved"
+	DeviceAuthorizationDenied   DeviceAuthorizationStatus = "denied"
+)
+
+// DeviceAuthorization is a device authorization grant (RFC 8628) started by
+// a device without a browser, such as a CLI. The user approves it by entering
+// the user code on another device; the device polls with the device code.
+// Both codes are only stored hashed.
+type DeviceAuthorization struct {
+	ID             string
+	DeviceCodeHash string
+	UserCodeHash   string
+	ClientID       string // shown to the user approving the device
+	Status         DeviceAuthorizationStatus
+	UserID         *string // the approving user
+	ExpiresAt      time.Time
+	LastPolledAt   *time.Time
+	CreatedAt      time.Time
+}
+
+// IsExpired reports whether the authorization expired at now
+func (d *DeviceAuthorization) IsExpired(now time.Time) bool {
+	return !now.Before(d.ExpiresAt)
+}
+
+// UserCodeAlphabet holds the characters of user codes: consonants only, so
+// codes cannot spell words and are not confused with digits
+const UserCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// UserCodeLength is the length of user codes without the separator; 20^8
+// codes leave guessing hopeless within a code's lifetime and rate limits
+const UserCodeLength = 8
+
+// GenerateUserCode returns a uniformly random normalized user code
+func GenerateUserCode() (string, error) {
+	limit := big.NewInt(int64(len(UserCodeAlphabet)))
+	code := make([]byte, UserCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		code[i] = UserCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// NormalizeUserCode uppercases a user code and drops the separators users
+// may type, so "bcdf-ghjk" and "BCDF GHJK" match
+func NormalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// FormatUserCode splits a normalized user code into two halves, as shown to users
+func FormatUserCode(code string) string {
+	if len(code) < 2 {
+		return code
+	}
+	half := len(code) / 2
+	return code[:half] + "-" + code[half:]
+}
+
+var clientIDRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ValidateClientID checks a client_id naming the requesting application
+func ValidateClientID(clientID string) error {
+	if !clientIDRegex.MatchString(clientID) {
+		return ErrInvalidClientID
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrAccountDisabled is returned when a disabled account tries to authenticate
+	ErrAccountDisabled = errors.New("account is disabled")
+
+	// ErrDormancyRunInProgress is returned when a dormancy run is requested
+	// while another one has not finished
+	ErrDormancyRunInProgress = errors.New("dormancy run already in progress")
+
+	// ErrDormancyReportNotFound is returned for an unknown dormancy run report
+	ErrDormancyReportNotFound = errors.New("dormancy report not found")
+)
+
+// DormantAccount is an account selected by the dormancy policy
+type DormantAccount struct {
+	UserID       string
+	Email        string
+	Locale       *string
+	LastActiveAt time.Time
+	NotifiedAt   *time.Time
+	DisabledAt   *time.Time
+}
+
+// IsDisabled reports whether the account has been disabled
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	// ErrInvalidEmailCode is returned for a wrong, expired or unknown code
+	ErrInvalidEmailCode = errors.New("invalid or expired code")
+	// ErrTooManyCodeAttempts is returned once a code has been guessed wrong
+	// too often; a new code has to be requested
+	ErrTooManyCodeAttempts = errors.New("too many incorrect codes")
+)
+
+// EmailCodeDigits is the length of one-time email codes
+const EmailCodeDigits = 6
+
+// EmailCodePurpose is what a one-time email code is used for
+type EmailCodePurpose string
+
+const (
+	EmailCodeVerification  EmailCodePurpose = "email_verification"
+	EmailCodePasswordReset EmailCodePurpose = "password_reset"
+	// Grants to set a client-hashed password, issued for a reset code
+	EmailCodePasswordResetGrant EmailCodePurpose = "password_reset_grant"
+
+	// Codes sent by SMS share the table under their own purposes
+	SMSCodePhoneVerification EmailCodePurpose = "phone_verification"
+	SMSCodeLogin             EmailCodePurpose = "sms_login"
+	SMSCodeRecovery          EmailCodePurpose = "sms_recovery"
+)
+
+// EmailCode is a hashed one-time code sent by email, for users who cannot
+// open links, e.g. in mobile apps, or by SMS
+type EmailCode struct {
+	UserID    string
+	Purpose   EmailCodePurpose
+	CodeHash  string
+	Attempts  int // incorrect and pending guesses so far
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// IsExpired reports whether the code can no longer be used
+func (c *EmailCode) IsExpired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// GenerateEmailCode returns a uniformly random code of EmailCodeDigits
+// digits, including leading zeros
+func GenerateEmailCode() (string, error) {
+	limit := big.NewInt(1_000_000)
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", EmailCodeDigits, n.Int64()), nil
+}
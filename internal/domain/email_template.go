@@ -0,0 +1,6 @@
+package domain
+
+import "errors"
+
+// ErrEmailTemplateNotFound is returned for an unknown email template name
+var ErrEmailTemplateNotFound = errors.New("email template not found")
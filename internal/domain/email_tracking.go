@@ -0,0 +1,7 @@
+package domain
+
+import "errors"
+
+// ErrInvalidTrackingLink is returned for email tracking links that are
+// malformed or whose signature does not match
+var ErrInvalidTrackingLink = errors.New("invalid tracking link")
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+var (
+	// ErrNoTrustedDevice is returned when a login approval is requested for an
+	// account without trusted devices, or an unknown account
+	ErrNoTrustedDevice = errors.New("no trusted device to approve the login")
+	// ErrTrustedDeviceNotFound is returned when a trusted device is unknown or
+	// its secret does not match
+	ErrTrustedDeviceNotFound = errors.New("trusted device not found")
+	// ErrLoginApprovalNotFound is returned for an unknown, expired or already
+	// used login approval
+	ErrLoginApprovalNotFound = errors.New("login approval not found or expired")
+	// ErrLoginApprovalPending is returned while a login approval awaits a decision
+	ErrLoginApprovalPending = errors.New("login approval pending")
+	// ErrLoginDenied is returned when a trusted device denied the login
+	ErrLoginDenied = errors.New("login denied by trusted device")
+)
+
+// TrustedDevice is a device a user registered to approve logins from other
+// devices. Its secret is only stored hashed.
+type TrustedDevice struct {
+	ID         string
+	UserID     string
+	Name       string
+	SecretHash string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time // last approval or denial
+}
+
+// LoginApprovalStatus is the state of a login approval
+type LoginApprovalStatus string
+
+const (
+	LoginApprovalPending  LoginApprovalStatus = "pending"
+	LoginApprovalApproved LoginApprovalStatus = "approved"
+	LoginApprovalDenied   LoginApprovalStatus = "denied"
+)
+
+// LoginApproval is a login attempt waiting for a trusted device of the user.
+// The client that started it polls with a secret only it knows, stored
+// hashed, and receives tokens once a device approved it.
+type LoginApproval struct {
+	ID            string
+	UserID        string
+	PollTokenHash string
+	Status        LoginApprovalStatus
+	DeviceID      *string // device that decided
+	IPAddress     *string // of the client waiting for approval, shown to the approver
+	UserAgent     *string
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+}
+
+// IsExpired reports whether the approval can no longer be decided or used
+func (a *LoginApproval) IsExpired(now time.Time) bool {
+	return !now.Before(a.ExpiresAt)
+}
+
+// uuidRegex matches the canonical text form of a UUID
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// IsUUID reports whether s is a UUID, so malformed ids sent by clients can be
+// rejected before they reach a UUID column
+func IsUUID(s string) bool {
+	return uuidRegex.MatchString(s)
+}
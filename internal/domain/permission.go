@@ -0,0 +1,11 @@
+package domain
+
+import "errors"
+
+// ErrPermissionDenied is returned when the caller is authenticated but lacks
+// the roles or scopes a route requires
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrAuthorizationUnavailable is returned when the external policy engine
+// cannot decide on a request
+var ErrAuthorizationUnavailable = errors.New("authorization service unavailable")
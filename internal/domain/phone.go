@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+)
+
+var (
+	// ErrInvalidPhoneNumber is returned when a phone number is not in E.164 format
+	ErrInvalidPhoneNumber = errors.New("phone number must be in E.164 format, e.g. +15551234567")
+	// ErrPhoneNotVerified is returned when a feature needs a verified phone number
+	ErrPhoneNotVerified = errors.New("phone number not verified")
+	// ErrSMSCodeRequired is returned when a login must be completed with the
+	// code sent by SMS
+	ErrSMSCodeRequired = errors.New("sms code required")
+)
+
+// e164Regex matches a plus sign and up to 15 digits without a leading zero
+var e164Regex = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// ValidatePhoneNumber checks a phone number is in E.164 format
+func ValidatePhoneNumber(phone string) error {
+	if !e164Regex.MatchString(phone) {
+		return ErrInvalidPhoneNumber
+	}
+	return nil
+}
+
+// SetPhoneNumber replaces the user's phone number. The new number is
+// unverified, so SMS second factor is turned off until it is verified.
+func (u *User) SetPhoneNumber(phone string) {
+	u.PhoneNumber = &phone
+	u.PhoneVerified = false
+	u.SMSSecondFactor = false
+}
+
+// MarkPhoneVerified marks the user's phone number as verified
+func (u *User) MarkPhoneVerified() {
+	u.PhoneVerified = true
+}
+
+// HasVerifiedPhone reports whether SMS can be sent to the user
+func (u *User) HasVerifiedPhone() bool {
+	return u.PhoneNumber != nil && u.PhoneVerified
+}
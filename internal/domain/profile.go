@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+var (
+	// ErrInvalidProfile is returned when a profile field fails validation
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrAvatarUploadsDisabled is returned when no avatar upload storage is configured
+	ErrAvatarUploadsDisabled = errors.New("avatar uploads are not enabled")
+)
+
+// Profile field limits
+const (
+	MaxDisplayNameLength = 100
+	MaxAvatarURLLength   = 500
+)
+
+// ProfileUpdate describes a partial profile update. Nil fields are left
+// unchanged and empty strings clear the field.
+type ProfileUpdate struct {
+	DisplayName *string
+	Locale      *string
+	Timezone    *string
+	AvatarURL   *string
+}
+
+// ApplyProfileUpdate validates and applies a profile update. The user is
+// left untouched when any field is invalid.
+func (u *User) ApplyProfileUpdate(update ProfileUpdate) error {
+	displayName, err := normalizeProfileField(update.DisplayName, NormalizeDisplayName)
+	if err != nil {
+		return err
+	}
+	locale, err := normalizeProfileField(update.Locale, NormalizeLocale)
+	if err != nil {
+		return err
+	}
+	timezone, err := normalizeProfileField(update.Timezone, NormalizeTimezone)
+	if err != nil {
+		return err
+	}
+	avatarURL, err := normalizeProfileField(update.AvatarURL, NormalizeAvatarURL)
+	if err != nil {
+		return err
+	}
+
+	if update.DisplayName != nil {
+		u.DisplayName = displayName
+	}
+	if update.Locale != nil {
+		u.Locale = locale
+	}
+	if update.Timezone != nil {
+		u.Timezone = timezone
+	}
+	if update.AvatarURL != nil {
+		u.AvatarURL = avatarURL
+	}
+	u.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// normalizeProfileField normalizes a non-nil, non-empty value; an empty
+// value clears the field
+func normalizeProfileField(value *string, normalize func(string) (string, error)) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil, nil
+	}
+	normalized, err := normalize(trimmed)
+	if err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
+
+// NormalizeDisplayName trims a display name and rejects control characters
+func NormalizeDisplayName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > MaxDisplayNameLength {
+		return "", fmt.Errorf("%w: display name must be at most %d characters", ErrInvalidProfile, MaxDisplayNameLength)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("%w: display name must not contain control characters", ErrInvalidProfile)
+		}
+	}
+	return name, nil
+}
+
+// NormalizeLocale returns the canonical form of a BCP 47 language tag
+func NormalizeLocale(locale string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil {
+		return "", fmt.Errorf("%w: locale must be a BCP 47 language tag", ErrInvalidProfile)
+	}
+	return tag.String(), nil
+}
+
+// NormalizeTimezone validates an IANA time zone name
+func NormalizeTimezone(tz string) (string, error) {
+	tz = strings.TrimSpace(tz)
+	// LoadLocation treats "" and "Local" specially; neither is a real zone
+	if tz == "" || tz == "Local" {
+		return "", fmt.Errorf("%w: timezone must be an IANA time zone name", ErrInvalidProfile)
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return "", fmt.Errorf("%w: timezone must be an IANA time zone name", ErrInvalidProfile)
+	}
+	return tz, nil
+}
+
+// NormalizeAvatarURL validates an absolute https avatar URL
+func NormalizeAvatarURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if len(rawURL) > MaxAvatarURLLength {
+		return "", fmt.Errorf("%w: avatar URL must be at most %d characters", ErrInvalidProfile, MaxAvatarURLLength)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return "", fmt.Errorf("%w: avatar URL must be an absolute https URL", ErrInvalidProfile)
+	}
+	return u.String(), nil
+}
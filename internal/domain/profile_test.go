@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUser_ApplyProfileUpdate(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		update  ProfileUpdate
+		wantErr bool
+		check   func(t *testing.T, u *User)
+	}{
+		{
+			name: "sets all fields",
+			update: ProfileUpdate{
+				DisplayName: str("  Jane Doe "),
+				Locale:      str("pt_br"),
+				Timezone:    str("Europe/Berlin"),
+				AvatarURL:   str("https://cdn.example.com/a.png"),
+			},
+			check: func(t *testing.T, u *User) {
+				if *u.DisplayName != "Jane Doe" {
+					t.Errorf("DisplayName = %q", *u.DisplayName)
+				}
+				if *u.Locale != "pt-BR" {
+					t.Errorf("Locale = %q, want pt-BR", *u.Locale)
+				}
+				if *u.Timezone != "Europe/Berlin" {
+					t.Errorf("Timezone = %q", *u.Timezone)
+				}
+				if *u.AvatarURL != "https://cdn.example.com/a.png" {
+					t.Errorf("AvatarURL = %q", *u.AvatarURL)
+				}
+			},
+		},
+		{
+			name:   "empty string clears field",
+			update: ProfileUpdate{Locale: str("")},
+			check: func(t *testing.T, u *User) {
+				if u.Locale != nil {
+					t.Errorf("Locale = %q, want nil", *u.Locale)
+				}
+				if u.DisplayName == nil {
+					t.Error("DisplayName should be left unchanged")
+				}
+			},
+		},
+		{name: "invalid locale", update: ProfileUpdate{Locale: str("not a locale")}, wantErr: true},
+		{name: "invalid timezone", update: ProfileUpdate{Timezone: str("Mars/Olympus")}, wantErr: true},
+		{name: "local timezone", update: ProfileUpdate{Timezone: str("Local")}, wantErr: true},
+		{name: "http avatar", update: ProfileUpdate{AvatarURL: str("http://cdn.example.com/a.png")}, wantErr: true},
+		{name: "relative avatar", update: ProfileUpdate{AvatarURL: str("/a.png")}, wantErr: true},
+		{name: "display name too long", update: ProfileUpdate{DisplayName: str(strings.Repeat("a", MaxDisplayNameLength+1))}, wantErr: true},
+		{name: "display name control character", update: ProfileUpdate{DisplayName: str("Jane\nDoe")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &User{DisplayName: str("Existing"), Locale: str("en")}
+			before := *user
+
+			err := user.ApplyProfileUpdate(tt.update)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyProfileUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidProfile) {
+					t.Errorf("ApplyProfileUpdate() error = %v, want %v", err, ErrInvalidProfile)
+				}
+				if user.DisplayName != before.DisplayName || user.Locale != before.Locale {
+					t.Error("invalid update should leave the user unchanged")
+				}
+				return
+			}
+			tt.check(t, user)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a subject has used its monthly request quota
+var ErrQuotaExceeded = errors.New("monthly request quota exceeded")
+
+// QuotaUsage is the number of requests a quota subject made in one billing
+// period. Subjects are "user:<id>" or "tenant:<name>".
+type QuotaUsage struct {
+	Subject  string
+	Period   time.Time // start of the calendar month, UTC
+	Requests int64
+	Limit    int64 // 0 counts requests without limiting them
+}
+
+// QuotaPeriod returns the billing period containing t
+func QuotaPeriod(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Remaining returns how many requests are left in the period, or -1 when the
+// subject is unlimited
+func (u *QuotaUsage) Remaining() int64 {
+	if u.Limit == 0 {
+		return -1
+	}
+	return max(u.Limit-u.Requests, 0)
+}
+
+// ResetsAt returns when the next billing period starts
+func (u *QuotaUsage) ResetsAt() time.Time {
+	return u.Period.AddDate(0, 1, 0)
+}
@@ -0,0 +1,7 @@
+package domain
+
+import "errors"
+
+// ErrReadOnlyMode is returned for writes while the service is in read-only
+// mode, e.g. during a database failover
+var ErrReadOnlyMode = errors.New("service is in read-only mode")
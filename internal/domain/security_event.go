@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidPageCursor is returned for a page cursor not made by the server
+var ErrInvalidPageCursor = errors.New("invalid page cursor")
+
+// Security event types shown to users
+const (
+	SecurityEventNewLogin             = "new_login"
+	SecurityEventPasswordChanged      = "password_changed"
+	SecurityEventSecondFactorEnabled  = "second_factor_enabled"
+	SecurityEventSecondFactorDisabled = "second_factor_disabled"
+	SecurityEventSessionsRevoked      = "sessions_revoked"
+)
+
+// SecurityEvent is an audit log entry about a user's account, as shown to
+// the user
+type SecurityEvent struct {
+	ID        string
+	Type      string
+	Reason    string // why sessions were revoked; empty for new logins
+	IPAddress *string
+	UserAgent *string
+	CreatedAt time.Time
+}
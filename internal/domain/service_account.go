@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrServiceAccountNotFound is returned for an unknown service account
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	// ErrServiceAccountKeyNotFound is returned for an unknown key of a
+	// service account
+	ErrServiceAccountKeyNotFound = errors.New("service account key not found")
+	// ErrServiceAccountKeyExists is returned when a public key is registered
+	// twice for the same account
+	ErrServiceAccountKeyExists = errors.New("service account key already registered")
+	// ErrTooManyServiceAccountKeys is returned when an account already has
+	// the maximum number of active keys
+	ErrTooManyServiceAccountKeys = errors.New("service account has too many active keys")
+	// ErrInvalidPublicKey is returned for a key that is not a supported
+	// public JWK
+	ErrInvalidPublicKey = errors.New("invalid public key")
+	// ErrInvalidClientAssertion is returned when a service account's signed
+	// assertion cannot be verified. The cause is not revealed to the client.
+	ErrInvalidClientAssertion = errors.New("invalid client assertion")
+	// ErrInvalidScope is returned when a client requests scopes it was not
+	// granted
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+// ServiceAccount is a non-human client that gets access tokens by signing
+// an assertion with one of its registered keys
+type ServiceAccount struct {
+	ID        string
+	Name      string
+	Scopes    []string // the most a token issued to the account can carry
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ServiceAccountKey is a public key a service account signs assertions
+// with. Its ID is the RFC 7638 thumbprint of the key, which clients send as
+// the kid header.
+type ServiceAccountKey struct {
+	ID               string
+	ServiceAccountID string
+	PublicKey        map[string]interface{} // public JWK
+	CreatedAt        time.Time
+	ExpiresAt        *time.Time // set when the key is revoked or rotated out
+}
+
+// IsActive reports whether assertions signed with the key are accepted at now
+func (k *ServiceAccountKey) IsActive(now time.Time) bool {
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSessionHandoffNotFound is returned for an unknown, expired or already
+// exchanged handoff code
+var ErrSessionHandoffNotFound = errors.New("session handoff code not found or expired")
+
+// SessionHandoff lets a signed-in device start a session on another device,
+// e.g. by showing a QR code it scans. The code is only stored hashed and is
+// exchanged once.
+type SessionHandoff struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	IPAddress *string // of the device that created the handoff
+	UserAgent *string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Device types sessions are grouped by in session analytics
+const (
+	DeviceTypeDesktop = "desktop"
+	DeviceTypeMobile  = "mobile"
+	DeviceTypeTablet  = "tablet"
+	DeviceTypeBot     = "bot"
+	DeviceTypeOther   = "other"
+	DeviceTypeUnknown = "unknown" // no user agent, or a session created before device types were recorded
+)
+
+// SessionStats aggregates active sessions, meaning unrevoked and unexpired
+// refresh tokens, of one user or of all users
+type SessionStats struct {
+	ActiveSessions int
+	Users          int           // users with at least one active session
+	AverageAge     time.Duration // mean time since the sessions were created
+	Devices        map[string]int
+}
+
+// DeviceType classifies a user agent into one of the DeviceType values. It
+// is stored with each session so sessions can be grouped by device in SQL
+// even when the user agent itself is encrypted at rest.
+func DeviceType(userAgent *string) string {
+	if userAgent == nil || strings.TrimSpace(*userAgent) == "" {
+		return DeviceTypeUnknown
+	}
+	ua := strings.ToLower(*userAgent)
+
+	contains := func(substrings ...string) bool {
+		for _, s := range substrings {
+			if strings.Contains(ua, s) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case contains("bot", "crawler", "spider", "curl/", "wget/", "python-requests", "go-http-client", "headless"):
+		return DeviceTypeBot
+	case contains("ipad", "tablet") || (contains("android") && !contains("mobile")):
+		return DeviceTypeTablet
+	case contains("mobile", "iphone", "ipod", "android"):
+		return DeviceTypeMobile
+	case contains("windows", "macintosh", "mac os x", "x11", "linux", "cros"):
+		return DeviceTypeDesktop
+	default:
+		return DeviceTypeOther
+	}
+}
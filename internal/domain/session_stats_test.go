@@ -0,0 +1,33 @@
+package domain
+
+import "testing"
+
+func TestDeviceType(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name      string
+		userAgent *string
+		want      string
+	}{
+		{name: "none", want: DeviceTypeUnknown},
+		{name: "blank", userAgent: str("  "), want: DeviceTypeUnknown},
+		{name: "windows", userAgent: str("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"), want: DeviceTypeDesktop},
+		{name: "mac", userAgent: str("Mozilla/5.0 (Macintosh; Intel Mac OS X 14_1) AppleWebKit/605.1.15 Safari/605.1.15"), want: DeviceTypeDesktop},
+		{name: "iphone", userAgent: str("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148"), want: DeviceTypeMobile},
+		{name: "android phone", userAgent: str("Mozilla/5.0 (Linux; Android 14; Pixel 8) Chrome/120.0 Mobile Safari/537.36"), want: DeviceTypeMobile},
+		{name: "android tablet", userAgent: str("Mozilla/5.0 (Linux; Android 13; SM-X700) Chrome/120.0 Safari/537.36"), want: DeviceTypeTablet},
+		{name: "ipad", userAgent: str("Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) Mobile/15E148"), want: DeviceTypeTablet},
+		{name: "bot", userAgent: str("Googlebot/2.1 (+http://www.google.com/bot.html)"), want: DeviceTypeBot},
+		{name: "curl", userAgent: str("curl/8.4.0"), want: DeviceTypeBot},
+		{name: "other", userAgent: str("MyApp/1.0"), want: DeviceTypeOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeviceType(tt.userAgent); got != tt.want {
+				t.Errorf("DeviceType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
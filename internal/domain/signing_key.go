@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSigningKeyNotFound is returned for an unknown or revoked admin signing key
+var ErrSigningKeyNotFound = errors.New("signing key not found")
+
+// AdminSigningKey is a key automation uses to sign admin API requests. Its
+// secret is derived from the server's signing secret and never stored.
+type AdminSigningKey struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *AdminSigningKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
@@ -5,6 +5,8 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/security"
 )
 
 var (
@@ -14,28 +16,64 @@ var (
 	ErrWeakPassword = errors.New("password must be at least 8 characters long")
 	// ErrUserNotFound is returned when user is not found
 	ErrUserNotFound = errors.New("user not found")
+	// ErrEmailDomainNotAllowed is returned when signup policy rejects the email domain
+	ErrEmailDomainNotAllowed = errors.New("email domain not allowed")
+	// ErrDisposableEmail is returned when signing up with a disposable email address
+	ErrDisposableEmail = errors.New("disposable email addresses are not allowed")
+	// ErrUndeliverableEmail is returned when the email domain cannot receive mail
+	ErrUndeliverableEmail = errors.New("email domain cannot receive mail")
 	// ErrDuplicateEmail is returned when email already exists
 	ErrDuplicateEmail = errors.New("email already exists")
+	// ErrInvalidUsername is returned when a username doesn't meet the format rules
+	ErrInvalidUsername = errors.New("username must be 3-30 characters of letters, digits, '.', '_' or '-'")
+	// ErrReservedUsername is returned when a username is reserved by the system
+	ErrReservedUsername = errors.New("username is reserved")
+	// ErrDuplicateUsername is returned when username already exists
+	ErrDuplicateUsername = errors.New("username already exists")
 	// ErrInvalidCredentials is returned when login credentials are invalid
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	// ErrEmailNotVerified is returned when email is not verified
 	ErrEmailNotVerified = errors.New("email not verified")
+	// ErrEmailAlreadyVerified is returned when a verification email is
+	// requested for a verified address
+	ErrEmailAlreadyVerified = errors.New("email already verified")
 	// ErrTokenExpired is returned when a token has expired
 	ErrTokenExpired = errors.New("token has expired")
 	// ErrInvalidToken is returned when a token is invalid
 	ErrInvalidToken = errors.New("invalid token")
+	// ErrUnsupportedTokenType is returned when revoking a kind of token the
+	// server cannot revoke, such as access tokens without a denylist
+	ErrUnsupportedTokenType = errors.New("unsupported token type")
+	// ErrCaptchaRequired is returned when a risky login must solve a captcha
+	ErrCaptchaRequired = errors.New("captcha required")
+	// ErrLoginConfirmationRequired is returned when a risky login must be confirmed by email
+	ErrLoginConfirmationRequired = errors.New("login confirmation required")
+	// ErrLoginBlocked is returned when a login attempt is blocked as too risky
+	ErrLoginBlocked = errors.New("login attempt blocked")
 )
 
 // User represents a user in the system
 type User struct {
 	ID                         string
 	Email                      string
+	NormalizedEmail            string  // canonical form used for uniqueness and lookups
+	Username                   *string // optional, stored lowercased
+	DisplayName                *string
+	Locale                     *string // BCP 47 language tag
+	Timezone                   *string // IANA time zone name
+	AvatarURL                  *string
+	PhoneNumber                *string // E.164
+	PhoneVerified              bool
+	SMSSecondFactor            bool // logins need a code sent to the verified phone
 	PasswordHash               string
 	EmailVerified              bool
 	EmailVerificationToken     *string
 	EmailVerificationExpiresAt *time.Time
 	PasswordResetToken         *string
 	PasswordResetExpiresAt     *time.Time
+	DisabledAt                 *time.Time             // set when the dormancy policy disables the account
+	Metadata                   map[string]interface{} // user-writable; never nil when read from the repository
+	AppMetadata                map[string]interface{} // admin-writable; never nil when read from the repository
 	CreatedAt                  time.Time
 	UpdatedAt                  time.Time
 }
@@ -71,6 +109,44 @@ func ValidateEmail(email string) error {
 	return nil
 }
 
+// Username length limits
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+)
+
+// usernameRegex allows letters, digits, '.', '_' and '-', starting and ending
+// with a letter or digit. Excluding '@' keeps usernames distinct from emails.
+var usernameRegex = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9._-]*[a-z0-9])?$`)
+
+// reservedUsernames cannot be registered because they could impersonate the
+// service or collide with routes
+var reservedUsernames = map[string]struct{}{
+	"admin": {}, "administrator": {}, "api": {}, "auth": {}, "help": {},
+	"login": {}, "logout": {}, "me": {}, "moderator": {}, "noreply": {},
+	"null": {}, "postmaster": {}, "root": {}, "security": {}, "signup": {},
+	"staff": {}, "support": {}, "system": {}, "undefined": {}, "webmaster": {},
+}
+
+// NormalizeUsername returns the canonical form of a username
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// ValidateUsername validates a normalized username
+func ValidateUsername(username string) error {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return ErrInvalidUsername
+	}
+	if !usernameRegex.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	if _, ok := reservedUsernames[username]; ok {
+		return ErrReservedUsername
+	}
+	return nil
+}
+
 // ValidatePassword validates password strength
 func ValidatePassword(password string) error {
 	if len(password) < 8 {
@@ -110,15 +186,21 @@ func (u *User) ClearPasswordResetToken() {
 
 // IsEmailVerificationTokenValid checks if the email verification token is valid
 func (u *User) IsEmailVerificationTokenValid(token string) bool {
+	return u.IsEmailVerificationTokenValidAt(token, time.Now())
+}
+
+// IsEmailVerificationTokenValidAt checks the email verification token as
+// of now
+func (u *User) IsEmailVerificationTokenValidAt(token string, now time.Time) bool {
 	if u.EmailVerificationToken == nil || u.EmailVerificationExpiresAt == nil {
 		return false
 	}
 
-	if *u.EmailVerificationToken != token {
+	if !security.ConstantTimeCompare(*u.EmailVerificationToken, token) {
 		return false
 	}
 
-	return time.Now().Before(*u.EmailVerificationExpiresAt)
+	return now.Before(*u.EmailVerificationExpiresAt)
 }
 
 // IsPasswordResetTokenValid checks if the password reset token is valid
@@ -127,7 +209,7 @@ func (u *User) IsPasswordResetTokenValid(token string) bool {
 		return false
 	}
 
-	if *u.PasswordResetToken != token {
+	if !security.ConstantTimeCompare(*u.PasswordResetToken, token) {
 		return false
 	}
 
@@ -145,6 +227,12 @@ type RefreshToken struct {
 	IPAddress  *string
 	CreatedAt  time.Time
 	LastUsedAt time.Time
+	// DPoPThumbprint is the JWK thumbprint of the DPoP key the token is bound to
+	DPoPThumbprint *string
+	// RememberMe marks a long session the user asked to stay signed in for.
+	// Its lifetime follows the remember-me policy rather than the refresh
+	// token TTL.
+	RememberMe bool
 }
 
 // NewRefreshToken creates a new refresh token
@@ -160,11 +248,16 @@ func NewRefreshToken(userID string, expiresAt time.Time) *RefreshToken {
 
 // IsValid checks if the refresh token is still valid
 func (rt *RefreshToken) IsValid() bool {
+	return rt.IsValidAt(time.Now())
+}
+
+// IsValidAt checks if the refresh token is valid as of now
+func (rt *RefreshToken) IsValidAt(now time.Time) bool {
 	if rt.Revoked {
 		return false
 	}
 
-	return time.Now().Before(rt.ExpiresAt)
+	return now.Before(rt.ExpiresAt)
 }
 
 // Revoke marks the token as revoked
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// UserEventEmailVerified is the type of the event sent when a user verifies
+// their email address
+const UserEventEmailVerified = "user.email_verified"
+
+// Email verification methods, recorded in user.email_verified events
+const (
+	EmailVerificationLink = "link" // the link in the verification email
+	EmailVerificationCode = "code" // the code in the verification email
+)
+
+// UserEvent is a change to a user that downstream systems, such as CRM sync
+// or billing, are told about by webhook. Events are kept so deliveries that
+// were missed can be replayed.
+type UserEvent struct {
+	ID         int64
+	Type       string
+	UserID     string
+	Email      string
+	Data       map[string]interface{} // type-specific fields, such as the verification method
+	OccurredAt time.Time
+}
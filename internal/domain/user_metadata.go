@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// MaxMetadataKeyLength is the longest metadata key accepted
+const MaxMetadataKeyLength = 64
+
+// MetadataKind tells a user's metadata from their app_metadata
+type MetadataKind string
+
+const (
+	// MetadataKindUser is metadata users edit themselves
+	MetadataKindUser MetadataKind = "metadata"
+	// MetadataKindApp is metadata only admins edit, e.g. a plan or tenant
+	MetadataKindApp MetadataKind = "app_metadata"
+)
+
+var (
+	// ErrInvalidMetadata is returned when a metadata update is not an object
+	// of valid keys
+	ErrInvalidMetadata = errors.New("invalid metadata")
+	// ErrMetadataTooLarge is returned when metadata would exceed its size limit
+	ErrMetadataTooLarge = errors.New("metadata is too large")
+)
+
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// MetadataPatch is an update to a user's metadata or app_metadata: Set
+// replaces top-level keys and Remove deletes them. Nested objects are
+// replaced whole.
+type MetadataPatch struct {
+	Set    map[string]interface{}
+	Remove []string
+}
+
+// NewMetadataPatch builds a patch from a JSON merge patch object (RFC 7396),
+// where null removes a key. Keys are letters, digits, '_' and '-', at most
+// MaxMetadataKeyLength long.
+func NewMetadataPatch(object map[string]interface{}) (MetadataPatch, error) {
+	if object == nil {
+		return MetadataPatch{}, fmt.Errorf("%w: must be a JSON object", ErrInvalidMetadata)
+	}
+
+	patch := MetadataPatch{Set: make(map[string]interface{})}
+	for key, value := range object {
+		if err := ValidateMetadataKey(key); err != nil {
+			return MetadataPatch{}, err
+		}
+		if value == nil {
+			patch.Remove = append(patch.Remove, key)
+			continue
+		}
+		patch.Set[key] = value
+	}
+	sort.Strings(patch.Remove)
+	return patch, nil
+}
+
+// Empty reports whether the patch changes nothing
+func (p MetadataPatch) Empty() bool {
+	return len(p.Set) == 0 && len(p.Remove) == 0
+}
+
+// ValidateMetadataKey checks a top-level metadata key
+func ValidateMetadataKey(key string) error {
+	if len(key) > MaxMetadataKeyLength || !metadataKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key %q must be 1-%d letters, digits, '_' or '-'", ErrInvalidMetadata, key, MaxMetadataKeyLength)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -105,6 +106,73 @@ func TestValidateEmail(t *testing.T) {
 	}
 }
 
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{"valid username", "jane_doe", nil},
+		{"digits and dots", "jane.doe-42", nil},
+		{"minimum length", "abc", nil},
+		{"maximum length", "abcdefghijabcdefghijabcdefghij", nil},
+		{"too short", "ab", ErrInvalidUsername},
+		{"too long", "abcdefghijabcdefghijabcdefghijk", ErrInvalidUsername},
+		{"uppercase not normalized", "JaneDoe", ErrInvalidUsername},
+		{"contains at", "jane@doe", ErrInvalidUsername},
+		{"contains space", "jane doe", ErrInvalidUsername},
+		{"leading punctuation", "_jane", ErrInvalidUsername},
+		{"trailing punctuation", "jane.", ErrInvalidUsername},
+		{"reserved", "admin", ErrReservedUsername},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUsername(tt.username)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateUsername() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePhoneNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		phone   string
+		wantErr error
+	}{
+		{"valid US number", "+15551234567", nil},
+		{"valid maximum length", "+123456789012345", nil},
+		{"missing plus", "15551234567", ErrInvalidPhoneNumber},
+		{"leading zero", "+05551234567", ErrInvalidPhoneNumber},
+		{"too long", "+1234567890123456", ErrInvalidPhoneNumber},
+		{"formatted", "+1 555 123 4567", ErrInvalidPhoneNumber},
+		{"empty", "", ErrInvalidPhoneNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePhoneNumber(tt.phone)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidatePhoneNumber() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUser_SetPhoneNumber(t *testing.T) {
+	user := &User{}
+	user.SetPhoneNumber("+15551234567")
+	user.MarkPhoneVerified()
+	user.SMSSecondFactor = true
+
+	user.SetPhoneNumber("+15557654321")
+	if user.HasVerifiedPhone() || user.SMSSecondFactor {
+		t.Error("changing the phone number should reset verification and SMS second factor")
+	}
+}
+
 func TestValidatePassword(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -180,6 +248,11 @@ func TestUser_IsEmailVerificationTokenValid(t *testing.T) {
 		t.Error("Should return false for incorrect token")
 	}
 
+	// Test with a prefix or extension of the token
+	if user.IsEmailVerificationTokenValid("valid-") || user.IsEmailVerificationTokenValid(validToken+"x") {
+		t.Error("Should return false for a partial token match")
+	}
+
 	// Test with expired token
 	pastTime := time.Now().Add(-1 * time.Hour)
 	user.EmailVerificationExpiresAt = &pastTime
@@ -188,6 +261,26 @@ func TestUser_IsEmailVerificationTokenValid(t *testing.T) {
 	}
 }
 
+func TestUser_IsPasswordResetTokenValid(t *testing.T) {
+	user := &User{}
+	if user.IsPasswordResetTokenValid("any-token") {
+		t.Error("Should return false when no token is set")
+	}
+
+	user.SetPasswordResetToken("reset-token", time.Now().Add(time.Hour))
+	if !user.IsPasswordResetTokenValid("reset-token") {
+		t.Error("Should return true for valid token")
+	}
+	if user.IsPasswordResetTokenValid("reset-tokeN") || user.IsPasswordResetTokenValid("reset") {
+		t.Error("Should return false for incorrect token")
+	}
+
+	user.ClearPasswordResetToken()
+	if user.IsPasswordResetTokenValid("reset-token") {
+		t.Error("Should return false after the token is cleared")
+	}
+}
+
 func TestRefreshToken_IsValid(t *testing.T) {
 	userID := "user-123"
 
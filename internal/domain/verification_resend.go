@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrResendTooSoon is returned when a verification email was resent too
+// recently or too often today. It is wrapped in a *ResendLimitError.
+var ErrResendTooSoon = errors.New("verification email resent too recently")
+
+// ResendLimitError reports when the next verification email can be resent
+type ResendLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ResendLimitError) Error() string {
+	return fmt.Sprintf("%v; retry in %s", ErrResendTooSoon, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ResendLimitError) Unwrap() error {
+	return ErrResendTooSoon
+}
+
+// VerificationResend counts the verification emails resent to a user.
+// Count restarts every UTC day.
+type VerificationResend struct {
+	UserID     string
+	Day        time.Time // UTC midnight of the day Count applies to
+	Count      int
+	LastSentAt time.Time
+}
+
+// ResendDay returns the UTC day t counts towards
+func ResendDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// RetryAfter returns how long until the next resend is allowed, or 0 when
+// it is allowed now. A dailyLimit of 0 means no daily limit.
+func (r *VerificationResend) RetryAfter(now time.Time, cooldown time.Duration, dailyLimit int) time.Duration {
+	var wait time.Duration
+	if next := r.LastSentAt.Add(cooldown); next.After(now) {
+		wait = next.Sub(now)
+	}
+	if dailyLimit > 0 && r.Count >= dailyLimit && ResendDay(r.Day).Equal(ResendDay(now)) {
+		if untilTomorrow := ResendDay(now).Add(24 * time.Hour).Sub(now); untilTomorrow > wait {
+			wait = untilTomorrow
+		}
+	}
+	return wait
+}
@@ -0,0 +1,48 @@
+package email
+
+import "sort"
+
+// DefaultLocale is the language of the untranslated templates
+const DefaultLocale = "en"
+
+// Catalog returns the templates the service sends, by name, for previews
+func Catalog() map[string]Template {
+	return map[string]Template{
+		"verification":             VerificationEmailTemplate,
+		"verification_code":        VerificationCodeEmailTemplate,
+		"password_reset":           PasswordResetEmailTemplate,
+		"password_reset_code":      PasswordResetCodeEmailTemplate,
+		"login_notification":       LoginNotificationEmailTemplate,
+		"dormancy_notice":          DormancyNoticeEmailTemplate,
+		"password_reset_cancelled": PasswordResetCancelledEmailTemplate,
+		"session_handoff":          SessionHandoffEmailTemplate,
+	}
+}
+
+// Locales lists the locales the template is translated to, DefaultLocale
+// first
+func (t Template) Locales() []string {
+	locales := make([]string, 0, len(t.Translations))
+	for tag := range t.Translations {
+		locales = append(locales, tag)
+	}
+	sort.Strings(locales)
+	return append([]string{DefaultLocale}, locales...)
+}
+
+// SampleData fills in every template field with placeholder values, so a
+// preview shows each part of a template. The app fields of base are kept.
+func SampleData(base TemplateData) TemplateData {
+	data := base
+	data.RecipientName = "Jane Doe"
+	data.VerificationToken = "sample-verification-token"
+	data.VerificationURL = base.BaseURL + "/verify-email?token=sample-verification-token&email=" + base.RecipientEmail
+	data.ResetToken = "sample-reset-token"
+	data.ResetURL = base.BaseURL + "/reset-password?token=sample-reset-token"
+	data.LoginURL = base.BaseURL + "/login"
+	data.ExpirationHours = 24
+	data.DisableDate = "2030-01-02"
+	data.Code = "123456"
+	data.ExpirationMinutes = 15
+	return data
+}
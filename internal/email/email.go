@@ -15,6 +15,10 @@ type Email struct {
 	Body        string
 	HTMLBody    string
 	Attachments []Attachment
+
+	// Template names the template the email was rendered from, for logs
+	// and metrics; empty for emails built by hand
+	Template string
 }
 
 // Attachment represents an email attachment
@@ -31,9 +35,16 @@ type Service interface {
 
 // Template represents an email template
 type Template struct {
+	// Name is the catalog name, carried over to rendered emails
+	Name string
+
 	Subject string
 	Body    string
 	HTML    string
+
+	// Translations holds localized variants keyed by lowercase BCP 47 tag
+	// ("es", "pt-br"); see ForLocale
+	Translations map[string]Template
 }
 
 // TemplateData represents data for email templates
@@ -50,11 +61,16 @@ type TemplateData struct {
 	ResetURL          string
 	LoginURL          string
 	ExpirationHours   int
+	DisableDate       string // date a dormant account will be disabled
+	Code              string // one-time code for users who cannot open links
+	ExpirationMinutes int    // lifetime of Code
+	TrackingPixelURL  string // open tracking pixel of HTML emails; none when empty
 }
 
 // Templates for different email types
 var (
 	VerificationEmailTemplate = Template{
+		Name:    "verification",
 		Subject: "Verify your email address",
 		Body: `Hello,
 
@@ -103,11 +119,13 @@ The {{.AppName}} Team`,
             <p>If you have any questions, contact us at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a></p>
         </div>
     </div>
+    {{if .TrackingPixelURL}}<img src="{{.TrackingPixelURL}}" width="1" height="1" alt="" style="display: block;">{{end}}
 </body>
 </html>`,
 	}
 
 	PasswordResetEmailTemplate = Template{
+		Name:    "password_reset",
 		Subject: "Reset your password",
 		Body: `Hello,
 
@@ -163,6 +181,7 @@ The {{.AppName}} Team`,
 	}
 
 	LoginNotificationEmailTemplate = Template{
+		Name:    "login_notification",
 		Subject: "New login to your account",
 		Body: `Hello,
 
@@ -211,6 +230,285 @@ The {{.AppName}} Team`,
             <p>If you have any questions, contact us at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a></p>
         </div>
     </div>
+    {{if .TrackingPixelURL}}<img src="{{.TrackingPixelURL}}" width="1" height="1" alt="" style="display: block;">{{end}}
+</body>
+</html>`,
+	}
+
+	DormancyNoticeEmailTemplate = Template{
+		Name:    "dormancy_notice",
+		Subject: "Your {{.AppName}} account will be disabled",
+		Body: `Hello,
+
+We noticed you haven't used your {{.AppName}} account in a long time.
+
+To keep your data safe, inactive accounts are disabled and later deleted. Your account will be disabled on {{.DisableDate}} unless you sign in before then:
+
+{{.LoginURL}}
+
+If you no longer need the account, you don't have to do anything.
+
+Best regards,
+The {{.AppName}} Team`,
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Your account will be disabled</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #f8f9fa; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .button { display: inline-block; padding: 12px 24px; background-color: #007bff; color: white; text-decoration: none; border-radius: 4px; }
+        .footer { margin-top: 40px; padding-top: 20px; border-top: 1px solid #dee2e6; font-size: 14px; color: #6c757d; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>We miss you</h1>
+        </div>
+        <div class="content">
+            <p>Hello,</p>
+            <p>We noticed you haven't used your {{.AppName}} account in a long time.</p>
+            <p>To keep your data safe, inactive accounts are disabled and later deleted. Your account will be disabled on <strong>{{.DisableDate}}</strong> unless you sign in before then.</p>
+            <p style="text-align: center; margin: 30px 0;">
+                <a href="{{.LoginURL}}" class="button">Sign In</a>
+            </p>
+            <p>If you no longer need the account, you don't have to do anything.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.CurrentYear}} {{.AppName}}. All rights reserved.</p>
+            <p>If you have any questions, contact us at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a></p>
+        </div>
+    </div>
+</body>
+</html>`,
+	}
+	// VerificationCodeEmailTemplate is the verification email with a
+	// one-time code next to the link
+	VerificationCodeEmailTemplate = Template{
+		Name:    "verification_code",
+		Subject: "Your {{.AppName}} verification code: {{.Code}}",
+		Body: `Hello,
+
+Welcome to {{.AppName}}! Enter this code in the app to verify your email address:
+
+{{.Code}}
+
+The code expires in {{.ExpirationMinutes}} minutes. You can also open this link instead:
+
+{{.VerificationURL}}
+
+If you didn't create an account, please ignore this email.
+
+Best regards,
+The {{.AppName}} Team`,
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Verify your email address</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #f8f9fa; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .button { display: inline-block; padding: 12px 24px; background-color: #007bff; color: white; text-decoration: none; border-radius: 4px; }
+        .code { font-family: monospace; font-size: 32px; letter-spacing: 8px; text-align: center; margin: 30px 0; }
+        .footer { margin-top: 40px; padding-top: 20px; border-top: 1px solid #dee2e6; font-size: 14px; color: #6c757d; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Welcome to {{.AppName}}!</h1>
+        </div>
+        <div class="content">
+            <p>Hello,</p>
+            <p>Thank you for signing up! Enter this code in the app to verify your email address:</p>
+            <p class="code"><strong>{{.Code}}</strong></p>
+            <p>The code expires in {{.ExpirationMinutes}} minutes. You can also verify your address with the button below:</p>
+            <p style="text-align: center; margin: 30px 0;">
+                <a href="{{.VerificationURL}}" class="button">Verify Email Address</a>
+            </p>
+            <p>If you didn't create an account, please ignore this email.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.CurrentYear}} {{.AppName}}. All rights reserved.</p>
+            <p>If you have any questions, contact us at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a></p>
+        </div>
+    </div>
+    {{if .TrackingPixelURL}}<img src="{{.TrackingPixelURL}}" width="1" height="1" alt="" style="display: block;">{{end}}
+</body>
+</html>`,
+	}
+
+	// PasswordResetCodeEmailTemplate carries a one-time password reset code
+	PasswordResetCodeEmailTemplate = Template{
+		Name:    "password_reset_code",
+		Subject: "Your {{.AppName}} password reset code: {{.Code}}",
+		Body: `Hello,
+
+We received a request to reset the password of your {{.AppName}} account. Enter this code in the app to choose a new password:
+
+{{.Code}}
+{{if .ResetURL}}
+Or open this link on your phone:
+
+{{.ResetURL}}
+{{end}}
+The code expires in {{.ExpirationMinutes}} minutes. Resetting your password signs you out from all devices.
+
+If you didn't request a password reset, please ignore this email. Your password will not be changed.
+
+Best regards,
+The {{.AppName}} Team`,
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Reset your password</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #f8f9fa; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .button { display: inline-block; padding: 12px 24px; background-color: #007bff; color: white; text-decoration: none; border-radius: 4px; }
+        .code { font-family: monospace; font-size: 32px; letter-spacing: 8px; text-align: center; margin: 30px 0; }
+        .footer { margin-top: 40px; padding-top: 20px; border-top: 1px solid #dee2e6; font-size: 14px; color: #6c757d; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Password Reset</h1>
+        </div>
+        <div class="content">
+            <p>Hello,</p>
+            <p>We received a request to reset the password of your {{.AppName}} account. Enter this code in the app to choose a new password:</p>
+            <p class="code"><strong>{{.Code}}</strong></p>
+            {{if .ResetURL}}<p style="text-align: center;">
+                <a href="{{.ResetURL}}" class="button">Reset Password</a>
+            </p>{{end}}
+            <p>The code expires in {{.ExpirationMinutes}} minutes. Resetting your password signs you out from all devices.</p>
+            <p>If you didn't request a password reset, please ignore this email. Your password will not be changed.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.CurrentYear}} {{.AppName}}. All rights reserved.</p>
+            <p>If you have any questions, contact us at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a></p>
+        </div>
+    </div>
+</body>
+</html>`,
+	}
+	// PasswordResetCancelledEmailTemplate tells a user that signing in with
+	// the password cancelled a pending password reset
+	PasswordResetCancelledEmailTemplate = Template{
+		Name:    "password_reset_cancelled",
+		Subject: "Your {{.AppName}} password reset was cancelled",
+		Body: `Hello,
+
+You just signed in to your {{.AppName}} account with your password, so we cancelled the password reset that was still pending. The reset code we sent you no longer works.
+
+If you didn't request a password reset, someone may have entered your email address by mistake. Your account is safe and you don't have to do anything.
+
+If you still want to change your password, you can do it from your account settings:
+
+{{.LoginURL}}
+
+Best regards,
+The {{.AppName}} Team`,
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Password reset cancelled</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #f8f9fa; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .button { display: inline-block; padding: 12px 24px; background-color: #007bff; color: white; text-decoration: none; border-radius: 4px; }
+        .footer { margin-top: 40px; padding-top: 20px; border-top: 1px solid #dee2e6; font-size: 14px; color: #6c757d; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Password Reset Cancelled</h1>
+        </div>
+        <div class="content">
+            <p>Hello,</p>
+            <p>You just signed in to your {{.AppName}} account with your password, so we cancelled the password reset that was still pending. The reset code we sent you no longer works.</p>
+            <p>If you didn't request a password reset, someone may have entered your email address by mistake. Your account is safe and you don't have to do anything.</p>
+            <p style="text-align: center; margin: 30px 0;">
+                <a href="{{.LoginURL}}" class="button">Account Settings</a>
+            </p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.CurrentYear}} {{.AppName}}. All rights reserved.</p>
+            <p>If you have any questions, contact us at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a></p>
+        </div>
+    </div>
+</body>
+</html>`,
+	}
+	// SessionHandoffEmailTemplate tells a user that a device signed in with a
+	// handoff code created on one of their signed-in devices
+	SessionHandoffEmailTemplate = Template{
+		Name:    "session_handoff",
+		Subject: "A new device signed in to your {{.AppName}} account",
+		Body: `Hello,
+
+A new device just signed in to your {{.AppName}} account with a sign-in code, such as a QR code, shown on one of your signed-in devices.
+
+If this was you, you can safely ignore this email.
+
+If you didn't do this, someone may have seen the code. Sign out all devices and change your password from your account settings:
+
+{{.LoginURL}}
+
+Best regards,
+The {{.AppName}} Team`,
+		HTML: `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>New device signed in</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #f8f9fa; padding: 20px; text-align: center; }
+        .content { padding: 20px; }
+        .warning { background-color: #fff3cd; border: 1px solid #ffeaa7; padding: 15px; border-radius: 4px; margin: 20px 0; }
+        .button { display: inline-block; padding: 12px 24px; background-color: #ffc107; color: #212529; text-decoration: none; border-radius: 4px; }
+        .footer { margin-top: 40px; padding-top: 20px; border-top: 1px solid #dee2e6; font-size: 14px; color: #6c757d; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>New Device Signed In</h1>
+        </div>
+        <div class="content">
+            <p>Hello,</p>
+            <p>A new device just signed in to your {{.AppName}} account with a sign-in code, such as a QR code, shown on one of your signed-in devices.</p>
+            <div class="warning">
+                <p><strong>If this wasn't you:</strong></p>
+                <p>Someone may have seen the code. Sign out all devices and change your password.</p>
+                <p style="text-align: center; margin: 20px 0;">
+                    <a href="{{.LoginURL}}" class="button">Secure My Account</a>
+                </p>
+            </div>
+            <p>If this was you, you can safely ignore this email.</p>
+        </div>
+        <div class="footer">
+            <p>&copy; {{.CurrentYear}} {{.AppName}}. All rights reserved.</p>
+            <p>If you have any questions, contact us at <a href="mailto:{{.SupportEmail}}">{{.SupportEmail}}</a></p>
+        </div>
+    </div>
 </body>
 </html>`,
 	}
@@ -225,6 +523,9 @@ func RenderTemplate(tmpl Template, data TemplateData) (Email, error) {
 	if data.ExpirationHours == 0 {
 		data.ExpirationHours = 24
 	}
+	if data.ExpirationMinutes == 0 {
+		data.ExpirationMinutes = 15
+	}
 
 	// Render subject
 	subjectTmpl, err := template.New("subject").Parse(tmpl.Subject)
@@ -263,5 +564,6 @@ func RenderTemplate(tmpl Template, data TemplateData) (Email, error) {
 		Subject:  subjectBuf.String(),
 		Body:     bodyBuf.String(),
 		HTMLBody: htmlBuf.String(),
+		Template: tmpl.Name,
 	}, nil
 }
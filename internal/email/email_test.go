@@ -43,6 +43,28 @@ func TestRenderTemplate(t *testing.T) {
 				if !strings.Contains(email.HTMLBody, "Welcome to Test App") {
 					t.Error("HTML body should contain app name")
 				}
+				if strings.Contains(email.HTMLBody, "<img") {
+					t.Error("HTML body should not contain a tracking pixel")
+				}
+			},
+		},
+		{
+			name:     "verification email with tracking pixel",
+			template: VerificationEmailTemplate,
+			data: TemplateData{
+				AppName:          "Test App",
+				RecipientEmail:   "user@example.com",
+				VerificationURL:  "https://example.com/verify?token=abc123",
+				TrackingPixelURL: "https://example.com/api/v1/email/open?e=verification&s=sig",
+			},
+			wantErr: false,
+			validate: func(t *testing.T, email Email) {
+				if !strings.Contains(email.HTMLBody, `<img src="https://example.com/api/v1/email/open?e=verification&amp;s=sig"`) {
+					t.Errorf("HTML body should contain the tracking pixel, got: %s", email.HTMLBody)
+				}
+				if strings.Contains(email.Body, "api/v1/email/open") {
+					t.Error("text body should not contain the tracking pixel")
+				}
 			},
 		},
 		{
@@ -68,6 +90,47 @@ func TestRenderTemplate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "verification code email",
+			template: VerificationCodeEmailTemplate,
+			data: TemplateData{
+				AppName:           "Test App",
+				RecipientEmail:    "user@example.com",
+				VerificationURL:   "https://example.com/verify?token=abc123",
+				Code:              "042137",
+				ExpirationMinutes: 10,
+			},
+			wantErr: false,
+			validate: func(t *testing.T, email Email) {
+				if !strings.Contains(email.Body, "042137") || !strings.Contains(email.HTMLBody, "042137") {
+					t.Error("bodies should contain the code")
+				}
+				if !strings.Contains(email.Body, "10 minutes") {
+					t.Error("body should contain the code lifetime")
+				}
+				if !strings.Contains(email.Body, "https://example.com/verify?token=abc123") {
+					t.Error("body should still contain verification URL")
+				}
+			},
+		},
+		{
+			name:     "password reset code email",
+			template: PasswordResetCodeEmailTemplate,
+			data: TemplateData{
+				AppName:        "Test App",
+				RecipientEmail: "user@example.com",
+				Code:           "913004",
+			},
+			wantErr: false,
+			validate: func(t *testing.T, email Email) {
+				if !strings.Contains(email.Body, "913004") {
+					t.Error("body should contain the code")
+				}
+				if !strings.Contains(email.Body, "15 minutes") {
+					t.Error("body should contain default code lifetime")
+				}
+			},
+		},
 		{
 			name:     "login notification email",
 			template: LoginNotificationEmailTemplate,
@@ -141,3 +204,65 @@ func TestTemplateData_Defaults(t *testing.T) {
 		t.Errorf("Expected default expiration hours in subject")
 	}
 }
+
+func TestTemplate_ForLocale(t *testing.T) {
+	tmpl := Template{
+		Subject: "Hello",
+		Translations: map[string]Template{
+			"es":    {Subject: "Hola"},
+			"pt-br": {Subject: "Olá"},
+		},
+	}
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"", "Hello"},
+		{"en", "Hello"},
+		{"es", "Hola"},
+		{"es-MX", "Hola"},
+		{"pt-BR", "Olá"},
+		{"pt_BR", "Olá"},
+		{"pt-PT", "Hello"},
+		{"fr-CA", "Hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			if got := tmpl.ForLocale(tt.locale).Subject; got != tt.want {
+				t.Errorf("ForLocale(%q).Subject = %q, want %q", tt.locale, got, tt.want)
+			}
+		})
+	}
+
+	if got := VerificationEmailTemplate.ForLocale("es").Subject; got == VerificationEmailTemplate.Subject {
+		t.Error("expected a Spanish verification template")
+	}
+}
+
+func TestCatalog_RendersEveryLocale(t *testing.T) {
+	data := SampleData(TemplateData{BaseURL: "https://auth.example.com", AppName: "Example", RecipientEmail: "jane@example.com"})
+	for name, tmpl := range Catalog() {
+		locales := tmpl.Locales()
+		if locales[0] != DefaultLocale {
+			t.Errorf("%s: Locales() = %v, want %s first", name, locales, DefaultLocale)
+		}
+		for _, locale := range locales {
+			if got := tmpl.MatchLocale(locale); got != locale {
+				t.Errorf("%s: MatchLocale(%q) = %q", name, locale, got)
+			}
+			rendered, err := RenderTemplate(tmpl.ForLocale(locale), data)
+			if err != nil {
+				t.Errorf("%s in %s: RenderTemplate() error = %v", name, locale, err)
+				continue
+			}
+			if rendered.Subject == "" || strings.Contains(rendered.Body, "<no value>") {
+				t.Errorf("%s in %s rendered incompletely: %+v", name, locale, rendered)
+			}
+			if rendered.Template != name {
+				t.Errorf("%s in %s: Template = %q, want %q", name, locale, rendered.Template, name)
+			}
+		}
+	}
+}
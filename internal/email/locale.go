@@ -0,0 +1,129 @@
+package email
+
+import "strings"
+
+func init() {
+	VerificationEmailTemplate.Translations = map[string]Template{
+		"es": verificationEmailTemplateES,
+	}
+	LoginNotificationEmailTemplate.Translations = map[string]Template{
+		"es": loginNotificationEmailTemplateES,
+	}
+	DormancyNoticeEmailTemplate.Translations = map[string]Template{
+		"es": dormancyNoticeEmailTemplateES,
+	}
+	VerificationCodeEmailTemplate.Translations = map[string]Template{
+		"es": verificationCodeEmailTemplateES,
+	}
+	PasswordResetCancelledEmailTemplate.Translations = map[string]Template{
+		"es": passwordResetCancelledEmailTemplateES,
+	}
+}
+
+// ForLocale returns the translation of the template for a BCP 47 locale,
+// trying the full tag before its base language ("pt-BR", then "pt") and
+// falling back to the template itself
+func (t Template) ForLocale(locale string) Template {
+	if translated, ok := t.Translations[t.MatchLocale(locale)]; ok {
+		translated.Name = t.Name
+		return translated
+	}
+	return t
+}
+
+// MatchLocale returns the translation tag ForLocale picks for a locale, or
+// DefaultLocale when it falls back to the template itself
+func (t Template) MatchLocale(locale string) string {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	for tag != "" {
+		if _, ok := t.Translations[tag]; ok {
+			return tag
+		}
+		dash := strings.LastIndexByte(tag, '-')
+		if dash < 0 {
+			break
+		}
+		tag = tag[:dash]
+	}
+	return DefaultLocale
+}
+
+var verificationEmailTemplateES = Template{
+	Subject: "Verifica tu dirección de correo electrónico",
+	Body: `Hola:
+
+¡Te damos la bienvenida a {{.AppName}}! Verifica tu dirección de correo electrónico haciendo clic en el siguiente enlace:
+
+{{.VerificationURL}}
+
+Este enlace caducará en {{.ExpirationHours}} horas.
+
+Si no has creado una cuenta, ignora este correo.
+
+Saludos,
+El equipo de {{.AppName}}`,
+}
+
+var verificationCodeEmailTemplateES = Template{
+	Subject: "Tu código de verificación de {{.AppName}}: {{.Code}}",
+	Body: `Hola:
+
+¡Te damos la bienvenida a {{.AppName}}! Introduce este código en la aplicación para verificar tu dirección de correo electrónico:
+
+{{.Code}}
+
+El código caducará en {{.ExpirationMinutes}} minutos. También puedes abrir este enlace:
+
+{{.VerificationURL}}
+
+Si no has creado una cuenta, ignora este correo.
+
+Saludos,
+El equipo de {{.AppName}}`,
+}
+
+var loginNotificationEmailTemplateES = Template{
+	Subject: "Nuevo inicio de sesión en tu cuenta",
+	Body: `Hola:
+
+Hemos detectado un nuevo inicio de sesión en tu cuenta de {{.AppName}}.
+
+Si fuiste tú, puedes ignorar este correo.
+
+Si no has iniciado sesión, protege tu cuenta de inmediato cambiando tu contraseña.
+
+Saludos,
+El equipo de {{.AppName}}`,
+}
+
+var dormancyNoticeEmailTemplateES = Template{
+	Subject: "Tu cuenta de {{.AppName}} será desactivada",
+	Body: `Hola:
+
+Hemos notado que hace mucho tiempo que no usas tu cuenta de {{.AppName}}.
+
+Para proteger tus datos, las cuentas inactivas se desactivan y después se eliminan. Tu cuenta se desactivará el {{.DisableDate}} si no inicias sesión antes:
+
+{{.LoginURL}}
+
+Si ya no necesitas la cuenta, no tienes que hacer nada.
+
+Saludos,
+El equipo de {{.AppName}}`,
+}
+
+var passwordResetCancelledEmailTemplateES = Template{
+	Subject: "Se canceló el restablecimiento de tu contraseña de {{.AppName}}",
+	Body: `Hola:
+
+Acabas de iniciar sesión en tu cuenta de {{.AppName}} con tu contraseña, así que hemos cancelado el restablecimiento de contraseña que estaba pendiente. El código que te enviamos ya no funciona.
+
+Si no solicitaste restablecer tu contraseña, es posible que alguien haya escrito tu dirección de correo por error. Tu cuenta está segura y no tienes que hacer nada.
+
+Si aún quieres cambiar tu contraseña, puedes hacerlo desde la configuración de tu cuenta:
+
+{{.LoginURL}}
+
+Saludos,
+El equipo de {{.AppName}}`,
+}
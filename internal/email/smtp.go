@@ -3,6 +3,8 @@ package email
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -11,6 +13,30 @@ import (
 	"time"
 )
 
+// TLSPolicy is how SMTPService secures its connection to the server
+type TLSPolicy string
+
+const (
+	TLSPolicyNone     TLSPolicy = "none"     // plain text, even when the server offers STARTTLS
+	TLSPolicyStartTLS TLSPolicy = "starttls" // STARTTLS when the server offers it, plain text otherwise
+	TLSPolicyRequired TLSPolicy = "required" // STARTTLS, failing when the server does not offer it
+	TLSPolicyImplicit TLSPolicy = "implicit" // TLS from the start of the connection (SMTPS, port 465)
+)
+
+// ErrSTARTTLSNotOffered is returned when the TLS policy requires STARTTLS
+// and the server does not advertise it
+var ErrSTARTTLSNotOffered = errors.New("SMTP server does not offer STARTTLS, which the TLS policy requires")
+
+// ParseTLSPolicy parses a TLS policy name; an empty name is accepted and
+// leaves the policy to TLSEnabled and the port
+func ParseTLSPolicy(s string) (TLSPolicy, error) {
+	switch policy := TLSPolicy(s); policy {
+	case "", TLSPolicyNone, TLSPolicyStartTLS, TLSPolicyRequired, TLSPolicyImplicit:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid SMTP TLS policy %q: must be none, starttls, required or implicit", s)
+}
+
 // SMTPConfig holds SMTP configuration
 type SMTPConfig struct {
 	Host        string
@@ -21,6 +47,27 @@ type SMTPConfig struct {
 	FromName    string
 	TLSEnabled  bool
 	Timeout     time.Duration
+
+	// TLSPolicy overrides TLSEnabled. When empty, TLSEnabled means implicit
+	// TLS on port 465 and required STARTTLS on other ports.
+	TLSPolicy TLSPolicy
+	RootCAs   *x509.CertPool // trusted instead of the system roots when set
+	// InsecureSkipVerify accepts any server certificate; development only
+	InsecureSkipVerify bool
+}
+
+// EffectiveTLSPolicy is the TLS policy Send applies
+func (c SMTPConfig) EffectiveTLSPolicy() TLSPolicy {
+	switch {
+	case c.TLSPolicy != "":
+		return c.TLSPolicy
+	case !c.TLSEnabled:
+		return TLSPolicyNone
+	case c.Port == 465:
+		return TLSPolicyImplicit
+	default:
+		return TLSPolicyRequired
+	}
 }
 
 // SMTPService implements the email service using SMTP
@@ -77,22 +124,35 @@ func (s *SMTPService) Send(ctx context.Context, email Email) error {
 	// Set deadline on connection
 	conn.SetDeadline(deadline)
 
+	policy := s.config.EffectiveTLSPolicy()
+	if policy == TLSPolicyImplicit {
+		tlsConn := tls.Client(conn, s.tlsConfig())
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("implicit TLS handshake with %s failed, the server may expect STARTTLS instead: %w", addr, err)
+		}
+		conn = tlsConn
+	}
+
 	// Create SMTP client
 	client, err := smtp.NewClient(conn, s.config.Host)
 	if err != nil {
+		if s.config.Port == 465 && policy != TLSPolicyImplicit {
+			return fmt.Errorf("failed to create SMTP client, port 465 usually expects implicit TLS: %w", err)
+		}
 		return fmt.Errorf("failed to create SMTP client: %w", err)
 	}
 	defer client.Close()
 
-	// Start TLS if enabled
-	if s.config.TLSEnabled {
-		tlsConfig := &tls.Config{
-			ServerName: s.config.Host,
-			MinVersion: tls.VersionTLS12,
-		}
-
-		if err := client.StartTLS(tlsConfig); err != nil {
-			return fmt.Errorf("failed to start TLS: %w", err)
+	// Upgrade to TLS as the policy asks
+	if policy == TLSPolicyStartTLS || policy == TLSPolicyRequired {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(s.tlsConfig()); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		} else if policy == TLSPolicyRequired {
+			return fmt.Errorf("%w: %s", ErrSTARTTLSNotOffered, addr)
+		} else {
+			s.logger.Warn("SMTP server does not offer STARTTLS, sending in plain text", "server", addr)
 		}
 	}
 
@@ -142,6 +202,16 @@ func (s *SMTPService) Send(ctx context.Context, email Email) error {
 	return nil
 }
 
+// tlsConfig verifies the server's certificate for Host
+func (s *SMTPService) tlsConfig() *tls.Config {
+	return &tls.Config{
+		ServerName:         s.config.Host,
+		MinVersion:         tls.VersionTLS12,
+		RootCAs:            s.config.RootCAs,
+		InsecureSkipVerify: s.config.InsecureSkipVerify,
+	}
+}
+
 // ValidateSMTPConfig validates SMTP configuration
 func ValidateSMTPConfig(config SMTPConfig) error {
 	if config.Host == "" {
@@ -173,10 +243,21 @@ func ValidateSMTPConfig(config SMTPConfig) error {
 		slog.Warn("using non-standard SMTP port", "port", config.Port)
 	}
 
+	if _, err := ParseTLSPolicy(string(config.TLSPolicy)); err != nil {
+		return err
+	}
+
 	// TLS should be enabled for common secure ports
-	if (config.Port == 465 || config.Port == 587) && !config.TLSEnabled {
+	policy := config.EffectiveTLSPolicy()
+	if (config.Port == 465 || config.Port == 587) && policy == TLSPolicyNone {
 		slog.Warn("TLS is disabled for secure SMTP port", "port", config.Port)
 	}
+	if config.Port == 465 && policy != TLSPolicyImplicit && policy != TLSPolicyNone {
+		slog.Warn("SMTP port 465 usually expects implicit TLS", "port", config.Port, "tls_policy", policy)
+	}
+	if config.InsecureSkipVerify {
+		slog.Warn("SMTP server certificates are not verified")
+	}
 
 	return nil
 }
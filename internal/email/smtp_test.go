@@ -1,10 +1,15 @@
 package email
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"io"
 	"log/slog"
 	"net"
+	"net/http/httptest"
 	"net/smtp"
 	"strings"
 	"testing"
@@ -459,3 +464,181 @@ func TestSMTPService_SendWithMockServer(t *testing.T) {
 		t.Errorf("Unexpected error: %v", err)
 	}
 }
+
+// serveSMTP answers SMTP sessions on listener until it is closed. With
+// startTLS set, STARTTLS is advertised and upgrades the session.
+func serveSMTP(listener net.Listener, startTLS *tls.Config) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			conn.Write([]byte("220 mock.smtp.server ESMTP\r\n"))
+			inData := false
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if inData {
+					if line == ".\r\n" {
+						inData = false
+						conn.Write([]byte("250 OK: queued\r\n"))
+					}
+					continue
+				}
+				switch {
+				case strings.HasPrefix(line, "EHLO"):
+					if startTLS != nil {
+						conn.Write([]byte("250-mock.smtp.server\r\n250-STARTTLS\r\n250 AUTH PLAIN\r\n"))
+					} else {
+						conn.Write([]byte("250-mock.smtp.server\r\n250 AUTH PLAIN\r\n"))
+					}
+				case strings.HasPrefix(line, "STARTTLS"):
+					conn.Write([]byte("220 Ready to start TLS\r\n"))
+					conn = tls.Server(conn, startTLS)
+					reader = bufio.NewReader(conn)
+					startTLS = nil
+				case strings.HasPrefix(line, "AUTH"):
+					conn.Write([]byte("235 Authentication successful\r\n"))
+				case strings.HasPrefix(line, "DATA"):
+					inData = true
+					conn.Write([]byte("354 End data with <CR><LF>.<CR><LF>\r\n"))
+				case strings.HasPrefix(line, "QUIT"):
+					conn.Write([]byte("221 Bye\r\n"))
+					return
+				default:
+					conn.Write([]byte("250 OK\r\n"))
+				}
+			}
+		}(conn)
+	}
+}
+
+// testServerTLS returns a certificate for 127.0.0.1 and a pool trusting it
+func testServerTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return &tls.Config{Certificates: srv.TLS.Certificates}, pool
+}
+
+func TestSMTPConfig_EffectiveTLSPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SMTPConfig
+		want   TLSPolicy
+	}{
+		{"tls disabled", SMTPConfig{Port: 587}, TLSPolicyNone},
+		{"tls enabled", SMTPConfig{Port: 587, TLSEnabled: true}, TLSPolicyRequired},
+		{"tls enabled on 465", SMTPConfig{Port: 465, TLSEnabled: true}, TLSPolicyImplicit},
+		{"explicit policy", SMTPConfig{Port: 587, TLSEnabled: true, TLSPolicy: TLSPolicyStartTLS}, TLSPolicyStartTLS},
+		{"explicit none", SMTPConfig{Port: 465, TLSEnabled: true, TLSPolicy: TLSPolicyNone}, TLSPolicyNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.EffectiveTLSPolicy(); got != tt.want {
+				t.Errorf("EffectiveTLSPolicy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTLSPolicy(t *testing.T) {
+	for _, name := range []string{"", "none", "starttls", "required", "implicit"} {
+		if policy, err := ParseTLSPolicy(name); err != nil || string(policy) != name {
+			t.Errorf("ParseTLSPolicy(%q) = %q, %v", name, policy, err)
+		}
+	}
+	if _, err := ParseTLSPolicy("ssl"); err == nil {
+		t.Error("ParseTLSPolicy(ssl) should fail")
+	}
+}
+
+func TestSMTPService_SendTLSPolicies(t *testing.T) {
+	serverTLS, roots := testServerTLS(t)
+
+	plain, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer plain.Close()
+	go serveSMTP(plain, nil)
+
+	startTLS, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer startTLS.Close()
+	go serveSMTP(startTLS, serverTLS)
+
+	implicit, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer implicit.Close()
+	go serveSMTP(implicit, nil)
+
+	port := func(l net.Listener) int { return l.Addr().(*net.TCPAddr).Port }
+
+	tests := []struct {
+		name     string
+		port     int
+		policy   TLSPolicy
+		roots    *x509.CertPool
+		insecure bool
+		wantErr  error
+		wantFail bool
+	}{
+		{name: "none", port: port(plain), policy: TLSPolicyNone},
+		{name: "starttls falls back to plain text", port: port(plain), policy: TLSPolicyStartTLS},
+		{name: "required without STARTTLS", port: port(plain), policy: TLSPolicyRequired, wantErr: ErrSTARTTLSNotOffered},
+		{name: "required", port: port(startTLS), policy: TLSPolicyRequired, roots: roots},
+		{name: "required with unknown CA", port: port(startTLS), policy: TLSPolicyRequired, wantFail: true},
+		{name: "implicit", port: port(implicit), policy: TLSPolicyImplicit, roots: roots},
+		{name: "implicit without verification", port: port(implicit), policy: TLSPolicyImplicit, insecure: true},
+		{name: "implicit with unknown CA", port: port(implicit), policy: TLSPolicyImplicit, wantFail: true},
+		{name: "implicit against plain text", port: port(plain), policy: TLSPolicyImplicit, wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewSMTPService(SMTPConfig{
+				Host:               "127.0.0.1",
+				Port:               tt.port,
+				Username:           "user@example.com",
+				Password:           "password",
+				FromAddress:        "noreply@example.com",
+				TLSPolicy:          tt.policy,
+				RootCAs:            tt.roots,
+				InsecureSkipVerify: tt.insecure,
+				Timeout:            5 * time.Second,
+			}, slog.Default())
+
+			err := service.Send(context.Background(), Email{
+				To:      "recipient@example.com",
+				Subject: "Test Email",
+				Body:    "Test body",
+			})
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Send() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantFail:
+				if err == nil {
+					t.Error("Send() should fail")
+				}
+			case err != nil:
+				t.Errorf("Send() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Package emailnorm canonicalizes email addresses so that equivalent
+// spellings of the same mailbox map to a single account.
+package emailnorm
+
+import (
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"golang.org/x/text/unicode/norm"
+)
+
+// gmailDomains are the domains whose local parts ignore dots and +suffixes
+var gmailDomains = map[string]struct{}{
+	"gmail.com":      {},
+	"googlemail.com": {},
+}
+
+// Normalizer produces the value used for uniqueness checks and lookups
+type Normalizer struct {
+	// CanonicalizeGmail strips dots and +suffixes from Gmail addresses and
+	// folds googlemail.com into gmail.com
+	CanonicalizeGmail bool
+}
+
+// ToASCII returns the address trimmed, NFC-normalized and lowercased, with
+// a unicode domain converted to its IDNA (punycode) form. It is the form
+// stored as the user's email and used to send mail.
+func ToASCII(email string) (string, error) {
+	email = strings.ToLower(norm.NFC.String(strings.TrimSpace(email)))
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", domain.ErrInvalidEmail
+	}
+	local, host := email[:at], strings.TrimSuffix(email[at+1:], ".")
+
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if label == "" {
+			return "", domain.ErrInvalidEmail
+		}
+		ascii, err := toASCIILabel(label)
+		if err != nil {
+			return "", domain.ErrInvalidEmail
+		}
+		labels[i] = ascii
+	}
+
+	return local + "@" + strings.Join(labels, "."), nil
+}
+
+// Normalize returns the canonical form of an email address. Two addresses
+// that deliver to the same mailbox normalize to the same value.
+func (n Normalizer) Normalize(email string) (string, error) {
+	email, err := ToASCII(email)
+	if err != nil {
+		return "", err
+	}
+	if !n.CanonicalizeGmail {
+		return email, nil
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, host := email[:at], email[at+1:]
+	if _, ok := gmailDomains[host]; !ok {
+		return email, nil
+	}
+
+	if plus := strings.IndexByte(local, '+'); plus >= 0 {
+		local = local[:plus]
+	}
+	local = strings.ReplaceAll(local, ".", "")
+	if local == "" {
+		return "", domain.ErrInvalidEmail
+	}
+
+	return local + "@gmail.com", nil
+}
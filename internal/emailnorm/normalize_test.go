@@ -0,0 +1,121 @@
+package emailnorm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestToASCII(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		want    string
+		wantErr error
+	}{
+		{name: "lowercases and trims", email: "  John.Doe@Example.COM ", want: "john.doe@example.com"},
+		{name: "keeps plus addressing", email: "user+tag@example.com", want: "user+tag@example.com"},
+		{name: "unicode domain", email: "user@bücher.example", want: "user@xn--bcher-kva.example"},
+		{name: "unicode domain uppercase", email: "user@MÜNCHEN.de", want: "user@xn--mnchen-3ya.de"},
+		{name: "decomposed unicode is composed", email: "user@bu\u0308cher.example", want: "user@xn--bcher-kva.example"},
+		{name: "non-latin domain", email: "user@例え.jp", want: "user@xn--r8jz45g.jp"},
+		{name: "trailing dot", email: "user@example.com.", want: "user@example.com"},
+		{name: "missing at", email: "user.example.com", wantErr: domain.ErrInvalidEmail},
+		{name: "empty local part", email: "@example.com", wantErr: domain.ErrInvalidEmail},
+		{name: "empty label", email: "user@example..com", wantErr: domain.ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToASCII(tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ToASCII() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToASCII() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Normalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		canonical bool
+		email     string
+		want      string
+		wantErr   error
+	}{
+		{name: "gmail untouched by default", email: "John.Doe+news@Gmail.com", want: "john.doe+news@gmail.com"},
+		{name: "gmail dots and plus", canonical: true, email: "John.Doe+news@Gmail.com", want: "johndoe@gmail.com"},
+		{name: "googlemail folds into gmail", canonical: true, email: "j.doe@googlemail.com", want: "jdoe@gmail.com"},
+		{name: "other domains keep dots and plus", canonical: true, email: "j.doe+x@example.com", want: "j.doe+x@example.com"},
+		{name: "gmail local part only suffix", canonical: true, email: "+tag@gmail.com", wantErr: domain.ErrInvalidEmail},
+		{name: "invalid address", canonical: true, email: "not-an-email", wantErr: domain.ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := Normalizer{CanonicalizeGmail: tt.canonical}
+			got, err := n.Normalize(tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Normalize() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzNormalize(f *testing.F) {
+	for _, seed := range []string{
+		"User@Example.com",
+		"  jane.doe+news@GoogleMail.com ",
+		"user@bücher.example",
+		"user@bücher.example",
+		"@example.com",
+		"user@",
+		"user@a..b",
+		"user@xn--bcher-kva.example.",
+		"a@b@c",
+	} {
+		f.Add(seed)
+	}
+
+	normalizers := []Normalizer{{}, {CanonicalizeGmail: true}}
+	f.Fuzz(func(t *testing.T, email string) {
+		for _, n := range normalizers {
+			got, err := n.Normalize(email)
+			if err != nil {
+				if !errors.Is(err, domain.ErrInvalidEmail) {
+					t.Fatalf("Normalize(%q) error = %v, want ErrInvalidEmail", email, err)
+				}
+				continue
+			}
+
+			// Normalized values are stored and compared, so normalizing
+			// again must not change them
+			again, err := n.Normalize(got)
+			if err != nil {
+				t.Fatalf("Normalize(%q) = %q, which fails to normalize: %v", email, got, err)
+			}
+			if again != got {
+				t.Fatalf("Normalize is not idempotent: %q -> %q -> %q", email, got, again)
+			}
+
+			at := strings.LastIndex(got, "@")
+			if at <= 0 || at == len(got)-1 {
+				t.Fatalf("Normalize(%q) = %q, want local@domain", email, got)
+			}
+			for _, r := range got[at+1:] {
+				if r > unicode.MaxASCII {
+					t.Fatalf("Normalize(%q) = %q, domain is not ASCII", email, got)
+				}
+			}
+		}
+	})
+}
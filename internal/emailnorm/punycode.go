@@ -0,0 +1,131 @@
+package emailnorm
+
+import (
+	"errors"
+	"strings"
+)
+
+// Punycode parameters from RFC 3492 section 5
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+	acePrefix       = "xn--"
+)
+
+var errPunycodeOverflow = errors.New("punycode: overflow")
+
+// toASCIILabel converts a single domain label to its ACE form, leaving
+// ASCII-only labels untouched
+func toASCIILabel(label string) (string, error) {
+	for i := 0; i < len(label); i++ {
+		if label[i] >= 0x80 {
+			encoded, err := encodePunycode(label)
+			if err != nil {
+				return "", err
+			}
+			return acePrefix + encoded, nil
+		}
+	}
+	return label, nil
+}
+
+// encodePunycode implements the encoding procedure of RFC 3492 section 6.3
+func encodePunycode(s string) (string, error) {
+	input := []rune(s)
+	var out strings.Builder
+
+	basic := 0
+	for _, r := range input {
+		if r < 0x80 {
+			out.WriteRune(r)
+			basic++
+		}
+	}
+	if basic > 0 {
+		out.WriteByte('-')
+	}
+
+	n := rune(punyInitialN)
+	delta := 0
+	bias := punyInitialBias
+	for h := basic; h < len(input); {
+		m := rune(0x7fffffff)
+		for _, r := range input {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+
+		step := int(m-n) * (h + 1)
+		if step/(h+1) != int(m-n) || delta+step < delta {
+			return "", errPunycodeOverflow
+		}
+		delta += step
+		n = m
+
+		for _, r := range input {
+			if r < n {
+				delta++
+				if delta == 0 {
+					return "", errPunycodeOverflow
+				}
+			}
+			if r != n {
+				continue
+			}
+
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := k - bias
+				switch {
+				case t < punyTMin:
+					t = punyTMin
+				case t > punyTMax:
+					t = punyTMax
+				}
+				if q < t {
+					break
+				}
+				out.WriteByte(punyDigit(t + (q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			out.WriteByte(punyDigit(q))
+
+			bias = punyAdapt(delta, h+1, h == basic)
+			delta = 0
+			h++
+		}
+		delta++
+		n++
+	}
+
+	return out.String(), nil
+}
+
+func punyAdapt(delta, numPoints int, firstTime bool) int {
+	if firstTime {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
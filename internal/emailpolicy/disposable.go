@@ -0,0 +1,142 @@
+package emailpolicy
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+//go:embed disposable_domains.txt
+var embeddedDisposableDomains string
+
+// maxDatasetSize bounds the size of a downloaded disposable domain dataset
+const maxDatasetSize = 10 << 20
+
+// DisposableList is a set of disposable email domains that can be refreshed
+// at runtime
+type DisposableList struct {
+	mu      sync.RWMutex
+	domains map[string]struct{}
+}
+
+// NewDisposableList creates a list seeded with the embedded dataset
+func NewDisposableList() *DisposableList {
+	domains, _ := ParseDomainList(strings.NewReader(embeddedDisposableDomains))
+	l := &DisposableList{}
+	l.Replace(domains)
+	return l
+}
+
+// ParseDomainList reads one domain per line, ignoring blank lines and #
+// comments
+func ParseDomainList(r io.Reader) ([]string, error) {
+	var domains []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, normalizeDomain(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain list: %w", err)
+	}
+
+	return domains, nil
+}
+
+// Replace swaps the current dataset for the given domains
+func (l *DisposableList) Replace(domains []string) {
+	set := make(map[string]struct{}, len(domains))
+	for _, d := range domains {
+		if d = normalizeDomain(d); d != "" {
+			set[d] = struct{}{}
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.domains = set
+}
+
+// Len returns the number of domains in the dataset
+func (l *DisposableList) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.domains)
+}
+
+// Contains reports whether the domain, or any parent domain, is disposable
+func (l *DisposableList) Contains(domain string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return matchDomain(l.domains, normalizeDomain(domain))
+}
+
+// Refresh downloads the dataset from url and replaces the current one. An
+// empty download is rejected so a broken mirror cannot disable the check.
+func (l *DisposableList) Refresh(ctx context.Context, client *httpclient.Client, url string) error {
+	resp, err := client.Get(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to download disposable domains: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download disposable domains: unexpected status %d", resp.StatusCode)
+	}
+
+	domains, err := ParseDomainList(io.LimitReader(resp.Body, maxDatasetSize))
+	if err != nil {
+		return err
+	}
+	if len(domains) == 0 {
+		return fmt.Errorf("disposable domain dataset from %s is empty", url)
+	}
+
+	l.Replace(domains)
+	return nil
+}
+
+// StartRefresher refreshes the dataset immediately and then on every
+// interval until ctx is cancelled. Failures keep the previous dataset.
+func (l *DisposableList) StartRefresher(ctx context.Context, client *httpclient.Client, url string, interval time.Duration, logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	refresh := func() {
+		if err := l.Refresh(ctx, client, url); err != nil {
+			logger.Error("failed to refresh disposable email domains", "url", url, "error", err)
+			return
+		}
+		logger.Info("refreshed disposable email domains", "count", l.Len())
+	}
+
+	go func() {
+		refresh()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
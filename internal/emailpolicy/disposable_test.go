@@ -0,0 +1,60 @@
+package emailpolicy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+func TestNewDisposableList_Embedded(t *testing.T) {
+	l := NewDisposableList()
+
+	if l.Len() == 0 {
+		t.Fatal("expected embedded dataset to be loaded")
+	}
+	if !l.Contains("Mailinator.com.") {
+		t.Error("expected mailinator.com to be disposable")
+	}
+	if l.Contains("example.com") {
+		t.Error("example.com should not be disposable")
+	}
+}
+
+func TestDisposableList_Refresh(t *testing.T) {
+	body := "# remote list\nnew-disposable.test\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	config := httpclient.DefaultConfig()
+	config.MaxRetries = 0
+	client, err := httpclient.New(config, nil)
+	if err != nil {
+		t.Fatalf("httpclient.New() error = %v", err)
+	}
+
+	l := NewDisposableList()
+	if err := l.Refresh(context.Background(), client, server.URL); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if !l.Contains("new-disposable.test") {
+		t.Error("expected refreshed domain to be disposable")
+	}
+	if l.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", l.Len())
+	}
+
+	// An empty dataset must not replace the current one
+	body = "# nothing here\n"
+	if err := l.Refresh(context.Background(), client, server.URL); err == nil {
+		t.Error("Refresh() should reject an empty dataset")
+	}
+	if !l.Contains("new-disposable.test") {
+		t.Error("failed refresh should keep the previous dataset")
+	}
+}
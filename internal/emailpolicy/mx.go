@@ -0,0 +1,154 @@
+package emailpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// Resolver is the subset of *net.Resolver used for deliverability checks
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// maxMXCacheEntries bounds the number of cached domains, which signups
+// choose freely
+const maxMXCacheEntries = 10000
+
+// mxCacheEntry caches the deliverability of a domain
+type mxCacheEntry struct {
+	deliverable bool
+	expiresAt   time.Time
+}
+
+// MXChecker rejects email domains that cannot receive mail. Only definitive
+// DNS answers count as undeliverable; timeouts and server failures are
+// treated as deliverable so a DNS outage never blocks signups.
+type MXChecker struct {
+	resolver   Resolver
+	timeout    time.Duration
+	cacheTTL   time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu        sync.Mutex
+	cache     map[string]mxCacheEntry
+	lastSweep time.Time
+}
+
+// NewMXChecker creates a new MX checker. A nil resolver uses net.DefaultResolver.
+func NewMXChecker(resolver Resolver, timeout, cacheTTL time.Duration) *MXChecker {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	return &MXChecker{
+		resolver:   resolver,
+		timeout:    timeout,
+		cacheTTL:   cacheTTL,
+		maxEntries: maxMXCacheEntries,
+		now:        time.Now,
+		cache:      make(map[string]mxCacheEntry),
+	}
+}
+
+// Check returns domain.ErrUndeliverableEmail when the email's domain has no
+// mail host
+func (c *MXChecker) Check(ctx context.Context, email string) error {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return domain.ErrInvalidEmail
+	}
+
+	if !c.deliverable(ctx, normalizeDomain(email[at+1:])) {
+		return domain.ErrUndeliverableEmail
+	}
+	return nil
+}
+
+// deliverable reports whether the domain can receive mail, using the cache
+// when possible
+func (c *MXChecker) deliverable(ctx context.Context, emailDomain string) bool {
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.cache[emailDomain]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.deliverable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	deliverable, definitive := c.lookup(ctx, emailDomain)
+	if definitive && c.cacheTTL > 0 {
+		c.mu.Lock()
+		c.sweep(now)
+		// When full, new domains are looked up every time until the next
+		// sweep frees room
+		if _, cached := c.cache[emailDomain]; cached || len(c.cache) < c.maxEntries {
+			c.cache[emailDomain] = mxCacheEntry{deliverable: deliverable, expiresAt: now.Add(c.cacheTTL)}
+		}
+		c.mu.Unlock()
+	}
+
+	return deliverable
+}
+
+// sweep forgets expired domains, at most once per TTL; callers must hold
+// c.mu
+func (c *MXChecker) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.cacheTTL {
+		return
+	}
+	for emailDomain, entry := range c.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(c.cache, emailDomain)
+		}
+	}
+	c.lastSweep = now
+}
+
+// lookup resolves the domain's mail hosts. The second result is false when
+// the answer is inconclusive and must not be cached.
+func (c *MXChecker) lookup(ctx context.Context, emailDomain string) (deliverable, definitive bool) {
+	records, err := c.resolver.LookupMX(ctx, emailDomain)
+	if err == nil && len(records) > 0 {
+		// A single "." record is a null MX (RFC 7505): the domain accepts no mail
+		if len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+			return false, true
+		}
+		return true, true
+	}
+	if err != nil && !isNotFound(err) {
+		return true, false
+	}
+
+	// Without MX records, mail is delivered to the domain's address records
+	// (RFC 5321 section 5.1)
+	hosts, err := c.resolver.LookupHost(ctx, emailDomain)
+	if err == nil && len(hosts) > 0 {
+		return true, true
+	}
+	if err != nil && !isNotFound(err) {
+		return true, false
+	}
+
+	return false, true
+}
+
+// isNotFound reports whether a DNS error is a definitive "no such host/record"
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
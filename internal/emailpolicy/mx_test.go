@@ -0,0 +1,124 @@
+package emailpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+type fakeResolver struct {
+	mx      map[string][]*net.MX
+	hosts   map[string][]string
+	fail    bool
+	lookups int
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	r.lookups++
+	if r.fail {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestMXChecker_Check(t *testing.T) {
+	resolver := &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com": {{Host: "mx.example.com.", Pref: 10}},
+			"nullmx.com":  {{Host: ".", Pref: 0}},
+		},
+		hosts: map[string][]string{
+			"a-only.com": {"192.0.2.1"},
+		},
+	}
+	checker := NewMXChecker(resolver, time.Second, time.Hour)
+
+	tests := []struct {
+		email   string
+		wantErr error
+	}{
+		{"user@example.com", nil},
+		{"user@EXAMPLE.com", nil},
+		{"user@a-only.com", nil},
+		{"user@nullmx.com", domain.ErrUndeliverableEmail},
+		{"user@nowhere.invalid", domain.ErrUndeliverableEmail},
+		{"no-at-sign", domain.ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			if err := checker.Check(context.Background(), tt.email); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check(%q) error = %v, want %v", tt.email, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMXChecker_Cache(t *testing.T) {
+	resolver := &fakeResolver{mx: map[string][]*net.MX{"example.com": {{Host: "mx.example.com."}}}}
+	checker := NewMXChecker(resolver, time.Second, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if err := checker.Check(context.Background(), "user@example.com"); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+	}
+	if resolver.lookups != 1 {
+		t.Errorf("lookups = %d, want 1", resolver.lookups)
+	}
+}
+
+func TestMXChecker_CacheBounded(t *testing.T) {
+	resolver := &fakeResolver{mx: map[string][]*net.MX{
+		"a.com": {{Host: "mx.a.com."}},
+		"b.com": {{Host: "mx.b.com."}},
+		"c.com": {{Host: "mx.c.com."}},
+	}}
+	checker := NewMXChecker(resolver, time.Second, time.Hour)
+	checker.maxEntries = 2
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+
+	for _, email := range []string{"user@a.com", "user@b.com", "user@c.com"} {
+		checker.Check(context.Background(), email)
+	}
+	if len(checker.cache) != 2 {
+		t.Errorf("cached %d domains, want at most 2", len(checker.cache))
+	}
+
+	// Expired domains are swept, making room for new ones
+	now = now.Add(2 * time.Hour)
+	checker.Check(context.Background(), "user@c.com")
+	if _, ok := checker.cache["c.com"]; !ok || len(checker.cache) != 1 {
+		t.Errorf("after sweep cached %v, want only c.com", checker.cache)
+	}
+}
+
+func TestMXChecker_FailsOpen(t *testing.T) {
+	resolver := &fakeResolver{fail: true}
+	checker := NewMXChecker(resolver, time.Second, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if err := checker.Check(context.Background(), "user@example.com"); err != nil {
+			t.Errorf("Check() should fail open on DNS errors, got %v", err)
+		}
+	}
+	// Inconclusive answers are not cached
+	if resolver.lookups != 2 {
+		t.Errorf("lookups = %d, want 2", resolver.lookups)
+	}
+}
@@ -0,0 +1,75 @@
+package emailpolicy
+
+import (
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// Policy decides which email domains may be used to sign up
+type Policy struct {
+	allowed    map[string]struct{}
+	denied     map[string]struct{}
+	disposable *DisposableList
+}
+
+// NewPolicy creates a signup email policy. When allowed is non-empty only
+// those domains (and their subdomains) may sign up. A nil disposable list
+// disables disposable email blocking.
+func NewPolicy(allowed, denied []string, disposable *DisposableList) *Policy {
+	return &Policy{
+		allowed:    toSet(allowed),
+		denied:     toSet(denied),
+		disposable: disposable,
+	}
+}
+
+// Check returns an error when the email's domain is not permitted
+func (p *Policy) Check(email string) error {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return domain.ErrInvalidEmail
+	}
+	emailDomain := normalizeDomain(email[at+1:])
+
+	if len(p.allowed) > 0 && !matchDomain(p.allowed, emailDomain) {
+		return domain.ErrEmailDomainNotAllowed
+	}
+	if matchDomain(p.denied, emailDomain) {
+		return domain.ErrEmailDomainNotAllowed
+	}
+	if p.disposable != nil && p.disposable.Contains(emailDomain) {
+		return domain.ErrDisposableEmail
+	}
+
+	return nil
+}
+
+// matchDomain reports whether the domain or one of its parents is in set
+func matchDomain(set map[string]struct{}, d string) bool {
+	for d != "" {
+		if _, ok := set[d]; ok {
+			return true
+		}
+		dot := strings.IndexByte(d, '.')
+		if dot < 0 {
+			return false
+		}
+		d = d[dot+1:]
+	}
+	return false
+}
+
+func normalizeDomain(d string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+}
+
+func toSet(domains []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(domains))
+	for _, d := range domains {
+		if d = normalizeDomain(d); d != "" {
+			set[d] = struct{}{}
+		}
+	}
+	return set
+}
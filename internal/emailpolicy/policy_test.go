@@ -0,0 +1,83 @@
+package emailpolicy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *Policy
+		email   string
+		wantErr error
+	}{
+		{
+			name:   "no rules",
+			policy: NewPolicy(nil, nil, nil),
+			email:  "user@example.com",
+		},
+		{
+			name:   "allowed domain",
+			policy: NewPolicy([]string{"corp.com"}, nil, nil),
+			email:  "user@CORP.com",
+		},
+		{
+			name:   "allowed subdomain",
+			policy: NewPolicy([]string{"corp.com"}, nil, nil),
+			email:  "user@eu.corp.com",
+		},
+		{
+			name:    "not in allowlist",
+			policy:  NewPolicy([]string{"corp.com"}, nil, nil),
+			email:   "user@othercorp.com",
+			wantErr: domain.ErrEmailDomainNotAllowed,
+		},
+		{
+			name:    "denied domain",
+			policy:  NewPolicy(nil, []string{"competitor.com"}, nil),
+			email:   "user@competitor.com",
+			wantErr: domain.ErrEmailDomainNotAllowed,
+		},
+		{
+			name:    "denylist wins over allowlist",
+			policy:  NewPolicy([]string{"corp.com"}, []string{"contractors.corp.com"}, nil),
+			email:   "user@contractors.corp.com",
+			wantErr: domain.ErrEmailDomainNotAllowed,
+		},
+		{
+			name:    "disposable domain",
+			policy:  NewPolicy(nil, nil, NewDisposableList()),
+			email:   "user@mailinator.com",
+			wantErr: domain.ErrDisposableEmail,
+		},
+		{
+			name:    "disposable subdomain",
+			policy:  NewPolicy(nil, nil, NewDisposableList()),
+			email:   "user@inbox.yopmail.com",
+			wantErr: domain.ErrDisposableEmail,
+		},
+		{
+			name:   "disposable blocking disabled",
+			policy: NewPolicy(nil, nil, nil),
+			email:  "user@mailinator.com",
+		},
+		{
+			name:    "missing at sign",
+			policy:  NewPolicy(nil, nil, nil),
+			email:   "not-an-email",
+			wantErr: domain.ErrInvalidEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check(%q) error = %v, want %v", tt.email, err, tt.wantErr)
+			}
+		})
+	}
+}
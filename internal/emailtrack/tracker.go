@@ -0,0 +1,145 @@
+// Package emailtrack measures how emails convert. Links in tracked emails go
+// through a signed redirect and, when enabled, a pixel reports opens. Links
+// and pixels name the email but never the user, so the events cannot be tied
+// back to a recipient.
+package emailtrack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// Paths of the tracking endpoints
+const (
+	ClickPath = "/api/v1/email/click"
+	OpenPath  = "/api/v1/email/open"
+)
+
+// Tracking events, in funnel order
+const (
+	EventSent     = "sent"
+	EventOpened   = "opened"
+	EventClicked  = "clicked"
+	EventVerified = "verified"
+)
+
+// ErrInvalidLink is returned for tracking links that are malformed or whose
+// signature does not match, so the redirect cannot be abused to send users
+// to arbitrary sites
+var ErrInvalidLink = domain.ErrInvalidTrackingLink
+
+// Recorder counts tracking events
+type Recorder interface {
+	RecordEmailTrackingEvent(email, event string)
+}
+
+// Tracker builds and checks tracking links
+type Tracker struct {
+	secret   []byte
+	baseURL  string
+	pixel    bool
+	recorder Recorder
+}
+
+// New creates a tracker whose links point at baseURL and are signed with
+// secret. The open pixel is off until SetPixel turns it on.
+func New(secret, baseURL string) *Tracker {
+	return &Tracker{
+		secret:  []byte(secret),
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// SetPixel adds an open tracking pixel to HTML emails
+func (t *Tracker) SetPixel(enabled bool) {
+	t.pixel = enabled
+}
+
+// PixelEnabled reports whether emails carry an open tracking pixel
+func (t *Tracker) PixelEnabled() bool {
+	return t.pixel
+}
+
+// SetRecorder counts tracking events with recorder
+func (t *Tracker) SetRecorder(recorder Recorder) {
+	t.recorder = recorder
+}
+
+// Funnel returns the name an email template is counted under. Both
+// verification emails count as "verification", so their clicks line up with
+// verifications.
+func Funnel(template string) string {
+	if template == "verification_code" {
+		return "verification"
+	}
+	return template
+}
+
+// Record counts an event of an email template
+func (t *Tracker) Record(template, event string) {
+	if t.recorder != nil {
+		t.recorder.RecordEmailTrackingEvent(Funnel(template), event)
+	}
+}
+
+// Link returns a link that counts a click on the email and redirects to
+// target
+func (t *Tracker) Link(template, target string) string {
+	query := url.Values{
+		"e": {template},
+		"u": {target},
+		"s": {t.sign("click", template, target)},
+	}
+	return t.baseURL + ClickPath + "?" + query.Encode()
+}
+
+// Pixel returns the URL of the open tracking pixel of the email, or "" when
+// the pixel is off
+func (t *Tracker) Pixel(template string) string {
+	if !t.pixel {
+		return ""
+	}
+	query := url.Values{
+		"e": {template},
+		"s": {t.sign("open", template)},
+	}
+	return t.baseURL + OpenPath + "?" + query.Encode()
+}
+
+// Click checks a tracking link's query, counts the click and returns the
+// target to redirect to
+func (t *Tracker) Click(query url.Values) (string, error) {
+	template, target := query.Get("e"), query.Get("u")
+	if template == "" || target == "" || !security.ConstantTimeCompare(query.Get("s"), t.sign("click", template, target)) {
+		return "", ErrInvalidLink
+	}
+	t.Record(template, EventClicked)
+	return target, nil
+}
+
+// Open checks a pixel's query and counts the open
+func (t *Tracker) Open(query url.Values) error {
+	template := query.Get("e")
+	if !t.pixel || template == "" || !security.ConstantTimeCompare(query.Get("s"), t.sign("open", template)) {
+		return ErrInvalidLink
+	}
+	t.Record(template, EventOpened)
+	return nil
+}
+
+// sign returns the base64url HMAC-SHA256 of the link kind and fields
+func (t *Tracker) sign(kind string, fields ...string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(kind))
+	for _, field := range fields {
+		mac.Write([]byte{0})
+		mac.Write([]byte(field))
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,96 @@
+package emailtrack
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// countingRecorder counts events by email and event
+type countingRecorder struct {
+	events map[string]int
+}
+
+func (r *countingRecorder) RecordEmailTrackingEvent(email, event string) {
+	r.events[email+"/"+event]++
+}
+
+func TestTracker_Click(t *testing.T) {
+	recorder := &countingRecorder{events: make(map[string]int)}
+	tracker := New("0123456789abcdef0123456789abcdef", "https://auth.example.com/")
+	tracker.SetRecorder(recorder)
+
+	target := "https://app.example.com/verify-email?token=abc&email=user@example.com"
+	link := tracker.Link("verification_code", target)
+	if !strings.HasPrefix(link, "https://auth.example.com"+ClickPath+"?") {
+		t.Fatalf("Link() = %q", link)
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Link() is not a URL: %v", err)
+	}
+	got, err := tracker.Click(u.Query())
+	if err != nil {
+		t.Fatalf("Click() error = %v", err)
+	}
+	if got != target {
+		t.Errorf("Click() = %q, want %q", got, target)
+	}
+	if recorder.events["verification/clicked"] != 1 {
+		t.Errorf("events = %v, want one verification click", recorder.events)
+	}
+
+	tampered := u.Query()
+	tampered.Set("u", "https://evil.example.com/")
+	other := New("another-secret-another-secret-xx", "https://auth.example.com")
+	otherLink, _ := url.Parse(other.Link("verification", target))
+
+	for name, query := range map[string]url.Values{
+		"tampered target": tampered,
+		"other secret":    otherLink.Query(),
+		"no signature":    {"e": {"verification"}, "u": {target}},
+		"empty":           {},
+	} {
+		if _, err := tracker.Click(query); !errors.Is(err, ErrInvalidLink) {
+			t.Errorf("Click(%s) error = %v, want %v", name, err, ErrInvalidLink)
+		}
+	}
+	if len(recorder.events) != 1 {
+		t.Errorf("rejected clicks were counted: %v", recorder.events)
+	}
+}
+
+func TestTracker_Pixel(t *testing.T) {
+	recorder := &countingRecorder{events: make(map[string]int)}
+	tracker := New("0123456789abcdef0123456789abcdef", "https://auth.example.com")
+	tracker.SetRecorder(recorder)
+
+	if pixel := tracker.Pixel("verification"); pixel != "" {
+		t.Errorf("Pixel() with the pixel off = %q, want none", pixel)
+	}
+
+	tracker.SetPixel(true)
+	pixel, err := url.Parse(tracker.Pixel("login_notification"))
+	if err != nil || pixel.Path != OpenPath {
+		t.Fatalf("Pixel() = %v, %v", pixel, err)
+	}
+	if err := tracker.Open(pixel.Query()); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if recorder.events["login_notification/opened"] != 1 {
+		t.Errorf("events = %v, want one login notification open", recorder.events)
+	}
+
+	// A click signature does not open, nor the other way around
+	link, _ := url.Parse(tracker.Link("login_notification", "https://app.example.com/"))
+	if err := tracker.Open(link.Query()); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("Open(click link) error = %v, want %v", err, ErrInvalidLink)
+	}
+
+	tracker.SetPixel(false)
+	if err := tracker.Open(pixel.Query()); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("Open() with the pixel off error = %v, want %v", err, ErrInvalidLink)
+	}
+}
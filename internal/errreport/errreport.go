@@ -0,0 +1,170 @@
+// Package errreport sends panics and server errors to an error tracker such
+// as Sentry
+package errreport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Level is the severity of an event
+type Level string
+
+const (
+	LevelFatal Level = "fatal" // recovered panics
+	LevelError Level = "error" // 5xx responses
+)
+
+// Event is a panic or server error with the request it happened in. Events
+// are scrubbed before they reach a Reporter.
+type Event struct {
+	ID        string // 32 hex characters, as Sentry expects
+	Timestamp time.Time
+	Level     Level
+	Message   string
+	Stack     string // panics only
+
+	Status    int
+	Method    string
+	Path      string
+	Query     string
+	Headers   map[string]string
+	RequestID string
+	UserID    string // set when the request was authenticated
+}
+
+// Reporter delivers events to an error tracker. Implement it for trackers
+// that do not speak the Sentry protocol.
+type Reporter interface {
+	Report(ctx context.Context, event *Event) error
+}
+
+// Config holds configuration for the client
+type Config struct {
+	SampleRate    float64       // share of 5xx events sent; panics are always sent
+	QueueSize     int           // events waiting for the reporter; more are dropped
+	ReportTimeout time.Duration // per Report call
+}
+
+// DefaultConfig returns default configuration
+func DefaultConfig() Config {
+	return Config{
+		SampleRate:    1,
+		QueueSize:     100,
+		ReportTimeout: 5 * time.Second,
+	}
+}
+
+// Client samples and scrubs events and hands them to a reporter in the
+// background, so reporting never delays a response. When the reporter falls
+// behind, events are dropped rather than queued without bound.
+type Client struct {
+	reporter Reporter
+	config   Config
+	logger   *slog.Logger
+	queue    chan *Event
+	sample   func() float64
+
+	// mu guards closed so Capture never sends on a closed queue
+	mu     sync.RWMutex
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// NewClient creates a client for reporter
+func NewClient(reporter Reporter, config Config, logger *slog.Logger) *Client {
+	return &Client{
+		reporter: reporter,
+		config:   config,
+		logger:   logger,
+		queue:    make(chan *Event, config.QueueSize),
+		sample:   mathrand.Float64,
+	}
+}
+
+// Start starts the worker delivering events
+func (c *Client) Start() {
+	c.wg.Add(1)
+	go c.run()
+}
+
+// Stop stops accepting events and waits up to timeout for queued ones to
+// be delivered
+func (c *Client) Stop(timeout time.Duration) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.queue)
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("timeout waiting for error reports to be sent")
+	}
+}
+
+// Capture scrubs and queues an event. Error events are sampled; panics are
+// always queued. It reports whether the event was queued.
+func (c *Client) Capture(event *Event) bool {
+	if event.Level != LevelFatal && c.sample() >= c.config.SampleRate {
+		return false
+	}
+
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	Scrub(event)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return false
+	}
+
+	select {
+	case c.queue <- event:
+		return true
+	default:
+		c.logger.Warn("error report queue full, dropping event", "event_id", event.ID, "request_id", event.RequestID)
+		return false
+	}
+}
+
+func (c *Client) run() {
+	defer c.wg.Done()
+	for event := range c.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), c.config.ReportTimeout)
+		if err := c.reporter.Report(ctx, event); err != nil {
+			c.logger.Warn("failed to report error", "event_id", event.ID, "request_id", event.RequestID, "error", err)
+		}
+		cancel()
+	}
+}
+
+// newEventID returns a random 32 hex character event ID
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,160 @@
+package errreport
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// recordingReporter keeps reported events
+type recordingReporter struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (r *recordingReporter) Report(ctx context.Context, event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestClient_CaptureSamplesErrorsOnly(t *testing.T) {
+	reporter := &recordingReporter{}
+	config := DefaultConfig()
+	config.SampleRate = 0.5
+	client := NewClient(reporter, config, slog.Default())
+	client.sample = func() float64 { return 0.7 }
+	client.Start()
+
+	if client.Capture(&Event{Level: LevelError, Message: "internal error"}) {
+		t.Error("error event above the sample rate was queued")
+	}
+	if !client.Capture(&Event{Level: LevelFatal, Message: "panic"}) {
+		t.Error("panic was not queued")
+	}
+
+	if err := client.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if len(reporter.events) != 1 || reporter.events[0].Level != LevelFatal {
+		t.Fatalf("reported %d events, want the panic only", len(reporter.events))
+	}
+	if len(reporter.events[0].ID) != 32 || reporter.events[0].Timestamp.IsZero() {
+		t.Errorf("event ID %q or timestamp not set", reporter.events[0].ID)
+	}
+	if client.Capture(&Event{Level: LevelFatal}) {
+		t.Error("Capture() after Stop() queued an event")
+	}
+}
+
+func TestScrub(t *testing.T) {
+	event := &Event{
+		Message: "panic: user jane@example.com sent Bearer abc.def token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln",
+		Query:   "refresh_token=secret-value&page=2&poll_token=xyz",
+	}
+	Scrub(event)
+
+	for _, leaked := range []string{"jane@example.com", "abc.def", "eyJhbGciOiJIUzI1NiJ9", "secret-value", "xyz"} {
+		if strings.Contains(event.Message+event.Query, leaked) {
+			t.Errorf("scrubbed event still contains %q: %q %q", leaked, event.Message, event.Query)
+		}
+	}
+	if !strings.Contains(event.Query, "page=2") {
+		t.Errorf("harmless parameter scrubbed: %q", event.Query)
+	}
+
+	headers := ReportedHeaders(http.Header{
+		"Authorization": {"Bearer secret"},
+		"Cookie":        {"session=secret"},
+		"User-Agent":    {"curl/8.0"},
+	}.Get)
+	if len(headers) != 1 || headers["User-Agent"] != "curl/8.0" {
+		t.Errorf("ReportedHeaders() = %v, want User-Agent only", headers)
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn       string
+		wantURL   string
+		wantKey   string
+		wantError bool
+	}{
+		{dsn: "https://abc123@o1.ingest.sentry.io/42", wantURL: "https://o1.ingest.sentry.io/api/42/store/", wantKey: "abc123"},
+		{dsn: "https://abc123@errors.example.com/glitchtip/7", wantURL: "https://errors.example.com/glitchtip/api/7/store/", wantKey: "abc123"},
+		{dsn: "https://o1.ingest.sentry.io/42", wantError: true},
+		{dsn: "https://abc123@o1.ingest.sentry.io/", wantError: true},
+		{dsn: "ftp://abc123@example.com/1", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			storeURL, key, err := parseDSN(tt.dsn)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("parseDSN() error = %v, wantError %v", err, tt.wantError)
+			}
+			if storeURL != tt.wantURL || key != tt.wantKey {
+				t.Errorf("parseDSN() = %q, %q, want %q, %q", storeURL, key, tt.wantURL, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestSentryReporter_Report(t *testing.T) {
+	var got sentryEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/store/" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("X-Sentry-Auth"); !strings.Contains(auth, "sentry_key=abc123") {
+			t.Errorf("unexpected X-Sentry-Auth %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode event: %v", err)
+		}
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	client, err := httpclient.New(httpclient.DefaultConfig(), slog.Default())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	dsn := strings.Replace(server.URL, "://", "://abc123@", 1) + "/42"
+	reporter, err := NewSentryReporter(client, dsn, "production", "v1.2.0")
+	if err != nil {
+		t.Fatalf("NewSentryReporter() error = %v", err)
+	}
+
+	err = reporter.Report(context.Background(), &Event{
+		ID:        "0123456789abcdef0123456789abcdef",
+		Timestamp: time.Now(),
+		Level:     LevelFatal,
+		Message:   "panic: boom",
+		Stack:     "goroutine 1 [running]:",
+		Status:    http.StatusInternalServerError,
+		Method:    http.MethodGet,
+		Path:      "/api/v1/auth/me",
+		RequestID: "req-1",
+		UserID:    "user-123",
+	})
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	if got.Level != LevelFatal || got.Message != "panic: boom" || got.Release != "v1.2.0" || got.Environment != "production" {
+		t.Errorf("unexpected event %+v", got)
+	}
+	if got.User == nil || got.User.ID != "user-123" || got.Tags["request_id"] != "req-1" || got.Extra["stack"] == "" {
+		t.Errorf("request context missing from event %+v", got)
+	}
+}
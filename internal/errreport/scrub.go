@@ -0,0 +1,82 @@
+package errreport
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// filtered replaces scrubbed values, matching Sentry's own scrubbing
+const filtered = "[Filtered]"
+
+// reportedHeaders are the only request headers sent with an event;
+// credentials such as Authorization and Cookie never leave the service
+var reportedHeaders = []string{"Accept", "Content-Type", "User-Agent", "X-Request-ID"}
+
+// sensitiveParams are query parameter name fragments whose values are scrubbed
+var sensitiveParams = []string{"token", "code", "password", "secret", "key", "signature", "otp", "dpop"}
+
+var (
+	// jwtRegex matches compact JWS and JWE tokens
+	jwtRegex = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_.-]*`)
+	// bearerRegex matches credentials following an authorization scheme
+	bearerRegex = regexp.MustCompile(`(?i)\b(bearer|dpop|basic)\s+[A-Za-z0-9._~+/=-]+`)
+	// emailRegex matches email addresses, which are personal data
+	emailRegex = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// Scrub removes credentials and personal data from an event: sensitive
+// query parameters, tokens and email addresses in the message and stack
+func Scrub(event *Event) {
+	event.Message = scrubText(event.Message)
+	event.Stack = scrubText(event.Stack)
+	event.Query = scrubQuery(event.Query)
+}
+
+// ReportedHeaders returns the request headers that may be sent with an event
+func ReportedHeaders(get func(string) string) map[string]string {
+	headers := make(map[string]string)
+	for _, name := range reportedHeaders {
+		if v := get(name); v != "" {
+			headers[name] = scrubText(v)
+		}
+	}
+	return headers
+}
+
+func scrubText(s string) string {
+	s = jwtRegex.ReplaceAllString(s, filtered)
+	s = bearerRegex.ReplaceAllString(s, "$1 "+filtered)
+	return emailRegex.ReplaceAllString(s, filtered)
+}
+
+func scrubQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		// Unparsable queries may hide anything
+		return filtered
+	}
+	for name := range values {
+		if isSensitiveParam(name) {
+			values[name] = []string{filtered}
+		} else {
+			for i, v := range values[name] {
+				values[name][i] = scrubText(v)
+			}
+		}
+	}
+	return values.Encode()
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, fragment := range sensitiveParams {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
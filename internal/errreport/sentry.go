@@ -0,0 +1,141 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// SentryReporter sends events to Sentry, or to a tracker accepting Sentry's
+// store API such as GlitchTip, using a project DSN
+type SentryReporter struct {
+	client      *httpclient.Client
+	storeURL    string
+	publicKey   string
+	environment string
+	release     string
+}
+
+// NewSentryReporter creates a reporter for a DSN of the form
+// https://<key>@<host>[/<path>]/<project>. Environment and release are
+// attached to every event when set.
+func NewSentryReporter(client *httpclient.Client, dsn, environment, release string) (*SentryReporter, error) {
+	storeURL, publicKey, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &SentryReporter{
+		client:      client,
+		storeURL:    storeURL,
+		publicKey:   publicKey,
+		environment: environment,
+		release:     release,
+	}, nil
+}
+
+// parseDSN returns the store endpoint and public key of a DSN
+func parseDSN(dsn string) (storeURL, publicKey string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User == nil || u.User.Username() == "" {
+		return "", "", fmt.Errorf("invalid error reporting DSN")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	project := path[i+1:]
+	if _, err := strconv.ParseUint(project, 10, 64); err != nil {
+		return "", "", fmt.Errorf("invalid error reporting DSN: missing project ID")
+	}
+
+	return fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:i], project), u.User.Username(), nil
+}
+
+// sentryEvent is the store API layout of an event
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       Level             `json:"level"`
+	Logger      string            `json:"logger"`
+	Message     string            `json:"message"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Request     sentryRequest     `json:"request"`
+	User        *sentryUser       `json:"user,omitempty"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+type sentryRequest struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+type sentryUser struct {
+	ID string `json:"id"`
+}
+
+// Report sends the event to the store endpoint
+func (s *SentryReporter) Report(ctx context.Context, event *Event) error {
+	payload := sentryEvent{
+		EventID:     event.ID,
+		Timestamp:   event.Timestamp.UTC().Format(time.RFC3339),
+		Platform:    "go",
+		Level:       event.Level,
+		Logger:      "http",
+		Message:     event.Message,
+		Environment: s.environment,
+		Release:     s.release,
+		Request: sentryRequest{
+			Method:      event.Method,
+			URL:         event.Path,
+			QueryString: event.Query,
+			Headers:     event.Headers,
+		},
+		Tags: map[string]string{
+			"status":     strconv.Itoa(event.Status),
+			"request_id": event.RequestID,
+		},
+	}
+	if event.UserID != "" {
+		payload.User = &sentryUser{ID: event.UserID}
+	}
+	if event.Stack != "" {
+		payload.Extra = map[string]string{"stack": event.Stack}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode error report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create error report request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=go-auth-jwt/1.0, sentry_key=%s", s.publicKey))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send error report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("error tracker rejected report: unexpected status %s: %s", resp.Status, detail)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package context
+
+import "context"
+
+// CSPKey holds the *CSP of the response to a request
+const CSPKey ContextKey = "csp"
+
+// CSP is the Content-Security-Policy headers of a response. The security
+// headers middleware sets them when the response is written, so handlers can
+// replace them first.
+type CSP struct {
+	Policy     string
+	ReportOnly string
+}
+
+// SetContentSecurityPolicy replaces the enforced and report-only CSP of the
+// response to the request with ctx, for pages that need a policy of their
+// own. An empty policy leaves that header out. It reports false outside the
+// security headers middleware, where the caller sets the headers itself.
+func SetContentSecurityPolicy(ctx context.Context, policy, reportOnly string) bool {
+	csp, ok := ctx.Value(CSPKey).(*CSP)
+	if !ok {
+		return false
+	}
+	csp.Policy = policy
+	csp.ReportOnly = reportOnly
+	return true
+}
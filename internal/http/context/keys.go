@@ -8,4 +8,10 @@ const (
 	UserIDKey            ContextKey = "user_id"
 	UserEmailKey         ContextKey = "user_email"
 	UserEmailVerifiedKey ContextKey = "user_email_verified"
+	TokenVersionKey      ContextKey = "token_version"
+	TokenIDKey           ContextKey = "token_id"      // jti of the access token
+	AccessWindowKey      ContextKey = "access_window" // *token.AccessWindow the access token is limited to, when it is
 )
+
+// APIVersionKey holds the API version of the route group a request matched
+const APIVersionKey ContextKey = "api_version"
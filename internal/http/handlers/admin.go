@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// AdminHandler handles operator endpoints
+type AdminHandler struct {
+	accessWindows *service.AuthService
+	analytics     *service.AuthService
+	dormancy      *service.DormancyService
+	emails        *service.AuthServiceWithEmail
+	keys          *service.AdminKeyService
+	readOnly      ReadOnlySwitch
+	sessions      *service.AuthService
+	status        *StatusSources
+	userEvents    *service.AuthService
+	users         *service.AuthService
+}
+
+// NewAdminHandler creates a new admin handler. dormancy may be nil when the
+// dormancy policy is disabled.
+func NewAdminHandler(dormancy *service.DormancyService) *AdminHandler {
+	return &AdminHandler{dormancy: dormancy}
+}
+
+// DormancyEnabled reports whether the dormancy endpoints should be served
+func (h *AdminHandler) DormancyEnabled() bool {
+	return h.dormancy != nil
+}
+
+// DormancyPolicyResponse describes the configured dormancy policy
+type DormancyPolicyResponse struct {
+	NotifyAfter  string `json:"notify_after"`
+	DisableAfter string `json:"disable_after"`
+	PurgeAfter   string `json:"purge_after,omitempty"` // omitted when accounts are kept
+	BatchSize    int    `json:"batch_size"`
+}
+
+// DormancyActionResponse is one account handled by a dormancy run
+type DormancyActionResponse struct {
+	UserID       string    `json:"user_id"`
+	Email        string    `json:"email"`
+	LastActiveAt time.Time `json:"last_active_at"`
+}
+
+// DormancyFailureResponse is an account a dormancy run could not handle
+type DormancyFailureResponse struct {
+	UserID string `json:"user_id"`
+	Stage  string `json:"stage"`
+	Error  string `json:"error"`
+}
+
+// DormancyReportResponse is the detailed report of a dormancy run
+type DormancyReportResponse struct {
+	ID         string                    `json:"id"`
+	DryRun     bool                      `json:"dry_run"`
+	StartedAt  time.Time                 `json:"started_at"`
+	FinishedAt time.Time                 `json:"finished_at"`
+	Notified   []DormancyActionResponse  `json:"notified"`
+	Disabled   []DormancyActionResponse  `json:"disabled"`
+	Purged     []DormancyActionResponse  `json:"purged"`
+	Failures   []DormancyFailureResponse `json:"failures"`
+}
+
+// DormancyReportSummary is a dormancy run in the report list
+type DormancyReportSummary struct {
+	ID         string    `json:"id"`
+	DryRun     bool      `json:"dry_run"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Notified   int       `json:"notified"`
+	Disabled   int       `json:"disabled"`
+	Purged     int       `json:"purged"`
+	Failures   int       `json:"failures"`
+}
+
+// DormancyReportListResponse lists recent dormancy runs, newest first
+type DormancyReportListResponse struct {
+	Policy DormancyPolicyResponse  `json:"policy"`
+	Runs   []DormancyReportSummary `json:"runs"`
+}
+
+// RunDormancy runs the dormancy policy now. With ?dry_run=true nothing is
+// changed and the report lists what would have happened.
+func (h *AdminHandler) RunDormancy(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	dryRun := params.Bool("dry_run", false)
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	report, err := h.dormancy.Run(r.Context(), dryRun)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newDormancyReportResponse(report))
+}
+
+// ListDormancyRuns lists the most recent dormancy runs
+func (h *AdminHandler) ListDormancyRuns(w http.ResponseWriter, r *http.Request) {
+	policy := h.dormancy.Policy()
+	resp := DormancyReportListResponse{
+		Policy: DormancyPolicyResponse{
+			NotifyAfter:  policy.NotifyAfter.String(),
+			DisableAfter: policy.DisableAfter.String(),
+			BatchSize:    policy.BatchSize,
+		},
+		Runs: []DormancyReportSummary{},
+	}
+	if policy.PurgeAfter > 0 {
+		resp.Policy.PurgeAfter = policy.PurgeAfter.String()
+	}
+
+	for _, report := range h.dormancy.Reports() {
+		resp.Runs = append(resp.Runs, DormancyReportSummary{
+			ID:         report.ID,
+			DryRun:     report.DryRun,
+			StartedAt:  report.StartedAt,
+			FinishedAt: report.FinishedAt,
+			Notified:   len(report.Notified),
+			Disabled:   len(report.Disabled),
+			Purged:     len(report.Purged),
+			Failures:   len(report.Failures),
+		})
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetDormancyRun returns the detailed report of a dormancy run
+func (h *AdminHandler) GetDormancyRun(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.dormancy.Report(r.PathValue("id"))
+	if !ok {
+		response.WriteError(w, domain.ErrDormancyReportNotFound)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newDormancyReportResponse(report))
+}
+
+func newDormancyReportResponse(report *service.DormancyReport) DormancyReportResponse {
+	resp := DormancyReportResponse{
+		ID:         report.ID,
+		DryRun:     report.DryRun,
+		StartedAt:  report.StartedAt,
+		FinishedAt: report.FinishedAt,
+		Notified:   newDormancyActionResponses(report.Notified),
+		Disabled:   newDormancyActionResponses(report.Disabled),
+		Purged:     newDormancyActionResponses(report.Purged),
+		Failures:   make([]DormancyFailureResponse, 0, len(report.Failures)),
+	}
+	for _, failure := range report.Failures {
+		resp.Failures = append(resp.Failures, DormancyFailureResponse(failure))
+	}
+	return resp
+}
+
+func newDormancyActionResponses(actions []service.DormancyAction) []DormancyActionResponse {
+	resp := make([]DormancyActionResponse, 0, len(actions))
+	for _, action := range actions {
+		resp = append(resp, DormancyActionResponse(action))
+	}
+	return resp
+}
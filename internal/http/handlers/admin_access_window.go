@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// SetAccessWindows serves the access window endpoints
+func (h *AdminHandler) SetAccessWindows(auth *service.AuthService) {
+	h.accessWindows = auth
+}
+
+// AccessWindowsEnabled reports whether the access window endpoints should be
+// served
+func (h *AdminHandler) AccessWindowsEnabled() bool {
+	return h.accessWindows != nil && h.accessWindows.AccessWindowsEnabled()
+}
+
+// AccessWindowRequest sets a user's daily access window
+type AccessWindowRequest struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
+}
+
+// AccessWindowResponse is a user's daily access window
+type AccessWindowResponse struct {
+	UserID    string    `json:"user_id"`
+	Start     string    `json:"start"`
+	End       string    `json:"end"`
+	Timezone  string    `json:"timezone,omitempty"` // omitted when the user's profile timezone applies
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newAccessWindowResponse(userID string, window *domain.AccessWindow) AccessWindowResponse {
+	return AccessWindowResponse{
+		UserID:    userID,
+		Start:     window.Start,
+		End:       window.End,
+		Timezone:  window.Timezone,
+		UpdatedAt: window.UpdatedAt,
+	}
+}
+
+// GetAccessWindow returns a user's access window
+func (h *AdminHandler) GetAccessWindow(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	userID := params.PathUUID("id")
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	window, err := h.accessWindows.AccessWindow(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newAccessWindowResponse(userID, window))
+}
+
+// SetAccessWindow limits a user's access tokens to a daily window, such as
+// 08:00 to 18:00. Tokens issued before keep their previous window until
+// they expire.
+func (h *AdminHandler) SetAccessWindow(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	userID := params.PathUUID("id")
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	var req AccessWindowRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	window, err := h.accessWindows.SetAccessWindow(r.Context(), userID, domain.AccessWindow{
+		Start:    req.Start,
+		End:      req.End,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "user access window set", "user_id", userID, "start", window.Start, "end", window.End)
+	response.WriteJSON(w, http.StatusOK, newAccessWindowResponse(userID, window))
+}
+
+// ClearAccessWindow lifts a user's access window
+func (h *AdminHandler) ClearAccessWindow(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	userID := params.PathUUID("id")
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	if err := h.accessWindows.ClearAccessWindow(r.Context(), userID); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "user access window cleared", "user_id", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
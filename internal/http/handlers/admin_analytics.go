@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// SetAnalytics serves the session analytics endpoint
+func (h *AdminHandler) SetAnalytics(auth *service.AuthService) {
+	h.analytics = auth
+}
+
+// AnalyticsEnabled reports whether the session analytics endpoint should be
+// served
+func (h *AdminHandler) AnalyticsEnabled() bool {
+	return h.analytics != nil && h.analytics.SessionStatsEnabled()
+}
+
+// SessionAnalyticsResponse aggregates active sessions, of one user when
+// user_id is set and of all users otherwise
+type SessionAnalyticsResponse struct {
+	UserID                   string         `json:"user_id,omitempty"`
+	ActiveSessions           int            `json:"active_sessions"`
+	UsersWithSessions        int            `json:"users_with_sessions"`
+	AverageSessionAgeSeconds int64          `json:"average_session_age_seconds"`
+	Devices                  map[string]int `json:"devices"`
+}
+
+// sessionAnalyticsFields are the SessionAnalyticsResponse fields that may be
+// selected with the fields query parameter
+var sessionAnalyticsFields = []string{"user_id", "active_sessions", "users_with_sessions", "average_session_age_seconds", "devices"}
+
+// SessionAnalytics returns the number, average age and device breakdown of
+// active sessions. The optional user_id query parameter narrows them to
+// one user, and fields keeps only the listed fields.
+func (h *AdminHandler) SessionAnalytics(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	userID := params.QueryUUID("user_id")
+	fields := params.Fields("fields", sessionAnalyticsFields...)
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	stats, err := h.analytics.SessionStats(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	devices := stats.Devices
+	if devices == nil {
+		devices = map[string]int{}
+	}
+	selected, err := response.SelectFields(SessionAnalyticsResponse{
+		UserID:                   userID,
+		ActiveSessions:           stats.ActiveSessions,
+		UsersWithSessions:        stats.Users,
+		AverageSessionAgeSeconds: int64(stats.AverageAge.Seconds()),
+		Devices:                  devices,
+	}, "", fields)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, selected)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// previewRecipient is the address shown in previews
+const previewRecipient = "jane@example.com"
+
+// SetEmails serves the email template preview endpoints
+func (h *AdminHandler) SetEmails(emails *service.AuthServiceWithEmail) {
+	h.emails = emails
+}
+
+// EmailsEnabled reports whether the email template preview endpoints should be served
+func (h *AdminHandler) EmailsEnabled() bool {
+	return h.emails != nil
+}
+
+// EmailTemplateResponse describes a template operators can preview
+type EmailTemplateResponse struct {
+	Name    string   `json:"name"`
+	Locales []string `json:"locales"`
+}
+
+// EmailTemplateListResponse lists the email templates
+type EmailTemplateListResponse struct {
+	Templates []EmailTemplateResponse `json:"templates"`
+}
+
+// EmailPreviewResponse is a template rendered with sample data
+type EmailPreviewResponse struct {
+	Name    string `json:"name"`
+	Locale  string `json:"locale"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"` // omitted for plain text templates
+}
+
+// SendTestEmailRequest sends a template rendered with sample data
+type SendTestEmailRequest struct {
+	To     string `json:"to"`
+	Locale string `json:"locale"`
+}
+
+// ListEmailTemplates lists the email templates and their translations
+func (h *AdminHandler) ListEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	resp := EmailTemplateListResponse{Templates: []EmailTemplateResponse{}}
+	for _, template := range h.emails.EmailTemplates() {
+		resp.Templates = append(resp.Templates, EmailTemplateResponse{Name: template.Name, Locales: template.Locales})
+	}
+	response.WriteJSON(w, http.StatusOK, resp)
+}
+
+// PreviewEmailTemplate renders a template with sample data. The optional
+// locale query parameter picks a translation, falling back to English; the
+// response names the one used.
+func (h *AdminHandler) PreviewEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	preview, err := h.emails.PreviewEmail(name, r.URL.Query().Get("locale"), previewRecipient)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, EmailPreviewResponse{
+		Name:    name,
+		Locale:  preview.Locale,
+		To:      preview.To,
+		Subject: preview.Subject,
+		Text:    preview.Body,
+		HTML:    preview.HTMLBody,
+	})
+}
+
+// SendTestEmail queues a template rendered with sample data to the given
+// address
+func (h *AdminHandler) SendTestEmail(w http.ResponseWriter, r *http.Request) {
+	var req SendTestEmailRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	if validationErrors := request.ValidateRequiredFields(map[string]string{"to": req.To}); len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := h.emails.SendTestEmail(r.Context(), name, req.Locale, req.To); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "test email requested", "template", name, "email", req.To)
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// SetUserEvents serves the user event replay endpoint
+func (h *AdminHandler) SetUserEvents(auth *service.AuthService) {
+	h.userEvents = auth
+}
+
+// UserEventsEnabled reports whether the user event replay endpoint should be
+// served
+func (h *AdminHandler) UserEventsEnabled() bool {
+	return h.userEvents != nil && h.userEvents.UserEventsEnabled()
+}
+
+// ReplayUserEventsResponse reports a replay. Repeat the request with
+// after_id set to last_id while more is set.
+type ReplayUserEventsResponse struct {
+	Replayed int    `json:"replayed"`
+	LastID   int64  `json:"last_id"`
+	More     bool   `json:"more"`
+	Error    string `json:"error,omitempty"` // why the replay stopped early
+}
+
+// ReplayUserEvents delivers stored user events again, for downstream systems
+// that missed them. Query parameters: type (default user.email_verified),
+// from and to (RFC 3339, to exclusive), after_id and limit (default 100, at
+// most 1000). A failed delivery stops the replay with 502 Bad Gateway and the
+// progress so far.
+func (h *AdminHandler) ReplayUserEvents(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	eventType := params.Enum("type", domain.UserEventEmailVerified, service.UserEventTypes...)
+	from, to := params.TimeRange("from", "to")
+	afterID := params.Int("after_id", 0, 0, math.MaxInt)
+	limit := params.Int("limit", 100, 1, 1000)
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	output, err := h.userEvents.ReplayUserEvents(r.Context(), service.ReplayUserEventsInput{
+		Type:    eventType,
+		From:    from,
+		To:      to,
+		AfterID: int64(afterID),
+		Limit:   limit,
+	})
+	if output == nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := ReplayUserEventsResponse{Replayed: output.Replayed, LastID: output.LastID, More: output.More}
+	status := http.StatusOK
+	if err != nil {
+		slog.ErrorContext(r.Context(), "user event replay stopped", "type", eventType, "last_id", output.LastID, "error", err)
+		resp.Error = err.Error()
+		status = http.StatusBadGateway
+	}
+	response.WriteJSON(w, status, resp)
+}
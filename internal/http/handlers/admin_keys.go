@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// maxSigningKeyNameLength matches the admin_signing_keys.name column
+const maxSigningKeyNameLength = 255
+
+// SetSigningKeys serves the signing key endpoints
+func (h *AdminHandler) SetSigningKeys(keys *service.AdminKeyService) {
+	h.keys = keys
+}
+
+// SigningKeysEnabled reports whether the signing key endpoints should be served
+func (h *AdminHandler) SigningKeysEnabled() bool {
+	return h.keys != nil
+}
+
+// CreateSigningKeyRequest names a new signing key
+type CreateSigningKeyRequest struct {
+	Name string `json:"name"`
+}
+
+// SigningKeyResponse describes a signing key
+type SigningKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// signingKeyFields are the SigningKeyResponse fields that may be selected
+// with the fields query parameter
+var signingKeyFields = []string{"id", "name", "created_at", "revoked_at"}
+
+// CreatedSigningKeyResponse is a new signing key with its secret, which is
+// only ever returned here
+type CreatedSigningKeyResponse struct {
+	SigningKeyResponse
+	Secret string `json:"secret"`
+}
+
+// SigningKeyListResponse lists signing keys, newest first
+type SigningKeyListResponse struct {
+	Keys []SigningKeyResponse `json:"keys"`
+}
+
+// CreateSigningKey creates a key for signing admin requests
+func (h *AdminHandler) CreateSigningKey(w http.ResponseWriter, r *http.Request) {
+	var req CreateSigningKeyRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	name := request.SanitizeString(req.Name, maxSigningKeyNameLength)
+	if errs := request.ValidateRequiredFields(map[string]string{"name": name}); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	key, secret, err := h.keys.CreateKey(r.Context(), name)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, CreatedSigningKeyResponse{
+		SigningKeyResponse: newSigningKeyResponse(key),
+		Secret:             secret,
+	})
+}
+
+// ListSigningKeys lists all signing keys without their secrets. The
+// optional fields query parameter keeps only the listed fields of each key.
+func (h *AdminHandler) ListSigningKeys(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	fields := params.Fields("fields", signingKeyFields...)
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	keys, err := h.keys.ListKeys(r.Context())
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := SigningKeyListResponse{Keys: make([]SigningKeyResponse, 0, len(keys))}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, newSigningKeyResponse(key))
+	}
+
+	selected, err := response.SelectFields(resp, "keys", fields)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, selected)
+}
+
+// RevokeSigningKey revokes a signing key
+func (h *AdminHandler) RevokeSigningKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.keys.RevokeKey(r.Context(), r.PathValue("id")); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newSigningKeyResponse(key *domain.AdminSigningKey) SigningKeyResponse {
+	return SigningKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+)
+
+// maxReadOnlyReasonLength keeps the reason short enough for error responses
+const maxReadOnlyReasonLength = 200
+
+// defaultReadOnlyReason is shown to clients when no reason is given
+const defaultReadOnlyReason = "maintenance in progress"
+
+// ReadOnlySwitch turns read-only mode on and off at runtime
+type ReadOnlySwitch interface {
+	Enable(reason string)
+	Disable()
+	Status() (bool, string)
+}
+
+// SetReadOnly serves the read-only mode endpoints
+func (h *AdminHandler) SetReadOnly(mode ReadOnlySwitch) {
+	h.readOnly = mode
+}
+
+// ReadOnlyEnabled reports whether the read-only mode endpoints should be served
+func (h *AdminHandler) ReadOnlyEnabled() bool {
+	return h.readOnly != nil
+}
+
+// UpdateReadOnlyRequest turns read-only mode on or off
+type UpdateReadOnlyRequest struct {
+	Enabled *bool  `json:"enabled"`
+	Reason  string `json:"reason"` // shown to clients whose writes are rejected
+}
+
+// ReadOnlyResponse describes the read-only mode
+type ReadOnlyResponse struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ReadOnlyStatus reports whether the service is in read-only mode
+func (h *AdminHandler) ReadOnlyStatus(w http.ResponseWriter, r *http.Request) {
+	enabled, reason := h.readOnly.Status()
+	response.WriteJSON(w, http.StatusOK, ReadOnlyResponse{Enabled: enabled, Reason: reason})
+}
+
+// UpdateReadOnly turns read-only mode on or off
+func (h *AdminHandler) UpdateReadOnly(w http.ResponseWriter, r *http.Request) {
+	var req UpdateReadOnlyRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	if req.Enabled == nil {
+		response.WriteValidationError(w, []response.ValidationError{{
+			Field:   "enabled",
+			Message: "enabled is required",
+			Code:    "REQUIRED_FIELD",
+		}})
+		return
+	}
+
+	if *req.Enabled {
+		reason := request.SanitizeString(req.Reason, maxReadOnlyReasonLength)
+		if reason == "" {
+			reason = defaultReadOnlyReason
+		}
+		h.readOnly.Enable(reason)
+		slog.WarnContext(r.Context(), "read-only mode enabled", "reason", reason)
+	} else {
+		h.readOnly.Disable()
+		slog.WarnContext(r.Context(), "read-only mode disabled")
+	}
+
+	h.ReadOnlyStatus(w, r)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// revokeReasons maps the reason query parameter to the audited reason
+var revokeReasons = map[string]string{
+	"force_logout":       service.RevokeReasonForceLogout,
+	"account_compromise": service.RevokeReasonAccountCompromise,
+}
+
+// SetSessions serves the session revocation endpoint
+func (h *AdminHandler) SetSessions(auth *service.AuthService) {
+	h.sessions = auth
+}
+
+// SessionsEnabled reports whether the session revocation endpoint should be served
+func (h *AdminHandler) SessionsEnabled() bool {
+	return h.sessions != nil
+}
+
+// RevokeUserSessions signs a user out everywhere. The optional reason query
+// parameter is force_logout (default) or account_compromise.
+func (h *AdminHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	userID := params.PathUUID("id")
+	reason := revokeReasons[params.Enum("reason", "force_logout", "force_logout", "account_compromise")]
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	if err := h.sessions.RevokeSessions(r.Context(), userID, reason); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	slog.WarnContext(r.Context(), "user sessions revoked", "user_id", userID, "reason", reason)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+	"github.com/n1rocket/go-auth-jwt/internal/worker"
+)
+
+// StatusSources are the components GET /admin/status reports on. Nil
+// sources are left out of the report.
+type StatusSources struct {
+	Database    interface{ Stats() sql.DBStats }
+	EmailQueue  interface{ GetStats() worker.Stats }
+	RateLimits  interface{ Buckets() map[string]int }
+	Scheduler   interface{ Jobs() []worker.JobStatus }
+	SigningKeys interface{ SigningKeys() []token.SigningKey }
+}
+
+// SetStatus serves the operational status endpoint
+func (h *AdminHandler) SetStatus(sources StatusSources) {
+	h.status = &sources
+}
+
+// StatusEnabled reports whether the status endpoint should be served
+func (h *AdminHandler) StatusEnabled() bool {
+	return h.status != nil
+}
+
+// DatabaseStatus is the state of the database connection pool
+type DatabaseStatus struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`    // connections waited for since startup
+	WaitDuration       string `json:"wait_duration"` // total time spent waiting since startup
+}
+
+// EmailQueueStatus is the state of the email dispatcher queue
+type EmailQueueStatus struct {
+	Running   bool   `json:"running"`
+	Workers   int    `json:"workers"`
+	Queued    int    `json:"queued"`
+	Capacity  int    `json:"capacity"`
+	Throttled int    `json:"throttled"`            // emails held back by domain rate limits
+	OldestAge string `json:"oldest_age,omitempty"` // age of the oldest queued email; omitted when empty
+}
+
+// JobStatusResponse is a scheduled job and its last run
+type JobStatusResponse struct {
+	Name         string     `json:"name"`
+	Interval     string     `json:"interval"`
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"` // omitted until the job has run
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"` // omitted when the last run succeeded
+}
+
+// SigningKeyStatus is a published RS256 key and its rollover phase
+type SigningKeyStatus struct {
+	ID       string     `json:"kid"`
+	Phase    string     `json:"phase"`
+	ActiveAt *time.Time `json:"active_at,omitempty"` // omitted for the configured key
+}
+
+// StatusResponse gathers live operational data for incident triage
+type StatusResponse struct {
+	Time        time.Time           `json:"time"`
+	Goroutines  int                 `json:"goroutines"`
+	Database    *DatabaseStatus     `json:"database,omitempty"`
+	EmailQueue  *EmailQueueStatus   `json:"email_queue,omitempty"`
+	RateLimits  map[string]int      `json:"rate_limit_buckets,omitempty"` // buckets by policy
+	Jobs        []JobStatusResponse `json:"jobs,omitempty"`
+	SigningKeys []SigningKeyStatus  `json:"signing_keys,omitempty"`
+}
+
+// Status reports connection pool, email queue, rate limiter, scheduled job
+// and signing key state in one document
+func (h *AdminHandler) Status(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	resp := StatusResponse{
+		Time:       now.UTC(),
+		Goroutines: runtime.NumGoroutine(),
+	}
+
+	if h.status.Database != nil {
+		stats := h.status.Database.Stats()
+		resp.Database = &DatabaseStatus{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDuration:       stats.WaitDuration.String(),
+		}
+	}
+
+	if h.status.EmailQueue != nil {
+		stats := h.status.EmailQueue.GetStats()
+		resp.EmailQueue = &EmailQueueStatus{
+			Running:   stats.Running,
+			Workers:   stats.Workers,
+			Queued:    stats.QueueSize,
+			Capacity:  stats.QueueCapacity,
+			Throttled: stats.Throttled,
+		}
+		if !stats.OldestJob.IsZero() {
+			resp.EmailQueue.OldestAge = now.Sub(stats.OldestJob).Round(time.Millisecond).String()
+		}
+	}
+
+	if h.status.RateLimits != nil {
+		resp.RateLimits = h.status.RateLimits.Buckets()
+	}
+
+	if h.status.Scheduler != nil {
+		for _, job := range h.status.Scheduler.Jobs() {
+			status := JobStatusResponse{Name: job.Name, Interval: job.Interval.String()}
+			if job.LastRun != nil {
+				startedAt := job.LastRun.StartedAt.UTC()
+				status.LastRunAt = &startedAt
+				status.LastDuration = job.LastRun.Duration.Round(time.Millisecond).String()
+				status.LastError = job.LastRun.Error
+			}
+			resp.Jobs = append(resp.Jobs, status)
+		}
+	}
+
+	if h.status.SigningKeys != nil {
+		for _, key := range h.status.SigningKeys.SigningKeys() {
+			status := SigningKeyStatus{ID: key.ID, Phase: string(key.Phase)}
+			if !key.ActiveAt.IsZero() {
+				activeAt := key.ActiveAt.UTC()
+				status.ActiveAt = &activeAt
+			}
+			resp.SigningKeys = append(resp.SigningKeys, status)
+		}
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
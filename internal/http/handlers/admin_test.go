@@ -0,0 +1,510 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/config"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/handlers"
+	"github.com/n1rocket/go-auth-jwt/internal/http/middleware"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+	"github.com/n1rocket/go-auth-jwt/internal/worker"
+)
+
+// emptyActivityRepository has no dormant accounts
+type emptyActivityRepository struct{}
+
+func (emptyActivityRepository) RecordActivity(ctx context.Context, userID string, at time.Time) error {
+	return nil
+}
+
+func (emptyActivityRepository) ListInactive(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	return nil, nil
+}
+
+func (emptyActivityRepository) ListNotified(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	return nil, nil
+}
+
+func (emptyActivityRepository) ListDisabled(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	return nil, nil
+}
+
+func (emptyActivityRepository) MarkNotified(ctx context.Context, userID string, at time.Time) error {
+	return nil
+}
+
+func (emptyActivityRepository) Disable(ctx context.Context, userID string, at time.Time) error {
+	return nil
+}
+
+func TestAdminHandler_Dormancy(t *testing.T) {
+	dormancy := service.NewDormancyService(emptyActivityRepository{}, nil, nil, nil, service.DormancyPolicy{
+		NotifyAfter:  180 * 24 * time.Hour,
+		DisableAfter: 210 * 24 * time.Hour,
+		BatchSize:    100,
+	})
+	handler := handlers.NewAdminHandler(dormancy)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", handler.RunDormancy)
+	mux.HandleFunc("GET /runs", handler.ListDormancyRuns)
+	mux.HandleFunc("GET /runs/{id}", handler.GetDormancyRun)
+
+	// Invalid dry_run value
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/runs?dry_run=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid dry_run, got %d", rec.Code)
+	}
+
+	// Dry run
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/runs?dry_run=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report handlers.DormancyReportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if report.ID == "" || !report.DryRun || report.Notified == nil {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	// List
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/runs", nil))
+	var list handlers.DormancyReportListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if len(list.Runs) != 1 || list.Runs[0].ID != report.ID {
+		t.Errorf("unexpected runs: %+v", list.Runs)
+	}
+	if list.Policy.NotifyAfter != "4320h0m0s" || list.Policy.PurgeAfter != "" {
+		t.Errorf("unexpected policy: %+v", list.Policy)
+	}
+
+	// Get
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/runs/"+report.ID, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/runs/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown report, got %d", rec.Code)
+	}
+}
+
+// memorySigningKeyRepository keeps signing keys in memory
+type memorySigningKeyRepository struct {
+	keys []*domain.AdminSigningKey
+}
+
+func (m *memorySigningKeyRepository) Create(ctx context.Context, key *domain.AdminSigningKey) error {
+	m.keys = append(m.keys, key)
+	return nil
+}
+
+func (m *memorySigningKeyRepository) GetByID(ctx context.Context, id string) (*domain.AdminSigningKey, error) {
+	for _, key := range m.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, domain.ErrSigningKeyNotFound
+}
+
+func (m *memorySigningKeyRepository) List(ctx context.Context) ([]*domain.AdminSigningKey, error) {
+	return m.keys, nil
+}
+
+func (m *memorySigningKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	key, err := m.GetByID(ctx, id)
+	if err != nil || key.IsRevoked() {
+		return domain.ErrSigningKeyNotFound
+	}
+	key.RevokedAt = &at
+	return nil
+}
+
+func TestAdminHandler_SigningKeys(t *testing.T) {
+	handler := handlers.NewAdminHandler(nil)
+	handler.SetSigningKeys(service.NewAdminKeyService(&memorySigningKeyRepository{}, "fedcba9876543210fedcba9876543210"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /keys", handler.CreateSigningKey)
+	mux.HandleFunc("GET /keys", handler.ListSigningKeys)
+	mux.HandleFunc("DELETE /keys/{id}", handler.RevokeSigningKey)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/keys", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := create(`{"name":"  "}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a blank name, got %d", rec.Code)
+	}
+
+	rec := create(`{"name":"ci deploy"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created handlers.CreatedSigningKeyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode key: %v", err)
+	}
+	if created.ID == "" || created.Secret == "" || created.Name != "ci deploy" {
+		t.Errorf("unexpected key: %+v", created)
+	}
+
+	// Listing never shows secrets
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/keys", nil))
+	if strings.Contains(rec.Body.String(), created.Secret) {
+		t.Error("key list contains the key secret")
+	}
+
+	// Fields narrow each listed key
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/keys?fields=id", nil))
+	if want := `{"keys":[{"id":"` + created.ID + `"}]}` + "\n"; rec.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/keys?fields=secret", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 selecting the secret, got %d", rec.Code)
+	}
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/keys/"+created.ID, nil))
+		if rec.Code != want {
+			t.Errorf("expected status %d revoking the key, got %d", want, rec.Code)
+		}
+	}
+}
+
+func TestAdminHandler_ReadOnly(t *testing.T) {
+	mode := middleware.NewReadOnlyMode()
+	handler := handlers.NewAdminHandler(nil)
+	handler.SetReadOnly(mode)
+
+	update := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/read-only", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.UpdateReadOnly(rec, req)
+		return rec
+	}
+
+	if rec := update(`{"reason":"failover"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without enabled, got %d", rec.Code)
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantEnabled bool
+		wantReason  string
+	}{
+		{name: "enable with reason", body: `{"enabled":true,"reason":" primary database failover "}`, wantEnabled: true, wantReason: "primary database failover"},
+		{name: "enable without reason", body: `{"enabled":true}`, wantEnabled: true, wantReason: "maintenance in progress"},
+		{name: "disable", body: `{"enabled":false}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := update(tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var resp handlers.ReadOnlyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Enabled != tt.wantEnabled || resp.Reason != tt.wantReason {
+				t.Errorf("response = %+v, want enabled=%v reason=%q", resp, tt.wantEnabled, tt.wantReason)
+			}
+			if enabled, reason := mode.Status(); enabled != tt.wantEnabled || reason != tt.wantReason {
+				t.Errorf("mode = %v %q, want %v %q", enabled, reason, tt.wantEnabled, tt.wantReason)
+			}
+		})
+	}
+}
+
+// statusSource reports fixed operational data
+type statusSource struct {
+	db    sql.DBStats
+	queue worker.Stats
+	jobs  []worker.JobStatus
+	keys  []token.SigningKey
+}
+
+func (s statusSource) Stats() sql.DBStats              { return s.db }
+func (s statusSource) GetStats() worker.Stats          { return s.queue }
+func (s statusSource) Buckets() map[string]int         { return map[string]int{"auth": 3, "api": 7} }
+func (s statusSource) Jobs() []worker.JobStatus        { return s.jobs }
+func (s statusSource) SigningKeys() []token.SigningKey { return s.keys }
+
+func TestAdminHandler_Status(t *testing.T) {
+	now := time.Now()
+	source := statusSource{
+		db:    sql.DBStats{MaxOpenConnections: 25, OpenConnections: 10, InUse: 4, Idle: 6, WaitCount: 2, WaitDuration: 1500 * time.Millisecond},
+		queue: worker.Stats{Workers: 5, QueueSize: 12, QueueCapacity: 100, Running: true, OldestJob: now.Add(-time.Minute)},
+		jobs: []worker.JobStatus{
+			{Name: "dormancy", Interval: time.Hour, LastRun: &worker.JobResult{StartedAt: now, Duration: 2 * time.Second, Error: "database unavailable"}},
+			{Name: "jwks-rollover", Interval: time.Minute},
+		},
+		keys: []token.SigningKey{
+			{ID: "old", Phase: token.KeyPhaseActive},
+			{ID: "new", Phase: token.KeyPhasePending, ActiveAt: now.Add(time.Hour)},
+		},
+	}
+
+	t.Run("reports every source", func(t *testing.T) {
+		handler := handlers.NewAdminHandler(nil)
+		handler.SetStatus(handlers.StatusSources{
+			Database:    source,
+			EmailQueue:  source,
+			RateLimits:  source,
+			Scheduler:   source,
+			SigningKeys: source,
+		})
+
+		rec := httptest.NewRecorder()
+		handler.Status(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/status", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp handlers.StatusResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Goroutines <= 0 {
+			t.Errorf("goroutines = %d, want a positive count", resp.Goroutines)
+		}
+		if resp.Database == nil || resp.Database.InUse != 4 || resp.Database.WaitDuration != "1.5s" {
+			t.Errorf("database = %+v", resp.Database)
+		}
+		if resp.EmailQueue == nil || resp.EmailQueue.Queued != 12 || !strings.HasPrefix(resp.EmailQueue.OldestAge, "1m0") {
+			t.Errorf("email queue = %+v", resp.EmailQueue)
+		}
+		if resp.RateLimits["api"] != 7 {
+			t.Errorf("rate limit buckets = %v", resp.RateLimits)
+		}
+		if len(resp.Jobs) != 2 || resp.Jobs[0].LastError != "database unavailable" || resp.Jobs[0].LastDuration != "2s" || resp.Jobs[1].LastRunAt != nil {
+			t.Errorf("jobs = %+v", resp.Jobs)
+		}
+		if len(resp.SigningKeys) != 2 || resp.SigningKeys[0].ActiveAt != nil || resp.SigningKeys[1].Phase != "pending" {
+			t.Errorf("signing keys = %+v", resp.SigningKeys)
+		}
+	})
+
+	t.Run("leaves out missing sources", func(t *testing.T) {
+		handler := handlers.NewAdminHandler(nil)
+		handler.SetStatus(handlers.StatusSources{})
+
+		rec := httptest.NewRecorder()
+		handler.Status(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/status", nil))
+
+		var resp map[string]interface{}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		for _, field := range []string{"database", "email_queue", "rate_limit_buckets", "jobs", "signing_keys"} {
+			if _, ok := resp[field]; ok {
+				t.Errorf("%s reported without a source", field)
+			}
+		}
+	})
+}
+
+func TestAdminHandler_RevokeUserSessions_InvalidReason(t *testing.T) {
+	handler := handlers.NewAdminHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-123/revoke-sessions?reason=bored", nil)
+	req.SetPathValue("id", "user-123")
+	rec := httptest.NewRecorder()
+	handler.RevokeUserSessions(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"reason"`) {
+		t.Errorf("expected reason validation error, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_RevokeUserSessions_InvalidUserID(t *testing.T) {
+	handler := handlers.NewAdminHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-123/revoke-sessions", nil)
+	req.SetPathValue("id", "user-123")
+	rec := httptest.NewRecorder()
+	handler.RevokeUserSessions(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"id"`) {
+		t.Errorf("expected id validation error, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_SessionAnalytics_InvalidUserID(t *testing.T) {
+	handler := handlers.NewAdminHandler(nil)
+	if handler.AnalyticsEnabled() {
+		t.Error("AnalyticsEnabled() = true without session stats")
+	}
+
+	rec := httptest.NewRecorder()
+	handler.SessionAnalytics(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/analytics/sessions?user_id=user-123", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"user_id"`) {
+		t.Errorf("expected user_id validation error, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_SetAccessWindow_InvalidUserID(t *testing.T) {
+	handler := handlers.NewAdminHandler(nil)
+	if handler.AccessWindowsEnabled() {
+		t.Error("AccessWindowsEnabled() = true without access windows")
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/users/user-123/access-window", strings.NewReader(`{"start":"08:00","end":"18:00"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "user-123")
+	rec := httptest.NewRecorder()
+	handler.SetAccessWindow(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"id"`) {
+		t.Errorf("expected id validation error, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_ListUsers_InvalidLimit(t *testing.T) {
+	handler := handlers.NewAdminHandler(nil)
+
+	rec := httptest.NewRecorder()
+	handler.ListUsers(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/users?limit=1000", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"limit"`) {
+		t.Errorf("expected limit validation error, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_UpdateAppMetadata_InvalidUserID(t *testing.T) {
+	handler := handlers.NewAdminHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/admin/users/user-123/app-metadata", strings.NewReader(`{"plan":"pro"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "user-123")
+	rec := httptest.NewRecorder()
+	handler.UpdateAppMetadata(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"id"`) {
+		t.Errorf("expected id validation error, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_ReplayUserEvents_InvalidParams(t *testing.T) {
+	handler := handlers.NewAdminHandler(nil)
+
+	tests := map[string]string{
+		"type":     "type=user.deleted",
+		"to":       "from=2026-10-02T00:00:00Z&to=2026-10-01T00:00:00Z",
+		"after_id": "after_id=-1",
+		"limit":    "limit=5000",
+	}
+	for field, query := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/user-events/replay?"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ReplayUserEvents(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"`+field+`"`) {
+			t.Errorf("%s: expected %s validation error, got %s", query, field, rec.Body.String())
+		}
+	}
+}
+
+func TestAdminHandler_EmailTemplates(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{App: config.AppConfig{Name: "Example", BaseURL: "https://auth.example.com"}}
+	handler := handlers.NewAdminHandler(nil)
+	handler.SetEmails(service.NewAuthServiceWithEmail(nil, worker.NewEmailDispatcher(nil, worker.DefaultConfig(), logger), cfg, logger))
+
+	rec := httptest.NewRecorder()
+	handler.ListEmailTemplates(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/email-templates", nil))
+	var list handlers.EmailTemplateListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if len(list.Templates) == 0 || list.Templates[0].Name != "dormancy_notice" {
+		t.Errorf("templates = %+v, want them sorted by name", list.Templates)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/email-templates/dormancy_notice/preview?locale=es", nil)
+	req.SetPathValue("name", "dormancy_notice")
+	rec = httptest.NewRecorder()
+	handler.PreviewEmailTemplate(rec, req)
+	var preview handlers.EmailPreviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&preview); err != nil {
+		t.Fatalf("failed to decode preview: %v", err)
+	}
+	if rec.Code != http.StatusOK || preview.Locale != "es" || preview.Subject != "Tu cuenta de Example será desactivada" {
+		t.Errorf("preview = %d %+v", rec.Code, preview)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/email-templates/welcome/preview", nil)
+	req.SetPathValue("name", "welcome")
+	rec = httptest.NewRecorder()
+	handler.PreviewEmailTemplate(rec, req)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "EMAIL_TEMPLATE_NOT_FOUND") {
+		t.Errorf("preview of an unknown template = %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/email-templates/dormancy_notice/test-send", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("name", "dormancy_notice")
+	rec = httptest.NewRecorder()
+	handler.SendTestEmail(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"to"`) {
+		t.Errorf("test-send without an address = %d %s", rec.Code, rec.Body.String())
+	}
+}
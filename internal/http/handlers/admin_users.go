@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// SetUsers serves the user list and app_metadata endpoints
+func (h *AdminHandler) SetUsers(auth *service.AuthService) {
+	h.users = auth
+}
+
+// UsersEnabled reports whether the user list and app_metadata endpoints
+// should be served
+func (h *AdminHandler) UsersEnabled() bool {
+	return h.users != nil && h.users.UserMetadataEnabled()
+}
+
+// UsersResponse is a page of users, newest first
+type UsersResponse struct {
+	Users      []UserResponse `json:"users"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// AppMetadataResponse is a user's app_metadata after an update
+type AppMetadataResponse struct {
+	AppMetadata map[string]interface{} `json:"app_metadata"`
+}
+
+// ListUsers returns users, newest first. Query parameters
+// metadata.<key>=<value> and app_metadata.<key>=<value> keep users whose
+// top-level key has that value as text; ?limit sets the page size and
+// ?cursor, the next_cursor of the previous page, fetches older users;
+// ?fields keeps only the listed fields of each user.
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	limit := params.Int("limit", service.DefaultUsersPageSize, 1, service.MaxUsersPageSize)
+	fields := params.Fields("fields", userFields...)
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	input := service.ListUsersInput{
+		Metadata:    map[string]string{},
+		AppMetadata: map[string]string{},
+		Cursor:      r.URL.Query().Get("cursor"),
+		Limit:       limit,
+	}
+	for name, values := range r.URL.Query() {
+		kind, key, ok := strings.Cut(name, ".")
+		switch {
+		case !ok:
+		case kind == string(domain.MetadataKindUser):
+			input.Metadata[key] = values[0]
+		case kind == string(domain.MetadataKindApp):
+			input.AppMetadata[key] = values[0]
+		}
+	}
+
+	page, err := h.users.ListUsers(r.Context(), input)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := UsersResponse{
+		Users:      make([]UserResponse, 0, len(page.Users)),
+		NextCursor: page.NextCursor,
+	}
+	for _, user := range page.Users {
+		resp.Users = append(resp.Users, newUserResponse(user))
+	}
+	selected, err := response.SelectFields(resp, "users", fields)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, selected)
+}
+
+// UpdateAppMetadata applies a JSON merge patch to a user's app_metadata:
+// keys set to null are removed and keys left out are kept
+func (h *AdminHandler) UpdateAppMetadata(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	userID := params.PathUUID("id")
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	var patch map[string]interface{}
+	if err := request.ValidateJSONRequest(r, &patch); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	metadata, err := h.users.UpdateAppMetadata(r.Context(), userID, patch)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "user app metadata updated", "user_id", userID)
+	response.WriteJSON(w, http.StatusOK, AppMetadataResponse{AppMetadata: metadata})
+}
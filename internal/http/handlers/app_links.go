@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+)
+
+// appLinksMaxAge is how long clients may cache the app association files.
+// iOS and Android fetch them through their own CDNs when an app is installed.
+const appLinksMaxAge = time.Hour
+
+// AppLinks associates this server's domain with the mobile apps, so links
+// in emails open in the apps instead of the browser
+type AppLinks struct {
+	AppleAppIDs             []string // TEAMID.bundle.id of the iOS apps
+	Paths                   []string // paths iOS opens in the apps
+	AndroidPackage          string
+	AndroidCertFingerprints []string // SHA-256 fingerprints of the signing certificates
+}
+
+// AppleAppSiteAssociation serves the apple-app-site-association file that
+// lets iOS open links to Paths in the apps
+func (a AppLinks) AppleAppSiteAssociation() http.HandlerFunc {
+	components := make([]map[string]string, 0, len(a.Paths))
+	for _, path := range a.Paths {
+		components = append(components, map[string]string{"/": path})
+	}
+	return staticJSON(map[string]interface{}{
+		"applinks": map[string]interface{}{
+			"details": []map[string]interface{}{{
+				"appIDs":     a.AppleAppIDs,
+				"components": components,
+			}},
+		},
+	})
+}
+
+// AssetLinks serves the Digital Asset Links file that lets Android verify
+// the app's links to this domain. Which paths the app opens is declared in
+// its intent filters.
+func (a AppLinks) AssetLinks() http.HandlerFunc {
+	return staticJSON([]map[string]interface{}{{
+		"relation": []string{"delegate_permission/common.handle_all_urls"},
+		"target": map[string]interface{}{
+			"namespace":                "android_app",
+			"package_name":             a.AndroidPackage,
+			"sha256_cert_fingerprints": a.AndroidCertFingerprints,
+		},
+	}})
+}
+
+// staticJSON serves a document that only changes on deploy, encoded once
+func staticJSON(document interface{}) http.HandlerFunc {
+	body, _ := json.Marshal(document) // strings, slices and maps always encode
+	return func(w http.ResponseWriter, r *http.Request) {
+		setPublicCache(w, appLinksMaxAge)
+		response.WriteBodyWithETag(w, r, "application/json", body)
+	}
+}
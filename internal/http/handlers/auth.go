@@ -2,35 +2,66 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
 	"github.com/n1rocket/go-auth-jwt/internal/http/request"
 	"github.com/n1rocket/go-auth-jwt/internal/http/response"
 	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
 )
 
 // AuthHandler handles authentication-related HTTP requests
 type AuthHandler struct {
-	authService *service.AuthService
+	authService     *service.AuthService
+	emails          *service.AuthServiceWithEmail
+	serviceAccounts *service.ServiceAccountService
+	tokenFormat     TokenResponseFormat
+	rememberMe      *RememberMeCookie
 }
 
 // NewAuthHandler creates a new authentication handler
 func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		tokenFormat: TokenFormatNative,
 	}
 }
 
+// SetTokenResponseFormat sets the shape of login and refresh responses for
+// clients that do not negotiate one
+func (h *AuthHandler) SetTokenResponseFormat(format TokenResponseFormat) {
+	h.tokenFormat = format
+}
+
+// SetEmailService sends verification, password reset and login emails
+// through emails. Without it the handlers issue tokens and codes but never
+// mail them.
+func (h *AuthHandler) SetEmailService(emails *service.AuthServiceWithEmail) {
+	h.emails = emails
+}
+
 // SignupRequest represents the signup request payload
 type SignupRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email            string `json:"email"`
+	Password         string `json:"password"`
+	Username         string `json:"username,omitempty"`
+	Locale           string `json:"locale,omitempty"`
+	TermsVersion     string `json:"terms_version,omitempty"`
+	MarketingConsent bool   `json:"marketing_consent,omitempty"`
 }
 
+// enumerationSafeSignupMessage is returned for every accepted signup in
+// enumeration-safe mode
+const enumerationSafeSignupMessage = "If the address can be registered, a verification email has been sent."
+
 // SignupResponse represents the signup response
 type SignupResponse struct {
-	UserID  string `json:"user_id"`
+	UserID  string `json:"user_id,omitempty"` // omitted in enumeration-safe mode
 	Message string `json:"message"`
 }
 
@@ -44,6 +75,7 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 
 	// Trim whitespace
 	req.Email = strings.TrimSpace(req.Email)
+	req.Locale = strings.TrimSpace(req.Locale)
 
 	// Validate required fields
 	validationErrors := request.ValidateRequiredFields(map[string]string{
@@ -55,11 +87,30 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The address consents were given from is kept for compliance
+	ipAddress := getClientIP(r)
+
 	// Call service
-	output, err := h.authService.Signup(r.Context(), service.SignupInput{
-		Email:    req.Email,
-		Password: req.Password,
+	signup := h.authService.Signup
+	if h.emails != nil {
+		signup = h.emails.SignupWithEmail
+	}
+	output, err := signup(r.Context(), service.SignupInput{
+		Email:            req.Email,
+		Password:         req.Password,
+		Username:         req.Username,
+		Locale:           req.Locale,
+		TermsVersion:     strings.TrimSpace(req.TermsVersion),
+		MarketingConsent: req.MarketingConsent,
+		IPAddress:        &ipAddress,
 	})
+	if h.authService.EnumerationSafe() && (err == nil || errors.Is(err, domain.ErrDuplicateEmail)) {
+		// Registered and new addresses get the same answer
+		response.WriteJSON(w, http.StatusAccepted, SignupResponse{
+			Message: enumerationSafeSignupMessage,
+		})
+		return
+	}
 	if err != nil {
 		response.WriteError(w, err)
 		return
@@ -74,16 +125,27 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 
 // LoginRequest represents the login request payload
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email        string `json:"email,omitempty"`
+	Username     string `json:"username,omitempty"` // used instead of email when set
+	Password     string `json:"password"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+	SMSCode      string `json:"sms_code,omitempty"`    // answers SMS_CODE_REQUIRED
+	RememberMe   bool   `json:"remember_me,omitempty"` // keep the session in the remember-me cookie
+
+	// PasswordVerifier is sent instead of Password for accounts with a
+	// client-hashed password
+	PasswordVerifier string `json:"password_verifier,omitempty"`
 }
 
-// LoginResponse represents the login response
+// LoginResponse represents the login response. The refresh token of a
+// remember-me session is only in the cookie.
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	TokenType    string `json:"token_type"`
-	ExpiresIn    int64  `json:"expires_in"`
+	AccessToken         string     `json:"access_token"`
+	RefreshToken        string     `json:"refresh_token,omitempty"`
+	IDToken             string     `json:"id_token,omitempty"`
+	TokenType           string     `json:"token_type"`
+	ExpiresIn           int64      `json:"expires_in"`
+	RememberMeExpiresAt *time.Time `json:"remember_me_expires_at,omitempty"`
 }
 
 // Login handles user authentication
@@ -96,12 +158,20 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Trim whitespace
 	req.Email = strings.TrimSpace(req.Email)
+	req.Username = strings.TrimSpace(req.Username)
 
-	// Validate required fields
-	validationErrors := request.ValidateRequiredFields(map[string]string{
-		"email":    req.Email,
-		"password": req.Password,
-	})
+	// Validate required fields; either email or username identifies the
+	// user, and either the password or its verifier authenticates them
+	fields := map[string]string{"password": req.Password}
+	if req.PasswordVerifier != "" {
+		fields = map[string]string{"password_verifier": req.PasswordVerifier}
+	}
+	if req.Username != "" {
+		fields["username"] = req.Username
+	} else {
+		fields["email"] = req.Email
+	}
+	validationErrors := request.ValidateRequiredFields(fields)
 	if len(validationErrors) > 0 {
 		response.WriteValidationError(w, validationErrors)
 		return
@@ -112,11 +182,23 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	ipAddress := getClientIP(r)
 
 	// Call service
-	output, err := h.authService.Login(r.Context(), service.LoginInput{
-		Email:     req.Email,
-		Password:  req.Password,
-		UserAgent: &userAgent,
-		IPAddress: &ipAddress,
+	login := h.authService.Login
+	if h.emails != nil {
+		login = h.emails.LoginWithNotification
+	}
+	output, err := login(r.Context(), service.LoginInput{
+		Email:          req.Email,
+		Username:       req.Username,
+		Password:       req.Password,
+		UserAgent:      &userAgent,
+		IPAddress:      &ipAddress,
+		CaptchaToken:   req.CaptchaToken,
+		SMSCode:        strings.TrimSpace(req.SMSCode),
+		CertThumbprint: clientCertThumbprint(r),
+		DPoP:           dpopProof(r),
+		RememberMe:     req.RememberMe && h.rememberMe != nil,
+
+		PasswordVerifier: req.PasswordVerifier,
 	})
 	if err != nil {
 		response.WriteError(w, err)
@@ -124,15 +206,30 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return response
-	response.WriteJSON(w, http.StatusOK, LoginResponse{
+	h.writeSession(w, r, output, output.RememberMe)
+}
+
+// writeSession writes the tokens of a new or refreshed session. With
+// inCookie the refresh token goes in the remember-me cookie instead of the
+// body.
+func (h *AuthHandler) writeSession(w http.ResponseWriter, r *http.Request, output *service.LoginOutput, inCookie bool) {
+	resp := LoginResponse{
 		AccessToken:  output.AccessToken,
 		RefreshToken: output.RefreshToken,
-		TokenType:    "Bearer",
+		IDToken:      output.IDToken,
+		TokenType:    tokenType(output),
 		ExpiresIn:    output.ExpiresIn,
-	})
+	}
+	if inCookie {
+		h.rememberMe.set(w, output.RefreshToken, output.SessionExpiresAt)
+		resp.RefreshToken = ""
+		resp.RememberMeExpiresAt = &output.SessionExpiresAt
+	}
+	writeTokenResponse(w, r, h.tokenFormat, resp)
 }
 
-// RefreshRequest represents the refresh request payload
+// RefreshRequest represents the refresh request payload. The refresh token
+// may be left out when the remember-me cookie is sent.
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
@@ -145,6 +242,17 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A remember-me session sends its refresh token in the cookie
+	fromCookie := false
+	if req.RefreshToken == "" {
+		refreshToken, err := h.rememberMeToken(w, r)
+		if err != nil {
+			response.WriteError(w, err)
+			return
+		}
+		req.RefreshToken, fromCookie = refreshToken, refreshToken != ""
+	}
+
 	// Validate required fields
 	validationErrors := request.ValidateRequiredFields(map[string]string{
 		"refresh_token": req.RefreshToken,
@@ -160,25 +268,44 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 	// Call service
 	output, err := h.authService.Refresh(r.Context(), service.RefreshInput{
-		RefreshToken: req.RefreshToken,
-		UserAgent:    &userAgent,
-		IPAddress:    &ipAddress,
+		RefreshToken:   req.RefreshToken,
+		UserAgent:      &userAgent,
+		IPAddress:      &ipAddress,
+		CertThumbprint: clientCertThumbprint(r),
+		DPoP:           dpopProof(r),
 	})
 	if err != nil {
+		if fromCookie && errors.Is(err, domain.ErrInvalidToken) {
+			h.rememberMe.clear(w)
+		}
 		response.WriteError(w, err)
 		return
 	}
 
-	// Return response
-	response.WriteJSON(w, http.StatusOK, LoginResponse{
-		AccessToken:  output.AccessToken,
-		RefreshToken: output.RefreshToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    output.ExpiresIn,
-	})
+	// Return response; a session refreshed from the cookie stays in it
+	h.writeSession(w, r, output, fromCookie)
+}
+
+// rememberMeToken returns the refresh token in the remember-me cookie, or ""
+// when remember-me is disabled or the request has no cookie. A cookie that
+// fails verification is cleared.
+func (h *AuthHandler) rememberMeToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if h.rememberMe == nil {
+		return "", nil
+	}
+	refreshToken, err := h.rememberMe.read(r, time.Now())
+	if errors.Is(err, http.ErrNoCookie) {
+		return "", nil
+	}
+	if err != nil {
+		h.rememberMe.clear(w)
+		return "", domain.ErrInvalidToken
+	}
+	return refreshToken, nil
 }
 
-// LogoutRequest represents the logout request payload
+// LogoutRequest represents the logout request payload. The refresh token
+// may be left out when the remember-me cookie is sent.
 type LogoutRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
@@ -191,6 +318,25 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Logging out also ends the session in the remember-me cookie. The
+	// cookie is dropped even when it no longer verifies.
+	var cookieToken string
+	if h.rememberMe != nil {
+		if _, err := r.Cookie(h.rememberMe.Name); err == nil {
+			cookieToken, _ = h.rememberMe.read(r, time.Now())
+			h.rememberMe.clear(w)
+			if req.RefreshToken == "" && cookieToken == "" {
+				response.WriteJSON(w, http.StatusOK, map[string]string{
+					"message": "Logged out successfully",
+				})
+				return
+			}
+		}
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken, cookieToken = cookieToken, ""
+	}
+
 	// Validate required fields
 	validationErrors := request.ValidateRequiredFields(map[string]string{
 		"refresh_token": req.RefreshToken,
@@ -201,9 +347,14 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service
-	if err := h.authService.Logout(r.Context(), service.LogoutInput{RefreshToken: req.RefreshToken}); err != nil {
-		response.WriteError(w, err)
-		return
+	for _, refreshToken := range []string{req.RefreshToken, cookieToken} {
+		if refreshToken == "" {
+			continue
+		}
+		if err := h.authService.Logout(r.Context(), service.LogoutInput{RefreshToken: refreshToken}); err != nil {
+			response.WriteError(w, err)
+			return
+		}
 	}
 
 	// Return response
@@ -276,12 +427,81 @@ func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ResendVerificationRequest represents the verification email resend request
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// ResendVerification sends a new verification email. It is only routed
+// when the handler has an email service.
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req ResendVerificationRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email": req.Email,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if _, err := h.emails.ResendVerificationEmailWithNotification(r.Context(), req.Email); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If the address needs verifying, a verification email has been sent",
+	})
+}
+
 // UserResponse represents the user information response
 type UserResponse struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	EmailVerified bool   `json:"email_verified"`
-	CreatedAt     string `json:"created_at"`
+	ID            string  `json:"id"`
+	Email         string  `json:"email"`
+	Username      *string `json:"username,omitempty"`
+	DisplayName   *string `json:"display_name,omitempty"`
+	Locale        *string `json:"locale,omitempty"`
+	Timezone      *string `json:"timezone,omitempty"`
+	AvatarURL     *string `json:"avatar_url,omitempty"`
+	PhoneNumber   *string `json:"phone_number,omitempty"`
+	PhoneVerified bool    `json:"phone_verified,omitempty"`
+	EmailVerified bool    `json:"email_verified"`
+	CreatedAt     string  `json:"created_at"`
+
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	AppMetadata map[string]interface{} `json:"app_metadata,omitempty"`
+}
+
+// userFields are the UserResponse fields clients may select with the fields
+// query parameter
+var userFields = []string{
+	"id", "email", "username", "display_name", "locale", "timezone", "avatar_url",
+	"phone_number", "phone_verified", "email_verified", "created_at", "metadata", "app_metadata",
+}
+
+// newUserResponse builds the user information response
+func newUserResponse(user *domain.User) UserResponse {
+	return UserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		DisplayName:   user.DisplayName,
+		Locale:        user.Locale,
+		Timezone:      user.Timezone,
+		AvatarURL:     user.AvatarURL,
+		PhoneNumber:   user.PhoneNumber,
+		PhoneVerified: user.PhoneVerified,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Metadata:      user.Metadata,
+		AppMetadata:   user.AppMetadata,
+	}
 }
 
 // GetCurrentUser returns the current authenticated user's information
@@ -293,6 +513,13 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	params := request.NewParams(r)
+	fields := params.Fields("fields", userFields...)
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
 	// Get user from service
 	user, err := h.authService.GetUserByID(r.Context(), userID)
 	if err != nil {
@@ -300,12 +527,210 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resp, err := response.SelectFields(newUserResponse(user), "", fields)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Clients polling the profile revalidate with If-None-Match and get an
+	// empty 304 while it is unchanged
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Vary", "Authorization")
+	response.WriteJSONWithETag(w, r, resp)
+}
+
+// UpdateProfileRequest represents the profile update payload; omitted fields
+// are left unchanged and empty strings clear the field
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name"`
+	Locale      *string `json:"locale"`
+	Timezone    *string `json:"timezone"`
+	AvatarURL   *string `json:"avatar_url"`
+}
+
+// UpdateCurrentUser applies a partial profile update to the authenticated user
+func (h *AuthHandler) UpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Call service
+	user, err := h.authService.UpdateProfile(r.Context(), userID, domain.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		Locale:      req.Locale,
+		Timezone:    req.Timezone,
+		AvatarURL:   req.AvatarURL,
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
 	// Return response
-	response.WriteJSON(w, http.StatusOK, UserResponse{
-		ID:            user.ID,
-		Email:         user.Email,
-		EmailVerified: user.EmailVerified,
-		CreatedAt:     user.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	response.WriteJSON(w, http.StatusOK, newUserResponse(user))
+}
+
+// ChangePasswordRequest represents the password change request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePassword replaces the caller's password and signs them out everywhere
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Validate required fields
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"current_password": req.CurrentPassword,
+		"new_password":     req.NewPassword,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	// Call service
+	err := h.authService.ChangePassword(r.Context(), service.ChangePasswordInput{
+		UserID:          userID,
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Return response
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Password changed; signed out from all devices",
+	})
+}
+
+// AvatarUploadResponse represents a signed avatar upload URL
+type AvatarUploadResponse struct {
+	UploadURL string `json:"upload_url"`
+	Method    string `json:"method"`
+	AvatarURL string `json:"avatar_url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// CreateAvatarUpload issues a short-lived URL the client uploads its avatar to
+func (h *AuthHandler) CreateAvatarUpload(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	// Call service
+	output, err := h.authService.CreateAvatarUpload(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Return response
+	response.WriteJSON(w, http.StatusCreated, AvatarUploadResponse{
+		UploadURL: output.UploadURL,
+		Method:    output.Method,
+		AvatarURL: output.AvatarURL,
+		ExpiresAt: output.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z"),
+	})
+}
+
+// UserInfoResponse represents the OIDC userinfo response
+type UserInfoResponse struct {
+	Sub               string  `json:"sub"`
+	Email             string  `json:"email"`
+	EmailVerified     bool    `json:"email_verified"`
+	PreferredUsername *string `json:"preferred_username,omitempty"`
+	Name              *string `json:"name,omitempty"`
+	Picture           *string `json:"picture,omitempty"`
+	Locale            *string `json:"locale,omitempty"`
+	Zoneinfo          *string `json:"zoneinfo,omitempty"`
+	UpdatedAt         int64   `json:"updated_at"`
+}
+
+// UserInfo returns OIDC-standard claims for the user identified by the access token
+func (h *AuthHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	// Get user from service
+	user, err := h.authService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Return response
+	response.WriteJSON(w, http.StatusOK, UserInfoResponse{
+		Sub:               user.ID,
+		Email:             user.Email,
+		EmailVerified:     user.EmailVerified,
+		PreferredUsername: user.Username,
+		Name:              user.DisplayName,
+		Picture:           user.AvatarURL,
+		Locale:            user.Locale,
+		Zoneinfo:          user.Timezone,
+		UpdatedAt:         user.UpdatedAt.Unix(),
+	})
+}
+
+// UsernameAvailabilityResponse represents the username availability response
+type UsernameAvailabilityResponse struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+}
+
+// UsernameAvailable reports whether the username query parameter can be registered
+func (h *AuthHandler) UsernameAvailable(w http.ResponseWriter, r *http.Request) {
+	username := strings.TrimSpace(r.URL.Query().Get("username"))
+
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"username": username,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	available, err := h.authService.UsernameAvailable(r.Context(), username)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, UsernameAvailabilityResponse{
+		Username:  domain.NormalizeUsername(username),
+		Available: available,
 	})
 }
 
@@ -332,13 +757,40 @@ func getClientIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
-// Context keys
-type contextKey string
+// clientCertThumbprint returns the thumbprint of the verified mTLS client
+// certificate, or "" when the client did not present one
+func clientCertThumbprint(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	return token.CertificateThumbprint(r.TLS.PeerCertificates[0])
+}
 
-const (
-	// UserIDContextKey is the context key for the authenticated user ID
-	UserIDContextKey contextKey = "userID"
-)
+// dpopProof returns the DPoP proof sent with a token request, bound to the
+// request method and URL. The proof is empty when no DPoP header was sent.
+func dpopProof(r *http.Request) token.DPoPRequest {
+	proof := r.Header.Get(token.DPoPHeader)
+	if proof == "" {
+		return token.DPoPRequest{}
+	}
+	return token.DPoPRequest{
+		Proof:  proof,
+		Method: r.Method,
+		URL:    request.RequestURL(r),
+	}
+}
+
+// tokenType returns the token_type for issued tokens
+func tokenType(output *service.LoginOutput) string {
+	if output.DPoPBound {
+		return token.DPoPTokenType
+	}
+	return "Bearer"
+}
+
+// UserIDContextKey is the context key for the authenticated user ID, the
+// one RequireAuth sets
+const UserIDContextKey = httpcontext.UserIDKey
 
 // WithUserID adds the user ID to the request context
 func WithUserID(ctx context.Context, userID string) context.Context {
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
 	"github.com/n1rocket/go-auth-jwt/internal/http/handlers"
 )
 
@@ -405,3 +406,151 @@ func TestReadyEndpoint(t *testing.T) {
 		t.Errorf("expected status 'ready', got '%s'", response.Status)
 	}
 }
+
+func TestChangePassword_MissingFields(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/me/password", strings.NewReader(`{"current_password":"password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), httpcontext.UserIDKey, "user-123"))
+
+	w := httptest.NewRecorder()
+	handler := handlers.NewAuthHandler(nil)
+
+	handler.ChangePassword(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestConfirmPasswordReset_MissingFields(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password-reset/confirm", strings.NewReader(`{"email":"test@example.com","code":"123456"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler := handlers.NewAuthHandler(nil)
+
+	handler.ConfirmPasswordReset(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "new_password") {
+		t.Errorf("expected new_password in validation details, got %s", w.Body.String())
+	}
+}
+
+func TestSetSMSSecondFactor_MissingEnabled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/auth/me/phone/second-factor", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), httpcontext.UserIDKey, "user-123"))
+
+	w := httptest.NewRecorder()
+	handler := handlers.NewAuthHandler(nil)
+
+	handler.SetSMSSecondFactor(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestListSecurityEvents_InvalidLimit(t *testing.T) {
+	for _, limit := range []string{"0", "101", "ten"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me/security-events?limit="+limit, nil)
+		req = req.WithContext(context.WithValue(req.Context(), httpcontext.UserIDKey, "user-123"))
+
+		w := httptest.NewRecorder()
+		handler := handlers.NewAuthHandler(nil)
+
+		handler.ListSecurityEvents(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s: expected status %d, got %d", limit, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestDecideLoginApproval_MissingFields(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/me/login-approvals/abc", strings.NewReader(`{"device_id":"dev-1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), httpcontext.UserIDKey, "user-123"))
+
+	w := httptest.NewRecorder()
+	handler := handlers.NewAuthHandler(nil)
+
+	handler.DecideLoginApproval(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	for _, field := range []string{"device_secret", "approve"} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("expected %s in validation details, got %s", field, w.Body.String())
+		}
+	}
+}
+
+func TestExchangeSessionHandoff_MissingCode(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/handoffs/token", strings.NewReader(`{"code":"  "}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler := handlers.NewAuthHandler(nil)
+
+	handler.ExchangeSessionHandoff(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "code") {
+		t.Errorf("expected code in validation details, got %s", w.Body.String())
+	}
+}
+
+func TestDeviceToken_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{name: "missing device code", body: `{}`, field: "device_code"},
+		{name: "wrong grant type", body: `{"device_code":"abc","grant_type":"password"}`, field: "grant_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/device/token", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			handlers.NewAuthHandler(nil).DeviceToken(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.field) {
+				t.Errorf("expected %s in validation details, got %s", tt.field, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDevicePage_Form(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/device", nil)
+	w := httptest.NewRecorder()
+
+	handlers.NewAuthHandler(nil).DevicePage(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "frame-ancestors 'none'") {
+		t.Errorf("Content-Security-Policy = %q", csp)
+	}
+	if !strings.Contains(w.Body.String(), `name="user_code"`) {
+		t.Errorf("expected user code field, got %s", w.Body.String())
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
 	"github.com/n1rocket/go-auth-jwt/internal/repository"
 	"github.com/n1rocket/go-auth-jwt/internal/security"
 	"github.com/n1rocket/go-auth-jwt/internal/service"
@@ -30,12 +31,14 @@ func timePtr(t time.Time) *time.Time {
 // Mock implementations
 
 type mockUserRepository struct {
-	createFunc        func(ctx context.Context, user *domain.User) error
-	getByEmailFunc    func(ctx context.Context, email string) (*domain.User, error)
-	getByIDFunc       func(ctx context.Context, id string) (*domain.User, error)
-	updateFunc        func(ctx context.Context, user *domain.User) error
-	deleteFunc        func(ctx context.Context, id string) error
-	existsByEmailFunc func(ctx context.Context, email string) (bool, error)
+	createFunc           func(ctx context.Context, user *domain.User) error
+	getByEmailFunc       func(ctx context.Context, email string) (*domain.User, error)
+	getByIDFunc          func(ctx context.Context, id string) (*domain.User, error)
+	updateFunc           func(ctx context.Context, user *domain.User) error
+	deleteFunc           func(ctx context.Context, id string) error
+	existsByEmailFunc    func(ctx context.Context, email string) (bool, error)
+	getByUsernameFunc    func(ctx context.Context, username string) (*domain.User, error)
+	existsByUsernameFunc func(ctx context.Context, username string) (bool, error)
 }
 
 func (m *mockUserRepository) Create(ctx context.Context, user *domain.User) error {
@@ -86,6 +89,24 @@ func (m *mockUserRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockUserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if m != nil && m.getByUsernameFunc != nil {
+		return m.getByUsernameFunc(ctx, username)
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	if m != nil && m.existsByUsernameFunc != nil {
+		return m.existsByUsernameFunc(ctx, username)
+	}
+	return false, nil
+}
+
 func (m *mockUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	if m != nil && m.existsByEmailFunc != nil {
 		return m.existsByEmailFunc(ctx, email)
@@ -151,6 +172,10 @@ func (m *mockRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userI
 	return nil
 }
 
+func (m *mockRefreshTokenRepository) GetActiveCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
 func (m *mockRefreshTokenRepository) DeleteExpired(ctx context.Context) error {
 	if m.deleteExpiredFunc != nil {
 		return m.deleteExpiredFunc(ctx)
@@ -873,6 +898,250 @@ func TestAuthHandler_GetCurrentUser(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_GetCurrentUser_Fields(t *testing.T) {
+	h := NewAuthHandler(createTestAuthService(nil, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me?fields=id,email_verified", nil)
+	req = req.WithContext(WithUserID(req.Context(), "user-123"))
+	w := httptest.NewRecorder()
+	h.GetCurrentUser(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); body != "{\"email_verified\":true,\"id\":\"user-123\"}\n" {
+		t.Errorf("Expected only the selected fields, got %s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/auth/me?fields=id,password_hash", nil)
+	req = req.WithContext(WithUserID(req.Context(), "user-123"))
+	w = httptest.NewRecorder()
+	h.GetCurrentUser(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown field, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_UserInfo(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		userRepo       *mockUserRepository
+		expectedStatus int
+	}{
+		{
+			name:           "successful userinfo",
+			userID:         "user-123",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing user context",
+			userID:         "",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "user not found",
+			userID: "user-123",
+			userRepo: &mockUserRepository{
+				getByIDFunc: func(ctx context.Context, id string) (*domain.User, error) {
+					return nil, domain.ErrUserNotFound
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := createTestAuthService(tt.userRepo, nil)
+			h := NewAuthHandler(authService)
+
+			req := httptest.NewRequest("GET", "/auth/userinfo", nil)
+			if tt.userID != "" {
+				ctx := context.WithValue(req.Context(), httpcontext.UserIDKey, tt.userID)
+				req = req.WithContext(ctx)
+			}
+
+			w := httptest.NewRecorder()
+
+			h.UserInfo(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp UserInfoResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if resp.Sub != tt.userID {
+					t.Errorf("Expected sub %s, got %s", tt.userID, resp.Sub)
+				}
+				if resp.UpdatedAt == 0 {
+					t.Error("Expected updated_at to be set")
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_UpdateCurrentUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		body           string
+		userRepo       *mockUserRepository
+		expectedStatus int
+		wantLocale     string
+	}{
+		{
+			name:           "successful update",
+			userID:         "user-123",
+			body:           `{"display_name":"Jane","locale":"es-mx","timezone":"America/Mexico_City"}`,
+			expectedStatus: http.StatusOK,
+			wantLocale:     "es-MX",
+		},
+		{
+			name:           "invalid timezone",
+			userID:         "user-123",
+			body:           `{"timezone":"Mars/Olympus"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "insecure avatar url",
+			userID:         "user-123",
+			body:           `{"avatar_url":"http://example.com/a.png"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid json",
+			userID:         "user-123",
+			body:           `{"locale":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing user context",
+			body:           `{"locale":"en"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "user not found",
+			userID: "user-123",
+			body:   `{"locale":"en"}`,
+			userRepo: &mockUserRepository{
+				getByIDFunc: func(ctx context.Context, id string) (*domain.User, error) {
+					return nil, domain.ErrUserNotFound
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := createTestAuthService(tt.userRepo, nil)
+			h := NewAuthHandler(authService)
+
+			req := httptest.NewRequest("PATCH", "/auth/me", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.userID != "" {
+				ctx := context.WithValue(req.Context(), httpcontext.UserIDKey, tt.userID)
+				req = req.WithContext(ctx)
+			}
+
+			w := httptest.NewRecorder()
+
+			h.UpdateCurrentUser(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp UserResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if resp.Locale == nil || *resp.Locale != tt.wantLocale {
+					t.Errorf("Expected locale %s, got %v", tt.wantLocale, resp.Locale)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_UsernameAvailable(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		userRepo       *mockUserRepository
+		expectedStatus int
+		wantAvailable  bool
+	}{
+		{
+			name:           "available",
+			query:          "?username=Jane_Doe",
+			expectedStatus: http.StatusOK,
+			wantAvailable:  true,
+		},
+		{
+			name:  "taken",
+			query: "?username=jane_doe",
+			userRepo: &mockUserRepository{
+				existsByUsernameFunc: func(ctx context.Context, username string) (bool, error) {
+					return username == "jane_doe", nil
+				},
+			},
+			expectedStatus: http.StatusOK,
+			wantAvailable:  false,
+		},
+		{
+			name:           "reserved",
+			query:          "?username=admin",
+			expectedStatus: http.StatusOK,
+			wantAvailable:  false,
+		},
+		{
+			name:           "invalid format",
+			query:          "?username=a@b",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing username",
+			query:          "",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := createTestAuthService(tt.userRepo, nil)
+			h := NewAuthHandler(authService)
+
+			req := httptest.NewRequest("GET", "/auth/username-available"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.UsernameAvailable(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp UsernameAvailabilityResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if resp.Available != tt.wantAvailable {
+					t.Errorf("Expected available %v, got %v", tt.wantAvailable, resp.Available)
+				}
+			}
+		})
+	}
+}
+
 func TestGetClientIP(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -951,3 +1220,187 @@ func TestGetClientIP(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthHandler_LoginTokenResponseFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        TokenResponseFormat // handler default; native when empty
+		header        string
+		version       int // API version of the matched route; unversioned when 0
+		wantNoStore   bool
+		unexpectedKey string
+	}{
+		{
+			name:          "native by default",
+			unexpectedKey: "scope",
+		},
+		{
+			name:        "oauth2 negotiated via header",
+			header:      "oauth2",
+			wantNoStore: true,
+		},
+		{
+			name:          "unknown format falls back to default",
+			header:        "soap",
+			unexpectedKey: "scope",
+		},
+		{
+			name:        "oauth2 as handler default",
+			format:      TokenFormatOAuth2,
+			wantNoStore: true,
+		},
+		{
+			name:          "native negotiated over oauth2 default",
+			format:        TokenFormatOAuth2,
+			header:        "native",
+			unexpectedKey: "scope",
+		},
+		{
+			name:        "oauth2 by default on version 2",
+			version:     APIVersion2,
+			wantNoStore: true,
+		},
+		{
+			name:          "native negotiated on version 2",
+			header:        "native",
+			version:       APIVersion2,
+			unexpectedKey: "scope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher := security.NewPasswordHasher(10)
+			hash, _ := hasher.Hash("password123")
+			userRepo := &mockUserRepository{
+				getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+					return &domain.User{
+						ID:            "user-123",
+						Email:         email,
+						EmailVerified: true,
+						PasswordHash:  hash,
+					}, nil
+				},
+			}
+
+			h := NewAuthHandler(createTestAuthService(userRepo, &mockRefreshTokenRepository{}))
+			if tt.format != "" {
+				h.SetTokenResponseFormat(tt.format)
+			}
+
+			body := bytes.NewBufferString(`{"email":"test@example.com","password":"password123"}`)
+			req := httptest.NewRequest("POST", "/auth/login", body)
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set(TokenFormatHeader, tt.header)
+			}
+			if tt.version != 0 {
+				req = req.WithContext(context.WithValue(req.Context(), httpcontext.APIVersionKey, tt.version))
+			}
+			w := httptest.NewRecorder()
+
+			h.Login(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			if got := w.Header().Get("Cache-Control") == "no-store"; got != tt.wantNoStore {
+				t.Errorf("Cache-Control no-store = %v, want %v", got, tt.wantNoStore)
+			}
+
+			var resp map[string]interface{}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp["access_token"] == "" || resp["token_type"] != "Bearer" {
+				t.Errorf("Unexpected token response: %v", resp)
+			}
+			// expires_in is the lifetime of the access token, not of the
+			// refresh token
+			if resp["expires_in"] != float64(3600) {
+				t.Errorf("expires_in = %v, want 3600", resp["expires_in"])
+			}
+			if tt.unexpectedKey != "" {
+				if _, ok := resp[tt.unexpectedKey]; ok {
+					t.Errorf("Response should not contain %q", tt.unexpectedKey)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTokenResponseFormat(t *testing.T) {
+	if f, err := ParseTokenResponseFormat(" OAuth2 "); err != nil || f != TokenFormatOAuth2 {
+		t.Errorf("ParseTokenResponseFormat() = %v, %v", f, err)
+	}
+	if _, err := ParseTokenResponseFormat("xml"); err == nil {
+		t.Error("ParseTokenResponseFormat() should reject unknown formats")
+	}
+}
+
+func TestAuthHandler_SignupEnumerationSafe(t *testing.T) {
+	tests := []struct {
+		name           string
+		password       string
+		userRepo       *mockUserRepository
+		expectedStatus int
+	}{
+		{
+			name:           "new email",
+			password:       "Password123!",
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:     "registered email",
+			password: "Password123!",
+			userRepo: &mockUserRepository{
+				existsByEmailFunc: func(ctx context.Context, email string) (bool, error) {
+					return true, nil
+				},
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "weak password",
+			password:       "weak",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := createTestAuthService(tt.userRepo, nil)
+			authService.SetEnumerationSafe(true)
+			h := NewAuthHandler(authService)
+
+			body, _ := json.Marshal(map[string]string{
+				"email":    "test@example.com",
+				"password": tt.password,
+			})
+			req := httptest.NewRequest("POST", "/auth/signup", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			h.Signup(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Code != http.StatusAccepted {
+				return
+			}
+
+			var resp SignupResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.UserID != "" {
+				t.Errorf("Expected no user_id, got %q", resp.UserID)
+			}
+			if resp.Message != enumerationSafeSignupMessage {
+				t.Errorf("Expected generic message, got %q", resp.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// ClientHashParamsResponse tells the client how to derive a password
+// verifier
+type ClientHashParamsResponse struct {
+	Algorithm  string `json:"algorithm"`
+	Salt       string `json:"salt"`
+	Iterations int    `json:"iterations"`
+}
+
+func newClientHashParamsResponse(params security.ClientHashParams) ClientHashParamsResponse {
+	return ClientHashParamsResponse{
+		Algorithm:  security.ClientHashAlgorithm,
+		Salt:       params.Salt,
+		Iterations: params.Iterations,
+	}
+}
+
+// LoginParams returns the params the client derives the login verifier
+// with. Every address gets params, so the response does not reveal which
+// accounts exist.
+func (h *AuthHandler) LoginParams(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email": req.Email,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	params, err := h.authService.ClientHashParams(r.Context(), req.Email)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newClientHashParamsResponse(params))
+}
+
+// PasswordResetGrantRequest represents the request to exchange a reset code
+// for a grant
+type PasswordResetGrantRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// PasswordResetGrantResponse carries the grant and the params the new
+// verifier has to be derived with
+type PasswordResetGrantResponse struct {
+	Grant string `json:"grant"`
+	ClientHashParamsResponse
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GrantPasswordReset exchanges a password reset code for a grant to set a
+// client-hashed password
+func (h *AuthHandler) GrantPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetGrantRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	req.Code = strings.TrimSpace(req.Code)
+
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email": req.Email,
+		"code":  req.Code,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	grant, err := h.authService.GrantPasswordReset(r.Context(), service.ResetGrantInput{
+		Email: req.Email,
+		Code:  req.Code,
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, PasswordResetGrantResponse{
+		Grant:                    grant.Grant,
+		ClientHashParamsResponse: newClientHashParamsResponse(grant.Params),
+		ExpiresAt:                grant.ExpiresAt,
+	})
+}
+
+// ClientPasswordResetRequest represents the request to set a client-hashed
+// password with a reset grant
+type ClientPasswordResetRequest struct {
+	Email            string `json:"email"`
+	Grant            string `json:"grant"`
+	PasswordVerifier string `json:"password_verifier"`
+}
+
+// ResetClientHashedPassword sets the verifier derived from the new password
+// with the grant's params and signs the user out everywhere
+func (h *AuthHandler) ResetClientHashedPassword(w http.ResponseWriter, r *http.Request) {
+	var req ClientPasswordResetRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	req.Grant = strings.TrimSpace(req.Grant)
+
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email":             req.Email,
+		"grant":             req.Grant,
+		"password_verifier": req.PasswordVerifier,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if err := h.authService.ResetClientHashedPassword(r.Context(), service.ClientPasswordResetInput{
+		Email:    req.Email,
+		Grant:    req.Grant,
+		Verifier: req.PasswordVerifier,
+	}); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Password reset; signed out from all devices",
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// ConnectionTokenRequest represents a connection token request. The body is
+// optional.
+type ConnectionTokenRequest struct {
+	Audience string `json:"audience,omitempty"`
+}
+
+// ConnectionTokenResponse is a token that opens a WebSocket or SSE connection
+type ConnectionTokenResponse struct {
+	ConnectionToken string `json:"connection_token"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// CreateConnectionToken issues the caller a short-lived token for a
+// WebSocket or SSE handshake, so the socket URL carries a token that can do
+// nothing else instead of the access token
+func (h *AuthHandler) CreateConnectionToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req ConnectionTokenRequest
+	if r.ContentLength != 0 {
+		if err := request.ValidateJSONRequest(r, &req); err != nil {
+			response.WriteError(w, err)
+			return
+		}
+	}
+	req.Audience = strings.TrimSpace(req.Audience)
+	if len(req.Audience) > 255 {
+		response.WriteValidationError(w, []response.ValidationError{
+			{Field: "audience", Message: "audience must be at most 255 characters", Code: "TOO_LONG"},
+		})
+		return
+	}
+
+	output, err := h.authService.IssueConnectionToken(userID, req.Audience)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.WriteJSON(w, http.StatusOK, ConnectionTokenResponse{
+		ConnectionToken: output.Token,
+		ExpiresIn:       output.ExpiresIn,
+	})
+}
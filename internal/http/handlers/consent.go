@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// ConsentRequest accepts the current terms of service, grants or withdraws
+// marketing consent, or both
+type ConsentRequest struct {
+	TermsVersion     string `json:"terms_version,omitempty"`
+	MarketingConsent *bool  `json:"marketing_consent,omitempty"`
+}
+
+// ConsentResponse represents one consent answer in API responses
+type ConsentResponse struct {
+	Kind      string    `json:"kind"`
+	Version   string    `json:"version,omitempty"`
+	Granted   bool      `json:"granted"`
+	IPAddress *string   `json:"ip_address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConsentsResponse is the caller's current consent and every answer they
+// gave, oldest first
+type ConsentsResponse struct {
+	TermsVersion         string            `json:"terms_version"`
+	AcceptedTermsVersion string            `json:"accepted_terms_version,omitempty"`
+	TermsAcceptedAt      *time.Time        `json:"terms_accepted_at,omitempty"`
+	TermsAccepted        bool              `json:"terms_accepted"`
+	MarketingConsent     bool              `json:"marketing_consent"`
+	History              []ConsentResponse `json:"history"`
+}
+
+func newConsentsResponse(status *service.ConsentStatus) ConsentsResponse {
+	resp := ConsentsResponse{
+		TermsVersion:         status.TermsVersion,
+		AcceptedTermsVersion: status.AcceptedTermsVersion,
+		TermsAcceptedAt:      status.TermsAcceptedAt,
+		TermsAccepted:        status.AcceptedTermsVersion == status.TermsVersion,
+		MarketingConsent:     status.MarketingConsent,
+		History:              make([]ConsentResponse, 0, len(status.History)),
+	}
+	for _, consent := range status.History {
+		resp.History = append(resp.History, newConsentResponse(consent))
+	}
+	return resp
+}
+
+func newConsentResponse(consent *domain.Consent) ConsentResponse {
+	return ConsentResponse{
+		Kind:      string(consent.Kind),
+		Version:   consent.Version,
+		Granted:   consent.Granted,
+		IPAddress: consent.IPAddress,
+		CreatedAt: consent.CreatedAt,
+	}
+}
+
+// GetConsents returns the caller's terms of service and marketing consent
+// with the history of their answers
+func (h *AuthHandler) GetConsents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	status, err := h.authService.Consents(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newConsentsResponse(status))
+}
+
+// RecordConsent records the caller's answer, such as accepting a new terms
+// of service version, and returns their consent
+func (h *AuthHandler) RecordConsent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req ConsentRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	req.TermsVersion = strings.TrimSpace(req.TermsVersion)
+	if req.TermsVersion == "" && req.MarketingConsent == nil {
+		response.WriteValidationError(w, []response.ValidationError{
+			{Field: "terms_version", Message: "terms_version is required", Code: "REQUIRED"},
+		})
+		return
+	}
+
+	ipAddress := getClientIP(r)
+	err := h.authService.RecordConsent(r.Context(), service.ConsentInput{
+		UserID:           userID,
+		TermsVersion:     req.TermsVersion,
+		MarketingConsent: req.MarketingConsent,
+		IPAddress:        &ipAddress,
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	status, err := h.authService.Consents(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newConsentsResponse(status))
+}
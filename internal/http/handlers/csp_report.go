@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
+)
+
+// CSPReportPath is where browsers send Content Security Policy violation
+// reports
+const CSPReportPath = "/api/v1/csp-reports"
+
+// maxCSPReportSize caps a violation report body. Browsers batch reports, but
+// a batch is a few kilobytes at most.
+const maxCSPReportSize = 64 << 10
+
+// cspViolation is a violation report in either format browsers send
+type cspViolation struct {
+	DocumentURL string
+	Directive   string
+	BlockedURL  string
+	Disposition string
+}
+
+// cspReport is the report-uri format (application/csp-report)
+type cspReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport is a report of the Reporting API format
+// (application/reports+json), which browsers send in batches
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+// CSPReports ingests the Content Security Policy violation reports browsers
+// send, logging each and counting it by directive when httpMetrics is set.
+// Reports come from browsers rather than API clients, so a malformed report
+// is dropped rather than answered with an error.
+func CSPReports(httpMetrics *metrics.HTTPMetrics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportSize))
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		for _, v := range parseCSPReports(r.Header.Get("Content-Type"), body) {
+			slog.WarnContext(r.Context(), "content security policy violation",
+				"document_uri", v.DocumentURL,
+				"directive", v.Directive,
+				"blocked_uri", v.BlockedURL,
+				"disposition", v.Disposition,
+			)
+			if httpMetrics != nil {
+				httpMetrics.RecordCSPViolation(v.Directive)
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// parseCSPReports decodes the violations of a report body by its content
+// type, skipping reports that are not CSP violations
+func parseCSPReports(contentType string, body []byte) []cspViolation {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/reports+json" {
+		var reports []reportingAPIReport
+		if err := json.Unmarshal(body, &reports); err != nil {
+			return nil
+		}
+		violations := make([]cspViolation, 0, len(reports))
+		for _, report := range reports {
+			if report.Type != "csp-violation" {
+				continue
+			}
+			violations = append(violations, cspViolation{
+				DocumentURL: report.Body.DocumentURL,
+				Directive:   report.Body.EffectiveDirective,
+				BlockedURL:  report.Body.BlockedURL,
+				Disposition: report.Body.Disposition,
+			})
+		}
+		return violations
+	}
+
+	// application/csp-report, which some browsers send as application/json
+	var report cspReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil
+	}
+	directive := report.Report.EffectiveDirective
+	if directive == "" {
+		// Older browsers only send the directive with its sources
+		directive, _, _ = strings.Cut(report.Report.ViolatedDirective, " ")
+	}
+	if directive == "" {
+		return nil
+	}
+	return []cspViolation{{
+		DocumentURL: report.Report.DocumentURI,
+		Directive:   directive,
+		BlockedURL:  report.Report.BlockedURI,
+		Disposition: report.Report.Disposition,
+	}}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
+)
+
+func TestCSPReports(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        map[string]int64
+	}{
+		{
+			name:        "report-uri",
+			contentType: "application/csp-report",
+			body:        `{"csp-report":{"document-uri":"https://auth.example.com/device","violated-directive":"script-src 'self'","blocked-uri":"inline","disposition":"report"}}`,
+			want:        map[string]int64{"script-src": 1},
+		},
+		{
+			name:        "reporting api",
+			contentType: "application/reports+json",
+			body: `[
+				{"type":"csp-violation","body":{"documentURL":"https://auth.example.com/device","effectiveDirective":"img-src","blockedURL":"https://cdn.example.com/x.png","disposition":"report"}},
+				{"type":"deprecation","body":{"id":"x"}},
+				{"type":"csp-violation","body":{"documentURL":"https://auth.example.com/device","effectiveDirective":"img-src","blockedURL":"data","disposition":"enforce"}}
+			]`,
+			want: map[string]int64{"img-src": 2},
+		},
+		{
+			name:        "malformed",
+			contentType: "application/csp-report",
+			body:        `{"csp-report":`,
+			want:        map[string]int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpMetrics := metrics.NewHTTPMetrics()
+			req := httptest.NewRequest(http.MethodPost, CSPReportPath, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			CSPReports(httpMetrics)(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+			}
+			var total int64
+			for directive, count := range tt.want {
+				if got := httpMetrics.CSPViolations.WithLabels(map[string]string{"directive": directive}).Value(); got != count {
+					t.Errorf("violations of %s = %d, want %d", directive, got, count)
+				}
+				total += count
+			}
+			if got := httpMetrics.CSPViolations.Value(); got != total {
+				t.Errorf("violations = %v, want %d", got, total)
+			}
+		})
+	}
+}
+
+func TestCSPReports_TooLarge(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, CSPReportPath, strings.NewReader(strings.Repeat("x", maxCSPReportSize+1)))
+	req.Header.Set("Content-Type", "application/csp-report")
+	w := httptest.NewRecorder()
+
+	CSPReports(nil)(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
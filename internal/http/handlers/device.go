@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// DeviceCodeGrantType is the grant_type of device token requests (RFC 8628)
+const DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// maxDeviceFormSize caps the verification form body
+const maxDeviceFormSize = 16 << 10
+
+//go:embed templates/device.html
+var templateFS embed.FS
+
+var deviceTemplate = template.Must(template.ParseFS(templateFS, "templates/device.html"))
+
+// DeviceAuthorizationRequest represents a device authorization request
+type DeviceAuthorizationRequest struct {
+	ClientID string `json:"client_id"`
+}
+
+// DeviceAuthorizationResponse represents the device authorization response
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// StartDeviceAuthorization starts the device authorization grant for a
+// client without a browser
+func (h *AuthHandler) StartDeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	var req DeviceAuthorizationRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.ClientID = strings.TrimSpace(req.ClientID)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"client_id": req.ClientID,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	output, err := h.authService.StartDeviceAuthorization(r.Context(), req.ClientID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, DeviceAuthorizationResponse{
+		DeviceCode:              output.DeviceCode,
+		UserCode:                output.UserCode,
+		VerificationURI:         output.VerificationURI,
+		VerificationURIComplete: output.VerificationURIComplete,
+		ExpiresIn:               output.ExpiresIn,
+		Interval:                output.Interval,
+	})
+}
+
+// DeviceTokenRequest represents a device's poll for tokens
+type DeviceTokenRequest struct {
+	GrantType  string `json:"grant_type,omitempty"` // DeviceCodeGrantType when set
+	DeviceCode string `json:"device_code"`
+}
+
+// DeviceToken returns tokens once the user approved the device. Until then
+// it answers 400 with the RFC 8628 errors authorization_pending, slow_down,
+// access_denied or expired_token.
+func (h *AuthHandler) DeviceToken(w http.ResponseWriter, r *http.Request) {
+	var req DeviceTokenRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.DeviceCode = strings.TrimSpace(req.DeviceCode)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"device_code": req.DeviceCode,
+	})
+	if req.GrantType != "" && req.GrantType != DeviceCodeGrantType {
+		validationErrors = append(validationErrors, response.ValidationError{
+			Field:   "grant_type",
+			Message: "grant_type must be " + DeviceCodeGrantType,
+		})
+	}
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	userAgent := r.Header.Get("User-Agent")
+	ipAddress := getClientIP(r)
+
+	output, err := h.authService.PollDeviceToken(r.Context(), service.PollDeviceTokenInput{
+		DeviceCode:     req.DeviceCode,
+		UserAgent:      &userAgent,
+		IPAddress:      &ipAddress,
+		CertThumbprint: clientCertThumbprint(r),
+		DPoP:           dpopProof(r),
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	writeTokenResponse(w, r, h.tokenFormat, LoginResponse{
+		AccessToken:  output.AccessToken,
+		RefreshToken: output.RefreshToken,
+		IDToken:      output.IDToken,
+		TokenType:    tokenType(output),
+		ExpiresIn:    output.ExpiresIn,
+	})
+}
+
+// VerifyDeviceRequest represents a signed-in user's decision on a device
+type VerifyDeviceRequest struct {
+	UserCode string `json:"user_code"`
+	Approve  bool   `json:"approve"`
+}
+
+// VerifyDevice approves or denies a device for the caller, for apps that
+// already hold a session
+func (h *AuthHandler) VerifyDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req VerifyDeviceRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"user_code": strings.TrimSpace(req.UserCode),
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if err := h.authService.DecideDeviceAuthorization(r.Context(), userID, req.UserCode, req.Approve); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// devicePage is the data rendered by the device verification page
+type devicePage struct {
+	UserCode string
+	Email    string
+	ClientID string
+	Error    string
+	Done     bool
+	Approved bool
+}
+
+// DevicePage serves the verification page where users enter the code shown
+// on their device, sign in and approve or deny it. The page works without
+// JavaScript or a session, so it can be opened on any browser.
+func (h *AuthHandler) DevicePage(w http.ResponseWriter, r *http.Request) {
+	page := devicePage{UserCode: r.URL.Query().Get("user_code")}
+	status := http.StatusOK
+
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxDeviceFormSize)
+		if err := r.ParseForm(); err != nil {
+			writeDevicePage(w, r, http.StatusBadRequest, devicePage{Error: "The form could not be read. Please try again."})
+			return
+		}
+		page.UserCode = r.PostForm.Get("user_code")
+		page.Email = strings.TrimSpace(r.PostForm.Get("email"))
+		approve := r.PostForm.Get("action") == "approve"
+
+		userAgent := r.Header.Get("User-Agent")
+		ipAddress := getClientIP(r)
+		err := h.authService.DecideDeviceAuthorizationWithPassword(r.Context(), service.LoginInput{
+			Email:     page.Email,
+			Password:  r.PostForm.Get("password"),
+			SMSCode:   strings.TrimSpace(r.PostForm.Get("sms_code")),
+			UserAgent: &userAgent,
+			IPAddress: &ipAddress,
+		}, page.UserCode, approve)
+		if err == nil {
+			writeDevicePage(w, r, http.StatusOK, devicePage{Done: true, Approved: approve})
+			return
+		}
+		status, page.Error = devicePageError(r, err)
+	}
+
+	// Name the client so users notice codes they did not request
+	if page.UserCode != "" {
+		if auth, err := h.authService.LookupDeviceAuthorization(r.Context(), page.UserCode); err == nil {
+			page.ClientID = auth.ClientID
+		} else if page.Error == "" {
+			status, page.Error = devicePageError(r, err)
+		}
+	}
+
+	writeDevicePage(w, r, status, page)
+}
+
+// devicePageError returns the status and message shown for a failed
+// verification
+func devicePageError(r *http.Request, err error) (int, string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidUserCode):
+		return http.StatusBadRequest, "This code is invalid or has expired. Check the code on your device."
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		return http.StatusUnauthorized, "Incorrect email or password."
+	case errors.Is(err, domain.ErrSMSCodeRequired), errors.Is(err, domain.ErrInvalidEmailCode):
+		return http.StatusUnauthorized, "Enter the current code sent to your phone."
+	case errors.Is(err, domain.ErrTooManyCodeAttempts):
+		return http.StatusTooManyRequests, "Too many incorrect codes. Submit the form without a code to get a new one."
+	case errors.Is(err, domain.ErrLoginBlocked), errors.Is(err, domain.ErrLoginConfirmationRequired), errors.Is(err, domain.ErrCaptchaRequired):
+		return http.StatusForbidden, "This sign-in looks unusual. Sign in to the app first, then try again."
+	case errors.Is(err, domain.ErrAccountDisabled):
+		return http.StatusForbidden, "This account is disabled."
+	default:
+		slog.ErrorContext(r.Context(), "device verification failed", "error", err)
+		return http.StatusInternalServerError, "Something went wrong. Please try again."
+	}
+}
+
+// devicePageCSP is the policy of the verification page, which has inline
+// styles and posts its form to itself
+const devicePageCSP = "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'"
+
+// writeDevicePage renders the verification page. It replaces the API CSP,
+// and any report-only policy meant for it, with its own; the URL may hold the
+// user code, so it is not sent as a referrer.
+func writeDevicePage(w http.ResponseWriter, r *http.Request, status int, page devicePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if !httpcontext.SetContentSecurityPolicy(r.Context(), devicePageCSP, "") {
+		w.Header().Set("Content-Security-Policy", devicePageCSP)
+	}
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := deviceTemplate.Execute(w, page); err != nil {
+		slog.Error("failed to render device page", "error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// DefaultJWKSMaxAge is how long resource servers may cache the signing keys
+// when no other lifetime is configured
+const DefaultJWKSMaxAge = time.Hour
+
+// openAPIMaxAge is how long clients may cache the OpenAPI document. It only
+// changes on deploy, and the ETag makes revalidation cheap.
+const openAPIMaxAge = 5 * time.Minute
+
+// setPublicCache lets clients and shared caches reuse a response for maxAge.
+// Expires is set for HTTP/1.0 caches that ignore Cache-Control.
+func setPublicCache(w http.ResponseWriter, maxAge time.Duration) {
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+	w.Header().Set("Expires", time.Now().Add(maxAge).UTC().Format(http.TimeFormat))
+}
+
+// JWKS serves the public keys that verify access tokens. The ETag follows
+// the key set, so caches revalidating after maxAge get a 304 until the keys
+// change.
+func JWKS(tokenManager *token.Manager, maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jwks, err := tokenManager.GetJWKS()
+		if err != nil {
+			response.WriteError(w, err)
+			return
+		}
+
+		setPublicCache(w, maxAge)
+		response.WriteJSONWithETag(w, r, jwks)
+	}
+}
+
+// OpenAPI serves the OpenAPI document. The ETag follows the document, so it
+// changes with the spec version.
+func OpenAPI(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setPublicCache(w, openAPIMaxAge)
+		response.WriteBodyWithETag(w, r, "application/json", spec)
+	}
+}
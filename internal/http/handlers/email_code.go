@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+// VerifyEmailCodeRequest represents the email verification by code request
+type VerifyEmailCodeRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// VerifyEmailCode verifies an email address with the code sent by email
+func (h *AuthHandler) VerifyEmailCode(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailCodeRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Trim whitespace
+	req.Email = strings.TrimSpace(req.Email)
+	req.Code = strings.TrimSpace(req.Code)
+
+	// Validate required fields
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email": req.Email,
+		"code":  req.Code,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	// Call service
+	if err := h.authService.VerifyEmailCode(r.Context(), service.VerifyEmailCodeInput{
+		Email: req.Email,
+		Code:  req.Code,
+	}); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Return response
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Email verified successfully",
+	})
+}
+
+// PasswordResetRequest represents the password reset request
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// RequestPasswordReset sends a password reset code. The response is the same
+// whether or not the address belongs to an account.
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email": req.Email,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	requestReset := h.authService.RequestPasswordReset
+	if h.emails != nil {
+		requestReset = h.emails.RequestPasswordResetWithEmail
+	}
+	if _, err := requestReset(r.Context(), req.Email); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If the address belongs to an account, a reset code has been sent",
+	})
+}
+
+// ConfirmPasswordResetRequest represents the password reset confirmation request
+type ConfirmPasswordResetRequest struct {
+	Email       string `json:"email"`
+	Code        string `json:"code"`
+	NewPassword string `json:"new_password"`
+}
+
+// ConfirmPasswordReset sets a new password with a reset code and signs the
+// user out everywhere
+func (h *AuthHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmPasswordResetRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	req.Code = strings.TrimSpace(req.Code)
+
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email":        req.Email,
+		"code":         req.Code,
+		"new_password": req.NewPassword,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), service.ResetPasswordInput{
+		Email:       req.Email,
+		Code:        req.Code,
+		NewPassword: req.NewPassword,
+	}); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Password reset; signed out from all devices",
+	})
+}
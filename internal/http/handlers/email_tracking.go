@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// trackingPixel is a transparent 1x1 GIF
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// EmailTrackingRequest sets the caller's email tracking preference
+type EmailTrackingRequest struct {
+	DoNotTrack *bool `json:"do_not_track"`
+}
+
+// EmailTrackingResponse is the caller's email tracking preference
+type EmailTrackingResponse struct {
+	DoNotTrack bool `json:"do_not_track"`
+}
+
+// TrackEmailClick counts a click on a link in an email and redirects to the
+// link's target. Only links signed by this server are followed.
+func (h *AuthHandler) TrackEmailClick(w http.ResponseWriter, r *http.Request) {
+	target, err := h.authService.EmailTracker().Click(r.URL.Query())
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Every click has to reach the server to be counted
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// TrackEmailOpen counts an opened email and returns a transparent pixel
+func (h *AuthHandler) TrackEmailOpen(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.EmailTracker().Open(r.URL.Query()); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(trackingPixel)
+}
+
+// GetEmailTracking returns whether the caller opted out of email tracking
+func (h *AuthHandler) GetEmailTracking(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	optedOut, err := h.authService.EmailTrackingOptedOut(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, EmailTrackingResponse{DoNotTrack: optedOut})
+}
+
+// UpdateEmailTracking opts the caller out of email tracking or back in.
+// Emails to users who opted out carry plain links and no pixel, and are left
+// out of the conversion metrics.
+func (h *AuthHandler) UpdateEmailTracking(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req EmailTrackingRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	if req.DoNotTrack == nil {
+		response.WriteValidationError(w, []response.ValidationError{
+			{Field: "do_not_track", Message: "do_not_track is required", Code: "REQUIRED"},
+		})
+		return
+	}
+
+	if err := h.authService.SetEmailTrackingOptOut(r.Context(), userID, *req.DoNotTrack); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, EmailTrackingResponse{DoNotTrack: *req.DoNotTrack})
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"runtime"
+	"sync"
 
 	"github.com/n1rocket/go-auth-jwt/internal/http/response"
 )
@@ -35,3 +37,79 @@ func Ready(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// Readiness is a readiness probe that components can mark degraded, e.g. a
+// backed-up email queue, so orchestrators stop routing traffic to the
+// instance until it recovers
+type Readiness struct {
+	mu       sync.RWMutex
+	degraded map[string]bool
+}
+
+// NewReadiness creates a readiness probe with no degraded components
+func NewReadiness() *Readiness {
+	return &Readiness{degraded: make(map[string]bool)}
+}
+
+// SetDegraded marks component as degraded or recovered
+func (rd *Readiness) SetDegraded(component string, degraded bool) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if degraded {
+		rd.degraded[component] = true
+	} else {
+		delete(rd.degraded, component)
+	}
+}
+
+// Handler serves the readiness check: 200 as Ready does, or 503 with status
+// "degraded" while any component is degraded
+func (rd *Readiness) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		services := map[string]string{
+			"database": "ok",
+			"auth":     "ok",
+		}
+
+		rd.mu.RLock()
+		for component := range rd.degraded {
+			services[component] = "degraded"
+		}
+		degraded := len(rd.degraded) > 0
+		rd.mu.RUnlock()
+
+		if degraded {
+			response.WriteJSON(w, http.StatusServiceUnavailable, ReadyResponse{
+				Status:   "degraded",
+				Services: services,
+			})
+			return
+		}
+		response.WriteJSON(w, http.StatusOK, ReadyResponse{
+			Status:   "ready",
+			Services: services,
+		})
+	}
+}
+
+// BuildVersion is the service version reported by /version
+var BuildVersion = "dev"
+
+// FIPSMode is reported by /version so operators can confirm the crypto mode
+var FIPSMode bool
+
+// VersionResponse represents the version endpoint response
+type VersionResponse struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	FIPSMode  bool   `json:"fips_mode"`
+}
+
+// Version reports the build version and crypto mode
+func Version(w http.ResponseWriter, r *http.Request) {
+	response.WriteJSON(w, http.StatusOK, VersionResponse{
+		Version:   BuildVersion,
+		GoVersion: runtime.Version(),
+		FIPSMode:  FIPSMode,
+	})
+}
@@ -50,6 +50,37 @@ func TestReady(t *testing.T) {
 	}
 }
 
+func TestReadiness_Degraded(t *testing.T) {
+	readiness := handlers.NewReadiness()
+	probe := func() (int, handlers.ReadyResponse) {
+		w := httptest.NewRecorder()
+		readiness.Handler()(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		var response handlers.ReadyResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return w.Code, response
+	}
+
+	if code, response := probe(); code != http.StatusOK || response.Status != "ready" {
+		t.Errorf("probe = %d %q, want 200 ready", code, response.Status)
+	}
+
+	readiness.SetDegraded("email_queue", true)
+	code, response := probe()
+	if code != http.StatusServiceUnavailable || response.Status != "degraded" {
+		t.Errorf("probe = %d %q, want 503 degraded", code, response.Status)
+	}
+	if response.Services["email_queue"] != "degraded" || response.Services["database"] != "ok" {
+		t.Errorf("services = %v", response.Services)
+	}
+
+	readiness.SetDegraded("email_queue", false)
+	if code, response := probe(); code != http.StatusOK || response.Status != "ready" {
+		t.Errorf("probe after recovery = %d %q, want 200 ready", code, response.Status)
+	}
+}
+
 func TestHealth_MultipleCallsConsistent(t *testing.T) {
 	// Test that multiple calls to Health return consistent results
 	for i := 0; i < 5; i++ {
@@ -121,3 +152,27 @@ func TestReady_ContentNegotiation(t *testing.T) {
 		})
 	}
 }
+
+func TestVersion(t *testing.T) {
+	oldVersion, oldFIPS := handlers.BuildVersion, handlers.FIPSMode
+	defer func() { handlers.BuildVersion, handlers.FIPSMode = oldVersion, oldFIPS }()
+	handlers.BuildVersion = "v1.2.3"
+	handlers.FIPSMode = true
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	w := httptest.NewRecorder()
+
+	handlers.Version(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response handlers.VersionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Version != "v1.2.3" || !response.FIPSMode || response.GoVersion == "" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
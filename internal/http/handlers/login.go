@@ -39,10 +39,15 @@ func (h *LoginHandler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	// Create service input
 	input := service.LoginInput{
-		Email:     req.Email,
-		Password:  req.Password,
-		IPAddress: &clientIP,
-		UserAgent: &userAgent,
+		Email:          req.Email,
+		Username:       req.Username,
+		Password:       req.Password,
+		IPAddress:      &clientIP,
+		UserAgent:      &userAgent,
+		CaptchaToken:   req.CaptchaToken,
+		SMSCode:        req.SMSCode,
+		CertThumbprint: clientCertThumbprint(r),
+		DPoP:           dpopProof(r),
 	}
 
 	// Call service
@@ -56,7 +61,8 @@ func (h *LoginHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	response.NewBuilder(w).Success(loginResponseData{
 		AccessToken:  output.AccessToken,
 		RefreshToken: output.RefreshToken,
-		TokenType:    "Bearer",
+		IDToken:      output.IDToken,
+		TokenType:    tokenType(output),
 		ExpiresIn:    output.ExpiresIn,
 	})
 }
@@ -65,6 +71,7 @@ func (h *LoginHandler) Handle(w http.ResponseWriter, r *http.Request) {
 type loginResponseData struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token,omitempty"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int64  `json:"expires_in"`
 }
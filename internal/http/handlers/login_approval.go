@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// TrustedDeviceResponse represents a trusted device in API responses
+type TrustedDeviceResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// Secret is only returned when the device is registered
+	Secret string `json:"secret,omitempty"`
+}
+
+func newTrustedDeviceResponse(device *domain.TrustedDevice) TrustedDeviceResponse {
+	return TrustedDeviceResponse{
+		ID:         device.ID,
+		Name:       device.Name,
+		CreatedAt:  device.CreatedAt,
+		LastUsedAt: device.LastUsedAt,
+	}
+}
+
+// RegisterTrustedDeviceRequest represents the request to register a trusted device
+type RegisterTrustedDeviceRequest struct {
+	Name string `json:"name"`
+}
+
+// RegisterTrustedDevice registers a device of the caller that can approve
+// logins. The returned secret must be kept on the device.
+func (h *AuthHandler) RegisterTrustedDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req RegisterTrustedDeviceRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"name": req.Name,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+	if len(req.Name) > 100 {
+		response.WriteValidationError(w, []response.ValidationError{
+			{Field: "name", Message: "name must be at most 100 characters", Code: "TOO_LONG"},
+		})
+		return
+	}
+
+	output, err := h.authService.RegisterTrustedDevice(r.Context(), userID, req.Name)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := newTrustedDeviceResponse(output.Device)
+	resp.Secret = output.Secret
+	response.WriteJSON(w, http.StatusCreated, resp)
+}
+
+// ListTrustedDevices returns the caller's trusted devices
+func (h *AuthHandler) ListTrustedDevices(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	devices, err := h.authService.ListTrustedDevices(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := make([]TrustedDeviceResponse, 0, len(devices))
+	for _, device := range devices {
+		resp = append(resp, newTrustedDeviceResponse(device))
+	}
+	response.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"devices": resp,
+	})
+}
+
+// RemoveTrustedDevice removes one of the caller's trusted devices
+func (h *AuthHandler) RemoveTrustedDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	params := request.NewParams(r)
+	deviceID := params.PathUUID("id")
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	if err := h.authService.RemoveTrustedDevice(r.Context(), userID, deviceID); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartLoginApprovalRequest represents the request to log in with a trusted
+// device's approval
+type StartLoginApprovalRequest struct {
+	Email string `json:"email"`
+}
+
+// StartLoginApprovalResponse identifies a login waiting for approval
+type StartLoginApprovalResponse struct {
+	ApprovalID string `json:"approval_id"`
+	PollToken  string `json:"poll_token"`
+	ExpiresIn  int64  `json:"expires_in"`
+	Interval   int64  `json:"interval"`
+}
+
+// StartLoginApproval asks the account's trusted devices to approve a login.
+// The client then polls PollLoginApproval with the returned poll token.
+func (h *AuthHandler) StartLoginApproval(w http.ResponseWriter, r *http.Request) {
+	var req StartLoginApprovalRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email": req.Email,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	userAgent := r.Header.Get("User-Agent")
+	ipAddress := getClientIP(r)
+
+	output, err := h.authService.StartLoginApproval(r.Context(), service.StartLoginApprovalInput{
+		Email:     req.Email,
+		UserAgent: &userAgent,
+		IPAddress: &ipAddress,
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusAccepted, StartLoginApprovalResponse{
+		ApprovalID: output.ApprovalID,
+		PollToken:  output.PollToken,
+		ExpiresIn:  output.ExpiresIn,
+		Interval:   output.Interval,
+	})
+}
+
+// PollLoginApprovalRequest represents a waiting client's poll
+type PollLoginApprovalRequest struct {
+	PollToken string `json:"poll_token"`
+}
+
+// PollLoginApproval returns tokens once a trusted device approved the login,
+// and 202 with status pending until then
+func (h *AuthHandler) PollLoginApproval(w http.ResponseWriter, r *http.Request) {
+	var req PollLoginApprovalRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	params := request.NewParams(r)
+	approvalID := params.PathUUID("id")
+	req.PollToken = strings.TrimSpace(req.PollToken)
+	validationErrors := append(params.Errors(), request.ValidateRequiredFields(map[string]string{
+		"poll_token": req.PollToken,
+	})...)
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	userAgent := r.Header.Get("User-Agent")
+	ipAddress := getClientIP(r)
+
+	output, err := h.authService.PollLoginApproval(r.Context(), service.PollLoginApprovalInput{
+		ApprovalID:     approvalID,
+		PollToken:      req.PollToken,
+		UserAgent:      &userAgent,
+		IPAddress:      &ipAddress,
+		CertThumbprint: clientCertThumbprint(r),
+		DPoP:           dpopProof(r),
+	})
+	if errors.Is(err, domain.ErrLoginApprovalPending) {
+		response.WriteJSON(w, http.StatusAccepted, map[string]string{
+			"status": string(domain.LoginApprovalPending),
+		})
+		return
+	}
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	writeTokenResponse(w, r, h.tokenFormat, LoginResponse{
+		AccessToken:  output.AccessToken,
+		RefreshToken: output.RefreshToken,
+		IDToken:      output.IDToken,
+		TokenType:    tokenType(output),
+		ExpiresIn:    output.ExpiresIn,
+	})
+}
+
+// LoginApprovalResponse represents a pending login shown on a trusted device
+type LoginApprovalResponse struct {
+	ID        string    `json:"id"`
+	IPAddress *string   `json:"ip_address,omitempty"`
+	UserAgent *string   `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ListLoginApprovals returns the caller's logins awaiting approval
+func (h *AuthHandler) ListLoginApprovals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	approvals, err := h.authService.ListLoginApprovals(r.Context(), userID)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := make([]LoginApprovalResponse, 0, len(approvals))
+	for _, approval := range approvals {
+		resp = append(resp, LoginApprovalResponse{
+			ID:        approval.ID,
+			IPAddress: approval.IPAddress,
+			UserAgent: approval.UserAgent,
+			CreatedAt: approval.CreatedAt,
+			ExpiresAt: approval.ExpiresAt,
+		})
+	}
+	response.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"approvals": resp,
+	})
+}
+
+// DecideLoginApprovalRequest represents a trusted device's decision
+type DecideLoginApprovalRequest struct {
+	DeviceID     string `json:"device_id"`
+	DeviceSecret string `json:"device_secret"`
+	Approve      *bool  `json:"approve"`
+}
+
+// DecideLoginApproval approves or denies one of the caller's pending logins
+// from a trusted device
+func (h *AuthHandler) DecideLoginApproval(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req DecideLoginApprovalRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	params := request.NewParams(r)
+	approvalID := params.PathUUID("id")
+	req.DeviceID = strings.TrimSpace(req.DeviceID)
+	validationErrors := append(params.Errors(), request.ValidateRequiredFields(map[string]string{
+		"device_id":     req.DeviceID,
+		"device_secret": req.DeviceSecret,
+	})...)
+	if req.Approve == nil {
+		validationErrors = append(validationErrors, response.ValidationError{
+			Field: "approve", Message: "approve is required", Code: "REQUIRED",
+		})
+	}
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if err := h.authService.DecideLoginApproval(r.Context(), service.DecideLoginApprovalInput{
+		UserID:       userID,
+		ApprovalID:   approvalID,
+		DeviceID:     req.DeviceID,
+		DeviceSecret: req.DeviceSecret,
+		Approve:      *req.Approve,
+	}); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// AddPhoneRequest represents the request to set the caller's phone number
+type AddPhoneRequest struct {
+	PhoneNumber string `json:"phone_number"` // E.164, e.g. +15551234567
+}
+
+// AddPhone sets the caller's phone number and texts it a verification code
+func (h *AuthHandler) AddPhone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req AddPhoneRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"phone_number": req.PhoneNumber,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if err := h.authService.AddPhoneNumber(r.Context(), userID, req.PhoneNumber); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "Verification code sent",
+	})
+}
+
+// VerifyPhoneRequest represents the phone verification request
+type VerifyPhoneRequest struct {
+	Code string `json:"code"`
+}
+
+// VerifyPhone confirms the caller's phone number with the texted code
+func (h *AuthHandler) VerifyPhone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req VerifyPhoneRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Code = strings.TrimSpace(req.Code)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"code": req.Code,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if err := h.authService.VerifyPhoneNumber(r.Context(), userID, req.Code); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Phone number verified",
+	})
+}
+
+// SMSSecondFactorRequest turns the SMS second factor on or off
+type SMSSecondFactorRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// SetSMSSecondFactor turns the caller's SMS second factor on or off
+func (h *AuthHandler) SetSMSSecondFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var req SMSSecondFactorRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	if req.Enabled == nil {
+		response.WriteValidationError(w, []response.ValidationError{
+			{Field: "enabled", Message: "enabled is required", Code: "REQUIRED"},
+		})
+		return
+	}
+
+	if err := h.authService.SetSMSSecondFactor(r.Context(), userID, *req.Enabled); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SMSRecoveryRequest represents the request for an SMS recovery code
+type SMSRecoveryRequest struct {
+	Email string `json:"email"`
+}
+
+// RequestSMSRecovery texts a password reset code to the account's verified
+// phone. The response is the same whether or not one exists.
+func (h *AuthHandler) RequestSMSRecovery(w http.ResponseWriter, r *http.Request) {
+	var req SMSRecoveryRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email": req.Email,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if err := h.authService.RequestSMSRecovery(r.Context(), req.Email); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If the account has a verified phone number, a recovery code has been sent",
+	})
+}
+
+// ConfirmSMSRecovery sets a new password with an SMS recovery code and signs
+// the user out everywhere. It takes the same body as ConfirmPasswordReset.
+func (h *AuthHandler) ConfirmSMSRecovery(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmPasswordResetRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	req.Code = strings.TrimSpace(req.Code)
+
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"email":        req.Email,
+		"code":         req.Code,
+		"new_password": req.NewPassword,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	if err := h.authService.RecoverWithSMS(r.Context(), service.ResetPasswordInput{
+		Email:       req.Email,
+		Code:        req.Code,
+		NewPassword: req.NewPassword,
+	}); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Password reset; signed out from all devices",
+	})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// QuotaHandler reports monthly quota usage
+type QuotaHandler struct {
+	quotas  *service.QuotaService
+	subject func(r *http.Request) string // the quota middleware's key function
+}
+
+// NewQuotaHandler creates a new quota handler. subject must identify the
+// caller the same way the quota middleware does.
+func NewQuotaHandler(quotas *service.QuotaService, subject func(r *http.Request) string) *QuotaHandler {
+	return &QuotaHandler{quotas: quotas, subject: subject}
+}
+
+// QuotaUsageResponse describes the caller's usage in the current period
+type QuotaUsageResponse struct {
+	Subject   string    `json:"subject"`
+	Period    string    `json:"period"` // YYYY-MM
+	Requests  int64     `json:"requests"`
+	Limit     *int64    `json:"limit"`     // null when unlimited
+	Remaining *int64    `json:"remaining"` // null when unlimited
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// Usage returns the caller's quota usage for the current month. Checking
+// usage is not counted against the quota.
+func (h *QuotaHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	subject := h.subject(r)
+	if subject == "" {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	usage, err := h.quotas.Usage(r.Context(), subject)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := QuotaUsageResponse{
+		Subject:  usage.Subject,
+		Period:   usage.Period.Format("2006-01"),
+		Requests: usage.Requests,
+		ResetsAt: usage.ResetsAt(),
+	}
+	if usage.Limit > 0 {
+		limit, remaining := usage.Limit, usage.Remaining()
+		resp.Limit = &limit
+		resp.Remaining = &remaining
+	}
+
+	response.WriteJSON(w, http.StatusOK, resp)
+}
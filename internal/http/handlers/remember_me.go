@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// RememberMeCookie configures the signed cookie that carries the refresh
+// token of a remember-me session. The token never appears in a response
+// body, so scripts on the page cannot read it.
+type RememberMeCookie struct {
+	Name   string
+	Path   string // should cover the refresh and logout endpoints
+	Domain string // host-only when empty
+	Secure bool
+	Signer *security.CookieSigner
+}
+
+// SetRememberMeCookie lets clients ask for a remember-me session at login,
+// kept in cookie. Refresh and logout then accept the cookie in place of a
+// refresh token in the body.
+func (h *AuthHandler) SetRememberMeCookie(cookie *RememberMeCookie) {
+	h.rememberMe = cookie
+}
+
+// set stores a refresh token in the cookie until expiresAt
+func (c *RememberMeCookie) set(w http.ResponseWriter, refreshToken string, expiresAt time.Time) {
+	http.SetCookie(w, c.cookie(c.Signer.Sign(c.Name, refreshToken, expiresAt), expiresAt))
+}
+
+// clear tells the browser to drop the cookie
+func (c *RememberMeCookie) clear(w http.ResponseWriter) {
+	cookie := c.cookie("", time.Unix(0, 0))
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+// read returns the refresh token in the request's cookie. It returns
+// http.ErrNoCookie when there is none, and a security error when the cookie
+// was tampered with or has expired.
+func (c *RememberMeCookie) read(r *http.Request, now time.Time) (string, error) {
+	cookie, err := r.Cookie(c.Name)
+	if err != nil {
+		return "", err
+	}
+	return c.Signer.Verify(c.Name, cookie.Value, now)
+}
+
+func (c *RememberMeCookie) cookie(value string, expiresAt time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		Expires:  expiresAt,
+		Secure:   c.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
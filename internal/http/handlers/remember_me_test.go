@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+)
+
+func TestAuthHandler_RememberMe(t *testing.T) {
+	passwordHash, _ := security.NewPasswordHasher(10).Hash("Password123!")
+	user := &domain.User{ID: "user-123", Email: "test@example.com", EmailVerified: true, PasswordHash: passwordHash}
+	userRepo := &mockUserRepository{
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) { return user, nil },
+		getByIDFunc:    func(ctx context.Context, id string) (*domain.User, error) { return user, nil },
+	}
+
+	tokens := make(map[string]*domain.RefreshToken)
+	refreshRepo := &mockRefreshTokenRepository{
+		createFunc: func(ctx context.Context, token *domain.RefreshToken) error {
+			token.Token = fmt.Sprintf("refresh-%d", len(tokens)+1)
+			tokens[token.Token] = token
+			return nil
+		},
+		getByTokenFunc: func(ctx context.Context, token string) (*domain.RefreshToken, error) {
+			if refreshToken, ok := tokens[token]; ok {
+				return refreshToken, nil
+			}
+			return nil, domain.ErrInvalidToken
+		},
+		revokeFunc: func(ctx context.Context, token string) error {
+			tokens[token].Revoked = true
+			return nil
+		},
+	}
+
+	authService := createTestAuthService(userRepo, refreshRepo)
+	authService.SetRememberMe(service.RememberMePolicy{TTL: 30 * 24 * time.Hour})
+	signer, err := security.NewCookieSigner("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("NewCookieSigner() error = %v", err)
+	}
+	handler := NewAuthHandler(authService)
+	handler.SetRememberMeCookie(&RememberMeCookie{Name: "remember_me", Path: "/api", Secure: true, Signer: signer})
+
+	post := func(handle http.HandlerFunc, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handle(w, req)
+		return w
+	}
+	session := func(t *testing.T, w *httptest.ResponseRecorder) (LoginResponse, *http.Cookie) {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		var resp LoginResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("cookies = %v, want the remember-me cookie", cookies)
+		}
+		return resp, cookies[0]
+	}
+
+	// A normal login keeps the refresh token in the body
+	w := post(handler.Login, `{"email":"test@example.com","password":"Password123!"}`, nil)
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("login without remember_me set cookies %v", w.Result().Cookies())
+	}
+
+	resp, cookie := session(t, post(handler.Login, `{"email":"test@example.com","password":"Password123!","remember_me":true}`, nil))
+	if resp.RefreshToken != "" {
+		t.Error("remember-me login returned the refresh token in the body")
+	}
+	if resp.RememberMeExpiresAt == nil || resp.RememberMeExpiresAt.Before(time.Now().Add(29*24*time.Hour)) {
+		t.Errorf("remember_me_expires_at = %v, want the remember-me TTL", resp.RememberMeExpiresAt)
+	}
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode || cookie.Path != "/api" {
+		t.Errorf("cookie = %+v, want HttpOnly, Secure and SameSite=Strict on /api", cookie)
+	}
+
+	// Refreshing from the cookie rotates the token in the cookie
+	resp, rotated := session(t, post(handler.Refresh, `{}`, cookie))
+	if resp.RefreshToken != "" || resp.RememberMeExpiresAt == nil || rotated.Value == cookie.Value {
+		t.Errorf("refresh from the cookie = %+v, cookie %q", resp, rotated.Value)
+	}
+	if w := post(handler.Refresh, `{}`, cookie); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh with the rotated cookie status = %d, want 401", w.Code)
+	}
+
+	// A tampered cookie is rejected and cleared
+	tampered := *rotated
+	tampered.Value = "x" + tampered.Value
+	w = post(handler.Refresh, `{}`, &tampered)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh with a tampered cookie status = %d, want 401", w.Code)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("tampered cookie was not cleared: %v", cookies)
+	}
+
+	// Logging out revokes the session in the cookie and clears it
+	w = post(handler.Logout, `{}`, rotated)
+	if w.Code != http.StatusOK {
+		t.Fatalf("logout status = %d, body %s", w.Code, w.Body.String())
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("logout did not clear the cookie: %v", cookies)
+	}
+	for _, refreshToken := range tokens {
+		if refreshToken.RememberMe && !refreshToken.Revoked {
+			t.Errorf("remember-me session %s still active after logout", refreshToken.Token)
+		}
+	}
+}
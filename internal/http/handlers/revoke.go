@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// maxRevokeFormSize caps a form-encoded revocation request
+const maxRevokeFormSize = 16 << 10
+
+// RevokeRequest is a token revocation request (RFC 7009 section 2.1). Users
+// authenticate with their access token; service accounts with a client
+// assertion, as at the token endpoint.
+type RevokeRequest struct {
+	Token               string `json:"token"`
+	TokenTypeHint       string `json:"token_type_hint,omitempty"` // access_token or refresh_token
+	ClientAssertionType string `json:"client_assertion_type,omitempty"`
+	ClientAssertion     string `json:"client_assertion,omitempty"`
+}
+
+// SetServiceAccounts lets service accounts authenticate token revocation
+// requests with a client assertion
+func (h *AuthHandler) SetServiceAccounts(accounts *service.ServiceAccountService) {
+	h.serviceAccounts = accounts
+}
+
+// Revoke revokes a refresh or access token of the caller. The body is
+// form-encoded, as RFC 7009 specifies, or JSON like the rest of the API. The
+// answer is the same whether or not the token was found, so it does not tell
+// which tokens exist.
+func (h *AuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRevokeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	req.Token = strings.TrimSpace(req.Token)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"token": req.Token,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	// Set by RequireAuth when the request carries an access token
+	caller, ok := r.Context().Value(UserIDContextKey).(string)
+	if !ok {
+		req.ClientAssertion = strings.TrimSpace(req.ClientAssertion)
+		if h.serviceAccounts == nil || req.ClientAssertion == "" || req.ClientAssertionType != token.ClientAssertionType {
+			response.WriteError(w, domain.ErrInvalidClientAssertion)
+			return
+		}
+		account, err := h.serviceAccounts.AuthenticateClient(r.Context(), req.ClientAssertion)
+		if err != nil {
+			response.WriteError(w, err)
+			return
+		}
+		caller = account.ID
+	}
+
+	err := h.authService.RevokeToken(r.Context(), service.RevokeTokenInput{
+		Token:         req.Token,
+		TokenTypeHint: req.TokenTypeHint,
+		Caller:        caller,
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Revocation request processed",
+	})
+}
+
+// decodeRevokeRequest reads a form-encoded or JSON revocation request,
+// answering the request when it cannot be read
+func decodeRevokeRequest(w http.ResponseWriter, r *http.Request) (RevokeRequest, bool) {
+	var req RevokeRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		if err := request.ValidateJSONRequest(r, &req); err != nil {
+			response.WriteError(w, err)
+			return req, false
+		}
+		return req, true
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRevokeFormSize)
+	if err := r.ParseForm(); err != nil {
+		response.WriteJSON(w, http.StatusBadRequest, response.ErrorResponse{
+			Error:   "bad_request",
+			Message: "Invalid request format",
+			Code:    "INVALID_REQUEST",
+		})
+		return req, false
+	}
+	req.Token = r.PostForm.Get("token")
+	req.TokenTypeHint = r.PostForm.Get("token_type_hint")
+	req.ClientAssertionType = r.PostForm.Get("client_assertion_type")
+	req.ClientAssertion = r.PostForm.Get("client_assertion")
+	return req, true
+}
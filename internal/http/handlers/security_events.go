@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// SecurityEventResponse represents a security event in API responses
+type SecurityEventResponse struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason,omitempty"`
+	IPAddress *string   `json:"ip_address,omitempty"`
+	UserAgent *string   `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecurityEventsResponse is a page of the caller's security events
+type SecurityEventsResponse struct {
+	Events     []SecurityEventResponse `json:"events"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+func newSecurityEventResponse(event *domain.SecurityEvent) SecurityEventResponse {
+	return SecurityEventResponse{
+		ID:        event.ID,
+		Type:      event.Type,
+		Reason:    event.Reason,
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+// ListSecurityEvents returns the caller's recent security events, newest
+// first. ?limit sets the page size and ?cursor, the next_cursor of the
+// previous page, fetches older events.
+func (h *AuthHandler) ListSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	params := request.NewParams(r)
+	limit := params.Int("limit", service.DefaultSecurityEventsPageSize, 1, service.MaxSecurityEventsPageSize)
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	page, err := h.authService.ListSecurityEvents(r.Context(), userID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := SecurityEventsResponse{
+		Events:     make([]SecurityEventResponse, 0, len(page.Events)),
+		NextCursor: page.NextCursor,
+	}
+	for _, event := range page.Events {
+		resp.Events = append(resp.Events, newSecurityEventResponse(event))
+	}
+	response.WriteJSON(w, http.StatusOK, resp)
+}
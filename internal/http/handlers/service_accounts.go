@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// ClientCredentialsGrantType is the grant_type of service account token
+// requests
+const ClientCredentialsGrantType = "client_credentials"
+
+// maxServiceAccountNameLength matches the service_accounts.name column
+const maxServiceAccountNameLength = 255
+
+// maxKeyRotationGrace bounds how long a rotated-out key keeps working
+const maxKeyRotationGrace = 7 * 24 * time.Hour
+
+// ServiceAccountHandler serves the service account token endpoint and the
+// admin endpoints managing accounts and their keys
+type ServiceAccountHandler struct {
+	accounts *service.ServiceAccountService
+}
+
+// NewServiceAccountHandler creates a new service account handler
+func NewServiceAccountHandler(accounts *service.ServiceAccountService) *ServiceAccountHandler {
+	return &ServiceAccountHandler{accounts: accounts}
+}
+
+// ServiceTokenRequest authenticates a service account with a signed JWT
+// assertion (RFC 7523 section 2.2)
+type ServiceTokenRequest struct {
+	GrantType           string `json:"grant_type"`
+	ClientAssertionType string `json:"client_assertion_type"`
+	ClientAssertion     string `json:"client_assertion"`
+	Scope               string `json:"scope,omitempty"` // subset of the account's scopes; all of them when empty
+}
+
+// Token issues an access token to the service account that signed the
+// client assertion. There is no refresh token; clients sign a new assertion
+// when the access token expires.
+func (h *ServiceAccountHandler) Token(w http.ResponseWriter, r *http.Request) {
+	var req ServiceTokenRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.ClientAssertion = strings.TrimSpace(req.ClientAssertion)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"client_assertion": req.ClientAssertion,
+	})
+	if req.GrantType != ClientCredentialsGrantType {
+		validationErrors = append(validationErrors, response.ValidationError{
+			Field:   "grant_type",
+			Message: "grant_type must be " + ClientCredentialsGrantType,
+		})
+	}
+	if req.ClientAssertionType != token.ClientAssertionType {
+		validationErrors = append(validationErrors, response.ValidationError{
+			Field:   "client_assertion_type",
+			Message: "client_assertion_type must be " + token.ClientAssertionType,
+		})
+	}
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	output, err := h.accounts.IssueToken(r.Context(), req.ClientAssertion, req.Scope)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	// Token responses must not be cached (RFC 6749 section 5.1)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	response.WriteJSON(w, http.StatusOK, OAuth2TokenResponse{
+		AccessToken: output.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   output.ExpiresIn,
+		Scope:       output.Scope,
+	})
+}
+
+// CreateServiceAccountRequest names a new service account and the scopes it
+// may be granted
+type CreateServiceAccountRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// UpdateServiceAccountScopesRequest replaces a service account's scopes
+type UpdateServiceAccountScopesRequest struct {
+	Scopes []string `json:"scopes"`
+}
+
+// ServiceAccountResponse describes a service account
+type ServiceAccountResponse struct {
+	ID        string                      `json:"id"`
+	Name      string                      `json:"name"`
+	Scopes    []string                    `json:"scopes"`
+	CreatedAt time.Time                   `json:"created_at"`
+	UpdatedAt time.Time                   `json:"updated_at"`
+	Keys      []ServiceAccountKeyResponse `json:"keys,omitempty"` // only when a single account is fetched
+}
+
+// serviceAccountFields are the ServiceAccountResponse fields that may be
+// selected with the fields query parameter when listing accounts
+var serviceAccountFields = []string{"id", "name", "scopes", "created_at", "updated_at"}
+
+// ServiceAccountListResponse lists service accounts, newest first
+type ServiceAccountListResponse struct {
+	ServiceAccounts []ServiceAccountResponse `json:"service_accounts"`
+}
+
+// RegisterServiceAccountKeyRequest carries a public JWK
+type RegisterServiceAccountKeyRequest struct {
+	JWK map[string]interface{} `json:"jwk"`
+}
+
+// RotateServiceAccountKeyRequest replaces a key with a new public JWK. The
+// old key keeps working for GracePeriodSeconds.
+type RotateServiceAccountKeyRequest struct {
+	JWK                map[string]interface{} `json:"jwk"`
+	GracePeriodSeconds int64                  `json:"grace_period_seconds"`
+}
+
+// ServiceAccountKeyResponse describes a registered key. The id is the
+// RFC 7638 thumbprint clients send as the kid header of their assertions.
+type ServiceAccountKeyResponse struct {
+	ID        string                 `json:"id"`
+	JWK       map[string]interface{} `json:"jwk"`
+	CreatedAt time.Time              `json:"created_at"`
+	ExpiresAt *time.Time             `json:"expires_at,omitempty"`
+	Active    bool                   `json:"active"`
+}
+
+// CreateServiceAccount creates a service account
+func (h *ServiceAccountHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	var req CreateServiceAccountRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	name := request.SanitizeString(req.Name, maxServiceAccountNameLength)
+	if errs := request.ValidateRequiredFields(map[string]string{"name": name}); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	account, err := h.accounts.CreateAccount(r.Context(), name, req.Scopes)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, newServiceAccountResponse(account, nil))
+}
+
+// ListServiceAccounts lists all service accounts without their keys. The
+// optional fields query parameter keeps only the listed fields of each
+// account.
+func (h *ServiceAccountHandler) ListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	params := request.NewParams(r)
+	fields := params.Fields("fields", serviceAccountFields...)
+	if errs := params.Errors(); len(errs) > 0 {
+		response.WriteValidationError(w, errs)
+		return
+	}
+
+	accounts, err := h.accounts.ListAccounts(r.Context())
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	resp := ServiceAccountListResponse{ServiceAccounts: make([]ServiceAccountResponse, 0, len(accounts))}
+	for _, account := range accounts {
+		resp.ServiceAccounts = append(resp.ServiceAccounts, newServiceAccountResponse(account, nil))
+	}
+
+	selected, err := response.SelectFields(resp, "service_accounts", fields)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, selected)
+}
+
+// GetServiceAccount returns a service account with its keys
+func (h *ServiceAccountHandler) GetServiceAccount(w http.ResponseWriter, r *http.Request) {
+	account, keys, err := h.accounts.GetAccount(r.Context(), r.PathValue("id"))
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newServiceAccountResponse(account, keys))
+}
+
+// UpdateServiceAccountScopes replaces the scopes of a service account
+func (h *ServiceAccountHandler) UpdateServiceAccountScopes(w http.ResponseWriter, r *http.Request) {
+	var req UpdateServiceAccountScopesRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	account, err := h.accounts.UpdateScopes(r.Context(), r.PathValue("id"), req.Scopes)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newServiceAccountResponse(account, nil))
+}
+
+// DeleteServiceAccount deletes a service account and its keys
+func (h *ServiceAccountHandler) DeleteServiceAccount(w http.ResponseWriter, r *http.Request) {
+	if err := h.accounts.DeleteAccount(r.Context(), r.PathValue("id")); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegisterServiceAccountKey registers a public key for a service account
+func (h *ServiceAccountHandler) RegisterServiceAccountKey(w http.ResponseWriter, r *http.Request) {
+	var req RegisterServiceAccountKeyRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	if req.JWK == nil {
+		response.WriteValidationError(w, []response.ValidationError{{Field: "jwk", Message: "jwk is required", Code: "REQUIRED_FIELD"}})
+		return
+	}
+
+	key, err := h.accounts.AddKey(r.Context(), r.PathValue("id"), req.JWK)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, newServiceAccountKeyResponse(key, time.Now()))
+}
+
+// RotateServiceAccountKey registers a new key and retires an existing one
+// once the grace period is over
+func (h *ServiceAccountHandler) RotateServiceAccountKey(w http.ResponseWriter, r *http.Request) {
+	var req RotateServiceAccountKeyRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	var validationErrors []response.ValidationError
+	if req.JWK == nil {
+		validationErrors = append(validationErrors, response.ValidationError{Field: "jwk", Message: "jwk is required", Code: "REQUIRED_FIELD"})
+	}
+	grace := time.Duration(req.GracePeriodSeconds) * time.Second
+	if req.GracePeriodSeconds < 0 || grace > maxKeyRotationGrace {
+		validationErrors = append(validationErrors, response.ValidationError{
+			Field:   "grace_period_seconds",
+			Message: "grace_period_seconds must be between 0 and 604800",
+		})
+	}
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	key, err := h.accounts.RotateKey(r.Context(), r.PathValue("id"), r.PathValue("kid"), req.JWK, grace)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, newServiceAccountKeyResponse(key, time.Now()))
+}
+
+// RevokeServiceAccountKey stops accepting assertions signed with a key
+func (h *ServiceAccountHandler) RevokeServiceAccountKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.accounts.RevokeKey(r.Context(), r.PathValue("id"), r.PathValue("kid")); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newServiceAccountResponse(account *domain.ServiceAccount, keys []*domain.ServiceAccountKey) ServiceAccountResponse {
+	resp := ServiceAccountResponse{
+		ID:        account.ID,
+		Name:      account.Name,
+		Scopes:    account.Scopes,
+		CreatedAt: account.CreatedAt,
+		UpdatedAt: account.UpdatedAt,
+	}
+	if resp.Scopes == nil {
+		resp.Scopes = []string{}
+	}
+	now := time.Now()
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, newServiceAccountKeyResponse(key, now))
+	}
+	return resp
+}
+
+func newServiceAccountKeyResponse(key *domain.ServiceAccountKey, now time.Time) ServiceAccountKeyResponse {
+	return ServiceAccountKeyResponse{
+		ID:        key.ID,
+		JWK:       key.PublicKey,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
+		Active:    key.IsActive(now),
+	}
+}
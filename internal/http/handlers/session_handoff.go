@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/service"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// SessionHandoffResponse is a handoff code for the caller to show another
+// device, e.g. encoded in a QR code
+type SessionHandoffResponse struct {
+	Code      string    `json:"code"`
+	ExpiresIn int64     `json:"expires_in"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateSessionHandoff returns a one-time code another device exchanges for
+// its own session. A new code invalidates the caller's previous one.
+func (h *AuthHandler) CreateSessionHandoff(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	userAgent := r.Header.Get("User-Agent")
+	ipAddress := getClientIP(r)
+
+	output, err := h.authService.CreateSessionHandoff(r.Context(), service.CreateSessionHandoffInput{
+		UserID:    userID,
+		UserAgent: &userAgent,
+		IPAddress: &ipAddress,
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, SessionHandoffResponse{
+		Code:      output.Code,
+		ExpiresIn: output.ExpiresIn,
+		ExpiresAt: output.ExpiresAt,
+	})
+}
+
+// ExchangeSessionHandoffRequest represents a new device presenting a
+// handoff code
+type ExchangeSessionHandoffRequest struct {
+	Code string `json:"code"`
+}
+
+// ExchangeSessionHandoff issues a token pair to the device presenting a
+// handoff code and tells the user by email when emails are set up
+func (h *AuthHandler) ExchangeSessionHandoff(w http.ResponseWriter, r *http.Request) {
+	var req ExchangeSessionHandoffRequest
+	if err := request.ValidateJSONRequest(r, &req); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	req.Code = strings.TrimSpace(req.Code)
+	validationErrors := request.ValidateRequiredFields(map[string]string{
+		"code": req.Code,
+	})
+	if len(validationErrors) > 0 {
+		response.WriteValidationError(w, validationErrors)
+		return
+	}
+
+	userAgent := r.Header.Get("User-Agent")
+	ipAddress := getClientIP(r)
+
+	exchange := h.authService.ExchangeSessionHandoff
+	if h.emails != nil {
+		exchange = h.emails.ExchangeSessionHandoffWithNotification
+	}
+	output, err := exchange(r.Context(), service.ExchangeSessionHandoffInput{
+		Code:           req.Code,
+		UserAgent:      &userAgent,
+		IPAddress:      &ipAddress,
+		CertThumbprint: clientCertThumbprint(r),
+		DPoP:           dpopProof(r),
+	})
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	writeTokenResponse(w, r, h.tokenFormat, LoginResponse{
+		AccessToken:  output.AccessToken,
+		RefreshToken: output.RefreshToken,
+		IDToken:      output.IDToken,
+		TokenType:    tokenType(output),
+		ExpiresIn:    output.ExpiresIn,
+	})
+}
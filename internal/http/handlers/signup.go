@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
 	"github.com/n1rocket/go-auth-jwt/internal/http/request"
 	"github.com/n1rocket/go-auth-jwt/internal/http/response"
 	"github.com/n1rocket/go-auth-jwt/internal/service"
@@ -32,13 +34,25 @@ func (h *SignupHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create service input
+	ipAddress := getClientIP(r)
 	input := service.SignupInput{
-		Email:    req.Email,
-		Password: req.Password,
+		Email:            req.Email,
+		Password:         req.Password,
+		Username:         req.Username,
+		Locale:           req.Locale,
+		TermsVersion:     req.TermsVersion,
+		MarketingConsent: req.MarketingConsent,
+		IPAddress:        &ipAddress,
 	}
 
 	// Call service
 	output, err := h.authService.Signup(r.Context(), input)
+	if h.authService.EnumerationSafe() && (err == nil || errors.Is(err, domain.ErrDuplicateEmail)) {
+		response.NewBuilder(w).
+			Status(http.StatusAccepted).
+			Success(signupResponseData{Message: enumerationSafeSignupMessage})
+		return
+	}
 	if err != nil {
 		response.WriteError(w, err)
 		return
@@ -55,6 +69,6 @@ func (h *SignupHandler) Handle(w http.ResponseWriter, r *http.Request) {
 
 // signupResponseData represents the signup API response data
 type signupResponseData struct {
-	UserID  string `json:"user_id"`
+	UserID  string `json:"user_id,omitempty"`
 	Message string `json:"message"`
 }
@@ -35,7 +35,9 @@ func (h *TokenHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 
 	// Create service input
 	input := service.RefreshInput{
-		RefreshToken: req.RefreshToken,
+		RefreshToken:   req.RefreshToken,
+		CertThumbprint: clientCertThumbprint(r),
+		DPoP:           dpopProof(r),
 	}
 
 	// Call service
@@ -49,7 +51,8 @@ func (h *TokenHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 	response.NewBuilder(w).Success(refreshResponseData{
 		AccessToken:  output.AccessToken,
 		RefreshToken: output.RefreshToken,
-		TokenType:    "Bearer",
+		IDToken:      output.IDToken,
+		TokenType:    tokenType(output),
 		ExpiresIn:    output.ExpiresIn,
 	})
 }
@@ -105,6 +108,7 @@ func (h *TokenHandler) HandleLogoutAll(w http.ResponseWriter, r *http.Request) {
 type refreshResponseData struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token,omitempty"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int64  `json:"expires_in"`
 }
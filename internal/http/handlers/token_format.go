@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+)
+
+// TokenResponseFormat selects the shape of login and refresh responses
+type TokenResponseFormat string
+
+const (
+	// TokenFormatNative is the service's original response shape
+	TokenFormatNative TokenResponseFormat = "native"
+	// TokenFormatOAuth2 follows RFC 6749 section 5.1, including scope and
+	// no-store cache headers
+	TokenFormatOAuth2 TokenResponseFormat = "oauth2"
+)
+
+// TokenFormatHeader lets a client request a specific token response format
+const TokenFormatHeader = "X-Token-Response-Format"
+
+// ParseTokenResponseFormat validates a token response format name
+func ParseTokenResponseFormat(s string) (TokenResponseFormat, error) {
+	switch f := TokenResponseFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case TokenFormatNative, TokenFormatOAuth2:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported token response format: %s", s)
+	}
+}
+
+// OAuth2TokenResponse represents an RFC 6749 access token response
+type OAuth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+
+	// RememberMeExpiresAt is when a remember-me session ends. It is not part
+	// of RFC 6749; clients show it to the user.
+	RememberMeExpiresAt *time.Time `json:"remember_me_expires_at,omitempty"`
+}
+
+// negotiateTokenFormat returns the format requested by the client, falling
+// back to the handler default for missing or unknown values so existing
+// clients keep their current response shape. API version 2 defaults to
+// OAuth2.
+func negotiateTokenFormat(r *http.Request, fallback TokenResponseFormat) TokenResponseFormat {
+	if value := r.Header.Get(TokenFormatHeader); value != "" {
+		if format, err := ParseTokenResponseFormat(value); err == nil {
+			return format
+		}
+	}
+	if apiVersion(r) >= APIVersion2 {
+		return TokenFormatOAuth2
+	}
+	return fallback
+}
+
+// writeTokenResponse writes a login or refresh response in the negotiated format
+func writeTokenResponse(w http.ResponseWriter, r *http.Request, fallback TokenResponseFormat, resp LoginResponse) {
+	if negotiateTokenFormat(r, fallback) != TokenFormatOAuth2 {
+		response.WriteJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	scope := ""
+	if resp.IDToken != "" {
+		scope = "openid email"
+	}
+
+	// Token responses must not be cached (RFC 6749 section 5.1)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	response.WriteJSON(w, http.StatusOK, OAuth2TokenResponse{
+		AccessToken:  resp.AccessToken,
+		TokenType:    resp.TokenType,
+		ExpiresIn:    resp.ExpiresIn,
+		RefreshToken: resp.RefreshToken,
+		Scope:        scope,
+		IDToken:      resp.IDToken,
+
+		RememberMeExpiresAt: resp.RememberMeExpiresAt,
+	})
+}
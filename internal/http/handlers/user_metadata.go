@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// MetadataResponse is a user's metadata after an update
+type MetadataResponse struct {
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// UpdateMetadata applies a JSON merge patch to the caller's metadata: keys
+// set to null are removed and keys left out are kept
+func (h *AuthHandler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+	if !ok {
+		response.WriteError(w, token.ErrInvalidToken)
+		return
+	}
+
+	var patch map[string]interface{}
+	if err := request.ValidateJSONRequest(r, &patch); err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	metadata, err := h.authService.UpdateUserMetadata(r.Context(), userID, patch)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, MetadataResponse{Metadata: metadata})
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+)
+
+// API versions served by the router. Both are served by the same handlers
+// from the same services; where responses differ, handlers branch on
+// apiVersion.
+const (
+	APIVersion1 = 1
+	// APIVersion2 answers login and refresh with RFC 6749 token responses
+	// unless the client negotiates another format
+	APIVersion2 = 2
+)
+
+// apiVersion returns the API version of the route r matched, APIVersion1
+// for routes outside a versioned group
+func apiVersion(r *http.Request) int {
+	if version, ok := r.Context().Value(httpcontext.APIVersionKey).(int); ok {
+		return version
+	}
+	return APIVersion1
+}
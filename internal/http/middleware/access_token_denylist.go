@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// AccessTokenDenylistSource reports whether an access token was revoked
+type AccessTokenDenylistSource interface {
+	Contains(ctx context.Context, tokenID string) (bool, error)
+}
+
+// AccessTokenDenylist returns a middleware that rejects access tokens revoked
+// through the revocation endpoint. RequireAuth has to run first. Tokens
+// issued before access tokens carried a jti cannot be revoked and pass. As
+// with token versions, a failed lookup rejects the request.
+func AccessTokenDenylist(denylist AccessTokenDenylistSource) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenID, _ := r.Context().Value(httpcontext.TokenIDKey).(string)
+			if tokenID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			revoked, err := denylist.Contains(r.Context(), tokenID)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "failed to check access token denylist", "error", err)
+				response.WriteError(w, err)
+				return
+			}
+			if revoked {
+				response.WriteError(w, token.ErrInvalidToken)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
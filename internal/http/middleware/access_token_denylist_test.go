@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+)
+
+// fakeDenylist holds revoked token ids, or fails every lookup with err
+type fakeDenylist struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (d *fakeDenylist) Contains(ctx context.Context, tokenID string) (bool, error) {
+	return d.revoked[tokenID], d.err
+}
+
+func TestAccessTokenDenylist(t *testing.T) {
+	denylist := &fakeDenylist{revoked: map[string]bool{"revoked": true}}
+
+	tests := []struct {
+		name       string
+		tokenID    string
+		denylist   *fakeDenylist
+		wantStatus int
+	}{
+		{name: "not revoked", tokenID: "live", denylist: denylist, wantStatus: http.StatusOK},
+		{name: "revoked", tokenID: "revoked", denylist: denylist, wantStatus: http.StatusUnauthorized},
+		{name: "token without jti", denylist: &fakeDenylist{err: errors.New("not called")}, wantStatus: http.StatusOK},
+		{name: "lookup failure", tokenID: "live", denylist: &fakeDenylist{err: errors.New("connection refused")}, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := AccessTokenDenylist(tt.denylist)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx := context.WithValue(req.Context(), httpcontext.TokenIDKey, tt.tokenID)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req.WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// AccessWindowRecorder records requests rejected for falling outside the
+// caller's access window
+type AccessWindowRecorder interface {
+	RecordAccessWindowDenied(ctx context.Context, userID, ipAddress string, window domain.AccessWindow)
+}
+
+// AccessWindow returns a middleware that rejects requests made outside the
+// daily access window carried by the access token with 403
+// OUTSIDE_ACCESS_WINDOW, and records them. Tokens without a window are let
+// through. RequireAuth has to run first.
+func AccessWindow(recorder AccessWindowRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claimed, ok := r.Context().Value(httpcontext.AccessWindowKey).(*token.AccessWindow)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			window := domain.AccessWindow{Start: claimed.Start, End: claimed.End, Timezone: claimed.Timezone}
+			if !window.Contains(time.Now()) {
+				userID, _ := r.Context().Value(httpcontext.UserIDKey).(string)
+				recorder.RecordAccessWindowDenied(r.Context(), userID, getClientIP(r), window)
+				response.WriteError(w, domain.ErrOutsideAccessWindow)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
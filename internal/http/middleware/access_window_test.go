@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// deniedWindows records out-of-window requests
+type deniedWindows struct {
+	users []string
+}
+
+func (d *deniedWindows) RecordAccessWindowDenied(ctx context.Context, userID, ipAddress string, window domain.AccessWindow) {
+	d.users = append(d.users, userID)
+}
+
+func TestAccessWindow(t *testing.T) {
+	now := time.Now().UTC()
+	clock := func(d time.Duration) string { return now.Add(d).Format("15:04") }
+
+	tests := []struct {
+		name       string
+		window     *token.AccessWindow
+		wantStatus int
+	}{
+		{name: "no window", wantStatus: http.StatusOK},
+		{name: "inside", window: &token.AccessWindow{Start: clock(-time.Hour), End: clock(time.Hour), Timezone: "UTC"}, wantStatus: http.StatusOK},
+		{name: "outside", window: &token.AccessWindow{Start: clock(time.Hour), End: clock(2 * time.Hour), Timezone: "UTC"}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			denied := &deniedWindows{}
+			handler := AccessWindow(denied)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+			ctx := context.WithValue(req.Context(), httpcontext.UserIDKey, "user-1")
+			if tt.window != nil {
+				ctx = context.WithValue(ctx, httpcontext.AccessWindowKey, tt.window)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req.WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if wantDenied := tt.wantStatus == http.StatusForbidden; (len(denied.users) == 1) != wantDenied {
+				t.Errorf("denied = %v, want recorded %v", denied.users, wantDenied)
+			}
+		})
+	}
+}
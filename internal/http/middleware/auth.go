@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"net/http"
 
 	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
 	"github.com/n1rocket/go-auth-jwt/internal/http/request"
 	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
 )
 
@@ -14,7 +17,7 @@ import (
 func RequireAuth(tokenManager *token.Manager, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract token from Authorization header
-		tokenString, err := request.ExtractBearerToken(r)
+		scheme, tokenString, err := request.ExtractAccessToken(r)
 		if err != nil {
 			response.WriteError(w, token.ErrInvalidToken)
 			return
@@ -27,12 +30,28 @@ func RequireAuth(tokenManager *token.Manager, next http.Handler) http.Handler {
 			return
 		}
 
+		// Bound tokens are only valid with their client certificate or DPoP key
+		if err := verifyBinding(tokenManager, r, scheme, tokenString, claims); err != nil {
+			if errors.Is(err, token.ErrInvalidDPoPProof) {
+				w.Header().Set("WWW-Authenticate", `DPoP error="invalid_dpop_proof"`)
+				err = token.ErrInvalidToken
+			}
+			response.WriteError(w, err)
+			return
+		}
+
 		// Add user ID to context
+		setReportUser(r.Context(), claims.UserID)
 		ctx := context.WithValue(r.Context(), httpcontext.UserIDKey, claims.UserID)
 
 		// Add additional claims to context if needed
 		ctx = context.WithValue(ctx, httpcontext.UserEmailKey, claims.Email)
 		ctx = context.WithValue(ctx, httpcontext.UserEmailVerifiedKey, claims.EmailVerified)
+		ctx = context.WithValue(ctx, httpcontext.TokenVersionKey, claims.TokenVersion)
+		ctx = context.WithValue(ctx, httpcontext.TokenIDKey, claims.ID)
+		if claims.AccessWindow != nil {
+			ctx = context.WithValue(ctx, httpcontext.AccessWindowKey, claims.AccessWindow)
+		}
 
 		// Call next handler with updated context
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -43,7 +62,7 @@ func RequireAuth(tokenManager *token.Manager, next http.Handler) http.Handler {
 func OptionalAuth(tokenManager *token.Manager, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Try to extract token from Authorization header
-		tokenString, err := request.ExtractBearerToken(r)
+		scheme, tokenString, err := request.ExtractAccessToken(r)
 		if err != nil {
 			// No token or invalid format - continue without auth
 			next.ServeHTTP(w, r)
@@ -52,6 +71,9 @@ func OptionalAuth(tokenManager *token.Manager, next http.Handler) http.Handler {
 
 		// Try to validate token
 		claims, err := tokenManager.ValidateAccessToken(tokenString)
+		if err == nil {
+			err = verifyBinding(tokenManager, r, scheme, tokenString, claims)
+		}
 		if err != nil {
 			// Invalid token - continue without auth
 			next.ServeHTTP(w, r)
@@ -59,6 +81,7 @@ func OptionalAuth(tokenManager *token.Manager, next http.Handler) http.Handler {
 		}
 
 		// Add user ID to context
+		setReportUser(r.Context(), claims.UserID)
 		ctx := context.WithValue(r.Context(), httpcontext.UserIDKey, claims.UserID)
 
 		// Add additional claims to context
@@ -70,6 +93,53 @@ func OptionalAuth(tokenManager *token.Manager, next http.Handler) http.Handler {
 	})
 }
 
+// verifyBinding checks a validated token against the client certificate and
+// DPoP proof presented with the request
+func verifyBinding(tokenManager *token.Manager, r *http.Request, scheme, tokenString string, claims *token.Claims) error {
+	if err := claims.VerifyCertificateBinding(peerCertificates(r)); err != nil {
+		return err
+	}
+
+	var thumbprint string
+	if claims.DPoPThumbprint() != "" && scheme == token.DPoPTokenType {
+		var err error
+		thumbprint, err = tokenManager.VerifyDPoPProof(token.DPoPRequest{
+			Proof:       r.Header.Get(token.DPoPHeader),
+			Method:      r.Method,
+			URL:         request.RequestURL(r),
+			AccessToken: tokenString,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return claims.VerifyDPoPBinding(scheme, thumbprint)
+}
+
+// peerCertificates returns the client certificates verified during the TLS
+// handshake, if any
+func peerCertificates(r *http.Request) []*x509.Certificate {
+	if r.TLS == nil {
+		return nil
+	}
+	return r.TLS.PeerCertificates
+}
+
+// RequireAdminToken is a middleware that protects operator endpoints with a
+// static bearer token. An empty token rejects every request.
+func RequireAdminToken(adminToken string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, err := request.ExtractBearerToken(r)
+		if err != nil || adminToken == "" || !security.ConstantTimeCompare(tokenString, adminToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			response.WriteError(w, token.ErrInvalidToken)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // RequireVerifiedEmail is a middleware that requires the user to have a verified email
 func RequireVerifiedEmail(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -2,7 +2,15 @@ package middleware
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -332,6 +340,43 @@ func TestRequireVerifiedEmail(t *testing.T) {
 	}
 }
 
+func TestRequireAdminToken(t *testing.T) {
+	const adminToken = "admin-token-0123456789abcdef"
+
+	tests := []struct {
+		name           string
+		configured     string
+		authHeader     string
+		expectedStatus int
+	}{
+		{name: "valid token", configured: adminToken, authHeader: "Bearer " + adminToken, expectedStatus: http.StatusOK},
+		{name: "wrong token", configured: adminToken, authHeader: "Bearer admin-token-wrong-value", expectedStatus: http.StatusUnauthorized},
+		{name: "missing header", configured: adminToken, expectedStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", configured: adminToken, authHeader: "Basic " + adminToken, expectedStatus: http.StatusUnauthorized},
+		{name: "no token configured", configured: "", authHeader: "Bearer " + adminToken, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireAdminToken(tt.configured, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/dormancy/runs", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
 func TestEmailNotVerifiedError(t *testing.T) {
 	err := &emailNotVerifiedError{}
 	if err.Error() != "email not verified" {
@@ -342,3 +387,227 @@ func TestEmailNotVerifiedError(t *testing.T) {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func newTestClientCertificate(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestRequireAuth_CertificateBinding(t *testing.T) {
+	manager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create token manager: %v", err)
+	}
+	clientCert := newTestClientCertificate(t)
+	otherCert := newTestClientCertificate(t)
+
+	bound, _ := manager.GenerateAccessTokenWithOptions("user-123", "user@example.com", true, token.AccessTokenOptions{Confirmation: token.Confirmation{X509Thumbprint: token.CertificateThumbprint(clientCert)}})
+	unbound, _ := manager.GenerateAccessToken("user-123", "user@example.com", true)
+
+	tests := []struct {
+		name           string
+		token          string
+		tls            *tls.ConnectionState
+		expectedStatus int
+	}{
+		{
+			name:           "bound token with its certificate",
+			token:          bound,
+			tls:            &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "bound token over plain connection",
+			token:          bound,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "bound token with another certificate",
+			token:          bound,
+			tls:            &tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCert}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unbound token over plain connection",
+			token:          unbound,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireAuth(manager, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			req.TLS = tt.tls
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+// newTestDPoPProof signs a DPoP proof with an embedded P-256 JWK
+func newTestDPoPProof(t *testing.T, key *ecdsa.PrivateKey, method, url, accessToken string) string {
+	t.Helper()
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		t.Fatalf("Failed to generate jti: %v", err)
+	}
+	claims := jwt.MapClaims{
+		"htm": method,
+		"htu": url,
+		"jti": base64.RawURLEncoding.EncodeToString(jti),
+		"iat": time.Now().Unix(),
+	}
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		claims["ath"] = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	proof := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	proof.Header["typ"] = "dpop+jwt"
+	proof.Header["jwk"] = map[string]interface{}{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+	signed, err := proof.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign DPoP proof: %v", err)
+	}
+	return signed
+}
+
+func TestRequireAuth_DPoPBinding(t *testing.T) {
+	manager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create token manager: %v", err)
+	}
+	manager.EnableDPoP(time.Minute)
+
+	clientKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	const url = "http://example.com/api/v1/auth/me"
+
+	// Bind the token the way the login endpoint does
+	jkt, err := manager.VerifyDPoPProof(token.DPoPRequest{
+		Proof:  newTestDPoPProof(t, clientKey, http.MethodPost, "http://example.com/api/v1/auth/login", ""),
+		Method: http.MethodPost,
+		URL:    "http://example.com/api/v1/auth/login",
+	})
+	if err != nil {
+		t.Fatalf("VerifyDPoPProof() error = %v", err)
+	}
+	bound, _ := manager.GenerateAccessTokenWithOptions("user-123", "user@example.com", true, token.AccessTokenOptions{Confirmation: token.Confirmation{JWKThumbprint: jkt}})
+	unbound, _ := manager.GenerateAccessToken("user-123", "user@example.com", true)
+	replayed := newTestDPoPProof(t, clientKey, http.MethodGet, url, bound)
+
+	tests := []struct {
+		name           string
+		authorization  string
+		proof          string
+		expectedStatus int
+		wantChallenge  bool
+	}{
+		{
+			name:           "bound token with proof from its key",
+			authorization:  "DPoP " + bound,
+			proof:          replayed,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "replayed proof",
+			authorization:  "DPoP " + bound,
+			proof:          replayed,
+			expectedStatus: http.StatusUnauthorized,
+			wantChallenge:  true,
+		},
+		{
+			name:           "proof from another key",
+			authorization:  "DPoP " + bound,
+			proof:          newTestDPoPProof(t, otherKey, http.MethodGet, url, bound),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "proof for another URL",
+			authorization:  "DPoP " + bound,
+			proof:          newTestDPoPProof(t, clientKey, http.MethodGet, "http://example.com/api/v1/admin", bound),
+			expectedStatus: http.StatusUnauthorized,
+			wantChallenge:  true,
+		},
+		{
+			name:           "bound token without proof",
+			authorization:  "DPoP " + bound,
+			expectedStatus: http.StatusUnauthorized,
+			wantChallenge:  true,
+		},
+		{
+			name:           "bound token downgraded to bearer",
+			authorization:  "Bearer " + bound,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unbound token sent as DPoP",
+			authorization:  "DPoP " + unbound,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unbound bearer token",
+			authorization:  "Bearer " + unbound,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireAuth(manager, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			req.Header.Set("Authorization", tt.authorization)
+			if tt.proof != "" {
+				req.Header.Set(token.DPoPHeader, tt.proof)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate") != ""; got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate present = %v, want %v", got, tt.wantChallenge)
+			}
+		})
+	}
+}
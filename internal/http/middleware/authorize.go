@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/authz"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// Authorize enforces a route policy in front of a mux. The rule is looked up
+// by the pattern the request matches, so it applies to exactly the handler
+// that will serve it; requests matching no pattern are left to the mux.
+// Routes without a rule are rejected with 403.
+//
+// User rules require a valid access token with the rule's roles and scopes.
+// The policy only adds requirements: routes keep their own authentication,
+// which also checks token binding, and admin routes are authenticated by
+// RequireAdminAuth.
+func Authorize(policy *authz.Policy, tokenManager *token.Manager, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		rule, ok := policy.Rule(pattern)
+		if !ok {
+			slog.ErrorContext(r.Context(), "route has no policy rule", "route", pattern)
+			response.WriteError(w, domain.ErrPermissionDenied)
+			return
+		}
+
+		if rule.Access == authz.AccessUser {
+			_, tokenString, err := request.ExtractAccessToken(r)
+			if err != nil {
+				response.WriteError(w, token.ErrInvalidToken)
+				return
+			}
+
+			claims, err := tokenManager.ValidateAccessToken(tokenString)
+			if err != nil {
+				response.WriteError(w, err)
+				return
+			}
+
+			if !rule.Allows(claims.Roles, claims.Scopes()) {
+				response.WriteError(w, domain.ErrPermissionDenied)
+				return
+			}
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/n1rocket/go-auth-jwt/internal/authz"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+func TestAuthorize(t *testing.T) {
+	manager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	policy, err := authz.ParsePolicy([]byte(`
+routes:
+  - route: GET /public
+    access: public
+  - route: GET /me
+    access: user
+  - route: GET /reports
+    access: user
+    roles: [support]
+    scopes: [reports:read]
+`))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	mux := http.NewServeMux()
+	for _, pattern := range []string{"GET /public", "GET /me", "GET /reports", "GET /unmapped"} {
+		mux.Handle(pattern, ok)
+	}
+	handler := Authorize(policy, manager, mux)
+
+	sign := func(roles []string, scope string) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, token.Claims{
+			UserID: "user-123",
+			Roles:  roles,
+			Scope:  scope,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}).SignedString([]byte("test-secret"))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "public route", path: "/public", wantStatus: http.StatusOK},
+		{name: "user route without token", path: "/me", wantStatus: http.StatusUnauthorized},
+		{name: "user route with invalid token", path: "/me", token: "not-a-jwt", wantStatus: http.StatusUnauthorized},
+		{name: "user route with token", path: "/me", token: sign(nil, ""), wantStatus: http.StatusOK},
+		{name: "missing scope", path: "/reports", token: sign([]string{"support"}, "profile"), wantStatus: http.StatusForbidden},
+		{name: "missing role", path: "/reports", token: sign([]string{"billing"}, "reports:read"), wantStatus: http.StatusForbidden},
+		{name: "role and scope", path: "/reports", token: sign([]string{"support"}, "profile reports:read"), wantStatus: http.StatusOK},
+		{name: "route without rule", path: "/unmapped", wantStatus: http.StatusForbidden},
+		{name: "unknown route", path: "/missing", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
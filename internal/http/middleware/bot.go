@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/risk"
+)
+
+// BotDetection scores every request with detector and stores the assessment
+// in the request context, where the rate limiter and the login risk engine
+// pick it up. Requests are never rejected here, and requests from trusted
+// automation are not scored.
+func BotDetection(detector *risk.BotDetector) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if risk.ExemptFromContext(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			assessment := detector.Inspect(getClientIP(r), r.Header)
+			next.ServeHTTP(w, r.WithContext(risk.ContextWithBotAssessment(r.Context(), assessment)))
+		})
+	}
+}
+
+// RiskExemption marks requests from trusted automation, such as synthetic
+// monitoring, in the request context so bot scoring and the login risk
+// engine leave them alone. Networks match the connection's peer address,
+// since forwarding headers can be forged; traffic arriving through a proxy
+// is identified by its key in the risk.ExemptionKeyHeader header.
+func RiskExemption(exemptions *risk.Exemptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				peer = r.RemoteAddr
+			}
+			if exemptions.Match(peer, r.Header.Get(risk.ExemptionKeyHeader)) {
+				r = r.WithContext(risk.ContextWithExemption(r.Context()))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
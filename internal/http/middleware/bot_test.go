@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/risk"
+)
+
+func TestBotDetection_HighRiskRateLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	detector := risk.NewBotDetector(risk.DefaultBotConfig(), logger)
+
+	var score int
+	handler := BotDetection(detector)(RateLimit(RateLimitConfig{
+		Name:          "test",
+		Rate:          5,
+		Burst:         3,
+		Window:        time.Minute,
+		KeyFunc:       IPKeyFunc(),
+		HighRiskScore: detector.HighRiskScore(),
+		HighRiskRate:  1,
+		HighRiskBurst: 1,
+	}, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bot, _ := risk.BotAssessmentFromContext(r.Context())
+		score = bot.Score
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	send := func(ip string, browser bool) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+		req.RemoteAddr = ip + ":1234"
+		if browser {
+			req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh) Safari/605.1.15")
+			req.Header.Set("Accept", "*/*")
+			req.Header.Set("Accept-Encoding", "gzip")
+			req.Header.Set("Accept-Language", "en")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A headerless client scores as a likely bot and gets one attempt
+	if code := send("203.0.113.1", false); code != http.StatusOK {
+		t.Fatalf("first bot request: status %d, want 200", code)
+	}
+	if score < detector.HighRiskScore() {
+		t.Errorf("bot score %d stored in context, want at least %d", score, detector.HighRiskScore())
+	}
+	if code := send("203.0.113.1", false); code != http.StatusTooManyRequests {
+		t.Errorf("second bot request: status %d, want 429", code)
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := send("203.0.113.2", true); code != http.StatusOK {
+			t.Errorf("browser request %d: status %d, want 200", i+1, code)
+		}
+	}
+}
+
+func TestRiskExemption(t *testing.T) {
+	exemptions, err := risk.NewExemptions([]string{"192.0.2.0/24"}, []string{"0123456789abcdef0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("NewExemptions() error = %v", err)
+	}
+	detector := risk.NewBotDetector(risk.DefaultBotConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var exempt, scored bool
+	handler := RiskExemption(exemptions)(BotDetection(detector)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exempt = risk.ExemptFromContext(r.Context())
+		_, scored = risk.BotAssessmentFromContext(r.Context())
+	})))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		wantExempt bool
+	}{
+		{"exempt network", "192.0.2.10:443", nil, true},
+		{"exempt key", "203.0.113.5:443", http.Header{risk.ExemptionKeyHeader: {"0123456789abcdef0123456789abcdef"}}, true},
+		{"forged forwarded address", "203.0.113.5:443", http.Header{"X-Forwarded-For": {"192.0.2.10"}}, false},
+		{"other network", "203.0.113.5:443", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if exempt != tt.wantExempt {
+				t.Errorf("exempt = %v, want %v", exempt, tt.wantExempt)
+			}
+			if scored == tt.wantExempt {
+				t.Errorf("bot scored = %v, want %v", scored, !tt.wantExempt)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"runtime/debug"
 	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/errreport"
 	"github.com/n1rocket/go-auth-jwt/internal/http/response"
 )
 
@@ -66,13 +67,55 @@ func Logger(next http.Handler) http.Handler {
 
 // Recover recovers from panics and returns a 500 error
 func Recover(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				// Check if it's ErrAbortHandler - this is a special case
-				if err == http.ErrAbortHandler {
-					// Don't log for ErrAbortHandler but still return 500
+	return RecoverAndReport(nil)(next)
+}
+
+// RecoverAndReport is Recover that also sends recovered panics and 5xx
+// responses to an error tracker, with the request, the stack and the
+// authenticated user. A nil client only recovers.
+func RecoverAndReport(client *errreport.Client) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var scope *reportScope
+			var wrapped *responseWriter
+			if client != nil {
+				// Auth middleware further in records the user in the scope
+				scope = &reportScope{}
+				r = r.WithContext(context.WithValue(r.Context(), reportScopeKey{}, scope))
+				wrapped = &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+				w = wrapped
+			}
+
+			defer func() {
+				if err := recover(); err != nil {
+					// Check if it's ErrAbortHandler - this is a special case
+					if err == http.ErrAbortHandler {
+						// Don't log for ErrAbortHandler but still return 500
+						if w.Header().Get("Content-Type") == "" {
+							response.WriteJSON(w, http.StatusInternalServerError, map[string]string{
+								"error":   "internal_error",
+								"message": "An unexpected error occurred",
+							})
+						}
+						return
+					}
+
+					// Log the panic
+					stack := string(debug.Stack())
+					requestID := requestIDOf(w, r)
+					slog.Error("panic recovered",
+						"request_id", requestID,
+						"panic", err,
+						"stack", stack,
+					)
+					if client != nil {
+						client.Capture(newReportEvent(r, scope, requestID, errreport.LevelFatal, http.StatusInternalServerError,
+							fmt.Sprintf("panic: %v", err), stack))
+					}
+
+					// Check if response has already been written
 					if w.Header().Get("Content-Type") == "" {
+						// Return 500 error only if headers haven't been sent
 						response.WriteJSON(w, http.StatusInternalServerError, map[string]string{
 							"error":   "internal_error",
 							"message": "An unexpected error occurred",
@@ -81,27 +124,54 @@ func Recover(next http.Handler) http.Handler {
 					return
 				}
 
-				// Log the panic
-				requestID, _ := r.Context().Value("request_id").(string)
-				slog.Error("panic recovered",
-					"request_id", requestID,
-					"panic", err,
-					"stack", string(debug.Stack()),
-				)
-
-				// Check if response has already been written
-				if w.Header().Get("Content-Type") == "" {
-					// Return 500 error only if headers haven't been sent
-					response.WriteJSON(w, http.StatusInternalServerError, map[string]string{
-						"error":   "internal_error",
-						"message": "An unexpected error occurred",
-					})
+				if wrapped != nil && wrapped.statusCode >= http.StatusInternalServerError {
+					client.Capture(newReportEvent(r, scope, requestIDOf(w, r), errreport.LevelError, wrapped.statusCode,
+						fmt.Sprintf("%d %s", wrapped.statusCode, http.StatusText(wrapped.statusCode)), ""))
 				}
-			}
-		}()
+			}()
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reportScope collects request details known only further in the chain
+type reportScope struct {
+	userID string
+}
+
+type reportScopeKey struct{}
+
+// setReportUser records the authenticated user for error reports of the
+// request, when they are enabled
+func setReportUser(ctx context.Context, userID string) {
+	if scope, ok := ctx.Value(reportScopeKey{}).(*reportScope); ok {
+		scope.userID = userID
+	}
+}
+
+// requestIDOf returns the request ID. Recover runs outside RequestID, so the
+// ID is usually only in the response header.
+func requestIDOf(w http.ResponseWriter, r *http.Request) string {
+	if requestID, ok := r.Context().Value("request_id").(string); ok {
+		return requestID
+	}
+	return w.Header().Get("X-Request-ID")
+}
+
+func newReportEvent(r *http.Request, scope *reportScope, requestID string, level errreport.Level, status int, message, stack string) *errreport.Event {
+	return &errreport.Event{
+		Level:     level,
+		Message:   message,
+		Stack:     stack,
+		Status:    status,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		Headers:   errreport.ReportedHeaders(r.Header.Get),
+		RequestID: requestID,
+		UserID:    scope.userID,
+	}
 }
 
 // CORS adds CORS headers to responses
@@ -157,6 +227,10 @@ type responseWriter struct {
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
+	if informational(code) {
+		rw.ResponseWriter.WriteHeader(code)
+		return
+	}
 	if !rw.written {
 		rw.statusCode = code
 		rw.ResponseWriter.WriteHeader(code)
@@ -10,6 +10,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/errreport"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
 )
 
 func TestRequestID(t *testing.T) {
@@ -444,3 +448,66 @@ func TestJoinStrings(t *testing.T) {
 		})
 	}
 }
+
+// recordingReporter keeps reported error events
+type recordingReporter struct {
+	events []*errreport.Event
+}
+
+func (r *recordingReporter) Report(ctx context.Context, event *errreport.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestRecoverAndReport(t *testing.T) {
+	manager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create token manager: %v", err)
+	}
+	accessToken, err := manager.GenerateAccessToken("user-123", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+
+	reporter := &recordingReporter{}
+	client := errreport.NewClient(reporter, errreport.DefaultConfig(), slog.Default())
+	client.Start()
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /panic", RequireAuth(manager, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	mux.HandleFunc("GET /unavailable", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, r *http.Request) {})
+	handler := RecoverAndReport(client)(RequestID(mux))
+
+	for _, path := range []string{"/panic?code=123456", "/unavailable", "/ok"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if err := client.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if len(reporter.events) != 2 {
+		t.Fatalf("reported %d events, want 2", len(reporter.events))
+	}
+
+	panicEvent := reporter.events[0]
+	if panicEvent.Level != errreport.LevelFatal || panicEvent.Message != "panic: boom" || panicEvent.Stack == "" {
+		t.Errorf("unexpected panic event %+v", panicEvent)
+	}
+	if panicEvent.UserID != "user-123" || panicEvent.RequestID == "" {
+		t.Errorf("panic event missing user or request ID: %+v", panicEvent)
+	}
+	if strings.Contains(panicEvent.Query, "123456") || panicEvent.Headers["Authorization"] != "" {
+		t.Errorf("panic event not scrubbed: %+v", panicEvent)
+	}
+
+	if errorEvent := reporter.events[1]; errorEvent.Level != errreport.LevelError || errorEvent.Status != http.StatusBadGateway {
+		t.Errorf("unexpected error event %+v", errorEvent)
+	}
+}
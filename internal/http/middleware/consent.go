@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+)
+
+// ConsentSource reports whether a user accepted the current terms of service
+type ConsentSource interface {
+	TermsAccepted(ctx context.Context, userID string) (bool, error)
+}
+
+// RequireConsent returns a middleware that rejects requests of users who
+// have not accepted the current terms of service with 403 CONSENT_REQUIRED,
+// so clients can ask them to accept the terms. RequireAuth has to run first.
+func RequireConsent(consents ConsentSource) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(httpcontext.UserIDKey).(string)
+
+			accepted, err := consents.TermsAccepted(r.Context(), userID)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "failed to check terms consent", "user_id", userID, "error", err)
+				response.WriteError(w, err)
+				return
+			}
+			if !accepted {
+				response.WriteError(w, domain.ErrConsentRequired)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+)
+
+// fakeConsents holds users who accepted the current terms, or fails every
+// lookup with err
+type fakeConsents struct {
+	accepted map[string]bool
+	err      error
+}
+
+func (c *fakeConsents) TermsAccepted(ctx context.Context, userID string) (bool, error) {
+	return c.accepted[userID], c.err
+}
+
+func TestRequireConsent(t *testing.T) {
+	consents := &fakeConsents{accepted: map[string]bool{"current": true}}
+
+	tests := []struct {
+		name       string
+		userID     string
+		consents   *fakeConsents
+		wantStatus int
+	}{
+		{name: "accepted", userID: "current", consents: consents, wantStatus: http.StatusOK},
+		{name: "outdated terms", userID: "stale", consents: consents, wantStatus: http.StatusForbidden},
+		{name: "lookup failure", userID: "current", consents: &fakeConsents{err: errors.New("connection refused")}, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireConsent(tt.consents)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx := context.WithValue(req.Context(), httpcontext.UserIDKey, tt.userID)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req.WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
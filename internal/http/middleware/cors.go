@@ -33,6 +33,7 @@ func DefaultCORSConfig() CORSConfig {
 			"Accept",
 			"Authorization",
 			"Content-Type",
+			"DPoP",
 			"X-Request-ID",
 		},
 		ExposedHeaders: []string{
@@ -54,6 +55,7 @@ func StrictCORSConfig(allowedOrigins []string) CORSConfig {
 			http.MethodGet,
 			http.MethodPost,
 			http.MethodPut,
+			http.MethodPatch,
 			http.MethodDelete,
 			http.MethodOptions,
 		},
@@ -61,6 +63,7 @@ func StrictCORSConfig(allowedOrigins []string) CORSConfig {
 			"Accept",
 			"Authorization",
 			"Content-Type",
+			"DPoP",
 			"X-Request-ID",
 		},
 		ExposedHeaders: []string{
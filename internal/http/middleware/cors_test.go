@@ -39,7 +39,7 @@ func TestStrictCORSConfig(t *testing.T) {
 		t.Error("Expected AllowCredentials to be true")
 	}
 
-	expectedMethods := []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	expectedMethods := []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	if len(config.AllowedMethods) != len(expectedMethods) {
 		t.Errorf("Expected %d methods, got %d", len(expectedMethods), len(config.AllowedMethods))
 	}
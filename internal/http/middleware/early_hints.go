@@ -0,0 +1,32 @@
+package middleware
+
+import "net/http"
+
+// EarlyHints sends a 103 Early Hints response (RFC 8297) with the given Link
+// header values before the handler runs, so browsers start connecting to
+// origins and fetching assets while the page renders. The links are sent
+// with the final response too. HTTP/1.0 clients do not understand 1xx
+// responses and get the links with the final response only.
+func EarlyHints(links []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(links) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, link := range links {
+				w.Header().Add("Link", link)
+			}
+			if r.Method == http.MethodGet && r.ProtoAtLeast(1, 1) {
+				w.WriteHeader(http.StatusEarlyHints)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// informational reports whether code is an interim 1xx response, after
+// which the handler still writes the final status. 101 Switching Protocols
+// ends the response.
+func informational(code int) bool {
+	return code >= 100 && code < 200 && code != http.StatusSwitchingProtocols
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/i18n"
+)
+
+// Localize translates the messages of error responses into the language
+// the Accept-Language header prefers. Error codes are not translated, so
+// clients can keep matching on them.
+func Localize(messages *i18n.Bundle) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lw := &localizedResponseWriter{
+				ResponseWriter: w,
+				messages:       messages,
+				language:       messages.Match(r.Header.Get("Accept-Language")),
+			}
+			next.ServeHTTP(lw, r)
+		})
+	}
+}
+
+// localizedResponseWriter is the response.Localizer of a request
+type localizedResponseWriter struct {
+	http.ResponseWriter
+	messages *i18n.Bundle
+	language string
+	varied   bool
+}
+
+// Localize translates a message and marks the response as depending on
+// Accept-Language
+func (w *localizedResponseWriter) Localize(code, message string, params map[string]string) string {
+	if !w.varied {
+		w.varied = true
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	translated := w.messages.Translate(w.language, code, message, params)
+	if translated != message {
+		w.Header().Set("Content-Language", w.language)
+	}
+	return translated
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *localizedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
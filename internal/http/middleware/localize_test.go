@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/i18n"
+)
+
+func TestLocalize(t *testing.T) {
+	messages, err := i18n.NewBundle()
+	if err != nil {
+		t.Fatalf("NewBundle() error = %v", err)
+	}
+	handler := Localize(messages)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/validate" {
+			response.WriteValidationError(w, []response.ValidationError{
+				{Field: "email", Message: "email is required", Code: "REQUIRED_FIELD"},
+				{Field: "name", Message: "name must be at most 100 characters", Code: "TOO_LONG"},
+			})
+			return
+		}
+		response.WriteError(w, domain.ErrUserNotFound)
+	}))
+
+	serve := func(path, acceptLanguage string) (*httptest.ResponseRecorder, response.ErrorResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var body response.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rec, body
+	}
+
+	rec, body := serve("/user", "es-ES,es;q=0.9,en;q=0.8")
+	if body.Message != "Usuario no encontrado" || body.Code != "USER_NOT_FOUND" {
+		t.Errorf("Spanish error = %+v", body)
+	}
+	if got := rec.Header().Get("Content-Language"); got != "es" {
+		t.Errorf("Content-Language = %q, want es", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Language" {
+		t.Errorf("Vary = %q, want Accept-Language", got)
+	}
+
+	rec, body = serve("/user", "")
+	if body.Message != "User not found" {
+		t.Errorf("default error message = %q", body.Message)
+	}
+	if got := rec.Header().Get("Content-Language"); got != "" {
+		t.Errorf("untranslated response: Content-Language = %q", got)
+	}
+
+	_, body = serve("/validate", "es")
+	if body.Message != "La validación de la solicitud falló" || body.Code != "VALIDATION_FAILED" {
+		t.Errorf("Spanish validation error = %+v", body)
+	}
+	if got := body.Details["email"]; got != "email es obligatorio" {
+		t.Errorf("email detail = %q", got)
+	}
+	if got := body.Details["name"]; got != "name must be at most 100 characters" {
+		t.Errorf("untranslated detail = %q", got)
+	}
+}
@@ -3,6 +3,8 @@ package middleware
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/n1rocket/go-auth-jwt/internal/metrics"
@@ -16,7 +18,9 @@ type metricsResponseWriter struct {
 }
 
 func (rw *metricsResponseWriter) WriteHeader(code int) {
-	rw.statusCode = code
+	if !informational(code) {
+		rw.statusCode = code
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
 
@@ -26,8 +30,81 @@ func (rw *metricsResponseWriter) Write(data []byte) (int, error) {
 	return n, err
 }
 
-// Metrics returns a middleware that collects HTTP metrics
-func Metrics(m *metrics.Metrics) func(http.Handler) http.Handler {
+// OtherRouteLabel is the path label of requests that match no route, and of
+// routes seen after the label limit was reached
+const OtherRouteLabel = "other"
+
+// DefaultMaxRouteLabels is the route label limit when none is configured
+const DefaultMaxRouteLabels = 200
+
+// RouteResolver finds the route pattern serving a request, as
+// http.ServeMux.Handler does
+type RouteResolver interface {
+	Handler(r *http.Request) (http.Handler, string)
+}
+
+// RouteLabeler turns requests into path labels with bounded cardinality.
+// Requests are labeled with the path of the route pattern serving them, such
+// as /api/v1/admin/users/{id}, never with the raw path, which would add a
+// series per ID. Past maxLabels distinct routes, new ones share
+// OtherRouteLabel.
+type RouteLabeler struct {
+	routes    RouteResolver
+	maxLabels int
+
+	mu     sync.RWMutex
+	labels map[string]bool
+}
+
+// NewRouteLabeler creates a labeler resolving routes with routes.
+// DefaultMaxRouteLabels applies when maxLabels is not positive.
+func NewRouteLabeler(routes RouteResolver, maxLabels int) *RouteLabeler {
+	if maxLabels <= 0 {
+		maxLabels = DefaultMaxRouteLabels
+	}
+	return &RouteLabeler{
+		routes:    routes,
+		maxLabels: maxLabels,
+		labels:    make(map[string]bool),
+	}
+}
+
+// Label returns the path label of a request
+func (l *RouteLabeler) Label(r *http.Request) string {
+	pattern := r.Pattern
+	if pattern == "" && l.routes != nil {
+		_, pattern = l.routes.Handler(r)
+	}
+	if pattern == "" {
+		return OtherRouteLabel
+	}
+	// The method has its own label
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = path
+	}
+
+	l.mu.RLock()
+	known := l.labels[pattern]
+	l.mu.RUnlock()
+	if known {
+		return pattern
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.labels[pattern] {
+		return pattern
+	}
+	if len(l.labels) >= l.maxLabels {
+		return OtherRouteLabel
+	}
+	l.labels[pattern] = true
+	return pattern
+}
+
+// Metrics returns a middleware that collects HTTP metrics, labeled by route
+// with routes
+func Metrics(m *metrics.Metrics, routes *RouteLabeler) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Track in-flight requests
@@ -43,13 +120,6 @@ func Metrics(m *metrics.Metrics) func(http.Handler) http.Handler {
 				statusCode:     http.StatusOK,
 			}
 
-			// Get route pattern for path label
-			path := r.URL.Path
-			if r.URL.Path != "" && r.URL.Path[0] == '/' {
-				// Normalize path for metrics (remove IDs, etc.)
-				path = normalizePath(r.URL.Path)
-			}
-
 			// Process request
 			next.ServeHTTP(rw, r)
 
@@ -57,43 +127,11 @@ func Metrics(m *metrics.Metrics) func(http.Handler) http.Handler {
 			duration := time.Since(start)
 			status := strconv.Itoa(rw.statusCode)
 
-			m.RecordHTTPRequest(r.Method, path, status, duration, rw.size)
+			m.RecordHTTPRequest(r.Method, routes.Label(r), status, duration, rw.size)
 		})
 	}
 }
 
-// normalizePath normalizes URL paths for metrics to avoid high cardinality
-func normalizePath(path string) string {
-	// Common patterns to normalize
-	patterns := map[string]string{
-		// Auth endpoints
-		"/api/v1/auth/verify-email": "/api/v1/auth/verify-email",
-		"/api/v1/auth/signup":       "/api/v1/auth/signup",
-		"/api/v1/auth/login":        "/api/v1/auth/login",
-		"/api/v1/auth/logout":       "/api/v1/auth/logout",
-		"/api/v1/auth/refresh":      "/api/v1/auth/refresh",
-		"/api/v1/auth/me":           "/api/v1/auth/me",
-
-		// Health endpoints
-		"/health":  "/health",
-		"/ready":   "/ready",
-		"/metrics": "/metrics",
-	}
-
-	// Check exact matches first
-	if normalized, ok := patterns[path]; ok {
-		return normalized
-	}
-
-	// For paths with IDs (UUIDs, numbers), normalize them
-	// This is a simple implementation; could be enhanced with regex
-	if len(path) > 36 { // Likely contains a UUID
-		return "/api/v1/resource/:id"
-	}
-
-	return path
-}
-
 // MetricsCollector provides methods to record various metrics
 type MetricsCollector struct {
 	metrics *metrics.Metrics
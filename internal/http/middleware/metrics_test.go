@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -81,7 +83,7 @@ func TestMetrics(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := Metrics(metricsInstance)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler := Metrics(metricsInstance, NewRouteLabeler(nil, 0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(tt.statusCode)
 				w.Write([]byte("response"))
 			}))
@@ -98,76 +100,61 @@ func TestMetrics(t *testing.T) {
 	}
 }
 
-func TestNormalizePath(t *testing.T) {
+func TestRouteLabeler(t *testing.T) {
+	mux := http.NewServeMux()
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	mux.HandleFunc("GET /api/v1/admin/users/{id}", noop)
+	mux.HandleFunc("POST /api/v1/auth/login", noop)
+	mux.HandleFunc("GET /health", noop)
+	labeler := NewRouteLabeler(mux, 2)
+
 	tests := []struct {
-		name     string
-		path     string
-		expected string
+		method string
+		path   string
+		want   string
 	}{
-		{
-			name:     "auth login",
-			path:     "/api/auth/login",
-			expected: "/api/auth/login",
-		},
-		{
-			name:     "auth register",
-			path:     "/api/auth/register",
-			expected: "/api/auth/register",
-		},
-		{
-			name:     "auth logout",
-			path:     "/api/auth/logout",
-			expected: "/api/auth/logout",
-		},
-		{
-			name:     "auth refresh",
-			path:     "/api/auth/refresh",
-			expected: "/api/auth/refresh",
-		},
-		{
-			name:     "auth verify email",
-			path:     "/api/auth/verify-email",
-			expected: "/api/auth/verify-email",
-		},
-		{
-			name:     "user ID path",
-			path:     "/api/users/123",
-			expected: "/api/users/123",
-		},
-		{
-			name:     "user ID with action",
-			path:     "/api/users/456/profile",
-			expected: "/api/users/456/profile",
-		},
-		{
-			name:     "UUID in path",
-			path:     "/api/resources/550e8400-e29b-41d4-a716-446655440000",
-			expected: "/api/v1/resource/:id",
-		},
-		{
-			name:     "multiple IDs",
-			path:     "/api/users/123/posts/456",
-			expected: "/api/users/123/posts/456",
-		},
-		{
-			name:     "root path",
-			path:     "/",
-			expected: "/",
-		},
-		{
-			name:     "other path",
-			path:     "/health",
-			expected: "/health",
-		},
+		{http.MethodGet, "/api/v1/admin/users/550e8400-e29b-41d4-a716-446655440000", "/api/v1/admin/users/{id}"},
+		{http.MethodGet, "/api/v1/admin/users/42", "/api/v1/admin/users/{id}"},
+		{http.MethodPost, "/api/v1/auth/login", "/api/v1/auth/login"},
+		{http.MethodGet, "/no/such/route", OtherRouteLabel},
+		// Over the limit of two routes
+		{http.MethodGet, "/health", OtherRouteLabel},
+		{http.MethodPost, "/api/v1/auth/login", "/api/v1/auth/login"},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := normalizePath(tt.path)
-			if result != tt.expected {
-				t.Errorf("Expected %q, got %q", tt.expected, result)
-			}
-		})
+		if got := labeler.Label(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+			t.Errorf("Label(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMetrics_BoundedPathLabels(t *testing.T) {
+	metricsInstance := metrics.NewMetrics()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/admin/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := Metrics(metricsInstance, NewRouteLabeler(mux, 10))(mux)
+
+	paths := make(map[string]bool)
+	for i := 0; i < 500; i++ {
+		id := make([]byte, 8)
+		rand.Read(id)
+		for _, path := range []string{"/api/v1/admin/users/" + hex.EncodeToString(id), "/" + hex.EncodeToString(id)} {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	metricsInstance.PrometheusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "http_requests_total{") {
+			continue
+		}
+		_, rest, _ := strings.Cut(line, `path="`)
+		path, _, _ := strings.Cut(rest, `"`)
+		paths[path] = true
+	}
+	if len(paths) != 2 || !paths["/api/v1/admin/users/{id}"] || !paths[OtherRouteLabel] {
+		t.Errorf("path labels = %v, want the route pattern and %q", paths, OtherRouteLabel)
 	}
 }
 
@@ -246,7 +233,7 @@ func TestMetricsMiddlewareIntegration(t *testing.T) {
 	metricsInstance := metrics.NewMetrics()
 
 	// Create a handler that simulates different response times
-	handler := Metrics(metricsInstance)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Metrics(metricsInstance, NewRouteLabeler(nil, 0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Simulate some processing time
 		time.Sleep(10 * time.Millisecond)
 
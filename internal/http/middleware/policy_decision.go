@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/authz"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/request"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// PolicyDecision returns a middleware that asks a policy engine whether the
+// request may proceed. It must wrap handlers registered on a ServeMux, which
+// sets the route pattern. The caller is taken from a valid access token if
+// there is one; routes keep their own authentication. The tenant is read from
+// tenantHeader, which must be set by a trusted proxy.
+//
+// Requests are rejected with 503 when the engine cannot decide, unless
+// failOpen lets them through.
+func PolicyDecision(decider authz.Decider, tokenManager *token.Manager, tenantHeader string, failOpen bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			input := authz.Input{
+				Method: r.Method,
+				Path:   r.URL.Path,
+				Route:  r.Pattern,
+				User:   subjectOf(tokenManager, r),
+			}
+			if tenantHeader != "" {
+				input.Tenant = strings.TrimSpace(r.Header.Get(tenantHeader))
+			}
+
+			allowed, err := decider.Decide(r.Context(), input)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "policy decision failed", "route", r.Pattern, "fail_open", failOpen, "error", err)
+				if !failOpen {
+					response.WriteError(w, domain.ErrAuthorizationUnavailable)
+					return
+				}
+				allowed = true
+			}
+			if !allowed {
+				response.WriteError(w, domain.ErrPermissionDenied)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// subjectOf returns the caller identified by the request's access token, or
+// nil when there is no valid token
+func subjectOf(tokenManager *token.Manager, r *http.Request) *authz.Subject {
+	_, tokenString, err := request.ExtractAccessToken(r)
+	if err != nil {
+		return nil
+	}
+	claims, err := tokenManager.ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil
+	}
+	subject := &authz.Subject{
+		ID:            claims.UserID,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Roles:         claims.Roles,
+		Scopes:        claims.Scopes(),
+	}
+	// Policies iterate over roles, so send an empty list rather than null
+	if subject.Roles == nil {
+		subject.Roles = []string{}
+	}
+	return subject
+}
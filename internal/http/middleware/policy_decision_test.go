@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/authz"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// recordingDecider returns a fixed decision and records its input
+type recordingDecider struct {
+	allowed bool
+	err     error
+	input   authz.Input
+}
+
+func (d *recordingDecider) Decide(ctx context.Context, input authz.Input) (bool, error) {
+	d.input = input
+	return d.allowed, d.err
+}
+
+func TestPolicyDecision(t *testing.T) {
+	manager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	accessToken, err := manager.GenerateAccessToken("user-123", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		decider    *recordingDecider
+		failOpen   bool
+		token      string
+		wantStatus int
+		wantUser   string
+	}{
+		{name: "allowed", decider: &recordingDecider{allowed: true}, token: accessToken, wantStatus: http.StatusOK, wantUser: "user-123"},
+		{name: "denied", decider: &recordingDecider{}, token: accessToken, wantStatus: http.StatusForbidden, wantUser: "user-123"},
+		{name: "anonymous", decider: &recordingDecider{allowed: true}, wantStatus: http.StatusOK},
+		{name: "invalid token is anonymous", decider: &recordingDecider{allowed: true}, token: "not-a-jwt", wantStatus: http.StatusOK},
+		{name: "engine down", decider: &recordingDecider{err: errors.New("connection refused")}, wantStatus: http.StatusServiceUnavailable},
+		{name: "engine down, fail open", decider: &recordingDecider{err: errors.New("connection refused")}, failOpen: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("GET /api/v1/items/{id}", PolicyDecision(tt.decider, manager, "X-Tenant", tt.failOpen)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/items/42", nil)
+			req.Header.Set("X-Tenant", "acme")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			input := tt.decider.input
+			if input.Route != "GET /api/v1/items/{id}" || input.Path != "/api/v1/items/42" || input.Tenant != "acme" {
+				t.Errorf("unexpected input %+v", input)
+			}
+			if tt.wantUser == "" && input.User != nil {
+				t.Errorf("expected anonymous input, got user %+v", input.User)
+			}
+			if tt.wantUser != "" && (input.User == nil || input.User.ID != tt.wantUser || input.User.Roles == nil) {
+				t.Errorf("expected user %s with roles list, got %+v", tt.wantUser, input.User)
+			}
+		})
+	}
+}
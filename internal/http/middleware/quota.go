@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+)
+
+// QuotaConsumer counts requests against monthly quotas
+type QuotaConsumer interface {
+	Consume(ctx context.Context, subject string) (*domain.QuotaUsage, error)
+}
+
+// QuotaKeyFunc returns a key function for quota subjects. When tenantHeader
+// is set, requests carrying it are counted as "tenant:<value>"; the header
+// must be set by a trusted proxy. Other requests are counted per
+// authenticated user, so RequireAuth has to run first.
+func QuotaKeyFunc(tenantHeader string) KeyFunc {
+	return func(r *http.Request) string {
+		if tenantHeader != "" {
+			if tenant := strings.TrimSpace(r.Header.Get(tenantHeader)); tenant != "" {
+				return "tenant:" + tenant
+			}
+		}
+
+		userID, ok := r.Context().Value(httpcontext.UserIDKey).(string)
+		if !ok || userID == "" {
+			return ""
+		}
+		return "user:" + userID
+	}
+}
+
+// Quota returns a middleware that enforces monthly request quotas. Requests
+// over quota get 429 with code QUOTA_EXCEEDED. If usage cannot be recorded
+// the request is let through, so an accounting outage does not take the API
+// down.
+func Quota(quotas QuotaConsumer, keyFunc KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject := keyFunc(r)
+			if subject == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			usage, err := quotas.Consume(r.Context(), subject)
+			if err != nil && !errors.Is(err, domain.ErrQuotaExceeded) {
+				slog.ErrorContext(r.Context(), "failed to record quota usage", "subject", subject, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setQuotaHeaders(w, usage)
+
+			if err != nil {
+				w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(usage.ResetsAt()).Seconds())))
+				response.WriteError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setQuotaHeaders reports a limited subject's quota on the response
+func setQuotaHeaders(w http.ResponseWriter, usage *domain.QuotaUsage) {
+	if usage.Limit == 0 {
+		return
+	}
+	w.Header().Set("X-Quota-Limit", strconv.FormatInt(usage.Limit, 10))
+	w.Header().Set("X-Quota-Remaining", strconv.FormatInt(usage.Remaining(), 10))
+	w.Header().Set("X-Quota-Reset", strconv.FormatInt(usage.ResetsAt().Unix(), 10))
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+)
+
+// fakeQuota allows limit requests per subject
+type fakeQuota struct {
+	limit  int64
+	counts map[string]int64
+	err    error
+}
+
+func (q *fakeQuota) Consume(ctx context.Context, subject string) (*domain.QuotaUsage, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	usage := &domain.QuotaUsage{Subject: subject, Period: domain.QuotaPeriod(time.Now()), Limit: q.limit}
+	if q.counts[subject] >= q.limit {
+		usage.Requests = q.limit
+		return usage, domain.ErrQuotaExceeded
+	}
+	q.counts[subject]++
+	usage.Requests = q.counts[subject]
+	return usage, nil
+}
+
+func TestQuotaKeyFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		tenant string
+		userID string
+		want   string
+	}{
+		{name: "authenticated user", userID: "123", want: "user:123"},
+		{name: "tenant header", header: "X-Tenant-ID", tenant: "acme", userID: "123", want: "tenant:acme"},
+		{name: "missing tenant falls back to user", header: "X-Tenant-ID", userID: "123", want: "user:123"},
+		{name: "tenant header ignored when not configured", tenant: "acme", userID: "123", want: "user:123"},
+		{name: "anonymous", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant-ID", tt.tenant)
+			}
+			if tt.userID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), httpcontext.UserIDKey, tt.userID))
+			}
+
+			if got := QuotaKeyFunc(tt.header)(req); got != tt.want {
+				t.Errorf("QuotaKeyFunc() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuota(t *testing.T) {
+	quotas := &fakeQuota{limit: 2, counts: make(map[string]int64)}
+	handler := Quota(quotas, QuotaKeyFunc(""))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), httpcontext.UserIDKey, "123"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := request()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, rec.Code)
+		}
+		if rec.Header().Get("X-Quota-Limit") != "2" || rec.Header().Get("X-Quota-Remaining") != remaining {
+			t.Errorf("request %d: unexpected quota headers %v", i+1, rec.Header())
+		}
+	}
+
+	rec := request()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" || rec.Header().Get("X-Quota-Reset") == "" {
+		t.Errorf("expected Retry-After and X-Quota-Reset headers, got %v", rec.Header())
+	}
+
+	// Accounting failures let requests through
+	quotas.err = errors.New("database error")
+	if rec := request(); rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 when usage cannot be recorded, got %d", rec.Code)
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
 	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
+	"github.com/n1rocket/go-auth-jwt/internal/risk"
 )
 
 // RateLimiter implements token bucket algorithm for rate limiting
@@ -21,12 +25,17 @@ type RateLimiter struct {
 	window  time.Duration // time window
 	keyFunc KeyFunc       // function to extract key from request
 	logger  *slog.Logger
+	clock   clock.Clock
+	name    string // policy name used as the metrics label
+	metrics *metrics.RateLimitMetrics
+	shadow  bool // denials are recorded as shadow denials
 }
 
 // TokenBucket represents a token bucket for rate limiting
 type TokenBucket struct {
 	tokens   float64
 	lastFill time.Time
+	removed  bool // set by cleanup so Allow never spends from a dropped bucket
 	mu       sync.Mutex
 }
 
@@ -60,16 +69,32 @@ func PathKeyFunc() KeyFunc {
 
 // RateLimitConfig holds rate limiter configuration
 type RateLimitConfig struct {
+	Name     string                     // policy name reported in metrics
 	Rate     int                        // tokens per window
 	Burst    int                        // max burst size
 	Window   time.Duration              // time window
 	KeyFunc  KeyFunc                    // key extraction function
 	SkipFunc func(r *http.Request) bool // skip rate limiting for certain requests
+	Metrics  *metrics.RateLimitMetrics  // records decisions and bucket state when set
+	Stats    *RateLimitStats            // reports the limiter's bucket count when set
+	Clock    clock.Clock                // refills buckets; the system clock when nil
+
+	// Shadow never blocks a request. Requests the policy would deny are
+	// logged with their key and counted as shadow_denied, so Rate and Burst
+	// can be tuned against real traffic before they are enforced.
+	Shadow bool
+
+	// Requests whose bot score reaches HighRiskScore draw from separate,
+	// smaller buckets; 0 disables. Scores come from BotDetection.
+	HighRiskScore int
+	HighRiskRate  int
+	HighRiskBurst int
 }
 
 // DefaultRateLimitConfig returns default rate limit configuration
 func DefaultRateLimitConfig() RateLimitConfig {
 	return RateLimitConfig{
+		Name:    "default",
 		Rate:    100,
 		Burst:   10,
 		Window:  time.Minute,
@@ -86,6 +111,14 @@ func NewRateLimiter(config RateLimitConfig, logger *slog.Logger) *RateLimiter {
 		window:  config.Window,
 		keyFunc: config.KeyFunc,
 		logger:  logger,
+		name:    config.Name,
+		metrics: config.Metrics,
+		shadow:  config.Shadow,
+		clock:   clock.OrReal(config.Clock),
+	}
+
+	if config.Stats != nil {
+		config.Stats.add(rl)
 	}
 
 	// Start cleanup goroutine
@@ -94,10 +127,57 @@ func NewRateLimiter(config RateLimitConfig, logger *slog.Logger) *RateLimiter {
 	return rl
 }
 
+// Buckets returns the number of keys with a bucket
+func (rl *RateLimiter) Buckets() int {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return len(rl.buckets)
+}
+
+// RateLimitStats reports the bucket counts of the rate limiters created
+// with it, for operators checking who is being limited during an incident
+type RateLimitStats struct {
+	mu       sync.Mutex
+	limiters []*RateLimiter
+}
+
+// NewRateLimitStats creates stats with no limiters
+func NewRateLimitStats() *RateLimitStats {
+	return &RateLimitStats{}
+}
+
+func (s *RateLimitStats) add(rl *RateLimiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limiters = append(s.limiters, rl)
+}
+
+// Buckets returns the bucket count of each limiter by policy name
+func (s *RateLimitStats) Buckets() map[string]int {
+	s.mu.Lock()
+	limiters := slices.Clone(s.limiters)
+	s.mu.Unlock()
+
+	buckets := make(map[string]int, len(limiters))
+	for _, rl := range limiters {
+		buckets[rl.name] += rl.Buckets()
+	}
+	return buckets
+}
+
 // RateLimit returns a middleware that enforces rate limiting
 func RateLimit(config RateLimitConfig, logger *slog.Logger) func(http.Handler) http.Handler {
 	limiter := NewRateLimiter(config, logger)
 
+	var highRiskLimiter *RateLimiter
+	if config.HighRiskScore > 0 {
+		highRiskConfig := config
+		highRiskConfig.Name = config.Name + "_high_risk"
+		highRiskConfig.Rate = config.HighRiskRate
+		highRiskConfig.Burst = config.HighRiskBurst
+		highRiskLimiter = NewRateLimiter(highRiskConfig, logger)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Check if we should skip rate limiting
@@ -114,22 +194,42 @@ func RateLimit(config RateLimitConfig, logger *slog.Logger) func(http.Handler) h
 				return
 			}
 
-			// Check rate limit
-			allowed, remaining, resetTime := limiter.Allow(key)
+			// Check rate limit, strictly for likely bots
+			active, limit := limiter, config.Rate
+			if bot, ok := risk.BotAssessmentFromContext(r.Context()); ok && highRiskLimiter != nil && bot.Score >= config.HighRiskScore {
+				active, limit = highRiskLimiter, config.HighRiskRate
+			}
+			allowed, remaining, resetTime := active.Allow(key)
+
+			// Shadow policies only report; their headers would announce a
+			// limit that isn't enforced
+			if config.Shadow {
+				if !allowed && logger != nil {
+					logger.Warn("rate limit would deny request",
+						"policy", active.name,
+						"key", key,
+						"method", r.Method,
+						"path", r.URL.Path,
+					)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
 
 			// Set rate limit headers
-			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(config.Rate))
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
 			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
 			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetTime.Unix(), 10))
 
 			if !allowed {
 				// Rate limit exceeded
-				w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(resetTime).Seconds())))
+				retryAfter := int(resetTime.Sub(active.clock.Now()).Seconds())
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 
 				response.WriteJSON(w, http.StatusTooManyRequests, map[string]interface{}{
 					"error":       "rate_limit_exceeded",
 					"message":     "Too many requests. Please try again later.",
-					"retry_after": int(time.Until(resetTime).Seconds()),
+					"retry_after": retryAfter,
 				})
 				return
 			}
@@ -141,22 +241,18 @@ func RateLimit(config RateLimitConfig, logger *slog.Logger) func(http.Handler) h
 
 // Allow checks if a request is allowed under the rate limit
 func (rl *RateLimiter) Allow(key string) (allowed bool, remaining int, resetTime time.Time) {
-	rl.mu.Lock()
-	bucket, exists := rl.buckets[key]
-	if !exists {
-		bucket = &TokenBucket{
-			tokens:   float64(rl.burst),
-			lastFill: time.Now(),
-		}
-		rl.buckets[key] = bucket
-	}
-	rl.mu.Unlock()
-
+	bucket := rl.bucket(key)
 	bucket.mu.Lock()
+	for bucket.removed {
+		// Cleanup dropped the bucket after we looked it up; use its replacement
+		bucket.mu.Unlock()
+		bucket = rl.bucket(key)
+		bucket.mu.Lock()
+	}
 	defer bucket.mu.Unlock()
 
 	// Fill tokens based on time elapsed
-	now := time.Now()
+	now := rl.clock.Now()
 	elapsed := now.Sub(bucket.lastFill)
 	tokensToAdd := elapsed.Seconds() * float64(rl.rate) / rl.window.Seconds()
 
@@ -173,6 +269,14 @@ func (rl *RateLimiter) Allow(key string) (allowed bool, remaining int, resetTime
 		remaining = 0
 	}
 
+	if rl.metrics != nil {
+		if rl.shadow && !allowed {
+			rl.metrics.RecordShadowDenial(rl.name, key)
+		} else {
+			rl.metrics.RecordDecision(rl.name, key, allowed)
+		}
+	}
+
 	// Calculate reset time
 	if bucket.tokens < float64(rl.burst) {
 		tokensNeeded := float64(rl.burst) - bucket.tokens
@@ -185,23 +289,58 @@ func (rl *RateLimiter) Allow(key string) (allowed bool, remaining int, resetTime
 	return allowed, remaining, resetTime
 }
 
+// bucket returns the bucket for key, creating a full one if needed
+func (rl *RateLimiter) bucket(key string) *TokenBucket {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	bucket, exists := rl.buckets[key]
+	if !exists {
+		bucket = &TokenBucket{
+			tokens:   float64(rl.burst),
+			lastFill: rl.clock.Now(),
+		}
+		rl.buckets[key] = bucket
+		if rl.metrics != nil {
+			rl.metrics.SetActiveBuckets(rl.name, len(rl.buckets))
+		}
+	}
+	return bucket
+}
+
 // cleanup removes old buckets periodically
 func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for key, bucket := range rl.buckets {
-			bucket.mu.Lock()
-			// Remove buckets that haven't been used for 2x the window
-			if now.Sub(bucket.lastFill) > 2*rl.window {
-				delete(rl.buckets, key)
-			}
-			bucket.mu.Unlock()
+	for {
+		now := <-rl.clock.After(5 * time.Minute)
+		rl.removeStale(now)
+	}
+}
+
+// removeStale drops buckets that haven't been used for 2x the window, or for
+// as long as a full refill takes if that is longer. Such buckets are full
+// again, so recreating them never grants extra requests.
+func (rl *RateLimiter) removeStale(now time.Time) {
+	start := time.Now()
+	staleAfter := 2 * rl.window
+	if refill := time.Duration(float64(rl.window) * float64(rl.burst) / float64(rl.rate)); refill > staleAfter {
+		staleAfter = refill
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for key, bucket := range rl.buckets {
+		bucket.mu.Lock()
+		if now.Sub(bucket.lastFill) > staleAfter {
+			bucket.removed = true
+			delete(rl.buckets, key)
 		}
-		rl.mu.Unlock()
+		bucket.mu.Unlock()
+	}
+
+	if rl.metrics != nil {
+		rl.metrics.SetActiveBuckets(rl.name, len(rl.buckets))
+		rl.metrics.RecordCleanup(rl.name, time.Since(start))
 	}
 }
 
@@ -242,16 +381,21 @@ func getClientIP(r *http.Request) string {
 
 // Common rate limit configurations
 var (
-	// AuthEndpointLimiter for authentication endpoints (strict)
+	// AuthEndpointLimiter for authentication endpoints (strict); likely
+	// bots get one attempt a minute once HighRiskScore is set
 	AuthEndpointLimiter = RateLimitConfig{
-		Rate:    5,
-		Burst:   2,
-		Window:  time.Minute,
-		KeyFunc: IPKeyFunc(),
+		Name:          "auth",
+		Rate:          5,
+		Burst:         2,
+		Window:        time.Minute,
+		KeyFunc:       IPKeyFunc(),
+		HighRiskRate:  1,
+		HighRiskBurst: 1,
 	}
 
 	// APIEndpointLimiter for general API endpoints (moderate)
 	APIEndpointLimiter = RateLimitConfig{
+		Name:    "api",
 		Rate:    100,
 		Burst:   20,
 		Window:  time.Minute,
@@ -260,6 +404,7 @@ var (
 
 	// PublicEndpointLimiter for public endpoints (relaxed)
 	PublicEndpointLimiter = RateLimitConfig{
+		Name:    "public",
 		Rate:    1000,
 		Burst:   100,
 		Window:  time.Minute,
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+func TestHeaderKeyFunc(t *testing.T) {
+	keyFunc := HeaderKeyFunc("X-API-Key")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if key := keyFunc(req); key != "" {
+		t.Errorf("key without the header = %q, want none", key)
+	}
+
+	req.Header.Set("X-API-Key", "secret-api-key")
+	key := keyFunc(req)
+	if !strings.HasPrefix(key, "header:") || strings.Contains(key, "secret-api-key") {
+		t.Errorf("key = %q, want a hash of the header", key)
+	}
+
+	other := httptest.NewRequest(http.MethodGet, "/", nil)
+	other.Header.Set("X-API-Key", "other-api-key")
+	if keyFunc(other) == key {
+		t.Error("different header values share a key")
+	}
+}
+
+func TestClaimKeyFunc(t *testing.T) {
+	manager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	clientToken, err := manager.GenerateClientAccessToken("sa_build", []string{"read"})
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	userToken, err := manager.GenerateAccessToken("user-123", "test@example.com", true)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	keyFunc := ClaimKeyFunc(manager, "client_id")
+	tests := []struct {
+		name          string
+		authorization string
+		want          string
+	}{
+		{name: "claim", authorization: "Bearer " + clientToken, want: "client_id:sa_build"},
+		{name: "token without the claim", authorization: "Bearer " + userToken, want: ""},
+		{name: "invalid token", authorization: "Bearer not-a-token", want: ""},
+		{name: "no token", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if key := keyFunc(req); key != tt.want {
+				t.Errorf("key = %q, want %q", key, tt.want)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+userToken)
+	if key := ClaimKeyFunc(manager, "sub")(req); key != "sub:user-123" {
+		t.Errorf("sub key = %q", key)
+	}
+}
+
+func TestParseKeyFunc(t *testing.T) {
+	manager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	clientToken, err := manager.GenerateClientAccessToken("sa_build", nil)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	keyFunc, err := ParseKeyFunc("ip+path+claim:client_id", manager)
+	if err != nil {
+		t.Fatalf("ParseKeyFunc() error = %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	req.Header.Set("Authorization", "Bearer "+clientToken)
+	if key := keyFunc(req); key != "192.168.1.1|/api/v1/auth/me|client_id:sa_build" {
+		t.Errorf("composite key = %q", key)
+	}
+	req.Header.Del("Authorization")
+	if key := keyFunc(req); key != "192.168.1.1|/api/v1/auth/me|-" {
+		t.Errorf("composite key without a token = %q", key)
+	}
+
+	// Leaving out the header falls back to the client IP
+	keyFunc, err = ParseKeyFunc("header:X-API-Key", manager)
+	if err != nil {
+		t.Fatalf("ParseKeyFunc() error = %v", err)
+	}
+	if key := keyFunc(req); key != "192.168.1.1" {
+		t.Errorf("key without the header = %q, want the client IP", key)
+	}
+
+	for _, spec := range []string{"", "ip+", "cookie:session", "claim:", "ip:x"} {
+		if _, err := ParseKeyFunc(spec, manager); err == nil {
+			t.Errorf("ParseKeyFunc(%q) should fail", spec)
+		}
+	}
+}
@@ -1,13 +1,20 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/quick"
 	"time"
 )
 
@@ -46,11 +53,13 @@ func TestRateLimiter(t *testing.T) {
 	})
 
 	t.Run("refills tokens over time", func(t *testing.T) {
+		fake := newFakeClock()
 		config := RateLimitConfig{
 			Rate:    10,
 			Burst:   2,
 			Window:  time.Second,
 			KeyFunc: IPKeyFunc(),
+			Clock:   fake,
 		}
 
 		limiter := NewRateLimiter(config, logger)
@@ -65,8 +74,13 @@ func TestRateLimiter(t *testing.T) {
 			t.Error("Should be denied after using all tokens")
 		}
 
-		// Wait for refill
-		time.Sleep(200 * time.Millisecond) // Should refill 2 tokens (10 per second * 0.2s)
+		// Not refilled yet
+		fake.Advance(50 * time.Millisecond)
+		if allowed, _, _ := limiter.Allow("test-key"); allowed {
+			t.Error("Should be denied before a token refills")
+		}
+
+		fake.Advance(200 * time.Millisecond) // Should refill 2 tokens (10 per second * 0.2s)
 
 		// Should be allowed again
 		allowed, _, _ = limiter.Allow("test-key")
@@ -183,6 +197,47 @@ func TestRateLimitMiddleware(t *testing.T) {
 		}
 	})
 
+	t.Run("shadow mode logs instead of denying", func(t *testing.T) {
+		var logs bytes.Buffer
+		m := metrics.NewRateLimitMetrics()
+		config := RateLimitConfig{
+			Name:    "auth",
+			Rate:    10,
+			Burst:   1,
+			Window:  time.Minute,
+			KeyFunc: IPKeyFunc(),
+			Metrics: m,
+			Shadow:  true,
+		}
+
+		wrappedHandler := RateLimit(config, slog.New(slog.NewTextHandler(&logs, nil)))(handler)
+
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = "127.0.0.1:1234"
+			w := httptest.NewRecorder()
+			wrappedHandler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Request %d should not be blocked in shadow mode, got %d", i+1, w.Code)
+			}
+			if w.Header().Get("X-RateLimit-Limit") != "" {
+				t.Error("Shadow mode should not announce the limit")
+			}
+		}
+
+		shadowDenied := m.RateLimitRequests.WithLabels(map[string]string{"policy": "auth", "decision": "shadow_denied"}).Value()
+		if shadowDenied != 2 {
+			t.Errorf("shadow_denied = %d, want 2", shadowDenied)
+		}
+		if got := m.RateLimitExceeded.Value().(int64); got != 0 {
+			t.Errorf("rate_limit_exceeded_total = %d, want 0 in shadow mode", got)
+		}
+		if !strings.Contains(logs.String(), "rate limit would deny request") || !strings.Contains(logs.String(), "key=127.0.0.1") {
+			t.Errorf("would-be denials should be logged with their key, got %q", logs.String())
+		}
+	})
+
 	t.Run("skip function bypasses rate limiting", func(t *testing.T) {
 		config := RateLimitConfig{
 			Rate:    1,
@@ -364,6 +419,7 @@ func TestRateLimiterCleanup(t *testing.T) {
 		window:  config.Window,
 		keyFunc: config.KeyFunc,
 		logger:  logger,
+		clock:   clock.Real{},
 	}
 
 	// Add some buckets
@@ -386,16 +442,7 @@ func TestRateLimiterCleanup(t *testing.T) {
 	limiter.mu.Unlock()
 
 	// Manually trigger cleanup logic
-	limiter.mu.Lock()
-	for key, bucket := range limiter.buckets {
-		bucket.mu.Lock()
-		// Remove buckets that haven't been used for 2x the window
-		if now.Sub(bucket.lastFill) > 2*limiter.window {
-			delete(limiter.buckets, key)
-		}
-		bucket.mu.Unlock()
-	}
-	limiter.mu.Unlock()
+	limiter.removeStale(now)
 
 	// Check that old bucket was removed
 	limiter.mu.RLock()
@@ -417,6 +464,55 @@ func TestRateLimiterCleanup(t *testing.T) {
 	}
 }
 
+func TestRateLimiterMetrics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	m := metrics.NewRateLimitMetrics()
+
+	// Create limiter without starting the cleanup goroutine
+	limiter := &RateLimiter{
+		buckets: make(map[string]*TokenBucket),
+		rate:    1,
+		burst:   1,
+		window:  time.Minute,
+		logger:  logger,
+		name:    "auth",
+		metrics: m,
+		clock:   clock.Real{},
+	}
+
+	limiter.Allow("10.0.0.1")
+	limiter.Allow("10.0.0.1")
+	limiter.Allow("10.0.0.1")
+	limiter.Allow("10.0.0.2")
+
+	allowed := m.RateLimitRequests.WithLabels(map[string]string{"policy": "auth", "decision": "allowed"}).Value()
+	denied := m.RateLimitRequests.WithLabels(map[string]string{"policy": "auth", "decision": "denied"}).Value()
+	if allowed != 2 || denied != 2 {
+		t.Errorf("allowed = %d, denied = %d, want 2 and 2", allowed, denied)
+	}
+	if got := m.RateLimitExceeded.Value().(int64); got != 2 {
+		t.Errorf("rate_limit_exceeded_total = %d, want 2", got)
+	}
+
+	top := m.RateLimitTopDeniedKeys.Top()
+	if len(top) != 1 || top[0].Labels["key"] != "10.0.0.1" || top[0].Count != 2 {
+		t.Errorf("top denied keys = %+v", top)
+	}
+
+	buckets := m.RateLimitBuckets.WithLabels(map[string]string{"policy": "auth"})
+	if got := buckets.Value(); got != 2 {
+		t.Errorf("active buckets = %v, want 2", got)
+	}
+
+	limiter.removeStale(time.Now().Add(time.Hour))
+	if got := buckets.Value(); got != 0 {
+		t.Errorf("active buckets after cleanup = %v, want 0", got)
+	}
+	if m.RateLimitCleanup.Count() != 1 {
+		t.Error("cleanup duration should be recorded")
+	}
+}
+
 func TestGetClientIP(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -484,3 +580,168 @@ func TestGetClientIP(t *testing.T) {
 		})
 	}
 }
+
+// newFakeClock returns the clock the property tests advance by hand
+func newFakeClock() *clock.Fake {
+	return clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// newPropertyLimiter builds a limiter on a fake clock without the cleanup goroutine
+func newPropertyLimiter(rate, burst uint8, fake *clock.Fake) *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*TokenBucket),
+		rate:    1 + int(rate%50),
+		burst:   1 + int(burst%20),
+		window:  time.Second,
+		clock:   fake,
+	}
+}
+
+// propertyGap maps a random value to a delay between requests, mostly short
+// bursts that drain the bucket with the occasional idle period
+func propertyGap(v uint16) time.Duration {
+	if v%8 == 0 {
+		return time.Duration(v%5000) * time.Millisecond
+	}
+	return time.Duration(v%20) * time.Millisecond
+}
+
+// budget is the most requests a limiter may allow over elapsed seconds
+func budget(rl *RateLimiter, elapsed float64) float64 {
+	return float64(rl.burst) + float64(rl.rate)*elapsed/rl.window.Seconds() + 1e-9
+}
+
+func TestRateLimiter_PropertyNeverExceedsBudget(t *testing.T) {
+	property := func(rate, burst uint8, gaps []uint16, cleanups []bool) bool {
+		clock := newFakeClock()
+		limiter := newPropertyLimiter(rate, burst, clock)
+
+		// Every window of time, not just the whole run, must stay within budget
+		var allowedAt []time.Time
+		for i, gap := range gaps {
+			clock.Advance(propertyGap(gap))
+			if i < len(cleanups) && cleanups[i] {
+				limiter.removeStale(clock.Now())
+			}
+			if ok, _, _ := limiter.Allow("key"); !ok {
+				continue
+			}
+			allowedAt = append(allowedAt, clock.Now())
+			last := len(allowedAt) - 1
+			for first := range allowedAt {
+				count := float64(last - first + 1)
+				if elapsed := allowedAt[last].Sub(allowedAt[first]).Seconds(); count > budget(limiter, elapsed) {
+					t.Logf("rate=%d burst=%d: %v allowed within %.3fs", limiter.rate, limiter.burst, count, elapsed)
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRateLimiter_PropertyConcurrentBudget(t *testing.T) {
+	property := func(rate, burst, workers, steps uint8) bool {
+		clock := newFakeClock()
+		limiter := newPropertyLimiter(rate, burst, clock)
+		start := clock.Now()
+
+		var allowed atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 2+int(workers%8); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if ok, _, _ := limiter.Allow("key"); ok {
+						allowed.Add(1)
+					}
+				}
+			}()
+		}
+
+		// Advance time and run cleanup while the workers race
+		for i := 0; i < int(steps%20); i++ {
+			clock.Advance(time.Duration(i*97%1500) * time.Millisecond)
+			limiter.removeStale(clock.Now())
+		}
+		wg.Wait()
+
+		if float64(allowed.Load()) > budget(limiter, clock.Now().Sub(start).Seconds()) {
+			t.Logf("rate=%d burst=%d: %d allowed after %.3fs", limiter.rate, limiter.burst, allowed.Load(), clock.Now().Sub(start).Seconds())
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRateLimiter_PropertyCleanupKeepsLiveBuckets(t *testing.T) {
+	property := func(rate, burst uint8, idleMillis uint16) bool {
+		clock := newFakeClock()
+		limiter := newPropertyLimiter(rate, burst, clock)
+
+		limiter.Allow("key")
+		idle := time.Duration(idleMillis%2001) * time.Millisecond // at most 2x the window
+		clock.Advance(idle)
+		limiter.removeStale(clock.Now())
+
+		limiter.mu.RLock()
+		_, exists := limiter.buckets["key"]
+		limiter.mu.RUnlock()
+		if !exists {
+			t.Logf("bucket idle for %v was removed", idle)
+		}
+		return exists
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+
+	// A bucket dropped while a request holds it is never spent from
+	clock := newFakeClock()
+	limiter := newPropertyLimiter(0, 0, clock) // rate 1, burst 1
+	held := limiter.bucket("key")
+	clock.Advance(time.Hour)
+	limiter.removeStale(clock.Now())
+	if ok, _, _ := limiter.Allow("key"); !ok {
+		t.Error("Allow() should use a fresh bucket after cleanup")
+	}
+	if held.tokens != 1 {
+		t.Errorf("dropped bucket was spent from: %v tokens left", held.tokens)
+	}
+}
+
+func TestRateLimitStats(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	stats := NewRateLimitStats()
+
+	config := AuthEndpointLimiter
+	config.Stats = stats
+	config.HighRiskScore = 50
+	config.HighRiskRate = 1
+	config.HighRiskBurst = 1
+	handler := RateLimit(config, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, ip := range []string{"10.0.0.1:1234", "10.0.0.2:1234", "10.0.0.1:5678"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+		req.RemoteAddr = ip
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	buckets := stats.Buckets()
+	if buckets["auth"] != 2 {
+		t.Errorf("auth buckets = %d, want 2", buckets["auth"])
+	}
+	if got, ok := buckets["auth_high_risk"]; !ok || got != 0 {
+		t.Errorf("auth_high_risk buckets = %d (reported %v), want 0", got, ok)
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+)
+
+// ReadOnlyMode is a runtime switch that rejects writes. It is safe for
+// concurrent use.
+type ReadOnlyMode struct {
+	mu      sync.RWMutex
+	enabled bool
+	reason  string
+}
+
+// NewReadOnlyMode creates a switch, initially off
+func NewReadOnlyMode() *ReadOnlyMode {
+	return &ReadOnlyMode{}
+}
+
+// Enable rejects writes; reason is shown to clients
+func (m *ReadOnlyMode) Enable(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled = true
+	m.reason = reason
+}
+
+// Disable accepts writes again
+func (m *ReadOnlyMode) Disable() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled = false
+	m.reason = ""
+}
+
+// Status reports whether read-only mode is on and why
+func (m *ReadOnlyMode) Status() (bool, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled, m.reason
+}
+
+// ReadOnly returns a middleware that answers requests with unsafe methods
+// with 503 READ_ONLY_MODE while the switch is on. GET, HEAD and OPTIONS
+// requests, such as token validation through /me, keep working.
+func ReadOnly(mode *ReadOnlyMode) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			enabled, reason := mode.Status()
+			if !enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err := domain.ErrReadOnlyMode
+			if reason != "" {
+				err = fmt.Errorf("%w: %s", domain.ErrReadOnlyMode, reason)
+			}
+			w.Header().Set("Retry-After", "60")
+			response.WriteError(w, err)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	mode := NewReadOnlyMode()
+	handler := ReadOnly(mode)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/api/v1/auth/me", nil))
+		return rec
+	}
+
+	if rec := serve(http.MethodPost); rec.Code != http.StatusOK {
+		t.Errorf("write while off: expected status 200, got %d", rec.Code)
+	}
+
+	mode.Enable("database failover in progress")
+
+	if rec := serve(http.MethodGet); rec.Code != http.StatusOK {
+		t.Errorf("read while on: expected status 200, got %d", rec.Code)
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPatch, http.MethodDelete} {
+		rec := serve(method)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s while on: expected status 503, got %d", method, rec.Code)
+		}
+
+		var body struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if body.Code != "READ_ONLY_MODE" || !strings.Contains(body.Message, "database failover in progress") {
+			t.Errorf("unexpected error response: %+v", body)
+		}
+	}
+
+	mode.Disable()
+
+	if rec := serve(http.MethodPost); rec.Code != http.StatusOK {
+		t.Errorf("write after disabling: expected status 200, got %d", rec.Code)
+	}
+}
@@ -1,15 +1,22 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
 )
 
 // SecurityConfig holds security headers configuration
 type SecurityConfig struct {
-	// Content Security Policy
+	// Content Security Policy. Handlers may replace either policy for their
+	// response with httpcontext.SetContentSecurityPolicy.
 	ContentSecurityPolicy string
+	// ContentSecurityPolicyReportOnly is reported on by browsers but not
+	// enforced, for trying out a policy before enforcing it
+	ContentSecurityPolicyReportOnly string
 
 	// Cross-Origin policies
 	CrossOriginEmbedderPolicy string
@@ -110,8 +117,13 @@ func SecurityHeaders(config SecurityConfig) func(http.Handler) http.Handler {
 				return
 			}
 
+			// The CSP headers are set when the response is written, so the
+			// handler can replace them
+			csp := &httpcontext.CSP{Policy: config.ContentSecurityPolicy, ReportOnly: config.ContentSecurityPolicyReportOnly}
+			cw := &cspResponseWriter{ResponseWriter: w, csp: csp}
+			r = r.WithContext(context.WithValue(r.Context(), httpcontext.CSPKey, csp))
+
 			// Set security headers
-			setHeader(w, "Content-Security-Policy", config.ContentSecurityPolicy)
 			setHeader(w, "Cross-Origin-Embedder-Policy", config.CrossOriginEmbedderPolicy)
 			setHeader(w, "Cross-Origin-Opener-Policy", config.CrossOriginOpenerPolicy)
 			setHeader(w, "Cross-Origin-Resource-Policy", config.CrossOriginResourcePolicy)
@@ -132,11 +144,49 @@ func SecurityHeaders(config SecurityConfig) func(http.Handler) http.Handler {
 				w.Header().Set(name, value)
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(cw, r)
+
+			// Handlers that write nothing still get the headers
+			cw.setCSP()
 		})
 	}
 }
 
+// cspResponseWriter sets the CSP headers before the response is written
+type cspResponseWriter struct {
+	http.ResponseWriter
+	csp     *httpcontext.CSP
+	written bool
+}
+
+func (w *cspResponseWriter) WriteHeader(code int) {
+	// The handler may still replace the policy after an interim response
+	if !informational(code) {
+		w.setCSP()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cspResponseWriter) Write(b []byte) (int, error) {
+	w.setCSP()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *cspResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// setCSP sets the CSP headers once
+func (w *cspResponseWriter) setCSP() {
+	if w.written {
+		return
+	}
+	w.written = true
+	setHeader(w, "Content-Security-Policy", w.csp.Policy)
+	setHeader(w, "Content-Security-Policy-Report-Only", w.csp.ReportOnly)
+}
+
 // setHeader sets a header only if the value is not empty
 func setHeader(w http.ResponseWriter, name, value string) {
 	if value != "" {
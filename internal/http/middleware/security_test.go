@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
 )
 
 func TestDefaultSecurityConfig(t *testing.T) {
@@ -205,6 +207,52 @@ func TestSecurityHeaders(t *testing.T) {
 	}
 }
 
+func TestSecurityHeaders_ContentSecurityPolicy(t *testing.T) {
+	config := APISecurityConfig()
+	config.ContentSecurityPolicy = "default-src 'none'"
+	config.ContentSecurityPolicyReportOnly = "default-src 'self'; report-uri /api/v1/csp-reports"
+
+	t.Run("report-only", func(t *testing.T) {
+		handler := SecurityHeaders(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got := w.Header().Get("Content-Security-Policy"); got != config.ContentSecurityPolicy {
+			t.Errorf("Content-Security-Policy = %q", got)
+		}
+		if got := w.Header().Get("Content-Security-Policy-Report-Only"); got != config.ContentSecurityPolicyReportOnly {
+			t.Errorf("Content-Security-Policy-Report-Only = %q", got)
+		}
+	})
+
+	t.Run("overridden by the handler", func(t *testing.T) {
+		handler := SecurityHeaders(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !httpcontext.SetContentSecurityPolicy(r.Context(), "style-src 'unsafe-inline'", "") {
+				t.Error("SetContentSecurityPolicy() = false inside SecurityHeaders")
+			}
+			w.Write([]byte("<html></html>"))
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if got := w.Header().Get("Content-Security-Policy"); got != "style-src 'unsafe-inline'" {
+			t.Errorf("Content-Security-Policy = %q", got)
+		}
+		if got := w.Header().Get("Content-Security-Policy-Report-Only"); got != "" {
+			t.Errorf("Content-Security-Policy-Report-Only = %q, want none", got)
+		}
+	})
+
+	t.Run("outside the middleware", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if httpcontext.SetContentSecurityPolicy(req.Context(), "default-src 'none'", "") {
+			t.Error("SetContentSecurityPolicy() = true without SecurityHeaders")
+		}
+	})
+}
+
 func TestCSPBuilder(t *testing.T) {
 	tests := []struct {
 		name     string
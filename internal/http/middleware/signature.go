@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// SigningKeyStore returns the secret of an active request signing key
+type SigningKeyStore interface {
+	SigningSecret(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// SignatureVerifier checks HMAC-signed requests. Accepted signatures are
+// remembered for the replay window, so a captured request cannot be sent
+// again to the same instance.
+type SignatureVerifier struct {
+	keys    SigningKeyStore
+	maxSkew time.Duration
+	now     func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time // signature -> end of its replay window
+}
+
+// NewSignatureVerifier creates a verifier accepting requests dated within
+// maxSkew of the server clock
+func NewSignatureVerifier(keys SigningKeyStore, maxSkew time.Duration) *SignatureVerifier {
+	return &SignatureVerifier{
+		keys:    keys,
+		maxSkew: maxSkew,
+		now:     time.Now,
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// Verify checks the signature of r and returns the signing key id. signed
+// is false when r carries no signature at all.
+func (v *SignatureVerifier) Verify(r *http.Request) (keyID string, signed bool, err error) {
+	req, signed, err := security.ParseSignedRequest(r)
+	if !signed || err != nil {
+		return "", signed, err
+	}
+
+	secret, err := v.keys.SigningSecret(r.Context(), req.KeyID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrSigningKeyNotFound) {
+			slog.ErrorContext(r.Context(), "failed to look up signing key", "key_id", req.KeyID, "error", err)
+		}
+		return "", true, security.ErrInvalidRequestSignature
+	}
+
+	now := v.now()
+	if err := req.Verify(r, secret, now, v.maxSkew); err != nil {
+		return "", true, err
+	}
+	if !v.remember(req.Signature, req.Date.Add(v.maxSkew), now) {
+		return "", true, security.ErrInvalidRequestSignature
+	}
+
+	return req.KeyID, true, nil
+}
+
+// remember records a signature until expiresAt and reports whether it was new
+func (v *SignatureVerifier) remember(signature string, expiresAt, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for seen, expiry := range v.seen {
+		if now.After(expiry) {
+			delete(v.seen, seen)
+		}
+	}
+
+	if _, replayed := v.seen[signature]; replayed {
+		return false
+	}
+	v.seen[signature] = expiresAt
+	return true
+}
+
+// RequireAdminAuth protects operator endpoints like RequireAdminToken, and
+// also accepts requests signed with an admin signing key when signatures is
+// set. A signed request with a bad signature is rejected even if it would
+// otherwise be allowed.
+func RequireAdminAuth(adminToken string, signatures *SignatureVerifier, next http.Handler) http.Handler {
+	bearer := RequireAdminToken(adminToken, next)
+	if signatures == nil {
+		return bearer
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID, signed, err := signatures.Verify(r)
+		if !signed {
+			bearer.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", security.RequestSignatureScheme)
+			response.WriteError(w, token.ErrInvalidToken)
+			return
+		}
+
+		slog.InfoContext(r.Context(), "signed admin request", "key_id", keyID, "method", r.Method, "path", r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// fakeKeyStore holds the secrets of active signing keys
+type fakeKeyStore map[string][]byte
+
+func (s fakeKeyStore) SigningSecret(ctx context.Context, keyID string) ([]byte, error) {
+	secret, ok := s[keyID]
+	if !ok {
+		return nil, domain.ErrSigningKeyNotFound
+	}
+	return secret, nil
+}
+
+func TestRequireAdminAuth(t *testing.T) {
+	adminToken := "0123456789abcdef0123456789abcdef"
+	secret := []byte("fedcba9876543210fedcba9876543210")
+	verifier := NewSignatureVerifier(fakeKeyStore{"ak_ci": secret}, 5*time.Minute)
+
+	handler := RequireAdminAuth(adminToken, verifier, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	signed := func(keyID string, key []byte, at time.Time) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/dormancy/runs", strings.NewReader(`{}`))
+		if err := security.SignRequest(req, keyID, key, at); err != nil {
+			t.Fatalf("SignRequest() error = %v", err)
+		}
+		return req
+	}
+
+	bearer := httptest.NewRequest(http.MethodGet, "/api/v1/admin/dormancy/runs", nil)
+	bearer.Header.Set("Authorization", "Bearer "+adminToken)
+
+	replayed := signed("ak_ci", secret, time.Now())
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{name: "admin token", req: bearer, wantStatus: http.StatusOK},
+		{name: "signed request", req: replayed, wantStatus: http.StatusOK},
+		{name: "replayed request", req: replayed.Clone(context.Background()), wantStatus: http.StatusUnauthorized},
+		{name: "unknown key", req: signed("ak_revoked", secret, time.Now()), wantStatus: http.StatusUnauthorized},
+		{name: "wrong secret", req: signed("ak_ci", []byte("guess"), time.Now()), wantStatus: http.StatusUnauthorized},
+		{name: "stale date", req: signed("ak_ci", secret, time.Now().Add(-time.Hour)), wantStatus: http.StatusUnauthorized},
+		{name: "unauthenticated", req: httptest.NewRequest(http.MethodGet, "/", nil), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
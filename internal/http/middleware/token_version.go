@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// TokenVersionSource returns the current token version of a user
+type TokenVersionSource interface {
+	Get(ctx context.Context, userID string) (int, error)
+}
+
+// TokenVersion returns a middleware that rejects access tokens issued before
+// the user's token version was last bumped, so revoked sessions stop working
+// before their tokens expire. RequireAuth has to run first. The version is
+// read on every request; if it cannot be read the request is rejected rather
+// than let a revoked token through.
+func TokenVersion(versions TokenVersionSource) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(httpcontext.UserIDKey).(string)
+			claimed, _ := r.Context().Value(httpcontext.TokenVersionKey).(int)
+
+			current, err := versions.Get(r.Context(), userID)
+			if err != nil {
+				if errors.Is(err, domain.ErrUserNotFound) {
+					response.WriteError(w, token.ErrInvalidToken)
+					return
+				}
+				slog.ErrorContext(r.Context(), "failed to get token version", "user_id", userID, "error", err)
+				response.WriteError(w, err)
+				return
+			}
+
+			if claimed < current {
+				response.WriteError(w, token.ErrInvalidToken)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+)
+
+// fakeTokenVersions returns a fixed version or error
+type fakeTokenVersions struct {
+	version int
+	err     error
+}
+
+func (v *fakeTokenVersions) Get(ctx context.Context, userID string) (int, error) {
+	return v.version, v.err
+}
+
+func TestTokenVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		claimed    int
+		versions   *fakeTokenVersions
+		wantStatus int
+	}{
+		{name: "current version", claimed: 2, versions: &fakeTokenVersions{version: 2}, wantStatus: http.StatusOK},
+		{name: "token issued before bump", claimed: 1, versions: &fakeTokenVersions{version: 2}, wantStatus: http.StatusUnauthorized},
+		{name: "token without version claim", versions: &fakeTokenVersions{version: 1}, wantStatus: http.StatusUnauthorized},
+		{name: "never bumped", versions: &fakeTokenVersions{}, wantStatus: http.StatusOK},
+		{name: "deleted user", versions: &fakeTokenVersions{err: domain.ErrUserNotFound}, wantStatus: http.StatusUnauthorized},
+		{name: "lookup failure", versions: &fakeTokenVersions{err: errors.New("connection refused")}, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := TokenVersion(tt.versions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			ctx := context.WithValue(req.Context(), httpcontext.UserIDKey, "123")
+			ctx = context.WithValue(ctx, httpcontext.TokenVersionKey, tt.claimed)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req.WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	httpcontext "github.com/n1rocket/go-auth-jwt/internal/http/context"
+)
+
+// APIVersionHeader names the API version that served a response
+const APIVersionHeader = "API-Version"
+
+// APIVersion tags requests with the API version of their route group, so
+// handlers shared between versions can tell them apart
+func APIVersion(version int) func(http.Handler) http.Handler {
+	value := strconv.Itoa(version)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(APIVersionHeader, value)
+			ctx := context.WithValue(r.Context(), httpcontext.APIVersionKey, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Deprecation describes a route slated for removal
+type Deprecation struct {
+	Since     time.Time // when the route was deprecated
+	Sunset    time.Time // when the route stops being served; unknown when zero
+	Successor string    // path of the route replacing it, if any
+}
+
+// Deprecated announces that a route is deprecated with the Deprecation
+// header (RFC 9745), its removal date with the Sunset header (RFC 8594) and
+// its replacement with a successor-version link
+func Deprecated(d Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+			if !d.Sunset.IsZero() {
+				w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.Successor != "" {
+				w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", d.Successor))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -7,13 +7,20 @@ import (
 
 // SignupRequest represents a user signup request
 type SignupRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email            string `json:"email"`
+	Password         string `json:"password"`
+	Username         string `json:"username,omitempty"`
+	Locale           string `json:"locale,omitempty"`
+	TermsVersion     string `json:"terms_version,omitempty"`
+	MarketingConsent bool   `json:"marketing_consent,omitempty"`
 }
 
 // TrimStrings trims whitespace from string fields
 func (r *SignupRequest) TrimStrings() {
 	r.Email = strings.TrimSpace(r.Email)
+	r.Username = strings.TrimSpace(r.Username)
+	r.Locale = strings.TrimSpace(r.Locale)
+	r.TermsVersion = strings.TrimSpace(r.TermsVersion)
 }
 
 // Validate validates the signup request
@@ -31,19 +38,24 @@ func (r *SignupRequest) Validate() error {
 
 // LoginRequest represents a user login request
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email        string `json:"email,omitempty"`
+	Username     string `json:"username,omitempty"`
+	Password     string `json:"password"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+	SMSCode      string `json:"sms_code,omitempty"`
 }
 
 // TrimStrings trims whitespace from string fields
 func (r *LoginRequest) TrimStrings() {
 	r.Email = strings.TrimSpace(r.Email)
+	r.Username = strings.TrimSpace(r.Username)
+	r.SMSCode = strings.TrimSpace(r.SMSCode)
 }
 
 // Validate validates the login request
 func (r *LoginRequest) Validate() error {
-	if r.Email == "" {
-		return fmt.Errorf("email is required")
+	if r.Email == "" && r.Username == "" {
+		return fmt.Errorf("email or username is required")
 	}
 
 	if r.Password == "" {
@@ -0,0 +1,164 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/http/response"
+)
+
+// Params parses path and query parameters. Each invalid parameter adds a
+// validation error instead of failing at once, so a handler reports them
+// all in one response:
+//
+//	params := request.NewParams(r)
+//	userID := params.PathUUID("id")
+//	limit := params.Int("limit", 20, 1, 100)
+//	if errs := params.Errors(); len(errs) > 0 {
+//		response.WriteValidationError(w, errs)
+//		return
+//	}
+type Params struct {
+	r      *http.Request
+	query  url.Values
+	errors []response.ValidationError
+}
+
+// NewParams creates a parameter parser for a request
+func NewParams(r *http.Request) *Params {
+	return &Params{r: r, query: r.URL.Query()}
+}
+
+// Errors returns the validation errors of the parameters parsed so far
+func (p *Params) Errors() []response.ValidationError {
+	return p.errors
+}
+
+// PathUUID returns the named path value, which must be a UUID
+func (p *Params) PathUUID(name string) string {
+	value := p.r.PathValue(name)
+	if !domain.IsUUID(value) {
+		p.invalid(name, name+" must be a UUID")
+	}
+	return value
+}
+
+// QueryUUID returns the named query parameter, which must be a UUID, or ""
+// when it is absent
+func (p *Params) QueryUUID(name string) string {
+	v := p.query.Get(name)
+	if v != "" && !domain.IsUUID(v) {
+		p.invalid(name, name+" must be a UUID")
+		return ""
+	}
+	return v
+}
+
+// Int returns the named query parameter as an integer between min and max,
+// or def when it is absent
+func (p *Params) Int(name string, def, min, max int) int {
+	v := p.query.Get(name)
+	if v == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(v)
+	if err != nil || parsed < min || parsed > max {
+		p.invalid(name, fmt.Sprintf("%s must be between %d and %d", name, min, max))
+		return def
+	}
+	return parsed
+}
+
+// Bool returns the named query parameter as a boolean, or def when it is
+// absent
+func (p *Params) Bool(name string, def bool) bool {
+	v := p.query.Get(name)
+	if v == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		p.invalid(name, name+" must be true or false")
+		return def
+	}
+	return parsed
+}
+
+// Enum returns the named query parameter, which must be one of allowed, or
+// def when it is absent
+func (p *Params) Enum(name, def string, allowed ...string) string {
+	v := p.query.Get(name)
+	if v == "" {
+		return def
+	}
+	for _, a := range allowed {
+		if v == a {
+			return v
+		}
+	}
+	p.invalid(name, name+" must be one of "+strings.Join(allowed, ", "))
+	return def
+}
+
+// Fields returns the comma-separated field names of the named query
+// parameter, each of which must be one of allowed, or nil when it is absent
+func (p *Params) Fields(name string, allowed ...string) []string {
+	v := p.query.Get(name)
+	if v == "" {
+		return nil
+	}
+	var fields []string
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(allowed, field) {
+			p.invalid(name, name+" must be a comma-separated list of "+strings.Join(allowed, ", "))
+			return nil
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// TimeRange returns the RFC 3339 timestamps of the from and to query
+// parameters. Either may be absent, which leaves it zero; when both are set
+// to must not be before from.
+func (p *Params) TimeRange(fromName, toName string) (from, to time.Time) {
+	from = p.time(fromName)
+	to = p.time(toName)
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		p.invalid(toName, toName+" must not be before "+fromName)
+		return time.Time{}, time.Time{}
+	}
+	return from, to
+}
+
+// time returns the named query parameter as an RFC 3339 timestamp, or the
+// zero time when it is absent or invalid
+func (p *Params) time(name string) time.Time {
+	v := p.query.Get(name)
+	if v == "" {
+		return time.Time{}
+	}
+	parsed, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		p.invalid(name, name+" must be an RFC 3339 timestamp")
+		return time.Time{}
+	}
+	return parsed
+}
+
+// invalid records an invalid parameter
+func (p *Params) invalid(name, message string) {
+	p.errors = append(p.errors, response.ValidationError{Field: name, Message: message, Code: "INVALID_VALUE"})
+}
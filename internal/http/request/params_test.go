@@ -0,0 +1,85 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParams(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/users/x?limit=50&user_id=0b7a2b5e-8c4d-4f7e-9a31-2f6d1c9e8b10&fields=id,+email,id&dry_run=true&reason=force_logout&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z", nil)
+	req.SetPathValue("id", "0b7a2b5e-8c4d-4f7e-9a31-2f6d1c9e8b10")
+
+	params := NewParams(req)
+	if id := params.PathUUID("id"); id != "0b7a2b5e-8c4d-4f7e-9a31-2f6d1c9e8b10" {
+		t.Errorf("PathUUID() = %q", id)
+	}
+	if userID := params.QueryUUID("user_id"); userID != "0b7a2b5e-8c4d-4f7e-9a31-2f6d1c9e8b10" {
+		t.Errorf("QueryUUID() = %q", userID)
+	}
+	if owner := params.QueryUUID("owner"); owner != "" {
+		t.Errorf("QueryUUID() of an absent parameter = %q, want empty", owner)
+	}
+	if limit := params.Int("limit", 20, 1, 100); limit != 50 {
+		t.Errorf("Int() = %d, want 50", limit)
+	}
+	if page := params.Int("page", 1, 1, 10); page != 1 {
+		t.Errorf("Int() of an absent parameter = %d, want the default 1", page)
+	}
+	if fields := params.Fields("fields", "id", "email", "locale"); len(fields) != 2 || fields[0] != "id" || fields[1] != "email" {
+		t.Errorf("Fields() = %v, want [id email]", fields)
+	}
+	if fields := params.Fields("expand", "id"); fields != nil {
+		t.Errorf("Fields() of an absent parameter = %v, want nil", fields)
+	}
+	if !params.Bool("dry_run", false) {
+		t.Error("Bool() = false, want true")
+	}
+	if reason := params.Enum("reason", "", "force_logout", "account_compromise"); reason != "force_logout" {
+		t.Errorf("Enum() = %q", reason)
+	}
+	from, to := params.TimeRange("from", "to")
+	if !from.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("TimeRange() = %v, %v", from, to)
+	}
+	if errs := params.Errors(); len(errs) != 0 {
+		t.Errorf("Errors() = %v, want none", errs)
+	}
+}
+
+func TestParams_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		id    string
+		parse func(p *Params)
+		field string
+	}{
+		{name: "id not a uuid", id: "user-123", parse: func(p *Params) { p.PathUUID("id") }, field: "id"},
+		{name: "query not a uuid", query: "user_id=user-123", parse: func(p *Params) { p.QueryUUID("user_id") }, field: "user_id"},
+		{name: "int not a number", query: "limit=ten", parse: func(p *Params) { p.Int("limit", 20, 1, 100) }, field: "limit"},
+		{name: "int below min", query: "limit=0", parse: func(p *Params) { p.Int("limit", 20, 1, 100) }, field: "limit"},
+		{name: "int above max", query: "limit=101", parse: func(p *Params) { p.Int("limit", 20, 1, 100) }, field: "limit"},
+		{name: "bool", query: "dry_run=maybe", parse: func(p *Params) { p.Bool("dry_run", false) }, field: "dry_run"},
+		{name: "enum", query: "reason=bored", parse: func(p *Params) { p.Enum("reason", "force_logout", "force_logout") }, field: "reason"},
+		{name: "fields", query: "fields=id,password_hash", parse: func(p *Params) { p.Fields("fields", "id", "email") }, field: "fields"},
+		{name: "time not rfc 3339", query: "from=yesterday", parse: func(p *Params) { p.TimeRange("from", "to") }, field: "from"},
+		{name: "range reversed", query: "from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", parse: func(p *Params) { p.TimeRange("from", "to") }, field: "to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			req.SetPathValue("id", tt.id)
+
+			params := NewParams(req)
+			tt.parse(params)
+
+			errs := params.Errors()
+			if len(errs) != 1 || errs[0].Field != tt.field || errs[0].Code != "INVALID_VALUE" {
+				t.Errorf("Errors() = %v, want one INVALID_VALUE error for %s", errs, tt.field)
+			}
+		})
+	}
+}
@@ -161,3 +161,35 @@ func ExtractBearerToken(r *http.Request) (string, error) {
 
 	return token, nil
 }
+
+// ExtractAccessToken extracts the token and its scheme from the Authorization
+// header, accepting the Bearer and DPoP schemes
+func ExtractAccessToken(r *http.Request) (scheme, token string, err error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", "", fmt.Errorf("Authorization header is required")
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "DPoP") {
+		return "", "", fmt.Errorf("Authorization header must use Bearer or DPoP scheme")
+	}
+
+	if err := ValidateToken(parts[1]); err != nil {
+		return "", "", err
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// RequestURL reconstructs the absolute URL the client used for the request,
+// honouring X-Forwarded-Proto from a TLS-terminating proxy
+func RequestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	} else if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
@@ -2,6 +2,7 @@ package request
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -594,3 +595,76 @@ func TestValidationError_Structure(t *testing.T) {
 		t.Errorf("Expected message to contain 'required', got %q", err.Message)
 	}
 }
+
+func FuzzDecodeJSON(f *testing.F) {
+	for _, seed := range []string{
+		`{"email":"user@example.com","password":"password123"}`,
+		`{"email":"user@example.com","unknown":true}`,
+		`{"email":"a"}{"email":"b"}`,
+		`{"email":`,
+		`[]`,
+		`null`,
+		``,
+		`{"email":"\ud800"}`,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, body string) {
+		var dst SignupRequest
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if err := DecodeJSON(req, &dst); err != nil {
+			return
+		}
+
+		if len(body) > MaxRequestBodySize {
+			t.Fatalf("DecodeJSON() accepted a %d byte body", len(body))
+		}
+
+		// Anything accepted must survive a round trip unchanged
+		encoded, err := json.Marshal(dst)
+		if err != nil {
+			t.Fatalf("failed to re-encode %+v: %v", dst, err)
+		}
+		var again SignupRequest
+		req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+		if err := DecodeJSON(req, &again); err != nil {
+			t.Fatalf("DecodeJSON() rejected its own output %s: %v", encoded, err)
+		}
+		if again != dst {
+			t.Fatalf("round trip changed %+v to %+v", dst, again)
+		}
+	})
+}
+
+func FuzzExtractBearerToken(f *testing.F) {
+	for _, seed := range []string{
+		"Bearer abcdefghijklmnop",
+		"bearer abcdefghijklmnop",
+		"Bearer  abcdefghijklmnop",
+		"Bearer",
+		"Bearer short",
+		"Basic dXNlcjpwYXNz",
+		"Bearer " + strings.Repeat("a", 1001),
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, header string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", header)
+
+		token, err := ExtractBearerToken(req)
+		if err != nil {
+			return
+		}
+
+		if req.Header.Get("Authorization") != "Bearer "+token {
+			t.Fatalf("ExtractBearerToken(%q) = %q, which is not the whole credential", header, token)
+		}
+		if strings.TrimSpace(token) == "" || len(token) > 1000 {
+			t.Fatalf("ExtractBearerToken(%q) accepted token %q", header, token)
+		}
+	})
+}
@@ -1,21 +1,32 @@
 package response
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
 )
 
+// StatusClientClosedRequest is the status logged for requests whose client
+// went away before the response (nginx's 499); the client never sees it
+const StatusClientClosedRequest = 499
+
 // ErrorResponse represents the standard error response format
 type ErrorResponse struct {
 	Error   string            `json:"error"`
 	Message string            `json:"message"`
 	Code    string            `json:"code,omitempty"`
 	Details map[string]string `json:"details,omitempty"`
+	// RetryAfter is the number of seconds to wait before retrying, also
+	// sent as the Retry-After header
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
 // WriteError writes an error response to the client
@@ -36,6 +47,7 @@ func WriteError(w http.ResponseWriter, err error) {
 				Message: "Invalid request format",
 				Code:    "INVALID_REQUEST",
 			}
+			errorResponse.Message = localize(w, errorResponse.Code, errorResponse.Message, nil)
 			WriteJSON(w, statusCode, errorResponse)
 			return
 		}
@@ -43,6 +55,20 @@ func WriteError(w http.ResponseWriter, err error) {
 
 	// Map domain errors to HTTP status codes
 	switch {
+	case errors.Is(err, context.Canceled):
+		statusCode = StatusClientClosedRequest
+		errorResponse = ErrorResponse{
+			Error:   "client_closed_request",
+			Message: "Request was canceled",
+			Code:    "REQUEST_CANCELED",
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		statusCode = http.StatusServiceUnavailable
+		errorResponse = ErrorResponse{
+			Error:   "service_unavailable",
+			Message: "Request timed out",
+			Code:    "REQUEST_TIMEOUT",
+		}
 	case errors.Is(err, domain.ErrUserNotFound):
 		statusCode = http.StatusNotFound
 		errorResponse = ErrorResponse{
@@ -57,6 +83,76 @@ func WriteError(w http.ResponseWriter, err error) {
 			Message: "Email already exists",
 			Code:    "DUPLICATE_EMAIL",
 		}
+	case errors.Is(err, domain.ErrDuplicateUsername):
+		statusCode = http.StatusConflict
+		errorResponse = ErrorResponse{
+			Error:   "conflict",
+			Message: "Username already exists",
+			Code:    "USERNAME_TAKEN",
+		}
+	case errors.Is(err, domain.ErrInvalidUsername):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+			Code:    "INVALID_USERNAME",
+		}
+	case errors.Is(err, domain.ErrReservedUsername):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "Username is reserved",
+			Code:    "USERNAME_RESERVED",
+		}
+	case errors.Is(err, domain.ErrInvalidProfile):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+			Code:    "INVALID_PROFILE",
+		}
+	case errors.Is(err, domain.ErrInvalidMetadata):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+			Code:    "INVALID_METADATA",
+		}
+	case errors.Is(err, domain.ErrInvalidAccessWindow):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+			Code:    "INVALID_ACCESS_WINDOW",
+		}
+	case errors.Is(err, domain.ErrAccessWindowNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Access window not found",
+			Code:    "ACCESS_WINDOW_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrMetadataTooLarge):
+		statusCode = http.StatusRequestEntityTooLarge
+		errorResponse = ErrorResponse{
+			Error:   "payload_too_large",
+			Message: "Metadata would exceed its size limit",
+			Code:    "METADATA_TOO_LARGE",
+		}
+	case errors.Is(err, domain.ErrInvalidPageCursor):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "bad_request",
+			Message: "Invalid page cursor",
+			Code:    "INVALID_CURSOR",
+		}
+	case errors.Is(err, domain.ErrAvatarUploadsDisabled):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Avatar uploads are not enabled",
+			Code:    "AVATAR_UPLOADS_DISABLED",
+		}
 	case errors.Is(err, domain.ErrInvalidEmail):
 		statusCode = http.StatusBadRequest
 		errorResponse = ErrorResponse{
@@ -64,6 +160,27 @@ func WriteError(w http.ResponseWriter, err error) {
 			Message: "Invalid email format",
 			Code:    "INVALID_EMAIL",
 		}
+	case errors.Is(err, domain.ErrEmailDomainNotAllowed):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "Email domain is not allowed",
+			Code:    "EMAIL_DOMAIN_NOT_ALLOWED",
+		}
+	case errors.Is(err, domain.ErrDisposableEmail):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "Disposable email addresses are not allowed",
+			Code:    "DISPOSABLE_EMAIL",
+		}
+	case errors.Is(err, domain.ErrUndeliverableEmail):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "Email domain cannot receive mail",
+			Code:    "UNDELIVERABLE_EMAIL",
+		}
 	case errors.Is(err, domain.ErrWeakPassword):
 		statusCode = http.StatusBadRequest
 		errorResponse = ErrorResponse{
@@ -74,6 +191,20 @@ func WriteError(w http.ResponseWriter, err error) {
 				"requirements": "Password must be at least 8 characters long",
 			},
 		}
+	case errors.Is(err, domain.ErrTermsNotAccepted):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "The current terms of service must be accepted",
+			Code:    "TERMS_NOT_ACCEPTED",
+		}
+	case errors.Is(err, domain.ErrInvalidTrackingLink):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "bad_request",
+			Message: "Invalid or tampered email link",
+			Code:    "INVALID_TRACKING_LINK",
+		}
 	case errors.Is(err, domain.ErrInvalidCredentials):
 		statusCode = http.StatusUnauthorized
 		errorResponse = ErrorResponse{
@@ -95,6 +226,344 @@ func WriteError(w http.ResponseWriter, err error) {
 			Message: "Email not verified",
 			Code:    "EMAIL_NOT_VERIFIED",
 		}
+	case errors.Is(err, domain.ErrEmailAlreadyVerified):
+		statusCode = http.StatusConflict
+		errorResponse = ErrorResponse{
+			Error:   "conflict",
+			Message: "Email already verified",
+			Code:    "EMAIL_ALREADY_VERIFIED",
+		}
+	case errors.Is(err, domain.ErrCaptchaRequired):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "Captcha verification required",
+			Code:    "CAPTCHA_REQUIRED",
+		}
+	case errors.Is(err, domain.ErrLoginConfirmationRequired):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "Login must be confirmed by email",
+			Code:    "LOGIN_CONFIRMATION_REQUIRED",
+		}
+	case errors.Is(err, domain.ErrSMSCodeRequired):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "Login must be completed with the code sent by SMS",
+			Code:    "SMS_CODE_REQUIRED",
+		}
+	case errors.Is(err, domain.ErrLoginBlocked):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "Login attempt blocked",
+			Code:    "LOGIN_BLOCKED",
+		}
+	case errors.Is(err, domain.ErrAccountDisabled):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "Account is disabled",
+			Code:    "ACCOUNT_DISABLED",
+		}
+	case errors.Is(err, domain.ErrPermissionDenied):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "Insufficient permissions",
+			Code:    "PERMISSION_DENIED",
+		}
+	case errors.Is(err, domain.ErrConsentRequired):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "The current terms of service must be accepted",
+			Code:    "CONSENT_REQUIRED",
+		}
+	case errors.Is(err, domain.ErrOutsideAccessWindow):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access is not allowed at this time",
+			Code:    "OUTSIDE_ACCESS_WINDOW",
+		}
+	case errors.Is(err, domain.ErrAuthorizationUnavailable):
+		statusCode = http.StatusServiceUnavailable
+		errorResponse = ErrorResponse{
+			Error:   "service_unavailable",
+			Message: "Authorization service unavailable",
+			Code:    "AUTHORIZATION_UNAVAILABLE",
+		}
+	case errors.Is(err, domain.ErrInvalidPhoneNumber):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "Phone number must be in E.164 format, e.g. +15551234567",
+			Code:    "INVALID_PHONE_NUMBER",
+		}
+	case errors.Is(err, domain.ErrPhoneNotVerified):
+		statusCode = http.StatusConflict
+		errorResponse = ErrorResponse{
+			Error:   "conflict",
+			Message: "Phone number must be verified first",
+			Code:    "PHONE_NOT_VERIFIED",
+		}
+	case errors.Is(err, domain.ErrNoTrustedDevice):
+		statusCode = http.StatusConflict
+		errorResponse = ErrorResponse{
+			Error:   "conflict",
+			Message: "No trusted device can approve this login",
+			Code:    "NO_TRUSTED_DEVICE",
+		}
+	case errors.Is(err, domain.ErrTrustedDeviceNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Trusted device not found",
+			Code:    "TRUSTED_DEVICE_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrLoginApprovalNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Login approval not found or expired",
+			Code:    "LOGIN_APPROVAL_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrSessionHandoffNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Handoff code not found or expired",
+			Code:    "SESSION_HANDOFF_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrLoginDenied):
+		statusCode = http.StatusForbidden
+		errorResponse = ErrorResponse{
+			Error:   "forbidden",
+			Message: "Login was denied by a trusted device",
+			Code:    "LOGIN_DENIED",
+		}
+	// Device grant errors use the RFC 8628 error codes clients poll for
+	case errors.Is(err, domain.ErrAuthorizationPending):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "authorization_pending",
+			Message: "The user has not yet approved the device",
+			Code:    "AUTHORIZATION_PENDING",
+		}
+	case errors.Is(err, domain.ErrSlowDown):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "slow_down",
+			Message: "Polling too frequently; increase the interval by 5 seconds",
+			Code:    "SLOW_DOWN",
+		}
+	case errors.Is(err, domain.ErrDeviceAccessDenied):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "access_denied",
+			Message: "The user denied the device",
+			Code:    "ACCESS_DENIED",
+		}
+	case errors.Is(err, domain.ErrDeviceCodeExpired):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "expired_token",
+			Message: "The device code has expired",
+			Code:    "EXPIRED_TOKEN",
+		}
+	case errors.Is(err, domain.ErrInvalidDeviceCode):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "invalid_grant",
+			Message: "Invalid device code",
+			Code:    "INVALID_GRANT",
+		}
+	case errors.Is(err, domain.ErrInvalidUserCode):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "bad_request",
+			Message: "Invalid or expired user code",
+			Code:    "INVALID_USER_CODE",
+		}
+	case errors.Is(err, domain.ErrInvalidClientID):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid client_id",
+			Code:    "INVALID_CLIENT_ID",
+		}
+	case errors.Is(err, domain.ErrInvalidEmailCode):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "bad_request",
+			Message: "Invalid or expired code",
+			Code:    "INVALID_CODE",
+		}
+	case errors.Is(err, domain.ErrInvalidResetGrant):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "bad_request",
+			Message: "Invalid or expired reset grant",
+			Code:    "INVALID_RESET_GRANT",
+		}
+	case errors.Is(err, domain.ErrInvalidPasswordVerifier):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "Password verifier must be 64 lowercase hex characters",
+			Code:    "INVALID_PASSWORD_VERIFIER",
+		}
+	case errors.Is(err, domain.ErrPasswordResetRequired):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "bad_request",
+			Message: "Client-hashed passwords can only be changed by password reset",
+			Code:    "PASSWORD_RESET_REQUIRED",
+		}
+	case errors.Is(err, domain.ErrTooManyCodeAttempts):
+		statusCode = http.StatusTooManyRequests
+		errorResponse = ErrorResponse{
+			Error:   "too_many_attempts",
+			Message: "Too many attempts, request a new code",
+			Code:    "TOO_MANY_ATTEMPTS",
+		}
+	case errors.Is(err, domain.ErrDormancyRunInProgress):
+		statusCode = http.StatusConflict
+		errorResponse = ErrorResponse{
+			Error:   "conflict",
+			Message: "A dormancy run is already in progress",
+			Code:    "DORMANCY_RUN_IN_PROGRESS",
+		}
+	case errors.Is(err, domain.ErrDormancyReportNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Dormancy report not found",
+			Code:    "DORMANCY_REPORT_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrEmailTemplateNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Email template not found",
+			Code:    "EMAIL_TEMPLATE_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrSigningKeyNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Signing key not found",
+			Code:    "SIGNING_KEY_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrServiceAccountNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Service account not found",
+			Code:    "SERVICE_ACCOUNT_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrServiceAccountKeyNotFound):
+		statusCode = http.StatusNotFound
+		errorResponse = ErrorResponse{
+			Error:   "not_found",
+			Message: "Service account key not found",
+			Code:    "SERVICE_ACCOUNT_KEY_NOT_FOUND",
+		}
+	case errors.Is(err, domain.ErrServiceAccountKeyExists):
+		statusCode = http.StatusConflict
+		errorResponse = ErrorResponse{
+			Error:   "conflict",
+			Message: "The key is already registered for the service account",
+			Code:    "SERVICE_ACCOUNT_KEY_EXISTS",
+		}
+	case errors.Is(err, domain.ErrTooManyServiceAccountKeys):
+		statusCode = http.StatusConflict
+		errorResponse = ErrorResponse{
+			Error:   "conflict",
+			Message: "The service account has too many active keys; revoke one first",
+			Code:    "TOO_MANY_SERVICE_ACCOUNT_KEYS",
+		}
+	case errors.Is(err, domain.ErrInvalidPublicKey):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "validation_error",
+			Message: "The key must be a public EC P-256 or RSA (2048 bits or more) JWK",
+			Code:    "INVALID_PUBLIC_KEY",
+		}
+	// Client authentication errors use the RFC 6749 error codes
+	case errors.Is(err, domain.ErrInvalidClientAssertion):
+		statusCode = http.StatusUnauthorized
+		errorResponse = ErrorResponse{
+			Error:   "invalid_client",
+			Message: "Client authentication failed",
+			Code:    "INVALID_CLIENT",
+		}
+	case errors.Is(err, domain.ErrUnsupportedTokenType):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "unsupported_token_type",
+			Message: "The server does not revoke this type of token",
+			Code:    "UNSUPPORTED_TOKEN_TYPE",
+		}
+	case errors.Is(err, domain.ErrInvalidScope):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "invalid_scope",
+			Message: "The requested scope is invalid or not granted",
+			Code:    "INVALID_SCOPE",
+		}
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		statusCode = http.StatusTooManyRequests
+		errorResponse = ErrorResponse{
+			Error:   "quota_exceeded",
+			Message: "Monthly request quota exceeded",
+			Code:    "QUOTA_EXCEEDED",
+		}
+	case errors.Is(err, domain.ErrResendTooSoon):
+		statusCode = http.StatusTooManyRequests
+		errorResponse = ErrorResponse{
+			Error:   "too_many_requests",
+			Message: "Verification email was sent recently; try again later",
+			Code:    "RESEND_TOO_SOON",
+		}
+		var limitErr *domain.ResendLimitError
+		if errors.As(err, &limitErr) {
+			errorResponse.RetryAfter = int(math.Ceil(limitErr.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(errorResponse.RetryAfter))
+		}
+	case errors.Is(err, security.ErrHashingOverloaded):
+		statusCode = http.StatusServiceUnavailable
+		errorResponse = ErrorResponse{
+			Error:      "service_unavailable",
+			Message:    "Server is busy; try again shortly",
+			Code:       "SERVER_BUSY",
+			RetryAfter: 1,
+		}
+		w.Header().Set("Retry-After", "1")
+	case errors.Is(err, domain.ErrReadOnlyMode):
+		statusCode = http.StatusServiceUnavailable
+		errorResponse = ErrorResponse{
+			Error:   "service_unavailable",
+			Message: err.Error(),
+			Code:    "READ_ONLY_MODE",
+		}
+	case errors.Is(err, token.ErrInvalidDPoPProof):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "invalid_dpop_proof",
+			Message: "Invalid DPoP proof",
+			Code:    "INVALID_DPOP_PROOF",
+		}
+	case errors.Is(err, token.ErrDPoPDisabled):
+		statusCode = http.StatusBadRequest
+		errorResponse = ErrorResponse{
+			Error:   "bad_request",
+			Message: "DPoP is not enabled",
+			Code:    "DPOP_NOT_ENABLED",
+		}
 	case errors.Is(err, token.ErrInvalidToken):
 		statusCode = http.StatusUnauthorized
 		errorResponse = ErrorResponse{
@@ -118,6 +587,8 @@ func WriteError(w http.ResponseWriter, err error) {
 		}
 	}
 
+	errorResponse.Message = localize(w, errorResponse.Code, errorResponse.Message, nil)
+
 	// Write JSON response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -142,14 +613,14 @@ type ValidationError struct {
 func WriteValidationError(w http.ResponseWriter, errors []ValidationError) {
 	errorResponse := ErrorResponse{
 		Error:   "validation_error",
-		Message: "Request validation failed",
+		Message: localize(w, "VALIDATION_FAILED", "Request validation failed", nil),
 		Code:    "VALIDATION_FAILED",
 		Details: make(map[string]string),
 	}
 
 	// Add field-specific errors to details
 	for _, ve := range errors {
-		errorResponse.Details[ve.Field] = ve.Message
+		errorResponse.Details[ve.Field] = localize(w, ve.Code, ve.Message, map[string]string{"field": ve.Field})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
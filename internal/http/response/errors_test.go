@@ -1,13 +1,17 @@
 package response
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
 )
 
@@ -68,6 +72,349 @@ func TestWriteError(t *testing.T) {
 			expectedError:  "forbidden",
 			expectedCode:   "EMAIL_NOT_VERIFIED",
 		},
+		{
+			name:           "domain.ErrEmailAlreadyVerified",
+			err:            domain.ErrEmailAlreadyVerified,
+			expectedStatus: http.StatusConflict,
+			expectedError:  "conflict",
+			expectedCode:   "EMAIL_ALREADY_VERIFIED",
+		},
+		{
+			name:           "domain.ErrDisposableEmail",
+			err:            domain.ErrDisposableEmail,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation_error",
+			expectedCode:   "DISPOSABLE_EMAIL",
+		},
+		{
+			name:           "domain.ErrCaptchaRequired",
+			err:            domain.ErrCaptchaRequired,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+			expectedCode:   "CAPTCHA_REQUIRED",
+		},
+		{
+			name:           "domain.ErrLoginBlocked",
+			err:            domain.ErrLoginBlocked,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+			expectedCode:   "LOGIN_BLOCKED",
+		},
+		{
+			name:           "domain.ErrAccountDisabled",
+			err:            domain.ErrAccountDisabled,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+			expectedCode:   "ACCOUNT_DISABLED",
+		},
+		{
+			name:           "domain.ErrDormancyRunInProgress",
+			err:            domain.ErrDormancyRunInProgress,
+			expectedStatus: http.StatusConflict,
+			expectedError:  "conflict",
+			expectedCode:   "DORMANCY_RUN_IN_PROGRESS",
+		},
+		{
+			name:           "domain.ErrDormancyReportNotFound",
+			err:            domain.ErrDormancyReportNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+			expectedCode:   "DORMANCY_REPORT_NOT_FOUND",
+		},
+		{
+			name:           "domain.ErrSigningKeyNotFound",
+			err:            domain.ErrSigningKeyNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+			expectedCode:   "SIGNING_KEY_NOT_FOUND",
+		},
+		{
+			name:           "domain.ErrServiceAccountNotFound",
+			err:            domain.ErrServiceAccountNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+			expectedCode:   "SERVICE_ACCOUNT_NOT_FOUND",
+		},
+		{
+			name:           "domain.ErrServiceAccountKeyExists",
+			err:            domain.ErrServiceAccountKeyExists,
+			expectedStatus: http.StatusConflict,
+			expectedError:  "conflict",
+			expectedCode:   "SERVICE_ACCOUNT_KEY_EXISTS",
+		},
+		{
+			name:           "domain.ErrInvalidClientAssertion",
+			err:            domain.ErrInvalidClientAssertion,
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+			expectedCode:   "INVALID_CLIENT",
+		},
+		{
+			name:           "wrapped domain.ErrInvalidScope",
+			err:            fmt.Errorf("%w: admin is not granted", domain.ErrInvalidScope),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_scope",
+			expectedCode:   "INVALID_SCOPE",
+		},
+		{
+			name:           "domain.ErrQuotaExceeded",
+			err:            domain.ErrQuotaExceeded,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedError:  "quota_exceeded",
+			expectedCode:   "QUOTA_EXCEEDED",
+		},
+		{
+			name:           "context.Canceled",
+			err:            fmt.Errorf("failed to get user: %w", context.Canceled),
+			expectedStatus: StatusClientClosedRequest,
+			expectedError:  "client_closed_request",
+			expectedCode:   "REQUEST_CANCELED",
+		},
+		{
+			name:           "context.DeadlineExceeded",
+			err:            fmt.Errorf("failed to get user: %w", context.DeadlineExceeded),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedError:  "service_unavailable",
+			expectedCode:   "REQUEST_TIMEOUT",
+		},
+		{
+			name:           "domain.ErrResendTooSoon",
+			err:            domain.ErrResendTooSoon,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedError:  "too_many_requests",
+			expectedCode:   "RESEND_TOO_SOON",
+		},
+		{
+			name:           "domain.ErrPermissionDenied",
+			err:            domain.ErrPermissionDenied,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+			expectedCode:   "PERMISSION_DENIED",
+		},
+		{
+			name:           "domain.ErrConsentRequired",
+			err:            domain.ErrConsentRequired,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+			expectedCode:   "CONSENT_REQUIRED",
+		},
+		{
+			name:           "domain.ErrOutsideAccessWindow",
+			err:            domain.ErrOutsideAccessWindow,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+			expectedCode:   "OUTSIDE_ACCESS_WINDOW",
+		},
+		{
+			name:           "domain.ErrInvalidAccessWindow",
+			err:            fmt.Errorf("%w: start must be a time as HH:MM", domain.ErrInvalidAccessWindow),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation_error",
+			expectedCode:   "INVALID_ACCESS_WINDOW",
+		},
+		{
+			name:           "domain.ErrAccessWindowNotFound",
+			err:            domain.ErrAccessWindowNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+			expectedCode:   "ACCESS_WINDOW_NOT_FOUND",
+		},
+		{
+			name:           "domain.ErrTermsNotAccepted",
+			err:            domain.ErrTermsNotAccepted,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation_error",
+			expectedCode:   "TERMS_NOT_ACCEPTED",
+		},
+		{
+			name:           "domain.ErrInvalidTrackingLink",
+			err:            domain.ErrInvalidTrackingLink,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "bad_request",
+			expectedCode:   "INVALID_TRACKING_LINK",
+		},
+		{
+			name:           "domain.ErrAuthorizationUnavailable",
+			err:            domain.ErrAuthorizationUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedError:  "service_unavailable",
+			expectedCode:   "AUTHORIZATION_UNAVAILABLE",
+		},
+		{
+			name:           "domain.ErrInvalidPhoneNumber",
+			err:            domain.ErrInvalidPhoneNumber,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation_error",
+			expectedCode:   "INVALID_PHONE_NUMBER",
+		},
+		{
+			name:           "domain.ErrPhoneNotVerified",
+			err:            domain.ErrPhoneNotVerified,
+			expectedStatus: http.StatusConflict,
+			expectedError:  "conflict",
+			expectedCode:   "PHONE_NOT_VERIFIED",
+		},
+		{
+			name:           "domain.ErrSMSCodeRequired",
+			err:            domain.ErrSMSCodeRequired,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+			expectedCode:   "SMS_CODE_REQUIRED",
+		},
+		{
+			name:           "domain.ErrNoTrustedDevice",
+			err:            domain.ErrNoTrustedDevice,
+			expectedStatus: http.StatusConflict,
+			expectedError:  "conflict",
+			expectedCode:   "NO_TRUSTED_DEVICE",
+		},
+		{
+			name:           "domain.ErrTrustedDeviceNotFound",
+			err:            domain.ErrTrustedDeviceNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+			expectedCode:   "TRUSTED_DEVICE_NOT_FOUND",
+		},
+		{
+			name:           "domain.ErrLoginApprovalNotFound",
+			err:            domain.ErrLoginApprovalNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+			expectedCode:   "LOGIN_APPROVAL_NOT_FOUND",
+		},
+		{
+			name:           "domain.ErrInvalidMetadata",
+			err:            domain.ErrInvalidMetadata,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation_error",
+			expectedCode:   "INVALID_METADATA",
+		},
+		{
+			name:           "domain.ErrMetadataTooLarge",
+			err:            domain.ErrMetadataTooLarge,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedError:  "payload_too_large",
+			expectedCode:   "METADATA_TOO_LARGE",
+		},
+		{
+			name:           "domain.ErrSessionHandoffNotFound",
+			err:            domain.ErrSessionHandoffNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+			expectedCode:   "SESSION_HANDOFF_NOT_FOUND",
+		},
+		{
+			name:           "domain.ErrLoginDenied",
+			err:            domain.ErrLoginDenied,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+			expectedCode:   "LOGIN_DENIED",
+		},
+		{
+			name:           "domain.ErrAuthorizationPending",
+			err:            domain.ErrAuthorizationPending,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "authorization_pending",
+			expectedCode:   "AUTHORIZATION_PENDING",
+		},
+		{
+			name:           "domain.ErrSlowDown",
+			err:            domain.ErrSlowDown,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "slow_down",
+			expectedCode:   "SLOW_DOWN",
+		},
+		{
+			name:           "domain.ErrDeviceAccessDenied",
+			err:            domain.ErrDeviceAccessDenied,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "access_denied",
+			expectedCode:   "ACCESS_DENIED",
+		},
+		{
+			name:           "domain.ErrDeviceCodeExpired",
+			err:            domain.ErrDeviceCodeExpired,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "expired_token",
+			expectedCode:   "EXPIRED_TOKEN",
+		},
+		{
+			name:           "domain.ErrInvalidDeviceCode",
+			err:            domain.ErrInvalidDeviceCode,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_grant",
+			expectedCode:   "INVALID_GRANT",
+		},
+		{
+			name:           "domain.ErrInvalidPageCursor",
+			err:            domain.ErrInvalidPageCursor,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "bad_request",
+			expectedCode:   "INVALID_CURSOR",
+		},
+		{
+			name:           "domain.ErrInvalidUserCode",
+			err:            domain.ErrInvalidUserCode,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "bad_request",
+			expectedCode:   "INVALID_USER_CODE",
+		},
+		{
+			name:           "domain.ErrInvalidClientID",
+			err:            domain.ErrInvalidClientID,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation_error",
+			expectedCode:   "INVALID_CLIENT_ID",
+		},
+		{
+			name:           "domain.ErrInvalidEmailCode",
+			err:            domain.ErrInvalidEmailCode,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "bad_request",
+			expectedCode:   "INVALID_CODE",
+		},
+		{
+			name:           "domain.ErrInvalidResetGrant",
+			err:            domain.ErrInvalidResetGrant,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "bad_request",
+			expectedCode:   "INVALID_RESET_GRANT",
+		},
+		{
+			name:           "domain.ErrInvalidPasswordVerifier",
+			err:            domain.ErrInvalidPasswordVerifier,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation_error",
+			expectedCode:   "INVALID_PASSWORD_VERIFIER",
+		},
+		{
+			name:           "domain.ErrPasswordResetRequired",
+			err:            domain.ErrPasswordResetRequired,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "bad_request",
+			expectedCode:   "PASSWORD_RESET_REQUIRED",
+		},
+		{
+			name:           "domain.ErrTooManyCodeAttempts",
+			err:            domain.ErrTooManyCodeAttempts,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedError:  "too_many_attempts",
+			expectedCode:   "TOO_MANY_ATTEMPTS",
+		},
+		{
+			name:           "domain.ErrReadOnlyMode",
+			err:            domain.ErrReadOnlyMode,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedError:  "service_unavailable",
+			expectedCode:   "READ_ONLY_MODE",
+		},
+		{
+			name:           "security.ErrHashingOverloaded",
+			err:            fmt.Errorf("failed to hash password: %w", security.ErrHashingOverloaded),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedError:  "service_unavailable",
+			expectedCode:   "SERVER_BUSY",
+		},
 		{
 			name:           "token.ErrInvalidToken",
 			err:            token.ErrInvalidToken,
@@ -168,6 +515,26 @@ func TestWriteError_WeakPassword(t *testing.T) {
 	}
 }
 
+func TestWriteError_ResendRetryAfter(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, fmt.Errorf("resend: %w", &domain.ResendLimitError{RetryAfter: 41500 * time.Millisecond}))
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "42" {
+		t.Errorf("Expected Retry-After 42, got %q", got)
+	}
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if resp.RetryAfter != 42 {
+		t.Errorf("Expected retry_after 42, got %d", resp.RetryAfter)
+	}
+}
+
 func TestWriteJSON(t *testing.T) {
 	tests := []struct {
 		name       string
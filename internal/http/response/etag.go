@@ -0,0 +1,58 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ETag returns a strong entity tag for a response body
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified reports whether the request's If-None-Match header matches
+// etag. Weak tags match their strong form, as RFC 9110 requires for GET.
+func NotModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSONWithETag writes a 200 JSON response tagged with the ETag of its
+// body, or an empty 304 when the client already has that body. Callers set
+// Cache-Control and Vary before calling.
+func WriteJSONWithETag(w http.ResponseWriter, r *http.Request, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	// Match the trailing newline written by WriteJSON
+	return WriteBodyWithETag(w, r, "application/json", append(body, '\n'))
+}
+
+// WriteBodyWithETag is WriteJSONWithETag for a body that is already encoded
+func WriteBodyWithETag(w http.ResponseWriter, r *http.Request, contentType string, body []byte) error {
+	etag := ETag(body)
+	w.Header().Set("ETag", etag)
+	if NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,51 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SelectFields keeps only the named fields of data's JSON object, or of each
+// object in its items array when items is set, so clients can fetch just the
+// fields they use. data is returned as is when fields is empty. Fields come
+// out in alphabetical order.
+func SelectFields(data interface{}, items string, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		return nil, fmt.Errorf("failed to select fields: %w", err)
+	}
+	if items == "" {
+		return selectFields(object, fields), nil
+	}
+
+	var list []map[string]json.RawMessage
+	if err := json.Unmarshal(object[items], &list); err != nil {
+		return nil, fmt.Errorf("failed to select fields of %s: %w", items, err)
+	}
+	selected := make([]map[string]json.RawMessage, 0, len(list))
+	for _, item := range list {
+		selected = append(selected, selectFields(item, fields))
+	}
+	if object[items], err = json.Marshal(selected); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func selectFields(object map[string]json.RawMessage, fields []string) map[string]json.RawMessage {
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := object[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected
+}
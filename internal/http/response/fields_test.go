@@ -0,0 +1,58 @@
+package response
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type fieldsItem struct {
+	ID    string  `json:"id"`
+	Email string  `json:"email"`
+	Name  *string `json:"name,omitempty"`
+}
+
+type fieldsList struct {
+	Items      []fieldsItem `json:"items"`
+	NextCursor string       `json:"next_cursor"`
+}
+
+func TestSelectFields(t *testing.T) {
+	item := fieldsItem{ID: "user-1", Email: "a@example.com"}
+	list := fieldsList{Items: []fieldsItem{item, {ID: "user-2", Email: "b@example.com"}}, NextCursor: "next"}
+
+	tests := []struct {
+		name   string
+		data   interface{}
+		items  string
+		fields []string
+		want   string
+	}{
+		{name: "all fields", data: item, want: `{"id":"user-1","email":"a@example.com"}`},
+		{name: "object", data: item, fields: []string{"email"}, want: `{"email":"a@example.com"}`},
+		{name: "omitted field", data: item, fields: []string{"id", "name"}, want: `{"id":"user-1"}`},
+		{name: "list items", data: list, items: "items", fields: []string{"id"}, want: `{"items":[{"id":"user-1"},{"id":"user-2"}],"next_cursor":"next"}`},
+		{name: "empty list", data: fieldsList{Items: []fieldsItem{}}, items: "items", fields: []string{"id"}, want: `{"items":[],"next_cursor":""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectFields(tt.data, tt.items, tt.fields)
+			if err != nil {
+				t.Fatalf("SelectFields() error = %v", err)
+			}
+			got, _ := json.Marshal(selected)
+			if string(got) != tt.want {
+				t.Errorf("SelectFields() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFields_NotAnObject(t *testing.T) {
+	if _, err := SelectFields([]string{"a"}, "", []string{"id"}); err == nil {
+		t.Error("SelectFields() of an array error = nil")
+	}
+	if _, err := SelectFields(fieldsList{}, "next_cursor", []string{"id"}); err == nil {
+		t.Error("SelectFields() of items that are not an array error = nil")
+	}
+}
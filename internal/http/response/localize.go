@@ -0,0 +1,25 @@
+package response
+
+import "net/http"
+
+// Localizer translates the message of an error response by its code. The
+// Localize middleware puts one on the response writer, for the language
+// the client asked for.
+type Localizer interface {
+	Localize(code, message string, params map[string]string) string
+}
+
+// localize translates message with the Localizer of w or of a writer it
+// wraps, and returns it unchanged when there is none
+func localize(w http.ResponseWriter, code, message string, params map[string]string) string {
+	for {
+		if l, ok := w.(Localizer); ok {
+			return l.Localize(code, message, params)
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return message
+		}
+		w = u.Unwrap()
+	}
+}
@@ -0,0 +1,36 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type codeLocalizer struct {
+	http.ResponseWriter
+}
+
+func (codeLocalizer) Localize(code, message string, params map[string]string) string {
+	return code
+}
+
+type wrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w wrappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestLocalize_FindsWrappedLocalizer(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	if got := localize(rec, "USER_NOT_FOUND", "User not found", nil); got != "User not found" {
+		t.Errorf("without a localizer: localize() = %q", got)
+	}
+
+	w := wrappingWriter{wrappingWriter{codeLocalizer{rec}}}
+	if got := localize(w, "USER_NOT_FOUND", "User not found", nil); got != "USER_NOT_FOUND" {
+		t.Errorf("wrapped localizer: localize() = %q", got)
+	}
+}
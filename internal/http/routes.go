@@ -2,48 +2,546 @@ package http
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/authz"
+	"github.com/n1rocket/go-auth-jwt/internal/errreport"
 	"github.com/n1rocket/go-auth-jwt/internal/http/handlers"
 	"github.com/n1rocket/go-auth-jwt/internal/http/middleware"
+	"github.com/n1rocket/go-auth-jwt/internal/i18n"
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
+	"github.com/n1rocket/go-auth-jwt/internal/risk"
 	"github.com/n1rocket/go-auth-jwt/internal/service"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
 )
 
+// RouteOptions enables optional route groups and instrumentation
+type RouteOptions struct {
+	Admin           *handlers.AdminHandler        // serves /api/v1/admin when set
+	AdminToken      string                        // bearer token required by the admin routes
+	AdminSignatures *middleware.SignatureVerifier // also accepts signed admin requests when set
+	Metrics         *metrics.Metrics              // records request and rate limiter metrics when set
+	MaxRouteLabels  int                           // distinct route labels of request metrics; middleware.DefaultMaxRouteLabels when zero
+
+	Quota             *service.QuotaService // enforces monthly quotas on protected routes when set
+	QuotaTenantHeader string                // trusted header naming the tenant; quotas are per user without it
+
+	ServiceAccounts *service.ServiceAccountService // serves the service account token endpoint, and its admin routes with Admin, when set
+
+	RememberMeCookie *handlers.RememberMeCookie // keeps remember-me sessions in a signed cookie when set
+
+	TokenResponseFormat handlers.TokenResponseFormat // shape of login and refresh responses when clients do not negotiate one; native when empty
+
+	ReadOnly *middleware.ReadOnlyMode // rejects writes on the public API while on; admin routes are exempt
+
+	TokenVersions       middleware.TokenVersionSource        // rejects access tokens of revoked sessions when set
+	AccessTokenDenylist middleware.AccessTokenDenylistSource // rejects access tokens revoked through /api/v1/auth/revoke when set
+
+	Policy *authz.Policy // maps every route to the access it requires when set
+
+	Decider              authz.Decider // asks a policy engine about every API request when set
+	DecisionTenantHeader string        // trusted header naming the tenant sent to the policy engine
+	DecisionFailOpen     bool          // lets requests through when the policy engine cannot decide
+
+	JWKSMaxAge  time.Duration // how long the JWKS may be cached; DefaultJWKSMaxAge when zero
+	OpenAPISpec []byte        // serves /api/v1/openapi.json when set
+
+	AppLinks *handlers.AppLinks // serves the iOS and Android app association files when set
+
+	AllowAnyOrigin bool // accepts cross-origin requests from any origin, for local development
+
+	EarlyHints []string // Link header values sent in a 103 Early Hints response before the device verification page
+
+	CSPReportOnly string // Content-Security-Policy-Report-Only policy, reporting to /api/v1/csp-reports, when set
+
+	ErrorReports *errreport.Client // reports panics and 5xx responses when set
+
+	Messages *i18n.Bundle // translates error messages into the language of Accept-Language when set
+
+	Email     *service.AuthServiceWithEmail // sends verification, reset and login emails when set
+	Readiness *handlers.Readiness           // serves /ready, reporting degraded components, when set
+
+	BotDetector    *risk.BotDetector // scores requests to the auth routes and limits likely bots harder when set
+	RiskExemptions *risk.Exemptions  // trusted automation that skips bot scoring and login risk checks when set
+
+	ShadowRateLimits []string                   // rate limit policies, such as "auth", that log would-be denials instead of enforcing them
+	RateLimitKeys    map[string]string          // key specs by rate limit policy, such as "api": "claim:client_id"; see middleware.ParseKeyFunc
+	RateLimitStats   *middleware.RateLimitStats // counts the buckets of every limiter when set
+
+	APIv2        bool                              // serves the core auth routes under /api/v2 as well
+	Deprecations map[string]middleware.Deprecation // deprecation headers by route pattern, such as "POST /api/v1/auth/login"
+}
+
 // Routes configures and returns the HTTP routes
 func Routes(authService *service.AuthService, tokenManager *token.Manager) http.Handler {
+	return RoutesWithOptions(authService, tokenManager, RouteOptions{})
+}
+
+// RoutesWithOptions configures the HTTP routes with optional features. It
+// panics if opts.Policy misses a route; use NewRouter to handle the error.
+func RoutesWithOptions(authService *service.AuthService, tokenManager *token.Manager, opts RouteOptions) http.Handler {
+	handler, err := NewRouter(authService, tokenManager, opts)
+	if err != nil {
+		panic(err)
+	}
+	return handler
+}
+
+// NewRouter configures the HTTP routes with optional features. With a
+// policy, every registered route must have a rule.
+func NewRouter(authService *service.AuthService, tokenManager *token.Manager, opts RouteOptions) (http.Handler, error) {
 	mux := http.NewServeMux()
 	logger := slog.Default()
 
+	// The policy engine decides on every API route once the mux has matched
+	// it, so it sees the route pattern
+	decide := func(next http.Handler) http.Handler { return next }
+	if opts.Decider != nil {
+		decide = middleware.PolicyDecision(opts.Decider, tokenManager, opts.DecisionTenantHeader, opts.DecisionFailOpen)
+	}
+
+	// Registered patterns are checked against the policy
+	var patterns []string
+	deprecated := make(map[string]bool, len(opts.Deprecations))
+	handle := func(pattern string, handler http.Handler) {
+		if d, ok := opts.Deprecations[pattern]; ok {
+			handler = middleware.Deprecated(d)(handler)
+			deprecated[pattern] = true
+		}
+		if version, ok := routeAPIVersion(pattern); ok {
+			handler = middleware.APIVersion(version)(handler)
+		}
+		mux.Handle(pattern, decide(handler))
+		patterns = append(patterns, pattern)
+	}
+	// Probes stay available while the policy engine is down
+	handleProbe := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, handler)
+		patterns = append(patterns, pattern)
+	}
+
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(authService)
+	if opts.Email != nil {
+		authHandler.SetEmailService(opts.Email)
+	}
+	if opts.ServiceAccounts != nil {
+		authHandler.SetServiceAccounts(opts.ServiceAccounts)
+	}
+	if opts.TokenResponseFormat != "" {
+		authHandler.SetTokenResponseFormat(opts.TokenResponseFormat)
+	}
+	if opts.RememberMeCookie != nil {
+		authHandler.SetRememberMeCookie(opts.RememberMeCookie)
+	}
 
 	// Create rate limiters
-	authLimiter := middleware.RateLimit(middleware.AuthEndpointLimiter, logger)
-	apiLimiter := middleware.RateLimit(middleware.APIEndpointLimiter, logger)
+	authLimiterConfig := middleware.AuthEndpointLimiter
+	apiLimiterConfig := middleware.APIEndpointLimiter
+	if opts.Metrics != nil {
+		authLimiterConfig.Metrics = opts.Metrics.RateLimit
+		apiLimiterConfig.Metrics = opts.Metrics.RateLimit
+	}
+	if opts.BotDetector != nil {
+		authLimiterConfig.HighRiskScore = opts.BotDetector.HighRiskScore()
+	}
+	authLimiterConfig.Stats = opts.RateLimitStats
+	apiLimiterConfig.Stats = opts.RateLimitStats
+	authLimiterConfig.Shadow = slices.Contains(opts.ShadowRateLimits, authLimiterConfig.Name)
+	apiLimiterConfig.Shadow = slices.Contains(opts.ShadowRateLimits, apiLimiterConfig.Name)
+	for _, limiterConfig := range []*middleware.RateLimitConfig{&authLimiterConfig, &apiLimiterConfig} {
+		spec := opts.RateLimitKeys[limiterConfig.Name]
+		if spec == "" {
+			continue
+		}
+		keyFunc, err := middleware.ParseKeyFunc(spec, tokenManager)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit key for %s: %w", limiterConfig.Name, err)
+		}
+		limiterConfig.KeyFunc = keyFunc
+	}
+	authLimiter := middleware.RateLimit(authLimiterConfig, logger)
+	apiLimiter := middleware.RateLimit(apiLimiterConfig, logger)
+
+	// Bot detection runs ahead of the auth limiter, which is where
+	// credential stuffing lands, so the limiter and the login risk engine
+	// see the score
+	if opts.BotDetector != nil {
+		detect := middleware.BotDetection(opts.BotDetector)
+		limit := authLimiter
+		authLimiter = func(next http.Handler) http.Handler { return detect(limit(next)) }
+	}
+	// Trusted automation is recognised first so bot scoring skips it
+	if opts.RiskExemptions != nil {
+		exempt := middleware.RiskExemption(opts.RiskExemptions)
+		limit := authLimiter
+		authLimiter = func(next http.Handler) http.Handler { return exempt(limit(next)) }
+	}
+
+	// Monthly quotas count requests once the caller is authenticated
+	quota := func(next http.Handler) http.Handler { return next }
+	quotaKey := middleware.QuotaKeyFunc(opts.QuotaTenantHeader)
+	if opts.Quota != nil {
+		quota = middleware.Quota(opts.Quota, quotaKey)
+	}
+
+	// Token versions are checked once the caller is authenticated, so
+	// revoked sessions stop working before their access tokens expire
+	tokenVersion := func(next http.Handler) http.Handler { return next }
+	if opts.TokenVersions != nil {
+		tokenVersion = middleware.TokenVersion(opts.TokenVersions)
+	}
+	// Revoked access tokens are rejected along with old token versions, so
+	// every route checking one checks both
+	if opts.AccessTokenDenylist != nil {
+		deny := middleware.AccessTokenDenylist(opts.AccessTokenDenylist)
+		version := tokenVersion
+		tokenVersion = func(next http.Handler) http.Handler { return version(deny(next)) }
+	}
+	// Access windows travel in the access token, so every authenticated
+	// route checks them, session routes included
+	if authService.AccessWindowsEnabled() {
+		window := middleware.AccessWindow(authService)
+		version := tokenVersion
+		tokenVersion = func(next http.Handler) http.Handler { return version(window(next)) }
+	}
+	// Users who have not accepted the current terms of service are sent to
+	// accept them. Session routes skip the check, so they can still read
+	// their account, answer the consent and sign out.
+	session := tokenVersion
+	if authService.ConsentsEnabled() {
+		consent := middleware.RequireConsent(authService)
+		version := tokenVersion
+		tokenVersion = func(next http.Handler) http.Handler { return version(consent(next)) }
+	}
+
+	// Read-only mode rejects writes before they reach the handlers; reads
+	// such as token validation through /me are not wrapped
+	readOnly := func(next http.Handler) http.Handler { return next }
+	if opts.ReadOnly != nil {
+		readOnly = middleware.ReadOnly(opts.ReadOnly)
+	}
 
 	// Public routes with strict rate limiting
-	mux.Handle("POST /api/v1/auth/signup", authLimiter(http.HandlerFunc(authHandler.Signup)))
-	mux.Handle("POST /api/v1/auth/login", authLimiter(http.HandlerFunc(authHandler.Login)))
-	mux.Handle("POST /api/v1/auth/refresh", authLimiter(http.HandlerFunc(authHandler.Refresh)))
-	mux.Handle("POST /api/v1/auth/verify-email", authLimiter(http.HandlerFunc(authHandler.VerifyEmail)))
+	handle("POST /api/v1/auth/signup", authLimiter(readOnly(http.HandlerFunc(authHandler.Signup))))
+	handle("POST /api/v1/auth/login", authLimiter(readOnly(http.HandlerFunc(authHandler.Login))))
+	handle("POST /api/v1/auth/refresh", authLimiter(readOnly(http.HandlerFunc(authHandler.Refresh))))
+	handle("POST /api/v1/auth/verify-email", authLimiter(readOnly(http.HandlerFunc(authHandler.VerifyEmail))))
+	handle("GET /api/v1/auth/username-available", authLimiter(http.HandlerFunc(authHandler.UsernameAvailable)))
+
+	// Resending needs something to send the email with
+	if opts.Email != nil {
+		handle("POST /api/v1/auth/verify-email/resend", authLimiter(readOnly(http.HandlerFunc(authHandler.ResendVerification))))
+	}
+
+	// One-time email codes
+	if authService.EmailCodesEnabled() {
+		handle("POST /api/v1/auth/verify-email/code", authLimiter(readOnly(http.HandlerFunc(authHandler.VerifyEmailCode))))
+		handle("POST /api/v1/auth/password-reset", authLimiter(readOnly(http.HandlerFunc(authHandler.RequestPasswordReset))))
+		handle("POST /api/v1/auth/password-reset/confirm", authLimiter(readOnly(http.HandlerFunc(authHandler.ConfirmPasswordReset))))
+	}
+
+	// Passwords hashed by the client, reset with a grant for a reset code
+	if authService.ClientPasswordHashingEnabled() {
+		handle("POST /api/v1/auth/login/params", authLimiter(http.HandlerFunc(authHandler.LoginParams)))
+		if authService.EmailCodesEnabled() {
+			handle("POST /api/v1/auth/password-reset/grant", authLimiter(readOnly(http.HandlerFunc(authHandler.GrantPasswordReset))))
+			handle("POST /api/v1/auth/password-reset/verifier", authLimiter(readOnly(http.HandlerFunc(authHandler.ResetClientHashedPassword))))
+		}
+	}
+
+	// Phone verification, SMS second factor and SMS recovery
+	if authService.SMSEnabled() {
+		handle("POST /api/v1/auth/recovery/sms", authLimiter(readOnly(http.HandlerFunc(authHandler.RequestSMSRecovery))))
+		handle("POST /api/v1/auth/recovery/sms/confirm", authLimiter(readOnly(http.HandlerFunc(authHandler.ConfirmSMSRecovery))))
+		handle("POST /api/v1/auth/me/phone",
+			authLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.AddPhone)))))))
+		handle("POST /api/v1/auth/me/phone/verify",
+			authLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.VerifyPhone)))))))
+		handle("PUT /api/v1/auth/me/phone/second-factor",
+			apiLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.SetSMSSecondFactor)))))))
+	}
+
+	// Logins approved from a trusted device. Waiting clients poll every few
+	// seconds, so polling gets the API limit rather than the auth limit.
+	if authService.LoginApprovalsEnabled() {
+		handle("POST /api/v1/auth/login/approvals", authLimiter(readOnly(http.HandlerFunc(authHandler.StartLoginApproval))))
+		handle("POST /api/v1/auth/login/approvals/{id}/token", apiLimiter(readOnly(http.HandlerFunc(authHandler.PollLoginApproval))))
+		handle("GET /api/v1/auth/me/devices",
+			apiLimiter(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.ListTrustedDevices))))))
+		handle("POST /api/v1/auth/me/devices",
+			authLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.RegisterTrustedDevice)))))))
+		handle("DELETE /api/v1/auth/me/devices/{id}",
+			apiLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.RemoveTrustedDevice)))))))
+		handle("GET /api/v1/auth/me/login-approvals",
+			apiLimiter(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.ListLoginApprovals))))))
+		handle("POST /api/v1/auth/me/login-approvals/{id}",
+			authLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.DecideLoginApproval)))))))
+	}
+
+	// Session handoff: a signed-in device shows a code, e.g. as a QR code,
+	// that a new device trades for its own session. The code is a
+	// credential, so exchanging it gets the auth limit.
+	if authService.SessionHandoffEnabled() {
+		handle("POST /api/v1/auth/me/handoffs",
+			authLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.CreateSessionHandoff)))))))
+		handle("POST /api/v1/auth/handoffs/token", authLimiter(readOnly(http.HandlerFunc(authHandler.ExchangeSessionHandoff))))
+	}
+
+	// Device authorization grant for CLIs and TVs. Devices poll for tokens,
+	// so polling gets the API limit; the verification page takes passwords,
+	// so submitting it gets the auth limit.
+	if authService.DeviceAuthorizationEnabled() {
+		handle("POST /api/v1/auth/device/code", authLimiter(readOnly(http.HandlerFunc(authHandler.StartDeviceAuthorization))))
+		handle("POST /api/v1/auth/device/token", apiLimiter(readOnly(http.HandlerFunc(authHandler.DeviceToken))))
+		handle("POST /api/v1/auth/device/verify",
+			authLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.VerifyDevice)))))))
+		handle("GET /device", apiLimiter(middleware.EarlyHints(opts.EarlyHints)(http.HandlerFunc(authHandler.DevicePage))))
+		handle("POST /device", authLimiter(readOnly(http.HandlerFunc(authHandler.DevicePage))))
+	}
+
+	// Service accounts trade a signed assertion for an access token; the
+	// assertion is a credential, so the endpoint gets the auth limit
+	var serviceAccounts *handlers.ServiceAccountHandler
+	if opts.ServiceAccounts != nil {
+		serviceAccounts = handlers.NewServiceAccountHandler(opts.ServiceAccounts)
+		handle("POST /api/v1/auth/service-accounts/token", authLimiter(readOnly(http.HandlerFunc(serviceAccounts.Token))))
+	}
+
+	// Token revocation (RFC 7009). Users authenticate with their access
+	// token and service accounts with a client assertion in the body, so only
+	// requests with an Authorization header go through RequireAuth.
+	revoke := http.HandlerFunc(authHandler.Revoke)
+	revokeWithToken := middleware.RequireAuth(tokenManager, session(quota(revoke)))
+	handle("POST /api/v1/auth/revoke", authLimiter(readOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			revokeWithToken.ServeHTTP(w, r)
+			return
+		}
+		revoke.ServeHTTP(w, r)
+	}))))
 
 	// Protected routes with API rate limiting
-	mux.Handle("POST /api/v1/auth/logout",
-		apiLimiter(middleware.RequireAuth(tokenManager, http.HandlerFunc(authHandler.Logout))))
-	mux.Handle("POST /api/v1/auth/logout-all",
-		apiLimiter(middleware.RequireAuth(tokenManager, http.HandlerFunc(authHandler.LogoutAll))))
-	mux.Handle("GET /api/v1/auth/me",
-		apiLimiter(middleware.RequireAuth(tokenManager, http.HandlerFunc(authHandler.GetCurrentUser))))
+	handle("POST /api/v1/auth/logout",
+		apiLimiter(readOnly(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.Logout)))))))
+	handle("POST /api/v1/auth/logout-all",
+		apiLimiter(readOnly(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.LogoutAll)))))))
+	handle("GET /api/v1/auth/me",
+		apiLimiter(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.GetCurrentUser))))))
+	handle("PATCH /api/v1/auth/me",
+		apiLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.UpdateCurrentUser)))))))
+	handle("POST /api/v1/auth/me/avatar-upload-url",
+		apiLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.CreateAvatarUpload)))))))
+	handle("POST /api/v1/auth/me/password",
+		apiLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.ChangePassword)))))))
+	if authService.UserMetadataEnabled() {
+		handle("PATCH /api/v1/auth/me/metadata",
+			apiLimiter(readOnly(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.UpdateMetadata)))))))
+	}
+	if authService.SecurityEventsEnabled() {
+		handle("GET /api/v1/auth/me/security-events",
+			apiLimiter(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.ListSecurityEvents))))))
+	}
+	handle("GET /api/v1/auth/userinfo",
+		apiLimiter(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.UserInfo))))))
+
+	// Short-lived tokens for WebSocket and SSE handshakes, checked by socket
+	// servers with pkg/authmiddleware. Issuing one writes nothing, so
+	// read-only mode lets it through.
+	handle("POST /api/v1/auth/connection-token",
+		apiLimiter(middleware.RequireAuth(tokenManager, tokenVersion(quota(http.HandlerFunc(authHandler.CreateConnectionToken))))))
+
+	// Terms of service and marketing consent, answerable without having
+	// accepted the current terms
+	if authService.ConsentsEnabled() {
+		handle("GET /api/v1/auth/me/consents",
+			apiLimiter(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.GetConsents))))))
+		handle("POST /api/v1/auth/me/consents",
+			apiLimiter(readOnly(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.RecordConsent)))))))
+	}
+
+	// Email click and open tracking. The redirect and pixel are public since
+	// mail clients follow them without credentials; the do-not-track
+	// preference is a privacy choice, so it is open before accepting terms.
+	if authService.EmailTrackingEnabled() {
+		handle("GET /api/v1/email/click", apiLimiter(http.HandlerFunc(authHandler.TrackEmailClick)))
+		if authService.EmailTracker().PixelEnabled() {
+			handle("GET /api/v1/email/open", apiLimiter(http.HandlerFunc(authHandler.TrackEmailOpen)))
+		}
+		handle("GET /api/v1/auth/me/email-tracking",
+			apiLimiter(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.GetEmailTracking))))))
+		handle("PUT /api/v1/auth/me/email-tracking",
+			apiLimiter(readOnly(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.UpdateEmailTracking)))))))
+	}
+
+	// Version 2 scaffold: the core auth routes, served by the same handlers.
+	// Handlers branch on the version where responses differ.
+	if opts.APIv2 {
+		handle("POST /api/v2/auth/signup", authLimiter(readOnly(http.HandlerFunc(authHandler.Signup))))
+		handle("POST /api/v2/auth/login", authLimiter(readOnly(http.HandlerFunc(authHandler.Login))))
+		handle("POST /api/v2/auth/refresh", authLimiter(readOnly(http.HandlerFunc(authHandler.Refresh))))
+		handle("POST /api/v2/auth/logout",
+			apiLimiter(readOnly(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.Logout)))))))
+		handle("GET /api/v2/auth/me",
+			apiLimiter(middleware.RequireAuth(tokenManager, session(quota(http.HandlerFunc(authHandler.GetCurrentUser))))))
+	}
+
+	// Quota usage of the caller, not counted against the quota itself
+	if opts.Quota != nil {
+		quotaHandler := handlers.NewQuotaHandler(opts.Quota, quotaKey)
+		handle("GET /api/v1/quota",
+			apiLimiter(middleware.RequireAuth(tokenManager, tokenVersion(http.HandlerFunc(quotaHandler.Usage)))))
+	}
+
+	// Admin routes, authenticated with the operator token or a signing key
+	if admin := opts.Admin; admin != nil && admin.DormancyEnabled() {
+		handle("POST /api/v1/admin/dormancy/runs",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.RunDormancy))))
+		handle("GET /api/v1/admin/dormancy/runs",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.ListDormancyRuns))))
+		handle("GET /api/v1/admin/dormancy/runs/{id}",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.GetDormancyRun))))
+	}
+
+	// Signing keys are only managed with the operator token, so a leaked key
+	// cannot mint more keys
+	if admin := opts.Admin; admin != nil && admin.SigningKeysEnabled() {
+		handle("POST /api/v1/admin/signing-keys",
+			apiLimiter(middleware.RequireAdminToken(opts.AdminToken, http.HandlerFunc(admin.CreateSigningKey))))
+		handle("GET /api/v1/admin/signing-keys",
+			apiLimiter(middleware.RequireAdminToken(opts.AdminToken, http.HandlerFunc(admin.ListSigningKeys))))
+		handle("DELETE /api/v1/admin/signing-keys/{id}",
+			apiLimiter(middleware.RequireAdminToken(opts.AdminToken, http.HandlerFunc(admin.RevokeSigningKey))))
+	}
+
+	// Service accounts and the public keys they sign assertions with. Like
+	// signing keys, they are only managed with the operator token, so a
+	// leaked signing key cannot register credentials.
+	if opts.Admin != nil && serviceAccounts != nil {
+		adminAuth := func(h http.HandlerFunc) http.Handler {
+			return apiLimiter(middleware.RequireAdminToken(opts.AdminToken, h))
+		}
+		handle("POST /api/v1/admin/service-accounts", adminAuth(serviceAccounts.CreateServiceAccount))
+		handle("GET /api/v1/admin/service-accounts", adminAuth(serviceAccounts.ListServiceAccounts))
+		handle("GET /api/v1/admin/service-accounts/{id}", adminAuth(serviceAccounts.GetServiceAccount))
+		handle("PUT /api/v1/admin/service-accounts/{id}/scopes", adminAuth(serviceAccounts.UpdateServiceAccountScopes))
+		handle("DELETE /api/v1/admin/service-accounts/{id}", adminAuth(serviceAccounts.DeleteServiceAccount))
+		handle("POST /api/v1/admin/service-accounts/{id}/keys", adminAuth(serviceAccounts.RegisterServiceAccountKey))
+		handle("POST /api/v1/admin/service-accounts/{id}/keys/{kid}/rotate", adminAuth(serviceAccounts.RotateServiceAccountKey))
+		handle("DELETE /api/v1/admin/service-accounts/{id}/keys/{kid}", adminAuth(serviceAccounts.RevokeServiceAccountKey))
+	}
+
+	// Session revocation for forced logouts and compromised accounts
+	if admin := opts.Admin; admin != nil && admin.SessionsEnabled() {
+		handle("POST /api/v1/admin/users/{id}/revoke-sessions",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.RevokeUserSessions))))
+	}
+
+	// User lookup by metadata and edits to the admin-only app_metadata
+	if admin := opts.Admin; admin != nil && admin.UsersEnabled() {
+		handle("GET /api/v1/admin/users",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.ListUsers))))
+		handle("PATCH /api/v1/admin/users/{id}/app-metadata",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.UpdateAppMetadata))))
+	}
+
+	// Daily access windows that limit when users' access tokens are accepted
+	if admin := opts.Admin; admin != nil && admin.AccessWindowsEnabled() {
+		handle("GET /api/v1/admin/users/{id}/access-window",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.GetAccessWindow))))
+		handle("PUT /api/v1/admin/users/{id}/access-window",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.SetAccessWindow))))
+		handle("DELETE /api/v1/admin/users/{id}/access-window",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.ClearAccessWindow))))
+	}
+
+	// Session counts, ages and devices from active refresh tokens
+	if admin := opts.Admin; admin != nil && admin.AnalyticsEnabled() {
+		handle("GET /api/v1/admin/analytics/sessions",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.SessionAnalytics))))
+	}
+
+	// Operational data for incident triage
+	if admin := opts.Admin; admin != nil && admin.StatusEnabled() {
+		handle("GET /api/v1/admin/status",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.Status))))
+	}
+
+	// Redelivery of user events to downstream systems that missed them
+	if admin := opts.Admin; admin != nil && admin.UserEventsEnabled() {
+		handle("POST /api/v1/admin/user-events/replay",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.ReplayUserEvents))))
+	}
+
+	// Email template previews, so template changes can be checked without
+	// emailing users
+	if admin := opts.Admin; admin != nil && admin.EmailsEnabled() {
+		handle("GET /api/v1/admin/email-templates",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.ListEmailTemplates))))
+		handle("GET /api/v1/admin/email-templates/{name}/preview",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.PreviewEmailTemplate))))
+		handle("POST /api/v1/admin/email-templates/{name}/test-send",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.SendTestEmail))))
+	}
+
+	// Read-only mode stays switchable while it rejects writes elsewhere
+	if admin := opts.Admin; admin != nil && admin.ReadOnlyEnabled() {
+		handle("GET /api/v1/admin/read-only",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.ReadOnlyStatus))))
+		handle("PUT /api/v1/admin/read-only",
+			apiLimiter(middleware.RequireAdminAuth(opts.AdminToken, opts.AdminSignatures, http.HandlerFunc(admin.UpdateReadOnly))))
+	}
+
+	// Signing keys for resource servers, with RS256, and the token
+	// encryption key when enabled. Gateways fetch these often, so they get
+	// the API limit and caching headers.
+	if _, err := tokenManager.GetJWKS(); err == nil {
+		jwksMaxAge := opts.JWKSMaxAge
+		if jwksMaxAge == 0 {
+			jwksMaxAge = handlers.DefaultJWKSMaxAge
+		}
+		handle("GET /.well-known/jwks.json", apiLimiter(handlers.JWKS(tokenManager, jwksMaxAge)))
+	}
+	if opts.OpenAPISpec != nil {
+		handle("GET /api/v1/openapi.json", apiLimiter(handlers.OpenAPI(opts.OpenAPISpec)))
+	}
+
+	// Mobile app association files. iOS and Android fetch them to check the
+	// apps may open links to this domain, so they are served without
+	// redirects at fixed paths.
+	if opts.AppLinks != nil {
+		if len(opts.AppLinks.AppleAppIDs) > 0 {
+			handle("GET /.well-known/apple-app-site-association", apiLimiter(opts.AppLinks.AppleAppSiteAssociation()))
+		}
+		if opts.AppLinks.AndroidPackage != "" {
+			handle("GET /.well-known/assetlinks.json", apiLimiter(opts.AppLinks.AssetLinks()))
+		}
+	}
+	if opts.CSPReportOnly != "" {
+		var httpMetrics *metrics.HTTPMetrics
+		if opts.Metrics != nil {
+			httpMetrics = opts.Metrics.HTTP
+		}
+		handle("POST "+handlers.CSPReportPath, apiLimiter(handlers.CSPReports(httpMetrics)))
+	}
 
 	// Health check
-	mux.HandleFunc("GET /health", handlers.Health)
-	mux.HandleFunc("GET /ready", handlers.Ready)
+	handleProbe("GET /health", handlers.Health)
+	if opts.Readiness != nil {
+		handleProbe("GET /ready", opts.Readiness.Handler())
+	} else {
+		handleProbe("GET /ready", handlers.Ready)
+	}
+	handleProbe("GET /version", handlers.Version)
 
 	// Configure CORS
 	corsConfig := middleware.DefaultCORSConfig()
-	if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+	if opts.AllowAnyOrigin || slog.Default().Enabled(context.Background(), slog.LevelDebug) {
 		// Development mode - allow all origins
 		corsConfig.AllowedOrigins = []string{"*"}
 	} else {
@@ -56,13 +554,76 @@ func Routes(authService *service.AuthService, tokenManager *token.Manager) http.
 
 	// Configure security headers
 	securityConfig := middleware.APISecurityConfig()
+	if opts.CSPReportOnly != "" {
+		securityConfig.ContentSecurityPolicyReportOnly = withCSPReportURI(opts.CSPReportOnly, handlers.CSPReportPath)
+	}
 
-	// Add common middleware
-	handler := middleware.RequestID(mux)
+	// A deprecation or SLO for a route that is not served is most likely a
+	// typo
+	if opts.Metrics != nil {
+		served := make(map[string]bool, len(patterns))
+		for _, pattern := range patterns {
+			served[pattern] = true
+		}
+		for _, route := range opts.Metrics.SLO.Routes() {
+			if !served[route] {
+				return nil, fmt.Errorf("SLO for unknown route %q", route)
+			}
+		}
+	}
+	for pattern := range opts.Deprecations {
+		if !deprecated[pattern] {
+			return nil, fmt.Errorf("deprecation for unknown route %q", pattern)
+		}
+	}
+
+	// The policy is enforced in front of the mux, so a route without a rule
+	// is rejected rather than served unprotected
+	var handler http.Handler = mux
+	if opts.Policy != nil {
+		if err := opts.Policy.Validate(patterns); err != nil {
+			return nil, fmt.Errorf("invalid route policy: %w", err)
+		}
+		handler = middleware.Authorize(opts.Policy, tokenManager, mux)
+	}
+
+	// Add common middleware. Localize goes first so the writer that
+	// handlers and route middleware write errors to translates them.
+	if opts.Messages != nil {
+		handler = middleware.Localize(opts.Messages)(handler)
+	}
+	handler = middleware.RequestID(handler)
 	handler = middleware.Logger(handler)
-	handler = middleware.Recover(handler)
+	if opts.Metrics != nil {
+		handler = middleware.Metrics(opts.Metrics, middleware.NewRouteLabeler(mux, opts.MaxRouteLabels))(handler)
+	}
+	handler = middleware.RecoverAndReport(opts.ErrorReports)(handler)
 	handler = middleware.NewCORS(corsConfig)(handler)
 	handler = middleware.SecurityHeaders(securityConfig)(handler)
 
-	return handler
+	return handler, nil
+}
+
+// withCSPReportURI points a policy without a report-uri at the violation
+// report endpoint
+func withCSPReportURI(policy, reportURI string) string {
+	for _, directive := range strings.Split(policy, ";") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(directive), " "); name == "report-uri" {
+			return policy
+		}
+	}
+	return strings.TrimRight(strings.TrimSpace(policy), ";") + "; report-uri " + reportURI
+}
+
+// routeAPIVersion returns the API version of a route pattern under /api/vN/
+func routeAPIVersion(pattern string) (int, bool) {
+	_, path, _ := strings.Cut(pattern, " ")
+	switch {
+	case strings.HasPrefix(path, "/api/v1/"):
+		return handlers.APIVersion1, true
+	case strings.HasPrefix(path, "/api/v2/"):
+		return handlers.APIVersion2, true
+	default:
+		return 0, false
+	}
 }
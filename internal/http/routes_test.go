@@ -2,31 +2,44 @@ package http_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/authz"
+	"github.com/n1rocket/go-auth-jwt/internal/config"
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/emailtrack"
 	inthttp "github.com/n1rocket/go-auth-jwt/internal/http"
 	"github.com/n1rocket/go-auth-jwt/internal/http/handlers"
+	"github.com/n1rocket/go-auth-jwt/internal/http/middleware"
+	"github.com/n1rocket/go-auth-jwt/internal/metrics"
 	"github.com/n1rocket/go-auth-jwt/internal/security"
 	"github.com/n1rocket/go-auth-jwt/internal/service"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
+	"github.com/n1rocket/go-auth-jwt/internal/worker"
 )
 
 var ErrNotFound = errors.New("not found")
 
 // Mock repositories
 type mockUserRepository struct {
-	createFunc        func(ctx context.Context, user *domain.User) error
-	getByEmailFunc    func(ctx context.Context, email string) (*domain.User, error)
-	getByIDFunc       func(ctx context.Context, id string) (*domain.User, error)
-	updateFunc        func(ctx context.Context, user *domain.User) error
-	deleteFunc        func(ctx context.Context, id string) error
-	existsByEmailFunc func(ctx context.Context, email string) (bool, error)
+	createFunc           func(ctx context.Context, user *domain.User) error
+	getByEmailFunc       func(ctx context.Context, email string) (*domain.User, error)
+	getByIDFunc          func(ctx context.Context, id string) (*domain.User, error)
+	updateFunc           func(ctx context.Context, user *domain.User) error
+	deleteFunc           func(ctx context.Context, id string) error
+	existsByEmailFunc    func(ctx context.Context, email string) (bool, error)
+	getByUsernameFunc    func(ctx context.Context, username string) (*domain.User, error)
+	existsByUsernameFunc func(ctx context.Context, username string) (bool, error)
 }
 
 func (m *mockUserRepository) Create(ctx context.Context, user *domain.User) error {
@@ -64,6 +77,24 @@ func (m *mockUserRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockUserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if m.getByUsernameFunc != nil {
+		return m.getByUsernameFunc(ctx, username)
+	}
+	return nil, ErrNotFound
+}
+
+func (m *mockUserRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	if m.existsByUsernameFunc != nil {
+		return m.existsByUsernameFunc(ctx, username)
+	}
+	return false, nil
+}
+
 func (m *mockUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	if m.existsByEmailFunc != nil {
 		return m.existsByEmailFunc(ctx, email)
@@ -147,6 +178,10 @@ func (m *mockRefreshTokenRepository) Revoke(ctx context.Context, token string) e
 	return nil
 }
 
+func (m *mockRefreshTokenRepository) GetActiveCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
 func (m *mockRefreshTokenRepository) DeleteExpired(ctx context.Context) error {
 	if m.deleteExpiredFunc != nil {
 		return m.deleteExpiredFunc(ctx)
@@ -210,6 +245,12 @@ func TestRoutes(t *testing.T) {
 			path:       "/ready",
 			wantStatus: http.StatusOK,
 		},
+		{
+			name:       "version endpoint",
+			method:     "GET",
+			path:       "/version",
+			wantStatus: http.StatusOK,
+		},
 		{
 			name:       "signup endpoint - invalid body",
 			method:     "POST",
@@ -238,6 +279,12 @@ func TestRoutes(t *testing.T) {
 			body:       `{}`,
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name:       "username-available endpoint - missing username",
+			method:     "GET",
+			path:       "/api/v1/auth/username-available",
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "logout endpoint - no auth",
 			method:     "POST",
@@ -256,6 +303,24 @@ func TestRoutes(t *testing.T) {
 			path:       "/api/v1/auth/me",
 			wantStatus: http.StatusUnauthorized,
 		},
+		{
+			name:       "update me endpoint - no auth",
+			method:     "PATCH",
+			path:       "/api/v1/auth/me",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "avatar upload url endpoint - no auth",
+			method:     "POST",
+			path:       "/api/v1/auth/me/avatar-upload-url",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "userinfo endpoint - no auth",
+			method:     "GET",
+			path:       "/api/v1/auth/userinfo",
+			wantStatus: http.StatusUnauthorized,
+		},
 		{
 			name:       "non-existent endpoint",
 			method:     "GET",
@@ -362,3 +427,616 @@ func TestHealthEndpoint(t *testing.T) {
 		t.Errorf("expected Content-Type application/json, got %s", contentType)
 	}
 }
+
+func TestNewRouter_Policy(t *testing.T) {
+	authService, tokenManager := createTestServices()
+
+	policy, err := authz.LoadPolicy("../../examples/route-policy.yaml")
+	if err != nil {
+		t.Fatalf("failed to load example policy: %v", err)
+	}
+	admin := handlers.NewAdminHandler(nil)
+	admin.SetSessions(authService)
+	handler, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{Admin: admin, AdminToken: "admin-token", Policy: policy, APIv2: true})
+	if err != nil {
+		t.Fatalf("NewRouter() with example policy error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without token, got %d", rec.Code)
+	}
+
+	partial, err := authz.ParsePolicy([]byte("routes:\n  - route: GET /health\n    access: public\n"))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	if _, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{Policy: partial}); err == nil ||
+		!strings.Contains(err.Error(), "POST /api/v1/auth/login") {
+		t.Errorf("NewRouter() error = %v, want error naming unmapped routes", err)
+	}
+}
+
+func TestNewRouter_Email(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	resend := func(handler http.Handler) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/verify-email/resend", strings.NewReader(`{"email":""}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	handler, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	if code := resend(handler); code != http.StatusNotFound {
+		t.Errorf("resend without email delivery: status %d, want 404", code)
+	}
+
+	policy, err := authz.LoadPolicy("../../examples/route-policy.yaml")
+	if err != nil {
+		t.Fatalf("failed to load example policy: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dispatcher := worker.NewEmailDispatcher(nil, worker.DefaultConfig(), logger)
+	emails := service.NewAuthServiceWithEmail(authService, dispatcher, &config.Config{}, logger)
+	handler, err = inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{Email: emails, Policy: policy})
+	if err != nil {
+		t.Fatalf("NewRouter() with email delivery error = %v", err)
+	}
+	if code := resend(handler); code != http.StatusBadRequest {
+		t.Errorf("resend without an address: status %d, want 400", code)
+	}
+}
+
+func TestNewRouter_Versioning(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	handler := inthttp.Routes(authService, tokenManager)
+	if rec := get(handler, "/api/v2/auth/me"); rec.Code != http.StatusNotFound {
+		t.Errorf("/api/v2 without APIv2: status %d, want 404", rec.Code)
+	}
+	if rec := get(handler, "/api/v1/auth/me"); rec.Header().Get(middleware.APIVersionHeader) != "1" {
+		t.Errorf("%s = %q, want 1", middleware.APIVersionHeader, rec.Header().Get(middleware.APIVersionHeader))
+	}
+	if rec := get(handler, "/health"); rec.Header().Get(middleware.APIVersionHeader) != "" {
+		t.Error("probes are not versioned")
+	}
+
+	since := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC)
+	handler, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{
+		APIv2: true,
+		Deprecations: map[string]middleware.Deprecation{
+			"GET /api/v1/auth/me": {Since: since, Sunset: sunset, Successor: "/api/v2/auth/me"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	rec := get(handler, "/api/v1/auth/me")
+	if got := rec.Header().Get("Deprecation"); got != "@1793491200" {
+		t.Errorf("Deprecation = %q, want @1793491200", got)
+	}
+	if got := rec.Header().Get("Sunset"); got != "Sat, 01 May 2027 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</api/v2/auth/me>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+
+	rec = get(handler, "/api/v2/auth/me")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get(middleware.APIVersionHeader) != "2" {
+		t.Errorf("/api/v2/auth/me: status %d, version %q; want 401 from version 2", rec.Code, rec.Header().Get(middleware.APIVersionHeader))
+	}
+	if rec.Header().Get("Deprecation") != "" {
+		t.Error("version 2 route should not be deprecated")
+	}
+
+	_, err = inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{
+		Deprecations: map[string]middleware.Deprecation{"GET /api/v1/auth/whoami": {Since: since}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "GET /api/v1/auth/whoami") {
+		t.Errorf("NewRouter() error = %v, want error naming the unknown route", err)
+	}
+}
+
+func TestNewRouter_RateLimitKeys(t *testing.T) {
+	authService, tokenManager := createTestServices()
+
+	if _, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{RateLimitKeys: map[string]string{"api": "ip+claim:client_id"}}); err != nil {
+		t.Errorf("NewRouter() error = %v", err)
+	}
+	if _, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{RateLimitKeys: map[string]string{"auth": "cookie:session"}}); err == nil ||
+		!strings.Contains(err.Error(), "auth") {
+		t.Errorf("NewRouter() error = %v, want error naming the policy", err)
+	}
+}
+
+func TestNewRouter_CSPReportOnly(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	handler, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{CSPReportOnly: "default-src 'self';"})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Content-Security-Policy-Report-Only"); got != "default-src 'self'; report-uri /api/v1/csp-reports" {
+		t.Errorf("Content-Security-Policy-Report-Only = %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/csp-reports", strings.NewReader(`{"csp-report":{"violated-directive":"img-src"}}`))
+	req.Header.Set("Content-Type", "application/csp-report")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("csp report status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestDiscoveryEndpoints_Caching(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	spec := []byte(`{"openapi":"3.0.3","info":{"title":"Auth API","version":"1.2.0"}}`)
+	handler := inthttp.RoutesWithOptions(authService, tokenManager, inthttp.RouteOptions{OpenAPISpec: spec})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != string(spec) {
+		t.Fatalf("openapi.json status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "public, max-age=") {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	if rec.Header().Get("Expires") == "" {
+		t.Error("Expires not set")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+
+	// HS256 keys are secret, so there is no key set to publish
+	req = httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("jwks.json status with HS256 = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAppLinks(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	fingerprint := strings.TrimSuffix(strings.Repeat("AB:", 32), ":")
+	handler := inthttp.RoutesWithOptions(authService, tokenManager, inthttp.RouteOptions{
+		AppLinks: &handlers.AppLinks{
+			AppleAppIDs:             []string{"ABCDE12345.com.example.app"},
+			Paths:                   []string{"/verify-email", "/reset-password"},
+			AndroidPackage:          "com.example.app",
+			AndroidCertFingerprints: []string{fingerprint},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/apple-app-site-association", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("apple-app-site-association status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var aasa struct {
+		AppLinks struct {
+			Details []struct {
+				AppIDs     []string            `json:"appIDs"`
+				Components []map[string]string `json:"components"`
+			} `json:"details"`
+		} `json:"applinks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &aasa); err != nil {
+		t.Fatalf("apple-app-site-association is not JSON: %v", err)
+	}
+	if details := aasa.AppLinks.Details; len(details) != 1 || details[0].AppIDs[0] != "ABCDE12345.com.example.app" ||
+		len(details[0].Components) != 2 || details[0].Components[1]["/"] != "/reset-password" {
+		t.Errorf("apple-app-site-association = %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/assetlinks.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("assetlinks.json status = %d", rec.Code)
+	}
+	var statements []struct {
+		Relation []string `json:"relation"`
+		Target   struct {
+			Namespace    string   `json:"namespace"`
+			PackageName  string   `json:"package_name"`
+			Fingerprints []string `json:"sha256_cert_fingerprints"`
+		} `json:"target"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &statements); err != nil {
+		t.Fatalf("assetlinks.json is not JSON: %v", err)
+	}
+	if len(statements) != 1 || statements[0].Target.PackageName != "com.example.app" ||
+		statements[0].Target.Fingerprints[0] != fingerprint || statements[0].Relation[0] != "delegate_permission/common.handle_all_urls" {
+		t.Errorf("assetlinks.json = %s", rec.Body.String())
+	}
+
+	// Without apps the files are not served
+	handler = inthttp.RoutesWithOptions(authService, tokenManager, inthttp.RouteOptions{})
+	for _, path := range []string{"/.well-known/apple-app-site-association", "/.well-known/assetlinks.json"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s status without apps = %d, want %d", path, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestNewRouter_RouteSLOs(t *testing.T) {
+	authService, tokenManager := createTestServices()
+
+	served := metrics.NewMetricsWithConfig(metrics.Config{
+		SLOs: map[string]metrics.SLO{"POST /api/v1/auth/login": {Latency: 300 * time.Millisecond, Objective: 0.99}},
+	})
+	defer served.Stop()
+	if _, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{Metrics: served}); err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	unknown := metrics.NewMetricsWithConfig(metrics.Config{
+		SLOs: map[string]metrics.SLO{"POST /api/v1/auth/signin": {Latency: 300 * time.Millisecond, Objective: 0.99}},
+	})
+	defer unknown.Stop()
+	_, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{Metrics: unknown})
+	if err == nil || !strings.Contains(err.Error(), "POST /api/v1/auth/signin") {
+		t.Errorf("NewRouter() error = %v, want error naming the unknown route", err)
+	}
+}
+
+// emptyAuditLogReader is an audit trail without entries
+type emptyAuditLogReader struct{}
+
+func (emptyAuditLogReader) ListByUser(ctx context.Context, userID string, actions []string, before *domain.AuditCursor, limit int) ([]*domain.AuditLog, error) {
+	return nil, nil
+}
+
+func TestNewRouter_SecurityEvents(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	get := func(handler http.Handler) *httptest.ResponseRecorder {
+		accessToken, err := tokenManager.GenerateAccessToken("user-123", "user@example.com", true)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken() error = %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me/security-events", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get(inthttp.Routes(authService, tokenManager)); rec.Code != http.StatusNotFound {
+		t.Errorf("without security events: status %d, want 404", rec.Code)
+	}
+
+	authService.SetSecurityEvents(emptyAuditLogReader{})
+	rec := get(inthttp.Routes(authService, tokenManager))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"events":[]}` {
+		t.Errorf("body = %s, want an empty page", body)
+	}
+}
+
+func TestNewRouter_AllowAnyOrigin(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	allowedOrigin := func(opts inthttp.RouteOptions) string {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Origin", "http://localhost:5173")
+		rec := httptest.NewRecorder()
+		inthttp.RoutesWithOptions(authService, tokenManager, opts).ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	if origin := allowedOrigin(inthttp.RouteOptions{}); origin != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", origin)
+	}
+	if origin := allowedOrigin(inthttp.RouteOptions{AllowAnyOrigin: true}); origin != "http://localhost:5173" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the request origin", origin)
+	}
+}
+
+func TestNewRouter_ServiceAccounts(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	post := func(handler http.Handler, path, body, adminToken string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if adminToken != "" {
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	const tokenPath = "/api/v1/auth/service-accounts/token"
+	const wrongGrant = `{"grant_type":"password","client_assertion":"x"}`
+
+	if code := post(inthttp.Routes(authService, tokenManager), tokenPath, wrongGrant, ""); code != http.StatusNotFound {
+		t.Errorf("without service accounts: status %d, want 404", code)
+	}
+
+	policy, err := authz.LoadPolicy("../../examples/route-policy.yaml")
+	if err != nil {
+		t.Fatalf("failed to load example policy: %v", err)
+	}
+	accounts := service.NewServiceAccountService(nil, tokenManager, []string{"test-issuer"}, time.Minute)
+	handler, err := inthttp.NewRouter(authService, tokenManager, inthttp.RouteOptions{
+		Admin:           handlers.NewAdminHandler(nil),
+		AdminToken:      "admin-token",
+		Policy:          policy,
+		ServiceAccounts: accounts,
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() with example policy error = %v", err)
+	}
+
+	if code := post(handler, tokenPath, wrongGrant, ""); code != http.StatusBadRequest {
+		t.Errorf("token with wrong grant_type: status %d, want 400", code)
+	}
+	if code := post(handler, "/api/v1/admin/service-accounts", `{"name":""}`, ""); code != http.StatusUnauthorized {
+		t.Errorf("admin route without token: status %d, want 401", code)
+	}
+	if code := post(handler, "/api/v1/admin/service-accounts", `{"name":""}`, "admin-token"); code != http.StatusBadRequest {
+		t.Errorf("admin route with token: status %d, want 400", code)
+	}
+}
+
+// memoryDenylist keeps revoked access token ids in memory
+type memoryDenylist map[string]bool
+
+func (d memoryDenylist) Add(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	d[tokenID] = true
+	return nil
+}
+
+func (d memoryDenylist) Contains(ctx context.Context, tokenID string) (bool, error) {
+	return d[tokenID], nil
+}
+
+func TestNewRouter_Revoke(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	denylist := memoryDenylist{}
+	authService.SetAccessTokenDenylist(denylist)
+	handler := inthttp.RoutesWithOptions(authService, tokenManager, inthttp.RouteOptions{AccessTokenDenylist: denylist})
+
+	accessToken, err := tokenManager.GenerateAccessToken("user-1", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	// Each request comes from its own address, so the auth limit is not hit
+	requests := 0
+	send := func(method, path string, form url.Values, bearer string) int {
+		requests++
+		req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", requests)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	revoke := url.Values{"token": {accessToken}}
+
+	if code := send(http.MethodPost, "/api/v1/auth/revoke", revoke, ""); code != http.StatusUnauthorized {
+		t.Errorf("revoke without credentials: status %d, want 401", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/auth/revoke", url.Values{}, accessToken); code != http.StatusBadRequest {
+		t.Errorf("revoke without token: status %d, want 400", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/auth/revoke", revoke, accessToken); code != http.StatusOK {
+		t.Fatalf("revoke: status %d, want 200", code)
+	}
+
+	// The revoked token no longer authenticates, not even another revocation
+	if code := send(http.MethodGet, "/api/v1/auth/me", nil, accessToken); code != http.StatusUnauthorized {
+		t.Errorf("GET /me with a revoked token: status %d, want 401", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/auth/revoke", revoke, accessToken); code != http.StatusUnauthorized {
+		t.Errorf("revoke with a revoked token: status %d, want 401", code)
+	}
+}
+
+// memoryConsents keeps consent answers in memory
+type memoryConsents struct {
+	consents []*domain.Consent
+}
+
+func (c *memoryConsents) Create(ctx context.Context, consent *domain.Consent) error {
+	consent.CreatedAt = time.Now()
+	c.consents = append(c.consents, consent)
+	return nil
+}
+
+func (c *memoryConsents) ListByUser(ctx context.Context, userID string) ([]*domain.Consent, error) {
+	var consents []*domain.Consent
+	for _, consent := range c.consents {
+		if consent.UserID == userID {
+			consents = append(consents, consent)
+		}
+	}
+	return consents, nil
+}
+
+func (c *memoryConsents) AcceptedTermsVersion(ctx context.Context, userID string) (string, error) {
+	version := ""
+	for _, consent := range c.consents {
+		if consent.UserID == userID && consent.Kind == domain.ConsentTerms && consent.Granted {
+			version = consent.Version
+		}
+	}
+	return version, nil
+}
+
+func TestNewRouter_Consents(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	authService.SetConsents(&memoryConsents{}, "2026-01")
+	handler := inthttp.Routes(authService, tokenManager)
+
+	accessToken, err := tokenManager.GenerateAccessToken("user-1", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Users who have not accepted the current terms can only read their
+	// account, answer the consent and sign out
+	if rec := send(http.MethodPost, "/api/v1/auth/me/password", `{}`); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "CONSENT_REQUIRED") {
+		t.Errorf("before consent: status %d, want 403 CONSENT_REQUIRED: %s", rec.Code, rec.Body.String())
+	}
+	if rec := send(http.MethodGet, "/api/v1/auth/me", ""); rec.Code == http.StatusForbidden {
+		t.Errorf("GET /me before consent: status %d, want it exempt", rec.Code)
+	}
+	if rec := send(http.MethodPost, "/api/v1/auth/me/consents", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty consent: status %d, want 400", rec.Code)
+	}
+	if rec := send(http.MethodPost, "/api/v1/auth/me/consents", `{"terms_version":"2025-06"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("outdated terms: status %d, want 400", rec.Code)
+	}
+
+	rec := send(http.MethodPost, "/api/v1/auth/me/consents", `{"terms_version":"2026-01","marketing_consent":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("consent: status %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"terms_accepted":true`) || !strings.Contains(rec.Body.String(), `"marketing_consent":true`) {
+		t.Errorf("consent body = %s", rec.Body.String())
+	}
+
+	if rec := send(http.MethodPost, "/api/v1/auth/me/password", `{}`); rec.Code == http.StatusForbidden {
+		t.Errorf("after consent: status %d, want the request let through", rec.Code)
+	}
+}
+
+func TestNewRouter_ConnectionToken(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	handler := inthttp.Routes(authService, tokenManager)
+
+	accessToken, err := tokenManager.GenerateAccessToken("user-1", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	send := func(method, path, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/api/v1/auth/connection-token", accessToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	var resp handlers.ConnectionTokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if claims, err := tokenManager.ValidateConnectionToken(resp.ConnectionToken); err != nil || claims.UserID != "user-1" {
+		t.Errorf("ValidateConnectionToken() = %+v, %v", claims, err)
+	}
+	if resp.ExpiresIn <= 0 || resp.ExpiresIn > int64(token.ConnectionTokenTTL.Seconds()) {
+		t.Errorf("expires_in = %d", resp.ExpiresIn)
+	}
+
+	// A connection token is not an access token
+	if rec := send(http.MethodGet, "/api/v1/auth/me", resp.ConnectionToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /me with a connection token: status %d, want 401", rec.Code)
+	}
+}
+
+// memoryTrackingOptOuts keeps email tracking opt-outs in memory
+type memoryTrackingOptOuts struct {
+	optedOut map[string]bool
+}
+
+func (o *memoryTrackingOptOuts) IsOptedOut(ctx context.Context, userID string) (bool, error) {
+	return o.optedOut[userID], nil
+}
+
+func (o *memoryTrackingOptOuts) SetOptedOut(ctx context.Context, userID string, optedOut bool) error {
+	o.optedOut[userID] = optedOut
+	return nil
+}
+
+func TestNewRouter_EmailTracking(t *testing.T) {
+	authService, tokenManager := createTestServices()
+	tracker := emailtrack.New("0123456789abcdef0123456789abcdef", "https://auth.example.com")
+	authService.SetEmailTracking(tracker, &memoryTrackingOptOuts{optedOut: make(map[string]bool)})
+	handler := inthttp.Routes(authService, tokenManager)
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	link := strings.TrimPrefix(tracker.Link("verification", "https://app.example.com/verify-email?token=abc"), "https://auth.example.com")
+	rec := send(http.MethodGet, link, "")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://app.example.com/verify-email?token=abc" {
+		t.Errorf("click: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := send(http.MethodGet, emailtrack.ClickPath+"?e=verification&u=https://evil.example.com/&s=forged", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("forged click: status %d, want 400", rec.Code)
+	}
+
+	// The pixel is off by default
+	if rec := send(http.MethodGet, emailtrack.OpenPath+"?e=verification&s=forged", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("open with the pixel off: status %d, want 404", rec.Code)
+	}
+
+	accessToken, err := tokenManager.GenerateAccessToken("user-1", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	sendAuthed := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/auth/me/email-tracking", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := sendAuthed(http.MethodPut, `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty preference: status %d, want 400", rec.Code)
+	}
+	if rec := sendAuthed(http.MethodPut, `{"do_not_track":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("opt out: status %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if rec := sendAuthed(http.MethodGet, ""); strings.TrimSpace(rec.Body.String()) != `{"do_not_track":true}` {
+		t.Errorf("preference = %s, want do_not_track", rec.Body.String())
+	}
+}
@@ -0,0 +1,132 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request is rejected by an open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State represents the state of a circuit breaker
+type State int
+
+const (
+	// StateClosed allows all requests through
+	StateClosed State = iota
+	// StateOpen rejects all requests until the cooldown elapses
+	StateOpen
+	// StateHalfOpen allows a single trial request through
+	StateHalfOpen
+)
+
+// String returns a string representation of the state
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops calling a failing dependency after a number of
+// consecutive failures and probes it again once a cooldown has elapsed
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a new circuit breaker
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     StateClosed,
+	}
+}
+
+// Allow reports whether a request may proceed
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case StateOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return ErrCircuitOpen
+		}
+		// Cooldown elapsed, let a single trial request through
+		cb.state = StateHalfOpen
+		cb.probing = true
+		return nil
+	case StateHalfOpen:
+		if cb.probing {
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess records a successful request and closes the circuit
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.state = StateClosed
+	cb.failures = 0
+	cb.probing = false
+}
+
+// RecordFailure records a failed request and opens the circuit if needed
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+
+	// A failed trial request reopens the circuit immediately
+	if cb.state == StateHalfOpen {
+		cb.state = StateOpen
+		cb.openedAt = time.Now()
+		return
+	}
+
+	cb.failures++
+	if cb.failures >= cb.threshold {
+		cb.state = StateOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// State returns the current state of the circuit breaker
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == StateOpen && time.Since(cb.openedAt) >= cb.cooldown {
+		return StateHalfOpen
+	}
+	return cb.state
+}
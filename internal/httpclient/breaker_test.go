@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("opens after threshold failures", func(t *testing.T) {
+		cb := NewCircuitBreaker(3, time.Minute)
+
+		for i := 0; i < 3; i++ {
+			if err := cb.Allow(); err != nil {
+				t.Fatalf("Request %d should be allowed, got %v", i+1, err)
+			}
+			cb.RecordFailure()
+		}
+
+		if cb.State() != StateOpen {
+			t.Errorf("Expected state open, got %s", cb.State())
+		}
+		if err := cb.Allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen, got %v", err)
+		}
+	})
+
+	t.Run("success resets failure count", func(t *testing.T) {
+		cb := NewCircuitBreaker(2, time.Minute)
+
+		cb.RecordFailure()
+		cb.RecordSuccess()
+		cb.RecordFailure()
+
+		if cb.State() != StateClosed {
+			t.Errorf("Expected state closed, got %s", cb.State())
+		}
+	})
+
+	t.Run("half-open allows a single probe", func(t *testing.T) {
+		cb := NewCircuitBreaker(1, 10*time.Millisecond)
+		cb.RecordFailure()
+
+		time.Sleep(20 * time.Millisecond)
+
+		if cb.State() != StateHalfOpen {
+			t.Errorf("Expected state half-open, got %s", cb.State())
+		}
+		if err := cb.Allow(); err != nil {
+			t.Fatalf("Probe should be allowed, got %v", err)
+		}
+		if err := cb.Allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Second request during probe should be rejected, got %v", err)
+		}
+
+		cb.RecordSuccess()
+		if cb.State() != StateClosed {
+			t.Errorf("Expected state closed after successful probe, got %s", cb.State())
+		}
+	})
+
+	t.Run("failed probe reopens circuit", func(t *testing.T) {
+		cb := NewCircuitBreaker(1, 10*time.Millisecond)
+		cb.RecordFailure()
+
+		time.Sleep(20 * time.Millisecond)
+
+		if err := cb.Allow(); err != nil {
+			t.Fatalf("Probe should be allowed, got %v", err)
+		}
+		cb.RecordFailure()
+
+		if err := cb.Allow(); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen after failed probe, got %v", err)
+		}
+	})
+}
+
+func TestState_String(t *testing.T) {
+	tests := []struct {
+		state State
+		want  string
+	}{
+		{StateClosed, "closed"},
+		{StateOpen, "open"},
+		{StateHalfOpen, "half-open"},
+		{State(42), "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("State(%d).String() = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Config holds configuration for outbound HTTP clients
+type Config struct {
+	// Timeouts
+	Timeout               time.Duration
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+
+	// Connection pooling
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	// ProxyURL overrides the proxy from the environment when set
+	ProxyURL string
+
+	// Retry policy
+	MaxRetries   int
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	// RetryNonIdempotent also retries POST and PATCH requests, for
+	// receivers that tolerate duplicates. Requests with an Idempotency-Key
+	// header are retried either way.
+	RetryNonIdempotent bool
+
+	// Circuit breaker policy (per host)
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultConfig returns default configuration
+func DefaultConfig() Config {
+	return Config{
+		Timeout:               10 * time.Second,
+		DialTimeout:           5 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		MaxRetries:            3,
+		RetryWaitMin:          100 * time.Millisecond,
+		RetryWaitMax:          2 * time.Second,
+		BreakerThreshold:      5,
+		BreakerCooldown:       30 * time.Second,
+	}
+}
+
+// Client is an outbound HTTP client with retries and per-host circuit breakers
+type Client struct {
+	httpClient *http.Client
+	config     Config
+	logger     *slog.Logger
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+// New creates a new outbound HTTP client
+func New(config Config, logger *slog.Logger) (*Client, error) {
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+		},
+		config:   config,
+		logger:   logger,
+		breakers: make(map[string]*CircuitBreaker),
+	}, nil
+}
+
+// newTransport builds the pooled transport for the given configuration
+func newTransport(config Config) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		ForceAttemptHTTP2:     true,
+	}, nil
+}
+
+// HTTPClient returns the underlying *http.Client without retries or circuit breaking
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// Do sends an HTTP request, retrying transient failures with exponential
+// backoff. Only idempotent requests are retried unless the config opts in,
+// and requests with a body must set GetBody to be retried. When a failure
+// cannot be retried, the server's response is returned untouched.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	breaker := c.breaker(req.URL.Host)
+	maxRetries := c.config.MaxRetries
+	if !c.canRetry(req) {
+		maxRetries = 0
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(req.Context(), attempt); err != nil {
+				return nil, err
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("failed to rewind request body: %w", err)
+				}
+				req.Body = body
+			}
+		}
+
+		if err := breaker.Allow(); err != nil {
+			return nil, fmt.Errorf("%s: %w", req.URL.Host, err)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if !shouldRetry(resp, err) {
+			breaker.RecordSuccess()
+			return resp, err
+		}
+		breaker.RecordFailure()
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("unexpected status: %s", resp.Status)
+			// Return the final response to the caller untouched
+			if attempt == maxRetries {
+				return resp, nil
+			}
+			drainBody(resp)
+		}
+
+		c.logger.Warn("outbound request failed",
+			"method", req.Method,
+			"host", req.URL.Host,
+			"attempt", attempt+1,
+			"error", lastErr,
+		)
+
+		// Context errors are not transient
+		if req.Context().Err() != nil {
+			return nil, req.Context().Err()
+		}
+	}
+
+	return nil, fmt.Errorf("request failed after retries: %w", lastErr)
+}
+
+// Get issues a GET request to the given URL
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// canRetry reports whether req may be sent again after a failure
+func (c *Client) canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return c.config.RetryNonIdempotent || req.Header.Get("Idempotency-Key") != ""
+}
+
+// BreakerState returns the circuit breaker state for a host
+func (c *Client) BreakerState(host string) State {
+	return c.breaker(host).State()
+}
+
+// breaker returns the circuit breaker for a host, creating it if needed
+func (c *Client) breaker(host string) *CircuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cb, exists := c.breakers[host]
+	if !exists {
+		cb = NewCircuitBreaker(c.config.BreakerThreshold, c.config.BreakerCooldown)
+		c.breakers[host] = cb
+	}
+	return cb
+}
+
+// wait sleeps for the backoff duration of the given attempt
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(backoff(c.config.RetryWaitMin, c.config.RetryWaitMax, attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns an exponential backoff duration with jitter
+func backoff(minWait, maxWait time.Duration, attempt int) time.Duration {
+	if minWait <= 0 {
+		return 0
+	}
+
+	wait := minWait << (attempt - 1)
+	if wait <= 0 || (maxWait > 0 && wait > maxWait) {
+		wait = maxWait
+	}
+
+	// Add up to 20% jitter to avoid thundering herds
+	jitter := time.Duration(rand.Int63n(int64(wait)/5 + 1))
+	return wait - jitter
+}
+
+// shouldRetry reports whether a request outcome is transient
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented
+}
+
+// drainBody discards and closes a response body so the connection can be reused
+func drainBody(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
@@ -0,0 +1,310 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.RetryWaitMin = time.Millisecond
+	config.RetryWaitMax = 5 * time.Millisecond
+	return config
+}
+
+func newTestClient(t *testing.T, config Config) *Client {
+	t.Helper()
+
+	client, err := New(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return client
+}
+
+func TestNew(t *testing.T) {
+	t.Run("valid proxy URL", func(t *testing.T) {
+		config := DefaultConfig()
+		config.ProxyURL = "http://proxy.internal:3128"
+
+		client := newTestClient(t, config)
+		if client.HTTPClient().Timeout != config.Timeout {
+			t.Errorf("Expected timeout %v, got %v", config.Timeout, client.HTTPClient().Timeout)
+		}
+	})
+
+	t.Run("invalid proxy URL", func(t *testing.T) {
+		config := DefaultConfig()
+		config.ProxyURL = "://bad"
+
+		if _, err := New(config, nil); err == nil {
+			t.Error("Expected error for invalid proxy URL")
+		}
+	})
+}
+
+func TestClient_Do(t *testing.T) {
+	t.Run("retries transient failures", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := newTestClient(t, testConfig())
+		resp, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", resp.StatusCode)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 calls, got %d", calls)
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client := newTestClient(t, testConfig())
+		resp, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+
+		if calls != 1 {
+			t.Errorf("Expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("returns final response after exhausting retries", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		config := testConfig()
+		config.MaxRetries = 2
+		config.BreakerThreshold = 10
+
+		client := newTestClient(t, config)
+		resp, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("Expected status 502, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("replays request body on retry", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "payload" {
+				t.Errorf("Expected body 'payload', got %q", body)
+			}
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		config := testConfig()
+		config.RetryNonIdempotent = true
+
+		client := newTestClient(t, config)
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		resp.Body.Close()
+
+		if calls != 2 {
+			t.Errorf("Expected 2 calls, got %d", calls)
+		}
+	})
+
+	t.Run("retries only idempotent requests by default", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		config := testConfig()
+		config.MaxRetries = 2
+		config.BreakerThreshold = 10
+
+		tests := []struct {
+			method         string
+			idempotencyKey string
+			wantCalls      int32
+		}{
+			{http.MethodPost, "", 1},
+			{http.MethodPatch, "", 1},
+			{http.MethodPost, "key-123", 3},
+			{http.MethodPut, "", 3},
+			{http.MethodDelete, "", 3},
+		}
+		for _, tt := range tests {
+			atomic.StoreInt32(&calls, 0)
+			client := newTestClient(t, config)
+			req, _ := http.NewRequest(tt.method, server.URL, strings.NewReader("payload"))
+			if tt.idempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", tt.idempotencyKey)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("%s: Do() error = %v", tt.method, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("%s: Expected status 503, got %d", tt.method, resp.StatusCode)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("%s with key %q: Expected %d calls, got %d", tt.method, tt.idempotencyKey, tt.wantCalls, calls)
+			}
+		}
+	})
+
+	t.Run("returns the response when the body cannot be replayed", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("upstream failed"))
+		}))
+		defer server.Close()
+
+		client := newTestClient(t, testConfig())
+		// Without GetBody the request cannot be sent again
+		req, _ := http.NewRequest(http.MethodPut, server.URL, io.NopCloser(strings.NewReader("payload")))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusInternalServerError || string(body) != "upstream failed" {
+			t.Errorf("Expected the server's 500 response, got %d %q", resp.StatusCode, body)
+		}
+		if calls != 1 {
+			t.Errorf("Expected 1 call, got %d", calls)
+		}
+	})
+
+	t.Run("opens circuit after repeated failures", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		config := testConfig()
+		config.MaxRetries = 0
+		config.BreakerThreshold = 2
+
+		client := newTestClient(t, config)
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			resp.Body.Close()
+		}
+
+		_, err := client.Get(context.Background(), server.URL)
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen, got %v", err)
+		}
+
+		host := strings.TrimPrefix(server.URL, "http://")
+		if client.BreakerState(host) != StateOpen {
+			t.Errorf("Expected breaker open for %s", host)
+		}
+	})
+
+	t.Run("stops retrying when context is cancelled", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		config := testConfig()
+		config.RetryWaitMin = time.Second
+		config.RetryWaitMax = time.Second
+
+		client := newTestClient(t, config)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := client.Get(ctx, server.URL)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	minWait := 100 * time.Millisecond
+	maxWait := time.Second
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		wait := backoff(minWait, maxWait, attempt)
+		if wait > maxWait {
+			t.Errorf("Attempt %d: wait %v exceeds max %v", attempt, wait, maxWait)
+		}
+		if wait <= 0 {
+			t.Errorf("Attempt %d: wait should be positive, got %v", attempt, wait)
+		}
+	}
+}
+
+func TestNewTransport_Proxy(t *testing.T) {
+	config := DefaultConfig()
+	config.ProxyURL = "http://proxy.internal:3128"
+
+	transport, err := newTransport(config)
+	if err != nil {
+		t.Fatalf("newTransport() error = %v", err)
+	}
+
+	req := &http.Request{URL: &url.URL{Scheme: "https", Host: "example.com"}}
+	proxyURL, err := transport.Proxy(req)
+	if err != nil {
+		t.Fatalf("Proxy() error = %v", err)
+	}
+	if proxyURL == nil || proxyURL.Host != "proxy.internal:3128" {
+		t.Errorf("Expected proxy host proxy.internal:3128, got %v", proxyURL)
+	}
+}
@@ -0,0 +1,191 @@
+// Package i18n translates user-facing messages. Messages are looked up by
+// their stable code, such as the code of an error response, so clients can
+// keep matching on codes whatever language the message is in.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language of the messages in the code
+const DefaultLanguage = "en"
+
+//go:embed catalogs/*.json
+var builtinCatalogs embed.FS
+
+// Catalog maps message codes to messages in one language. Messages may
+// contain {name} placeholders filled in from the params of Translate.
+type Catalog map[string]string
+
+// Loader loads catalogs keyed by lowercase BCP 47 tag ("es", "pt-br")
+type Loader interface {
+	Load() (map[string]Catalog, error)
+}
+
+// Bundle holds the catalogs of every supported language
+type Bundle struct {
+	source   Catalog // the English messages written by the code
+	catalogs map[string]Catalog
+}
+
+// NewBundle creates a bundle with the built-in English and Spanish
+// catalogs. Catalogs of the loaders are merged in order, so a loader can
+// add languages and override built-in messages code by code.
+func NewBundle(loaders ...Loader) (*Bundle, error) {
+	builtin, err := loadBuiltin()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{source: builtin[DefaultLanguage], catalogs: make(map[string]Catalog)}
+	b.merge(builtin)
+	for _, loader := range loaders {
+		catalogs, err := loader.Load()
+		if err != nil {
+			return nil, err
+		}
+		b.merge(catalogs)
+	}
+	return b, nil
+}
+
+// merge adds catalogs to the bundle, replacing messages with the same code
+func (b *Bundle) merge(catalogs map[string]Catalog) {
+	for tag, catalog := range catalogs {
+		tag = normalizeTag(tag)
+		if b.catalogs[tag] == nil {
+			b.catalogs[tag] = make(Catalog, len(catalog))
+		}
+		for code, message := range catalog {
+			b.catalogs[tag][code] = message
+		}
+	}
+}
+
+// Languages lists the supported languages, DefaultLanguage first
+func (b *Bundle) Languages() []string {
+	languages := make([]string, 0, len(b.catalogs))
+	for tag := range b.catalogs {
+		if tag != DefaultLanguage {
+			languages = append(languages, tag)
+		}
+	}
+	sort.Strings(languages)
+	return append([]string{DefaultLanguage}, languages...)
+}
+
+// Match picks the supported language an Accept-Language header prefers,
+// trying each tag before its base language ("es-MX", then "es"). It
+// returns DefaultLanguage when none is supported.
+func (b *Bundle) Match(acceptLanguage string) string {
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		for tag != "" {
+			if _, ok := b.catalogs[tag]; ok {
+				return tag
+			}
+			dash := strings.LastIndexByte(tag, '-')
+			if dash < 0 {
+				break
+			}
+			tag = tag[:dash]
+		}
+	}
+	return DefaultLanguage
+}
+
+// Translate returns the message for code in language. The message is only
+// translated while it is the built-in English message for the code, so
+// messages carrying details, such as a validation error, are kept as they
+// are rather than replaced by a generic translation.
+func (b *Bundle) Translate(language, code, message string, params map[string]string) string {
+	source, ok := b.source[code]
+	if !ok || fill(source, params) != message {
+		return message
+	}
+	translated, ok := b.catalogs[language][code]
+	if !ok {
+		return message
+	}
+	return fill(translated, params)
+}
+
+// fill replaces the {name} placeholders of a message
+func fill(message string, params map[string]string) string {
+	if len(params) == 0 {
+		return message
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// loadBuiltin reads the catalogs shipped with the service
+func loadBuiltin() (map[string]Catalog, error) {
+	entries, err := builtinCatalogs.ReadDir("catalogs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built-in catalogs: %w", err)
+	}
+	catalogs := make(map[string]Catalog, len(entries))
+	for _, entry := range entries {
+		data, err := builtinCatalogs.ReadFile(path.Join("catalogs", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in catalog %s: %w", entry.Name(), err)
+		}
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("invalid built-in catalog %s: %w", entry.Name(), err)
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+	return catalogs, nil
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header,
+// normalized and most preferred first. Wildcards and tags with q=0 are
+// left out.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalizeTag(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+// normalizeTag lowercases a language tag and uses dashes ("pt_BR" is "pt-br")
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBundle_Match(t *testing.T) {
+	bundle, err := NewBundle()
+	if err != nil {
+		t.Fatalf("NewBundle() error = %v", err)
+	}
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"es-MX,es;q=0.9", "es"},
+		{"fr-CA, es;q=0.5", "es"},
+		{"en;q=0.4, es;q=0.8", "es"},
+		{"es;q=0, en", "en"},
+		{"ES_mx", "es"},
+		{"*", "en"},
+		{"fr", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := bundle.Match(tt.header); got != tt.want {
+				t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBundle_Translate(t *testing.T) {
+	bundle, err := NewBundle()
+	if err != nil {
+		t.Fatalf("NewBundle() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		code     string
+		message  string
+		params   map[string]string
+		want     string
+	}{
+		{"translated", "es", "USER_NOT_FOUND", "User not found", nil, "Usuario no encontrado"},
+		{"default language", "en", "USER_NOT_FOUND", "User not found", nil, "User not found"},
+		{"placeholder", "es", "REQUIRED_FIELD", "email is required", map[string]string{"field": "email"}, "email es obligatorio"},
+		{"detailed message kept", "es", "EXPIRED_TOKEN", "The device code has expired", nil, "The device code has expired"},
+		{"unknown code", "es", "SOMETHING_NEW", "Something new", nil, "Something new"},
+		{"unsupported language", "fr", "USER_NOT_FOUND", "User not found", nil, "User not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bundle.Translate(tt.language, tt.code, tt.message, tt.params); got != tt.want {
+				t.Errorf("Translate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinCatalogs_Complete(t *testing.T) {
+	catalogs, err := loadBuiltin()
+	if err != nil {
+		t.Fatalf("loadBuiltin() error = %v", err)
+	}
+	for tag, catalog := range catalogs {
+		for code := range catalogs[DefaultLanguage] {
+			if catalog[code] == "" {
+				t.Errorf("%s catalog misses %s", tag, code)
+			}
+		}
+	}
+}
+
+func TestDirLoader(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pt-BR.json": `{"USER_NOT_FOUND": "Usuário não encontrado"}`,
+		"es.json":    `{"USER_NOT_FOUND": "No existe el usuario"}`,
+		"README.md":  "not a catalog",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bundle, err := NewBundle(DirLoader{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewBundle() error = %v", err)
+	}
+
+	if got, want := bundle.Languages(), []string{"en", "es", "pt-br"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Languages() = %v, want %v", got, want)
+	}
+	if got := bundle.Match("pt-BR"); got != "pt-br" {
+		t.Errorf("Match(pt-BR) = %q, want pt-br", got)
+	}
+	if got := bundle.Translate("pt-br", "USER_NOT_FOUND", "User not found", nil); got != "Usuário não encontrado" {
+		t.Errorf("added language: Translate() = %q", got)
+	}
+	if got := bundle.Translate("es", "USER_NOT_FOUND", "User not found", nil); got != "No existe el usuario" {
+		t.Errorf("overridden message: Translate() = %q", got)
+	}
+	if got := bundle.Translate("es", "DUPLICATE_EMAIL", "Email already exists", nil); got != "El correo electrónico ya existe" {
+		t.Errorf("built-in message: Translate() = %q", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "de.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewBundle(DirLoader{Dir: dir}); err == nil {
+		t.Error("NewBundle() with an invalid catalog: expected an error")
+	}
+}
@@ -0,0 +1,40 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirLoader loads one JSON catalog per language from a directory, named by
+// the language tag ("es.json", "pt-BR.json"). Other files are ignored.
+type DirLoader struct {
+	Dir string
+}
+
+// Load reads the catalogs in the directory
+func (l DirLoader) Load() (map[string]Catalog, error) {
+	entries, err := os.ReadDir(l.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message catalogs: %w", err)
+	}
+
+	catalogs := make(map[string]Catalog)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(l.Dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message catalog: %w", err)
+		}
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("invalid message catalog %s: %w", entry.Name(), err)
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+	return catalogs, nil
+}
@@ -0,0 +1,176 @@
+// Package ids generates account identifiers in the service layer, so an id
+// is known before the row is inserted. Every strategy produces 128-bit ids
+// formatted as UUIDs, which fit the uuid primary keys of the schema.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Strategy names an id generation strategy
+type Strategy string
+
+const (
+	// StrategyDatabase leaves id generation to PostgreSQL (gen_random_uuid)
+	StrategyDatabase Strategy = "database"
+	// StrategyUUIDv4 generates random UUIDs (RFC 9562 version 4)
+	StrategyUUIDv4 Strategy = "uuidv4"
+	// StrategyUUIDv7 generates time-ordered UUIDs (RFC 9562 version 7)
+	StrategyUUIDv7 Strategy = "uuidv7"
+	// StrategyULID generates ULIDs: a 48-bit millisecond timestamp followed
+	// by 80 random bits, monotonic within a millisecond
+	StrategyULID Strategy = "ulid"
+)
+
+// Generator creates ids
+type Generator interface {
+	NewID() (string, error)
+}
+
+// New returns the generator for a strategy, or nil for StrategyDatabase
+func New(strategy Strategy) (Generator, error) {
+	switch strategy {
+	case StrategyDatabase:
+		return nil, nil
+	case StrategyUUIDv4:
+		return &uuidV4{rand: rand.Reader}, nil
+	case StrategyUUIDv7:
+		return &uuidV7{clock: newClock()}, nil
+	case StrategyULID:
+		return &ulid{clock: newClock()}, nil
+	default:
+		return nil, fmt.Errorf("unknown id strategy: %s", strategy)
+	}
+}
+
+// errClockOverflow is returned when more ids are requested within one
+// millisecond than the monotonic counter can order
+var errClockOverflow = errors.New("too many ids generated within one millisecond")
+
+type uuidV4 struct {
+	rand io.Reader
+}
+
+// NewID returns a random version 4 UUID
+func (g *uuidV4) NewID() (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(g.rand, b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return format(b), nil
+}
+
+// clock hands out millisecond timestamps with a counter that orders ids
+// created within the same millisecond
+type clock struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	rand   io.Reader
+	lastMS uint64
+	last   [16]byte
+}
+
+func newClock() clock {
+	return clock{now: time.Now, rand: rand.Reader}
+}
+
+type uuidV7 struct {
+	clock
+}
+
+// NewID returns a version 7 UUID. Within a millisecond the 12-bit rand_a
+// field is used as a counter (RFC 9562 section 6.2, method 1).
+func (g *uuidV7) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var b [16]byte
+	ms := uint64(g.now().UnixMilli())
+	if ms <= g.lastMS {
+		counter := binary.BigEndian.Uint16(g.last[6:8])&0x0fff + 1
+		if counter > 0x0fff {
+			return "", errClockOverflow
+		}
+		b = g.last
+		binary.BigEndian.PutUint16(b[6:8], 0x7000|counter)
+	} else {
+		if _, err := io.ReadFull(g.rand, b[6:]); err != nil {
+			return "", fmt.Errorf("failed to generate id: %w", err)
+		}
+		putMillis(&b, ms)
+		// Start the counter in the lower half so it has room to grow
+		b[6] = b[6]&0x07 | 0x70
+		b[8] = b[8]&0x3f | 0x80
+		g.lastMS = ms
+	}
+
+	g.last = b
+	return format(b), nil
+}
+
+type ulid struct {
+	clock
+}
+
+// NewID returns a ULID. Within a millisecond the random part of the
+// previous id is incremented, as the ULID specification requires.
+func (g *ulid) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var b [16]byte
+	ms := uint64(g.now().UnixMilli())
+	if ms <= g.lastMS {
+		b = g.last
+		i := 15
+		for ; i >= 6; i-- {
+			b[i]++
+			if b[i] != 0 {
+				break
+			}
+		}
+		if i < 6 {
+			return "", errClockOverflow
+		}
+	} else {
+		if _, err := io.ReadFull(g.rand, b[6:]); err != nil {
+			return "", fmt.Errorf("failed to generate id: %w", err)
+		}
+		putMillis(&b, ms)
+		g.lastMS = ms
+	}
+
+	g.last = b
+	return format(b), nil
+}
+
+// putMillis writes a 48-bit millisecond timestamp to the first six bytes
+func putMillis(b *[16]byte, ms uint64) {
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(b[:6], ts[2:])
+}
+
+// format renders 16 bytes in the 8-4-4-4-12 UUID layout
+func format(b [16]byte) string {
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
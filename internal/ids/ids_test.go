@@ -0,0 +1,127 @@
+package ids
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func TestNew(t *testing.T) {
+	if g, err := New(StrategyDatabase); err != nil || g != nil {
+		t.Errorf("New(database) = %v, %v; want no generator", g, err)
+	}
+	for _, s := range []Strategy{StrategyUUIDv4, StrategyUUIDv7, StrategyULID} {
+		if g, err := New(s); err != nil || g == nil {
+			t.Errorf("New(%s) = %v, %v", s, g, err)
+		}
+	}
+	if _, err := New("snowflake"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestGenerators_Format(t *testing.T) {
+	tests := []struct {
+		strategy Strategy
+		version  byte // expected version nibble; 0 when the strategy has none
+	}{
+		{StrategyUUIDv4, '4'},
+		{StrategyUUIDv7, '7'},
+		{StrategyULID, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			g, _ := New(tt.strategy)
+			seen := make(map[string]bool)
+			for i := 0; i < 1000; i++ {
+				id, err := g.NewID()
+				if err != nil {
+					t.Fatalf("NewID() error = %v", err)
+				}
+				if !uuidPattern.MatchString(id) {
+					t.Fatalf("id %q is not formatted as a UUID", id)
+				}
+				if tt.version != 0 && (id[14] != tt.version || !strings.ContainsRune("89ab", rune(id[19]))) {
+					t.Fatalf("id %q lacks version %c and the RFC 9562 variant", id, tt.version)
+				}
+				if seen[id] {
+					t.Fatalf("duplicate id %q", id)
+				}
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func TestGenerators_TimeOrdered(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	for _, strategy := range []Strategy{StrategyUUIDv7, StrategyULID} {
+		t.Run(string(strategy), func(t *testing.T) {
+			g, _ := New(strategy)
+			setNow(g, func() time.Time { return now })
+
+			var prev string
+			for i := 0; i < 3000; i++ {
+				// Several ids per millisecond, and a clock that steps back once
+				switch {
+				case i%500 == 499:
+					now = now.Add(time.Millisecond)
+				case i == 1000:
+					now = now.Add(-time.Second)
+				}
+
+				id, err := g.NewID()
+				if err != nil {
+					t.Fatalf("NewID() error = %v", err)
+				}
+				if id <= prev {
+					t.Fatalf("id %q does not sort after %q", id, prev)
+				}
+				prev = id
+			}
+
+			// The first 48 bits are the creation time in milliseconds
+			g, _ = New(strategy)
+			setNow(g, func() time.Time { return now })
+			id, _ := g.NewID()
+			ms, _ := strconv.ParseUint(strings.ReplaceAll(id, "-", "")[:12], 16, 64)
+			if int64(ms) != now.UnixMilli() {
+				t.Errorf("timestamp %d, want %d", ms, now.UnixMilli())
+			}
+		})
+	}
+}
+
+func TestUUIDv7_CounterOverflow(t *testing.T) {
+	g, _ := New(StrategyUUIDv7)
+	now := time.Now()
+	setNow(g, func() time.Time { return now })
+
+	var err error
+	for i := 0; i <= 0x1000 && err == nil; i++ {
+		_, err = g.NewID()
+	}
+	if !errors.Is(err, errClockOverflow) {
+		t.Errorf("expected errClockOverflow, got %v", err)
+	}
+
+	now = now.Add(time.Millisecond)
+	if _, err := g.NewID(); err != nil {
+		t.Errorf("NewID() in the next millisecond error = %v", err)
+	}
+}
+
+func setNow(g Generator, now func() time.Time) {
+	switch g := g.(type) {
+	case *uuidV7:
+		g.now = now
+	case *ulid:
+		g.now = now
+	}
+}
@@ -0,0 +1,137 @@
+// Package lock coordinates work between instances with named leases, such
+// as electing the instance that runs scheduled jobs
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+var (
+	// ErrNotAcquired is returned by TryLock while another holder has the lock
+	ErrNotAcquired = errors.New("lock is held by another holder")
+	// ErrLockLost is returned by Extend and Unlock once the lease expired or
+	// was taken over
+	ErrLockLost = errors.New("lock lease was lost")
+)
+
+// Lease is a held lock
+type Lease struct {
+	Key string
+	// Token is a fencing token: every later lease of Key has a greater one,
+	// so storage can reject writes from a holder that lost the lock without
+	// noticing, for example after a long GC pause
+	Token     uint64
+	ExpiresAt time.Time
+
+	owner string // random id, so only this lease can extend or release the lock
+}
+
+// Locker hands out leases on named locks shared by every instance
+type Locker interface {
+	// TryLock takes the lock for ttl without waiting, or returns
+	// ErrNotAcquired
+	TryLock(ctx context.Context, key string, ttl time.Duration) (*Lease, error)
+	// Extend keeps holding the lock for ttl from now
+	Extend(ctx context.Context, lease *Lease, ttl time.Duration) error
+	// Unlock releases the lock so others can take it at once
+	Unlock(ctx context.Context, lease *Lease) error
+}
+
+// newOwner returns a random lease owner id
+func newOwner() (string, error) {
+	owner, err := security.GenerateToken(16)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate lock owner: %w", err)
+	}
+	return owner, nil
+}
+
+// Acquire is TryLock retried every poll until the lock is taken or ctx is
+// done
+func Acquire(ctx context.Context, locker Locker, key string, ttl, poll time.Duration) (*Lease, error) {
+	for {
+		lease, err := locker.TryLock(ctx, key, ttl)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lease, err
+		}
+
+		timer := time.NewTimer(poll)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("waiting for lock %s: %w", key, ctx.Err())
+		}
+	}
+}
+
+// Do runs fn while holding the lock on key, waiting for it as Acquire does.
+// The lease is extended every third of ttl, and fn's context is canceled
+// when it is lost.
+func Do(ctx context.Context, locker Locker, key string, ttl, poll time.Duration, fn func(ctx context.Context) error) error {
+	lease, err := Acquire(ctx, locker, key, ttl, poll)
+	if err != nil {
+		return err
+	}
+
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := locker.Extend(ctx, lease, ttl); err != nil {
+					cancel(fmt.Errorf("lock %s: %w", key, err))
+					return
+				}
+			}
+		}
+	}()
+
+	err = fn(fnCtx)
+	close(done)
+	var lost error
+	if fnCtx.Err() != nil && ctx.Err() == nil {
+		lost = context.Cause(fnCtx)
+	}
+	cancel(nil)
+
+	// Release even when ctx is done, so others need not wait out the ttl
+	unlockCtx, cancelUnlock := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelUnlock()
+	unlockErr := locker.Unlock(unlockCtx, lease)
+
+	switch {
+	case lost != nil:
+		// fn may have overlapped with the next holder
+		return lost
+	case err != nil:
+		return err
+	case unlockErr != nil:
+		return fmt.Errorf("failed to release lock %s: %w", key, unlockErr)
+	}
+	return nil
+}
+
+// New returns the locker of a backend: postgres, on db's advisory locks,
+// redis, at redisURL, or memory, for a single instance
+func New(backend, redisURL string, db ConnPool) (Locker, error) {
+	switch backend {
+	case "postgres":
+		return NewPostgresLocker(db), nil
+	case "redis":
+		return NewRedisLocker(redisURL)
+	case "memory":
+		return NewMemoryLocker(), nil
+	}
+	return nil, fmt.Errorf("unknown lock backend %q", backend)
+}
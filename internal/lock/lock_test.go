@@ -0,0 +1,128 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	locker := NewMemoryLocker()
+	locker.now = func() time.Time { return now }
+
+	first, err := locker.TryLock(ctx, "jobs", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	if _, err := locker.TryLock(ctx, "jobs", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Errorf("TryLock() on a held lock error = %v, want ErrNotAcquired", err)
+	}
+	if _, err := locker.TryLock(ctx, "other", time.Minute); err != nil {
+		t.Errorf("TryLock() on another key error = %v", err)
+	}
+
+	// Extending keeps others out past the first ttl
+	now = now.Add(50 * time.Second)
+	if err := locker.Extend(ctx, first, time.Minute); err != nil {
+		t.Fatalf("Extend() error = %v", err)
+	}
+	now = now.Add(50 * time.Second)
+	if _, err := locker.TryLock(ctx, "jobs", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Errorf("TryLock() after Extend error = %v, want ErrNotAcquired", err)
+	}
+
+	// An expired lease is lost and the next one gets a greater token
+	now = now.Add(time.Minute)
+	second, err := locker.TryLock(ctx, "jobs", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock() after expiry error = %v", err)
+	}
+	if second.Token <= first.Token {
+		t.Errorf("fencing token %d is not greater than %d", second.Token, first.Token)
+	}
+	if err := locker.Extend(ctx, first, time.Minute); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Extend() of a lost lease error = %v, want ErrLockLost", err)
+	}
+	if err := locker.Unlock(ctx, first); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Unlock() of a lost lease error = %v, want ErrLockLost", err)
+	}
+
+	if err := locker.Unlock(ctx, second); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if _, err := locker.TryLock(ctx, "jobs", time.Minute); err != nil {
+		t.Errorf("TryLock() after Unlock error = %v", err)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+
+	held, err := locker.TryLock(ctx, "migrations", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = locker.Unlock(ctx, held)
+	}()
+	if _, err := Acquire(ctx, locker, "migrations", time.Minute, 5*time.Millisecond); err != nil {
+		t.Errorf("Acquire() error = %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := Acquire(timeout, locker, "migrations", time.Minute, 5*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() on a held lock error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("holds the lock while fn runs", func(t *testing.T) {
+		locker := NewMemoryLocker()
+		var running atomic.Bool
+		err := Do(ctx, locker, "job", 30*time.Millisecond, time.Millisecond, func(ctx context.Context) error {
+			running.Store(true)
+			// Outlives the ttl, so the lease must have been extended
+			time.Sleep(60 * time.Millisecond)
+			if _, err := locker.TryLock(ctx, "job", time.Minute); !errors.Is(err, ErrNotAcquired) {
+				t.Errorf("TryLock() while fn runs error = %v, want ErrNotAcquired", err)
+			}
+			return nil
+		})
+		if err != nil || !running.Load() {
+			t.Fatalf("Do() error = %v, ran = %v", err, running.Load())
+		}
+		if _, err := locker.TryLock(ctx, "job", time.Minute); err != nil {
+			t.Errorf("TryLock() after Do error = %v", err)
+		}
+	})
+
+	t.Run("cancels fn when the lease is lost", func(t *testing.T) {
+		locker := NewMemoryLocker()
+		err := Do(ctx, locker, "job", 30*time.Millisecond, time.Millisecond, func(fnCtx context.Context) error {
+			// Another holder takes over, as after a Redis failover
+			locker.mu.Lock()
+			delete(locker.locks, "job")
+			locker.mu.Unlock()
+
+			select {
+			case <-fnCtx.Done():
+				return fnCtx.Err()
+			case <-time.After(time.Second):
+				t.Error("fn was not canceled")
+				return nil
+			}
+		})
+		if !errors.Is(err, ErrLockLost) {
+			t.Errorf("Do() error = %v, want ErrLockLost", err)
+		}
+	})
+}
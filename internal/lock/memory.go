@@ -0,0 +1,91 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
+)
+
+// MemoryLocker holds locks in process, for single-instance deployments and
+// tests
+type MemoryLocker struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	locks  map[string]memoryLock
+	tokens map[string]uint64 // last fencing token by key
+}
+
+type memoryLock struct {
+	owner     string
+	expiresAt time.Time
+}
+
+// NewMemoryLocker creates an in-process locker
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		now:    time.Now,
+		locks:  make(map[string]memoryLock),
+		tokens: make(map[string]uint64),
+	}
+}
+
+// SetClock sets the clock leases expire by. The system clock is used by
+// default. Must be called before the locker is used.
+func (l *MemoryLocker) SetClock(c clock.Clock) {
+	l.now = clock.OrReal(c).Now
+}
+
+// TryLock takes the lock unless an unexpired lease holds it
+func (l *MemoryLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	owner, err := newOwner()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if held, ok := l.locks[key]; ok && now.Before(held.expiresAt) {
+		return nil, ErrNotAcquired
+	}
+	l.tokens[key]++
+	lease := &Lease{Key: key, Token: l.tokens[key], ExpiresAt: now.Add(ttl), owner: owner}
+	l.locks[key] = memoryLock{owner: owner, expiresAt: lease.ExpiresAt}
+	return lease, nil
+}
+
+// Extend keeps holding the lock for ttl from now
+func (l *MemoryLocker) Extend(ctx context.Context, lease *Lease, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.holdsLocked(lease, now) {
+		return ErrLockLost
+	}
+	lease.ExpiresAt = now.Add(ttl)
+	l.locks[lease.Key] = memoryLock{owner: lease.owner, expiresAt: lease.ExpiresAt}
+	return nil
+}
+
+// Unlock releases the lock
+func (l *MemoryLocker) Unlock(ctx context.Context, lease *Lease) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.holdsLocked(lease, l.now()) {
+		return ErrLockLost
+	}
+	delete(l.locks, lease.Key)
+	return nil
+}
+
+// holdsLocked reports whether lease still holds its lock
+func (l *MemoryLocker) holdsLocked(lease *Lease, now time.Time) bool {
+	held, ok := l.locks[lease.Key]
+	return ok && held.owner == lease.owner && now.Before(held.expiresAt)
+}
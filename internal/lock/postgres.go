@@ -0,0 +1,130 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ConnPool reserves database connections, such as *sql.DB or a rotating
+// *db.DB
+type ConnPool interface {
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+// PostgresLocker takes session-level advisory locks, each on a connection
+// reserved for the lease. Postgres releases them when the connection ends,
+// so a crashed holder frees its locks at once; the ttl is not enforced
+// while the connection lives, and Extend only checks that it does.
+//
+// Fencing tokens are transaction ids, which only ever grow across the
+// database.
+type PostgresLocker struct {
+	db ConnPool
+
+	mu    sync.Mutex
+	conns map[string]*sql.Conn // by lease owner
+}
+
+// NewPostgresLocker creates a locker on db's advisory locks
+func NewPostgresLocker(db ConnPool) *PostgresLocker {
+	return &PostgresLocker{db: db, conns: make(map[string]*sql.Conn)}
+}
+
+// advisoryKey maps a lock name to an advisory lock key
+func advisoryKey(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("go-auth-jwt:lock:" + key))
+	return int64(h.Sum64())
+}
+
+// TryLock takes the advisory lock on a connection of its own
+func (l *PostgresLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	owner, err := newOwner()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve lock connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, advisoryKey(key)).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take lock %s: %w", key, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, ErrNotAcquired
+	}
+
+	var token int64
+	if err := conn.QueryRowContext(ctx, `SELECT txid_current()`).Scan(&token); err != nil {
+		discard(conn)
+		return nil, fmt.Errorf("failed to issue fencing token: %w", err)
+	}
+
+	l.mu.Lock()
+	l.conns[owner] = conn
+	l.mu.Unlock()
+	return &Lease{Key: key, Token: uint64(token), ExpiresAt: time.Now().Add(ttl), owner: owner}, nil
+}
+
+// Extend checks that the lease's connection, and with it the lock, is
+// still alive
+func (l *PostgresLocker) Extend(ctx context.Context, lease *Lease, ttl time.Duration) error {
+	l.mu.Lock()
+	conn, ok := l.conns[lease.owner]
+	l.mu.Unlock()
+	if !ok {
+		return ErrLockLost
+	}
+
+	if err := conn.PingContext(ctx); err != nil {
+		l.take(lease)
+		discard(conn)
+		return fmt.Errorf("%w: %v", ErrLockLost, err)
+	}
+	lease.ExpiresAt = time.Now().Add(ttl)
+	return nil
+}
+
+// Unlock releases the advisory lock and the connection
+func (l *PostgresLocker) Unlock(ctx context.Context, lease *Lease) error {
+	conn, ok := l.take(lease)
+	if !ok {
+		return ErrLockLost
+	}
+
+	var released bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryKey(lease.Key)).Scan(&released); err != nil {
+		discard(conn)
+		return fmt.Errorf("failed to release lock %s: %w", lease.Key, err)
+	}
+	conn.Close()
+	if !released {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// take removes and returns the lease's connection
+func (l *PostgresLocker) take(lease *Lease) (*sql.Conn, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	conn, ok := l.conns[lease.owner]
+	delete(l.conns, lease.owner)
+	return conn, ok
+}
+
+// discard ends conn's session instead of returning it to the pool, which
+// releases every advisory lock it holds
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	conn.Close()
+}
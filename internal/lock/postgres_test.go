@@ -0,0 +1,89 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPostgresLocker(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	locker := NewPostgresLocker(db)
+	key := advisoryKey("scheduler")
+
+	// Held elsewhere
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_lock($1)`)).WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(false))
+	if _, err := locker.TryLock(ctx, "scheduler", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Errorf("TryLock() error = %v, want ErrNotAcquired", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_lock($1)`)).WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT txid_current()`)).
+		WillReturnRows(sqlmock.NewRows([]string{"txid"}).AddRow(int64(4242)))
+	lease, err := locker.TryLock(ctx, "scheduler", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	if lease.Token != 4242 {
+		t.Errorf("Token = %d, want the transaction id", lease.Token)
+	}
+
+	mock.ExpectPing()
+	if err := locker.Extend(ctx, lease, time.Minute); err != nil {
+		t.Errorf("Extend() error = %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(true))
+	if err := locker.Unlock(ctx, lease); err != nil {
+		t.Errorf("Unlock() error = %v", err)
+	}
+	if err := locker.Unlock(ctx, lease); !errors.Is(err, ErrLockLost) {
+		t.Errorf("second Unlock() error = %v, want ErrLockLost", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresLocker_LostConnection(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	locker := NewPostgresLocker(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_lock($1)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT txid_current()`)).
+		WillReturnRows(sqlmock.NewRows([]string{"txid"}).AddRow(int64(1)))
+	lease, err := locker.TryLock(ctx, "scheduler", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+
+	// The session ended, and its advisory lock with it
+	mock.ExpectPing().WillReturnError(errors.New("connection reset"))
+	if err := locker.Extend(ctx, lease, time.Minute); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Extend() error = %v, want ErrLockLost", err)
+	}
+	if err := locker.Unlock(ctx, lease); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Unlock() after a lost lease error = %v, want ErrLockLost", err)
+	}
+}
@@ -0,0 +1,315 @@
+package lock
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// redisKeyPrefix namespaces lock keys in a shared Redis
+	redisKeyPrefix = "go-auth-jwt:lock:"
+	// redisTimeout bounds commands whose context has no deadline
+	redisTimeout = 5 * time.Second
+)
+
+// The scripts run atomically in Redis. KEYS[1] holds the owner of the lock
+// and KEYS[2] the key's last fencing token, which never expires so tokens
+// keep growing.
+const (
+	redisLockScript = `if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return redis.call('INCR', KEYS[2])
+end
+return 0`
+	redisExtendScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`
+	redisUnlockScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+// RedisLocker takes locks that expire in Redis after their ttl, so a
+// crashed holder frees its locks once they run out. It suits a single Redis
+// primary; a failover can lose locks, which fencing tokens guard against.
+type RedisLocker struct {
+	client *redisClient
+}
+
+// NewRedisLocker connects to the Redis at rawURL, such as
+// redis://:password@host:6379/0, or rediss:// for TLS. The connection is
+// opened on first use.
+func NewRedisLocker(rawURL string) (*RedisLocker, error) {
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisLocker{client: client}, nil
+}
+
+// Close closes the connection to Redis
+func (l *RedisLocker) Close() error {
+	return l.client.close()
+}
+
+// TryLock sets the lock key unless it exists and bumps the fencing token
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	owner, err := newOwner()
+	if err != nil {
+		return nil, err
+	}
+	token, err := l.client.eval(ctx, redisLockScript, []string{redisKeyPrefix + key, redisKeyPrefix + key + ":fence"}, owner, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take lock %s: %w", key, err)
+	}
+	if token == 0 {
+		return nil, ErrNotAcquired
+	}
+	return &Lease{Key: key, Token: uint64(token), ExpiresAt: time.Now().Add(ttl), owner: owner}, nil
+}
+
+// Extend resets the lock key's expiry if the lease still owns it
+func (l *RedisLocker) Extend(ctx context.Context, lease *Lease, ttl time.Duration) error {
+	extended, err := l.client.eval(ctx, redisExtendScript, []string{redisKeyPrefix + lease.Key}, lease.owner, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to extend lock %s: %w", lease.Key, err)
+	}
+	if extended == 0 {
+		return ErrLockLost
+	}
+	lease.ExpiresAt = time.Now().Add(ttl)
+	return nil
+}
+
+// Unlock deletes the lock key if the lease still owns it
+func (l *RedisLocker) Unlock(ctx context.Context, lease *Lease) error {
+	deleted, err := l.client.eval(ctx, redisUnlockScript, []string{redisKeyPrefix + lease.Key}, lease.owner, 0)
+	if err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", lease.Key, err)
+	}
+	if deleted == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// redisError is an error reply from Redis
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisClient is a minimal RESP client running one command at a time on a
+// single connection, which is redialed after errors
+type redisClient struct {
+	addr      string
+	tlsConfig *tls.Config // nil for plain connections
+	username  string
+	password  string
+	db        int
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// newRedisClient parses a redis:// or rediss:// URL
+func newRedisClient(rawURL string) (*redisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid Redis URL: scheme must be redis or rediss")
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid Redis URL: host is required")
+	}
+
+	client := &redisClient{addr: u.Host}
+	if u.Port() == "" {
+		client.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.Scheme == "rediss" {
+		client.tlsConfig = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+	if u.User != nil {
+		client.username = u.User.Username()
+		client.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if client.db, err = strconv.Atoi(db); err != nil || client.db < 0 {
+			return nil, fmt.Errorf("invalid Redis URL: database must be a number")
+		}
+	}
+	return client, nil
+}
+
+// eval runs script with ttl in milliseconds after the other args, and
+// returns its integer reply
+func (c *redisClient) eval(ctx context.Context, script string, keys []string, owner string, ttl time.Duration) (int64, error) {
+	args := []string{"EVAL", script, strconv.Itoa(len(keys))}
+	args = append(args, keys...)
+	args = append(args, owner, strconv.FormatInt(ttl.Milliseconds(), 10))
+
+	reply, err := c.do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply %v", reply)
+	}
+	return n, nil
+}
+
+// do sends a command and reads its reply
+func (c *redisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	if c.conn == nil {
+		if err := c.dial(ctx, deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	c.conn.SetDeadline(deadline)
+	reply, err := c.roundTrip(args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection may be mid-reply; start over on the next command
+		c.closeLocked()
+	}
+	return reply, err
+}
+
+// dial connects and authenticates
+func (c *redisClient) dial(ctx context.Context, deadline time.Time) error {
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.tlsConfig}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+
+	conn.SetDeadline(deadline)
+	var setup [][]string
+	if c.password != "" {
+		if c.username != "" {
+			setup = append(setup, []string{"AUTH", c.username, c.password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.password})
+		}
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		if _, err := c.roundTrip(args); err != nil {
+			c.closeLocked()
+			return fmt.Errorf("failed to set up Redis connection: %w", err)
+		}
+	}
+	return nil
+}
+
+// roundTrip writes a command as an array of bulk strings and reads the reply
+func (c *redisClient) roundTrip(args []string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+// readReply reads one RESP2 reply. Bulk strings are returned as strings,
+// null replies as nil and error replies as redisError.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: malformed reply %q", line)
+}
+
+func (c *redisClient) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+func (c *redisClient) closeLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.r = nil, nil
+	return err
+}
@@ -0,0 +1,164 @@
+package lock
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves the lock scripts from memory, ignoring expiry
+type fakeRedis struct {
+	mu       sync.Mutex
+	values   map[string]string
+	counters map[string]int64
+	commands []string // command names in the order received
+}
+
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{values: make(map[string]string), counters: make(map[string]int64)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server, listener.Addr().String()
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		fmt.Fprint(conn, s.handle(args))
+	}
+}
+
+func (s *fakeRedis) handle(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, args[0])
+
+	switch args[0] {
+	case "AUTH":
+		if args[len(args)-1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "EVAL":
+		key, owner := args[3], args[len(args)-2]
+		switch args[1] {
+		case redisLockScript:
+			if _, ok := s.values[key]; ok {
+				return ":0\r\n"
+			}
+			s.values[key] = owner
+			s.counters[args[4]]++
+			return ":" + strconv.FormatInt(s.counters[args[4]], 10) + "\r\n"
+		case redisExtendScript:
+			if s.values[key] != owner {
+				return ":0\r\n"
+			}
+			return ":1\r\n"
+		case redisUnlockScript:
+			if s.values[key] != owner {
+				return ":0\r\n"
+			}
+			delete(s.values, key)
+			return ":1\r\n"
+		}
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func TestRedisLocker(t *testing.T) {
+	server, addr := startFakeRedis(t)
+	ctx := context.Background()
+
+	locker, err := NewRedisLocker("redis://:secret@" + addr + "/2")
+	if err != nil {
+		t.Fatalf("NewRedisLocker() error = %v", err)
+	}
+	defer locker.Close()
+
+	first, err := locker.TryLock(ctx, "scheduler", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	if _, err := locker.TryLock(ctx, "scheduler", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Errorf("TryLock() on a held lock error = %v, want ErrNotAcquired", err)
+	}
+	if err := locker.Extend(ctx, first, time.Minute); err != nil {
+		t.Errorf("Extend() error = %v", err)
+	}
+	if err := locker.Unlock(ctx, first); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if err := locker.Extend(ctx, first, time.Minute); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Extend() after Unlock error = %v, want ErrLockLost", err)
+	}
+
+	second, err := locker.TryLock(ctx, "scheduler", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock() after Unlock error = %v", err)
+	}
+	if second.Token <= first.Token {
+		t.Errorf("fencing token %d is not greater than %d", second.Token, first.Token)
+	}
+
+	// The connection is authenticated and set up once
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.commands) < 2 || server.commands[0] != "AUTH" || server.commands[1] != "SELECT" {
+		t.Errorf("commands = %v, want AUTH and SELECT first", server.commands)
+	}
+}
+
+func TestRedisLocker_WrongPassword(t *testing.T) {
+	_, addr := startFakeRedis(t)
+
+	locker, err := NewRedisLocker("redis://:wrong@" + addr)
+	if err != nil {
+		t.Fatalf("NewRedisLocker() error = %v", err)
+	}
+	defer locker.Close()
+
+	var replyErr redisError
+	if _, err := locker.TryLock(context.Background(), "scheduler", time.Minute); !errors.As(err, &replyErr) {
+		t.Errorf("TryLock() error = %v, want the AUTH error", err)
+	}
+}
+
+func TestNewRedisLocker_InvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"http://localhost:6379", "redis://", "redis://localhost/db"} {
+		if _, err := NewRedisLocker(rawURL); err == nil {
+			t.Errorf("NewRedisLocker(%q) should fail", rawURL)
+		}
+	}
+}
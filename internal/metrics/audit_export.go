@@ -0,0 +1,59 @@
+package metrics
+
+import "time"
+
+// AuditExportMetrics contains all audit export metrics, labeled by exporter
+type AuditExportMetrics struct {
+	AuditExportEvents     *Counter // also labeled by result (exported, failed or dropped)
+	AuditExportLag        *Histogram
+	AuditExportQueueDepth *Gauge
+}
+
+// NewAuditExportMetrics creates a new AuditExportMetrics instance
+func NewAuditExportMetrics() *AuditExportMetrics {
+	return &AuditExportMetrics{
+		AuditExportEvents: NewCounter("audit_export_events_total", "Audit events handled by exporter and result"),
+		AuditExportLag: NewHistogramWithBuckets("audit_export_lag_seconds", "Time from an audit event to its export",
+			[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}),
+		AuditExportQueueDepth: NewGauge("audit_export_queue_depth", "Audit events waiting to be exported"),
+	}
+}
+
+// Register registers all audit export metrics
+func (a *AuditExportMetrics) Register(registry MetricRegistry) {
+	registry.Register(a.AuditExportEvents)
+	registry.Register(a.AuditExportLag)
+	registry.Register(a.AuditExportQueueDepth)
+}
+
+// RecordExported records a batch delivered to an exporter with the lag of
+// each of its events
+func (a *AuditExportMetrics) RecordExported(exporter string, lags []time.Duration) {
+	a.recordEvents(exporter, "exported", len(lags))
+
+	labeled := a.AuditExportLag.WithLabels(map[string]string{"exporter": exporter})
+	for _, lag := range lags {
+		a.AuditExportLag.Observe(lag.Seconds())
+		labeled.Observe(lag.Seconds())
+	}
+}
+
+// RecordFailed records events an exporter gave up on after retrying
+func (a *AuditExportMetrics) RecordFailed(exporter string, count int) {
+	a.recordEvents(exporter, "failed", count)
+}
+
+// RecordDropped records an event dropped because the exporter's queue was full
+func (a *AuditExportMetrics) RecordDropped(exporter string) {
+	a.recordEvents(exporter, "dropped", 1)
+}
+
+// SetQueueDepth records the number of events waiting for an exporter
+func (a *AuditExportMetrics) SetQueueDepth(exporter string, depth int) {
+	a.AuditExportQueueDepth.WithLabels(map[string]string{"exporter": exporter}).Set(float64(depth))
+}
+
+func (a *AuditExportMetrics) recordEvents(exporter, result string, count int) {
+	a.AuditExportEvents.Add(int64(count))
+	a.AuditExportEvents.WithLabels(map[string]string{"exporter": exporter, "result": result}).Add(int64(count))
+}
@@ -12,6 +12,7 @@ type AuthMetrics struct {
 	TokensRefreshed *Counter
 	TokensRevoked   *Counter
 	ActiveSessions  *Gauge
+	RolloutOutcomes *Counter // labeled by flag, variant and outcome
 }
 
 // NewAuthMetrics creates a new AuthMetrics instance
@@ -27,6 +28,7 @@ func NewAuthMetrics() *AuthMetrics {
 		TokensRefreshed: NewCounter("auth_tokens_refreshed_total", "Total number of tokens refreshed"),
 		TokensRevoked:   NewCounter("auth_tokens_revoked_total", "Total number of tokens revoked"),
 		ActiveSessions:  NewGauge("auth_active_sessions", "Number of active user sessions"),
+		RolloutOutcomes: NewCounter("auth_rollout_outcomes_total", "Outcomes of auth steps gated by a rollout flag, by variant"),
 	}
 }
 
@@ -42,6 +44,7 @@ func (a *AuthMetrics) Register(registry MetricRegistry) {
 	registry.Register(a.TokensRefreshed)
 	registry.Register(a.TokensRevoked)
 	registry.Register(a.ActiveSessions)
+	registry.Register(a.RolloutOutcomes)
 }
 
 // RecordLogin records a login attempt
@@ -65,6 +68,26 @@ func (a *AuthMetrics) RecordSignup(success bool) {
 	}
 }
 
+// RecordLoginOutcome records a login attempt labeled by outcome, such as
+// success, bad_password or unknown_user
+func (a *AuthMetrics) RecordLoginOutcome(outcome string) {
+	a.RecordLogin(outcome == "success")
+	a.LoginAttempts.WithLabels(map[string]string{"outcome": outcome}).Inc()
+}
+
+// RecordSignupOutcome records a signup attempt labeled by outcome, such as
+// success, duplicate_email or weak_password
+func (a *AuthMetrics) RecordSignupOutcome(outcome string) {
+	a.RecordSignup(outcome == "success")
+	a.SignupAttempts.WithLabels(map[string]string{"outcome": outcome}).Inc()
+}
+
+// RecordRolloutOutcome records an auth step gated by a rollout flag for a
+// user in the enabled or control variant
+func (a *AuthMetrics) RecordRolloutOutcome(flag, variant, outcome string) {
+	a.RolloutOutcomes.WithLabels(map[string]string{"flag": flag, "variant": variant, "outcome": outcome}).Inc()
+}
+
 // RecordTokenIssued records a token issuance
 func (a *AuthMetrics) RecordTokenIssued() {
 	a.TokensIssued.Inc()
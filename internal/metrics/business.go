@@ -7,16 +7,20 @@ type BusinessMetrics struct {
 	UsersVerified     *Counter
 	PasswordResets    *Counter
 	VerificationsSent *Counter
+	// VerificationResends counts resend requests by outcome (sent or
+	// throttled); a rising throttled share points at resend abuse
+	VerificationResends *Counter
 }
 
 // NewBusinessMetrics creates a new BusinessMetrics instance
 func NewBusinessMetrics() *BusinessMetrics {
 	return &BusinessMetrics{
-		UsersTotal:        NewCounter("users_total", "Total number of registered users"),
-		UsersActive:       NewGauge("users_active", "Number of active users"),
-		UsersVerified:     NewCounter("users_verified_total", "Total number of verified users"),
-		PasswordResets:    NewCounter("password_resets_total", "Total number of password reset requests"),
-		VerificationsSent: NewCounter("verifications_sent_total", "Total number of verification emails sent"),
+		UsersTotal:          NewCounter("users_total", "Total number of registered users"),
+		UsersActive:         NewGauge("users_active", "Number of active users"),
+		UsersVerified:       NewCounter("users_verified_total", "Total number of verified users"),
+		PasswordResets:      NewCounter("password_resets_total", "Total number of password reset requests"),
+		VerificationsSent:   NewCounter("verifications_sent_total", "Total number of verification emails sent"),
+		VerificationResends: NewCounter("verification_resends_total", "Total number of verification email resend requests"),
 	}
 }
 
@@ -27,6 +31,7 @@ func (b *BusinessMetrics) Register(registry MetricRegistry) {
 	registry.Register(b.UsersVerified)
 	registry.Register(b.PasswordResets)
 	registry.Register(b.VerificationsSent)
+	registry.Register(b.VerificationResends)
 }
 
 // RecordUserRegistered records a new user registration
@@ -49,7 +54,13 @@ func (b *BusinessMetrics) RecordVerificationSent() {
 	b.VerificationsSent.Inc()
 }
 
+// RecordVerificationResend records a verification email resend request
+func (b *BusinessMetrics) RecordVerificationResend(outcome string) {
+	b.VerificationResends.Inc()
+	b.VerificationResends.WithLabels(map[string]string{"outcome": outcome}).Inc()
+}
+
 // SetActiveUsers sets the number of active users
 func (b *BusinessMetrics) SetActiveUsers(count float64) {
 	b.UsersActive.Set(count)
-}
\ No newline at end of file
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 )
@@ -53,8 +54,12 @@ func (c *Counter) WithLabels(labels map[string]string) *LabeledCounter {
 
 	if !exists {
 		c.mu.Lock()
-		lc = &labeledCounter{labels: labels}
-		c.labels[key] = lc
+		// Check again after acquiring write lock
+		lc, exists = c.labels[key]
+		if !exists {
+			lc = &labeledCounter{labels: labels}
+			c.labels[key] = lc
+		}
 		c.mu.Unlock()
 	}
 
@@ -76,6 +81,19 @@ func (c *Counter) String() string {
 	return fmt.Sprintf("%s: %d", c.name, c.Value())
 }
 
+// labeledValues returns the value of every label combination, keyed by the
+// labels in Prometheus syntax
+func (c *Counter) labeledValues() map[string]int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	values := make(map[string]int64, len(c.labels))
+	for _, lc := range c.labels {
+		values[formatLabels(lc.labels)] += atomic.LoadInt64(&lc.value)
+	}
+	return values
+}
+
 // Reset resets the counter to zero
 func (c *Counter) Reset() {
 	atomic.StoreInt64(&c.value, 0)
@@ -108,18 +126,25 @@ func (lc *LabeledCounter) Value() int64 {
 	return atomic.LoadInt64(&lc.counter.value)
 }
 
-// labelsToKey converts labels to a string key
+// labelsToKey converts labels to a string key. Names are sorted so the same
+// labels always map to the same series.
 func labelsToKey(labels map[string]string) string {
 	if len(labels) == 0 {
 		return ""
 	}
 
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
 	key := ""
-	for k, v := range labels {
+	for _, k := range names {
 		if key != "" {
 			key += ","
 		}
-		key += fmt.Sprintf("%s=%s", k, v)
+		key += fmt.Sprintf("%s=%s", k, labels[k])
 	}
 	return key
 }
@@ -6,6 +6,8 @@ type DatabaseMetrics struct {
 	DBQueriesTotal  *Counter
 	DBQueryDuration *Histogram
 	DBErrors        *Counter
+
+	DBCredentialRotations *Counter // labeled by result (success or failure)
 }
 
 // NewDatabaseMetrics creates a new DatabaseMetrics instance
@@ -15,6 +17,8 @@ func NewDatabaseMetrics() *DatabaseMetrics {
 		DBQueriesTotal:  NewCounter("db_queries_total", "Total number of database queries"),
 		DBQueryDuration: NewHistogram("db_query_duration_seconds", "Database query latencies in seconds"),
 		DBErrors:        NewCounter("db_errors_total", "Total number of database errors"),
+
+		DBCredentialRotations: NewCounter("db_credential_rotations_total", "Database credential rotations by result"),
 	}
 }
 
@@ -24,6 +28,7 @@ func (d *DatabaseMetrics) Register(registry MetricRegistry) {
 	registry.Register(d.DBQueriesTotal)
 	registry.Register(d.DBQueryDuration)
 	registry.Register(d.DBErrors)
+	registry.Register(d.DBCredentialRotations)
 }
 
 // RecordQuery records a database query
@@ -35,6 +40,11 @@ func (d *DatabaseMetrics) RecordQuery(duration float64, err error) {
 	}
 }
 
+// RecordCredentialRotation records a database credential rotation
+func (d *DatabaseMetrics) RecordCredentialRotation(result string) {
+	d.DBCredentialRotations.WithLabels(map[string]string{"result": result}).Inc()
+}
+
 // SetActiveConnections sets the number of active connections
 func (d *DatabaseMetrics) SetActiveConnections(count float64) {
 	d.DBConnections.Set(count)
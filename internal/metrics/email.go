@@ -6,6 +6,11 @@ type EmailMetrics struct {
 	EmailsFailed     *Counter
 	EmailQueue       *Gauge
 	EmailSendLatency *Histogram
+	StuckWorkers     *Counter
+	TrackingEvents   *Counter
+	ProviderSends    *Counter
+	ActiveProvider   *Gauge
+	Failovers        *Counter
 }
 
 // NewEmailMetrics creates a new EmailMetrics instance
@@ -15,6 +20,11 @@ func NewEmailMetrics() *EmailMetrics {
 		EmailsFailed:     NewCounter("email_failed_total", "Total number of failed email attempts"),
 		EmailQueue:       NewGauge("email_queue_size", "Number of emails in queue"),
 		EmailSendLatency: NewHistogram("email_send_duration_seconds", "Email send latencies in seconds"),
+		StuckWorkers:     NewCounter("email_stuck_workers_total", "Email workers abandoned on shutdown while sending"),
+		TrackingEvents:   NewCounter("email_tracking_events_total", "Tracked emails sent, opened and clicked, and verifications after them"),
+		ProviderSends:    NewCounter("email_provider_sends_total", "Email sends per provider and outcome"),
+		ActiveProvider:   NewGauge("email_active_provider", "1 for the email provider the last email was sent through"),
+		Failovers:        NewCounter("email_provider_failovers_total", "Switches of the active email provider"),
 	}
 }
 
@@ -24,6 +34,11 @@ func (e *EmailMetrics) Register(registry MetricRegistry) {
 	registry.Register(e.EmailsFailed)
 	registry.Register(e.EmailQueue)
 	registry.Register(e.EmailSendLatency)
+	registry.Register(e.StuckWorkers)
+	registry.Register(e.TrackingEvents)
+	registry.Register(e.ProviderSends)
+	registry.Register(e.ActiveProvider)
+	registry.Register(e.Failovers)
 }
 
 // RecordEmailSent records a sent email
@@ -37,6 +52,41 @@ func (e *EmailMetrics) RecordEmailFailed() {
 	e.EmailsFailed.Inc()
 }
 
+// RecordStuckWorker records a worker abandoned on shutdown while sending an
+// email of the given template
+func (e *EmailMetrics) RecordStuckWorker(template string) {
+	if template == "" {
+		template = "other"
+	}
+	e.StuckWorkers.WithLabels(map[string]string{"type": template}).Inc()
+}
+
+// RecordTrackingEvent records a tracking event (sent, opened, clicked or
+// verified) of a tracked email
+func (e *EmailMetrics) RecordTrackingEvent(email, event string) {
+	e.TrackingEvents.WithLabels(map[string]string{"email": email, "event": event}).Inc()
+}
+
+// RecordProviderSend records a send (sent or failed) through an email
+// provider
+func (e *EmailMetrics) RecordProviderSend(provider, outcome string) {
+	e.ProviderSends.WithLabels(map[string]string{"provider": provider, "outcome": outcome}).Inc()
+}
+
+// SetActiveProvider marks whether an email provider is the active one
+func (e *EmailMetrics) SetActiveProvider(provider string, active bool) {
+	value := 0.0
+	if active {
+		value = 1
+	}
+	e.ActiveProvider.WithLabels(map[string]string{"provider": provider}).Set(value)
+}
+
+// RecordFailover records a switch of the active email provider
+func (e *EmailMetrics) RecordFailover(from, to string) {
+	e.Failovers.WithLabels(map[string]string{"from": from, "to": to}).Inc()
+}
+
 // SetQueueSize sets the current email queue size
 func (e *EmailMetrics) SetQueueSize(size float64) {
 	e.EmailQueue.Set(size)
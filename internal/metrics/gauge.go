@@ -98,6 +98,19 @@ func (g *Gauge) String() string {
 	return fmt.Sprintf("%s: %.2f", g.name, g.Value())
 }
 
+// labeledValues returns the value of every label combination, keyed by the
+// labels in Prometheus syntax
+func (g *Gauge) labeledValues() map[string]float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	values := make(map[string]float64, len(g.labels))
+	for _, lg := range g.labels {
+		values[formatLabels(lg.labels)] = math.Float64frombits(atomic.LoadUint64(&lg.value))
+	}
+	return values
+}
+
 // Reset resets the gauge to zero
 func (g *Gauge) Reset() {
 	atomic.StoreUint64(&g.value, 0)
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"sort"
 	"sync"
@@ -209,6 +210,56 @@ func (h *Histogram) Percentile(p float64) float64 {
 	return 0
 }
 
+// writePrometheus exports the histogram in the Prometheus text format: the
+// unlabeled series followed by one series per label combination, with
+// buckets in ascending order
+func (h *Histogram) writePrometheus(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+
+	count := h.Count()
+	cumulative := uint64(0)
+	for i, bound := range h.buckets {
+		cumulative += atomic.LoadUint64(&h.counts[i])
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", h.name, bound, cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, count)
+	fmt.Fprintf(w, "%s_sum %f\n", h.name, h.Sum())
+	fmt.Fprintf(w, "%s_count %d\n", h.name, count)
+
+	h.mu.RLock()
+	labeled := make(map[string]*labeledHistogram, len(h.labels))
+	for key, lh := range h.labels {
+		labeled[key] = lh
+	}
+	h.mu.RUnlock()
+
+	for _, key := range sortedKeys(labeled) {
+		lh := labeled[key]
+		lh.mu.Lock()
+		counts := append([]uint64(nil), lh.counts...)
+		sum := math.Float64frombits(lh.sum)
+		total := lh.count
+		lh.mu.Unlock()
+
+		labels := make(map[string]string, len(lh.labels)+1)
+		for name, value := range lh.labels {
+			labels[name] = value
+		}
+		cumulative := uint64(0)
+		for i, bound := range lh.buckets {
+			cumulative += counts[i]
+			labels["le"] = fmt.Sprintf("%g", bound)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels), cumulative)
+		}
+		labels["le"] = "+Inf"
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels), total)
+		delete(labels, "le")
+		fmt.Fprintf(w, "%s_sum%s %f\n", h.name, formatLabels(labels), sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(labels), total)
+	}
+}
+
 // Reset resets the histogram
 func (h *Histogram) Reset() {
 	atomic.StoreUint64(&h.sum, 0)
@@ -6,6 +6,7 @@ type HTTPMetrics struct {
 	RequestDuration  *Histogram
 	RequestsInFlight *Gauge
 	ResponseSize     *Histogram
+	CSPViolations    *Counter // labeled by directive
 }
 
 // NewHTTPMetrics creates a new HTTPMetrics instance
@@ -15,6 +16,7 @@ func NewHTTPMetrics() *HTTPMetrics {
 		RequestDuration:  NewHistogram("http_request_duration_seconds", "HTTP request latencies in seconds"),
 		RequestsInFlight: NewGauge("http_requests_in_flight", "Number of HTTP requests currently being processed"),
 		ResponseSize:     NewHistogram("http_response_size_bytes", "HTTP response sizes in bytes"),
+		CSPViolations:    NewCounter("csp_violations_total", "Content Security Policy violation reports by directive"),
 	}
 }
 
@@ -24,6 +26,7 @@ func (h *HTTPMetrics) Register(registry MetricRegistry) {
 	registry.Register(h.RequestDuration)
 	registry.Register(h.RequestsInFlight)
 	registry.Register(h.ResponseSize)
+	registry.Register(h.CSPViolations)
 }
 
 // RecordRequest records an HTTP request
@@ -41,4 +44,9 @@ func (h *HTTPMetrics) IncrementInFlight() {
 // DecrementInFlight decrements the in-flight requests gauge
 func (h *HTTPMetrics) DecrementInFlight() {
 	h.RequestsInFlight.Dec()
-}
\ No newline at end of file
+}
+// RecordCSPViolation records a Content Security Policy violation report
+func (h *HTTPMetrics) RecordCSPViolation(directive string) {
+	h.CSPViolations.Inc()
+	h.CSPViolations.WithLabels(map[string]string{"directive": directive}).Inc()
+}
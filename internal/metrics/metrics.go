@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 )
@@ -12,13 +13,18 @@ import (
 // Metrics holds all application metrics grouped by domain
 type Metrics struct {
 	// Grouped metrics
-	HTTP      *HTTPMetrics
-	Auth      *AuthMetrics
-	Email     *EmailMetrics
-	Database  *DatabaseMetrics
-	System    *SystemMetrics
-	Business  *BusinessMetrics
-	RateLimit *RateLimitMetrics
+	HTTP        *HTTPMetrics
+	Auth        *AuthMetrics
+	Email       *EmailMetrics
+	Database    *DatabaseMetrics
+	System      *SystemMetrics
+	Business    *BusinessMetrics
+	RateLimit   *RateLimitMetrics
+	Risk        *RiskMetrics
+	AuditExport *AuditExportMetrics
+	SigningKeys *SigningKeyMetrics
+	Hashing     *PasswordHashMetrics
+	SLO         *SLOInfo
 
 	// Custom registry
 	registry map[string]Metric
@@ -34,22 +40,22 @@ var (
 )
 
 // HTTP metrics getters
-func (m *Metrics) RequestsTotal() *Counter      { return m.HTTP.RequestsTotal }
-func (m *Metrics) RequestDuration() *Histogram  { return m.HTTP.RequestDuration }
-func (m *Metrics) RequestsInFlight() *Gauge     { return m.HTTP.RequestsInFlight }
-func (m *Metrics) ResponseSize() *Histogram     { return m.HTTP.ResponseSize }
-
-// Auth metrics getters  
-func (m *Metrics) LoginAttempts() *Counter      { return m.Auth.LoginAttempts }
-func (m *Metrics) LoginSuccess() *Counter       { return m.Auth.LoginSuccess }
-func (m *Metrics) LoginFailure() *Counter       { return m.Auth.LoginFailure }
-func (m *Metrics) SignupAttempts() *Counter     { return m.Auth.SignupAttempts }
-func (m *Metrics) SignupSuccess() *Counter      { return m.Auth.SignupSuccess }
-func (m *Metrics) SignupFailure() *Counter      { return m.Auth.SignupFailure }
-func (m *Metrics) TokensIssued() *Counter       { return m.Auth.TokensIssued }
-func (m *Metrics) TokensRefreshed() *Counter    { return m.Auth.TokensRefreshed }
-func (m *Metrics) TokensRevoked() *Counter      { return m.Auth.TokensRevoked }
-func (m *Metrics) ActiveSessions() *Gauge       { return m.Auth.ActiveSessions }
+func (m *Metrics) RequestsTotal() *Counter     { return m.HTTP.RequestsTotal }
+func (m *Metrics) RequestDuration() *Histogram { return m.HTTP.RequestDuration }
+func (m *Metrics) RequestsInFlight() *Gauge    { return m.HTTP.RequestsInFlight }
+func (m *Metrics) ResponseSize() *Histogram    { return m.HTTP.ResponseSize }
+
+// Auth metrics getters
+func (m *Metrics) LoginAttempts() *Counter   { return m.Auth.LoginAttempts }
+func (m *Metrics) LoginSuccess() *Counter    { return m.Auth.LoginSuccess }
+func (m *Metrics) LoginFailure() *Counter    { return m.Auth.LoginFailure }
+func (m *Metrics) SignupAttempts() *Counter  { return m.Auth.SignupAttempts }
+func (m *Metrics) SignupSuccess() *Counter   { return m.Auth.SignupSuccess }
+func (m *Metrics) SignupFailure() *Counter   { return m.Auth.SignupFailure }
+func (m *Metrics) TokensIssued() *Counter    { return m.Auth.TokensIssued }
+func (m *Metrics) TokensRefreshed() *Counter { return m.Auth.TokensRefreshed }
+func (m *Metrics) TokensRevoked() *Counter   { return m.Auth.TokensRevoked }
+func (m *Metrics) ActiveSessions() *Gauge    { return m.Auth.ActiveSessions }
 
 // Email metrics getters
 func (m *Metrics) EmailsSent() *Counter         { return m.Email.EmailsSent }
@@ -58,27 +64,27 @@ func (m *Metrics) EmailQueue() *Gauge           { return m.Email.EmailQueue }
 func (m *Metrics) EmailSendLatency() *Histogram { return m.Email.EmailSendLatency }
 
 // Database metrics getters
-func (m *Metrics) DBConnections() *Gauge        { return m.Database.DBConnections }
-func (m *Metrics) DBQueriesTotal() *Counter     { return m.Database.DBQueriesTotal }
-func (m *Metrics) DBQueryDuration() *Histogram  { return m.Database.DBQueryDuration }
-func (m *Metrics) DBErrors() *Counter           { return m.Database.DBErrors }
+func (m *Metrics) DBConnections() *Gauge       { return m.Database.DBConnections }
+func (m *Metrics) DBQueriesTotal() *Counter    { return m.Database.DBQueriesTotal }
+func (m *Metrics) DBQueryDuration() *Histogram { return m.Database.DBQueryDuration }
+func (m *Metrics) DBErrors() *Counter          { return m.Database.DBErrors }
 
 // System metrics getters
-func (m *Metrics) GoRoutines() *Gauge           { return m.System.GoRoutines }
-func (m *Metrics) MemoryAllocated() *Gauge      { return m.System.MemoryAllocated }
-func (m *Metrics) MemoryTotal() *Gauge          { return m.System.MemoryTotal }
-func (m *Metrics) GCPauses() *Histogram         { return m.System.GCPauses }
+func (m *Metrics) GoRoutines() *Gauge      { return m.System.GoRoutines }
+func (m *Metrics) MemoryAllocated() *Gauge { return m.System.MemoryAllocated }
+func (m *Metrics) MemoryTotal() *Gauge     { return m.System.MemoryTotal }
+func (m *Metrics) GCPauses() *Histogram    { return m.System.GCPauses }
 
 // Business metrics getters
-func (m *Metrics) UsersTotal() *Counter         { return m.Business.UsersTotal }
-func (m *Metrics) UsersActive() *Gauge          { return m.Business.UsersActive }
-func (m *Metrics) UsersVerified() *Counter      { return m.Business.UsersVerified }
-func (m *Metrics) PasswordResets() *Counter     { return m.Business.PasswordResets }
-func (m *Metrics) VerificationsSent() *Counter  { return m.Business.VerificationsSent }
+func (m *Metrics) UsersTotal() *Counter        { return m.Business.UsersTotal }
+func (m *Metrics) UsersActive() *Gauge         { return m.Business.UsersActive }
+func (m *Metrics) UsersVerified() *Counter     { return m.Business.UsersVerified }
+func (m *Metrics) PasswordResets() *Counter    { return m.Business.PasswordResets }
+func (m *Metrics) VerificationsSent() *Counter { return m.Business.VerificationsSent }
 
 // Rate limit metrics getters
-func (m *Metrics) RateLimitHits() *Counter      { return m.RateLimit.RateLimitHits }
-func (m *Metrics) RateLimitExceeded() *Counter  { return m.RateLimit.RateLimitExceeded }
+func (m *Metrics) RateLimitHits() *Counter     { return m.RateLimit.RateLimitHits }
+func (m *Metrics) RateLimitExceeded() *Counter { return m.RateLimit.RateLimitExceeded }
 
 // Metric is the interface for all metric types
 type Metric interface {
@@ -96,16 +102,35 @@ func (m *Metrics) Register(metric Metric) {
 
 // NewMetrics creates a new Metrics instance
 func NewMetrics() *Metrics {
+	return NewMetricsWithConfig(Config{})
+}
+
+// NewMetricsWithConfig creates a new Metrics instance with custom latency
+// buckets and route SLOs
+func NewMetricsWithConfig(cfg Config) *Metrics {
 	m := &Metrics{
-		HTTP:      NewHTTPMetrics(),
-		Auth:      NewAuthMetrics(),
-		Email:     NewEmailMetrics(),
-		Database:  NewDatabaseMetrics(),
-		System:    NewSystemMetrics(),
-		Business:  NewBusinessMetrics(),
-		RateLimit: NewRateLimitMetrics(),
-		registry:  make(map[string]Metric),
-		stopCh:    make(chan struct{}),
+		HTTP:        NewHTTPMetrics(),
+		Auth:        NewAuthMetrics(),
+		Email:       NewEmailMetrics(),
+		Database:    NewDatabaseMetrics(),
+		System:      NewSystemMetrics(),
+		Business:    NewBusinessMetrics(),
+		RateLimit:   NewRateLimitMetrics(),
+		Risk:        NewRiskMetrics(),
+		AuditExport: NewAuditExportMetrics(),
+		SigningKeys: NewSigningKeyMetrics(),
+		Hashing:     NewPasswordHashMetrics(),
+		SLO:         NewSLOInfo(cfg.SLOs),
+		registry:    make(map[string]Metric),
+		stopCh:      make(chan struct{}),
+	}
+
+	// Latency histograms share the configured buckets
+	if len(cfg.LatencyBuckets) > 0 || len(cfg.SLOs) > 0 {
+		buckets := latencyBuckets(cfg)
+		m.HTTP.RequestDuration = NewHistogramWithBuckets("http_request_duration_seconds", "HTTP request latencies in seconds", buckets)
+		m.Database.DBQueryDuration = NewHistogramWithBuckets("db_query_duration_seconds", "Database query latencies in seconds", buckets)
+		m.Email.EmailSendLatency = NewHistogramWithBuckets("email_send_duration_seconds", "Email send latencies in seconds", buckets)
 	}
 
 	// Register all metrics
@@ -183,30 +208,40 @@ func (m *Metrics) PrometheusHandler() http.Handler {
 				fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
 				fmt.Fprintf(w, "# TYPE %s counter\n", v.name)
 				fmt.Fprintf(w, "%s %d\n", v.name, v.Value())
+				values := v.labeledValues()
+				for _, labels := range sortedKeys(values) {
+					fmt.Fprintf(w, "%s%s %d\n", v.name, labels, values[labels])
+				}
 			case *Gauge:
 				fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
 				fmt.Fprintf(w, "# TYPE %s gauge\n", v.name)
 				fmt.Fprintf(w, "%s %f\n", v.name, v.Value())
-			case *Histogram:
-				fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
-				fmt.Fprintf(w, "# TYPE %s histogram\n", v.name)
-
-				buckets := v.Buckets()
-				sum := v.Sum()
-				count := v.Count()
-
-				for bound, count := range buckets {
-					fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", v.name, bound, count)
+				values := v.labeledValues()
+				for _, labels := range sortedKeys(values) {
+					fmt.Fprintf(w, "%s%s %f\n", v.name, labels, values[labels])
 				}
-				fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", v.name, count)
-				fmt.Fprintf(w, "%s_sum %f\n", v.name, sum)
-				fmt.Fprintf(w, "%s_count %d\n", v.name, count)
+			case *TopK:
+				v.writePrometheus(w)
+			case *SLOInfo:
+				v.writePrometheus(w)
+			case *Histogram:
+				v.writePrometheus(w)
 			}
 			fmt.Fprintln(w)
 		}
 	})
 }
 
+// sortedKeys returns the keys of m in order so exports are stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // registerAll registers all metrics
 func (m *Metrics) registerAll() {
 	// Register all grouped metrics
@@ -217,10 +252,15 @@ func (m *Metrics) registerAll() {
 	m.System.Register(m)
 	m.Business.Register(m)
 	m.RateLimit.Register(m)
+	m.Risk.Register(m)
+	m.AuditExport.Register(m)
+	m.SigningKeys.Register(m)
+	m.Hashing.Register(m)
+	if len(m.SLO.slos) > 0 {
+		m.Register(m.SLO)
+	}
 }
 
-
-
 // RecordHTTPRequest records HTTP request metrics
 func (m *Metrics) RecordHTTPRequest(method, path, status string, duration time.Duration, size int) {
 	labels := map[string]string{
@@ -253,6 +293,36 @@ func (m *Metrics) RecordDBQuery(operation string, duration time.Duration, err er
 	}
 }
 
+// RecordRiskAssessment records login risk metrics
+func (m *Metrics) RecordRiskAssessment(decision string, score int) {
+	m.Risk.RecordRiskAssessment(decision, score)
+}
+
+// RecordBotAssessment records bot detection metrics
+func (m *Metrics) RecordBotAssessment(score int, signals []string) {
+	m.Risk.RecordBotAssessment(score, signals)
+}
+
+// RecordLoginAttempt records a login attempt by outcome
+func (m *Metrics) RecordLoginAttempt(outcome string) {
+	m.Auth.RecordLoginOutcome(outcome)
+}
+
+// RecordSignupAttempt records a signup attempt by outcome
+func (m *Metrics) RecordSignupAttempt(outcome string) {
+	m.Auth.RecordSignupOutcome(outcome)
+}
+
+// RecordRolloutOutcome records an auth step gated by a rollout flag
+func (m *Metrics) RecordRolloutOutcome(flag, variant, outcome string) {
+	m.Auth.RecordRolloutOutcome(flag, variant, outcome)
+}
+
+// RecordVerificationResend records verification resend metrics
+func (m *Metrics) RecordVerificationResend(outcome string) {
+	m.Business.RecordVerificationResend(outcome)
+}
+
 // RecordEmailSent records email metrics
 func (m *Metrics) RecordEmailSent(emailType string, duration time.Duration, err error) {
 	labels := map[string]string{
@@ -266,3 +336,28 @@ func (m *Metrics) RecordEmailSent(emailType string, duration time.Duration, err
 		m.EmailSendLatency().WithLabels(labels).Observe(duration.Seconds())
 	}
 }
+
+// RecordStuckEmailWorker records an email worker abandoned on shutdown
+func (m *Metrics) RecordStuckEmailWorker(template string) {
+	m.Email.RecordStuckWorker(template)
+}
+
+// RecordEmailTrackingEvent records a tracking event of a tracked email
+func (m *Metrics) RecordEmailTrackingEvent(email, event string) {
+	m.Email.RecordTrackingEvent(email, event)
+}
+
+// RecordEmailProviderSend records a send through an email provider
+func (m *Metrics) RecordEmailProviderSend(provider, outcome string) {
+	m.Email.RecordProviderSend(provider, outcome)
+}
+
+// SetActiveEmailProvider marks whether an email provider is the active one
+func (m *Metrics) SetActiveEmailProvider(provider string, active bool) {
+	m.Email.SetActiveProvider(provider, active)
+}
+
+// RecordEmailFailover records a switch of the active email provider
+func (m *Metrics) RecordEmailFailover(from, to string) {
+	m.Email.RecordFailover(from, to)
+}
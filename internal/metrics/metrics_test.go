@@ -198,6 +198,118 @@ func TestMetrics_RecordDBQuery(t *testing.T) {
 	}
 }
 
+func TestMetrics_RecordVerificationResend(t *testing.T) {
+	m := NewMetrics()
+
+	m.RecordVerificationResend("sent")
+	m.RecordVerificationResend("throttled")
+	m.RecordVerificationResend("throttled")
+
+	if v, ok := m.Business.VerificationResends.Value().(int64); !ok || v != 3 {
+		t.Errorf("Expected VerificationResends to be 3, got %v", m.Business.VerificationResends.Value())
+	}
+	throttled := m.Business.VerificationResends.WithLabels(map[string]string{"outcome": "throttled"})
+	if v := throttled.Value(); v != 2 {
+		t.Errorf("Expected throttled resends to be 2, got %v", v)
+	}
+}
+
+func TestMetrics_RecordLoginAttempt(t *testing.T) {
+	m := NewMetrics()
+
+	m.RecordLoginAttempt("success")
+	m.RecordLoginAttempt("bad_password")
+	m.RecordLoginAttempt("bad_password")
+	m.RecordLoginAttempt("unknown_user")
+	m.RecordSignupAttempt("duplicate_email")
+
+	if v, ok := m.LoginAttempts().Value().(int64); !ok || v != 4 {
+		t.Errorf("Expected LoginAttempts to be 4, got %v", m.LoginAttempts().Value())
+	}
+	if v, ok := m.LoginFailure().Value().(int64); !ok || v != 3 {
+		t.Errorf("Expected LoginFailure to be 3, got %v", m.LoginFailure().Value())
+	}
+	badPassword := m.LoginAttempts().WithLabels(map[string]string{"outcome": "bad_password"})
+	if v := badPassword.Value(); v != 2 {
+		t.Errorf("Expected bad_password logins to be 2, got %v", v)
+	}
+	duplicate := m.SignupAttempts().WithLabels(map[string]string{"outcome": "duplicate_email"})
+	if v := duplicate.Value(); v != 1 {
+		t.Errorf("Expected duplicate_email signups to be 1, got %v", v)
+	}
+	if v, ok := m.SignupFailure().Value().(int64); !ok || v != 1 {
+		t.Errorf("Expected SignupFailure to be 1, got %v", m.SignupFailure().Value())
+	}
+}
+
+func TestMetrics_RecordBotAssessment(t *testing.T) {
+	m := NewMetrics()
+
+	m.RecordBotAssessment(45, []string{"missing_accept_language", "ip_burst"})
+	m.RecordBotAssessment(30, []string{"ip_burst"})
+	m.RecordBotAssessment(0, nil)
+
+	if v, ok := m.Risk.BotRequests.Value().(int64); !ok || v != 3 {
+		t.Errorf("Expected BotRequests to be 3, got %v", m.Risk.BotRequests.Value())
+	}
+	burst := m.Risk.BotSignals.WithLabels(map[string]string{"signal": "ip_burst"})
+	if v := burst.Value(); v != 2 {
+		t.Errorf("Expected ip_burst signals to be 2, got %v", v)
+	}
+}
+
+func TestMetrics_RecordStuckEmailWorker(t *testing.T) {
+	m := NewMetrics()
+
+	m.RecordStuckEmailWorker("verification")
+	m.RecordStuckEmailWorker("")
+
+	if v := m.Email.StuckWorkers.WithLabels(map[string]string{"type": "verification"}).Value(); v != 1 {
+		t.Errorf("Expected 1 stuck verification worker, got %v", v)
+	}
+	if v := m.Email.StuckWorkers.WithLabels(map[string]string{"type": "other"}).Value(); v != 1 {
+		t.Errorf("Expected 1 stuck worker without a template, got %v", v)
+	}
+}
+
+func TestMetrics_RecordEmailTrackingEvent(t *testing.T) {
+	m := NewMetrics()
+
+	m.RecordEmailTrackingEvent("verification", "sent")
+	m.RecordEmailTrackingEvent("verification", "clicked")
+	m.RecordEmailTrackingEvent("verification", "clicked")
+
+	if v := m.Email.TrackingEvents.WithLabels(map[string]string{"email": "verification", "event": "clicked"}).Value(); v != 2 {
+		t.Errorf("Expected 2 clicks, got %v", v)
+	}
+	if v := m.Email.TrackingEvents.WithLabels(map[string]string{"email": "verification", "event": "sent"}).Value(); v != 1 {
+		t.Errorf("Expected 1 sent email, got %v", v)
+	}
+}
+
+func TestMetrics_EmailProviders(t *testing.T) {
+	m := NewMetrics()
+
+	m.RecordEmailProviderSend("primary", "failed")
+	m.RecordEmailProviderSend("secondary", "sent")
+	m.SetActiveEmailProvider("primary", false)
+	m.SetActiveEmailProvider("secondary", true)
+	m.RecordEmailFailover("primary", "secondary")
+
+	if v := m.Email.ProviderSends.WithLabels(map[string]string{"provider": "primary", "outcome": "failed"}).Value(); v != 1 {
+		t.Errorf("Expected 1 failed primary send, got %v", v)
+	}
+	if v := m.Email.ActiveProvider.WithLabels(map[string]string{"provider": "secondary"}).Value(); v != 1 {
+		t.Errorf("Expected secondary to be active, got %v", v)
+	}
+	if v := m.Email.ActiveProvider.WithLabels(map[string]string{"provider": "primary"}).Value(); v != 0 {
+		t.Errorf("Expected primary to be inactive, got %v", v)
+	}
+	if v := m.Email.Failovers.WithLabels(map[string]string{"from": "primary", "to": "secondary"}).Value(); v != 1 {
+		t.Errorf("Expected 1 failover, got %v", v)
+	}
+}
+
 func TestMetrics_RecordEmailSent(t *testing.T) {
 	m := NewMetrics()
 
@@ -359,6 +471,80 @@ func TestMetrics_PrometheusHandler(t *testing.T) {
 	}
 }
 
+func TestMetrics_PrometheusHandler_Labels(t *testing.T) {
+	m := NewMetrics()
+	defer m.Stop()
+
+	m.RateLimit.RecordDecision("auth", "10.0.0.1", false)
+	m.RateLimit.RecordDecision("auth", "10.0.0.2", true)
+	m.RateLimit.SetActiveBuckets("auth", 2)
+
+	rr := httptest.NewRecorder()
+	m.PrometheusHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+
+	for _, want := range []string{
+		`rate_limit_requests_total{decision="allowed",policy="auth"} 1`,
+		`rate_limit_requests_total{decision="denied",policy="auth"} 1`,
+		`rate_limit_active_buckets{policy="auth"} 2`,
+		`rate_limit_top_denied_keys{key="10.0.0.1",policy="auth"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in metrics output", want)
+		}
+	}
+}
+
+func TestMetrics_PrometheusHandler_RouteSLOs(t *testing.T) {
+	m := NewMetricsWithConfig(Config{
+		LatencyBuckets: []float64{0.5, 0.1},
+		SLOs: map[string]SLO{
+			"POST /api/v1/auth/login": {Latency: 300 * time.Millisecond, Objective: 0.99},
+		},
+	})
+	defer m.Stop()
+
+	m.RecordHTTPRequest("POST", "/api/v1/auth/login", "200", 200*time.Millisecond, 10)
+
+	rr := httptest.NewRecorder()
+	m.PrometheusHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+
+	// The SLO threshold becomes a bucket, and buckets are exported in order
+	want := []string{
+		`http_request_duration_seconds_bucket{le="0.1",method="POST",path="/api/v1/auth/login",status="200"} 0`,
+		`http_request_duration_seconds_bucket{le="0.3",method="POST",path="/api/v1/auth/login",status="200"} 1`,
+		`http_request_duration_seconds_bucket{le="0.5",method="POST",path="/api/v1/auth/login",status="200"} 1`,
+		`http_request_duration_seconds_bucket{le="+Inf",method="POST",path="/api/v1/auth/login",status="200"} 1`,
+		`http_request_duration_seconds_count{method="POST",path="/api/v1/auth/login",status="200"} 1`,
+		`http_route_slo_info{latency_seconds="0.3",method="POST",objective="0.99",path="/api/v1/auth/login",route="POST /api/v1/auth/login"} 1`,
+	}
+	last := -1
+	for _, line := range want {
+		i := strings.Index(body, line)
+		if i < 0 {
+			t.Fatalf("Expected %s in metrics output:\n%s", line, body)
+		}
+		if i < last && strings.HasPrefix(line, "http_request_duration_seconds_bucket") {
+			t.Errorf("Bucket %s is out of order", line)
+		}
+		last = i
+	}
+
+	if got := m.SLO.Routes(); len(got) != 1 || got[0] != "POST /api/v1/auth/login" {
+		t.Errorf("SLO.Routes() = %v", got)
+	}
+
+	// Without SLOs the info metric is not exported
+	plain := NewMetrics()
+	defer plain.Stop()
+	rr = httptest.NewRecorder()
+	plain.PrometheusHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(rr.Body.String(), "http_route_slo_info") {
+		t.Error("Unexpected http_route_slo_info without SLOs")
+	}
+}
+
 func TestMetrics_collectSystemMetrics_goroutine(t *testing.T) {
 	m := NewMetrics()
 
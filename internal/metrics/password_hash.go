@@ -0,0 +1,41 @@
+package metrics
+
+import "time"
+
+// PasswordHashMetrics contains the password hashing pool metrics
+type PasswordHashMetrics struct {
+	QueueWait  *Histogram
+	QueueDepth *Gauge
+	Shed       *Counter // labeled by reason (queue_full or wait_budget)
+}
+
+// NewPasswordHashMetrics creates a new PasswordHashMetrics instance
+func NewPasswordHashMetrics() *PasswordHashMetrics {
+	return &PasswordHashMetrics{
+		QueueWait:  NewHistogram("password_hash_queue_wait_seconds", "Time password hashes wait for a hashing worker"),
+		QueueDepth: NewGauge("password_hash_queue_depth", "Password hashes waiting for a hashing worker"),
+		Shed:       NewCounter("password_hash_shed_total", "Password hashes rejected with 503 by reason"),
+	}
+}
+
+// Register registers all password hashing metrics
+func (p *PasswordHashMetrics) Register(registry MetricRegistry) {
+	registry.Register(p.QueueWait)
+	registry.Register(p.QueueDepth)
+	registry.Register(p.Shed)
+}
+
+// ObserveHashQueueWait records how long a hash waited for a worker
+func (p *PasswordHashMetrics) ObserveHashQueueWait(wait time.Duration) {
+	p.QueueWait.Observe(wait.Seconds())
+}
+
+// SetHashQueueDepth records the number of hashes waiting for a worker
+func (p *PasswordHashMetrics) SetHashQueueDepth(depth int) {
+	p.QueueDepth.Set(float64(depth))
+}
+
+// RecordHashShed records a hash rejected because the pool is overloaded
+func (p *PasswordHashMetrics) RecordHashShed(reason string) {
+	p.Shed.WithLabels(map[string]string{"reason": reason}).Inc()
+}
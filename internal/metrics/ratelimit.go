@@ -1,9 +1,20 @@
 package metrics
 
+import "time"
+
+// topDeniedKeys is how many of the most rate limited keys are exported
+const topDeniedKeys = 10
+
 // RateLimitMetrics contains all rate limiting-related metrics
 type RateLimitMetrics struct {
 	RateLimitHits     *Counter
 	RateLimitExceeded *Counter
+
+	// Per-policy limiter state, labeled by policy
+	RateLimitRequests      *Counter // also labeled by decision (allowed, denied or shadow_denied)
+	RateLimitBuckets       *Gauge
+	RateLimitCleanup       *Histogram
+	RateLimitTopDeniedKeys *TopK // approximate, labeled by policy and key
 }
 
 // NewRateLimitMetrics creates a new RateLimitMetrics instance
@@ -11,6 +22,11 @@ func NewRateLimitMetrics() *RateLimitMetrics {
 	return &RateLimitMetrics{
 		RateLimitHits:     NewCounter("rate_limit_hits_total", "Total number of rate limit checks"),
 		RateLimitExceeded: NewCounter("rate_limit_exceeded_total", "Total number of rate limit exceeded events"),
+		RateLimitRequests: NewCounter("rate_limit_requests_total", "Rate limit decisions by policy and decision"),
+		RateLimitBuckets:  NewGauge("rate_limit_active_buckets", "Number of active rate limit buckets by policy"),
+		RateLimitCleanup: NewHistogramWithBuckets("rate_limit_cleanup_duration_seconds", "Duration of rate limit bucket cleanup cycles",
+			[]float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}),
+		RateLimitTopDeniedKeys: NewTopK("rate_limit_top_denied_keys", "Approximate denied request counts of the most rate limited keys", topDeniedKeys),
 	}
 }
 
@@ -18,6 +34,10 @@ func NewRateLimitMetrics() *RateLimitMetrics {
 func (r *RateLimitMetrics) Register(registry MetricRegistry) {
 	registry.Register(r.RateLimitHits)
 	registry.Register(r.RateLimitExceeded)
+	registry.Register(r.RateLimitRequests)
+	registry.Register(r.RateLimitBuckets)
+	registry.Register(r.RateLimitCleanup)
+	registry.Register(r.RateLimitTopDeniedKeys)
 }
 
 // RecordHit records a rate limit check
@@ -26,4 +46,38 @@ func (r *RateLimitMetrics) RecordHit(exceeded bool) {
 	if exceeded {
 		r.RateLimitExceeded.Inc()
 	}
-}
\ No newline at end of file
+}
+
+// RecordDecision records a rate limit check for a policy. Denied keys feed
+// the top-K sketch.
+func (r *RateLimitMetrics) RecordDecision(policy, key string, allowed bool) {
+	r.RecordHit(!allowed)
+
+	decision := "allowed"
+	if !allowed {
+		decision = "denied"
+		r.RateLimitTopDeniedKeys.Inc(map[string]string{"policy": policy, "key": key})
+	}
+	r.RateLimitRequests.Inc()
+	r.RateLimitRequests.WithLabels(map[string]string{"policy": policy, "decision": decision}).Inc()
+}
+
+// RecordShadowDenial records a request a shadow policy would have denied.
+// It is not counted as exceeded, but its key feeds the top-K sketch.
+func (r *RateLimitMetrics) RecordShadowDenial(policy, key string) {
+	r.RecordHit(false)
+	r.RateLimitTopDeniedKeys.Inc(map[string]string{"policy": policy, "key": key})
+	r.RateLimitRequests.Inc()
+	r.RateLimitRequests.WithLabels(map[string]string{"policy": policy, "decision": "shadow_denied"}).Inc()
+}
+
+// SetActiveBuckets records the number of buckets a policy holds
+func (r *RateLimitMetrics) SetActiveBuckets(policy string, count int) {
+	r.RateLimitBuckets.WithLabels(map[string]string{"policy": policy}).Set(float64(count))
+}
+
+// RecordCleanup records the duration of a bucket cleanup cycle
+func (r *RateLimitMetrics) RecordCleanup(policy string, duration time.Duration) {
+	r.RateLimitCleanup.Observe(duration.Seconds())
+	r.RateLimitCleanup.WithLabels(map[string]string{"policy": policy}).Observe(duration.Seconds())
+}
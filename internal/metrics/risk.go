@@ -0,0 +1,58 @@
+package metrics
+
+// RiskMetrics contains all login risk and bot scoring metrics
+type RiskMetrics struct {
+	RiskAssessments *Counter
+	RiskChallenges  *Counter
+	RiskScore       *Histogram
+
+	BotRequests *Counter
+	BotSignals  *Counter
+	BotScore    *Histogram
+}
+
+// NewRiskMetrics creates a new RiskMetrics instance
+func NewRiskMetrics() *RiskMetrics {
+	return &RiskMetrics{
+		RiskAssessments: NewCounter("auth_risk_assessments_total", "Total number of login risk assessments"),
+		RiskChallenges:  NewCounter("auth_risk_challenges_total", "Total number of logins challenged or blocked by risk scoring"),
+		RiskScore: NewHistogramWithBuckets("auth_risk_score", "Distribution of login risk scores",
+			[]float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}),
+		BotRequests: NewCounter("auth_bot_requests_total", "Total number of requests scored by bot detection"),
+		BotSignals:  NewCounter("auth_bot_signals_total", "Total number of bot signals raised, labeled by signal"),
+		BotScore: NewHistogramWithBuckets("auth_bot_score", "Distribution of request bot scores",
+			[]float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}),
+	}
+}
+
+// Register registers all risk metrics
+func (r *RiskMetrics) Register(registry MetricRegistry) {
+	registry.Register(r.RiskAssessments)
+	registry.Register(r.RiskChallenges)
+	registry.Register(r.RiskScore)
+	registry.Register(r.BotRequests)
+	registry.Register(r.BotSignals)
+	registry.Register(r.BotScore)
+}
+
+// RecordRiskAssessment records the outcome of a login risk assessment
+func (r *RiskMetrics) RecordRiskAssessment(decision string, score int) {
+	labels := map[string]string{"decision": decision}
+
+	r.RiskAssessments.Inc()
+	r.RiskAssessments.WithLabels(labels).Inc()
+	if decision != "allow" && decision != "exempt" {
+		r.RiskChallenges.Inc()
+	}
+	r.RiskScore.Observe(float64(score))
+}
+
+// RecordBotAssessment records the bot score of a request and each signal it raised
+func (r *RiskMetrics) RecordBotAssessment(score int, signals []string) {
+	r.BotRequests.Inc()
+	for _, signal := range signals {
+		r.BotSignals.Inc()
+		r.BotSignals.WithLabels(map[string]string{"signal": signal}).Inc()
+	}
+	r.BotScore.Observe(float64(score))
+}
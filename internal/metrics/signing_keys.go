@@ -0,0 +1,23 @@
+package metrics
+
+// SigningKeyMetrics contains the JWT signing key rollover metrics
+type SigningKeyMetrics struct {
+	SigningKeys *Gauge // labeled by phase (pending, active or retiring)
+}
+
+// NewSigningKeyMetrics creates a new SigningKeyMetrics instance
+func NewSigningKeyMetrics() *SigningKeyMetrics {
+	return &SigningKeyMetrics{
+		SigningKeys: NewGauge("jwt_signing_keys", "Published JWT signing keys by rollover phase"),
+	}
+}
+
+// Register registers all signing key metrics
+func (s *SigningKeyMetrics) Register(registry MetricRegistry) {
+	registry.Register(s.SigningKeys)
+}
+
+// SetSigningKeys records the number of signing keys in a rollover phase
+func (s *SigningKeyMetrics) SetSigningKeys(phase string, count int) {
+	s.SigningKeys.WithLabels(map[string]string{"phase": phase}).Set(float64(count))
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config customizes the metrics created by NewMetricsWithConfig
+type Config struct {
+	// LatencyBuckets are the bucket upper bounds, in seconds, of the HTTP
+	// request, database query and email send latency histograms;
+	// DefaultBuckets when empty
+	LatencyBuckets []float64
+
+	// SLOs are latency objectives by route pattern, such as
+	// "POST /api/v1/auth/login". Each threshold is added to the HTTP
+	// latency buckets so the objective can be computed exactly.
+	SLOs map[string]SLO
+}
+
+// SLO is a latency objective: Objective of the requests to a route finish
+// within Latency
+type SLO struct {
+	Latency   time.Duration
+	Objective float64 // fraction between 0 and 1, such as 0.99
+}
+
+// SLOInfo exports the configured route SLOs as an info metric, one series
+// per route with value 1, so alerting rules can be generated from the
+// thresholds the service was deployed with
+type SLOInfo struct {
+	name string
+	help string
+	slos map[string]SLO
+}
+
+// NewSLOInfo creates the info metric for the given route SLOs
+func NewSLOInfo(slos map[string]SLO) *SLOInfo {
+	copied := make(map[string]SLO, len(slos))
+	for route, slo := range slos {
+		copied[route] = slo
+	}
+	return &SLOInfo{
+		name: "http_route_slo_info",
+		help: "Latency objectives of routes; the value is always 1",
+		slos: copied,
+	}
+}
+
+// Name returns the metric name
+func (s *SLOInfo) Name() string {
+	return s.name
+}
+
+// Value returns the objectives by route
+func (s *SLOInfo) Value() interface{} {
+	values := make(map[string]interface{}, len(s.slos))
+	for route, slo := range s.slos {
+		values[route] = map[string]interface{}{
+			"latency_seconds": slo.Latency.Seconds(),
+			"objective":       slo.Objective,
+		}
+	}
+	return values
+}
+
+// String returns a string representation of the metric
+func (s *SLOInfo) String() string {
+	return fmt.Sprintf("%s: %d routes", s.name, len(s.slos))
+}
+
+// Routes returns the route patterns that have an SLO
+func (s *SLOInfo) Routes() []string {
+	return sortedKeys(s.slos)
+}
+
+// writePrometheus exports one info series per route. The method and path
+// labels match those of http_request_duration_seconds for routes without
+// wildcards.
+func (s *SLOInfo) writePrometheus(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", s.name, s.help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", s.name)
+	for _, route := range sortedKeys(s.slos) {
+		slo := s.slos[route]
+		method, path, _ := strings.Cut(route, " ")
+		fmt.Fprintf(w, "%s%s 1\n", s.name, formatLabels(map[string]string{
+			"route":           route,
+			"method":          method,
+			"path":            path,
+			"latency_seconds": strconv.FormatFloat(slo.Latency.Seconds(), 'g', -1, 64),
+			"objective":       strconv.FormatFloat(slo.Objective, 'g', -1, 64),
+		}))
+	}
+}
+
+// latencyBuckets returns the configured buckets with every SLO threshold
+// added
+func latencyBuckets(cfg Config) []float64 {
+	buckets := cfg.LatencyBuckets
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	seen := make(map[float64]bool, len(buckets))
+	merged := make([]float64, 0, len(buckets)+len(cfg.SLOs))
+	for _, b := range buckets {
+		if !seen[b] {
+			seen[b] = true
+			merged = append(merged, b)
+		}
+	}
+	for _, slo := range cfg.SLOs {
+		if b := slo.Latency.Seconds(); !seen[b] {
+			seen[b] = true
+			merged = append(merged, b)
+		}
+	}
+	sort.Float64s(merged)
+	return merged
+}
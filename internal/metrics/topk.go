@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	sketchDepth = 4
+	sketchWidth = 2048
+)
+
+// TopK tracks the k most frequent label sets approximately. Counts come from
+// a Count-Min sketch, so memory stays bounded however many distinct keys are
+// seen; estimates may overcount but never undercount.
+type TopK struct {
+	name string
+	help string
+	k    int
+
+	mu         sync.Mutex
+	sketch     [sketchDepth][sketchWidth]uint64
+	candidates map[string]*TopKEntry
+}
+
+// TopKEntry is a tracked label set and its estimated count
+type TopKEntry struct {
+	Labels map[string]string `json:"labels"`
+	Count  uint64            `json:"count"`
+}
+
+// NewTopK creates a top-k tracker
+func NewTopK(name, help string, k int) *TopK {
+	return &TopK{
+		name:       name,
+		help:       help,
+		k:          k,
+		candidates: make(map[string]*TopKEntry, k),
+	}
+}
+
+// Inc counts one occurrence of the label set
+func (t *TopK) Inc(labels map[string]string) {
+	id := formatLabels(labels)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	estimate := t.add(id)
+	if entry, ok := t.candidates[id]; ok {
+		entry.Count = estimate
+		return
+	}
+	if len(t.candidates) < t.k {
+		t.candidates[id] = &TopKEntry{Labels: labels, Count: estimate}
+		return
+	}
+
+	// Replace the smallest candidate if this key now outranks it
+	var minID string
+	var minCount uint64
+	for candidateID, entry := range t.candidates {
+		if minID == "" || entry.Count < minCount {
+			minID, minCount = candidateID, entry.Count
+		}
+	}
+	if estimate > minCount {
+		delete(t.candidates, minID)
+		t.candidates[id] = &TopKEntry{Labels: labels, Count: estimate}
+	}
+}
+
+// add increments id in every sketch row and returns its estimated count
+func (t *TopK) add(id string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)
+
+	var estimate uint64
+	for row := 0; row < sketchDepth; row++ {
+		// Double hashing gives independent-enough row indexes from one hash
+		col := (h1 + uint32(row)*h2) % sketchWidth
+		t.sketch[row][col]++
+		if row == 0 || t.sketch[row][col] < estimate {
+			estimate = t.sketch[row][col]
+		}
+	}
+	return estimate
+}
+
+// Top returns the tracked entries, most frequent first
+func (t *TopK) Top() []TopKEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries := make([]TopKEntry, 0, len(t.candidates))
+	for _, entry := range t.candidates {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return formatLabels(entries[i].Labels) < formatLabels(entries[j].Labels)
+	})
+	return entries
+}
+
+// Reset forgets all counts
+func (t *TopK) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sketch = [sketchDepth][sketchWidth]uint64{}
+	t.candidates = make(map[string]*TopKEntry, t.k)
+}
+
+// Name returns the metric name
+func (t *TopK) Name() string {
+	return t.name
+}
+
+// Value returns the tracked entries
+func (t *TopK) Value() interface{} {
+	return t.Top()
+}
+
+// String returns a string representation of the tracked entries
+func (t *TopK) String() string {
+	var b strings.Builder
+	b.WriteString(t.name + ":")
+	for _, entry := range t.Top() {
+		fmt.Fprintf(&b, " %s=%d", formatLabels(entry.Labels), entry.Count)
+	}
+	return b.String()
+}
+
+// writePrometheus exports the entries as a gauge
+func (t *TopK) writePrometheus(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", t.name, t.help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", t.name)
+	for _, entry := range t.Top() {
+		fmt.Fprintf(w, "%s%s %d\n", t.name, formatLabels(entry.Labels), entry.Count)
+	}
+}
+
+// formatLabels renders labels in Prometheus syntax with sorted names
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%q", name, labels[name]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	topK := NewTopK("test_top", "Test top-k", 3)
+
+	// Three heavy hitters among many one-off keys
+	for i := 0; i < 100; i++ {
+		topK.Inc(map[string]string{"key": "heavy-a"})
+		if i < 50 {
+			topK.Inc(map[string]string{"key": "heavy-b"})
+		}
+		if i < 25 {
+			topK.Inc(map[string]string{"key": "heavy-c"})
+		}
+		topK.Inc(map[string]string{"key": fmt.Sprintf("noise-%d", i)})
+	}
+
+	top := topK.Top()
+	if len(top) != 3 {
+		t.Fatalf("Top() returned %d entries, want 3", len(top))
+	}
+	for i, want := range []string{"heavy-a", "heavy-b", "heavy-c"} {
+		if top[i].Labels["key"] != want {
+			t.Errorf("Top()[%d] = %s, want %s", i, top[i].Labels["key"], want)
+		}
+	}
+	// Count-Min estimates never undercount
+	if top[0].Count < 100 {
+		t.Errorf("heavy-a count = %d, want at least 100", top[0].Count)
+	}
+
+	topK.Reset()
+	if len(topK.Top()) != 0 {
+		t.Error("Reset() should forget all entries")
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	got := formatLabels(map[string]string{"policy": "auth", "decision": "denied"})
+	if want := `{decision="denied",policy="auth"}`; got != want {
+		t.Errorf("formatLabels() = %s, want %s", got, want)
+	}
+	if got := formatLabels(nil); got != "" {
+		t.Errorf("formatLabels(nil) = %q, want empty", got)
+	}
+}
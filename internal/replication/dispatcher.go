@@ -0,0 +1,260 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Config holds configuration for the dispatcher
+type Config struct {
+	Region         string        // region this instance runs in
+	QueueSize      int           // events buffered per replicator
+	BatchSize      int           // events per Replicate call
+	FlushInterval  time.Duration // longest time an event waits for its batch to fill
+	EnqueueTimeout time.Duration // how long Publish waits for a full queue; 0 drops immediately
+	MaxRetries     int
+	RetryDelay     time.Duration // doubled after every failed attempt
+	Timeout        time.Duration // per Replicate attempt
+}
+
+// DefaultConfig returns default configuration
+func DefaultConfig() Config {
+	return Config{
+		QueueSize:      10000,
+		BatchSize:      100,
+		FlushInterval:  time.Second,
+		EnqueueTimeout: 50 * time.Millisecond,
+		MaxRetries:     5,
+		RetryDelay:     500 * time.Millisecond,
+		Timeout:        10 * time.Second,
+	}
+}
+
+// sink is the queue and worker state of one replicator
+type sink struct {
+	replicator Replicator
+	queue      chan Event
+}
+
+// Dispatcher delivers events to replicators. Each replicator has a single
+// worker, so events reach a secondary region in the order they were
+// published, and its own bounded queue, so a slow region does not hold back
+// the others or the request path.
+type Dispatcher struct {
+	sinks  []*sink
+	config Config
+	logger *slog.Logger
+
+	// mu guards closed so Publish never sends on a closed queue
+	mu     sync.RWMutex
+	closed bool
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewDispatcher creates a dispatcher for the given replicators
+func NewDispatcher(replicators []Replicator, config Config, logger *slog.Logger) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &Dispatcher{
+		config: config,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, replicator := range replicators {
+		d.sinks = append(d.sinks, &sink{
+			replicator: replicator,
+			queue:      make(chan Event, config.QueueSize),
+		})
+	}
+	return d
+}
+
+// Region returns the region events are published from
+func (d *Dispatcher) Region() string {
+	return d.config.Region
+}
+
+// Start starts one worker per replicator
+func (d *Dispatcher) Start() {
+	for _, sk := range d.sinks {
+		d.logger.Info("starting refresh token replicator",
+			"replicator", sk.replicator.Name(),
+			"region", d.config.Region,
+			"queue_size", cap(sk.queue),
+		)
+		d.wg.Add(1)
+		go d.run(sk)
+	}
+}
+
+// Stop stops accepting events, flushes the queues and waits for the workers
+// to finish. Deliveries still running after the timeout are canceled.
+func (d *Dispatcher) Stop(timeout time.Duration) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	for _, sk := range d.sinks {
+		close(sk.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+	case <-time.After(timeout):
+		d.cancel()
+		return fmt.Errorf("timeout waiting for refresh token replicators to flush")
+	}
+
+	// Release connections held by replicators such as the secondary database
+	for _, sk := range d.sinks {
+		if closer, ok := sk.replicator.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				d.logger.Warn("failed to close refresh token replicator", "replicator", sk.replicator.Name(), "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Publish queues an event for every replicator. When a queue is full Publish
+// waits up to the enqueue timeout and then drops the event for that
+// replicator; the primary region is unaffected, but a user whose event was
+// dropped may have to sign in again after a failover.
+func (d *Dispatcher) Publish(event Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return
+	}
+
+	for _, sk := range d.sinks {
+		if !d.enqueue(sk, event) {
+			d.logger.Warn("replication queue full, dropping event",
+				"replicator", sk.replicator.Name(),
+				"type", event.Type,
+				"user_id", event.UserID,
+			)
+		}
+	}
+}
+
+func (d *Dispatcher) enqueue(sk *sink, event Event) bool {
+	select {
+	case sk.queue <- event:
+		return true
+	default:
+	}
+
+	if d.config.EnqueueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(d.config.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case sk.queue <- event:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// run batches queued events until the queue is closed and drained
+func (d *Dispatcher) run(sk *sink) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, d.config.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			d.replicate(sk, batch)
+			batch = make([]Event, 0, d.config.BatchSize)
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-sk.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= d.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// replicate delivers a batch, retrying with exponential backoff. Later
+// batches wait, which keeps the secondary region in order.
+func (d *Dispatcher) replicate(sk *sink, batch []Event) {
+	name := sk.replicator.Name()
+	delay := d.config.RetryDelay
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(d.ctx, d.config.Timeout)
+		err = sk.replicator.Replicate(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		d.logger.Warn("refresh token replication failed",
+			"replicator", name,
+			"events", len(batch),
+			"attempt", attempt+1,
+			"error", err,
+		)
+
+		if attempt == d.config.MaxRetries || !d.wait(delay) {
+			break
+		}
+		delay *= 2
+	}
+
+	d.logger.Error("refresh token replication failed after retries",
+		"replicator", name,
+		"events", len(batch),
+		"error", err,
+	)
+}
+
+// wait sleeps before a retry and reports false when the dispatcher was stopped
+func (d *Dispatcher) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
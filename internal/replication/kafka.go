@@ -0,0 +1,100 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// kafkaContentType is the Kafka REST Proxy v2 content type for JSON records
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// KafkaReplicator produces events to a Kafka topic through a Kafka REST
+// Proxy. Records are keyed by user, so all events of a user land on the same
+// partition and are consumed in order by the secondary region.
+type KafkaReplicator struct {
+	client *httpclient.Client
+	url    string
+}
+
+// kafkaRecord is a record in a Kafka REST Proxy produce request
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+// kafkaProduceResponse is the Kafka REST Proxy produce response
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// NewKafkaReplicator creates a replicator that produces to topic through the
+// REST proxy at proxyURL
+func NewKafkaReplicator(client *httpclient.Client, proxyURL, topic string) *KafkaReplicator {
+	return &KafkaReplicator{
+		client: client,
+		url:    strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+	}
+}
+
+// Name identifies the replicator
+func (r *KafkaReplicator) Name() string {
+	return "kafka"
+}
+
+// Replicate produces the batch in a single request
+func (r *KafkaReplicator) Replicate(ctx context.Context, events []Event) error {
+	records := make([]kafkaRecord, len(events))
+	for i, event := range events {
+		key := event.UserID
+		if key == "" {
+			key = event.Token
+		}
+		records[i] = kafkaRecord{Key: key, Value: event}
+	}
+
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return fmt.Errorf("failed to encode replication records: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create produce request: %w", err)
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to produce replication records: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to produce replication records: unexpected status %s: %s", resp.Status, detail)
+	}
+
+	// The proxy reports records it could not produce with a 200 status
+	var result kafkaProduceResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode produce response: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("failed to produce replication records: error %d: %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
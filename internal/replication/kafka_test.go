@@ -0,0 +1,88 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+func TestKafkaReplicator_Replicate(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		wantErr  bool
+	}{
+		{
+			name:     "produced",
+			status:   http.StatusOK,
+			response: `{"offsets":[{"partition":0,"offset":1},{"partition":0,"offset":2}]}`,
+		},
+		{
+			name:     "record rejected",
+			status:   http.StatusOK,
+			response: `{"offsets":[{"partition":0,"offset":1},{"error_code":50002,"error":"not enough replicas"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "topic not found",
+			status:   http.StatusNotFound,
+			response: `{"error_code":40401,"message":"Topic not found"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, contentType string
+			var body struct {
+				Records []struct {
+					Key   string `json:"key"`
+					Value Event  `json:"value"`
+				} `json:"records"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				contentType = r.Header.Get("Content-Type")
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			config := httpclient.DefaultConfig()
+			config.MaxRetries = 0
+			client, err := httpclient.New(config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatalf("httpclient.New() error = %v", err)
+			}
+
+			r := NewKafkaReplicator(client, server.URL+"/", "auth.refresh-tokens")
+			events := []Event{
+				NewUserRevokedEvent("eu-west-1", "user-1"),
+				NewRevokedEvent("eu-west-1", "", "token-2"),
+			}
+
+			err = r.Replicate(context.Background(), events)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Replicate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if path != "/topics/auth.refresh-tokens" || contentType != kafkaContentType {
+				t.Errorf("unexpected request: path=%q Content-Type=%q", path, contentType)
+			}
+			// Records are keyed by user, falling back to the token
+			if len(body.Records) != 2 || body.Records[0].Key != "user-1" || body.Records[1].Key != "token-2" {
+				t.Errorf("unexpected records: %+v", body.Records)
+			}
+			if body.Records[0].Value.Type != EventUserTokensRevoked {
+				t.Errorf("record value type = %s, want %s", body.Records[0].Value.Type, EventUserTokensRevoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package replication copies refresh token changes to a secondary region so
+// a regional failover does not log every user out. Events are published in
+// order after the primary write succeeds and delivered asynchronously.
+package replication
+
+import (
+	"context"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// EventType names a refresh token change
+type EventType string
+
+// Replicated refresh token changes
+const (
+	EventTokenCreated      EventType = "refresh_token.created"
+	EventTokenRevoked      EventType = "refresh_token.revoked"
+	EventUserTokensRevoked EventType = "refresh_token.user_revoked"
+)
+
+// Event is a refresh token change. Client IP addresses and user agents are
+// not replicated: they are personal data that may be encrypted with keys the
+// secondary region does not share.
+type Event struct {
+	Type       EventType `json:"type"`
+	Region     string    `json:"region"` // region the change was made in
+	OccurredAt time.Time `json:"occurred_at"`
+	UserID     string    `json:"user_id"` // empty when a revoked token was not found
+
+	// Set for created and revoked tokens
+	Token string `json:"token,omitempty"`
+
+	// Set for created tokens
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	DPoPThumbprint *string    `json:"dpop_jkt,omitempty"`
+	RememberMe     bool       `json:"remember_me,omitempty"`
+}
+
+// Replicator delivers batches of events to a secondary region in order.
+// Applying an event twice must be harmless, since failed batches are retried
+// as a whole.
+type Replicator interface {
+	// Name identifies the replicator in logs
+	Name() string
+	Replicate(ctx context.Context, events []Event) error
+}
+
+// NewCreatedEvent describes a new refresh token
+func NewCreatedEvent(region string, token *domain.RefreshToken) Event {
+	expiresAt, createdAt := token.ExpiresAt, token.CreatedAt
+	return Event{
+		Type:           EventTokenCreated,
+		Region:         region,
+		OccurredAt:     time.Now().UTC(),
+		UserID:         token.UserID,
+		Token:          token.Token,
+		ExpiresAt:      &expiresAt,
+		CreatedAt:      &createdAt,
+		DPoPThumbprint: token.DPoPThumbprint,
+		RememberMe:     token.RememberMe,
+	}
+}
+
+// NewRevokedEvent describes a revoked refresh token
+func NewRevokedEvent(region, userID, token string) Event {
+	return Event{
+		Type:       EventTokenRevoked,
+		Region:     region,
+		OccurredAt: time.Now().UTC(),
+		UserID:     userID,
+		Token:      token,
+	}
+}
+
+// NewUserRevokedEvent describes revoking every refresh token of a user.
+// Tokens the secondary region issued after OccurredAt are kept.
+func NewUserRevokedEvent(region, userID string) Event {
+	return Event{
+		Type:       EventUserTokensRevoked,
+		Region:     region,
+		OccurredAt: time.Now().UTC(),
+		UserID:     userID,
+	}
+}
@@ -0,0 +1,71 @@
+package replication
+
+import (
+	"context"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// Repository publishes refresh token changes to the dispatcher after they
+// have been stored in the primary region
+type Repository struct {
+	repository.RefreshTokenRepository
+	dispatcher *Dispatcher
+}
+
+// NewRepository wraps a refresh token repository
+func NewRepository(repo repository.RefreshTokenRepository, dispatcher *Dispatcher) *Repository {
+	return &Repository{RefreshTokenRepository: repo, dispatcher: dispatcher}
+}
+
+// Create stores the token and replicates it
+func (r *Repository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	if err := r.RefreshTokenRepository.Create(ctx, token); err != nil {
+		return err
+	}
+	r.dispatcher.Publish(NewCreatedEvent(r.dispatcher.Region(), token))
+	return nil
+}
+
+// Revoke revokes the token and replicates the revocation
+func (r *Repository) Revoke(ctx context.Context, tokenValue string) error {
+	if err := r.RefreshTokenRepository.Revoke(ctx, tokenValue); err != nil {
+		return err
+	}
+	r.dispatcher.Publish(NewRevokedEvent(r.dispatcher.Region(), r.ownerOf(ctx, tokenValue), tokenValue))
+	return nil
+}
+
+// RevokeAllForUser revokes every token of the user and replicates the
+// revocation
+func (r *Repository) RevokeAllForUser(ctx context.Context, userID string) error {
+	if err := r.RefreshTokenRepository.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	r.dispatcher.Publish(NewUserRevokedEvent(r.dispatcher.Region(), userID))
+	return nil
+}
+
+// DeleteByToken deletes the token and replicates it as a revocation, so the
+// token stops working in the secondary region too
+func (r *Repository) DeleteByToken(ctx context.Context, tokenValue string) error {
+	userID := r.ownerOf(ctx, tokenValue)
+	if err := r.RefreshTokenRepository.DeleteByToken(ctx, tokenValue); err != nil {
+		return err
+	}
+	r.dispatcher.Publish(NewRevokedEvent(r.dispatcher.Region(), userID, tokenValue))
+	return nil
+}
+
+// ownerOf looks up the user of a token so replicators can keep each user's
+// events in order. An unknown owner still replicates the revocation.
+func (r *Repository) ownerOf(ctx context.Context, tokenValue string) string {
+	token, err := r.RefreshTokenRepository.GetByToken(ctx, tokenValue)
+	if err != nil {
+		return ""
+	}
+	return token.UserID
+}
+
+var _ repository.RefreshTokenRepository = (*Repository)(nil)
@@ -0,0 +1,205 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// recordingReplicator keeps replicated events and fails the first failures calls
+type recordingReplicator struct {
+	mu       sync.Mutex
+	events   []Event
+	calls    int
+	failures int
+}
+
+func (r *recordingReplicator) Name() string {
+	return "test"
+}
+
+func (r *recordingReplicator) Replicate(ctx context.Context, events []Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("region unavailable")
+	}
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *recordingReplicator) replicated() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// memoryTokenRepository stores tokens in a map
+type memoryTokenRepository struct {
+	repository.RefreshTokenRepository
+	tokens map[string]*domain.RefreshToken
+}
+
+func (m *memoryTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	if token.UserID == "" {
+		return errors.New("user required")
+	}
+	token.Token = "token-" + token.UserID
+	m.tokens[token.Token] = token
+	return nil
+}
+
+func (m *memoryTokenRepository) GetByToken(ctx context.Context, tokenValue string) (*domain.RefreshToken, error) {
+	token, ok := m.tokens[tokenValue]
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	return token, nil
+}
+
+func (m *memoryTokenRepository) Revoke(ctx context.Context, tokenValue string) error {
+	token, ok := m.tokens[tokenValue]
+	if !ok || token.Revoked {
+		return domain.ErrInvalidToken
+	}
+	token.Revoked = true
+	return nil
+}
+
+func (m *memoryTokenRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (m *memoryTokenRepository) DeleteByToken(ctx context.Context, tokenValue string) error {
+	delete(m.tokens, tokenValue)
+	return nil
+}
+
+func testDispatcherConfig() Config {
+	config := DefaultConfig()
+	config.Region = "eu-west-1"
+	config.QueueSize = 10
+	config.BatchSize = 2
+	config.FlushInterval = time.Hour
+	config.EnqueueTimeout = 0
+	config.RetryDelay = time.Millisecond
+	return config
+}
+
+func newTestDispatcher(replicator Replicator, config Config) *Dispatcher {
+	return NewDispatcher([]Replicator{replicator}, config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRepository_PublishesChanges(t *testing.T) {
+	replicator := &recordingReplicator{}
+	d := newTestDispatcher(replicator, testDispatcherConfig())
+	d.Start()
+
+	repo := NewRepository(&memoryTokenRepository{tokens: make(map[string]*domain.RefreshToken)}, d)
+	ctx := context.Background()
+
+	token := domain.NewRefreshToken("user-1", time.Now().Add(time.Hour))
+	if err := repo.Create(ctx, token); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// Failed writes are not replicated
+	if err := repo.Create(ctx, domain.NewRefreshToken("", time.Now().Add(time.Hour))); err == nil {
+		t.Fatal("Create() without user should fail")
+	}
+	if err := repo.Revoke(ctx, token.Token); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := repo.Revoke(ctx, token.Token); err == nil {
+		t.Fatal("second Revoke() should fail")
+	}
+	if err := repo.RevokeAllForUser(ctx, "user-1"); err != nil {
+		t.Fatalf("RevokeAllForUser() error = %v", err)
+	}
+	if err := repo.DeleteByToken(ctx, token.Token); err != nil {
+		t.Fatalf("DeleteByToken() error = %v", err)
+	}
+
+	if err := d.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	want := []EventType{EventTokenCreated, EventTokenRevoked, EventUserTokensRevoked, EventTokenRevoked}
+	got := replicator.replicated()
+	if len(got) != len(want) {
+		t.Fatalf("replicated %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, event := range got {
+		if event.Type != want[i] {
+			t.Errorf("event %d type = %s, want %s", i, event.Type, want[i])
+		}
+		if event.UserID != "user-1" || event.Region != "eu-west-1" {
+			t.Errorf("event %d = %+v, want user-1 from eu-west-1", i, event)
+		}
+	}
+	if got[0].Token != token.Token || got[0].ExpiresAt == nil || !got[0].ExpiresAt.Equal(token.ExpiresAt) {
+		t.Errorf("created event = %+v, want token %s", got[0], token.Token)
+	}
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		wantCalls int
+		wantCount int
+	}{
+		{name: "recovers after retry", failures: 2, wantCalls: 3, wantCount: 1},
+		{name: "gives up after max retries", failures: 10, wantCalls: 6, wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replicator := &recordingReplicator{failures: tt.failures}
+			d := newTestDispatcher(replicator, testDispatcherConfig())
+			d.Start()
+
+			d.Publish(NewUserRevokedEvent("eu-west-1", "user-1"))
+			if err := d.Stop(time.Second); err != nil {
+				t.Fatalf("Stop() error = %v", err)
+			}
+
+			if replicator.calls != tt.wantCalls {
+				t.Errorf("Replicate() calls = %d, want %d", replicator.calls, tt.wantCalls)
+			}
+			if n := len(replicator.replicated()); n != tt.wantCount {
+				t.Errorf("replicated events = %d, want %d", n, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestDispatcher_DropsWhenFull(t *testing.T) {
+	replicator := &recordingReplicator{}
+	config := testDispatcherConfig()
+	config.QueueSize = 1
+	config.BatchSize = 10
+	d := newTestDispatcher(replicator, config)
+
+	// Without a running worker only the first event fits in the queue
+	for i := 0; i < 3; i++ {
+		d.Publish(NewUserRevokedEvent("eu-west-1", "user-1"))
+	}
+	d.Start()
+	if err := d.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if n := len(replicator.replicated()); n != 1 {
+		t.Errorf("replicated events = %d, want 1", n)
+	}
+}
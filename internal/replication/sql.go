@@ -0,0 +1,92 @@
+package replication
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SQLReplicator writes events directly to the refresh_tokens table of the
+// secondary region's database. Users must already be replicated there;
+// tokens of unknown users are skipped.
+//
+// Conflicts are resolved so that a revocation always wins: a token that
+// already exists is never overwritten, a revoked token is never revived, and
+// revoking all of a user's tokens keeps tokens the secondary region issued
+// after the revocation.
+type SQLReplicator struct {
+	db *sql.DB
+}
+
+// NewSQLReplicator creates a replicator for the secondary database
+func NewSQLReplicator(db *sql.DB) *SQLReplicator {
+	return &SQLReplicator{db: db}
+}
+
+// Name identifies the replicator
+func (r *SQLReplicator) Name() string {
+	return "sql"
+}
+
+// Replicate applies the batch in one transaction
+func (r *SQLReplicator) Replicate(ctx context.Context, events []Event) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin replication transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, event := range events {
+		if err := r.apply(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit replication transaction: %w", err)
+	}
+	return nil
+}
+
+// Close closes the secondary database
+func (r *SQLReplicator) Close() error {
+	return r.db.Close()
+}
+
+func (r *SQLReplicator) apply(ctx context.Context, tx *sql.Tx, event Event) error {
+	var err error
+	switch event.Type {
+	case EventTokenCreated:
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO refresh_tokens (token, user_id, expires_at, created_at, dpop_jkt, remember_me)
+			SELECT $1, $2, $3, $4, $5, $6
+			WHERE EXISTS (SELECT 1 FROM users WHERE id = $2)
+			ON CONFLICT (token) DO NOTHING`,
+			event.Token, event.UserID, event.ExpiresAt, event.CreatedAt, event.DPoPThumbprint, event.RememberMe,
+		)
+	case EventTokenRevoked:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE refresh_tokens SET
+				revoked = true,
+				revoked_at = COALESCE(revoked_at, $2)
+			WHERE token = $1`,
+			event.Token, event.OccurredAt,
+		)
+	case EventUserTokensRevoked:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE refresh_tokens SET
+				revoked = true,
+				revoked_at = $2
+			WHERE user_id = $1 AND revoked = false AND created_at <= $2`,
+			event.UserID, event.OccurredAt,
+		)
+	default:
+		// Skip events from newer releases instead of blocking the queue
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to replicate %s: %w", event.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestSQLReplicator_Replicate(t *testing.T) {
+	token := domain.NewRefreshToken("user-1", time.Now().Add(time.Hour))
+	token.Token = "6f1c3b8e-3d4a-4e55-9a43-1b0f3c2d4e5f"
+	created := NewCreatedEvent("eu-west-1", token)
+	revoked := NewRevokedEvent("eu-west-1", "user-1", token.Token)
+	userRevoked := NewUserRevokedEvent("eu-west-1", "user-1")
+
+	tests := []struct {
+		name    string
+		events  []Event
+		mock    func(mock sqlmock.Sqlmock)
+		wantErr bool
+	}{
+		{
+			name:   "applies batch in order",
+			events: []Event{created, revoked, userRevoked, {Type: "refresh_token.unknown"}},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO refresh_tokens .* ON CONFLICT \(token\) DO NOTHING`).
+					WithArgs(token.Token, "user-1", created.ExpiresAt, created.CreatedAt, nil, false).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE refresh_tokens SET .*COALESCE\(revoked_at, \$2\)`).
+					WithArgs(token.Token, revoked.OccurredAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE refresh_tokens SET .* created_at <= \$2`).
+					WithArgs("user-1", userRevoked.OccurredAt).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "rolls back on error",
+			events: []Event{created, revoked},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO refresh_tokens`).
+					WillReturnError(errors.New("connection reset"))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			tt.mock(mock)
+
+			err = NewSQLReplicator(db).Replicate(context.Background(), tt.events)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Replicate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
 )
@@ -14,17 +15,48 @@ type UserRepository interface {
 	// GetByID retrieves a user by ID
 	GetByID(ctx context.Context, id string) (*domain.User, error)
 
-	// GetByEmail retrieves a user by email
+	// GetByIDs retrieves the users with the given IDs in one query. IDs
+	// without a user are left out of the result.
+	GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error)
+
+	// GetByEmail retrieves a user by normalized email
 	GetByEmail(ctx context.Context, email string) (*domain.User, error)
 
+	// GetByUsername retrieves a user by normalized username
+	GetByUsername(ctx context.Context, username string) (*domain.User, error)
+
 	// Update updates a user
 	Update(ctx context.Context, user *domain.User) error
 
 	// Delete deletes a user
 	Delete(ctx context.Context, id string) error
 
-	// ExistsByEmail checks if a user exists with the given email
+	// ExistsByEmail checks if a user exists with the given normalized email
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
+
+	// ExistsByUsername checks if a user exists with the given normalized username
+	ExistsByUsername(ctx context.Context, username string) (bool, error)
+}
+
+// UserMetadataRepository defines data access for user metadata
+type UserMetadataRepository interface {
+	// PatchMetadata applies the patch to the user's metadata of the given
+	// kind and returns the result. It returns domain.ErrMetadataTooLarge,
+	// leaving the metadata unchanged, when the result would take more than
+	// maxBytes as JSON.
+	PatchMetadata(ctx context.Context, userID string, kind domain.MetadataKind, patch domain.MetadataPatch, maxBytes int) (map[string]interface{}, error)
+
+	// List returns up to filter.Limit users matching the filter, newest first
+	List(ctx context.Context, filter UserListFilter) ([]*domain.User, error)
+}
+
+// UserListFilter selects users to list. Metadata values are compared with
+// the stored values as text, so "42" matches both "42" and 42.
+type UserListFilter struct {
+	Metadata    map[string]string // top-level metadata keys and their values
+	AppMetadata map[string]string // top-level app_metadata keys and their values
+	Before      *domain.AuditCursor
+	Limit       int
 }
 
 // RefreshTokenRepository defines the interface for refresh token data access
@@ -38,6 +70,10 @@ type RefreshTokenRepository interface {
 	// GetByUserID retrieves all refresh tokens for a user
 	GetByUserID(ctx context.Context, userID string) ([]*domain.RefreshToken, error)
 
+	// GetActiveCounts counts the unrevoked, unexpired refresh tokens of each
+	// user in one query. Users without one are left out of the result.
+	GetActiveCounts(ctx context.Context, userIDs []string) (map[string]int, error)
+
 	// Update updates a refresh token
 	Update(ctx context.Context, token *domain.RefreshToken) error
 
@@ -53,3 +89,331 @@ type RefreshTokenRepository interface {
 	// DeleteByToken deletes a refresh token by its token value
 	DeleteByToken(ctx context.Context, token string) error
 }
+
+// SessionStatsReader aggregates active refresh tokens for session analytics
+type SessionStatsReader interface {
+	// SessionStats aggregates the unrevoked refresh tokens of userID, or of
+	// every user when userID is empty, that are unexpired at now
+	SessionStats(ctx context.Context, userID string, now time.Time) (*domain.SessionStats, error)
+}
+
+// AuditLogRepository defines the interface for audit trail data access
+type AuditLogRepository interface {
+	// Create appends an entry to the audit trail
+	Create(ctx context.Context, log *domain.AuditLog) error
+}
+
+// AuditLogReader lists a user's audit trail
+type AuditLogReader interface {
+	// ListByUser returns up to limit of the user's entries with one of the
+	// given actions, newest first. With a non-nil before, only entries
+	// older than it are returned.
+	ListByUser(ctx context.Context, userID string, actions []string, before *domain.AuditCursor, limit int) ([]*domain.AuditLog, error)
+}
+
+// AuditChainRepository reads the hash-chained part of the audit trail
+type AuditChainRepository interface {
+	// ChainHead returns the last chained entry's position and hash, or a
+	// zero anchor when nothing has been chained yet
+	ChainHead(ctx context.Context) (domain.AuditAnchor, error)
+
+	// ListChain returns up to limit chained entries after afterSeq, in
+	// chain order
+	ListChain(ctx context.Context, afterSeq int64, limit int) ([]*domain.AuditLog, error)
+}
+
+// IntegrityRepository finds and repairs data that breaks the application's
+// invariants
+type IntegrityRepository interface {
+	// CountOrphanedRefreshTokens counts refresh tokens, unrevoked and
+	// unexpired at now, whose user was deleted or disabled
+	CountOrphanedRefreshTokens(ctx context.Context, now time.Time) (int, error)
+
+	// RevokeOrphanedRefreshTokens revokes those tokens and returns how many
+	// were revoked
+	RevokeOrphanedRefreshTokens(ctx context.Context, now time.Time) (int, error)
+
+	// CountExpiredVerifications counts unverified users whose email
+	// verification token expired before now
+	CountExpiredVerifications(ctx context.Context, now time.Time) (int, error)
+
+	// ClearExpiredVerifications removes those tokens and returns how many
+	// users were updated
+	ClearExpiredVerifications(ctx context.Context, now time.Time) (int, error)
+
+	// ListAuditGaps returns up to limit gaps in the audit hash chain, in
+	// chain order
+	ListAuditGaps(ctx context.Context, limit int) ([]domain.AuditGap, error)
+
+	// MigrationState returns the schema migration version and whether the
+	// last migration failed halfway
+	MigrationState(ctx context.Context) (version int64, dirty bool, err error)
+}
+
+// AccountActivityRepository defines data access for account activity and the
+// dormancy policy. Every List method returns at most limit accounts, oldest
+// first.
+type AccountActivityRepository interface {
+	// RecordActivity marks a user active and clears any dormancy notice
+	RecordActivity(ctx context.Context, userID string, at time.Time) error
+
+	// ListInactive returns enabled accounts without a dormancy notice that
+	// were last active before the cutoff
+	ListInactive(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error)
+
+	// ListNotified returns enabled accounts whose dormancy notice was sent
+	// before the cutoff
+	ListNotified(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error)
+
+	// ListDisabled returns accounts disabled before the cutoff
+	ListDisabled(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error)
+
+	// MarkNotified records that the dormancy notice was sent
+	MarkNotified(ctx context.Context, userID string, at time.Time) error
+
+	// Disable disables an account
+	Disable(ctx context.Context, userID string, at time.Time) error
+}
+
+// QuotaRepository defines data access for monthly request quotas
+type QuotaRepository interface {
+	// Increment counts one request for the subject and returns its new total
+	// for the period. It returns domain.ErrQuotaExceeded without counting
+	// when the subject already made limit requests; a limit of 0 never
+	// rejects.
+	Increment(ctx context.Context, subject string, period time.Time, limit int64) (int64, error)
+
+	// Get returns the number of requests counted for the period
+	Get(ctx context.Context, subject string, period time.Time) (int64, error)
+}
+
+// AdminSigningKeyRepository defines data access for admin request signing keys
+type AdminSigningKeyRepository interface {
+	// Create stores a new key
+	Create(ctx context.Context, key *domain.AdminSigningKey) error
+
+	// GetByID retrieves a key, revoked or not
+	GetByID(ctx context.Context, id string) (*domain.AdminSigningKey, error)
+
+	// List returns all keys, newest first
+	List(ctx context.Context) ([]*domain.AdminSigningKey, error)
+
+	// Revoke revokes an active key
+	Revoke(ctx context.Context, id string, at time.Time) error
+}
+
+// ServiceAccountRepository defines data access for service accounts, their
+// keys and the assertions they have used
+type ServiceAccountRepository interface {
+	// Create stores a new account
+	Create(ctx context.Context, account *domain.ServiceAccount) error
+
+	// GetByID retrieves an account
+	GetByID(ctx context.Context, id string) (*domain.ServiceAccount, error)
+
+	// List returns all accounts, newest first
+	List(ctx context.Context) ([]*domain.ServiceAccount, error)
+
+	// UpdateScopes replaces the scopes of an account
+	UpdateScopes(ctx context.Context, id string, scopes []string, at time.Time) error
+
+	// Delete deletes an account with its keys
+	Delete(ctx context.Context, id string) error
+
+	// CreateKey registers a key. It returns domain.ErrServiceAccountKeyExists
+	// when the account already has the key.
+	CreateKey(ctx context.Context, key *domain.ServiceAccountKey) error
+
+	// GetKey retrieves a key of an account, expired or not
+	GetKey(ctx context.Context, accountID, keyID string) (*domain.ServiceAccountKey, error)
+
+	// ListKeys returns the keys of an account, newest first
+	ListKeys(ctx context.Context, accountID string) ([]*domain.ServiceAccountKey, error)
+
+	// ExpireKey makes a key expire at the given time, unless it already
+	// expires sooner
+	ExpireKey(ctx context.Context, accountID, keyID string, at time.Time) error
+
+	// RecordAssertion remembers an assertion id until it expires, reporting
+	// false if the account already used it
+	RecordAssertion(ctx context.Context, accountID, assertionID string, expiresAt time.Time) (bool, error)
+}
+
+// TokenVersionRepository defines the interface for the per-user access token
+// version
+type TokenVersionRepository interface {
+	// Get returns the user's current token version
+	Get(ctx context.Context, userID string) (int, error)
+
+	// Increment bumps the user's token version and returns the new one
+	Increment(ctx context.Context, userID string) (int, error)
+}
+
+// AccessTokenDenylistRepository defines data access for access tokens
+// revoked before they expire, by jti
+type AccessTokenDenylistRepository interface {
+	// Add denies the token until it expires
+	Add(ctx context.Context, tokenID string, expiresAt time.Time) error
+
+	// Contains reports whether the token has been revoked
+	Contains(ctx context.Context, tokenID string) (bool, error)
+}
+
+// ConsentRepository defines data access for the consent answers of users
+type ConsentRepository interface {
+	// Create stores an answer and sets its ID and CreatedAt
+	Create(ctx context.Context, consent *domain.Consent) error
+
+	// ListByUser returns the user's answers, oldest first
+	ListByUser(ctx context.Context, userID string) ([]*domain.Consent, error)
+
+	// AcceptedTermsVersion returns the terms of service version the user
+	// accepted last, or "" if none
+	AcceptedTermsVersion(ctx context.Context, userID string) (string, error)
+}
+
+// UserEventRepository defines data access for the events delivered to
+// downstream systems
+type UserEventRepository interface {
+	// Create stores an event and sets its ID and OccurredAt
+	Create(ctx context.Context, event *domain.UserEvent) error
+
+	// List returns up to limit events of the type that occurred in
+	// [from, to) and have an ID above afterID, in ID order. A zero from or
+	// to leaves that end open.
+	List(ctx context.Context, eventType string, from, to time.Time, afterID int64, limit int) ([]*domain.UserEvent, error)
+}
+
+// EmailTrackingOptOutRepository defines data access for the users who opted
+// out of email open and click tracking
+type EmailTrackingOptOutRepository interface {
+	// IsOptedOut reports whether the user opted out
+	IsOptedOut(ctx context.Context, userID string) (bool, error)
+
+	// SetOptedOut records or withdraws the user's opt-out
+	SetOptedOut(ctx context.Context, userID string, optedOut bool) error
+}
+
+// EmailCodeRepository defines data access for one-time email codes
+type EmailCodeRepository interface {
+	// Save stores a code, replacing the user's previous code for the purpose
+	Save(ctx context.Context, code *domain.EmailCode) error
+
+	// RecordAttempt counts a guess at the user's code and returns the code
+	// with the new count, so concurrent guesses cannot exceed the limit. It
+	// returns domain.ErrInvalidEmailCode when there is no code.
+	RecordAttempt(ctx context.Context, userID string, purpose domain.EmailCodePurpose) (*domain.EmailCode, error)
+
+	// Delete removes the user's code for the purpose
+	Delete(ctx context.Context, userID string, purpose domain.EmailCodePurpose) error
+
+	// DeleteUnexpired removes the user's code for the purpose if it is
+	// still usable at now, and reports whether there was one
+	DeleteUnexpired(ctx context.Context, userID string, purpose domain.EmailCodePurpose, now time.Time) (bool, error)
+}
+
+// TrustedDeviceRepository defines data access for devices that approve logins
+type TrustedDeviceRepository interface {
+	// Create stores a device and sets its ID and CreatedAt
+	Create(ctx context.Context, device *domain.TrustedDevice) error
+
+	// GetByID retrieves one of the user's devices. It returns
+	// domain.ErrTrustedDeviceNotFound for unknown devices and devices of
+	// other users.
+	GetByID(ctx context.Context, userID, id string) (*domain.TrustedDevice, error)
+
+	// ListByUser returns the user's devices, newest first
+	ListByUser(ctx context.Context, userID string) ([]*domain.TrustedDevice, error)
+
+	// Touch records that the device was used
+	Touch(ctx context.Context, id string, at time.Time) error
+
+	// Delete removes one of the user's devices
+	Delete(ctx context.Context, userID, id string) error
+}
+
+// LoginApprovalRepository defines data access for logins awaiting a trusted device
+type LoginApprovalRepository interface {
+	// Create stores a pending approval and sets its ID
+	Create(ctx context.Context, approval *domain.LoginApproval) error
+
+	// GetByID retrieves an approval. It returns
+	// domain.ErrLoginApprovalNotFound when there is none.
+	GetByID(ctx context.Context, id string) (*domain.LoginApproval, error)
+
+	// ListPending returns the user's approvals still awaiting a decision at now
+	ListPending(ctx context.Context, userID string, now time.Time) ([]*domain.LoginApproval, error)
+
+	// Decide approves or denies one of the user's pending approvals. It
+	// returns domain.ErrLoginApprovalNotFound when the approval is unknown,
+	// expired or already decided, so each approval is decided once.
+	Decide(ctx context.Context, userID, id, deviceID string, status domain.LoginApprovalStatus, now time.Time) error
+
+	// Consume deletes an approved, unexpired approval, so it is exchanged for
+	// tokens once. It returns domain.ErrLoginApprovalNotFound otherwise.
+	Consume(ctx context.Context, id string, now time.Time) error
+}
+
+// SessionHandoffRepository defines data access for session handoff codes
+type SessionHandoffRepository interface {
+	// Create stores a handoff and sets its ID, replacing the user's previous
+	// handoff so each user has at most one code outstanding
+	Create(ctx context.Context, handoff *domain.SessionHandoff) error
+
+	// Consume deletes and returns the handoff with the hashed code if it is
+	// unexpired at now, so it is exchanged once. It returns
+	// domain.ErrSessionHandoffNotFound otherwise.
+	Consume(ctx context.Context, codeHash string, now time.Time) (*domain.SessionHandoff, error)
+}
+
+// DeviceAuthorizationRepository defines data access for device authorization grants
+type DeviceAuthorizationRepository interface {
+	// Create stores a pending authorization and sets its ID
+	Create(ctx context.Context, auth *domain.DeviceAuthorization) error
+
+	// GetByDeviceCodeHash retrieves an authorization by its hashed device
+	// code. It returns domain.ErrInvalidDeviceCode when there is none.
+	GetByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*domain.DeviceAuthorization, error)
+
+	// GetPendingByUserCodeHash retrieves an authorization awaiting a decision
+	// at now by its hashed user code. It returns domain.ErrInvalidUserCode
+	// when there is none.
+	GetPendingByUserCodeHash(ctx context.Context, userCodeHash string, now time.Time) (*domain.DeviceAuthorization, error)
+
+	// Decide approves or denies a pending authorization for userID. It
+	// returns domain.ErrInvalidUserCode when the authorization is unknown,
+	// expired or already decided, so each authorization is decided once.
+	Decide(ctx context.Context, userCodeHash, userID string, status domain.DeviceAuthorizationStatus, now time.Time) error
+
+	// RecordPoll records a poll at now. It returns domain.ErrSlowDown when
+	// the previous poll was less than minInterval ago.
+	RecordPoll(ctx context.Context, id string, now time.Time, minInterval time.Duration) error
+
+	// Consume deletes an approved, unexpired authorization, so it is
+	// exchanged for tokens once. It returns domain.ErrInvalidDeviceCode
+	// otherwise.
+	Consume(ctx context.Context, id string, now time.Time) error
+}
+
+// VerificationResendRepository counts verification email resends per user
+type VerificationResendRepository interface {
+	// Record counts a resend at now unless the previous one was less than
+	// cooldown ago or the user already had dailyLimit resends that UTC day
+	// (0: no daily limit). It returns the counter after the resend, or the
+	// unchanged counter and domain.ErrResendTooSoon when it was refused.
+	Record(ctx context.Context, userID string, now time.Time, cooldown time.Duration, dailyLimit int) (*domain.VerificationResend, error)
+}
+
+// AccessWindowRepository defines data access for the daily windows users'
+// access tokens are limited to
+type AccessWindowRepository interface {
+	// Get returns the user's access window, or domain.ErrAccessWindowNotFound
+	Get(ctx context.Context, userID string) (*domain.AccessWindow, error)
+
+	// Set creates or replaces the user's access window
+	Set(ctx context.Context, userID string, window *domain.AccessWindow) error
+
+	// Delete removes the user's access window. It returns
+	// domain.ErrAccessWindowNotFound when there is none.
+	Delete(ctx context.Context, userID string) error
+}
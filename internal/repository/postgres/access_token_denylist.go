@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// AccessTokenDenylistRepository implements
+// repository.AccessTokenDenylistRepository using the revoked_access_tokens
+// table
+type AccessTokenDenylistRepository struct {
+	db DBTX
+}
+
+// NewAccessTokenDenylistRepository creates a new PostgreSQL access token
+// denylist repository
+func NewAccessTokenDenylistRepository(db DBTX) *AccessTokenDenylistRepository {
+	return &AccessTokenDenylistRepository{db: db}
+}
+
+// Add denies the token until it expires. Tokens that have expired since they
+// were denied are dropped first, so the table only holds tokens that would
+// still be accepted otherwise.
+func (r *AccessTokenDenylistRepository) Add(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	purge := `DELETE FROM revoked_access_tokens WHERE expires_at < NOW()`
+	if _, err := r.db.ExecContext(ctx, purge); err != nil {
+		return fmt.Errorf("failed to purge revoked access tokens: %w", err)
+	}
+
+	query := `
+		INSERT INTO revoked_access_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, tokenID, expiresAt); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+// Contains reports whether the token has been revoked
+func (r *AccessTokenDenylistRepository) Contains(ctx context.Context, tokenID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1)`
+
+	var revoked bool
+	if err := r.db.QueryRowContext(ctx, query, tokenID).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("failed to check revoked access token: %w", err)
+	}
+	return revoked, nil
+}
+
+// Ensure AccessTokenDenylistRepository implements
+// repository.AccessTokenDenylistRepository
+var _ repository.AccessTokenDenylistRepository = (*AccessTokenDenylistRepository)(nil)
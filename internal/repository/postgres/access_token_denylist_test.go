@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAccessTokenDenylistRepository_Add(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	expiresAt := time.Now().Add(15 * time.Minute)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM revoked_access_tokens WHERE expires_at < NOW()`)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO revoked_access_tokens (jti, expires_at)`)).
+		WithArgs("jti-1", expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := NewAccessTokenDenylistRepository(db).Add(context.Background(), "jti-1", expiresAt); err != nil {
+		t.Errorf("Add() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAccessTokenDenylistRepository_Contains(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1)`)
+
+	for _, revoked := range []bool{true, false} {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating mock database: %v", err)
+		}
+
+		mock.ExpectQuery(query).
+			WithArgs("jti-1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(revoked))
+
+		got, err := NewAccessTokenDenylistRepository(db).Contains(context.Background(), "jti-1")
+		if err != nil || got != revoked {
+			t.Errorf("Contains() = %v, %v; want %v", got, err, revoked)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// AccessWindowRepository implements repository.AccessWindowRepository for
+// PostgreSQL
+type AccessWindowRepository struct {
+	db DBTX
+}
+
+// NewAccessWindowRepository creates a new PostgreSQL access window repository
+func NewAccessWindowRepository(db DBTX) *AccessWindowRepository {
+	return &AccessWindowRepository{db: db}
+}
+
+// Get retrieves the user's access window
+func (r *AccessWindowRepository) Get(ctx context.Context, userID string) (*domain.AccessWindow, error) {
+	query := `SELECT start_time, end_time, timezone, updated_at FROM access_windows WHERE user_id = $1`
+
+	window := &domain.AccessWindow{}
+	var timezone sql.NullString
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&window.Start, &window.End, &timezone, &window.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrAccessWindowNotFound
+		}
+		return nil, fmt.Errorf("failed to get access window: %w", err)
+	}
+	window.Timezone = timezone.String
+	return window, nil
+}
+
+// Set creates or replaces the user's access window
+func (r *AccessWindowRepository) Set(ctx context.Context, userID string, window *domain.AccessWindow) error {
+	query := `
+		INSERT INTO access_windows (user_id, start_time, end_time, timezone, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET start_time = EXCLUDED.start_time, end_time = EXCLUDED.end_time,
+			timezone = EXCLUDED.timezone, updated_at = EXCLUDED.updated_at`
+
+	timezone := sql.NullString{String: window.Timezone, Valid: window.Timezone != ""}
+	if _, err := r.db.ExecContext(ctx, query, userID, window.Start, window.End, timezone, window.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to set access window: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the user's access window
+func (r *AccessWindowRepository) Delete(ctx context.Context, userID string) error {
+	query := `DELETE FROM access_windows WHERE user_id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete access window: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return domain.ErrAccessWindowNotFound
+	}
+	return nil
+}
+
+// Ensure AccessWindowRepository implements repository.AccessWindowRepository
+var _ repository.AccessWindowRepository = (*AccessWindowRepository)(nil)
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestAccessWindowRepository_Get(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT start_time, end_time, timezone, updated_at FROM access_windows WHERE user_id = $1`)
+	updatedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+	repo := NewAccessWindowRepository(db)
+
+	mock.ExpectQuery(query).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"start_time", "end_time", "timezone", "updated_at"}).
+			AddRow("08:00", "18:00", nil, updatedAt))
+	window, err := repo.Get(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if window.Start != "08:00" || window.End != "18:00" || window.Timezone != "" || !window.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("Get() = %+v", window)
+	}
+
+	mock.ExpectQuery(query).WithArgs("user-2").WillReturnError(sql.ErrNoRows)
+	if _, err := repo.Get(context.Background(), "user-2"); !errors.Is(err, domain.ErrAccessWindowNotFound) {
+		t.Errorf("Get() of a user without a window error = %v, want %v", err, domain.ErrAccessWindowNotFound)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAccessWindowRepository_Set(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	window := &domain.AccessWindow{Start: "08:00", End: "18:00", Timezone: "Europe/Madrid", UpdatedAt: time.Now()}
+	mock.ExpectExec(`INSERT INTO access_windows`).
+		WithArgs("user-1", "08:00", "18:00", "Europe/Madrid", window.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := NewAccessWindowRepository(db).Set(context.Background(), "user-1", window); err != nil {
+		t.Errorf("Set() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAccessWindowRepository_Delete(t *testing.T) {
+	query := regexp.QuoteMeta(`DELETE FROM access_windows WHERE user_id = $1`)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+	repo := NewAccessWindowRepository(db)
+
+	mock.ExpectExec(query).WithArgs("user-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.Delete(context.Background(), "user-1"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+
+	mock.ExpectExec(query).WithArgs("user-2").WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.Delete(context.Background(), "user-2"); !errors.Is(err, domain.ErrAccessWindowNotFound) {
+		t.Errorf("Delete() of a user without a window error = %v, want %v", err, domain.ErrAccessWindowNotFound)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// AccountActivityRepository implements repository.AccountActivityRepository
+// using PostgreSQL
+type AccountActivityRepository struct {
+	db DBTX
+}
+
+// NewAccountActivityRepository creates a new PostgreSQL account activity repository
+func NewAccountActivityRepository(db DBTX) *AccountActivityRepository {
+	return &AccountActivityRepository{db: db}
+}
+
+// dormantAccountQuery selects accounts with their activity. Users without an
+// activity row have not logged in since they signed up.
+const dormantAccountQuery = `
+	SELECT u.id, u.email, u.locale,
+		COALESCE(a.last_active_at, u.created_at), a.dormancy_notified_at, u.disabled_at
+	FROM users u
+	LEFT JOIN account_activity a ON a.user_id = u.id`
+
+// RecordActivity marks a user active and clears any dormancy notice
+func (r *AccountActivityRepository) RecordActivity(ctx context.Context, userID string, at time.Time) error {
+	query := `
+		INSERT INTO account_activity (user_id, last_active_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			last_active_at = EXCLUDED.last_active_at,
+			dormancy_notified_at = NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, at); err != nil {
+		return fmt.Errorf("failed to record account activity: %w", err)
+	}
+	return nil
+}
+
+// ListInactive returns enabled accounts without a dormancy notice that were
+// last active before the cutoff
+func (r *AccountActivityRepository) ListInactive(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	query := dormantAccountQuery + `
+		WHERE u.disabled_at IS NULL
+			AND a.dormancy_notified_at IS NULL
+			AND COALESCE(a.last_active_at, u.created_at) < $1
+		ORDER BY COALESCE(a.last_active_at, u.created_at)
+		LIMIT $2`
+	return r.list(ctx, query, before, limit)
+}
+
+// ListNotified returns enabled accounts whose dormancy notice was sent before
+// the cutoff. Activity clears the notice, so these are still inactive.
+func (r *AccountActivityRepository) ListNotified(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	query := dormantAccountQuery + `
+		WHERE u.disabled_at IS NULL
+			AND a.dormancy_notified_at < $1
+		ORDER BY a.dormancy_notified_at
+		LIMIT $2`
+	return r.list(ctx, query, before, limit)
+}
+
+// ListDisabled returns accounts disabled before the cutoff
+func (r *AccountActivityRepository) ListDisabled(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	query := dormantAccountQuery + `
+		WHERE u.disabled_at < $1
+		ORDER BY u.disabled_at
+		LIMIT $2`
+	return r.list(ctx, query, before, limit)
+}
+
+// MarkNotified records that the dormancy notice was sent
+func (r *AccountActivityRepository) MarkNotified(ctx context.Context, userID string, at time.Time) error {
+	query := `
+		INSERT INTO account_activity (user_id, last_active_at, dormancy_notified_at)
+		SELECT id, created_at, $2 FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE SET dormancy_notified_at = EXCLUDED.dormancy_notified_at`
+
+	result, err := r.db.ExecContext(ctx, query, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark dormancy notice: %w", err)
+	}
+	return requireRow(result)
+}
+
+// Disable disables an account
+func (r *AccountActivityRepository) Disable(ctx context.Context, userID string, at time.Time) error {
+	query := `UPDATE users SET disabled_at = $2 WHERE id = $1 AND disabled_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to disable account: %w", err)
+	}
+	return requireRow(result)
+}
+
+// list runs a dormantAccountQuery with a cutoff and limit
+func (r *AccountActivityRepository) list(ctx context.Context, query string, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	rows, err := r.db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dormant accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*domain.DormantAccount
+	for rows.Next() {
+		account := &domain.DormantAccount{}
+		if err := rows.Scan(
+			&account.UserID,
+			&account.Email,
+			&account.Locale,
+			&account.LastActiveAt,
+			&account.NotifiedAt,
+			&account.DisabledAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan dormant account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dormant accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// requireRow returns domain.ErrUserNotFound when an update matched no rows
+func requireRow(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// Ensure AccountActivityRepository implements repository.AccountActivityRepository
+var _ repository.AccountActivityRepository = (*AccountActivityRepository)(nil)
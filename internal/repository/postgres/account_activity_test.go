@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+var dormantAccountColumns = []string{
+	"id", "email", "locale", "last_active_at", "dormancy_notified_at", "disabled_at",
+}
+
+func TestAccountActivityRepository_RecordActivity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO account_activity (user_id, last_active_at)`)).
+		WithArgs("user-123", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewAccountActivityRepository(db)
+	if err := repo.RecordActivity(context.Background(), "user-123", now); err != nil {
+		t.Errorf("RecordActivity() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAccountActivityRepository_List(t *testing.T) {
+	cutoff := time.Now().Add(-180 * 24 * time.Hour)
+	lastActive := cutoff.Add(-time.Hour)
+	notifiedAt := cutoff.Add(-2 * time.Hour)
+
+	tests := []struct {
+		name      string
+		list      func(repo *AccountActivityRepository) ([]*domain.DormantAccount, error)
+		setupMock func(sqlmock.Sqlmock)
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name: "inactive accounts",
+			list: func(repo *AccountActivityRepository) ([]*domain.DormantAccount, error) {
+				return repo.ListInactive(context.Background(), cutoff, 10)
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(dormantAccountColumns).
+					AddRow("user-1", "one@example.com", nil, lastActive, nil, nil).
+					AddRow("user-2", "two@example.com", "es", lastActive, nil, nil)
+				mock.ExpectQuery(regexp.QuoteMeta(`AND a.dormancy_notified_at IS NULL`)).
+					WithArgs(cutoff, 10).
+					WillReturnRows(rows)
+			},
+			wantCount: 2,
+		},
+		{
+			name: "notified accounts",
+			list: func(repo *AccountActivityRepository) ([]*domain.DormantAccount, error) {
+				return repo.ListNotified(context.Background(), cutoff, 10)
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(dormantAccountColumns).
+					AddRow("user-1", "one@example.com", nil, lastActive, notifiedAt, nil)
+				mock.ExpectQuery(regexp.QuoteMeta(`AND a.dormancy_notified_at < $1`)).
+					WithArgs(cutoff, 10).
+					WillReturnRows(rows)
+			},
+			wantCount: 1,
+		},
+		{
+			name: "disabled accounts",
+			list: func(repo *AccountActivityRepository) ([]*domain.DormantAccount, error) {
+				return repo.ListDisabled(context.Background(), cutoff, 10)
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE u.disabled_at < $1`)).
+					WithArgs(cutoff, 10).
+					WillReturnRows(sqlmock.NewRows(dormantAccountColumns))
+			},
+			wantCount: 0,
+		},
+		{
+			name: "database error",
+			list: func(repo *AccountActivityRepository) ([]*domain.DormantAccount, error) {
+				return repo.ListInactive(context.Background(), cutoff, 10)
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM users u`)).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			accounts, err := tt.list(NewAccountActivityRepository(db))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("list error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(accounts) != tt.wantCount {
+				t.Errorf("got %d accounts, want %d", len(accounts), tt.wantCount)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestAccountActivityRepository_Disable(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "disables account",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET disabled_at = $2`)).
+					WithArgs("user-123", now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "unknown or already disabled account",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET disabled_at = $2`)).
+					WithArgs("user-123", now).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: domain.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			err = NewAccountActivityRepository(db).Disable(context.Background(), "user-123", now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Disable() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestAccountActivityRepository_MarkNotified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO account_activity (user_id, last_active_at, dormancy_notified_at)`)).
+		WithArgs("user-123", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := NewAccountActivityRepository(db).MarkNotified(context.Background(), "user-123", now); err != nil {
+		t.Errorf("MarkNotified() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
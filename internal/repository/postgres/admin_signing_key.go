@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// AdminSigningKeyRepository implements repository.AdminSigningKeyRepository
+// using PostgreSQL
+type AdminSigningKeyRepository struct {
+	db DBTX
+}
+
+// NewAdminSigningKeyRepository creates a new PostgreSQL admin signing key repository
+func NewAdminSigningKeyRepository(db DBTX) *AdminSigningKeyRepository {
+	return &AdminSigningKeyRepository{db: db}
+}
+
+// Create stores a new key
+func (r *AdminSigningKeyRepository) Create(ctx context.Context, key *domain.AdminSigningKey) error {
+	query := `INSERT INTO admin_signing_keys (id, name, created_at) VALUES ($1, $2, $3)`
+
+	if _, err := r.db.ExecContext(ctx, query, key.ID, key.Name, key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a key, revoked or not
+func (r *AdminSigningKeyRepository) GetByID(ctx context.Context, id string) (*domain.AdminSigningKey, error) {
+	query := `SELECT id, name, created_at, revoked_at FROM admin_signing_keys WHERE id = $1`
+
+	key := &domain.AdminSigningKey{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&key.ID, &key.Name, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrSigningKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get signing key: %w", err)
+	}
+	return key, nil
+}
+
+// List returns all keys, newest first
+func (r *AdminSigningKeyRepository) List(ctx context.Context) ([]*domain.AdminSigningKey, error) {
+	query := `SELECT id, name, created_at, revoked_at FROM admin_signing_keys ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*domain.AdminSigningKey
+	for rows.Next() {
+		key := &domain.AdminSigningKey{}
+		if err := rows.Scan(&key.ID, &key.Name, &key.CreatedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes an active key
+func (r *AdminSigningKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE admin_signing_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return fmt.Errorf("failed to revoke signing key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return domain.ErrSigningKeyNotFound
+	}
+	return nil
+}
+
+// Ensure AdminSigningKeyRepository implements repository.AdminSigningKeyRepository
+var _ repository.AdminSigningKeyRepository = (*AdminSigningKeyRepository)(nil)
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestAdminSigningKeyRepository_GetByID(t *testing.T) {
+	createdAt := time.Now()
+
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "existing key",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM admin_signing_keys WHERE id = $1`)).
+					WithArgs("ak_ci").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "revoked_at"}).
+						AddRow("ak_ci", "ci deploy", createdAt, nil))
+			},
+		},
+		{
+			name: "unknown key",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM admin_signing_keys WHERE id = $1`)).
+					WithArgs("ak_ci").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at", "revoked_at"}))
+			},
+			wantErr: domain.ErrSigningKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			key, err := NewAdminSigningKeyRepository(db).GetByID(context.Background(), "ak_ci")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetByID() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (key.Name != "ci deploy" || key.IsRevoked()) {
+				t.Errorf("unexpected key: %+v", key)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestAdminSigningKeyRepository_Revoke(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE admin_signing_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`)).
+		WithArgs("ak_ci", now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewAdminSigningKeyRepository(db).Revoke(context.Background(), "ak_ci", now)
+	if !errors.Is(err, domain.ErrSigningKeyNotFound) {
+		t.Errorf("Revoke() of revoked key error = %v, want ErrSigningKeyNotFound", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// auditChainLockKey is the advisory lock serializing appends to the chain
+const auditChainLockKey = 7261746963 // arbitrary, unique to the audit chain
+
+// ChainedAuditLogRepository writes audit log entries as a hash chain: each
+// entry stores the hash of the previous one, so deleting or modifying an
+// entry breaks every later link. Appends are serialized with an advisory
+// lock, which caps audit write throughput at one transaction at a time.
+type ChainedAuditLogRepository struct {
+	db TxBeginner
+}
+
+// TxBeginner is a DBTX that can start transactions, such as *sql.DB or a
+// rotating *db.DB
+type TxBeginner interface {
+	DBTX
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// NewChainedAuditLogRepository creates a hash-chained audit log repository
+func NewChainedAuditLogRepository(db TxBeginner) *ChainedAuditLogRepository {
+	return &ChainedAuditLogRepository{db: db}
+}
+
+// Create appends an entry to the chain
+func (r *ChainedAuditLogRepository) Create(ctx context.Context, log *domain.AuditLog) error {
+	metadata := log.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit metadata: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin audit transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLockKey); err != nil {
+		return fmt.Errorf("failed to lock audit chain: %w", err)
+	}
+	head, err := chainHead(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// created_at has no time zone and microsecond precision; store the value
+	// that reads back unchanged so the hash can be recomputed
+	log.CreatedAt = log.CreatedAt.UTC().Truncate(time.Microsecond)
+	log.ChainSeq = head.Seq + 1
+	log.PrevHash = head.Hash
+	log.UserDigest = domain.AuditUserDigest(log.UserID)
+	if log.Hash, err = log.ComputeHash(); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_logs (
+			user_id, action, resource_type, resource_id,
+			ip_address, user_agent, request_id, status,
+			error_message, metadata, created_at,
+			chain_seq, prev_hash, hash, user_digest
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+		) RETURNING id`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		log.UserID,
+		log.Action,
+		log.ResourceType,
+		log.ResourceID,
+		log.IPAddress,
+		log.UserAgent,
+		log.RequestID,
+		log.Status,
+		log.ErrorMessage,
+		metadataJSON,
+		log.CreatedAt,
+		log.ChainSeq,
+		log.PrevHash,
+		log.Hash,
+		log.UserDigest,
+	).Scan(&log.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit audit log: %w", err)
+	}
+	return nil
+}
+
+// ChainHead returns the position and hash of the last chained entry
+func (r *ChainedAuditLogRepository) ChainHead(ctx context.Context) (domain.AuditAnchor, error) {
+	return chainHead(ctx, r.db)
+}
+
+// ListChain returns up to limit chained entries after afterSeq
+func (r *ChainedAuditLogRepository) ListChain(ctx context.Context, afterSeq int64, limit int) ([]*domain.AuditLog, error) {
+	query := `
+		SELECT id, user_id, action, resource_type, resource_id,
+			ip_address, user_agent, request_id, status,
+			error_message, metadata, created_at,
+			chain_seq, prev_hash, hash, user_digest
+		FROM audit_logs
+		WHERE chain_seq > $1
+		ORDER BY chain_seq
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit chain: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*domain.AuditLog
+	for rows.Next() {
+		var (
+			log          domain.AuditLog
+			status       sql.NullString
+			metadataJSON []byte
+			prevHash     sql.NullString
+			userDigest   sql.NullString
+		)
+		if err := rows.Scan(
+			&log.ID, &log.UserID, &log.Action, &log.ResourceType, &log.ResourceID,
+			&log.IPAddress, &log.UserAgent, &log.RequestID, &status,
+			&log.ErrorMessage, &metadataJSON, &log.CreatedAt,
+			&log.ChainSeq, &prevHash, &log.Hash, &userDigest,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		log.Status = status.String
+		log.PrevHash = prevHash.String
+		log.UserDigest = userDigest.String
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &log.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal audit metadata: %w", err)
+			}
+		}
+		logs = append(logs, &log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit chain: %w", err)
+	}
+
+	return logs, nil
+}
+
+// chainHead reads the last chained entry
+func chainHead(ctx context.Context, db DBTX) (domain.AuditAnchor, error) {
+	var head domain.AuditAnchor
+	err := db.QueryRowContext(ctx, `
+		SELECT chain_seq, hash FROM audit_logs
+		WHERE chain_seq IS NOT NULL
+		ORDER BY chain_seq DESC
+		LIMIT 1`,
+	).Scan(&head.Seq, &head.Hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.AuditAnchor{}, nil
+	}
+	if err != nil {
+		return domain.AuditAnchor{}, fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+	return head, nil
+}
+
+// Ensure ChainedAuditLogRepository implements the audit repositories
+var (
+	_ repository.AuditLogRepository   = (*ChainedAuditLogRepository)(nil)
+	_ repository.AuditChainRepository = (*ChainedAuditLogRepository)(nil)
+)
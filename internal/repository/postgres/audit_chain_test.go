@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestChainedAuditLogRepository_Create(t *testing.T) {
+	createdAt := time.Date(2026, 3, 4, 10, 0, 0, 123456789, time.FixedZone("CET", 3600))
+	prevHash := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name     string
+		head     *sqlmock.Rows
+		wantSeq  int64
+		wantPrev string
+	}{
+		{
+			name:    "first entry",
+			head:    sqlmock.NewRows([]string{"chain_seq", "hash"}),
+			wantSeq: 1,
+		},
+		{
+			name:     "appends to head",
+			head:     sqlmock.NewRows([]string{"chain_seq", "hash"}).AddRow(41, prevHash),
+			wantSeq:  42,
+			wantPrev: prevHash,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			log := &domain.AuditLog{
+				UserID:    stringPtr("user-123"),
+				Action:    domain.AuditActionSessionsRevoked,
+				Status:    domain.AuditStatusSuccess,
+				Metadata:  map[string]interface{}{"reason": "password_changed"},
+				CreatedAt: createdAt,
+			}
+			wantTime := createdAt.UTC().Truncate(time.Microsecond)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).
+				WithArgs(auditChainLockKey).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT chain_seq, hash FROM audit_logs`)).
+				WillReturnRows(tt.head)
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO audit_logs`)).
+				WithArgs(
+					"user-123",
+					domain.AuditActionSessionsRevoked,
+					nil, nil, nil, nil, nil,
+					domain.AuditStatusSuccess,
+					nil,
+					[]byte(`{"reason":"password_changed"}`),
+					wantTime,
+					tt.wantSeq,
+					tt.wantPrev,
+					sqlmock.AnyArg(),
+					domain.AuditUserDigest(stringPtr("user-123")),
+				).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("audit-1"))
+			mock.ExpectCommit()
+
+			if err := NewChainedAuditLogRepository(db).Create(context.Background(), log); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			if log.ID != "audit-1" || log.ChainSeq != tt.wantSeq || log.PrevHash != tt.wantPrev {
+				t.Errorf("got id %q seq %d prev %q", log.ID, log.ChainSeq, log.PrevHash)
+			}
+			if want, _ := log.ComputeHash(); log.Hash == "" || log.Hash != want {
+				t.Errorf("Hash = %q, want %q", log.Hash, want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestChainedAuditLogRepository_ListChain(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	// An entry as written by Create, whose user has since been deleted
+	written := &domain.AuditLog{
+		UserID:    stringPtr("user-123"),
+		Action:    domain.AuditActionLoginRisk,
+		Status:    domain.AuditStatusBlocked,
+		Metadata:  map[string]interface{}{"score": 90, "signals": []string{"tor"}},
+		CreatedAt: time.Date(2026, 3, 4, 10, 0, 0, 123456000, time.UTC),
+		ChainSeq:  1,
+	}
+	written.UserDigest = domain.AuditUserDigest(written.UserID)
+	written.Hash, _ = written.ComputeHash()
+
+	columns := []string{
+		"id", "user_id", "action", "resource_type", "resource_id",
+		"ip_address", "user_agent", "request_id", "status",
+		"error_message", "metadata", "created_at",
+		"chain_seq", "prev_hash", "hash", "user_digest",
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM audit_logs`)).
+		WithArgs(int64(0), 100).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(
+			"audit-1", nil, written.Action, nil, nil,
+			nil, nil, nil, written.Status,
+			nil, []byte(`{"score": 90, "signals": ["tor"]}`), written.CreatedAt,
+			1, "", written.Hash, written.UserDigest,
+		))
+
+	logs, err := NewChainedAuditLogRepository(db).ListChain(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatalf("ListChain() error = %v", err)
+	}
+	if len(logs) != 1 || logs[0].UserID != nil || logs[0].ChainSeq != 1 {
+		t.Fatalf("unexpected entries: %+v", logs)
+	}
+	if got, _ := logs[0].ComputeHash(); got != written.Hash {
+		t.Errorf("hash of the stored entry = %q, want %q", got, written.Hash)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// AuditLogRepository implements repository.AuditLogRepository using PostgreSQL
+type AuditLogRepository struct {
+	db DBTX
+}
+
+// NewAuditLogRepository creates a new PostgreSQL audit log repository
+func NewAuditLogRepository(db DBTX) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// Create appends an entry to the audit_logs table
+func (r *AuditLogRepository) Create(ctx context.Context, log *domain.AuditLog) error {
+	metadata := log.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit metadata: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_logs (
+			user_id, action, resource_type, resource_id,
+			ip_address, user_agent, request_id, status,
+			error_message, metadata, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		) RETURNING id`
+
+	err = r.db.QueryRowContext(
+		ctx,
+		query,
+		log.UserID,
+		log.Action,
+		log.ResourceType,
+		log.ResourceID,
+		log.IPAddress,
+		log.UserAgent,
+		log.RequestID,
+		log.Status,
+		log.ErrorMessage,
+		metadataJSON,
+		log.CreatedAt,
+	).Scan(&log.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
+
+// Ensure AuditLogRepository implements repository.AuditLogRepository
+var _ repository.AuditLogRepository = (*AuditLogRepository)(nil)
+
+// ListByUser returns the user's entries with one of the given actions, newest
+// first
+func (r *AuditLogRepository) ListByUser(ctx context.Context, userID string, actions []string, before *domain.AuditCursor, limit int) ([]*domain.AuditLog, error) {
+	if len(actions) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{userID}
+	placeholders := make([]string, len(actions))
+	for i, action := range actions {
+		args = append(args, action)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+	query := `
+		SELECT id, user_id, action, resource_type, resource_id,
+			ip_address, user_agent, request_id, status,
+			error_message, metadata, created_at
+		FROM audit_logs
+		WHERE user_id = $1 AND action IN (` + strings.Join(placeholders, ", ") + `)`
+	if before != nil {
+		args = append(args, before.CreatedAt, before.ID)
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*domain.AuditLog
+	for rows.Next() {
+		var (
+			log          domain.AuditLog
+			status       sql.NullString
+			metadataJSON []byte
+		)
+		if err := rows.Scan(
+			&log.ID, &log.UserID, &log.Action, &log.ResourceType, &log.ResourceID,
+			&log.IPAddress, &log.UserAgent, &log.RequestID, &status,
+			&log.ErrorMessage, &metadataJSON, &log.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		log.Status = status.String
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &log.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal audit metadata: %w", err)
+			}
+		}
+		logs = append(logs, &log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+// Ensure AuditLogRepository implements repository.AuditLogReader
+var _ repository.AuditLogReader = (*AuditLogRepository)(nil)
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestAuditLogRepository_Create(t *testing.T) {
+	fixedTime := time.Now()
+
+	tests := []struct {
+		name      string
+		log       *domain.AuditLog
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   bool
+		wantID    string
+	}{
+		{
+			name: "successful creation",
+			log: &domain.AuditLog{
+				UserID:    stringPtr("user-123"),
+				Action:    domain.AuditActionLoginRisk,
+				IPAddress: stringPtr("192.168.1.1"),
+				Status:    domain.AuditStatusSuccess,
+				Metadata:  map[string]interface{}{"score": 20},
+				CreatedAt: fixedTime,
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id"}).AddRow("audit-1")
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO audit_logs`)).
+					WithArgs(
+						"user-123",
+						domain.AuditActionLoginRisk,
+						nil,
+						nil,
+						"192.168.1.1",
+						nil,
+						nil,
+						domain.AuditStatusSuccess,
+						nil,
+						[]byte(`{"score":20}`),
+						fixedTime,
+					).
+					WillReturnRows(rows)
+			},
+			wantID: "audit-1",
+		},
+		{
+			name: "database error",
+			log: &domain.AuditLog{
+				Action:    domain.AuditActionLoginRisk,
+				Status:    domain.AuditStatusBlocked,
+				CreatedAt: fixedTime,
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO audit_logs`)).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			repo := NewAuditLogRepository(db)
+			err = repo.Create(context.Background(), tt.log)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.log.ID != tt.wantID {
+				t.Errorf("Create() ID = %v, want %v", tt.log.ID, tt.wantID)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuditLogRepository_ListByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	before := &domain.AuditCursor{CreatedAt: createdAt.Add(time.Hour), ID: "audit-9"}
+	columns := []string{
+		"id", "user_id", "action", "resource_type", "resource_id",
+		"ip_address", "user_agent", "request_id", "status",
+		"error_message", "metadata", "created_at",
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND action IN ($2, $3) AND (created_at, id) < ($4, $5) ORDER BY created_at DESC, id DESC LIMIT $6`)).
+		WithArgs("user-123", domain.AuditActionLogin, domain.AuditActionSessionsRevoked, before.CreatedAt, before.ID, 21).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(
+			"audit-1", "user-123", domain.AuditActionSessionsRevoked, "user", "user-123",
+			nil, nil, nil, domain.AuditStatusSuccess,
+			nil, []byte(`{"reason": "password_change"}`), createdAt,
+		))
+
+	repo := NewAuditLogRepository(db)
+	logs, err := repo.ListByUser(context.Background(), "user-123",
+		[]string{domain.AuditActionLogin, domain.AuditActionSessionsRevoked}, before, 21)
+	if err != nil {
+		t.Fatalf("ListByUser() error = %v", err)
+	}
+	if len(logs) != 1 || logs[0].ID != "audit-1" || logs[0].Metadata["reason"] != "password_change" {
+		t.Fatalf("unexpected entries: %+v", logs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
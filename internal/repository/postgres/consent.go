@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// ConsentRepository implements repository.ConsentRepository for PostgreSQL
+type ConsentRepository struct {
+	db DBTX
+}
+
+// NewConsentRepository creates a new PostgreSQL consent repository
+func NewConsentRepository(db DBTX) *ConsentRepository {
+	return &ConsentRepository{db: db}
+}
+
+// Create stores an answer
+func (r *ConsentRepository) Create(ctx context.Context, consent *domain.Consent) error {
+	query := `
+		INSERT INTO user_consents (user_id, kind, version, granted, ip_address)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		consent.UserID, consent.Kind, consent.Version, consent.Granted, consent.IPAddress,
+	).Scan(&consent.ID, &consent.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create consent: %w", err)
+	}
+	return nil
+}
+
+// ListByUser returns the user's answers, oldest first
+func (r *ConsentRepository) ListByUser(ctx context.Context, userID string) ([]*domain.Consent, error) {
+	query := `
+		SELECT id, user_id, kind, version, granted, ip_address, created_at
+		FROM user_consents WHERE user_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consents: %w", err)
+	}
+	defer rows.Close()
+
+	var consents []*domain.Consent
+	for rows.Next() {
+		consent := &domain.Consent{}
+		if err := rows.Scan(
+			&consent.ID, &consent.UserID, &consent.Kind, &consent.Version, &consent.Granted, &consent.IPAddress, &consent.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan consent: %w", err)
+		}
+		consents = append(consents, consent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating consents: %w", err)
+	}
+
+	return consents, nil
+}
+
+// AcceptedTermsVersion returns the terms of service version the user
+// accepted last
+func (r *ConsentRepository) AcceptedTermsVersion(ctx context.Context, userID string) (string, error) {
+	query := `
+		SELECT version FROM user_consents
+		WHERE user_id = $1 AND kind = $2 AND granted
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	var version string
+	err := r.db.QueryRowContext(ctx, query, userID, domain.ConsentTerms).Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get accepted terms version: %w", err)
+	}
+	return version, nil
+}
+
+// Ensure ConsentRepository implements repository.ConsentRepository
+var _ repository.ConsentRepository = (*ConsentRepository)(nil)
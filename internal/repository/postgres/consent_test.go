@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestConsentRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	ip := "203.0.113.7"
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO user_consents (user_id, kind, version, granted, ip_address)`)).
+		WithArgs("user-1", domain.ConsentTerms, "2026-01", true, &ip).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("consent-1", now))
+
+	consent := &domain.Consent{UserID: "user-1", Kind: domain.ConsentTerms, Version: "2026-01", Granted: true, IPAddress: &ip}
+	if err := NewConsentRepository(db).Create(context.Background(), consent); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if consent.ID != "consent-1" || !consent.CreatedAt.Equal(now) {
+		t.Errorf("Create() did not set ID and CreatedAt: %+v", consent)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestConsentRepository_AcceptedTermsVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+		want string
+	}{
+		{name: "accepted", rows: sqlmock.NewRows([]string{"version"}).AddRow("2026-01"), want: "2026-01"},
+		{name: "never accepted", rows: sqlmock.NewRows([]string{"version"}), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT version FROM user_consents`)).
+				WithArgs("user-1", domain.ConsentTerms).
+				WillReturnRows(tt.rows)
+
+			got, err := NewConsentRepository(db).AcceptedTermsVersion(context.Background(), "user-1")
+			if err != nil {
+				t.Fatalf("AcceptedTermsVersion() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("AcceptedTermsVersion() = %q, want %q", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
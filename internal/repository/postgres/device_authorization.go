@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// DeviceAuthorizationRepository implements repository.DeviceAuthorizationRepository for PostgreSQL
+type DeviceAuthorizationRepository struct {
+	db DBTX
+}
+
+// NewDeviceAuthorizationRepository creates a new PostgreSQL device authorization repository
+func NewDeviceAuthorizationRepository(db DBTX) *DeviceAuthorizationRepository {
+	return &DeviceAuthorizationRepository{db: db}
+}
+
+// deviceAuthorizationColumns lists the device_authorizations columns in the
+// order scanDeviceAuthorization reads them
+const deviceAuthorizationColumns = `
+	id, device_code_hash, user_code_hash, client_id, status, user_id, expires_at, last_polled_at, created_at`
+
+// scanDeviceAuthorization reads a row selected with deviceAuthorizationColumns
+func scanDeviceAuthorization(scan func(dest ...any) error) (*domain.DeviceAuthorization, error) {
+	auth := &domain.DeviceAuthorization{}
+	err := scan(
+		&auth.ID,
+		&auth.DeviceCodeHash,
+		&auth.UserCodeHash,
+		&auth.ClientID,
+		&auth.Status,
+		&auth.UserID,
+		&auth.ExpiresAt,
+		&auth.LastPolledAt,
+		&auth.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// Create stores a pending authorization
+func (r *DeviceAuthorizationRepository) Create(ctx context.Context, auth *domain.DeviceAuthorization) error {
+	query := `
+		INSERT INTO device_authorizations (device_code_hash, user_code_hash, client_id, status, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+
+	err := r.db.QueryRowContext(ctx, query,
+		auth.DeviceCodeHash,
+		auth.UserCodeHash,
+		auth.ClientID,
+		auth.Status,
+		auth.ExpiresAt,
+		auth.CreatedAt,
+	).Scan(&auth.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create device authorization: %w", err)
+	}
+	return nil
+}
+
+// GetByDeviceCodeHash retrieves an authorization by its hashed device code
+func (r *DeviceAuthorizationRepository) GetByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*domain.DeviceAuthorization, error) {
+	query := `SELECT ` + deviceAuthorizationColumns + ` FROM device_authorizations WHERE device_code_hash = $1`
+
+	auth, err := scanDeviceAuthorization(r.db.QueryRowContext(ctx, query, deviceCodeHash).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidDeviceCode
+		}
+		return nil, fmt.Errorf("failed to get device authorization: %w", err)
+	}
+	return auth, nil
+}
+
+// GetPendingByUserCodeHash retrieves a pending, unexpired authorization by
+// its hashed user code
+func (r *DeviceAuthorizationRepository) GetPendingByUserCodeHash(ctx context.Context, userCodeHash string, now time.Time) (*domain.DeviceAuthorization, error) {
+	query := `SELECT ` + deviceAuthorizationColumns + `
+		FROM device_authorizations
+		WHERE user_code_hash = $1 AND status = $2 AND expires_at > $3`
+
+	auth, err := scanDeviceAuthorization(r.db.QueryRowContext(ctx, query, userCodeHash, domain.DeviceAuthorizationPending, now).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidUserCode
+		}
+		return nil, fmt.Errorf("failed to get device authorization: %w", err)
+	}
+	return auth, nil
+}
+
+// Decide approves or denies a pending authorization in a single statement
+func (r *DeviceAuthorizationRepository) Decide(ctx context.Context, userCodeHash, userID string, status domain.DeviceAuthorizationStatus, now time.Time) error {
+	query := `
+		UPDATE device_authorizations SET status = $3, user_id = $2
+		WHERE user_code_hash = $1 AND status = $4 AND expires_at > $5`
+
+	result, err := r.db.ExecContext(ctx, query, userCodeHash, userID, status, domain.DeviceAuthorizationPending, now)
+	if err != nil {
+		return fmt.Errorf("failed to decide device authorization: %w", err)
+	}
+	return deviceAuthorizationAffected(result, domain.ErrInvalidUserCode)
+}
+
+// RecordPoll records a poll unless the previous one was too recent
+func (r *DeviceAuthorizationRepository) RecordPoll(ctx context.Context, id string, now time.Time, minInterval time.Duration) error {
+	query := `
+		UPDATE device_authorizations SET last_polled_at = $2
+		WHERE id = $1 AND (last_polled_at IS NULL OR last_polled_at <= $3)`
+
+	result, err := r.db.ExecContext(ctx, query, id, now, now.Add(-minInterval))
+	if err != nil {
+		return fmt.Errorf("failed to record device poll: %w", err)
+	}
+	return deviceAuthorizationAffected(result, domain.ErrSlowDown)
+}
+
+// Consume deletes an approved, unexpired authorization
+func (r *DeviceAuthorizationRepository) Consume(ctx context.Context, id string, now time.Time) error {
+	query := `DELETE FROM device_authorizations WHERE id = $1 AND status = $2 AND expires_at > $3`
+
+	result, err := r.db.ExecContext(ctx, query, id, domain.DeviceAuthorizationApproved, now)
+	if err != nil {
+		return fmt.Errorf("failed to consume device authorization: %w", err)
+	}
+	return deviceAuthorizationAffected(result, domain.ErrInvalidDeviceCode)
+}
+
+// deviceAuthorizationAffected returns notFound when a statement matched no row
+func deviceAuthorizationAffected(result sql.Result, notFound error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// Ensure DeviceAuthorizationRepository implements repository.DeviceAuthorizationRepository
+var _ repository.DeviceAuthorizationRepository = (*DeviceAuthorizationRepository)(nil)
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestDeviceAuthorizationRepository_GetByDeviceCodeHash(t *testing.T) {
+	columns := []string{"id", "device_code_hash", "user_code_hash", "client_id", "status", "user_id", "expires_at", "last_polled_at", "created_at"}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		wantErr error
+	}{
+		{
+			name: "approved authorization",
+			rows: sqlmock.NewRows(columns).
+				AddRow("auth-1", "device-hash", "user-hash", "authctl", "approved", "user-1", now.Add(time.Minute), nil, now),
+		},
+		{
+			name:    "unknown device code",
+			rows:    sqlmock.NewRows(columns),
+			wantErr: domain.ErrInvalidDeviceCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(`FROM device_authorizations WHERE device_code_hash = $1`)).
+				WithArgs("device-hash").
+				WillReturnRows(tt.rows)
+
+			auth, err := NewDeviceAuthorizationRepository(db).GetByDeviceCodeHash(context.Background(), "device-hash")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetByDeviceCodeHash() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (auth.Status != domain.DeviceAuthorizationApproved || *auth.UserID != "user-1" || auth.LastPolledAt != nil) {
+				t.Errorf("unexpected authorization: %+v", auth)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeviceAuthorizationRepository_Decide(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		rows    int64
+		wantErr error
+	}{
+		{name: "pending authorization", rows: 1},
+		{name: "already decided or expired", rows: 0, wantErr: domain.ErrInvalidUserCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE device_authorizations SET status = $3, user_id = $2`)).
+				WithArgs("user-hash", "user-1", domain.DeviceAuthorizationApproved, domain.DeviceAuthorizationPending, now).
+				WillReturnResult(sqlmock.NewResult(0, tt.rows))
+
+			err = NewDeviceAuthorizationRepository(db).Decide(context.Background(), "user-hash", "user-1", domain.DeviceAuthorizationApproved, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decide() error = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeviceAuthorizationRepository_RecordPoll(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		rows    int64
+		wantErr error
+	}{
+		{name: "first or well-spaced poll", rows: 1},
+		{name: "polled too soon", rows: 0, wantErr: domain.ErrSlowDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE device_authorizations SET last_polled_at = $2`)).
+				WithArgs("auth-1", now, now.Add(-4*time.Second)).
+				WillReturnResult(sqlmock.NewResult(0, tt.rows))
+
+			err = NewDeviceAuthorizationRepository(db).RecordPoll(context.Background(), "auth-1", now, 4*time.Second)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RecordPoll() error = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// EmailCodeRepository implements repository.EmailCodeRepository for PostgreSQL
+type EmailCodeRepository struct {
+	db DBTX
+}
+
+// NewEmailCodeRepository creates a new PostgreSQL email code repository
+func NewEmailCodeRepository(db DBTX) *EmailCodeRepository {
+	return &EmailCodeRepository{db: db}
+}
+
+// Save stores a code, replacing the user's previous code for the purpose and
+// its attempt count
+func (r *EmailCodeRepository) Save(ctx context.Context, code *domain.EmailCode) error {
+	query := `
+		INSERT INTO email_codes (user_id, purpose, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, 0, $4, $5)
+		ON CONFLICT (user_id, purpose) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash,
+			attempts = 0,
+			expires_at = EXCLUDED.expires_at,
+			created_at = EXCLUDED.created_at`
+
+	_, err := r.db.ExecContext(ctx, query, code.UserID, code.Purpose, code.CodeHash, code.ExpiresAt, code.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save email code: %w", err)
+	}
+	return nil
+}
+
+// RecordAttempt counts a guess and returns the code in a single statement
+func (r *EmailCodeRepository) RecordAttempt(ctx context.Context, userID string, purpose domain.EmailCodePurpose) (*domain.EmailCode, error) {
+	query := `
+		UPDATE email_codes SET attempts = attempts + 1
+		WHERE user_id = $1 AND purpose = $2
+		RETURNING code_hash, attempts, expires_at, created_at`
+
+	code := &domain.EmailCode{UserID: userID, Purpose: purpose}
+	err := r.db.QueryRowContext(ctx, query, userID, purpose).Scan(
+		&code.CodeHash,
+		&code.Attempts,
+		&code.ExpiresAt,
+		&code.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidEmailCode
+		}
+		return nil, fmt.Errorf("failed to record email code attempt: %w", err)
+	}
+	return code, nil
+}
+
+// Delete removes the user's code for the purpose
+func (r *EmailCodeRepository) Delete(ctx context.Context, userID string, purpose domain.EmailCodePurpose) error {
+	query := `DELETE FROM email_codes WHERE user_id = $1 AND purpose = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to delete email code: %w", err)
+	}
+	return nil
+}
+
+// DeleteUnexpired removes the user's code for the purpose if it has not
+// expired at now
+func (r *EmailCodeRepository) DeleteUnexpired(ctx context.Context, userID string, purpose domain.EmailCodePurpose, now time.Time) (bool, error) {
+	query := `DELETE FROM email_codes WHERE user_id = $1 AND purpose = $2 AND expires_at > $3`
+
+	result, err := r.db.ExecContext(ctx, query, userID, purpose, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete email code: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Ensure EmailCodeRepository implements repository.EmailCodeRepository
+var _ repository.EmailCodeRepository = (*EmailCodeRepository)(nil)
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestEmailCodeRepository_Save(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	code := &domain.EmailCode{
+		UserID:    "user-1",
+		Purpose:   domain.EmailCodeVerification,
+		CodeHash:  "hash",
+		ExpiresAt: now.Add(15 * time.Minute),
+		CreatedAt: now,
+	}
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO email_codes`)).
+		WithArgs("user-1", domain.EmailCodeVerification, "hash", code.ExpiresAt, code.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := NewEmailCodeRepository(db).Save(context.Background(), code); err != nil {
+		t.Errorf("Save() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestEmailCodeRepository_RecordAttempt(t *testing.T) {
+	query := regexp.QuoteMeta(`UPDATE email_codes SET attempts = attempts + 1`)
+	expiresAt := time.Now().Add(10 * time.Minute)
+
+	tests := []struct {
+		name         string
+		setupMock    func(sqlmock.Sqlmock)
+		wantAttempts int
+		wantErr      error
+	}{
+		{
+			name: "existing code",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("user-1", domain.EmailCodePasswordReset).
+					WillReturnRows(sqlmock.NewRows([]string{"code_hash", "attempts", "expires_at", "created_at"}).
+						AddRow("hash", 2, expiresAt, time.Now()))
+			},
+			wantAttempts: 2,
+		},
+		{
+			name: "no code",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("user-1", domain.EmailCodePasswordReset).
+					WillReturnRows(sqlmock.NewRows([]string{"code_hash", "attempts", "expires_at", "created_at"}))
+			},
+			wantErr: domain.ErrInvalidEmailCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			code, err := NewEmailCodeRepository(db).RecordAttempt(context.Background(), "user-1", domain.EmailCodePasswordReset)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RecordAttempt() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (code.Attempts != tt.wantAttempts || code.CodeHash != "hash") {
+				t.Errorf("RecordAttempt() = %+v, want %d attempts", code, tt.wantAttempts)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestEmailCodeRepository_DeleteUnexpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	query := regexp.QuoteMeta(`DELETE FROM email_codes WHERE user_id = $1 AND purpose = $2 AND expires_at > $3`)
+	mock.ExpectExec(query).
+		WithArgs("user-1", domain.EmailCodePasswordReset, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).
+		WithArgs("user-1", domain.SMSCodeRecovery, now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewEmailCodeRepository(db)
+	if deleted, err := repo.DeleteUnexpired(context.Background(), "user-1", domain.EmailCodePasswordReset, now); err != nil || !deleted {
+		t.Errorf("DeleteUnexpired() = %v, %v, want a deleted code", deleted, err)
+	}
+	if deleted, err := repo.DeleteUnexpired(context.Background(), "user-1", domain.SMSCodeRecovery, now); err != nil || deleted {
+		t.Errorf("DeleteUnexpired() without a code = %v, %v, want none deleted", deleted, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
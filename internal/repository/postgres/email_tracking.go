@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// EmailTrackingOptOutRepository implements
+// repository.EmailTrackingOptOutRepository for PostgreSQL
+type EmailTrackingOptOutRepository struct {
+	db DBTX
+}
+
+// NewEmailTrackingOptOutRepository creates a new PostgreSQL email tracking
+// opt-out repository
+func NewEmailTrackingOptOutRepository(db DBTX) *EmailTrackingOptOutRepository {
+	return &EmailTrackingOptOutRepository{db: db}
+}
+
+// IsOptedOut reports whether the user opted out of email tracking
+func (r *EmailTrackingOptOutRepository) IsOptedOut(ctx context.Context, userID string) (bool, error) {
+	var optedOut bool
+	query := `SELECT EXISTS (SELECT 1 FROM email_tracking_opt_outs WHERE user_id = $1)`
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&optedOut); err != nil {
+		return false, fmt.Errorf("failed to check email tracking opt-out: %w", err)
+	}
+	return optedOut, nil
+}
+
+// SetOptedOut records or withdraws the user's opt-out
+func (r *EmailTrackingOptOutRepository) SetOptedOut(ctx context.Context, userID string, optedOut bool) error {
+	query := `DELETE FROM email_tracking_opt_outs WHERE user_id = $1`
+	if optedOut {
+		query = `INSERT INTO email_tracking_opt_outs (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`
+	}
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to update email tracking opt-out: %w", err)
+	}
+	return nil
+}
+
+// Ensure EmailTrackingOptOutRepository implements
+// repository.EmailTrackingOptOutRepository
+var _ repository.EmailTrackingOptOutRepository = (*EmailTrackingOptOutRepository)(nil)
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestEmailTrackingOptOutRepository_IsOptedOut(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM email_tracking_opt_outs WHERE user_id = $1)`)
+
+	for _, optedOut := range []bool{true, false} {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating mock database: %v", err)
+		}
+
+		mock.ExpectQuery(query).
+			WithArgs("user-1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(optedOut))
+
+		got, err := NewEmailTrackingOptOutRepository(db).IsOptedOut(context.Background(), "user-1")
+		if err != nil || got != optedOut {
+			t.Errorf("IsOptedOut() = %v, %v; want %v", got, err, optedOut)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+		db.Close()
+	}
+}
+
+func TestEmailTrackingOptOutRepository_SetOptedOut(t *testing.T) {
+	tests := []struct {
+		optedOut bool
+		query    string
+	}{
+		{optedOut: true, query: `INSERT INTO email_tracking_opt_outs (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`},
+		{optedOut: false, query: `DELETE FROM email_tracking_opt_outs WHERE user_id = $1`},
+	}
+
+	for _, tt := range tests {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating mock database: %v", err)
+		}
+
+		mock.ExpectExec(regexp.QuoteMeta(tt.query)).
+			WithArgs("user-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		if err := NewEmailTrackingOptOutRepository(db).SetOptedOut(context.Background(), "user-1", tt.optedOut); err != nil {
+			t.Errorf("SetOptedOut(%v) error = %v", tt.optedOut, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// IntegrityRepository implements repository.IntegrityRepository using
+// PostgreSQL
+type IntegrityRepository struct {
+	db DBTX
+}
+
+// NewIntegrityRepository creates a new PostgreSQL integrity repository
+func NewIntegrityRepository(db DBTX) *IntegrityRepository {
+	return &IntegrityRepository{db: db}
+}
+
+// orphanedRefreshTokens matches active refresh tokens without an enabled
+// user. The foreign key deletes the tokens of deleted users, so a missing
+// user means the constraint was dropped or bypassed.
+const orphanedRefreshTokens = `
+	t.revoked = false
+		AND t.expires_at > $1
+		AND NOT EXISTS (
+			SELECT 1 FROM users u WHERE u.id = t.user_id AND u.disabled_at IS NULL
+		)`
+
+// expiredVerifications matches unverified users whose verification token
+// can no longer be used
+const expiredVerifications = `
+	email_verified = false
+		AND email_verification_token IS NOT NULL
+		AND email_verification_expires_at < $1`
+
+// CountOrphanedRefreshTokens counts active refresh tokens of deleted or
+// disabled users
+func (r *IntegrityRepository) CountOrphanedRefreshTokens(ctx context.Context, now time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM refresh_tokens t WHERE` + orphanedRefreshTokens
+	return r.count(ctx, "orphaned refresh tokens", query, now)
+}
+
+// RevokeOrphanedRefreshTokens revokes active refresh tokens of deleted or
+// disabled users
+func (r *IntegrityRepository) RevokeOrphanedRefreshTokens(ctx context.Context, now time.Time) (int, error) {
+	query := `UPDATE refresh_tokens t SET revoked = true, revoked_at = $1 WHERE` + orphanedRefreshTokens
+	return r.exec(ctx, "revoke orphaned refresh tokens", query, now)
+}
+
+// CountExpiredVerifications counts unverified users with an expired
+// verification token
+func (r *IntegrityRepository) CountExpiredVerifications(ctx context.Context, now time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM users WHERE` + expiredVerifications
+	return r.count(ctx, "expired verifications", query, now)
+}
+
+// ClearExpiredVerifications removes expired verification tokens. Verifying
+// with an expired token already fails, so users see no difference and can
+// still request a new email.
+func (r *IntegrityRepository) ClearExpiredVerifications(ctx context.Context, now time.Time) (int, error) {
+	query := `
+		UPDATE users SET
+			email_verification_token = NULL,
+			email_verification_expires_at = NULL
+		WHERE` + expiredVerifications
+	return r.exec(ctx, "clear expired verifications", query, now)
+}
+
+// ListAuditGaps returns up to limit ranges of chain positions with no audit
+// entry. The chain starts at 1, so missing leading entries are a gap too.
+func (r *IntegrityRepository) ListAuditGaps(ctx context.Context, limit int) ([]domain.AuditGap, error) {
+	query := `
+		SELECT prev_seq + 1, chain_seq - 1
+		FROM (
+			SELECT chain_seq, COALESCE(LAG(chain_seq) OVER (ORDER BY chain_seq), 0) AS prev_seq
+			FROM audit_logs
+			WHERE chain_seq IS NOT NULL
+		) chain
+		WHERE chain_seq > prev_seq + 1
+		ORDER BY chain_seq
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit gaps: %w", err)
+	}
+	defer rows.Close()
+
+	var gaps []domain.AuditGap
+	for rows.Next() {
+		var gap domain.AuditGap
+		if err := rows.Scan(&gap.From, &gap.To); err != nil {
+			return nil, fmt.Errorf("failed to scan audit gap: %w", err)
+		}
+		gaps = append(gaps, gap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit gaps: %w", err)
+	}
+
+	return gaps, nil
+}
+
+// MigrationState returns the schema migration version and dirty flag. A
+// database that was never migrated is at version 0.
+func (r *IntegrityRepository) MigrationState(ctx context.Context) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := r.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// count runs a COUNT query
+func (r *IntegrityRepository) count(ctx context.Context, what, query string, args ...interface{}) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", what, err)
+	}
+	return n, nil
+}
+
+// exec runs an update and returns the number of rows it changed
+func (r *IntegrityRepository) exec(ctx context.Context, what, query string, args ...interface{}) (int, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to %s: %w", what, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+// Ensure IntegrityRepository implements repository.IntegrityRepository
+var _ repository.IntegrityRepository = (*IntegrityRepository)(nil)
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestIntegrityRepository_RefreshTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM refresh_tokens t WHERE`)).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE refresh_tokens t SET revoked = true, revoked_at = $1 WHERE`)).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	repo := NewIntegrityRepository(db)
+	if n, err := repo.CountOrphanedRefreshTokens(context.Background(), now); err != nil || n != 3 {
+		t.Errorf("CountOrphanedRefreshTokens() = %d, %v, want 3", n, err)
+	}
+	if n, err := repo.RevokeOrphanedRefreshTokens(context.Background(), now); err != nil || n != 3 {
+		t.Errorf("RevokeOrphanedRefreshTokens() = %d, %v, want 3", n, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestIntegrityRepository_Verifications(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`AND email_verification_expires_at < $1`)).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec(regexp.QuoteMeta(`email_verification_token = NULL`)).
+		WithArgs(now).
+		WillReturnError(errors.New("connection reset"))
+
+	repo := NewIntegrityRepository(db)
+	if n, err := repo.CountExpiredVerifications(context.Background(), now); err != nil || n != 2 {
+		t.Errorf("CountExpiredVerifications() = %d, %v, want 2", n, err)
+	}
+	if _, err := repo.ClearExpiredVerifications(context.Background(), now); err == nil {
+		t.Error("ClearExpiredVerifications() expected error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestIntegrityRepository_ListAuditGaps(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"from", "to"}).
+		AddRow(int64(1), int64(2)).
+		AddRow(int64(10), int64(10))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE chain_seq > prev_seq + 1`)).
+		WithArgs(100).
+		WillReturnRows(rows)
+
+	repo := NewIntegrityRepository(db)
+	gaps, err := repo.ListAuditGaps(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("ListAuditGaps() error = %v", err)
+	}
+	want := []domain.AuditGap{{From: 1, To: 2}, {From: 10, To: 10}}
+	if len(gaps) != len(want) || gaps[0] != want[0] || gaps[1] != want[1] {
+		t.Errorf("ListAuditGaps() = %v, want %v", gaps, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestIntegrityRepository_MigrationState(t *testing.T) {
+	tests := []struct {
+		name        string
+		setupMock   func(sqlmock.Sqlmock)
+		wantVersion int64
+		wantDirty   bool
+		wantErr     bool
+	}{
+		{
+			name: "dirty",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, dirty FROM schema_migrations`)).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(int64(24), true))
+			},
+			wantVersion: 24,
+			wantDirty:   true,
+		},
+		{
+			name: "never migrated",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, dirty FROM schema_migrations`)).
+					WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, dirty FROM schema_migrations`)).
+					WillReturnError(errors.New("relation does not exist"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+			tt.setupMock(mock)
+
+			version, dirty, err := NewIntegrityRepository(db).MigrationState(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MigrationState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if version != tt.wantVersion || dirty != tt.wantDirty {
+				t.Errorf("MigrationState() = %d, %v, want %d, %v", version, dirty, tt.wantVersion, tt.wantDirty)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// TrustedDeviceRepository implements repository.TrustedDeviceRepository for PostgreSQL
+type TrustedDeviceRepository struct {
+	db DBTX
+}
+
+// NewTrustedDeviceRepository creates a new PostgreSQL trusted device repository
+func NewTrustedDeviceRepository(db DBTX) *TrustedDeviceRepository {
+	return &TrustedDeviceRepository{db: db}
+}
+
+// Create stores a device
+func (r *TrustedDeviceRepository) Create(ctx context.Context, device *domain.TrustedDevice) error {
+	query := `
+		INSERT INTO trusted_devices (user_id, name, secret_hash)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, device.UserID, device.Name, device.SecretHash).
+		Scan(&device.ID, &device.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create trusted device: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves one of the user's devices
+func (r *TrustedDeviceRepository) GetByID(ctx context.Context, userID, id string) (*domain.TrustedDevice, error) {
+	query := `
+		SELECT id, user_id, name, secret_hash, created_at, last_used_at
+		FROM trusted_devices WHERE id = $1 AND user_id = $2`
+
+	device := &domain.TrustedDevice{}
+	err := r.db.QueryRowContext(ctx, query, id, userID).Scan(
+		&device.ID, &device.UserID, &device.Name, &device.SecretHash, &device.CreatedAt, &device.LastUsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrTrustedDeviceNotFound
+		}
+		return nil, fmt.Errorf("failed to get trusted device: %w", err)
+	}
+	return device, nil
+}
+
+// ListByUser returns the user's devices, newest first
+func (r *TrustedDeviceRepository) ListByUser(ctx context.Context, userID string) ([]*domain.TrustedDevice, error) {
+	query := `
+		SELECT id, user_id, name, secret_hash, created_at, last_used_at
+		FROM trusted_devices WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trusted devices: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []*domain.TrustedDevice
+	for rows.Next() {
+		device := &domain.TrustedDevice{}
+		if err := rows.Scan(
+			&device.ID, &device.UserID, &device.Name, &device.SecretHash, &device.CreatedAt, &device.LastUsedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan trusted device: %w", err)
+		}
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trusted devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// Touch records that the device was used
+func (r *TrustedDeviceRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE trusted_devices SET last_used_at = $2 WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id, at); err != nil {
+		return fmt.Errorf("failed to touch trusted device: %w", err)
+	}
+	return nil
+}
+
+// Delete removes one of the user's devices
+func (r *TrustedDeviceRepository) Delete(ctx context.Context, userID, id string) error {
+	query := `DELETE FROM trusted_devices WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete trusted device: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return domain.ErrTrustedDeviceNotFound
+	}
+	return nil
+}
+
+// LoginApprovalRepository implements repository.LoginApprovalRepository for PostgreSQL
+type LoginApprovalRepository struct {
+	db DBTX
+}
+
+// NewLoginApprovalRepository creates a new PostgreSQL login approval repository
+func NewLoginApprovalRepository(db DBTX) *LoginApprovalRepository {
+	return &LoginApprovalRepository{db: db}
+}
+
+// loginApprovalColumns lists the login_approvals columns in the order
+// scanLoginApproval reads them
+const loginApprovalColumns = `
+	id, user_id, poll_token_hash, status, device_id, ip_address, user_agent, expires_at, created_at`
+
+// scanLoginApproval reads a row selected with loginApprovalColumns
+func scanLoginApproval(scan func(dest ...any) error) (*domain.LoginApproval, error) {
+	approval := &domain.LoginApproval{}
+	err := scan(
+		&approval.ID,
+		&approval.UserID,
+		&approval.PollTokenHash,
+		&approval.Status,
+		&approval.DeviceID,
+		&approval.IPAddress,
+		&approval.UserAgent,
+		&approval.ExpiresAt,
+		&approval.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+// Create stores a pending approval
+func (r *LoginApprovalRepository) Create(ctx context.Context, approval *domain.LoginApproval) error {
+	query := `
+		INSERT INTO login_approvals (user_id, poll_token_hash, status, ip_address, user_agent, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+
+	err := r.db.QueryRowContext(ctx, query,
+		approval.UserID,
+		approval.PollTokenHash,
+		approval.Status,
+		approval.IPAddress,
+		approval.UserAgent,
+		approval.ExpiresAt,
+		approval.CreatedAt,
+	).Scan(&approval.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create login approval: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an approval
+func (r *LoginApprovalRepository) GetByID(ctx context.Context, id string) (*domain.LoginApproval, error) {
+	query := `SELECT ` + loginApprovalColumns + ` FROM login_approvals WHERE id = $1`
+
+	approval, err := scanLoginApproval(r.db.QueryRowContext(ctx, query, id).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrLoginApprovalNotFound
+		}
+		return nil, fmt.Errorf("failed to get login approval: %w", err)
+	}
+	return approval, nil
+}
+
+// ListPending returns the user's approvals awaiting a decision, oldest first
+func (r *LoginApprovalRepository) ListPending(ctx context.Context, userID string, now time.Time) ([]*domain.LoginApproval, error) {
+	query := `SELECT ` + loginApprovalColumns + `
+		FROM login_approvals
+		WHERE user_id = $1 AND status = $2 AND expires_at > $3
+		ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, domain.LoginApprovalPending, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var approvals []*domain.LoginApproval
+	for rows.Next() {
+		approval, err := scanLoginApproval(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan login approval: %w", err)
+		}
+		approvals = append(approvals, approval)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating login approvals: %w", err)
+	}
+
+	return approvals, nil
+}
+
+// Decide approves or denies a pending approval in a single statement
+func (r *LoginApprovalRepository) Decide(ctx context.Context, userID, id, deviceID string, status domain.LoginApprovalStatus, now time.Time) error {
+	query := `
+		UPDATE login_approvals SET status = $4, device_id = $3
+		WHERE id = $1 AND user_id = $2 AND status = $5 AND expires_at > $6`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID, deviceID, status, domain.LoginApprovalPending, now)
+	if err != nil {
+		return fmt.Errorf("failed to decide login approval: %w", err)
+	}
+	return approvalAffected(result)
+}
+
+// Consume deletes an approved, unexpired approval
+func (r *LoginApprovalRepository) Consume(ctx context.Context, id string, now time.Time) error {
+	query := `DELETE FROM login_approvals WHERE id = $1 AND status = $2 AND expires_at > $3`
+
+	result, err := r.db.ExecContext(ctx, query, id, domain.LoginApprovalApproved, now)
+	if err != nil {
+		return fmt.Errorf("failed to consume login approval: %w", err)
+	}
+	return approvalAffected(result)
+}
+
+// approvalAffected maps an update that matched no approval to
+// domain.ErrLoginApprovalNotFound
+func approvalAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return domain.ErrLoginApprovalNotFound
+	}
+	return nil
+}
+
+// Ensure the repositories implement their interfaces
+var (
+	_ repository.TrustedDeviceRepository = (*TrustedDeviceRepository)(nil)
+	_ repository.LoginApprovalRepository = (*LoginApprovalRepository)(nil)
+)
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestTrustedDeviceRepository_Delete(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    int64
+		wantErr error
+	}{
+		{name: "own device", rows: 1},
+		{name: "unknown or other user's device", rows: 0, wantErr: domain.ErrTrustedDeviceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM trusted_devices WHERE id = $1 AND user_id = $2`)).
+				WithArgs("device-1", "user-1").
+				WillReturnResult(sqlmock.NewResult(0, tt.rows))
+
+			err = NewTrustedDeviceRepository(db).Delete(context.Background(), "user-1", "device-1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Delete() error = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoginApprovalRepository_GetByID(t *testing.T) {
+	columns := []string{"id", "user_id", "poll_token_hash", "status", "device_id", "ip_address", "user_agent", "expires_at", "created_at"}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		wantErr   error
+	}{
+		{
+			name: "pending approval",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM login_approvals WHERE id = $1`)).
+					WithArgs("approval-1").
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow("approval-1", "user-1", "hash", "pending", nil, "203.0.113.7", nil, now.Add(time.Minute), now))
+			},
+		},
+		{
+			name: "unknown approval",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM login_approvals WHERE id = $1`)).
+					WithArgs("approval-1").
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+			wantErr: domain.ErrLoginApprovalNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			approval, err := NewLoginApprovalRepository(db).GetByID(context.Background(), "approval-1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetByID() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (approval.Status != domain.LoginApprovalPending || *approval.IPAddress != "203.0.113.7") {
+				t.Errorf("unexpected approval: %+v", approval)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoginApprovalRepository_Decide(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		rows    int64
+		wantErr error
+	}{
+		{name: "pending approval", rows: 1},
+		{name: "already decided or expired", rows: 0, wantErr: domain.ErrLoginApprovalNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE login_approvals SET status = $4, device_id = $3`)).
+				WithArgs("approval-1", "user-1", "device-1", domain.LoginApprovalApproved, domain.LoginApprovalPending, now).
+				WillReturnResult(sqlmock.NewResult(0, tt.rows))
+
+			err = NewLoginApprovalRepository(db).Decide(context.Background(), "user-1", "approval-1", "device-1", domain.LoginApprovalApproved, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decide() error = %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"reflect"
 )
 
 // mockDB implements DBTX interface for testing
@@ -55,3 +57,21 @@ func (m *mockResult) LastInsertId() (int64, error) {
 func (m *mockResult) RowsAffected() (int64, error) {
 	return m.rowsAffected, m.err
 }
+
+// arrayConverter passes []string arguments through as pgx does, which
+// sqlmock's default converter rejects
+type arrayConverter struct{}
+
+func (arrayConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if s, ok := v.([]string); ok {
+		return s, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+// stringsArg matches a []string argument
+type stringsArg []string
+
+func (a stringsArg) Match(v driver.Value) bool {
+	return reflect.DeepEqual(v, []string(a))
+}
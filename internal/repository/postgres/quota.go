@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// QuotaRepository implements repository.QuotaRepository using PostgreSQL
+type QuotaRepository struct {
+	db DBTX
+}
+
+// NewQuotaRepository creates a new PostgreSQL quota repository
+func NewQuotaRepository(db DBTX) *QuotaRepository {
+	return &QuotaRepository{db: db}
+}
+
+// Increment counts one request for the subject and returns its new total.
+// The limit is checked in the same statement, so concurrent requests can
+// never push a subject past its quota.
+func (r *QuotaRepository) Increment(ctx context.Context, subject string, period time.Time, limit int64) (int64, error) {
+	query := `
+		INSERT INTO quota_usage (subject, period, request_count, updated_at)
+		VALUES ($1, $2, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (subject, period) DO UPDATE SET
+			request_count = quota_usage.request_count + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE $3 = 0 OR quota_usage.request_count < $3
+		RETURNING request_count`
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query, subject, period, limit).Scan(&count)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.ErrQuotaExceeded
+		}
+		return 0, fmt.Errorf("failed to increment quota usage: %w", err)
+	}
+	return count, nil
+}
+
+// Get returns the number of requests counted for the period
+func (r *QuotaRepository) Get(ctx context.Context, subject string, period time.Time) (int64, error) {
+	query := `SELECT request_count FROM quota_usage WHERE subject = $1 AND period = $2`
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query, subject, period).Scan(&count)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get quota usage: %w", err)
+	}
+	return count, nil
+}
+
+// Ensure QuotaRepository implements repository.QuotaRepository
+var _ repository.QuotaRepository = (*QuotaRepository)(nil)
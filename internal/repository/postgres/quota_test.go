@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestQuotaRepository_Increment(t *testing.T) {
+	period := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		wantCount int64
+		wantErr   error
+	}{
+		{
+			name: "counts request",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO quota_usage`)).
+					WithArgs("tenant:acme", period, int64(1000)).
+					WillReturnRows(sqlmock.NewRows([]string{"request_count"}).AddRow(42))
+			},
+			wantCount: 42,
+		},
+		{
+			name: "quota exceeded",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE $3 = 0 OR quota_usage.request_count < $3`)).
+					WithArgs("tenant:acme", period, int64(1000)).
+					WillReturnRows(sqlmock.NewRows([]string{"request_count"}))
+			},
+			wantErr: domain.ErrQuotaExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			count, err := NewQuotaRepository(db).Increment(context.Background(), "tenant:acme", period, 1000)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Increment() error = %v, want %v", err, tt.wantErr)
+			}
+			if count != tt.wantCount {
+				t.Errorf("Increment() = %d, want %d", count, tt.wantCount)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestQuotaRepository_Get(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	period := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT request_count FROM quota_usage`)).
+		WithArgs("user:123", period).
+		WillReturnRows(sqlmock.NewRows([]string{"request_count"}))
+
+	count, err := NewQuotaRepository(db).Get(context.Background(), "user:123", period)
+	if err != nil || count != 0 {
+		t.Errorf("Get() = %d, %v; want 0 for a subject without usage", count, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
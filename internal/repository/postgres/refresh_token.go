@@ -9,11 +9,13 @@ import (
 
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
 	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
 )
 
 // RefreshTokenRepository implements repository.RefreshTokenRepository using PostgreSQL
 type RefreshTokenRepository struct {
-	db DBTX
+	db     DBTX
+	cipher *security.FieldCipher
 }
 
 // NewRefreshTokenRepository creates a new PostgreSQL refresh token repository
@@ -21,27 +23,80 @@ func NewRefreshTokenRepository(db DBTX) *RefreshTokenRepository {
 	return &RefreshTokenRepository{db: db}
 }
 
+// SetFieldCipher encrypts user_agent and ip_address at rest. Rows written
+// before encryption was enabled are still read as plaintext.
+func (r *RefreshTokenRepository) SetFieldCipher(cipher *security.FieldCipher) {
+	r.cipher = cipher
+}
+
+// sealField encrypts an optional column value when a cipher is configured
+func (r *RefreshTokenRepository) sealField(value *string) (*string, error) {
+	if r.cipher == nil || value == nil {
+		return value, nil
+	}
+	sealed, err := r.cipher.Encrypt(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// openFields decrypts the encrypted columns of a scanned token in place
+func (r *RefreshTokenRepository) openFields(token *domain.RefreshToken) error {
+	if r.cipher == nil {
+		return nil
+	}
+	for _, field := range []*string{token.UserAgent, token.IPAddress} {
+		if field == nil {
+			continue
+		}
+		plaintext, err := r.cipher.Decrypt(*field)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt refresh token field: %w", err)
+		}
+		*field = plaintext
+	}
+	return nil
+}
+
 // Create creates a new refresh token in the database
 func (r *RefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
 	query := `
 		INSERT INTO refresh_tokens (
 			token, user_id, expires_at, revoked, revoked_at,
-			user_agent, ip_address, created_at, last_used_at
+			user_agent, ip_address, created_at, last_used_at, dpop_jkt, remember_me,
+			device_type
 		) VALUES (
-			gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8
+			gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 		) RETURNING token`
 
-	err := r.db.QueryRowContext(
+	// Classified before the user agent is sealed, so sessions can be
+	// grouped by device without decrypting it
+	deviceType := domain.DeviceType(token.UserAgent)
+
+	userAgent, err := r.sealField(token.UserAgent)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt user agent: %w", err)
+	}
+	ipAddress, err := r.sealField(token.IPAddress)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt ip address: %w", err)
+	}
+
+	err = r.db.QueryRowContext(
 		ctx,
 		query,
 		token.UserID,
 		token.ExpiresAt,
 		token.Revoked,
 		token.RevokedAt,
-		token.UserAgent,
-		token.IPAddress,
+		userAgent,
+		ipAddress,
 		token.CreatedAt,
 		token.LastUsedAt,
+		token.DPoPThumbprint,
+		token.RememberMe,
+		deviceType,
 	).Scan(&token.Token)
 
 	if err != nil {
@@ -57,7 +112,7 @@ func (r *RefreshTokenRepository) GetByToken(ctx context.Context, tokenValue stri
 	query := `
 		SELECT 
 			token, user_id, expires_at, revoked, revoked_at,
-			user_agent, ip_address, created_at, last_used_at
+			user_agent, ip_address, created_at, last_used_at, dpop_jkt, remember_me
 		FROM refresh_tokens
 		WHERE token = $1`
 
@@ -71,6 +126,8 @@ func (r *RefreshTokenRepository) GetByToken(ctx context.Context, tokenValue stri
 		&token.IPAddress,
 		&token.CreatedAt,
 		&token.LastUsedAt,
+		&token.DPoPThumbprint,
+		&token.RememberMe,
 	)
 
 	if err != nil {
@@ -79,6 +136,9 @@ func (r *RefreshTokenRepository) GetByToken(ctx context.Context, tokenValue stri
 		}
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
+	if err := r.openFields(token); err != nil {
+		return nil, err
+	}
 
 	return token, nil
 }
@@ -88,7 +148,7 @@ func (r *RefreshTokenRepository) GetByUserID(ctx context.Context, userID string)
 	query := `
 		SELECT 
 			token, user_id, expires_at, revoked, revoked_at,
-			user_agent, ip_address, created_at, last_used_at
+			user_agent, ip_address, created_at, last_used_at, dpop_jkt, remember_me
 		FROM refresh_tokens
 		WHERE user_id = $1
 		ORDER BY created_at DESC`
@@ -112,10 +172,15 @@ func (r *RefreshTokenRepository) GetByUserID(ctx context.Context, userID string)
 			&token.IPAddress,
 			&token.CreatedAt,
 			&token.LastUsedAt,
+			&token.DPoPThumbprint,
+			&token.RememberMe,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan refresh token: %w", err)
 		}
+		if err := r.openFields(token); err != nil {
+			return nil, err
+		}
 		tokens = append(tokens, token)
 	}
 
@@ -126,6 +191,92 @@ func (r *RefreshTokenRepository) GetByUserID(ctx context.Context, userID string)
 	return tokens, nil
 }
 
+// GetActiveCounts counts the unrevoked, unexpired refresh tokens of each
+// user in one query
+func (r *RefreshTokenRepository) GetActiveCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+
+	query := `
+		SELECT user_id, COUNT(*)
+		FROM refresh_tokens
+		WHERE user_id = ANY($1) AND revoked = false AND expires_at > $2
+		GROUP BY user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, userIDs, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan refresh token count: %w", err)
+		}
+		counts[userID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating refresh token counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// SessionStats aggregates the active refresh tokens of userID, or of every
+// user when userID is empty, with aggregate queries rather than loading the
+// tokens
+func (r *RefreshTokenRepository) SessionStats(ctx context.Context, userID string, now time.Time) (*domain.SessionStats, error) {
+	filter := "revoked = false AND expires_at > $1"
+	args := []interface{}{now}
+	if userID != "" {
+		filter += " AND user_id = $2"
+		args = append(args, userID)
+	}
+
+	stats := &domain.SessionStats{Devices: make(map[string]int)}
+	var averageAgeSeconds float64
+	query := `
+		SELECT COUNT(*), COUNT(DISTINCT user_id),
+			COALESCE(AVG(EXTRACT(EPOCH FROM ($1 - created_at))), 0)
+		FROM refresh_tokens
+		WHERE ` + filter
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&stats.ActiveSessions, &stats.Users, &averageAgeSeconds); err != nil {
+		return nil, fmt.Errorf("failed to aggregate sessions: %w", err)
+	}
+	stats.AverageAge = time.Duration(averageAgeSeconds * float64(time.Second)).Round(time.Second)
+
+	query = `
+		SELECT COALESCE(device_type, '` + domain.DeviceTypeUnknown + `'), COUNT(*)
+		FROM refresh_tokens
+		WHERE ` + filter + `
+		GROUP BY 1`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sessions by device: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deviceType string
+		var count int
+		if err := rows.Scan(&deviceType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan session device count: %w", err)
+		}
+		stats.Devices[deviceType] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session device counts: %w", err)
+	}
+
+	return stats, nil
+}
+
 // Update updates a refresh token in the database
 func (r *RefreshTokenRepository) Update(ctx context.Context, token *domain.RefreshToken) error {
 	query := `
@@ -1,15 +1,19 @@
 package postgres
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"reflect"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
 )
 
 func TestNewRefreshTokenRepository(t *testing.T) {
@@ -61,6 +65,9 @@ func TestRefreshTokenRepository_Create(t *testing.T) {
 						nil,
 						fixedTime,
 						fixedTime,
+						nil,
+						false,
+						domain.DeviceTypeUnknown,
 					).
 					WillReturnRows(rows)
 			},
@@ -90,6 +97,9 @@ func TestRefreshTokenRepository_Create(t *testing.T) {
 						"192.168.1.1",
 						fixedTime,
 						fixedTime,
+						nil,
+						false,
+						domain.DeviceTypeOther,
 					).
 					WillReturnRows(rows)
 			},
@@ -115,6 +125,9 @@ func TestRefreshTokenRepository_Create(t *testing.T) {
 						nil,
 						fixedTime,
 						fixedTime,
+						nil,
+						false,
+						domain.DeviceTypeUnknown,
 					).
 					WillReturnError(errors.New("database error"))
 			},
@@ -169,10 +182,10 @@ func TestRefreshTokenRepository_GetByToken(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
 					"token", "user_id", "expires_at", "revoked", "revoked_at",
-					"user_agent", "ip_address", "created_at", "last_used_at",
+					"user_agent", "ip_address", "created_at", "last_used_at", "dpop_jkt", "remember_me",
 				}).AddRow(
 					"valid-token", "user-123", fixedTime.Add(24*time.Hour), false, nil,
-					"Mozilla/5.0", "192.168.1.1", fixedTime, fixedTime,
+					"Mozilla/5.0", "192.168.1.1", fixedTime, fixedTime, nil, false,
 				)
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT token, user_id, expires_at`)).
 					WithArgs("valid-token").
@@ -196,10 +209,10 @@ func TestRefreshTokenRepository_GetByToken(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
 					"token", "user_id", "expires_at", "revoked", "revoked_at",
-					"user_agent", "ip_address", "created_at", "last_used_at",
+					"user_agent", "ip_address", "created_at", "last_used_at", "dpop_jkt", "remember_me",
 				}).AddRow(
 					"revoked-token", "user-123", fixedTime.Add(24*time.Hour), true, revokedTime,
-					nil, nil, fixedTime, fixedTime,
+					nil, nil, fixedTime, fixedTime, nil, false,
 				)
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT token, user_id, expires_at`)).
 					WithArgs("revoked-token").
@@ -290,10 +303,10 @@ func TestRefreshTokenRepository_GetByUserID(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
 					"token", "user_id", "expires_at", "revoked", "revoked_at",
-					"user_agent", "ip_address", "created_at", "last_used_at",
+					"user_agent", "ip_address", "created_at", "last_used_at", "dpop_jkt", "remember_me",
 				}).
-					AddRow("token-1", "user-123", fixedTime.Add(24*time.Hour), false, nil, nil, nil, fixedTime, fixedTime).
-					AddRow("token-2", "user-123", fixedTime.Add(48*time.Hour), false, nil, nil, nil, fixedTime.Add(-1*time.Hour), fixedTime.Add(-1*time.Hour))
+					AddRow("token-1", "user-123", fixedTime.Add(24*time.Hour), false, nil, nil, nil, fixedTime, fixedTime, nil, false).
+					AddRow("token-2", "user-123", fixedTime.Add(48*time.Hour), false, nil, nil, nil, fixedTime.Add(-1*time.Hour), fixedTime.Add(-1*time.Hour), nil, true)
 
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT token, user_id, expires_at`)).
 					WithArgs("user-123").
@@ -308,7 +321,7 @@ func TestRefreshTokenRepository_GetByUserID(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
 					"token", "user_id", "expires_at", "revoked", "revoked_at",
-					"user_agent", "ip_address", "created_at", "last_used_at",
+					"user_agent", "ip_address", "created_at", "last_used_at", "dpop_jkt", "remember_me",
 				})
 
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT token, user_id, expires_at`)).
@@ -335,9 +348,9 @@ func TestRefreshTokenRepository_GetByUserID(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
 					"token", "user_id", "expires_at", "revoked", "revoked_at",
-					"user_agent", "ip_address", "created_at", "last_used_at",
+					"user_agent", "ip_address", "created_at", "last_used_at", "dpop_jkt", "remember_me",
 				}).
-					AddRow("token-1", "user-scan", "invalid-time", false, nil, nil, nil, fixedTime, fixedTime) // invalid time will cause scan error
+					AddRow("token-1", "user-scan", "invalid-time", false, nil, nil, nil, fixedTime, fixedTime, nil, false) // invalid time will cause scan error
 
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT token, user_id, expires_at`)).
 					WithArgs("user-scan").
@@ -352,9 +365,9 @@ func TestRefreshTokenRepository_GetByUserID(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
 					"token", "user_id", "expires_at", "revoked", "revoked_at",
-					"user_agent", "ip_address", "created_at", "last_used_at",
+					"user_agent", "ip_address", "created_at", "last_used_at", "dpop_jkt", "remember_me",
 				}).
-					AddRow("token-1", "user-rows-err", fixedTime.Add(24*time.Hour), false, nil, nil, nil, fixedTime, fixedTime).
+					AddRow("token-1", "user-rows-err", fixedTime.Add(24*time.Hour), false, nil, nil, nil, fixedTime, fixedTime, nil, false).
 					RowError(0, errors.New("row error"))
 
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT token, user_id, expires_at`)).
@@ -824,3 +837,233 @@ func TestRefreshTokenRepository_DeleteByToken(t *testing.T) {
 		})
 	}
 }
+
+func TestRefreshTokenRepository_GetActiveCounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(sqlmock.Sqlmock)
+		want      map[string]int
+		wantErr   bool
+	}{
+		{
+			name: "counts per user",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"user_id", "count"}).
+					AddRow("user-1", 3).
+					AddRow("user-2", 1)
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = ANY($1) AND revoked = false AND expires_at > $2`)).
+					WithArgs(stringsArg{"user-1", "user-2", "user-3"}, sqlmock.AnyArg()).
+					WillReturnRows(rows)
+			},
+			want: map[string]int{"user-1": 3, "user-2": 1},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT user_id, COUNT(*)`)).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			repo := NewRefreshTokenRepository(db)
+			got, err := repo.GetActiveCounts(context.Background(), []string{"user-1", "user-2", "user-3"})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetActiveCounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetActiveCounts() = %v, want %v", got, tt.want)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenRepository_SessionStats(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		userID    string
+		setupMock func(sqlmock.Sqlmock)
+		want      *domain.SessionStats
+		wantErr   bool
+	}{
+		{
+			name: "all users",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE revoked = false AND expires_at > $1`)).
+					WithArgs(now).
+					WillReturnRows(sqlmock.NewRows([]string{"count", "users", "avg"}).AddRow(5, 3, 3600.4))
+				mock.ExpectQuery(regexp.QuoteMeta(`GROUP BY 1`)).
+					WithArgs(now).
+					WillReturnRows(sqlmock.NewRows([]string{"device_type", "count"}).
+						AddRow("desktop", 3).
+						AddRow("unknown", 2))
+			},
+			want: &domain.SessionStats{
+				ActiveSessions: 5,
+				Users:          3,
+				AverageAge:     time.Hour,
+				Devices:        map[string]int{"desktop": 3, "unknown": 2},
+			},
+		},
+		{
+			name:   "one user",
+			userID: "user-1",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`expires_at > $1 AND user_id = $2`)).
+					WithArgs(now, "user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"count", "users", "avg"}).AddRow(0, 0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(`GROUP BY 1`)).
+					WithArgs(now, "user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"device_type", "count"}))
+			},
+			want: &domain.SessionStats{Devices: map[string]int{}},
+		},
+		{
+			name: "database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*)`)).
+					WillReturnError(errors.New("database error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			repo := NewRefreshTokenRepository(db)
+			got, err := repo.SessionStats(context.Background(), tt.userID, now)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionStats() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SessionStats() = %+v, want %+v", got, tt.want)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+// sealedArg matches a column value encrypted with the active key and
+// remembers it so the test can read it back
+type sealedArg struct {
+	cipher *security.FieldCipher
+	value  *string
+}
+
+func (a *sealedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok || a.cipher.NeedsReencryption(s) {
+		return false
+	}
+	*a.value = s
+	return true
+}
+
+func TestRefreshTokenRepository_FieldEncryption(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	cipher, err := security.NewFieldCipher(map[string][]byte{"k1": bytes.Repeat([]byte{7}, security.FieldKeySize)}, "k1")
+	if err != nil {
+		t.Fatalf("NewFieldCipher() error = %v", err)
+	}
+	repo := NewRefreshTokenRepository(db)
+	repo.SetFieldCipher(cipher)
+
+	fixedTime := time.Now()
+	token := &domain.RefreshToken{
+		UserID:     "user-123",
+		ExpiresAt:  fixedTime.Add(24 * time.Hour),
+		UserAgent:  stringPtr("Mozilla/5.0"),
+		IPAddress:  stringPtr("192.168.1.1"),
+		CreatedAt:  fixedTime,
+		LastUsedAt: fixedTime,
+	}
+
+	var sealedUA, sealedIP string
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO refresh_tokens`)).
+		WithArgs(
+			"user-123",
+			fixedTime.Add(24*time.Hour),
+			false,
+			nil,
+			&sealedArg{cipher: cipher, value: &sealedUA},
+			&sealedArg{cipher: cipher, value: &sealedIP},
+			fixedTime,
+			fixedTime,
+			nil,
+			false,
+			domain.DeviceTypeOther,
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"token"}).AddRow("generated-token-uuid"))
+
+	if err := repo.Create(context.Background(), token); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if *token.UserAgent != "Mozilla/5.0" || *token.IPAddress != "192.168.1.1" {
+		t.Error("Create() should not modify the caller's token")
+	}
+
+	// Encrypted rows and plaintext rows written before encryption both read back
+	rows := sqlmock.NewRows([]string{
+		"token", "user_id", "expires_at", "revoked", "revoked_at",
+		"user_agent", "ip_address", "created_at", "last_used_at", "dpop_jkt", "remember_me",
+	}).
+		AddRow("generated-token-uuid", "user-123", fixedTime.Add(24*time.Hour), false, nil,
+			sealedUA, sealedIP, fixedTime, fixedTime, nil, false).
+		AddRow("legacy-token", "user-123", fixedTime.Add(24*time.Hour), false, nil,
+			"curl/8.0", "10.0.0.1", fixedTime, fixedTime, nil, false)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM refresh_tokens`)).
+		WithArgs("user-123").
+		WillReturnRows(rows)
+
+	tokens, err := repo.GetByUserID(context.Background(), "user-123")
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	want := [][2]string{{"Mozilla/5.0", "192.168.1.1"}, {"curl/8.0", "10.0.0.1"}}
+	if len(tokens) != len(want) {
+		t.Fatalf("GetByUserID() returned %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		if *tok.UserAgent != want[i][0] || *tok.IPAddress != want[i][1] {
+			t.Errorf("token %d = %q, %q, want %q, %q", i, *tok.UserAgent, *tok.IPAddress, want[i][0], want[i][1])
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
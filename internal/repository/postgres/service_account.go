@@ -0,0 +1,253 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// ServiceAccountRepository implements repository.ServiceAccountRepository
+// using PostgreSQL. Scopes are stored space-delimited, as in the scope claim.
+type ServiceAccountRepository struct {
+	db DBTX
+}
+
+// NewServiceAccountRepository creates a new PostgreSQL service account repository
+func NewServiceAccountRepository(db DBTX) *ServiceAccountRepository {
+	return &ServiceAccountRepository{db: db}
+}
+
+// Create stores a new account
+func (r *ServiceAccountRepository) Create(ctx context.Context, account *domain.ServiceAccount) error {
+	query := `
+		INSERT INTO service_accounts (id, name, scope, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		account.ID, account.Name, strings.Join(account.Scopes, " "), account.CreatedAt, account.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create service account: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an account
+func (r *ServiceAccountRepository) GetByID(ctx context.Context, id string) (*domain.ServiceAccount, error) {
+	query := `SELECT id, name, scope, created_at, updated_at FROM service_accounts WHERE id = $1`
+
+	account, err := scanServiceAccount(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrServiceAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get service account: %w", err)
+	}
+	return account, nil
+}
+
+// List returns all accounts, newest first
+func (r *ServiceAccountRepository) List(ctx context.Context) ([]*domain.ServiceAccount, error) {
+	query := `SELECT id, name, scope, created_at, updated_at FROM service_accounts ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*domain.ServiceAccount
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan service account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating service accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// UpdateScopes replaces the scopes of an account
+func (r *ServiceAccountRepository) UpdateScopes(ctx context.Context, id string, scopes []string, at time.Time) error {
+	query := `UPDATE service_accounts SET scope = $2, updated_at = $3 WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id, strings.Join(scopes, " "), at)
+	if err != nil {
+		return fmt.Errorf("failed to update service account: %w", err)
+	}
+	return requireServiceAccountRow(result, domain.ErrServiceAccountNotFound)
+}
+
+// Delete deletes an account; its keys and used assertions go with it
+func (r *ServiceAccountRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM service_accounts WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete service account: %w", err)
+	}
+	return requireServiceAccountRow(result, domain.ErrServiceAccountNotFound)
+}
+
+// CreateKey registers a key
+func (r *ServiceAccountRepository) CreateKey(ctx context.Context, key *domain.ServiceAccountKey) error {
+	publicKey, err := json.Marshal(key.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	query := `
+		INSERT INTO service_account_keys (id, service_account_id, public_key, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (service_account_id, id) DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query, key.ID, key.ServiceAccountID, publicKey, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create service account key: %w", err)
+	}
+	return requireServiceAccountRow(result, domain.ErrServiceAccountKeyExists)
+}
+
+// GetKey retrieves a key of an account, expired or not
+func (r *ServiceAccountRepository) GetKey(ctx context.Context, accountID, keyID string) (*domain.ServiceAccountKey, error) {
+	query := `
+		SELECT id, service_account_id, public_key, created_at, expires_at
+		FROM service_account_keys
+		WHERE service_account_id = $1 AND id = $2`
+
+	key, err := scanServiceAccountKey(r.db.QueryRowContext(ctx, query, accountID, keyID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrServiceAccountKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get service account key: %w", err)
+	}
+	return key, nil
+}
+
+// ListKeys returns the keys of an account, newest first
+func (r *ServiceAccountRepository) ListKeys(ctx context.Context, accountID string) ([]*domain.ServiceAccountKey, error) {
+	query := `
+		SELECT id, service_account_id, public_key, created_at, expires_at
+		FROM service_account_keys
+		WHERE service_account_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service account keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*domain.ServiceAccountKey
+	for rows.Next() {
+		key, err := scanServiceAccountKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan service account key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating service account keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// ExpireKey makes a key expire at the given time, unless it already expires
+// sooner
+func (r *ServiceAccountRepository) ExpireKey(ctx context.Context, accountID, keyID string, at time.Time) error {
+	query := `
+		UPDATE service_account_keys
+		SET expires_at = LEAST(COALESCE(expires_at, $3), $3)
+		WHERE service_account_id = $1 AND id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, accountID, keyID, at)
+	if err != nil {
+		return fmt.Errorf("failed to expire service account key: %w", err)
+	}
+	return requireServiceAccountRow(result, domain.ErrServiceAccountKeyNotFound)
+}
+
+// RecordAssertion remembers an assertion id until it expires. Expired ids of
+// the account are dropped first, so an id may be reused once its assertion
+// could no longer be accepted anyway.
+func (r *ServiceAccountRepository) RecordAssertion(ctx context.Context, accountID, assertionID string, expiresAt time.Time) (bool, error) {
+	purge := `DELETE FROM service_account_assertions WHERE service_account_id = $1 AND expires_at < NOW()`
+	if _, err := r.db.ExecContext(ctx, purge, accountID); err != nil {
+		return false, fmt.Errorf("failed to purge used assertions: %w", err)
+	}
+
+	query := `
+		INSERT INTO service_account_assertions (service_account_id, jti, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (service_account_id, jti) DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query, accountID, assertionID, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record assertion: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanServiceAccount reads an account from a row of id, name, scope,
+// created_at and updated_at
+func scanServiceAccount(row rowScanner) (*domain.ServiceAccount, error) {
+	account := &domain.ServiceAccount{}
+	var scope string
+	if err := row.Scan(&account.ID, &account.Name, &scope, &account.CreatedAt, &account.UpdatedAt); err != nil {
+		return nil, err
+	}
+	account.Scopes = strings.Fields(scope)
+	return account, nil
+}
+
+// scanServiceAccountKey reads a key from a row of id, service_account_id,
+// public_key, created_at and expires_at
+func scanServiceAccountKey(row rowScanner) (*domain.ServiceAccountKey, error) {
+	key := &domain.ServiceAccountKey{}
+	var publicKey []byte
+	if err := row.Scan(&key.ID, &key.ServiceAccountID, &publicKey, &key.CreatedAt, &key.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(publicKey, &key.PublicKey); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal public key: %w", err)
+	}
+	return key, nil
+}
+
+// requireServiceAccountRow returns notFound unless the statement changed a row
+func requireServiceAccountRow(result sql.Result, notFound error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// Ensure ServiceAccountRepository implements repository.ServiceAccountRepository
+var _ repository.ServiceAccountRepository = (*ServiceAccountRepository)(nil)
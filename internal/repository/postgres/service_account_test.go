@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestServiceAccountRepository_GetKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Now()
+	columns := []string{"id", "service_account_id", "public_key", "created_at", "expires_at"}
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM service_account_keys`)).
+		WithArgs("sa_build", "kid-1").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("kid-1", "sa_build", []byte(`{"kty":"EC","crv":"P-256","x":"eA","y":"eQ"}`), createdAt, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM service_account_keys`)).
+		WithArgs("sa_build", "kid-2").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	repo := NewServiceAccountRepository(db)
+	key, err := repo.GetKey(context.Background(), "sa_build", "kid-1")
+	if err != nil {
+		t.Fatalf("GetKey() error = %v", err)
+	}
+	if key.PublicKey["crv"] != "P-256" || !key.IsActive(createdAt) {
+		t.Errorf("unexpected key: %+v", key)
+	}
+
+	if _, err := repo.GetKey(context.Background(), "sa_build", "kid-2"); !errors.Is(err, domain.ErrServiceAccountKeyNotFound) {
+		t.Errorf("GetKey() error = %v, want ErrServiceAccountKeyNotFound", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestServiceAccountRepository_RecordAssertion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	expiresAt := time.Now().Add(time.Minute)
+	purge := regexp.QuoteMeta(`DELETE FROM service_account_assertions WHERE service_account_id = $1 AND expires_at < NOW()`)
+	insert := regexp.QuoteMeta(`INSERT INTO service_account_assertions`)
+	mock.ExpectExec(purge).WithArgs("sa_build").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(insert).WithArgs("sa_build", "jti-1", expiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(purge).WithArgs("sa_build").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(insert).WithArgs("sa_build", "jti-1", expiresAt).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewServiceAccountRepository(db)
+	if fresh, err := repo.RecordAssertion(context.Background(), "sa_build", "jti-1", expiresAt); err != nil || !fresh {
+		t.Errorf("RecordAssertion() = %v, %v; want a fresh assertion", fresh, err)
+	}
+	if fresh, err := repo.RecordAssertion(context.Background(), "sa_build", "jti-1", expiresAt); err != nil || fresh {
+		t.Errorf("RecordAssertion() of a replay = %v, %v; want false", fresh, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestServiceAccountRepository_ExpireKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta(`SET expires_at = LEAST(COALESCE(expires_at, $3), $3)`)).
+		WithArgs("sa_build", "kid-1", at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewServiceAccountRepository(db).ExpireKey(context.Background(), "sa_build", "kid-1", at)
+	if !errors.Is(err, domain.ErrServiceAccountKeyNotFound) {
+		t.Errorf("ExpireKey() error = %v, want ErrServiceAccountKeyNotFound", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
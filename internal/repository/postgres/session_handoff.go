@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// SessionHandoffRepository implements repository.SessionHandoffRepository for PostgreSQL
+type SessionHandoffRepository struct {
+	db DBTX
+}
+
+// NewSessionHandoffRepository creates a new PostgreSQL session handoff repository
+func NewSessionHandoffRepository(db DBTX) *SessionHandoffRepository {
+	return &SessionHandoffRepository{db: db}
+}
+
+// Create stores a handoff, deleting the user's previous one in the same
+// statement
+func (r *SessionHandoffRepository) Create(ctx context.Context, handoff *domain.SessionHandoff) error {
+	query := `
+		WITH replaced AS (
+			DELETE FROM session_handoffs WHERE user_id = $1
+		)
+		INSERT INTO session_handoffs (user_id, code_hash, ip_address, user_agent, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+
+	err := r.db.QueryRowContext(ctx, query,
+		handoff.UserID,
+		handoff.CodeHash,
+		handoff.IPAddress,
+		handoff.UserAgent,
+		handoff.ExpiresAt,
+		handoff.CreatedAt,
+	).Scan(&handoff.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create session handoff: %w", err)
+	}
+	return nil
+}
+
+// Consume deletes and returns an unexpired handoff by its hashed code
+func (r *SessionHandoffRepository) Consume(ctx context.Context, codeHash string, now time.Time) (*domain.SessionHandoff, error) {
+	query := `
+		DELETE FROM session_handoffs
+		WHERE code_hash = $1 AND expires_at > $2
+		RETURNING id, user_id, code_hash, ip_address, user_agent, expires_at, created_at`
+
+	handoff := &domain.SessionHandoff{}
+	err := r.db.QueryRowContext(ctx, query, codeHash, now).Scan(
+		&handoff.ID,
+		&handoff.UserID,
+		&handoff.CodeHash,
+		&handoff.IPAddress,
+		&handoff.UserAgent,
+		&handoff.ExpiresAt,
+		&handoff.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrSessionHandoffNotFound
+		}
+		return nil, fmt.Errorf("failed to consume session handoff: %w", err)
+	}
+	return handoff, nil
+}
+
+// Ensure SessionHandoffRepository implements repository.SessionHandoffRepository
+var _ repository.SessionHandoffRepository = (*SessionHandoffRepository)(nil)
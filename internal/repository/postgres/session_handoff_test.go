@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestSessionHandoffRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	ip := "203.0.113.7"
+	handoff := &domain.SessionHandoff{
+		UserID:    "user-1",
+		CodeHash:  "hash",
+		IPAddress: &ip,
+		ExpiresAt: now.Add(time.Minute),
+		CreatedAt: now,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM session_handoffs WHERE user_id = $1`)).
+		WithArgs("user-1", "hash", &ip, nil, handoff.ExpiresAt, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("handoff-1"))
+
+	if err := NewSessionHandoffRepository(db).Create(context.Background(), handoff); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if handoff.ID != "handoff-1" {
+		t.Errorf("ID = %q, want handoff-1", handoff.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSessionHandoffRepository_Consume(t *testing.T) {
+	columns := []string{"id", "user_id", "code_hash", "ip_address", "user_agent", "expires_at", "created_at"}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		wantErr error
+	}{
+		{
+			name: "unexpired handoff",
+			rows: sqlmock.NewRows(columns).AddRow("handoff-1", "user-1", "hash", "203.0.113.7", "phone", now.Add(time.Minute), now),
+		},
+		{
+			name:    "unknown, expired or used handoff",
+			rows:    sqlmock.NewRows(columns),
+			wantErr: domain.ErrSessionHandoffNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM session_handoffs
+		WHERE code_hash = $1 AND expires_at > $2`)).
+				WithArgs("hash", now).
+				WillReturnRows(tt.rows)
+
+			handoff, err := NewSessionHandoffRepository(db).Consume(context.Background(), "hash", now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Consume() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && handoff.UserID != "user-1" {
+				t.Errorf("UserID = %q, want user-1", handoff.UserID)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -62,8 +62,8 @@ func CreateTestUser(t *testing.T, db *sql.DB, email, passwordHash string) string
 
 	var userID string
 	query := `
-		INSERT INTO users (id, email, password_hash, email_verified, created_at, updated_at)
-		VALUES (gen_random_uuid(), $1, $2, false, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO users (id, email, normalized_email, password_hash, email_verified, created_at, updated_at)
+		VALUES (gen_random_uuid(), $1, lower($1), $2, false, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		RETURNING id`
 
 	err := db.QueryRow(query, email, passwordHash).Scan(&userID)
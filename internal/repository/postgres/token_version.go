@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// TokenVersionRepository implements repository.TokenVersionRepository using
+// the users.token_version column
+type TokenVersionRepository struct {
+	db DBTX
+}
+
+// NewTokenVersionRepository creates a new PostgreSQL token version repository
+func NewTokenVersionRepository(db DBTX) *TokenVersionRepository {
+	return &TokenVersionRepository{db: db}
+}
+
+// Get returns the user's current token version
+func (r *TokenVersionRepository) Get(ctx context.Context, userID string) (int, error) {
+	query := `SELECT token_version FROM users WHERE id = $1`
+
+	var version int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.ErrUserNotFound
+		}
+		return 0, fmt.Errorf("failed to get token version: %w", err)
+	}
+	return version, nil
+}
+
+// Increment bumps the user's token version in a single statement, so
+// concurrent bumps are never lost
+func (r *TokenVersionRepository) Increment(ctx context.Context, userID string) (int, error) {
+	query := `UPDATE users SET token_version = token_version + 1 WHERE id = $1 RETURNING token_version`
+
+	var version int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.ErrUserNotFound
+		}
+		return 0, fmt.Errorf("failed to increment token version: %w", err)
+	}
+	return version, nil
+}
+
+// Ensure TokenVersionRepository implements repository.TokenVersionRepository
+var _ repository.TokenVersionRepository = (*TokenVersionRepository)(nil)
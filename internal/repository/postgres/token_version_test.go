@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestTokenVersionRepository_Increment(t *testing.T) {
+	query := regexp.QuoteMeta(`UPDATE users SET token_version = token_version + 1 WHERE id = $1 RETURNING token_version`)
+
+	tests := []struct {
+		name        string
+		setupMock   func(sqlmock.Sqlmock)
+		wantVersion int
+		wantErr     error
+	}{
+		{
+			name: "existing user",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"token_version"}).AddRow(4))
+			},
+			wantVersion: 4,
+		},
+		{
+			name: "unknown user",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"token_version"}))
+			},
+			wantErr: domain.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			version, err := NewTokenVersionRepository(db).Increment(context.Background(), "user-1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Increment() error = %v, want %v", err, tt.wantErr)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("Increment() = %d, want %d", version, tt.wantVersion)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
@@ -22,6 +23,15 @@ type UserRepository struct {
 	db DBTX
 }
 
+// userColumns lists the users columns in the order scanUser reads them
+const userColumns = `
+	id, email, normalized_email, username, password_hash, email_verified,
+	email_verification_token, email_verification_expires_at,
+	password_reset_token, password_reset_expires_at,
+	display_name, locale, timezone, avatar_url,
+	phone_number, phone_verified, sms_second_factor,
+	disabled_at, metadata, app_metadata, created_at, updated_at`
+
 // DBTX interface allows the repository to work with both *sql.DB and *sql.Tx
 type DBTX interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
@@ -39,24 +49,43 @@ func NewUserRepository(db DBTX) *UserRepository {
 func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 	query := `
 		INSERT INTO users (
-			id, email, password_hash, email_verified,
+			id, email, normalized_email, username, password_hash, email_verified,
 			email_verification_token, email_verification_expires_at,
 			password_reset_token, password_reset_expires_at,
+			display_name, locale, timezone, avatar_url,
+			phone_number, phone_verified, sms_second_factor,
 			created_at, updated_at
 		) VALUES (
-			gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, $9
+			COALESCE($1::uuid, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
 		) RETURNING id`
 
+	// Services that generate ids before insert set user.ID; otherwise the
+	// database assigns a random UUID
+	var id *string
+	if user.ID != "" {
+		id = &user.ID
+	}
+
 	err := r.db.QueryRowContext(
 		ctx,
 		query,
+		id,
 		user.Email,
+		normalizedEmail(user),
+		user.Username,
 		user.PasswordHash,
 		user.EmailVerified,
 		user.EmailVerificationToken,
 		user.EmailVerificationExpiresAt,
 		user.PasswordResetToken,
 		user.PasswordResetExpiresAt,
+		user.DisplayName,
+		user.Locale,
+		user.Timezone,
+		user.AvatarURL,
+		user.PhoneNumber,
+		user.PhoneVerified,
+		user.SMSSecondFactor,
 		user.CreatedAt,
 		user.UpdatedAt,
 	).Scan(&user.ID)
@@ -64,7 +93,7 @@ func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok {
 			if pgErr.Code == uniqueViolationCode {
-				return domain.ErrDuplicateEmail
+				return duplicateError(pgErr)
 			}
 		}
 		return fmt.Errorf("failed to create user: %w", err)
@@ -75,29 +104,9 @@ func (r *UserRepository) Create(ctx context.Context, user *domain.User) error {
 
 // GetByID retrieves a user by their ID
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
-	user := &domain.User{}
-	query := `
-		SELECT 
-			id, email, password_hash, email_verified,
-			email_verification_token, email_verification_expires_at,
-			password_reset_token, password_reset_expires_at,
-			created_at, updated_at
-		FROM users
-		WHERE id = $1`
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.EmailVerified,
-		&user.EmailVerificationToken,
-		&user.EmailVerificationExpiresAt,
-		&user.PasswordResetToken,
-		&user.PasswordResetExpiresAt,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrUserNotFound
@@ -108,31 +117,41 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*domain.User,
 	return user, nil
 }
 
+// GetByIDs retrieves the users with the given IDs in one query
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ANY($1)`
+
+	rows, err := r.db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users by ids: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
 // GetByEmail retrieves a user by their email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
-	user := &domain.User{}
-	query := `
-		SELECT 
-			id, email, password_hash, email_verified,
-			email_verification_token, email_verification_expires_at,
-			password_reset_token, password_reset_expires_at,
-			created_at, updated_at
-		FROM users
-		WHERE email = $1`
-
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.EmailVerified,
-		&user.EmailVerificationToken,
-		&user.EmailVerificationExpiresAt,
-		&user.PasswordResetToken,
-		&user.PasswordResetExpiresAt,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	query := `SELECT ` + userColumns + ` FROM users WHERE normalized_email = $1`
 
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, email))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrUserNotFound
@@ -143,18 +162,42 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.
 	return user, nil
 }
 
+// GetByUsername retrieves a user by their username
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	}
+
+	return user, nil
+}
+
 // Update updates a user in the database
 func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 	query := `
 		UPDATE users SET
 			email = $2,
-			password_hash = $3,
-			email_verified = $4,
-			email_verification_token = $5,
-			email_verification_expires_at = $6,
-			password_reset_token = $7,
-			password_reset_expires_at = $8,
-			updated_at = $9
+			normalized_email = $3,
+			username = $4,
+			password_hash = $5,
+			email_verified = $6,
+			email_verification_token = $7,
+			email_verification_expires_at = $8,
+			password_reset_token = $9,
+			password_reset_expires_at = $10,
+			display_name = $11,
+			locale = $12,
+			timezone = $13,
+			avatar_url = $14,
+			phone_number = $15,
+			phone_verified = $16,
+			sms_second_factor = $17,
+			updated_at = $18
 		WHERE id = $1`
 
 	result, err := r.db.ExecContext(
@@ -162,19 +205,28 @@ func (r *UserRepository) Update(ctx context.Context, user *domain.User) error {
 		query,
 		user.ID,
 		user.Email,
+		normalizedEmail(user),
+		user.Username,
 		user.PasswordHash,
 		user.EmailVerified,
 		user.EmailVerificationToken,
 		user.EmailVerificationExpiresAt,
 		user.PasswordResetToken,
 		user.PasswordResetExpiresAt,
+		user.DisplayName,
+		user.Locale,
+		user.Timezone,
+		user.AvatarURL,
+		user.PhoneNumber,
+		user.PhoneVerified,
+		user.SMSSecondFactor,
 		time.Now(),
 	)
 
 	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok {
 			if pgErr.Code == uniqueViolationCode {
-				return domain.ErrDuplicateEmail
+				return duplicateError(pgErr)
 			}
 		}
 		return fmt.Errorf("failed to update user: %w", err)
@@ -216,7 +268,7 @@ func (r *UserRepository) Delete(ctx context.Context, id string) error {
 // ExistsByEmail checks if a user exists with the given email
 func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE normalized_email = $1)`
 
 	err := r.db.QueryRowContext(ctx, query, email).Scan(&exists)
 	if err != nil {
@@ -226,5 +278,76 @@ func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool,
 	return exists, nil
 }
 
+// ExistsByUsername checks if a user exists with the given username
+func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)`
+
+	err := r.db.QueryRowContext(ctx, query, username).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if user exists: %w", err)
+	}
+
+	return exists, nil
+}
+
+// scanUser reads a row selected with userColumns
+func scanUser(row rowScanner) (*domain.User, error) {
+	user := &domain.User{}
+	var metadata, appMetadata []byte
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.NormalizedEmail,
+		&user.Username,
+		&user.PasswordHash,
+		&user.EmailVerified,
+		&user.EmailVerificationToken,
+		&user.EmailVerificationExpiresAt,
+		&user.PasswordResetToken,
+		&user.PasswordResetExpiresAt,
+		&user.DisplayName,
+		&user.Locale,
+		&user.Timezone,
+		&user.AvatarURL,
+		&user.PhoneNumber,
+		&user.PhoneVerified,
+		&user.SMSSecondFactor,
+		&user.DisabledAt,
+		&metadata,
+		&appMetadata,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if user.Metadata, err = decodeMetadata(metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	if user.AppMetadata, err = decodeMetadata(appMetadata); err != nil {
+		return nil, fmt.Errorf("invalid app_metadata: %w", err)
+	}
+	return user, nil
+}
+
+// duplicateError maps a unique violation to the domain error for the
+// column that collided
+func duplicateError(pgErr *pgconn.PgError) error {
+	if pgErr.ConstraintName == "idx_users_username" {
+		return domain.ErrDuplicateUsername
+	}
+	return domain.ErrDuplicateEmail
+}
+
+// normalizedEmail returns the user's normalized email, falling back to the
+// lowercased address for users built without one
+func normalizedEmail(user *domain.User) string {
+	if user.NormalizedEmail != "" {
+		return user.NormalizedEmail
+	}
+	return strings.ToLower(strings.TrimSpace(user.Email))
+}
+
 // Ensure UserRepository implements repository.UserRepository
 var _ repository.UserRepository = (*UserRepository)(nil)
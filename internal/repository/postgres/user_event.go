@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// UserEventRepository implements repository.UserEventRepository for
+// PostgreSQL
+type UserEventRepository struct {
+	db DBTX
+}
+
+// NewUserEventRepository creates a new PostgreSQL user event repository
+func NewUserEventRepository(db DBTX) *UserEventRepository {
+	return &UserEventRepository{db: db}
+}
+
+// Create stores an event
+func (r *UserEventRepository) Create(ctx context.Context, event *domain.UserEvent) error {
+	data := event.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode user event data: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_events (type, user_id, email, data)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, occurred_at`
+
+	err = r.db.QueryRowContext(ctx, query,
+		event.Type, event.UserID, event.Email, dataJSON,
+	).Scan(&event.ID, &event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user event: %w", err)
+	}
+	return nil
+}
+
+// List returns events of a type in a time range, in ID order
+func (r *UserEventRepository) List(ctx context.Context, eventType string, from, to time.Time, afterID int64, limit int) ([]*domain.UserEvent, error) {
+	query := `
+		SELECT id, type, user_id, email, data, occurred_at
+		FROM user_events
+		WHERE type = $1 AND id > $2
+			AND ($3::timestamptz IS NULL OR occurred_at >= $3)
+			AND ($4::timestamptz IS NULL OR occurred_at < $4)
+		ORDER BY id
+		LIMIT $5`
+
+	rows, err := r.db.QueryContext(ctx, query, eventType, afterID, nullTime(from), nullTime(to), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*domain.UserEvent
+	for rows.Next() {
+		event := &domain.UserEvent{}
+		var dataJSON []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.UserID, &event.Email, &dataJSON, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user event: %w", err)
+		}
+		if err := json.Unmarshal(dataJSON, &event.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode user event data: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user events: %w", err)
+	}
+
+	return events, nil
+}
+
+// nullTime maps the zero time to NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Ensure UserEventRepository implements repository.UserEventRepository
+var _ repository.UserEventRepository = (*UserEventRepository)(nil)
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestUserEventRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO user_events (type, user_id, email, data)`)).
+		WithArgs(domain.UserEventEmailVerified, "user-1", "user@example.com", []byte(`{"method":"link"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "occurred_at"}).AddRow(int64(7), now))
+
+	event := &domain.UserEvent{
+		Type:   domain.UserEventEmailVerified,
+		UserID: "user-1",
+		Email:  "user@example.com",
+		Data:   map[string]interface{}{"method": domain.EmailVerificationLink},
+	}
+	if err := NewUserEventRepository(db).Create(context.Background(), event); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if event.ID != 7 || !event.OccurredAt.Equal(now) {
+		t.Errorf("Create() did not set ID and OccurredAt: %+v", event)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUserEventRepository_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "type", "user_id", "email", "data", "occurred_at"}).
+		AddRow(int64(8), domain.UserEventEmailVerified, "user-1", "user@example.com", []byte(`{"method":"code"}`), from.Add(time.Hour))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM user_events`)).
+		WithArgs(domain.UserEventEmailVerified, int64(7), &from, nil, 100).
+		WillReturnRows(rows)
+
+	events, err := NewUserEventRepository(db).List(context.Background(), domain.UserEventEmailVerified, from, time.Time{}, 7, 100)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events) != 1 || events[0].ID != 8 || events[0].Data["method"] != domain.EmailVerificationCode {
+		t.Errorf("List() = %+v, want event 8 verified by code", events)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
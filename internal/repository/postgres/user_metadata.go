@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// metadataColumn returns the users column holding metadata of the kind
+func metadataColumn(kind domain.MetadataKind) (string, error) {
+	switch kind {
+	case domain.MetadataKindUser:
+		return "metadata", nil
+	case domain.MetadataKindApp:
+		return "app_metadata", nil
+	default:
+		return "", fmt.Errorf("unknown metadata kind %q", kind)
+	}
+}
+
+// decodeMetadata reads a metadata column, treating NULL as empty
+func decodeMetadata(data []byte) (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+	if len(data) == 0 {
+		return metadata, nil
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	return metadata, nil
+}
+
+// PatchMetadata applies a metadata patch in one statement, so concurrent
+// patches of different keys both apply. The size limit is checked on the
+// patched value as PostgreSQL renders it.
+func (r *UserRepository) PatchMetadata(ctx context.Context, userID string, kind domain.MetadataKind, patch domain.MetadataPatch, maxBytes int) (map[string]interface{}, error) {
+	column, err := metadataColumn(kind)
+	if err != nil {
+		return nil, err
+	}
+	set, err := json.Marshal(patch.Set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", column, err)
+	}
+	remove := patch.Remove
+	if remove == nil {
+		remove = []string{}
+	}
+
+	patched := `((` + column + ` || $2::jsonb) - $3::text[])`
+	query := `
+		UPDATE users SET ` + column + ` = ` + patched + `, updated_at = $4
+		WHERE id = $1 AND octet_length(` + patched + `::text) <= $5
+		RETURNING ` + column
+
+	var data []byte
+	err = r.db.QueryRowContext(ctx, query, userID, string(set), remove, time.Now(), maxBytes).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Either the user is gone or the result is too large
+		exists, existsErr := r.exists(ctx, userID)
+		if existsErr != nil {
+			return nil, existsErr
+		}
+		if !exists {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, domain.ErrMetadataTooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", column, err)
+	}
+
+	metadata, err := decodeMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", column, err)
+	}
+	return metadata, nil
+}
+
+// exists reports whether the user exists
+func (r *UserRepository) exists(ctx context.Context, userID string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check if user exists: %w", err)
+	}
+	return exists, nil
+}
+
+// List returns users matching the filter, newest first. Metadata filters
+// compare the text value of each top-level key.
+func (r *UserRepository) List(ctx context.Context, filter repository.UserListFilter) ([]*domain.User, error) {
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	for _, f := range []struct {
+		column string
+		values map[string]string
+	}{
+		{"metadata", filter.Metadata},
+		{"app_metadata", filter.AppMetadata},
+	} {
+		// Sorted so the same filter always produces the same query
+		keys := make([]string, 0, len(f.values))
+		for key := range f.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			conditions = append(conditions, f.column+" ->> "+arg(key)+" = "+arg(f.values[key]))
+		}
+	}
+	if filter.Before != nil {
+		conditions = append(conditions, "(created_at, id) < ("+arg(filter.Before.CreatedAt)+", "+arg(filter.Before.ID)+")")
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ` + arg(filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
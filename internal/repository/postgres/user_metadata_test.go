@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+func TestUserRepository_PatchMetadata(t *testing.T) {
+	patch := domain.MetadataPatch{Set: map[string]interface{}{"plan": "pro"}, Remove: []string{"trial"}}
+
+	tests := []struct {
+		name      string
+		setupMock func(mock sqlmock.Sqlmock)
+		want      map[string]interface{}
+		wantErr   error
+	}{
+		{
+			name: "patched",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users SET app_metadata = ((app_metadata || $2::jsonb) - $3::text[])`)).
+					WithArgs("user-1", `{"plan":"pro"}`, stringsArg{"trial"}, sqlmock.AnyArg(), 100).
+					WillReturnRows(sqlmock.NewRows([]string{"app_metadata"}).AddRow([]byte(`{"plan": "pro", "seats": 3}`)))
+			},
+			want: map[string]interface{}{"plan": "pro", "seats": float64(3)},
+		},
+		{
+			name: "too large",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users SET app_metadata`)).WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`)).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			wantErr: domain.ErrMetadataTooLarge,
+		},
+		{
+			name: "user not found",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users SET app_metadata`)).WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`)).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			wantErr: domain.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+			tt.setupMock(mock)
+
+			got, err := NewUserRepository(db).PatchMetadata(context.Background(), "user-1", domain.MetadataKindApp, patch, 100)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchMetadata() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want != nil && (got["plan"] != tt.want["plan"] || got["seats"] != tt.want["seats"]) {
+				t.Errorf("PatchMetadata() = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestUserRepository_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	before := domain.AuditCursor{CreatedAt: time.Now(), ID: "01890a5d-ac96-774b-bcce-b302099a8057"}
+	rows := sqlmock.NewRows([]string{
+		"id", "email", "normalized_email", "username", "password_hash", "email_verified",
+		"email_verification_token", "email_verification_expires_at",
+		"password_reset_token", "password_reset_expires_at",
+		"display_name", "locale", "timezone", "avatar_url",
+		"phone_number", "phone_verified", "sms_second_factor",
+		"disabled_at", "metadata", "app_metadata", "created_at", "updated_at",
+	}).AddRow(
+		"user-1", "a@example.com", "a@example.com", nil, "hashed_password", true,
+		nil, nil, nil, nil,
+		nil, nil, nil, nil,
+		nil, false, false,
+		nil, []byte(`{"team":"blue"}`), nil, before.CreatedAt, before.CreatedAt,
+	)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE metadata ->> $1 = $2 AND app_metadata ->> $3 = $4 AND (created_at, id) < ($5, $6) ORDER BY created_at DESC, id DESC LIMIT $7`)).
+		WithArgs("team", "blue", "plan", "pro", before.CreatedAt, before.ID, 11).
+		WillReturnRows(rows)
+
+	users, err := NewUserRepository(db).List(context.Background(), repository.UserListFilter{
+		Metadata:    map[string]string{"team": "blue"},
+		AppMetadata: map[string]string{"plan": "pro"},
+		Before:      &before,
+		Limit:       11,
+	})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(users) != 1 || users[0].Metadata["team"] != "blue" || users[0].AppMetadata == nil {
+		t.Errorf("List() = %+v", users)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -55,13 +55,61 @@ func TestUserRepository_Create(t *testing.T) {
 					AddRow("generated-uuid")
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users`)).
 					WithArgs(
+						nil,
+						"test@example.com",
+						"test@example.com",
+						nil,
+						"hashed_password",
+						false,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
+						fixedTime,
+						fixedTime,
+					).
+					WillReturnRows(rows)
+			},
+			wantErr: false,
+		},
+		{
+			name: "id generated by the service",
+			user: &domain.User{
+				ID:           "01890a5d-ac96-774b-bcce-b302099a8057",
+				Email:        "test@example.com",
+				PasswordHash: "hashed_password",
+				CreatedAt:    fixedTime,
+				UpdatedAt:    fixedTime,
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id"}).
+					AddRow("01890a5d-ac96-774b-bcce-b302099a8057")
+				mock.ExpectQuery(regexp.QuoteMeta(`COALESCE($1::uuid, gen_random_uuid())`)).
+					WithArgs(
+						"01890a5d-ac96-774b-bcce-b302099a8057",
 						"test@example.com",
+						"test@example.com",
+						nil,
 						"hashed_password",
 						false,
 						nil,
 						nil,
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						fixedTime,
 						fixedTime,
 					).
@@ -81,13 +129,23 @@ func TestUserRepository_Create(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users`)).
 					WithArgs(
+						nil,
 						"existing@example.com",
+						"existing@example.com",
+						nil,
 						"hashed_password",
 						false,
 						nil,
 						nil,
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						fixedTime,
 						fixedTime,
 					).
@@ -110,13 +168,23 @@ func TestUserRepository_Create(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users`)).
 					WithArgs(
+						nil,
 						"test@example.com",
+						"test@example.com",
+						nil,
 						"hashed_password",
 						false,
 						nil,
 						nil,
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						fixedTime,
 						fixedTime,
 					).
@@ -140,13 +208,102 @@ func TestUserRepository_Create(t *testing.T) {
 					AddRow("generated-uuid")
 				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users`)).
 					WithArgs(
+						nil,
+						"test@example.com",
 						"test@example.com",
+						nil,
 						"hashed_password",
 						false,
 						"verification-token",
 						fixedTime.Add(24*time.Hour),
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
+						fixedTime,
+						fixedTime,
+					).
+					WillReturnRows(rows)
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate username error",
+			user: &domain.User{
+				Email:         "test@example.com",
+				Username:      stringPtr("taken"),
+				PasswordHash:  "hashed_password",
+				EmailVerified: false,
+				CreatedAt:     fixedTime,
+				UpdatedAt:     fixedTime,
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users`)).
+					WithArgs(
+						nil,
+						"test@example.com",
+						"test@example.com",
+						"taken",
+						"hashed_password",
+						false,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
+						fixedTime,
+						fixedTime,
+					).
+					WillReturnError(&pgconn.PgError{
+						Code:           uniqueViolationCode,
+						ConstraintName: "idx_users_username",
+					})
+			},
+			wantErr: true,
+			errType: domain.ErrDuplicateUsername,
+		},
+		{
+			name: "with normalized email",
+			user: &domain.User{
+				Email:           "john.doe+news@gmail.com",
+				NormalizedEmail: "johndoe@gmail.com",
+				PasswordHash:    "hashed_password",
+				CreatedAt:       fixedTime,
+				UpdatedAt:       fixedTime,
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id"}).
+					AddRow("generated-uuid")
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users`)).
+					WithArgs(
+						nil,
+						"john.doe+news@gmail.com",
+						"johndoe@gmail.com",
+						nil,
+						"hashed_password",
+						false,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						fixedTime,
 						fixedTime,
 					).
@@ -205,26 +362,33 @@ func TestUserRepository_GetByID(t *testing.T) {
 			userID: "user-123",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
-					"id", "email", "password_hash", "email_verified",
+					"id", "email", "normalized_email", "username", "password_hash", "email_verified",
 					"email_verification_token", "email_verification_expires_at",
 					"password_reset_token", "password_reset_expires_at",
-					"created_at", "updated_at",
+					"display_name", "locale", "timezone", "avatar_url",
+					"phone_number", "phone_verified", "sms_second_factor",
+					"disabled_at", "metadata", "app_metadata", "created_at", "updated_at",
 				}).AddRow(
-					"user-123", "test@example.com", "hashed_password", true,
+					"user-123", "test@example.com", "test@example.com", nil, "hashed_password", true,
 					nil, nil, nil, nil,
-					fixedTime, fixedTime,
+					nil, nil, nil, nil,
+					nil, false, false,
+					nil, []byte(`{}`), []byte(`{}`), fixedTime, fixedTime,
 				)
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password_hash`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, normalized_email, username, password_hash`)).
 					WithArgs("user-123").
 					WillReturnRows(rows)
 			},
 			want: &domain.User{
-				ID:            "user-123",
-				Email:         "test@example.com",
-				PasswordHash:  "hashed_password",
-				EmailVerified: true,
-				CreatedAt:     fixedTime,
-				UpdatedAt:     fixedTime,
+				ID:              "user-123",
+				Email:           "test@example.com",
+				NormalizedEmail: "test@example.com",
+				PasswordHash:    "hashed_password",
+				EmailVerified:   true,
+				Metadata:        map[string]interface{}{},
+				AppMetadata:     map[string]interface{}{},
+				CreatedAt:       fixedTime,
+				UpdatedAt:       fixedTime,
 			},
 			wantErr: false,
 		},
@@ -232,7 +396,7 @@ func TestUserRepository_GetByID(t *testing.T) {
 			name:   "user not found",
 			userID: "non-existent",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password_hash`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, normalized_email, username, password_hash`)).
 					WithArgs("non-existent").
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -243,7 +407,7 @@ func TestUserRepository_GetByID(t *testing.T) {
 			name:   "database error",
 			userID: "user-123",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password_hash`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, normalized_email, username, password_hash`)).
 					WithArgs("user-123").
 					WillReturnError(errors.New("database error"))
 			},
@@ -302,26 +466,33 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 			email: "test@example.com",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
-					"id", "email", "password_hash", "email_verified",
+					"id", "email", "normalized_email", "username", "password_hash", "email_verified",
 					"email_verification_token", "email_verification_expires_at",
 					"password_reset_token", "password_reset_expires_at",
-					"created_at", "updated_at",
+					"display_name", "locale", "timezone", "avatar_url",
+					"phone_number", "phone_verified", "sms_second_factor",
+					"disabled_at", "metadata", "app_metadata", "created_at", "updated_at",
 				}).AddRow(
-					"user-123", "test@example.com", "hashed_password", true,
+					"user-123", "test@example.com", "test@example.com", nil, "hashed_password", true,
 					nil, nil, nil, nil,
-					fixedTime, fixedTime,
+					nil, nil, nil, nil,
+					nil, false, false,
+					nil, []byte(`{}`), []byte(`{}`), fixedTime, fixedTime,
 				)
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password_hash`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, normalized_email, username, password_hash`)).
 					WithArgs("test@example.com").
 					WillReturnRows(rows)
 			},
 			want: &domain.User{
-				ID:            "user-123",
-				Email:         "test@example.com",
-				PasswordHash:  "hashed_password",
-				EmailVerified: true,
-				CreatedAt:     fixedTime,
-				UpdatedAt:     fixedTime,
+				ID:              "user-123",
+				Email:           "test@example.com",
+				NormalizedEmail: "test@example.com",
+				PasswordHash:    "hashed_password",
+				EmailVerified:   true,
+				Metadata:        map[string]interface{}{},
+				AppMetadata:     map[string]interface{}{},
+				CreatedAt:       fixedTime,
+				UpdatedAt:       fixedTime,
 			},
 			wantErr: false,
 		},
@@ -329,7 +500,7 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 			name:  "user not found",
 			email: "nonexistent@example.com",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password_hash`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, normalized_email, username, password_hash`)).
 					WithArgs("nonexistent@example.com").
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -340,7 +511,7 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 			name:  "database error",
 			email: "test@example.com",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, password_hash`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, normalized_email, username, password_hash`)).
 					WithArgs("test@example.com").
 					WillReturnError(errors.New("database error"))
 			},
@@ -383,6 +554,65 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 	}
 }
 
+func TestUserRepository_GetByUsername(t *testing.T) {
+	fixedTime := time.Now()
+
+	t.Run("successful retrieval", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating mock database: %v", err)
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows([]string{
+			"id", "email", "normalized_email", "username", "password_hash", "email_verified",
+			"email_verification_token", "email_verification_expires_at",
+			"password_reset_token", "password_reset_expires_at",
+			"display_name", "locale", "timezone", "avatar_url",
+			"phone_number", "phone_verified", "sms_second_factor",
+			"disabled_at", "metadata", "app_metadata", "created_at", "updated_at",
+		}).AddRow(
+			"user-123", "test@example.com", "test@example.com", "jane_doe", "hashed_password", true,
+			nil, nil, nil, nil,
+			nil, nil, nil, nil,
+			nil, false, false,
+			nil, []byte(`{}`), []byte(`{}`), fixedTime, fixedTime,
+		)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, normalized_email, username, password_hash`)).
+			WithArgs("jane_doe").
+			WillReturnRows(rows)
+
+		repo := &UserRepository{db: db}
+		user, err := repo.GetByUsername(context.Background(), "jane_doe")
+		if err != nil {
+			t.Fatalf("GetByUsername() error = %v", err)
+		}
+		if user.Username == nil || *user.Username != "jane_doe" {
+			t.Errorf("GetByUsername() username = %v, want jane_doe", user.Username)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating mock database: %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email, normalized_email, username, password_hash`)).
+			WithArgs("nobody").
+			WillReturnError(sql.ErrNoRows)
+
+		repo := &UserRepository{db: db}
+		if _, err := repo.GetByUsername(context.Background(), "nobody"); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("GetByUsername() error = %v, want %v", err, domain.ErrUserNotFound)
+		}
+	})
+}
+
 func TestUserRepository_Update(t *testing.T) {
 
 	tests := []struct {
@@ -405,12 +635,21 @@ func TestUserRepository_Update(t *testing.T) {
 					WithArgs(
 						"user-123",
 						"updated@example.com",
+						"updated@example.com",
+						nil,
 						"new_hash",
 						true,
 						nil,
 						nil,
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						sqlmock.AnyArg(), // updated_at
 					).
 					WillReturnResult(sqlmock.NewResult(0, 1))
@@ -429,12 +668,21 @@ func TestUserRepository_Update(t *testing.T) {
 					WithArgs(
 						"non-existent",
 						"test@example.com",
+						"test@example.com",
+						nil,
 						"hash",
 						false,
 						nil,
 						nil,
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						sqlmock.AnyArg(),
 					).
 					WillReturnResult(sqlmock.NewResult(0, 0))
@@ -454,12 +702,21 @@ func TestUserRepository_Update(t *testing.T) {
 					WithArgs(
 						"user-123",
 						"existing@example.com",
+						"existing@example.com",
+						nil,
 						"hash",
 						false,
 						nil,
 						nil,
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						sqlmock.AnyArg(),
 					).
 					WillReturnError(&pgconn.PgError{
@@ -481,12 +738,21 @@ func TestUserRepository_Update(t *testing.T) {
 					WithArgs(
 						"user-rows",
 						"test@example.com",
+						"test@example.com",
+						nil,
 						"hash",
 						false,
 						nil,
 						nil,
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						sqlmock.AnyArg(),
 					).
 					WillReturnResult(sqlmock.NewErrorResult(errors.New("rows affected error")))
@@ -505,12 +771,21 @@ func TestUserRepository_Update(t *testing.T) {
 					WithArgs(
 						"user-123",
 						"test@example.com",
+						"test@example.com",
+						nil,
 						"hash",
 						false,
 						nil,
 						nil,
 						nil,
 						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						nil,
+						false,
+						false,
 						sqlmock.AnyArg(),
 					).
 					WillReturnError(errors.New("database error"))
@@ -641,7 +916,7 @@ func TestUserRepository_ExistsByEmail(t *testing.T) {
 			email: "existing@example.com",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"exists"}).AddRow(true)
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE normalized_email = $1)`)).
 					WithArgs("existing@example.com").
 					WillReturnRows(rows)
 			},
@@ -653,7 +928,7 @@ func TestUserRepository_ExistsByEmail(t *testing.T) {
 			email: "nonexistent@example.com",
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"exists"}).AddRow(false)
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE normalized_email = $1)`)).
 					WithArgs("nonexistent@example.com").
 					WillReturnRows(rows)
 			},
@@ -664,7 +939,7 @@ func TestUserRepository_ExistsByEmail(t *testing.T) {
 			name:  "database error",
 			email: "test@example.com",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE normalized_email = $1)`)).
 					WithArgs("test@example.com").
 					WillReturnError(errors.New("database error"))
 			},
@@ -701,3 +976,77 @@ func TestUserRepository_ExistsByEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRepository_ExistsByUsername(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"exists"}).AddRow(true)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)`)).
+		WithArgs("jane_doe").
+		WillReturnRows(rows)
+
+	repo := &UserRepository{db: db}
+	exists, err := repo.ExistsByUsername(context.Background(), "jane_doe")
+	if err != nil {
+		t.Fatalf("ExistsByUsername() error = %v", err)
+	}
+	if !exists {
+		t.Error("ExistsByUsername() = false, want true")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUserRepository_GetByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	if err != nil {
+		t.Fatalf("error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	fixedTime := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "email", "normalized_email", "username", "password_hash", "email_verified",
+		"email_verification_token", "email_verification_expires_at",
+		"password_reset_token", "password_reset_expires_at",
+		"display_name", "locale", "timezone", "avatar_url",
+		"phone_number", "phone_verified", "sms_second_factor",
+		"disabled_at", "metadata", "app_metadata", "created_at", "updated_at",
+	})
+	for _, id := range []string{"user-1", "user-2"} {
+		rows.AddRow(
+			id, id+"@example.com", id+"@example.com", nil, "hashed_password", true,
+			nil, nil, nil, nil,
+			nil, nil, nil, nil,
+			nil, false, false,
+			nil, []byte(`{}`), []byte(`{}`), fixedTime, fixedTime,
+		)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE id = ANY($1)`)).
+		WithArgs(stringsArg{"user-1", "user-2", "user-3"}).
+		WillReturnRows(rows)
+
+	repo := &UserRepository{db: db}
+	users, err := repo.GetByIDs(context.Background(), []string{"user-1", "user-2", "user-3"})
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	if len(users) != 2 || users[0].ID != "user-1" || users[1].Email != "user-2@example.com" {
+		t.Errorf("GetByIDs() = %v, want user-1 and user-2", users)
+	}
+
+	// No IDs need no query
+	users, err = repo.GetByIDs(context.Background(), nil)
+	if err != nil || len(users) != 0 {
+		t.Errorf("GetByIDs(nil) = %v, %v, want no users", users, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %s", err)
+	}
+}
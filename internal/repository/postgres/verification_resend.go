@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// VerificationResendRepository implements repository.VerificationResendRepository for PostgreSQL
+type VerificationResendRepository struct {
+	db DBTX
+}
+
+// NewVerificationResendRepository creates a new PostgreSQL verification resend repository
+func NewVerificationResendRepository(db DBTX) *VerificationResendRepository {
+	return &VerificationResendRepository{db: db}
+}
+
+// Record counts a resend in one statement, so concurrent resends cannot
+// both pass the cooldown or the daily limit
+func (r *VerificationResendRepository) Record(ctx context.Context, userID string, now time.Time, cooldown time.Duration, dailyLimit int) (*domain.VerificationResend, error) {
+	query := `
+		INSERT INTO verification_resends (user_id, day, count, last_sent_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			count = CASE WHEN verification_resends.day = EXCLUDED.day THEN verification_resends.count + 1 ELSE 1 END,
+			day = EXCLUDED.day,
+			last_sent_at = EXCLUDED.last_sent_at
+		WHERE verification_resends.last_sent_at <= $4
+			AND ($5 = 0 OR verification_resends.day <> EXCLUDED.day OR verification_resends.count < $5)
+		RETURNING day, count, last_sent_at`
+
+	resend := &domain.VerificationResend{UserID: userID}
+	err := r.db.QueryRowContext(ctx, query, userID, domain.ResendDay(now), now, now.Add(-cooldown), dailyLimit).
+		Scan(&resend.Day, &resend.Count, &resend.LastSentAt)
+	if err == nil {
+		return resend, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to record verification resend: %w", err)
+	}
+
+	// The conflicting row was kept; return it so the caller can tell when
+	// the next resend is allowed
+	query = `SELECT day, count, last_sent_at FROM verification_resends WHERE user_id = $1`
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&resend.Day, &resend.Count, &resend.LastSentAt); err != nil {
+		return nil, fmt.Errorf("failed to get verification resends: %w", err)
+	}
+	return resend, domain.ErrResendTooSoon
+}
+
+// Ensure VerificationResendRepository implements repository.VerificationResendRepository
+var _ repository.VerificationResendRepository = (*VerificationResendRepository)(nil)
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestVerificationResendRepository_Record(t *testing.T) {
+	columns := []string{"day", "count", "last_sent_at"}
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	day := domain.ResendDay(now)
+
+	tests := []struct {
+		name      string
+		setup     func(mock sqlmock.Sqlmock)
+		wantErr   error
+		wantCount int
+	}{
+		{
+			name: "resend counted",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO verification_resends`)).
+					WithArgs("user-1", day, now, now.Add(-time.Minute), 5).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(day, 2, now))
+			},
+			wantCount: 2,
+		},
+		{
+			name: "resend refused",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO verification_resends`)).
+					WithArgs("user-1", day, now, now.Add(-time.Minute), 5).
+					WillReturnRows(sqlmock.NewRows(columns))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT day, count, last_sent_at FROM verification_resends WHERE user_id = $1`)).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(day, 5, now.Add(-time.Hour)))
+			},
+			wantErr:   domain.ErrResendTooSoon,
+			wantCount: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating mock database: %v", err)
+			}
+			defer db.Close()
+			tt.setup(mock)
+
+			resend, err := NewVerificationResendRepository(db).Record(context.Background(), "user-1", now, time.Minute, 5)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Record() error = %v, want %v", err, tt.wantErr)
+			}
+			if resend == nil || resend.Count != tt.wantCount {
+				t.Errorf("Record() = %+v, want count %d", resend, tt.wantCount)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
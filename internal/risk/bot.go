@@ -0,0 +1,256 @@
+package risk
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bot signal names reported in a bot Assessment
+const (
+	SignalMissingUserAgent      = "missing_user_agent"
+	SignalAutomationUserAgent   = "automation_user_agent"
+	SignalMissingAcceptLanguage = "missing_accept_language"
+	SignalHeaderAnomaly         = "header_anomaly"
+	SignalIPBurst               = "ip_burst"
+	SignalASNBurst              = "asn_burst"
+)
+
+// automationAgents are User-Agent fragments of HTTP libraries and headless
+// browsers; real users log in from browsers and mobile apps
+var automationAgents = []string{
+	"curl/", "wget/", "python-requests", "python-urllib", "aiohttp", "go-http-client",
+	"okhttp", "java/", "libwww-perl", "apache-httpclient", "node-fetch", "axios/",
+	"scrapy", "headlesschrome", "phantomjs",
+}
+
+// BotConfig holds the weights and burst limits used to score requests
+type BotConfig struct {
+	// Signal weights
+	MissingUserAgentWeight      int
+	AutomationUserAgentWeight   int
+	MissingAcceptLanguageWeight int
+	HeaderAnomalyWeight         int
+	IPBurstWeight               int
+	ASNBurstWeight              int
+
+	// Requests from one IP or one ASN within BurstWindow above which the
+	// burst signals fire; 0 disables that signal
+	BurstWindow       time.Duration
+	IPBurstLimit      int
+	ASNBurstLimit     int
+	MaxTrackedSources int // bounds the number of remembered IPs and ASNs
+
+	// HighRiskScore is the score at which traffic gets stricter policies
+	HighRiskScore int
+}
+
+// DefaultBotConfig returns default configuration
+func DefaultBotConfig() BotConfig {
+	return BotConfig{
+		MissingUserAgentWeight:      30,
+		AutomationUserAgentWeight:   30,
+		MissingAcceptLanguageWeight: 20,
+		HeaderAnomalyWeight:         20,
+		IPBurstWeight:               30,
+		ASNBurstWeight:              20,
+		BurstWindow:                 time.Minute,
+		IPBurstLimit:                30,
+		ASNBurstLimit:               300,
+		MaxTrackedSources:           100000,
+		HighRiskScore:               50,
+	}
+}
+
+// ASNLocator resolves an IP address to the autonomous system announcing it
+type ASNLocator interface {
+	// ASN returns false when the address cannot be resolved
+	ASN(ip string) (string, bool)
+}
+
+// BotRecorder receives bot assessments, typically for metrics per signal
+type BotRecorder interface {
+	RecordBotAssessment(score int, signals []string)
+}
+
+// burstCounter counts requests from one source in a fixed window
+type burstCounter struct {
+	start time.Time
+	count int
+}
+
+// BotDetector scores requests for signs of bots and credential stuffing.
+// Scores only tag traffic; the risk engine and rate limiter decide what a
+// high score costs.
+type BotDetector struct {
+	config   BotConfig
+	logger   *slog.Logger
+	asn      ASNLocator
+	recorder BotRecorder
+	now      func() time.Time
+
+	mu        sync.Mutex
+	bursts    map[string]*burstCounter
+	lastSweep time.Time
+}
+
+// NewBotDetector creates a new bot detector
+func NewBotDetector(config BotConfig, logger *slog.Logger) *BotDetector {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if config.BurstWindow <= 0 {
+		config.BurstWindow = time.Minute
+	}
+	if config.MaxTrackedSources <= 0 {
+		config.MaxTrackedSources = 100000
+	}
+
+	return &BotDetector{
+		config: config,
+		logger: logger,
+		now:    time.Now,
+		bursts: make(map[string]*burstCounter),
+	}
+}
+
+// SetASNLocator enables the ASN burst signal
+func (d *BotDetector) SetASNLocator(asn ASNLocator) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.asn = asn
+}
+
+// SetRecorder sets where assessments are reported
+func (d *BotDetector) SetRecorder(recorder BotRecorder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recorder = recorder
+}
+
+// HighRiskScore returns the score at which traffic gets stricter policies
+func (d *BotDetector) HighRiskScore() int {
+	return d.config.HighRiskScore
+}
+
+// Inspect scores a request from ipAddress with the given headers. The
+// decision of the returned assessment is always DecisionAllow.
+func (d *BotDetector) Inspect(ipAddress string, header http.Header) Assessment {
+	var assessment Assessment
+	add := func(signal string, weight int) {
+		if weight <= 0 {
+			return
+		}
+		assessment.Score += weight
+		assessment.Signals = append(assessment.Signals, signal)
+	}
+
+	userAgent := header.Get("User-Agent")
+	switch {
+	case userAgent == "":
+		add(SignalMissingUserAgent, d.config.MissingUserAgentWeight)
+	case isAutomationAgent(userAgent):
+		add(SignalAutomationUserAgent, d.config.AutomationUserAgentWeight)
+	}
+	if header.Get("Accept-Language") == "" {
+		add(SignalMissingAcceptLanguage, d.config.MissingAcceptLanguageWeight)
+	}
+	// Browsers always send these; a browser User-Agent without them is
+	// usually a script pretending to be one
+	if strings.HasPrefix(userAgent, "Mozilla/") && (header.Get("Accept") == "" || header.Get("Accept-Encoding") == "") {
+		add(SignalHeaderAnomaly, d.config.HeaderAnomalyWeight)
+	}
+
+	d.mu.Lock()
+	now := d.now()
+	d.sweep(now)
+	if ipAddress != "" && d.burst("ip:"+ipAddress, now, d.config.IPBurstLimit) {
+		add(SignalIPBurst, d.config.IPBurstWeight)
+	}
+	if d.asn != nil {
+		if asn, ok := d.asn.ASN(ipAddress); ok && d.burst("asn:"+asn, now, d.config.ASNBurstLimit) {
+			add(SignalASNBurst, d.config.ASNBurstWeight)
+		}
+	}
+	recorder := d.recorder
+	d.mu.Unlock()
+
+	if recorder != nil {
+		recorder.RecordBotAssessment(assessment.Score, assessment.Signals)
+	}
+
+	if d.config.HighRiskScore > 0 && assessment.Score >= d.config.HighRiskScore {
+		d.logger.Warn("likely bot traffic",
+			"ip_address", ipAddress,
+			"score", assessment.Score,
+			"signals", strings.Join(assessment.Signals, ","),
+		)
+	}
+
+	return assessment
+}
+
+// burst counts a request from key and reports whether the source is over
+// limit in the current window; callers must hold d.mu
+func (d *BotDetector) burst(key string, now time.Time, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+
+	c, ok := d.bursts[key]
+	if !ok || now.Sub(c.start) >= d.config.BurstWindow {
+		if !ok && len(d.bursts) >= d.config.MaxTrackedSources {
+			// Too many sources to track; new ones are not counted until
+			// the next sweep frees room
+			return false
+		}
+		c = &burstCounter{start: now}
+		d.bursts[key] = c
+	}
+	c.count++
+	return c.count > limit
+}
+
+// sweep forgets sources whose window has ended, at most once per window;
+// callers must hold d.mu
+func (d *BotDetector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.config.BurstWindow {
+		return
+	}
+	for key, c := range d.bursts {
+		if now.Sub(c.start) >= d.config.BurstWindow {
+			delete(d.bursts, key)
+		}
+	}
+	d.lastSweep = now
+}
+
+// isAutomationAgent reports whether userAgent names an HTTP library or a
+// headless browser
+func isAutomationAgent(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, agent := range automationAgents {
+		if strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// botContextKey is the context key of a request's bot assessment
+type botContextKey struct{}
+
+// ContextWithBotAssessment returns a copy of ctx carrying the bot
+// assessment of the request
+func ContextWithBotAssessment(ctx context.Context, assessment Assessment) context.Context {
+	return context.WithValue(ctx, botContextKey{}, assessment)
+}
+
+// BotAssessmentFromContext returns the bot assessment stored in ctx
+func BotAssessmentFromContext(ctx context.Context) (Assessment, bool) {
+	assessment, ok := ctx.Value(botContextKey{}).(Assessment)
+	return assessment, ok
+}
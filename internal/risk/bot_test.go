@@ -0,0 +1,120 @@
+package risk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakeASN map[string]string
+
+func (a fakeASN) ASN(ip string) (string, bool) {
+	asn, ok := a[ip]
+	return asn, ok
+}
+
+type fakeBotRecorder struct {
+	signals []string
+}
+
+func (r *fakeBotRecorder) RecordBotAssessment(score int, signals []string) {
+	r.signals = append(r.signals, signals...)
+}
+
+// browserHeaders are the headers a browser sends with a login form
+func browserHeaders() http.Header {
+	h := http.Header{}
+	h.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 Chrome/126.0 Safari/537.36")
+	h.Set("Accept", "application/json")
+	h.Set("Accept-Encoding", "gzip, deflate, br")
+	h.Set("Accept-Language", "en-US,en;q=0.9")
+	return h
+}
+
+func TestBotDetector_HeaderSignals(t *testing.T) {
+	tests := []struct {
+		name   string
+		header func(h http.Header)
+		want   []string
+	}{
+		{"browser", func(h http.Header) {}, nil},
+		{"no user agent", func(h http.Header) { h.Del("User-Agent") }, []string{SignalMissingUserAgent}},
+		{"http library", func(h http.Header) { h.Set("User-Agent", "python-requests/2.32") }, []string{SignalAutomationUserAgent}},
+		{"no accept language", func(h http.Header) { h.Del("Accept-Language") }, []string{SignalMissingAcceptLanguage}},
+		{"browser without encoding", func(h http.Header) { h.Del("Accept-Encoding") }, []string{SignalHeaderAnomaly}},
+		{"bare curl", func(h http.Header) {
+			h.Set("User-Agent", "curl/8.5.0")
+			h.Del("Accept-Language")
+		}, []string{SignalAutomationUserAgent, SignalMissingAcceptLanguage}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewBotDetector(DefaultBotConfig(), nil)
+			h := browserHeaders()
+			tt.header(h)
+
+			a := d.Inspect("203.0.113.7", h)
+			if len(a.Signals) != len(tt.want) {
+				t.Fatalf("Inspect() signals = %v, want %v", a.Signals, tt.want)
+			}
+			for _, signal := range tt.want {
+				if !hasSignal(a, signal) {
+					t.Errorf("Inspect() signals = %v, want %v", a.Signals, tt.want)
+				}
+			}
+			if a.Decision != DecisionAllow {
+				t.Errorf("Inspect() decision = %v, want allow", a.Decision)
+			}
+		})
+	}
+}
+
+func TestBotDetector_Bursts(t *testing.T) {
+	config := DefaultBotConfig()
+	config.IPBurstLimit = 3
+	config.ASNBurstLimit = 5
+	d := NewBotDetector(config, nil)
+	d.SetASNLocator(fakeASN{"198.51.100.1": "AS64500", "198.51.100.2": "AS64500"})
+	recorder := &fakeBotRecorder{}
+	d.SetRecorder(recorder)
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if a := d.Inspect("198.51.100.1", browserHeaders()); a.Score != 0 {
+			t.Fatalf("request %d: Inspect() = %+v, want no signals", i+1, a)
+		}
+	}
+	if a := d.Inspect("198.51.100.1", browserHeaders()); !hasSignal(a, SignalIPBurst) || hasSignal(a, SignalASNBurst) {
+		t.Errorf("fourth request from one IP: signals = %v, want IP burst only", a.Signals)
+	}
+
+	// Spreading over addresses of one network still trips the ASN limit
+	d.Inspect("198.51.100.2", browserHeaders())
+	if a := d.Inspect("198.51.100.2", browserHeaders()); !hasSignal(a, SignalASNBurst) || hasSignal(a, SignalIPBurst) {
+		t.Errorf("sixth request from one ASN: signals = %v, want ASN burst only", a.Signals)
+	}
+	if len(recorder.signals) != 2 {
+		t.Errorf("recorded signals = %v", recorder.signals)
+	}
+
+	now = now.Add(config.BurstWindow)
+	if a := d.Inspect("198.51.100.1", browserHeaders()); a.Score != 0 {
+		t.Errorf("after the window: Inspect() = %+v, want no signals", a)
+	}
+	if len(d.bursts) != 2 {
+		t.Errorf("tracked sources = %d, want the ended windows swept", len(d.bursts))
+	}
+}
+
+func TestBotAssessmentContext(t *testing.T) {
+	if _, ok := BotAssessmentFromContext(context.Background()); ok {
+		t.Error("expected no assessment in an empty context")
+	}
+	ctx := ContextWithBotAssessment(context.Background(), Assessment{Score: 45})
+	if a, ok := BotAssessmentFromContext(ctx); !ok || a.Score != 45 {
+		t.Errorf("BotAssessmentFromContext() = %+v, %v", a, ok)
+	}
+}
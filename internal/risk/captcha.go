@@ -0,0 +1,61 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// SiteVerifier checks captcha solutions against a siteverify endpoint, the
+// protocol shared by reCAPTCHA, hCaptcha and Cloudflare Turnstile
+type SiteVerifier struct {
+	client *httpclient.Client
+	url    string
+	secret string
+}
+
+// NewSiteVerifier creates a verifier posting solutions to url with the
+// provider's secret key
+func NewSiteVerifier(client *httpclient.Client, url, secret string) *SiteVerifier {
+	return &SiteVerifier{client: client, url: url, secret: secret}
+}
+
+// siteVerifyResponse is the part of the provider's answer that matters
+type siteVerifyResponse struct {
+	Success bool `json:"success"`
+}
+
+// Verify reports whether token is a valid solution. remoteIP is passed on
+// when known, so providers can match it against the solver.
+func (v *SiteVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("failed to create captcha request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify captcha: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("captcha provider returned status %d", resp.StatusCode)
+	}
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode captcha response: %w", err)
+	}
+	return result.Success, nil
+}
@@ -0,0 +1,63 @@
+package risk
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+func TestSiteVerifier_Verify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("secret") != "site-secret" {
+			t.Errorf("secret = %q, want site-secret", r.PostForm.Get("secret"))
+		}
+		if r.PostForm.Get("remoteip") != "203.0.113.10" {
+			t.Errorf("remoteip = %q, want 203.0.113.10", r.PostForm.Get("remoteip"))
+		}
+		switch r.PostForm.Get("response") {
+		case "solved":
+			w.Write([]byte(`{"success": true}`))
+		case "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer server.Close()
+
+	config := httpclient.DefaultConfig()
+	config.MaxRetries = 0
+	client, err := httpclient.New(config, slog.Default())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	verifier := NewSiteVerifier(client, server.URL, "site-secret")
+
+	tests := []struct {
+		token   string
+		want    bool
+		wantErr bool
+	}{
+		{"solved", true, false},
+		{"wrong", false, false},
+		{"broken", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			ok, err := verifier.Verify(context.Background(), tt.token, "203.0.113.10")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.want {
+				t.Errorf("Verify() = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
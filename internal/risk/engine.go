@@ -0,0 +1,411 @@
+package risk
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decision is the action taken for a login attempt based on its score
+type Decision int
+
+const (
+	// DecisionAllow lets the attempt proceed without a challenge
+	DecisionAllow Decision = iota
+	// DecisionCaptcha requires a solved captcha before the attempt proceeds
+	DecisionCaptcha
+	// DecisionEmailConfirmation requires the user to confirm the login by email
+	DecisionEmailConfirmation
+	// DecisionBlock rejects the attempt outright
+	DecisionBlock
+	// DecisionExempt lets trusted automation proceed without scoring
+	DecisionExempt
+)
+
+// String returns a string representation of the decision
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllow:
+		return "allow"
+	case DecisionCaptcha:
+		return "captcha"
+	case DecisionEmailConfirmation:
+		return "email_confirmation"
+	case DecisionBlock:
+		return "block"
+	case DecisionExempt:
+		return "exempt"
+	default:
+		return "unknown"
+	}
+}
+
+// Signal names reported in an Assessment
+const (
+	SignalNewIP            = "new_ip"
+	SignalImpossibleTravel = "impossible_travel"
+	SignalRecentFailures   = "recent_failures"
+	SignalTorExitNode      = "tor_exit_node"
+	SignalBotTraffic       = "bot_traffic"
+)
+
+// Config holds the weights and thresholds used to score login attempts
+type Config struct {
+	// Signal weights
+	NewIPWeight            int
+	ImpossibleTravelWeight int
+	FailureWeight          int // added per recent failure, capped at MaxFailureScore
+	MaxFailureScore        int
+	TorExitWeight          int
+	MaxBotScore            int // caps the bot score added to an attempt
+
+	// FailureWindow is how far back failed attempts count towards the score
+	FailureWindow time.Duration
+	// MaxTravelSpeedKmh is the fastest plausible travel between two logins
+	MaxTravelSpeedKmh float64
+	// MaxKnownIPs bounds the number of remembered IPs per user
+	MaxKnownIPs int
+	// MaxTrackedFailures bounds the number of login identifiers with
+	// remembered failures, which clients choose freely
+	MaxTrackedFailures int
+
+	// Decision thresholds; a zero threshold disables that decision
+	CaptchaThreshold           int
+	EmailConfirmationThreshold int
+	BlockThreshold             int
+}
+
+// DefaultConfig returns default configuration
+func DefaultConfig() Config {
+	return Config{
+		NewIPWeight:                20,
+		ImpossibleTravelWeight:     50,
+		FailureWeight:              10,
+		MaxFailureScore:            50,
+		TorExitWeight:              40,
+		MaxBotScore:                50,
+		FailureWindow:              15 * time.Minute,
+		MaxTravelSpeedKmh:          1000,
+		MaxKnownIPs:                20,
+		MaxTrackedFailures:         100000,
+		CaptchaThreshold:           30,
+		EmailConfirmationThreshold: 60,
+		BlockThreshold:             90,
+	}
+}
+
+// Attempt describes a login attempt to be scored
+type Attempt struct {
+	UserID    string // empty when the account is unknown
+	Email     string
+	IPAddress string
+	UserAgent string
+	BotScore  int  // score from the BotDetector, 0 when the request was not inspected
+	Exempt    bool // trusted automation, see Exemptions
+	Time      time.Time
+}
+
+// Assessment is the result of scoring a login attempt
+type Assessment struct {
+	Score    int
+	Signals  []string
+	Decision Decision
+}
+
+// Recorder receives assessment outcomes, typically for metrics
+type Recorder interface {
+	RecordRiskAssessment(decision string, score int)
+}
+
+// userHistory is what the engine remembers about a user's successful logins
+type userHistory struct {
+	knownIPs     []string
+	lastLocation *Location
+	lastLoginAt  time.Time
+}
+
+// Engine scores login attempts and decides whether to challenge them
+type Engine struct {
+	config   Config
+	logger   *slog.Logger
+	geo      GeoLocator
+	recorder Recorder
+
+	mu        sync.Mutex
+	history   map[string]*userHistory
+	failures  map[string][]time.Time
+	lastSweep time.Time
+	torExits  map[string]struct{}
+}
+
+// NewEngine creates a new risk engine
+func NewEngine(config Config, logger *slog.Logger) *Engine {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if config.MaxKnownIPs <= 0 {
+		config.MaxKnownIPs = 20
+	}
+	if config.FailureWindow <= 0 {
+		config.FailureWindow = 15 * time.Minute
+	}
+	if config.MaxTrackedFailures <= 0 {
+		config.MaxTrackedFailures = 100000
+	}
+
+	return &Engine{
+		config:   config,
+		logger:   logger,
+		history:  make(map[string]*userHistory),
+		failures: make(map[string][]time.Time),
+		torExits: make(map[string]struct{}),
+	}
+}
+
+// SetGeoLocator enables the impossible travel signal
+func (e *Engine) SetGeoLocator(geo GeoLocator) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.geo = geo
+}
+
+// SetRecorder sets where assessment outcomes are reported
+func (e *Engine) SetRecorder(recorder Recorder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recorder = recorder
+}
+
+// SetTorExitNodes replaces the set of known TOR exit node addresses
+func (e *Engine) SetTorExitNodes(ips []string) {
+	exits := make(map[string]struct{}, len(ips))
+	for _, ip := range ips {
+		exits[ip] = struct{}{}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.torExits = exits
+}
+
+// Assess scores a login attempt and returns the resulting decision
+func (e *Engine) Assess(attempt Attempt) Assessment {
+	if attempt.Time.IsZero() {
+		attempt.Time = time.Now()
+	}
+
+	// Exempt attempts are reported so dashboards still see them
+	if attempt.Exempt {
+		e.mu.Lock()
+		recorder := e.recorder
+		e.mu.Unlock()
+		if recorder != nil {
+			recorder.RecordRiskAssessment(DecisionExempt.String(), 0)
+		}
+		return Assessment{Decision: DecisionExempt}
+	}
+
+	e.mu.Lock()
+	var assessment Assessment
+	add := func(signal string, weight int) {
+		if weight <= 0 {
+			return
+		}
+		assessment.Score += weight
+		assessment.Signals = append(assessment.Signals, signal)
+	}
+
+	if _, ok := e.torExits[attempt.IPAddress]; ok && attempt.IPAddress != "" {
+		add(SignalTorExitNode, e.config.TorExitWeight)
+	}
+
+	if attempt.BotScore > 0 {
+		score := attempt.BotScore
+		if e.config.MaxBotScore > 0 && score > e.config.MaxBotScore {
+			score = e.config.MaxBotScore
+		}
+		add(SignalBotTraffic, score)
+	}
+
+	if failures := e.recentFailures(failureKey(attempt.Email), attempt.Time); failures > 0 {
+		score := failures * e.config.FailureWeight
+		if e.config.MaxFailureScore > 0 && score > e.config.MaxFailureScore {
+			score = e.config.MaxFailureScore
+		}
+		add(SignalRecentFailures, score)
+	}
+
+	if h, ok := e.history[attempt.UserID]; ok && attempt.UserID != "" {
+		if attempt.IPAddress != "" && !containsString(h.knownIPs, attempt.IPAddress) {
+			add(SignalNewIP, e.config.NewIPWeight)
+		}
+		if e.isImpossibleTravel(h, attempt) {
+			add(SignalImpossibleTravel, e.config.ImpossibleTravelWeight)
+		}
+	}
+
+	assessment.Decision = e.decide(assessment.Score)
+	recorder := e.recorder
+	e.mu.Unlock()
+
+	if recorder != nil {
+		recorder.RecordRiskAssessment(assessment.Decision.String(), assessment.Score)
+	}
+
+	if assessment.Decision != DecisionAllow {
+		e.logger.Warn("risky login attempt",
+			"user_id", attempt.UserID,
+			"ip_address", attempt.IPAddress,
+			"score", assessment.Score,
+			"signals", strings.Join(assessment.Signals, ","),
+			"decision", assessment.Decision.String(),
+		)
+	}
+
+	return assessment
+}
+
+// RecordFailure remembers a failed attempt so later attempts score higher.
+// Exempt attempts are not remembered.
+func (e *Engine) RecordFailure(attempt Attempt) {
+	if attempt.Exempt {
+		return
+	}
+	if attempt.Time.IsZero() {
+		attempt.Time = time.Now()
+	}
+	key := failureKey(attempt.Email)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sweepFailures(attempt.Time)
+	if e.recentFailures(key, attempt.Time) == 0 && len(e.failures) >= e.config.MaxTrackedFailures {
+		// Too many identifiers to track, e.g. while random emails are
+		// sprayed; new ones are not counted until the next sweep frees room
+		return
+	}
+	e.failures[key] = append(e.failures[key], attempt.Time)
+}
+
+// RecordSuccess remembers the IP and location of a successful login and
+// clears the failure count for the account. Exempt attempts are not
+// remembered.
+func (e *Engine) RecordSuccess(attempt Attempt) {
+	if attempt.Exempt {
+		return
+	}
+	if attempt.Time.IsZero() {
+		attempt.Time = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.failures, failureKey(attempt.Email))
+	if attempt.UserID == "" {
+		return
+	}
+
+	h, ok := e.history[attempt.UserID]
+	if !ok {
+		h = &userHistory{}
+		e.history[attempt.UserID] = h
+	}
+
+	if attempt.IPAddress != "" && !containsString(h.knownIPs, attempt.IPAddress) {
+		h.knownIPs = append(h.knownIPs, attempt.IPAddress)
+		if len(h.knownIPs) > e.config.MaxKnownIPs {
+			h.knownIPs = h.knownIPs[len(h.knownIPs)-e.config.MaxKnownIPs:]
+		}
+	}
+
+	if e.geo != nil {
+		if loc, ok := e.geo.Locate(attempt.IPAddress); ok {
+			h.lastLocation = &loc
+		}
+	}
+	h.lastLoginAt = attempt.Time
+}
+
+// decide maps a score to a decision, strictest first; callers must hold e.mu
+func (e *Engine) decide(score int) Decision {
+	switch {
+	case e.config.BlockThreshold > 0 && score >= e.config.BlockThreshold:
+		return DecisionBlock
+	case e.config.EmailConfirmationThreshold > 0 && score >= e.config.EmailConfirmationThreshold:
+		return DecisionEmailConfirmation
+	case e.config.CaptchaThreshold > 0 && score >= e.config.CaptchaThreshold:
+		return DecisionCaptcha
+	default:
+		return DecisionAllow
+	}
+}
+
+// recentFailures prunes failures outside the window and returns the count;
+// callers must hold e.mu
+func (e *Engine) recentFailures(key string, now time.Time) int {
+	failures := e.failures[key]
+	cutoff := now.Add(-e.config.FailureWindow)
+
+	kept := failures[:0]
+	for _, t := range failures {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+
+	if len(kept) == 0 {
+		delete(e.failures, key)
+		return 0
+	}
+	e.failures[key] = kept
+	return len(kept)
+}
+
+// sweepFailures forgets identifiers whose failures have all left the window,
+// at most once per window; callers must hold e.mu
+func (e *Engine) sweepFailures(now time.Time) {
+	if now.Sub(e.lastSweep) < e.config.FailureWindow {
+		return
+	}
+	for key := range e.failures {
+		e.recentFailures(key, now)
+	}
+	e.lastSweep = now
+}
+
+// isImpossibleTravel reports whether reaching the attempt's location from the
+// last successful login would require travelling faster than allowed;
+// callers must hold e.mu
+func (e *Engine) isImpossibleTravel(h *userHistory, attempt Attempt) bool {
+	if e.geo == nil || h.lastLocation == nil || e.config.MaxTravelSpeedKmh <= 0 {
+		return false
+	}
+
+	loc, ok := e.geo.Locate(attempt.IPAddress)
+	if !ok {
+		return false
+	}
+
+	distance := DistanceKm(*h.lastLocation, loc)
+	hours := attempt.Time.Sub(h.lastLoginAt).Hours()
+	if hours <= 0 {
+		return distance > 0
+	}
+	return distance/hours > e.config.MaxTravelSpeedKmh
+}
+
+// failureKey normalizes an email for failure tracking
+func failureKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
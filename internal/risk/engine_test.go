@@ -0,0 +1,251 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeGeo map[string]Location
+
+func (g fakeGeo) Locate(ip string) (Location, bool) {
+	loc, ok := g[ip]
+	return loc, ok
+}
+
+type fakeRecorder struct {
+	decisions []string
+}
+
+func (r *fakeRecorder) RecordRiskAssessment(decision string, score int) {
+	r.decisions = append(r.decisions, decision)
+}
+
+func hasSignal(a Assessment, signal string) bool {
+	for _, s := range a.Signals {
+		if s == signal {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEngine_Decide(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+
+	tests := []struct {
+		score int
+		want  Decision
+	}{
+		{0, DecisionAllow},
+		{29, DecisionAllow},
+		{30, DecisionCaptcha},
+		{60, DecisionEmailConfirmation},
+		{90, DecisionBlock},
+		{200, DecisionBlock},
+	}
+
+	for _, tt := range tests {
+		if got := e.decide(tt.score); got != tt.want {
+			t.Errorf("decide(%d) = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestEngine_DisabledThresholds(t *testing.T) {
+	config := DefaultConfig()
+	config.CaptchaThreshold = 0
+	config.EmailConfirmationThreshold = 0
+	e := NewEngine(config, nil)
+
+	if got := e.decide(50); got != DecisionAllow {
+		t.Errorf("decide(50) = %v, want allow", got)
+	}
+	if got := e.decide(95); got != DecisionBlock {
+		t.Errorf("decide(95) = %v, want block", got)
+	}
+}
+
+func TestEngine_RecentFailures(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+	now := time.Now()
+	attempt := Attempt{Email: "User@Example.com", IPAddress: "10.0.0.1", Time: now}
+
+	for i := 0; i < 3; i++ {
+		e.RecordFailure(attempt)
+	}
+
+	a := e.Assess(Attempt{Email: "user@example.com", Time: now})
+	if a.Score != 30 || !hasSignal(a, SignalRecentFailures) {
+		t.Errorf("Assess() = %+v, want score 30 with recent failures", a)
+	}
+	if a.Decision != DecisionCaptcha {
+		t.Errorf("Decision = %v, want captcha", a.Decision)
+	}
+
+	// Failures outside the window no longer count
+	a = e.Assess(Attempt{Email: "user@example.com", Time: now.Add(time.Hour)})
+	if a.Score != 0 {
+		t.Errorf("Assess() after window score = %d, want 0", a.Score)
+	}
+
+	// A successful login clears the failure count
+	for i := 0; i < 10; i++ {
+		e.RecordFailure(attempt)
+	}
+	a = e.Assess(attempt)
+	if a.Score != DefaultConfig().MaxFailureScore {
+		t.Errorf("failure score = %d, want capped at %d", a.Score, DefaultConfig().MaxFailureScore)
+	}
+	e.RecordSuccess(attempt)
+	if a = e.Assess(attempt); hasSignal(a, SignalRecentFailures) {
+		t.Error("RecordSuccess() should clear recent failures")
+	}
+}
+
+func TestEngine_MaxTrackedFailures(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxTrackedFailures = 2
+	config.FailureWindow = time.Minute
+	e := NewEngine(config, nil)
+	now := time.Now()
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		e.RecordFailure(Attempt{Email: email, Time: now})
+	}
+	if len(e.failures) != 2 {
+		t.Errorf("tracked %d identifiers, want at most 2", len(e.failures))
+	}
+	// Identifiers already tracked keep counting
+	e.RecordFailure(Attempt{Email: "a@example.com", Time: now})
+	if got := len(e.failures["a@example.com"]); got != 2 {
+		t.Errorf("failures for a tracked identifier = %d, want 2", got)
+	}
+
+	// Once the window has passed, a sweep drops identifiers that were never
+	// seen again
+	e.RecordFailure(Attempt{Email: "d@example.com", Time: now.Add(2 * time.Minute)})
+	if _, ok := e.failures["d@example.com"]; !ok || len(e.failures) != 1 {
+		t.Errorf("after sweep tracked %v, want only d@example.com", e.failures)
+	}
+}
+
+func TestEngine_NewIP(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+	known := Attempt{UserID: "user-1", Email: "a@example.com", IPAddress: "10.0.0.1"}
+
+	// No history yet, so the first IP is not treated as new
+	if a := e.Assess(known); hasSignal(a, SignalNewIP) {
+		t.Error("first login should not be flagged as a new IP")
+	}
+	e.RecordSuccess(known)
+
+	if a := e.Assess(known); a.Score != 0 {
+		t.Errorf("known IP score = %d, want 0", a.Score)
+	}
+
+	other := known
+	other.IPAddress = "10.0.0.2"
+	if a := e.Assess(other); !hasSignal(a, SignalNewIP) {
+		t.Error("unseen IP should be flagged")
+	}
+}
+
+func TestEngine_ImpossibleTravel(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+	e.SetGeoLocator(fakeGeo{
+		"10.0.0.1": {Latitude: 40.7128, Longitude: -74.0060}, // New York
+		"10.0.0.2": {Latitude: 51.5074, Longitude: -0.1278},  // London
+	})
+
+	start := time.Now()
+	e.RecordSuccess(Attempt{UserID: "user-1", IPAddress: "10.0.0.1", Time: start})
+
+	a := e.Assess(Attempt{UserID: "user-1", IPAddress: "10.0.0.2", Time: start.Add(time.Hour)})
+	if !hasSignal(a, SignalImpossibleTravel) {
+		t.Errorf("Assess() = %+v, want impossible travel", a)
+	}
+
+	a = e.Assess(Attempt{UserID: "user-1", IPAddress: "10.0.0.2", Time: start.Add(12 * time.Hour)})
+	if hasSignal(a, SignalImpossibleTravel) {
+		t.Error("12 hours is enough time to fly from New York to London")
+	}
+}
+
+func TestEngine_TorExitNode(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+	recorder := &fakeRecorder{}
+	e.SetRecorder(recorder)
+
+	ips, err := ParseTorExitList(strings.NewReader("# exit list\n\n185.220.101.1\n185.220.101.2\n"))
+	if err != nil {
+		t.Fatalf("ParseTorExitList() error = %v", err)
+	}
+	e.SetTorExitNodes(ips)
+
+	a := e.Assess(Attempt{Email: "a@example.com", IPAddress: "185.220.101.2"})
+	if !hasSignal(a, SignalTorExitNode) || a.Decision != DecisionCaptcha {
+		t.Errorf("Assess() = %+v, want TOR signal and captcha", a)
+	}
+
+	if len(recorder.decisions) != 1 || recorder.decisions[0] != "captcha" {
+		t.Errorf("recorded decisions = %v", recorder.decisions)
+	}
+}
+
+func TestEngine_BotTraffic(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+
+	a := e.Assess(Attempt{Email: "a@example.com", BotScore: 35})
+	if !hasSignal(a, SignalBotTraffic) || a.Score != 35 || a.Decision != DecisionCaptcha {
+		t.Errorf("Assess() = %+v, want bot signal and captcha", a)
+	}
+
+	// The bot score alone never blocks a login
+	a = e.Assess(Attempt{Email: "a@example.com", BotScore: 200})
+	if a.Score != DefaultConfig().MaxBotScore {
+		t.Errorf("Assess() score = %d, want capped at %d", a.Score, DefaultConfig().MaxBotScore)
+	}
+}
+
+func TestParseTorExitList_Invalid(t *testing.T) {
+	if _, err := ParseTorExitList(strings.NewReader("not-an-ip\n")); err == nil {
+		t.Error("ParseTorExitList() should reject invalid addresses")
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	newYork := Location{Latitude: 40.7128, Longitude: -74.0060}
+	london := Location{Latitude: 51.5074, Longitude: -0.1278}
+
+	d := DistanceKm(newYork, london)
+	if d < 5500 || d > 5600 {
+		t.Errorf("DistanceKm() = %.0f, want about 5570", d)
+	}
+	if DistanceKm(london, london) != 0 {
+		t.Error("distance to self should be 0")
+	}
+}
+
+func TestEngine_Exempt(t *testing.T) {
+	e := NewEngine(DefaultConfig(), nil)
+	recorder := &fakeRecorder{}
+	e.SetRecorder(recorder)
+
+	attempt := Attempt{Email: "monitor@example.com", IPAddress: "198.51.100.7", Exempt: true}
+	for i := 0; i < 10; i++ {
+		e.RecordFailure(attempt)
+	}
+	if a := e.Assess(attempt); a.Decision != DecisionExempt || a.Score != 0 {
+		t.Errorf("Assess() of an exempt attempt = %+v, want exempt", a)
+	}
+	if len(recorder.decisions) != 1 || recorder.decisions[0] != "exempt" {
+		t.Errorf("recorded decisions = %v, want [exempt]", recorder.decisions)
+	}
+
+	// Failures of exempt attempts do not count against the account
+	attempt.Exempt = false
+	if a := e.Assess(attempt); hasSignal(a, SignalRecentFailures) {
+		t.Errorf("Assess() after exempt failures = %+v, want no recent failures", a)
+	}
+}
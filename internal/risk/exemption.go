@@ -0,0 +1,77 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// ExemptionKeyHeader carries the API key of trusted automation, such as
+// synthetic monitoring
+const ExemptionKeyHeader = "X-Risk-Exemption-Key"
+
+// Exemptions lists trusted automation that skips captcha challenges, bot
+// scoring and failure counting. Exempt logins are still counted in metrics
+// and audited.
+type Exemptions struct {
+	prefixes []netip.Prefix
+	keys     []string
+}
+
+// NewExemptions parses the exempt networks, in CIDR notation, and API keys
+func NewExemptions(cidrs, keys []string) (*Exemptions, error) {
+	e := &Exemptions{keys: keys}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exempt network %q: %w", cidr, err)
+		}
+		e.prefixes = append(e.prefixes, prefix.Masked())
+	}
+	return e, nil
+}
+
+// Match reports whether a request from peerAddr, the address of the
+// connection rather than a forwarded one, or carrying key is exempt
+func (e *Exemptions) Match(peerAddr, key string) bool {
+	if key != "" {
+		// Every key is compared so the time taken does not tell which matched
+		matched := false
+		for _, k := range e.keys {
+			if security.ConstantTimeCompare(key, k) {
+				matched = true
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	addr, err := netip.ParseAddr(peerAddr)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range e.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+type exemptContextKey struct{}
+
+// ContextWithExemption returns a copy of ctx marking the request as trusted
+// automation
+func ContextWithExemption(ctx context.Context) context.Context {
+	return context.WithValue(ctx, exemptContextKey{}, true)
+}
+
+// ExemptFromContext reports whether ctx belongs to trusted automation
+func ExemptFromContext(ctx context.Context) bool {
+	exempt, _ := ctx.Value(exemptContextKey{}).(bool)
+	return exempt
+}
@@ -0,0 +1,48 @@
+package risk
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExemptions_Match(t *testing.T) {
+	exemptions, err := NewExemptions([]string{"10.20.0.0/16", "2001:db8::/32"}, []string{"0123456789abcdef0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("NewExemptions() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		peer string
+		key  string
+		want bool
+	}{
+		{"exempt network", "10.20.3.4", "", true},
+		{"ipv4-mapped address", "::ffff:10.20.3.4", "", true},
+		{"exempt ipv6 network", "2001:db8::1", "", true},
+		{"other network", "10.21.3.4", "", false},
+		{"valid key", "203.0.113.9", "0123456789abcdef0123456789abcdef", true},
+		{"wrong key", "203.0.113.9", "0123456789abcdef0123456789abcdeX", false},
+		{"invalid address", "not-an-ip", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exemptions.Match(tt.peer, tt.key); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.peer, tt.key, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewExemptions([]string{"10.20.0.0"}, nil); err == nil {
+		t.Error("NewExemptions() accepted an address without a prefix length")
+	}
+}
+
+func TestExemptFromContext(t *testing.T) {
+	if ExemptFromContext(context.Background()) {
+		t.Error("ExemptFromContext() = true for a plain context")
+	}
+	if !ExemptFromContext(ContextWithExemption(context.Background())) {
+		t.Error("ExemptFromContext() = false for an exempt context")
+	}
+}
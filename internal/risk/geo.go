@@ -0,0 +1,30 @@
+package risk
+
+import "math"
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// Location is a geographic coordinate
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// GeoLocator resolves an IP address to an approximate location
+type GeoLocator interface {
+	// Locate returns false when the address cannot be resolved
+	Locate(ip string) (Location, bool)
+}
+
+// DistanceKm returns the great-circle distance between two locations
+func DistanceKm(a, b Location) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := (b.Latitude - a.Latitude) * math.Pi / 180
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
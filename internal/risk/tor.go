@@ -0,0 +1,51 @@
+package risk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// ParseTorExitList reads one IP address per line, ignoring blank lines and
+// # comments. The format matches the published TOR bulk exit list.
+func ParseTorExitList(r io.Reader) ([]string, error) {
+	var ips []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if net.ParseIP(line) == nil {
+			return nil, fmt.Errorf("invalid IP address in exit list: %q", line)
+		}
+		ips = append(ips, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read exit list: %w", err)
+	}
+
+	return ips, nil
+}
+
+// LoadTorExitListFile loads a TOR exit list from disk into the engine
+func (e *Engine) LoadTorExitListFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open exit list: %w", err)
+	}
+	defer f.Close()
+
+	ips, err := ParseTorExitList(f)
+	if err != nil {
+		return err
+	}
+
+	e.SetTorExitNodes(ips)
+	e.logger.Info("loaded TOR exit list", "path", path, "count", len(ips))
+	return nil
+}
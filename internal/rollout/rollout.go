@@ -0,0 +1,91 @@
+// Package rollout enables auth behavior changes for a share of users before
+// everyone gets them. Users are split into cohorts by a hash of the flag name
+// and their ID, so each flag picks its own users and a user keeps their
+// cohort as a rollout widens.
+package rollout
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Cohorts is the number of cohorts users are split into, so one cohort is
+// 1% of users
+const Cohorts = 100
+
+// Flags gating behavior changes
+const (
+	// StrictEmailVerification rejects logins of users who have not verified
+	// their email
+	StrictEmailVerification = "strict_email_verification"
+)
+
+// Known lists the flags that gate a behavior change
+var Known = []string{StrictEmailVerification}
+
+// Variants of a flag, reported in metrics
+const (
+	VariantEnabled = "enabled" // the user gets the new behavior
+	VariantControl = "control" // the user keeps the old behavior
+)
+
+// Flags decides which users get each behavior change. A nil *Flags enables
+// nothing.
+type Flags struct {
+	enabled map[string]*[Cohorts]bool
+}
+
+// New enables each flag for the users in the listed cohorts, numbered 0 to
+// Cohorts-1
+func New(cohorts map[string][]int) (*Flags, error) {
+	f := &Flags{enabled: make(map[string]*[Cohorts]bool, len(cohorts))}
+	for flag, list := range cohorts {
+		if !slices.Contains(Known, flag) {
+			return nil, fmt.Errorf("unknown rollout flag %q; known flags are %s", flag, strings.Join(Known, ", "))
+		}
+		var enabled [Cohorts]bool
+		for _, cohort := range list {
+			if cohort < 0 || cohort >= Cohorts {
+				return nil, fmt.Errorf("rollout flag %s: cohort %d is not between 0 and %d", flag, cohort, Cohorts-1)
+			}
+			enabled[cohort] = true
+		}
+		f.enabled[flag] = &enabled
+	}
+	return f, nil
+}
+
+// Cohort returns the cohort of userID for flag
+func Cohort(flag, userID string) int {
+	sum := sha256.Sum256([]byte(flag + ":" + userID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % Cohorts)
+}
+
+// Configured reports whether flag is rolled out to any cohort, so callers
+// can skip recording a variant for flags nobody is trying
+func (f *Flags) Configured(flag string) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.enabled[flag]
+	return ok
+}
+
+// Enabled reports whether userID gets the behavior gated by flag
+func (f *Flags) Enabled(flag, userID string) bool {
+	if !f.Configured(flag) {
+		return false
+	}
+	return f.enabled[flag][Cohort(flag, userID)]
+}
+
+// Variant returns VariantEnabled or VariantControl for userID
+func (f *Flags) Variant(flag, userID string) string {
+	if f.Enabled(flag, userID) {
+		return VariantEnabled
+	}
+	return VariantControl
+}
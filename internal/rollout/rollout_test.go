@@ -0,0 +1,89 @@
+package rollout
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(map[string][]int{"dark_mode": {0}}); err == nil {
+		t.Error("expected error for unknown flag")
+	}
+	if _, err := New(map[string][]int{StrictEmailVerification: {Cohorts}}); err == nil {
+		t.Error("expected error for cohort out of range")
+	}
+	if _, err := New(map[string][]int{StrictEmailVerification: {0, 99}}); err != nil {
+		t.Errorf("New() error = %v", err)
+	}
+}
+
+func TestFlags_Enabled(t *testing.T) {
+	percent := func(n int) []int {
+		cohorts := make([]int, n)
+		for i := range cohorts {
+			cohorts[i] = i
+		}
+		return cohorts
+	}
+	users := make([]string, 10000)
+	for i := range users {
+		users[i] = fmt.Sprintf("user-%d", i)
+	}
+
+	t.Run("enables roughly the configured share", func(t *testing.T) {
+		flags, _ := New(map[string][]int{StrictEmailVerification: percent(5)})
+		enabled := 0
+		for _, user := range users {
+			if flags.Enabled(StrictEmailVerification, user) {
+				enabled++
+			}
+		}
+		if enabled < 350 || enabled > 650 {
+			t.Errorf("enabled for %d of %d users, want about 5%%", enabled, len(users))
+		}
+	})
+
+	t.Run("widening keeps earlier users", func(t *testing.T) {
+		five, _ := New(map[string][]int{StrictEmailVerification: percent(5)})
+		twenty, _ := New(map[string][]int{StrictEmailVerification: percent(20)})
+		for _, user := range users {
+			if five.Enabled(StrictEmailVerification, user) && !twenty.Enabled(StrictEmailVerification, user) {
+				t.Fatalf("%s lost the flag when the rollout widened", user)
+			}
+		}
+	})
+
+	t.Run("targets listed cohorts", func(t *testing.T) {
+		flags, _ := New(map[string][]int{StrictEmailVerification: {42}})
+		for _, user := range users[:500] {
+			want := Cohort(StrictEmailVerification, user) == 42
+			if got := flags.Enabled(StrictEmailVerification, user); got != want {
+				t.Fatalf("Enabled(%s) = %v, want %v", user, got, want)
+			}
+		}
+	})
+
+	t.Run("unconfigured flags are off", func(t *testing.T) {
+		var nilFlags *Flags
+		empty, _ := New(nil)
+		for _, flags := range []*Flags{nilFlags, empty} {
+			if flags.Configured(StrictEmailVerification) || flags.Enabled(StrictEmailVerification, "user-1") {
+				t.Error("expected flag to be off")
+			}
+			if v := flags.Variant(StrictEmailVerification, "user-1"); v != VariantControl {
+				t.Errorf("Variant() = %q, want control", v)
+			}
+		}
+	})
+}
+
+func TestCohort(t *testing.T) {
+	if Cohort(StrictEmailVerification, "user-1") != Cohort(StrictEmailVerification, "user-1") {
+		t.Error("cohort is not stable")
+	}
+	for i := 0; i < 1000; i++ {
+		if c := Cohort(StrictEmailVerification, fmt.Sprintf("user-%d", i)); c < 0 || c >= Cohorts {
+			t.Fatalf("Cohort() = %d, out of range", c)
+		}
+	}
+}
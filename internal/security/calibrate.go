@@ -0,0 +1,93 @@
+package security
+
+import (
+	"crypto/sha256"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// MaxPBKDF2Iterations is the largest work factor calibration selects
+const MaxPBKDF2Iterations = 5000000
+
+// calibrationPassword is hashed while measuring; its value does not matter
+const calibrationPassword = "calibration-password"
+
+// Calibration reports the work factor chosen for a target hash duration
+type Calibration struct {
+	Algorithm string        // bcrypt or pbkdf2-sha256
+	Cost      int           // bcrypt cost or PBKDF2 iterations
+	Duration  time.Duration // measured time of one hash at Cost
+	Clamped   bool          // the target lies outside the allowed bounds
+}
+
+// CalibrateBcrypt measures bcrypt on this host and returns the highest cost
+// between MinCost and MaxCost whose hash takes no longer than target
+func CalibrateBcrypt(target time.Duration) Calibration {
+	return calibrateBcrypt(target, func(cost int) time.Duration {
+		return measure(func() {
+			_, _ = bcrypt.GenerateFromPassword([]byte(calibrationPassword), cost)
+		})
+	})
+}
+
+// CalibratePBKDF2 measures PBKDF2-HMAC-SHA256 on this host and returns the
+// iteration count, between MinPBKDF2Iterations and MaxPBKDF2Iterations,
+// that takes about target
+func CalibratePBKDF2(target time.Duration) Calibration {
+	return calibratePBKDF2(target, func(iterations int) time.Duration {
+		salt := make([]byte, pbkdf2SaltLen)
+		return measure(func() {
+			pbkdf2.Key([]byte(calibrationPassword), salt, iterations, pbkdf2KeyLen, sha256.New)
+		})
+	})
+}
+
+func calibrateBcrypt(target time.Duration, measure func(cost int) time.Duration) Calibration {
+	c := Calibration{Algorithm: "bcrypt", Cost: MinCost, Duration: measure(MinCost)}
+	// Each cost step doubles the work, so stop before a step that would
+	// overshoot rather than measuring a pathologically slow cost
+	for c.Cost < MaxCost && c.Duration*2 <= target {
+		d := measure(c.Cost + 1)
+		if d > target {
+			break
+		}
+		c.Cost++
+		c.Duration = d
+	}
+	c.Clamped = c.Duration > target || (c.Cost == MaxCost && c.Duration*2 <= target)
+	return c
+}
+
+func calibratePBKDF2(target time.Duration, measure func(iterations int) time.Duration) Calibration {
+	c := Calibration{Algorithm: "pbkdf2-sha256"}
+
+	// The work is linear in the iteration count, so scale a baseline run
+	base := measure(MinPBKDF2Iterations)
+	iterations := MaxPBKDF2Iterations
+	if base > 0 {
+		iterations = int(int64(MinPBKDF2Iterations) * int64(target) / int64(base))
+	}
+	iterations -= iterations % 1000
+
+	switch {
+	case iterations < MinPBKDF2Iterations:
+		iterations = MinPBKDF2Iterations
+		c.Clamped = true
+	case iterations > MaxPBKDF2Iterations:
+		iterations = MaxPBKDF2Iterations
+		c.Clamped = true
+	}
+
+	c.Cost = iterations
+	c.Duration = measure(iterations)
+	return c
+}
+
+// measure returns how long fn takes
+func measure(fn func()) time.Duration {
+	start := time.Now()
+	fn()
+	return time.Since(start)
+}
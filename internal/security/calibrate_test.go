@@ -0,0 +1,88 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+// bcryptTimings simulates a host where cost 10 takes base and each step doubles it
+func bcryptTimings(base time.Duration, measured *[]int) func(int) time.Duration {
+	return func(cost int) time.Duration {
+		*measured = append(*measured, cost)
+		return base << (cost - MinCost)
+	}
+}
+
+func TestCalibrateBcrypt(t *testing.T) {
+	tests := []struct {
+		name        string
+		base        time.Duration
+		target      time.Duration
+		wantCost    int
+		wantClamped bool
+		maxMeasured int
+	}{
+		{name: "fast host", base: 15 * time.Millisecond, target: 250 * time.Millisecond, wantCost: 14, maxMeasured: 14},
+		{name: "typical host", base: 60 * time.Millisecond, target: 250 * time.Millisecond, wantCost: 12, maxMeasured: 12},
+		{name: "exact target", base: 62500 * time.Microsecond, target: 250 * time.Millisecond, wantCost: 12, maxMeasured: 12},
+		{name: "slow host", base: 400 * time.Millisecond, target: 250 * time.Millisecond, wantCost: MinCost, wantClamped: true, maxMeasured: 10},
+		{name: "target beyond max cost", base: time.Millisecond, target: time.Second, wantCost: MaxCost, wantClamped: true, maxMeasured: 14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var measured []int
+			c := calibrateBcrypt(tt.target, bcryptTimings(tt.base, &measured))
+
+			if c.Algorithm != "bcrypt" || c.Cost != tt.wantCost || c.Clamped != tt.wantClamped {
+				t.Errorf("calibrateBcrypt() = %+v, want cost %d clamped %v", c, tt.wantCost, tt.wantClamped)
+			}
+			if c.Duration != tt.base<<(c.Cost-MinCost) {
+				t.Errorf("Duration = %v, want the time measured at cost %d", c.Duration, c.Cost)
+			}
+			for _, cost := range measured {
+				if cost > tt.maxMeasured {
+					t.Errorf("measured cost %d, expected nothing above %d", cost, tt.maxMeasured)
+				}
+			}
+		})
+	}
+}
+
+func TestCalibratePBKDF2(t *testing.T) {
+	tests := []struct {
+		name           string
+		perIteration   time.Duration
+		target         time.Duration
+		wantIterations int
+		wantClamped    bool
+	}{
+		{name: "scales baseline", perIteration: 250 * time.Nanosecond, target: 250 * time.Millisecond, wantIterations: 1000000},
+		{name: "rounds down", perIteration: 300 * time.Nanosecond, target: 250 * time.Millisecond, wantIterations: 833000},
+		{name: "slow host", perIteration: 10 * time.Microsecond, target: 250 * time.Millisecond, wantIterations: MinPBKDF2Iterations, wantClamped: true},
+		{name: "fast host", perIteration: time.Nanosecond, target: time.Second, wantIterations: MaxPBKDF2Iterations, wantClamped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := calibratePBKDF2(tt.target, func(iterations int) time.Duration {
+				return time.Duration(iterations) * tt.perIteration
+			})
+
+			if c.Algorithm != "pbkdf2-sha256" || c.Cost != tt.wantIterations || c.Clamped != tt.wantClamped {
+				t.Errorf("calibratePBKDF2() = %+v, want %d iterations clamped %v", c, tt.wantIterations, tt.wantClamped)
+			}
+			if c.Duration != time.Duration(c.Cost)*tt.perIteration {
+				t.Errorf("Duration = %v, want the time measured at %d iterations", c.Duration, c.Cost)
+			}
+		})
+	}
+}
+
+func TestCalibrateBcrypt_Host(t *testing.T) {
+	// A tiny target measures only the minimum cost
+	c := CalibrateBcrypt(time.Nanosecond)
+	if c.Cost != MinCost || !c.Clamped || c.Duration <= 0 {
+		t.Errorf("CalibrateBcrypt() = %+v", c)
+	}
+}
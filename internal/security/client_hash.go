@@ -0,0 +1,97 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// ClientHashAlgorithm is how clients derive password verifiers:
+	// PBKDF2-HMAC-SHA256 over the password with the hex-decoded salt,
+	// giving ClientVerifierLen bytes sent in lowercase hex
+	ClientHashAlgorithm = "pbkdf2-sha256"
+	// ClientVerifierLen is the length in bytes of a password verifier
+	ClientVerifierLen = 32
+
+	// clientHashPrefix identifies password hashes of client-derived
+	// verifiers: $client-pbkdf2-sha256$i=<iterations>$<salt>$<server hash>
+	clientHashPrefix  = "$client-" + ClientHashAlgorithm + "$"
+	clientHashSaltLen = 16
+)
+
+// ClientHashParams are what a client derives a user's password verifier
+// with. They are stored with the password hash and are not secret.
+type ClientHashParams struct {
+	Salt       string // hex
+	Iterations int
+}
+
+// NewClientHashParams returns params with a new random salt
+func NewClientHashParams(iterations int) (ClientHashParams, error) {
+	salt := make([]byte, clientHashSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return ClientHashParams{}, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return ClientHashParams{Salt: hex.EncodeToString(salt), Iterations: iterations}, nil
+}
+
+// FakeClientHashParams returns params for an account that has none, with a
+// salt derived from key and identifier. They stay the same across requests
+// and restarts, so they cannot be told apart from stored params.
+func FakeClientHashParams(key []byte, identifier string, iterations int) ClientHashParams {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(identifier))
+	return ClientHashParams{Salt: hex.EncodeToString(mac.Sum(nil)[:clientHashSaltLen]), Iterations: iterations}
+}
+
+// DeriveClientVerifier derives the verifier a client sends instead of the
+// password
+func DeriveClientVerifier(password string, params ClientHashParams) (string, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil || params.Iterations <= 0 {
+		return "", fmt.Errorf("malformed client hash params")
+	}
+	key := pbkdf2.Key([]byte(password), salt, params.Iterations, ClientVerifierLen, sha256.New)
+	return hex.EncodeToString(key), nil
+}
+
+// ValidClientVerifier reports whether v has the shape of a verifier, so
+// passwords sent by mistake are rejected instead of stored
+func ValidClientVerifier(v string) bool {
+	if len(v) != hex.EncodedLen(ClientVerifierLen) || strings.ToLower(v) != v {
+		return false
+	}
+	_, err := hex.DecodeString(v)
+	return err == nil
+}
+
+// WrapClientHash records params with hash, the server-side hash of a
+// verifier, so Compare checks verifiers against it and ParseClientHash can
+// return the params for logins
+func WrapClientHash(params ClientHashParams, hash string) string {
+	return fmt.Sprintf("%si=%d$%s$%s", clientHashPrefix, params.Iterations, params.Salt, hash)
+}
+
+// ParseClientHash returns the params and inner hash of a hash produced by
+// WrapClientHash. ok is false for hashes of plain passwords.
+func ParseClientHash(hash string) (params ClientHashParams, inner string, ok bool) {
+	if !strings.HasPrefix(hash, clientHashPrefix) {
+		return ClientHashParams{}, "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(hash, clientHashPrefix), "$", 3)
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "i=") {
+		return ClientHashParams{}, "", false
+	}
+	iterations, err := strconv.Atoi(strings.TrimPrefix(parts[0], "i="))
+	if err != nil || iterations <= 0 {
+		return ClientHashParams{}, "", false
+	}
+	return ClientHashParams{Salt: parts[1], Iterations: iterations}, parts[2], true
+}
@@ -0,0 +1,63 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClientHash(t *testing.T) {
+	params, err := NewClientHashParams(1000)
+	if err != nil {
+		t.Fatalf("NewClientHashParams() error = %v", err)
+	}
+	if len(params.Salt) != 2*clientHashSaltLen || params.Iterations != 1000 {
+		t.Errorf("NewClientHashParams() = %+v", params)
+	}
+
+	verifier, err := DeriveClientVerifier("testPassword123", params)
+	if err != nil {
+		t.Fatalf("DeriveClientVerifier() error = %v", err)
+	}
+	if !ValidClientVerifier(verifier) {
+		t.Errorf("ValidClientVerifier(%q) = false", verifier)
+	}
+	for _, v := range []string{"testPassword123", strings.ToUpper(verifier), verifier[:60], strings.Repeat("z", 64)} {
+		if ValidClientVerifier(v) {
+			t.Errorf("ValidClientVerifier(%q) = true", v)
+		}
+	}
+
+	ph := NewPasswordHasher(MinCost)
+	inner, _ := ph.Hash(verifier)
+	hash := WrapClientHash(params, inner)
+
+	got, gotInner, ok := ParseClientHash(hash)
+	if !ok || got != params || gotInner != inner {
+		t.Errorf("ParseClientHash() = %+v, %q, %v, want %+v, %q", got, gotInner, ok, params, inner)
+	}
+	if _, _, ok := ParseClientHash(inner); ok {
+		t.Error("ParseClientHash() accepted a plain password hash")
+	}
+
+	// Only the verifier matches, never the password it was derived from
+	if err := ph.Compare(verifier, hash); err != nil {
+		t.Errorf("Compare(verifier) error = %v", err)
+	}
+	if err := ph.Compare("testPassword123", hash); err == nil {
+		t.Error("Compare(password) should fail against a verifier hash")
+	}
+}
+
+func TestFakeClientHashParams(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	a := FakeClientHashParams(key, "ghost@example.com", 1000)
+	if again := FakeClientHashParams(key, "ghost@example.com", 1000); again != a {
+		t.Errorf("FakeClientHashParams() = %+v, then %+v", a, again)
+	}
+	if other := FakeClientHashParams(key, "other@example.com", 1000); other.Salt == a.Salt {
+		t.Error("FakeClientHashParams() gave two accounts the same salt")
+	}
+	if len(a.Salt) != 2*clientHashSaltLen {
+		t.Errorf("salt %q has the wrong length", a.Salt)
+	}
+}
@@ -0,0 +1,65 @@
+package security
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCookieSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestNewCookieSigner(t *testing.T) {
+	if _, err := NewCookieSigner("short"); err == nil {
+		t.Error("NewCookieSigner() with short key should fail")
+	}
+	if _, err := NewCookieSigner(testCookieSigningKey); err != nil {
+		t.Errorf("NewCookieSigner() error = %v", err)
+	}
+}
+
+func TestCookieSigner_SignVerify(t *testing.T) {
+	signer, err := NewCookieSigner(testCookieSigningKey)
+	if err != nil {
+		t.Fatalf("NewCookieSigner() error = %v", err)
+	}
+	now := time.Now()
+	signed := signer.Sign("remember_me", "token.with.dots", now.Add(time.Hour))
+
+	parts := strings.Split(signed, ".")
+	extended := strings.Join(append(parts[:3:3], "9999999999", parts[4]), ".")
+
+	tests := []struct {
+		name      string
+		cookie    string
+		value     string
+		now       time.Time
+		wantValue string
+		wantErr   error
+	}{
+		{name: "valid", cookie: "remember_me", value: signed, now: now, wantValue: "token.with.dots"},
+		{name: "expired", cookie: "remember_me", value: signed, now: now.Add(time.Hour), wantErr: ErrCookieExpired},
+		{name: "other cookie", cookie: "session", value: signed, now: now, wantErr: ErrInvalidCookie},
+		{name: "extended expiry", cookie: "remember_me", value: extended, now: now, wantErr: ErrInvalidCookie},
+		{name: "changed value", cookie: "remember_me", value: "x" + signed, now: now, wantErr: ErrInvalidCookie},
+		{name: "unsigned", cookie: "remember_me", value: "token", now: now, wantErr: ErrInvalidCookie},
+		{name: "empty", cookie: "remember_me", value: "", now: now, wantErr: ErrInvalidCookie},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := signer.Verify(tt.cookie, tt.value, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if value != tt.wantValue {
+				t.Errorf("Verify() = %q, want %q", value, tt.wantValue)
+			}
+		})
+	}
+
+	other, _ := NewCookieSigner(strings.Repeat("x", MinCookieSigningKeyLength))
+	if _, err := other.Verify("remember_me", signed, now); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("Verify() with another key error = %v, want %v", err, ErrInvalidCookie)
+	}
+}
@@ -0,0 +1,148 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EncryptedFieldPrefix marks column values written by FieldCipher. Values
+// without it are treated as plaintext written before encryption was enabled.
+const EncryptedFieldPrefix = "enc:"
+
+// FieldKeySize is the required key length; keys select AES-256
+const FieldKeySize = 32
+
+var (
+	// ErrUnknownFieldKey is returned when a value was encrypted with a key
+	// that is not configured
+	ErrUnknownFieldKey = errors.New("unknown field encryption key")
+	// ErrInvalidCiphertext is returned when an encrypted value is malformed
+	// or fails authentication
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// FieldCipher encrypts individual column values with AES-GCM. Each value
+// carries the ID of the key that sealed it, so old keys can stay configured
+// for reading while new writes use the active key.
+type FieldCipher struct {
+	keys        map[string]cipher.AEAD
+	activeKeyID string
+}
+
+// NewFieldCipher creates a cipher from 32-byte keys indexed by key ID.
+// activeKeyID selects the key used for new values.
+func NewFieldCipher(keys map[string][]byte, activeKeyID string) (*FieldCipher, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active key %q is not configured", activeKeyID)
+	}
+
+	c := &FieldCipher{
+		keys:        make(map[string]cipher.AEAD, len(keys)),
+		activeKeyID: activeKeyID,
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		if len(key) != FieldKeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes", id, FieldKeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		c.keys[id] = aead
+	}
+
+	return c, nil
+}
+
+// ParseFieldKeys parses a comma-separated list of id:base64key pairs
+func ParseFieldKeys(spec string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("key %q must have the form id:base64key", pair)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("duplicate key ID %q", id)
+		}
+		keys[id] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption keys configured")
+	}
+	return keys, nil
+}
+
+// ActiveKeyID returns the ID of the key used for new values
+func (c *FieldCipher) ActiveKeyID() string {
+	return c.activeKeyID
+}
+
+// Encrypt seals plaintext with the active key. The result has the form
+// enc:<key id>:<base64url nonce+ciphertext>.
+func (c *FieldCipher) Encrypt(plaintext string) (string, error) {
+	aead := c.keys[c.activeKeyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncryptedFieldPrefix + c.activeKeyID + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Values without the encrypted
+// prefix are returned unchanged.
+func (c *FieldCipher) Decrypt(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, EncryptedFieldPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", ErrInvalidCiphertext
+	}
+	aead, ok := c.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownFieldKey, id)
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsReencryption reports whether value is plaintext or sealed with a key
+// other than the active one
+func (c *FieldCipher) NeedsReencryption(value string) bool {
+	return !strings.HasPrefix(value, EncryptedFieldPrefix+c.activeKeyID+":")
+}
@@ -0,0 +1,148 @@
+package security
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testFieldKeys() map[string][]byte {
+	return map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, FieldKeySize),
+		"k2": bytes.Repeat([]byte{2}, FieldKeySize),
+	}
+}
+
+func TestNewFieldCipher(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    map[string][]byte
+		active  string
+		wantErr bool
+	}{
+		{name: "valid", keys: testFieldKeys(), active: "k1"},
+		{name: "missing active key", keys: testFieldKeys(), active: "k3", wantErr: true},
+		{name: "short key", keys: map[string][]byte{"k1": []byte("short")}, active: "k1", wantErr: true},
+		{name: "colon in key ID", keys: map[string][]byte{"a:b": bytes.Repeat([]byte{1}, FieldKeySize)}, active: "a:b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFieldCipher(tt.keys, tt.active)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFieldCipher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldCipher_EncryptDecrypt(t *testing.T) {
+	c, err := NewFieldCipher(testFieldKeys(), "k1")
+	if err != nil {
+		t.Fatalf("NewFieldCipher() error = %v", err)
+	}
+
+	sealed, err := c.Encrypt("192.168.1.1")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !strings.HasPrefix(sealed, "enc:k1:") {
+		t.Errorf("Encrypt() = %q, want enc:k1: prefix", sealed)
+	}
+	if strings.Contains(sealed, "192.168") {
+		t.Error("ciphertext contains the plaintext")
+	}
+	again, _ := c.Encrypt("192.168.1.1")
+	if again == sealed {
+		t.Error("Encrypt() should use a fresh nonce per value")
+	}
+
+	tampered := sealed[:len(sealed)-2] + "AA"
+	if tampered == sealed {
+		tampered = sealed[:len(sealed)-2] + "BB"
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{name: "round trip", value: sealed, want: "192.168.1.1"},
+		{name: "plaintext passes through", value: "Mozilla/5.0", want: "Mozilla/5.0"},
+		{name: "tampered", value: tampered, wantErr: ErrInvalidCiphertext},
+		{name: "missing key ID", value: "enc:abc", wantErr: ErrInvalidCiphertext},
+		{name: "unknown key", value: "enc:k9:" + base64.RawURLEncoding.EncodeToString(make([]byte, 32)), wantErr: ErrUnknownFieldKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Decrypt(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decrypt() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldCipher_Rotation(t *testing.T) {
+	old, _ := NewFieldCipher(testFieldKeys(), "k1")
+	rotated, _ := NewFieldCipher(testFieldKeys(), "k2")
+
+	sealed, err := old.Encrypt("Mozilla/5.0")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	if got, err := rotated.Decrypt(sealed); err != nil || got != "Mozilla/5.0" {
+		t.Errorf("Decrypt() with retired key = %q, %v", got, err)
+	}
+	if !rotated.NeedsReencryption(sealed) {
+		t.Error("value sealed with the old key should need re-encryption")
+	}
+	if !rotated.NeedsReencryption("Mozilla/5.0") {
+		t.Error("plaintext should need re-encryption")
+	}
+
+	resealed, _ := rotated.Encrypt("Mozilla/5.0")
+	if rotated.NeedsReencryption(resealed) {
+		t.Error("value sealed with the active key should not need re-encryption")
+	}
+}
+
+func TestParseFieldKeys(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, FieldKeySize))
+
+	tests := []struct {
+		name    string
+		spec    string
+		wantIDs []string
+		wantErr bool
+	}{
+		{name: "single key", spec: "k1:" + key, wantIDs: []string{"k1"}},
+		{name: "two keys with spaces", spec: "k1:" + key + ", k2:" + key, wantIDs: []string{"k1", "k2"}},
+		{name: "empty", spec: "", wantErr: true},
+		{name: "missing ID", spec: key, wantErr: true},
+		{name: "bad base64", spec: "k1:not*base64", wantErr: true},
+		{name: "duplicate ID", spec: "k1:" + key + ",k1:" + key, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseFieldKeys(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFieldKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, id := range tt.wantIDs {
+				if len(keys[id]) != FieldKeySize {
+					t.Errorf("key %q has %d bytes", id, len(keys[id]))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrHashingOverloaded is returned when the hashing pool sheds a hash or
+// compare because its queue is full or the wait would exceed the budget
+var ErrHashingOverloaded = errors.New("password hashing is overloaded")
+
+// Reasons the hashing pool sheds work, as recorded in metrics
+const (
+	HashShedQueueFull = "queue_full"
+	HashShedWait      = "wait_budget"
+)
+
+// HashPoolRecorder records the hashing pool's queue
+type HashPoolRecorder interface {
+	ObserveHashQueueWait(wait time.Duration)
+	SetHashQueueDepth(depth int)
+	RecordHashShed(reason string)
+}
+
+// hashJob is a hash or compare waiting for a worker
+type hashJob struct {
+	ctx    context.Context
+	fn     func()
+	queued time.Time
+	done   chan struct{}
+}
+
+// HashPool runs password hashes and compares on a fixed number of workers,
+// so a login storm queues CPU-bound work instead of running it on every
+// request goroutine at once. Work is shed with ErrHashingOverloaded when
+// the queue is full, or when the expected wait, estimated from recent hash
+// durations, exceeds the wait budget or the request's deadline.
+type HashPool struct {
+	workers  int
+	maxWait  time.Duration
+	jobs     chan hashJob
+	recorder HashPoolRecorder
+
+	avgDuration atomic.Int64 // moving average of a hash, in nanoseconds
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewHashPool creates a pool of workers with room for queueSize waiting
+// hashes. A maxWait of zero only sheds work when the queue is full.
+func NewHashPool(workers, queueSize int, maxWait time.Duration) *HashPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	return &HashPool{
+		workers: workers,
+		maxWait: maxWait,
+		jobs:    make(chan hashJob, queueSize),
+		stop:    make(chan struct{}),
+	}
+}
+
+// SetMetrics records queue waits, depth and shed work
+func (p *HashPool) SetMetrics(recorder HashPoolRecorder) {
+	p.recorder = recorder
+}
+
+// Start starts the workers
+func (p *HashPool) Start() {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+}
+
+// Stop stops the workers once the hashes they are running finish. Queued
+// hashes are abandoned and their callers get ErrHashingOverloaded.
+func (p *HashPool) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// Run runs fn on a worker and waits for it. It returns ErrHashingOverloaded
+// without running fn when the pool sheds it, and ctx.Err() once ctx is done;
+// fn is skipped if ctx is done before a worker picks it up.
+func (p *HashPool) Run(ctx context.Context, fn func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if budget, ok := p.waitBudget(ctx); ok && p.expectedWait() > budget {
+		p.shed(HashShedWait)
+		return ErrHashingOverloaded
+	}
+
+	job := hashJob{ctx: ctx, fn: fn, queued: time.Now(), done: make(chan struct{})}
+	select {
+	case p.jobs <- job:
+		p.recordDepth()
+	default:
+		p.shed(HashShedQueueFull)
+		return ErrHashingOverloaded
+	}
+
+	select {
+	case <-job.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.stop:
+		return ErrHashingOverloaded
+	}
+}
+
+// waitBudget is how long a new hash may wait in the queue: the pool's
+// budget or, when sooner, the time left before ctx's deadline
+func (p *HashPool) waitBudget(ctx context.Context) (time.Duration, bool) {
+	budget, ok := p.maxWait, p.maxWait > 0
+	if deadline, has := ctx.Deadline(); has {
+		if left := time.Until(deadline); !ok || left < budget {
+			budget, ok = left, true
+		}
+	}
+	return budget, ok
+}
+
+// expectedWait estimates how long a hash queued now waits for a worker
+func (p *HashPool) expectedWait() time.Duration {
+	queued := len(p.jobs)
+	if queued == 0 {
+		return 0
+	}
+	rounds := (queued + p.workers - 1) / p.workers
+	return time.Duration(rounds) * time.Duration(p.avgDuration.Load())
+}
+
+func (p *HashPool) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case job := <-p.jobs:
+			p.recordDepth()
+			p.runJob(job)
+		}
+	}
+}
+
+func (p *HashPool) runJob(job hashJob) {
+	if p.recorder != nil {
+		p.recorder.ObserveHashQueueWait(time.Since(job.queued))
+	}
+	// The caller has given up, so the hash would be thrown away
+	if job.ctx.Err() != nil {
+		return
+	}
+
+	start := time.Now()
+	job.fn()
+	p.observeDuration(time.Since(start))
+	close(job.done)
+}
+
+// observeDuration folds a hash's duration into the moving average, weighing
+// it by 1/8
+func (p *HashPool) observeDuration(d time.Duration) {
+	for {
+		avg := p.avgDuration.Load()
+		next := int64(d)
+		if avg > 0 {
+			next = avg + (int64(d)-avg)/8
+		}
+		if p.avgDuration.CompareAndSwap(avg, next) {
+			return
+		}
+	}
+}
+
+func (p *HashPool) recordDepth() {
+	if p.recorder != nil {
+		p.recorder.SetHashQueueDepth(len(p.jobs))
+	}
+}
+
+func (p *HashPool) shed(reason string) {
+	if p.recorder != nil {
+		p.recorder.RecordHashShed(reason)
+	}
+}
@@ -0,0 +1,163 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type hashPoolRecorder struct {
+	mu    sync.Mutex
+	waits int
+	shed  []string
+}
+
+func (r *hashPoolRecorder) ObserveHashQueueWait(wait time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waits++
+}
+
+func (r *hashPoolRecorder) SetHashQueueDepth(depth int) {}
+
+func (r *hashPoolRecorder) RecordHashShed(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shed = append(r.shed, reason)
+}
+
+// busyPool returns a started pool whose only worker is blocked until the
+// returned function is called, and one more hash waiting in the queue
+func busyPool(t *testing.T, queueSize int, maxWait time.Duration) (*HashPool, *hashPoolRecorder, func()) {
+	t.Helper()
+	pool := NewHashPool(1, queueSize, maxWait)
+	recorder := &hashPoolRecorder{}
+	pool.SetMetrics(recorder)
+	pool.Start()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_ = pool.Run(context.Background(), func() {
+			close(started)
+			<-release
+		})
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		_ = pool.Run(context.Background(), func() {})
+	}()
+	for len(pool.jobs) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	return pool, recorder, func() {
+		close(release)
+		wg.Wait()
+		pool.Stop()
+	}
+}
+
+func TestHashPool_Run(t *testing.T) {
+	pool := NewHashPool(2, 4, time.Second)
+	recorder := &hashPoolRecorder{}
+	pool.SetMetrics(recorder)
+	pool.Start()
+	defer pool.Stop()
+
+	ran := false
+	if err := pool.Run(context.Background(), func() { ran = true }); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !ran || recorder.waits != 1 {
+		t.Errorf("ran = %v, queue waits = %d", ran, recorder.waits)
+	}
+}
+
+func TestHashPool_ShedsWhenQueueFull(t *testing.T) {
+	pool, recorder, release := busyPool(t, 1, 0)
+	defer release()
+
+	if err := pool.Run(context.Background(), func() {}); !errors.Is(err, ErrHashingOverloaded) {
+		t.Fatalf("Run() error = %v, want %v", err, ErrHashingOverloaded)
+	}
+	if len(recorder.shed) != 1 || recorder.shed[0] != HashShedQueueFull {
+		t.Errorf("shed = %v, want [%s]", recorder.shed, HashShedQueueFull)
+	}
+}
+
+func TestHashPool_ShedsBeyondWaitBudget(t *testing.T) {
+	pool, recorder, release := busyPool(t, 8, 100*time.Millisecond)
+	defer release()
+	pool.observeDuration(time.Second)
+
+	if err := pool.Run(context.Background(), func() {}); !errors.Is(err, ErrHashingOverloaded) {
+		t.Fatalf("Run() error = %v, want %v", err, ErrHashingOverloaded)
+	}
+	if len(recorder.shed) != 1 || recorder.shed[0] != HashShedWait {
+		t.Errorf("shed = %v, want [%s]", recorder.shed, HashShedWait)
+	}
+}
+
+func TestHashPool_ShedsBeyondDeadline(t *testing.T) {
+	pool, _, release := busyPool(t, 8, 0)
+	defer release()
+	pool.observeDuration(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := pool.Run(ctx, func() {}); !errors.Is(err, ErrHashingOverloaded) {
+		t.Errorf("Run() with a near deadline error = %v, want %v", err, ErrHashingOverloaded)
+	}
+}
+
+func TestHashPool_SkipsCanceledWork(t *testing.T) {
+	pool, _, release := busyPool(t, 8, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{}, 1)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- pool.Run(ctx, func() { ran <- struct{}{} })
+	}()
+	for len(pool.jobs) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+
+	release()
+	select {
+	case <-ran:
+		t.Error("canceled hash ran")
+	default:
+	}
+}
+
+func TestPasswordHasher_Pool(t *testing.T) {
+	pool := NewHashPool(1, 4, 0)
+	pool.Start()
+	defer pool.Stop()
+	hasher := NewPasswordHasher(MinCost)
+	hasher.SetPool(pool)
+	ctx := context.Background()
+
+	hash, err := hasher.HashContext(ctx, "password123")
+	if err != nil {
+		t.Fatalf("HashContext() error = %v", err)
+	}
+	if err := hasher.CompareContext(ctx, "password123", hash); err != nil {
+		t.Errorf("CompareContext() error = %v", err)
+	}
+	if err := hasher.CompareContext(ctx, "wrong", hash); err == nil {
+		t.Error("CompareContext() with the wrong password error = nil")
+	}
+}
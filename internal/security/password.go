@@ -1,13 +1,19 @@
 package security
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
 )
 
 const (
@@ -17,11 +23,27 @@ const (
 	MinCost = 10
 	// MaxCost is the maximum allowed bcrypt cost
 	MaxCost = 14
+
+	// DefaultPBKDF2Iterations is the default PBKDF2-HMAC-SHA256 work factor
+	DefaultPBKDF2Iterations = 600000
+	// MinPBKDF2Iterations is the minimum allowed PBKDF2 work factor
+	MinPBKDF2Iterations = 100000
+
+	// pbkdf2Prefix identifies PBKDF2-HMAC-SHA256 hashes
+	pbkdf2Prefix  = "$pbkdf2-sha256$"
+	pbkdf2SaltLen = 16
+	pbkdf2KeyLen  = 32
 )
 
+// ErrMismatchedPassword is returned when a password does not match its hash
+var ErrMismatchedPassword = errors.New("password does not match hash")
+
 // PasswordHasher handles password hashing and verification
 type PasswordHasher struct {
-	cost int
+	cost             int
+	pbkdf2Iterations int // hash with PBKDF2 instead of bcrypt when set
+
+	pool *HashPool // runs HashContext and CompareContext when set
 }
 
 // NewPasswordHasher creates a new password hasher with the specified cost
@@ -40,8 +62,21 @@ func NewDefaultPasswordHasher() *PasswordHasher {
 	return NewPasswordHasher(DefaultCost)
 }
 
-// Hash hashes a password using bcrypt
+// NewPBKDF2PasswordHasher creates a password hasher using the FIPS-approved
+// PBKDF2-HMAC-SHA256. Existing bcrypt hashes can still be compared.
+func NewPBKDF2PasswordHasher(iterations int) *PasswordHasher {
+	if iterations < MinPBKDF2Iterations {
+		iterations = MinPBKDF2Iterations
+	}
+	return &PasswordHasher{cost: DefaultCost, pbkdf2Iterations: iterations}
+}
+
+// Hash hashes a password using bcrypt, or PBKDF2 when configured
 func (ph *PasswordHasher) Hash(password string) (string, error) {
+	if ph.pbkdf2Iterations > 0 {
+		return ph.hashPBKDF2(password)
+	}
+
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), ph.cost)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
@@ -49,11 +84,133 @@ func (ph *PasswordHasher) Hash(password string) (string, error) {
 	return string(bytes), nil
 }
 
-// Compare compares a password with a hash
+// Compare compares a password with a hash. The algorithm is taken from the
+// hash, so bcrypt and PBKDF2 hashes can coexist.
 func (ph *PasswordHasher) Compare(password, hash string) error {
+	// Hashes of client-derived verifiers are compared with the verifier
+	if _, inner, ok := ParseClientHash(hash); ok {
+		hash = inner
+	}
+	if strings.HasPrefix(hash, pbkdf2Prefix) {
+		return comparePBKDF2(password, hash)
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
+// SetPool runs HashContext and CompareContext on pool's workers instead of
+// the calling goroutine
+func (ph *PasswordHasher) SetPool(pool *HashPool) {
+	ph.pool = pool
+}
+
+// HashContext is Hash that returns ctx.Err() once ctx is done, so a
+// canceled request does not wait for the work factor. The hash still
+// completes in the background and is discarded. With a pool it returns
+// ErrHashingOverloaded when the pool sheds the hash.
+func (ph *PasswordHasher) HashContext(ctx context.Context, password string) (string, error) {
+	return runHash(ctx, ph.pool, func() (string, error) {
+		return ph.Hash(password)
+	})
+}
+
+// CompareContext is Compare that returns ctx.Err() once ctx is done, or
+// ErrHashingOverloaded when the pool sheds the compare
+func (ph *PasswordHasher) CompareContext(ctx context.Context, password, hash string) error {
+	_, err := runHash(ctx, ph.pool, func() (struct{}, error) {
+		return struct{}{}, ph.Compare(password, hash)
+	})
+	return err
+}
+
+// runHash runs fn on pool when one is set and on its own goroutine
+// otherwise
+func runHash[T any](ctx context.Context, pool *HashPool, fn func() (T, error)) (T, error) {
+	if pool == nil {
+		return runContext(ctx, fn)
+	}
+	return runPool(ctx, pool, fn)
+}
+
+// runContext runs fn unless ctx is already done, and stops waiting for it
+// when ctx is done before fn returns
+func runContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1) // fn's goroutine never blocks on an abandoned result
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// runPool runs fn on pool and returns its result once it finished
+func runPool[T any](ctx context.Context, pool *HashPool, fn func() (T, error)) (T, error) {
+	var value T
+	var fnErr error
+	if err := pool.Run(ctx, func() { value, fnErr = fn() }); err != nil {
+		var zero T
+		return zero, err
+	}
+	return value, fnErr
+}
+
+// hashPBKDF2 returns $pbkdf2-sha256$i=<iterations>$<salt>$<key>
+func (ph *PasswordHasher) hashPBKDF2(password string) (string, error) {
+	salt := make([]byte, pbkdf2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	key := pbkdf2.Key([]byte(password), salt, ph.pbkdf2Iterations, pbkdf2KeyLen, sha256.New)
+	return fmt.Sprintf("%si=%d$%s$%s", pbkdf2Prefix, ph.pbkdf2Iterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// comparePBKDF2 checks a password against a hash produced by hashPBKDF2
+func comparePBKDF2(password, hash string) error {
+	parts := strings.Split(strings.TrimPrefix(hash, pbkdf2Prefix), "$")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "i=") {
+		return fmt.Errorf("malformed PBKDF2 hash")
+	}
+	iterations, err := strconv.Atoi(strings.TrimPrefix(parts[0], "i="))
+	if err != nil || iterations <= 0 {
+		return fmt.Errorf("malformed PBKDF2 hash")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("malformed PBKDF2 hash")
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(want) == 0 {
+		return fmt.Errorf("malformed PBKDF2 hash")
+	}
+
+	got := pbkdf2.Key([]byte(password), salt, iterations, len(want), sha256.New)
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
 // GenerateToken generates a secure random token
 func GenerateToken(length int) (string, error) {
 	if length <= 0 {
@@ -86,12 +243,21 @@ func GenerateSecureToken(byteLength int) (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
-// ConstantTimeCompare performs a constant-time comparison of two strings
+// HashToken returns the hex SHA-256 digest of a random token, for storing
+// high-entropy secrets such as device secrets without a slow password hash
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ConstantTimeCompare performs a constant-time comparison of two strings.
+// Both values are hashed first so the time taken reveals neither the length
+// nor the matching prefix of a secret. Use it for every comparison of a
+// client-supplied token, code or signature against a stored secret.
 func ConstantTimeCompare(a, b string) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
 }
 
 // ValidatePasswordStrength checks if a password meets strength requirements
@@ -1,8 +1,11 @@
 package security
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -104,6 +107,83 @@ func TestPasswordHasher_Compare(t *testing.T) {
 	}
 }
 
+func TestPasswordHasher_Context(t *testing.T) {
+	hasher := NewPasswordHasher(MinCost)
+
+	hash, err := hasher.HashContext(context.Background(), "password123")
+	if err != nil {
+		t.Fatalf("HashContext() error = %v", err)
+	}
+	if err := hasher.CompareContext(context.Background(), "password123", hash); err != nil {
+		t.Errorf("CompareContext() error = %v", err)
+	}
+	if err := hasher.CompareContext(context.Background(), "wrong", hash); err == nil {
+		t.Error("CompareContext() with wrong password should fail")
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := hasher.HashContext(canceled, "password123"); !errors.Is(err, context.Canceled) {
+		t.Errorf("HashContext() error = %v, want %v", err, context.Canceled)
+	}
+	if err := hasher.CompareContext(canceled, "password123", hash); !errors.Is(err, context.Canceled) {
+		t.Errorf("CompareContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	// A deadline shorter than the work factor stops the wait
+	expiring, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	slow := NewPasswordHasher(MaxCost)
+	if _, err := slow.HashContext(expiring, "password123"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("HashContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPBKDF2PasswordHasher(t *testing.T) {
+	if got := NewPBKDF2PasswordHasher(1000).pbkdf2Iterations; got != MinPBKDF2Iterations {
+		t.Errorf("NewPBKDF2PasswordHasher() iterations = %d, want %d", got, MinPBKDF2Iterations)
+	}
+
+	ph := NewPBKDF2PasswordHasher(MinPBKDF2Iterations)
+	password := "testPassword123"
+	hash, err := ph.Hash(password)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(hash, "$pbkdf2-sha256$i=100000$") {
+		t.Errorf("Hash() = %q, want PBKDF2 format", hash)
+	}
+	if again, _ := ph.Hash(password); again == hash {
+		t.Error("Hash() should use a fresh salt per password")
+	}
+
+	bcryptHash, _ := NewPasswordHasher(MinCost).Hash(password)
+	parts := strings.Split(hash, "$")
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		wantErr  bool
+	}{
+		{"valid password", password, hash, false},
+		{"wrong password", "wrongPassword", hash, true},
+		{"existing bcrypt hash", password, bcryptHash, false},
+		{"tampered key", password, strings.Join(append(parts[:len(parts)-1], "AAAA"), "$"), true},
+		{"missing iterations", password, "$pbkdf2-sha256$" + parts[3] + "$" + parts[4], true},
+		{"bad salt", password, "$pbkdf2-sha256$i=100000$!!$" + parts[4], true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ph.Compare(tt.password, tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestGenerateToken(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -267,3 +347,11 @@ func BenchmarkPasswordHasher_Compare(b *testing.B) {
 		}
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	// SHA-256 of "abc"
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := HashToken("abc"); got != want {
+		t.Errorf("HashToken() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package security
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRequestSigningKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSignRequest_Verify(t *testing.T) {
+	now := time.Now()
+	body := `{"name":"deploy"}`
+
+	newSigned := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/dormancy/runs?dry_run=true", strings.NewReader(body))
+		if err := SignRequest(req, "ak_test", testRequestSigningKey, now); err != nil {
+			t.Fatalf("SignRequest() error = %v", err)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name       string
+		tamper     func(req *http.Request)
+		secret     []byte
+		at         time.Time
+		wantParse  error
+		wantVerify error
+	}{
+		{name: "valid signature", secret: testRequestSigningKey, at: now},
+		{
+			name:       "wrong secret",
+			secret:     []byte("another key"),
+			at:         now,
+			wantVerify: ErrInvalidRequestSignature,
+		},
+		{
+			name:       "outside replay window",
+			secret:     testRequestSigningKey,
+			at:         now.Add(10 * time.Minute),
+			wantVerify: ErrInvalidRequestSignature,
+		},
+		{
+			name:       "tampered query",
+			tamper:     func(req *http.Request) { req.URL.RawQuery = "dry_run=false" },
+			secret:     testRequestSigningKey,
+			at:         now,
+			wantVerify: ErrInvalidRequestSignature,
+		},
+		{
+			name:      "tampered body",
+			tamper:    func(req *http.Request) { req.Body = io.NopCloser(strings.NewReader(`{"name":"other"}`)) },
+			wantParse: ErrInvalidRequestSignature,
+		},
+		{
+			name:      "missing date",
+			tamper:    func(req *http.Request) { req.Header.Del("Date") },
+			wantParse: ErrInvalidRequestSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newSigned()
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			signed, ok, err := ParseSignedRequest(req)
+			if !ok {
+				t.Fatal("ParseSignedRequest() did not detect the signature")
+			}
+			if !errors.Is(err, tt.wantParse) {
+				t.Fatalf("ParseSignedRequest() error = %v, want %v", err, tt.wantParse)
+			}
+			if err != nil {
+				return
+			}
+
+			if err := signed.Verify(req, tt.secret, tt.at, 5*time.Minute); !errors.Is(err, tt.wantVerify) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantVerify)
+			}
+			if signed.KeyID != "ak_test" {
+				t.Errorf("KeyID = %q, want ak_test", signed.KeyID)
+			}
+
+			// The handler can still read the body
+			if got, _ := io.ReadAll(req.Body); string(got) != body {
+				t.Errorf("body after parsing = %q, want %q", got, body)
+			}
+		})
+	}
+}
+
+func TestParseSignedRequest_Unsigned(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/dormancy/runs", nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	if _, ok, err := ParseSignedRequest(req); ok || err != nil {
+		t.Errorf("ParseSignedRequest() = %v, %v; want unsigned", ok, err)
+	}
+}
+
+func TestContentDigest(t *testing.T) {
+	// SHA-256 of the empty string
+	want := "sha-256=:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=:"
+	if got := ContentDigest(nil); got != want {
+		t.Errorf("ContentDigest() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package security
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testURLSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestNewURLSigner(t *testing.T) {
+	if _, err := NewURLSigner("short"); err == nil {
+		t.Error("NewURLSigner() with short key should fail")
+	}
+	if _, err := NewURLSigner(testURLSigningKey); err != nil {
+		t.Errorf("NewURLSigner() error = %v", err)
+	}
+}
+
+func TestURLSigner_SignVerify(t *testing.T) {
+	signer, err := NewURLSigner(testURLSigningKey)
+	if err != nil {
+		t.Fatalf("NewURLSigner() error = %v", err)
+	}
+	now := time.Now()
+
+	signed, err := signer.Sign("PUT", "https://uploads.example.com/avatars/user-1/abc?content_type=image%2Fpng", now.Add(5*time.Minute))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	tamper := func(param, value string) string {
+		u, _ := url.Parse(signed)
+		q := u.Query()
+		q.Set(param, value)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		now     time.Time
+		wantErr error
+	}{
+		{name: "valid", method: "PUT", url: signed, now: now},
+		{name: "method is case-insensitive", method: "put", url: signed, now: now},
+		{name: "wrong method", method: "GET", url: signed, now: now, wantErr: ErrInvalidSignature},
+		{name: "expired", method: "PUT", url: signed, now: now.Add(5 * time.Minute), wantErr: ErrSignedURLExpired},
+		{name: "other path", method: "PUT", url: strings.Replace(signed, "/abc", "/xyz", 1), now: now, wantErr: ErrInvalidSignature},
+		{name: "other host", method: "PUT", url: strings.Replace(signed, "uploads.example.com", "evil.example.com", 1), now: now, wantErr: ErrInvalidSignature},
+		{name: "extended expiry", method: "PUT", url: tamper(SignedURLExpiresParam, "9999999999"), now: now, wantErr: ErrInvalidSignature},
+		{name: "changed query", method: "PUT", url: tamper("content_type", "text/html"), now: now, wantErr: ErrInvalidSignature},
+		{name: "missing signature", method: "PUT", url: tamper(SignedURLSignatureParam, ""), now: now, wantErr: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signer.Verify(tt.method, tt.url, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	other, _ := NewURLSigner(strings.Repeat("x", MinURLSigningKeyLength))
+	if err := other.Verify("PUT", signed, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() with another key error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
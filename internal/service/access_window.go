@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// SetAccessWindows limits users' access tokens to the daily windows admins
+// set for them. The window travels in the token, so a change applies to
+// tokens issued after it.
+func (s *AuthService) SetAccessWindows(windows repository.AccessWindowRepository) {
+	s.accessWindows = windows
+}
+
+// AccessWindowsEnabled reports whether access windows are enforced
+func (s *AuthService) AccessWindowsEnabled() bool {
+	return s.accessWindows != nil
+}
+
+// AccessWindow returns the user's access window, or
+// domain.ErrAccessWindowNotFound when access is not limited
+func (s *AuthService) AccessWindow(ctx context.Context, userID string) (*domain.AccessWindow, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.accessWindows.Get(ctx, userID)
+}
+
+// SetAccessWindow limits the user's access to a daily window and returns it.
+// The change is audited.
+func (s *AuthService) SetAccessWindow(ctx context.Context, userID string, window domain.AccessWindow) (*domain.AccessWindow, error) {
+	window.Timezone = strings.TrimSpace(window.Timezone)
+	if err := window.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	window.UpdatedAt = s.clock.Now()
+	if err := s.accessWindows.Set(ctx, userID, &window); err != nil {
+		return nil, err
+	}
+	s.auditAccessWindowUpdate(ctx, userID, &window)
+	return &window, nil
+}
+
+// ClearAccessWindow lifts the user's access window. The change is audited.
+func (s *AuthService) ClearAccessWindow(ctx context.Context, userID string) error {
+	if err := s.accessWindows.Delete(ctx, userID); err != nil {
+		return err
+	}
+	s.auditAccessWindowUpdate(ctx, userID, nil)
+	return nil
+}
+
+// RecordAccessWindowDenied audits a request rejected for falling outside the
+// caller's access window; failures are only logged
+func (s *AuthService) RecordAccessWindowDenied(ctx context.Context, userID, ipAddress string, window domain.AccessWindow) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	resourceType := "user"
+	entry := domain.NewAuditLog(domain.AuditActionAccessWindowDenied, domain.AuditStatusBlocked)
+	entry.UserID = &userID
+	entry.ResourceType = &resourceType
+	entry.ResourceID = &userID
+	if ipAddress != "" {
+		entry.IPAddress = &ipAddress
+	}
+	entry.Metadata["start"] = window.Start
+	entry.Metadata["end"] = window.End
+	entry.Metadata["timezone"] = window.Timezone
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write access window audit log", "user_id", userID, "error", err)
+	}
+}
+
+// tokenAccessWindow returns the access window claim of the user's tokens, or
+// nil when access is not limited. A window without a time zone follows the
+// user's profile timezone, or UTC.
+func (s *AuthService) tokenAccessWindow(ctx context.Context, user *domain.User) (*token.AccessWindow, error) {
+	if s.accessWindows == nil {
+		return nil, nil
+	}
+	window, err := s.accessWindows.Get(ctx, user.ID)
+	if errors.Is(err, domain.ErrAccessWindowNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access window: %w", err)
+	}
+
+	timezone := window.Timezone
+	if timezone == "" {
+		timezone = stringValue(user.Timezone)
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	return &token.AccessWindow{Start: window.Start, End: window.End, Timezone: timezone}, nil
+}
+
+// auditAccessWindowUpdate records an admin setting or, with a nil window,
+// lifting a user's access window. Failures are only logged.
+func (s *AuthService) auditAccessWindowUpdate(ctx context.Context, userID string, window *domain.AccessWindow) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	resourceType := "user"
+	entry := domain.NewAuditLog(domain.AuditActionAccessWindowUpdate, domain.AuditStatusSuccess)
+	entry.UserID = &userID
+	entry.ResourceType = &resourceType
+	entry.ResourceID = &userID
+	if window != nil {
+		entry.Metadata["start"] = window.Start
+		entry.Metadata["end"] = window.End
+		entry.Metadata["timezone"] = window.Timezone
+	} else {
+		entry.Metadata["cleared"] = true
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write access window audit log", "user_id", userID, "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockAccessWindowRepository keeps access windows in memory
+type mockAccessWindowRepository struct {
+	windows map[string]*domain.AccessWindow
+}
+
+func (m *mockAccessWindowRepository) Get(ctx context.Context, userID string) (*domain.AccessWindow, error) {
+	window, ok := m.windows[userID]
+	if !ok {
+		return nil, domain.ErrAccessWindowNotFound
+	}
+	return window, nil
+}
+
+func (m *mockAccessWindowRepository) Set(ctx context.Context, userID string, window *domain.AccessWindow) error {
+	m.windows[userID] = window
+	return nil
+}
+
+func (m *mockAccessWindowRepository) Delete(ctx context.Context, userID string) error {
+	if _, ok := m.windows[userID]; !ok {
+		return domain.ErrAccessWindowNotFound
+	}
+	delete(m.windows, userID)
+	return nil
+}
+
+func TestAuthService_AccessWindow(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	audit := &mockAuditLogRepository{}
+	service.SetAuditLog(audit)
+	service.SetAccessWindows(&mockAccessWindowRepository{windows: map[string]*domain.AccessWindow{}})
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "contractor@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	userID := signup.UserID
+	timezone := "America/Bogota"
+	userRepo.users["contractor@example.com"].Timezone = &timezone
+
+	if _, err := service.SetAccessWindow(ctx, userID, domain.AccessWindow{Start: "8am", End: "18:00"}); !errors.Is(err, domain.ErrInvalidAccessWindow) {
+		t.Fatalf("SetAccessWindow() with an invalid start error = %v, want %v", err, domain.ErrInvalidAccessWindow)
+	}
+	if _, err := service.SetAccessWindow(ctx, "user-unknown", domain.AccessWindow{Start: "08:00", End: "18:00"}); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("SetAccessWindow() for an unknown user error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	window, err := service.SetAccessWindow(ctx, userID, domain.AccessWindow{Start: "08:00", End: "18:00"})
+	if err != nil {
+		t.Fatalf("SetAccessWindow() error = %v", err)
+	}
+	if window.UpdatedAt.IsZero() {
+		t.Error("SetAccessWindow() did not set UpdatedAt")
+	}
+
+	// Tokens carry the window, in the user's timezone when it has none
+	login, err := service.Login(ctx, LoginInput{Email: "contractor@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	claims, err := service.tokenManager.ValidateAccessToken(login.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if w := claims.AccessWindow; w == nil || w.Start != "08:00" || w.End != "18:00" || w.Timezone != timezone {
+		t.Errorf("access window claim = %+v", claims.AccessWindow)
+	}
+
+	if err := service.ClearAccessWindow(ctx, userID); err != nil {
+		t.Fatalf("ClearAccessWindow() error = %v", err)
+	}
+	if _, err := service.AccessWindow(ctx, userID); !errors.Is(err, domain.ErrAccessWindowNotFound) {
+		t.Errorf("AccessWindow() after clearing error = %v, want %v", err, domain.ErrAccessWindowNotFound)
+	}
+	login, err = service.Login(ctx, LoginInput{Email: "contractor@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if claims, _ := service.tokenManager.ValidateAccessToken(login.AccessToken); claims.AccessWindow != nil {
+		t.Errorf("access window claim after clearing = %+v", claims.AccessWindow)
+	}
+
+	service.RecordAccessWindowDenied(ctx, userID, "203.0.113.7", *window)
+
+	var actions []string
+	for _, log := range audit.logs {
+		if log.Action == domain.AuditActionAccessWindowUpdate || log.Action == domain.AuditActionAccessWindowDenied {
+			actions = append(actions, log.Action+"/"+log.Status)
+		}
+	}
+	want := []string{
+		domain.AuditActionAccessWindowUpdate + "/" + domain.AuditStatusSuccess,
+		domain.AuditActionAccessWindowUpdate + "/" + domain.AuditStatusSuccess,
+		domain.AuditActionAccessWindowDenied + "/" + domain.AuditStatusBlocked,
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("audited %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("audited %v, want %v", actions, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// signingKeyIDPrefix marks admin signing key ids
+const signingKeyIDPrefix = "ak_"
+
+// AdminKeyService manages the keys automation uses to sign admin API
+// requests. A key's secret is derived from the server's signing secret and
+// its id, so only key metadata is stored and every instance sharing the
+// secret can verify any key. Changing the server secret invalidates all keys.
+type AdminKeyService struct {
+	repo      repository.AdminSigningKeyRepository
+	auditRepo repository.AuditLogRepository
+	secret    []byte
+	now       func() time.Time
+}
+
+// NewAdminKeyService creates an admin key service deriving key secrets from secret
+func NewAdminKeyService(repo repository.AdminSigningKeyRepository, secret string) *AdminKeyService {
+	return &AdminKeyService{
+		repo:   repo,
+		secret: []byte(secret),
+		now:    time.Now,
+	}
+}
+
+// SetAuditLog records created and revoked keys in the audit trail
+func (s *AdminKeyService) SetAuditLog(auditRepo repository.AuditLogRepository) {
+	s.auditRepo = auditRepo
+}
+
+// CreateKey creates a signing key and returns it with its secret. The secret
+// is not stored and cannot be shown again.
+func (s *AdminKeyService) CreateKey(ctx context.Context, name string) (*domain.AdminSigningKey, string, error) {
+	id, err := security.GenerateToken(12)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate key id: %w", err)
+	}
+
+	key := &domain.AdminSigningKey{
+		ID:        signingKeyIDPrefix + id,
+		Name:      name,
+		CreatedAt: s.now(),
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	s.audit(ctx, domain.AuditActionSigningKeyCreate, key)
+	return key, s.keySecret(key.ID), nil
+}
+
+// ListKeys returns all signing keys, newest first
+func (s *AdminKeyService) ListKeys(ctx context.Context) ([]*domain.AdminSigningKey, error) {
+	return s.repo.List(ctx)
+}
+
+// RevokeKey revokes a signing key; requests signed with it are rejected
+// from then on
+func (s *AdminKeyService) RevokeKey(ctx context.Context, id string) error {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Revoke(ctx, id, s.now()); err != nil {
+		return err
+	}
+
+	s.audit(ctx, domain.AuditActionSigningKeyRevoke, key)
+	return nil
+}
+
+// SigningSecret returns the secret of an active key. Unknown and revoked
+// keys return domain.ErrSigningKeyNotFound.
+func (s *AdminKeyService) SigningSecret(ctx context.Context, id string) ([]byte, error) {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return nil, domain.ErrSigningKeyNotFound
+	}
+	return []byte(s.keySecret(id)), nil
+}
+
+// keySecret derives the secret of a key from the server secret
+func (s *AdminKeyService) keySecret(id string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("admin-signing-key\n"))
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// audit records a key management action; failures are only logged
+func (s *AdminKeyService) audit(ctx context.Context, action string, key *domain.AdminSigningKey) {
+	if s.auditRepo == nil {
+		return
+	}
+	resourceType := "admin_signing_key"
+	entry := domain.NewAuditLog(action, domain.AuditStatusSuccess)
+	entry.ResourceType = &resourceType
+	entry.ResourceID = &key.ID
+	entry.Metadata["name"] = key.Name
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write signing key audit log", "key_id", key.ID, "action", action, "error", err)
+	}
+}
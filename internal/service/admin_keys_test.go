@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockAdminSigningKeyRepository keeps signing keys in memory
+type mockAdminSigningKeyRepository struct {
+	keys map[string]*domain.AdminSigningKey
+}
+
+func (m *mockAdminSigningKeyRepository) Create(ctx context.Context, key *domain.AdminSigningKey) error {
+	copied := *key
+	m.keys[key.ID] = &copied
+	return nil
+}
+
+func (m *mockAdminSigningKeyRepository) GetByID(ctx context.Context, id string) (*domain.AdminSigningKey, error) {
+	key, ok := m.keys[id]
+	if !ok {
+		return nil, domain.ErrSigningKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (m *mockAdminSigningKeyRepository) List(ctx context.Context) ([]*domain.AdminSigningKey, error) {
+	var keys []*domain.AdminSigningKey
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (m *mockAdminSigningKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	key, ok := m.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return domain.ErrSigningKeyNotFound
+	}
+	key.RevokedAt = &at
+	return nil
+}
+
+func TestAdminKeyService(t *testing.T) {
+	repo := &mockAdminSigningKeyRepository{keys: make(map[string]*domain.AdminSigningKey)}
+	audit := &mockAuditLogRepository{}
+	keys := NewAdminKeyService(repo, "fedcba9876543210fedcba9876543210")
+	keys.SetAuditLog(audit)
+	ctx := context.Background()
+
+	key, secret, err := keys.CreateKey(ctx, "ci deploy")
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+	if !strings.HasPrefix(key.ID, signingKeyIDPrefix) || key.Name != "ci deploy" || secret == "" {
+		t.Errorf("unexpected key %+v with secret %q", key, secret)
+	}
+
+	// The secret is derived, so it can be recomputed for verification
+	got, err := keys.SigningSecret(ctx, key.ID)
+	if err != nil || string(got) != secret {
+		t.Errorf("SigningSecret() = %q, %v; want the secret returned on creation", got, err)
+	}
+
+	// Another server secret derives another key secret
+	other := NewAdminKeyService(repo, "another-secret-another-secret-xx")
+	if got, _ := other.SigningSecret(ctx, key.ID); string(got) == secret {
+		t.Error("key secret does not depend on the server secret")
+	}
+
+	if err := keys.RevokeKey(ctx, key.ID); err != nil {
+		t.Fatalf("RevokeKey() error = %v", err)
+	}
+	if _, err := keys.SigningSecret(ctx, key.ID); !errors.Is(err, domain.ErrSigningKeyNotFound) {
+		t.Errorf("SigningSecret() of revoked key error = %v, want ErrSigningKeyNotFound", err)
+	}
+	if err := keys.RevokeKey(ctx, "ak_unknown"); !errors.Is(err, domain.ErrSigningKeyNotFound) {
+		t.Errorf("RevokeKey() of unknown key error = %v, want ErrSigningKeyNotFound", err)
+	}
+
+	if len(audit.logs) != 2 ||
+		audit.logs[0].Action != domain.AuditActionSigningKeyCreate ||
+		audit.logs[1].Action != domain.AuditActionSigningKeyRevoke {
+		t.Errorf("unexpected audit logs: %+v", audit.logs)
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// auditChainPageSize is the number of entries read per verification query
+const auditChainPageSize = 1000
+
+// AuditChainBreak describes one place where the audit chain does not verify
+type AuditChainBreak struct {
+	Seq    int64  `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+// AuditChainReport is the result of verifying the audit chain
+type AuditChainReport struct {
+	Checked int64              `json:"checked"`
+	Head    domain.AuditAnchor `json:"head"`
+	Breaks  []AuditChainBreak  `json:"breaks,omitempty"`
+}
+
+// OK reports whether the chain verified without breaks
+func (r *AuditChainReport) OK() bool {
+	return len(r.Breaks) == 0
+}
+
+func (r *AuditChainReport) addBreak(seq int64, format string, args ...any) {
+	r.Breaks = append(r.Breaks, AuditChainBreak{Seq: seq, Reason: fmt.Sprintf(format, args...)})
+}
+
+// VerifyAuditChain walks the whole audit chain, recomputing every entry's
+// hash and checking it links to its predecessor. Each anchor must match the
+// entry at its position; anchors past the head mean the chain was truncated.
+func VerifyAuditChain(ctx context.Context, repo repository.AuditChainRepository, anchors []domain.AuditAnchor) (*AuditChainReport, error) {
+	pending := make(map[int64]string, len(anchors))
+	for _, a := range anchors {
+		pending[a.Seq] = a.Hash
+	}
+
+	report := &AuditChainReport{}
+	for {
+		logs, err := repo.ListChain(ctx, report.Head.Seq, auditChainPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, log := range logs {
+			if want := report.Head.Seq + 1; log.ChainSeq != want {
+				report.addBreak(log.ChainSeq, "entries %d to %d are missing", want, log.ChainSeq-1)
+			} else if log.PrevHash != report.Head.Hash {
+				report.addBreak(log.ChainSeq, "previous hash does not match entry %d", report.Head.Seq)
+			}
+
+			hash, err := log.ComputeHash()
+			if err != nil {
+				return nil, err
+			}
+			if hash != log.Hash {
+				report.addBreak(log.ChainSeq, "content does not match its hash")
+			}
+			if log.UserID != nil && domain.AuditUserDigest(log.UserID) != log.UserDigest {
+				report.addBreak(log.ChainSeq, "user does not match its digest")
+			}
+
+			if anchored, ok := pending[log.ChainSeq]; ok {
+				if anchored != log.Hash {
+					report.addBreak(log.ChainSeq, "hash does not match the published anchor")
+				}
+				delete(pending, log.ChainSeq)
+			}
+
+			report.Checked++
+			report.Head = domain.AuditAnchor{Seq: log.ChainSeq, Hash: log.Hash}
+		}
+
+		if len(logs) < auditChainPageSize {
+			break
+		}
+	}
+
+	missing := make([]int64, 0, len(pending))
+	for seq := range pending {
+		missing = append(missing, seq)
+	}
+	slices.Sort(missing)
+	for _, seq := range missing {
+		report.addBreak(seq, "anchored entry is missing")
+	}
+	return report, nil
+}
+
+// AuditAnchorEvent is the payload posted to the anchor webhook
+type AuditAnchorEvent struct {
+	Seq        int64     `json:"seq"`
+	Hash       string    `json:"hash"`
+	AnchoredAt time.Time `json:"anchored_at"`
+}
+
+// AuditAnchorer periodically publishes the audit chain head outside the
+// database: to the log and, when configured, to a signed webhook. A verifier
+// given these anchors detects a chain that was rewritten in full.
+type AuditAnchorer struct {
+	repo   repository.AuditChainRepository
+	logger *slog.Logger
+
+	client *httpclient.Client
+	url    string
+	secret []byte
+
+	mu   sync.Mutex
+	last domain.AuditAnchor
+}
+
+// NewAuditAnchorer creates an anchorer for the given chain
+func NewAuditAnchorer(repo repository.AuditChainRepository, logger *slog.Logger) *AuditAnchorer {
+	return &AuditAnchorer{repo: repo, logger: logger}
+}
+
+// SetWebhook posts every new anchor to url, signed like quota webhooks
+func (a *AuditAnchorer) SetWebhook(client *httpclient.Client, url, secret string) {
+	a.client = client
+	a.url = url
+	a.secret = []byte(secret)
+}
+
+// Anchor publishes the current chain head. Nothing is published while the
+// chain is empty or has not grown since the last anchor.
+func (a *AuditAnchorer) Anchor(ctx context.Context) (domain.AuditAnchor, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	head, err := a.repo.ChainHead(ctx)
+	if err != nil {
+		return domain.AuditAnchor{}, err
+	}
+	if head.Seq == 0 || head == a.last {
+		return head, nil
+	}
+
+	a.logger.Info("Audit chain anchor", "anchor", head.String())
+	if a.url != "" {
+		body, err := json.Marshal(AuditAnchorEvent{Seq: head.Seq, Hash: head.Hash, AnchoredAt: time.Now().UTC()})
+		if err != nil {
+			return domain.AuditAnchor{}, fmt.Errorf("failed to encode audit anchor: %w", err)
+		}
+		if err := postWebhook(ctx, a.client, a.url, a.secret, time.Now(), body); err != nil {
+			return domain.AuditAnchor{}, err
+		}
+	}
+
+	a.last = head
+	return head, nil
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// mockAuditChainRepository holds a chain in memory
+type mockAuditChainRepository struct {
+	logs []*domain.AuditLog
+}
+
+// append chains an entry the way the postgres repository does
+func (m *mockAuditChainRepository) append(t *testing.T, log *domain.AuditLog) {
+	t.Helper()
+	head, _ := m.ChainHead(context.Background())
+	log.ChainSeq = head.Seq + 1
+	log.PrevHash = head.Hash
+	log.UserDigest = domain.AuditUserDigest(log.UserID)
+	hash, err := log.ComputeHash()
+	if err != nil {
+		t.Fatalf("ComputeHash() error = %v", err)
+	}
+	log.Hash = hash
+	m.logs = append(m.logs, log)
+}
+
+// rehash recomputes every hash from the first entry on, as an attacker with
+// write access to the table could
+func (m *mockAuditChainRepository) rehash(t *testing.T) {
+	logs := m.logs
+	m.logs = nil
+	for _, log := range logs {
+		m.append(t, log)
+	}
+}
+
+func (m *mockAuditChainRepository) ChainHead(ctx context.Context) (domain.AuditAnchor, error) {
+	if len(m.logs) == 0 {
+		return domain.AuditAnchor{}, nil
+	}
+	last := m.logs[len(m.logs)-1]
+	return domain.AuditAnchor{Seq: last.ChainSeq, Hash: last.Hash}, nil
+}
+
+func (m *mockAuditChainRepository) ListChain(ctx context.Context, afterSeq int64, limit int) ([]*domain.AuditLog, error) {
+	var logs []*domain.AuditLog
+	for _, log := range m.logs {
+		if log.ChainSeq > afterSeq && len(logs) < limit {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func newTestAuditChain(t *testing.T, n int) *mockAuditChainRepository {
+	repo := &mockAuditChainRepository{}
+	start := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		userID := testUUID(i%3 + 1)
+		log := domain.NewAuditLog(domain.AuditActionSessionsRevoked, domain.AuditStatusSuccess)
+		log.UserID = &userID
+		log.Metadata["reason"] = RevokeReasonPasswordChange
+		log.CreatedAt = start.Add(time.Duration(i) * time.Second)
+		repo.append(t, log)
+	}
+	return repo
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		entries    int
+		tamper     func(t *testing.T, repo *mockAuditChainRepository) []domain.AuditAnchor
+		wantBreaks []int64
+	}{
+		{
+			name:    "intact chain across pages",
+			entries: auditChainPageSize + 5,
+		},
+		{
+			name:    "empty chain",
+			entries: 0,
+		},
+		{
+			name:    "user deleted",
+			entries: 3,
+			tamper: func(t *testing.T, repo *mockAuditChainRepository) []domain.AuditAnchor {
+				repo.logs[1].UserID = nil
+				return nil
+			},
+		},
+		{
+			name:    "modified entry",
+			entries: 5,
+			tamper: func(t *testing.T, repo *mockAuditChainRepository) []domain.AuditAnchor {
+				repo.logs[2].Status = domain.AuditStatusFailure
+				return nil
+			},
+			wantBreaks: []int64{3},
+		},
+		{
+			name:    "reassigned user",
+			entries: 3,
+			tamper: func(t *testing.T, repo *mockAuditChainRepository) []domain.AuditAnchor {
+				other := testUUID(9)
+				repo.logs[0].UserID = &other
+				return nil
+			},
+			wantBreaks: []int64{1},
+		},
+		{
+			name:    "deleted entry",
+			entries: 5,
+			tamper: func(t *testing.T, repo *mockAuditChainRepository) []domain.AuditAnchor {
+				repo.logs = append(repo.logs[:1], repo.logs[2:]...)
+				return nil
+			},
+			wantBreaks: []int64{3},
+		},
+		{
+			name:    "truncated after anchor",
+			entries: 5,
+			tamper: func(t *testing.T, repo *mockAuditChainRepository) []domain.AuditAnchor {
+				anchor, _ := repo.ChainHead(context.Background())
+				repo.logs = repo.logs[:3]
+				return []domain.AuditAnchor{anchor}
+			},
+			wantBreaks: []int64{5},
+		},
+		{
+			name:    "rewritten chain caught by anchor",
+			entries: 5,
+			tamper: func(t *testing.T, repo *mockAuditChainRepository) []domain.AuditAnchor {
+				anchor := domain.AuditAnchor{Seq: 4, Hash: repo.logs[3].Hash}
+				repo.logs[1].Metadata["reason"] = "tampered"
+				repo.rehash(t)
+				return []domain.AuditAnchor{anchor}
+			},
+			wantBreaks: []int64{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestAuditChain(t, tt.entries)
+			var anchors []domain.AuditAnchor
+			if tt.tamper != nil {
+				anchors = tt.tamper(t, repo)
+			}
+
+			report, err := VerifyAuditChain(context.Background(), repo, anchors)
+			if err != nil {
+				t.Fatalf("VerifyAuditChain() error = %v", err)
+			}
+
+			var got []int64
+			for _, b := range report.Breaks {
+				got = append(got, b.Seq)
+			}
+			if len(got) != len(tt.wantBreaks) {
+				t.Fatalf("breaks = %+v, want entries %v", report.Breaks, tt.wantBreaks)
+			}
+			for i := range got {
+				if got[i] != tt.wantBreaks[i] {
+					t.Errorf("breaks = %+v, want entries %v", report.Breaks, tt.wantBreaks)
+				}
+			}
+			if report.OK() != (len(tt.wantBreaks) == 0) {
+				t.Errorf("OK() = %v with %d breaks", report.OK(), len(report.Breaks))
+			}
+			if tt.tamper == nil && report.Checked != int64(tt.entries) {
+				t.Errorf("Checked = %d, want %d", report.Checked, tt.entries)
+			}
+		})
+	}
+}
+
+func TestAuditAnchorer(t *testing.T) {
+	secret := "0123456789abcdef0123456789abcdef"
+	var received []AuditAnchorEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		want := "sha256=" + SignWebhook([]byte(secret), r.Header.Get("X-Webhook-Timestamp"), body)
+		if !security.ConstantTimeCompare(r.Header.Get("X-Webhook-Signature"), want) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event AuditAnchorEvent
+		json.Unmarshal(body, &event)
+		received = append(received, event)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := httpclient.DefaultConfig()
+	config.MaxRetries = 0
+	client, err := httpclient.New(config, slog.Default())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	var logs strings.Builder
+	repo := &mockAuditChainRepository{}
+	anchorer := NewAuditAnchorer(repo, slog.New(slog.NewTextHandler(&logs, nil)))
+	anchorer.SetWebhook(client, server.URL, secret)
+	ctx := context.Background()
+
+	// An empty chain is not anchored
+	if _, err := anchorer.Anchor(ctx); err != nil || len(received) != 0 {
+		t.Fatalf("Anchor() on empty chain: err = %v, %d webhooks", err, len(received))
+	}
+
+	repo.logs = newTestAuditChain(t, 3).logs
+	head, err := anchorer.Anchor(ctx)
+	if err != nil {
+		t.Fatalf("Anchor() error = %v", err)
+	}
+	if head.Seq != 3 || len(received) != 1 || received[0].Seq != 3 || received[0].Hash != head.Hash {
+		t.Errorf("anchor %+v, received %+v", head, received)
+	}
+	if !strings.Contains(logs.String(), head.String()) {
+		t.Errorf("anchor not logged: %s", logs.String())
+	}
+
+	// An unchanged head is not published again
+	if _, err := anchorer.Anchor(ctx); err != nil || len(received) != 1 {
+		t.Errorf("Anchor() without new entries: err = %v, %d webhooks", err, len(received))
+	}
+
+	// A rejected webhook is reported and retried on the next run
+	anchorer.SetWebhook(client, server.URL, "another-secret")
+	repo.append(t, domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusBlocked))
+	if _, err := anchorer.Anchor(ctx); err == nil {
+		t.Error("expected error for rejected webhook")
+	}
+	anchorer.SetWebhook(client, server.URL, secret)
+	if head, err := anchorer.Anchor(ctx); err != nil || head.Seq != 4 || len(received) != 2 {
+		t.Errorf("Anchor() retry: head %+v, err = %v, %d webhooks", head, err, len(received))
+	}
+}
@@ -1,24 +1,93 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/emailnorm"
+	"github.com/n1rocket/go-auth-jwt/internal/emailtrack"
+	"github.com/n1rocket/go-auth-jwt/internal/ids"
 	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/risk"
+	"github.com/n1rocket/go-auth-jwt/internal/rollout"
 	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/sms"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
 )
 
 // AuthService handles authentication operations
 type AuthService struct {
-	userRepo         repository.UserRepository
-	refreshTokenRepo repository.RefreshTokenRepository
-	passwordHasher   *security.PasswordHasher
-	tokenManager     *token.Manager
-	refreshTokenTTL  time.Duration
+	userRepo            repository.UserRepository
+	refreshTokenRepo    repository.RefreshTokenRepository
+	passwordHasher      *security.PasswordHasher
+	tokenManager        *token.Manager
+	refreshTokenTTL     time.Duration
+	idTokenAudience     string
+	riskEngine          *risk.Engine
+	auditRepo           repository.AuditLogRepository
+	captchaVerifier     CaptchaVerifier
+	emailPolicy         EmailPolicy
+	deliverability      DeliverabilityChecker
+	rejectUndeliverable bool
+	emailNormalizer     emailnorm.Normalizer
+	avatarSigner        *security.URLSigner
+	avatarBaseURL       string
+	avatarUploadTTL     time.Duration
+	minResponseTime     time.Duration
+	dummyHashOnce       sync.Once
+	dummyHash           string
+	enumerationSafe     bool
+	activity            ActivityRecorder
+	tokenVersions       repository.TokenVersionRepository
+	accessTokenDenylist repository.AccessTokenDenylistRepository
+	emailCodes          repository.EmailCodeRepository
+	emailCodePolicy     CodePolicy
+	smsSender           sms.Sender
+	smsCodes            repository.EmailCodeRepository
+	smsCodePolicy       CodePolicy
+	smsAppName          string
+	trustedDevices      repository.TrustedDeviceRepository
+	loginApprovals      repository.LoginApprovalRepository
+	loginApprovalTTL    time.Duration
+	approvalNotifier    ApprovalNotifier
+	sessionHandoffs     repository.SessionHandoffRepository
+	sessionHandoffTTL   time.Duration
+	userMetadata        repository.UserMetadataRepository
+	metadataMaxBytes    int
+	metadataClaims      map[domain.MetadataKind][]string
+	deviceAuths         repository.DeviceAuthorizationRepository
+	deviceCodeTTL       time.Duration
+	deviceVerifyURI     string
+	resends             repository.VerificationResendRepository
+	resendCooldown      time.Duration
+	resendDailyLimit    int
+	resendRecorder      ResendRecorder
+	idGenerator         ids.Generator
+	refreshGrace        *refreshGrace
+	authRecorder        AuthRecorder
+	securityEvents      repository.AuditLogReader
+	consents            repository.ConsentRepository
+	termsVersion        string
+	emailTracker        *emailtrack.Tracker
+	trackingOptOuts     repository.EmailTrackingOptOutRepository
+	rememberMe          RememberMePolicy
+	userEvents          repository.UserEventRepository
+	userEventNotifier   UserEventNotifier
+	rollout             *rollout.Flags
+	rolloutRecorder     RolloutRecorder
+	sessionStats        repository.SessionStatsReader
+	accessWindows       repository.AccessWindowRepository
+	clientHashRounds    int
+	clientHashSaltKey   []byte
+	clock               clock.Clock
 }
 
 // NewAuthService creates a new authentication service
@@ -35,55 +104,263 @@ func NewAuthService(
 		passwordHasher:   passwordHasher,
 		tokenManager:     tokenManager,
 		refreshTokenTTL:  refreshTokenTTL,
+		clock:            clock.Real{},
 	}
 }
 
+// EnableIDTokens makes Login and Refresh also issue an OIDC-shaped ID token
+// for the given audience. An empty audience disables ID tokens.
+func (s *AuthService) EnableIDTokens(audience string) {
+	s.idTokenAudience = audience
+}
+
+// SetEmailPolicy restricts which email domains may be used to sign up
+func (s *AuthService) SetEmailPolicy(policy EmailPolicy) {
+	s.emailPolicy = policy
+}
+
+// SetDeliverabilityChecker checks that signup email domains can receive
+// mail. When reject is false, undeliverable addresses are accepted but
+// flagged in SignupOutput instead.
+func (s *AuthService) SetDeliverabilityChecker(checker DeliverabilityChecker, reject bool) {
+	s.deliverability = checker
+	s.rejectUndeliverable = reject
+}
+
+// SetEmailNormalizer changes how emails are canonicalized for uniqueness
+// checks and lookups. By default addresses are only lowercased and their
+// domains converted to IDNA form.
+func (s *AuthService) SetEmailNormalizer(normalizer emailnorm.Normalizer) {
+	s.emailNormalizer = normalizer
+}
+
+// SetMinResponseTime makes Login, Signup and ResendVerificationEmail take
+// at least d, so response times do not reveal whether an account exists.
+// Zero disables the padding.
+func (s *AuthService) SetMinResponseTime(d time.Duration) {
+	s.minResponseTime = d
+}
+
+// SetClock sets the clock that expiry, cooldowns and response padding are
+// measured by. The system clock is used by default.
+func (s *AuthService) SetClock(c clock.Clock) {
+	s.clock = clock.OrReal(c)
+}
+
+// SetEnumerationSafe makes responses that would reveal whether an email
+// address is registered indistinguishable from the success case. The
+// difference is only recorded in the logs.
+func (s *AuthService) SetEnumerationSafe(enabled bool) {
+	s.enumerationSafe = enabled
+}
+
+// SetIDGenerator makes Signup assign user ids before insert instead of
+// leaving them to the database. A nil generator restores the default.
+func (s *AuthService) SetIDGenerator(generator ids.Generator) {
+	s.idGenerator = generator
+}
+
+// SetRefreshGracePeriod lets a refresh token that was just rotated be used
+// once more within d, answering with the pair it was rotated into. Clients
+// that refresh concurrently are then not logged out by strict rotation.
+// Zero disables the grace period.
+func (s *AuthService) SetRefreshGracePeriod(d time.Duration) {
+	s.refreshGrace = nil
+	if d > 0 {
+		s.refreshGrace = newRefreshGrace(d)
+	}
+}
+
+// SetActivityRecorder records successful logins and refreshes so the
+// dormancy policy can find inactive accounts
+func (s *AuthService) SetActivityRecorder(recorder ActivityRecorder) {
+	s.activity = recorder
+}
+
+// EnumerationSafe reports whether enumeration-safe responses are enabled
+func (s *AuthService) EnumerationSafe() bool {
+	return s.enumerationSafe
+}
+
+// padResponse waits until minResponseTime has passed since start, returning
+// early if the request is cancelled
+func (s *AuthService) padResponse(ctx context.Context, start time.Time) {
+	remaining := s.minResponseTime - s.clock.Now().Sub(start)
+	if remaining <= 0 {
+		return
+	}
+
+	timer := s.clock.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+	case <-ctx.Done():
+	}
+}
+
+// burnPasswordCheck compares the password against a dummy hash so paths that
+// never reach bcrypt, such as unknown users, cost as much as those that do.
+// It returns security.ErrHashingOverloaded when the hashing pool sheds the
+// compare, which callers return so they shed like a real check would.
+func (s *AuthService) burnPasswordCheck(ctx context.Context, password string) error {
+	s.dummyHashOnce.Do(func() {
+		if hash, err := s.passwordHasher.Hash("dummy-password-for-timing"); err == nil {
+			s.dummyHash = hash
+		}
+	})
+	if err := s.passwordHasher.CompareContext(ctx, password, s.dummyHash); errors.Is(err, security.ErrHashingOverloaded) {
+		return err
+	}
+	return nil
+}
+
+// normalizeEmail returns the value users are looked up by. Addresses that
+// cannot be normalized are returned lowercased so lookups simply miss.
+func (s *AuthService) normalizeEmail(email string) string {
+	normalized, err := s.emailNormalizer.Normalize(email)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(email))
+	}
+	return normalized
+}
+
 // SignupInput represents the input for signup
 type SignupInput struct {
 	Email    string
 	Password string
+	Username string // optional
+	Locale   string // optional BCP 47 language tag
+
+	// Consent answers, recorded when consents are set with SetConsents
+	TermsVersion     string // terms of service version the user accepted
+	MarketingConsent bool
+	IPAddress        *string
 }
 
 // SignupOutput represents the output for signup
 type SignupOutput struct {
 	UserID                 string
 	EmailVerificationToken string
+	EmailVerificationCode  string // set when email codes are enabled
+	EmailUndeliverable     bool   // set when the domain cannot receive mail and rejection is off
 }
 
 // Signup creates a new user account
 func (s *AuthService) Signup(ctx context.Context, input SignupInput) (*SignupOutput, error) {
-	// Validate email
-	if err := domain.ValidateEmail(input.Email); err != nil {
+	defer s.padResponse(ctx, s.clock.Now())
+
+	output, err := s.signup(ctx, input)
+	s.recordSignup(err)
+	return output, err
+}
+
+// signup creates the account for Signup, which records the outcome
+func (s *AuthService) signup(ctx context.Context, input SignupInput) (*SignupOutput, error) {
+	// Normalize and validate email
+	email, err := emailnorm.ToASCII(input.Email)
+	if err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateEmail(email); err != nil {
 		return nil, err
 	}
+	normalizedEmail, err := s.emailNormalizer.Normalize(email)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enforce signup domain policy
+	if s.emailPolicy != nil {
+		if err := s.emailPolicy.Check(email); err != nil {
+			return nil, err
+		}
+	}
 
 	// Validate password
 	if err := domain.ValidatePassword(input.Password); err != nil {
 		return nil, err
 	}
 
+	// The current terms must be accepted to sign up
+	if s.ConsentsEnabled() && input.TermsVersion != s.termsVersion {
+		return nil, domain.ErrTermsNotAccepted
+	}
+
+	// Validate and reserve the optional username
+	var username *string
+	if input.Username != "" {
+		normalized := domain.NormalizeUsername(input.Username)
+		if err := domain.ValidateUsername(normalized); err != nil {
+			return nil, err
+		}
+		taken, err := s.userRepo.ExistsByUsername(ctx, normalized)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if username exists: %w", err)
+		}
+		if taken {
+			if err := s.burnPasswordCheck(ctx, input.Password); err != nil {
+				return nil, err
+			}
+			return nil, domain.ErrDuplicateUsername
+		}
+		username = &normalized
+	}
+
 	// Check if user already exists
-	exists, err := s.userRepo.ExistsByEmail(ctx, input.Email)
+	exists, err := s.userRepo.ExistsByEmail(ctx, normalizedEmail)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if user exists: %w", err)
 	}
 	if exists {
+		// Spend the time a new signup spends hashing the password
+		if err := s.burnPasswordCheck(ctx, input.Password); err != nil {
+			return nil, err
+		}
+		if s.enumerationSafe {
+			slog.InfoContext(ctx, "signup for registered email suppressed", "reason", "duplicate_email")
+		}
 		return nil, domain.ErrDuplicateEmail
 	}
 
 	// Create new user
-	user, err := domain.NewUser(input.Email)
+	user, err := domain.NewUser(email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
+	user.NormalizedEmail = normalizedEmail
+	user.Username = username
+	if input.Locale != "" {
+		if err := user.ApplyProfileUpdate(domain.ProfileUpdate{Locale: &input.Locale}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check deliverability while the password is being hashed
+	var deliverability chan error
+	if s.deliverability != nil {
+		deliverability = make(chan error, 1)
+		go func() {
+			deliverability <- s.deliverability.Check(ctx, email)
+		}()
+	}
 
 	// Hash password
-	passwordHash, err := s.passwordHasher.Hash(input.Password)
+	passwordHash, err := s.passwordHasher.HashContext(ctx, input.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 	user.PasswordHash = passwordHash
 
+	undeliverable := false
+	if deliverability != nil {
+		if err := <-deliverability; err != nil {
+			if s.rejectUndeliverable {
+				return nil, err
+			}
+			undeliverable = true
+		}
+	}
+
 	// Generate email verification token
 	verificationToken, err := security.GenerateToken(32)
 	if err != nil {
@@ -91,83 +368,247 @@ func (s *AuthService) Signup(ctx context.Context, input SignupInput) (*SignupOut
 	}
 
 	// Set verification token with 24-hour expiry
-	user.SetEmailVerificationToken(verificationToken, time.Now().Add(24*time.Hour))
+	user.SetEmailVerificationToken(verificationToken, s.clock.Now().Add(24*time.Hour))
 
 	// Save user to database
+	if s.idGenerator != nil {
+		if user.ID, err = s.idGenerator.NewID(); err != nil {
+			return nil, err
+		}
+	}
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	if s.ConsentsEnabled() {
+		if err := s.recordConsent(ctx, user.ID, domain.ConsentTerms, s.termsVersion, true, input.IPAddress); err != nil {
+			return nil, err
+		}
+		if err := s.recordConsent(ctx, user.ID, domain.ConsentMarketing, "", input.MarketingConsent, input.IPAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	// Issue a code for clients that cannot open the verification link
+	var verificationCode string
+	if s.emailCodes != nil {
+		if verificationCode, err = s.issueCode(ctx, s.emailCodes, s.emailCodePolicy, user.ID, domain.EmailCodeVerification); err != nil {
+			return nil, err
+		}
+	}
+
 	return &SignupOutput{
 		UserID:                 user.ID,
 		EmailVerificationToken: verificationToken,
+		EmailVerificationCode:  verificationCode,
+		EmailUndeliverable:     undeliverable,
 	}, nil
 }
 
-// LoginInput represents the input for login
+// LoginInput represents the input for login. Username is used to look up
+// the account instead of Email when set.
 type LoginInput struct {
-	Email     string
-	Password  string
-	UserAgent *string
-	IPAddress *string
+	Email          string
+	Username       string
+	Password       string
+	UserAgent      *string
+	IPAddress      *string
+	CaptchaToken   string            // only checked when the risk engine asks for a captcha
+	SMSCode        string            // only checked for users with SMS second factor
+	CertThumbprint string            // mTLS client certificate thumbprint; binds the access token when set
+	DPoP           token.DPoPRequest // DPoP proof; binds the access and refresh tokens to its key when set
+	RememberMe     bool              // ask for a remember-me session; ignored unless remember-me is enabled
+
+	// PasswordVerifier is sent instead of Password for accounts with a
+	// client-hashed password
+	PasswordVerifier string
 }
 
 // LoginOutput represents the output for login
 type LoginOutput struct {
 	AccessToken  string
 	RefreshToken string
-	ExpiresIn    int64
+	IDToken      string
+	ExpiresIn    int64 // seconds until the access token expires
+	DPoPBound    bool  // tokens are bound to the DPoP key and use the DPoP token type
+
+	// RememberMe is set for remember-me sessions, which end at
+	// SessionExpiresAt unless a refresh slides them
+	RememberMe       bool
+	SessionExpiresAt time.Time
+
+	// user ID, email and locale of the authenticated user, used to address,
+	// localize and track notifications
+	userID string
+	email  string
+	locale string
+
+	// resetInvalidated is set when the login cancelled a pending password
+	// reset, so the user is told by email
+	resetInvalidated bool
 }
 
 // Login authenticates a user and returns tokens
 func (s *AuthService) Login(ctx context.Context, input LoginInput) (*LoginOutput, error) {
-	// Find user by email
-	user, err := s.userRepo.GetByEmail(ctx, input.Email)
+	defer s.padResponse(ctx, s.clock.Now())
+
+	// Reject a bad proof before it can be used to probe credentials
+	cnf, err := s.confirmation(input.CertThumbprint, input.DPoP)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.authenticate(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := s.issueLoginTokens(ctx, user, cnf, input.UserAgent, input.IPAddress, input.RememberMe)
+	if err != nil {
+		return nil, err
+	}
+	output.resetInvalidated = s.invalidatePasswordResets(ctx, user.ID)
+	return output, nil
+}
+
+// authenticate checks a user's credentials, login risk and second factor,
+// and records the activity and outcome. Callers pad the response time.
+func (s *AuthService) authenticate(ctx context.Context, input LoginInput) (*domain.User, error) {
+	// Find user by username or email
+	var user *domain.User
+	var err error
+	if input.Username != "" {
+		user, err = s.userRepo.GetByUsername(ctx, domain.NormalizeUsername(input.Username))
+	} else {
+		user, err = s.userRepo.GetByEmail(ctx, s.normalizeEmail(input.Email))
+	}
 	if err != nil {
 		if errors.Is(err, domain.ErrUserNotFound) {
+			// Unknown users must take as long as wrong passwords
+			if err := s.burnPasswordCheck(ctx, cmp.Or(input.Password, input.PasswordVerifier)); err != nil {
+				s.recordLogin(LoginOutcomeError)
+				return nil, err
+			}
+			s.recordRiskOutcome(newRiskAttempt(ctx, input, nil), false)
+			s.recordLogin(LoginOutcomeUnknownUser)
 			return nil, domain.ErrInvalidCredentials
 		}
+		s.recordLogin(LoginOutcomeError)
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	// Score the attempt before checking the password so risky attempts
+	// cannot be used to probe credentials
+	attempt := newRiskAttempt(ctx, input, user)
+	if err := s.checkLoginRisk(ctx, attempt, input.CaptchaToken); err != nil {
+		s.recordLogin(loginFailureOutcome(err))
+		return nil, err
+	}
+
 	// Verify password
-	if err := s.passwordHasher.Compare(input.Password, user.PasswordHash); err != nil {
+	if err := s.passwordHasher.CompareContext(ctx, loginSecret(user, input), user.PasswordHash); err != nil {
+		if isCheckAborted(err) {
+			s.recordLogin(LoginOutcomeError)
+			return nil, err
+		}
+		s.recordRiskOutcome(attempt, false)
+		s.recordLogin(LoginOutcomeBadPassword)
 		return nil, domain.ErrInvalidCredentials
 	}
+	s.recordRiskOutcome(attempt, true)
 
-	// Check if email is verified (optional - depends on business requirements)
-	// if !user.EmailVerified {
-	//     return nil, domain.ErrEmailNotVerified
-	// }
+	// Only reported after the password matched so it cannot be probed
+	if user.IsDisabled() {
+		s.recordLogin(LoginOutcomeLocked)
+		return nil, domain.ErrAccountDisabled
+	}
+	if err := s.checkStrictEmailVerification(user); err != nil {
+		s.recordLogin(LoginOutcomeUnverified)
+		return nil, err
+	}
+	if err := s.checkSMSSecondFactor(ctx, user, input.SMSCode); err != nil {
+		s.recordLogin(loginFailureOutcome(err))
+		return nil, err
+	}
+	s.recordActivity(ctx, user.ID)
+	s.recordLogin(LoginOutcomeSuccess)
 
+	return user, nil
+}
+
+// issueLoginTokens starts a session for an authenticated user
+func (s *AuthService) issueLoginTokens(ctx context.Context, user *domain.User, cnf token.Confirmation, userAgent, ipAddress *string, rememberMe bool) (*LoginOutput, error) {
 	// Generate access token
-	accessToken, err := s.tokenManager.GenerateAccessToken(user.ID, user.Email, user.EmailVerified)
+	accessToken, err := s.generateAccessToken(ctx, user, cnf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
 	// Create refresh token
-	refreshToken := domain.NewRefreshToken(user.ID, time.Now().Add(s.refreshTokenTTL))
-	refreshToken.UserAgent = input.UserAgent
-	refreshToken.IPAddress = input.IPAddress
+	expiresAt, rememberMe := s.sessionExpiry(s.clock.Now(), rememberMe)
+	refreshToken := domain.NewRefreshToken(user.ID, expiresAt)
+	refreshToken.RememberMe = rememberMe
+	refreshToken.UserAgent = userAgent
+	refreshToken.IPAddress = ipAddress
+	refreshToken.DPoPThumbprint = optionalString(cnf.JWKThumbprint)
 
 	// Save refresh token
 	if err := s.refreshTokenRepo.Create(ctx, refreshToken); err != nil {
 		return nil, fmt.Errorf("failed to create refresh token: %w", err)
 	}
 
+	idToken, err := s.generateIDToken(user, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.auditLogin(ctx, user.ID, userAgent, ipAddress)
+
 	return &LoginOutput{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken.Token,
-		ExpiresIn:    int64(s.refreshTokenTTL.Seconds()),
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken.Token,
+		IDToken:          idToken,
+		ExpiresIn:        int64(s.tokenManager.AccessTokenTTL().Seconds()),
+		DPoPBound:        cnf.JWKThumbprint != "",
+		RememberMe:       rememberMe,
+		SessionExpiresAt: expiresAt,
+		userID:           user.ID,
+		email:            user.Email,
+		locale:           stringValue(user.Locale),
 	}, nil
 }
 
+// recordActivity marks the user active for the dormancy policy. Failures are
+// logged rather than failing the login.
+func (s *AuthService) recordActivity(ctx context.Context, userID string) {
+	if s.activity == nil {
+		return
+	}
+	if err := s.activity.RecordActivity(ctx, userID, s.clock.Now()); err != nil {
+		slog.WarnContext(ctx, "failed to record account activity", "user_id", userID, "error", err)
+	}
+}
+
+// confirmation builds the cnf claim for the mTLS certificate or DPoP proof
+// presented when tokens are issued
+func (s *AuthService) confirmation(certThumbprint string, proof token.DPoPRequest) (token.Confirmation, error) {
+	cnf := token.Confirmation{X509Thumbprint: certThumbprint}
+	if proof.Proof != "" {
+		jkt, err := s.tokenManager.VerifyDPoPProof(proof)
+		if err != nil {
+			return cnf, err
+		}
+		cnf.JWKThumbprint = jkt
+	}
+	return cnf, nil
+}
+
 // RefreshInput represents the input for token refresh
 type RefreshInput struct {
-	RefreshToken string
-	UserAgent    *string
-	IPAddress    *string
+	RefreshToken   string
+	UserAgent      *string
+	IPAddress      *string
+	CertThumbprint string            // mTLS client certificate thumbprint; binds the access token when set
+	DPoP           token.DPoPRequest // DPoP proof; required when the refresh token is DPoP-bound
 }
 
 // Refresh generates new tokens using a refresh token
@@ -181,16 +622,83 @@ func (s *AuthService) Refresh(ctx context.Context, input RefreshInput) (*LoginOu
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
 
-	// Validate refresh token
-	if !refreshToken.IsValid() {
+	// Validate refresh token; one that was just rotated may be within the
+	// grace period
+	if !refreshToken.IsValidAt(s.clock.Now()) {
+		if s.refreshGrace == nil || !refreshToken.Revoked {
+			return nil, domain.ErrInvalidToken
+		}
+		if _, err := s.refreshConfirmation(refreshToken, input); err != nil {
+			return nil, err
+		}
+		return s.replayRefresh(ctx, input.RefreshToken)
+	}
+
+	cnf, err := s.refreshConfirmation(refreshToken, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.refreshGrace == nil {
+		return s.rotateRefreshToken(ctx, refreshToken, cnf, input)
+	}
+	grace := s.refreshGrace.begin(input.RefreshToken)
+	if grace == nil {
+		// A concurrent request is rotating the same token
+		return s.replayRefresh(ctx, input.RefreshToken)
+	}
+	output, err := s.rotateRefreshToken(ctx, refreshToken, cnf, input)
+	s.refreshGrace.finish(input.RefreshToken, grace, output)
+	return output, err
+}
+
+// refreshConfirmation builds the cnf claim for a refresh. A DPoP-bound
+// refresh token is only usable with a proof from its key.
+func (s *AuthService) refreshConfirmation(refreshToken *domain.RefreshToken, input RefreshInput) (token.Confirmation, error) {
+	cnf, err := s.confirmation(input.CertThumbprint, input.DPoP)
+	if err != nil {
+		return token.Confirmation{}, err
+	}
+	if bound := stringValue(refreshToken.DPoPThumbprint); bound != "" && cnf.JWKThumbprint != bound {
+		return token.Confirmation{}, token.ErrInvalidDPoPProof
+	}
+	return cnf, nil
+}
+
+// replayRefresh answers a refresh with a token that another request just
+// rotated, returning the pair that request issued
+func (s *AuthService) replayRefresh(ctx context.Context, refreshToken string) (*LoginOutput, error) {
+	output, err := s.refreshGrace.replay(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions revoked since the rotation are not revived
+	rotated, err := s.refreshTokenRepo.GetByToken(ctx, output.RefreshToken)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidToken) {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if !rotated.IsValidAt(s.clock.Now()) {
 		return nil, domain.ErrInvalidToken
 	}
 
+	return output, nil
+}
+
+// rotateRefreshToken revokes refreshToken and issues a new pair in its place
+func (s *AuthService) rotateRefreshToken(ctx context.Context, refreshToken *domain.RefreshToken, cnf token.Confirmation, input RefreshInput) (*LoginOutput, error) {
 	// Get user
 	user, err := s.userRepo.GetByID(ctx, refreshToken.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	if user.IsDisabled() {
+		return nil, domain.ErrAccountDisabled
+	}
+	s.recordActivity(ctx, user.ID)
 
 	// Rotate refresh token (create new, revoke old)
 	if err := s.refreshTokenRepo.Revoke(ctx, input.RefreshToken); err != nil {
@@ -198,28 +706,65 @@ func (s *AuthService) Refresh(ctx context.Context, input RefreshInput) (*LoginOu
 	}
 
 	// Generate new access token
-	accessToken, err := s.tokenManager.GenerateAccessToken(user.ID, user.Email, user.EmailVerified)
+	accessToken, err := s.generateAccessToken(ctx, user, cnf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
 	// Create new refresh token
-	newRefreshToken := domain.NewRefreshToken(user.ID, time.Now().Add(s.refreshTokenTTL))
+	expiresAt, rememberMe := s.rotatedSessionExpiry(refreshToken, s.clock.Now())
+	newRefreshToken := domain.NewRefreshToken(user.ID, expiresAt)
+	newRefreshToken.RememberMe = rememberMe
 	newRefreshToken.UserAgent = input.UserAgent
 	newRefreshToken.IPAddress = input.IPAddress
+	newRefreshToken.DPoPThumbprint = optionalString(cnf.JWKThumbprint)
 
 	// Save new refresh token
 	if err := s.refreshTokenRepo.Create(ctx, newRefreshToken); err != nil {
 		return nil, fmt.Errorf("failed to create new refresh token: %w", err)
 	}
 
+	// The original authentication happened when the session was created
+	idToken, err := s.generateIDToken(user, refreshToken.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
 	return &LoginOutput{
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken.Token,
-		ExpiresIn:    int64(s.refreshTokenTTL.Seconds()),
+		AccessToken:      accessToken,
+		RefreshToken:     newRefreshToken.Token,
+		IDToken:          idToken,
+		ExpiresIn:        int64(s.tokenManager.AccessTokenTTL().Seconds()),
+		DPoPBound:        cnf.JWKThumbprint != "",
+		RememberMe:       rememberMe,
+		SessionExpiresAt: expiresAt,
 	}, nil
 }
 
+// generateIDToken issues an ID token when ID tokens are enabled
+func (s *AuthService) generateIDToken(user *domain.User, authTime time.Time) (string, error) {
+	if s.idTokenAudience == "" {
+		return "", nil
+	}
+
+	idToken, err := s.tokenManager.GenerateIDToken(token.IDTokenUser{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Username:      stringValue(user.Username),
+		Name:          stringValue(user.DisplayName),
+		Picture:       stringValue(user.AvatarURL),
+		Locale:        stringValue(user.Locale),
+		Zoneinfo:      stringValue(user.Timezone),
+		UpdatedAt:     user.UpdatedAt,
+	}, authTime, s.idTokenAudience)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID token: %w", err)
+	}
+
+	return idToken, nil
+}
+
 // LogoutInput represents the input for logout
 type LogoutInput struct {
 	RefreshToken string
@@ -245,6 +790,7 @@ func (s *AuthService) LogoutAll(ctx context.Context, userID string) error {
 		return fmt.Errorf("failed to revoke all refresh tokens: %w", err)
 	}
 
+	s.auditSessionRevocation(ctx, userID, RevokeReasonLogoutAll, 0)
 	return nil
 }
 
@@ -257,7 +803,7 @@ type VerifyEmailInput struct {
 // VerifyEmail verifies a user's email address
 func (s *AuthService) VerifyEmail(ctx context.Context, input VerifyEmailInput) error {
 	// Get user by email
-	user, err := s.userRepo.GetByEmail(ctx, input.Email)
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(input.Email))
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
@@ -268,7 +814,7 @@ func (s *AuthService) VerifyEmail(ctx context.Context, input VerifyEmailInput) e
 	}
 
 	// Validate token
-	if !user.IsEmailVerificationTokenValid(input.Token) {
+	if !user.IsEmailVerificationTokenValidAt(input.Token, s.clock.Now()) {
 		return domain.ErrInvalidToken
 	}
 
@@ -280,25 +826,57 @@ func (s *AuthService) VerifyEmail(ctx context.Context, input VerifyEmailInput) e
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
-	return nil
+	// Sessions started before verification carry email_verified=false
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	s.trackEmailEvent(ctx, user.ID, "verification", emailtrack.EventVerified)
+	s.emitUserEvent(ctx, user, domain.UserEventEmailVerified, map[string]interface{}{"method": domain.EmailVerificationLink})
+	return s.RevokeSessions(ctx, user.ID, RevokeReasonEmailVerified)
 }
 
 // ResendVerificationEmailOutput represents the output for resending verification email
 type ResendVerificationEmailOutput struct {
 	EmailVerificationToken string
+	EmailVerificationCode  string // set when email codes are enabled
+	// Suppressed is set in enumeration-safe mode when no email must be sent
+	// because the address is unknown or already verified
+	Suppressed bool
+
+	// user ID and locale of the user, used to track and localize the
+	// verification email
+	userID string
+	locale string
 }
 
 // ResendVerificationEmail generates a new verification token and returns it
 func (s *AuthService) ResendVerificationEmail(ctx context.Context, email string) (*ResendVerificationEmailOutput, error) {
+	defer s.padResponse(ctx, s.clock.Now())
+
 	// Get user by email
-	user, err := s.userRepo.GetByEmail(ctx, email)
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(email))
 	if err != nil {
+		if s.enumerationSafe && errors.Is(err, domain.ErrUserNotFound) {
+			slog.InfoContext(ctx, "verification resend suppressed", "reason", "unknown_email")
+			return &ResendVerificationEmailOutput{Suppressed: true}, nil
+		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	// Check if already verified
 	if user.EmailVerified {
-		return nil, errors.New("email already verified")
+		if s.enumerationSafe {
+			slog.InfoContext(ctx, "verification resend suppressed", "reason", "already_verified", "user_id", user.ID)
+			return &ResendVerificationEmailOutput{Suppressed: true}, nil
+		}
+		return nil, domain.ErrEmailAlreadyVerified
+	}
+
+	if err := s.checkResendLimit(ctx, user.ID); err != nil {
+		// A 429 would tell unknown emails apart from throttled accounts
+		if s.enumerationSafe && errors.Is(err, domain.ErrResendTooSoon) {
+			return &ResendVerificationEmailOutput{Suppressed: true}, nil
+		}
+		return nil, err
 	}
 
 	// Generate new verification token
@@ -308,18 +886,66 @@ func (s *AuthService) ResendVerificationEmail(ctx context.Context, email string)
 	}
 
 	// Set new token with 24-hour expiry
-	user.SetEmailVerificationToken(verificationToken, time.Now().Add(24*time.Hour))
+	user.SetEmailVerificationToken(verificationToken, s.clock.Now().Add(24*time.Hour))
 
 	// Update user
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	// Replace the code sent with the previous email
+	var verificationCode string
+	if s.emailCodes != nil {
+		if verificationCode, err = s.issueCode(ctx, s.emailCodes, s.emailCodePolicy, user.ID, domain.EmailCodeVerification); err != nil {
+			return nil, err
+		}
+	}
+
 	return &ResendVerificationEmailOutput{
 		EmailVerificationToken: verificationToken,
+		EmailVerificationCode:  verificationCode,
+		userID:                 user.ID,
+		locale:                 stringValue(user.Locale),
 	}, nil
 }
 
+// UpdateProfile validates and applies a partial profile update
+func (s *AuthService) UpdateProfile(ctx context.Context, userID string, update domain.ProfileUpdate) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := user.ApplyProfileUpdate(update); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// UsernameAvailable reports whether a username can be registered. Reserved
+// usernames are reported as unavailable; malformed ones return an error.
+func (s *AuthService) UsernameAvailable(ctx context.Context, username string) (bool, error) {
+	username = domain.NormalizeUsername(username)
+	if err := domain.ValidateUsername(username); err != nil {
+		if errors.Is(err, domain.ErrReservedUsername) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	taken, err := s.userRepo.ExistsByUsername(ctx, username)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if username exists: %w", err)
+	}
+
+	return !taken, nil
+}
+
 // GetUserByID retrieves a user by their ID
 func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*domain.User, error) {
 	user, err := s.userRepo.GetByID(ctx, userID)
@@ -329,3 +955,19 @@ func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*domain.U
 
 	return user, nil
 }
+
+// stringValue dereferences an optional string field
+func stringValue(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// optionalString returns nil for an empty string
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
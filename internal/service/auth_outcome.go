@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// Outcomes of a login attempt, reported to the AuthRecorder
+const (
+	LoginOutcomeSuccess     = "success"
+	LoginOutcomeBadPassword = "bad_password"
+	LoginOutcomeUnknownUser = "unknown_user"
+	LoginOutcomeLocked      = "locked"
+	LoginOutcomeUnverified  = "unverified"
+	LoginOutcomeMFARequired = "mfa_required"
+	LoginOutcomeMFAFailed   = "mfa_failed"
+	LoginOutcomeChallenged  = "challenged" // the risk engine asked for a captcha or confirmation
+	LoginOutcomeBlocked     = "blocked"    // the risk engine blocked the attempt
+	LoginOutcomeError       = "error"
+)
+
+// Outcomes of a signup attempt, reported to the AuthRecorder
+const (
+	SignupOutcomeSuccess           = "success"
+	SignupOutcomeInvalidEmail      = "invalid_email"
+	SignupOutcomeEmailRejected     = "email_rejected" // domain policy, disposable or undeliverable address
+	SignupOutcomeWeakPassword      = "weak_password"
+	SignupOutcomeInvalidUsername   = "invalid_username"
+	SignupOutcomeDuplicateEmail    = "duplicate_email"
+	SignupOutcomeDuplicateUsername = "duplicate_username"
+	SignupOutcomeError             = "error"
+)
+
+// AuthRecorder receives login and signup outcomes, typically for metrics
+// that break failures down by reason. Outcomes are never returned to
+// clients, so they may tell unknown users from wrong passwords.
+type AuthRecorder interface {
+	RecordLoginAttempt(outcome string)
+	RecordSignupAttempt(outcome string)
+}
+
+// SetAuthRecorder reports the outcome of every login and signup attempt
+func (s *AuthService) SetAuthRecorder(recorder AuthRecorder) {
+	s.authRecorder = recorder
+}
+
+func (s *AuthService) recordLogin(outcome string) {
+	if s.authRecorder != nil {
+		s.authRecorder.RecordLoginAttempt(outcome)
+	}
+}
+
+func (s *AuthService) recordSignup(err error) {
+	if s.authRecorder != nil {
+		s.authRecorder.RecordSignupAttempt(signupOutcome(err))
+	}
+}
+
+// loginFailureOutcome classifies errors from the risk check and second
+// factor; credential failures are classified where they happen
+func loginFailureOutcome(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrLoginBlocked):
+		return LoginOutcomeBlocked
+	case errors.Is(err, domain.ErrCaptchaRequired), errors.Is(err, domain.ErrLoginConfirmationRequired):
+		return LoginOutcomeChallenged
+	case errors.Is(err, domain.ErrSMSCodeRequired):
+		return LoginOutcomeMFARequired
+	case errors.Is(err, domain.ErrInvalidEmailCode), errors.Is(err, domain.ErrTooManyCodeAttempts):
+		return LoginOutcomeMFAFailed
+	case errors.Is(err, domain.ErrEmailNotVerified):
+		return LoginOutcomeUnverified
+	default:
+		return LoginOutcomeError
+	}
+}
+
+// signupOutcome classifies the result of Signup
+func signupOutcome(err error) string {
+	switch {
+	case err == nil:
+		return SignupOutcomeSuccess
+	case errors.Is(err, domain.ErrInvalidEmail):
+		return SignupOutcomeInvalidEmail
+	case errors.Is(err, domain.ErrEmailDomainNotAllowed), errors.Is(err, domain.ErrDisposableEmail), errors.Is(err, domain.ErrUndeliverableEmail):
+		return SignupOutcomeEmailRejected
+	case errors.Is(err, domain.ErrWeakPassword):
+		return SignupOutcomeWeakPassword
+	case errors.Is(err, domain.ErrInvalidUsername), errors.Is(err, domain.ErrReservedUsername):
+		return SignupOutcomeInvalidUsername
+	case errors.Is(err, domain.ErrDuplicateEmail):
+		return SignupOutcomeDuplicateEmail
+	case errors.Is(err, domain.ErrDuplicateUsername):
+		return SignupOutcomeDuplicateUsername
+	default:
+		return SignupOutcomeError
+	}
+}
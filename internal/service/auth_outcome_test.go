@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// authOutcomes records login and signup outcomes
+type authOutcomes struct {
+	logins  []string
+	signups []string
+}
+
+func (r *authOutcomes) RecordLoginAttempt(outcome string)  { r.logins = append(r.logins, outcome) }
+func (r *authOutcomes) RecordSignupAttempt(outcome string) { r.signups = append(r.signups, outcome) }
+
+func TestAuthService_AuthRecorder(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	outcomes := &authOutcomes{}
+	service.SetAuthRecorder(outcomes)
+	ctx := context.Background()
+
+	signups := []SignupInput{
+		{Email: "outcome@example.com", Password: "password123"},
+		{Email: "outcome@example.com", Password: "password123"},
+		{Email: "not-an-email", Password: "password123"},
+		{Email: "weak@example.com", Password: "123"},
+	}
+	for _, input := range signups {
+		service.Signup(ctx, input)
+	}
+
+	logins := []LoginInput{
+		{Email: "outcome@example.com", Password: "password123"},
+		{Email: "outcome@example.com", Password: "wrong-password"},
+		{Email: "nobody@example.com", Password: "password123"},
+	}
+	for _, input := range logins {
+		service.Login(ctx, input)
+	}
+	disabledAt := time.Now()
+	userRepo.users["outcome@example.com"].DisabledAt = &disabledAt
+	service.Login(ctx, LoginInput{Email: "outcome@example.com", Password: "password123"})
+
+	wantSignups := []string{SignupOutcomeSuccess, SignupOutcomeDuplicateEmail, SignupOutcomeInvalidEmail, SignupOutcomeWeakPassword}
+	wantLogins := []string{LoginOutcomeSuccess, LoginOutcomeBadPassword, LoginOutcomeUnknownUser, LoginOutcomeLocked}
+	if !slices.Equal(outcomes.signups, wantSignups) {
+		t.Errorf("signup outcomes = %v, want %v", outcomes.signups, wantSignups)
+	}
+	if !slices.Equal(outcomes.logins, wantLogins) {
+		t.Errorf("login outcomes = %v, want %v", outcomes.logins, wantLogins)
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/emailnorm"
 	"github.com/n1rocket/go-auth-jwt/internal/security"
 	"github.com/n1rocket/go-auth-jwt/internal/token"
 )
@@ -25,11 +28,17 @@ func newMockUserRepository() *mockUserRepository {
 }
 
 func (m *mockUserRepository) Create(ctx context.Context, user *domain.User) error {
-	if _, exists := m.users[user.Email]; exists {
+	key := user.NormalizedEmail
+	if key == "" {
+		key = user.Email
+	}
+	if _, exists := m.users[key]; exists {
 		return domain.ErrDuplicateEmail
 	}
-	user.ID = "user-" + user.Email
-	m.users[user.Email] = user
+	if user.ID == "" {
+		user.ID = "user-" + user.Email
+	}
+	m.users[key] = user
 	return nil
 }
 
@@ -70,6 +79,30 @@ func (m *mockUserRepository) ExistsByEmail(ctx context.Context, email string) (b
 	return exists, nil
 }
 
+func (m *mockUserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	for _, user := range m.users {
+		if user.Username != nil && *user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	var users []*domain.User
+	for _, id := range ids {
+		if user, err := m.GetByID(ctx, id); err == nil {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (m *mockUserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	_, err := m.GetByUsername(ctx, username)
+	return err == nil, nil
+}
+
 type mockRefreshTokenRepository struct {
 	tokens  map[string]*domain.RefreshToken
 	counter int
@@ -109,6 +142,19 @@ func (m *mockRefreshTokenRepository) GetByUserID(ctx context.Context, userID str
 	return tokens, nil
 }
 
+func (m *mockRefreshTokenRepository) GetActiveCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, userID := range userIDs {
+		tokens, _ := m.GetByUserID(ctx, userID)
+		for _, token := range tokens {
+			if token.IsValid() {
+				counts[userID]++
+			}
+		}
+	}
+	return counts, nil
+}
+
 func (m *mockRefreshTokenRepository) Update(ctx context.Context, token *domain.RefreshToken) error {
 	m.tokens[token.Token] = token
 	return nil
@@ -359,8 +405,8 @@ func TestAuthService_Login(t *testing.T) {
 					t.Error("Login() returned empty RefreshToken")
 				}
 
-				if output.ExpiresIn <= 0 {
-					t.Error("Login() returned invalid ExpiresIn")
+				if output.ExpiresIn != int64((15 * time.Minute).Seconds()) {
+					t.Errorf("Login() ExpiresIn = %d, want the access token TTL", output.ExpiresIn)
 				}
 			}
 		})
@@ -496,6 +542,39 @@ func TestAuthService_Refresh(t *testing.T) {
 	}
 }
 
+func TestAuthService_RefreshTokenExpiry(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	now := clock.NewFake(time.Now())
+	service.SetClock(now)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "expiry@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	login, err := service.Login(ctx, LoginInput{Email: "expiry@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	now.Advance(7*24*time.Hour - time.Minute)
+	refreshed, err := service.Refresh(ctx, RefreshInput{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh() before expiry error = %v", err)
+	}
+
+	// The rotated token lasts a full TTL from the refresh
+	now.Advance(7*24*time.Hour - time.Minute)
+	refreshed, err = service.Refresh(ctx, RefreshInput{RefreshToken: refreshed.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh() of the rotated token error = %v", err)
+	}
+
+	now.Advance(7 * 24 * time.Hour)
+	if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: refreshed.RefreshToken}); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("Refresh() after expiry error = %v, want ErrInvalidToken", err)
+	}
+}
+
 func TestAuthService_VerifyEmail(t *testing.T) {
 	service, userRepo, _ := createTestAuthService(t)
 	ctx := context.Background()
@@ -559,3 +638,516 @@ func TestAuthService_VerifyEmail(t *testing.T) {
 		}
 	})
 }
+
+func TestAuthService_LoginWithIDToken(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{
+		Email:    "oidc@example.com",
+		Password: "password123",
+	}); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	// ID tokens are disabled by default
+	output, err := service.Login(ctx, LoginInput{Email: "oidc@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if output.IDToken != "" {
+		t.Error("Login() should not return an ID token when disabled")
+	}
+
+	service.EnableIDTokens("test-client")
+
+	output, err = service.Login(ctx, LoginInput{Email: "oidc@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if output.IDToken == "" {
+		t.Fatal("Login() should return an ID token when enabled")
+	}
+
+	refreshed, err := service.Refresh(ctx, RefreshInput{RefreshToken: output.RefreshToken})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if refreshed.IDToken == "" {
+		t.Error("Refresh() should return an ID token when enabled")
+	}
+}
+
+type denyAllEmailPolicy struct{}
+
+func (denyAllEmailPolicy) Check(email string) error {
+	return domain.ErrDisposableEmail
+}
+
+func TestAuthService_SignupEmailPolicy(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	service.SetEmailPolicy(denyAllEmailPolicy{})
+
+	_, err := service.Signup(context.Background(), SignupInput{
+		Email:    "user@mailinator.com",
+		Password: "password123",
+	})
+	if !errors.Is(err, domain.ErrDisposableEmail) {
+		t.Errorf("Signup() error = %v, want %v", err, domain.ErrDisposableEmail)
+	}
+	if len(userRepo.users) != 0 {
+		t.Error("rejected signup should not create a user")
+	}
+}
+
+type undeliverableChecker struct{}
+
+func (undeliverableChecker) Check(ctx context.Context, email string) error {
+	return domain.ErrUndeliverableEmail
+}
+
+func TestAuthService_SignupDeliverability(t *testing.T) {
+	t.Run("reject", func(t *testing.T) {
+		service, userRepo, _ := createTestAuthService(t)
+		service.SetDeliverabilityChecker(undeliverableChecker{}, true)
+
+		_, err := service.Signup(context.Background(), SignupInput{Email: "user@nomail.example", Password: "password123"})
+		if !errors.Is(err, domain.ErrUndeliverableEmail) {
+			t.Errorf("Signup() error = %v, want %v", err, domain.ErrUndeliverableEmail)
+		}
+		if len(userRepo.users) != 0 {
+			t.Error("rejected signup should not create a user")
+		}
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		service, _, _ := createTestAuthService(t)
+		service.SetDeliverabilityChecker(undeliverableChecker{}, false)
+
+		output, err := service.Signup(context.Background(), SignupInput{Email: "user@nomail.example", Password: "password123"})
+		if err != nil {
+			t.Fatalf("Signup() error = %v", err)
+		}
+		if !output.EmailUndeliverable {
+			t.Error("expected signup to be flagged as undeliverable")
+		}
+	})
+}
+
+type sequenceIDGenerator struct {
+	next int
+	err  error
+}
+
+func (g *sequenceIDGenerator) NewID() (string, error) {
+	g.next++
+	return testUUID(g.next), g.err
+}
+
+func TestAuthService_SignupIDGenerator(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	generator := &sequenceIDGenerator{}
+	service.SetIDGenerator(generator)
+
+	output, err := service.Signup(context.Background(), SignupInput{Email: "ids@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if output.UserID != testUUID(1) {
+		t.Errorf("user id = %q, want the generated %q", output.UserID, testUUID(1))
+	}
+
+	generator.err = errors.New("entropy unavailable")
+	if _, err := service.Signup(context.Background(), SignupInput{Email: "ids2@example.com", Password: "password123"}); err == nil {
+		t.Error("expected generator error")
+	}
+	if len(userRepo.users) != 1 {
+		t.Errorf("%d users stored, want 1", len(userRepo.users))
+	}
+}
+
+func TestAuthService_EmailNormalization(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	service.SetEmailNormalizer(emailnorm.Normalizer{CanonicalizeGmail: true})
+	ctx := context.Background()
+
+	_, err := service.Signup(ctx, SignupInput{Email: " John.Doe+news@GMAIL.com ", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	user, ok := userRepo.users["johndoe@gmail.com"]
+	if !ok {
+		t.Fatal("expected user to be stored under its normalized email")
+	}
+	if user.Email != "john.doe+news@gmail.com" {
+		t.Errorf("Email = %q, want %q", user.Email, "john.doe+news@gmail.com")
+	}
+
+	duplicates := []string{"johndoe@gmail.com", "J.O.H.N.DOE@googlemail.com", "johndoe+other@gmail.com"}
+	for _, email := range duplicates {
+		_, err := service.Signup(ctx, SignupInput{Email: email, Password: "password123"})
+		if !errors.Is(err, domain.ErrDuplicateEmail) {
+			t.Errorf("Signup(%q) error = %v, want %v", email, err, domain.ErrDuplicateEmail)
+		}
+	}
+
+	user.EmailVerified = true
+	if _, err := service.Login(ctx, LoginInput{Email: "JohnDoe@Gmail.com", Password: "password123"}); err != nil {
+		t.Errorf("Login() with equivalent address error = %v", err)
+	}
+
+	t.Run("unicode domain", func(t *testing.T) {
+		_, err := service.Signup(ctx, SignupInput{Email: "user@Bücher.example", Password: "password123"})
+		if err != nil {
+			t.Fatalf("Signup() error = %v", err)
+		}
+		if _, ok := userRepo.users["user@xn--bcher-kva.example"]; !ok {
+			t.Error("expected unicode domain to be stored in IDNA form")
+		}
+	})
+}
+
+func TestAuthService_Username(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	_, err := service.Signup(ctx, SignupInput{Email: "jane@example.com", Password: "password123", Username: "Jane_Doe"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		username string
+		wantErr  error
+	}{
+		{name: "taken username", email: "other@example.com", username: "jane_doe", wantErr: domain.ErrDuplicateUsername},
+		{name: "reserved username", email: "other@example.com", username: "admin", wantErr: domain.ErrReservedUsername},
+		{name: "invalid username", email: "other@example.com", username: "no spaces", wantErr: domain.ErrInvalidUsername},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Signup(ctx, SignupInput{Email: tt.email, Password: "password123", Username: tt.username})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Signup() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := service.Login(ctx, LoginInput{Username: "JANE_DOE", Password: "password123"}); err != nil {
+		t.Errorf("Login() by username error = %v", err)
+	}
+	if _, err := service.Login(ctx, LoginInput{Username: "jane_doe", Password: "wrong-password"}); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("Login() with wrong password error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+	if _, err := service.Login(ctx, LoginInput{Username: "nobody", Password: "password123"}); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("Login() with unknown username error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+
+	available, err := service.UsernameAvailable(ctx, "jane_doe")
+	if err != nil || available {
+		t.Errorf("UsernameAvailable(taken) = %v, %v; want false, nil", available, err)
+	}
+	available, err = service.UsernameAvailable(ctx, "john_doe")
+	if err != nil || !available {
+		t.Errorf("UsernameAvailable(free) = %v, %v; want true, nil", available, err)
+	}
+}
+
+func TestAuthService_UpdateProfile(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "profile@example.com", Password: "password123", Locale: "es_mx"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	output, err := service.Login(ctx, LoginInput{Email: "profile@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	claims, err := service.tokenManager.ValidateAccessToken(output.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.Locale != "es-MX" {
+		t.Errorf("access token locale = %q, want %q", claims.Locale, "es-MX")
+	}
+
+	name := "  Jane Doe  "
+	tz := "Europe/Madrid"
+	user, err := service.UpdateProfile(ctx, signup.UserID, domain.ProfileUpdate{DisplayName: &name, Timezone: &tz})
+	if err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	if user.DisplayName == nil || *user.DisplayName != "Jane Doe" {
+		t.Errorf("DisplayName = %v, want %q", user.DisplayName, "Jane Doe")
+	}
+	if user.Locale == nil || *user.Locale != "es-MX" {
+		t.Errorf("Locale should be unchanged, got %v", user.Locale)
+	}
+
+	bad := "Nowhere/City"
+	if _, err := service.UpdateProfile(ctx, signup.UserID, domain.ProfileUpdate{DisplayName: &name, Timezone: &bad}); !errors.Is(err, domain.ErrInvalidProfile) {
+		t.Errorf("UpdateProfile() error = %v, want %v", err, domain.ErrInvalidProfile)
+	}
+	stored, _ := service.GetUserByID(ctx, signup.UserID)
+	if stored.Timezone == nil || *stored.Timezone != tz {
+		t.Errorf("failed update should not modify the stored user, got timezone %v", stored.Timezone)
+	}
+
+	if _, err := service.UpdateProfile(ctx, "missing", domain.ProfileUpdate{}); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("UpdateProfile() for missing user error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
+
+func TestAuthService_CreateAvatarUpload(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "avatar@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	if _, err := service.CreateAvatarUpload(ctx, signup.UserID); !errors.Is(err, domain.ErrAvatarUploadsDisabled) {
+		t.Errorf("CreateAvatarUpload() error = %v, want %v", err, domain.ErrAvatarUploadsDisabled)
+	}
+
+	signer, err := security.NewURLSigner("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("NewURLSigner() error = %v", err)
+	}
+	service.SetAvatarUploads(signer, "https://uploads.example.com/", 5*time.Minute)
+
+	output, err := service.CreateAvatarUpload(ctx, signup.UserID)
+	if err != nil {
+		t.Fatalf("CreateAvatarUpload() error = %v", err)
+	}
+	if err := signer.Verify(output.Method, output.UploadURL, time.Now()); err != nil {
+		t.Errorf("upload URL should verify: %v", err)
+	}
+	if !strings.HasPrefix(output.AvatarURL, "https://uploads.example.com/avatars/"+signup.UserID+"/") {
+		t.Errorf("AvatarURL = %q, want it under the user's avatar prefix", output.AvatarURL)
+	}
+
+	// The public URL is accepted as the user's avatar
+	if _, err := service.UpdateProfile(ctx, signup.UserID, domain.ProfileUpdate{AvatarURL: &output.AvatarURL}); err != nil {
+		t.Errorf("UpdateProfile() with uploaded avatar error = %v", err)
+	}
+
+	if _, err := service.CreateAvatarUpload(ctx, "missing"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("CreateAvatarUpload() for missing user error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
+
+func TestAuthService_MinResponseTime(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "timing@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	const minimum = 600 * time.Millisecond
+	service.SetMinResponseTime(minimum)
+
+	tests := []struct {
+		name string
+		call func(context.Context) error
+		want error
+	}{
+		{
+			name: "unknown user login",
+			call: func(ctx context.Context) error {
+				_, err := service.Login(ctx, LoginInput{Email: "nobody@example.com", Password: "password123"})
+				return err
+			},
+			want: domain.ErrInvalidCredentials,
+		},
+		{
+			name: "wrong password login",
+			call: func(ctx context.Context) error {
+				_, err := service.Login(ctx, LoginInput{Email: "timing@example.com", Password: "wrong-password"})
+				return err
+			},
+			want: domain.ErrInvalidCredentials,
+		},
+		{
+			name: "duplicate signup",
+			call: func(ctx context.Context) error {
+				_, err := service.Signup(ctx, SignupInput{Email: "timing@example.com", Password: "password123"})
+				return err
+			},
+			want: domain.ErrDuplicateEmail,
+		},
+		{
+			name: "unknown user resend",
+			call: func(ctx context.Context) error {
+				_, err := service.ResendVerificationEmail(ctx, "nobody@example.com")
+				return err
+			},
+			want: domain.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := tt.call(ctx)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed < minimum {
+				t.Errorf("returned after %v, want at least %v", elapsed, minimum)
+			}
+		})
+	}
+
+	// Padding stops when the caller gives up
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	start := time.Now()
+	service.ResendVerificationEmail(cancelled, "nobody@example.com")
+	if elapsed := time.Since(start); elapsed >= minimum {
+		t.Errorf("cancelled request was padded for %v", elapsed)
+	}
+}
+
+func TestAuthService_HashingOverloaded(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "busy@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	// A stopped pool sheds every hash
+	pool := security.NewHashPool(1, 1, 0)
+	pool.Start()
+	pool.Stop()
+	service.passwordHasher.SetPool(pool)
+
+	// Known and unknown accounts are shed alike, so a 503 reveals nothing
+	for _, email := range []string{"busy@example.com", "nobody@example.com"} {
+		if _, err := service.Login(ctx, LoginInput{Email: email, Password: "password123"}); !errors.Is(err, security.ErrHashingOverloaded) {
+			t.Errorf("Login(%s) error = %v, want %v", email, err, security.ErrHashingOverloaded)
+		}
+	}
+	for _, email := range []string{"busy@example.com", "new@example.com"} {
+		if _, err := service.Signup(ctx, SignupInput{Email: email, Password: "password123"}); !errors.Is(err, security.ErrHashingOverloaded) {
+			t.Errorf("Signup(%s) error = %v, want %v", email, err, security.ErrHashingOverloaded)
+		}
+	}
+}
+
+func TestAuthService_EnumerationSafeResend(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	output, err := service.Signup(ctx, SignupInput{Email: "pending@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if _, err := service.Signup(ctx, SignupInput{Email: "verified@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	userRepo.users["verified@example.com"].EmailVerified = true
+
+	// Without the flag unknown and verified addresses are reported
+	if _, err := service.ResendVerificationEmail(ctx, "nobody@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("unknown email error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+	if _, err := service.ResendVerificationEmail(ctx, "verified@example.com"); err == nil {
+		t.Error("expected error for verified email")
+	}
+
+	service.SetEnumerationSafe(true)
+
+	tests := []struct {
+		name           string
+		email          string
+		wantSuppressed bool
+	}{
+		{name: "unknown email", email: "nobody@example.com", wantSuppressed: true},
+		{name: "verified email", email: "verified@example.com", wantSuppressed: true},
+		{name: "pending email", email: "pending@example.com", wantSuppressed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resent, err := service.ResendVerificationEmail(ctx, tt.email)
+			if err != nil {
+				t.Fatalf("ResendVerificationEmail() error = %v", err)
+			}
+			if resent.Suppressed != tt.wantSuppressed {
+				t.Errorf("Suppressed = %v, want %v", resent.Suppressed, tt.wantSuppressed)
+			}
+			if !tt.wantSuppressed && (resent.EmailVerificationToken == "" || resent.EmailVerificationToken == output.EmailVerificationToken) {
+				t.Error("expected a fresh verification token")
+			}
+		})
+	}
+
+	// Signup still reports the duplicate so callers can log it
+	if _, err := service.Signup(ctx, SignupInput{Email: "pending@example.com", Password: "password123"}); !errors.Is(err, domain.ErrDuplicateEmail) {
+		t.Errorf("duplicate signup error = %v, want %v", err, domain.ErrDuplicateEmail)
+	}
+}
+
+func TestAuthService_DPoPBoundRefreshToken(t *testing.T) {
+	service, _, refreshTokenRepo := createTestAuthService(t)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "dpop@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	// Proofs are refused until DPoP is enabled
+	_, err := service.Login(ctx, LoginInput{
+		Email:    "dpop@example.com",
+		Password: "password123",
+		DPoP:     token.DPoPRequest{Proof: "proof", Method: "POST", URL: "https://auth.example.com/api/v1/auth/login"},
+	})
+	if !errors.Is(err, token.ErrDPoPDisabled) {
+		t.Fatalf("Login() error = %v, want %v", err, token.ErrDPoPDisabled)
+	}
+
+	loginOutput, err := service.Login(ctx, LoginInput{Email: "dpop@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if loginOutput.DPoPBound {
+		t.Error("tokens issued without a proof should not be DPoP-bound")
+	}
+
+	// Simulate a session established with a DPoP proof
+	jkt := "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I"
+	refreshTokenRepo.tokens[loginOutput.RefreshToken].DPoPThumbprint = &jkt
+	service.tokenManager.EnableDPoP(time.Minute)
+
+	tests := []struct {
+		name  string
+		proof token.DPoPRequest
+	}{
+		{name: "without proof"},
+		{name: "with invalid proof", proof: token.DPoPRequest{Proof: "not-a-proof", Method: "POST", URL: "https://auth.example.com/api/v1/auth/refresh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Refresh(ctx, RefreshInput{RefreshToken: loginOutput.RefreshToken, DPoP: tt.proof})
+			if !errors.Is(err, token.ErrInvalidDPoPProof) {
+				t.Errorf("Refresh() error = %v, want %v", err, token.ErrInvalidDPoPProof)
+			}
+		})
+	}
+
+	// A failed proof must not burn the refresh token
+	if refreshTokenRepo.tokens[loginOutput.RefreshToken].Revoked {
+		t.Error("refresh token should not be revoked after a rejected proof")
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"strings"
 
 	"github.com/n1rocket/go-auth-jwt/internal/config"
 	emailpkg "github.com/n1rocket/go-auth-jwt/internal/email"
+	"github.com/n1rocket/go-auth-jwt/internal/emailtrack"
 	"github.com/n1rocket/go-auth-jwt/internal/worker"
 )
 
@@ -41,6 +44,15 @@ func (s *AuthServiceWithEmail) SignupWithEmail(ctx context.Context, input Signup
 		return nil, err
 	}
 
+	// Don't waste a send on a domain that cannot receive mail
+	if output.EmailUndeliverable {
+		s.logger.Warn("skipping verification email for undeliverable address",
+			"user_id", output.UserID,
+			"email", input.Email,
+		)
+		return output, nil
+	}
+
 	// Prepare email data
 	emailData := emailpkg.TemplateData{
 		BaseURL:           s.config.App.BaseURL,
@@ -48,16 +60,14 @@ func (s *AuthServiceWithEmail) SignupWithEmail(ctx context.Context, input Signup
 		SupportEmail:      s.config.Email.SupportEmail,
 		RecipientEmail:    input.Email,
 		VerificationToken: output.EmailVerificationToken,
-		VerificationURL: fmt.Sprintf("%s/verify-email?token=%s&email=%s",
-			s.config.App.BaseURL,
-			output.EmailVerificationToken,
-			input.Email,
-		),
-		ExpirationHours: 24,
+		ExpirationHours:   24,
 	}
+	link := s.verificationLink(&emailData, output.EmailVerificationToken, input.Email)
+	template := s.verificationTemplate(&emailData, output.EmailVerificationCode)
+	tracked := s.trackLinks(ctx, output.UserID, template.Name, link, &emailData)
 
 	// Render verification email
-	verificationEmail, err := emailpkg.RenderTemplate(emailpkg.VerificationEmailTemplate, emailData)
+	verificationEmail, err := emailpkg.RenderTemplate(template.ForLocale(input.Locale), emailData)
 	if err != nil {
 		s.logger.Error("failed to render verification email",
 			"error", err,
@@ -69,7 +79,7 @@ func (s *AuthServiceWithEmail) SignupWithEmail(ctx context.Context, input Signup
 	}
 
 	// Queue email for sending
-	if err := s.emailDispatcher.EnqueueWithContext(ctx, verificationEmail); err != nil {
+	if err := s.enqueue(ctx, verificationEmail); err != nil {
 		s.logger.Error("failed to queue verification email",
 			"error", err,
 			"user_id", output.UserID,
@@ -81,11 +91,21 @@ func (s *AuthServiceWithEmail) SignupWithEmail(ctx context.Context, input Signup
 			"user_id", output.UserID,
 			"email", input.Email,
 		)
+		s.trackSent(tracked, template.Name)
 	}
 
 	return output, nil
 }
 
+// enqueue queues an email whose account change is already committed. A
+// client that disconnects must not lose it, so only a full queue that does
+// not drain within followUpTimeout drops it.
+func (s *AuthServiceWithEmail) enqueue(ctx context.Context, email emailpkg.Email) error {
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	return s.emailDispatcher.EnqueueWithContext(ctx, email)
+}
+
 // ResendVerificationEmailWithNotification resends verification email
 func (s *AuthServiceWithEmail) ResendVerificationEmailWithNotification(ctx context.Context, emailAddress string) (*ResendVerificationEmailOutput, error) {
 	// Call the base method
@@ -93,6 +113,9 @@ func (s *AuthServiceWithEmail) ResendVerificationEmailWithNotification(ctx conte
 	if err != nil {
 		return nil, err
 	}
+	if output.Suppressed {
+		return output, nil
+	}
 
 	// Prepare email data
 	emailData := emailpkg.TemplateData{
@@ -101,16 +124,14 @@ func (s *AuthServiceWithEmail) ResendVerificationEmailWithNotification(ctx conte
 		SupportEmail:      s.config.Email.SupportEmail,
 		RecipientEmail:    emailAddress,
 		VerificationToken: output.EmailVerificationToken,
-		VerificationURL: fmt.Sprintf("%s/verify-email?token=%s&email=%s",
-			s.config.App.BaseURL,
-			output.EmailVerificationToken,
-			emailAddress,
-		),
-		ExpirationHours: 24,
+		ExpirationHours:   24,
 	}
+	link := s.verificationLink(&emailData, output.EmailVerificationToken, emailAddress)
+	template := s.verificationTemplate(&emailData, output.EmailVerificationCode)
+	tracked := s.trackLinks(ctx, output.userID, template.Name, link, &emailData)
 
 	// Render verification email
-	verificationEmail, err := emailpkg.RenderTemplate(emailpkg.VerificationEmailTemplate, emailData)
+	verificationEmail, err := emailpkg.RenderTemplate(template.ForLocale(output.locale), emailData)
 	if err != nil {
 		s.logger.Error("failed to render verification email",
 			"error", err,
@@ -120,7 +141,7 @@ func (s *AuthServiceWithEmail) ResendVerificationEmailWithNotification(ctx conte
 	}
 
 	// Queue email for sending
-	if err := s.emailDispatcher.EnqueueWithContext(ctx, verificationEmail); err != nil {
+	if err := s.enqueue(ctx, verificationEmail); err != nil {
 		s.logger.Error("failed to queue verification email",
 			"error", err,
 			"email", emailAddress,
@@ -129,6 +150,106 @@ func (s *AuthServiceWithEmail) ResendVerificationEmailWithNotification(ctx conte
 		s.logger.Info("verification email re-sent",
 			"email", emailAddress,
 		)
+		s.trackSent(tracked, template.Name)
+	}
+
+	return output, nil
+}
+
+// verificationLink sets the link of a verification email: the mobile deep
+// link when a template is configured, the web page otherwise. It returns the
+// link for click tracking, or nil for deep links, as a tracking redirect
+// keeps universal links from opening the app.
+func (s *AuthServiceWithEmail) verificationLink(data *emailpkg.TemplateData, token, email string) *string {
+	if template := s.config.Mobile.VerifyLinkTemplate; template != "" {
+		data.VerificationURL = expandLink(template, "{token}", token, "{email}", email)
+		return nil
+	}
+	data.VerificationURL = fmt.Sprintf("%s/verify-email?token=%s&email=%s", s.config.App.BaseURL, token, email)
+	return &data.VerificationURL
+}
+
+// expandLink replaces the placeholders of a link template, given as
+// placeholder and value pairs, with the query-escaped values
+func expandLink(template string, pairs ...string) string {
+	for i := 1; i < len(pairs); i += 2 {
+		pairs[i] = url.QueryEscape(pairs[i])
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// trackLinks sends the email's link, unless it is nil, through a tracking
+// redirect and adds the open pixel, unless tracking is off or the user opted
+// out. It reports whether the email is tracked.
+func (s *AuthServiceWithEmail) trackLinks(ctx context.Context, userID, template string, link *string, data *emailpkg.TemplateData) bool {
+	if !s.emailTracked(ctx, userID) {
+		return false
+	}
+	if link != nil {
+		*link = s.emailTracker.Link(template, *link)
+	}
+	data.TrackingPixelURL = s.emailTracker.Pixel(template)
+	return true
+}
+
+// trackSent counts a queued email when it is tracked
+func (s *AuthServiceWithEmail) trackSent(tracked bool, template string) {
+	if tracked {
+		s.emailTracker.Record(template, emailtrack.EventSent)
+	}
+}
+
+// verificationTemplate picks the verification email carrying the code, if
+// one was issued, and adds the code to the template data
+func (s *AuthServiceWithEmail) verificationTemplate(data *emailpkg.TemplateData, code string) emailpkg.Template {
+	if code == "" {
+		return emailpkg.VerificationEmailTemplate
+	}
+	data.Code = code
+	data.ExpirationMinutes = int(s.EmailCodeTTL().Minutes())
+	return emailpkg.VerificationCodeEmailTemplate
+}
+
+// RequestPasswordResetWithEmail issues a password reset code and emails it
+func (s *AuthServiceWithEmail) RequestPasswordResetWithEmail(ctx context.Context, emailAddress string) (*PasswordResetOutput, error) {
+	output, err := s.AuthService.RequestPasswordReset(ctx, emailAddress)
+	if err != nil {
+		return nil, err
+	}
+	if output.Suppressed {
+		return output, nil
+	}
+
+	emailData := emailpkg.TemplateData{
+		BaseURL:           s.config.App.BaseURL,
+		AppName:           s.config.App.Name,
+		SupportEmail:      s.config.Email.SupportEmail,
+		RecipientEmail:    emailAddress,
+		Code:              output.Code,
+		ExpirationMinutes: int(s.EmailCodeTTL().Minutes()),
+	}
+	if template := s.config.Mobile.ResetLinkTemplate; template != "" {
+		emailData.ResetURL = expandLink(template, "{code}", output.Code, "{email}", emailAddress)
+	}
+
+	resetEmail, err := emailpkg.RenderTemplate(emailpkg.PasswordResetCodeEmailTemplate.ForLocale(output.locale), emailData)
+	if err != nil {
+		s.logger.Error("failed to render password reset email",
+			"error", err,
+			"email", emailAddress,
+		)
+		return output, nil
+	}
+
+	if err := s.enqueue(ctx, resetEmail); err != nil {
+		s.logger.Error("failed to queue password reset email",
+			"error", err,
+			"email", emailAddress,
+		)
+	} else {
+		s.logger.Info("password reset email queued",
+			"email", emailAddress,
+		)
 	}
 
 	return output, nil
@@ -142,6 +263,12 @@ func (s *AuthServiceWithEmail) LoginWithNotification(ctx context.Context, input
 		return nil, err
 	}
 
+	// Sent even without login notifications: the reset the user may be
+	// waiting for no longer works
+	if output.resetInvalidated {
+		s.sendPasswordResetCancelled(ctx, output)
+	}
+
 	// Check if login notifications are enabled
 	if !s.config.Email.SendLoginNotifications {
 		return output, nil
@@ -152,29 +279,97 @@ func (s *AuthServiceWithEmail) LoginWithNotification(ctx context.Context, input
 		BaseURL:        s.config.App.BaseURL,
 		AppName:        s.config.App.Name,
 		SupportEmail:   s.config.Email.SupportEmail,
-		RecipientEmail: input.Email,
+		RecipientEmail: output.email,
 		LoginURL:       fmt.Sprintf("%s/account/security", s.config.App.BaseURL),
 	}
+	template := emailpkg.LoginNotificationEmailTemplate
+	tracked := s.trackLinks(ctx, output.userID, template.Name, &emailData.LoginURL, &emailData)
 
 	// Render login notification email
-	loginEmail, err := emailpkg.RenderTemplate(emailpkg.LoginNotificationEmailTemplate, emailData)
+	loginEmail, err := emailpkg.RenderTemplate(template.ForLocale(output.locale), emailData)
 	if err != nil {
 		s.logger.Error("failed to render login notification email",
 			"error", err,
-			"email", input.Email,
+			"email", output.email,
 		)
 		return output, nil
 	}
 
-	// Queue email for sending (don't wait)
+	// Queue email for sending (don't wait). Notifications are the first
+	// emails shed when the queue is full.
 	go func() {
-		if err := s.emailDispatcher.Enqueue(loginEmail); err != nil {
+		if err := s.emailDispatcher.EnqueuePriority(loginEmail, worker.PriorityLow); err != nil {
 			s.logger.Error("failed to queue login notification email",
 				"error", err,
-				"email", input.Email,
+				"email", output.email,
 			)
+			return
 		}
+		s.trackSent(tracked, template.Name)
 	}()
 
 	return output, nil
 }
+
+// ExchangeSessionHandoffWithNotification starts a session from a handoff
+// code and tells the user. Unlike login notifications this email is always
+// sent: whoever saw the code can use it.
+func (s *AuthServiceWithEmail) ExchangeSessionHandoffWithNotification(ctx context.Context, input ExchangeSessionHandoffInput) (*LoginOutput, error) {
+	output, err := s.AuthService.ExchangeSessionHandoff(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	emailData := emailpkg.TemplateData{
+		BaseURL:        s.config.App.BaseURL,
+		AppName:        s.config.App.Name,
+		SupportEmail:   s.config.Email.SupportEmail,
+		RecipientEmail: output.email,
+		LoginURL:       fmt.Sprintf("%s/account/security", s.config.App.BaseURL),
+	}
+
+	handoffEmail, err := emailpkg.RenderTemplate(emailpkg.SessionHandoffEmailTemplate.ForLocale(output.locale), emailData)
+	if err != nil {
+		s.logger.Error("failed to render session handoff email",
+			"error", err,
+			"email", output.email,
+		)
+		return output, nil
+	}
+
+	if err := s.enqueue(ctx, handoffEmail); err != nil {
+		s.logger.Error("failed to queue session handoff email",
+			"error", err,
+			"email", output.email,
+		)
+	}
+	return output, nil
+}
+
+// sendPasswordResetCancelled tells the user that the login cancelled a
+// pending password reset
+func (s *AuthServiceWithEmail) sendPasswordResetCancelled(ctx context.Context, output *LoginOutput) {
+	emailData := emailpkg.TemplateData{
+		BaseURL:        s.config.App.BaseURL,
+		AppName:        s.config.App.Name,
+		SupportEmail:   s.config.Email.SupportEmail,
+		RecipientEmail: output.email,
+		LoginURL:       fmt.Sprintf("%s/account/security", s.config.App.BaseURL),
+	}
+
+	cancelledEmail, err := emailpkg.RenderTemplate(emailpkg.PasswordResetCancelledEmailTemplate.ForLocale(output.locale), emailData)
+	if err != nil {
+		s.logger.Error("failed to render password reset cancelled email",
+			"error", err,
+			"email", output.email,
+		)
+		return
+	}
+
+	if err := s.enqueue(ctx, cancelledEmail); err != nil {
+		s.logger.Error("failed to queue password reset cancelled email",
+			"error", err,
+			"email", output.email,
+		)
+	}
+}
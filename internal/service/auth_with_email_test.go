@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -54,12 +56,14 @@ func (t *testEmailDispatcher) EnqueueWithContext(ctx context.Context, email emai
 
 // Mock user repository that implements the full interface
 type mockUserRepositoryWithEmail struct {
-	createFunc        func(ctx context.Context, user *domain.User) error
-	getByEmailFunc    func(ctx context.Context, email string) (*domain.User, error)
-	getByIDFunc       func(ctx context.Context, id string) (*domain.User, error)
-	updateFunc        func(ctx context.Context, user *domain.User) error
-	deleteFunc        func(ctx context.Context, id string) error
-	existsByEmailFunc func(ctx context.Context, email string) (bool, error)
+	createFunc           func(ctx context.Context, user *domain.User) error
+	getByEmailFunc       func(ctx context.Context, email string) (*domain.User, error)
+	getByIDFunc          func(ctx context.Context, id string) (*domain.User, error)
+	updateFunc           func(ctx context.Context, user *domain.User) error
+	deleteFunc           func(ctx context.Context, id string) error
+	existsByEmailFunc    func(ctx context.Context, email string) (bool, error)
+	getByUsernameFunc    func(ctx context.Context, username string) (*domain.User, error)
+	existsByUsernameFunc func(ctx context.Context, username string) (bool, error)
 }
 
 func (m *mockUserRepositoryWithEmail) Create(ctx context.Context, user *domain.User) error {
@@ -101,6 +105,24 @@ func (m *mockUserRepositoryWithEmail) Delete(ctx context.Context, id string) err
 	return nil
 }
 
+func (m *mockUserRepositoryWithEmail) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if m.getByUsernameFunc != nil {
+		return m.getByUsernameFunc(ctx, username)
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepositoryWithEmail) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepositoryWithEmail) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	if m.existsByUsernameFunc != nil {
+		return m.existsByUsernameFunc(ctx, username)
+	}
+	return false, nil
+}
+
 func (m *mockUserRepositoryWithEmail) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	if m.existsByEmailFunc != nil {
 		return m.existsByEmailFunc(ctx, email)
@@ -163,6 +185,10 @@ func (m *mockRefreshTokenRepositoryWithEmail) RevokeAllForUser(ctx context.Conte
 	return nil
 }
 
+func (m *mockRefreshTokenRepositoryWithEmail) GetActiveCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
 func (m *mockRefreshTokenRepositoryWithEmail) DeleteExpired(ctx context.Context) error {
 	return nil
 }
@@ -573,3 +599,149 @@ func TestAuthServiceWithEmail_LoginWithNotification(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthServiceWithEmail_LoginWithNotificationByUsername(t *testing.T) {
+	passwordHasher := security.NewPasswordHasher(10)
+	validHash, _ := passwordHasher.Hash("Password123!")
+	username := "jdoe"
+
+	userRepo := &mockUserRepositoryWithEmail{
+		getByUsernameFunc: func(ctx context.Context, name string) (*domain.User, error) {
+			return &domain.User{
+				ID:            "user-123",
+				Email:         "jdoe@example.com",
+				Username:      &username,
+				EmailVerified: true,
+				PasswordHash:  validHash,
+				CreatedAt:     time.Now(),
+				UpdatedAt:     time.Now(),
+			}, nil
+		},
+	}
+	sent := make(chan email.Email, 1)
+	service := createTestAuthServiceWithEmail(userRepo, nil, &mockEmailService{
+		sendFunc: func(ctx context.Context, e email.Email) error {
+			sent <- e
+			return nil
+		},
+	})
+	service.emailDispatcher.Start()
+	defer service.emailDispatcher.Stop(time.Second)
+
+	if _, err := service.LoginWithNotification(context.Background(), LoginInput{Username: username, Password: "Password123!"}); err != nil {
+		t.Fatalf("LoginWithNotification() error = %v", err)
+	}
+
+	// The login names no address, so the notice goes to the account's
+	select {
+	case e := <-sent:
+		if e.To != "jdoe@example.com" {
+			t.Errorf("notification sent to %q, want jdoe@example.com", e.To)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("login notification was not sent")
+	}
+}
+
+func TestAuthServiceWithEmail_LoginCancelsPasswordReset(t *testing.T) {
+	passwordHasher := security.NewPasswordHasher(10)
+	validHash, _ := passwordHasher.Hash("Password123!")
+
+	userRepo := &mockUserRepositoryWithEmail{
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			return &domain.User{
+				ID:            "user-123",
+				Email:         email,
+				EmailVerified: true,
+				PasswordHash:  validHash,
+				CreatedAt:     time.Now(),
+				UpdatedAt:     time.Now(),
+			}, nil
+		},
+	}
+	sent := make(chan email.Email, 2)
+	service := createTestAuthServiceWithEmail(userRepo, nil, &mockEmailService{
+		sendFunc: func(ctx context.Context, e email.Email) error {
+			sent <- e
+			return nil
+		},
+	})
+	cfg := *service.config
+	cfg.Email.SendLoginNotifications = false
+	service.config = &cfg
+	codes := newMockEmailCodeRepository()
+	service.SetEmailCodes(codes, CodePolicy{TTL: 15 * time.Minute, MaxAttempts: 3})
+	service.emailDispatcher.Start()
+	defer service.emailDispatcher.Stop(time.Second)
+
+	if _, err := service.AuthService.RequestPasswordReset(context.Background(), "test@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	if _, err := service.LoginWithNotification(context.Background(), LoginInput{Email: "test@example.com", Password: "Password123!"}); err != nil {
+		t.Fatalf("LoginWithNotification() error = %v", err)
+	}
+
+	// Sent although login notifications are off
+	select {
+	case e := <-sent:
+		if e.Template != email.PasswordResetCancelledEmailTemplate.Name || e.To != "test@example.com" {
+			t.Errorf("sent %s to %q, want the cancelled reset notice to test@example.com", e.Template, e.To)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancelled reset notice was not sent")
+	}
+	if len(codes.codes) != 0 {
+		t.Error("pending reset code was not deleted")
+	}
+}
+
+func TestAuthServiceWithEmail_MobileLinks(t *testing.T) {
+	userRepo := &mockUserRepositoryWithEmail{
+		getByEmailFunc: func(ctx context.Context, email string) (*domain.User, error) {
+			return &domain.User{ID: "user-123", Email: email, EmailVerified: true, CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
+		},
+	}
+	sent := make(chan email.Email, 2)
+	service := createTestAuthServiceWithEmail(userRepo, nil, &mockEmailService{
+		sendFunc: func(ctx context.Context, e email.Email) error {
+			sent <- e
+			return nil
+		},
+	})
+	cfg := *service.config
+	cfg.Mobile.VerifyLinkTemplate = "https://app.example.com/verify-email?token={token}&email={email}"
+	cfg.Mobile.ResetLinkTemplate = "https://app.example.com/reset-password?code={code}&email={email}"
+	service.config = &cfg
+	service.SetEmailCodes(newMockEmailCodeRepository(), CodePolicy{TTL: 15 * time.Minute, MaxAttempts: 3})
+	service.emailDispatcher.Start()
+	defer service.emailDispatcher.Stop(time.Second)
+
+	receive := func() email.Email {
+		t.Helper()
+		select {
+		case e := <-sent:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("email was not sent")
+			return email.Email{}
+		}
+	}
+
+	signup, err := service.SignupWithEmail(context.Background(), SignupInput{Email: "new+user@example.com", Password: "Password123!"})
+	if err != nil {
+		t.Fatalf("SignupWithEmail() error = %v", err)
+	}
+	want := html.EscapeString("https://app.example.com/verify-email?token=" + signup.EmailVerificationToken + "&email=new%2Buser%40example.com")
+	if e := receive(); !strings.Contains(e.HTMLBody, `href="`+want+`"`) {
+		t.Errorf("verification email does not link to %s:\n%s", want, e.HTMLBody)
+	}
+
+	reset, err := service.RequestPasswordResetWithEmail(context.Background(), "test@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordResetWithEmail() error = %v", err)
+	}
+	want = html.EscapeString("https://app.example.com/reset-password?code=" + reset.Code + "&email=test%40example.com")
+	if e := receive(); !strings.Contains(e.HTMLBody, `href="`+want+`"`) {
+		t.Errorf("reset email does not link to %s:\n%s", want, e.HTMLBody)
+	}
+}
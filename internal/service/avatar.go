@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// AvatarUploadOutput describes a short-lived avatar upload URL
+type AvatarUploadOutput struct {
+	UploadURL string    // signed URL the client uploads the image to
+	Method    string    // HTTP method the upload URL is signed for
+	AvatarURL string    // public URL to set as avatar_url once the upload succeeds
+	ExpiresAt time.Time // when the upload URL stops being accepted
+}
+
+// SetAvatarUploads enables signed avatar upload URLs under baseURL. The
+// storage backend behind baseURL verifies uploads with the same signer key,
+// so image bytes never pass through this service.
+func (s *AuthService) SetAvatarUploads(signer *security.URLSigner, baseURL string, ttl time.Duration) {
+	s.avatarSigner = signer
+	s.avatarBaseURL = strings.TrimRight(baseURL, "/")
+	s.avatarUploadTTL = ttl
+}
+
+// CreateAvatarUpload issues a signed URL the user can upload a new avatar to
+func (s *AuthService) CreateAvatarUpload(ctx context.Context, userID string) (*AvatarUploadOutput, error) {
+	if s.avatarSigner == nil {
+		return nil, domain.ErrAvatarUploadsDisabled
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// A fresh object name per upload keeps cached avatars from going stale
+	name, err := security.GenerateToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate avatar name: %w", err)
+	}
+
+	avatarURL := fmt.Sprintf("%s/avatars/%s/%s", s.avatarBaseURL, user.ID, name)
+	expiresAt := s.clock.Now().Add(s.avatarUploadTTL)
+
+	uploadURL, err := s.avatarSigner.Sign(http.MethodPut, avatarURL, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign upload url: %w", err)
+	}
+
+	return &AvatarUploadOutput{
+		UploadURL: uploadURL,
+		Method:    http.MethodPut,
+		AvatarURL: avatarURL,
+		ExpiresAt: expiresAt,
+	}, nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// SetClientPasswordHashing lets users reset their password to a verifier
+// their client derives from it, so the password never reaches the server.
+// Verifiers are derived with iterations rounds and a salt issued with the
+// reset grant; logins then send the verifier instead of the password.
+// saltKey derives stable salts for accounts without one, so the login
+// params do not reveal which accounts exist. Grants are issued for
+// password reset codes and expire with them.
+func (s *AuthService) SetClientPasswordHashing(iterations int, saltKey []byte) {
+	s.clientHashRounds = iterations
+	s.clientHashSaltKey = saltKey
+}
+
+// ClientPasswordHashingEnabled reports whether client-hashed passwords can
+// be set
+func (s *AuthService) ClientPasswordHashingEnabled() bool {
+	return s.clientHashRounds > 0
+}
+
+// ClientHashParams returns what the client derives the verifier of the
+// account with email from. Accounts without a client-hashed password, and
+// unknown addresses, get params that stay the same but match no verifier.
+func (s *AuthService) ClientHashParams(ctx context.Context, email string) (security.ClientHashParams, error) {
+	email = s.normalizeEmail(email)
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return security.ClientHashParams{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user != nil {
+		if params, _, ok := security.ParseClientHash(user.PasswordHash); ok {
+			return params, nil
+		}
+	}
+	return security.FakeClientHashParams(s.clientHashSaltKey, email, s.clientHashRounds), nil
+}
+
+// ResetGrantInput represents the input for exchanging a reset code for a
+// grant
+type ResetGrantInput struct {
+	Email string
+	Code  string
+}
+
+// ResetGrant lets a client set a client-hashed password once. The salt is
+// part of the grant, so the verifier has to be derived with Params.
+type ResetGrant struct {
+	Grant     string
+	Params    security.ClientHashParams
+	ExpiresAt time.Time
+}
+
+// GrantPasswordReset exchanges a password reset code for a grant with new
+// client hash params. The code is used up; the grant expires when the code
+// would have.
+func (s *AuthService) GrantPasswordReset(ctx context.Context, input ResetGrantInput) (*ResetGrant, error) {
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(input.Email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrInvalidEmailCode
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.checkCode(ctx, s.emailCodes, s.emailCodePolicy, user.ID, domain.EmailCodePasswordReset, input.Code); err != nil {
+		return nil, err
+	}
+
+	params, err := security.NewClientHashParams(s.clientHashRounds)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := security.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	// The params travel in the grant so they need no storage of their own
+	grant := fmt.Sprintf("%d.%s.%s", params.Iterations, params.Salt, secret)
+
+	now := s.clock.Now()
+	expiresAt := now.Add(s.emailCodePolicy.TTL)
+	if err := s.emailCodes.Save(ctx, &domain.EmailCode{
+		UserID:    user.ID,
+		Purpose:   domain.EmailCodePasswordResetGrant,
+		CodeHash:  security.HashToken(grant),
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &ResetGrant{Grant: grant, Params: params, ExpiresAt: expiresAt}, nil
+}
+
+// ClientPasswordResetInput represents the input for setting a
+// client-hashed password with a reset grant
+type ClientPasswordResetInput struct {
+	Email    string
+	Grant    string
+	Verifier string
+}
+
+// ResetClientHashedPassword replaces the user's password with the verifier
+// derived with the grant's params and signs the user out everywhere. Later
+// logins have to send the verifier.
+func (s *AuthService) ResetClientHashedPassword(ctx context.Context, input ClientPasswordResetInput) error {
+	// Reject passwords sent in clear before spending the grant
+	if !security.ValidClientVerifier(input.Verifier) {
+		return domain.ErrInvalidPasswordVerifier
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(input.Email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrInvalidResetGrant
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	params, ok := parseResetGrant(input.Grant)
+	if !ok {
+		return domain.ErrInvalidResetGrant
+	}
+	if err := s.checkResetGrant(ctx, user.ID, input.Grant); err != nil {
+		return err
+	}
+
+	verifierHash, err := s.passwordHasher.HashContext(ctx, input.Verifier)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	return s.replacePasswordHash(ctx, user, security.WrapClientHash(params, verifierHash))
+}
+
+// parseResetGrant returns the params a grant was issued with
+func parseResetGrant(grant string) (security.ClientHashParams, bool) {
+	parts := strings.SplitN(grant, ".", 3)
+	if len(parts) != 3 {
+		return security.ClientHashParams{}, false
+	}
+	iterations, err := strconv.Atoi(parts[0])
+	if err != nil || iterations <= 0 {
+		return security.ClientHashParams{}, false
+	}
+	return security.ClientHashParams{Salt: parts[1], Iterations: iterations}, true
+}
+
+// checkResetGrant spends one attempt on the user's grant and deletes it once
+// it matched or its attempts ran out, so the reset has to start over.
+func (s *AuthService) checkResetGrant(ctx context.Context, userID, grant string) error {
+	stored, err := s.emailCodes.RecordAttempt(ctx, userID, domain.EmailCodePasswordResetGrant)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidEmailCode) {
+			return domain.ErrInvalidResetGrant
+		}
+		return err
+	}
+	if stored.Attempts > s.emailCodePolicy.MaxAttempts {
+		if err := s.emailCodes.Delete(ctx, userID, domain.EmailCodePasswordResetGrant); err != nil {
+			return err
+		}
+		return domain.ErrTooManyCodeAttempts
+	}
+	if stored.IsExpired(s.clock.Now()) || !security.ConstantTimeCompare(security.HashToken(grant), stored.CodeHash) {
+		return domain.ErrInvalidResetGrant
+	}
+	return s.emailCodes.Delete(ctx, userID, domain.EmailCodePasswordResetGrant)
+}
+
+// loginSecret returns what a login is checked with against the user's
+// password hash: the verifier for client-hashed passwords and the password
+// otherwise
+func loginSecret(user *domain.User, input LoginInput) string {
+	if _, _, ok := security.ParseClientHash(user.PasswordHash); ok {
+		return input.PasswordVerifier
+	}
+	return input.Password
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+func TestAuthService_ClientHashedPasswordReset(t *testing.T) {
+	service, _, _ := createTestAuthServiceWithCodes(t)
+	service.SetClientPasswordHashing(1000, []byte("0123456789abcdef0123456789abcdef"))
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "zk@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	// Accounts without a verifier get stable params like unknown addresses
+	before, err := service.ClientHashParams(ctx, "zk@example.com")
+	if err != nil {
+		t.Fatalf("ClientHashParams() error = %v", err)
+	}
+	if again, _ := service.ClientHashParams(ctx, "ZK@example.com"); again != before {
+		t.Errorf("ClientHashParams() = %+v, then %+v", before, again)
+	}
+
+	reset, err := service.RequestPasswordReset(ctx, "zk@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	if _, err := service.GrantPasswordReset(ctx, ResetGrantInput{Email: "zk@example.com", Code: "wrong"}); !errors.Is(err, domain.ErrInvalidEmailCode) {
+		t.Fatalf("GrantPasswordReset() with wrong code error = %v", err)
+	}
+	grant, err := service.GrantPasswordReset(ctx, ResetGrantInput{Email: "zk@example.com", Code: reset.Code})
+	if err != nil {
+		t.Fatalf("GrantPasswordReset() error = %v", err)
+	}
+	if grant.Params.Iterations != 1000 || grant.Params == before {
+		t.Errorf("grant params = %+v", grant.Params)
+	}
+
+	verifier, err := security.DeriveClientVerifier("new-password123", grant.Params)
+	if err != nil {
+		t.Fatalf("DeriveClientVerifier() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		input   ClientPasswordResetInput
+		wantErr error
+	}{
+		{
+			name:    "password instead of verifier",
+			input:   ClientPasswordResetInput{Email: "zk@example.com", Grant: grant.Grant, Verifier: "new-password123"},
+			wantErr: domain.ErrInvalidPasswordVerifier,
+		},
+		{
+			name:    "wrong grant",
+			input:   ClientPasswordResetInput{Email: "zk@example.com", Grant: "1000.00.wrong", Verifier: verifier},
+			wantErr: domain.ErrInvalidResetGrant,
+		},
+		{
+			name:  "valid reset",
+			input: ClientPasswordResetInput{Email: "zk@example.com", Grant: grant.Grant, Verifier: verifier},
+		},
+		{
+			name:    "grant reused",
+			input:   ClientPasswordResetInput{Email: "zk@example.com", Grant: grant.Grant, Verifier: verifier},
+			wantErr: domain.ErrInvalidResetGrant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.ResetClientHashedPassword(ctx, tt.input); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResetClientHashedPassword() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Logins fetch the grant's params and only pass with the verifier
+	params, err := service.ClientHashParams(ctx, "zk@example.com")
+	if err != nil || params != grant.Params {
+		t.Errorf("ClientHashParams() = %+v, %v, want %+v", params, err, grant.Params)
+	}
+	for _, input := range []LoginInput{
+		{Email: "zk@example.com", Password: "new-password123"},
+		{Email: "zk@example.com", Password: verifier},
+	} {
+		if _, err := service.Login(ctx, input); !errors.Is(err, domain.ErrInvalidCredentials) {
+			t.Errorf("Login(%+v) error = %v, want invalid credentials", input, err)
+		}
+	}
+	login, err := service.Login(ctx, LoginInput{Email: "zk@example.com", PasswordVerifier: verifier})
+	if err != nil {
+		t.Fatalf("Login() with verifier error = %v", err)
+	}
+
+	// Password changes must go through a reset grant, whether the client
+	// sends the password or the verifier, and leave the verifier in place
+	for _, current := range []string{"new-password123", verifier} {
+		err := service.ChangePassword(ctx, ChangePasswordInput{UserID: login.userID, CurrentPassword: current, NewPassword: "other-password123"})
+		if !errors.Is(err, domain.ErrPasswordResetRequired) {
+			t.Errorf("ChangePassword() error = %v, want %v", err, domain.ErrPasswordResetRequired)
+		}
+	}
+	if _, err := service.Login(ctx, LoginInput{Email: "zk@example.com", PasswordVerifier: verifier}); err != nil {
+		t.Errorf("Login() with verifier after refused change error = %v", err)
+	}
+}
+
+func TestAuthService_ResetGrantAttempts(t *testing.T) {
+	service, _, codes := createTestAuthServiceWithCodes(t)
+	service.SetClientPasswordHashing(1000, []byte("0123456789abcdef0123456789abcdef"))
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "zk@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	reset, err := service.RequestPasswordReset(ctx, "zk@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	grant, err := service.GrantPasswordReset(ctx, ResetGrantInput{Email: "zk@example.com", Code: reset.Code})
+	if err != nil {
+		t.Fatalf("GrantPasswordReset() error = %v", err)
+	}
+	verifier, err := security.DeriveClientVerifier("new-password123", grant.Params)
+	if err != nil {
+		t.Fatalf("DeriveClientVerifier() error = %v", err)
+	}
+
+	wrong := ClientPasswordResetInput{Email: "zk@example.com", Grant: "1000.00.wrong", Verifier: verifier}
+	for i := 0; i < 3; i++ {
+		if err := service.ResetClientHashedPassword(ctx, wrong); !errors.Is(err, domain.ErrInvalidResetGrant) {
+			t.Fatalf("attempt %d error = %v, want %v", i+1, err, domain.ErrInvalidResetGrant)
+		}
+	}
+	if err := service.ResetClientHashedPassword(ctx, wrong); !errors.Is(err, domain.ErrTooManyCodeAttempts) {
+		t.Fatalf("attempt over the limit error = %v, want %v", err, domain.ErrTooManyCodeAttempts)
+	}
+
+	// The spent grant is gone, so even the right one no longer works
+	if _, ok := codes.codes[codes.key(signup.UserID, domain.EmailCodePasswordResetGrant)]; ok {
+		t.Error("grant kept after its attempts were spent")
+	}
+	valid := ClientPasswordResetInput{Email: "zk@example.com", Grant: grant.Grant, Verifier: verifier}
+	if err := service.ResetClientHashedPassword(ctx, valid); !errors.Is(err, domain.ErrInvalidResetGrant) {
+		t.Errorf("ResetClientHashedPassword() with spent grant error = %v, want %v", err, domain.ErrInvalidResetGrant)
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// ConnectionTokenOutput is a token that opens a WebSocket or SSE connection
+type ConnectionTokenOutput struct {
+	Token     string
+	ExpiresIn int64 // seconds
+}
+
+// IssueConnectionToken issues a short-lived token the user opens a WebSocket
+// or SSE connection with, in place of their access token. audience names the
+// socket endpoint the token is for and may be empty.
+func (s *AuthService) IssueConnectionToken(userID, audience string) (*ConnectionTokenOutput, error) {
+	connectionToken, expiresAt, err := s.tokenManager.GenerateConnectionToken(userID, audience)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate connection token: %w", err)
+	}
+	return &ConnectionTokenOutput{
+		Token:     connectionToken,
+		ExpiresIn: int64(expiresAt.Sub(s.clock.Now()).Round(time.Second).Seconds()),
+	}, nil
+}
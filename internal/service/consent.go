@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// SetConsents records terms of service and marketing consent answers.
+// Signup then requires accepting termsVersion, and users who accepted an
+// older version have to accept it again (see TermsAccepted). Changing
+// termsVersion starts the re-consent.
+func (s *AuthService) SetConsents(repo repository.ConsentRepository, termsVersion string) {
+	s.consents = repo
+	s.termsVersion = termsVersion
+}
+
+// ConsentsEnabled reports whether consent answers are recorded
+func (s *AuthService) ConsentsEnabled() bool {
+	return s.consents != nil
+}
+
+// TermsVersion returns the terms of service version users must accept
+func (s *AuthService) TermsVersion() string {
+	return s.termsVersion
+}
+
+// TermsAccepted reports whether the user accepted the current terms of
+// service. It is true when consents are not recorded.
+func (s *AuthService) TermsAccepted(ctx context.Context, userID string) (bool, error) {
+	if !s.ConsentsEnabled() {
+		return true, nil
+	}
+	accepted, err := s.consents.AcceptedTermsVersion(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return accepted == s.termsVersion, nil
+}
+
+// ConsentInput is a user's answer to the terms of service, marketing
+// consent or both
+type ConsentInput struct {
+	UserID           string
+	TermsVersion     string // accepts this terms version when set; it must be the current one
+	MarketingConsent *bool  // grants or withdraws marketing consent when set
+	IPAddress        *string
+}
+
+// RecordConsent records the user's answers
+func (s *AuthService) RecordConsent(ctx context.Context, input ConsentInput) error {
+	if input.TermsVersion != "" && input.TermsVersion != s.termsVersion {
+		return domain.ErrTermsNotAccepted
+	}
+
+	if input.TermsVersion != "" {
+		if err := s.recordConsent(ctx, input.UserID, domain.ConsentTerms, input.TermsVersion, true, input.IPAddress); err != nil {
+			return err
+		}
+	}
+	if input.MarketingConsent != nil {
+		if err := s.recordConsent(ctx, input.UserID, domain.ConsentMarketing, "", *input.MarketingConsent, input.IPAddress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordConsent stores one answer
+func (s *AuthService) recordConsent(ctx context.Context, userID string, kind domain.ConsentKind, version string, granted bool, ipAddress *string) error {
+	consent := &domain.Consent{
+		UserID:    userID,
+		Kind:      kind,
+		Version:   version,
+		Granted:   granted,
+		IPAddress: ipAddress,
+	}
+	if err := s.consents.Create(ctx, consent); err != nil {
+		return fmt.Errorf("failed to record %s consent: %w", kind, err)
+	}
+	return nil
+}
+
+// ConsentStatus is a user's current answers and every answer they gave
+type ConsentStatus struct {
+	TermsVersion         string // version users must accept
+	AcceptedTermsVersion string // version the user accepted last; empty if none
+	TermsAcceptedAt      *time.Time
+	MarketingConsent     bool
+	History              []*domain.Consent // oldest first
+}
+
+// Consents returns the user's consent answers
+func (s *AuthService) Consents(ctx context.Context, userID string) (*ConsentStatus, error) {
+	history, err := s.consents.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ConsentStatus{TermsVersion: s.termsVersion, History: history}
+	for _, consent := range history {
+		switch consent.Kind {
+		case domain.ConsentTerms:
+			if consent.Granted {
+				status.AcceptedTermsVersion = consent.Version
+				acceptedAt := consent.CreatedAt
+				status.TermsAcceptedAt = &acceptedAt
+			}
+		case domain.ConsentMarketing:
+			status.MarketingConsent = consent.Granted
+		}
+	}
+	return status, nil
+}
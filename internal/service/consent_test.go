@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+type mockConsentRepository struct {
+	consents []*domain.Consent
+}
+
+func (m *mockConsentRepository) Create(ctx context.Context, consent *domain.Consent) error {
+	consent.ID = fmt.Sprintf("consent-%d", len(m.consents)+1)
+	consent.CreatedAt = time.Now().Add(time.Duration(len(m.consents)) * time.Second)
+	m.consents = append(m.consents, consent)
+	return nil
+}
+
+func (m *mockConsentRepository) ListByUser(ctx context.Context, userID string) ([]*domain.Consent, error) {
+	var consents []*domain.Consent
+	for _, consent := range m.consents {
+		if consent.UserID == userID {
+			consents = append(consents, consent)
+		}
+	}
+	return consents, nil
+}
+
+func (m *mockConsentRepository) AcceptedTermsVersion(ctx context.Context, userID string) (string, error) {
+	version := ""
+	for _, consent := range m.consents {
+		if consent.UserID == userID && consent.Kind == domain.ConsentTerms && consent.Granted {
+			version = consent.Version
+		}
+	}
+	return version, nil
+}
+
+func TestAuthService_SignupConsent(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	consents := &mockConsentRepository{}
+	service.SetConsents(consents, "2026-01")
+	ctx := context.Background()
+	ip := "203.0.113.7"
+
+	for _, version := range []string{"", "2025-06"} {
+		_, err := service.Signup(ctx, SignupInput{Email: "stale@example.com", Password: "password123", TermsVersion: version})
+		if !errors.Is(err, domain.ErrTermsNotAccepted) {
+			t.Errorf("Signup() with terms version %q error = %v, want %v", version, err, domain.ErrTermsNotAccepted)
+		}
+	}
+
+	signup, err := service.Signup(ctx, SignupInput{
+		Email: "consent@example.com", Password: "password123",
+		TermsVersion: "2026-01", MarketingConsent: true, IPAddress: &ip,
+	})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	status, err := service.Consents(ctx, signup.UserID)
+	if err != nil {
+		t.Fatalf("Consents() error = %v", err)
+	}
+	if status.AcceptedTermsVersion != "2026-01" || status.TermsAcceptedAt == nil || !status.MarketingConsent {
+		t.Errorf("Consents() after signup = %+v", status)
+	}
+	if len(status.History) != 2 || *status.History[0].IPAddress != ip {
+		t.Errorf("history = %+v, want terms and marketing answers from %s", status.History, ip)
+	}
+}
+
+func TestAuthService_ReConsent(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	consents := &mockConsentRepository{}
+	service.SetConsents(consents, "2026-01")
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "reconsent@example.com", Password: "password123", TermsVersion: "2026-01"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if accepted, err := service.TermsAccepted(ctx, signup.UserID); err != nil || !accepted {
+		t.Fatalf("TermsAccepted() after signup = %v, %v", accepted, err)
+	}
+
+	// New terms are published
+	service.SetConsents(consents, "2026-07")
+	if accepted, _ := service.TermsAccepted(ctx, signup.UserID); accepted {
+		t.Error("TermsAccepted() = true for the previous version")
+	}
+
+	err = service.RecordConsent(ctx, ConsentInput{UserID: signup.UserID, TermsVersion: "2026-01"})
+	if !errors.Is(err, domain.ErrTermsNotAccepted) {
+		t.Errorf("RecordConsent() for old terms error = %v, want %v", err, domain.ErrTermsNotAccepted)
+	}
+
+	withdraw := false
+	if err := service.RecordConsent(ctx, ConsentInput{UserID: signup.UserID, TermsVersion: "2026-07", MarketingConsent: &withdraw}); err != nil {
+		t.Fatalf("RecordConsent() error = %v", err)
+	}
+	if accepted, _ := service.TermsAccepted(ctx, signup.UserID); !accepted {
+		t.Error("TermsAccepted() = false after accepting the new version")
+	}
+
+	status, err := service.Consents(ctx, signup.UserID)
+	if err != nil {
+		t.Fatalf("Consents() error = %v", err)
+	}
+	if status.TermsVersion != "2026-07" || status.AcceptedTermsVersion != "2026-07" || status.MarketingConsent {
+		t.Errorf("Consents() after re-consent = %+v", status)
+	}
+	if len(status.History) != 4 {
+		t.Errorf("history has %d answers, want 4", len(status.History))
+	}
+}
+
+func TestAuthService_TermsAcceptedWithoutConsents(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+
+	accepted, err := service.TermsAccepted(context.Background(), "user-1")
+	if err != nil || !accepted {
+		t.Errorf("TermsAccepted() without consents = %v, %v, want true", accepted, err)
+	}
+}
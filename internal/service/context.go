@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// followUpTimeout bounds work that runs on after the caller's context ended
+const followUpTimeout = 10 * time.Second
+
+// detach returns a context for work that must finish once a change was
+// committed, such as revoking sessions after a password change or queueing
+// the email for a new account. It keeps ctx's values but not its
+// cancellation, so a client that disconnects cannot leave the change half
+// done.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), followUpTimeout)
+}
+
+// isContextError reports whether err comes from a canceled or expired
+// context rather than from the operation itself, so an interrupted password
+// or code check is not reported as a wrong one
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// isCheckAborted reports whether a password or code check did not run to
+// completion, because its context ended or the hashing pool shed it under
+// load
+func isCheckAborted(err error) bool {
+	return isContextError(err) || errors.Is(err, security.ErrHashingOverloaded)
+}
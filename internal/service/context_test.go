@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/sms"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// The ctx* repositories fail like database/sql once the context is done,
+// so tests can check that services pass cancellation through instead of
+// masking it, e.g. as invalid credentials or a suppressed response
+
+type ctxUserRepository struct{ *mockUserRepository }
+
+func (r ctxUserRepository) Create(ctx context.Context, user *domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockUserRepository.Create(ctx, user)
+}
+
+func (r ctxUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockUserRepository.GetByID(ctx, id)
+}
+
+func (r ctxUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockUserRepository.GetByEmail(ctx, email)
+}
+
+func (r ctxUserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockUserRepository.GetByUsername(ctx, username)
+}
+
+func (r ctxUserRepository) Update(ctx context.Context, user *domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockUserRepository.Update(ctx, user)
+}
+
+func (r ctxUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return r.mockUserRepository.ExistsByEmail(ctx, email)
+}
+
+func (r ctxUserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return r.mockUserRepository.ExistsByUsername(ctx, username)
+}
+
+type ctxRefreshTokenRepository struct{ *mockRefreshTokenRepository }
+
+func (r ctxRefreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockRefreshTokenRepository.Create(ctx, token)
+}
+
+func (r ctxRefreshTokenRepository) GetByToken(ctx context.Context, token string) (*domain.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockRefreshTokenRepository.GetByToken(ctx, token)
+}
+
+func (r ctxRefreshTokenRepository) Revoke(ctx context.Context, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockRefreshTokenRepository.Revoke(ctx, token)
+}
+
+func (r ctxRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockRefreshTokenRepository.RevokeAllForUser(ctx, userID)
+}
+
+type ctxDeviceAuthorizationRepository struct {
+	*mockDeviceAuthorizationRepository
+}
+
+func (r ctxDeviceAuthorizationRepository) Create(ctx context.Context, auth *domain.DeviceAuthorization) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockDeviceAuthorizationRepository.Create(ctx, auth)
+}
+
+func (r ctxDeviceAuthorizationRepository) GetByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*domain.DeviceAuthorization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockDeviceAuthorizationRepository.GetByDeviceCodeHash(ctx, deviceCodeHash)
+}
+
+func (r ctxDeviceAuthorizationRepository) GetPendingByUserCodeHash(ctx context.Context, userCodeHash string, now time.Time) (*domain.DeviceAuthorization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockDeviceAuthorizationRepository.GetPendingByUserCodeHash(ctx, userCodeHash, now)
+}
+
+func (r ctxDeviceAuthorizationRepository) Decide(ctx context.Context, userCodeHash, userID string, status domain.DeviceAuthorizationStatus, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockDeviceAuthorizationRepository.Decide(ctx, userCodeHash, userID, status, now)
+}
+
+type ctxTrustedDeviceRepository struct{ *mockTrustedDeviceRepository }
+
+func (r ctxTrustedDeviceRepository) Create(ctx context.Context, device *domain.TrustedDevice) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockTrustedDeviceRepository.Create(ctx, device)
+}
+
+func (r ctxTrustedDeviceRepository) GetByID(ctx context.Context, userID, id string) (*domain.TrustedDevice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockTrustedDeviceRepository.GetByID(ctx, userID, id)
+}
+
+func (r ctxTrustedDeviceRepository) ListByUser(ctx context.Context, userID string) ([]*domain.TrustedDevice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockTrustedDeviceRepository.ListByUser(ctx, userID)
+}
+
+func (r ctxTrustedDeviceRepository) Delete(ctx context.Context, userID, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return r.mockTrustedDeviceRepository.Delete(ctx, userID, id)
+}
+
+type ctxLoginApprovalRepository struct{ *mockLoginApprovalRepository }
+
+func (r ctxLoginApprovalRepository) GetByID(ctx context.Context, id string) (*domain.LoginApproval, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockLoginApprovalRepository.GetByID(ctx, id)
+}
+
+func (r ctxLoginApprovalRepository) ListPending(ctx context.Context, userID string, now time.Time) ([]*domain.LoginApproval, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.mockLoginApprovalRepository.ListPending(ctx, userID, now)
+}
+
+// createContextTestAuthService returns a service with every optional flow
+// enabled on context-aware repositories, and a verified user with a session
+func createContextTestAuthService(t *testing.T) (*AuthService, *SignupOutput, *LoginOutput) {
+	t.Helper()
+	tokenManager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create token manager: %v", err)
+	}
+	service := NewAuthService(
+		ctxUserRepository{newMockUserRepository()},
+		ctxRefreshTokenRepository{newMockRefreshTokenRepository()},
+		security.NewPasswordHasher(security.MinCost),
+		tokenManager,
+		time.Hour,
+	)
+	signer, err := security.NewURLSigner("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("NewURLSigner() error = %v", err)
+	}
+	policy := CodePolicy{TTL: 5 * time.Minute, MaxAttempts: 3}
+	service.SetAvatarUploads(signer, "https://uploads.example.com", time.Minute)
+	service.SetEmailCodes(newMockEmailCodeRepository(), policy)
+	service.SetSMS(sms.NewMockSender(), newMockEmailCodeRepository(), "Test App", policy)
+	service.SetLoginApprovals(ctxTrustedDeviceRepository{newMockTrustedDeviceRepository()}, ctxLoginApprovalRepository{newMockLoginApprovalRepository()}, time.Minute)
+	service.SetDeviceAuthorization(ctxDeviceAuthorizationRepository{newMockDeviceAuthorizationRepository()}, 10*time.Minute, "https://auth.example.com/device")
+
+	ctx := context.Background()
+	signup, err := service.Signup(ctx, SignupInput{Email: "ctx@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	login, err := service.Login(ctx, LoginInput{Email: "ctx@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	return service, signup, login
+}
+
+func TestAuthService_CanceledContext(t *testing.T) {
+	service, signup, login := createContextTestAuthService(t)
+	userID := signup.UserID
+	credentials := LoginInput{Email: "ctx@example.com", Password: "password123"}
+	userCode, err := domain.GenerateUserCode()
+	if err != nil {
+		t.Fatalf("GenerateUserCode() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"Signup", func(ctx context.Context) error {
+			_, err := service.Signup(ctx, SignupInput{Email: "new@example.com", Password: "password123"})
+			return err
+		}},
+		{"Login", func(ctx context.Context) error {
+			_, err := service.Login(ctx, credentials)
+			return err
+		}},
+		{"Refresh", func(ctx context.Context) error {
+			_, err := service.Refresh(ctx, RefreshInput{RefreshToken: login.RefreshToken})
+			return err
+		}},
+		{"Logout", func(ctx context.Context) error {
+			return service.Logout(ctx, LogoutInput{RefreshToken: login.RefreshToken})
+		}},
+		{"LogoutAll", func(ctx context.Context) error {
+			return service.LogoutAll(ctx, userID)
+		}},
+		{"VerifyEmail", func(ctx context.Context) error {
+			return service.VerifyEmail(ctx, VerifyEmailInput{Email: "ctx@example.com", Token: signup.EmailVerificationToken})
+		}},
+		{"ResendVerificationEmail", func(ctx context.Context) error {
+			_, err := service.ResendVerificationEmail(ctx, "ctx@example.com")
+			return err
+		}},
+		{"UpdateProfile", func(ctx context.Context) error {
+			name := "Ctx"
+			_, err := service.UpdateProfile(ctx, userID, domain.ProfileUpdate{DisplayName: &name})
+			return err
+		}},
+		{"UsernameAvailable", func(ctx context.Context) error {
+			_, err := service.UsernameAvailable(ctx, "ctx_user")
+			return err
+		}},
+		{"GetUserByID", func(ctx context.Context) error {
+			_, err := service.GetUserByID(ctx, userID)
+			return err
+		}},
+		{"CreateAvatarUpload", func(ctx context.Context) error {
+			_, err := service.CreateAvatarUpload(ctx, userID)
+			return err
+		}},
+		{"RevokeSessions", func(ctx context.Context) error {
+			return service.RevokeSessions(ctx, userID, RevokeReasonForceLogout)
+		}},
+		{"ChangePassword", func(ctx context.Context) error {
+			return service.ChangePassword(ctx, ChangePasswordInput{UserID: userID, CurrentPassword: "password123", NewPassword: "password456"})
+		}},
+		{"VerifyEmailCode", func(ctx context.Context) error {
+			return service.VerifyEmailCode(ctx, VerifyEmailCodeInput{Email: "ctx@example.com", Code: "123456"})
+		}},
+		{"RequestPasswordReset", func(ctx context.Context) error {
+			_, err := service.RequestPasswordReset(ctx, "ctx@example.com")
+			return err
+		}},
+		{"ResetPassword", func(ctx context.Context) error {
+			return service.ResetPassword(ctx, ResetPasswordInput{Email: "ctx@example.com", Code: "123456", NewPassword: "password456"})
+		}},
+		{"AddPhoneNumber", func(ctx context.Context) error {
+			return service.AddPhoneNumber(ctx, userID, "+15551234567")
+		}},
+		{"VerifyPhoneNumber", func(ctx context.Context) error {
+			return service.VerifyPhoneNumber(ctx, userID, "123456")
+		}},
+		{"SetSMSSecondFactor", func(ctx context.Context) error {
+			return service.SetSMSSecondFactor(ctx, userID, false)
+		}},
+		{"RequestSMSRecovery", func(ctx context.Context) error {
+			return service.RequestSMSRecovery(ctx, "ctx@example.com")
+		}},
+		{"RecoverWithSMS", func(ctx context.Context) error {
+			return service.RecoverWithSMS(ctx, ResetPasswordInput{Email: "ctx@example.com", Code: "123456", NewPassword: "password456"})
+		}},
+		{"RegisterTrustedDevice", func(ctx context.Context) error {
+			_, err := service.RegisterTrustedDevice(ctx, userID, "Phone")
+			return err
+		}},
+		{"ListTrustedDevices", func(ctx context.Context) error {
+			_, err := service.ListTrustedDevices(ctx, userID)
+			return err
+		}},
+		{"RemoveTrustedDevice", func(ctx context.Context) error {
+			return service.RemoveTrustedDevice(ctx, userID, testUUID(1))
+		}},
+		{"StartLoginApproval", func(ctx context.Context) error {
+			_, err := service.StartLoginApproval(ctx, StartLoginApprovalInput{Email: "ctx@example.com"})
+			return err
+		}},
+		{"ListLoginApprovals", func(ctx context.Context) error {
+			_, err := service.ListLoginApprovals(ctx, userID)
+			return err
+		}},
+		{"DecideLoginApproval", func(ctx context.Context) error {
+			return service.DecideLoginApproval(ctx, DecideLoginApprovalInput{UserID: userID, ApprovalID: testUUID(2), DeviceID: testUUID(1), DeviceSecret: "secret", Approve: true})
+		}},
+		{"PollLoginApproval", func(ctx context.Context) error {
+			_, err := service.PollLoginApproval(ctx, PollLoginApprovalInput{ApprovalID: testUUID(2), PollToken: "poll-token"})
+			return err
+		}},
+		{"StartDeviceAuthorization", func(ctx context.Context) error {
+			_, err := service.StartDeviceAuthorization(ctx, "authctl")
+			return err
+		}},
+		{"LookupDeviceAuthorization", func(ctx context.Context) error {
+			_, err := service.LookupDeviceAuthorization(ctx, userCode)
+			return err
+		}},
+		{"DecideDeviceAuthorization", func(ctx context.Context) error {
+			return service.DecideDeviceAuthorization(ctx, userID, userCode, true)
+		}},
+		{"DecideDeviceAuthorizationWithPassword", func(ctx context.Context) error {
+			return service.DecideDeviceAuthorizationWithPassword(ctx, credentials, userCode, true)
+		}},
+		{"PollDeviceToken", func(ctx context.Context) error {
+			_, err := service.PollDeviceToken(ctx, PollDeviceTokenInput{DeviceCode: "device-code"})
+			return err
+		}},
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(canceled); !errors.Is(err, context.Canceled) {
+				t.Errorf("%s() error = %v, want %v", tt.name, err, context.Canceled)
+			}
+		})
+	}
+}
+
+func TestAuthService_InterruptedRequests(t *testing.T) {
+	service, signup, _ := createContextTestAuthService(t)
+	audit := &mockAuditLogRepository{}
+	service.SetAuditLog(audit)
+
+	// The deadline passes while bcrypt runs: the login must fail with the
+	// deadline, not with invalid credentials
+	expiring, cancel := context.WithDeadline(context.Background(), time.Now().Add(time.Millisecond))
+	defer cancel()
+	service.passwordHasher = security.NewPasswordHasher(security.MaxCost)
+	_, err := service.Login(expiring, LoginInput{Email: "ctx@example.com", Password: "password123"})
+	if errors.Is(err, domain.ErrInvalidCredentials) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Login() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Work after a committed change outlives the caller's context
+	canceledAfterCommit := &cancelOnUpdateUserRepository{
+		ctxUserRepository: service.userRepo.(ctxUserRepository),
+	}
+	service.userRepo = canceledAfterCommit
+	service.passwordHasher = security.NewPasswordHasher(security.MinCost)
+	ctx, cancel := context.WithCancel(context.Background())
+	canceledAfterCommit.cancel = cancel
+	if err := service.VerifyEmail(ctx, VerifyEmailInput{Email: "ctx@example.com", Token: signup.EmailVerificationToken}); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	if len(audit.logs) != 1 || audit.logs[0].Metadata["reason"] != RevokeReasonEmailVerified {
+		t.Errorf("expected the session revocation to be audited, got %+v", audit.logs)
+	}
+}
+
+// cancelOnUpdateUserRepository cancels the caller's context right after a
+// user update, like a client disconnecting once the change is committed
+type cancelOnUpdateUserRepository struct {
+	ctxUserRepository
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnUpdateUserRepository) Update(ctx context.Context, user *domain.User) error {
+	err := r.ctxUserRepository.Update(ctx, user)
+	r.cancel()
+	return err
+}
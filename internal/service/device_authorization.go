@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// DeviceCodePollInterval is how often devices should poll for tokens
+const DeviceCodePollInterval = 5 * time.Second
+
+// devicePollSlack lets polls arrive this much early, e.g. after network
+// jitter, before they count as too fast
+const devicePollSlack = time.Second
+
+// SetDeviceAuthorization enables the device authorization grant (RFC 8628)
+// for clients without a browser, such as authctl. Users approve devices at
+// verificationURI; codes not approved and used within ttl expire.
+func (s *AuthService) SetDeviceAuthorization(repo repository.DeviceAuthorizationRepository, ttl time.Duration, verificationURI string) {
+	s.deviceAuths = repo
+	s.deviceCodeTTL = ttl
+	s.deviceVerifyURI = verificationURI
+}
+
+// DeviceAuthorizationEnabled reports whether the device authorization grant is enabled
+func (s *AuthService) DeviceAuthorizationEnabled() bool {
+	return s.deviceAuths != nil
+}
+
+// DeviceAuthorizationOutput is the device authorization response
+type DeviceAuthorizationOutput struct {
+	DeviceCode              string // proves the device when it polls
+	UserCode                string // entered by the user at VerificationURI
+	VerificationURI         string
+	VerificationURIComplete string // VerificationURI with the user code filled in, e.g. for a QR code
+	ExpiresIn               int64  // seconds
+	Interval                int64  // seconds between polls
+}
+
+// StartDeviceAuthorization starts a device authorization for clientID
+func (s *AuthService) StartDeviceAuthorization(ctx context.Context, clientID string) (*DeviceAuthorizationOutput, error) {
+	if err := domain.ValidateClientID(clientID); err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := security.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := domain.GenerateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	auth := &domain.DeviceAuthorization{
+		DeviceCodeHash: security.HashToken(deviceCode),
+		UserCodeHash:   security.HashToken(userCode),
+		ClientID:       clientID,
+		Status:         domain.DeviceAuthorizationPending,
+		ExpiresAt:      now.Add(s.deviceCodeTTL),
+		CreatedAt:      now,
+	}
+	if err := s.deviceAuths.Create(ctx, auth); err != nil {
+		return nil, err
+	}
+
+	formatted := domain.FormatUserCode(userCode)
+	return &DeviceAuthorizationOutput{
+		DeviceCode:              deviceCode,
+		UserCode:                formatted,
+		VerificationURI:         s.deviceVerifyURI,
+		VerificationURIComplete: s.deviceVerifyURI + "?user_code=" + url.QueryEscape(formatted),
+		ExpiresIn:               int64(s.deviceCodeTTL.Seconds()),
+		Interval:                int64(DeviceCodePollInterval.Seconds()),
+	}, nil
+}
+
+// LookupDeviceAuthorization returns the authorization awaiting a decision
+// for a user code, so the user can check which client asks for access. It
+// returns domain.ErrInvalidUserCode for unknown, expired or decided codes.
+func (s *AuthService) LookupDeviceAuthorization(ctx context.Context, userCode string) (*domain.DeviceAuthorization, error) {
+	code := domain.NormalizeUserCode(userCode)
+	if len(code) != domain.UserCodeLength {
+		return nil, domain.ErrInvalidUserCode
+	}
+	return s.deviceAuths.GetPendingByUserCodeHash(ctx, security.HashToken(code), s.clock.Now())
+}
+
+// DecideDeviceAuthorization approves or denies the device showing userCode
+// for the signed-in user
+func (s *AuthService) DecideDeviceAuthorization(ctx context.Context, userID, userCode string, approve bool) error {
+	code := domain.NormalizeUserCode(userCode)
+	if len(code) != domain.UserCodeLength {
+		return domain.ErrInvalidUserCode
+	}
+
+	status := domain.DeviceAuthorizationDenied
+	if approve {
+		status = domain.DeviceAuthorizationApproved
+	}
+	if err := s.deviceAuths.Decide(ctx, security.HashToken(code), userID, status, s.clock.Now()); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "device authorization decided", "user_id", userID, "status", status)
+	return nil
+}
+
+// DecideDeviceAuthorizationWithPassword signs the user in with the login
+// credentials and then approves or denies the device, for the verification
+// page that has no session of its own
+func (s *AuthService) DecideDeviceAuthorizationWithPassword(ctx context.Context, login LoginInput, userCode string, approve bool) error {
+	defer s.padResponse(ctx, s.clock.Now())
+
+	user, err := s.authenticate(ctx, login)
+	if err != nil {
+		return err
+	}
+	return s.DecideDeviceAuthorization(ctx, user.ID, userCode, approve)
+}
+
+// PollDeviceTokenInput represents a device's poll for tokens
+type PollDeviceTokenInput struct {
+	DeviceCode     string
+	UserAgent      *string
+	IPAddress      *string
+	CertThumbprint string            // mTLS client certificate thumbprint; binds the access token when set
+	DPoP           token.DPoPRequest // DPoP proof; binds the tokens to its key when set
+}
+
+// PollDeviceToken returns tokens once the user approved the device. Until
+// then it fails with domain.ErrAuthorizationPending, with domain.ErrSlowDown
+// when polled faster than DeviceCodePollInterval, and with
+// domain.ErrDeviceAccessDenied or domain.ErrDeviceCodeExpired once the
+// authorization is over. Tokens are issued once.
+func (s *AuthService) PollDeviceToken(ctx context.Context, input PollDeviceTokenInput) (*LoginOutput, error) {
+	cnf, err := s.confirmation(input.CertThumbprint, input.DPoP)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.DeviceCode == "" {
+		return nil, domain.ErrInvalidDeviceCode
+	}
+	auth, err := s.deviceAuths.GetByDeviceCodeHash(ctx, security.HashToken(input.DeviceCode))
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if auth.IsExpired(now) {
+		return nil, domain.ErrDeviceCodeExpired
+	}
+	if err := s.deviceAuths.RecordPoll(ctx, auth.ID, now, DeviceCodePollInterval-devicePollSlack); err != nil {
+		return nil, err
+	}
+
+	switch auth.Status {
+	case domain.DeviceAuthorizationPending:
+		return nil, domain.ErrAuthorizationPending
+	case domain.DeviceAuthorizationDenied:
+		return nil, domain.ErrDeviceAccessDenied
+	}
+
+	// Concurrent polls race here; only one consumes the authorization
+	if err := s.deviceAuths.Consume(ctx, auth.ID, now); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, *auth.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, domain.ErrAccountDisabled
+	}
+	s.recordActivity(ctx, user.ID)
+
+	slog.InfoContext(ctx, "device authorized", "user_id", user.ID, "client_id", auth.ClientID)
+	return s.issueLoginTokens(ctx, user, cnf, input.UserAgent, input.IPAddress, false)
+}
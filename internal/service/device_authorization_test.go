@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+type mockDeviceAuthorizationRepository struct {
+	auths  map[string]*domain.DeviceAuthorization
+	nextID int
+}
+
+func newMockDeviceAuthorizationRepository() *mockDeviceAuthorizationRepository {
+	return &mockDeviceAuthorizationRepository{auths: make(map[string]*domain.DeviceAuthorization)}
+}
+
+func (m *mockDeviceAuthorizationRepository) Create(ctx context.Context, auth *domain.DeviceAuthorization) error {
+	m.nextID++
+	auth.ID = testUUID(m.nextID)
+	m.auths[auth.ID] = auth
+	return nil
+}
+
+func (m *mockDeviceAuthorizationRepository) GetByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*domain.DeviceAuthorization, error) {
+	for _, auth := range m.auths {
+		if auth.DeviceCodeHash == deviceCodeHash {
+			return auth, nil
+		}
+	}
+	return nil, domain.ErrInvalidDeviceCode
+}
+
+func (m *mockDeviceAuthorizationRepository) GetPendingByUserCodeHash(ctx context.Context, userCodeHash string, now time.Time) (*domain.DeviceAuthorization, error) {
+	for _, auth := range m.auths {
+		if auth.UserCodeHash == userCodeHash && auth.Status == domain.DeviceAuthorizationPending && !auth.IsExpired(now) {
+			return auth, nil
+		}
+	}
+	return nil, domain.ErrInvalidUserCode
+}
+
+func (m *mockDeviceAuthorizationRepository) Decide(ctx context.Context, userCodeHash, userID string, status domain.DeviceAuthorizationStatus, now time.Time) error {
+	auth, err := m.GetPendingByUserCodeHash(ctx, userCodeHash, now)
+	if err != nil {
+		return err
+	}
+	auth.Status = status
+	auth.UserID = &userID
+	return nil
+}
+
+func (m *mockDeviceAuthorizationRepository) RecordPoll(ctx context.Context, id string, now time.Time, minInterval time.Duration) error {
+	auth := m.auths[id]
+	if auth.LastPolledAt != nil && auth.LastPolledAt.After(now.Add(-minInterval)) {
+		return domain.ErrSlowDown
+	}
+	auth.LastPolledAt = &now
+	return nil
+}
+
+func (m *mockDeviceAuthorizationRepository) Consume(ctx context.Context, id string, now time.Time) error {
+	auth, ok := m.auths[id]
+	if !ok || auth.Status != domain.DeviceAuthorizationApproved || auth.IsExpired(now) {
+		return domain.ErrInvalidDeviceCode
+	}
+	delete(m.auths, id)
+	return nil
+}
+
+// rewindPolls pretends the last polls happened long enough ago
+func (m *mockDeviceAuthorizationRepository) rewindPolls() {
+	for _, auth := range m.auths {
+		auth.LastPolledAt = nil
+	}
+}
+
+func TestAuthService_DeviceAuthorization(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	repo := newMockDeviceAuthorizationRepository()
+	service.SetDeviceAuthorization(repo, 10*time.Minute, "https://auth.example.com/device")
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "device@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	if _, err := service.StartDeviceAuthorization(ctx, "auth ctl"); !errors.Is(err, domain.ErrInvalidClientID) {
+		t.Fatalf("StartDeviceAuthorization() error = %v, want %v", err, domain.ErrInvalidClientID)
+	}
+	start, err := service.StartDeviceAuthorization(ctx, "authctl")
+	if err != nil {
+		t.Fatalf("StartDeviceAuthorization() error = %v", err)
+	}
+	if len(start.UserCode) != domain.UserCodeLength+1 || start.UserCode[4] != '-' {
+		t.Errorf("UserCode = %q, want XXXX-XXXX", start.UserCode)
+	}
+	if start.VerificationURIComplete != start.VerificationURI+"?user_code="+url.QueryEscape(start.UserCode) {
+		t.Errorf("VerificationURIComplete = %q", start.VerificationURIComplete)
+	}
+	if start.ExpiresIn != 600 || start.Interval != 5 {
+		t.Errorf("ExpiresIn = %d, Interval = %d", start.ExpiresIn, start.Interval)
+	}
+
+	poll := PollDeviceTokenInput{DeviceCode: start.DeviceCode}
+	if _, err := service.PollDeviceToken(ctx, poll); !errors.Is(err, domain.ErrAuthorizationPending) {
+		t.Fatalf("PollDeviceToken() error = %v, want %v", err, domain.ErrAuthorizationPending)
+	}
+	if _, err := service.PollDeviceToken(ctx, poll); !errors.Is(err, domain.ErrSlowDown) {
+		t.Fatalf("PollDeviceToken() too fast error = %v, want %v", err, domain.ErrSlowDown)
+	}
+	if _, err := service.PollDeviceToken(ctx, PollDeviceTokenInput{DeviceCode: "wrong"}); !errors.Is(err, domain.ErrInvalidDeviceCode) {
+		t.Fatalf("PollDeviceToken() with wrong code error = %v, want %v", err, domain.ErrInvalidDeviceCode)
+	}
+
+	// Users may type the code in lower case and without the dash
+	typed := strings.ToLower(strings.ReplaceAll(start.UserCode, "-", ""))
+	auth, err := service.LookupDeviceAuthorization(ctx, typed)
+	if err != nil || auth.ClientID != "authctl" {
+		t.Fatalf("LookupDeviceAuthorization() = %+v, error = %v", auth, err)
+	}
+
+	wrongPassword := LoginInput{Email: "device@example.com", Password: "wrong"}
+	if err := service.DecideDeviceAuthorizationWithPassword(ctx, wrongPassword, typed, true); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("DecideDeviceAuthorizationWithPassword() error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+	login := LoginInput{Email: "device@example.com", Password: "password123"}
+	if err := service.DecideDeviceAuthorizationWithPassword(ctx, login, typed, true); err != nil {
+		t.Fatalf("DecideDeviceAuthorizationWithPassword() error = %v", err)
+	}
+
+	// Each code is decided once
+	if err := service.DecideDeviceAuthorization(ctx, signup.UserID, start.UserCode, false); !errors.Is(err, domain.ErrInvalidUserCode) {
+		t.Fatalf("second DecideDeviceAuthorization() error = %v, want %v", err, domain.ErrInvalidUserCode)
+	}
+
+	repo.rewindPolls()
+	output, err := service.PollDeviceToken(ctx, poll)
+	if err != nil {
+		t.Fatalf("PollDeviceToken() error = %v", err)
+	}
+	if output.AccessToken == "" || output.RefreshToken == "" {
+		t.Error("PollDeviceToken() returned no tokens")
+	}
+
+	// Tokens are issued once
+	if _, err := service.PollDeviceToken(ctx, poll); !errors.Is(err, domain.ErrInvalidDeviceCode) {
+		t.Fatalf("second PollDeviceToken() error = %v, want %v", err, domain.ErrInvalidDeviceCode)
+	}
+}
+
+func TestAuthService_DeviceAuthorizationDeniedAndExpired(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	repo := newMockDeviceAuthorizationRepository()
+	service.SetDeviceAuthorization(repo, 10*time.Minute, "https://auth.example.com/device")
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "deny-device@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	denied, err := service.StartDeviceAuthorization(ctx, "authctl")
+	if err != nil {
+		t.Fatalf("StartDeviceAuthorization() error = %v", err)
+	}
+	if err := service.DecideDeviceAuthorization(ctx, signup.UserID, denied.UserCode, false); err != nil {
+		t.Fatalf("DecideDeviceAuthorization() error = %v", err)
+	}
+	if _, err := service.PollDeviceToken(ctx, PollDeviceTokenInput{DeviceCode: denied.DeviceCode}); !errors.Is(err, domain.ErrDeviceAccessDenied) {
+		t.Fatalf("PollDeviceToken() error = %v, want %v", err, domain.ErrDeviceAccessDenied)
+	}
+
+	expired, err := service.StartDeviceAuthorization(ctx, "authctl")
+	if err != nil {
+		t.Fatalf("StartDeviceAuthorization() error = %v", err)
+	}
+	for _, auth := range repo.auths {
+		auth.ExpiresAt = time.Now().Add(-time.Second)
+	}
+	if _, err := service.PollDeviceToken(ctx, PollDeviceTokenInput{DeviceCode: expired.DeviceCode}); !errors.Is(err, domain.ErrDeviceCodeExpired) {
+		t.Fatalf("PollDeviceToken() error = %v, want %v", err, domain.ErrDeviceCodeExpired)
+	}
+	if err := service.DecideDeviceAuthorization(ctx, signup.UserID, expired.UserCode, true); !errors.Is(err, domain.ErrInvalidUserCode) {
+		t.Fatalf("DecideDeviceAuthorization() error = %v, want %v", err, domain.ErrInvalidUserCode)
+	}
+}
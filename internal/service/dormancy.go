@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/config"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	emailpkg "github.com/n1rocket/go-auth-jwt/internal/email"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// Dormancy stages, in the order an inactive account goes through them
+const (
+	DormancyStageNotify  = "notify"
+	DormancyStageDisable = "disable"
+	DormancyStagePurge   = "purge"
+)
+
+// maxDormancyReports is how many run reports are kept for the admin API
+const maxDormancyReports = 20
+
+// DormancyPolicy configures when inactive accounts are notified, disabled and
+// purged. Each duration is measured from the account's last activity.
+type DormancyPolicy struct {
+	NotifyAfter  time.Duration
+	DisableAfter time.Duration
+	PurgeAfter   time.Duration // zero keeps disabled accounts forever
+	BatchSize    int           // accounts handled per stage and run
+}
+
+// Validate checks that the stages are ordered
+func (p DormancyPolicy) Validate() error {
+	if p.NotifyAfter <= 0 {
+		return fmt.Errorf("notify after must be positive")
+	}
+	if p.DisableAfter <= p.NotifyAfter {
+		return fmt.Errorf("disable after must be longer than notify after")
+	}
+	if p.PurgeAfter != 0 && p.PurgeAfter <= p.DisableAfter {
+		return fmt.Errorf("purge after must be longer than disable after")
+	}
+	if p.BatchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	return nil
+}
+
+// DormancyNotifier tells a user that their account will be disabled
+type DormancyNotifier interface {
+	NotifyDormant(ctx context.Context, account *domain.DormantAccount, disableAt time.Time) error
+}
+
+// DormancyAction is one account handled by a dormancy run
+type DormancyAction struct {
+	UserID       string
+	Email        string
+	LastActiveAt time.Time
+}
+
+// DormancyFailure is an account a dormancy run could not handle
+type DormancyFailure struct {
+	UserID string
+	Stage  string
+	Error  string
+}
+
+// DormancyReport describes a dormancy run. In a dry run the actions list
+// what would have happened and nothing is changed.
+type DormancyReport struct {
+	ID         string
+	DryRun     bool
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Notified   []DormancyAction
+	Disabled   []DormancyAction
+	Purged     []DormancyAction
+	Failures   []DormancyFailure
+}
+
+// DormancyService applies the dormancy policy to inactive accounts
+type DormancyService struct {
+	activity         repository.AccountActivityRepository
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	auditRepo        repository.AuditLogRepository
+	notifier         DormancyNotifier
+	policy           DormancyPolicy
+	now              func() time.Time
+
+	runMu   sync.Mutex // held for the duration of a run
+	mu      sync.Mutex
+	reports []*DormancyReport // newest first
+}
+
+// NewDormancyService creates a dormancy service
+func NewDormancyService(
+	activity repository.AccountActivityRepository,
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	notifier DormancyNotifier,
+	policy DormancyPolicy,
+) *DormancyService {
+	return &DormancyService{
+		activity:         activity,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		notifier:         notifier,
+		policy:           policy,
+		now:              time.Now,
+	}
+}
+
+// SetAuditLog records disabled and purged accounts in the audit trail
+func (s *DormancyService) SetAuditLog(auditRepo repository.AuditLogRepository) {
+	s.auditRepo = auditRepo
+}
+
+// Policy returns the configured policy
+func (s *DormancyService) Policy() DormancyPolicy {
+	return s.policy
+}
+
+// Run applies the policy once: accounts past the notify threshold are
+// notified, notified accounts whose warning period has passed are disabled
+// and their sessions revoked, and accounts disabled long enough are purged.
+// Each stage only acts on accounts that went through the previous one, so a
+// long-inactive account is never disabled without notice. Per-account
+// failures are recorded in the report; an error is only returned when an
+// account list cannot be read.
+func (s *DormancyService) Run(ctx context.Context, dryRun bool) (*DormancyReport, error) {
+	if !s.runMu.TryLock() {
+		return nil, domain.ErrDormancyRunInProgress
+	}
+	defer s.runMu.Unlock()
+
+	id, err := security.GenerateToken(8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate report id: %w", err)
+	}
+	now := s.now()
+	report := &DormancyReport{ID: id, DryRun: dryRun, StartedAt: now}
+	defer func() {
+		report.FinishedAt = s.now()
+		s.saveReport(report)
+	}()
+
+	// Notify accounts that crossed the first threshold
+	inactive, err := s.activity.ListInactive(ctx, now.Add(-s.policy.NotifyAfter), s.policy.BatchSize)
+	if err != nil {
+		return report, err
+	}
+	for _, account := range inactive {
+		if !dryRun {
+			// The warning period starts now, whatever the account's age
+			disableAt := now.Add(s.policy.DisableAfter - s.policy.NotifyAfter)
+			if err := s.notifier.NotifyDormant(ctx, account, disableAt); err != nil {
+				report.fail(account, DormancyStageNotify, err)
+				continue
+			}
+			if err := s.activity.MarkNotified(ctx, account.UserID, now); err != nil {
+				report.fail(account, DormancyStageNotify, err)
+				continue
+			}
+		}
+		report.Notified = append(report.Notified, newDormancyAction(account))
+	}
+
+	// Disable accounts whose warning period has passed
+	notified, err := s.activity.ListNotified(ctx, now.Add(-(s.policy.DisableAfter - s.policy.NotifyAfter)), s.policy.BatchSize)
+	if err != nil {
+		return report, err
+	}
+	for _, account := range notified {
+		if !dryRun {
+			if err := s.activity.Disable(ctx, account.UserID, now); err != nil {
+				report.fail(account, DormancyStageDisable, err)
+				continue
+			}
+			if err := s.refreshTokenRepo.RevokeAllForUser(ctx, account.UserID); err != nil {
+				report.fail(account, DormancyStageDisable, err)
+				continue
+			}
+			s.audit(ctx, domain.AuditActionDormancyDisable, account)
+		}
+		report.Disabled = append(report.Disabled, newDormancyAction(account))
+	}
+
+	// Purge accounts that stayed disabled for the retention period
+	if s.policy.PurgeAfter > 0 {
+		disabled, err := s.activity.ListDisabled(ctx, now.Add(-(s.policy.PurgeAfter - s.policy.DisableAfter)), s.policy.BatchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, account := range disabled {
+			if !dryRun {
+				// Audited first; the entry outlives the user, whose ID is
+				// kept in the metadata once user_id is set to NULL
+				s.audit(ctx, domain.AuditActionDormancyPurge, account)
+				if err := s.userRepo.Delete(ctx, account.UserID); err != nil {
+					report.fail(account, DormancyStagePurge, err)
+					continue
+				}
+			}
+			report.Purged = append(report.Purged, newDormancyAction(account))
+		}
+	}
+
+	slog.InfoContext(ctx, "dormancy run finished",
+		"report_id", report.ID,
+		"dry_run", dryRun,
+		"notified", len(report.Notified),
+		"disabled", len(report.Disabled),
+		"purged", len(report.Purged),
+		"failures", len(report.Failures),
+	)
+	return report, nil
+}
+
+// Reports returns the most recent run reports, newest first
+func (s *DormancyService) Reports() []*DormancyReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*DormancyReport(nil), s.reports...)
+}
+
+// Report returns a run report by ID
+func (s *DormancyService) Report(id string) (*DormancyReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, report := range s.reports {
+		if report.ID == id {
+			return report, true
+		}
+	}
+	return nil, false
+}
+
+func (s *DormancyService) saveReport(report *DormancyReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = append([]*DormancyReport{report}, s.reports...)
+	if len(s.reports) > maxDormancyReports {
+		s.reports = s.reports[:maxDormancyReports]
+	}
+}
+
+// audit records a dormancy action; failures are only logged
+func (s *DormancyService) audit(ctx context.Context, action string, account *domain.DormantAccount) {
+	if s.auditRepo == nil {
+		return
+	}
+	entry := domain.NewAuditLog(action, domain.AuditStatusSuccess)
+	entry.UserID = &account.UserID
+	entry.Metadata["user_id"] = account.UserID
+	entry.Metadata["last_active_at"] = account.LastActiveAt.UTC().Format(time.RFC3339)
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write dormancy audit log", "user_id", account.UserID, "action", action, "error", err)
+	}
+}
+
+func (r *DormancyReport) fail(account *domain.DormantAccount, stage string, err error) {
+	r.Failures = append(r.Failures, DormancyFailure{UserID: account.UserID, Stage: stage, Error: err.Error()})
+}
+
+func newDormancyAction(account *domain.DormantAccount) DormancyAction {
+	return DormancyAction{UserID: account.UserID, Email: account.Email, LastActiveAt: account.LastActiveAt}
+}
+
+// EmailDormancyNotifier sends the dormancy notice by email. Sending is
+// synchronous so an account is only marked notified once the mail server
+// accepted the notice.
+type EmailDormancyNotifier struct {
+	sender emailpkg.Service
+	config *config.Config
+}
+
+// NewEmailDormancyNotifier creates a notifier sending through sender
+func NewEmailDormancyNotifier(sender emailpkg.Service, config *config.Config) *EmailDormancyNotifier {
+	return &EmailDormancyNotifier{sender: sender, config: config}
+}
+
+// NotifyDormant emails the account's owner the date it will be disabled
+func (n *EmailDormancyNotifier) NotifyDormant(ctx context.Context, account *domain.DormantAccount, disableAt time.Time) error {
+	data := emailpkg.TemplateData{
+		BaseURL:        n.config.App.BaseURL,
+		AppName:        n.config.App.Name,
+		SupportEmail:   n.config.Email.SupportEmail,
+		RecipientEmail: account.Email,
+		LoginURL:       fmt.Sprintf("%s/login", n.config.App.BaseURL),
+		DisableDate:    disableAt.UTC().Format("2006-01-02"),
+	}
+
+	notice, err := emailpkg.RenderTemplate(emailpkg.DormancyNoticeEmailTemplate.ForLocale(stringValue(account.Locale)), data)
+	if err != nil {
+		return fmt.Errorf("failed to render dormancy notice: %w", err)
+	}
+
+	if err := n.sender.Send(ctx, notice); err != nil {
+		return fmt.Errorf("failed to send dormancy notice: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockAccountActivityRepository keeps account activity in memory
+type mockAccountActivityRepository struct {
+	accounts map[string]*domain.DormantAccount
+	recorded map[string]time.Time
+}
+
+func newMockAccountActivityRepository() *mockAccountActivityRepository {
+	return &mockAccountActivityRepository{
+		accounts: make(map[string]*domain.DormantAccount),
+		recorded: make(map[string]time.Time),
+	}
+}
+
+func (m *mockAccountActivityRepository) RecordActivity(ctx context.Context, userID string, at time.Time) error {
+	m.recorded[userID] = at
+	if account, ok := m.accounts[userID]; ok {
+		account.LastActiveAt = at
+		account.NotifiedAt = nil
+	}
+	return nil
+}
+
+func (m *mockAccountActivityRepository) list(limit int, match func(*domain.DormantAccount) bool) []*domain.DormantAccount {
+	var accounts []*domain.DormantAccount
+	for _, account := range m.accounts {
+		if match(account) {
+			copied := *account
+			accounts = append(accounts, &copied)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].LastActiveAt.Before(accounts[j].LastActiveAt) })
+	if len(accounts) > limit {
+		accounts = accounts[:limit]
+	}
+	return accounts
+}
+
+func (m *mockAccountActivityRepository) ListInactive(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	return m.list(limit, func(a *domain.DormantAccount) bool {
+		return a.DisabledAt == nil && a.NotifiedAt == nil && a.LastActiveAt.Before(before)
+	}), nil
+}
+
+func (m *mockAccountActivityRepository) ListNotified(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	return m.list(limit, func(a *domain.DormantAccount) bool {
+		return a.DisabledAt == nil && a.NotifiedAt != nil && a.NotifiedAt.Before(before)
+	}), nil
+}
+
+func (m *mockAccountActivityRepository) ListDisabled(ctx context.Context, before time.Time, limit int) ([]*domain.DormantAccount, error) {
+	return m.list(limit, func(a *domain.DormantAccount) bool {
+		return a.DisabledAt != nil && a.DisabledAt.Before(before)
+	}), nil
+}
+
+func (m *mockAccountActivityRepository) MarkNotified(ctx context.Context, userID string, at time.Time) error {
+	m.accounts[userID].NotifiedAt = &at
+	return nil
+}
+
+func (m *mockAccountActivityRepository) Disable(ctx context.Context, userID string, at time.Time) error {
+	m.accounts[userID].DisabledAt = &at
+	return nil
+}
+
+type mockDormancyNotifier struct {
+	notified map[string]time.Time
+	err      error
+}
+
+func (m *mockDormancyNotifier) NotifyDormant(ctx context.Context, account *domain.DormantAccount, disableAt time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.notified[account.UserID] = disableAt
+	return nil
+}
+
+type dormancyTestEnv struct {
+	service  *DormancyService
+	activity *mockAccountActivityRepository
+	users    *mockUserRepository
+	tokens   *mockRefreshTokenRepository
+	audit    *mockAuditLogRepository
+	notifier *mockDormancyNotifier
+	now      time.Time
+}
+
+func newDormancyTestEnv(t *testing.T, policy DormancyPolicy) *dormancyTestEnv {
+	t.Helper()
+
+	env := &dormancyTestEnv{
+		activity: newMockAccountActivityRepository(),
+		users:    newMockUserRepository(),
+		tokens:   newMockRefreshTokenRepository(),
+		audit:    &mockAuditLogRepository{},
+		notifier: &mockDormancyNotifier{notified: make(map[string]time.Time)},
+		now:      time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	env.service = NewDormancyService(env.activity, env.users, env.tokens, env.notifier, policy)
+	env.service.SetAuditLog(env.audit)
+	env.service.now = func() time.Time { return env.now }
+	return env
+}
+
+// addAccount creates a user last active at the given time
+func (env *dormancyTestEnv) addAccount(t *testing.T, email string, lastActive time.Time) string {
+	t.Helper()
+
+	user := &domain.User{Email: email}
+	if err := env.users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	env.activity.accounts[user.ID] = &domain.DormantAccount{UserID: user.ID, Email: email, LastActiveAt: lastActive}
+	if err := env.tokens.Create(context.Background(), &domain.RefreshToken{UserID: user.ID, ExpiresAt: env.now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return user.ID
+}
+
+func testDormancyPolicy() DormancyPolicy {
+	return DormancyPolicy{
+		NotifyAfter:  180 * 24 * time.Hour,
+		DisableAfter: 210 * 24 * time.Hour,
+		PurgeAfter:   300 * 24 * time.Hour,
+		BatchSize:    100,
+	}
+}
+
+func TestDormancyPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *DormancyPolicy)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(p *DormancyPolicy) {}},
+		{name: "keep disabled accounts", modify: func(p *DormancyPolicy) { p.PurgeAfter = 0 }},
+		{name: "no notify period", modify: func(p *DormancyPolicy) { p.NotifyAfter = 0 }, wantErr: true},
+		{name: "disable before notify", modify: func(p *DormancyPolicy) { p.DisableAfter = p.NotifyAfter }, wantErr: true},
+		{name: "purge before disable", modify: func(p *DormancyPolicy) { p.PurgeAfter = p.DisableAfter }, wantErr: true},
+		{name: "no batch size", modify: func(p *DormancyPolicy) { p.BatchSize = 0 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := testDormancyPolicy()
+			tt.modify(&policy)
+			if err := policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDormancyService_Run(t *testing.T) {
+	ctx := context.Background()
+	env := newDormancyTestEnv(t, testDormancyPolicy())
+
+	active := env.addAccount(t, "active@example.com", env.now.Add(-24*time.Hour))
+	dormant := env.addAccount(t, "dormant@example.com", env.now.Add(-200*24*time.Hour))
+
+	// A dry run reports the notice without sending it
+	report, err := env.service.Run(ctx, true)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !report.DryRun || len(report.Notified) != 1 || report.Notified[0].UserID != dormant {
+		t.Fatalf("dry run report = %+v, want one notified account", report)
+	}
+	if len(env.notifier.notified) != 0 || env.activity.accounts[dormant].NotifiedAt != nil {
+		t.Fatal("dry run must not notify accounts")
+	}
+
+	// The notice gives the full warning period, not what is left of it
+	if _, err := env.service.Run(ctx, false); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, want := env.notifier.notified[dormant], env.now.Add(30*24*time.Hour); !got.Equal(want) {
+		t.Errorf("disable date = %v, want %v", got, want)
+	}
+	if _, notified := env.notifier.notified[active]; notified {
+		t.Error("active account must not be notified")
+	}
+
+	// Not disabled before the warning period ends
+	env.now = env.now.Add(29 * 24 * time.Hour)
+	report, _ = env.service.Run(ctx, false)
+	if len(report.Disabled) != 0 {
+		t.Fatalf("disabled %d accounts before the warning period ended", len(report.Disabled))
+	}
+
+	env.now = env.now.Add(2 * 24 * time.Hour)
+	report, _ = env.service.Run(ctx, false)
+	if len(report.Disabled) != 1 || report.Disabled[0].UserID != dormant {
+		t.Fatalf("report = %+v, want dormant account disabled", report)
+	}
+	if env.activity.accounts[dormant].DisabledAt == nil {
+		t.Error("account should be disabled")
+	}
+	for _, token := range env.tokens.tokens {
+		if token.UserID == dormant && !token.Revoked {
+			t.Error("disabled account's refresh tokens should be revoked")
+		}
+	}
+
+	// Purged once it stayed disabled for the retention period
+	env.now = env.now.Add(91 * 24 * time.Hour)
+	report, _ = env.service.Run(ctx, false)
+	if len(report.Purged) != 1 || report.Purged[0].UserID != dormant {
+		t.Fatalf("report = %+v, want dormant account purged", report)
+	}
+	if _, err := env.users.GetByID(ctx, dormant); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Error("purged account should be deleted")
+	}
+
+	var actions []string
+	for _, entry := range env.audit.logs {
+		actions = append(actions, entry.Action)
+	}
+	if len(actions) != 2 || actions[0] != domain.AuditActionDormancyDisable || actions[1] != domain.AuditActionDormancyPurge {
+		t.Errorf("audit actions = %v", actions)
+	}
+
+	// Reports are kept newest first
+	reports := env.service.Reports()
+	if len(reports) != 5 || reports[0].ID != report.ID {
+		t.Errorf("Reports() returned %d reports", len(reports))
+	}
+	if got, ok := env.service.Report(report.ID); !ok || got != report {
+		t.Error("Report() should find the latest report")
+	}
+}
+
+func TestDormancyService_KeepsDisabledAccounts(t *testing.T) {
+	policy := testDormancyPolicy()
+	policy.PurgeAfter = 0
+	env := newDormancyTestEnv(t, policy)
+
+	disabledAt := env.now.Add(-1000 * 24 * time.Hour)
+	userID := env.addAccount(t, "kept@example.com", disabledAt)
+	env.activity.accounts[userID].DisabledAt = &disabledAt
+
+	report, err := env.service.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Purged) != 0 {
+		t.Error("accounts must not be purged without a retention period")
+	}
+}
+
+func TestDormancyService_NotifierFailure(t *testing.T) {
+	env := newDormancyTestEnv(t, testDormancyPolicy())
+	env.notifier.err = errors.New("smtp unavailable")
+	userID := env.addAccount(t, "dormant@example.com", env.now.Add(-200*24*time.Hour))
+
+	report, err := env.service.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Notified) != 0 || len(report.Failures) != 1 {
+		t.Fatalf("report = %+v, want one failure", report)
+	}
+	if f := report.Failures[0]; f.UserID != userID || f.Stage != DormancyStageNotify {
+		t.Errorf("failure = %+v", f)
+	}
+	// Left unmarked so the next run retries
+	if env.activity.accounts[userID].NotifiedAt != nil {
+		t.Error("account must not be marked notified when the notice failed")
+	}
+}
+
+func TestDormancyService_RunInProgress(t *testing.T) {
+	env := newDormancyTestEnv(t, testDormancyPolicy())
+
+	env.service.runMu.Lock()
+	defer env.service.runMu.Unlock()
+
+	if _, err := env.service.Run(context.Background(), true); !errors.Is(err, domain.ErrDormancyRunInProgress) {
+		t.Errorf("Run() error = %v, want ErrDormancyRunInProgress", err)
+	}
+}
+
+func TestAuthService_DisabledAccount(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	activity := newMockAccountActivityRepository()
+	service.SetActivityRecorder(activity)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "disabled@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	output, err := service.Login(ctx, LoginInput{Email: "disabled@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	user := userRepo.users["disabled@example.com"]
+	if _, ok := activity.recorded[user.ID]; !ok {
+		t.Error("login should record activity")
+	}
+
+	now := time.Now()
+	user.DisabledAt = &now
+
+	if _, err := service.Login(ctx, LoginInput{Email: "disabled@example.com", Password: "password123"}); !errors.Is(err, domain.ErrAccountDisabled) {
+		t.Errorf("Login() error = %v, want ErrAccountDisabled", err)
+	}
+	if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: output.RefreshToken}); !errors.Is(err, domain.ErrAccountDisabled) {
+		t.Errorf("Refresh() error = %v, want ErrAccountDisabled", err)
+	}
+}
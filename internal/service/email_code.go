@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/emailtrack"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// RevokeReasonPasswordReset is recorded when a password reset signs the user
+// out everywhere
+const RevokeReasonPasswordReset = "password_reset"
+
+// CodePolicy configures one-time codes sent by email or SMS
+type CodePolicy struct {
+	TTL         time.Duration // how long a code can be used
+	MaxAttempts int           // wrong guesses before a new code has to be requested
+}
+
+// SetEmailCodes issues 6-digit codes next to email verification links and
+// enables password reset by code, for clients that cannot open links. Codes
+// are stored hashed like passwords.
+func (s *AuthService) SetEmailCodes(codes repository.EmailCodeRepository, policy CodePolicy) {
+	s.emailCodes = codes
+	s.emailCodePolicy = policy
+}
+
+// EmailCodesEnabled reports whether email codes are issued
+func (s *AuthService) EmailCodesEnabled() bool {
+	return s.emailCodes != nil
+}
+
+// EmailCodeTTL returns how long issued codes can be used
+func (s *AuthService) EmailCodeTTL() time.Duration {
+	return s.emailCodePolicy.TTL
+}
+
+// issueCode stores a new code for the user, replacing the previous one for
+// the purpose, and returns it in clear for sending
+func (s *AuthService) issueCode(ctx context.Context, codes repository.EmailCodeRepository, policy CodePolicy, userID string, purpose domain.EmailCodePurpose) (string, error) {
+	code, err := domain.GenerateEmailCode()
+	if err != nil {
+		return "", err
+	}
+	codeHash, err := s.passwordHasher.HashContext(ctx, code)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash code: %w", err)
+	}
+
+	now := s.clock.Now()
+	if err := codes.Save(ctx, &domain.EmailCode{
+		UserID:    userID,
+		Purpose:   purpose,
+		CodeHash:  codeHash,
+		ExpiresAt: now.Add(policy.TTL),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// checkCode spends one attempt on the user's code and deletes the code once
+// it matched. The attempt is counted before comparing, so concurrent guesses
+// cannot exceed the limit.
+func (s *AuthService) checkCode(ctx context.Context, codes repository.EmailCodeRepository, policy CodePolicy, userID string, purpose domain.EmailCodePurpose, code string) error {
+	stored, err := codes.RecordAttempt(ctx, userID, purpose)
+	if err != nil {
+		return err
+	}
+	if stored.IsExpired(s.clock.Now()) {
+		return domain.ErrInvalidEmailCode
+	}
+	if stored.Attempts > policy.MaxAttempts {
+		return domain.ErrTooManyCodeAttempts
+	}
+	if err := s.passwordHasher.CompareContext(ctx, code, stored.CodeHash); err != nil {
+		if isCheckAborted(err) {
+			return err
+		}
+		return domain.ErrInvalidEmailCode
+	}
+
+	return codes.Delete(ctx, userID, purpose)
+}
+
+// VerifyEmailCodeInput represents the input for verifying an email by code
+type VerifyEmailCodeInput struct {
+	Email string
+	Code  string
+}
+
+// VerifyEmailCode verifies a user's email address with the code sent in the
+// verification email
+func (s *AuthService) VerifyEmailCode(ctx context.Context, input VerifyEmailCodeInput) error {
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(input.Email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrInvalidEmailCode
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Check if already verified
+	if user.EmailVerified {
+		return nil
+	}
+
+	if err := s.checkCode(ctx, s.emailCodes, s.emailCodePolicy, user.ID, domain.EmailCodeVerification, input.Code); err != nil {
+		return err
+	}
+
+	user.MarkEmailVerified()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	s.trackEmailEvent(ctx, user.ID, "verification", emailtrack.EventVerified)
+	s.emitUserEvent(ctx, user, domain.UserEventEmailVerified, map[string]interface{}{"method": domain.EmailVerificationCode})
+	return s.RevokeSessions(ctx, user.ID, RevokeReasonEmailVerified)
+}
+
+// PasswordResetOutput represents the output for requesting a password reset
+type PasswordResetOutput struct {
+	Code string
+	// Suppressed is set when no email must be sent because the address is
+	// unknown or the account is disabled
+	Suppressed bool
+
+	// locale of the user, used to localize the reset email
+	locale string
+}
+
+// RequestPasswordReset issues a password reset code. Unknown addresses are
+// not reported, so the endpoint cannot be used to find accounts.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) (*PasswordResetOutput, error) {
+	defer s.padResponse(ctx, s.clock.Now())
+
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			slog.InfoContext(ctx, "password reset suppressed", "reason", "unknown_email")
+			return &PasswordResetOutput{Suppressed: true}, nil
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.DisabledAt != nil {
+		slog.InfoContext(ctx, "password reset suppressed", "reason", "account_disabled", "user_id", user.ID)
+		return &PasswordResetOutput{Suppressed: true}, nil
+	}
+
+	code, err := s.issueCode(ctx, s.emailCodes, s.emailCodePolicy, user.ID, domain.EmailCodePasswordReset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasswordResetOutput{
+		Code:   code,
+		locale: stringValue(user.Locale),
+	}, nil
+}
+
+// ResetPasswordInput represents the input for resetting a password by code
+type ResetPasswordInput struct {
+	Email       string
+	Code        string
+	NewPassword string
+}
+
+// ResetPassword replaces the user's password after checking the reset code
+// and signs the user out everywhere
+func (s *AuthService) ResetPassword(ctx context.Context, input ResetPasswordInput) error {
+	// Reject weak passwords before spending an attempt on the code
+	if err := domain.ValidatePassword(input.NewPassword); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(input.Email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrInvalidEmailCode
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.checkCode(ctx, s.emailCodes, s.emailCodePolicy, user.ID, domain.EmailCodePasswordReset, input.Code); err != nil {
+		return err
+	}
+
+	return s.resetPassword(ctx, user, input.NewPassword)
+}
+
+// resetPassword replaces the password of a user who proved access to a
+// recovery channel and signs the user out everywhere
+func (s *AuthService) resetPassword(ctx context.Context, user *domain.User, newPassword string) error {
+	passwordHash, err := s.passwordHasher.HashContext(ctx, newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	return s.replacePasswordHash(ctx, user, passwordHash)
+}
+
+// replacePasswordHash stores the user's new password hash and signs the user
+// out everywhere
+func (s *AuthService) replacePasswordHash(ctx context.Context, user *domain.User, passwordHash string) error {
+	user.PasswordHash = passwordHash
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	return s.RevokeSessions(ctx, user.ID, RevokeReasonPasswordReset)
+}
+
+// invalidatePasswordResets deletes the user's pending password reset and SMS
+// recovery codes after a password login. The user knows the password, so a
+// code intercepted in the meantime must not be usable to take over the
+// account. It reports whether a code was pending; failures are logged rather
+// than failing the login.
+func (s *AuthService) invalidatePasswordResets(ctx context.Context, userID string) bool {
+	now := s.clock.Now()
+	pending := false
+	for _, recovery := range []struct {
+		codes   repository.EmailCodeRepository
+		purpose domain.EmailCodePurpose
+	}{
+		{s.emailCodes, domain.EmailCodePasswordReset},
+		{s.smsCodes, domain.SMSCodeRecovery},
+	} {
+		if recovery.codes == nil {
+			continue
+		}
+		deleted, err := recovery.codes.DeleteUnexpired(ctx, userID, recovery.purpose, now)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to invalidate password reset", "user_id", userID, "purpose", recovery.purpose, "error", err)
+			continue
+		}
+		if deleted {
+			slog.InfoContext(ctx, "password reset invalidated by login", "user_id", userID, "purpose", recovery.purpose)
+			pending = true
+		}
+	}
+	return pending
+}
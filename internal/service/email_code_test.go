@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockEmailCodeRepository keeps email codes in memory
+type mockEmailCodeRepository struct {
+	codes map[string]*domain.EmailCode
+}
+
+func newMockEmailCodeRepository() *mockEmailCodeRepository {
+	return &mockEmailCodeRepository{codes: make(map[string]*domain.EmailCode)}
+}
+
+func (m *mockEmailCodeRepository) key(userID string, purpose domain.EmailCodePurpose) string {
+	return userID + "/" + string(purpose)
+}
+
+func (m *mockEmailCodeRepository) Save(ctx context.Context, code *domain.EmailCode) error {
+	stored := *code
+	m.codes[m.key(code.UserID, code.Purpose)] = &stored
+	return nil
+}
+
+func (m *mockEmailCodeRepository) RecordAttempt(ctx context.Context, userID string, purpose domain.EmailCodePurpose) (*domain.EmailCode, error) {
+	code, ok := m.codes[m.key(userID, purpose)]
+	if !ok {
+		return nil, domain.ErrInvalidEmailCode
+	}
+	code.Attempts++
+	stored := *code
+	return &stored, nil
+}
+
+func (m *mockEmailCodeRepository) Delete(ctx context.Context, userID string, purpose domain.EmailCodePurpose) error {
+	delete(m.codes, m.key(userID, purpose))
+	return nil
+}
+
+func (m *mockEmailCodeRepository) DeleteUnexpired(ctx context.Context, userID string, purpose domain.EmailCodePurpose, now time.Time) (bool, error) {
+	code, ok := m.codes[m.key(userID, purpose)]
+	if !ok || code.IsExpired(now) {
+		return false, nil
+	}
+	delete(m.codes, m.key(userID, purpose))
+	return true, nil
+}
+
+func createTestAuthServiceWithCodes(t *testing.T) (*AuthService, *mockUserRepository, *mockEmailCodeRepository) {
+	service, userRepo, _ := createTestAuthService(t)
+	codes := newMockEmailCodeRepository()
+	service.SetEmailCodes(codes, CodePolicy{TTL: 15 * time.Minute, MaxAttempts: 3})
+	return service, userRepo, codes
+}
+
+func TestAuthService_VerifyEmailCode(t *testing.T) {
+	service, userRepo, codes := createTestAuthServiceWithCodes(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "code@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if len(signup.EmailVerificationCode) != domain.EmailCodeDigits {
+		t.Fatalf("verification code = %q, want %d digits", signup.EmailVerificationCode, domain.EmailCodeDigits)
+	}
+
+	err = service.VerifyEmailCode(ctx, VerifyEmailCodeInput{Email: "code@example.com", Code: "not-it"})
+	if !errors.Is(err, domain.ErrInvalidEmailCode) {
+		t.Fatalf("VerifyEmailCode() with wrong code error = %v, want %v", err, domain.ErrInvalidEmailCode)
+	}
+
+	err = service.VerifyEmailCode(ctx, VerifyEmailCodeInput{Email: "code@example.com", Code: signup.EmailVerificationCode})
+	if err != nil {
+		t.Fatalf("VerifyEmailCode() error = %v", err)
+	}
+	if !userRepo.users["code@example.com"].EmailVerified {
+		t.Error("email was not verified")
+	}
+	if len(codes.codes) != 0 {
+		t.Error("used code was not deleted")
+	}
+
+	err = service.VerifyEmailCode(ctx, VerifyEmailCodeInput{Email: "unknown@example.com", Code: "123456"})
+	if !errors.Is(err, domain.ErrInvalidEmailCode) {
+		t.Errorf("VerifyEmailCode() for unknown email error = %v, want %v", err, domain.ErrInvalidEmailCode)
+	}
+}
+
+func TestAuthService_EmailCodeLimits(t *testing.T) {
+	service, _, codes := createTestAuthServiceWithCodes(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "limits@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	input := VerifyEmailCodeInput{Email: "limits@example.com", Code: signup.EmailVerificationCode}
+
+	t.Run("expired code", func(t *testing.T) {
+		codes.codes[codes.key(signup.UserID, domain.EmailCodeVerification)].ExpiresAt = time.Now().Add(-time.Second)
+		if err := service.VerifyEmailCode(ctx, input); !errors.Is(err, domain.ErrInvalidEmailCode) {
+			t.Errorf("VerifyEmailCode() error = %v, want %v", err, domain.ErrInvalidEmailCode)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		resend, err := service.ResendVerificationEmail(ctx, "limits@example.com")
+		if err != nil {
+			t.Fatalf("ResendVerificationEmail() error = %v", err)
+		}
+		input.Code = resend.EmailVerificationCode
+
+		for i := 0; i < 3; i++ {
+			_ = service.VerifyEmailCode(ctx, VerifyEmailCodeInput{Email: input.Email, Code: "wrong"})
+		}
+		// Even the right code is refused once the attempts are spent
+		if err := service.VerifyEmailCode(ctx, input); !errors.Is(err, domain.ErrTooManyCodeAttempts) {
+			t.Errorf("VerifyEmailCode() error = %v, want %v", err, domain.ErrTooManyCodeAttempts)
+		}
+	})
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	service, _, _ := createTestAuthServiceWithCodes(t)
+	versions := &mockTokenVersionRepository{versions: make(map[string]int)}
+	service.SetTokenVersions(versions)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "reset@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	unknown, err := service.RequestPasswordReset(ctx, "unknown@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset() for unknown email error = %v", err)
+	}
+	if !unknown.Suppressed || unknown.Code != "" {
+		t.Errorf("RequestPasswordReset() for unknown email = %+v, want suppressed", unknown)
+	}
+
+	reset, err := service.RequestPasswordReset(ctx, "reset@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		input   ResetPasswordInput
+		wantErr error
+	}{
+		{
+			name:    "weak new password",
+			input:   ResetPasswordInput{Email: "reset@example.com", Code: reset.Code, NewPassword: "short"},
+			wantErr: domain.ErrWeakPassword,
+		},
+		{
+			name:    "wrong code",
+			input:   ResetPasswordInput{Email: "reset@example.com", Code: "wrong", NewPassword: "new-password123"},
+			wantErr: domain.ErrInvalidEmailCode,
+		},
+		{
+			name:  "valid reset",
+			input: ResetPasswordInput{Email: "reset@example.com", Code: reset.Code, NewPassword: "new-password123"},
+		},
+		{
+			name:    "code reused",
+			input:   ResetPasswordInput{Email: "reset@example.com", Code: reset.Code, NewPassword: "other-password123"},
+			wantErr: domain.ErrInvalidEmailCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.ResetPassword(ctx, tt.input); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResetPassword() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if versions.versions[signup.UserID] != 1 {
+		t.Errorf("token version = %d, want 1", versions.versions[signup.UserID])
+	}
+	if _, err := service.Login(ctx, LoginInput{Email: "reset@example.com", Password: "new-password123"}); err != nil {
+		t.Errorf("Login() with new password error = %v", err)
+	}
+}
+
+func TestAuthService_LoginInvalidatesPasswordReset(t *testing.T) {
+	service, _, codes := createTestAuthServiceWithCodes(t)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "pending@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	login := LoginInput{Email: "pending@example.com", Password: "password123"}
+
+	output, err := service.Login(ctx, login)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if output.resetInvalidated {
+		t.Error("Login() without a pending reset reported one")
+	}
+
+	reset, err := service.RequestPasswordReset(ctx, "pending@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	output, err = service.Login(ctx, login)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if !output.resetInvalidated {
+		t.Error("Login() did not report the cancelled reset")
+	}
+
+	// The intercepted code no longer resets the password
+	err = service.ResetPassword(ctx, ResetPasswordInput{Email: "pending@example.com", Code: reset.Code, NewPassword: "attacker-password123"})
+	if !errors.Is(err, domain.ErrInvalidEmailCode) {
+		t.Errorf("ResetPassword() after login error = %v, want %v", err, domain.ErrInvalidEmailCode)
+	}
+
+	// Expired codes are not worth an email
+	reset, err = service.RequestPasswordReset(ctx, "pending@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	for _, code := range codes.codes {
+		code.ExpiresAt = time.Now().Add(-time.Second)
+	}
+	if output, err = service.Login(ctx, login); err != nil || output.resetInvalidated {
+		t.Errorf("Login() with an expired reset = %v, %v, want none cancelled", output, err)
+	}
+}
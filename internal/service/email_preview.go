@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	emailpkg "github.com/n1rocket/go-auth-jwt/internal/email"
+)
+
+// EmailTemplateInfo describes a template operators can preview
+type EmailTemplateInfo struct {
+	Name    string
+	Locales []string
+}
+
+// EmailTemplates lists the email templates by name
+func (s *AuthServiceWithEmail) EmailTemplates() []EmailTemplateInfo {
+	catalog := emailpkg.Catalog()
+	templates := make([]EmailTemplateInfo, 0, len(catalog))
+	for name, template := range catalog {
+		templates = append(templates, EmailTemplateInfo{Name: name, Locales: template.Locales()})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// EmailPreview is a template rendered with sample data
+type EmailPreview struct {
+	emailpkg.Email
+	Locale string // translation used; DefaultLocale when the locale has none
+}
+
+// PreviewEmail renders a template with sample data in the given locale,
+// addressed to recipient. Nothing is sent.
+func (s *AuthServiceWithEmail) PreviewEmail(name, locale, recipient string) (*EmailPreview, error) {
+	template, ok := emailpkg.Catalog()[name]
+	if !ok {
+		return nil, domain.ErrEmailTemplateNotFound
+	}
+
+	data := emailpkg.SampleData(emailpkg.TemplateData{
+		BaseURL:        s.config.App.BaseURL,
+		AppName:        s.config.App.Name,
+		SupportEmail:   s.config.Email.SupportEmail,
+		RecipientEmail: recipient,
+	})
+	email, err := emailpkg.RenderTemplate(template.ForLocale(locale), data)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailPreview{Email: email, Locale: template.MatchLocale(locale)}, nil
+}
+
+// SendTestEmail queues a template rendered with sample data to an
+// operator's address. The subject is marked as a test, since the links in
+// it don't work.
+func (s *AuthServiceWithEmail) SendTestEmail(ctx context.Context, name, locale, to string) error {
+	if err := domain.ValidateEmail(to); err != nil {
+		return err
+	}
+
+	preview, err := s.PreviewEmail(name, locale, to)
+	if err != nil {
+		return err
+	}
+	email := preview.Email
+	email.Subject = "[Test] " + email.Subject
+
+	if err := s.emailDispatcher.EnqueueWithContext(ctx, email); err != nil {
+		return err
+	}
+	s.logger.Info("test email queued", "template", name, "locale", locale, "email", to)
+	return nil
+}
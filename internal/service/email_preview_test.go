@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/email"
+)
+
+func TestAuthServiceWithEmail_PreviewEmail(t *testing.T) {
+	service := createTestAuthServiceWithEmail(nil, nil, nil)
+
+	preview, err := service.PreviewEmail("verification", "es-MX", "jane@example.com")
+	if err != nil {
+		t.Fatalf("PreviewEmail() error = %v", err)
+	}
+	if preview.Locale != "es" || !strings.Contains(preview.Subject, "Verifica") {
+		t.Errorf("PreviewEmail() = %q in %q, want the Spanish translation", preview.Subject, preview.Locale)
+	}
+	if preview.To != "jane@example.com" || !strings.Contains(preview.Body, "http://localhost:8080/verify-email?token=sample-verification-token") {
+		t.Errorf("PreviewEmail() should render sample data, got %+v", preview.Email)
+	}
+
+	preview, err = service.PreviewEmail("password_reset", "fr", "jane@example.com")
+	if err != nil {
+		t.Fatalf("PreviewEmail() error = %v", err)
+	}
+	if preview.Locale != email.DefaultLocale {
+		t.Errorf("PreviewEmail() locale = %q, want the English fallback", preview.Locale)
+	}
+
+	if _, err := service.PreviewEmail("welcome", "", "jane@example.com"); !errors.Is(err, domain.ErrEmailTemplateNotFound) {
+		t.Errorf("PreviewEmail() of an unknown template error = %v, want ErrEmailTemplateNotFound", err)
+	}
+}
+
+func TestAuthServiceWithEmail_SendTestEmail(t *testing.T) {
+	sent := make(chan email.Email, 1)
+	service := createTestAuthServiceWithEmail(nil, nil, &mockEmailService{
+		sendFunc: func(ctx context.Context, e email.Email) error {
+			sent <- e
+			return nil
+		},
+	})
+	service.emailDispatcher.Start()
+	defer service.emailDispatcher.Stop(time.Second)
+
+	if err := service.SendTestEmail(context.Background(), "login_notification", "", "not-an-email"); !errors.Is(err, domain.ErrInvalidEmail) {
+		t.Errorf("SendTestEmail() to an invalid address error = %v, want ErrInvalidEmail", err)
+	}
+	if err := service.SendTestEmail(context.Background(), "login_notification", "", "ops@example.com"); err != nil {
+		t.Fatalf("SendTestEmail() error = %v", err)
+	}
+
+	select {
+	case e := <-sent:
+		if e.To != "ops@example.com" || e.Subject != "[Test] New login to your account" {
+			t.Errorf("sent %q to %q", e.Subject, e.To)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("test email was not sent")
+	}
+}
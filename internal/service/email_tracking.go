@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/n1rocket/go-auth-jwt/internal/emailtrack"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// SetEmailTracking tracks clicks, and opens when the tracker's pixel is on,
+// of verification and login emails. Users who opted out in optOuts get
+// untracked emails and are left out of the conversion counts.
+func (s *AuthService) SetEmailTracking(tracker *emailtrack.Tracker, optOuts repository.EmailTrackingOptOutRepository) {
+	s.emailTracker = tracker
+	s.trackingOptOuts = optOuts
+}
+
+// EmailTrackingEnabled reports whether emails are tracked
+func (s *AuthService) EmailTrackingEnabled() bool {
+	return s.emailTracker != nil
+}
+
+// EmailTracker returns the tracker of emails, or nil when they are not
+// tracked
+func (s *AuthService) EmailTracker() *emailtrack.Tracker {
+	return s.emailTracker
+}
+
+// EmailTrackingOptedOut reports whether the user asked not to be tracked
+func (s *AuthService) EmailTrackingOptedOut(ctx context.Context, userID string) (bool, error) {
+	return s.trackingOptOuts.IsOptedOut(ctx, userID)
+}
+
+// SetEmailTrackingOptOut records whether the user asked not to be tracked
+func (s *AuthService) SetEmailTrackingOptOut(ctx context.Context, userID string, optedOut bool) error {
+	return s.trackingOptOuts.SetOptedOut(ctx, userID, optedOut)
+}
+
+// emailTracked reports whether emails to the user are tracked. A failed
+// lookup counts as an opt-out, so the preference is never overridden.
+func (s *AuthService) emailTracked(ctx context.Context, userID string) bool {
+	if !s.EmailTrackingEnabled() {
+		return false
+	}
+	optedOut, err := s.trackingOptOuts.IsOptedOut(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to check email tracking opt-out", "user_id", userID, "error", err)
+		return false
+	}
+	return !optedOut
+}
+
+// trackEmailEvent counts an event of an email template for a tracked user
+func (s *AuthService) trackEmailEvent(ctx context.Context, userID, template, event string) {
+	if s.emailTracked(ctx, userID) {
+		s.emailTracker.Record(template, event)
+	}
+}
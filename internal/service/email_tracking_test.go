@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/emailtrack"
+)
+
+type mockEmailTrackingOptOuts struct {
+	optedOut map[string]bool
+}
+
+func (m *mockEmailTrackingOptOuts) IsOptedOut(ctx context.Context, userID string) (bool, error) {
+	return m.optedOut[userID], nil
+}
+
+func (m *mockEmailTrackingOptOuts) SetOptedOut(ctx context.Context, userID string, optedOut bool) error {
+	m.optedOut[userID] = optedOut
+	return nil
+}
+
+type mockTrackingRecorder struct {
+	events []string
+}
+
+func (m *mockTrackingRecorder) RecordEmailTrackingEvent(email, event string) {
+	m.events = append(m.events, email+"/"+event)
+}
+
+func TestAuthService_EmailTrackingVerified(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	recorder := &mockTrackingRecorder{}
+	tracker := emailtrack.New("0123456789abcdef0123456789abcdef", "https://auth.example.com")
+	tracker.SetRecorder(recorder)
+	optOuts := &mockEmailTrackingOptOuts{optedOut: make(map[string]bool)}
+	service.SetEmailTracking(tracker, optOuts)
+	ctx := context.Background()
+
+	tracked, err := service.Signup(ctx, SignupInput{Email: "tracked@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	untracked, err := service.Signup(ctx, SignupInput{Email: "untracked@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if err := service.SetEmailTrackingOptOut(ctx, untracked.UserID, true); err != nil {
+		t.Fatalf("SetEmailTrackingOptOut() error = %v", err)
+	}
+	if optedOut, _ := service.EmailTrackingOptedOut(ctx, untracked.UserID); !optedOut {
+		t.Error("EmailTrackingOptedOut() = false after opting out")
+	}
+
+	for email, signup := range map[string]*SignupOutput{"tracked@example.com": tracked, "untracked@example.com": untracked} {
+		if err := service.VerifyEmail(ctx, VerifyEmailInput{Email: email, Token: signup.EmailVerificationToken}); err != nil {
+			t.Fatalf("VerifyEmail(%s) error = %v", email, err)
+		}
+	}
+
+	if len(recorder.events) != 1 || recorder.events[0] != "verification/verified" {
+		t.Errorf("events = %v, want one verification of the tracked user", recorder.events)
+	}
+}
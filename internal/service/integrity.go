@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// Integrity checks, by the name used in reports and fix lists
+const (
+	IntegrityCheckOrphanedRefreshTokens = "orphaned_refresh_tokens"
+	IntegrityCheckExpiredVerifications  = "expired_verifications"
+	IntegrityCheckAuditGaps             = "audit_gaps"
+	IntegrityCheckDirtyMigrations       = "dirty_migrations"
+)
+
+// maxAuditGaps is how many audit chain gaps a report lists
+const maxAuditGaps = 100
+
+// fixableIntegrityChecks are the checks whose issues can be fixed
+// automatically. Missing audit entries cannot be restored, and a dirty
+// migration has to be finished by hand before it is forced.
+var fixableIntegrityChecks = []string{
+	IntegrityCheckOrphanedRefreshTokens,
+	IntegrityCheckExpiredVerifications,
+}
+
+// ParseIntegrityFixes parses a comma-separated list of checks to fix
+// automatically. "all" selects every fixable check.
+func ParseIntegrityFixes(list string) (map[string]bool, error) {
+	fixes := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+		case "all":
+			for _, check := range fixableIntegrityChecks {
+				fixes[check] = true
+			}
+		case IntegrityCheckOrphanedRefreshTokens, IntegrityCheckExpiredVerifications:
+			fixes[name] = true
+		case IntegrityCheckAuditGaps, IntegrityCheckDirtyMigrations:
+			return nil, fmt.Errorf("%s cannot be fixed automatically", name)
+		default:
+			return nil, fmt.Errorf("unknown integrity check %q", name)
+		}
+	}
+	return fixes, nil
+}
+
+// IntegrityFinding is the outcome of one check
+type IntegrityFinding struct {
+	Check  string `json:"check"`
+	Issues int    `json:"issues"`
+	Fixed  int    `json:"fixed"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// IntegrityReport describes an integrity check run, one finding per check
+type IntegrityReport struct {
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Findings   []IntegrityFinding `json:"findings"`
+}
+
+// OK reports whether every check ran and no issue is left unfixed
+func (r *IntegrityReport) OK() bool {
+	for _, finding := range r.Findings {
+		if finding.Error != "" || finding.Issues > finding.Fixed {
+			return false
+		}
+	}
+	return true
+}
+
+// IntegrityChecker detects data the application should never leave behind:
+// sessions of deleted or disabled users, users stuck with an expired
+// verification link, entries missing from the audit hash chain and a
+// migration that failed halfway
+type IntegrityChecker struct {
+	repo repository.IntegrityRepository
+	now  func() time.Time
+}
+
+// NewIntegrityChecker creates an integrity checker
+func NewIntegrityChecker(repo repository.IntegrityRepository) *IntegrityChecker {
+	return &IntegrityChecker{repo: repo, now: time.Now}
+}
+
+// Check runs every check, fixing the issues of the checks in fix. A check
+// that fails is recorded in its finding and the others still run.
+func (c *IntegrityChecker) Check(ctx context.Context, fix map[string]bool) *IntegrityReport {
+	now := c.now()
+	report := &IntegrityReport{StartedAt: now}
+
+	report.Findings = append(report.Findings,
+		c.fixable(ctx, IntegrityCheckOrphanedRefreshTokens, fix, now,
+			c.repo.CountOrphanedRefreshTokens, c.repo.RevokeOrphanedRefreshTokens,
+			"active refresh tokens of deleted or disabled users"),
+		c.fixable(ctx, IntegrityCheckExpiredVerifications, fix, now,
+			c.repo.CountExpiredVerifications, c.repo.ClearExpiredVerifications,
+			"unverified users with an expired verification token"),
+		c.auditGaps(ctx),
+		c.migrations(ctx),
+	)
+
+	report.FinishedAt = c.now()
+	return report
+}
+
+// fixable runs a check that can be fixed. When fixing, the rows the fix
+// changed are the issues, so a row appearing between a count and the fix is
+// not reported as left over.
+func (c *IntegrityChecker) fixable(
+	ctx context.Context,
+	check string,
+	fix map[string]bool,
+	now time.Time,
+	count, repair func(context.Context, time.Time) (int, error),
+	detail string,
+) IntegrityFinding {
+	finding := IntegrityFinding{Check: check}
+	var err error
+	if fix[check] {
+		finding.Fixed, err = repair(ctx, now)
+		finding.Issues = finding.Fixed
+	} else {
+		finding.Issues, err = count(ctx, now)
+	}
+	if err != nil {
+		finding.Error = err.Error()
+	}
+	if finding.Issues > 0 {
+		finding.Detail = detail
+	}
+	return finding
+}
+
+// auditGaps lists the ranges missing from the audit hash chain. Run
+// migrate -command verify-audit-chain to check the hashes as well.
+func (c *IntegrityChecker) auditGaps(ctx context.Context) IntegrityFinding {
+	finding := IntegrityFinding{Check: IntegrityCheckAuditGaps}
+	gaps, err := c.repo.ListAuditGaps(ctx, maxAuditGaps)
+	if err != nil {
+		finding.Error = err.Error()
+		return finding
+	}
+
+	finding.Issues = len(gaps)
+	ranges := make([]string, 0, len(gaps))
+	for _, gap := range gaps {
+		if gap.From == gap.To {
+			ranges = append(ranges, fmt.Sprintf("%d", gap.From))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", gap.From, gap.To))
+		}
+	}
+	if len(ranges) > 0 {
+		finding.Detail = "missing audit entries " + strings.Join(ranges, ", ")
+		if len(gaps) == maxAuditGaps {
+			finding.Detail += " and possibly more"
+		}
+	}
+	return finding
+}
+
+// migrations reports a migration that failed halfway
+func (c *IntegrityChecker) migrations(ctx context.Context) IntegrityFinding {
+	finding := IntegrityFinding{Check: IntegrityCheckDirtyMigrations}
+	version, dirty, err := c.repo.MigrationState(ctx)
+	if err != nil {
+		finding.Error = err.Error()
+		return finding
+	}
+
+	if dirty {
+		finding.Issues = 1
+		finding.Detail = fmt.Sprintf(
+			"migration %d is dirty; finish or undo it by hand, then run migrate -command force -version <version>",
+			version)
+	}
+	return finding
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockIntegrityRepository counts issues in memory and clears them when fixed
+type mockIntegrityRepository struct {
+	orphanedTokens int
+	expired        int
+	gaps           []domain.AuditGap
+	version        int64
+	dirty          bool
+	gapsErr        error
+}
+
+func (m *mockIntegrityRepository) CountOrphanedRefreshTokens(ctx context.Context, now time.Time) (int, error) {
+	return m.orphanedTokens, nil
+}
+
+func (m *mockIntegrityRepository) RevokeOrphanedRefreshTokens(ctx context.Context, now time.Time) (int, error) {
+	n := m.orphanedTokens
+	m.orphanedTokens = 0
+	return n, nil
+}
+
+func (m *mockIntegrityRepository) CountExpiredVerifications(ctx context.Context, now time.Time) (int, error) {
+	return m.expired, nil
+}
+
+func (m *mockIntegrityRepository) ClearExpiredVerifications(ctx context.Context, now time.Time) (int, error) {
+	n := m.expired
+	m.expired = 0
+	return n, nil
+}
+
+func (m *mockIntegrityRepository) ListAuditGaps(ctx context.Context, limit int) ([]domain.AuditGap, error) {
+	return m.gaps, m.gapsErr
+}
+
+func (m *mockIntegrityRepository) MigrationState(ctx context.Context) (int64, bool, error) {
+	return m.version, m.dirty, nil
+}
+
+func TestParseIntegrityFixes(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{list: ""},
+		{list: "all", want: []string{IntegrityCheckOrphanedRefreshTokens, IntegrityCheckExpiredVerifications}},
+		{list: " expired_verifications ", want: []string{IntegrityCheckExpiredVerifications}},
+		{list: "audit_gaps", wantErr: true},
+		{list: "orphaned_refresh_tokens,users", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		fixes, err := ParseIntegrityFixes(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseIntegrityFixes(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if len(fixes) != len(tt.want) {
+			t.Errorf("ParseIntegrityFixes(%q) = %v, want %v", tt.list, fixes, tt.want)
+		}
+		for _, check := range tt.want {
+			if !fixes[check] {
+				t.Errorf("ParseIntegrityFixes(%q) = %v, want %s", tt.list, fixes, check)
+			}
+		}
+	}
+}
+
+func TestIntegrityChecker_Check(t *testing.T) {
+	repo := &mockIntegrityRepository{
+		orphanedTokens: 3,
+		expired:        2,
+		gaps:           []domain.AuditGap{{From: 4, To: 6}, {From: 9, To: 9}},
+		version:        24,
+		dirty:          true,
+	}
+	checker := NewIntegrityChecker(repo)
+
+	report := checker.Check(context.Background(), map[string]bool{IntegrityCheckExpiredVerifications: true})
+	if report.OK() {
+		t.Error("OK() = true with issues left")
+	}
+
+	findings := make(map[string]IntegrityFinding)
+	for _, finding := range report.Findings {
+		findings[finding.Check] = finding
+	}
+	if f := findings[IntegrityCheckOrphanedRefreshTokens]; f.Issues != 3 || f.Fixed != 0 {
+		t.Errorf("orphaned tokens = %+v, want 3 issues left", f)
+	}
+	if f := findings[IntegrityCheckExpiredVerifications]; f.Issues != 2 || f.Fixed != 2 || repo.expired != 0 {
+		t.Errorf("expired verifications = %+v, want 2 fixed", f)
+	}
+	if f := findings[IntegrityCheckAuditGaps]; f.Issues != 2 || !strings.Contains(f.Detail, "4-6, 9") {
+		t.Errorf("audit gaps = %+v, want 4-6 and 9", f)
+	}
+	if f := findings[IntegrityCheckDirtyMigrations]; f.Issues != 1 || !strings.Contains(f.Detail, "migration 24") {
+		t.Errorf("dirty migrations = %+v, want migration 24", f)
+	}
+
+	// Fixing the rest leaves only the issues that cannot be fixed
+	repo.gaps, repo.dirty = nil, false
+	report = checker.Check(context.Background(), map[string]bool{IntegrityCheckOrphanedRefreshTokens: true})
+	if !report.OK() {
+		t.Errorf("OK() = false after fixing, findings %+v", report.Findings)
+	}
+	if repo.orphanedTokens != 0 {
+		t.Error("orphaned tokens were not revoked")
+	}
+
+	// A failing check is reported and the others still run
+	repo.gapsErr = errors.New("connection reset")
+	report = checker.Check(context.Background(), nil)
+	if report.OK() || len(report.Findings) != 4 {
+		t.Errorf("Check() with a failing check = %+v", report.Findings)
+	}
+}
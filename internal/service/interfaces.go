@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
 )
@@ -16,4 +17,22 @@ type AuthServiceInterface interface {
 	VerifyEmail(ctx context.Context, input VerifyEmailInput) error
 	ResendVerificationEmail(ctx context.Context, email string) (*ResendVerificationEmailOutput, error)
 	GetUserByID(ctx context.Context, userID string) (*domain.User, error)
+	UsernameAvailable(ctx context.Context, username string) (bool, error)
+	UpdateProfile(ctx context.Context, userID string, update domain.ProfileUpdate) (*domain.User, error)
+	CreateAvatarUpload(ctx context.Context, userID string) (*AvatarUploadOutput, error)
+}
+
+// EmailPolicy decides whether an email address may be used to sign up
+type EmailPolicy interface {
+	Check(email string) error
+}
+
+// DeliverabilityChecker reports whether an email address can receive mail
+type DeliverabilityChecker interface {
+	Check(ctx context.Context, email string) error
+}
+
+// ActivityRecorder records when a user was last active
+type ActivityRecorder interface {
+	RecordActivity(ctx context.Context, userID string, at time.Time) error
 }
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// LoginApprovalPollInterval is how often clients waiting for an approval
+// should poll
+const LoginApprovalPollInterval = 5 * time.Second
+
+// ApprovalNotifier pushes a new login approval to the user's trusted
+// devices, e.g. through a mobile push service. Devices without push can poll
+// ListLoginApprovals instead.
+type ApprovalNotifier interface {
+	NotifyLoginApproval(ctx context.Context, devices []*domain.TrustedDevice, approval *domain.LoginApproval) error
+}
+
+// SetLoginApprovals enables passwordless logins approved by a trusted device.
+// Approvals not decided and used within ttl expire.
+func (s *AuthService) SetLoginApprovals(devices repository.TrustedDeviceRepository, approvals repository.LoginApprovalRepository, ttl time.Duration) {
+	s.trustedDevices = devices
+	s.loginApprovals = approvals
+	s.loginApprovalTTL = ttl
+}
+
+// SetApprovalNotifier sets the notifier told about new login approvals
+func (s *AuthService) SetApprovalNotifier(notifier ApprovalNotifier) {
+	s.approvalNotifier = notifier
+}
+
+// LoginApprovalsEnabled reports whether login approvals are enabled
+func (s *AuthService) LoginApprovalsEnabled() bool {
+	return s.loginApprovals != nil
+}
+
+// RegisterTrustedDeviceOutput represents a newly registered trusted device
+type RegisterTrustedDeviceOutput struct {
+	Device *domain.TrustedDevice
+	// Secret proves the device when it approves logins. It is only returned
+	// here; the server keeps its hash.
+	Secret string
+}
+
+// RegisterTrustedDevice registers a device of the user that can approve logins
+func (s *AuthService) RegisterTrustedDevice(ctx context.Context, userID, name string) (*RegisterTrustedDeviceOutput, error) {
+	secret, err := security.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	device := &domain.TrustedDevice{
+		UserID:     userID,
+		Name:       name,
+		SecretHash: security.HashToken(secret),
+	}
+	if err := s.trustedDevices.Create(ctx, device); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "trusted device registered", "user_id", userID, "device_id", device.ID)
+	return &RegisterTrustedDeviceOutput{Device: device, Secret: secret}, nil
+}
+
+// ListTrustedDevices returns the user's trusted devices
+func (s *AuthService) ListTrustedDevices(ctx context.Context, userID string) ([]*domain.TrustedDevice, error) {
+	return s.trustedDevices.ListByUser(ctx, userID)
+}
+
+// RemoveTrustedDevice removes one of the user's trusted devices
+func (s *AuthService) RemoveTrustedDevice(ctx context.Context, userID, deviceID string) error {
+	if !domain.IsUUID(deviceID) {
+		return domain.ErrTrustedDeviceNotFound
+	}
+	return s.trustedDevices.Delete(ctx, userID, deviceID)
+}
+
+// StartLoginApprovalInput represents the input for starting an approved login
+type StartLoginApprovalInput struct {
+	Email     string
+	UserAgent *string
+	IPAddress *string
+}
+
+// StartLoginApprovalOutput identifies a login waiting for approval
+type StartLoginApprovalOutput struct {
+	ApprovalID string
+	PollToken  string // proves the waiting client when it polls
+	ExpiresIn  int64  // seconds
+	Interval   int64  // seconds between polls
+}
+
+// StartLoginApproval asks the user's trusted devices to approve a login.
+// Unknown accounts, disabled accounts and accounts without trusted devices
+// all fail with domain.ErrNoTrustedDevice.
+func (s *AuthService) StartLoginApproval(ctx context.Context, input StartLoginApprovalInput) (*StartLoginApprovalOutput, error) {
+	defer s.padResponse(ctx, s.clock.Now())
+
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(input.Email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, domain.ErrNoTrustedDevice
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsDisabled() {
+		return nil, domain.ErrNoTrustedDevice
+	}
+
+	devices, err := s.trustedDevices.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, domain.ErrNoTrustedDevice
+	}
+
+	pollToken, err := security.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	approval := &domain.LoginApproval{
+		UserID:        user.ID,
+		PollTokenHash: security.HashToken(pollToken),
+		Status:        domain.LoginApprovalPending,
+		IPAddress:     input.IPAddress,
+		UserAgent:     input.UserAgent,
+		ExpiresAt:     now.Add(s.loginApprovalTTL),
+		CreatedAt:     now,
+	}
+	if err := s.loginApprovals.Create(ctx, approval); err != nil {
+		return nil, err
+	}
+
+	// Devices can still poll for approvals when the push fails
+	if s.approvalNotifier != nil {
+		if err := s.approvalNotifier.NotifyLoginApproval(ctx, devices, approval); err != nil {
+			slog.WarnContext(ctx, "failed to notify trusted devices", "user_id", user.ID, "error", err)
+		}
+	}
+
+	return &StartLoginApprovalOutput{
+		ApprovalID: approval.ID,
+		PollToken:  pollToken,
+		ExpiresIn:  int64(s.loginApprovalTTL.Seconds()),
+		Interval:   int64(LoginApprovalPollInterval.Seconds()),
+	}, nil
+}
+
+// ListLoginApprovals returns the user's logins awaiting approval, for trusted
+// devices to show
+func (s *AuthService) ListLoginApprovals(ctx context.Context, userID string) ([]*domain.LoginApproval, error) {
+	return s.loginApprovals.ListPending(ctx, userID, s.clock.Now())
+}
+
+// DecideLoginApprovalInput represents a trusted device's decision
+type DecideLoginApprovalInput struct {
+	UserID       string
+	ApprovalID   string
+	DeviceID     string
+	DeviceSecret string
+	Approve      bool
+}
+
+// DecideLoginApproval approves or denies a pending login. The caller must be
+// signed in as the user and prove a trusted device with its secret.
+func (s *AuthService) DecideLoginApproval(ctx context.Context, input DecideLoginApprovalInput) error {
+	if !domain.IsUUID(input.DeviceID) {
+		return domain.ErrTrustedDeviceNotFound
+	}
+	if !domain.IsUUID(input.ApprovalID) {
+		return domain.ErrLoginApprovalNotFound
+	}
+
+	device, err := s.trustedDevices.GetByID(ctx, input.UserID, input.DeviceID)
+	if err != nil {
+		return err
+	}
+	if !security.ConstantTimeCompare(security.HashToken(input.DeviceSecret), device.SecretHash) {
+		return domain.ErrTrustedDeviceNotFound
+	}
+
+	status := domain.LoginApprovalDenied
+	if input.Approve {
+		status = domain.LoginApprovalApproved
+	}
+	now := s.clock.Now()
+	if err := s.loginApprovals.Decide(ctx, input.UserID, input.ApprovalID, device.ID, status, now); err != nil {
+		return err
+	}
+
+	if err := s.trustedDevices.Touch(ctx, device.ID, now); err != nil {
+		slog.WarnContext(ctx, "failed to record trusted device use", "device_id", device.ID, "error", err)
+	}
+	slog.InfoContext(ctx, "login approval decided",
+		"user_id", input.UserID,
+		"approval_id", input.ApprovalID,
+		"device_id", device.ID,
+		"status", status,
+	)
+	return nil
+}
+
+// PollLoginApprovalInput represents a waiting client's poll
+type PollLoginApprovalInput struct {
+	ApprovalID     string
+	PollToken      string
+	UserAgent      *string
+	IPAddress      *string
+	CertThumbprint string            // mTLS client certificate thumbprint; binds the access token when set
+	DPoP           token.DPoPRequest // DPoP proof; binds the tokens to its key when set
+}
+
+// PollLoginApproval returns tokens once a trusted device approved the login.
+// It fails with domain.ErrLoginApprovalPending until then and with
+// domain.ErrLoginDenied when a device denied it. Tokens are issued once.
+func (s *AuthService) PollLoginApproval(ctx context.Context, input PollLoginApprovalInput) (*LoginOutput, error) {
+	cnf, err := s.confirmation(input.CertThumbprint, input.DPoP)
+	if err != nil {
+		return nil, err
+	}
+
+	if !domain.IsUUID(input.ApprovalID) {
+		return nil, domain.ErrLoginApprovalNotFound
+	}
+	approval, err := s.loginApprovals.GetByID(ctx, input.ApprovalID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if !security.ConstantTimeCompare(security.HashToken(input.PollToken), approval.PollTokenHash) || approval.IsExpired(now) {
+		return nil, domain.ErrLoginApprovalNotFound
+	}
+
+	switch approval.Status {
+	case domain.LoginApprovalPending:
+		return nil, domain.ErrLoginApprovalPending
+	case domain.LoginApprovalDenied:
+		return nil, domain.ErrLoginDenied
+	}
+
+	// Concurrent polls race here; only one consumes the approval
+	if err := s.loginApprovals.Consume(ctx, approval.ID, now); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, approval.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, domain.ErrAccountDisabled
+	}
+	s.recordActivity(ctx, user.ID)
+
+	return s.issueLoginTokens(ctx, user, cnf, input.UserAgent, input.IPAddress, false)
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// testUUID returns a distinct UUID-shaped id for in-memory repositories
+func testUUID(n int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", n)
+}
+
+type mockTrustedDeviceRepository struct {
+	devices map[string]*domain.TrustedDevice
+	nextID  int
+}
+
+func newMockTrustedDeviceRepository() *mockTrustedDeviceRepository {
+	return &mockTrustedDeviceRepository{devices: make(map[string]*domain.TrustedDevice)}
+}
+
+func (m *mockTrustedDeviceRepository) Create(ctx context.Context, device *domain.TrustedDevice) error {
+	m.nextID++
+	device.ID = testUUID(m.nextID)
+	device.CreatedAt = time.Now()
+	m.devices[device.ID] = device
+	return nil
+}
+
+func (m *mockTrustedDeviceRepository) GetByID(ctx context.Context, userID, id string) (*domain.TrustedDevice, error) {
+	device, ok := m.devices[id]
+	if !ok || device.UserID != userID {
+		return nil, domain.ErrTrustedDeviceNotFound
+	}
+	return device, nil
+}
+
+func (m *mockTrustedDeviceRepository) ListByUser(ctx context.Context, userID string) ([]*domain.TrustedDevice, error) {
+	var devices []*domain.TrustedDevice
+	for _, device := range m.devices {
+		if device.UserID == userID {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+func (m *mockTrustedDeviceRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	if device, ok := m.devices[id]; ok {
+		device.LastUsedAt = &at
+	}
+	return nil
+}
+
+func (m *mockTrustedDeviceRepository) Delete(ctx context.Context, userID, id string) error {
+	if _, err := m.GetByID(ctx, userID, id); err != nil {
+		return err
+	}
+	delete(m.devices, id)
+	return nil
+}
+
+type mockLoginApprovalRepository struct {
+	approvals map[string]*domain.LoginApproval
+	nextID    int
+}
+
+func newMockLoginApprovalRepository() *mockLoginApprovalRepository {
+	return &mockLoginApprovalRepository{approvals: make(map[string]*domain.LoginApproval)}
+}
+
+func (m *mockLoginApprovalRepository) Create(ctx context.Context, approval *domain.LoginApproval) error {
+	m.nextID++
+	approval.ID = testUUID(m.nextID)
+	m.approvals[approval.ID] = approval
+	return nil
+}
+
+func (m *mockLoginApprovalRepository) GetByID(ctx context.Context, id string) (*domain.LoginApproval, error) {
+	approval, ok := m.approvals[id]
+	if !ok {
+		return nil, domain.ErrLoginApprovalNotFound
+	}
+	return approval, nil
+}
+
+func (m *mockLoginApprovalRepository) ListPending(ctx context.Context, userID string, now time.Time) ([]*domain.LoginApproval, error) {
+	var approvals []*domain.LoginApproval
+	for _, approval := range m.approvals {
+		if approval.UserID == userID && approval.Status == domain.LoginApprovalPending && !approval.IsExpired(now) {
+			approvals = append(approvals, approval)
+		}
+	}
+	return approvals, nil
+}
+
+func (m *mockLoginApprovalRepository) Decide(ctx context.Context, userID, id, deviceID string, status domain.LoginApprovalStatus, now time.Time) error {
+	approval, ok := m.approvals[id]
+	if !ok || approval.UserID != userID || approval.Status != domain.LoginApprovalPending || approval.IsExpired(now) {
+		return domain.ErrLoginApprovalNotFound
+	}
+	approval.Status = status
+	approval.DeviceID = &deviceID
+	return nil
+}
+
+func (m *mockLoginApprovalRepository) Consume(ctx context.Context, id string, now time.Time) error {
+	approval, ok := m.approvals[id]
+	if !ok || approval.Status != domain.LoginApprovalApproved || approval.IsExpired(now) {
+		return domain.ErrLoginApprovalNotFound
+	}
+	delete(m.approvals, id)
+	return nil
+}
+
+func createTestAuthServiceWithLoginApprovals(t *testing.T) *AuthService {
+	service, _, _ := createTestAuthService(t)
+	service.SetLoginApprovals(newMockTrustedDeviceRepository(), newMockLoginApprovalRepository(), 2*time.Minute)
+	return service
+}
+
+func TestAuthService_LoginApproval(t *testing.T) {
+	service := createTestAuthServiceWithLoginApprovals(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "approve@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	// Without a trusted device nobody can approve
+	if _, err := service.StartLoginApproval(ctx, StartLoginApprovalInput{Email: "approve@example.com"}); !errors.Is(err, domain.ErrNoTrustedDevice) {
+		t.Fatalf("StartLoginApproval() error = %v, want %v", err, domain.ErrNoTrustedDevice)
+	}
+	if _, err := service.StartLoginApproval(ctx, StartLoginApprovalInput{Email: "nobody@example.com"}); !errors.Is(err, domain.ErrNoTrustedDevice) {
+		t.Fatalf("StartLoginApproval() unknown user error = %v, want %v", err, domain.ErrNoTrustedDevice)
+	}
+
+	device, err := service.RegisterTrustedDevice(ctx, signup.UserID, "Phone")
+	if err != nil {
+		t.Fatalf("RegisterTrustedDevice() error = %v", err)
+	}
+	if device.Secret == "" || device.Device.SecretHash == device.Secret {
+		t.Fatal("device secret must be returned and stored hashed")
+	}
+
+	start, err := service.StartLoginApproval(ctx, StartLoginApprovalInput{Email: "approve@example.com"})
+	if err != nil {
+		t.Fatalf("StartLoginApproval() error = %v", err)
+	}
+	poll := PollLoginApprovalInput{ApprovalID: start.ApprovalID, PollToken: start.PollToken}
+
+	if _, err := service.PollLoginApproval(ctx, poll); !errors.Is(err, domain.ErrLoginApprovalPending) {
+		t.Fatalf("PollLoginApproval() error = %v, want %v", err, domain.ErrLoginApprovalPending)
+	}
+	wrongToken := poll
+	wrongToken.PollToken = "wrong"
+	if _, err := service.PollLoginApproval(ctx, wrongToken); !errors.Is(err, domain.ErrLoginApprovalNotFound) {
+		t.Fatalf("PollLoginApproval() with wrong token error = %v, want %v", err, domain.ErrLoginApprovalNotFound)
+	}
+
+	pending, err := service.ListLoginApprovals(ctx, signup.UserID)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("ListLoginApprovals() = %d approvals, error = %v", len(pending), err)
+	}
+
+	decide := DecideLoginApprovalInput{
+		UserID:       signup.UserID,
+		ApprovalID:   start.ApprovalID,
+		DeviceID:     device.Device.ID,
+		DeviceSecret: "wrong",
+		Approve:      true,
+	}
+	if err := service.DecideLoginApproval(ctx, decide); !errors.Is(err, domain.ErrTrustedDeviceNotFound) {
+		t.Fatalf("DecideLoginApproval() with wrong secret error = %v, want %v", err, domain.ErrTrustedDeviceNotFound)
+	}
+	decide.DeviceSecret = device.Secret
+	if err := service.DecideLoginApproval(ctx, decide); err != nil {
+		t.Fatalf("DecideLoginApproval() error = %v", err)
+	}
+	if device.Device.LastUsedAt == nil {
+		t.Error("device use was not recorded")
+	}
+
+	output, err := service.PollLoginApproval(ctx, poll)
+	if err != nil {
+		t.Fatalf("PollLoginApproval() error = %v", err)
+	}
+	if output.AccessToken == "" || output.RefreshToken == "" {
+		t.Error("PollLoginApproval() returned no tokens")
+	}
+
+	// Tokens are issued once
+	if _, err := service.PollLoginApproval(ctx, poll); !errors.Is(err, domain.ErrLoginApprovalNotFound) {
+		t.Fatalf("second PollLoginApproval() error = %v, want %v", err, domain.ErrLoginApprovalNotFound)
+	}
+}
+
+func TestAuthService_LoginApprovalDenied(t *testing.T) {
+	service := createTestAuthServiceWithLoginApprovals(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "deny@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	device, err := service.RegisterTrustedDevice(ctx, signup.UserID, "Laptop")
+	if err != nil {
+		t.Fatalf("RegisterTrustedDevice() error = %v", err)
+	}
+	start, err := service.StartLoginApproval(ctx, StartLoginApprovalInput{Email: "deny@example.com"})
+	if err != nil {
+		t.Fatalf("StartLoginApproval() error = %v", err)
+	}
+
+	if err := service.DecideLoginApproval(ctx, DecideLoginApprovalInput{
+		UserID:       signup.UserID,
+		ApprovalID:   start.ApprovalID,
+		DeviceID:     device.Device.ID,
+		DeviceSecret: device.Secret,
+	}); err != nil {
+		t.Fatalf("DecideLoginApproval() error = %v", err)
+	}
+
+	poll := PollLoginApprovalInput{ApprovalID: start.ApprovalID, PollToken: start.PollToken}
+	if _, err := service.PollLoginApproval(ctx, poll); !errors.Is(err, domain.ErrLoginDenied) {
+		t.Fatalf("PollLoginApproval() error = %v, want %v", err, domain.ErrLoginDenied)
+	}
+
+	// A removed device can no longer approve
+	if err := service.RemoveTrustedDevice(ctx, signup.UserID, device.Device.ID); err != nil {
+		t.Fatalf("RemoveTrustedDevice() error = %v", err)
+	}
+	if err := service.RemoveTrustedDevice(ctx, signup.UserID, "not-a-uuid"); !errors.Is(err, domain.ErrTrustedDeviceNotFound) {
+		t.Fatalf("RemoveTrustedDevice() error = %v, want %v", err, domain.ErrTrustedDeviceNotFound)
+	}
+	if _, err := service.StartLoginApproval(ctx, StartLoginApprovalInput{Email: "deny@example.com"}); !errors.Is(err, domain.ErrNoTrustedDevice) {
+		t.Fatalf("StartLoginApproval() error = %v, want %v", err, domain.ErrNoTrustedDevice)
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// QuotaThresholds are the percentages of a monthly limit reported to the
+// quota notifier
+var QuotaThresholds = []int{80, 100}
+
+// quotaNotifyTimeout bounds the delivery of one threshold event
+const quotaNotifyTimeout = 30 * time.Second
+
+// QuotaEvent reports that a subject reached a threshold of its monthly quota
+type QuotaEvent struct {
+	Type      string    `json:"type"`
+	Subject   string    `json:"subject"`
+	Period    string    `json:"period"` // YYYY-MM
+	Threshold int       `json:"threshold"`
+	Requests  int64     `json:"requests"`
+	Limit     int64     `json:"limit"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// QuotaEventThresholdReached is the type of threshold events
+const QuotaEventThresholdReached = "quota.threshold_reached"
+
+// QuotaNotifier delivers quota threshold events, e.g. to a billing system
+type QuotaNotifier interface {
+	NotifyQuotaThreshold(ctx context.Context, event QuotaEvent) error
+}
+
+// QuotaService counts requests against monthly per-subject quotas
+type QuotaService struct {
+	repo         repository.QuotaRepository
+	defaultLimit int64
+	limits       map[string]int64 // per-subject overrides of defaultLimit
+	notifier     QuotaNotifier
+	now          func() time.Time
+}
+
+// NewQuotaService creates a quota service. Subjects without an entry in
+// limits get defaultLimit; a limit of 0 only counts requests.
+func NewQuotaService(repo repository.QuotaRepository, defaultLimit int64, limits map[string]int64) *QuotaService {
+	return &QuotaService{
+		repo:         repo,
+		defaultLimit: defaultLimit,
+		limits:       limits,
+		now:          time.Now,
+	}
+}
+
+// SetNotifier reports threshold events to notifier. Events are delivered in
+// the background so requests are never held up by the receiver.
+func (s *QuotaService) SetNotifier(notifier QuotaNotifier) {
+	s.notifier = notifier
+}
+
+// Limit returns the monthly limit of a subject
+func (s *QuotaService) Limit(subject string) int64 {
+	if limit, ok := s.limits[subject]; ok {
+		return limit
+	}
+	return s.defaultLimit
+}
+
+// Consume counts one request for the subject. When the quota is used up it
+// returns the usage together with domain.ErrQuotaExceeded.
+func (s *QuotaService) Consume(ctx context.Context, subject string) (*domain.QuotaUsage, error) {
+	usage := &domain.QuotaUsage{
+		Subject: subject,
+		Period:  domain.QuotaPeriod(s.now()),
+		Limit:   s.Limit(subject),
+	}
+
+	count, err := s.repo.Increment(ctx, subject, usage.Period, usage.Limit)
+	if err != nil {
+		if errors.Is(err, domain.ErrQuotaExceeded) {
+			usage.Requests = usage.Limit
+		}
+		return usage, err
+	}
+	usage.Requests = count
+
+	// Counts grow one at a time, so exactly one request reaches each threshold
+	for _, threshold := range QuotaThresholds {
+		if usage.Limit > 0 && count == thresholdCount(usage.Limit, threshold) {
+			s.notify(ctx, usage, threshold)
+		}
+	}
+
+	return usage, nil
+}
+
+// Usage returns the subject's usage in the current period
+func (s *QuotaService) Usage(ctx context.Context, subject string) (*domain.QuotaUsage, error) {
+	usage := &domain.QuotaUsage{
+		Subject: subject,
+		Period:  domain.QuotaPeriod(s.now()),
+		Limit:   s.Limit(subject),
+	}
+
+	count, err := s.repo.Get(ctx, subject, usage.Period)
+	if err != nil {
+		return nil, err
+	}
+	usage.Requests = count
+	return usage, nil
+}
+
+// notify delivers a threshold event in the background
+func (s *QuotaService) notify(ctx context.Context, usage *domain.QuotaUsage, threshold int) {
+	if s.notifier == nil {
+		return
+	}
+
+	event := QuotaEvent{
+		Type:      QuotaEventThresholdReached,
+		Subject:   usage.Subject,
+		Period:    usage.Period.Format("2006-01"),
+		Threshold: threshold,
+		Requests:  usage.Requests,
+		Limit:     usage.Limit,
+		Timestamp: s.now().UTC(),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), quotaNotifyTimeout)
+		defer cancel()
+
+		if err := s.notifier.NotifyQuotaThreshold(ctx, event); err != nil {
+			slog.ErrorContext(ctx, "failed to deliver quota event",
+				"subject", event.Subject,
+				"threshold", event.Threshold,
+				"error", err,
+			)
+		}
+	}()
+}
+
+// thresholdCount returns the request count at which percent of limit is reached
+func thresholdCount(limit int64, percent int) int64 {
+	return (limit*int64(percent) + 99) / 100
+}
+
+// WebhookQuotaNotifier posts quota events as JSON to a webhook. Bodies are
+// signed with HMAC-SHA256 over "<timestamp>.<body>" so receivers can verify
+// the sender and reject replays.
+type WebhookQuotaNotifier struct {
+	client *httpclient.Client
+	url    string
+	secret []byte
+}
+
+// NewWebhookQuotaNotifier creates a notifier posting to url
+func NewWebhookQuotaNotifier(client *httpclient.Client, url, secret string) *WebhookQuotaNotifier {
+	return &WebhookQuotaNotifier{client: client, url: url, secret: []byte(secret)}
+}
+
+// NotifyQuotaThreshold posts the event to the webhook
+func (n *WebhookQuotaNotifier) NotifyQuotaThreshold(ctx context.Context, event QuotaEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode quota event: %w", err)
+	}
+	return postWebhook(ctx, n.client, n.url, n.secret, event.Timestamp, body)
+}
+
+// postWebhook posts a JSON body signed with SignWebhook
+func postWebhook(ctx context.Context, client *httpclient.Client, url string, secret []byte, at time.Time, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook rejected event: %s", resp.Status)
+	}
+	return nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>"
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// mockQuotaRepository counts quota usage in memory
+type mockQuotaRepository struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	err    error
+}
+
+func newMockQuotaRepository() *mockQuotaRepository {
+	return &mockQuotaRepository{counts: make(map[string]int64)}
+}
+
+func (m *mockQuotaRepository) Increment(ctx context.Context, subject string, period time.Time, limit int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return 0, m.err
+	}
+	key := subject + "/" + period.Format("2006-01")
+	if limit > 0 && m.counts[key] >= limit {
+		return 0, domain.ErrQuotaExceeded
+	}
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+func (m *mockQuotaRepository) Get(ctx context.Context, subject string, period time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[subject+"/"+period.Format("2006-01")], m.err
+}
+
+// channelQuotaNotifier hands delivered events to the test
+type channelQuotaNotifier chan QuotaEvent
+
+func (n channelQuotaNotifier) NotifyQuotaThreshold(ctx context.Context, event QuotaEvent) error {
+	n <- event
+	return nil
+}
+
+func TestQuotaService_Consume(t *testing.T) {
+	repo := newMockQuotaRepository()
+	quotas := NewQuotaService(repo, 10, map[string]int64{"tenant:acme": 5, "user:free": 0})
+	quotas.now = func() time.Time { return time.Date(2026, 3, 14, 15, 9, 26, 0, time.UTC) }
+	events := make(channelQuotaNotifier, 10)
+	quotas.SetNotifier(events)
+
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		usage, err := quotas.Consume(ctx, "tenant:acme")
+		if err != nil {
+			t.Fatalf("request %d: unexpected error: %v", i, err)
+		}
+		if usage.Requests != int64(i) || usage.Remaining() != int64(5-i) {
+			t.Errorf("request %d: got %d requests, %d remaining", i, usage.Requests, usage.Remaining())
+		}
+	}
+
+	usage, err := quotas.Consume(ctx, "tenant:acme")
+	if !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if usage.Remaining() != 0 || !usage.ResetsAt().Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected usage over quota: %+v", usage)
+	}
+
+	// 80% of 5 is reached on the 4th request, 100% on the 5th. Events are
+	// delivered in the background, so they may arrive in either order.
+	received := make(map[int]QuotaEvent)
+	for len(received) < 2 {
+		select {
+		case event := <-events:
+			received[event.Threshold] = event
+		case <-time.After(time.Second):
+			t.Fatalf("expected 2 threshold events, got %d", len(received))
+		}
+	}
+	for threshold, requests := range map[int]int64{80: 4, 100: 5} {
+		event := received[threshold]
+		if event.Requests != requests || event.Subject != "tenant:acme" ||
+			event.Period != "2026-03" || event.Type != QuotaEventThresholdReached {
+			t.Errorf("unexpected %d%% event: %+v", threshold, event)
+		}
+	}
+
+	// Other subjects use the default limit; a zero limit only counts
+	if limit := quotas.Limit("user:123"); limit != 10 {
+		t.Errorf("expected default limit 10, got %d", limit)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := quotas.Consume(ctx, "user:free"); err != nil {
+			t.Fatalf("unlimited subject rejected: %v", err)
+		}
+	}
+	usage, err = quotas.Usage(ctx, "user:free")
+	if err != nil || usage.Requests != 20 || usage.Remaining() != -1 {
+		t.Errorf("unexpected usage %+v, err %v", usage, err)
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event: %+v", event)
+	default:
+	}
+}
+
+func TestQuotaService_RepositoryError(t *testing.T) {
+	repo := newMockQuotaRepository()
+	repo.err = errors.New("database error")
+	quotas := NewQuotaService(repo, 10, nil)
+
+	if _, err := quotas.Consume(context.Background(), "user:123"); err == nil || errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Errorf("expected repository error, got %v", err)
+	}
+}
+
+func TestWebhookQuotaNotifier(t *testing.T) {
+	secret := "0123456789abcdef0123456789abcdef"
+	var received QuotaEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		want := "sha256=" + SignWebhook([]byte(secret), r.Header.Get("X-Webhook-Timestamp"), body)
+		if !security.ConstantTimeCompare(r.Header.Get("X-Webhook-Signature"), want) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := httpclient.DefaultConfig()
+	config.MaxRetries = 0
+	client, err := httpclient.New(config, slog.Default())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	event := QuotaEvent{
+		Type:      QuotaEventThresholdReached,
+		Subject:   "tenant:acme",
+		Period:    "2026-03",
+		Threshold: 80,
+		Requests:  800,
+		Limit:     1000,
+		Timestamp: time.Now(),
+	}
+	if err := NewWebhookQuotaNotifier(client, server.URL, secret).NotifyQuotaThreshold(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.Subject != "tenant:acme" || received.Threshold != 80 {
+		t.Errorf("unexpected event received: %+v", received)
+	}
+
+	// A receiver with another secret rejects the event
+	err = NewWebhookQuotaNotifier(client, server.URL, "another-secret").NotifyQuotaThreshold(context.Background(), event)
+	if err == nil {
+		t.Error("expected error for rejected webhook")
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// refreshGrace remembers the pair each refresh token was rotated into for a
+// short window, so a client that sends two refreshes at once gets the same
+// pair twice instead of being logged out by the loser. Each rotation is
+// replayed at most once; any further use of the old token is still rejected.
+//
+// Rotations are held in memory, so the window only covers concurrent
+// requests that reach the same instance.
+type refreshGrace struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*graceEntry
+}
+
+// graceEntry is one rotation; done is closed once output is known
+type graceEntry struct {
+	done    chan struct{}
+	output  *LoginOutput // nil when the rotation failed
+	expires time.Time
+	used    bool
+}
+
+func newRefreshGrace(window time.Duration) *refreshGrace {
+	return &refreshGrace{
+		window:  window,
+		now:     time.Now,
+		entries: make(map[string]*graceEntry),
+	}
+}
+
+// begin claims the rotation of refreshToken. It returns nil when another
+// request is rotating or has rotated the token.
+func (g *refreshGrace) begin(refreshToken string) *graceEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	for key, e := range g.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(g.entries, key)
+		}
+	}
+
+	if _, ok := g.entries[refreshToken]; ok {
+		return nil
+	}
+	e := &graceEntry{done: make(chan struct{})}
+	g.entries[refreshToken] = e
+	return e
+}
+
+// finish records the outcome of a rotation claimed with begin. A failed
+// rotation is forgotten so the token can be tried again.
+func (g *refreshGrace) finish(refreshToken string, e *graceEntry, output *LoginOutput) {
+	g.mu.Lock()
+	e.output = output
+	e.expires = g.now().Add(g.window)
+	if output == nil {
+		delete(g.entries, refreshToken)
+	}
+	g.mu.Unlock()
+	close(e.done)
+}
+
+// replay returns the pair refreshToken was rotated into, waiting for a
+// rotation still in progress
+func (g *refreshGrace) replay(ctx context.Context, refreshToken string) (*LoginOutput, error) {
+	g.mu.Lock()
+	e, ok := g.entries[refreshToken]
+	g.mu.Unlock()
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if e.output == nil || e.used || g.now().After(e.expires) {
+		return nil, domain.ErrInvalidToken
+	}
+	e.used = true
+
+	output := *e.output
+	return &output, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func TestAuthService_RefreshGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	login := func(t *testing.T, service *AuthService) *LoginOutput {
+		t.Helper()
+		if _, err := service.Signup(ctx, SignupInput{Email: "grace@example.com", Password: "password123"}); err != nil {
+			t.Fatalf("Signup() error = %v", err)
+		}
+		output, err := service.Login(ctx, LoginInput{Email: "grace@example.com", Password: "password123"})
+		if err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+		return output
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		service, _, _ := createTestAuthService(t)
+		session := login(t, service)
+
+		if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken}); err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken}); !errors.Is(err, domain.ErrInvalidToken) {
+			t.Errorf("reused token: error = %v, want %v", err, domain.ErrInvalidToken)
+		}
+	})
+
+	t.Run("replayed once", func(t *testing.T) {
+		service, _, _ := createTestAuthService(t)
+		service.SetRefreshGracePeriod(10 * time.Second)
+		session := login(t, service)
+
+		first, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken})
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		second, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken})
+		if err != nil {
+			t.Fatalf("Refresh() within the grace period error = %v", err)
+		}
+		if second.RefreshToken != first.RefreshToken || second.AccessToken != first.AccessToken {
+			t.Error("expected the same pair within the grace period")
+		}
+
+		if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken}); !errors.Is(err, domain.ErrInvalidToken) {
+			t.Errorf("third use: error = %v, want %v", err, domain.ErrInvalidToken)
+		}
+		if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: first.RefreshToken}); err != nil {
+			t.Errorf("Refresh() with the new token error = %v", err)
+		}
+	})
+
+	t.Run("window elapsed", func(t *testing.T) {
+		service, _, _ := createTestAuthService(t)
+		service.SetRefreshGracePeriod(10 * time.Second)
+		session := login(t, service)
+
+		if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken}); err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		service.refreshGrace.now = func() time.Time { return time.Now().Add(11 * time.Second) }
+		if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken}); !errors.Is(err, domain.ErrInvalidToken) {
+			t.Errorf("error = %v, want %v", err, domain.ErrInvalidToken)
+		}
+	})
+
+	t.Run("sessions revoked after rotation", func(t *testing.T) {
+		service, _, tokenRepo := createTestAuthService(t)
+		service.SetRefreshGracePeriod(10 * time.Second)
+		session := login(t, service)
+
+		first, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken})
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		tokenRepo.RevokeAllForUser(ctx, tokenRepo.tokens[first.RefreshToken].UserID)
+		if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken}); !errors.Is(err, domain.ErrInvalidToken) {
+			t.Errorf("error = %v, want %v", err, domain.ErrInvalidToken)
+		}
+	})
+
+	t.Run("logged out token", func(t *testing.T) {
+		service, _, _ := createTestAuthService(t)
+		service.SetRefreshGracePeriod(10 * time.Second)
+		session := login(t, service)
+
+		if err := service.Logout(ctx, LogoutInput{RefreshToken: session.RefreshToken}); err != nil {
+			t.Fatalf("Logout() error = %v", err)
+		}
+		if _, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken}); !errors.Is(err, domain.ErrInvalidToken) {
+			t.Errorf("error = %v, want %v", err, domain.ErrInvalidToken)
+		}
+	})
+}
+
+func TestRefreshGrace_Concurrent(t *testing.T) {
+	grace := newRefreshGrace(10 * time.Second)
+	ctx := context.Background()
+
+	entry := grace.begin("old")
+	if entry == nil {
+		t.Fatal("begin() = nil for a new token")
+	}
+	if grace.begin("old") != nil {
+		t.Fatal("begin() claimed a token that is already rotating")
+	}
+
+	// Requests that arrive mid-rotation wait for its result; only one of
+	// them is answered
+	var wg sync.WaitGroup
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, err := grace.replay(ctx, "old")
+			if err == nil && output.RefreshToken != "new" {
+				t.Errorf("replayed %q, want the rotated token", output.RefreshToken)
+			}
+			results <- err
+		}()
+	}
+
+	grace.finish("old", entry, &LoginOutput{RefreshToken: "new"})
+	wg.Wait()
+	close(results)
+
+	var replayed int
+	for err := range results {
+		switch {
+		case err == nil:
+			replayed++
+		case !errors.Is(err, domain.ErrInvalidToken):
+			t.Errorf("replay() error = %v", err)
+		}
+	}
+	if replayed != 1 {
+		t.Errorf("%d requests replayed, want 1", replayed)
+	}
+}
+
+func TestRefreshGrace_FailedRotation(t *testing.T) {
+	grace := newRefreshGrace(10 * time.Second)
+
+	entry := grace.begin("old")
+	grace.finish("old", entry, nil)
+
+	if _, err := grace.replay(context.Background(), "old"); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("replay() error = %v, want %v", err, domain.ErrInvalidToken)
+	}
+	if grace.begin("old") == nil {
+		t.Error("a failed rotation should let the token be rotated again")
+	}
+}
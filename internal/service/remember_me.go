@@ -0,0 +1,50 @@
+package service
+
+import (
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// RememberMePolicy controls remember-me sessions, which outlive normal
+// sessions so users can stay signed in on a browser they trust
+type RememberMePolicy struct {
+	TTL     time.Duration // lifetime of a remember-me session; zero disables remember-me
+	Sliding bool          // each refresh extends the session by TTL instead of keeping the login's expiry
+}
+
+// SetRememberMe lets users ask for a remember-me session at login. Its
+// refresh tokens expire after policy.TTL instead of the refresh token TTL.
+func (s *AuthService) SetRememberMe(policy RememberMePolicy) {
+	s.rememberMe = policy
+}
+
+// RememberMeEnabled reports whether users can ask for a remember-me session
+func (s *AuthService) RememberMeEnabled() bool {
+	return s.rememberMe.TTL > 0
+}
+
+// sessionExpiry returns when a new session ends and whether it is a
+// remember-me session
+func (s *AuthService) sessionExpiry(now time.Time, rememberMe bool) (time.Time, bool) {
+	if rememberMe && s.RememberMeEnabled() {
+		return now.Add(s.rememberMe.TTL), true
+	}
+	return now.Add(s.refreshTokenTTL), false
+}
+
+// rotatedSessionExpiry returns when the session continued by rotating old
+// ends. A remember-me session keeps its expiry unless the policy slides it,
+// and becomes a normal session once remember-me is disabled.
+func (s *AuthService) rotatedSessionExpiry(old *domain.RefreshToken, now time.Time) (time.Time, bool) {
+	if !old.RememberMe || !s.RememberMeEnabled() {
+		return now.Add(s.refreshTokenTTL), false
+	}
+
+	limit := now.Add(s.rememberMe.TTL)
+	if s.rememberMe.Sliding || old.ExpiresAt.After(limit) {
+		// A shortened TTL also shortens sessions issued before the change
+		return limit, true
+	}
+	return old.ExpiresAt, true
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAuthService_RememberMe(t *testing.T) {
+	ctx := context.Background()
+	login := func(t *testing.T, service *AuthService, rememberMe bool) *LoginOutput {
+		t.Helper()
+		if _, err := service.Signup(ctx, SignupInput{Email: "remember@example.com", Password: "password123"}); err != nil {
+			t.Fatalf("Signup() error = %v", err)
+		}
+		output, err := service.Login(ctx, LoginInput{Email: "remember@example.com", Password: "password123", RememberMe: rememberMe})
+		if err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+		return output
+	}
+	near := func(got, want time.Time) bool {
+		return got.Sub(want).Abs() < time.Minute
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		service, _, tokenRepo := createTestAuthService(t)
+		session := login(t, service, true)
+
+		if session.RememberMe || tokenRepo.tokens[session.RefreshToken].RememberMe {
+			t.Error("remember-me session issued while remember-me is disabled")
+		}
+		if !near(session.SessionExpiresAt, time.Now().Add(7*24*time.Hour)) {
+			t.Errorf("SessionExpiresAt = %v, want the refresh token TTL", session.SessionExpiresAt)
+		}
+	})
+
+	t.Run("fixed expiry", func(t *testing.T) {
+		service, _, tokenRepo := createTestAuthService(t)
+		service.SetRememberMe(RememberMePolicy{TTL: 30 * 24 * time.Hour})
+		session := login(t, service, true)
+
+		stored := tokenRepo.tokens[session.RefreshToken]
+		if !session.RememberMe || !stored.RememberMe {
+			t.Fatal("expected a remember-me session")
+		}
+		if !near(session.SessionExpiresAt, time.Now().Add(30*24*time.Hour)) {
+			t.Errorf("SessionExpiresAt = %v, want the remember-me TTL", session.SessionExpiresAt)
+		}
+
+		// Without sliding, a refresh keeps the expiry set at login
+		stored.ExpiresAt = time.Now().Add(10 * 24 * time.Hour)
+		refreshed, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken})
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		if !refreshed.RememberMe || !refreshed.SessionExpiresAt.Equal(stored.ExpiresAt) {
+			t.Errorf("Refresh() = remember-me %v until %v, want until %v", refreshed.RememberMe, refreshed.SessionExpiresAt, stored.ExpiresAt)
+		}
+	})
+
+	t.Run("sliding expiry", func(t *testing.T) {
+		service, _, tokenRepo := createTestAuthService(t)
+		service.SetRememberMe(RememberMePolicy{TTL: 30 * 24 * time.Hour, Sliding: true})
+		session := login(t, service, true)
+
+		tokenRepo.tokens[session.RefreshToken].ExpiresAt = time.Now().Add(10 * 24 * time.Hour)
+		refreshed, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken})
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		if !refreshed.RememberMe || !near(refreshed.SessionExpiresAt, time.Now().Add(30*24*time.Hour)) {
+			t.Errorf("Refresh() = remember-me %v until %v, want extended by the TTL", refreshed.RememberMe, refreshed.SessionExpiresAt)
+		}
+	})
+
+	t.Run("not requested", func(t *testing.T) {
+		service, _, _ := createTestAuthService(t)
+		service.SetRememberMe(RememberMePolicy{TTL: 30 * 24 * time.Hour})
+		session := login(t, service, false)
+
+		if session.RememberMe || !near(session.SessionExpiresAt, time.Now().Add(7*24*time.Hour)) {
+			t.Errorf("Login() = remember-me %v until %v, want a normal session", session.RememberMe, session.SessionExpiresAt)
+		}
+	})
+
+	t.Run("disabled after login", func(t *testing.T) {
+		service, _, _ := createTestAuthService(t)
+		service.SetRememberMe(RememberMePolicy{TTL: 30 * 24 * time.Hour})
+		session := login(t, service, true)
+
+		service.SetRememberMe(RememberMePolicy{})
+		refreshed, err := service.Refresh(ctx, RefreshInput{RefreshToken: session.RefreshToken})
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		if refreshed.RememberMe || !near(refreshed.SessionExpiresAt, time.Now().Add(7*24*time.Hour)) {
+			t.Errorf("Refresh() = remember-me %v until %v, want a normal session", refreshed.RememberMe, refreshed.SessionExpiresAt)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// Token type hints of a revocation request (RFC 7009 section 2.1)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// SetAccessTokenDenylist lets access tokens be revoked one by one before they
+// expire. Without it only refresh tokens can be revoked.
+func (s *AuthService) SetAccessTokenDenylist(denylist repository.AccessTokenDenylistRepository) {
+	s.accessTokenDenylist = denylist
+}
+
+// RevokeTokenInput represents a token revocation request
+type RevokeTokenInput struct {
+	Token         string
+	TokenTypeHint string // which kind of token to look for first; may be empty
+	// Caller is the user or service account the request authenticated as.
+	// Only its own tokens are revoked.
+	Caller string
+}
+
+// RevokeToken revokes a refresh or access token of the caller. As RFC 7009
+// asks, a token that is unknown, already revoked, expired or not the
+// caller's is not an error, so the answer does not tell which tokens exist.
+// The hint only decides which kind is looked for first; unknown hints are
+// ignored.
+func (s *AuthService) RevokeToken(ctx context.Context, input RevokeTokenInput) error {
+	revokers := []func(context.Context, RevokeTokenInput) (bool, error){s.revokeAccessToken, s.revokeRefreshToken}
+	if input.TokenTypeHint == TokenTypeHintRefreshToken {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	for _, revoke := range revokers {
+		found, err := revoke(ctx, input)
+		if err != nil || found {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeRefreshToken revokes a refresh token, reporting whether the token
+// is one
+func (s *AuthService) revokeRefreshToken(ctx context.Context, input RevokeTokenInput) (bool, error) {
+	refreshToken, err := s.refreshTokenRepo.GetByToken(ctx, input.Token)
+	if errors.Is(err, domain.ErrInvalidToken) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if refreshToken.UserID != input.Caller {
+		slog.InfoContext(ctx, "refresh token revocation by another caller ignored", "caller", input.Caller)
+		return true, nil
+	}
+
+	if err := s.refreshTokenRepo.Revoke(ctx, input.Token); err != nil && !errors.Is(err, domain.ErrInvalidToken) {
+		return true, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return true, nil
+}
+
+// revokeAccessToken denies an access token until it expires, reporting
+// whether the token is one. Expired tokens need no revoking and count as
+// unknown.
+func (s *AuthService) revokeAccessToken(ctx context.Context, input RevokeTokenInput) (bool, error) {
+	claims, err := s.tokenManager.ValidateAccessToken(input.Token)
+	if err != nil {
+		return false, nil
+	}
+	if s.accessTokenDenylist == nil {
+		return true, domain.ErrUnsupportedTokenType
+	}
+	if claims.Subject != input.Caller {
+		slog.InfoContext(ctx, "access token revocation by another caller ignored", "caller", input.Caller)
+		return true, nil
+	}
+	if claims.ID == "" {
+		// Issued before access tokens carried a jti; it expires on its own
+		return true, nil
+	}
+
+	if err := s.accessTokenDenylist.Add(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return true, fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockAccessTokenDenylist keeps revoked token ids in memory
+type mockAccessTokenDenylist struct {
+	revoked map[string]time.Time
+}
+
+func (m *mockAccessTokenDenylist) Add(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	m.revoked[tokenID] = expiresAt
+	return nil
+}
+
+func (m *mockAccessTokenDenylist) Contains(ctx context.Context, tokenID string) (bool, error) {
+	_, ok := m.revoked[tokenID]
+	return ok, nil
+}
+
+func TestAuthService_RevokeToken(t *testing.T) {
+	service, _, refreshRepo := createTestAuthService(t)
+	denylist := &mockAccessTokenDenylist{revoked: make(map[string]time.Time)}
+	service.SetAccessTokenDenylist(denylist)
+	ctx := context.Background()
+
+	owner, err := service.Signup(ctx, SignupInput{Email: "owner@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	other, err := service.Signup(ctx, SignupInput{Email: "other@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	login, err := service.Login(ctx, LoginInput{Email: "owner@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	// Another user's tokens are left alone without an error
+	for _, tokenString := range []string{login.RefreshToken, login.AccessToken} {
+		if err := service.RevokeToken(ctx, RevokeTokenInput{Token: tokenString, Caller: other.UserID}); err != nil {
+			t.Errorf("RevokeToken() by another user error = %v", err)
+		}
+	}
+	if refreshRepo.tokens[login.RefreshToken].Revoked || len(denylist.revoked) != 0 {
+		t.Fatal("another user revoked the owner's tokens")
+	}
+
+	// A wrong hint still finds the token
+	err = service.RevokeToken(ctx, RevokeTokenInput{Token: login.RefreshToken, TokenTypeHint: TokenTypeHintAccessToken, Caller: owner.UserID})
+	if err != nil {
+		t.Fatalf("RevokeToken(refresh token) error = %v", err)
+	}
+	if !refreshRepo.tokens[login.RefreshToken].Revoked {
+		t.Error("refresh token was not revoked")
+	}
+
+	err = service.RevokeToken(ctx, RevokeTokenInput{Token: login.AccessToken, TokenTypeHint: TokenTypeHintRefreshToken, Caller: owner.UserID})
+	if err != nil {
+		t.Fatalf("RevokeToken(access token) error = %v", err)
+	}
+	claims, err := service.tokenManager.ValidateAccessToken(login.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if expiresAt, ok := denylist.revoked[claims.ID]; !ok || !expiresAt.Equal(claims.ExpiresAt.Time) {
+		t.Errorf("denylist = %v, want %s until %s", denylist.revoked, claims.ID, claims.ExpiresAt.Time)
+	}
+
+	// Unknown and already revoked tokens are not an error
+	for _, tokenString := range []string{"unknown-token", login.RefreshToken} {
+		if err := service.RevokeToken(ctx, RevokeTokenInput{Token: tokenString, Caller: owner.UserID}); err != nil {
+			t.Errorf("RevokeToken(%q) error = %v", tokenString, err)
+		}
+	}
+}
+
+func TestAuthService_RevokeToken_NoDenylist(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "nodenylist@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	login, err := service.Login(ctx, LoginInput{Email: "nodenylist@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	err = service.RevokeToken(ctx, RevokeTokenInput{Token: login.AccessToken, Caller: signup.UserID})
+	if !errors.Is(err, domain.ErrUnsupportedTokenType) {
+		t.Errorf("RevokeToken(access token) error = %v, want ErrUnsupportedTokenType", err)
+	}
+}
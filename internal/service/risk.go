@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/risk"
+)
+
+// CaptchaVerifier checks a captcha solution submitted with a login
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// EnableRiskEngine scores every login attempt with the given engine and
+// challenges or blocks risky ones. Assessments are written to the audit
+// trail when auditRepo is not nil.
+func (s *AuthService) EnableRiskEngine(engine *risk.Engine, auditRepo repository.AuditLogRepository) {
+	s.riskEngine = engine
+	s.auditRepo = auditRepo
+}
+
+// SetCaptchaVerifier sets the verifier used for captcha challenges. Without
+// one, logins that require a captcha are refused, since no solution can be
+// checked.
+func (s *AuthService) SetCaptchaVerifier(verifier CaptchaVerifier) {
+	s.captchaVerifier = verifier
+}
+
+// newRiskAttempt builds the risk engine input for a login
+func newRiskAttempt(ctx context.Context, input LoginInput, user *domain.User) risk.Attempt {
+	// Failures are tracked per login identifier, or per account email once
+	// the account is known so username and email attempts add up
+	attempt := risk.Attempt{Email: input.Email}
+	if attempt.Email == "" {
+		attempt.Email = input.Username
+	}
+	if user != nil {
+		attempt.UserID = user.ID
+		attempt.Email = user.Email
+	}
+	if input.IPAddress != nil {
+		attempt.IPAddress = *input.IPAddress
+	}
+	if input.UserAgent != nil {
+		attempt.UserAgent = *input.UserAgent
+	}
+	attempt.Exempt = risk.ExemptFromContext(ctx)
+	return attempt
+}
+
+// checkLoginRisk scores a login attempt and returns an error when the
+// attempt must be challenged or blocked
+func (s *AuthService) checkLoginRisk(ctx context.Context, attempt risk.Attempt, captchaToken string) error {
+	if s.riskEngine == nil {
+		return nil
+	}
+
+	if bot, ok := risk.BotAssessmentFromContext(ctx); ok {
+		attempt.BotScore = bot.Score
+	}
+	assessment := s.riskEngine.Assess(attempt)
+
+	var err error
+	switch assessment.Decision {
+	case risk.DecisionBlock:
+		err = domain.ErrLoginBlocked
+	case risk.DecisionEmailConfirmation:
+		// There is no flow to complete the confirmation yet, so this
+		// refuses the login like a block that clients can tell apart
+		err = domain.ErrLoginConfirmationRequired
+	case risk.DecisionCaptcha:
+		if !s.verifyCaptcha(ctx, captchaToken, attempt.IPAddress) {
+			err = domain.ErrCaptchaRequired
+		}
+	}
+
+	s.auditRiskAssessment(ctx, attempt, assessment, err)
+	return err
+}
+
+// verifyCaptcha reports whether the captcha challenge has been satisfied. It
+// fails closed when no verifier is configured.
+func (s *AuthService) verifyCaptcha(ctx context.Context, token, remoteIP string) bool {
+	if s.captchaVerifier == nil {
+		slog.WarnContext(ctx, "captcha required but no captcha verifier is configured")
+		return false
+	}
+	if token == "" {
+		return false
+	}
+
+	ok, err := s.captchaVerifier.Verify(ctx, token, remoteIP)
+	if err != nil {
+		slog.Error("failed to verify captcha", "error", err)
+		return false
+	}
+	return ok
+}
+
+// recordRiskOutcome feeds the result of a login back into the risk engine
+func (s *AuthService) recordRiskOutcome(attempt risk.Attempt, success bool) {
+	if s.riskEngine == nil {
+		return
+	}
+	if success {
+		s.riskEngine.RecordSuccess(attempt)
+	} else {
+		s.riskEngine.RecordFailure(attempt)
+	}
+}
+
+// auditRiskAssessment writes a risk assessment to the audit trail. Failures
+// are logged rather than returned so auditing never blocks a login.
+func (s *AuthService) auditRiskAssessment(ctx context.Context, attempt risk.Attempt, assessment risk.Assessment, challengeErr error) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	entry := domain.NewAuditLog(domain.AuditActionLoginRisk, domain.AuditStatusSuccess)
+	if challengeErr != nil {
+		entry.Status = domain.AuditStatusBlocked
+		message := challengeErr.Error()
+		entry.ErrorMessage = &message
+	}
+	if attempt.UserID != "" {
+		entry.UserID = &attempt.UserID
+	}
+	if attempt.IPAddress != "" {
+		entry.IPAddress = &attempt.IPAddress
+	}
+	if attempt.UserAgent != "" {
+		entry.UserAgent = &attempt.UserAgent
+	}
+	entry.Metadata["score"] = assessment.Score
+	entry.Metadata["signals"] = assessment.Signals
+	entry.Metadata["decision"] = assessment.Decision.String()
+
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.Error("failed to write risk assessment to audit log",
+			"user_id", attempt.UserID,
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/risk"
+)
+
+type mockAuditLogRepository struct {
+	logs []*domain.AuditLog
+}
+
+func (m *mockAuditLogRepository) Create(ctx context.Context, log *domain.AuditLog) error {
+	if log.ID == "" {
+		log.ID = fmt.Sprintf("00000000-0000-4000-8000-%012d", len(m.logs)+1)
+	}
+	m.logs = append(m.logs, log)
+	return nil
+}
+
+// revocations returns the session revocation entries
+func (m *mockAuditLogRepository) revocations() []*domain.AuditLog {
+	var logs []*domain.AuditLog
+	for _, log := range m.logs {
+		if log.Action == domain.AuditActionSessionsRevoked {
+			logs = append(logs, log)
+		}
+	}
+	return logs
+}
+
+type mockCaptchaVerifier struct {
+	valid string
+}
+
+func (m *mockCaptchaVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	return token == m.valid, nil
+}
+
+func TestAuthService_LoginRiskScoring(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "risk@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	auditRepo := &mockAuditLogRepository{}
+	service.EnableRiskEngine(risk.NewEngine(risk.DefaultConfig(), nil), auditRepo)
+	service.SetCaptchaVerifier(&mockCaptchaVerifier{valid: "solved"})
+
+	ip := "203.0.113.10"
+	login := func(password, captcha string) error {
+		_, err := service.Login(ctx, LoginInput{
+			Email:        "risk@example.com",
+			Password:     password,
+			IPAddress:    &ip,
+			CaptchaToken: captcha,
+		})
+		return err
+	}
+
+	if err := login("password123", ""); err != nil {
+		t.Fatalf("first login should succeed, got %v", err)
+	}
+
+	// Three failures push the score to the captcha threshold
+	for i := 0; i < 3; i++ {
+		if err := login("wrong-password", ""); !errors.Is(err, domain.ErrInvalidCredentials) {
+			t.Fatalf("expected invalid credentials, got %v", err)
+		}
+	}
+
+	if err := login("password123", ""); !errors.Is(err, domain.ErrCaptchaRequired) {
+		t.Errorf("expected captcha to be required, got %v", err)
+	}
+	if err := login("password123", "solved"); err != nil {
+		t.Errorf("login with a solved captcha should succeed, got %v", err)
+	}
+
+	// Enough failures block the attempt before the password is checked
+	for i := 0; i < 10; i++ {
+		login("wrong-password", "solved")
+	}
+	service.riskEngine.SetTorExitNodes([]string{ip})
+	if err := login("password123", "solved"); !errors.Is(err, domain.ErrLoginBlocked) {
+		t.Errorf("expected login to be blocked, got %v", err)
+	}
+
+	if len(auditRepo.logs) == 0 {
+		t.Fatal("expected risk assessments in the audit log")
+	}
+	last := auditRepo.logs[len(auditRepo.logs)-1]
+	if last.Action != domain.AuditActionLoginRisk || last.Status != domain.AuditStatusBlocked {
+		t.Errorf("unexpected audit entry: %+v", last)
+	}
+	if last.Metadata["decision"] != "block" {
+		t.Errorf("audit decision = %v, want block", last.Metadata["decision"])
+	}
+}
+
+func TestAuthService_LoginRiskCaptchaWithoutVerifier(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "nocaptcha@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	service.EnableRiskEngine(risk.NewEngine(risk.DefaultConfig(), nil), nil)
+
+	ip := "203.0.113.30"
+	login := func(password, captcha string) error {
+		_, err := service.Login(ctx, LoginInput{
+			Email:        "nocaptcha@example.com",
+			Password:     password,
+			IPAddress:    &ip,
+			CaptchaToken: captcha,
+		})
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		login("wrong-password", "")
+	}
+
+	// Without a verifier no solution can be checked, so the challenge
+	// cannot be passed
+	if err := login("password123", "any-token"); !errors.Is(err, domain.ErrCaptchaRequired) {
+		t.Errorf("expected captcha to be required, got %v", err)
+	}
+}
+
+func TestAuthService_LoginRiskExemption(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "monitor@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	auditRepo := &mockAuditLogRepository{}
+	service.EnableRiskEngine(risk.NewEngine(risk.DefaultConfig(), nil), auditRepo)
+	service.SetCaptchaVerifier(&mockCaptchaVerifier{valid: "solved"})
+
+	ip := "203.0.113.20"
+	login := func(ctx context.Context, password string) error {
+		_, err := service.Login(ctx, LoginInput{Email: "monitor@example.com", Password: password, IPAddress: &ip})
+		return err
+	}
+
+	// Failing probes from trusted automation neither challenge it nor count
+	// against the account
+	exempt := risk.ContextWithExemption(ctx)
+	for i := 0; i < 5; i++ {
+		if err := login(exempt, "wrong-password"); !errors.Is(err, domain.ErrInvalidCredentials) {
+			t.Fatalf("expected invalid credentials, got %v", err)
+		}
+	}
+	if err := login(exempt, "password123"); err != nil {
+		t.Fatalf("exempt login should succeed without a captcha, got %v", err)
+	}
+	if err := login(ctx, "password123"); err != nil {
+		t.Errorf("regular login after exempt failures should succeed, got %v", err)
+	}
+
+	if decision := auditRepo.logs[0].Metadata["decision"]; decision != "exempt" {
+		t.Errorf("audit decision = %v, want exempt", decision)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/rollout"
+)
+
+// RolloutRecorder receives the outcome of auth steps gated by a rollout
+// flag, by variant, so the users trying a change can be compared with the
+// rest
+type RolloutRecorder interface {
+	RecordRolloutOutcome(flag, variant, outcome string)
+}
+
+// SetRollout enables the behavior changes in flags for their cohorts.
+// recorder may be nil.
+func (s *AuthService) SetRollout(flags *rollout.Flags, recorder RolloutRecorder) {
+	s.rollout = flags
+	s.rolloutRecorder = recorder
+}
+
+func (s *AuthService) recordRollout(flag, variant, outcome string) {
+	if s.rolloutRecorder != nil {
+		s.rolloutRecorder.RecordRolloutOutcome(flag, variant, outcome)
+	}
+}
+
+// checkStrictEmailVerification rejects users who have not verified their
+// email when the strict_email_verification flag is enabled for them. Only
+// call it once the password matched, so it cannot be used to probe accounts.
+func (s *AuthService) checkStrictEmailVerification(user *domain.User) error {
+	flag := rollout.StrictEmailVerification
+	if !s.rollout.Configured(flag) {
+		return nil
+	}
+
+	variant := s.rollout.Variant(flag, user.ID)
+	if variant == rollout.VariantEnabled && !user.EmailVerified {
+		s.recordRollout(flag, variant, LoginOutcomeUnverified)
+		return domain.ErrEmailNotVerified
+	}
+	s.recordRollout(flag, variant, LoginOutcomeSuccess)
+	return nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/rollout"
+)
+
+// rolloutOutcomes records outcomes as flag/variant/outcome
+type rolloutOutcomes []string
+
+func (r *rolloutOutcomes) RecordRolloutOutcome(flag, variant, outcome string) {
+	*r = append(*r, flag+"/"+variant+"/"+outcome)
+}
+
+func TestAuthService_StrictEmailVerificationRollout(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	ctx := context.Background()
+
+	for _, email := range []string{"canary@example.com", "control@example.com"} {
+		if _, err := service.Signup(ctx, SignupInput{Email: email, Password: "password123"}); err != nil {
+			t.Fatalf("Signup() error = %v", err)
+		}
+	}
+	canary := userRepo.users["canary@example.com"]
+	control := userRepo.users["control@example.com"]
+	if rollout.Cohort(rollout.StrictEmailVerification, canary.ID) == rollout.Cohort(rollout.StrictEmailVerification, control.ID) {
+		t.Skip("test users share a cohort")
+	}
+
+	login := func(email string) error {
+		_, err := service.Login(ctx, LoginInput{Email: email, Password: "password123"})
+		return err
+	}
+
+	// Without a rollout nobody's login depends on verification
+	if err := login("canary@example.com"); err != nil {
+		t.Fatalf("Login() without rollout error = %v", err)
+	}
+
+	flags, err := rollout.New(map[string][]int{
+		rollout.StrictEmailVerification: {rollout.Cohort(rollout.StrictEmailVerification, canary.ID)},
+	})
+	if err != nil {
+		t.Fatalf("rollout.New() error = %v", err)
+	}
+	outcomes := &rolloutOutcomes{}
+	service.SetRollout(flags, outcomes)
+
+	if err := login("canary@example.com"); !errors.Is(err, domain.ErrEmailNotVerified) {
+		t.Errorf("Login() in the rollout error = %v, want ErrEmailNotVerified", err)
+	}
+	if err := login("control@example.com"); err != nil {
+		t.Errorf("Login() outside the rollout error = %v", err)
+	}
+	canary.MarkEmailVerified()
+	if err := login("canary@example.com"); err != nil {
+		t.Errorf("Login() in the rollout after verifying error = %v", err)
+	}
+	// Wrong passwords never reach the flag
+	service.Login(ctx, LoginInput{Email: "canary@example.com", Password: "wrong-password"})
+
+	want := rolloutOutcomes{
+		"strict_email_verification/enabled/unverified",
+		"strict_email_verification/control/success",
+		"strict_email_verification/enabled/success",
+	}
+	if !slices.Equal(*outcomes, want) {
+		t.Errorf("outcomes = %v, want %v", *outcomes, want)
+	}
+}
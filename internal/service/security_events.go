@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// Security event page sizes
+const (
+	DefaultSecurityEventsPageSize = 20
+	MaxSecurityEventsPageSize     = 100
+)
+
+// securityEventActions are the audit log actions security events are derived
+// from
+var securityEventActions = []string{domain.AuditActionLogin, domain.AuditActionSessionsRevoked}
+
+// SetSecurityEvents lets users list their own security events, read from the
+// audit trail written through SetAuditLog
+func (s *AuthService) SetSecurityEvents(reader repository.AuditLogReader) {
+	s.securityEvents = reader
+}
+
+// SecurityEventsEnabled reports whether users can list their security events
+func (s *AuthService) SecurityEventsEnabled() bool {
+	return s.securityEvents != nil
+}
+
+// SecurityEventsPage is one page of a user's security events, newest first
+type SecurityEventsPage struct {
+	Events []*domain.SecurityEvent
+	// NextCursor fetches the next, older page; empty on the last page
+	NextCursor string
+}
+
+// ListSecurityEvents returns a page of the user's security events. cursor is
+// empty for the first page and NextCursor of the previous page after that;
+// limit is capped at MaxSecurityEventsPageSize.
+func (s *AuthService) ListSecurityEvents(ctx context.Context, userID, cursor string, limit int) (*SecurityEventsPage, error) {
+	var before *domain.AuditCursor
+	if cursor != "" {
+		decoded, err := decodeAuditCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		before = &decoded
+	}
+	if limit <= 0 {
+		limit = DefaultSecurityEventsPageSize
+	}
+	limit = min(limit, MaxSecurityEventsPageSize)
+
+	// One more entry than requested tells whether there is a next page
+	logs, err := s.securityEvents.ListByUser(ctx, userID, securityEventActions, before, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security events: %w", err)
+	}
+
+	page := &SecurityEventsPage{Events: make([]*domain.SecurityEvent, 0, min(len(logs), limit))}
+	for i, log := range logs {
+		if i == limit {
+			last := logs[limit-1]
+			page.NextCursor = encodeAuditCursor(domain.AuditCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+			break
+		}
+		page.Events = append(page.Events, newSecurityEvent(log))
+	}
+	return page, nil
+}
+
+// newSecurityEvent derives the security event shown to the user from an
+// audit log entry
+func newSecurityEvent(log *domain.AuditLog) *domain.SecurityEvent {
+	event := &domain.SecurityEvent{
+		ID:        log.ID,
+		Type:      domain.SecurityEventNewLogin,
+		IPAddress: log.IPAddress,
+		UserAgent: log.UserAgent,
+		CreatedAt: log.CreatedAt,
+	}
+	if log.Action != domain.AuditActionSessionsRevoked {
+		return event
+	}
+
+	// Password and second factor changes sign the user out everywhere; the
+	// revocation reason tells what changed
+	event.Reason, _ = log.Metadata["reason"].(string)
+	switch event.Reason {
+	case RevokeReasonPasswordChange, RevokeReasonPasswordReset:
+		event.Type = domain.SecurityEventPasswordChanged
+	case RevokeReasonSecondFactorEnabled:
+		event.Type = domain.SecurityEventSecondFactorEnabled
+	case RevokeReasonSecondFactorDisabled:
+		event.Type = domain.SecurityEventSecondFactorDisabled
+	default:
+		event.Type = domain.SecurityEventSessionsRevoked
+	}
+	return event
+}
+
+// auditLogin records a new session; failures are only logged
+func (s *AuthService) auditLogin(ctx context.Context, userID string, userAgent, ipAddress *string) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	resourceType := "user"
+	entry := domain.NewAuditLog(domain.AuditActionLogin, domain.AuditStatusSuccess)
+	entry.UserID = &userID
+	entry.ResourceType = &resourceType
+	entry.ResourceID = &userID
+	entry.IPAddress = ipAddress
+	entry.UserAgent = userAgent
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write login audit log", "user_id", userID, "error", err)
+	}
+}
+
+// encodeAuditCursor makes an opaque page cursor
+func encodeAuditCursor(cursor domain.AuditCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeAuditCursor reads a cursor made by encodeAuditCursor
+func decodeAuditCursor(cursor string) (domain.AuditCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return domain.AuditCursor{}, domain.ErrInvalidPageCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || !domain.IsUUID(id) {
+		return domain.AuditCursor{}, domain.ErrInvalidPageCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return domain.AuditCursor{}, domain.ErrInvalidPageCursor
+	}
+	return domain.AuditCursor{CreatedAt: t, ID: id}, nil
+}
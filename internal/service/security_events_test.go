@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+func (m *mockAuditLogRepository) ListByUser(ctx context.Context, userID string, actions []string, before *domain.AuditCursor, limit int) ([]*domain.AuditLog, error) {
+	newer := func(a, b *domain.AuditLog) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+
+	var logs []*domain.AuditLog
+	for _, log := range m.logs {
+		if log.UserID == nil || *log.UserID != userID || !slices.Contains(actions, log.Action) {
+			continue
+		}
+		if before != nil && !newer(&domain.AuditLog{CreatedAt: before.CreatedAt, ID: before.ID}, log) {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	sort.Slice(logs, func(i, j int) bool { return newer(logs[i], logs[j]) })
+	if len(logs) > limit {
+		logs = logs[:limit]
+	}
+	return logs, nil
+}
+
+func TestAuthService_ListSecurityEvents(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	audit := &mockAuditLogRepository{}
+	service.SetAuditLog(audit)
+	service.SetSecurityEvents(audit)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "events@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	ip := "203.0.113.7"
+	if _, err := service.Login(ctx, LoginInput{Email: "events@example.com", Password: "password123", IPAddress: &ip}); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if err := service.ChangePassword(ctx, ChangePasswordInput{UserID: signup.UserID, CurrentPassword: "password123", NewPassword: "password456"}); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if err := service.LogoutAll(ctx, signup.UserID); err != nil {
+		t.Fatalf("LogoutAll() error = %v", err)
+	}
+
+	// Newest first, two per page
+	first, err := service.ListSecurityEvents(ctx, signup.UserID, "", 2)
+	if err != nil {
+		t.Fatalf("ListSecurityEvents() error = %v", err)
+	}
+	if len(first.Events) != 2 || first.NextCursor == "" ||
+		first.Events[0].Type != domain.SecurityEventSessionsRevoked || first.Events[0].Reason != RevokeReasonLogoutAll ||
+		first.Events[1].Type != domain.SecurityEventPasswordChanged {
+		t.Fatalf("first page = %+v", first)
+	}
+
+	second, err := service.ListSecurityEvents(ctx, signup.UserID, first.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("ListSecurityEvents() error = %v", err)
+	}
+	if len(second.Events) != 1 || second.NextCursor != "" || second.Events[0].Type != domain.SecurityEventNewLogin {
+		t.Fatalf("second page = %+v", second)
+	}
+	if second.Events[0].IPAddress == nil || *second.Events[0].IPAddress != ip {
+		t.Errorf("login IP address = %v, want %s", second.Events[0].IPAddress, ip)
+	}
+
+	// Other users' events are not listed
+	others, err := service.ListSecurityEvents(ctx, "00000000-0000-4000-8000-999999999999", "", 0)
+	if err != nil || len(others.Events) != 0 {
+		t.Errorf("ListSecurityEvents() of another user = %+v, %v", others, err)
+	}
+
+	if _, err := service.ListSecurityEvents(ctx, signup.UserID, "not-a-cursor", 2); !errors.Is(err, domain.ErrInvalidPageCursor) {
+		t.Errorf("ListSecurityEvents() error = %v, want %v", err, domain.ErrInvalidPageCursor)
+	}
+}
+
+func TestNewSecurityEvent(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   string
+	}{
+		{RevokeReasonPasswordChange, domain.SecurityEventPasswordChanged},
+		{RevokeReasonPasswordReset, domain.SecurityEventPasswordChanged},
+		{RevokeReasonSecondFactorEnabled, domain.SecurityEventSecondFactorEnabled},
+		{RevokeReasonSecondFactorDisabled, domain.SecurityEventSecondFactorDisabled},
+		{RevokeReasonForceLogout, domain.SecurityEventSessionsRevoked},
+		{RevokeReasonEmailVerified, domain.SecurityEventSessionsRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			log := domain.NewAuditLog(domain.AuditActionSessionsRevoked, domain.AuditStatusSuccess)
+			log.Metadata["reason"] = tt.reason
+			if got := newSecurityEvent(log); got.Type != tt.want || got.Reason != tt.reason {
+				t.Errorf("newSecurityEvent() = %s (%s), want %s", got.Type, got.Reason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// serviceAccountIDPrefix marks service account ids, so their tokens are
+// told apart from users' at a glance
+const serviceAccountIDPrefix = "sa_"
+
+// MaxServiceAccountKeys is how many unexpired keys a service account may
+// have: enough to rotate one key while another is in use
+const MaxServiceAccountKeys = 3
+
+// ServiceAccountService manages service accounts and issues their access
+// tokens. An account authenticates by signing a short-lived JWT assertion
+// with the private half of a registered key (RFC 7523, as private_key_jwt);
+// the server only ever stores public keys.
+type ServiceAccountService struct {
+	repo            repository.ServiceAccountRepository
+	auditRepo       repository.AuditLogRepository
+	tokenManager    *token.Manager
+	audiences       []string
+	maxAssertionAge time.Duration
+	now             func() time.Time
+}
+
+// NewServiceAccountService creates a service account service. Assertions
+// must name one of audiences in aud and expire within maxAssertionAge.
+func NewServiceAccountService(repo repository.ServiceAccountRepository, tokenManager *token.Manager, audiences []string, maxAssertionAge time.Duration) *ServiceAccountService {
+	return &ServiceAccountService{
+		repo:            repo,
+		tokenManager:    tokenManager,
+		audiences:       audiences,
+		maxAssertionAge: maxAssertionAge,
+		now:             time.Now,
+	}
+}
+
+// SetAuditLog records account and key changes and issued tokens in the
+// audit trail
+func (s *ServiceAccountService) SetAuditLog(auditRepo repository.AuditLogRepository) {
+	s.auditRepo = auditRepo
+}
+
+// CreateAccount creates a service account that may be granted up to scopes
+func (s *ServiceAccountService) CreateAccount(ctx context.Context, name string, scopes []string) (*domain.ServiceAccount, error) {
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	id, err := security.GenerateToken(12)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate service account id: %w", err)
+	}
+
+	now := s.now()
+	account := &domain.ServiceAccount{
+		ID:        serviceAccountIDPrefix + id,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, account); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, domain.AuditActionServiceAccountCreate, account.ID, map[string]interface{}{
+		"name":  account.Name,
+		"scope": strings.Join(account.Scopes, " "),
+	})
+	return account, nil
+}
+
+// ListAccounts returns all service accounts, newest first
+func (s *ServiceAccountService) ListAccounts(ctx context.Context) ([]*domain.ServiceAccount, error) {
+	return s.repo.List(ctx)
+}
+
+// GetAccount returns a service account with its keys, newest first
+func (s *ServiceAccountService) GetAccount(ctx context.Context, id string) (*domain.ServiceAccount, []*domain.ServiceAccountKey, error) {
+	account, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := s.repo.ListKeys(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, keys, nil
+}
+
+// UpdateScopes replaces the scopes an account may be granted. Tokens already
+// issued keep their scopes until they expire.
+func (s *ServiceAccountService) UpdateScopes(ctx context.Context, id string, scopes []string) (*domain.ServiceAccount, error) {
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateScopes(ctx, id, scopes, s.now()); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, domain.AuditActionServiceAccountUpdate, id, map[string]interface{}{
+		"scope": strings.Join(scopes, " "),
+	})
+	return s.repo.GetByID(ctx, id)
+}
+
+// DeleteAccount deletes a service account and its keys
+func (s *ServiceAccountService) DeleteAccount(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.audit(ctx, domain.AuditActionServiceAccountDelete, id, nil)
+	return nil
+}
+
+// AddKey registers a public JWK the account can sign assertions with. Only
+// the key members are kept; the key id is its RFC 7638 thumbprint.
+func (s *ServiceAccountService) AddKey(ctx context.Context, accountID string, jwk map[string]interface{}) (*domain.ServiceAccountKey, error) {
+	publicKey, kid, err := token.NormalizePublicJWK(jwk)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPublicKey, err)
+	}
+
+	keys, err := s.repo.ListKeys(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		// Tell an unknown account apart from one without keys
+		if _, err := s.repo.GetByID(ctx, accountID); err != nil {
+			return nil, err
+		}
+	}
+	now := s.now()
+	active := 0
+	for _, key := range keys {
+		if key.IsActive(now) {
+			active++
+		}
+	}
+	if active >= MaxServiceAccountKeys {
+		return nil, domain.ErrTooManyServiceAccountKeys
+	}
+
+	key := &domain.ServiceAccountKey{
+		ID:               kid,
+		ServiceAccountID: accountID,
+		PublicKey:        publicKey,
+		CreatedAt:        now,
+	}
+	if err := s.repo.CreateKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, domain.AuditActionServiceAccountKeyAdd, accountID, map[string]interface{}{"key_id": kid})
+	return key, nil
+}
+
+// RotateKey registers a new key and retires an active one after grace, so
+// clients can switch keys without failing requests
+func (s *ServiceAccountService) RotateKey(ctx context.Context, accountID, oldKeyID string, jwk map[string]interface{}, grace time.Duration) (*domain.ServiceAccountKey, error) {
+	old, err := s.repo.GetKey(ctx, accountID, oldKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if !old.IsActive(s.now()) {
+		return nil, domain.ErrServiceAccountKeyNotFound
+	}
+
+	key, err := s.AddKey(ctx, accountID, jwk)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.expireKey(ctx, accountID, oldKeyID, s.now().Add(grace)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// RevokeKey stops accepting assertions signed with a key
+func (s *ServiceAccountService) RevokeKey(ctx context.Context, accountID, keyID string) error {
+	return s.expireKey(ctx, accountID, keyID, s.now())
+}
+
+// expireKey retires a key at the given time
+func (s *ServiceAccountService) expireKey(ctx context.Context, accountID, keyID string, at time.Time) error {
+	if err := s.repo.ExpireKey(ctx, accountID, keyID, at); err != nil {
+		return err
+	}
+
+	s.audit(ctx, domain.AuditActionServiceAccountKeyRevoke, accountID, map[string]interface{}{
+		"key_id":     keyID,
+		"expires_at": at.UTC().Format(time.RFC3339),
+	})
+	return nil
+}
+
+// ServiceTokenOutput is an access token issued to a service account
+type ServiceTokenOutput struct {
+	AccessToken string
+	ExpiresIn   int64
+	Scope       string // the granted scopes, space-delimited
+}
+
+// IssueToken verifies a client assertion and issues an access token to the
+// service account that signed it. scope requests a subset of the account's
+// scopes; empty requests all of them. Assertions are accepted once.
+func (s *ServiceAccountService) IssueToken(ctx context.Context, assertion, scope string) (*ServiceTokenOutput, error) {
+	account, key, claims, err := s.verifyAssertion(ctx, assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := account.Scopes
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		for _, name := range requested {
+			if !slices.Contains(account.Scopes, name) {
+				return nil, fmt.Errorf("%w: %s is not granted to the service account", domain.ErrInvalidScope, name)
+			}
+		}
+		scopes = requested
+	}
+
+	// Checked last so a rejected request does not burn the assertion
+	if err := s.useAssertion(ctx, account.ID, claims); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.tokenManager.GenerateClientAccessToken(account.ID, scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	s.audit(ctx, domain.AuditActionServiceAccountToken, account.ID, map[string]interface{}{
+		"key_id": key.ID,
+		"scope":  strings.Join(scopes, " "),
+	})
+	return &ServiceTokenOutput{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(s.tokenManager.AccessTokenTTL().Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// AuthenticateClient verifies a client assertion presented to an endpoint
+// other than the token endpoint, such as token revocation, and returns the
+// service account that signed it. The assertion is used up, as at the token
+// endpoint.
+func (s *ServiceAccountService) AuthenticateClient(ctx context.Context, assertion string) (*domain.ServiceAccount, error) {
+	account, _, claims, err := s.verifyAssertion(ctx, assertion)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.useAssertion(ctx, account.ID, claims); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// verifyAssertion checks a client assertion's signature and claims against
+// the key it names, without using it up
+func (s *ServiceAccountService) verifyAssertion(ctx context.Context, assertion string) (*domain.ServiceAccount, *domain.ServiceAccountKey, *jwt.RegisteredClaims, error) {
+	parsed, err := token.ParseClientAssertion(assertion)
+	if err != nil {
+		return nil, nil, nil, s.rejectAssertion(ctx, "", err)
+	}
+
+	account, err := s.repo.GetByID(ctx, parsed.Issuer)
+	if errors.Is(err, domain.ErrServiceAccountNotFound) {
+		return nil, nil, nil, s.rejectAssertion(ctx, parsed.Issuer, err)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, err := s.repo.GetKey(ctx, account.ID, parsed.KeyID)
+	if errors.Is(err, domain.ErrServiceAccountKeyNotFound) {
+		return nil, nil, nil, s.rejectAssertion(ctx, account.ID, err)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	now := s.now()
+	if !key.IsActive(now) {
+		return nil, nil, nil, s.rejectAssertion(ctx, account.ID, fmt.Errorf("key %s has expired", key.ID))
+	}
+	claims, err := parsed.Verify(key.PublicKey, s.audiences, now, s.maxAssertionAge)
+	if err != nil {
+		return nil, nil, nil, s.rejectAssertion(ctx, account.ID, err)
+	}
+	return account, key, claims, nil
+}
+
+// useAssertion records a verified assertion's id, rejecting replays
+func (s *ServiceAccountService) useAssertion(ctx context.Context, accountID string, claims *jwt.RegisteredClaims) error {
+	fresh, err := s.repo.RecordAssertion(ctx, accountID, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return s.rejectAssertion(ctx, accountID, errors.New("assertion has already been used"))
+	}
+	return nil
+}
+
+// rejectAssertion logs why an assertion was rejected, which the client is
+// not told, and returns the error to answer with
+func (s *ServiceAccountService) rejectAssertion(ctx context.Context, accountID string, reason error) error {
+	slog.InfoContext(ctx, "service account assertion rejected", "service_account_id", accountID, "reason", reason)
+	return domain.ErrInvalidClientAssertion
+}
+
+// audit records a service account action; failures are only logged
+func (s *ServiceAccountService) audit(ctx context.Context, action, accountID string, metadata map[string]interface{}) {
+	if s.auditRepo == nil {
+		return
+	}
+	resourceType := "service_account"
+	entry := domain.NewAuditLog(action, domain.AuditStatusSuccess)
+	entry.ResourceType = &resourceType
+	entry.ResourceID = &accountID
+	for k, v := range metadata {
+		entry.Metadata[k] = v
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write service account audit log", "service_account_id", accountID, "action", action, "error", err)
+	}
+}
+
+// normalizeScopes checks scope names (RFC 6749 section 3.3) and drops
+// duplicates, keeping the order given
+func normalizeScopes(scopes []string) ([]string, error) {
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope == "" || strings.ContainsFunc(scope, func(r rune) bool {
+			return r < 0x21 || r > 0x7e || r == '"' || r == '\\'
+		}) {
+			return nil, fmt.Errorf("%w: %q is not a valid scope name", domain.ErrInvalidScope, scope)
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
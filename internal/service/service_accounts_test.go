@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+const testServiceTokenAudience = "https://auth.example.com/api/v1/auth/service-accounts/token"
+
+// mockServiceAccountRepository keeps service accounts, keys and used
+// assertions in memory
+type mockServiceAccountRepository struct {
+	accounts   map[string]*domain.ServiceAccount
+	keys       map[string]*domain.ServiceAccountKey // by account id and key id
+	assertions map[string]bool
+}
+
+func newMockServiceAccountRepository() *mockServiceAccountRepository {
+	return &mockServiceAccountRepository{
+		accounts:   make(map[string]*domain.ServiceAccount),
+		keys:       make(map[string]*domain.ServiceAccountKey),
+		assertions: make(map[string]bool),
+	}
+}
+
+func (m *mockServiceAccountRepository) Create(ctx context.Context, account *domain.ServiceAccount) error {
+	copied := *account
+	m.accounts[account.ID] = &copied
+	return nil
+}
+
+func (m *mockServiceAccountRepository) GetByID(ctx context.Context, id string) (*domain.ServiceAccount, error) {
+	account, ok := m.accounts[id]
+	if !ok {
+		return nil, domain.ErrServiceAccountNotFound
+	}
+	copied := *account
+	return &copied, nil
+}
+
+func (m *mockServiceAccountRepository) List(ctx context.Context) ([]*domain.ServiceAccount, error) {
+	var accounts []*domain.ServiceAccount
+	for _, account := range m.accounts {
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func (m *mockServiceAccountRepository) UpdateScopes(ctx context.Context, id string, scopes []string, at time.Time) error {
+	account, ok := m.accounts[id]
+	if !ok {
+		return domain.ErrServiceAccountNotFound
+	}
+	account.Scopes = scopes
+	account.UpdatedAt = at
+	return nil
+}
+
+func (m *mockServiceAccountRepository) Delete(ctx context.Context, id string) error {
+	if _, ok := m.accounts[id]; !ok {
+		return domain.ErrServiceAccountNotFound
+	}
+	delete(m.accounts, id)
+	return nil
+}
+
+func (m *mockServiceAccountRepository) CreateKey(ctx context.Context, key *domain.ServiceAccountKey) error {
+	id := key.ServiceAccountID + "/" + key.ID
+	if _, ok := m.keys[id]; ok {
+		return domain.ErrServiceAccountKeyExists
+	}
+	copied := *key
+	m.keys[id] = &copied
+	return nil
+}
+
+func (m *mockServiceAccountRepository) GetKey(ctx context.Context, accountID, keyID string) (*domain.ServiceAccountKey, error) {
+	key, ok := m.keys[accountID+"/"+keyID]
+	if !ok {
+		return nil, domain.ErrServiceAccountKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (m *mockServiceAccountRepository) ListKeys(ctx context.Context, accountID string) ([]*domain.ServiceAccountKey, error) {
+	var keys []*domain.ServiceAccountKey
+	for _, key := range m.keys {
+		if key.ServiceAccountID == accountID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *mockServiceAccountRepository) ExpireKey(ctx context.Context, accountID, keyID string, at time.Time) error {
+	key, ok := m.keys[accountID+"/"+keyID]
+	if !ok {
+		return domain.ErrServiceAccountKeyNotFound
+	}
+	if key.ExpiresAt == nil || at.Before(*key.ExpiresAt) {
+		key.ExpiresAt = &at
+	}
+	return nil
+}
+
+func (m *mockServiceAccountRepository) RecordAssertion(ctx context.Context, accountID, assertionID string, expiresAt time.Time) (bool, error) {
+	id := accountID + "/" + assertionID
+	if m.assertions[id] {
+		return false, nil
+	}
+	m.assertions[id] = true
+	return true, nil
+}
+
+// serviceAccountKey is a client key pair and its public JWK
+type serviceAccountKey struct {
+	private *ecdsa.PrivateKey
+	jwk     map[string]interface{}
+}
+
+func newServiceAccountKey(t *testing.T) serviceAccountKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return serviceAccountKey{private: key, jwk: map[string]interface{}{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}}
+}
+
+// sign makes an assertion for the account signed with the key
+func (k serviceAccountKey) sign(t *testing.T, accountID, kid, jti string) string {
+	t.Helper()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Issuer:    accountID,
+		Subject:   accountID,
+		Audience:  jwt.ClaimStrings{testServiceTokenAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		ID:        jti,
+	})
+	assertion.Header["kid"] = kid
+	signed, err := assertion.SignedString(k.private)
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+	return signed
+}
+
+func newTestServiceAccountService(t *testing.T) (*ServiceAccountService, *mockServiceAccountRepository, *token.Manager) {
+	t.Helper()
+	tokenManager, err := token.NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create token manager: %v", err)
+	}
+	repo := newMockServiceAccountRepository()
+	return NewServiceAccountService(repo, tokenManager, []string{testServiceTokenAudience}, 5*time.Minute), repo, tokenManager
+}
+
+func TestServiceAccountService_IssueToken(t *testing.T) {
+	accounts, _, tokenManager := newTestServiceAccountService(t)
+	audit := &mockAuditLogRepository{}
+	accounts.SetAuditLog(audit)
+	ctx := context.Background()
+
+	account, err := accounts.CreateAccount(ctx, "nightly reports", []string{"reports:read", "reports:write", "reports:read"})
+	if err != nil {
+		t.Fatalf("CreateAccount() error = %v", err)
+	}
+	if !strings.HasPrefix(account.ID, serviceAccountIDPrefix) || len(account.Scopes) != 2 {
+		t.Errorf("unexpected account %+v", account)
+	}
+	clientKey := newServiceAccountKey(t)
+	key, err := accounts.AddKey(ctx, account.ID, clientKey.jwk)
+	if err != nil {
+		t.Fatalf("AddKey() error = %v", err)
+	}
+
+	output, err := accounts.IssueToken(ctx, clientKey.sign(t, account.ID, key.ID, "jti-1"), "reports:read")
+	if err != nil {
+		t.Fatalf("IssueToken() error = %v", err)
+	}
+	claims, err := tokenManager.ValidateAccessToken(output.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.Subject != account.ID || claims.Scope != "reports:read" || output.Scope != "reports:read" {
+		t.Errorf("claims = %+v, scope %q; want a token for %s with reports:read", claims, output.Scope, account.ID)
+	}
+	if output.ExpiresIn != 900 {
+		t.Errorf("ExpiresIn = %d, want 900", output.ExpiresIn)
+	}
+
+	// Assertions are only accepted once
+	if _, err := accounts.IssueToken(ctx, clientKey.sign(t, account.ID, key.ID, "jti-1"), ""); !errors.Is(err, domain.ErrInvalidClientAssertion) {
+		t.Errorf("replayed assertion: error = %v, want ErrInvalidClientAssertion", err)
+	}
+
+	// Without a scope, every scope of the account is granted
+	output, err = accounts.IssueToken(ctx, clientKey.sign(t, account.ID, key.ID, "jti-2"), "")
+	if err != nil || output.Scope != "reports:read reports:write" {
+		t.Errorf("IssueToken() = %+v, %v; want all scopes", output, err)
+	}
+
+	if _, err := accounts.IssueToken(ctx, clientKey.sign(t, account.ID, key.ID, "jti-3"), "admin"); !errors.Is(err, domain.ErrInvalidScope) {
+		t.Errorf("ungranted scope: error = %v, want ErrInvalidScope", err)
+	}
+	// The rejected request did not use up its assertion
+	if _, err := accounts.IssueToken(ctx, clientKey.sign(t, account.ID, key.ID, "jti-3"), ""); err != nil {
+		t.Errorf("IssueToken() after a scope error = %v", err)
+	}
+
+	// Unknown accounts and keys are not told apart from bad signatures
+	other := newServiceAccountKey(t)
+	for name, assertion := range map[string]string{
+		"unknown account": clientKey.sign(t, "sa_unknown", key.ID, "jti-4"),
+		"unknown key":     other.sign(t, account.ID, "unknown-kid", "jti-5"),
+		"wrong signature": other.sign(t, account.ID, key.ID, "jti-6"),
+	} {
+		if _, err := accounts.IssueToken(ctx, assertion, ""); !errors.Is(err, domain.ErrInvalidClientAssertion) {
+			t.Errorf("%s: error = %v, want ErrInvalidClientAssertion", name, err)
+		}
+	}
+
+	if err := accounts.RevokeKey(ctx, account.ID, key.ID); err != nil {
+		t.Fatalf("RevokeKey() error = %v", err)
+	}
+	if _, err := accounts.IssueToken(ctx, clientKey.sign(t, account.ID, key.ID, "jti-7"), ""); !errors.Is(err, domain.ErrInvalidClientAssertion) {
+		t.Errorf("revoked key: error = %v, want ErrInvalidClientAssertion", err)
+	}
+
+	issued := 0
+	for _, entry := range audit.logs {
+		if entry.Action == domain.AuditActionServiceAccountToken {
+			issued++
+		}
+	}
+	if issued != 3 {
+		t.Errorf("audited %d issued tokens, want 3", issued)
+	}
+}
+
+func TestServiceAccountService_AuthenticateClient(t *testing.T) {
+	accounts, _, _ := newTestServiceAccountService(t)
+	ctx := context.Background()
+
+	account, err := accounts.CreateAccount(ctx, "revoker", []string{"reports:read"})
+	if err != nil {
+		t.Fatalf("CreateAccount() error = %v", err)
+	}
+	clientKey := newServiceAccountKey(t)
+	key, err := accounts.AddKey(ctx, account.ID, clientKey.jwk)
+	if err != nil {
+		t.Fatalf("AddKey() error = %v", err)
+	}
+
+	authenticated, err := accounts.AuthenticateClient(ctx, clientKey.sign(t, account.ID, key.ID, "jti-1"))
+	if err != nil || authenticated.ID != account.ID {
+		t.Fatalf("AuthenticateClient() = %+v, %v; want %s", authenticated, err, account.ID)
+	}
+
+	// The assertion is used up for the token endpoint as well
+	if _, err := accounts.IssueToken(ctx, clientKey.sign(t, account.ID, key.ID, "jti-1"), ""); !errors.Is(err, domain.ErrInvalidClientAssertion) {
+		t.Errorf("IssueToken() with a used assertion: error = %v, want ErrInvalidClientAssertion", err)
+	}
+	if _, err := accounts.AuthenticateClient(ctx, "not-an-assertion"); !errors.Is(err, domain.ErrInvalidClientAssertion) {
+		t.Errorf("AuthenticateClient() with a malformed assertion: error = %v, want ErrInvalidClientAssertion", err)
+	}
+}
+
+func TestServiceAccountService_RotateKey(t *testing.T) {
+	accounts, repo, _ := newTestServiceAccountService(t)
+	ctx := context.Background()
+	now := time.Now()
+	accounts.now = func() time.Time { return now }
+
+	account, err := accounts.CreateAccount(ctx, "deployer", []string{"deploy"})
+	if err != nil {
+		t.Fatalf("CreateAccount() error = %v", err)
+	}
+	oldKey := newServiceAccountKey(t)
+	old, err := accounts.AddKey(ctx, account.ID, oldKey.jwk)
+	if err != nil {
+		t.Fatalf("AddKey() error = %v", err)
+	}
+	if _, err := accounts.AddKey(ctx, account.ID, oldKey.jwk); !errors.Is(err, domain.ErrServiceAccountKeyExists) {
+		t.Errorf("AddKey() twice error = %v, want ErrServiceAccountKeyExists", err)
+	}
+
+	newKey := newServiceAccountKey(t)
+	rotated, err := accounts.RotateKey(ctx, account.ID, old.ID, newKey.jwk, time.Hour)
+	if err != nil {
+		t.Fatalf("RotateKey() error = %v", err)
+	}
+	if rotated.ID == old.ID || !rotated.IsActive(now) {
+		t.Errorf("rotated key = %+v, want a new active key", rotated)
+	}
+
+	// The old key keeps working during the grace period
+	expired, _ := repo.GetKey(ctx, account.ID, old.ID)
+	if !expired.IsActive(now) || expired.IsActive(now.Add(time.Hour)) {
+		t.Errorf("old key expires at %v, want %v", expired.ExpiresAt, now.Add(time.Hour))
+	}
+	if _, err := accounts.IssueToken(ctx, oldKey.sign(t, account.ID, old.ID, "during-grace"), ""); err != nil {
+		t.Errorf("IssueToken() with the old key during grace = %v", err)
+	}
+
+	if _, err := accounts.AddKey(ctx, account.ID, newServiceAccountKey(t).jwk); err != nil {
+		t.Fatalf("AddKey() error = %v", err)
+	}
+	if _, err := accounts.AddKey(ctx, account.ID, newServiceAccountKey(t).jwk); !errors.Is(err, domain.ErrTooManyServiceAccountKeys) {
+		t.Errorf("AddKey() over the limit error = %v, want ErrTooManyServiceAccountKeys", err)
+	}
+
+	if _, err := accounts.AddKey(ctx, "sa_unknown", newKey.jwk); !errors.Is(err, domain.ErrServiceAccountNotFound) {
+		t.Errorf("AddKey() for unknown account error = %v, want ErrServiceAccountNotFound", err)
+	}
+	if _, err := accounts.AddKey(ctx, account.ID, map[string]interface{}{"kty": "oct", "k": "c2VjcmV0"}); !errors.Is(err, domain.ErrInvalidPublicKey) {
+		t.Errorf("AddKey() with a symmetric key error = %v, want ErrInvalidPublicKey", err)
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	scopes, err := normalizeScopes([]string{"reports:read", "reports:read", "deploy"})
+	if err != nil || strings.Join(scopes, " ") != "reports:read deploy" {
+		t.Errorf("normalizeScopes() = %v, %v", scopes, err)
+	}
+
+	for _, invalid := range []string{"", "two words", `quote"d`, "émoji"} {
+		if _, err := normalizeScopes([]string{invalid}); !errors.Is(err, domain.ErrInvalidScope) {
+			t.Errorf("normalizeScopes(%q) error = %v, want ErrInvalidScope", invalid, err)
+		}
+	}
+}
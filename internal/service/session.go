@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// Reasons for revoking all sessions of a user, recorded in the audit trail
+const (
+	RevokeReasonPasswordChange    = "password_change"
+	RevokeReasonAccountCompromise = "account_compromise"
+	RevokeReasonForceLogout       = "admin_force_logout"
+	RevokeReasonLogoutAll         = "logout_all"
+
+	// Privilege changes: tokens issued before them carry stale claims, and
+	// sessions started before them must not inherit the new privileges
+	RevokeReasonEmailVerified        = "email_verified"
+	RevokeReasonSecondFactorEnabled  = "second_factor_enabled"
+	RevokeReasonSecondFactorDisabled = "second_factor_disabled"
+)
+
+// SetTokenVersions puts the user's token version into access tokens.
+// RevokeSessions bumps it, which invalidates every access token issued
+// before wherever the version is checked (middleware.TokenVersion).
+func (s *AuthService) SetTokenVersions(versions repository.TokenVersionRepository) {
+	s.tokenVersions = versions
+}
+
+// SetAuditLog records logins, session revocations and session handoffs in
+// the audit trail
+func (s *AuthService) SetAuditLog(auditRepo repository.AuditLogRepository) {
+	s.auditRepo = auditRepo
+}
+
+// generateAccessToken issues an access token carrying the user's current
+// token version and access window
+func (s *AuthService) generateAccessToken(ctx context.Context, user *domain.User, cnf token.Confirmation) (string, error) {
+	var version int
+	if s.tokenVersions != nil {
+		var err error
+		if version, err = s.tokenVersions.Get(ctx, user.ID); err != nil {
+			return "", fmt.Errorf("failed to get token version: %w", err)
+		}
+	}
+	window, err := s.tokenAccessWindow(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	return s.tokenManager.GenerateAccessTokenWithOptions(user.ID, user.Email, user.EmailVerified, token.AccessTokenOptions{
+		Locale:       stringValue(user.Locale),
+		Confirmation: cnf,
+		Version:      version,
+		Metadata:     s.tokenMetadata(user),
+		Window:       window,
+	})
+}
+
+// RevokeSessions signs a user out everywhere: all refresh tokens are revoked
+// and, with token versions enabled, all outstanding access tokens stop
+// working immediately instead of when they expire
+func (s *AuthService) RevokeSessions(ctx context.Context, userID, reason string) error {
+	var version int
+	if s.tokenVersions != nil {
+		var err error
+		if version, err = s.tokenVersions.Increment(ctx, userID); err != nil {
+			return fmt.Errorf("failed to increment token version: %w", err)
+		}
+	}
+
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke all refresh tokens: %w", err)
+	}
+
+	s.auditSessionRevocation(ctx, userID, reason, version)
+	return nil
+}
+
+// auditSessionRevocation records a session revocation; failures are only
+// logged. version is the token version the revocation bumped to, or 0 when
+// access tokens stay valid until they expire.
+func (s *AuthService) auditSessionRevocation(ctx context.Context, userID, reason string, version int) {
+	if s.auditRepo == nil {
+		return
+	}
+	// The sessions are already revoked; record it even if the caller left
+	ctx, cancel := detach(ctx)
+	defer cancel()
+
+	resourceType := "user"
+	entry := domain.NewAuditLog(domain.AuditActionSessionsRevoked, domain.AuditStatusSuccess)
+	entry.UserID = &userID
+	entry.ResourceType = &resourceType
+	entry.ResourceID = &userID
+	entry.Metadata["reason"] = reason
+	if version > 0 {
+		entry.Metadata["token_version"] = version
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write session revocation audit log", "user_id", userID, "reason", reason, "error", err)
+	}
+}
+
+// ChangePasswordInput represents the input for a password change
+type ChangePasswordInput struct {
+	UserID          string
+	CurrentPassword string
+	NewPassword     string
+}
+
+// ChangePassword replaces the user's password after checking the current one
+// and signs the user out everywhere
+func (s *AuthService) ChangePassword(ctx context.Context, input ChangePasswordInput) error {
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	// The new verifier has to be derived with fresh params, which only a
+	// reset grant hands out; hashing it here would also silently move the
+	// account off client hashing
+	if _, _, ok := security.ParseClientHash(user.PasswordHash); ok {
+		return domain.ErrPasswordResetRequired
+	}
+
+	if err := s.passwordHasher.CompareContext(ctx, input.CurrentPassword, user.PasswordHash); err != nil {
+		if isCheckAborted(err) {
+			return err
+		}
+		return domain.ErrInvalidCredentials
+	}
+	if err := domain.ValidatePassword(input.NewPassword); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.passwordHasher.HashContext(ctx, input.NewPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = passwordHash
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	return s.RevokeSessions(ctx, user.ID, RevokeReasonPasswordChange)
+}
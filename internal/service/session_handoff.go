@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// SetSessionHandoff lets signed-in users start a session on another device
+// with a one-time code, e.g. shown as a QR code. Codes not exchanged within
+// ttl expire.
+func (s *AuthService) SetSessionHandoff(handoffs repository.SessionHandoffRepository, ttl time.Duration) {
+	s.sessionHandoffs = handoffs
+	s.sessionHandoffTTL = ttl
+}
+
+// SessionHandoffEnabled reports whether session handoffs are enabled
+func (s *AuthService) SessionHandoffEnabled() bool {
+	return s.sessionHandoffs != nil
+}
+
+// CreateSessionHandoffInput represents a signed-in device asking for a
+// handoff code
+type CreateSessionHandoffInput struct {
+	UserID    string
+	UserAgent *string
+	IPAddress *string
+}
+
+// CreateSessionHandoffOutput is a handoff code for another device
+type CreateSessionHandoffOutput struct {
+	Code      string
+	ExpiresIn int64 // seconds
+	ExpiresAt time.Time
+}
+
+// CreateSessionHandoff returns a one-time code another device exchanges for
+// its own session. Creating a code invalidates the user's previous one.
+func (s *AuthService) CreateSessionHandoff(ctx context.Context, input CreateSessionHandoffInput) (*CreateSessionHandoffOutput, error) {
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, domain.ErrAccountDisabled
+	}
+
+	code, err := security.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	handoff := &domain.SessionHandoff{
+		UserID:    user.ID,
+		CodeHash:  security.HashToken(code),
+		IPAddress: input.IPAddress,
+		UserAgent: input.UserAgent,
+		ExpiresAt: now.Add(s.sessionHandoffTTL),
+		CreatedAt: now,
+	}
+	if err := s.sessionHandoffs.Create(ctx, handoff); err != nil {
+		return nil, err
+	}
+	s.auditSessionHandoff(ctx, domain.AuditActionSessionHandoffCreate, handoff, input.UserAgent, input.IPAddress)
+
+	return &CreateSessionHandoffOutput{
+		Code:      code,
+		ExpiresIn: int64(s.sessionHandoffTTL.Seconds()),
+		ExpiresAt: handoff.ExpiresAt,
+	}, nil
+}
+
+// ExchangeSessionHandoffInput represents a new device presenting a handoff
+// code
+type ExchangeSessionHandoffInput struct {
+	Code           string
+	UserAgent      *string
+	IPAddress      *string
+	CertThumbprint string            // mTLS client certificate thumbprint; binds the access token when set
+	DPoP           token.DPoPRequest // DPoP proof; binds the tokens to its key when set
+}
+
+// ExchangeSessionHandoff starts a session for the device presenting a
+// handoff code. The session is separate from the one of the device that
+// created the code. Unknown, expired and used codes fail with
+// domain.ErrSessionHandoffNotFound.
+func (s *AuthService) ExchangeSessionHandoff(ctx context.Context, input ExchangeSessionHandoffInput) (*LoginOutput, error) {
+	cnf, err := s.confirmation(input.CertThumbprint, input.DPoP)
+	if err != nil {
+		return nil, err
+	}
+
+	// Concurrent exchanges race here; only one consumes the code
+	handoff, err := s.sessionHandoffs.Consume(ctx, security.HashToken(input.Code), s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, handoff.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return nil, domain.ErrAccountDisabled
+	}
+	s.recordActivity(ctx, user.ID)
+
+	output, err := s.issueLoginTokens(ctx, user, cnf, input.UserAgent, input.IPAddress, false)
+	if err != nil {
+		return nil, err
+	}
+	s.auditSessionHandoff(ctx, domain.AuditActionSessionHandoffExchange, handoff, input.UserAgent, input.IPAddress)
+	slog.InfoContext(ctx, "session handed off", "user_id", user.ID, "handoff_id", handoff.ID)
+
+	return output, nil
+}
+
+// auditSessionHandoff records a handoff created or exchanged by the device
+// with userAgent and ipAddress; failures are only logged. Exchanges also
+// record the device that created the code.
+func (s *AuthService) auditSessionHandoff(ctx context.Context, action string, handoff *domain.SessionHandoff, userAgent, ipAddress *string) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	resourceType := "session_handoff"
+	entry := domain.NewAuditLog(action, domain.AuditStatusSuccess)
+	entry.UserID = &handoff.UserID
+	entry.ResourceType = &resourceType
+	entry.ResourceID = &handoff.ID
+	entry.IPAddress = ipAddress
+	entry.UserAgent = userAgent
+	if action == domain.AuditActionSessionHandoffExchange {
+		entry.Metadata["created_by_ip_address"] = stringValue(handoff.IPAddress)
+		entry.Metadata["created_by_user_agent"] = stringValue(handoff.UserAgent)
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write session handoff audit log", "user_id", handoff.UserID, "action", action, "error", err)
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+type mockSessionHandoffRepository struct {
+	handoffs map[string]*domain.SessionHandoff // by code hash
+	nextID   int
+}
+
+func newMockSessionHandoffRepository() *mockSessionHandoffRepository {
+	return &mockSessionHandoffRepository{handoffs: make(map[string]*domain.SessionHandoff)}
+}
+
+func (m *mockSessionHandoffRepository) Create(ctx context.Context, handoff *domain.SessionHandoff) error {
+	for hash, existing := range m.handoffs {
+		if existing.UserID == handoff.UserID {
+			delete(m.handoffs, hash)
+		}
+	}
+	m.nextID++
+	handoff.ID = testUUID(m.nextID)
+	m.handoffs[handoff.CodeHash] = handoff
+	return nil
+}
+
+func (m *mockSessionHandoffRepository) Consume(ctx context.Context, codeHash string, now time.Time) (*domain.SessionHandoff, error) {
+	handoff, ok := m.handoffs[codeHash]
+	if !ok || !now.Before(handoff.ExpiresAt) {
+		return nil, domain.ErrSessionHandoffNotFound
+	}
+	delete(m.handoffs, codeHash)
+	return handoff, nil
+}
+
+func TestAuthService_SessionHandoff(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	service.SetSessionHandoff(newMockSessionHandoffRepository(), time.Minute)
+	auditRepo := &mockAuditLogRepository{}
+	service.SetAuditLog(auditRepo)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "handoff@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	phone := "203.0.113.7"
+	created, err := service.CreateSessionHandoff(ctx, CreateSessionHandoffInput{UserID: signup.UserID, IPAddress: &phone})
+	if err != nil {
+		t.Fatalf("CreateSessionHandoff() error = %v", err)
+	}
+	if created.Code == "" || created.ExpiresIn != 60 {
+		t.Fatalf("CreateSessionHandoff() = %+v", created)
+	}
+
+	if _, err := service.ExchangeSessionHandoff(ctx, ExchangeSessionHandoffInput{Code: "wrong"}); !errors.Is(err, domain.ErrSessionHandoffNotFound) {
+		t.Fatalf("ExchangeSessionHandoff() with wrong code error = %v, want %v", err, domain.ErrSessionHandoffNotFound)
+	}
+
+	tv := "198.51.100.2"
+	output, err := service.ExchangeSessionHandoff(ctx, ExchangeSessionHandoffInput{Code: created.Code, IPAddress: &tv})
+	if err != nil {
+		t.Fatalf("ExchangeSessionHandoff() error = %v", err)
+	}
+	if output.AccessToken == "" || output.RefreshToken == "" || output.userID != signup.UserID {
+		t.Error("ExchangeSessionHandoff() returned no session for the user")
+	}
+
+	// Codes are exchanged once
+	if _, err := service.ExchangeSessionHandoff(ctx, ExchangeSessionHandoffInput{Code: created.Code}); !errors.Is(err, domain.ErrSessionHandoffNotFound) {
+		t.Fatalf("second ExchangeSessionHandoff() error = %v, want %v", err, domain.ErrSessionHandoffNotFound)
+	}
+
+	var exchanged *domain.AuditLog
+	for _, log := range auditRepo.logs {
+		if log.Action == domain.AuditActionSessionHandoffExchange {
+			exchanged = log
+		}
+	}
+	if exchanged == nil {
+		t.Fatal("handoff exchange was not audited")
+	}
+	if *exchanged.IPAddress != tv || exchanged.Metadata["created_by_ip_address"] != phone {
+		t.Errorf("audit entry = ip %s, metadata %v; want the new device and the creating device", *exchanged.IPAddress, exchanged.Metadata)
+	}
+}
+
+func TestAuthService_SessionHandoffReplacesCode(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	service.SetSessionHandoff(newMockSessionHandoffRepository(), time.Minute)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "replace@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	first, err := service.CreateSessionHandoff(ctx, CreateSessionHandoffInput{UserID: signup.UserID})
+	if err != nil {
+		t.Fatalf("CreateSessionHandoff() error = %v", err)
+	}
+	second, err := service.CreateSessionHandoff(ctx, CreateSessionHandoffInput{UserID: signup.UserID})
+	if err != nil {
+		t.Fatalf("CreateSessionHandoff() error = %v", err)
+	}
+
+	if _, err := service.ExchangeSessionHandoff(ctx, ExchangeSessionHandoffInput{Code: first.Code}); !errors.Is(err, domain.ErrSessionHandoffNotFound) {
+		t.Errorf("ExchangeSessionHandoff() with replaced code error = %v, want %v", err, domain.ErrSessionHandoffNotFound)
+	}
+	if _, err := service.ExchangeSessionHandoff(ctx, ExchangeSessionHandoffInput{Code: second.Code}); err != nil {
+		t.Errorf("ExchangeSessionHandoff() error = %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// SetSessionStats enables session analytics computed by stats
+func (s *AuthService) SetSessionStats(stats repository.SessionStatsReader) {
+	s.sessionStats = stats
+}
+
+// SessionStatsEnabled reports whether session analytics are available
+func (s *AuthService) SessionStatsEnabled() bool {
+	return s.sessionStats != nil
+}
+
+// SessionStats aggregates the active sessions of userID, or of every user
+// when userID is empty: how many there are, how old they are on average and
+// which devices they were started on
+func (s *AuthService) SessionStats(ctx context.Context, userID string) (*domain.SessionStats, error) {
+	if userID != "" {
+		if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	stats, err := s.sessionStats.SessionStats(ctx, userID, s.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute session stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockSessionStatsReader returns fixed stats and records what it was asked for
+type mockSessionStatsReader struct {
+	userID string
+	now    time.Time
+}
+
+func (m *mockSessionStatsReader) SessionStats(ctx context.Context, userID string, now time.Time) (*domain.SessionStats, error) {
+	m.userID, m.now = userID, now
+	return &domain.SessionStats{
+		ActiveSessions: 3,
+		Users:          1,
+		AverageAge:     time.Hour,
+		Devices:        map[string]int{domain.DeviceTypeDesktop: 2, domain.DeviceTypeMobile: 1},
+	}, nil
+}
+
+func TestAuthService_SessionStats(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	ctx := context.Background()
+	if service.SessionStatsEnabled() {
+		t.Fatal("SessionStatsEnabled() = true before SetSessionStats")
+	}
+
+	reader := &mockSessionStatsReader{}
+	service.SetSessionStats(reader)
+	if !service.SessionStatsEnabled() {
+		t.Fatal("SessionStatsEnabled() = false after SetSessionStats")
+	}
+
+	stats, err := service.SessionStats(ctx, "")
+	if err != nil {
+		t.Fatalf("SessionStats() error = %v", err)
+	}
+	if stats.ActiveSessions != 3 || reader.userID != "" || reader.now.IsZero() {
+		t.Errorf("SessionStats() = %+v, asked for user %q at %v", stats, reader.userID, reader.now)
+	}
+
+	user := &domain.User{ID: testUUID(1), Email: "stats@example.com"}
+	userRepo.users[user.Email] = user
+	if _, err := service.SessionStats(ctx, user.ID); err != nil {
+		t.Fatalf("SessionStats() for a user error = %v", err)
+	}
+	if reader.userID != user.ID {
+		t.Errorf("stats asked for user %q, want %q", reader.userID, user.ID)
+	}
+
+	if _, err := service.SessionStats(ctx, testUUID(2)); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("SessionStats() for an unknown user error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockTokenVersionRepository keeps token versions in memory
+type mockTokenVersionRepository struct {
+	versions map[string]int
+}
+
+func (m *mockTokenVersionRepository) Get(ctx context.Context, userID string) (int, error) {
+	return m.versions[userID], nil
+}
+
+func (m *mockTokenVersionRepository) Increment(ctx context.Context, userID string) (int, error) {
+	m.versions[userID]++
+	return m.versions[userID], nil
+}
+
+func TestAuthService_RevokeSessions(t *testing.T) {
+	service, _, refreshRepo := createTestAuthService(t)
+	versions := &mockTokenVersionRepository{versions: make(map[string]int)}
+	audit := &mockAuditLogRepository{}
+	service.SetTokenVersions(versions)
+	service.SetAuditLog(audit)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "revoke@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	login, err := service.Login(ctx, LoginInput{Email: "revoke@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if err := service.RevokeSessions(ctx, signup.UserID, RevokeReasonAccountCompromise); err != nil {
+		t.Fatalf("RevokeSessions() error = %v", err)
+	}
+
+	if versions.versions[signup.UserID] != 1 {
+		t.Errorf("token version = %d, want 1", versions.versions[signup.UserID])
+	}
+	if token := refreshRepo.tokens[login.RefreshToken]; !token.Revoked {
+		t.Error("refresh token was not revoked")
+	}
+	if revocations := audit.revocations(); len(revocations) != 1 ||
+		revocations[0].Metadata["reason"] != RevokeReasonAccountCompromise {
+		t.Errorf("unexpected audit logs: %+v", audit.logs)
+	}
+
+	// Tokens issued afterwards carry the new version
+	login, err = service.Login(ctx, LoginInput{Email: "revoke@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	claims, err := service.tokenManager.ValidateAccessToken(login.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.TokenVersion != 1 {
+		t.Errorf("access token version = %d, want 1", claims.TokenVersion)
+	}
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	versions := &mockTokenVersionRepository{versions: make(map[string]int)}
+	service.SetTokenVersions(versions)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "change@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		input       ChangePasswordInput
+		wantErr     error
+		wantVersion int
+	}{
+		{
+			name:    "wrong current password",
+			input:   ChangePasswordInput{UserID: signup.UserID, CurrentPassword: "wrong-password", NewPassword: "new-password123"},
+			wantErr: domain.ErrInvalidCredentials,
+		},
+		{
+			name:    "weak new password",
+			input:   ChangePasswordInput{UserID: signup.UserID, CurrentPassword: "password123", NewPassword: "short"},
+			wantErr: domain.ErrWeakPassword,
+		},
+		{
+			name:        "valid change",
+			input:       ChangePasswordInput{UserID: signup.UserID, CurrentPassword: "password123", NewPassword: "new-password123"},
+			wantVersion: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ChangePassword(ctx, tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangePassword() error = %v, want %v", err, tt.wantErr)
+			}
+			if versions.versions[signup.UserID] != tt.wantVersion {
+				t.Errorf("token version = %d, want %d", versions.versions[signup.UserID], tt.wantVersion)
+			}
+		})
+	}
+
+	// Only the new password works
+	if _, err := service.Login(ctx, LoginInput{Email: "change@example.com", Password: "password123"}); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("Login() with old password error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+	if _, err := service.Login(ctx, LoginInput{Email: "change@example.com", Password: "new-password123"}); err != nil {
+		t.Errorf("Login() with new password error = %v", err)
+	}
+}
+
+func TestAuthService_VerifyEmailRevokesSessions(t *testing.T) {
+	service, _, refreshRepo := createTestAuthService(t)
+	versions := &mockTokenVersionRepository{versions: make(map[string]int)}
+	audit := &mockAuditLogRepository{}
+	service.SetTokenVersions(versions)
+	service.SetAuditLog(audit)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "fixation@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	login, err := service.Login(ctx, LoginInput{Email: "fixation@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	input := VerifyEmailInput{Email: "fixation@example.com", Token: signup.EmailVerificationToken}
+	if err := service.VerifyEmail(ctx, input); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+
+	if token := refreshRepo.tokens[login.RefreshToken]; !token.Revoked {
+		t.Error("refresh token issued before verification was not revoked")
+	}
+	if versions.versions[signup.UserID] != 1 {
+		t.Errorf("token version = %d, want 1", versions.versions[signup.UserID])
+	}
+	if revocations := audit.revocations(); len(revocations) != 1 || revocations[0].Metadata["reason"] != RevokeReasonEmailVerified {
+		t.Errorf("unexpected audit logs: %+v", audit.logs)
+	}
+
+	// Verifying again changes nothing
+	if err := service.VerifyEmail(ctx, input); err != nil {
+		t.Fatalf("second VerifyEmail() error = %v", err)
+	}
+	if versions.versions[signup.UserID] != 1 {
+		t.Errorf("token version after second verification = %d, want 1", versions.versions[signup.UserID])
+	}
+}
+
+func TestAuthService_SecondFactorRevokesSessions(t *testing.T) {
+	service, sender := createTestAuthServiceWithSMS(t)
+	versions := &mockTokenVersionRepository{versions: make(map[string]int)}
+	audit := &mockAuditLogRepository{}
+	service.SetTokenVersions(versions)
+	service.SetAuditLog(audit)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "factor@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if err := service.AddPhoneNumber(ctx, signup.UserID, "+15551234567"); err != nil {
+		t.Fatalf("AddPhoneNumber() error = %v", err)
+	}
+	if err := service.VerifyPhoneNumber(ctx, signup.UserID, lastSMSCode(t, sender)); err != nil {
+		t.Fatalf("VerifyPhoneNumber() error = %v", err)
+	}
+
+	steps := []struct {
+		enabled    bool
+		wantReason string
+	}{
+		{true, RevokeReasonSecondFactorEnabled},
+		{true, ""}, // unchanged
+		{false, RevokeReasonSecondFactorDisabled},
+		{true, RevokeReasonSecondFactorEnabled},
+	}
+	var reasons []string
+	for _, step := range steps {
+		if err := service.SetSMSSecondFactor(ctx, signup.UserID, step.enabled); err != nil {
+			t.Fatalf("SetSMSSecondFactor(%v) error = %v", step.enabled, err)
+		}
+		if step.wantReason != "" {
+			reasons = append(reasons, step.wantReason)
+		}
+	}
+
+	// A new phone number turns the second factor off too
+	if err := service.AddPhoneNumber(ctx, signup.UserID, "+15557654321"); err != nil {
+		t.Fatalf("AddPhoneNumber() error = %v", err)
+	}
+	reasons = append(reasons, RevokeReasonSecondFactorDisabled)
+
+	if versions.versions[signup.UserID] != len(reasons) {
+		t.Errorf("token version = %d, want %d", versions.versions[signup.UserID], len(reasons))
+	}
+	if len(audit.logs) != len(reasons) {
+		t.Fatalf("got %d audit logs, want %d", len(audit.logs), len(reasons))
+	}
+	for i, reason := range reasons {
+		if audit.logs[i].Metadata["reason"] != reason {
+			t.Errorf("audit log %d reason = %v, want %s", i, audit.logs[i].Metadata["reason"], reason)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/sms"
+)
+
+// SetSMS enables phone numbers verified by SMS, which users can then use as
+// a second factor and to recover their account. appName prefixes the
+// messages; codes are stored hashed like email codes.
+func (s *AuthService) SetSMS(sender sms.Sender, codes repository.EmailCodeRepository, appName string, policy CodePolicy) {
+	s.smsSender = sender
+	s.smsCodes = codes
+	s.smsAppName = appName
+	s.smsCodePolicy = policy
+}
+
+// SMSEnabled reports whether SMS codes can be sent
+func (s *AuthService) SMSEnabled() bool {
+	return s.smsSender != nil
+}
+
+// sendSMSCode issues a code for the purpose and texts it to the user's phone
+func (s *AuthService) sendSMSCode(ctx context.Context, user *domain.User, purpose domain.EmailCodePurpose) error {
+	code, err := s.issueCode(ctx, s.smsCodes, s.smsCodePolicy, user.ID, purpose)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("%s code: %s. It expires in %d minutes. Never share it.",
+		s.smsAppName, code, int(s.smsCodePolicy.TTL/time.Minute))
+	if err := s.smsSender.Send(ctx, sms.Message{To: *user.PhoneNumber, Body: body}); err != nil {
+		return fmt.Errorf("failed to send SMS code: %w", err)
+	}
+	return nil
+}
+
+// AddPhoneNumber sets the user's phone number and texts it a verification
+// code. The number stays unverified, and SMS second factor off, until the
+// code is confirmed with VerifyPhoneNumber.
+func (s *AuthService) AddPhoneNumber(ctx context.Context, userID, phone string) error {
+	if err := domain.ValidatePhoneNumber(phone); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	hadSecondFactor := user.SMSSecondFactor
+	user.SetPhoneNumber(phone)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if hadSecondFactor && !user.SMSSecondFactor {
+		revokeCtx, cancel := detach(ctx)
+		defer cancel()
+		if err := s.RevokeSessions(revokeCtx, user.ID, RevokeReasonSecondFactorDisabled); err != nil {
+			return err
+		}
+	}
+
+	return s.sendSMSCode(ctx, user, domain.SMSCodePhoneVerification)
+}
+
+// VerifyPhoneNumber confirms the user's phone number with the code texted to it
+func (s *AuthService) VerifyPhoneNumber(ctx context.Context, userID, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PhoneNumber == nil {
+		return domain.ErrInvalidEmailCode
+	}
+
+	if err := s.checkCode(ctx, s.smsCodes, s.smsCodePolicy, user.ID, domain.SMSCodePhoneVerification, code); err != nil {
+		return err
+	}
+
+	user.MarkPhoneVerified()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// SetSMSSecondFactor turns the SMS second factor on or off. Turning it on
+// needs a verified phone number. A change signs the user out everywhere, so
+// no session outlives the factor it was (or was not) started with.
+func (s *AuthService) SetSMSSecondFactor(ctx context.Context, userID string, enabled bool) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if enabled && !user.HasVerifiedPhone() {
+		return domain.ErrPhoneNotVerified
+	}
+
+	if user.SMSSecondFactor == enabled {
+		return nil
+	}
+
+	user.SMSSecondFactor = enabled
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	reason := RevokeReasonSecondFactorDisabled
+	if enabled {
+		reason = RevokeReasonSecondFactorEnabled
+	}
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	return s.RevokeSessions(ctx, user.ID, reason)
+}
+
+// checkSMSSecondFactor completes the login of a user with SMS second factor.
+// Without a code, one is texted and ErrSMSCodeRequired returned so the
+// client can repeat the login with it.
+func (s *AuthService) checkSMSSecondFactor(ctx context.Context, user *domain.User, code string) error {
+	if s.smsSender == nil || !user.SMSSecondFactor || !user.HasVerifiedPhone() {
+		return nil
+	}
+
+	if code == "" {
+		if err := s.sendSMSCode(ctx, user, domain.SMSCodeLogin); err != nil {
+			return err
+		}
+		return domain.ErrSMSCodeRequired
+	}
+	return s.checkCode(ctx, s.smsCodes, s.smsCodePolicy, user.ID, domain.SMSCodeLogin, code)
+}
+
+// RequestSMSRecovery texts a password reset code to the verified phone of
+// the account. Nothing is reported for unknown accounts or accounts without
+// a verified phone, so the endpoint cannot be used to find either.
+func (s *AuthService) RequestSMSRecovery(ctx context.Context, email string) error {
+	defer s.padResponse(ctx, s.clock.Now())
+
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			slog.InfoContext(ctx, "sms recovery suppressed", "reason", "unknown_email")
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsDisabled() || !user.HasVerifiedPhone() {
+		slog.InfoContext(ctx, "sms recovery suppressed", "reason", "no_verified_phone", "user_id", user.ID)
+		return nil
+	}
+
+	return s.sendSMSCode(ctx, user, domain.SMSCodeRecovery)
+}
+
+// RecoverWithSMS replaces the user's password after checking the recovery
+// code texted to their phone and signs the user out everywhere
+func (s *AuthService) RecoverWithSMS(ctx context.Context, input ResetPasswordInput) error {
+	// Reject weak passwords before spending an attempt on the code
+	if err := domain.ValidatePassword(input.NewPassword); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, s.normalizeEmail(input.Email))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrInvalidEmailCode
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.checkCode(ctx, s.smsCodes, s.smsCodePolicy, user.ID, domain.SMSCodeRecovery, input.Code); err != nil {
+		return err
+	}
+
+	return s.resetPassword(ctx, user, input.NewPassword)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/sms"
+)
+
+var smsCodeRegex = regexp.MustCompile(`\b[0-9]{6}\b`)
+
+// lastSMSCode returns the code in the last message sent
+func lastSMSCode(t *testing.T, sender *sms.MockSender) string {
+	t.Helper()
+	sent := sender.Sent()
+	if len(sent) == 0 {
+		t.Fatal("no SMS sent")
+	}
+	code := smsCodeRegex.FindString(sent[len(sent)-1].Body)
+	if code == "" {
+		t.Fatalf("no code in SMS %q", sent[len(sent)-1].Body)
+	}
+	return code
+}
+
+func createTestAuthServiceWithSMS(t *testing.T) (*AuthService, *sms.MockSender) {
+	service, _, _ := createTestAuthService(t)
+	sender := sms.NewMockSender()
+	service.SetSMS(sender, newMockEmailCodeRepository(), "Test App", CodePolicy{TTL: 5 * time.Minute, MaxAttempts: 3})
+	return service, sender
+}
+
+func TestAuthService_PhoneVerificationAndSecondFactor(t *testing.T) {
+	service, sender := createTestAuthServiceWithSMS(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "phone@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	if err := service.AddPhoneNumber(ctx, signup.UserID, "555-1234"); !errors.Is(err, domain.ErrInvalidPhoneNumber) {
+		t.Fatalf("AddPhoneNumber() error = %v, want %v", err, domain.ErrInvalidPhoneNumber)
+	}
+	if err := service.AddPhoneNumber(ctx, signup.UserID, "+15551234567"); err != nil {
+		t.Fatalf("AddPhoneNumber() error = %v", err)
+	}
+	if to := sender.Sent()[0].To; to != "+15551234567" {
+		t.Errorf("SMS sent to %q", to)
+	}
+
+	// The second factor needs a verified phone
+	if err := service.SetSMSSecondFactor(ctx, signup.UserID, true); !errors.Is(err, domain.ErrPhoneNotVerified) {
+		t.Fatalf("SetSMSSecondFactor() error = %v, want %v", err, domain.ErrPhoneNotVerified)
+	}
+	if err := service.VerifyPhoneNumber(ctx, signup.UserID, lastSMSCode(t, sender)); err != nil {
+		t.Fatalf("VerifyPhoneNumber() error = %v", err)
+	}
+	if err := service.SetSMSSecondFactor(ctx, signup.UserID, true); err != nil {
+		t.Fatalf("SetSMSSecondFactor() error = %v", err)
+	}
+
+	login := LoginInput{Email: "phone@example.com", Password: "password123"}
+	if _, err := service.Login(ctx, login); !errors.Is(err, domain.ErrSMSCodeRequired) {
+		t.Fatalf("Login() without code error = %v, want %v", err, domain.ErrSMSCodeRequired)
+	}
+	code := lastSMSCode(t, sender)
+
+	login.SMSCode = "000000"
+	if code == login.SMSCode {
+		login.SMSCode = "111111"
+	}
+	if _, err := service.Login(ctx, login); !errors.Is(err, domain.ErrInvalidEmailCode) {
+		t.Fatalf("Login() with wrong code error = %v, want %v", err, domain.ErrInvalidEmailCode)
+	}
+
+	login.SMSCode = code
+	if _, err := service.Login(ctx, login); err != nil {
+		t.Fatalf("Login() with code error = %v", err)
+	}
+}
+
+func TestAuthService_RecoverWithSMS(t *testing.T) {
+	service, sender := createTestAuthServiceWithSMS(t)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "recover@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	// Accounts without a verified phone get no message, and no error
+	if err := service.RequestSMSRecovery(ctx, "recover@example.com"); err != nil {
+		t.Fatalf("RequestSMSRecovery() error = %v", err)
+	}
+	if len(sender.Sent()) != 0 {
+		t.Fatal("recovery SMS sent without a verified phone")
+	}
+
+	if err := service.AddPhoneNumber(ctx, signup.UserID, "+15551234567"); err != nil {
+		t.Fatalf("AddPhoneNumber() error = %v", err)
+	}
+	if err := service.VerifyPhoneNumber(ctx, signup.UserID, lastSMSCode(t, sender)); err != nil {
+		t.Fatalf("VerifyPhoneNumber() error = %v", err)
+	}
+
+	if err := service.RequestSMSRecovery(ctx, "recover@example.com"); err != nil {
+		t.Fatalf("RequestSMSRecovery() error = %v", err)
+	}
+	err = service.RecoverWithSMS(ctx, ResetPasswordInput{
+		Email:       "recover@example.com",
+		Code:        lastSMSCode(t, sender),
+		NewPassword: "new-password123",
+	})
+	if err != nil {
+		t.Fatalf("RecoverWithSMS() error = %v", err)
+	}
+
+	if _, err := service.Login(ctx, LoginInput{Email: "recover@example.com", Password: "new-password123"}); err != nil {
+		t.Errorf("Login() with new password error = %v", err)
+	}
+}
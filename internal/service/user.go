@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/emailnorm"
+	"github.com/n1rocket/go-auth-jwt/internal/ids"
 	"github.com/n1rocket/go-auth-jwt/internal/repository"
 	"github.com/n1rocket/go-auth-jwt/internal/security"
 )
@@ -14,6 +16,8 @@ import (
 type UserService struct {
 	userRepo       repository.UserRepository
 	passwordHasher *security.PasswordHasher
+	normalizer     emailnorm.Normalizer
+	idGenerator    ids.Generator
 }
 
 // NewUserService creates a new user service
@@ -27,10 +31,29 @@ func NewUserService(
 	}
 }
 
+// SetEmailNormalizer changes how emails are canonicalized for lookups
+func (s *UserService) SetEmailNormalizer(normalizer emailnorm.Normalizer) {
+	s.normalizer = normalizer
+}
+
+// SetIDGenerator makes CreateUser assign user ids before insert
+func (s *UserService) SetIDGenerator(generator ids.Generator) {
+	s.idGenerator = generator
+}
+
 // CreateUser creates a new user
 func (s *UserService) CreateUser(ctx context.Context, email, password string) (*domain.User, error) {
+	email, err := emailnorm.ToASCII(email)
+	if err != nil {
+		return nil, err
+	}
+	normalizedEmail, err := s.normalizer.Normalize(email)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
-	existingUser, err := s.userRepo.GetByEmail(ctx, email)
+	existingUser, err := s.userRepo.GetByEmail(ctx, normalizedEmail)
 	if err == nil && existingUser != nil {
 		return nil, fmt.Errorf("user with email %s already exists", email)
 	}
@@ -43,10 +66,16 @@ func (s *UserService) CreateUser(ctx context.Context, email, password string) (*
 
 	// Create user
 	user := &domain.User{
-		Email:        email,
-		PasswordHash: hashedPassword,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		Email:           email,
+		NormalizedEmail: normalizedEmail,
+		PasswordHash:    hashedPassword,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if s.idGenerator != nil {
+		if user.ID, err = s.idGenerator.NewID(); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
@@ -70,7 +99,12 @@ func (s *UserService) GetUserByID(ctx context.Context, userID string) (*domain.U
 
 // GetUserByEmail retrieves a user by email
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
-	user, err := s.userRepo.GetByEmail(ctx, email)
+	normalizedEmail, err := s.normalizer.Normalize(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, normalizedEmail)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
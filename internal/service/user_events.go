@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// UserEventTypes are the user events that are delivered and can be replayed
+var UserEventTypes = []string{domain.UserEventEmailVerified}
+
+// userEventNotifyTimeout bounds the delivery of one user event
+const userEventNotifyTimeout = 30 * time.Second
+
+// UserEventPayload is the body of a user event webhook
+type UserEventPayload struct {
+	ID         string    `json:"id"` // same in replays, so receivers can drop duplicates
+	Type       string    `json:"type"`
+	UserID     string    `json:"user_id"`
+	Email      string    `json:"email"`
+	Method     string    `json:"method,omitempty"` // how the email was verified: link or code
+	OccurredAt time.Time `json:"occurred_at"`      // when the email was verified
+	Replayed   bool      `json:"replayed,omitempty"`
+}
+
+// UserEventNotifier delivers user events to a downstream system
+type UserEventNotifier interface {
+	NotifyUserEvent(ctx context.Context, event UserEventPayload) error
+}
+
+// SetUserEvents records user events in repo and delivers them to notifier,
+// in the background so requests are never held up by the receiver. Missed
+// deliveries are sent again with ReplayUserEvents.
+func (s *AuthService) SetUserEvents(repo repository.UserEventRepository, notifier UserEventNotifier) {
+	s.userEvents = repo
+	s.userEventNotifier = notifier
+}
+
+// UserEventsEnabled reports whether user events are delivered
+func (s *AuthService) UserEventsEnabled() bool {
+	return s.userEvents != nil && s.userEventNotifier != nil
+}
+
+// emitUserEvent records an event about user and delivers it. Failures are
+// logged; a stored event can be replayed later.
+func (s *AuthService) emitUserEvent(ctx context.Context, user *domain.User, eventType string, data map[string]interface{}) {
+	if !s.UserEventsEnabled() {
+		return
+	}
+
+	event := &domain.UserEvent{Type: eventType, UserID: user.ID, Email: user.Email, Data: data}
+	if err := s.userEvents.Create(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "failed to record user event", "type", eventType, "user_id", user.ID, "error", err)
+		event.OccurredAt = s.clock.Now()
+	}
+	payload := newUserEventPayload(event)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), userEventNotifyTimeout)
+		defer cancel()
+
+		if err := s.userEventNotifier.NotifyUserEvent(ctx, payload); err != nil {
+			slog.ErrorContext(ctx, "failed to deliver user event",
+				"id", payload.ID,
+				"type", payload.Type,
+				"user_id", payload.UserID,
+				"error", err,
+			)
+		}
+	}()
+}
+
+// ReplayUserEventsInput selects the events to deliver again
+type ReplayUserEventsInput struct {
+	Type    string
+	From    time.Time // zero for no lower bound
+	To      time.Time // exclusive; zero for no upper bound
+	AfterID int64     // resume after this event
+	Limit   int
+}
+
+// ReplayUserEventsOutput reports a replay. Pass LastID as AfterID to
+// continue when More is set.
+type ReplayUserEventsOutput struct {
+	Replayed int
+	LastID   int64
+	More     bool
+}
+
+// ReplayUserEvents delivers stored events again, in order, for receivers
+// that missed them. It stops at the first failed delivery and returns the
+// progress so far together with the error.
+func (s *AuthService) ReplayUserEvents(ctx context.Context, input ReplayUserEventsInput) (*ReplayUserEventsOutput, error) {
+	// One more than the limit tells whether there are more
+	events, err := s.userEvents.List(ctx, input.Type, input.From, input.To, input.AfterID, input.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ReplayUserEventsOutput{LastID: input.AfterID, More: len(events) > input.Limit}
+	if output.More {
+		events = events[:input.Limit]
+	}
+	for _, event := range events {
+		payload := newUserEventPayload(event)
+		payload.Replayed = true
+		if err := s.userEventNotifier.NotifyUserEvent(ctx, payload); err != nil {
+			output.More = true
+			return output, fmt.Errorf("failed to replay user event %d: %w", event.ID, err)
+		}
+		output.Replayed++
+		output.LastID = event.ID
+	}
+	return output, nil
+}
+
+// newUserEventPayload builds the webhook body of a stored event
+func newUserEventPayload(event *domain.UserEvent) UserEventPayload {
+	payload := UserEventPayload{
+		Type:       event.Type,
+		UserID:     event.UserID,
+		Email:      event.Email,
+		OccurredAt: event.OccurredAt.UTC(),
+	}
+	if event.ID != 0 {
+		payload.ID = strconv.FormatInt(event.ID, 10)
+	}
+	if method, ok := event.Data["method"].(string); ok {
+		payload.Method = method
+	}
+	return payload
+}
+
+// WebhookUserEventNotifier posts user events as JSON to a webhook, signed
+// like quota webhooks. The signed timestamp is the delivery time, so
+// replayed events pass the receiver's freshness check.
+type WebhookUserEventNotifier struct {
+	client *httpclient.Client
+	url    string
+	secret []byte
+}
+
+// NewWebhookUserEventNotifier creates a notifier posting to url
+func NewWebhookUserEventNotifier(client *httpclient.Client, url, secret string) *WebhookUserEventNotifier {
+	return &WebhookUserEventNotifier{client: client, url: url, secret: []byte(secret)}
+}
+
+// NotifyUserEvent posts the event to the webhook
+func (n *WebhookUserEventNotifier) NotifyUserEvent(ctx context.Context, event UserEventPayload) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode user event: %w", err)
+	}
+	return postWebhook(ctx, n.client, n.url, n.secret, time.Now(), body)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockUserEventRepository keeps events in memory
+type mockUserEventRepository struct {
+	mu     sync.Mutex
+	events []*domain.UserEvent
+}
+
+func (m *mockUserEventRepository) Create(ctx context.Context, event *domain.UserEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	event.ID = int64(len(m.events) + 1)
+	event.OccurredAt = time.Now()
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *mockUserEventRepository) List(ctx context.Context, eventType string, from, to time.Time, afterID int64, limit int) ([]*domain.UserEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []*domain.UserEvent
+	for _, event := range m.events {
+		if event.Type != eventType || event.ID <= afterID || event.OccurredAt.Before(from) || (!to.IsZero() && !event.OccurredAt.Before(to)) {
+			continue
+		}
+		if len(events) == limit {
+			break
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// channelUserEventNotifier hands delivered events to the test and fails
+// while failing is set
+type channelUserEventNotifier struct {
+	events  chan UserEventPayload
+	mu      sync.Mutex
+	failing bool
+}
+
+func (n *channelUserEventNotifier) NotifyUserEvent(ctx context.Context, event UserEventPayload) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.failing {
+		return errors.New("receiver unavailable")
+	}
+	n.events <- event
+	return nil
+}
+
+func TestAuthService_UserEvents(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	repo := &mockUserEventRepository{}
+	notifier := &channelUserEventNotifier{events: make(chan UserEventPayload, 10)}
+	service.SetUserEvents(repo, notifier)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "events@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if err := service.VerifyEmail(ctx, VerifyEmailInput{Email: "events@example.com", Token: signup.EmailVerificationToken}); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+
+	select {
+	case event := <-notifier.events:
+		if event.ID != "1" || event.Type != domain.UserEventEmailVerified || event.UserID != signup.UserID ||
+			event.Method != domain.EmailVerificationLink || event.OccurredAt.IsZero() || event.Replayed {
+			t.Errorf("delivered event = %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("user.email_verified event was not delivered")
+	}
+
+	// Verifying again emits nothing
+	if err := service.VerifyEmail(ctx, VerifyEmailInput{Email: "events@example.com", Token: signup.EmailVerificationToken}); err != nil {
+		t.Fatalf("VerifyEmail() again error = %v", err)
+	}
+	if len(repo.events) != 1 {
+		t.Errorf("recorded %d events, want 1", len(repo.events))
+	}
+
+	// More events for the replay
+	for _, email := range []string{"second@example.com", "third@example.com"} {
+		repo.Create(ctx, &domain.UserEvent{Type: domain.UserEventEmailVerified, UserID: "user-" + email, Email: email,
+			Data: map[string]interface{}{"method": domain.EmailVerificationCode}})
+	}
+
+	output, err := service.ReplayUserEvents(ctx, ReplayUserEventsInput{Type: domain.UserEventEmailVerified, Limit: 2})
+	if err != nil {
+		t.Fatalf("ReplayUserEvents() error = %v", err)
+	}
+	if output.Replayed != 2 || output.LastID != 2 || !output.More {
+		t.Errorf("ReplayUserEvents() = %+v, want 2 replayed and more", output)
+	}
+	for i := 0; i < 2; i++ {
+		if event := <-notifier.events; !event.Replayed {
+			t.Errorf("replayed event %+v not marked as replayed", event)
+		}
+	}
+
+	// A failed delivery stops the replay where it can be resumed
+	notifier.failing = true
+	output, err = service.ReplayUserEvents(ctx, ReplayUserEventsInput{Type: domain.UserEventEmailVerified, AfterID: 2, Limit: 2})
+	if err == nil || output.Replayed != 0 || output.LastID != 2 || !output.More {
+		t.Errorf("ReplayUserEvents() with a failing receiver = %+v, %v", output, err)
+	}
+	notifier.failing = false
+	output, err = service.ReplayUserEvents(ctx, ReplayUserEventsInput{Type: domain.UserEventEmailVerified, AfterID: output.LastID, Limit: 2})
+	if err != nil || output.Replayed != 1 || output.LastID != 3 || output.More {
+		t.Errorf("resumed ReplayUserEvents() = %+v, %v", output, err)
+	}
+	if event := <-notifier.events; event.Method != domain.EmailVerificationCode {
+		t.Errorf("replayed event method = %q, want code", event.Method)
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+// Page sizes of the admin user list
+const (
+	DefaultUsersPageSize = 50
+	MaxUsersPageSize     = 200
+)
+
+// SetUserMetadata lets users edit their metadata and admins their
+// app_metadata, each at most maxBytes as JSON. claims lists the keys copied
+// into access tokens, as metadata.<key> or app_metadata.<key>.
+func (s *AuthService) SetUserMetadata(metadata repository.UserMetadataRepository, maxBytes int, claims []string) {
+	s.userMetadata = metadata
+	s.metadataMaxBytes = maxBytes
+	s.metadataClaims = make(map[domain.MetadataKind][]string)
+	for _, claim := range claims {
+		kind, key, ok := strings.Cut(claim, ".")
+		if ok {
+			s.metadataClaims[domain.MetadataKind(kind)] = append(s.metadataClaims[domain.MetadataKind(kind)], key)
+		}
+	}
+}
+
+// UserMetadataEnabled reports whether user metadata can be edited and listed
+func (s *AuthService) UserMetadataEnabled() bool {
+	return s.userMetadata != nil
+}
+
+// UpdateUserMetadata applies a JSON merge patch to the user's own metadata
+// and returns the result. Top-level keys set to null are removed.
+func (s *AuthService) UpdateUserMetadata(ctx context.Context, userID string, object map[string]interface{}) (map[string]interface{}, error) {
+	patch, err := domain.NewMetadataPatch(object)
+	if err != nil {
+		return nil, err
+	}
+	return s.userMetadata.PatchMetadata(ctx, userID, domain.MetadataKindUser, patch, s.metadataMaxBytes)
+}
+
+// UpdateAppMetadata applies a JSON merge patch to the user's app_metadata,
+// which users cannot change themselves, and returns the result. The change
+// is audited with the keys it touched.
+func (s *AuthService) UpdateAppMetadata(ctx context.Context, userID string, object map[string]interface{}) (map[string]interface{}, error) {
+	patch, err := domain.NewMetadataPatch(object)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := s.userMetadata.PatchMetadata(ctx, userID, domain.MetadataKindApp, patch, s.metadataMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	s.auditAppMetadataUpdate(ctx, userID, patch)
+	return metadata, nil
+}
+
+// ListUsersInput selects a page of users for the admin user list
+type ListUsersInput struct {
+	Metadata    map[string]string // top-level metadata keys and the values, as text, they must have
+	AppMetadata map[string]string // top-level app_metadata keys and the values, as text, they must have
+	Cursor      string            // NextCursor of the previous page; empty for the first
+	Limit       int
+}
+
+// UsersPage is a page of users, newest first
+type UsersPage struct {
+	Users      []*domain.User
+	NextCursor string // empty on the last page
+}
+
+// ListUsers returns a page of users matching the metadata filters
+func (s *AuthService) ListUsers(ctx context.Context, input ListUsersInput) (*UsersPage, error) {
+	for _, filter := range []map[string]string{input.Metadata, input.AppMetadata} {
+		for key := range filter {
+			if err := domain.ValidateMetadataKey(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	filter := repository.UserListFilter{
+		Metadata:    input.Metadata,
+		AppMetadata: input.AppMetadata,
+	}
+	if input.Cursor != "" {
+		before, err := decodeAuditCursor(input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.Before = &before
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultUsersPageSize
+	}
+	limit = min(limit, MaxUsersPageSize)
+
+	// One more user than requested tells whether there is a next page
+	filter.Limit = limit + 1
+	users, err := s.userMetadata.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	page := &UsersPage{Users: users}
+	if len(users) > limit {
+		last := users[limit-1]
+		page.Users = users[:limit]
+		page.NextCursor = encodeAuditCursor(domain.AuditCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
+
+// tokenMetadata returns the configured metadata keys the user has, to be
+// copied into their access tokens
+func (s *AuthService) tokenMetadata(user *domain.User) token.MetadataClaims {
+	return token.MetadataClaims{
+		Metadata:    selectKeys(user.Metadata, s.metadataClaims[domain.MetadataKindUser]),
+		AppMetadata: selectKeys(user.AppMetadata, s.metadataClaims[domain.MetadataKindApp]),
+	}
+}
+
+// selectKeys returns the entries of metadata with the given keys, or nil
+// when there are none
+func selectKeys(metadata map[string]interface{}, keys []string) map[string]interface{} {
+	var selected map[string]interface{}
+	for _, key := range keys {
+		value, ok := metadata[key]
+		if !ok {
+			continue
+		}
+		if selected == nil {
+			selected = make(map[string]interface{}, len(keys))
+		}
+		selected[key] = value
+	}
+	return selected
+}
+
+// auditAppMetadataUpdate records which app_metadata keys an admin changed;
+// values are left out as they may be sensitive. Failures are only logged.
+func (s *AuthService) auditAppMetadataUpdate(ctx context.Context, userID string, patch domain.MetadataPatch) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	set := make([]string, 0, len(patch.Set))
+	for key := range patch.Set {
+		set = append(set, key)
+	}
+	resourceType := "user"
+	entry := domain.NewAuditLog(domain.AuditActionAppMetadataUpdate, domain.AuditStatusSuccess)
+	entry.UserID = &userID
+	entry.ResourceType = &resourceType
+	entry.ResourceID = &userID
+	entry.Metadata["set_keys"] = set
+	entry.Metadata["removed_keys"] = patch.Remove
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		slog.WarnContext(ctx, "failed to write app metadata audit log", "user_id", userID, "error", err)
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// mockUserMetadataRepository patches the users of a mockUserRepository
+type mockUserMetadataRepository struct {
+	users *mockUserRepository
+}
+
+func (m *mockUserMetadataRepository) PatchMetadata(ctx context.Context, userID string, kind domain.MetadataKind, patch domain.MetadataPatch, maxBytes int) (map[string]interface{}, error) {
+	user, err := m.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	metadata := map[string]interface{}{}
+	current := user.Metadata
+	if kind == domain.MetadataKindApp {
+		current = user.AppMetadata
+	}
+	for key, value := range current {
+		metadata[key] = value
+	}
+	for key, value := range patch.Set {
+		metadata[key] = value
+	}
+	for _, key := range patch.Remove {
+		delete(metadata, key)
+	}
+	if data, _ := json.Marshal(metadata); len(data) > maxBytes {
+		return nil, domain.ErrMetadataTooLarge
+	}
+	if kind == domain.MetadataKindApp {
+		user.AppMetadata = metadata
+	} else {
+		user.Metadata = metadata
+	}
+	return metadata, nil
+}
+
+func (m *mockUserMetadataRepository) List(ctx context.Context, filter repository.UserListFilter) ([]*domain.User, error) {
+	var users []*domain.User
+	for _, user := range m.users.users {
+		if filter.Before != nil && !user.CreatedAt.Before(filter.Before.CreatedAt) {
+			continue
+		}
+		if matchesMetadata(user.Metadata, filter.Metadata) && matchesMetadata(user.AppMetadata, filter.AppMetadata) {
+			users = append(users, user)
+		}
+	}
+	// Newest first
+	for i := 1; i < len(users); i++ {
+		for j := i; j > 0 && users[j].CreatedAt.After(users[j-1].CreatedAt); j-- {
+			users[j], users[j-1] = users[j-1], users[j]
+		}
+	}
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+	}
+	return users, nil
+}
+
+func matchesMetadata(metadata map[string]interface{}, filter map[string]string) bool {
+	for key, want := range filter {
+		if value, ok := metadata[key]; !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAuthService_UserMetadata(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	service.SetUserMetadata(&mockUserMetadataRepository{users: userRepo}, 64, []string{"metadata.theme", "app_metadata.plan"})
+	auditRepo := &mockAuditLogRepository{}
+	service.SetAuditLog(auditRepo)
+	ctx := context.Background()
+
+	signup, err := service.Signup(ctx, SignupInput{Email: "metadata@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	if _, err := service.UpdateUserMetadata(ctx, signup.UserID, map[string]interface{}{"bad key": 1}); !errors.Is(err, domain.ErrInvalidMetadata) {
+		t.Fatalf("UpdateUserMetadata() with invalid key error = %v, want %v", err, domain.ErrInvalidMetadata)
+	}
+	if _, err := service.UpdateUserMetadata(ctx, signup.UserID, map[string]interface{}{"bio": string(make([]byte, 100))}); !errors.Is(err, domain.ErrMetadataTooLarge) {
+		t.Fatalf("UpdateUserMetadata() too large error = %v, want %v", err, domain.ErrMetadataTooLarge)
+	}
+
+	metadata, err := service.UpdateUserMetadata(ctx, signup.UserID, map[string]interface{}{"theme": "dark", "lang": "en"})
+	if err != nil {
+		t.Fatalf("UpdateUserMetadata() error = %v", err)
+	}
+	metadata, err = service.UpdateUserMetadata(ctx, signup.UserID, map[string]interface{}{"lang": nil})
+	if err != nil {
+		t.Fatalf("UpdateUserMetadata() error = %v", err)
+	}
+	if len(metadata) != 1 || metadata["theme"] != "dark" {
+		t.Errorf("UpdateUserMetadata() = %v, want only theme", metadata)
+	}
+
+	if _, err := service.UpdateAppMetadata(ctx, signup.UserID, map[string]interface{}{"plan": "pro", "seats": 3}); err != nil {
+		t.Fatalf("UpdateAppMetadata() error = %v", err)
+	}
+	if len(auditRepo.logs) != 1 || auditRepo.logs[0].Action != domain.AuditActionAppMetadataUpdate {
+		t.Fatalf("audit logs = %+v, want one app metadata update", auditRepo.logs)
+	}
+
+	// Only the configured keys become claims
+	login, err := service.Login(ctx, LoginInput{Email: "metadata@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	claims, err := service.tokenManager.ValidateAccessToken(login.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.Metadata["theme"] != "dark" || claims.AppMetadata["plan"] != "pro" || claims.AppMetadata["seats"] != nil {
+		t.Errorf("claims metadata = %v, app_metadata = %v", claims.Metadata, claims.AppMetadata)
+	}
+}
+
+func TestAuthService_ListUsers(t *testing.T) {
+	service, userRepo, _ := createTestAuthService(t)
+	service.SetUserMetadata(&mockUserMetadataRepository{users: userRepo}, 4096, nil)
+	ctx := context.Background()
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		signup, err := service.Signup(ctx, SignupInput{Email: fmt.Sprintf("list%d@example.com", i), Password: "password123"})
+		if err != nil {
+			t.Fatalf("Signup() error = %v", err)
+		}
+		user, _ := userRepo.GetByID(ctx, signup.UserID)
+		// Cursors carry the user's UUID
+		user.ID = testUUID(i + 1)
+		user.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		if _, err := service.UpdateAppMetadata(ctx, user.ID, map[string]interface{}{"plan": "pro"}); err != nil {
+			t.Fatalf("UpdateAppMetadata() error = %v", err)
+		}
+	}
+
+	page, err := service.ListUsers(ctx, ListUsersInput{AppMetadata: map[string]string{"plan": "pro"}, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(page.Users) != 2 || page.Users[0].Email != "list2@example.com" || page.NextCursor == "" {
+		t.Fatalf("ListUsers() first page = %d users, cursor %q", len(page.Users), page.NextCursor)
+	}
+
+	page, err = service.ListUsers(ctx, ListUsersInput{AppMetadata: map[string]string{"plan": "pro"}, Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(page.Users) != 1 || page.Users[0].Email != "list0@example.com" || page.NextCursor != "" {
+		t.Errorf("ListUsers() second page = %d users, cursor %q", len(page.Users), page.NextCursor)
+	}
+
+	if _, err := service.ListUsers(ctx, ListUsersInput{Cursor: "not-a-cursor"}); !errors.Is(err, domain.ErrInvalidPageCursor) {
+		t.Errorf("ListUsers() with invalid cursor error = %v, want %v", err, domain.ErrInvalidPageCursor)
+	}
+	if _, err := service.ListUsers(ctx, ListUsersInput{Metadata: map[string]string{"a b": "c"}}); !errors.Is(err, domain.ErrInvalidMetadata) {
+		t.Errorf("ListUsers() with invalid key error = %v, want %v", err, domain.ErrInvalidMetadata)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+	"github.com/n1rocket/go-auth-jwt/internal/repository"
+)
+
+// Outcomes of a verification email resend, reported to the ResendRecorder
+const (
+	ResendOutcomeSent      = "sent"
+	ResendOutcomeThrottled = "throttled"
+)
+
+// ResendRecorder receives verification resend outcomes, typically for
+// metrics that reveal resend abuse
+type ResendRecorder interface {
+	RecordVerificationResend(outcome string)
+}
+
+// SetVerificationResendLimit throttles ResendVerificationEmail per user:
+// resends need cooldown between them and at most dailyLimit are allowed
+// per UTC day (0: no daily limit). Counters are kept in repo so the limit
+// holds across instances.
+func (s *AuthService) SetVerificationResendLimit(repo repository.VerificationResendRepository, cooldown time.Duration, dailyLimit int) {
+	s.resends = repo
+	s.resendCooldown = cooldown
+	s.resendDailyLimit = dailyLimit
+}
+
+// SetResendRecorder reports the outcome of every verification resend
+func (s *AuthService) SetResendRecorder(recorder ResendRecorder) {
+	s.resendRecorder = recorder
+}
+
+// checkResendLimit counts a verification resend for the user, or returns a
+// *domain.ResendLimitError when it is refused
+func (s *AuthService) checkResendLimit(ctx context.Context, userID string) error {
+	if s.resends == nil {
+		s.recordResend(ResendOutcomeSent)
+		return nil
+	}
+
+	now := s.clock.Now()
+	resend, err := s.resends.Record(ctx, userID, now, s.resendCooldown, s.resendDailyLimit)
+	if errors.Is(err, domain.ErrResendTooSoon) {
+		s.recordResend(ResendOutcomeThrottled)
+		retryAfter := resend.RetryAfter(now, s.resendCooldown, s.resendDailyLimit)
+		slog.InfoContext(ctx, "verification resend throttled", "user_id", userID, "count", resend.Count, "retry_after", retryAfter)
+		return &domain.ResendLimitError{RetryAfter: retryAfter}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.recordResend(ResendOutcomeSent)
+	return nil
+}
+
+func (s *AuthService) recordResend(outcome string) {
+	if s.resendRecorder != nil {
+		s.resendRecorder.RecordVerificationResend(outcome)
+	}
+}
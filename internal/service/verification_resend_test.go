@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/domain"
+)
+
+// mockVerificationResendRepository keeps resend counters in memory, with
+// an adjustable clock offset so tests can move past cooldowns
+type mockVerificationResendRepository struct {
+	resends map[string]*domain.VerificationResend
+	offset  time.Duration
+}
+
+func newMockVerificationResendRepository() *mockVerificationResendRepository {
+	return &mockVerificationResendRepository{resends: make(map[string]*domain.VerificationResend)}
+}
+
+func (m *mockVerificationResendRepository) Record(ctx context.Context, userID string, now time.Time, cooldown time.Duration, dailyLimit int) (*domain.VerificationResend, error) {
+	now = now.Add(m.offset)
+	resend, ok := m.resends[userID]
+	if !ok {
+		resend = &domain.VerificationResend{UserID: userID, Day: domain.ResendDay(now), Count: 1, LastSentAt: now}
+		m.resends[userID] = resend
+		return resend, nil
+	}
+	if resend.RetryAfter(now, cooldown, dailyLimit) > 0 {
+		// Report the counter relative to the caller's clock
+		shifted := *resend
+		shifted.LastSentAt = shifted.LastSentAt.Add(-m.offset)
+		return &shifted, domain.ErrResendTooSoon
+	}
+	if !resend.Day.Equal(domain.ResendDay(now)) {
+		resend.Day = domain.ResendDay(now)
+		resend.Count = 0
+	}
+	resend.Count++
+	resend.LastSentAt = now
+	return resend, nil
+}
+
+// resendOutcomes records resend outcomes
+type resendOutcomes map[string]int
+
+func (r resendOutcomes) RecordVerificationResend(outcome string) {
+	r[outcome]++
+}
+
+func TestAuthService_ResendVerificationEmailLimit(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	repo := newMockVerificationResendRepository()
+	outcomes := resendOutcomes{}
+	service.SetVerificationResendLimit(repo, time.Second, 2)
+	service.SetResendRecorder(outcomes)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "resend@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+
+	if _, err := service.ResendVerificationEmail(ctx, "resend@example.com"); err != nil {
+		t.Fatalf("first ResendVerificationEmail() error = %v", err)
+	}
+
+	// Within the cooldown
+	_, err := service.ResendVerificationEmail(ctx, "resend@example.com")
+	var limitErr *domain.ResendLimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, domain.ErrResendTooSoon) {
+		t.Fatalf("ResendVerificationEmail() within cooldown error = %v, want ResendLimitError", err)
+	}
+	if limitErr.RetryAfter <= 0 || limitErr.RetryAfter > time.Second {
+		t.Errorf("RetryAfter = %v, want within the cooldown", limitErr.RetryAfter)
+	}
+
+	// After the cooldown until the daily limit
+	repo.offset = 2 * time.Second
+	if _, err := service.ResendVerificationEmail(ctx, "resend@example.com"); err != nil {
+		t.Fatalf("ResendVerificationEmail() after cooldown error = %v", err)
+	}
+	repo.offset = 4 * time.Second
+	_, err = service.ResendVerificationEmail(ctx, "resend@example.com")
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("ResendVerificationEmail() over daily limit error = %v, want ResendLimitError", err)
+	}
+	if limitErr.RetryAfter <= time.Second || limitErr.RetryAfter > 24*time.Hour {
+		t.Errorf("RetryAfter = %v, want until the next UTC day", limitErr.RetryAfter)
+	}
+
+	if outcomes[ResendOutcomeSent] != 2 || outcomes[ResendOutcomeThrottled] != 2 {
+		t.Errorf("outcomes = %v, want 2 sent and 2 throttled", outcomes)
+	}
+}
+
+func TestAuthService_ResendVerificationEmailLimit_EnumerationSafe(t *testing.T) {
+	service, _, _ := createTestAuthService(t)
+	service.SetVerificationResendLimit(newMockVerificationResendRepository(), time.Minute, 0)
+	service.SetEnumerationSafe(true)
+	ctx := context.Background()
+
+	if _, err := service.Signup(ctx, SignupInput{Email: "quiet@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("Signup() error = %v", err)
+	}
+	if _, err := service.ResendVerificationEmail(ctx, "quiet@example.com"); err != nil {
+		t.Fatalf("first ResendVerificationEmail() error = %v", err)
+	}
+
+	// Throttled resends look like resends to unknown emails
+	output, err := service.ResendVerificationEmail(ctx, "quiet@example.com")
+	if err != nil {
+		t.Fatalf("ResendVerificationEmail() error = %v", err)
+	}
+	if !output.Suppressed || output.EmailVerificationToken != "" {
+		t.Errorf("expected a suppressed resend, got %+v", output)
+	}
+}
@@ -0,0 +1,56 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// HTTPSender posts messages as JSON to a generic gateway, for providers
+// without a dedicated sender or an in-house relay. The body is
+// {"to": "+15551234567", "body": "..."}.
+type HTTPSender struct {
+	client *httpclient.Client
+	url    string
+	token  string // sent as a bearer token when set
+}
+
+// NewHTTPSender creates a sender posting to url
+func NewHTTPSender(client *httpclient.Client, url, token string) *HTTPSender {
+	return &HTTPSender{client: client, url: url, token: token}
+}
+
+// httpMessage is the JSON layout of a message
+type httpMessage struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+// Send posts the message to the gateway
+func (s *HTTPSender) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(httpMessage{To: msg.To, Body: msg.Body})
+	if err != nil {
+		return fmt.Errorf("failed to encode SMS: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse("SMS gateway", resp)
+}
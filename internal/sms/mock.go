@@ -0,0 +1,45 @@
+package sms
+
+import (
+	"context"
+	"sync"
+)
+
+// MockSender records messages instead of sending them, for tests and local
+// development
+type MockSender struct {
+	mu   sync.Mutex
+	sent []Message
+	err  error
+}
+
+// NewMockSender creates a mock sender
+func NewMockSender() *MockSender {
+	return &MockSender{}
+}
+
+// Send records the message, or fails with the error set by SetError
+func (m *MockSender) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// SetError makes subsequent sends fail with err; nil restores success
+func (m *MockSender) SetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// Sent returns the recorded messages
+func (m *MockSender) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
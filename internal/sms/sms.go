@@ -0,0 +1,31 @@
+// Package sms sends text messages, used for phone verification and SMS
+// one-time codes
+package sms
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Message is a text message to a phone number in E.164 format
+type Message struct {
+	To   string
+	Body string
+}
+
+// Sender sends text messages through a provider
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// checkResponse turns a non-2xx provider response into an error carrying the
+// start of its body
+func checkResponse(provider string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s rejected message: unexpected status %s: %s", provider, resp.Status, detail)
+}
@@ -0,0 +1,93 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+func newTestClient(t *testing.T) *httpclient.Client {
+	t.Helper()
+	config := httpclient.DefaultConfig()
+	config.MaxRetries = 0
+	client, err := httpclient.New(config, slog.Default())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestTwilioSender_Send(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		status   int
+		wantFrom string
+		wantMG   string
+		wantErr  bool
+	}{
+		{name: "phone number sender", from: "+15550001111", status: http.StatusCreated, wantFrom: "+15550001111"},
+		{name: "messaging service", from: "MG123", status: http.StatusCreated, wantMG: "MG123"},
+		{name: "rejected", from: "+15550001111", status: http.StatusBadRequest, wantFrom: "+15550001111", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if user, pass, ok := r.BasicAuth(); !ok || user != "AC123" || pass != "secret" {
+					t.Error("missing basic auth")
+				}
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("failed to parse form: %v", err)
+				}
+				if r.PostForm.Get("To") != "+15551234567" || r.PostForm.Get("Body") != "Your code is 123456" {
+					t.Errorf("unexpected message %v", r.PostForm)
+				}
+				if r.PostForm.Get("From") != tt.wantFrom || r.PostForm.Get("MessagingServiceSid") != tt.wantMG {
+					t.Errorf("unexpected sender %v", r.PostForm)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"sid":"SM123"}`))
+			}))
+			defer server.Close()
+
+			sender := NewTwilioSender(newTestClient(t), "AC123", "secret", tt.from)
+			sender.baseURL = server.URL
+
+			err := sender.Send(context.Background(), Message{To: "+15551234567", Body: "Your code is 123456"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPSender_Send(t *testing.T) {
+	var got httpMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gateway-token" {
+			t.Errorf("unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode message: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sender := NewHTTPSender(newTestClient(t), server.URL, "gateway-token")
+	if err := sender.Send(context.Background(), Message{To: "+15551234567", Body: "Your code is 123456"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got.To != "+15551234567" || got.Body != "Your code is 123456" {
+		t.Errorf("gateway received %+v", got)
+	}
+}
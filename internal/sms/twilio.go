@@ -0,0 +1,64 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// twilioBaseURL is the Twilio REST API
+const twilioBaseURL = "https://api.twilio.com"
+
+// TwilioSender sends messages with the Twilio Programmable Messaging API
+type TwilioSender struct {
+	client     *httpclient.Client
+	baseURL    string
+	accountSID string
+	authToken  string
+	from       string // sender number or messaging service SID (MG...)
+}
+
+// NewTwilioSender creates a Twilio sender. from is the sending phone number,
+// or a messaging service SID starting with "MG".
+func NewTwilioSender(client *httpclient.Client, accountSID, authToken, from string) *TwilioSender {
+	return &TwilioSender{
+		client:     client,
+		baseURL:    twilioBaseURL,
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+	}
+}
+
+// Send creates a message resource
+func (s *TwilioSender) Send(ctx context.Context, msg Message) error {
+	form := url.Values{
+		"To":   {msg.To},
+		"Body": {msg.Body},
+	}
+	if strings.HasPrefix(s.from, "MG") {
+		form.Set("MessagingServiceSid", s.from)
+	} else {
+		form.Set("From", s.from)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.baseURL, url.PathEscape(s.accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create Twilio request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.accountSID, s.authToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS with Twilio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse("Twilio", resp)
+}
@@ -0,0 +1,73 @@
+package token
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+)
+
+// ErrCertificateMismatch is returned when a certificate-bound token is
+// presented without the client certificate it was issued to
+var ErrCertificateMismatch = fmt.Errorf("%w: client certificate does not match token binding", ErrInvalidToken)
+
+// ErrDPoPKeyMismatch is returned when a DPoP-bound token is presented without
+// a proof from the key it was issued to, or an unbound token is sent as DPoP
+var ErrDPoPKeyMismatch = fmt.Errorf("%w: DPoP proof does not match token binding", ErrInvalidToken)
+
+// Confirmation is the cnf claim binding a token to a key (RFC 7800)
+type Confirmation struct {
+	X509Thumbprint string `json:"x5t#S256,omitempty"` // RFC 8705 certificate thumbprint
+	JWKThumbprint  string `json:"jkt,omitempty"`      // RFC 9449 DPoP key thumbprint
+}
+
+// DPoPThumbprint returns the DPoP key thumbprint the token is bound to, or ""
+func (c *Claims) DPoPThumbprint() string {
+	if c.Confirmation == nil {
+		return ""
+	}
+	return c.Confirmation.JWKThumbprint
+}
+
+// VerifyDPoPBinding checks that the token was presented with the scheme its
+// binding requires and, for DPoP-bound tokens, with a proof whose key
+// thumbprint matches. proofThumbprint is "" when no proof was verified.
+func (c *Claims) VerifyDPoPBinding(scheme, proofThumbprint string) error {
+	bound := c.DPoPThumbprint()
+	if bound == "" {
+		if scheme == DPoPTokenType {
+			return ErrDPoPKeyMismatch
+		}
+		return nil
+	}
+	if scheme != DPoPTokenType || subtle.ConstantTimeCompare([]byte(proofThumbprint), []byte(bound)) != 1 {
+		return ErrDPoPKeyMismatch
+	}
+	return nil
+}
+
+// CertificateThumbprint returns the base64url SHA-256 thumbprint of a
+// certificate as used in the x5t#S256 confirmation method
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyCertificateBinding checks that a certificate-bound token is presented
+// over a connection authenticated with the same client certificate. Unbound
+// tokens are always accepted.
+func (c *Claims) VerifyCertificateBinding(peerCertificates []*x509.Certificate) error {
+	if c.Confirmation == nil || c.Confirmation.X509Thumbprint == "" {
+		return nil
+	}
+	if len(peerCertificates) == 0 {
+		return ErrCertificateMismatch
+	}
+
+	presented := CertificateThumbprint(peerCertificates[0])
+	if subtle.ConstantTimeCompare([]byte(presented), []byte(c.Confirmation.X509Thumbprint)) != 1 {
+		return ErrCertificateMismatch
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func generateTestCertificate(t *testing.T, commonName string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestManager_GenerateAccessTokenWithOptions_Confirmation(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	clientCert := generateTestCertificate(t, "client")
+	otherCert := generateTestCertificate(t, "other")
+
+	bound, err := manager.GenerateAccessTokenWithOptions("user-123", "test@example.com", true, AccessTokenOptions{Confirmation: Confirmation{X509Thumbprint: CertificateThumbprint(clientCert)}})
+	if err != nil {
+		t.Fatalf("GenerateAccessTokenWithOptions() error = %v", err)
+	}
+	boundClaims, err := manager.ValidateAccessToken(bound)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if boundClaims.Confirmation == nil || boundClaims.Confirmation.X509Thumbprint != CertificateThumbprint(clientCert) {
+		t.Fatalf("cnf claim = %+v, want thumbprint of client certificate", boundClaims.Confirmation)
+	}
+
+	unbound, _ := manager.GenerateAccessTokenWithOptions("user-123", "test@example.com", true, AccessTokenOptions{})
+	unboundClaims, err := manager.ValidateAccessToken(unbound)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if unboundClaims.Confirmation != nil {
+		t.Errorf("unbound token has cnf claim %+v", unboundClaims.Confirmation)
+	}
+
+	tests := []struct {
+		name    string
+		claims  *Claims
+		certs   []*x509.Certificate
+		wantErr bool
+	}{
+		{name: "bound token with its certificate", claims: boundClaims, certs: []*x509.Certificate{clientCert}},
+		{name: "bound token without certificate", claims: boundClaims, wantErr: true},
+		{name: "bound token with another certificate", claims: boundClaims, certs: []*x509.Certificate{otherCert}, wantErr: true},
+		{name: "unbound token without certificate", claims: unboundClaims},
+		{name: "unbound token with certificate", claims: unboundClaims, certs: []*x509.Certificate{otherCert}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.claims.VerifyCertificateBinding(tt.certs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyCertificateBinding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("error %v should wrap ErrInvalidToken", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrTokenTooLarge is returned when a signed access token exceeds the claims
+// budget and the oversize mode does not allow issuing it
+var ErrTokenTooLarge = errors.New("token exceeds size budget")
+
+// OversizeMode selects what happens when an access token exceeds the budget
+type OversizeMode string
+
+const (
+	// OversizeWarn issues the token and logs a warning
+	OversizeWarn OversizeMode = "warn"
+	// OversizeFail refuses to issue the token
+	OversizeFail OversizeMode = "fail"
+	// OversizeReference moves roles and scope into a ClaimStore and embeds
+	// only a cref claim; tokens still over budget are refused
+	OversizeReference OversizeMode = "reference"
+)
+
+// ReferencedClaims holds the claim set kept server-side for a cref claim
+type ReferencedClaims struct {
+	Roles []string
+	Scope string
+}
+
+// ClaimStore keeps claim sets that were moved out of oversized tokens.
+// Entries only need to live until the token that references them expires.
+type ClaimStore interface {
+	Put(ref string, claims ReferencedClaims, expiresAt time.Time) error
+	Get(ref string) (ReferencedClaims, bool)
+}
+
+// MemoryClaimStore is an in-process ClaimStore. References are only
+// resolvable by the instance that issued the token.
+type MemoryClaimStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryClaimEntry
+	now     func() time.Time
+}
+
+type memoryClaimEntry struct {
+	claims    ReferencedClaims
+	expiresAt time.Time
+}
+
+// NewMemoryClaimStore creates an empty in-process claim store
+func NewMemoryClaimStore() *MemoryClaimStore {
+	return &MemoryClaimStore{
+		entries: make(map[string]memoryClaimEntry),
+		now:     time.Now,
+	}
+}
+
+// Put stores claims under ref until expiresAt, dropping expired entries
+func (s *MemoryClaimStore) Put(ref string, claims ReferencedClaims, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[ref] = memoryClaimEntry{claims: claims, expiresAt: expiresAt}
+	return nil
+}
+
+// Get returns the claims stored under ref unless they have expired
+func (s *MemoryClaimStore) Get(ref string) (ReferencedClaims, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[ref]
+	if !ok || !s.now().Before(e.expiresAt) {
+		return ReferencedClaims{}, false
+	}
+	return e.claims, true
+}
+
+// SetClaimsBudget limits signed access tokens to maxBytes. Zero disables
+// the check. Reference mode uses an in-process store unless SetClaimStore
+// is called.
+func (m *Manager) SetClaimsBudget(maxBytes int, mode OversizeMode) {
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	m.maxTokenBytes = maxBytes
+	m.oversizeMode = mode
+	if mode == OversizeReference && m.claimStore == nil {
+		m.claimStore = NewMemoryClaimStore()
+	}
+}
+
+// SetClaimStore sets where reference mode keeps offloaded claim sets
+func (m *Manager) SetClaimStore(store ClaimStore) {
+	m.claimStore = store
+}
+
+// signAccess signs access token claims, enforces the claims budget and
+// encrypts the result when encryption is enabled. The budget applies to the
+// signed token; encryption adds a roughly constant overhead on top. The
+// caller holds mu.
+func (m *Manager) signAccess(claims Claims) (string, error) {
+	// Every access token gets a jti, so it can be revoked on its own
+	if claims.ID == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to generate token id: %w", err)
+		}
+		claims.ID = base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	signed, err := m.signWithinBudget(claims)
+	if err != nil || m.encryption == nil {
+		return signed, err
+	}
+	return m.encryption.encrypt(signed)
+}
+
+// signWithinBudget signs access token claims and enforces the claims budget
+func (m *Manager) signWithinBudget(claims Claims) (string, error) {
+	signed, err := m.sign(claims)
+	if err != nil || m.maxTokenBytes == 0 || len(signed) <= m.maxTokenBytes {
+		return signed, err
+	}
+
+	switch m.oversizeMode {
+	case OversizeFail:
+		return "", fmt.Errorf("%w: %d bytes, limit %d", ErrTokenTooLarge, len(signed), m.maxTokenBytes)
+	case OversizeReference:
+		return m.signReferenced(claims, len(signed))
+	default:
+		slog.Warn("Access token exceeds size budget",
+			"sub", claims.Subject,
+			"bytes", len(signed),
+			"limit", m.maxTokenBytes,
+		)
+		return signed, nil
+	}
+}
+
+// signReferenced moves roles and scope into the claim store and signs the
+// remaining claims with a cref claim pointing at them
+func (m *Manager) signReferenced(claims Claims, size int) (string, error) {
+	if len(claims.Roles) == 0 && claims.Scope == "" {
+		return "", fmt.Errorf("%w: %d bytes, limit %d, no claims to offload", ErrTokenTooLarge, size, m.maxTokenBytes)
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate claims reference: %w", err)
+	}
+	ref := base64.RawURLEncoding.EncodeToString(b)
+
+	stored := ReferencedClaims{Roles: claims.Roles, Scope: claims.Scope}
+	if err := m.claimStore.Put(ref, stored, claims.ExpiresAt.Add(m.clockSkew)); err != nil {
+		return "", fmt.Errorf("failed to store referenced claims: %w", err)
+	}
+
+	claims.Roles = nil
+	claims.Scope = ""
+	claims.ClaimsRef = ref
+	signed, err := m.sign(claims)
+	if err != nil {
+		return "", err
+	}
+	if len(signed) > m.maxTokenBytes {
+		return "", fmt.Errorf("%w: %d bytes after offloading, limit %d", ErrTokenTooLarge, len(signed), m.maxTokenBytes)
+	}
+	return signed, nil
+}
+
+// resolveClaimsRef restores the claim set referenced by a cref claim
+func (m *Manager) resolveClaimsRef(claims *Claims) error {
+	if claims.ClaimsRef == "" {
+		return nil
+	}
+	if m.claimStore == nil {
+		return fmt.Errorf("%w: claims reference cannot be resolved", ErrInvalidToken)
+	}
+	stored, ok := m.claimStore.Get(claims.ClaimsRef)
+	if !ok {
+		return fmt.Errorf("%w: unknown claims reference", ErrInvalidToken)
+	}
+	claims.Roles = stored.Roles
+	claims.Scope = stored.Scope
+	return nil
+}
@@ -0,0 +1,114 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func roleClaims(m *Manager, roles int) Claims {
+	claims := m.newAccessClaims("user-123", "test@example.com", true, time.Now())
+	for i := 0; i < roles; i++ {
+		claims.Roles = append(claims.Roles, "org-"+strings.Repeat("x", 20))
+	}
+	claims.Scope = "users:read users:write"
+	return claims
+}
+
+func TestManager_ClaimsBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    OversizeMode
+		roles   int
+		wantErr error
+		wantRef bool
+	}{
+		{name: "within budget", mode: OversizeFail, roles: 0},
+		{name: "warn issues oversized token", mode: OversizeWarn, roles: 40},
+		{name: "fail refuses oversized token", mode: OversizeFail, roles: 40, wantErr: ErrTokenTooLarge},
+		{name: "reference offloads roles", mode: OversizeReference, roles: 40, wantRef: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+			manager.SetClaimsBudget(600, tt.mode)
+
+			tokenString, err := manager.signAccess(roleClaims(manager, tt.roles))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("signAccess() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			claims, err := manager.ValidateAccessToken(tokenString)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken() error = %v", err)
+			}
+			if (claims.ClaimsRef != "") != tt.wantRef {
+				t.Errorf("ClaimsRef = %q, want reference %v", claims.ClaimsRef, tt.wantRef)
+			}
+			if tt.wantRef && len(tokenString) > 600 {
+				t.Errorf("referenced token is %d bytes, over budget", len(tokenString))
+			}
+			if len(claims.Roles) != tt.roles || claims.Scope != "users:read users:write" {
+				t.Errorf("got %d roles and scope %q after validation", len(claims.Roles), claims.Scope)
+			}
+		})
+	}
+}
+
+func TestManager_ClaimsBudget_ReferenceNothingToOffload(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	manager.SetClaimsBudget(100, OversizeReference)
+
+	_, err := manager.GenerateAccessToken("user-123", "test@example.com", true)
+	if !errors.Is(err, ErrTokenTooLarge) {
+		t.Errorf("expected ErrTokenTooLarge, got %v", err)
+	}
+}
+
+func TestManager_ClaimsBudget_UnknownReference(t *testing.T) {
+	issuer, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	issuer.SetClaimsBudget(600, OversizeReference)
+	tokenString, err := issuer.signAccess(roleClaims(issuer, 40))
+	if err != nil {
+		t.Fatalf("signAccess() error = %v", err)
+	}
+
+	// Another instance with its own store cannot resolve the reference
+	other, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	other.SetClaimsBudget(600, OversizeReference)
+	if _, err := other.ValidateAccessToken(tokenString); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+
+	// Without any store the reference is rejected too
+	plain, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if _, err := plain.ValidateAccessToken(tokenString); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestMemoryClaimStore_Expiry(t *testing.T) {
+	store := NewMemoryClaimStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_ = store.Put("old", ReferencedClaims{Scope: "a"}, now.Add(time.Minute))
+	if _, ok := store.Get("old"); !ok {
+		t.Fatal("expected stored claims")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := store.Get("old"); ok {
+		t.Error("expected expired claims to be gone")
+	}
+
+	_ = store.Put("new", ReferencedClaims{Scope: "b"}, now.Add(time.Minute))
+	if len(store.entries) != 1 {
+		t.Errorf("expected expired entries to be pruned, have %d", len(store.entries))
+	}
+}
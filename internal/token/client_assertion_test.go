@@ -0,0 +1,165 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testAssertionAudience = "https://auth.example.com/api/v1/auth/service-accounts/token"
+
+func generateClientAssertion(t *testing.T, key *ecdsa.PrivateKey, kid string, claims jwt.RegisteredClaims) string {
+	t.Helper()
+
+	assertion := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	assertion.Header["kid"] = kid
+	signed, err := assertion.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign assertion: %v", err)
+	}
+	return signed
+}
+
+func TestClientAssertion_Verify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	now := time.Now()
+	valid := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    "sa_build",
+			Subject:   "sa_build",
+			Audience:  jwt.ClaimStrings{testAssertionAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			ID:        "assertion-1",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		claims  func(c *jwt.RegisteredClaims)
+		signer  *ecdsa.PrivateKey
+		wantErr bool
+	}{
+		{name: "valid", claims: func(c *jwt.RegisteredClaims) {}},
+		{name: "other audience", claims: func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"https://other.example.com"} }, wantErr: true},
+		{name: "expired", claims: func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) }, wantErr: true},
+		{name: "no expiry", claims: func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil }, wantErr: true},
+		{name: "lives too long", claims: func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(time.Hour)) }, wantErr: true},
+		{name: "no jti", claims: func(c *jwt.RegisteredClaims) { c.ID = "" }, wantErr: true},
+		{name: "signed with another key", claims: func(c *jwt.RegisteredClaims) {}, signer: otherKey, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.claims(&claims)
+			signer := key
+			if tt.signer != nil {
+				signer = tt.signer
+			}
+
+			parsed, err := ParseClientAssertion(generateClientAssertion(t, signer, "kid-1", claims))
+			if err != nil {
+				t.Fatalf("ParseClientAssertion() error = %v", err)
+			}
+			if parsed.Issuer != "sa_build" || parsed.KeyID != "kid-1" {
+				t.Errorf("parsed = %+v, want issuer sa_build and kid kid-1", parsed)
+			}
+
+			verified, err := parsed.Verify(ecJWK(&key.PublicKey), []string{testAssertionAudience, "go-auth-jwt"}, now, 5*time.Minute)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidClientAssertion) {
+					t.Errorf("Verify() error = %v, want ErrInvalidClientAssertion", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if verified.ID != "assertion-1" {
+				t.Errorf("jti = %q, want assertion-1", verified.ID)
+			}
+		})
+	}
+}
+
+func TestParseClientAssertion_Invalid(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	claims := jwt.RegisteredClaims{Issuer: "sa_build", Subject: "sa_other"}
+
+	for name, assertion := range map[string]string{
+		"not a jwt":             "not-a-jwt",
+		"issuer is not subject": generateClientAssertion(t, key, "kid-1", claims),
+		"no kid":                generateClientAssertion(t, key, "", jwt.RegisteredClaims{Issuer: "sa_build", Subject: "sa_build"}),
+	} {
+		if _, err := ParseClientAssertion(assertion); !errors.Is(err, ErrInvalidClientAssertion) {
+			t.Errorf("%s: ParseClientAssertion() error = %v, want ErrInvalidClientAssertion", name, err)
+		}
+	}
+}
+
+func TestNormalizePublicJWK(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	jwk := ecJWK(&key.PublicKey)
+	wantThumbprint, err := jwkThumbprint(jwk)
+	if err != nil {
+		t.Fatalf("jwkThumbprint() error = %v", err)
+	}
+
+	jwk["use"] = "sig"
+	jwk["kid"] = "client-chosen"
+	normalized, thumbprint, err := NormalizePublicJWK(jwk)
+	if err != nil {
+		t.Fatalf("NormalizePublicJWK() error = %v", err)
+	}
+	if thumbprint != wantThumbprint {
+		t.Errorf("thumbprint = %q, want %q", thumbprint, wantThumbprint)
+	}
+	if len(normalized) != 4 || normalized["kid"] != nil || normalized["crv"] != "P-256" {
+		t.Errorf("normalized = %v, want only kty, crv, x and y", normalized)
+	}
+
+	jwk["d"] = "private"
+	if _, _, err := NormalizePublicJWK(jwk); err == nil {
+		t.Error("NormalizePublicJWK() accepted a private key")
+	}
+}
+
+func TestManager_GenerateClientAccessToken(t *testing.T) {
+	manager, err := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	accessToken, err := manager.GenerateClientAccessToken("sa_build", []string{"reports:read", "reports:write"})
+	if err != nil {
+		t.Fatalf("GenerateClientAccessToken() error = %v", err)
+	}
+	claims, err := manager.ValidateAccessToken(accessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.Subject != "sa_build" || claims.ClientID != "sa_build" || claims.Email != "" {
+		t.Errorf("claims = %+v, want a client token for sa_build", claims)
+	}
+	if claims.Scope != "reports:read reports:write" {
+		t.Errorf("scope = %q", claims.Scope)
+	}
+}
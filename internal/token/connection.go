@@ -0,0 +1,70 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ConnectionTokenTTL is how long a connection token can open a connection
+const ConnectionTokenTTL = 30 * time.Second
+
+// ConnectionTokenUse is the token_use claim of connection tokens
+const ConnectionTokenUse = "connection"
+
+// GenerateConnectionToken generates a short-lived token that only opens a
+// WebSocket or SSE connection for the user, so long-lived sockets do not
+// carry access tokens in query strings. It holds no email, roles or scopes
+// and is signed but never encrypted, so socket servers can check it against
+// the JWKS. audience names the socket endpoint and is left out when empty.
+func (m *Manager) GenerateConnectionToken(userID, audience string) (string, time.Time, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.clock.Now()
+	expiresAt := now.Add(ConnectionTokenTTL)
+	claims := Claims{
+		UserID: userID,
+		Use:    ConnectionTokenUse,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			ID:        base64.RawURLEncoding.EncodeToString(b),
+		},
+	}
+	if audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+
+	signed, err := m.sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// ValidateConnectionToken validates a connection token and returns the
+// claims. Access tokens are rejected.
+func (m *Manager) ValidateConnectionToken(tokenString string) (*Claims, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	claims, err := m.parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Use != ConnectionTokenUse {
+		return nil, fmt.Errorf("%w: not a connection token", ErrInvalidToken)
+	}
+	return claims, nil
+}
@@ -0,0 +1,42 @@
+package token
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManager_ConnectionToken(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+
+	connectionToken, expiresAt, err := manager.GenerateConnectionToken("user-123", "wss://chat.example.com")
+	if err != nil {
+		t.Fatalf("GenerateConnectionToken() error = %v", err)
+	}
+	if ttl := time.Until(expiresAt); ttl <= 0 || ttl > ConnectionTokenTTL {
+		t.Errorf("expires in %v, want at most %v", ttl, ConnectionTokenTTL)
+	}
+
+	claims, err := manager.ValidateConnectionToken(connectionToken)
+	if err != nil {
+		t.Fatalf("ValidateConnectionToken() error = %v", err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "" || claims.ID == "" {
+		t.Errorf("claims = %+v, want the user, a jti and no email", claims)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "wss://chat.example.com" {
+		t.Errorf("aud = %v, want the socket endpoint", claims.Audience)
+	}
+
+	// Neither token stands in for the other
+	if _, err := manager.ValidateAccessToken(connectionToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken(connection token) error = %v, want %v", err, ErrInvalidToken)
+	}
+	accessToken, err := manager.GenerateAccessToken("user-123", "test@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	if _, err := manager.ValidateConnectionToken(accessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateConnectionToken(access token) error = %v, want %v", err, ErrInvalidToken)
+	}
+}
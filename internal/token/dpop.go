@@ -0,0 +1,250 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DPoPHeader is the request header carrying a DPoP proof
+	DPoPHeader = "DPoP"
+	// DPoPTokenType is the token_type of DPoP-bound access tokens
+	DPoPTokenType = "DPoP"
+
+	dpopProofType = "dpop+jwt"
+)
+
+var (
+	// ErrInvalidDPoPProof is returned when a DPoP proof is malformed, signed
+	// incorrectly, stale, replayed or issued for another request
+	ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+	// ErrDPoPDisabled is returned when a DPoP proof is sent but DPoP is not enabled
+	ErrDPoPDisabled = errors.New("DPoP is not enabled")
+)
+
+// DPoPRequest is the request a DPoP proof must be bound to
+type DPoPRequest struct {
+	Proof       string // value of the DPoP header
+	Method      string // HTTP method
+	URL         string // absolute request URL; query and fragment are ignored
+	AccessToken string // access token presented with the proof, if any
+}
+
+// dpopClaims are the claims of a DPoP proof JWT (RFC 9449 section 4.2)
+type dpopClaims struct {
+	HTTPMethod      string `json:"htm"`
+	HTTPURI         string `json:"htu"`
+	AccessTokenHash string `json:"ath,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// DPoPVerifier checks DPoP proofs and remembers their jti values until they
+// expire so a proof cannot be replayed. The replay cache is per process.
+type DPoPVerifier struct {
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewDPoPVerifier creates a verifier accepting proofs issued within maxAge
+// of the current time in either direction
+func NewDPoPVerifier(maxAge time.Duration) *DPoPVerifier {
+	return &DPoPVerifier{
+		maxAge: maxAge,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// Verify checks a proof against the request and returns the RFC 7638
+// thumbprint of the proof's public key
+func (v *DPoPVerifier) Verify(req DPoPRequest) (string, error) {
+	var jwk map[string]interface{}
+	claims := &dpopClaims{}
+	_, err := jwt.ParseWithClaims(req.Proof, claims, func(t *jwt.Token) (interface{}, error) {
+		if typ, _ := t.Header["typ"].(string); typ != dpopProofType {
+			return nil, fmt.Errorf("typ must be %s", dpopProofType)
+		}
+		jwk, _ = t.Header["jwk"].(map[string]interface{})
+		return publicKeyFromJWK(jwk)
+	},
+		jwt.WithValidMethods([]string{"ES256", "RS256", "PS256"}),
+		jwt.WithoutClaimsValidation(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	now := v.now()
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return "", fmt.Errorf("%w: jti and iat are required", ErrInvalidDPoPProof)
+	}
+	if age := now.Sub(claims.IssuedAt.Time); age > v.maxAge || age < -v.maxAge {
+		return "", fmt.Errorf("%w: proof is outside the accepted time window", ErrInvalidDPoPProof)
+	}
+	if !strings.EqualFold(claims.HTTPMethod, req.Method) {
+		return "", fmt.Errorf("%w: htm does not match the request", ErrInvalidDPoPProof)
+	}
+	if normalizeHTU(claims.HTTPURI) != normalizeHTU(req.URL) {
+		return "", fmt.Errorf("%w: htu does not match the request", ErrInvalidDPoPProof)
+	}
+	if req.AccessToken != "" {
+		sum := sha256.Sum256([]byte(req.AccessToken))
+		want := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(claims.AccessTokenHash), []byte(want)) != 1 {
+			return "", fmt.Errorf("%w: ath does not match the access token", ErrInvalidDPoPProof)
+		}
+	}
+
+	thumbprint, err := jwkThumbprint(jwk)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	// Checked last so a rejected proof does not burn its jti
+	if !v.remember(thumbprint+":"+claims.ID, now) {
+		return "", fmt.Errorf("%w: proof has already been used", ErrInvalidDPoPProof)
+	}
+
+	return thumbprint, nil
+}
+
+// remember records a proof ID, reporting false if it was already seen
+func (v *DPoPVerifier) remember(id string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if now.Sub(v.lastSweep) > v.maxAge {
+		for seenID, expires := range v.seen {
+			if now.After(expires) {
+				delete(v.seen, seenID)
+			}
+		}
+		v.lastSweep = now
+	}
+	if expires, replayed := v.seen[id]; replayed && !now.After(expires) {
+		return false
+	}
+	// A proof stays acceptable until iat+maxAge, at most 2*maxAge from now
+	v.seen[id] = now.Add(2 * v.maxAge)
+	return true
+}
+
+// normalizeHTU strips the query and fragment and lowercases scheme and host
+func normalizeHTU(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// publicKeyFromJWK decodes a public EC P-256 or RSA JWK
+func publicKeyFromJWK(jwk map[string]interface{}) (interface{}, error) {
+	if jwk == nil {
+		return nil, errors.New("jwk header is required")
+	}
+	if _, private := jwk["d"]; private {
+		return nil, errors.New("jwk must not contain a private key")
+	}
+
+	member := func(name string) (*big.Int, error) {
+		s, _ := jwk[name].(string)
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("jwk %s is invalid", name)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch jwk["kty"] {
+	case "EC":
+		if jwk["crv"] != "P-256" {
+			return nil, errors.New("only the P-256 curve is supported")
+		}
+		x, err := member("x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := member("y")
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("jwk point is not on the curve")
+		}
+		return key, nil
+	case "RSA":
+		n, err := member("n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := member("e")
+		if err != nil {
+			return nil, err
+		}
+		if n.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must have at least 2048 bits")
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("jwk exponent is invalid")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	default:
+		return nil, errors.New("jwk kty must be EC or RSA")
+	}
+}
+
+// jwkThumbprint computes the RFC 7638 SHA-256 thumbprint of a public JWK
+func jwkThumbprint(jwk map[string]interface{}) (string, error) {
+	var required []string
+	switch jwk["kty"] {
+	case "EC":
+		required = []string{"crv", "kty", "x", "y"}
+	case "RSA":
+		required = []string{"e", "kty", "n"}
+	default:
+		return "", errors.New("jwk kty must be EC or RSA")
+	}
+
+	// Members in lexicographic order with no whitespace
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range required {
+		value, ok := jwk[name].(string)
+		if !ok {
+			return "", fmt.Errorf("jwk %s is required", name)
+		}
+		encoded, _ := json.Marshal(value)
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%q:%s", name, encoded)
+	}
+	b.WriteByte('}')
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
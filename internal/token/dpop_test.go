@@ -0,0 +1,270 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// dpopProofOptions overrides fields of a generated test proof
+type dpopProofOptions struct {
+	typ         string
+	jti         string
+	iat         time.Time
+	accessToken string
+}
+
+func generateDPoPProof(t *testing.T, key *ecdsa.PrivateKey, method, url string, opts dpopProofOptions) string {
+	t.Helper()
+
+	if opts.typ == "" {
+		opts.typ = dpopProofType
+	}
+	if opts.jti == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			t.Fatalf("Failed to generate jti: %v", err)
+		}
+		opts.jti = base64.RawURLEncoding.EncodeToString(b)
+	}
+	if opts.iat.IsZero() {
+		opts.iat = time.Now()
+	}
+
+	claims := dpopClaims{
+		HTTPMethod:       method,
+		HTTPURI:          url,
+		RegisteredClaims: jwt.RegisteredClaims{ID: opts.jti, IssuedAt: jwt.NewNumericDate(opts.iat)},
+	}
+	if opts.accessToken != "" {
+		sum := sha256.Sum256([]byte(opts.accessToken))
+		claims.AccessTokenHash = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	proof := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	proof.Header["typ"] = opts.typ
+	proof.Header["jwk"] = ecJWK(&key.PublicKey)
+	signed, err := proof.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign DPoP proof: %v", err)
+	}
+	return signed
+}
+
+func ecJWK(key *ecdsa.PublicKey) map[string]interface{} {
+	return map[string]interface{}{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func TestDPoPVerifier_Verify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	wantThumbprint, err := jwkThumbprint(ecJWK(&key.PublicKey))
+	if err != nil {
+		t.Fatalf("jwkThumbprint() error = %v", err)
+	}
+
+	const url = "https://auth.example.com/api/v1/auth/login"
+
+	tests := []struct {
+		name    string
+		proof   string
+		req     DPoPRequest
+		wantErr bool
+	}{
+		{
+			name:  "valid proof",
+			proof: generateDPoPProof(t, key, "POST", url, dpopProofOptions{}),
+			req:   DPoPRequest{Method: "POST", URL: url},
+		},
+		{
+			name:  "query string is ignored",
+			proof: generateDPoPProof(t, key, "POST", url, dpopProofOptions{}),
+			req:   DPoPRequest{Method: "POST", URL: url + "?lang=en"},
+		},
+		{
+			name:  "valid proof with access token",
+			proof: generateDPoPProof(t, key, "GET", url, dpopProofOptions{accessToken: "access-token"}),
+			req:   DPoPRequest{Method: "GET", URL: url, AccessToken: "access-token"},
+		},
+		{
+			name:    "wrong method",
+			proof:   generateDPoPProof(t, key, "GET", url, dpopProofOptions{}),
+			req:     DPoPRequest{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name:    "wrong URL",
+			proof:   generateDPoPProof(t, key, "POST", "https://evil.example.com/api/v1/auth/login", dpopProofOptions{}),
+			req:     DPoPRequest{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name:    "stale proof",
+			proof:   generateDPoPProof(t, key, "POST", url, dpopProofOptions{iat: time.Now().Add(-5 * time.Minute)}),
+			req:     DPoPRequest{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name:    "wrong typ",
+			proof:   generateDPoPProof(t, key, "POST", url, dpopProofOptions{typ: "JWT"}),
+			req:     DPoPRequest{Method: "POST", URL: url},
+			wantErr: true,
+		},
+		{
+			name:    "access token hash mismatch",
+			proof:   generateDPoPProof(t, key, "GET", url, dpopProofOptions{accessToken: "other-token"}),
+			req:     DPoPRequest{Method: "GET", URL: url, AccessToken: "access-token"},
+			wantErr: true,
+		},
+		{
+			name:    "missing access token hash",
+			proof:   generateDPoPProof(t, key, "GET", url, dpopProofOptions{}),
+			req:     DPoPRequest{Method: "GET", URL: url, AccessToken: "access-token"},
+			wantErr: true,
+		},
+		{
+			name:    "not a JWT",
+			proof:   "not-a-proof",
+			req:     DPoPRequest{Method: "POST", URL: url},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewDPoPVerifier(time.Minute)
+			tt.req.Proof = tt.proof
+
+			thumbprint, err := v.Verify(tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDPoPProof) {
+					t.Errorf("Verify() error = %v, want ErrInvalidDPoPProof", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if thumbprint != wantThumbprint {
+				t.Errorf("Verify() thumbprint = %q, want %q", thumbprint, wantThumbprint)
+			}
+		})
+	}
+}
+
+func TestDPoPVerifier_Replay(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	v := NewDPoPVerifier(time.Minute)
+
+	const url = "https://auth.example.com/api/v1/auth/refresh"
+	req := DPoPRequest{
+		Proof:  generateDPoPProof(t, key, "POST", url, dpopProofOptions{jti: "proof-1"}),
+		Method: "POST",
+		URL:    url,
+	}
+
+	if _, err := v.Verify(req); err != nil {
+		t.Fatalf("first Verify() error = %v", err)
+	}
+	if _, err := v.Verify(req); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Errorf("replayed Verify() error = %v, want ErrInvalidDPoPProof", err)
+	}
+
+	// Once the proof has aged out of the window its jti is forgotten
+	v.now = func() time.Time { return time.Now().Add(3 * time.Minute) }
+	fresh := DPoPRequest{
+		Proof:  generateDPoPProof(t, key, "POST", url, dpopProofOptions{iat: v.now()}),
+		Method: "POST",
+		URL:    url,
+	}
+	if _, err := v.Verify(fresh); err != nil {
+		t.Fatalf("Verify() after sweep error = %v", err)
+	}
+	if _, ok := v.seen[jtiKey(t, key, "proof-1")]; ok {
+		t.Error("expired proof IDs should be swept")
+	}
+}
+
+func jtiKey(t *testing.T, key *ecdsa.PrivateKey, jti string) string {
+	t.Helper()
+	thumbprint, err := jwkThumbprint(ecJWK(&key.PublicKey))
+	if err != nil {
+		t.Fatalf("jwkThumbprint() error = %v", err)
+	}
+	return thumbprint + ":" + jti
+}
+
+func TestJWKThumbprint_RFC7638(t *testing.T) {
+	// Example key and thumbprint from RFC 7638 section 3.1
+	jwk := map[string]interface{}{
+		"kty": "RSA",
+		"n":   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		"e":   "AQAB",
+		"alg": "RS256",
+		"kid": "2011-04-29",
+	}
+
+	got, err := jwkThumbprint(jwk)
+	if err != nil {
+		t.Fatalf("jwkThumbprint() error = %v", err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("jwkThumbprint() = %q, want %q", got, want)
+	}
+}
+
+func TestManager_VerifyDPoPProof_Disabled(t *testing.T) {
+	manager, err := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	if _, err := manager.VerifyDPoPProof(DPoPRequest{Proof: "proof"}); !errors.Is(err, ErrDPoPDisabled) {
+		t.Errorf("VerifyDPoPProof() error = %v, want ErrDPoPDisabled", err)
+	}
+}
+
+func TestClaims_VerifyDPoPBinding(t *testing.T) {
+	bound := &Claims{Confirmation: &Confirmation{JWKThumbprint: "thumb"}}
+	unbound := &Claims{}
+
+	tests := []struct {
+		name       string
+		claims     *Claims
+		scheme     string
+		thumbprint string
+		wantErr    bool
+	}{
+		{name: "unbound bearer", claims: unbound, scheme: "Bearer"},
+		{name: "unbound sent as DPoP", claims: unbound, scheme: "DPoP", wantErr: true},
+		{name: "bound with matching proof", claims: bound, scheme: "DPoP", thumbprint: "thumb"},
+		{name: "bound with other key", claims: bound, scheme: "DPoP", thumbprint: "other", wantErr: true},
+		{name: "bound sent as bearer", claims: bound, scheme: "Bearer", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.claims.VerifyDPoPBinding(tt.scheme, tt.thumbprint)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyDPoPBinding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("VerifyDPoPBinding() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package token
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// Key management algorithms accepted by EnableEncryption (RFC 7518 section 4)
+const (
+	KeyAlgorithmRSAOAEP    = "RSA-OAEP"
+	KeyAlgorithmRSAOAEP256 = "RSA-OAEP-256"
+	KeyAlgorithmECDHES     = "ECDH-ES"
+)
+
+// contentEncryptionA256GCM is the only content encryption algorithm issued
+// and accepted
+const contentEncryptionA256GCM = "A256GCM"
+
+const cekSize = 32 // A256GCM key size in bytes
+
+// ErrEncryptionDisabled is returned when an encrypted token is presented but
+// token encryption is not enabled
+var ErrEncryptionDisabled = errors.New("token encryption is not enabled")
+
+// jweHeader is the protected header of a compact JWE (RFC 7516 section 4)
+type jweHeader struct {
+	Algorithm    string            `json:"alg"`
+	Encryption   string            `json:"enc"`
+	ContentType  string            `json:"cty,omitempty"`
+	KeyID        string            `json:"kid,omitempty"`
+	EphemeralKey map[string]string `json:"epk,omitempty"`
+	Compression  string            `json:"zip,omitempty"`
+	Critical     []string          `json:"crit,omitempty"`
+}
+
+// encryptionKey wraps content encryption keys for access tokens. The same
+// key pair encrypts on issue and decrypts on validation.
+type encryptionKey struct {
+	algorithm string
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdh.PrivateKey
+	jwk       map[string]string // public key, without use, alg and kid
+	kid       string
+}
+
+// EnableEncryption encrypts issued access tokens as nested JWTs (RFC 7519
+// section 5.2) with A256GCM, wrapping the content key with the private key
+// at privateKeyPath. RSA-OAEP and RSA-OAEP-256 need an RSA key, ECDH-ES a
+// P-256, P-384 or P-521 key. Signed tokens issued before encryption was
+// enabled stay valid until they expire.
+func (m *Manager) EnableEncryption(algorithm, privateKeyPath string) error {
+	data, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read encryption key: %w", err)
+	}
+	key, err := newEncryptionKey(algorithm, data)
+	if err != nil {
+		return err
+	}
+	m.encryption = key
+	return nil
+}
+
+// newEncryptionKey parses a PEM private key for the given algorithm
+func newEncryptionKey(algorithm string, pemData []byte) (*encryptionKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("failed to parse encryption key: no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("failed to parse encryption key: unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse encryption key: %w", err)
+	}
+
+	key := &encryptionKey{algorithm: algorithm}
+	switch algorithm {
+	case KeyAlgorithmRSAOAEP, KeyAlgorithmRSAOAEP256:
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an RSA key", algorithm)
+		}
+		if rsaKey.N.BitLen() < 2048 {
+			return nil, errors.New("RSA encryption keys must have at least 2048 bits")
+		}
+		key.rsaKey = rsaKey
+		key.jwk = map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		}
+	case KeyAlgorithmECDHES:
+		var ecKey *ecdh.PrivateKey
+		switch k := parsed.(type) {
+		case *ecdsa.PrivateKey:
+			if ecKey, err = k.ECDH(); err != nil {
+				return nil, fmt.Errorf("failed to parse encryption key: %w", err)
+			}
+		case *ecdh.PrivateKey:
+			ecKey = k
+		default:
+			return nil, fmt.Errorf("%s requires an EC key", algorithm)
+		}
+		jwk, err := ecPublicJWK(ecKey.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+		key.ecKey = ecKey
+		key.jwk = jwk
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm: %s", algorithm)
+	}
+
+	thumbprintInput := make(map[string]interface{}, len(key.jwk))
+	for k, v := range key.jwk {
+		thumbprintInput[k] = v
+	}
+	kid, err := jwkThumbprint(thumbprintInput)
+	if err != nil {
+		return nil, err
+	}
+	key.kid = kid
+
+	return key, nil
+}
+
+// publicJWK returns the JWKS entry advertising the encryption key
+func (k *encryptionKey) publicJWK() map[string]interface{} {
+	jwk := map[string]interface{}{
+		"use": "enc",
+		"kid": k.kid,
+		"alg": k.algorithm,
+	}
+	for name, value := range k.jwk {
+		jwk[name] = value
+	}
+	return jwk
+}
+
+// encrypt seals a signed JWT into a compact JWE
+func (k *encryptionKey) encrypt(signed string) (string, error) {
+	header := jweHeader{
+		Algorithm:   k.algorithm,
+		Encryption:  contentEncryptionA256GCM,
+		ContentType: "JWT",
+		KeyID:       k.kid,
+	}
+
+	var cek, encryptedKey []byte
+	switch k.algorithm {
+	case KeyAlgorithmECDHES:
+		ephemeral, err := k.ecKey.Curve().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate ephemeral key: %w", err)
+		}
+		if header.EphemeralKey, err = ecPublicJWK(ephemeral.PublicKey()); err != nil {
+			return "", err
+		}
+		shared, err := ephemeral.ECDH(k.ecKey.PublicKey())
+		if err != nil {
+			return "", fmt.Errorf("failed to agree on content key: %w", err)
+		}
+		cek = concatKDF(shared, contentEncryptionA256GCM, cekSize)
+	default:
+		cek = make([]byte, cekSize)
+		if _, err := rand.Read(cek); err != nil {
+			return "", fmt.Errorf("failed to generate content key: %w", err)
+		}
+		var err error
+		encryptedKey, err = rsa.EncryptOAEP(oaepHash(k.algorithm), rand.Reader, &k.rsaKey.PublicKey, cek, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to wrap content key: %w", err)
+		}
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWE header: %w", err)
+	}
+	protected := base64.RawURLEncoding.EncodeToString(headerJSON)
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("failed to generate IV: %w", err)
+	}
+	sealed := gcm.Seal(nil, iv, []byte(signed), []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	enc := base64.RawURLEncoding.EncodeToString
+	return strings.Join([]string{protected, enc(encryptedKey), enc(iv), enc(ciphertext), enc(tag)}, "."), nil
+}
+
+// decrypt opens a compact JWE and returns the nested JWT
+func (k *encryptionKey) decrypt(compact string) (string, error) {
+	parts := strings.Split(compact, ".")
+	if len(parts) != 5 {
+		return "", errors.New("JWE must have five parts")
+	}
+	decoded := make([][]byte, 5)
+	for i, part := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return "", fmt.Errorf("JWE part %d is not base64url", i+1)
+		}
+		decoded[i] = b
+	}
+
+	var header jweHeader
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		return "", errors.New("JWE header is invalid")
+	}
+	// Only the configured algorithm is accepted so a token cannot pick a
+	// weaker key management mode
+	if header.Algorithm != k.algorithm || header.Encryption != contentEncryptionA256GCM {
+		return "", fmt.Errorf("unexpected JWE algorithms %s/%s", header.Algorithm, header.Encryption)
+	}
+	if header.Compression != "" || len(header.Critical) > 0 {
+		return "", errors.New("JWE header uses unsupported extensions")
+	}
+
+	var cek []byte
+	switch k.algorithm {
+	case KeyAlgorithmECDHES:
+		if len(decoded[1]) != 0 {
+			return "", errors.New("ECDH-ES tokens must not carry an encrypted key")
+		}
+		ephemeral, err := ecPublicKeyFromJWK(header.EphemeralKey, k.ecKey.Curve())
+		if err != nil {
+			return "", err
+		}
+		shared, err := k.ecKey.ECDH(ephemeral)
+		if err != nil {
+			return "", errors.New("JWE ephemeral key is invalid")
+		}
+		cek = concatKDF(shared, contentEncryptionA256GCM, cekSize)
+	default:
+		var err error
+		cek, err = rsa.DecryptOAEP(oaepHash(k.algorithm), nil, k.rsaKey, decoded[1], nil)
+		if err != nil || len(cek) != cekSize {
+			return "", errors.New("JWE content key cannot be unwrapped")
+		}
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	if len(decoded[2]) != gcm.NonceSize() || len(decoded[4]) != gcm.Overhead() {
+		return "", errors.New("JWE IV or tag has the wrong size")
+	}
+	sealed := append(decoded[3], decoded[4]...)
+	plaintext, err := gcm.Open(nil, decoded[2], sealed, []byte(parts[0]))
+	if err != nil {
+		return "", errors.New("JWE decryption failed")
+	}
+	return string(plaintext), nil
+}
+
+// openAccess returns the signed JWT inside an encrypted access token, or the
+// token unchanged when it is not encrypted
+func (m *Manager) openAccess(tokenString string) (string, error) {
+	if strings.Count(tokenString, ".") != 4 {
+		return tokenString, nil
+	}
+	if m.encryption == nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidToken, ErrEncryptionDisabled)
+	}
+	signed, err := m.encryption.decrypt(tokenString)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return signed, nil
+}
+
+// newGCM returns an AES-GCM AEAD for the content key
+func newGCM(cek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create content cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// oaepHash returns the OAEP hash of an RSA key management algorithm
+func oaepHash(algorithm string) hash.Hash {
+	if algorithm == KeyAlgorithmRSAOAEP {
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+// concatKDF derives a key from an ECDH shared secret as specified for
+// ECDH-ES in direct key agreement mode (RFC 7518 section 4.6.2), with empty
+// apu and apv
+func concatKDF(shared []byte, algorithmID string, size int) []byte {
+	var otherInfo []byte
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(len(algorithmID)))
+	otherInfo = append(otherInfo, algorithmID...)
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, 0) // PartyUInfo
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, 0) // PartyVInfo
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(size*8))
+
+	var key []byte
+	for counter := uint32(1); len(key) < size; counter++ {
+		h := sha256.New()
+		h.Write(binary.BigEndian.AppendUint32(nil, counter))
+		h.Write(shared)
+		h.Write(otherInfo)
+		key = h.Sum(key)
+	}
+	return key[:size]
+}
+
+// ecCurves maps JWK curve names to ECDH curves
+var ecCurves = map[string]ecdh.Curve{
+	"P-256": ecdh.P256(),
+	"P-384": ecdh.P384(),
+	"P-521": ecdh.P521(),
+}
+
+// ecPublicJWK returns the JWK members of an EC public key
+func ecPublicJWK(key *ecdh.PublicKey) (map[string]string, error) {
+	for name, curve := range ecCurves {
+		if curve != key.Curve() {
+			continue
+		}
+		// Uncompressed point: 0x04 || X || Y, each coordinate padded to
+		// the curve size
+		point := key.Bytes()[1:]
+		size := len(point) / 2
+		return map[string]string{
+			"kty": "EC",
+			"crv": name,
+			"x":   base64.RawURLEncoding.EncodeToString(point[:size]),
+			"y":   base64.RawURLEncoding.EncodeToString(point[size:]),
+		}, nil
+	}
+	return nil, errors.New("EC encryption keys must use P-256, P-384 or P-521")
+}
+
+// ecPublicKeyFromJWK parses an ephemeral EC public key on the given curve
+func ecPublicKeyFromJWK(jwk map[string]string, curve ecdh.Curve) (*ecdh.PublicKey, error) {
+	if jwk["kty"] != "EC" || ecCurves[jwk["crv"]] != curve {
+		return nil, errors.New("JWE ephemeral key must be on the encryption key's curve")
+	}
+	x, errX := base64.RawURLEncoding.DecodeString(jwk["x"])
+	y, errY := base64.RawURLEncoding.DecodeString(jwk["y"])
+	if errX != nil || errY != nil || len(x) != len(y) {
+		return nil, errors.New("JWE ephemeral key is invalid")
+	}
+	point := append([]byte{4}, x...)
+	point = append(point, y...)
+	key, err := curve.NewPublicKey(point)
+	if err != nil {
+		return nil, errors.New("JWE ephemeral key is invalid")
+	}
+	return key, nil
+}
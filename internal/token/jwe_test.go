@@ -0,0 +1,201 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeEncryptionKey writes a new private key for algorithm and returns its path
+func writeEncryptionKey(t *testing.T, algorithm string, curve elliptic.Curve) string {
+	t.Helper()
+
+	var block *pem.Block
+	if algorithm == KeyAlgorithmECDHES {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate EC key: %v", err)
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatalf("Failed to marshal EC key: %v", err)
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	} else {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate RSA key: %v", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("Failed to marshal RSA key: %v", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	path := filepath.Join(t.TempDir(), "enc.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return path
+}
+
+func newEncryptingManager(t *testing.T, algorithm string, curve elliptic.Curve) *Manager {
+	t.Helper()
+
+	manager, err := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := manager.EnableEncryption(algorithm, writeEncryptionKey(t, algorithm, curve)); err != nil {
+		t.Fatalf("EnableEncryption() error = %v", err)
+	}
+	return manager
+}
+
+func TestManager_EncryptedAccessToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		curve     elliptic.Curve
+	}{
+		{"RSA-OAEP", KeyAlgorithmRSAOAEP, nil},
+		{"RSA-OAEP-256", KeyAlgorithmRSAOAEP256, nil},
+		{"ECDH-ES P-256", KeyAlgorithmECDHES, elliptic.P256()},
+		{"ECDH-ES P-384", KeyAlgorithmECDHES, elliptic.P384()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newEncryptingManager(t, tt.algorithm, tt.curve)
+
+			tokenString, err := manager.GenerateAccessToken("user-1", "user@example.com", true)
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error = %v", err)
+			}
+			if parts := strings.Count(tokenString, ".") + 1; parts != 5 {
+				t.Fatalf("token has %d parts, want 5", parts)
+			}
+
+			claims, err := manager.ValidateAccessToken(tokenString)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken() error = %v", err)
+			}
+			if claims.UserID != "user-1" || claims.Email != "user@example.com" {
+				t.Errorf("claims = %+v", claims)
+			}
+
+			// Flipping a ciphertext character must fail authentication
+			parts := strings.Split(tokenString, ".")
+			c := []byte(parts[3])
+			if c[0] == 'A' {
+				c[0] = 'B'
+			} else {
+				c[0] = 'A'
+			}
+			parts[3] = string(c)
+			if _, err := manager.ValidateAccessToken(strings.Join(parts, ".")); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("tampered token error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestManager_EncryptedAccessToken_Rejected(t *testing.T) {
+	issuer := newEncryptingManager(t, KeyAlgorithmRSAOAEP256, nil)
+	tokenString, err := issuer.GenerateAccessToken("user-1", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+
+	// Same signing secret, no encryption
+	plain, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if _, err := plain.ValidateAccessToken(tokenString); !errors.Is(err, ErrEncryptionDisabled) {
+		t.Errorf("ValidateAccessToken() error = %v, want %v", err, ErrEncryptionDisabled)
+	}
+
+	// Same signing secret, another encryption key
+	other := newEncryptingManager(t, KeyAlgorithmRSAOAEP256, nil)
+	if _, err := other.ValidateAccessToken(tokenString); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	// Tokens signed before encryption was enabled stay valid
+	signed, err := plain.GenerateAccessToken("user-1", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	if _, err := issuer.ValidateAccessToken(signed); err != nil {
+		t.Errorf("ValidateAccessToken() of signed token error = %v", err)
+	}
+}
+
+func TestManager_EnableEncryption_Errors(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+
+	rsaKey := writeEncryptionKey(t, KeyAlgorithmRSAOAEP, nil)
+	ecKey := writeEncryptionKey(t, KeyAlgorithmECDHES, elliptic.P256())
+
+	tests := []struct {
+		name      string
+		algorithm string
+		path      string
+	}{
+		{"missing file", KeyAlgorithmRSAOAEP, filepath.Join(t.TempDir(), "missing.pem")},
+		{"RSA algorithm with EC key", KeyAlgorithmRSAOAEP256, ecKey},
+		{"ECDH-ES with RSA key", KeyAlgorithmECDHES, rsaKey},
+		{"unsupported algorithm", "A256KW", rsaKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.EnableEncryption(tt.algorithm, tt.path); err == nil {
+				t.Error("EnableEncryption() should return error")
+			}
+		})
+	}
+	if manager.encryption != nil {
+		t.Error("failed EnableEncryption() calls must not enable encryption")
+	}
+}
+
+func TestManager_GetJWKS_EncryptionKey(t *testing.T) {
+	manager := newEncryptingManager(t, KeyAlgorithmECDHES, elliptic.P256())
+
+	jwks, err := manager.GetJWKS()
+	if err != nil {
+		t.Fatalf("GetJWKS() error = %v", err)
+	}
+	keys := jwks["keys"].([]map[string]interface{})
+	if len(keys) != 1 {
+		t.Fatalf("GetJWKS() returned %d keys, want 1", len(keys))
+	}
+	key := keys[0]
+	if key["use"] != "enc" || key["alg"] != KeyAlgorithmECDHES || key["crv"] != "P-256" {
+		t.Errorf("encryption key = %v", key)
+	}
+	if _, private := key["d"]; private {
+		t.Error("JWKS must not contain the private key")
+	}
+
+	// The kid is the key's thumbprint, as found in issued tokens
+	if _, err := publicKeyFromJWK(key); err != nil {
+		t.Fatalf("publicKeyFromJWK() error = %v", err)
+	}
+	thumbprint, err := jwkThumbprint(key)
+	if err != nil {
+		t.Fatalf("jwkThumbprint() error = %v", err)
+	}
+	if key["kid"] != thumbprint {
+		t.Errorf("kid = %v, want %s", key["kid"], thumbprint)
+	}
+}
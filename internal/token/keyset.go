@@ -0,0 +1,206 @@
+package token
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultKeySetMinRefreshInterval is how often a KeySet refetches the JWKS at
+// most when tokens name keys it does not know
+const DefaultKeySetMinRefreshInterval = 10 * time.Second
+
+// maxJWKSBytes bounds the JWKS document a KeySet reads
+const maxJWKSBytes = 1 << 20
+
+// ErrKeySetNotLoaded is returned when a KeySet has not fetched the JWKS yet
+var ErrKeySetNotLoaded = errors.New("JWKS has not been fetched yet")
+
+// HTTPDoer sends HTTP requests, e.g. an *http.Client
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// KeySet holds the RS256 verification keys published at another service's
+// JWKS URL, such as this service's /.well-known/jwks.json. Keys rolled in
+// by the issuer are picked up when a token names an unknown kid.
+type KeySet struct {
+	url    string
+	client HTTPDoer
+
+	minRefreshInterval time.Duration
+	now                func() time.Time
+
+	refreshMu sync.Mutex // serializes fetches
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	firstKey    *rsa.PublicKey // the oldest published key, see Key
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// NewKeySet creates a key set for the JWKS at url. Keys are fetched on the
+// first Refresh or lookup.
+func NewKeySet(url string, client HTTPDoer) *KeySet {
+	return &KeySet{
+		url:                url,
+		client:             client,
+		minRefreshInterval: DefaultKeySetMinRefreshInterval,
+		now:                time.Now,
+	}
+}
+
+// SetMinRefreshInterval sets how often unknown kids trigger a refetch at most
+func (s *KeySet) SetMinRefreshInterval(interval time.Duration) {
+	s.minRefreshInterval = interval
+}
+
+// Loaded reports whether the JWKS has been fetched
+func (s *KeySet) Loaded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.fetchedAt.IsZero()
+}
+
+// FetchedAt returns when the JWKS was last fetched; zero before the first
+// fetch
+func (s *KeySet) FetchedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fetchedAt
+}
+
+// Key returns the key a token's kid names, refetching the JWKS once per
+// minimum refresh interval when the kid is unknown. Tokens signed before
+// keys had their own kid carry "default" and are checked with the oldest
+// published key, which is the issuer's configured key until it retires.
+func (s *KeySet) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+
+	s.mu.RLock()
+	recent := !s.attemptedAt.IsZero() && s.now().Sub(s.attemptedAt) < s.minRefreshInterval
+	s.mu.RUnlock()
+	if !recent {
+		if err := s.Refresh(ctx); err != nil && !s.Loaded() {
+			return nil, fmt.Errorf("%w: %v", ErrKeySetNotLoaded, err)
+		}
+		if key, ok := s.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	if !s.Loaded() {
+		return nil, ErrKeySetNotLoaded
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup returns the cached key for kid
+func (s *KeySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if kid == legacyKeyID && s.firstKey != nil {
+		return s.firstKey, true
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// Refresh fetches the JWKS and replaces the cached keys. On failure the
+// previous keys stay in use.
+func (s *KeySet) Refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.Lock()
+	s.attemptedAt = s.now()
+	s.mu.Unlock()
+
+	keys, first, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.firstKey = first
+	s.fetchedAt = s.now()
+	s.mu.Unlock()
+	return nil
+}
+
+// fetch downloads the JWKS and returns its RS256 signing keys by kid, and
+// the first of them
+func (s *KeySet) fetch(ctx context.Context) (map[string]*rsa.PublicKey, *rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBytes)).Decode(&jwks); err != nil {
+		return nil, nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	var first *rsa.PublicKey
+	for _, jwk := range jwks.Keys {
+		// Encryption keys and keys for other algorithms never sign access tokens
+		kid, _ := jwk["kid"].(string)
+		if kid == "" || jwk["kty"] != "RSA" || (jwk["use"] != nil && jwk["use"] != "sig") || (jwk["alg"] != nil && jwk["alg"] != "RS256") {
+			continue
+		}
+		key, err := publicKeyFromJWK(jwk)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JWKS key %q: %w", kid, err)
+		}
+		keys[kid] = key.(*rsa.PublicKey)
+		if first == nil {
+			first = keys[kid]
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil, errors.New("JWKS has no RS256 signing keys")
+	}
+	return keys, first, nil
+}
+
+// Run refreshes the key set every interval until ctx is cancelled, logging
+// failures
+func (s *KeySet) Run(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				logger.WarnContext(ctx, "failed to refresh JWKS", "url", s.url, "error", err)
+			}
+		}
+	}
+}
@@ -2,12 +2,17 @@ package token
 
 import (
 	"crypto/rsa"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"math/big"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
 )
 
 var (
@@ -17,24 +22,100 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 	// ErrInvalidSigningMethod is returned when the signing method is invalid
 	ErrInvalidSigningMethod = errors.New("invalid signing method")
+	// ErrTokenNotValidYet is returned when the token's nbf is in the future
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
 )
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID        string `json:"user_id"`
-	Email         string `json:"email"`
-	EmailVerified bool   `json:"email_verified"`
+	UserID        string                 `json:"user_id"`
+	Email         string                 `json:"email"`
+	EmailVerified bool                   `json:"email_verified"`
+	Locale        string                 `json:"locale,omitempty"`
+	Confirmation  *Confirmation          `json:"cnf,omitempty"`
+	TokenVersion  int                    `json:"token_version,omitempty"` // user's token version when issued
+	Roles         []string               `json:"roles,omitempty"`         // not issued to users; checked by route policies
+	Scope         string                 `json:"scope,omitempty"`         // space-delimited, RFC 9068
+	ClaimsRef     string                 `json:"cref,omitempty"`          // roles and scope held server-side
+	ClientID      string                 `json:"client_id,omitempty"`     // set on tokens issued to clients acting for themselves
+	Use           string                 `json:"token_use,omitempty"`     // set on single-purpose tokens, such as connection tokens
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`      // selected keys of the user's metadata
+	AppMetadata   map[string]interface{} `json:"app_metadata,omitempty"`  // selected keys of the user's app_metadata
+	AccessWindow  *AccessWindow          `json:"access_window,omitempty"` // daily window of local time the token is accepted in
 	jwt.RegisteredClaims
 }
 
+// AccessWindow limits when an access token is accepted to a daily window of
+// local time. End is before Start for a window spanning midnight.
+type AccessWindow struct {
+	Start    string `json:"start"` // HH:MM
+	End      string `json:"end"`   // HH:MM
+	Timezone string `json:"tz"`    // IANA time zone
+}
+
+// Scopes returns the scopes granted by the token
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// IDTokenUse is the token_use claim of ID tokens. It keeps them from being
+// accepted as access tokens, which are signed with the same key and issuer.
+const IDTokenUse = "id"
+
+// IDTokenClaims represents the claims of an OIDC-shaped ID token
+type IDTokenClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+	Locale            string `json:"locale,omitempty"`
+	Zoneinfo          string `json:"zoneinfo,omitempty"`
+	UpdatedAt         int64  `json:"updated_at,omitempty"`
+	AuthTime          int64  `json:"auth_time,omitempty"`
+	Use               string `json:"token_use"`
+	jwt.RegisteredClaims
+}
+
+// IDTokenUser holds the user attributes carried by an ID token. Empty
+// optional fields are omitted from the token.
+type IDTokenUser struct {
+	ID            string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Name          string
+	Picture       string
+	Locale        string
+	Zoneinfo      string
+	UpdatedAt     time.Time
+}
+
 // Manager handles JWT token operations
 type Manager struct {
+	// mu guards the settings Reload swaps: the algorithm, secret, keys,
+	// issuer and access token lifetime. Token operations hold it for reading
+	// until they finish, so they never mix old and new settings and a reload
+	// waits for the validations in flight.
+	mu             sync.RWMutex
 	algorithm      string
 	secret         []byte
-	privateKey     *rsa.PrivateKey
-	publicKey      *rsa.PublicKey
 	issuer         string
 	accessTokenTTL time.Duration
+
+	clockSkew  time.Duration
+	dpop       *DPoPVerifier
+	encryption *encryptionKey // access tokens are issued as JWEs when set
+
+	maxTokenBytes int // signed access token size budget; 0 disables it
+	oversizeMode  OversizeMode
+	claimStore    ClaimStore
+
+	// RS256 keys, oldest activation first; see rollover.go
+	keys           []*signingKey
+	legacyKeyID    string // kid of the configured key, which tokens signed with kid "default" carry
+	rolloverWindow time.Duration
+	clock          clock.Clock
 }
 
 // NewManager creates a new token manager
@@ -43,6 +124,7 @@ func NewManager(algorithm, secret, privateKeyPath, publicKeyPath, issuer string,
 		algorithm:      algorithm,
 		issuer:         issuer,
 		accessTokenTTL: accessTokenTTL,
+		clock:          clock.Real{},
 	}
 
 	switch algorithm {
@@ -67,7 +149,6 @@ func NewManager(algorithm, secret, privateKeyPath, publicKeyPath, issuer string,
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse private key: %w", err)
 		}
-		m.privateKey = privateKey
 
 		// Load public key
 		publicKeyData, err := os.ReadFile(publicKeyPath)
@@ -79,7 +160,16 @@ func NewManager(algorithm, secret, privateKeyPath, publicKeyPath, issuer string,
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse public key: %w", err)
 		}
-		m.publicKey = publicKey
+		if !publicKey.Equal(&privateKey.PublicKey) {
+			return nil, fmt.Errorf("public key does not match the private key")
+		}
+
+		kid, err := rsaKeyID(&privateKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		m.keys = []*signingKey{{kid: kid, privateKey: privateKey}}
+		m.legacyKeyID = kid
 
 	default:
 		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
@@ -88,10 +178,148 @@ func NewManager(algorithm, secret, privateKeyPath, publicKeyPath, issuer string,
 	return m, nil
 }
 
+// MinFIPSRSAKeyBits is the smallest RSA modulus accepted in FIPS mode
+const MinFIPSRSAKeyBits = 2048
+
+// ValidateFIPS reports an error unless tokens are signed with a FIPS-approved
+// algorithm and key size
+func (m *Manager) ValidateFIPS() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.algorithm != "RS256" {
+		return fmt.Errorf("algorithm %s is not allowed in FIPS mode; use RS256", m.algorithm)
+	}
+	for _, key := range m.keys {
+		if bits := key.privateKey.N.BitLen(); bits < MinFIPSRSAKeyBits {
+			return fmt.Errorf("RSA key has %d bits; FIPS mode requires at least %d", bits, MinFIPSRSAKeyBits)
+		}
+	}
+	return nil
+}
+
+// SetClockSkew sets the leeway applied to exp, nbf and iat validation.
+// Tokens remain accepted for up to skew after their expiry, so the effective
+// access token lifetime is accessTokenTTL + skew.
+func (m *Manager) SetClockSkew(skew time.Duration) {
+	if skew < 0 {
+		skew = 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clockSkew = skew
+}
+
+// SetClock sets the clock tokens are issued, validated and rolled over by.
+// The system clock is used by default.
+func (m *Manager) SetClock(c clock.Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = clock.OrReal(c)
+}
+
+// ClockSkew returns the configured clock skew tolerance
+func (m *Manager) ClockSkew() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.clockSkew
+}
+
+// AccessTokenTTL returns how long access tokens are valid
+func (m *Manager) AccessTokenTTL() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.accessTokenTTL
+}
+
 // GenerateAccessToken generates a new access token
 func (m *Manager) GenerateAccessToken(userID, email string, emailVerified bool) (string, error) {
-	now := time.Now()
-	claims := Claims{
+	// A zero notBefore is raised to the current time under the lock, so
+	// the clock is never read while SetClock replaces it
+	return m.GenerateAccessTokenNotBefore(userID, email, emailVerified, time.Time{})
+}
+
+// MetadataClaims are user metadata keys copied into an access token
+type MetadataClaims struct {
+	Metadata    map[string]interface{}
+	AppMetadata map[string]interface{}
+}
+
+// AccessTokenOptions are the optional claims of an access token; zero values
+// omit their claim
+type AccessTokenOptions struct {
+	// Locale is the user's preferred locale
+	Locale string
+	// Confirmation binds the token to an mTLS client certificate (RFC 8705)
+	// or DPoP key (RFC 9449)
+	Confirmation Confirmation
+	// Version is the user's token version. Bumping the version in the
+	// database invalidates every token issued with an older one.
+	Version int
+	// Metadata is copied into the metadata and app_metadata claims
+	Metadata MetadataClaims
+	// Window limits the token to a daily access window
+	Window *AccessWindow
+}
+
+// GenerateAccessTokenWithOptions generates a new access token carrying the
+// given optional claims
+func (m *Manager) GenerateAccessTokenWithOptions(userID, email string, emailVerified bool, opts AccessTokenOptions) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	claims := m.newAccessClaims(userID, email, emailVerified, m.clock.Now())
+	claims.Locale = opts.Locale
+	if opts.Confirmation != (Confirmation{}) {
+		cnf := opts.Confirmation
+		claims.Confirmation = &cnf
+	}
+	claims.TokenVersion = opts.Version
+	if len(opts.Metadata.Metadata) > 0 {
+		claims.Metadata = opts.Metadata.Metadata
+	}
+	if len(opts.Metadata.AppMetadata) > 0 {
+		claims.AppMetadata = opts.Metadata.AppMetadata
+	}
+	claims.AccessWindow = opts.Window
+	return m.signAccess(claims)
+}
+
+// EnableDPoP accepts DPoP proofs issued within maxAge of the current time
+func (m *Manager) EnableDPoP(maxAge time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dpop = NewDPoPVerifier(maxAge)
+}
+
+// VerifyDPoPProof checks a DPoP proof and returns the thumbprint of its key
+func (m *Manager) VerifyDPoPProof(req DPoPRequest) (string, error) {
+	m.mu.RLock()
+	dpop := m.dpop
+	m.mu.RUnlock()
+	if dpop == nil {
+		return "", ErrDPoPDisabled
+	}
+	return dpop.Verify(req)
+}
+
+// GenerateAccessTokenNotBefore generates a new access token that is not valid
+// before the given time. The expiry is counted from notBefore.
+func (m *Manager) GenerateAccessTokenNotBefore(userID, email string, emailVerified bool, notBefore time.Time) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.signAccess(m.newAccessClaims(userID, email, emailVerified, notBefore))
+}
+
+// newAccessClaims builds access token claims valid from notBefore. The
+// caller holds mu.
+func (m *Manager) newAccessClaims(userID, email string, emailVerified bool, notBefore time.Time) Claims {
+	now := m.clock.Now()
+	if notBefore.Before(now) {
+		notBefore = now
+	}
+
+	return Claims{
 		UserID:        userID,
 		Email:         email,
 		EmailVerified: emailVerified,
@@ -99,27 +327,66 @@ func (m *Manager) GenerateAccessToken(userID, email string, emailVerified bool)
 			Issuer:    m.issuer,
 			Subject:   userID,
 			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(notBefore.Add(m.accessTokenTTL)),
+			NotBefore: jwt.NewNumericDate(notBefore),
+		},
+	}
+}
+
+// GenerateIDToken generates an OIDC-shaped ID token for the given audience
+func (m *Manager) GenerateIDToken(user IDTokenUser, authTime time.Time, audience string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.clock.Now()
+	claims := IDTokenClaims{
+		Email:             user.Email,
+		EmailVerified:     user.EmailVerified,
+		PreferredUsername: user.Username,
+		Name:              user.Name,
+		Picture:           user.Picture,
+		Locale:            user.Locale,
+		Zoneinfo:          user.Zoneinfo,
+		Use:               IDTokenUse,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTokenTTL)),
-			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
+	if !user.UpdatedAt.IsZero() {
+		claims.UpdatedAt = user.UpdatedAt.Unix()
+	}
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
+	}
+
+	return m.sign(claims)
+}
 
+// sign signs the given claims with the configured algorithm. The caller
+// holds mu.
+func (m *Manager) sign(claims jwt.Claims) (string, error) {
 	var token *jwt.Token
+	var signingKey interface{}
 	switch m.algorithm {
 	case "HS256":
 		token = jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		signingKey = m.getSigningKey()
 	case "RS256":
+		// The kid and key come from one lookup, so a token signed as a
+		// rollover activates names the key that signed it
+		key := m.activeKey()
 		token = jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = key.kid
+		signingKey = key.privateKey
 	default:
 		return "", fmt.Errorf("unsupported algorithm: %s", m.algorithm)
 	}
 
-	// Add key ID header for RS256
-	if m.algorithm == "RS256" {
-		token.Header["kid"] = "default"
-	}
-
-	tokenString, err := token.SignedString(m.getSigningKey())
+	tokenString, err := token.SignedString(signingKey)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -127,8 +394,34 @@ func (m *Manager) GenerateAccessToken(userID, email string, emailVerified bool)
 	return tokenString, nil
 }
 
-// ValidateAccessToken validates an access token and returns the claims
+// ValidateAccessToken validates an access token and returns the claims.
+// Single-purpose tokens are rejected.
 func (m *Manager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	claims, err := m.parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Use != "" {
+		return nil, fmt.Errorf("%w: %s token is not an access token", ErrInvalidToken, claims.Use)
+	}
+	if err := m.resolveClaimsRef(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// parseClaims verifies a token signed by the manager and returns its
+// claims. The caller holds mu.
+func (m *Manager) parseClaims(tokenString string) (*Claims, error) {
+	tokenString, err := m.openAccess(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		// Validate signing method
 		switch m.algorithm {
@@ -140,17 +433,22 @@ func (m *Manager) ValidateAccessToken(tokenString string) (*Claims, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 				return nil, ErrInvalidSigningMethod
 			}
+			kid, _ := token.Header["kid"].(string)
+			return m.verificationKey(kid)
 		default:
 			return nil, ErrInvalidSigningMethod
 		}
 
 		return m.getVerificationKey(), nil
-	})
+	}, jwt.WithLeeway(m.clockSkew), jwt.WithIssuedAt(), jwt.WithTimeFunc(m.clock.Now))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrExpiredToken
 		}
+		if errors.Is(err, jwt.ErrTokenNotValidYet) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidToken, ErrTokenNotValidYet)
+		}
 		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
 
@@ -166,57 +464,68 @@ func (m *Manager) ValidateAccessToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// GetPublicKey returns the public key for RS256 algorithm
+// GetPublicKey returns the public key that signs new RS256 tokens
 func (m *Manager) GetPublicKey() (*rsa.PublicKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	if m.algorithm != "RS256" {
 		return nil, fmt.Errorf("public key is only available for RS256 algorithm")
 	}
-	return m.publicKey, nil
+	return &m.activeKey().privateKey.PublicKey, nil
 }
 
-// GetJWKS returns the JSON Web Key Set for the public keys
+// GetJWKS returns the JSON Web Key Set for the public keys: the RS256 keys
+// in every rollover phase and, when tokens are encrypted, the encryption key
 func (m *Manager) GetJWKS() (map[string]interface{}, error) {
-	if m.algorithm != "RS256" {
-		return nil, fmt.Errorf("JWKS is only available for RS256 algorithm")
-	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	// This is a simplified JWKS response
-	// In production, you would want to properly encode the public key
-	jwks := map[string]interface{}{
-		"keys": []map[string]interface{}{
-			{
+	keys := []map[string]interface{}{}
+	if m.algorithm == "RS256" {
+		for _, key := range m.publishedKeys() {
+			publicKey := key.privateKey.PublicKey
+			keys = append(keys, map[string]interface{}{
 				"kty": "RSA",
 				"use": "sig",
-				"kid": "default",
+				"kid": key.kid,
 				"alg": "RS256",
-				"n":   "", // Base64 URL encoded modulus
-				"e":   "", // Base64 URL encoded exponent
-			},
-		},
+				"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		}
+	}
+	if m.encryption != nil {
+		keys = append(keys, m.encryption.publicJWK())
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS is only available for RS256 algorithm or encrypted tokens")
 	}
 
-	return jwks, nil
+	return map[string]interface{}{"keys": keys}, nil
 }
 
-// getSigningKey returns the key used for signing tokens
+// getSigningKey returns the key used for signing tokens. The caller holds
+// mu.
 func (m *Manager) getSigningKey() interface{} {
 	switch m.algorithm {
 	case "HS256":
 		return m.secret
 	case "RS256":
-		return m.privateKey
+		return m.activeKey().privateKey
 	default:
 		return nil
 	}
 }
 
-// getVerificationKey returns the key used for verifying tokens
+// getVerificationKey returns the key that verifies tokens signed now. The
+// caller holds mu.
 func (m *Manager) getVerificationKey() interface{} {
 	switch m.algorithm {
 	case "HS256":
 		return m.secret
 	case "RS256":
-		return m.publicKey
+		return &m.activeKey().privateKey.PublicKey
 	default:
 		return nil
 	}
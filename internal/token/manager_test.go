@@ -5,6 +5,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
 )
 
 func TestNewManager_HS256(t *testing.T) {
@@ -254,11 +256,13 @@ func TestManager_ValidateAccessToken_Errors(t *testing.T) {
 }
 
 func TestManager_ValidateAccessToken_ExpiredToken(t *testing.T) {
-	// Create manager with very short TTL
-	manager, err := NewManager("HS256", "test-secret", "", "", "test-issuer", 1*time.Nanosecond)
+	manager, err := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
 	if err != nil {
 		t.Fatalf("Failed to create manager: %v", err)
 	}
+	now := clock.NewFake(time.Now())
+	manager.SetClock(now)
+	manager.SetClockSkew(30 * time.Second)
 
 	// Generate token
 	tokenString, err := manager.GenerateAccessToken("user-123", "test@example.com", true)
@@ -266,16 +270,42 @@ func TestManager_ValidateAccessToken_ExpiredToken(t *testing.T) {
 		t.Fatalf("GenerateAccessToken() error = %v", err)
 	}
 
-	// Wait for token to expire
-	time.Sleep(10 * time.Millisecond)
+	// Still accepted within the clock skew after expiry
+	now.Advance(15*time.Minute + 20*time.Second)
+	if _, err := manager.ValidateAccessToken(tokenString); err != nil {
+		t.Errorf("ValidateAccessToken() within the skew error = %v", err)
+	}
 
 	// Try to validate expired token
+	now.Advance(20 * time.Second)
 	_, err = manager.ValidateAccessToken(tokenString)
 	if err != ErrExpiredToken {
 		t.Errorf("ValidateAccessToken() error = %v, want %v", err, ErrExpiredToken)
 	}
 }
 
+func TestManager_SetClockConcurrent(t *testing.T) {
+	manager, err := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	// Run with -race: replacing the clock must not race with issuing tokens
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			manager.SetClock(clock.NewFake(time.Now()))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := manager.GenerateAccessToken("user-123", "test@example.com", true); err != nil {
+			t.Fatalf("GenerateAccessToken() error = %v", err)
+		}
+	}
+	<-done
+}
+
 func TestManager_ValidateAccessToken_WrongSigningMethod(t *testing.T) {
 	// Create HS256 manager
 	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
@@ -330,6 +360,35 @@ func TestManager_GenerateAccessToken_UnsupportedAlgorithm(t *testing.T) {
 	}
 }
 
+func TestManager_ValidateFIPS(t *testing.T) {
+	hsManager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err := hsManager.ValidateFIPS(); err == nil {
+		t.Error("ValidateFIPS() should reject HS256")
+	}
+
+	tempDir := t.TempDir()
+	privateKeyPath := filepath.Join(tempDir, "private.pem")
+	publicKeyPath := filepath.Join(tempDir, "public.pem")
+	generateTestKeys(t, privateKeyPath, publicKeyPath)
+
+	rsManager, err := NewManager("RS256", "", privateKeyPath, publicKeyPath, "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := rsManager.ValidateFIPS(); err != nil {
+		t.Errorf("ValidateFIPS() with a 2048-bit key error = %v", err)
+	}
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	rsManager.keys[0].privateKey = smallKey
+	if err := rsManager.ValidateFIPS(); err == nil {
+		t.Error("ValidateFIPS() should reject a 1024-bit key")
+	}
+}
+
 func TestManager_SigningAndVerificationKeys(t *testing.T) {
 	// Test getSigningKey and getVerificationKey with unsupported algorithm
 	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
@@ -411,7 +470,16 @@ func TestManager_GetJWKS(t *testing.T) {
 	// Check JWKS structure
 	keys, ok := jwks["keys"].([]map[string]interface{})
 	if !ok || len(keys) == 0 {
-		t.Error("GetJWKS() should return keys array")
+		t.Fatal("GetJWKS() should return keys array")
+	}
+
+	// The published key must verify the manager's tokens
+	key, err := publicKeyFromJWK(keys[0])
+	if err != nil {
+		t.Fatalf("publicKeyFromJWK() error = %v", err)
+	}
+	if !key.(*rsa.PublicKey).Equal(&rs256Manager.keys[0].privateKey.PublicKey) {
+		t.Error("GetJWKS() key does not match the public key")
 	}
 }
 
@@ -460,3 +528,316 @@ func generateTestKeys(t *testing.T, privateKeyPath, publicKeyPath string) {
 		t.Fatalf("Failed to write public key: %v", err)
 	}
 }
+
+func TestManager_ClockSkew(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+
+	signClaims := func(exp, nbf, iat time.Time) string {
+		claims := Claims{
+			UserID: "user-123",
+			Email:  "test@example.com",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "test-issuer",
+				Subject:   "user-123",
+				ExpiresAt: jwt.NewNumericDate(exp),
+				NotBefore: jwt.NewNumericDate(nbf),
+				IssuedAt:  jwt.NewNumericDate(iat),
+			},
+		}
+		tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		skew      time.Duration
+		exp       time.Time
+		nbf       time.Time
+		iat       time.Time
+		wantErr   error
+		wantValid bool
+	}{
+		{
+			name:      "recently expired token rejected without skew",
+			skew:      0,
+			exp:       now.Add(-10 * time.Second),
+			nbf:       now.Add(-time.Minute),
+			iat:       now.Add(-time.Minute),
+			wantErr:   ErrExpiredToken,
+			wantValid: false,
+		},
+		{
+			name:      "recently expired token accepted within skew",
+			skew:      30 * time.Second,
+			exp:       now.Add(-10 * time.Second),
+			nbf:       now.Add(-time.Minute),
+			iat:       now.Add(-time.Minute),
+			wantValid: true,
+		},
+		{
+			name:      "token expired beyond skew rejected",
+			skew:      30 * time.Second,
+			exp:       now.Add(-time.Minute),
+			nbf:       now.Add(-2 * time.Minute),
+			iat:       now.Add(-2 * time.Minute),
+			wantErr:   ErrExpiredToken,
+			wantValid: false,
+		},
+		{
+			name:      "nbf slightly in future accepted within skew",
+			skew:      30 * time.Second,
+			exp:       now.Add(time.Hour),
+			nbf:       now.Add(10 * time.Second),
+			iat:       now.Add(10 * time.Second),
+			wantValid: true,
+		},
+		{
+			name:      "nbf beyond skew rejected",
+			skew:      30 * time.Second,
+			exp:       now.Add(time.Hour),
+			nbf:       now.Add(2 * time.Minute),
+			iat:       now,
+			wantErr:   ErrTokenNotValidYet,
+			wantValid: false,
+		},
+		{
+			name:      "iat in the future beyond skew rejected",
+			skew:      30 * time.Second,
+			exp:       now.Add(time.Hour),
+			nbf:       now,
+			iat:       now.Add(2 * time.Minute),
+			wantErr:   ErrInvalidToken,
+			wantValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager.SetClockSkew(tt.skew)
+
+			_, err := manager.ValidateAccessToken(signClaims(tt.exp, tt.nbf, tt.iat))
+			if tt.wantValid {
+				if err != nil {
+					t.Errorf("ValidateAccessToken() unexpected error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateAccessToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_SetClockSkew_Negative(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	manager.SetClockSkew(-time.Second)
+
+	if manager.ClockSkew() != 0 {
+		t.Errorf("ClockSkew() = %v, want 0", manager.ClockSkew())
+	}
+}
+
+func TestManager_GenerateAccessTokenWithOptions(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+
+	tokenString, err := manager.GenerateAccessTokenWithOptions("user-123", "test@example.com", true, AccessTokenOptions{
+		Locale:   "de-DE",
+		Version:  3,
+		Metadata: MetadataClaims{AppMetadata: map[string]interface{}{"plan": "pro"}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateAccessTokenWithOptions() error = %v", err)
+	}
+
+	claims, err := manager.ValidateAccessToken(tokenString)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.Locale != "de-DE" {
+		t.Errorf("Expected locale de-DE, got %s", claims.Locale)
+	}
+	if claims.TokenVersion != 3 {
+		t.Errorf("Expected token version 3, got %d", claims.TokenVersion)
+	}
+	if claims.Confirmation != nil {
+		t.Errorf("Expected no cnf claim, got %+v", claims.Confirmation)
+	}
+	if claims.AppMetadata["plan"] != "pro" {
+		t.Errorf("Expected app_metadata plan pro, got %v", claims.AppMetadata)
+	}
+	if claims.Metadata != nil {
+		t.Errorf("Expected no metadata claim, got %v", claims.Metadata)
+	}
+	if claims.AccessWindow != nil {
+		t.Errorf("Expected no access window claim, got %+v", claims.AccessWindow)
+	}
+}
+
+func TestManager_GenerateAccessTokenWithOptions_Window(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+
+	window := &AccessWindow{Start: "08:00", End: "18:00", Timezone: "Europe/Madrid"}
+	tokenString, err := manager.GenerateAccessTokenWithOptions("user-123", "test@example.com", true, AccessTokenOptions{Window: window})
+	if err != nil {
+		t.Fatalf("GenerateAccessTokenWithOptions() error = %v", err)
+	}
+
+	claims, err := manager.ValidateAccessToken(tokenString)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.AccessWindow == nil || *claims.AccessWindow != *window {
+		t.Errorf("Expected access window %+v, got %+v", window, claims.AccessWindow)
+	}
+}
+
+func TestManager_GenerateAccessToken_TokenID(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+
+	ids := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		tokenString, err := manager.GenerateAccessToken("user-123", "test@example.com", true)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken() error = %v", err)
+		}
+		claims, err := manager.ValidateAccessToken(tokenString)
+		if err != nil {
+			t.Fatalf("ValidateAccessToken() error = %v", err)
+		}
+		if claims.ID == "" || ids[claims.ID] {
+			t.Errorf("jti = %q, want a unique id per token", claims.ID)
+		}
+		ids[claims.ID] = true
+	}
+}
+
+func TestManager_GenerateAccessTokenNotBefore(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+
+	notBefore := time.Now().Add(time.Hour)
+	tokenString, err := manager.GenerateAccessTokenNotBefore("user-123", "test@example.com", true, notBefore)
+	if err != nil {
+		t.Fatalf("GenerateAccessTokenNotBefore() error = %v", err)
+	}
+
+	// Token must not be usable yet
+	if _, err := manager.ValidateAccessToken(tokenString); !errors.Is(err, ErrTokenNotValidYet) {
+		t.Errorf("ValidateAccessToken() error = %v, want %v", err, ErrTokenNotValidYet)
+	}
+
+	// Expiry is counted from nbf
+	claims := &Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if got := claims.ExpiresAt.Sub(claims.NotBefore.Time); got != 15*time.Minute {
+		t.Errorf("Expected exp - nbf = 15m, got %v", got)
+	}
+}
+
+func TestManager_GenerateIDToken(t *testing.T) {
+	manager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+
+	updatedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	tokenString, err := manager.GenerateIDToken(IDTokenUser{
+		ID:            "user-123",
+		Email:         "test@example.com",
+		EmailVerified: true,
+		Username:      "jane_doe",
+		Locale:        "pt-BR",
+		Zoneinfo:      "America/Sao_Paulo",
+		UpdatedAt:     updatedAt,
+	}, authTime, "test-client")
+	if err != nil {
+		t.Fatalf("GenerateIDToken() error = %v", err)
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte("test-secret"), nil
+	}, jwt.WithAudience("test-client"), jwt.WithIssuer("test-issuer"))
+	if err != nil {
+		t.Fatalf("Failed to parse ID token: %v", err)
+	}
+
+	if claims.Subject != "user-123" {
+		t.Errorf("Expected sub user-123, got %s", claims.Subject)
+	}
+	if claims.Email != "test@example.com" || !claims.EmailVerified {
+		t.Errorf("Unexpected email claims: %s, %v", claims.Email, claims.EmailVerified)
+	}
+	if claims.PreferredUsername != "jane_doe" {
+		t.Errorf("Expected preferred_username jane_doe, got %s", claims.PreferredUsername)
+	}
+	if claims.Locale != "pt-BR" || claims.Zoneinfo != "America/Sao_Paulo" {
+		t.Errorf("Unexpected locale claims: %s, %s", claims.Locale, claims.Zoneinfo)
+	}
+	if claims.Name != "" || claims.Picture != "" {
+		t.Errorf("Expected unset profile claims to be omitted, got %q, %q", claims.Name, claims.Picture)
+	}
+	if claims.UpdatedAt != updatedAt.Unix() {
+		t.Errorf("Expected updated_at %d, got %d", updatedAt.Unix(), claims.UpdatedAt)
+	}
+	if claims.AuthTime != authTime.Unix() {
+		t.Errorf("Expected auth_time %d, got %d", authTime.Unix(), claims.AuthTime)
+	}
+
+	// Same key and issuer, but never a bearer token
+	if claims.Use != IDTokenUse {
+		t.Errorf("Expected token_use %s, got %q", IDTokenUse, claims.Use)
+	}
+	if _, err := manager.ValidateAccessToken(tokenString); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() with an ID token error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func FuzzManager_ValidateAccessToken(f *testing.F) {
+	manager, err := NewManager("HS256", "fuzz-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		f.Fatalf("NewManager() error = %v", err)
+	}
+
+	valid, err := manager.GenerateAccessToken("fuzz-user", "fuzz@example.com", true)
+	if err != nil {
+		f.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	parts := strings.Split(valid, ".")
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": "fuzz-user"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	for _, seed := range []string{
+		valid,
+		parts[0] + "." + parts[1] + ".",
+		parts[0] + "." + parts[1],
+		unsigned,
+		valid + "x",
+		"",
+		"..",
+		"a.b.c",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, tokenString string) {
+		claims, err := manager.ValidateAccessToken(tokenString)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidToken) && !errors.Is(err, ErrExpiredToken) {
+				t.Fatalf("ValidateAccessToken() error = %v, want ErrInvalidToken or ErrExpiredToken", err)
+			}
+			return
+		}
+
+		// Without the secret the fuzzer cannot mint new tokens, so anything
+		// accepted must carry the claims of the seeded token
+		if claims == nil || claims.UserID != "fuzz-user" || claims.Email != "fuzz@example.com" {
+			t.Fatalf("ValidateAccessToken(%q) accepted unexpected claims %+v", tokenString, claims)
+		}
+	})
+}
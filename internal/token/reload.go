@@ -0,0 +1,59 @@
+package token
+
+import "time"
+
+// Config holds the settings Reload can change
+type Config struct {
+	Algorithm      string
+	Secret         string // HS256
+	PrivateKeyPath string // RS256
+	PublicKeyPath  string // RS256
+	Issuer         string
+	AccessTokenTTL time.Duration
+	FIPS           bool // reject settings ValidateFIPS would reject
+}
+
+// Reload replaces the algorithm, secret or key, issuer and access token
+// lifetime without a restart. The new settings are loaded and checked first,
+// so a bad configuration leaves the manager unchanged. The swap waits for
+// token operations in flight, which finish with the old settings.
+//
+// When the configured RS256 key is one the manager already has, such as a
+// staged key that has since been made the configured one, the keys and their
+// rollover are kept. Otherwise tokens signed with the old keys stop
+// validating; stage keys with StageSigningKey to replace them gracefully.
+func (m *Manager) Reload(cfg Config) error {
+	next, err := NewManager(cfg.Algorithm, cfg.Secret, cfg.PrivateKeyPath, cfg.PublicKeyPath, cfg.Issuer, cfg.AccessTokenTTL)
+	if err != nil {
+		return err
+	}
+	if cfg.FIPS {
+		if err := next.ValidateFIPS(); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if next.algorithm != "RS256" || m.algorithm != "RS256" || !m.hasKey(next.keys[0].kid) {
+		m.keys = next.keys
+		m.legacyKeyID = next.legacyKeyID
+	}
+	m.algorithm = next.algorithm
+	m.secret = next.secret
+	m.issuer = next.issuer
+	m.accessTokenTTL = next.accessTokenTTL
+	return nil
+}
+
+// hasKey reports whether kid names one of the RS256 keys. The caller holds
+// mu.
+func (m *Manager) hasKey(kid string) bool {
+	for _, key := range m.keys {
+		if key.kid == kid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManager_Reload_HS256(t *testing.T) {
+	manager, err := NewManager("HS256", "old-secret", "", "", "old-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	oldToken, _ := manager.GenerateAccessToken("user-123", "test@example.com", true)
+
+	err = manager.Reload(Config{Algorithm: "HS256", Secret: "new-secret", Issuer: "new-issuer", AccessTokenTTL: 5 * time.Minute})
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if _, err := manager.ValidateAccessToken(oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() with the old secret error = %v, want ErrInvalidToken", err)
+	}
+	newToken, _ := manager.GenerateAccessToken("user-123", "test@example.com", true)
+	claims, err := manager.ValidateAccessToken(newToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.Issuer != "new-issuer" {
+		t.Errorf("Issuer = %v, want new-issuer", claims.Issuer)
+	}
+	if manager.AccessTokenTTL() != 5*time.Minute {
+		t.Errorf("AccessTokenTTL() = %v, want 5m", manager.AccessTokenTTL())
+	}
+}
+
+func TestManager_Reload_InvalidConfig(t *testing.T) {
+	manager, err := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	tokenString, _ := manager.GenerateAccessToken("user-123", "test@example.com", true)
+
+	configs := map[string]Config{
+		"missing secret":    {Algorithm: "HS256", Issuer: "new-issuer", AccessTokenTTL: time.Minute},
+		"missing key files": {Algorithm: "RS256", PrivateKeyPath: "/nonexistent/private.pem", PublicKeyPath: "/nonexistent/public.pem"},
+		"not FIPS approved": {Algorithm: "HS256", Secret: "new-secret", Issuer: "new-issuer", FIPS: true},
+	}
+	for name, cfg := range configs {
+		if err := manager.Reload(cfg); err == nil {
+			t.Errorf("Reload() with %s should fail", name)
+		}
+	}
+
+	if _, err := manager.ValidateAccessToken(tokenString); err != nil {
+		t.Errorf("ValidateAccessToken() after a failed reload error = %v", err)
+	}
+	if manager.AccessTokenTTL() != 15*time.Minute {
+		t.Errorf("AccessTokenTTL() = %v, want the old 15m", manager.AccessTokenTTL())
+	}
+}
+
+func TestManager_Reload_RS256(t *testing.T) {
+	tempDir := t.TempDir()
+	privateKeyPath := filepath.Join(tempDir, "private.pem")
+	publicKeyPath := filepath.Join(tempDir, "public.pem")
+	generateTestKeys(t, privateKeyPath, publicKeyPath)
+
+	manager, err := NewManager("RS256", "", privateKeyPath, publicKeyPath, "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	oldToken, _ := manager.GenerateAccessToken("user-123", "test@example.com", true)
+
+	nextKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	if _, err := manager.StageSigningKey(nextKey, time.Now()); err != nil {
+		t.Fatalf("StageSigningKey() error = %v", err)
+	}
+
+	// The configured key is unchanged, so the staged key survives
+	cfg := Config{Algorithm: "RS256", PrivateKeyPath: privateKeyPath, PublicKeyPath: publicKeyPath, Issuer: "test-issuer", AccessTokenTTL: 15 * time.Minute}
+	if err := manager.Reload(cfg); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if keys := manager.SigningKeys(); len(keys) != 2 {
+		t.Errorf("SigningKeys() after reloading the same key = %v, want both keys", keys)
+	}
+
+	// A new configured key replaces the old ones
+	generateTestKeys(t, privateKeyPath, publicKeyPath)
+	if err := manager.Reload(cfg); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if keys := manager.SigningKeys(); len(keys) != 1 {
+		t.Errorf("SigningKeys() after reloading a new key = %v, want one key", keys)
+	}
+	if _, err := manager.ValidateAccessToken(oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() with a replaced key error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestManager_Reload_Concurrent(t *testing.T) {
+	manager, err := NewManager("HS256", "secret-a", "", "", "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				tokenString, err := manager.GenerateAccessToken("user-123", "test@example.com", true)
+				if err != nil {
+					t.Errorf("GenerateAccessToken() error = %v", err)
+					return
+				}
+				// A reload may land between the two calls, so only errors
+				// other than a rejected token are failures
+				if _, err := manager.ValidateAccessToken(tokenString); err != nil && !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("ValidateAccessToken() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		secret := "secret-a"
+		if i%2 == 1 {
+			secret = "secret-b"
+		}
+		if err := manager.Reload(Config{Algorithm: "HS256", Secret: secret, Issuer: "test-issuer", AccessTokenTTL: 15 * time.Minute}); err != nil {
+			t.Errorf("Reload() error = %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
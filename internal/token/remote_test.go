@@ -0,0 +1,206 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newJWKSServer serves the manager's JWKS and counts the fetches
+func newJWKSServer(t *testing.T, manager *Manager) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		jwks, err := manager.GetJWKS()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+func TestRemoteVerifier_ValidateAccessToken(t *testing.T) {
+	manager, _ := newRolloverManager(t)
+	server, fetches := newJWKSServer(t, manager)
+	verifier := NewRemoteVerifier(NewKeySet(server.URL, server.Client()), "test-issuer")
+	ctx := context.Background()
+
+	tokenString, err := manager.GenerateAccessToken("user-123", "test@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+
+	claims, err := verifier.ValidateAccessToken(ctx, tokenString)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.UserID != "user-123" || claims.Email != "test@example.com" || !claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// Known keys are served from the cache
+	if _, err := verifier.ValidateAccessToken(ctx, tokenString); err != nil {
+		t.Fatalf("ValidateAccessToken() again error = %v", err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+
+	other := NewRemoteVerifier(NewKeySet(server.URL, server.Client()), "other-issuer")
+	if _, err := other.ValidateAccessToken(ctx, tokenString); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() with another issuer error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestRemoteVerifier_ValidateAccessToken_Rejected(t *testing.T) {
+	manager, _ := newRolloverManager(t)
+	server, _ := newJWKSServer(t, manager)
+	verifier := NewRemoteVerifier(NewKeySet(server.URL, server.Client()), "")
+	ctx := context.Background()
+
+	expiredClaims := manager.newAccessClaims("user-123", "test@example.com", true, time.Now())
+	expiredClaims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	expiredClaims.NotBefore = expiredClaims.IssuedAt
+	expiredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expired := jwt.NewWithClaims(jwt.SigningMethodRS256, expiredClaims)
+	expired.Header["kid"] = manager.keys[0].kid
+	expiredToken, err := expired.SignedString(manager.keys[0].privateKey)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	if _, err := verifier.ValidateAccessToken(ctx, expiredToken); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("ValidateAccessToken() expired error = %v, want ErrExpiredToken", err)
+	}
+
+	connectionClaims := manager.newAccessClaims("user-123", "test@example.com", true, time.Now())
+	connectionClaims.Use = "connection"
+	connection := jwt.NewWithClaims(jwt.SigningMethodRS256, connectionClaims)
+	connection.Header["kid"] = manager.keys[0].kid
+	connectionToken, err := connection.SignedString(manager.keys[0].privateKey)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	if _, err := verifier.ValidateAccessToken(ctx, connectionToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() single-purpose error = %v, want ErrInvalidToken", err)
+	}
+
+	idToken, err := manager.GenerateIDToken(IDTokenUser{ID: "user-123", Email: "test@example.com"}, time.Now(), "client")
+	if err != nil {
+		t.Fatalf("GenerateIDToken() error = %v", err)
+	}
+	if _, err := verifier.ValidateAccessToken(ctx, idToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() ID token error = %v, want ErrInvalidToken", err)
+	}
+
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, connectionClaims)
+	hs256Token, err := hs256.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	if _, err := verifier.ValidateAccessToken(ctx, hs256Token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() HS256 error = %v, want ErrInvalidToken", err)
+	}
+
+	if _, err := verifier.ValidateAccessToken(ctx, "a.b.c.d.e"); !errors.Is(err, ErrEncryptionDisabled) {
+		t.Errorf("ValidateAccessToken() encrypted error = %v, want ErrEncryptionDisabled", err)
+	}
+}
+
+func TestRemoteVerifier_ValidateAccessToken_LegacyKeyID(t *testing.T) {
+	manager, _ := newRolloverManager(t)
+	server, _ := newJWKSServer(t, manager)
+	verifier := NewRemoteVerifier(NewKeySet(server.URL, server.Client()), "test-issuer")
+
+	claims := manager.newAccessClaims("user-123", "test@example.com", true, time.Now())
+	legacy := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	legacy.Header["kid"] = legacyKeyID
+	tokenString, err := legacy.SignedString(manager.keys[0].privateKey)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	if _, err := verifier.ValidateAccessToken(context.Background(), tokenString); err != nil {
+		t.Errorf("ValidateAccessToken() with kid %q error = %v", legacyKeyID, err)
+	}
+}
+
+func TestKeySet_RefreshesOnUnknownKeyID(t *testing.T) {
+	manager, now := newRolloverManager(t)
+	server, fetches := newJWKSServer(t, manager)
+	keys := NewKeySet(server.URL, server.Client())
+	if err := keys.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	nextKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	newKID, err := manager.StageSigningKey(nextKey, now.Now())
+	if err != nil {
+		t.Fatalf("StageSigningKey() error = %v", err)
+	}
+
+	// Lookups of an unknown kid refetch at most once per interval
+	keys.SetMinRefreshInterval(time.Hour)
+	if _, err := keys.Key(context.Background(), newKID); err == nil {
+		t.Error("Key() of a key staged after the last fetch succeeded before the refresh interval")
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+
+	keys.SetMinRefreshInterval(0)
+	if _, err := keys.Key(context.Background(), newKID); err != nil {
+		t.Errorf("Key() of a staged key error = %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+}
+
+func TestKeySet_NotLoaded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	keys := NewKeySet(server.URL, server.Client())
+	if err := keys.Refresh(context.Background()); err == nil {
+		t.Error("Refresh() succeeded with an unavailable JWKS")
+	}
+	if keys.Loaded() {
+		t.Error("Loaded() = true after a failed fetch")
+	}
+
+	tokenString := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "user-123"})
+	tokenString.Header["kid"] = "some-key"
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	signed, err := tokenString.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	verifier := NewRemoteVerifier(keys, "")
+	keys.SetMinRefreshInterval(0)
+	if _, err := verifier.ValidateAccessToken(context.Background(), signed); !errors.Is(err, ErrKeySetNotLoaded) {
+		t.Errorf("ValidateAccessToken() error = %v, want ErrKeySetNotLoaded", err)
+	}
+}
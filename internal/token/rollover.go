@@ -0,0 +1,270 @@
+package token
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// legacyKeyID is the kid of RS256 tokens issued before keys had their own
+const legacyKeyID = "default"
+
+// KeyPhase is where an RS256 key is in its rollover
+type KeyPhase string
+
+const (
+	// KeyPhasePending keys are published in the JWKS but do not sign yet, so
+	// resource servers have fetched them before they see tokens signed with
+	// them
+	KeyPhasePending KeyPhase = "pending"
+	// KeyPhaseActive is the key that signs new tokens
+	KeyPhaseActive KeyPhase = "active"
+	// KeyPhaseRetiring keys no longer sign but stay published until the
+	// tokens they signed have expired
+	KeyPhaseRetiring KeyPhase = "retiring"
+
+	// keyPhaseExpired keys are no longer published and are pruned
+	keyPhaseExpired KeyPhase = "expired"
+)
+
+// KeyPhases lists the phases of published keys in rollover order
+var KeyPhases = []KeyPhase{KeyPhasePending, KeyPhaseActive, KeyPhaseRetiring}
+
+// signingKey is an RS256 key and when it starts signing. The configured key
+// signs from the start.
+type signingKey struct {
+	kid        string
+	privateKey *rsa.PrivateKey
+	activeAt   time.Time
+}
+
+// SigningKey describes a published RS256 key
+type SigningKey struct {
+	ID       string
+	Phase    KeyPhase
+	ActiveAt time.Time // zero for the configured key
+}
+
+// rsaKeyID returns the RFC 7638 thumbprint of an RSA public key, used as its kid
+func rsaKeyID(publicKey *rsa.PublicKey) (string, error) {
+	return jwkThumbprint(map[string]interface{}{
+		"kty": "RSA",
+		"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	})
+}
+
+// SetKeyRolloverWindow sets how long a staged key is published before it
+// starts signing. It should be at least the JWKS cache lifetime.
+func (m *Manager) SetKeyRolloverWindow(window time.Duration) {
+	if window < 0 {
+		window = 0
+	}
+	m.rolloverWindow = window
+}
+
+// StageSigningKey publishes privateKey in the JWKS and makes it the signing
+// key once the rollover window has passed since announcedAt. The key it
+// replaces stays published until the tokens it signed have expired. Staging
+// a key that is already known does nothing.
+func (m *Manager) StageSigningKey(privateKey *rsa.PrivateKey, announcedAt time.Time) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.algorithm != "RS256" {
+		return "", fmt.Errorf("signing keys can only be staged for RS256 algorithm")
+	}
+	if bits := privateKey.N.BitLen(); bits < MinFIPSRSAKeyBits {
+		return "", fmt.Errorf("RSA key has %d bits; at least %d are required", bits, MinFIPSRSAKeyBits)
+	}
+	kid, err := rsaKeyID(&privateKey.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range m.keys {
+		if key.kid == kid {
+			return kid, nil
+		}
+	}
+	m.keys = append(m.keys, &signingKey{kid: kid, privateKey: privateKey, activeAt: announcedAt.Add(m.rolloverWindow)})
+	sort.SliceStable(m.keys, func(i, j int) bool { return m.keys[i].activeAt.Before(m.keys[j].activeAt) })
+	return kid, nil
+}
+
+// SigningKeys returns the published RS256 keys, oldest activation first
+func (m *Manager) SigningKeys() []SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.clock.Now()
+	keys := make([]SigningKey, 0, len(m.keys))
+	for i, key := range m.keys {
+		if phase := m.keyPhase(i, now); phase != keyPhaseExpired {
+			keys = append(keys, SigningKey{ID: key.kid, Phase: phase, ActiveAt: key.activeAt})
+		}
+	}
+	return keys
+}
+
+// PruneSigningKeys forgets keys whose tokens have all expired and returns
+// their ids
+func (m *Manager) PruneSigningKeys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	var pruned []string
+	kept := m.keys[:0:0]
+	for i, key := range m.keys {
+		if m.keyPhase(i, now) == keyPhaseExpired {
+			pruned = append(pruned, key.kid)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	m.keys = kept
+	return pruned
+}
+
+// keyPhase returns the phase of m.keys[i] at now. A key retires when the key
+// after it activates. The caller holds mu.
+func (m *Manager) keyPhase(i int, now time.Time) KeyPhase {
+	if m.keys[i].activeAt.After(now) {
+		return KeyPhasePending
+	}
+	if i+1 < len(m.keys) && !m.keys[i+1].activeAt.After(now) {
+		// Tokens are accepted for up to the clock skew past their expiry
+		if now.Before(m.keys[i+1].activeAt.Add(m.accessTokenTTL + m.clockSkew)) {
+			return KeyPhaseRetiring
+		}
+		return keyPhaseExpired
+	}
+	return KeyPhaseActive
+}
+
+// activeKey returns the key that signs new tokens. There always is one: the
+// configured key signs from the start and only a key that has been replaced
+// expires. The caller holds mu.
+func (m *Manager) activeKey() *signingKey {
+	now := m.clock.Now()
+	for i := len(m.keys) - 1; i > 0; i-- {
+		if !m.keys[i].activeAt.After(now) {
+			return m.keys[i]
+		}
+	}
+	return m.keys[0]
+}
+
+// publishedKeys returns the keys in the JWKS. The caller holds mu.
+func (m *Manager) publishedKeys() []*signingKey {
+	now := m.clock.Now()
+	keys := make([]*signingKey, 0, len(m.keys))
+	for i, key := range m.keys {
+		if m.keyPhase(i, now) != keyPhaseExpired {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// verificationKey returns the published key a token's kid names. Pending
+// keys are included, as another instance may have activated them a moment
+// earlier. The caller holds mu.
+func (m *Manager) verificationKey(kid string) (*rsa.PublicKey, error) {
+	if kid == legacyKeyID {
+		kid = m.legacyKeyID
+	}
+	for _, key := range m.publishedKeys() {
+		if key.kid == kid {
+			return &key.privateKey.PublicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// KeyRolloverRecorder records how many RS256 keys are in each rollover phase
+type KeyRolloverRecorder interface {
+	SetSigningKeys(phase string, count int)
+}
+
+// KeyRollover stages the RS256 key an operator writes to the next key path
+// and retires the keys it replaces. The file's modification time is when the
+// key was announced, so instances sharing the file switch keys together and a
+// restart resumes a rollover rather than starting it over.
+type KeyRollover struct {
+	manager  *Manager
+	path     string
+	logger   *slog.Logger
+	recorder KeyRolloverRecorder
+
+	modTime time.Time
+	phases  map[string]KeyPhase // by kid, as of the last run
+}
+
+// NewKeyRollover creates a rollover of manager's keys to the key at nextKeyPath
+func NewKeyRollover(manager *Manager, nextKeyPath string, logger *slog.Logger) *KeyRollover {
+	return &KeyRollover{
+		manager: manager,
+		path:    nextKeyPath,
+		logger:  logger,
+		phases:  make(map[string]KeyPhase),
+	}
+}
+
+// SetMetrics records the keys in each phase after every run
+func (r *KeyRollover) SetMetrics(recorder KeyRolloverRecorder) {
+	r.recorder = recorder
+}
+
+// Run stages the next key when its file has changed, forgets keys whose
+// tokens have expired and logs keys that changed phase. It is meant to run
+// periodically.
+func (r *KeyRollover) Run(ctx context.Context) error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read next signing key: %w", err)
+	}
+	if !info.ModTime().Equal(r.modTime) {
+		data, err := os.ReadFile(r.path)
+		if err != nil {
+			return fmt.Errorf("failed to read next signing key: %w", err)
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse next signing key: %w", err)
+		}
+		if _, err := r.manager.StageSigningKey(privateKey, info.ModTime()); err != nil {
+			return err
+		}
+		r.modTime = info.ModTime()
+	}
+
+	for _, kid := range r.manager.PruneSigningKeys() {
+		r.logger.InfoContext(ctx, "signing key unpublished", "kid", kid)
+		delete(r.phases, kid)
+	}
+
+	counts := make(map[KeyPhase]int, len(KeyPhases))
+	for _, key := range r.manager.SigningKeys() {
+		counts[key.Phase]++
+		if r.phases[key.ID] != key.Phase {
+			r.logger.InfoContext(ctx, "signing key phase changed", "kid", key.ID, "phase", key.Phase, "active_at", key.ActiveAt)
+			r.phases[key.ID] = key.Phase
+		}
+	}
+	if r.recorder != nil {
+		for _, phase := range KeyPhases {
+			r.recorder.SetSigningKeys(string(phase), counts[phase])
+		}
+	}
+	return nil
+}
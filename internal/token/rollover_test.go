@@ -0,0 +1,217 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
+)
+
+// newRolloverManager returns an RS256 manager with a 15 minute TTL, no clock
+// skew and a one hour rollover window, and a clock the test can move
+func newRolloverManager(t *testing.T) (*Manager, *clock.Fake) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	privateKeyPath := filepath.Join(tempDir, "private.pem")
+	publicKeyPath := filepath.Join(tempDir, "public.pem")
+	generateTestKeys(t, privateKeyPath, publicKeyPath)
+
+	manager, err := NewManager("RS256", "", privateKeyPath, publicKeyPath, "test-issuer", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.SetClockSkew(0)
+	manager.SetKeyRolloverWindow(time.Hour)
+
+	now := clock.NewFake(time.Now())
+	manager.SetClock(now)
+	return manager, now
+}
+
+func keyPhases(m *Manager) map[string]KeyPhase {
+	phases := make(map[string]KeyPhase)
+	for _, key := range m.SigningKeys() {
+		phases[key.ID] = key.Phase
+	}
+	return phases
+}
+
+func tokenKeyID(t *testing.T, tokenString string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified() error = %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestManager_KeyRollover(t *testing.T) {
+	manager, now := newRolloverManager(t)
+	oldKID := manager.SigningKeys()[0].ID
+
+	nextKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	newKID, err := manager.StageSigningKey(nextKey, now.Now())
+	if err != nil {
+		t.Fatalf("StageSigningKey() error = %v", err)
+	}
+
+	// Announced: published, but the old key still signs
+	if phases := keyPhases(manager); phases[oldKID] != KeyPhaseActive || phases[newKID] != KeyPhasePending {
+		t.Errorf("phases after staging = %v", phases)
+	}
+	if jwks, _ := manager.GetJWKS(); len(jwks["keys"].([]map[string]interface{})) != 2 {
+		t.Errorf("JWKS keys after staging = %v, want both keys", jwks["keys"])
+	}
+	now.Advance(time.Hour - time.Minute)
+	oldToken, _ := manager.GenerateAccessToken("user-123", "test@example.com", true)
+	if kid := tokenKeyID(t, oldToken); kid != oldKID {
+		t.Errorf("kid while pending = %s, want the old key %s", kid, oldKID)
+	}
+
+	// Active: the new key signs, and the old key's tokens are still accepted
+	now.Advance(time.Minute)
+	if phases := keyPhases(manager); phases[oldKID] != KeyPhaseRetiring || phases[newKID] != KeyPhaseActive {
+		t.Errorf("phases after the window = %v", phases)
+	}
+	newToken, _ := manager.GenerateAccessToken("user-123", "test@example.com", true)
+	if kid := tokenKeyID(t, newToken); kid != newKID {
+		t.Errorf("kid once active = %s, want the new key %s", kid, newKID)
+	}
+	for name, tokenString := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := manager.ValidateAccessToken(tokenString); err != nil {
+			t.Errorf("ValidateAccessToken(%s) error = %v", name, err)
+		}
+	}
+
+	// Expired: the old key is unpublished once its tokens have expired
+	now.Advance(15 * time.Minute)
+	if pruned := manager.PruneSigningKeys(); len(pruned) != 1 || pruned[0] != oldKID {
+		t.Errorf("PruneSigningKeys() = %v, want [%s]", pruned, oldKID)
+	}
+	if phases := keyPhases(manager); len(phases) != 1 || phases[newKID] != KeyPhaseActive {
+		t.Errorf("phases after retiring = %v", phases)
+	}
+	if _, err := manager.ValidateAccessToken(oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() with an unpublished key error = %v, want ErrInvalidToken", err)
+	}
+
+	// Staging the same key again does not restart its rollover
+	if _, err := manager.StageSigningKey(nextKey, now.Now()); err != nil {
+		t.Fatalf("StageSigningKey() again error = %v", err)
+	}
+	if phases := keyPhases(manager); phases[newKID] != KeyPhaseActive {
+		t.Errorf("phase after staging again = %v", phases[newKID])
+	}
+}
+
+func TestManager_KeyRollover_LegacyKeyID(t *testing.T) {
+	manager, _ := newRolloverManager(t)
+
+	claims := manager.newAccessClaims("user-123", "test@example.com", true, time.Now())
+	legacy := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	legacy.Header["kid"] = legacyKeyID
+	tokenString, err := legacy.SignedString(manager.keys[0].privateKey)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	if _, err := manager.ValidateAccessToken(tokenString); err != nil {
+		t.Errorf("ValidateAccessToken() with kid %q error = %v", legacyKeyID, err)
+	}
+}
+
+type fakeKeyRecorder map[string]int
+
+func (r fakeKeyRecorder) SetSigningKeys(phase string, count int) {
+	r[phase] = count
+}
+
+func TestKeyRollover_Run(t *testing.T) {
+	manager, now := newRolloverManager(t)
+
+	nextKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	nextKeyPath := filepath.Join(t.TempDir(), "next.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(nextKey)})
+	if err := os.WriteFile(nextKeyPath, data, 0o600); err != nil {
+		t.Fatalf("Failed to write next key: %v", err)
+	}
+	// Announced half an hour ago, e.g. before a restart
+	announcedAt := now.Now().Add(-30 * time.Minute)
+	if err := os.Chtimes(nextKeyPath, announcedAt, announcedAt); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	recorder := fakeKeyRecorder{}
+	rollover := NewKeyRollover(manager, nextKeyPath, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	rollover.SetMetrics(recorder)
+
+	if err := rollover.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if recorder["pending"] != 1 || recorder["active"] != 1 || recorder["retiring"] != 0 {
+		t.Errorf("recorded phases = %v, want one pending and one active", recorder)
+	}
+
+	// The window counts from the announcement, not from the first run
+	now.Advance(30 * time.Minute)
+	if err := rollover.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if recorder["pending"] != 0 || recorder["active"] != 1 || recorder["retiring"] != 1 {
+		t.Errorf("recorded phases = %v, want one active and one retiring", recorder)
+	}
+
+	now.Advance(15 * time.Minute)
+	if err := rollover.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if recorder["active"] != 1 || recorder["retiring"] != 0 {
+		t.Errorf("recorded phases = %v, want only the active key", recorder)
+	}
+
+	if err := os.WriteFile(nextKeyPath, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("Failed to write next key: %v", err)
+	}
+	if err := rollover.Run(context.Background()); err == nil {
+		t.Error("Run() with an invalid next key should fail")
+	}
+}
+
+func TestManager_StageSigningKey_Errors(t *testing.T) {
+	hsManager, _ := NewManager("HS256", "test-secret", "", "", "test-issuer", 15*time.Minute)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	if _, err := hsManager.StageSigningKey(key, time.Now()); err == nil {
+		t.Error("StageSigningKey() should reject HS256 managers")
+	}
+
+	rsManager, _ := newRolloverManager(t)
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	if _, err := rsManager.StageSigningKey(smallKey, time.Now()); err == nil {
+		t.Error("StageSigningKey() should reject a 1024-bit key")
+	}
+}
@@ -0,0 +1,381 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrQueueFull is returned when an email cannot be queued and the
+// backpressure policy does not hold it elsewhere
+var ErrQueueFull = errors.New("email queue is full")
+
+// Priority orders email jobs for the drop-lowest backpressure policy
+type Priority int
+
+const (
+	// PriorityLow is for informational emails such as login notifications
+	PriorityLow Priority = -1
+	// PriorityNormal is the priority of Enqueue and EnqueueWithContext
+	PriorityNormal Priority = 0
+	// PriorityHigh is for emails a user is waiting for
+	PriorityHigh Priority = 1
+)
+
+// BackpressurePolicy selects what happens to an email enqueued while the
+// queue is full
+type BackpressurePolicy string
+
+const (
+	// BackpressureReject fails with ErrQueueFull. EnqueueWithContext still
+	// waits for room until its context is done.
+	BackpressureReject BackpressurePolicy = "reject"
+	// BackpressureBlock waits up to BlockTimeout for room, then rejects
+	BackpressureBlock BackpressurePolicy = "block"
+	// BackpressureDropLowest drops the oldest queued job of the lowest
+	// priority to make room, or rejects the new job if it has the lowest
+	BackpressureDropLowest BackpressurePolicy = "drop_lowest"
+	// BackpressureSpill stores the job in the outbox; it is queued again
+	// once the queue drains. Without an outbox it rejects.
+	BackpressureSpill BackpressurePolicy = "spill"
+)
+
+// Outbox persistently holds jobs that did not fit in the queue
+type Outbox interface {
+	// Push stores a job until it is taken
+	Push(job EmailJob) error
+	// Take removes and returns up to max of the oldest stored jobs
+	Take(max int) ([]EmailJob, error)
+}
+
+// FileOutbox is an Outbox keeping one JSON file per job in a directory, so
+// spilled emails survive restarts. It is meant for a single process.
+type FileOutbox struct {
+	dir string
+}
+
+// NewFileOutbox creates an outbox in dir, creating the directory if needed
+func NewFileOutbox(dir string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create email outbox: %w", err)
+	}
+	return &FileOutbox{dir: dir}, nil
+}
+
+// Push writes the job to its own file, named so that files sort by the time
+// they were pushed
+func (o *FileOutbox) Push(job EmailJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox job: %w", err)
+	}
+
+	// Write to a temporary file first so Take never reads a partial job
+	name := fmt.Sprintf("%020d-%s.json", time.Now().UnixNano(), job.ID)
+	path := filepath.Join(o.dir, name)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("failed to write outbox job: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write outbox job: %w", err)
+	}
+	return nil
+}
+
+// Take reads and removes the oldest jobs. Files that cannot be decoded are
+// renamed with a .corrupt suffix and reported in the returned error.
+func (o *FileOutbox) Take(max int) ([]EmailJob, error) {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email outbox: %w", err)
+	}
+
+	var jobs []EmailJob
+	var errs []error
+	for _, entry := range entries {
+		if len(jobs) >= max {
+			break
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(o.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read outbox job: %w", err))
+			continue
+		}
+		var job EmailJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			errs = append(errs, fmt.Errorf("failed to decode outbox job %s: %w", entry.Name(), err))
+			_ = os.Rename(path, path+".corrupt")
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove outbox job: %w", err))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, errors.Join(errs...)
+}
+
+// SetOutbox sets where the spill policy stores jobs. It must be called
+// before Start.
+func (d *EmailDispatcher) SetOutbox(outbox Outbox) {
+	d.outbox = outbox
+}
+
+// SetWatermarkHandler sets a function called with true when the queue
+// fills to the high watermark and with false when it drains to half of it.
+// It must be called before Start.
+func (d *EmailDispatcher) SetWatermarkHandler(fn func(high bool)) {
+	d.onWatermark = fn
+}
+
+// enqueue queues a job or applies the backpressure policy. With
+// waitForContext, a full queue is waited on until ctx is done under the
+// reject policy, as EnqueueWithContext always did.
+func (d *EmailDispatcher) enqueue(ctx context.Context, job EmailJob, waitForContext bool) error {
+	err := d.send(ctx, job, waitForContext)
+	if errors.Is(err, ErrQueueFull) {
+		err = d.shed(job)
+	}
+	if err == nil {
+		d.logger.Debug("email job enqueued",
+			"job_id", job.ID,
+			"to", job.Email.To,
+			"subject", job.Email.Subject,
+			"priority", job.Priority,
+		)
+		d.checkWatermark()
+	}
+	return err
+}
+
+// send puts a job on the queue, waiting for room as the policy allows
+func (d *EmailDispatcher) send(ctx context.Context, job EmailJob, waitForContext bool) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return ErrDispatcherStopped
+	}
+
+	if trySend(d.jobQueue, job) {
+		d.queued.add(job)
+		return nil
+	}
+
+	var timeout <-chan time.Time
+	switch {
+	case d.backpressure == BackpressureBlock:
+		timer := time.NewTimer(d.blockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+		if !waitForContext {
+			ctx = context.Background()
+		}
+	case d.backpressure == BackpressureReject && waitForContext:
+		// Wait until the caller gives up
+	default:
+		return ErrQueueFull
+	}
+
+	select {
+	case d.jobQueue <- job:
+		d.queued.add(job)
+		return nil
+	case <-timeout:
+		return ErrQueueFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shed makes room for or stores a job that did not fit in the queue
+func (d *EmailDispatcher) shed(job EmailJob) error {
+	switch d.backpressure {
+	case BackpressureDropLowest:
+		return d.dropLowest(job)
+	case BackpressureSpill:
+		if d.outbox == nil {
+			return ErrQueueFull
+		}
+		if err := d.outbox.Push(job); err != nil {
+			return fmt.Errorf("failed to spill email job: %w", err)
+		}
+		d.logger.Warn("email queue full, job spilled to outbox",
+			"job_id", job.ID,
+			"to", job.Email.To,
+		)
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// dropLowest replaces the oldest queued job of the lowest priority with job
+// when job has a higher priority
+func (d *EmailDispatcher) dropLowest(job EmailJob) error {
+	// The write lock keeps enqueues out while the queue is rebuilt; workers
+	// may still take jobs, which only frees room
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return ErrDispatcherStopped
+	}
+
+	var queued []EmailJob
+drain:
+	for {
+		select {
+		case j := <-d.jobQueue:
+			d.queued.remove(j)
+			queued = append(queued, j)
+		default:
+			break drain
+		}
+	}
+
+	lowest := -1
+	for i, j := range queued {
+		if lowest < 0 || j.Priority < queued[lowest].Priority {
+			lowest = i
+		}
+	}
+
+	var err error
+	if lowest >= 0 && len(queued) == cap(d.jobQueue) {
+		if job.Priority > queued[lowest].Priority {
+			dropped := queued[lowest]
+			queued = append(queued[:lowest], queued[lowest+1:]...)
+			d.logger.Warn("email queue full, dropping lower priority job",
+				"job_id", dropped.ID,
+				"to", dropped.Email.To,
+				"priority", dropped.Priority,
+			)
+		} else {
+			err = ErrQueueFull
+		}
+	}
+	if err == nil {
+		queued = append(queued, job)
+	}
+
+	// Cannot block: at most cap jobs are put back and nobody else sends
+	for _, j := range queued {
+		d.jobQueue <- j
+		d.queued.add(j)
+	}
+	return err
+}
+
+// checkWatermark reports queue length crossings of the high watermark and
+// of half of it, so a briefly full queue does not flap the handler
+func (d *EmailDispatcher) checkWatermark() {
+	if d.highWatermark <= 0 {
+		return
+	}
+
+	n := len(d.jobQueue)
+	switch {
+	case n >= d.highWatermark && d.aboveWatermark.CompareAndSwap(false, true):
+		d.logger.Warn("email queue above high watermark",
+			"queued", n,
+			"high_watermark", d.highWatermark,
+		)
+		if d.onWatermark != nil {
+			d.onWatermark(true)
+		}
+	case n <= d.highWatermark/2 && d.aboveWatermark.CompareAndSwap(true, false):
+		d.logger.Info("email queue drained below watermark", "queued", n)
+		if d.onWatermark != nil {
+			d.onWatermark(false)
+		}
+	}
+}
+
+// trySend puts job on queue unless it is full
+func trySend(queue chan EmailJob, job EmailJob) bool {
+	select {
+	case queue <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// watermarkLength converts a fraction of the queue capacity into a length;
+// 0 disables the watermark
+func watermarkLength(fraction float64, capacity int) int {
+	if fraction <= 0 || capacity == 0 {
+		return 0
+	}
+	return max(1, int(math.Ceil(fraction*float64(capacity))))
+}
+
+// refillFromOutbox moves spilled jobs back to the queue while it has room
+func (d *EmailDispatcher) refillFromOutbox() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.drainOutbox()
+		}
+	}
+}
+
+// drainOutbox queues spilled jobs until the queue is half full. Jobs that
+// no longer fit are pushed back to the outbox.
+func (d *EmailDispatcher) drainOutbox() {
+	free := cap(d.jobQueue)/2 - len(d.jobQueue)
+	if free <= 0 {
+		return
+	}
+
+	jobs, err := d.outbox.Take(free)
+	if err != nil {
+		d.logger.Error("failed to take jobs from email outbox", "error", err)
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	d.mu.RLock()
+	queued := 0
+	for _, job := range jobs {
+		if d.stopped || !trySend(d.jobQueue, job) {
+			break
+		}
+		d.queued.add(job)
+		queued++
+	}
+	d.mu.RUnlock()
+
+	for _, job := range jobs[queued:] {
+		if err := d.outbox.Push(job); err != nil {
+			d.logger.Error("failed to return job to email outbox",
+				"job_id", job.ID,
+				"to", job.Email.To,
+				"error", err,
+			)
+		}
+	}
+
+	d.logger.Info("email jobs moved from outbox to queue", "jobs", queued)
+	d.checkWatermark()
+}
@@ -0,0 +1,245 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/email"
+)
+
+// newStalledDispatcher returns a dispatcher without workers, so queued jobs
+// stay queued
+func newStalledDispatcher(t *testing.T, policy BackpressurePolicy, queueSize int) (*EmailDispatcher, *email.MockService) {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := email.NewMockService(logger)
+	config := DefaultConfig()
+	config.Workers = 0
+	config.QueueSize = queueSize
+	config.Backpressure = policy
+	config.BlockTimeout = 20 * time.Millisecond
+	return NewEmailDispatcher(service, config, logger), service
+}
+
+// queuedRecipients drains the queue and returns the recipients in order
+func queuedRecipients(d *EmailDispatcher) []string {
+	var to []string
+	for len(d.jobQueue) > 0 {
+		to = append(to, (<-d.jobQueue).Email.To)
+	}
+	return to
+}
+
+func TestEmailDispatcher_BackpressureReject(t *testing.T) {
+	d, _ := newStalledDispatcher(t, BackpressureReject, 1)
+
+	if err := d.Enqueue(email.Email{To: "a@example.com"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := d.Enqueue(email.Email{To: "b@example.com"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Enqueue() error = %v, want %v", err, ErrQueueFull)
+	}
+}
+
+func TestEmailDispatcher_BackpressureBlock(t *testing.T) {
+	d, _ := newStalledDispatcher(t, BackpressureBlock, 1)
+	if err := d.Enqueue(email.Email{To: "a@example.com"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	// Nothing drains the queue: the wait ends after BlockTimeout
+	start := time.Now()
+	if err := d.Enqueue(email.Email{To: "b@example.com"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Enqueue() error = %v, want %v", err, ErrQueueFull)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("Enqueue() returned after %v, want at least the block timeout", waited)
+	}
+
+	// Room freed while waiting is used
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		<-d.jobQueue
+	}()
+	if err := d.Enqueue(email.Email{To: "c@example.com"}); err != nil {
+		t.Errorf("Enqueue() error = %v", err)
+	}
+
+	// The caller's context still bounds the wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.EnqueueWithContext(ctx, email.Email{To: "d@example.com"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("EnqueueWithContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestEmailDispatcher_BackpressureDropLowest(t *testing.T) {
+	d, _ := newStalledDispatcher(t, BackpressureDropLowest, 3)
+
+	enqueue := func(to string, priority Priority) error {
+		return d.EnqueuePriority(email.Email{To: to}, priority)
+	}
+	for _, job := range []struct {
+		to       string
+		priority Priority
+	}{
+		{"normal-1@example.com", PriorityNormal},
+		{"low-1@example.com", PriorityLow},
+		{"low-2@example.com", PriorityLow},
+	} {
+		if err := enqueue(job.to, job.priority); err != nil {
+			t.Fatalf("EnqueuePriority(%s) error = %v", job.to, err)
+		}
+	}
+
+	// A low priority job does not displace another low priority job
+	if err := enqueue("low-3@example.com", PriorityLow); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("EnqueuePriority(low) error = %v, want %v", err, ErrQueueFull)
+	}
+	// A higher priority job drops the oldest lowest priority job
+	if err := enqueue("high@example.com", PriorityHigh); err != nil {
+		t.Fatalf("EnqueuePriority(high) error = %v", err)
+	}
+
+	want := []string{"normal-1@example.com", "low-2@example.com", "high@example.com"}
+	got := queuedRecipients(d)
+	if len(got) != len(want) {
+		t.Fatalf("queued = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("queued = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEmailDispatcher_BackpressureSpill(t *testing.T) {
+	outbox, err := NewFileOutbox(filepath.Join(t.TempDir(), "outbox"))
+	if err != nil {
+		t.Fatalf("NewFileOutbox() error = %v", err)
+	}
+
+	d, _ := newStalledDispatcher(t, BackpressureSpill, 2)
+	d.SetOutbox(outbox)
+
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		if err := d.Enqueue(email.Email{To: to}); err != nil {
+			t.Fatalf("Enqueue(%s) error = %v", to, err)
+		}
+	}
+	if got := queuedRecipients(d); len(got) != 2 || got[0] != "a@example.com" {
+		t.Fatalf("queued = %v, want a and b", got)
+	}
+
+	// Spilled jobs come back in order once the queue has room; the queue
+	// is only filled to half its capacity
+	d.drainOutbox()
+	if got := queuedRecipients(d); len(got) != 1 || got[0] != "c@example.com" {
+		t.Fatalf("queued after first drain = %v, want [c@example.com]", got)
+	}
+	d.drainOutbox()
+	if got := queuedRecipients(d); len(got) != 1 || got[0] != "d@example.com" {
+		t.Fatalf("queued after second drain = %v, want [d@example.com]", got)
+	}
+	if jobs, err := outbox.Take(10); err != nil || len(jobs) != 0 {
+		t.Errorf("outbox still holds %d jobs, error = %v", len(jobs), err)
+	}
+}
+
+func TestEmailDispatcher_SpillDelivered(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := email.NewMockService(logger)
+	outbox, err := NewFileOutbox(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileOutbox() error = %v", err)
+	}
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := outbox.Push(newEmailJob(email.Email{To: to}, PriorityNormal)); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.Workers = 1
+	config.QueueSize = 4
+	config.OutboxPollInterval = 5 * time.Millisecond
+	d := NewEmailDispatcher(service, config, logger)
+	d.SetOutbox(outbox)
+	d.Start()
+	defer d.Stop(time.Second)
+
+	deadline := time.Now().Add(time.Second)
+	for service.CountEmails() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := service.CountEmails(); got != 2 {
+		t.Errorf("sent %d emails from the outbox, want 2", got)
+	}
+}
+
+func TestEmailDispatcher_Watermark(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config := DefaultConfig()
+	config.Workers = 0
+	config.QueueSize = 4
+	config.HighWatermark = 0.75
+	d := NewEmailDispatcher(email.NewMockService(logger), config, logger)
+
+	var mu sync.Mutex
+	var transitions []bool
+	d.SetWatermarkHandler(func(high bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, high)
+	})
+
+	for i := 0; i < 4; i++ {
+		if err := d.Enqueue(email.Email{To: "user@example.com"}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	// Dequeue as a worker would, down to half of the watermark (3/2 = 1)
+	for i := 0; i < 3; i++ {
+		<-d.jobQueue
+		d.checkWatermark()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(transitions) != 2 || !transitions[0] || transitions[1] {
+		t.Errorf("watermark transitions = %v, want [true false]", transitions)
+	}
+}
+
+func TestFileOutbox_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	outbox, err := NewFileOutbox(dir)
+	if err != nil {
+		t.Fatalf("NewFileOutbox() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001-bad.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Push(newEmailJob(email.Email{To: "a@example.com"}, PriorityHigh)); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	jobs, err := outbox.Take(10)
+	if err == nil {
+		t.Error("Take() should report the corrupt job")
+	}
+	if len(jobs) != 1 || jobs[0].Email.To != "a@example.com" || jobs[0].Priority != PriorityHigh {
+		t.Errorf("Take() = %+v", jobs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000001-bad.json.corrupt")); err != nil {
+		t.Errorf("corrupt job was not set aside: %v", err)
+	}
+}
@@ -2,18 +2,26 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/n1rocket/go-auth-jwt/internal/email"
 )
 
+// ErrDispatcherStopped is returned for emails enqueued after Stop
+var ErrDispatcherStopped = errors.New("email dispatcher stopped")
+
 // EmailJob represents an email sending job
 type EmailJob struct {
 	ID        string
 	Email     email.Email
+	Priority  Priority
 	Retries   int
 	CreatedAt time.Time
 }
@@ -21,6 +29,7 @@ type EmailJob struct {
 // EmailDispatcher manages email sending workers
 type EmailDispatcher struct {
 	emailService email.Service
+	failover     *failoverService // set when sending through several providers
 	workers      int
 	jobQueue     chan EmailJob
 	wg           sync.WaitGroup
@@ -29,6 +38,38 @@ type EmailDispatcher struct {
 	logger       *slog.Logger
 	maxRetries   int
 	retryDelay   time.Duration
+	snapshotPath string
+
+	backpressure       BackpressurePolicy
+	blockTimeout       time.Duration
+	outbox             Outbox
+	outboxPollInterval time.Duration
+	highWatermark      int // queue length; 0 disables the watermark
+	aboveWatermark     atomic.Bool
+	onWatermark        func(high bool)
+
+	// inFlight holds the job each worker is sending, so Stop can report
+	// the ones that hang
+	inFlightMu    sync.Mutex
+	inFlight      map[int]inFlightJob
+	stuckRecorder StuckWorkerRecorder
+
+	// queued tracks the jobs in the queue for the age of the oldest
+	queued queuedJobs
+
+	// throttle holds back jobs to domains over their rate; nil without
+	// domain rate limits
+	throttle *domainThrottle
+
+	// unsent holds jobs that are in neither the queue nor the throttle but
+	// still belong in the snapshot: snapshot entries that did not fit in the
+	// queue and retries cut short by Stop
+	unsentMu sync.Mutex
+	unsent   []EmailJob
+
+	// mu guards the queue against sends after Stop closed it
+	mu      sync.RWMutex
+	stopped bool
 }
 
 // Config holds configuration for the email dispatcher
@@ -38,6 +79,26 @@ type Config struct {
 	MaxRetries  int
 	RetryDelay  time.Duration
 	SendTimeout time.Duration
+
+	// SnapshotPath is where unsent jobs are written on Stop and restored
+	// from on Start. Persistence is disabled when empty.
+	SnapshotPath string
+
+	// Backpressure decides what happens to emails enqueued while the queue
+	// is full; BlockTimeout bounds the wait of the block policy
+	Backpressure BackpressurePolicy
+	BlockTimeout time.Duration
+
+	// OutboxPollInterval is how often spilled jobs are moved back to the
+	// queue when an outbox is set
+	OutboxPollInterval time.Duration
+
+	// HighWatermark is the fraction of QueueSize at which the watermark
+	// handler is told the queue is backing up; 0 disables it
+	HighWatermark float64
+
+	// DomainRateLimits caps the emails per minute to each recipient domain
+	DomainRateLimits DomainRateLimits
 }
 
 // DefaultConfig returns default configuration
@@ -48,12 +109,24 @@ func DefaultConfig() Config {
 		MaxRetries:  3,
 		RetryDelay:  5 * time.Second,
 		SendTimeout: 30 * time.Second,
+
+		Backpressure:       BackpressureReject,
+		BlockTimeout:       100 * time.Millisecond,
+		OutboxPollInterval: time.Second,
 	}
 }
 
 // NewEmailDispatcher creates a new email dispatcher
 func NewEmailDispatcher(emailService email.Service, config Config, logger *slog.Logger) *EmailDispatcher {
 	ctx, cancel := context.WithCancel(context.Background())
+	if config.Backpressure == "" {
+		config.Backpressure = BackpressureReject
+	}
+
+	var throttle *domainThrottle
+	if config.DomainRateLimits.enabled() {
+		throttle = newDomainThrottle(config.DomainRateLimits, config.QueueSize)
+	}
 
 	return &EmailDispatcher{
 		emailService: emailService,
@@ -64,6 +137,13 @@ func NewEmailDispatcher(emailService email.Service, config Config, logger *slog.
 		logger:       logger,
 		maxRetries:   config.MaxRetries,
 		retryDelay:   config.RetryDelay,
+		snapshotPath: config.SnapshotPath,
+
+		backpressure:       config.Backpressure,
+		blockTimeout:       config.BlockTimeout,
+		outboxPollInterval: config.OutboxPollInterval,
+		highWatermark:      watermarkLength(config.HighWatermark, config.QueueSize),
+		throttle:           throttle,
 	}
 }
 
@@ -74,22 +154,47 @@ func (d *EmailDispatcher) Start() {
 		"queue_size", cap(d.jobQueue),
 	)
 
+	// Restore jobs left over from the previous shutdown
+	if d.snapshotPath != "" {
+		if err := d.restoreSnapshot(); err != nil {
+			d.logger.Error("failed to restore email queue snapshot",
+				"path", d.snapshotPath,
+				"error", err,
+			)
+		}
+	}
+
 	for i := 0; i < d.workers; i++ {
 		d.wg.Add(1)
 		go d.worker(i)
 	}
+
+	if d.outbox != nil && d.outboxPollInterval > 0 {
+		d.wg.Add(1)
+		go d.refillFromOutbox()
+	}
 }
 
-// Stop stops the email dispatcher and waits for workers to finish
+// Stop stops the email dispatcher and waits for workers to finish. Workers
+// still sending after the timeout are abandoned and ErrWorkersAbandoned is
+// returned.
 func (d *EmailDispatcher) Stop(timeout time.Duration) error {
 	d.logger.Info("stopping email dispatcher")
 
+	// Close the job queue once pending enqueues are done; later ones fail
+	// with ErrDispatcherStopped
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return nil
+	}
+	d.stopped = true
+	close(d.jobQueue)
+	d.mu.Unlock()
+
 	// Signal workers to stop
 	d.cancel()
 
-	// Close the job queue
-	close(d.jobQueue)
-
 	// Wait for workers to finish with timeout
 	done := make(chan struct{})
 	go func() {
@@ -97,55 +202,165 @@ func (d *EmailDispatcher) Stop(timeout time.Duration) error {
 		close(done)
 	}()
 
+	var stopErr error
 	select {
 	case <-done:
 		d.logger.Info("email dispatcher stopped gracefully")
-		return nil
 	case <-time.After(timeout):
-		return fmt.Errorf("timeout waiting for workers to finish")
+		stopErr = d.abandonStuckWorkers()
 	}
+
+	// Persist jobs that were never picked up so they survive the restart
+	if d.snapshotPath != "" {
+		if err := d.writeSnapshot(); err != nil {
+			d.logger.Error("failed to write email queue snapshot",
+				"path", d.snapshotPath,
+				"error", err,
+			)
+			if stopErr == nil {
+				stopErr = err
+			}
+		}
+	}
+
+	return stopErr
 }
 
-// Enqueue adds an email job to the queue
-func (d *EmailDispatcher) Enqueue(email email.Email) error {
-	job := EmailJob{
-		ID:        generateJobID(),
-		Email:     email,
-		CreatedAt: time.Now(),
+// writeSnapshot drains the closed job queue and the jobs held back by
+// domain rate limits, and writes the unsent jobs to disk
+func (d *EmailDispatcher) writeSnapshot() error {
+	d.unsentMu.Lock()
+	jobs := d.unsent
+	d.unsent = nil
+	d.unsentMu.Unlock()
+	for _, job := range d.throttle.drain() {
+		d.queued.remove(job)
+		jobs = append(jobs, job)
+	}
+	for job := range d.jobQueue {
+		d.queued.remove(job)
+		jobs = append(jobs, job)
 	}
 
-	select {
-	case d.jobQueue <- job:
-		d.logger.Debug("email job enqueued",
-			"job_id", job.ID,
-			"to", email.To,
-			"subject", email.Subject,
-		)
+	if len(jobs) == 0 {
 		return nil
-	default:
-		return fmt.Errorf("email queue is full")
 	}
+
+	if err := d.saveSnapshot(jobs); err != nil {
+		return err
+	}
+
+	d.logger.Info("email queue snapshot written",
+		"path", d.snapshotPath,
+		"jobs", len(jobs),
+	)
+
+	return nil
+}
+
+// saveSnapshot replaces the snapshot file with jobs
+func (d *EmailDispatcher) saveSnapshot(jobs []EmailJob) error {
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return fmt.Errorf("failed to encode email queue snapshot: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial snapshot
+	tmpPath := d.snapshotPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write email queue snapshot: %w", err)
+	}
+	if err := os.Rename(tmpPath, d.snapshotPath); err != nil {
+		return fmt.Errorf("failed to write email queue snapshot: %w", err)
+	}
+	return nil
+}
+
+// keepUnsent holds a job for the snapshot written on Stop
+func (d *EmailDispatcher) keepUnsent(job EmailJob) {
+	d.unsentMu.Lock()
+	defer d.unsentMu.Unlock()
+	d.unsent = append(d.unsent, job)
+}
+
+// restoreSnapshot loads jobs from a previous snapshot back into the queue.
+// Jobs that do not fit stay in the snapshot and are written again on Stop.
+func (d *EmailDispatcher) restoreSnapshot() error {
+	data, err := os.ReadFile(d.snapshotPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read email queue snapshot: %w", err)
+	}
+
+	var jobs []EmailJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return fmt.Errorf("failed to decode email queue snapshot: %w", err)
+	}
+
+	restored := 0
+	var remaining []EmailJob
+	for _, job := range jobs {
+		if remaining == nil && trySend(d.jobQueue, job) {
+			d.queued.add(job)
+			restored++
+			continue
+		}
+		remaining = append(remaining, job)
+	}
+
+	// Keep only the jobs left over, so the restored ones are not restored
+	// twice and the others survive a crash
+	if len(remaining) == 0 {
+		if err := os.Remove(d.snapshotPath); err != nil {
+			return fmt.Errorf("failed to remove email queue snapshot: %w", err)
+		}
+	} else {
+		d.unsentMu.Lock()
+		d.unsent = append(d.unsent, remaining...)
+		d.unsentMu.Unlock()
+		if err := d.saveSnapshot(remaining); err != nil {
+			return err
+		}
+		d.logger.Warn("email queue full, snapshot jobs kept for the next start",
+			"path", d.snapshotPath,
+			"jobs", len(remaining),
+		)
+	}
+
+	d.logger.Info("email queue snapshot restored",
+		"path", d.snapshotPath,
+		"jobs", restored,
+	)
+
+	return nil
+}
+
+// Enqueue adds an email job to the queue; a full queue is handled by the
+// backpressure policy
+func (d *EmailDispatcher) Enqueue(email email.Email) error {
+	return d.EnqueuePriority(email, PriorityNormal)
+}
+
+// EnqueuePriority adds an email job with the given priority to the queue
+func (d *EmailDispatcher) EnqueuePriority(email email.Email, priority Priority) error {
+	return d.enqueue(context.Background(), newEmailJob(email, priority), false)
 }
 
 // EnqueueWithContext adds an email job to the queue with context
 func (d *EmailDispatcher) EnqueueWithContext(ctx context.Context, email email.Email) error {
-	job := EmailJob{
+	return d.enqueue(ctx, newEmailJob(email, PriorityNormal), true)
+}
+
+// newEmailJob creates a job for a first delivery attempt
+func newEmailJob(email email.Email, priority Priority) EmailJob {
+	return EmailJob{
 		ID:        generateJobID(),
 		Email:     email,
+		Priority:  priority,
 		CreatedAt: time.Now(),
 	}
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case d.jobQueue <- job:
-		d.logger.Debug("email job enqueued",
-			"job_id", job.ID,
-			"to", email.To,
-			"subject", email.Subject,
-		)
-		return nil
-	}
 }
 
 // QueueSize returns the current number of jobs in the queue
@@ -160,21 +375,71 @@ func (d *EmailDispatcher) worker(id int) {
 	d.logger.Debug("email worker started", "worker_id", id)
 
 	for {
+		// Jobs held back by domain rate limits go first once due
+		if job, ok := d.throttle.take(); ok {
+			d.queued.remove(job)
+			d.processJob(id, job)
+			continue
+		}
+
+		// Wake up when the first held job is due
+		var wake <-chan time.Time
+		if wait, ok := d.throttle.next(); ok {
+			wake = time.After(wait)
+		}
+
 		select {
 		case <-d.ctx.Done():
 			d.logger.Debug("email worker stopping", "worker_id", id)
 			return
+		case <-wake:
 		case job, ok := <-d.jobQueue:
 			if !ok {
 				d.logger.Debug("email worker stopping (queue closed)", "worker_id", id)
 				return
 			}
+			d.queued.remove(job)
+			d.checkWatermark()
 
-			d.processJob(id, job)
+			if d.admit(id, job) {
+				d.processJob(id, job)
+			}
 		}
 	}
 }
 
+// admit reports whether a job may be sent now. A job to a domain over its
+// rate is held back until its turn; when too many are held, the worker waits
+// for the turn instead.
+func (d *EmailDispatcher) admit(workerID int, job EmailJob) bool {
+	wait := d.throttle.reserve(job)
+	if wait == 0 {
+		return true
+	}
+
+	due := time.Now().Add(wait)
+	if d.throttle.hold(job, due, false) {
+		d.queued.add(job)
+		d.logger.Debug("email held back by domain rate limit",
+			"worker_id", workerID,
+			"job_id", job.ID,
+			"domain", recipientDomain(job.Email.To),
+			"wait", wait,
+		)
+		return false
+	}
+
+	select {
+	case <-time.After(wait):
+		return true
+	case <-d.ctx.Done():
+		// Keep the job for the snapshot
+		d.throttle.hold(job, due, true)
+		d.queued.add(job)
+		return false
+	}
+}
+
 // processJob processes a single email job with retries
 func (d *EmailDispatcher) processJob(workerID int, job EmailJob) {
 	startTime := time.Now()
@@ -191,7 +456,9 @@ func (d *EmailDispatcher) processJob(workerID int, job EmailJob) {
 	defer cancel()
 
 	// Try to send the email
+	d.trackJob(workerID, job)
 	err := d.emailService.Send(ctx, job.Email)
+	d.untrackJob(workerID)
 
 	if err == nil {
 		// Success
@@ -217,25 +484,16 @@ func (d *EmailDispatcher) processJob(workerID int, job EmailJob) {
 	if job.Retries < d.maxRetries {
 		job.Retries++
 
-		// Wait before retry
+		// Wait before retry; a retry cut short by Stop goes to the snapshot
 		select {
 		case <-d.ctx.Done():
+			d.keepUnsent(job)
 			return
 		case <-time.After(d.retryDelay * time.Duration(job.Retries)):
 		}
 
 		// Re-enqueue the job
-		select {
-		case d.jobQueue <- job:
-			d.logger.Debug("email job re-enqueued for retry",
-				"job_id", job.ID,
-				"retries", job.Retries,
-			)
-		default:
-			d.logger.Error("failed to re-enqueue email job (queue full)",
-				"job_id", job.ID,
-			)
-		}
+		d.requeue(job)
 	} else {
 		d.logger.Error("email job failed after max retries",
 			"job_id", job.ID,
@@ -245,6 +503,27 @@ func (d *EmailDispatcher) processJob(workerID int, job EmailJob) {
 	}
 }
 
+// requeue puts a job back for another attempt; a full queue is handled by
+// the backpressure policy. After Stop the job goes to the snapshot.
+func (d *EmailDispatcher) requeue(job EmailJob) {
+	err := d.enqueue(context.Background(), job, false)
+	if errors.Is(err, ErrDispatcherStopped) {
+		d.keepUnsent(job)
+		return
+	}
+	if err != nil {
+		d.logger.Error("failed to re-enqueue email job",
+			"job_id", job.ID,
+			"error", err,
+		)
+		return
+	}
+	d.logger.Debug("email job re-enqueued for retry",
+		"job_id", job.ID,
+		"retries", job.Retries,
+	)
+}
+
 // generateJobID generates a unique job ID
 func generateJobID() string {
 	return fmt.Sprintf("email-%d-%d", time.Now().UnixNano(), time.Now().Nanosecond())
@@ -256,6 +535,8 @@ type Stats struct {
 	QueueSize     int
 	QueueCapacity int
 	Running       bool
+	OldestJob     time.Time // when the oldest queued job was created; zero when the queue is empty
+	Throttled     int       // jobs held back by domain rate limits
 }
 
 // GetStats returns current dispatcher statistics
@@ -265,5 +546,59 @@ func (d *EmailDispatcher) GetStats() Stats {
 		QueueSize:     len(d.jobQueue),
 		QueueCapacity: cap(d.jobQueue),
 		Running:       d.ctx.Err() == nil,
+		OldestJob:     d.queued.oldest(),
+		Throttled:     d.throttle.len(),
+	}
+}
+
+// queuedJobs tracks when the jobs in the queue were created. A worker may
+// take a job before its send is recorded, so each entry counts sends minus
+// receives and is dropped at zero.
+type queuedJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*queuedJob
+}
+
+type queuedJob struct {
+	createdAt time.Time
+	count     int
+}
+
+func (q *queuedJobs) add(job EmailJob) {
+	q.adjust(job, 1)
+}
+
+func (q *queuedJobs) remove(job EmailJob) {
+	q.adjust(job, -1)
+}
+
+func (q *queuedJobs) adjust(job EmailJob, delta int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.jobs == nil {
+		q.jobs = make(map[string]*queuedJob)
+	}
+	entry, ok := q.jobs[job.ID]
+	if !ok {
+		entry = &queuedJob{createdAt: job.CreatedAt}
+		q.jobs[job.ID] = entry
+	}
+	entry.count += delta
+	if entry.count == 0 {
+		delete(q.jobs, job.ID)
+	}
+}
+
+// oldest returns the creation time of the oldest queued job, or the zero
+// time when there is none
+func (q *queuedJobs) oldest() time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var oldest time.Time
+	for _, entry := range q.jobs {
+		if entry.count > 0 && (oldest.IsZero() || entry.createdAt.Before(oldest)) {
+			oldest = entry.createdAt
+		}
 	}
+	return oldest
 }
@@ -2,8 +2,12 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -199,6 +203,17 @@ func TestEmailDispatcher_Stop(t *testing.T) {
 	if stats.Running {
 		t.Error("Dispatcher should not be running after stop")
 	}
+
+	// Emails queued during shutdown are refused instead of panicking
+	if err := dispatcher.Enqueue(testEmail); !errors.Is(err, ErrDispatcherStopped) {
+		t.Errorf("Enqueue() after stop error = %v, want %v", err, ErrDispatcherStopped)
+	}
+	if err := dispatcher.EnqueueWithContext(context.Background(), testEmail); !errors.Is(err, ErrDispatcherStopped) {
+		t.Errorf("EnqueueWithContext() after stop error = %v, want %v", err, ErrDispatcherStopped)
+	}
+	if err := dispatcher.Stop(time.Second); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
 }
 
 func TestEmailDispatcher_EnqueueWithContext(t *testing.T) {
@@ -273,3 +288,213 @@ func TestEmailDispatcher_EnqueueWithContext(t *testing.T) {
 		}
 	})
 }
+
+func TestEmailDispatcher_Snapshot(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	snapshotPath := filepath.Join(t.TempDir(), "email-queue.json")
+
+	config := DefaultConfig()
+	config.Workers = 1
+	config.SnapshotPath = snapshotPath
+
+	// Never start the first dispatcher so queued jobs stay unsent
+	dispatcher := NewEmailDispatcher(email.NewMockService(logger), config, logger)
+
+	emails := []email.Email{
+		{To: "first@example.com", Subject: "First", Body: "Body 1"},
+		{To: "second@example.com", Subject: "Second", Body: "Body 2"},
+	}
+	for _, e := range emails {
+		if err := dispatcher.Enqueue(e); err != nil {
+			t.Fatalf("Failed to enqueue email: %v", err)
+		}
+	}
+
+	if err := dispatcher.Stop(time.Second); err != nil {
+		t.Fatalf("Failed to stop dispatcher: %v", err)
+	}
+
+	if _, err := os.Stat(snapshotPath); err != nil {
+		t.Fatalf("Expected snapshot file to exist: %v", err)
+	}
+
+	// A new dispatcher should pick up and send the persisted jobs
+	mockService := email.NewMockService(logger)
+	restarted := NewEmailDispatcher(mockService, config, logger)
+	restarted.Start()
+	defer restarted.Stop(time.Second)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if mockService.CountEmails() != len(emails) {
+		t.Errorf("Expected %d restored emails to be sent, got %d", len(emails), mockService.CountEmails())
+	}
+	for _, e := range emails {
+		if _, found := mockService.FindEmail(e.To); !found {
+			t.Errorf("Email to %s was not restored", e.To)
+		}
+	}
+
+	if _, err := os.Stat(snapshotPath); !os.IsNotExist(err) {
+		t.Error("Snapshot file should be removed after restore")
+	}
+}
+
+func TestEmailDispatcher_SnapshotEmptyQueue(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	snapshotPath := filepath.Join(t.TempDir(), "email-queue.json")
+
+	config := DefaultConfig()
+	config.SnapshotPath = snapshotPath
+
+	dispatcher := NewEmailDispatcher(email.NewMockService(logger), config, logger)
+	dispatcher.Start()
+
+	if err := dispatcher.Stop(time.Second); err != nil {
+		t.Fatalf("Failed to stop dispatcher: %v", err)
+	}
+
+	if _, err := os.Stat(snapshotPath); !os.IsNotExist(err) {
+		t.Error("No snapshot should be written for an empty queue")
+	}
+}
+
+func TestEmailDispatcher_SnapshotLargerThanQueue(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	snapshotPath := filepath.Join(t.TempDir(), "email-queue.json")
+
+	config := DefaultConfig()
+	config.Workers = 0
+	config.SnapshotPath = snapshotPath
+
+	dispatcher := NewEmailDispatcher(email.NewMockService(logger), config, logger)
+	for i := 0; i < 5; i++ {
+		if err := dispatcher.Enqueue(email.Email{To: fmt.Sprintf("user%d@example.com", i), Subject: "Queued"}); err != nil {
+			t.Fatalf("Failed to enqueue email: %v", err)
+		}
+	}
+	if err := dispatcher.Stop(time.Second); err != nil {
+		t.Fatalf("Failed to stop dispatcher: %v", err)
+	}
+
+	// A smaller queue restores what fits and keeps the rest on disk
+	config.QueueSize = 2
+	small := NewEmailDispatcher(email.NewMockService(logger), config, logger)
+	small.Start()
+	if small.QueueSize() != 2 {
+		t.Errorf("QueueSize() = %d, want 2", small.QueueSize())
+	}
+	if jobs := readSnapshot(t, snapshotPath); len(jobs) != 3 {
+		t.Errorf("snapshot after restore has %d jobs, want 3", len(jobs))
+	}
+	if err := small.Stop(time.Second); err != nil {
+		t.Fatalf("Failed to stop dispatcher: %v", err)
+	}
+	if jobs := readSnapshot(t, snapshotPath); len(jobs) != 5 {
+		t.Errorf("snapshot after stop has %d jobs, want 5", len(jobs))
+	}
+
+	// Once there is room, every job is sent
+	config.QueueSize = 10
+	config.Workers = 1
+	mockService := email.NewMockService(logger)
+	restarted := NewEmailDispatcher(mockService, config, logger)
+	restarted.Start()
+	defer restarted.Stop(time.Second)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if mockService.CountEmails() != 5 {
+		t.Errorf("Expected 5 restored emails to be sent, got %d", mockService.CountEmails())
+	}
+}
+
+func TestEmailDispatcher_SnapshotPendingRetry(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	snapshotPath := filepath.Join(t.TempDir(), "email-queue.json")
+
+	config := DefaultConfig()
+	config.Workers = 1
+	config.RetryDelay = time.Hour
+	config.SnapshotPath = snapshotPath
+
+	service := &switchableService{down: true}
+	dispatcher := NewEmailDispatcher(service, config, logger)
+	dispatcher.Start()
+
+	if err := dispatcher.Enqueue(email.Email{To: "retry@example.com", Subject: "Retry"}); err != nil {
+		t.Fatalf("Failed to enqueue email: %v", err)
+	}
+
+	// Let the first attempt fail so the job waits for its retry
+	time.Sleep(100 * time.Millisecond)
+
+	if err := dispatcher.Stop(time.Second); err != nil {
+		t.Fatalf("Failed to stop dispatcher: %v", err)
+	}
+
+	jobs := readSnapshot(t, snapshotPath)
+	if len(jobs) != 1 {
+		t.Fatalf("snapshot has %d jobs, want the pending retry", len(jobs))
+	}
+	if jobs[0].Email.To != "retry@example.com" || jobs[0].Retries != 1 {
+		t.Errorf("snapshot job = %+v, want the retry to retry@example.com", jobs[0])
+	}
+}
+
+// readSnapshot decodes the jobs in a snapshot file
+func readSnapshot(t *testing.T, path string) []EmailJob {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	var jobs []EmailJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	return jobs
+}
+
+func TestEmailDispatcher_OldestJob(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	config := DefaultConfig()
+	config.Workers = 0
+
+	dispatcher := NewEmailDispatcher(email.NewMockService(logger), config, logger)
+	if oldest := dispatcher.GetStats().OldestJob; !oldest.IsZero() {
+		t.Errorf("OldestJob = %v for an empty queue, want zero", oldest)
+	}
+
+	before := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := dispatcher.Enqueue(email.Email{To: "test@example.com", Subject: "Queued"}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	oldest := dispatcher.GetStats().OldestJob
+	if oldest.Before(before) || oldest.After(time.Now()) {
+		t.Errorf("OldestJob = %v, want the first job's creation time", oldest)
+	}
+
+	// Workers taking every job empty the queue again
+	for i := 0; i < 3; i++ {
+		job := <-dispatcher.jobQueue
+		dispatcher.queued.remove(job)
+	}
+	if oldest := dispatcher.GetStats().OldestJob; !oldest.IsZero() {
+		t.Errorf("OldestJob = %v after the queue drained, want zero", oldest)
+	}
+}
+
+func TestQueuedJobs_ReceiveBeforeSend(t *testing.T) {
+	var queued queuedJobs
+	job := EmailJob{ID: "job-1", CreatedAt: time.Now()}
+
+	// A worker may take a job before the sender records it
+	queued.remove(job)
+	queued.add(job)
+	if oldest := queued.oldest(); !oldest.IsZero() {
+		t.Errorf("oldest() = %v, want zero once the job was taken", oldest)
+	}
+}
@@ -0,0 +1,169 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/email"
+	"github.com/n1rocket/go-auth-jwt/internal/httpclient"
+)
+
+// ErrNoEmailProvider is returned for sends while the circuit of every email
+// provider is open. The job is retried like any failed send.
+var ErrNoEmailProvider = errors.New("no healthy email provider")
+
+// EmailProvider is an email service the dispatcher can send through
+type EmailProvider struct {
+	Name    string
+	Service email.Service
+}
+
+// FailoverPolicy decides when a failing provider is taken out of rotation
+type FailoverPolicy struct {
+	// Threshold is the number of consecutive failed sends that trips a
+	// provider's circuit breaker
+	Threshold int
+	// Cooldown is how long a tripped provider is skipped before one send
+	// probes it again
+	Cooldown time.Duration
+}
+
+// ProviderRecorder records sends per email provider, the active provider
+// and failovers between providers
+type ProviderRecorder interface {
+	RecordEmailProviderSend(provider, outcome string)
+	SetActiveEmailProvider(provider string, active bool)
+	RecordEmailFailover(from, to string)
+}
+
+// failoverProvider is a provider with its circuit breaker
+type failoverProvider struct {
+	EmailProvider
+	breaker *httpclient.CircuitBreaker
+}
+
+// failoverService sends through the first provider, in order of preference,
+// whose circuit is closed. Failed sends are not retried on the next
+// provider: the provider may have accepted the email before failing, so
+// only a tripped circuit moves traffic.
+type failoverService struct {
+	providers []failoverProvider
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	active   string
+	recorder ProviderRecorder
+}
+
+// SetProviders replaces the email service with providers, in order of
+// preference. A provider failing policy.Threshold sends in a row is skipped
+// until policy.Cooldown has passed, so sends fail over to the next one and
+// move back once the preferred provider recovers. It must be called before
+// Start.
+func (d *EmailDispatcher) SetProviders(providers []EmailProvider, policy FailoverPolicy) {
+	failover := &failoverService{logger: d.logger}
+	for _, provider := range providers {
+		failover.providers = append(failover.providers, failoverProvider{
+			EmailProvider: provider,
+			breaker:       httpclient.NewCircuitBreaker(policy.Threshold, policy.Cooldown),
+		})
+	}
+	d.emailService = failover
+	d.failover = failover
+}
+
+// SetProviderRecorder sets where provider sends and failovers are counted.
+// It must be called after SetProviders and before Start.
+func (d *EmailDispatcher) SetProviderRecorder(recorder ProviderRecorder) {
+	if d.failover == nil {
+		return
+	}
+	d.failover.mu.Lock()
+	defer d.failover.mu.Unlock()
+	d.failover.recorder = recorder
+	for _, provider := range d.failover.providers {
+		recorder.SetActiveEmailProvider(provider.Name, provider.Name == d.failover.active)
+	}
+}
+
+// ActiveProvider returns the provider the last email was sent through, or
+// "" before the first send and without providers
+func (d *EmailDispatcher) ActiveProvider() string {
+	if d.failover == nil {
+		return ""
+	}
+	d.failover.mu.Lock()
+	defer d.failover.mu.Unlock()
+	return d.failover.active
+}
+
+// Send sends through the preferred healthy provider
+func (f *failoverService) Send(ctx context.Context, message email.Email) error {
+	for _, provider := range f.providers {
+		if provider.breaker.Allow() != nil {
+			continue
+		}
+
+		// Timeouts count as failures: a provider that hangs is as unhealthy
+		// as one that refuses
+		err := provider.Service.Send(ctx, message)
+		if err != nil {
+			provider.breaker.RecordFailure()
+			f.record(provider.Name, "failed")
+			if provider.breaker.State() == httpclient.StateOpen {
+				f.logger.Warn("email provider circuit open",
+					"provider", provider.Name,
+					"error", err,
+				)
+			}
+			return fmt.Errorf("provider %s: %w", provider.Name, err)
+		}
+
+		provider.breaker.RecordSuccess()
+		f.record(provider.Name, "sent")
+		f.activate(provider.Name)
+		return nil
+	}
+	return ErrNoEmailProvider
+}
+
+// record counts a send of a provider
+func (f *failoverService) record(provider, outcome string) {
+	f.mu.Lock()
+	recorder := f.recorder
+	f.mu.Unlock()
+	if recorder != nil {
+		recorder.RecordEmailProviderSend(provider, outcome)
+	}
+}
+
+// activate marks the provider that sent the last email, logging and
+// counting a failover when it changed
+func (f *failoverService) activate(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previous := f.active
+	if previous == name {
+		return
+	}
+	f.active = name
+	if previous != "" {
+		f.logger.Warn("email provider failover",
+			"from", previous,
+			"to", name,
+		)
+	}
+	if f.recorder == nil {
+		return
+	}
+	if previous != "" {
+		f.recorder.RecordEmailFailover(previous, name)
+		f.recorder.SetActiveEmailProvider(previous, false)
+	}
+	f.recorder.SetActiveEmailProvider(name, true)
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/email"
+)
+
+// switchableService fails every send while down
+type switchableService struct {
+	mu   sync.Mutex
+	down bool
+	sent int
+}
+
+func (s *switchableService) Send(ctx context.Context, message email.Email) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return errors.New("connection refused")
+	}
+	s.sent++
+	return nil
+}
+
+func (s *switchableService) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// providerEvents records provider metrics
+type providerEvents struct {
+	sends     map[string]int
+	active    map[string]bool
+	failovers []string
+}
+
+func (p *providerEvents) RecordEmailProviderSend(provider, outcome string) {
+	p.sends[provider+"/"+outcome]++
+}
+
+func (p *providerEvents) SetActiveEmailProvider(provider string, active bool) {
+	p.active[provider] = active
+}
+
+func (p *providerEvents) RecordEmailFailover(from, to string) {
+	p.failovers = append(p.failovers, from+"->"+to)
+}
+
+func TestEmailDispatcher_Failover(t *testing.T) {
+	primary, secondary := &switchableService{}, &switchableService{}
+	dispatcher := NewEmailDispatcher(nil, DefaultConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	dispatcher.SetProviders([]EmailProvider{
+		{Name: "primary", Service: primary},
+		{Name: "secondary", Service: secondary},
+	}, FailoverPolicy{Threshold: 2, Cooldown: 50 * time.Millisecond})
+	events := &providerEvents{sends: make(map[string]int), active: make(map[string]bool)}
+	dispatcher.SetProviderRecorder(events)
+
+	ctx := context.Background()
+	message := email.Email{To: "user@example.com", Subject: "Test"}
+	send := func() error { return dispatcher.emailService.Send(ctx, message) }
+
+	if err := send(); err != nil || dispatcher.ActiveProvider() != "primary" {
+		t.Fatalf("Send() error = %v, active %q", err, dispatcher.ActiveProvider())
+	}
+
+	// Failed sends stay on the primary until its circuit trips; they are
+	// not resent through the secondary, which could duplicate them
+	primary.setDown(true)
+	for i := 0; i < 2; i++ {
+		if err := send(); err == nil {
+			t.Fatal("Send() through a failing primary succeeded")
+		}
+	}
+	if secondary.sent != 0 {
+		t.Errorf("secondary sent %d emails before the primary tripped", secondary.sent)
+	}
+
+	if err := send(); err != nil {
+		t.Fatalf("Send() after the primary tripped error = %v", err)
+	}
+	if secondary.sent != 1 || dispatcher.ActiveProvider() != "secondary" {
+		t.Errorf("secondary sent %d, active %q; want the send failed over", secondary.sent, dispatcher.ActiveProvider())
+	}
+
+	// Once the cooldown passes, a probe finds the primary healthy again
+	primary.setDown(false)
+	time.Sleep(60 * time.Millisecond)
+	if err := send(); err != nil || dispatcher.ActiveProvider() != "primary" {
+		t.Errorf("Send() after recovery error = %v, active %q", err, dispatcher.ActiveProvider())
+	}
+
+	if events.sends["primary/failed"] != 2 || events.sends["secondary/sent"] != 1 {
+		t.Errorf("sends = %v", events.sends)
+	}
+	if len(events.failovers) != 2 || events.failovers[0] != "primary->secondary" || events.failovers[1] != "secondary->primary" {
+		t.Errorf("failovers = %v", events.failovers)
+	}
+	if !events.active["primary"] || events.active["secondary"] {
+		t.Errorf("active = %v, want primary", events.active)
+	}
+}
+
+func TestEmailDispatcher_FailoverAllDown(t *testing.T) {
+	primary := &switchableService{down: true}
+	dispatcher := NewEmailDispatcher(nil, DefaultConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	dispatcher.SetProviders([]EmailProvider{{Name: "primary", Service: primary}}, FailoverPolicy{Threshold: 1, Cooldown: time.Minute})
+
+	message := email.Email{To: "user@example.com"}
+	dispatcher.emailService.Send(context.Background(), message)
+	if err := dispatcher.emailService.Send(context.Background(), message); !errors.Is(err, ErrNoEmailProvider) {
+		t.Errorf("Send() with every circuit open error = %v, want %v", err, ErrNoEmailProvider)
+	}
+}
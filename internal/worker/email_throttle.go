@@ -0,0 +1,211 @@
+package worker
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDomainBuckets is how many recipient domains keep a bucket before full
+// buckets, which behave like new ones, are dropped
+const maxDomainBuckets = 1024
+
+// DomainRateLimits spaces out the emails sent to each recipient domain, so
+// a burst to one provider does not get the relay throttled or blocklisted
+type DomainRateLimits struct {
+	// PerMinute is the rate of every domain not listed in Domains; 0 leaves
+	// them unlimited
+	PerMinute int
+	// Burst is how many emails to a domain are sent back to back before the
+	// rate applies, capped at the domain's rate
+	Burst int
+	// Domains overrides the rate of lowercase domains; 0 leaves a domain
+	// unlimited
+	Domains map[string]int
+}
+
+// enabled reports whether any domain is limited
+func (l DomainRateLimits) enabled() bool {
+	if l.PerMinute > 0 {
+		return true
+	}
+	for _, rate := range l.Domains {
+		if rate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// domainThrottle holds token buckets per recipient domain. A job whose
+// domain is out of tokens is held aside until its turn, so the jobs queued
+// behind it keep moving.
+type domainThrottle struct {
+	limits   DomainRateLimits
+	capacity int // most held jobs; workers wait out the delay of any more
+	now      func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*domainBucket
+	held    []heldJob // by due time
+}
+
+type domainBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type heldJob struct {
+	job EmailJob
+	due time.Time
+}
+
+func newDomainThrottle(limits DomainRateLimits, capacity int) *domainThrottle {
+	return &domainThrottle{
+		limits:   limits,
+		capacity: capacity,
+		now:      time.Now,
+		buckets:  make(map[string]*domainBucket),
+	}
+}
+
+// recipientDomain is the lowercase domain of an address
+func recipientDomain(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimRight(address[at+1:], "> "))
+}
+
+// rate returns the emails per minute and burst of a domain
+func (t *domainThrottle) rate(domain string) (int, int) {
+	perMinute, ok := t.limits.Domains[domain]
+	if !ok {
+		perMinute = t.limits.PerMinute
+	}
+	burst := t.limits.Burst
+	if burst <= 0 || burst > perMinute {
+		burst = perMinute
+	}
+	return perMinute, burst
+}
+
+// reserve takes a token for the job's domain and returns how long the job
+// has to wait for it. Tokens are taken ahead of time, so each job held for
+// a domain waits behind the previous one.
+func (t *domainThrottle) reserve(job EmailJob) time.Duration {
+	if t == nil {
+		return 0
+	}
+	domain := recipientDomain(job.Email.To)
+	perMinute, burst := t.rate(domain)
+	if perMinute <= 0 {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	bucket, ok := t.buckets[domain]
+	if !ok {
+		if len(t.buckets) >= maxDomainBuckets {
+			t.pruneLocked(now)
+		}
+		bucket = &domainBucket{tokens: float64(burst), last: now}
+		t.buckets[domain] = bucket
+	}
+
+	perSecond := float64(perMinute) / 60
+	bucket.tokens = min(float64(burst), bucket.tokens+now.Sub(bucket.last).Seconds()*perSecond)
+	bucket.last = now
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / perSecond * float64(time.Second))
+}
+
+// pruneLocked drops the buckets that have refilled
+func (t *domainThrottle) pruneLocked(now time.Time) {
+	for domain, bucket := range t.buckets {
+		perMinute, burst := t.rate(domain)
+		if bucket.tokens+now.Sub(bucket.last).Minutes()*float64(perMinute) >= float64(burst) {
+			delete(t.buckets, domain)
+		}
+	}
+}
+
+// hold sets the job aside until due. With force unset, it fails when the
+// throttle already holds capacity jobs.
+func (t *domainThrottle) hold(job EmailJob, due time.Time, force bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !force && len(t.held) >= t.capacity {
+		return false
+	}
+	i := sort.Search(len(t.held), func(i int) bool { return t.held[i].due.After(due) })
+	t.held = append(t.held, heldJob{})
+	copy(t.held[i+1:], t.held[i:])
+	t.held[i] = heldJob{job: job, due: due}
+	return true
+}
+
+// take returns the first held job that is due
+func (t *domainThrottle) take() (EmailJob, bool) {
+	if t == nil {
+		return EmailJob{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.held) == 0 || t.held[0].due.After(t.now()) {
+		return EmailJob{}, false
+	}
+	job := t.held[0].job
+	t.held = t.held[1:]
+	return job, true
+}
+
+// next returns how long until the first held job is due
+func (t *domainThrottle) next() (time.Duration, bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.held) == 0 {
+		return 0, false
+	}
+	return max(t.held[0].due.Sub(t.now()), 0), true
+}
+
+// drain removes and returns every held job
+func (t *domainThrottle) drain() []EmailJob {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	jobs := make([]EmailJob, 0, len(t.held))
+	for _, held := range t.held {
+		jobs = append(jobs, held.job)
+	}
+	t.held = nil
+	return jobs
+}
+
+// len returns the number of held jobs
+func (t *domainThrottle) len() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.held)
+}
@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/email"
+)
+
+func throttleJob(to string) EmailJob {
+	return EmailJob{ID: to, Email: email.Email{To: to}}
+}
+
+func TestDomainThrottle_Reserve(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	throttle := newDomainThrottle(DomainRateLimits{
+		PerMinute: 60,
+		Burst:     2,
+		Domains:   map[string]int{"gmail.com": 6, "example.org": 0},
+	}, 10)
+	throttle.now = func() time.Time { return now }
+
+	// The burst goes out at once, then one email a second
+	for i, want := range []time.Duration{0, 0, time.Second, 2 * time.Second} {
+		if got := throttle.reserve(throttleJob("user@example.com")); got != want {
+			t.Errorf("reserve #%d = %v, want %v", i, got, want)
+		}
+	}
+
+	// Domains are limited separately and case-insensitively
+	if got := throttle.reserve(throttleJob("user@other.com")); got != 0 {
+		t.Errorf("other domain waits %v", got)
+	}
+	throttle.reserve(throttleJob("a@gmail.com"))
+	throttle.reserve(throttleJob("b@gmail.com"))
+	if got := throttle.reserve(throttleJob("C@GMAIL.COM")); got != 10*time.Second {
+		t.Errorf("gmail.com waits %v, want 10s", got)
+	}
+
+	// A rate of 0 leaves a domain unlimited
+	for i := 0; i < 5; i++ {
+		if got := throttle.reserve(throttleJob("user@example.org")); got != 0 {
+			t.Fatalf("unlimited domain waits %v", got)
+		}
+	}
+
+	// Tokens refill with time
+	now = now.Add(time.Minute)
+	if got := throttle.reserve(throttleJob("user@example.com")); got != 0 {
+		t.Errorf("refilled domain waits %v", got)
+	}
+}
+
+func TestDomainThrottle_Hold(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	throttle := newDomainThrottle(DomainRateLimits{PerMinute: 1}, 2)
+	throttle.now = func() time.Time { return now }
+
+	if !throttle.hold(throttleJob("late@example.com"), now.Add(2*time.Second), false) ||
+		!throttle.hold(throttleJob("early@example.com"), now.Add(time.Second), false) {
+		t.Fatal("hold failed below capacity")
+	}
+	if throttle.hold(throttleJob("full@example.com"), now, false) {
+		t.Error("hold should fail at capacity")
+	}
+
+	if _, ok := throttle.take(); ok {
+		t.Error("take returned a job before it was due")
+	}
+	if wait, ok := throttle.next(); !ok || wait != time.Second {
+		t.Errorf("next = %v, %v, want 1s", wait, ok)
+	}
+
+	now = now.Add(3 * time.Second)
+	for _, want := range []string{"early@example.com", "late@example.com"} {
+		job, ok := throttle.take()
+		if !ok || job.ID != want {
+			t.Errorf("take = %q, %v, want %q", job.ID, ok, want)
+		}
+	}
+	if throttle.len() != 0 {
+		t.Errorf("len = %d after taking every job", throttle.len())
+	}
+}
+
+func TestEmailDispatcher_DomainRateLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockService := email.NewMockService(logger)
+	snapshotPath := filepath.Join(t.TempDir(), "email-queue.json")
+
+	config := DefaultConfig()
+	config.Workers = 1
+	config.SnapshotPath = snapshotPath
+	config.DomainRateLimits = DomainRateLimits{Burst: 1, Domains: map[string]int{"slow.com": 1}}
+
+	dispatcher := NewEmailDispatcher(mockService, config, logger)
+	for _, to := range []string{"first@slow.com", "second@slow.com", "other@fast.com"} {
+		if err := dispatcher.Enqueue(email.Email{To: to, Subject: "Test", Body: "Body"}); err != nil {
+			t.Fatalf("Failed to enqueue email: %v", err)
+		}
+	}
+	dispatcher.Start()
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The second email to slow.com waits its turn without holding up fast.com
+	for _, to := range []string{"first@slow.com", "other@fast.com"} {
+		if _, found := mockService.FindEmail(to); !found {
+			t.Errorf("Email to %s was not sent", to)
+		}
+	}
+	if _, found := mockService.FindEmail("second@slow.com"); found {
+		t.Error("Email over the domain rate was sent")
+	}
+	if stats := dispatcher.GetStats(); stats.Throttled != 1 || stats.OldestJob.IsZero() {
+		t.Errorf("Throttled = %d, OldestJob = %v, want 1 held job", stats.Throttled, stats.OldestJob)
+	}
+
+	// Held jobs survive a restart
+	if err := dispatcher.Stop(time.Second); err != nil {
+		t.Fatalf("Failed to stop dispatcher: %v", err)
+	}
+	data, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	var jobs []EmailJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Email.To != "second@slow.com" {
+		t.Errorf("snapshot = %+v, want the held job", jobs)
+	}
+}
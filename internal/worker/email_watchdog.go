@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/security"
+)
+
+// ErrWorkersAbandoned is returned by Stop when workers were still sending
+// after the timeout. They are left running so the process can exit; their
+// jobs are not snapshotted, as the email may already have gone out.
+var ErrWorkersAbandoned = errors.New("timeout waiting for workers to finish; stuck workers abandoned")
+
+// StuckWorkerRecorder records workers abandoned on Stop, by the template of
+// the email they were sending
+type StuckWorkerRecorder interface {
+	RecordStuckEmailWorker(template string)
+}
+
+// inFlightJob is the job a worker is currently sending
+type inFlightJob struct {
+	job     EmailJob
+	started time.Time
+}
+
+// SetStuckWorkerRecorder sets where abandoned workers are counted. It must
+// be called before Start.
+func (d *EmailDispatcher) SetStuckWorkerRecorder(recorder StuckWorkerRecorder) {
+	d.stuckRecorder = recorder
+}
+
+// trackJob marks a job as being sent by a worker
+func (d *EmailDispatcher) trackJob(workerID int, job EmailJob) {
+	d.inFlightMu.Lock()
+	defer d.inFlightMu.Unlock()
+	if d.inFlight == nil {
+		d.inFlight = make(map[int]inFlightJob)
+	}
+	d.inFlight[workerID] = inFlightJob{job: job, started: time.Now()}
+}
+
+// untrackJob marks a worker as no longer sending
+func (d *EmailDispatcher) untrackJob(workerID int) {
+	d.inFlightMu.Lock()
+	defer d.inFlightMu.Unlock()
+	delete(d.inFlight, workerID)
+}
+
+// abandonStuckWorkers logs and counts the jobs of workers still sending
+// after the stop timeout. Recipients are logged as a hash so the log does
+// not collect addresses.
+func (d *EmailDispatcher) abandonStuckWorkers() error {
+	d.inFlightMu.Lock()
+	workerIDs := make([]int, 0, len(d.inFlight))
+	for id := range d.inFlight {
+		workerIDs = append(workerIDs, id)
+	}
+	sort.Ints(workerIDs)
+	stuck := make([]inFlightJob, 0, len(workerIDs))
+	for _, id := range workerIDs {
+		stuck = append(stuck, d.inFlight[id])
+	}
+	d.inFlightMu.Unlock()
+
+	for i, inFlight := range stuck {
+		d.logger.Error("abandoning stuck email worker",
+			"worker_id", workerIDs[i],
+			"job_id", inFlight.job.ID,
+			"template", inFlight.job.Email.Template,
+			"recipient_hash", security.HashToken(inFlight.job.Email.To)[:16],
+			"running", time.Since(inFlight.started),
+		)
+		if d.stuckRecorder != nil {
+			d.stuckRecorder.RecordStuckEmailWorker(inFlight.job.Email.Template)
+		}
+	}
+
+	return fmt.Errorf("%w (%d sending)", ErrWorkersAbandoned, len(stuck))
+}
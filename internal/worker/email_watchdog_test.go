@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/email"
+)
+
+// hangingService blocks every send until released, ignoring the context
+// like SMTP I/O without deadlines
+type hangingService struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *hangingService) Send(ctx context.Context, e email.Email) error {
+	s.started <- struct{}{}
+	<-s.release
+	return nil
+}
+
+type stuckRecorder struct {
+	mu        sync.Mutex
+	templates []string
+}
+
+func (r *stuckRecorder) RecordStuckEmailWorker(template string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates = append(r.templates, template)
+}
+
+func TestEmailDispatcher_StopAbandonsStuckWorkers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	svc := &hangingService{started: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(svc.release)
+
+	config := DefaultConfig()
+	config.Workers = 2
+	dispatcher := NewEmailDispatcher(svc, config, logger)
+	recorder := &stuckRecorder{}
+	dispatcher.SetStuckWorkerRecorder(recorder)
+	dispatcher.Start()
+
+	if err := dispatcher.Enqueue(email.Email{To: "stuck@example.com", Subject: "Verify", Template: "verification"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	select {
+	case <-svc.started:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not pick up the job")
+	}
+
+	start := time.Now()
+	err := dispatcher.Stop(50 * time.Millisecond)
+	if !errors.Is(err, ErrWorkersAbandoned) {
+		t.Fatalf("Stop() error = %v, want %v", err, ErrWorkersAbandoned)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop() took %v, want it to return after the timeout", elapsed)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.templates) != 1 || recorder.templates[0] != "verification" {
+		t.Errorf("recorded stuck templates = %v, want [verification]", recorder.templates)
+	}
+}
+
+func TestEmailDispatcher_StopUntracksFinishedJobs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mockService := email.NewMockService(logger)
+
+	dispatcher := NewEmailDispatcher(mockService, DefaultConfig(), logger)
+	dispatcher.Start()
+	if err := dispatcher.Enqueue(email.Email{To: "done@example.com", Subject: "Done"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if err := dispatcher.Stop(time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	dispatcher.inFlightMu.Lock()
+	defer dispatcher.inFlightMu.Unlock()
+	if len(dispatcher.inFlight) != 0 {
+		t.Errorf("in-flight jobs after stop = %v, want none", dispatcher.inFlight)
+	}
+}
@@ -0,0 +1,291 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
+	"github.com/n1rocket/go-auth-jwt/internal/lock"
+)
+
+// schedulerLockKey is the lock electing the instance that runs jobs
+const schedulerLockKey = "scheduler"
+
+// Job is a task the scheduler runs periodically
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+
+	// Local jobs keep state of the instance itself, such as its connection
+	// pool, so they run on every instance even with a locker
+	Local bool
+}
+
+// Scheduler runs jobs on fixed intervals. A job's runs never overlap; if a
+// run takes longer than the interval the next one starts when it finishes.
+type Scheduler struct {
+	jobs   []Job
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+	logger *slog.Logger
+	clock  clock.Clock
+
+	mu      sync.RWMutex
+	results map[string]JobResult // last run by job name
+
+	// With a locker, only the instance holding the scheduler lock runs
+	// jobs. leaderCtx is canceled when the lease is lost.
+	locker    lock.Locker
+	leaderTTL time.Duration
+	lease     *lock.Lease
+	leaderCtx context.Context
+	stepDown  context.CancelFunc
+}
+
+// JobResult is the outcome of a job's last run
+type JobResult struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Error     string // empty when the run succeeded
+}
+
+// JobStatus describes a scheduled job and its last run
+type JobStatus struct {
+	Name     string
+	Interval time.Duration
+	LastRun  *JobResult // nil until the job has run
+}
+
+// NewScheduler creates a scheduler with no jobs
+func NewScheduler(logger *slog.Logger) *Scheduler {
+	return &Scheduler{logger: logger, clock: clock.Real{}, results: make(map[string]JobResult)}
+}
+
+// SetClock sets the clock intervals are measured by. The system clock is
+// used by default. Must be called before Start.
+func (s *Scheduler) SetClock(c clock.Clock) {
+	s.clock = clock.OrReal(c)
+}
+
+// Jobs returns the registered jobs, in the order they were added, with the
+// result of their last run
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := JobStatus{Name: job.Name, Interval: job.Interval}
+		if result, ok := s.results[job.Name]; ok {
+			status.LastRun = &result
+		}
+		jobs = append(jobs, status)
+	}
+	return jobs
+}
+
+// SetLocker runs jobs only on the instance holding the scheduler lock, so
+// instances sharing a database run each job once per interval. The leader
+// holds the lock for ttl and renews it every third of ttl; when it stops,
+// another instance takes over within ttl. Must be called before Start.
+func (s *Scheduler) SetLocker(locker lock.Locker, ttl time.Duration) {
+	s.locker = locker
+	s.leaderTTL = ttl
+}
+
+// Leader reports whether this instance runs the jobs
+func (s *Scheduler) Leader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.locker == nil || s.lease != nil
+}
+
+// Add registers a job. Jobs must be added before Start.
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job name and run function are required")
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("job %s: interval must be positive", job.Name)
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Start runs every job once its first interval has elapsed and then on each
+// interval until Stop is called or ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	if s.locker != nil {
+		s.wg.Add(1)
+		go s.lead(ctx)
+	}
+	for _, job := range s.jobs {
+		s.logger.Info("scheduling job", "job", job.Name, "interval", job.Interval)
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	next := s.clock.Now().Add(job.Interval)
+	timer := s.clock.NewTimer(job.Interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+			if runCtx, ok := s.jobContext(ctx, job); ok {
+				s.run(runCtx, job)
+			} else {
+				s.logger.Debug("skipping job on follower", "job", job.Name)
+			}
+
+			// Keep to the interval, or start at once after a run that
+			// overran it
+			now := s.clock.Now()
+			next = next.Add(job.Interval)
+			if next.Before(now) {
+				next = now
+			}
+			timer.Reset(next.Sub(now))
+		}
+	}
+}
+
+// jobContext returns the context job runs in, which is canceled when this
+// instance stops leading. ok is false on followers, unless the job is local.
+func (s *Scheduler) jobContext(ctx context.Context, job Job) (context.Context, bool) {
+	if s.locker == nil || job.Local {
+		return ctx, true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.lease == nil {
+		return nil, false
+	}
+	return s.leaderCtx, true
+}
+
+// lead takes the scheduler lock, or renews it, every third of the ttl, and
+// releases it once ctx is done
+func (s *Scheduler) lead(ctx context.Context) {
+	defer s.wg.Done()
+
+	timer := s.clock.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.resign()
+			return
+		case <-timer.C():
+			s.renewLeadership(ctx)
+			timer.Reset(s.leaderTTL / 3)
+		}
+	}
+}
+
+// renewLeadership extends the lease, or tries to take the lock on followers
+func (s *Scheduler) renewLeadership(ctx context.Context) {
+	s.mu.RLock()
+	lease := s.lease
+	s.mu.RUnlock()
+
+	if lease == nil {
+		lease, err := s.locker.TryLock(ctx, schedulerLockKey, s.leaderTTL)
+		if err != nil {
+			if !errors.Is(err, lock.ErrNotAcquired) && ctx.Err() == nil {
+				s.logger.Warn("failed to take scheduler lock", "error", err)
+			}
+			return
+		}
+		leaderCtx, stepDown := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.lease, s.leaderCtx, s.stepDown = lease, leaderCtx, stepDown
+		s.mu.Unlock()
+		s.logger.Info("elected scheduler leader", "fencing_token", lease.Token)
+		return
+	}
+
+	if err := s.locker.Extend(ctx, lease, s.leaderTTL); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		// Step down even if the lock might still be held, so jobs never run
+		// on two instances
+		s.logger.Warn("lost scheduler leadership", "error", err)
+		s.mu.Lock()
+		s.stepDown()
+		s.lease = nil
+		s.mu.Unlock()
+	}
+}
+
+// resign cancels running jobs and releases the scheduler lock, so another
+// instance can take over at once
+func (s *Scheduler) resign() {
+	s.mu.Lock()
+	lease := s.lease
+	if lease != nil {
+		s.stepDown()
+		s.lease = nil
+	}
+	s.mu.Unlock()
+	if lease == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.locker.Unlock(ctx, lease); err != nil && !errors.Is(err, lock.ErrLockLost) {
+		s.logger.Warn("failed to release scheduler lock", "error", err)
+	}
+}
+
+// run executes a job once, recovering from panics so one bad run does not
+// stop the schedule
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	start := s.clock.Now()
+	result := JobResult{StartedAt: start}
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("scheduled job panicked", "job", job.Name, "panic", r)
+			result.Error = fmt.Sprintf("panic: %v", r)
+		}
+		result.Duration = s.clock.Now().Sub(start)
+		s.mu.Lock()
+		s.results[job.Name] = result
+		s.mu.Unlock()
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		s.logger.Error("scheduled job failed",
+			"job", job.Name,
+			"duration", s.clock.Now().Sub(start),
+			"error", err,
+		)
+		result.Error = err.Error()
+		return
+	}
+	s.logger.Debug("scheduled job finished", "job", job.Name, "duration", s.clock.Now().Sub(start))
+}
@@ -0,0 +1,209 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/clock"
+	"github.com/n1rocket/go-auth-jwt/internal/lock"
+)
+
+func TestScheduler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	t.Run("runs jobs on their interval", func(t *testing.T) {
+		fake := clock.NewFake(time.Now())
+		scheduler := NewScheduler(logger)
+		scheduler.SetClock(fake)
+
+		var runs, failing atomic.Int32
+		if err := scheduler.Add(Job{Name: "count", Interval: time.Minute, Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := scheduler.Add(Job{Name: "fail", Interval: time.Minute, Run: func(ctx context.Context) error {
+			failing.Add(1)
+			if failing.Load() == 1 {
+				panic("boom")
+			}
+			return errors.New("failed")
+		}}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+
+		scheduler.Start(context.Background())
+		fake.BlockUntil(2)
+		fake.Advance(59 * time.Second)
+		if runs.Load() != 0 {
+			t.Errorf("job ran %d times before its interval", runs.Load())
+		}
+
+		// Each job waits for its next run once the last one returns
+		fake.Advance(time.Second)
+		fake.BlockUntil(2)
+		fake.Advance(time.Minute)
+		fake.BlockUntil(2)
+		scheduler.Stop()
+
+		if runs.Load() != 2 {
+			t.Errorf("expected job to run twice, ran %d times", runs.Load())
+		}
+		// Failures and panics must not stop the schedule
+		if failing.Load() != 2 {
+			t.Errorf("expected failing job to keep running, ran %d times", failing.Load())
+		}
+
+		// No runs after Stop
+		fake.Advance(time.Hour)
+		if runs.Load() != 2 {
+			t.Error("job ran after Stop")
+		}
+	})
+
+	t.Run("stop cancels running jobs", func(t *testing.T) {
+		fake := clock.NewFake(time.Now())
+		scheduler := NewScheduler(logger)
+		scheduler.SetClock(fake)
+
+		started := make(chan struct{})
+		_ = scheduler.Add(Job{Name: "slow", Interval: time.Minute, Run: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+
+		scheduler.Start(context.Background())
+		fake.BlockUntil(1)
+		fake.Advance(time.Minute)
+		<-started
+
+		done := make(chan struct{})
+		go func() {
+			scheduler.Stop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Stop() did not return")
+		}
+	})
+
+	t.Run("reports the last run of each job", func(t *testing.T) {
+		fake := clock.NewFake(time.Now())
+		scheduler := NewScheduler(logger)
+		scheduler.SetClock(fake)
+		_ = scheduler.Add(Job{Name: "ok", Interval: time.Minute, Run: func(ctx context.Context) error { return nil }})
+		_ = scheduler.Add(Job{Name: "fail", Interval: time.Minute, Run: func(ctx context.Context) error { return errors.New("failed") }})
+		_ = scheduler.Add(Job{Name: "idle", Interval: time.Hour, Run: func(ctx context.Context) error { return nil }})
+
+		scheduler.Start(context.Background())
+		fake.BlockUntil(3)
+		fake.Advance(time.Minute)
+		// Jobs wait for their next run once they have recorded this one
+		fake.BlockUntil(3)
+		scheduler.Stop()
+
+		jobs := scheduler.Jobs()
+		if len(jobs) != 3 {
+			t.Fatalf("Jobs() returned %d jobs, want 3", len(jobs))
+		}
+		if jobs[0].Name != "ok" || jobs[0].LastRun == nil || jobs[0].LastRun.Error != "" {
+			t.Errorf("ok job = %+v, want a successful last run", jobs[0])
+		}
+		if jobs[1].LastRun == nil || jobs[1].LastRun.Error != "failed" {
+			t.Errorf("fail job = %+v, want the last run's error", jobs[1])
+		}
+		if jobs[2].LastRun != nil || jobs[2].Interval != time.Hour {
+			t.Errorf("idle job = %+v, want no last run", jobs[2])
+		}
+	})
+
+	t.Run("rejects invalid jobs", func(t *testing.T) {
+		scheduler := NewScheduler(logger)
+		noop := func(ctx context.Context) error { return nil }
+
+		if err := scheduler.Add(Job{Interval: time.Second, Run: noop}); err == nil {
+			t.Error("expected error for job without name")
+		}
+		if err := scheduler.Add(Job{Name: "noop", Run: noop}); err == nil {
+			t.Error("expected error for job without interval")
+		}
+		if err := scheduler.Add(Job{Name: "noop", Interval: time.Second}); err == nil {
+			t.Error("expected error for job without run function")
+		}
+	})
+
+	t.Run("stop before start", func(t *testing.T) {
+		NewScheduler(logger).Stop()
+	})
+
+	t.Run("runs jobs only on the leader", func(t *testing.T) {
+		fake := clock.NewFake(time.Now())
+		locker := lock.NewMemoryLocker()
+		locker.SetClock(fake)
+
+		// Leases are renewed every second; jobs run every five
+		var runs, localRuns [2]atomic.Int32
+		schedulers := make([]*Scheduler, 2)
+		for i := range schedulers {
+			schedulers[i] = NewScheduler(logger)
+			schedulers[i].SetClock(fake)
+			schedulers[i].SetLocker(locker, 3*time.Second)
+			_ = schedulers[i].Add(Job{Name: "count", Interval: 5 * time.Second, Run: func(ctx context.Context) error {
+				runs[i].Add(1)
+				return nil
+			}})
+			_ = schedulers[i].Add(Job{Name: "local", Interval: 5 * time.Second, Local: true, Run: func(ctx context.Context) error {
+				localRuns[i].Add(1)
+				return nil
+			}})
+		}
+
+		// Every running instance keeps a leadership timer and one timer per
+		// job pending; each step waits for them to be rearmed
+		step := func(seconds, pending int) {
+			for i := 0; i < seconds; i++ {
+				fake.Advance(time.Second)
+				fake.BlockUntil(pending)
+			}
+		}
+
+		// The first instance wins the election before the second starts
+		schedulers[0].Start(context.Background())
+		fake.BlockUntil(3)
+		schedulers[1].Start(context.Background())
+		fake.BlockUntil(6)
+		step(5, 6)
+
+		if !schedulers[0].Leader() || schedulers[1].Leader() {
+			t.Fatalf("Leader() = %v, %v, want the first instance only", schedulers[0].Leader(), schedulers[1].Leader())
+		}
+		if runs[0].Load() != 1 || runs[1].Load() != 0 {
+			t.Errorf("runs = %d, %d, want jobs on the leader only", runs[0].Load(), runs[1].Load())
+		}
+		if localRuns[1].Load() != 1 {
+			t.Error("local job did not run on the follower")
+		}
+
+		// Stopping the leader releases the lock to the other instance, which
+		// takes it on its next attempt
+		schedulers[0].Stop()
+		step(1, 3)
+		if !schedulers[1].Leader() {
+			t.Fatal("the follower did not take over")
+		}
+		step(4, 3)
+		schedulers[1].Stop()
+		if runs[1].Load() != 1 {
+			t.Errorf("runs on the new leader = %d, want 1", runs[1].Load())
+		}
+	})
+}
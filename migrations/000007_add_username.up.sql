@@ -0,0 +1,9 @@
+-- Add optional username usable for login alongside email
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(30);
+
+-- Usernames are stored lowercased; NULLs do not conflict
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username);
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Add display name and localization preferences (avatar_url exists since 000003)
+BEGIN;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(500),
+ADD COLUMN IF NOT EXISTS display_name VARCHAR(100),
+ADD COLUMN IF NOT EXISTS locale VARCHAR(35),
+ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+
+COMMIT;
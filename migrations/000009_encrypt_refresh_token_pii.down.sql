@@ -0,0 +1,8 @@
+-- Restore the INET type; encrypted addresses cannot be converted and are cleared
+BEGIN;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN ip_address TYPE INET
+USING CASE WHEN ip_address LIKE 'enc:%' THEN NULL ELSE ip_address::inet END;
+
+COMMIT;
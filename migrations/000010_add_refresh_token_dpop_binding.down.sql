@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS dpop_jkt;
+
+COMMIT;
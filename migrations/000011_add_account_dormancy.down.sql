@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_users_disabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
+
+DROP TABLE IF EXISTS account_activity;
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- Monthly request counts for tenant and client quotas
+BEGIN;
+
+-- One row per subject and calendar month; subjects are "user:<id>" or "tenant:<name>"
+CREATE TABLE IF NOT EXISTS quota_usage (
+    subject VARCHAR(255) NOT NULL,
+    period DATE NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (subject, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quota_usage_period ON quota_usage(period);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Keys for HMAC-signed admin API requests
+BEGIN;
+
+-- Secrets are derived from ADMIN_REQUEST_SIGNING_SECRET and the key id, so
+-- only metadata is stored
+CREATE TABLE IF NOT EXISTS admin_signing_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+COMMIT;
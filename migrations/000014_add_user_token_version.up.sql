@@ -0,0 +1,6 @@
+-- Version of a user's access tokens; bumping it invalidates the ones issued before
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+
+COMMIT;
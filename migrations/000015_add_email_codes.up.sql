@@ -0,0 +1,17 @@
+-- One-time codes sent by email for verification and password reset
+BEGIN;
+
+-- One active code per user and purpose; issuing a new code replaces it
+CREATE TABLE IF NOT EXISTS email_codes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    code_hash VARCHAR(255) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, purpose)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_codes_expires_at ON email_codes(expires_at);
+
+COMMIT;
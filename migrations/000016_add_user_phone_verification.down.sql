@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS sms_second_factor,
+DROP COLUMN IF EXISTS phone_verified;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Verification and SMS second factor for the phone number added with the profile
+BEGIN;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS phone_verified BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS sms_second_factor BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
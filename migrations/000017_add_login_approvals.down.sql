@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS login_approvals;
+DROP TABLE IF EXISTS trusted_devices;
+
+COMMIT;
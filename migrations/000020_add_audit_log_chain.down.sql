@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE audit_logs
+    DROP COLUMN IF EXISTS user_digest,
+    DROP COLUMN IF EXISTS hash,
+    DROP COLUMN IF EXISTS prev_hash,
+    DROP COLUMN IF EXISTS chain_seq;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Hash chain over audit log entries, making deletion or modification of
+-- entries detectable. Entries written before chaining was enabled keep
+-- NULL chain columns and are not verified.
+BEGIN;
+
+ALTER TABLE audit_logs
+    ADD COLUMN IF NOT EXISTS chain_seq BIGINT UNIQUE,
+    ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS user_digest VARCHAR(64);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS service_account_assertions;
+DROP TABLE IF EXISTS service_account_keys;
+DROP TABLE IF EXISTS service_accounts;
+
+COMMIT;
@@ -0,0 +1,35 @@
+-- Service accounts authenticate with JWT assertions signed by a registered key
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    scope TEXT NOT NULL DEFAULT '', -- space-delimited, like the scope claim
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Keys are identified by their RFC 7638 thumbprint and kept after they
+-- expire so rotations stay visible
+CREATE TABLE IF NOT EXISTS service_account_keys (
+    id VARCHAR(64) NOT NULL,
+    service_account_id VARCHAR(64) NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    public_key JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (service_account_id, id)
+);
+
+-- Assertion ids are remembered until the assertion expires, so each one is
+-- only accepted once
+CREATE TABLE IF NOT EXISTS service_account_assertions (
+    service_account_id VARCHAR(64) NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    jti VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (service_account_id, jti)
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_account_assertions_expires_at
+    ON service_account_assertions(expires_at);
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Access tokens revoked through the RFC 7009 endpoint, by jti. Rows are only
+-- needed until the token would have expired anyway.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti VARCHAR(255) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires_at
+    ON revoked_access_tokens(expires_at);
+
+COMMIT;
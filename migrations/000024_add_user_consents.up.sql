@@ -0,0 +1,17 @@
+-- Terms of service and marketing consent answers, kept for compliance.
+-- Rows are only added; the newest row of a kind is the user's current answer.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_consents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    version VARCHAR(64) NOT NULL DEFAULT '',
+    granted BOOLEAN NOT NULL,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_consents_user_kind ON user_consents(user_id, kind, created_at DESC);
+
+COMMIT;
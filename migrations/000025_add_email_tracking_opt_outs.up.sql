@@ -0,0 +1,10 @@
+-- Users who opted out of email open and click tracking. Tracked emails go
+-- to everyone else while EMAIL_TRACKING_ENABLED is on.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS email_tracking_opt_outs (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
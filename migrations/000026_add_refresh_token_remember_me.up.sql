@@ -0,0 +1,6 @@
+-- Mark the long-lived sessions users asked to be remembered for
+BEGIN;
+
+ALTER TABLE refresh_tokens ADD COLUMN remember_me BOOLEAN NOT NULL DEFAULT false;
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- Short-lived codes a signed-in device hands to another device, e.g. as a
+-- QR code, to start a session there
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS session_handoffs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_handoffs_user_id ON session_handoffs(user_id);
+
+COMMIT;
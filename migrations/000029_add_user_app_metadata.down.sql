@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE users DROP COLUMN IF EXISTS app_metadata;
+ALTER TABLE users ALTER COLUMN metadata DROP NOT NULL;
+
+COMMIT;
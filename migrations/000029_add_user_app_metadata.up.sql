@@ -0,0 +1,9 @@
+-- User-writable metadata (added with the profile) and admin-writable app_metadata
+BEGIN;
+
+UPDATE users SET metadata = '{}' WHERE metadata IS NULL;
+ALTER TABLE users ALTER COLUMN metadata SET NOT NULL;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS app_metadata JSONB NOT NULL DEFAULT '{}';
+
+COMMIT;
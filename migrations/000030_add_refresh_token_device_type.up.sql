@@ -0,0 +1,8 @@
+-- Coarse device type of each session for session analytics. The user agent
+-- may be encrypted, so it cannot be grouped by in SQL; sessions created
+-- before this migration have no device type and are reported as unknown.
+BEGIN;
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS device_type TEXT;
+
+COMMIT;
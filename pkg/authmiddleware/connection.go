@@ -0,0 +1,263 @@
+// Package authmiddleware lets services behind the auth service check its
+// tokens. ConnectionValidator checks the short-lived connection tokens issued
+// by POST /api/v1/auth/connection-token when a WebSocket or SSE connection
+// is opened, so long-lived sockets never carry access tokens.
+package authmiddleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ConnectionTokenParam is the query parameter browsers send connection
+// tokens in, since they cannot set headers on WebSocket handshakes
+const ConnectionTokenParam = "connection_token"
+
+// connectionTokenUse is the token_use claim of connection tokens; it matches
+// token.ConnectionTokenUse
+const connectionTokenUse = "connection"
+
+// DefaultLeeway is the clock skew allowed when checking token times
+const DefaultLeeway = 5 * time.Second
+
+// ErrInvalidConnectionToken is returned for tokens that are malformed,
+// expired, already used or not connection tokens
+var ErrInvalidConnectionToken = errors.New("invalid connection token")
+
+// Connection is the user a connection token was issued to
+type Connection struct {
+	UserID    string
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+// connectionClaims are the claims of a connection token
+type connectionClaims struct {
+	Use string `json:"token_use"`
+	jwt.RegisteredClaims
+}
+
+// ConnectionValidator checks connection tokens at upgrade time. A token
+// opens one connection: its jti is remembered until it expires, so a token
+// leaked through a logged URL cannot be replayed. The replay cache is per
+// process, so run one validator per socket server.
+type ConnectionValidator struct {
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+
+	keysMu sync.RWMutex
+	secret []byte                    // HS256 key
+	keys   map[string]*rsa.PublicKey // RS256 keys by kid
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewHS256ConnectionValidator creates a validator for tokens signed with the
+// auth service's JWT_SECRET
+func NewHS256ConnectionValidator(secret []byte, issuer string) *ConnectionValidator {
+	v := newConnectionValidator(issuer)
+	v.secret = secret
+	return v
+}
+
+// NewJWKSConnectionValidator creates a validator for RS256 tokens, checked
+// against the auth service's /.well-known/jwks.json. Refetch the key set and
+// pass it to SetJWKS regularly so rolled over keys are picked up.
+func NewJWKSConnectionValidator(jwks []byte, issuer string) (*ConnectionValidator, error) {
+	v := newConnectionValidator(issuer)
+	if err := v.SetJWKS(jwks); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func newConnectionValidator(issuer string) *ConnectionValidator {
+	return &ConnectionValidator{
+		issuer: issuer,
+		leeway: DefaultLeeway,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// SetJWKS replaces the RS256 keys with the signing keys of a JWKS document.
+// Keys of other types, such as the token encryption key, are skipped.
+func (v *ConnectionValidator) SetJWKS(jwks []byte) error {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(jwks, &set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return fmt.Errorf("invalid modulus of key %q: %w", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return fmt.Errorf("invalid exponent of key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no RSA signing keys")
+	}
+
+	v.keysMu.Lock()
+	v.keys = keys
+	v.keysMu.Unlock()
+	return nil
+}
+
+// SetAudience requires tokens issued for audience, such as the URL of the
+// socket endpoint. Tokens for other or no audiences are rejected.
+func (v *ConnectionValidator) SetAudience(audience string) {
+	v.audience = audience
+}
+
+// SetLeeway sets the clock skew allowed when checking token times
+func (v *ConnectionValidator) SetLeeway(leeway time.Duration) {
+	v.leeway = leeway
+}
+
+// Validate checks a connection token and uses it up
+func (v *ConnectionValidator) Validate(tokenString string) (*Connection, error) {
+	options := []jwt.ParserOption{
+		jwt.WithIssuer(v.issuer),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(v.now),
+	}
+	if v.audience != "" {
+		options = append(options, jwt.WithAudience(v.audience))
+	}
+
+	claims := &connectionClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, v.key, options...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConnectionToken, err)
+	}
+	if claims.Use != connectionTokenUse {
+		return nil, fmt.Errorf("%w: not a connection token", ErrInvalidConnectionToken)
+	}
+	if claims.Subject == "" || claims.ID == "" {
+		return nil, fmt.Errorf("%w: sub and jti are required", ErrInvalidConnectionToken)
+	}
+
+	// Checked last so a rejected token does not burn its jti
+	expiresAt := claims.ExpiresAt.Time
+	if !v.remember(claims.ID, expiresAt.Add(v.leeway)) {
+		return nil, fmt.Errorf("%w: token has already been used", ErrInvalidConnectionToken)
+	}
+
+	return &Connection{UserID: claims.Subject, TokenID: claims.ID, ExpiresAt: expiresAt}, nil
+}
+
+// key returns the key that verifies the token
+func (v *ConnectionValidator) key(token *jwt.Token) (interface{}, error) {
+	v.keysMu.RLock()
+	defer v.keysMu.RUnlock()
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if v.secret == nil {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		return v.secret, nil
+	case *jwt.SigningMethodRSA:
+		kid, _ := token.Header["kid"].(string)
+		key, ok := v.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+}
+
+// remember records a token ID until expiresAt, reporting false if it was
+// already seen
+func (v *ConnectionValidator) remember(id string, expiresAt time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	if now.Sub(v.lastSweep) > time.Minute {
+		for seenID, expires := range v.seen {
+			if now.After(expires) {
+				delete(v.seen, seenID)
+			}
+		}
+		v.lastSweep = now
+	}
+	if expires, replayed := v.seen[id]; replayed && !now.After(expires) {
+		return false
+	}
+	v.seen[id] = expiresAt
+	return true
+}
+
+// Middleware rejects requests without a valid connection token with 401
+// before they are upgraded, and passes the Connection to next in the request
+// context
+func (v *ConnectionValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := v.Validate(ConnectionToken(r))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, ErrInvalidConnectionToken.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), connectionKey{}, connection)))
+	})
+}
+
+// ConnectionToken returns the connection token of a request: the
+// connection_token query parameter or, for SSE clients that can set headers,
+// the bearer token
+func ConnectionToken(r *http.Request) string {
+	if connectionToken := r.URL.Query().Get(ConnectionTokenParam); connectionToken != "" {
+		return connectionToken
+	}
+	if scheme, credentials, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(credentials)
+	}
+	return ""
+}
+
+// connectionKey is the request context key of the Connection
+type connectionKey struct{}
+
+// ConnectionFromContext returns the Connection stored by Middleware
+func ConnectionFromContext(ctx context.Context) (*Connection, bool) {
+	connection, ok := ctx.Value(connectionKey{}).(*Connection)
+	return connection, ok
+}
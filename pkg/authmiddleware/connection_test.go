@@ -0,0 +1,187 @@
+package authmiddleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/n1rocket/go-auth-jwt/internal/token"
+)
+
+func TestConnectionTokenUse(t *testing.T) {
+	if connectionTokenUse != token.ConnectionTokenUse {
+		t.Errorf("connectionTokenUse = %q, want %q", connectionTokenUse, token.ConnectionTokenUse)
+	}
+}
+
+func TestConnectionValidator_HS256(t *testing.T) {
+	manager, err := token.NewManager("HS256", "test-secret", "", "", "auth.example.com", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	validator := NewHS256ConnectionValidator([]byte("test-secret"), "auth.example.com")
+
+	connectionToken, _, err := manager.GenerateConnectionToken("user-1", "")
+	if err != nil {
+		t.Fatalf("GenerateConnectionToken() error = %v", err)
+	}
+	connection, err := validator.Validate(connectionToken)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if connection.UserID != "user-1" || connection.TokenID == "" {
+		t.Errorf("Validate() = %+v", connection)
+	}
+
+	// A token opens one connection
+	if _, err := validator.Validate(connectionToken); !errors.Is(err, ErrInvalidConnectionToken) {
+		t.Errorf("Validate() replay error = %v, want %v", err, ErrInvalidConnectionToken)
+	}
+
+	accessToken, err := manager.GenerateAccessToken("user-1", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	otherIssuer, _ := token.NewManager("HS256", "test-secret", "", "", "evil.example.com", 15*time.Minute)
+	foreignToken, _, _ := otherIssuer.GenerateConnectionToken("user-1", "")
+	otherSecret, _ := token.NewManager("HS256", "other-secret", "", "", "auth.example.com", 15*time.Minute)
+	forgedToken, _, _ := otherSecret.GenerateConnectionToken("user-1", "")
+
+	for name, tokenString := range map[string]string{
+		"access token": accessToken,
+		"other issuer": foreignToken,
+		"wrong secret": forgedToken,
+		"empty":        "",
+		"not a jwt":    "connection",
+	} {
+		if _, err := validator.Validate(tokenString); !errors.Is(err, ErrInvalidConnectionToken) {
+			t.Errorf("Validate(%s) error = %v, want %v", name, err, ErrInvalidConnectionToken)
+		}
+	}
+}
+
+func TestConnectionValidator_Expired(t *testing.T) {
+	manager, _ := token.NewManager("HS256", "test-secret", "", "", "auth.example.com", 15*time.Minute)
+	validator := NewHS256ConnectionValidator([]byte("test-secret"), "auth.example.com")
+	validator.now = func() time.Time { return time.Now().Add(token.ConnectionTokenTTL + DefaultLeeway + time.Second) }
+
+	connectionToken, _, _ := manager.GenerateConnectionToken("user-1", "")
+	if _, err := validator.Validate(connectionToken); !errors.Is(err, ErrInvalidConnectionToken) {
+		t.Errorf("Validate() after expiry error = %v, want %v", err, ErrInvalidConnectionToken)
+	}
+}
+
+func TestConnectionValidator_JWKS(t *testing.T) {
+	manager := newRS256Manager(t)
+	jwks, err := manager.GetJWKS()
+	if err != nil {
+		t.Fatalf("GetJWKS() error = %v", err)
+	}
+	document, _ := json.Marshal(jwks)
+
+	validator, err := NewJWKSConnectionValidator(document, "auth.example.com")
+	if err != nil {
+		t.Fatalf("NewJWKSConnectionValidator() error = %v", err)
+	}
+	validator.SetAudience("wss://chat.example.com")
+
+	connectionToken, _, _ := manager.GenerateConnectionToken("user-1", "wss://chat.example.com")
+	if _, err := validator.Validate(connectionToken); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	otherAudience, _, _ := manager.GenerateConnectionToken("user-1", "wss://billing.example.com")
+	if _, err := validator.Validate(otherAudience); !errors.Is(err, ErrInvalidConnectionToken) {
+		t.Errorf("Validate() for another audience error = %v, want %v", err, ErrInvalidConnectionToken)
+	}
+
+	if _, err := NewJWKSConnectionValidator([]byte(`{"keys":[]}`), "auth.example.com"); err == nil {
+		t.Error("NewJWKSConnectionValidator() accepted a key set without signing keys")
+	}
+}
+
+func TestConnectionValidator_Middleware(t *testing.T) {
+	manager, _ := token.NewManager("HS256", "test-secret", "", "", "auth.example.com", 15*time.Minute)
+	validator := NewHS256ConnectionValidator([]byte("test-secret"), "auth.example.com")
+	handler := validator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, ok := ConnectionFromContext(r.Context())
+		if !ok {
+			t.Error("ConnectionFromContext() found no connection")
+			return
+		}
+		w.Write([]byte(connection.UserID))
+	}))
+
+	queryToken, _, _ := manager.GenerateConnectionToken("user-1", "")
+	headerToken, _, _ := manager.GenerateConnectionToken("user-2", "")
+	tests := []struct {
+		name       string
+		target     string
+		bearer     string
+		wantStatus int
+		wantUser   string
+	}{
+		{name: "query parameter", target: "/ws?connection_token=" + queryToken, wantStatus: http.StatusOK, wantUser: "user-1"},
+		{name: "bearer token", target: "/events", bearer: headerToken, wantStatus: http.StatusOK, wantUser: "user-2"},
+		{name: "replayed", target: "/ws?connection_token=" + queryToken, wantStatus: http.StatusUnauthorized},
+		{name: "missing", target: "/ws", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantUser != "" && rec.Body.String() != tt.wantUser {
+				t.Errorf("user = %q, want %q", rec.Body.String(), tt.wantUser)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+// newRS256Manager returns a token manager signing with a fresh RSA key
+func newRS256Manager(t *testing.T) *token.Manager {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := token.NewManager("RS256", "", privatePath, publicPath, "auth.example.com", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	return manager
+}